@@ -0,0 +1,422 @@
+package benchmarks
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+//go:embed corpora/*.zed
+var corpora embed.FS
+
+// writeBatchSize is the number of relationships written per request when loading a
+// corpus, which must not exceed the maximum updates per write of the test server.
+const writeBatchSize = 1000
+
+// corpus is a schema, the relationships generated for it, and the operations measured
+// against it, each of which has a known result.
+type corpus struct {
+	name          string
+	schemaFile    string
+	relationships func() []*v1.Relationship
+	checks        []checkCase
+	lookups       []lookupCase
+}
+
+type checkCase struct {
+	name       string
+	resource   *v1.ObjectReference
+	permission string
+	subject    *v1.SubjectReference
+	context    map[string]any
+	expected   v1.CheckPermissionResponse_Permissionship
+}
+
+type lookupCase struct {
+	name         string
+	resourceType string
+	permission   string
+	subject      *v1.SubjectReference
+	context      map[string]any
+
+	// expectedCount is the number of resources for which the subject has the permission
+	// unconditionally.
+	expectedCount int
+}
+
+var allCorpora = []corpus{
+	{
+		name:          "nested groups",
+		schemaFile:    "corpora/nestedgroups.zed",
+		relationships: nestedGroupsRelationships,
+		checks: []checkCase{
+			{
+				name:       "member of the deepest group",
+				resource:   object("document", "doc-0"),
+				permission: "view",
+				subject:    subject("user", "leaf"),
+				expected:   v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+			},
+			{
+				name:       "member of no group",
+				resource:   object("document", "doc-0"),
+				permission: "view",
+				subject:    subject("user", "outsider"),
+				expected:   v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+			},
+		},
+		lookups: []lookupCase{
+			{
+				name:          "documents of the deepest group member",
+				resourceType:  "document",
+				permission:    "view",
+				subject:       subject("user", "leaf"),
+				expectedCount: nestedGroupDocuments,
+			},
+		},
+	},
+	{
+		name:          "wide fan-out",
+		schemaFile:    "corpora/widefanout.zed",
+		relationships: wideFanOutRelationships,
+		checks: []checkCase{
+			{
+				name:       "team member through parent folder",
+				resource:   object("document", "doc-5"),
+				permission: "read",
+				subject:    subject("user", "member-7"),
+				expected:   v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+			},
+			{
+				name:       "team member without access",
+				resource:   object("document", "doc-19"),
+				permission: "read",
+				subject:    subject("user", "member-7"),
+				expected:   v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+			},
+		},
+		lookups: []lookupCase{
+			{
+				name:          "documents readable by a team member",
+				resourceType:  "document",
+				permission:    "read",
+				subject:       subject("user", "member-7"),
+				expectedCount: wideFanOutDocuments / 2,
+			},
+		},
+	},
+	{
+		name:          "caveats",
+		schemaFile:    "corpora/caveats.zed",
+		relationships: caveatedRelationships,
+		checks: []checkCase{
+			{
+				name:       "within quota",
+				resource:   object("document", "doc-99"),
+				permission: "view",
+				subject:    subject("user", "quota-user"),
+				context:    map[string]any{"used": 50},
+				expected:   v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+			},
+			{
+				name:       "over quota",
+				resource:   object("document", "doc-10"),
+				permission: "view",
+				subject:    subject("user", "quota-user"),
+				context:    map[string]any{"used": 50},
+				expected:   v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+			},
+			{
+				name:       "missing context",
+				resource:   object("document", "doc-99"),
+				permission: "view",
+				subject:    subject("user", "quota-user"),
+				expected:   v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION,
+			},
+			{
+				name:       "group member on allowed network",
+				resource:   object("document", "doc-3"),
+				permission: "view",
+				subject:    subject("user", "network-user-3"),
+				context:    map[string]any{"user_ip": "10.3.2.1"},
+				expected:   v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+			},
+		},
+		lookups: []lookupCase{
+			{
+				name:          "documents within quota",
+				resourceType:  "document",
+				permission:    "view",
+				subject:       subject("user", "quota-user"),
+				context:       map[string]any{"used": 50},
+				expectedCount: caveatedDocuments / 100 * 49,
+			},
+			{
+				name:          "documents on allowed network",
+				resourceType:  "document",
+				permission:    "view",
+				subject:       subject("user", "network-user-3"),
+				context:       map[string]any{"user_ip": "10.3.2.1"},
+				expectedCount: caveatedDocuments,
+			},
+		},
+	},
+}
+
+const (
+	nestedGroupDepth     = 25
+	nestedGroupDocuments = 200
+)
+
+// nestedGroupsRelationships generates a chain of groups, each nested in the one before
+// it and padded with direct members and dead-end subgroups, with a single user in the
+// deepest group.
+func nestedGroupsRelationships() []*v1.Relationship {
+	var rels []*v1.Relationship
+	for depth := 0; depth < nestedGroupDepth; depth++ {
+		group := fmt.Sprintf("level-%d", depth)
+		if depth+1 < nestedGroupDepth {
+			rels = append(rels, relationship("group", group, "member", subjectSet("group", fmt.Sprintf("level-%d", depth+1), "member"), nil))
+		}
+		for i := 0; i < 20; i++ {
+			rels = append(rels, relationship("group", group, "member", subject("user", fmt.Sprintf("%s-user-%d", group, i)), nil))
+		}
+		for i := 0; i < 5; i++ {
+			sibling := fmt.Sprintf("%s-sibling-%d", group, i)
+			rels = append(rels, relationship("group", group, "member", subjectSet("group", sibling, "member"), nil))
+			for j := 0; j < 10; j++ {
+				rels = append(rels, relationship("group", sibling, "member", subject("user", fmt.Sprintf("%s-user-%d", sibling, j)), nil))
+			}
+		}
+	}
+	rels = append(rels, relationship("group", fmt.Sprintf("level-%d", nestedGroupDepth-1), "member", subject("user", "leaf"), nil))
+
+	for i := 0; i < nestedGroupDocuments; i++ {
+		rels = append(rels,
+			relationship("document", fmt.Sprintf("doc-%d", i), "viewer", subjectSet("group", "level-0", "member"), nil),
+			relationship("document", fmt.Sprintf("unshared-%d", i), "viewer", subject("user", "outsider-owner"), nil),
+		)
+	}
+	return rels
+}
+
+const (
+	wideFanOutFolders   = 20
+	wideFanOutDocuments = 5000
+)
+
+// wideFanOutRelationships generates thousands of documents spread across folders, half
+// of which are viewable by a team, along with per-document writers.
+func wideFanOutRelationships() []*v1.Relationship {
+	var rels []*v1.Relationship
+	for i := 0; i < 100; i++ {
+		rels = append(rels, relationship("team", "eng", "member", subject("user", fmt.Sprintf("member-%d", i)), nil))
+	}
+	for i := 0; i < wideFanOutFolders/2; i++ {
+		rels = append(rels, relationship("folder", fmt.Sprintf("folder-%d", i), "viewer", subjectSet("team", "eng", "member"), nil))
+	}
+	for i := 0; i < wideFanOutDocuments; i++ {
+		doc := fmt.Sprintf("doc-%d", i)
+		rels = append(rels,
+			relationship("document", doc, "parent", subject("folder", fmt.Sprintf("folder-%d", i%wideFanOutFolders)), nil),
+			relationship("document", doc, "writer", subject("user", fmt.Sprintf("owner-%d", i%50)), nil),
+		)
+	}
+	return rels
+}
+
+const (
+	caveatedDocuments = 1000
+	caveatedGroups    = 10
+)
+
+// caveatedRelationships generates documents shared with a user under a per-document
+// quota, and with groups whose members are restricted to a network.
+func caveatedRelationships() []*v1.Relationship {
+	var rels []*v1.Relationship
+	for i := 0; i < caveatedGroups; i++ {
+		for j := 0; j < 50; j++ {
+			rels = append(rels, relationship("group", fmt.Sprintf("group-%d", i), "member",
+				subject("user", fmt.Sprintf("network-user-%d", j)),
+				caveat("on_network", map[string]any{"allowed_range": fmt.Sprintf("10.%d.0.0/16", j%5)})))
+		}
+	}
+	for i := 0; i < caveatedDocuments; i++ {
+		doc := fmt.Sprintf("doc-%d", i)
+		rels = append(rels,
+			relationship("document", doc, "viewer", subject("user", "quota-user"), caveat("within_quota", map[string]any{"quota": i % 100})),
+			relationship("document", doc, "viewer", subjectSet("group", fmt.Sprintf("group-%d", i%caveatedGroups), "member"), nil),
+		)
+	}
+	return rels
+}
+
+func object(objectType, objectID string) *v1.ObjectReference {
+	return &v1.ObjectReference{ObjectType: objectType, ObjectId: objectID}
+}
+
+func subject(objectType, objectID string) *v1.SubjectReference {
+	return &v1.SubjectReference{Object: object(objectType, objectID)}
+}
+
+func subjectSet(objectType, objectID, relation string) *v1.SubjectReference {
+	return &v1.SubjectReference{Object: object(objectType, objectID), OptionalRelation: relation}
+}
+
+func caveat(name string, context map[string]any) *v1.ContextualizedCaveat {
+	return &v1.ContextualizedCaveat{CaveatName: name, Context: mustStruct(context)}
+}
+
+func relationship(resourceType, resourceID, relation string, subject *v1.SubjectReference, caveat *v1.ContextualizedCaveat) *v1.Relationship {
+	return &v1.Relationship{
+		Resource:       object(resourceType, resourceID),
+		Relation:       relation,
+		Subject:        subject,
+		OptionalCaveat: caveat,
+	}
+}
+
+func mustStruct(values map[string]any) *structpb.Struct {
+	if values == nil {
+		return nil
+	}
+
+	s, err := structpb.NewStruct(values)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// loadedCorpus is a corpus loaded into a test server.
+type loadedCorpus struct {
+	corpus
+	permissions v1.PermissionsServiceClient
+	token       *v1.ZedToken
+}
+
+// load starts a test server over the datastore, which must be empty, and writes the
+// schema and relationships of the corpus through it.
+func (c corpus) load(tb testing.TB, ds datastore.Datastore) loadedCorpus {
+	conn, cleanup := testserver.NewTestServerForDatastore(require.New(tb), ds)
+	tb.Cleanup(cleanup)
+
+	schema, err := corpora.ReadFile(c.schemaFile)
+	require.NoError(tb, err)
+
+	ctx := context.Background()
+	_, err = v1.NewSchemaServiceClient(conn).WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: string(schema)})
+	require.NoError(tb, err)
+
+	permissions := v1.NewPermissionsServiceClient(conn)
+	rels := c.relationships()
+	var token *v1.ZedToken
+	for start := 0; start < len(rels); start += writeBatchSize {
+		end := start + writeBatchSize
+		if end > len(rels) {
+			end = len(rels)
+		}
+
+		updates := make([]*v1.RelationshipUpdate, 0, end-start)
+		for _, rel := range rels[start:end] {
+			updates = append(updates, &v1.RelationshipUpdate{Operation: v1.RelationshipUpdate_OPERATION_TOUCH, Relationship: rel})
+		}
+
+		resp, err := permissions.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{Updates: updates})
+		require.NoError(tb, err)
+		token = resp.WrittenAt
+	}
+
+	return loadedCorpus{corpus: c, permissions: permissions, token: token}
+}
+
+func (lc loadedCorpus) consistency() *v1.Consistency {
+	return &v1.Consistency{Requirement: &v1.Consistency_AtLeastAsFresh{AtLeastAsFresh: lc.token}}
+}
+
+func (lc loadedCorpus) check(ctx context.Context, cc checkCase) (v1.CheckPermissionResponse_Permissionship, error) {
+	resp, err := lc.permissions.CheckPermission(ctx, &v1.CheckPermissionRequest{
+		Consistency: lc.consistency(),
+		Resource:    cc.resource,
+		Permission:  cc.permission,
+		Subject:     cc.subject,
+		Context:     mustStruct(cc.context),
+	})
+	if err != nil {
+		return v1.CheckPermissionResponse_PERMISSIONSHIP_UNSPECIFIED, err
+	}
+	return resp.Permissionship, nil
+}
+
+// lookup returns the number of resources found for which the subject has the permission
+// unconditionally.
+func (lc loadedCorpus) lookup(ctx context.Context, l lookupCase) (int, error) {
+	stream, err := lc.permissions.LookupResources(ctx, &v1.LookupResourcesRequest{
+		Consistency:        lc.consistency(),
+		ResourceObjectType: l.resourceType,
+		Permission:         l.permission,
+		Subject:            l.subject,
+		Context:            mustStruct(l.context),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if resp.Permissionship == v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION {
+			count++
+		}
+	}
+}
+
+// runBenchmarks loads each corpus into a datastore created for it, then measures each of
+// its checks and lookups.
+func runBenchmarks(b *testing.B, newDatastore func(tb testing.TB) datastore.Datastore) {
+	for _, c := range allCorpora {
+		c := c
+		b.Run(c.name, func(b *testing.B) {
+			lc := c.load(b, newDatastore(b))
+			ctx := context.Background()
+
+			for _, cc := range c.checks {
+				cc := cc
+				b.Run("check/"+cc.name, func(b *testing.B) {
+					for n := 0; n < b.N; n++ {
+						result, err := lc.check(ctx, cc)
+						require.NoError(b, err)
+						require.Equal(b, cc.expected, result)
+					}
+				})
+			}
+
+			for _, lookup := range c.lookups {
+				lookup := lookup
+				b.Run("lookup/"+lookup.name, func(b *testing.B) {
+					for n := 0; n < b.N; n++ {
+						count, err := lc.lookup(ctx, lookup)
+						require.NoError(b, err)
+						require.Equal(b, lookup.expectedCount, count)
+					}
+				})
+			}
+		})
+	}
+}
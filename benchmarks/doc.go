@@ -0,0 +1,16 @@
+// Package benchmarks contains end-to-end benchmarks of Check and LookupResources over a
+// set of representative schema corpora: deeply nested groups, documents with a wide
+// fan-out through folders, and relationships guarded by caveats.
+//
+// Each corpus is a schema under corpora/ along with relationships generated at load
+// time, which are written through the API of a test server like any other client's. The
+// benchmarks run against memdb by default:
+//
+//	go test -run '^$' -bench . -benchmem ./benchmarks/
+//
+// and against Postgres when the docker tag is given:
+//
+//	go test -tags docker -run '^$' -bench Postgres -benchmem ./benchmarks/
+//
+// Results from two revisions can be compared with benchstat.
+package benchmarks
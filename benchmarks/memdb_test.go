@@ -0,0 +1,45 @@
+package benchmarks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+func newMemdbDatastore(tb testing.TB) datastore.Datastore {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(tb, err)
+	return ds
+}
+
+// TestCorpora ensures that every corpus loads and that each benchmarked operation returns
+// its expected result, so that the benchmarks keep measuring what they claim to.
+func TestCorpora(t *testing.T) {
+	for _, c := range allCorpora {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			lc := c.load(t, newMemdbDatastore(t))
+			ctx := context.Background()
+
+			for _, cc := range c.checks {
+				result, err := lc.check(ctx, cc)
+				require.NoError(t, err, cc.name)
+				require.Equal(t, cc.expected, result, cc.name)
+			}
+
+			for _, lookup := range c.lookups {
+				count, err := lc.lookup(ctx, lookup)
+				require.NoError(t, err, lookup.name)
+				require.Equal(t, lookup.expectedCount, count, lookup.name)
+			}
+		})
+	}
+}
+
+func BenchmarkMemdb(b *testing.B) {
+	runBenchmarks(b, newMemdbDatastore)
+}
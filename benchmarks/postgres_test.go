@@ -0,0 +1,25 @@
+//go:build docker && !skipintegrationtests
+// +build docker,!skipintegrationtests
+
+package benchmarks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/postgres"
+	testdatastore "github.com/authzed/spicedb/internal/testserver/datastore"
+	"github.com/authzed/spicedb/internal/testserver/datastore/config"
+	dsconfig "github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+func BenchmarkPostgres(b *testing.B) {
+	engine := testdatastore.RunDatastoreEngine(b, postgres.Engine)
+	runBenchmarks(b, func(tb testing.TB) datastore.Datastore {
+		return engine.NewDatastore(tb, config.DatastoreConfigInitFunc(tb,
+			dsconfig.WithWatchBufferLength(0),
+			dsconfig.WithGCWindow(time.Duration(90_000_000_000_000)),
+			dsconfig.WithRevisionQuantization(10)))
+	})
+}
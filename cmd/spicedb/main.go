@@ -15,6 +15,7 @@ import (
 	log "github.com/authzed/spicedb/internal/logging"
 	consistentbalancer "github.com/authzed/spicedb/pkg/balancer"
 	"github.com/authzed/spicedb/pkg/cmd"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
 	cmdutil "github.com/authzed/spicedb/pkg/cmd/server"
 	"github.com/authzed/spicedb/pkg/cmd/testserver"
 )
@@ -33,11 +34,13 @@ func main() {
 	// Enable Kubernetes gRPC resolver
 	kuberesolver.RegisterInCluster()
 
-	// Enable consistent hashring gRPC load balancer
-	balancer.Register(consistentbalancer.NewConsistentHashringBuilder(
+	// Enable consistent hashring gRPC load balancer, routing around repeatedly failing
+	// dispatch backends
+	balancer.Register(consistentbalancer.NewConsistentHashringBuilderWithCircuitBreaker(
 		xxhash.Sum64,
 		hashringReplicationFactor,
 		backendsPerKey,
+		consistentbalancer.DefaultCircuitBreakerConfig,
 	))
 
 	// Create a root command
@@ -63,6 +66,87 @@ func main() {
 	cmd.RegisterHeadFlags(headCmd)
 	rootCmd.AddCommand(headCmd)
 
+	// Add datastore maintenance commands
+	datastoreCmd := cmd.NewDatastoreCommand(rootCmd.Use)
+	rootCmd.AddCommand(datastoreCmd)
+
+	var backupConfig datastore.Config
+	backupCmd := cmd.NewBackupCommand(rootCmd.Use, &backupConfig)
+	cmd.RegisterBackupFlags(backupCmd, &backupConfig)
+	datastoreCmd.AddCommand(backupCmd)
+
+	var backupChangesConfig datastore.Config
+	backupChangesCmd := cmd.NewBackupChangesCommand(rootCmd.Use, &backupChangesConfig)
+	cmd.RegisterBackupChangesFlags(backupChangesCmd, &backupChangesConfig)
+	datastoreCmd.AddCommand(backupChangesCmd)
+
+	var restoreConfig datastore.Config
+	restoreCmd := cmd.NewRestoreCommand(rootCmd.Use, &restoreConfig)
+	cmd.RegisterRestoreFlags(restoreCmd, &restoreConfig)
+	datastoreCmd.AddCommand(restoreCmd)
+
+	datastoreCmd.AddCommand(cmd.NewBackupCheckpointsCommand(rootCmd.Use))
+
+	var copySourceConfig, copyTargetConfig datastore.Config
+	copyCmd := cmd.NewCopyCommand(rootCmd.Use, &copySourceConfig, &copyTargetConfig)
+	cmd.RegisterCopyFlags(copyCmd, &copySourceConfig, &copyTargetConfig)
+	datastoreCmd.AddCommand(copyCmd)
+
+	var repairConfig datastore.Config
+	repairCmd := cmd.NewRepairCommand(rootCmd.Use, &repairConfig)
+	cmd.RegisterRepairFlags(repairCmd, &repairConfig)
+	datastoreCmd.AddCommand(repairCmd)
+
+	var renameTypeConfig datastore.Config
+	renameTypeCmd := cmd.NewRenameTypeCommand(rootCmd.Use, &renameTypeConfig)
+	cmd.RegisterRenameTypeFlags(renameTypeCmd, &renameTypeConfig)
+	datastoreCmd.AddCommand(renameTypeCmd)
+
+	var compactConfig datastore.Config
+	compactCmd := cmd.NewCompactCommand(rootCmd.Use, &compactConfig)
+	cmd.RegisterCompactFlags(compactCmd, &compactConfig)
+	datastoreCmd.AddCommand(compactCmd)
+
+	var pseudonymizeConfig datastore.Config
+	pseudonymizeCmd := cmd.NewPseudonymizeCommand(rootCmd.Use, &pseudonymizeConfig)
+	cmd.RegisterPseudonymizeFlags(pseudonymizeCmd, &pseudonymizeConfig)
+	datastoreCmd.AddCommand(pseudonymizeCmd)
+
+	var generateConfig datastore.Config
+	generateCmd := cmd.NewGenerateCommand(rootCmd.Use, &generateConfig)
+	cmd.RegisterGenerateFlags(generateCmd, &generateConfig)
+	datastoreCmd.AddCommand(generateCmd)
+
+	// Add schema commands
+	schemaCmd := cmd.NewSchemaCommand(rootCmd.Use)
+	rootCmd.AddCommand(schemaCmd)
+
+	schemaCheckCmd := cmd.NewSchemaCheckCommand(rootCmd.Use)
+	cmd.RegisterSchemaCheckFlags(schemaCheckCmd)
+	schemaCmd.AddCommand(schemaCheckCmd)
+
+	schemaControllerCmd := cmd.NewSchemaControllerCommand(rootCmd.Use)
+	cmd.RegisterSchemaControllerFlags(schemaControllerCmd)
+	schemaCmd.AddCommand(schemaControllerCmd)
+
+	// Add import and export commands
+	importCmd := cmd.NewImportCommand(rootCmd.Use)
+	cmd.RegisterImportFlags(importCmd)
+	rootCmd.AddCommand(importCmd)
+
+	exportCmd := cmd.NewExportCommand(rootCmd.Use)
+	cmd.RegisterExportFlags(exportCmd)
+	rootCmd.AddCommand(exportCmd)
+
+	accessReportCmd := cmd.NewAccessReportCommand(rootCmd.Use)
+	cmd.RegisterAccessReportFlags(accessReportCmd)
+	rootCmd.AddCommand(accessReportCmd)
+
+	// Add load-testing commands
+	benchCmd := cmd.NewBenchCommand(rootCmd.Use)
+	cmd.RegisterBenchFlags(benchCmd)
+	rootCmd.AddCommand(benchCmd)
+
 	// Add server commands
 	var serverConfig cmdutil.Config
 	serveCmd := cmd.NewServeCommand(rootCmd.Use, &serverConfig)
@@ -76,7 +76,7 @@ func TestTestServer(t *testing.T) {
 			tuple.UpdateToRelationshipUpdate(tuple.Create(relationship)),
 		},
 	})
-	require.Equal("rpc error: code = Unavailable desc = service read-only", err.Error())
+	require.Equal("rpc error: code = FailedPrecondition desc = service read-only", err.Error())
 
 	// Write a simple relationship.
 	_, err = v1client.WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
@@ -142,7 +142,7 @@ func TestTestServer(t *testing.T) {
 		"schemaText": "definition user {}\ndefinition resource {\nrelation reader: user\nrelation writer: user\nrelation foobar: user\n}"
 	}`))
 	require.NoError(err)
-	require.Equal(503, wresp.StatusCode)
+	require.Equal(400, wresp.StatusCode)
 
 	body, err = ioutil.ReadAll(wresp.Body)
 	require.NoError(err)
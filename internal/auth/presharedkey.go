@@ -3,9 +3,11 @@ package auth
 import (
 	"context"
 	"crypto/subtle"
+	"fmt"
 
 	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/status"
 )
 
@@ -16,6 +18,21 @@ const (
 
 var errInvalidToken = "invalid token"
 
+type ctxKeyType struct{}
+
+var presharedKeyNameKey ctxKeyType = struct{}{}
+
+// PresharedKeyNameFromContext returns the name of the preshared key used to
+// authenticate the request, or an empty string if none is found. Keys are named
+// by their position in the configured list, e.g. `preshared-key-1`, so that the
+// key itself is never exposed.
+func PresharedKeyNameFromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(presharedKeyNameKey).(string); ok {
+		return name
+	}
+	return ""
+}
+
 // RequirePresharedKey requires that gRPC requests have a Bearer Token value
 // equivalent to one of the provided preshared key(s).
 func RequirePresharedKey(presharedKeys []string) grpcauth.AuthFunc {
@@ -29,6 +46,17 @@ func RequirePresharedKey(presharedKeys []string) grpcauth.AuthFunc {
 		}
 	}
 
+	return requirePresharedKey(func() []string { return presharedKeys })
+}
+
+// RequireDynamicPresharedKey requires that gRPC requests have a Bearer Token value
+// equivalent to one of the preshared key(s) returned by presharedKeys at the time of the
+// request, which allows the keys to be rotated while the server is running.
+func RequireDynamicPresharedKey(presharedKeys func() []string) grpcauth.AuthFunc {
+	return requirePresharedKey(presharedKeys)
+}
+
+func requirePresharedKey(presharedKeys func() []string) grpcauth.AuthFunc {
 	return func(ctx context.Context) (context.Context, error) {
 		token, err := grpcauth.AuthFromMD(ctx, "bearer")
 		if err != nil {
@@ -39,12 +67,37 @@ func RequirePresharedKey(presharedKeys []string) grpcauth.AuthFunc {
 			return nil, status.Errorf(codes.Unauthenticated, errMissingPresharedKey)
 		}
 
-		for _, presharedKey := range presharedKeys {
+		for index, presharedKey := range presharedKeys() {
 			if match := subtle.ConstantTimeCompare([]byte(presharedKey), []byte(token)); match == 1 {
-				return ctx, nil
+				return context.WithValue(ctx, presharedKeyNameKey, fmt.Sprintf("preshared-key-%d", index+1)), nil
 			}
 		}
 
 		return nil, status.Errorf(codes.PermissionDenied, errInvalidPresharedKey, errInvalidToken)
 	}
 }
+
+// PresharedKeyCredentials returns per-RPC credentials which authenticate requests with the
+// first of the preshared key(s) returned by presharedKeys at the time of each request as a
+// Bearer Token, so that clients follow the keys as they are rotated. Requests are sent
+// without a token while there are no keys.
+func PresharedKeyCredentials(presharedKeys func() []string, requireTransportSecurity bool) credentials.PerRPCCredentials {
+	return presharedKeyCredentials{presharedKeys: presharedKeys, requireTransportSecurity: requireTransportSecurity}
+}
+
+type presharedKeyCredentials struct {
+	presharedKeys            func() []string
+	requireTransportSecurity bool
+}
+
+func (c presharedKeyCredentials) GetRequestMetadata(_ context.Context, _ ...string) (map[string]string, error) {
+	keys := c.presharedKeys()
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return map[string]string{"authorization": "Bearer " + keys[0]}, nil
+}
+
+func (c presharedKeyCredentials) RequireTransportSecurity() bool {
+	return c.requireTransportSecurity
+}
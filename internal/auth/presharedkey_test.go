@@ -18,13 +18,14 @@ func TestPresharedKeys(t *testing.T) {
 		withMetadata   bool
 		authzHeader    string
 		expectedStatus codes.Code
+		expectedName   string
 	}{
-		{"valid request with the first key", []string{"one", "two"}, true, "bearer one", codes.OK},
-		{"valid request with the second key", []string{"one", "two"}, true, "bearer two", codes.OK},
-		{"denied due to unknown key", []string{"one", "two"}, true, "bearer three", codes.PermissionDenied},
-		{"unauthenticated due to missing key", []string{"one", "two"}, true, "bearer ", codes.Unauthenticated},
-		{"unauthenticated due to empty header", []string{"one", "two"}, true, "", codes.Unauthenticated},
-		{"unauthenticated due to missing metadata", []string{"one", "two"}, false, "", codes.Unauthenticated},
+		{"valid request with the first key", []string{"one", "two"}, true, "bearer one", codes.OK, "preshared-key-1"},
+		{"valid request with the second key", []string{"one", "two"}, true, "bearer two", codes.OK, "preshared-key-2"},
+		{"denied due to unknown key", []string{"one", "two"}, true, "bearer three", codes.PermissionDenied, ""},
+		{"unauthenticated due to missing key", []string{"one", "two"}, true, "bearer ", codes.Unauthenticated, ""},
+		{"unauthenticated due to empty header", []string{"one", "two"}, true, "", codes.Unauthenticated, ""},
+		{"unauthenticated due to missing metadata", []string{"one", "two"}, false, "", codes.Unauthenticated, ""},
 	}
 
 	for _, testcase := range testcases {
@@ -34,12 +35,13 @@ func TestPresharedKeys(t *testing.T) {
 			if testcase.withMetadata {
 				ctx = withTokenMetadata(testcase.authzHeader)
 			}
-			_, err := f(ctx)
+			authedCtx, err := f(ctx)
 			if testcase.expectedStatus != codes.OK {
 				require.Error(t, err)
 				grpcutil.RequireStatus(t, testcase.expectedStatus, err)
 			} else {
 				require.NoError(t, err)
+				require.Equal(t, testcase.expectedName, PresharedKeyNameFromContext(authedCtx))
 			}
 		})
 	}
@@ -49,3 +51,23 @@ func withTokenMetadata(authzHeader string) context.Context {
 	md := metadata.Pairs("authorization", authzHeader)
 	return metautils.MD(md).ToIncoming(context.Background())
 }
+
+func TestPresharedKeyCredentialsFollowRotation(t *testing.T) {
+	keys := []string{"one", "two"}
+	creds := PresharedKeyCredentials(func() []string { return keys }, true)
+	require.True(t, creds.RequireTransportSecurity())
+
+	md, err := creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"authorization": "Bearer one"}, md)
+
+	keys = []string{"three"}
+	md, err = creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"authorization": "Bearer three"}, md)
+
+	keys = nil
+	md, err = creds.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, md)
+}
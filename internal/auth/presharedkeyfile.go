@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/exp/slices"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+// PresharedKeyFile holds the preshared keys read from a file, one key per line, and
+// reloads them whenever the file changes. Blank lines and lines starting with `#` are
+// ignored.
+type PresharedKeyFile struct {
+	path string
+
+	sync.RWMutex
+	keys []string
+}
+
+// NewPresharedKeyFile reads the preshared keys from the file at path. The file is not
+// watched for changes until Start is called.
+func NewPresharedKeyFile(path string) (*PresharedKeyFile, error) {
+	keys, err := readPresharedKeys(path)
+	if err != nil {
+		return nil, err
+	}
+	return &PresharedKeyFile{path: path, keys: keys}, nil
+}
+
+// Keys returns the most recently loaded preshared keys.
+func (pkf *PresharedKeyFile) Keys() []string {
+	pkf.RLock()
+	defer pkf.RUnlock()
+	return pkf.keys
+}
+
+// Start watches the file for changes until the context is canceled. The directory
+// containing the file is watched, rather than the file itself, so that files which are
+// replaced by renaming or by swapping a symlink, as Kubernetes does for mounted secrets,
+// are reloaded as well. If a changed file cannot be read or contains no keys, the
+// previous keys remain in use.
+func (pkf *PresharedKeyFile) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("unable to watch preshared key file: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(pkf.path)); err != nil {
+		return fmt.Errorf("unable to watch preshared key file: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			pkf.reload()
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Ctx(ctx).Warn().Err(err).Str("path", pkf.path).Msg("error watching preshared key file")
+		}
+	}
+}
+
+func (pkf *PresharedKeyFile) reload() {
+	keys, err := readPresharedKeys(pkf.path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", pkf.path).Msg("unable to reload preshared keys; continuing with the previous keys")
+		return
+	}
+
+	pkf.Lock()
+	defer pkf.Unlock()
+	if slices.Equal(pkf.keys, keys) {
+		return
+	}
+	pkf.keys = keys
+	log.Info().Str("path", pkf.path).Int("preshared-keys-count", len(keys)).Msg("reloaded preshared keys")
+}
+
+func readPresharedKeys(path string) ([]string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read preshared key file: %w", err)
+	}
+
+	var keys []string
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read preshared key file: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("preshared key file contains no keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestPresharedKeyFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, []byte("# rotated weekly\none\n\n  two  \n"), 0o600))
+
+	keyFile, err := NewPresharedKeyFile(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"one", "two"}, keyFile.Keys())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- keyFile.Start(ctx) }()
+
+	authFunc := RequireDynamicPresharedKey(keyFile.Keys)
+	_, err = authFunc(withTokenMetadata("bearer one"))
+	require.NoError(t, err)
+
+	// Replace the file by renaming, as Kubernetes and most secret managers do.
+	require.Eventually(t, func() bool {
+		tmp := path + ".tmp"
+		require.NoError(t, os.WriteFile(tmp, []byte("three\n"), 0o600))
+		require.NoError(t, os.Rename(tmp, path))
+		return len(keyFile.Keys()) == 1 && keyFile.Keys()[0] == "three"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	_, err = authFunc(withTokenMetadata("bearer one"))
+	grpcutil.RequireStatus(t, codes.PermissionDenied, err)
+	_, err = authFunc(withTokenMetadata("bearer three"))
+	require.NoError(t, err)
+
+	// A file without keys is ignored, rather than locking out every client.
+	require.NoError(t, os.WriteFile(path, []byte("# empty\n"), 0o600))
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, []string{"three"}, keyFile.Keys())
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestPresharedKeyFileErrors(t *testing.T) {
+	_, err := NewPresharedKeyFile(filepath.Join(t.TempDir(), "missing"))
+	require.ErrorContains(t, err, "unable to read preshared key file")
+
+	path := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(path, []byte("\n# nothing here\n"), 0o600))
+	_, err = NewPresharedKeyFile(path)
+	require.ErrorContains(t, err, "contains no keys")
+}
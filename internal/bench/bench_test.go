@@ -0,0 +1,123 @@
+package bench
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/authzed-go/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+)
+
+func TestGenerate(t *testing.T) {
+	shape := Shape{Depth: 2, FanOut: 3, Users: 10, ViewersPerObject: 2, CaveatRatio: 0.5}
+	graph, err := Generate(shape, 42)
+	require.NoError(t, err)
+
+	// 3 root folders, 9 child folders and 27 documents.
+	require.Len(t, graph.Documents, 27)
+	require.Len(t, graph.Users, 10)
+	require.Equal(t, "d0_0_0", graph.Documents[0])
+
+	// Every folder but the roots and every document has a parent, and every folder and
+	// document has two viewers.
+	require.Len(t, graph.Relationships, (9+27)+(12+27)*2)
+
+	caveated := 0
+	for _, rel := range graph.Relationships {
+		if rel.OptionalCaveat != nil {
+			caveated++
+		}
+	}
+	require.True(t, graph.Caveated)
+	require.Greater(t, caveated, 0)
+	require.Less(t, caveated, (12+27)*2)
+
+	again, err := Generate(shape, 42)
+	require.NoError(t, err)
+	require.Equal(t, graph, again)
+
+	uncaveated, err := Generate(Shape{Depth: 1, FanOut: 1, Users: 1, ViewersPerObject: 1}, 42)
+	require.NoError(t, err)
+	require.False(t, uncaveated.Caveated)
+	require.NotContains(t, uncaveated.Schema, "caveat")
+}
+
+func TestShapeValidate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		shape Shape
+		err   string
+	}{
+		{"valid", Shape{Depth: 1, FanOut: 1, Users: 1, ViewersPerObject: 1}, ""},
+		{"no depth", Shape{FanOut: 1, Users: 1}, "depth must be at least 1"},
+		{"no fan-out", Shape{Depth: 1, Users: 1}, "fan-out must be at least 1"},
+		{"too many viewers", Shape{Depth: 1, FanOut: 1, Users: 1, ViewersPerObject: 2}, "viewers per object"},
+		{"invalid caveat ratio", Shape{Depth: 1, FanOut: 1, Users: 1, CaveatRatio: 2}, "caveat ratio"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.shape.Validate()
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 0, 100)
+	for i := 100; i > 0; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	stats := summarize(OperationCheck, latencies)
+	require.Equal(t, 100, stats.Count)
+	require.Equal(t, 50*time.Millisecond, stats.P50)
+	require.Equal(t, 90*time.Millisecond, stats.P90)
+	require.Equal(t, 99*time.Millisecond, stats.P99)
+	require.Equal(t, 100*time.Millisecond, stats.Max)
+}
+
+func TestLoadAndRun(t *testing.T) {
+	require := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	client := &authzed.Client{
+		SchemaServiceClient:      v1.NewSchemaServiceClient(conn),
+		PermissionsServiceClient: v1.NewPermissionsServiceClient(conn),
+	}
+
+	graph, err := Generate(Shape{Depth: 2, FanOut: 2, Users: 5, ViewersPerObject: 1, CaveatRatio: 0.5}, 1)
+	require.NoError(err)
+
+	ctx := context.Background()
+	require.NoError(Load(ctx, client, graph, 7))
+
+	// Loading is idempotent.
+	require.NoError(Load(ctx, client, graph, 100))
+
+	report, err := Run(ctx, client, graph, Workload{
+		Duration:        200 * time.Millisecond,
+		Concurrency:     2,
+		LookupRatio:     0.5,
+		FullyConsistent: true,
+	})
+	require.NoError(err)
+	require.Len(report.Stats, 2)
+	for _, stats := range report.Stats {
+		require.Greater(stats.Count, 0)
+		require.Zero(stats.Errors)
+		require.LessOrEqual(stats.P50, stats.P99)
+	}
+}
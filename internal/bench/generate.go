@@ -0,0 +1,209 @@
+// Package bench generates synthetic schemas and relationship graphs, loads them into a
+// running server and drives permission checks and lookups against it while recording
+// their latency.
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	userType     = "user"
+	folderType   = "folder"
+	documentType = "document"
+
+	parentRelation   = "parent"
+	viewerRelation   = "viewer"
+	viewPermission   = "view"
+	caveatName       = "bench_allowed"
+	caveatParameter  = "allowed"
+	schemaWithCaveat = `definition user {}
+
+caveat bench_allowed(allowed bool) {
+	allowed
+}
+
+definition folder {
+	relation parent: folder
+	relation viewer: user | user with bench_allowed
+	permission view = viewer + parent->view
+}
+
+definition document {
+	relation parent: folder
+	relation viewer: user | user with bench_allowed
+	permission view = viewer + parent->view
+}
+`
+	schemaWithoutCaveat = `definition user {}
+
+definition folder {
+	relation parent: folder
+	relation viewer: user
+	permission view = viewer + parent->view
+}
+
+definition document {
+	relation parent: folder
+	relation viewer: user
+	permission view = viewer + parent->view
+}
+`
+)
+
+// Shape describes the synthetic relationship graph: a forest of nested folders, with
+// documents in the innermost folders and users granted view on folders and documents.
+type Shape struct {
+	// Depth is the number of levels of folders above each document.
+	Depth int
+
+	// FanOut is the number of root folders, of child folders within each folder, and of
+	// documents within each innermost folder.
+	FanOut int
+
+	// Users is the size of the pool of users from which viewers are drawn.
+	Users int
+
+	// ViewersPerObject is the number of users directly granted view on each folder and
+	// document.
+	ViewersPerObject int
+
+	// CaveatRatio is the fraction, from 0 to 1, of viewer relationships which are
+	// caveated. Caveated relationships require a server with caveats enabled.
+	CaveatRatio float64
+}
+
+// Validate returns an error if the shape cannot be generated.
+func (s Shape) Validate() error {
+	switch {
+	case s.Depth < 1:
+		return errors.New("depth must be at least 1")
+	case s.FanOut < 1:
+		return errors.New("fan-out must be at least 1")
+	case s.Users < 1:
+		return errors.New("users must be at least 1")
+	case s.ViewersPerObject < 0 || s.ViewersPerObject > s.Users:
+		return fmt.Errorf("viewers per object must be between 0 and the number of users (%d)", s.Users)
+	case s.CaveatRatio < 0 || s.CaveatRatio > 1:
+		return errors.New("caveat ratio must be between 0 and 1")
+	default:
+		return nil
+	}
+}
+
+// Graph is a generated schema along with the relationships to write for it.
+type Graph struct {
+	Schema        string
+	Relationships []*v1.Relationship
+
+	// Documents and Users hold the IDs of every generated document and user, from which
+	// the benchmark traffic is drawn.
+	Documents []string
+	Users     []string
+
+	// Caveated is true if any relationship is caveated, in which case requests must
+	// provide the caveat context returned by CaveatContext.
+	Caveated bool
+}
+
+// Generate generates the graph for the shape. The same shape and seed always generate the
+// same graph, so that a graph loaded once can be benchmarked repeatedly.
+func Generate(shape Shape, seed int64) (Graph, error) {
+	if err := shape.Validate(); err != nil {
+		return Graph{}, err
+	}
+
+	g := &generator{
+		shape: shape,
+		rng:   rand.New(rand.NewSource(seed)),
+		graph: Graph{
+			Schema:   schemaWithoutCaveat,
+			Users:    make([]string, 0, shape.Users),
+			Caveated: shape.CaveatRatio > 0,
+		},
+	}
+	if g.graph.Caveated {
+		g.graph.Schema = schemaWithCaveat
+	}
+
+	for i := 0; i < shape.Users; i++ {
+		g.graph.Users = append(g.graph.Users, fmt.Sprintf("u%d", i))
+	}
+
+	for i := 0; i < shape.FanOut; i++ {
+		g.folder(fmt.Sprintf("f%d", i), "", 1)
+	}
+	return g.graph, nil
+}
+
+// CaveatContext returns the context under which caveated relationships in a generated
+// graph are satisfied.
+func CaveatContext() *structpb.Struct {
+	return &structpb.Struct{Fields: map[string]*structpb.Value{
+		caveatParameter: structpb.NewBoolValue(true),
+	}}
+}
+
+type generator struct {
+	shape Shape
+	rng   *rand.Rand
+	graph Graph
+}
+
+func (g *generator) folder(id, parentID string, level int) {
+	if parentID != "" {
+		g.parent(folderType, id, parentID)
+	}
+	g.viewers(folderType, id)
+
+	for i := 0; i < g.shape.FanOut; i++ {
+		if level < g.shape.Depth {
+			g.folder(fmt.Sprintf("%s_%d", id, i), id, level+1)
+			continue
+		}
+
+		documentID := "d" + strings.TrimPrefix(fmt.Sprintf("%s_%d", id, i), "f")
+		g.graph.Documents = append(g.graph.Documents, documentID)
+		g.parent(documentType, documentID, id)
+		g.viewers(documentType, documentID)
+	}
+}
+
+func (g *generator) parent(objectType, id, parentID string) {
+	g.graph.Relationships = append(g.graph.Relationships, &v1.Relationship{
+		Resource: &v1.ObjectReference{ObjectType: objectType, ObjectId: id},
+		Relation: parentRelation,
+		Subject: &v1.SubjectReference{
+			Object: &v1.ObjectReference{ObjectType: folderType, ObjectId: parentID},
+		},
+	})
+}
+
+func (g *generator) viewers(objectType, id string) {
+	chosen := make(map[int]struct{}, g.shape.ViewersPerObject)
+	for len(chosen) < g.shape.ViewersPerObject {
+		user := g.rng.Intn(g.shape.Users)
+		if _, ok := chosen[user]; ok {
+			continue
+		}
+		chosen[user] = struct{}{}
+
+		rel := &v1.Relationship{
+			Resource: &v1.ObjectReference{ObjectType: objectType, ObjectId: id},
+			Relation: viewerRelation,
+			Subject: &v1.SubjectReference{
+				Object: &v1.ObjectReference{ObjectType: userType, ObjectId: g.graph.Users[user]},
+			},
+		}
+		if g.rng.Float64() < g.shape.CaveatRatio {
+			rel.OptionalCaveat = &v1.ContextualizedCaveat{CaveatName: caveatName}
+		}
+		g.graph.Relationships = append(g.graph.Relationships, rel)
+	}
+}
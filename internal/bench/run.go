@@ -0,0 +1,287 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+)
+
+// Client is the subset of the API used by the benchmark, which is implemented by the
+// authzed-go client.
+type Client interface {
+	v1.SchemaServiceClient
+	v1.PermissionsServiceClient
+}
+
+// Load writes the schema of the graph followed by its relationships, batchSize at a time.
+// Relationships are touched rather than created, so a graph can be loaded repeatedly.
+func Load(ctx context.Context, client Client, graph Graph, batchSize int) error {
+	if batchSize < 1 {
+		return errors.New("batch size must be at least 1")
+	}
+
+	if _, err := client.WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: graph.Schema}); err != nil {
+		return fmt.Errorf("unable to write schema: %w", err)
+	}
+
+	for start := 0; start < len(graph.Relationships); start += batchSize {
+		end := start + batchSize
+		if end > len(graph.Relationships) {
+			end = len(graph.Relationships)
+		}
+
+		updates := make([]*v1.RelationshipUpdate, 0, end-start)
+		for _, rel := range graph.Relationships[start:end] {
+			updates = append(updates, &v1.RelationshipUpdate{
+				Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+				Relationship: rel,
+			})
+		}
+
+		if _, err := client.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{Updates: updates}); err != nil {
+			return fmt.Errorf("unable to write relationships: %w", err)
+		}
+	}
+	return nil
+}
+
+// Operation is a kind of request issued by the benchmark.
+type Operation string
+
+const (
+	// OperationCheck checks whether a random user can view a random document.
+	OperationCheck Operation = "check"
+
+	// OperationLookup looks up every document a random user can view.
+	OperationLookup Operation = "lookup"
+)
+
+// Workload describes the traffic driven against the server.
+type Workload struct {
+	// Duration is how long requests are issued for.
+	Duration time.Duration
+
+	// Concurrency is the number of requests in flight at once.
+	Concurrency int
+
+	// LookupRatio is the fraction, from 0 to 1, of requests which are lookups rather than
+	// checks.
+	LookupRatio float64
+
+	// FullyConsistent issues every request at the head revision, rather than allowing the
+	// server to pick a cached revision.
+	FullyConsistent bool
+
+	// Seed seeds the choice of requests.
+	Seed int64
+}
+
+// Stats are the latencies observed for one kind of request.
+type Stats struct {
+	Operation Operation `json:"operation"`
+	Count     int       `json:"count"`
+	Errors    int       `json:"errors"`
+
+	// PerSecond is the rate of successful requests.
+	PerSecond float64 `json:"per_second"`
+
+	P50 time.Duration `json:"p50"`
+	P90 time.Duration `json:"p90"`
+	P99 time.Duration `json:"p99"`
+	Max time.Duration `json:"max"`
+}
+
+// Report is the result of running a workload.
+type Report struct {
+	Elapsed time.Duration `json:"elapsed"`
+	Stats   []Stats       `json:"stats"`
+}
+
+// Run issues requests for the graph, which must already have been loaded, until the
+// duration of the workload elapses or the context is canceled. Failed requests are
+// counted but do not stop the run.
+func Run(ctx context.Context, client Client, graph Graph, workload Workload) (Report, error) {
+	switch {
+	case workload.Duration <= 0:
+		return Report{}, errors.New("duration must be positive")
+	case workload.Concurrency < 1:
+		return Report{}, errors.New("concurrency must be at least 1")
+	case workload.LookupRatio < 0 || workload.LookupRatio > 1:
+		return Report{}, errors.New("lookup ratio must be between 0 and 1")
+	case len(graph.Documents) == 0 || len(graph.Users) == 0:
+		return Report{}, errors.New("graph has no documents or users")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, workload.Duration)
+	defer cancel()
+
+	consistency := &v1.Consistency{Requirement: &v1.Consistency_MinimizeLatency{MinimizeLatency: true}}
+	if workload.FullyConsistent {
+		consistency = &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}
+	}
+
+	workers := make([]*worker, 0, workload.Concurrency)
+	for i := 0; i < workload.Concurrency; i++ {
+		workers = append(workers, &worker{
+			client:      client,
+			graph:       graph,
+			consistency: consistency,
+			lookupRatio: workload.LookupRatio,
+			rng:         rand.New(rand.NewSource(workload.Seed + int64(i))),
+			latencies:   make(map[Operation][]time.Duration),
+			errors:      make(map[Operation]int),
+		})
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.run(ctx)
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report := Report{Elapsed: elapsed}
+	for _, op := range []Operation{OperationCheck, OperationLookup} {
+		var latencies []time.Duration
+		errCount := 0
+		for _, w := range workers {
+			latencies = append(latencies, w.latencies[op]...)
+			errCount += w.errors[op]
+		}
+		if len(latencies) == 0 && errCount == 0 {
+			continue
+		}
+
+		stats := summarize(op, latencies)
+		stats.Errors = errCount
+		stats.PerSecond = float64(stats.Count) / elapsed.Seconds()
+		report.Stats = append(report.Stats, stats)
+	}
+	return report, nil
+}
+
+type worker struct {
+	client      Client
+	graph       Graph
+	consistency *v1.Consistency
+	lookupRatio float64
+	rng         *rand.Rand
+
+	latencies map[Operation][]time.Duration
+	errors    map[Operation]int
+}
+
+func (w *worker) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		op := OperationCheck
+		if w.rng.Float64() < w.lookupRatio {
+			op = OperationLookup
+		}
+
+		start := time.Now()
+		var err error
+		if op == OperationLookup {
+			err = w.lookup(ctx)
+		} else {
+			err = w.check(ctx)
+		}
+		latency := time.Since(start)
+
+		switch {
+		case err == nil:
+			w.latencies[op] = append(w.latencies[op], latency)
+		case ctx.Err() != nil:
+			// Requests cut short by the end of the run are not failures.
+			return
+		default:
+			w.errors[op]++
+		}
+	}
+}
+
+func (w *worker) subject() *v1.SubjectReference {
+	return &v1.SubjectReference{Object: &v1.ObjectReference{
+		ObjectType: userType,
+		ObjectId:   w.graph.Users[w.rng.Intn(len(w.graph.Users))],
+	}}
+}
+
+func (w *worker) check(ctx context.Context) error {
+	req := &v1.CheckPermissionRequest{
+		Consistency: w.consistency,
+		Resource: &v1.ObjectReference{
+			ObjectType: documentType,
+			ObjectId:   w.graph.Documents[w.rng.Intn(len(w.graph.Documents))],
+		},
+		Permission: viewPermission,
+		Subject:    w.subject(),
+	}
+	if w.graph.Caveated {
+		req.Context = CaveatContext()
+	}
+
+	_, err := w.client.CheckPermission(ctx, req)
+	return err
+}
+
+func (w *worker) lookup(ctx context.Context) error {
+	req := &v1.LookupResourcesRequest{
+		Consistency:        w.consistency,
+		ResourceObjectType: documentType,
+		Permission:         viewPermission,
+		Subject:            w.subject(),
+	}
+	if w.graph.Caveated {
+		req.Context = CaveatContext()
+	}
+
+	stream, err := w.client.LookupResources(ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func summarize(op Operation, latencies []time.Duration) Stats {
+	stats := Stats{Operation: op, Count: len(latencies)}
+	if len(latencies) == 0 {
+		return stats
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	stats.P50 = percentile(latencies, 0.50)
+	stats.P90 = percentile(latencies, 0.90)
+	stats.P99 = percentile(latencies, 0.99)
+	stats.Max = latencies[len(latencies)-1]
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
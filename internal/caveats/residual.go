@@ -0,0 +1,179 @@
+package caveats
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/authzed/spicedb/pkg/caveats"
+	"github.com/authzed/spicedb/pkg/caveats/types"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+// ComputeResidual partially evaluates the caveat expression over the given context, and
+// returns the residual of the caveats which could not be evaluated, or nil if the whole
+// expression was evaluated.
+func ComputeResidual(
+	ctx context.Context,
+	expr *v1.CaveatExpression,
+	context map[string]any,
+	reader datastore.CaveatReader,
+) (*caveats.Residual, error) {
+	caveatDefs, err := loadCaveats(ctx, expr, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	computed, err := computeResidual(expr, context, caveatDefs)
+	if err != nil {
+		return nil, err
+	}
+	return computed.residual, nil
+}
+
+// residualValue is either the known value of an expression, or its residual.
+type residualValue struct {
+	residual *caveats.Residual
+	value    bool
+}
+
+func computeResidual(
+	expr *v1.CaveatExpression,
+	context map[string]any,
+	caveatDefs map[string]*core.CaveatDefinition,
+) (residualValue, error) {
+	if expr.GetCaveat() != nil {
+		return computeCaveatResidual(expr.GetCaveat(), context, caveatDefs)
+	}
+
+	cop := expr.GetOperation()
+	children := make([]residualValue, 0, len(cop.Children))
+	for _, child := range cop.Children {
+		computed, err := computeResidual(child, context, caveatDefs)
+		if err != nil {
+			return residualValue{}, err
+		}
+		children = append(children, computed)
+	}
+
+	switch cop.Op {
+	case v1.CaveatOperation_AND, v1.CaveatOperation_OR:
+		// Children with a known value are dropped, unless they decide the operation.
+		shortCircuit := cop.Op == v1.CaveatOperation_OR
+		var unknown []*caveats.Residual
+		for _, child := range children {
+			if child.residual == nil {
+				if child.value == shortCircuit {
+					return residualValue{value: shortCircuit}, nil
+				}
+				continue
+			}
+			unknown = append(unknown, child.residual)
+		}
+
+		switch len(unknown) {
+		case 0:
+			return residualValue{value: !shortCircuit}, nil
+		case 1:
+			return residualValue{residual: unknown[0]}, nil
+		}
+
+		operation := caveats.ResidualAnd
+		if cop.Op == v1.CaveatOperation_OR {
+			operation = caveats.ResidualOr
+		}
+		return residualValue{residual: &caveats.Residual{Operation: operation, Children: unknown}}, nil
+
+	case v1.CaveatOperation_NOT:
+		if len(children) != 1 {
+			return residualValue{}, fmt.Errorf("caveat operation `not` requires a single child; found %d", len(children))
+		}
+		if children[0].residual == nil {
+			return residualValue{value: !children[0].value}, nil
+		}
+		return residualValue{residual: &caveats.Residual{
+			Operation: caveats.ResidualNot,
+			Children:  []*caveats.Residual{children[0].residual},
+		}}, nil
+
+	default:
+		panic("unknown op")
+	}
+}
+
+func computeCaveatResidual(
+	expr *core.ContextualizedCaveat,
+	context map[string]any,
+	caveatDefs map[string]*core.CaveatDefinition,
+) (residualValue, error) {
+	caveat, ok := caveatDefs[expr.CaveatName]
+	if !ok {
+		return residualValue{}, datastore.NewCaveatNameNotFoundErr(expr.CaveatName)
+	}
+
+	compiled, err := caveats.DeserializeCaveat(caveat.SerializedExpression)
+	if err != nil {
+		return residualValue{}, err
+	}
+
+	// As when running the expression, the written context takes precedence.
+	untypedFullContext := maps.Clone(context)
+	if untypedFullContext == nil {
+		untypedFullContext = map[string]any{}
+	}
+	maps.Copy(untypedFullContext, expr.GetContext().AsMap())
+
+	typedParameters, err := caveats.ConvertContextToParameters(
+		untypedFullContext,
+		caveat.ParameterTypes,
+		caveats.SkipUnknownParameters,
+	)
+	if err != nil {
+		return residualValue{}, fmt.Errorf("type error for parameters for caveat `%s`: %w", caveat.Name, err)
+	}
+
+	result, err := caveats.EvaluateCaveat(compiled, typedParameters)
+	if err != nil {
+		return residualValue{}, err
+	}
+	if !result.IsPartial() {
+		return residualValue{value: result.Value()}, nil
+	}
+
+	partial, err := result.PartialValue()
+	if err != nil {
+		return residualValue{}, err
+	}
+	exprString, err := partial.ExprString()
+	if err != nil {
+		// Comprehensions, such as `names.exists(n, n == name)`, are stored without the macro
+		// they were written with, and cannot be written back as CEL.
+		return residualValue{}, fmt.Errorf("unable to export the residual of caveat `%s`: %w", caveat.Name, err)
+	}
+
+	residual := &caveats.Residual{
+		Caveat:     caveat.Name,
+		Expression: exprString,
+		Parameters: make(map[string]string),
+	}
+	for _, name := range partial.ReferencedParameters(maps.Keys(caveat.ParameterTypes)).AsSlice() {
+		varType, err := types.DecodeParameterType(caveat.ParameterTypes[name])
+		if err != nil {
+			return residualValue{}, err
+		}
+		residual.Parameters[name] = varType.String()
+
+		// Values which were not inlined into the expression, such as IP addresses, are
+		// bound by the context of the residual.
+		if value, ok := untypedFullContext[name]; ok {
+			if residual.Context == nil {
+				residual.Context = make(map[string]any)
+			}
+			residual.Context[name] = value
+		}
+	}
+	return residualValue{residual: residual}, nil
+}
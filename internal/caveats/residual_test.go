@@ -0,0 +1,161 @@
+package caveats_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/authzed/spicedb/internal/caveats"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	pkgcaveats "github.com/authzed/spicedb/pkg/caveats"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+func caveatexprWithContext(t *testing.T, name string, context map[string]any) *v1.CaveatExpression {
+	structContext, err := structpb.NewStruct(context)
+	require.NoError(t, err)
+
+	return &v1.CaveatExpression{
+		OperationOrCaveat: &v1.CaveatExpression_Caveat{
+			Caveat: &core.ContextualizedCaveat{CaveatName: name, Context: structContext},
+		},
+	}
+}
+
+func TestComputeResidual(t *testing.T) {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		caveat firstCaveat(first int) {
+			first == 42
+		}
+
+		caveat secondCaveat(second string) {
+			second == 'hello'
+		}
+
+		caveat thirdCaveat(third bool) {
+			third
+		}
+
+		caveat limitCaveat(count int, limit int) {
+			count < limit
+		}
+
+		caveat ipCaveat(user_ip ipaddress, allowed_ip ipaddress) {
+			user_ip == allowed_ip
+		}
+
+		caveat listCaveat(names list<string>, name string) {
+			names.exists(n, n == name)
+		}
+		`, nil, require.New(t))
+	headRevision, err := ds.HeadRevision(context.Background())
+	require.NoError(t, err)
+	reader := ds.SnapshotReader(headRevision)
+
+	tcs := []struct {
+		name             string
+		expression       *v1.CaveatExpression
+		context          map[string]any
+		expectedResidual string
+		fullContexts     []map[string]any
+	}{
+		{
+			"fully evaluated",
+			caveatexpr("firstCaveat"),
+			map[string]any{"first": int64(42)},
+			`null`,
+			nil,
+		},
+		{
+			"single caveat",
+			caveatexpr("firstCaveat"),
+			nil,
+			`{"caveat": "firstCaveat", "expression": "first == 42", "parameters": {"first": "int"}}`,
+			[]map[string]any{{"first": int64(42)}, {"first": int64(12)}},
+		},
+		{
+			"known children are dropped",
+			caveatAnd(
+				caveatexpr("firstCaveat"),
+				caveatOr(caveatexpr("secondCaveat"), caveatexpr("thirdCaveat")),
+			),
+			map[string]any{"first": int64(42), "third": false},
+			`{"caveat": "secondCaveat", "expression": "second == \"hello\"", "parameters": {"second": "string"}}`,
+			[]map[string]any{{"first": int64(42), "second": "hello", "third": false}, {"first": int64(42), "second": "hi", "third": false}},
+		},
+		{
+			"operations are kept",
+			caveatOr(
+				caveatInvert(caveatexpr("thirdCaveat")),
+				caveatAnd(caveatexpr("firstCaveat"), caveatexpr("secondCaveat")),
+			),
+			nil,
+			`{"operation": "or", "children": [
+				{"operation": "not", "children": [{"caveat": "thirdCaveat", "expression": "third", "parameters": {"third": "bool"}}]},
+				{"operation": "and", "children": [
+					{"caveat": "firstCaveat", "expression": "first == 42", "parameters": {"first": "int"}},
+					{"caveat": "secondCaveat", "expression": "second == \"hello\"", "parameters": {"second": "string"}}
+				]}
+			]}`,
+			[]map[string]any{
+				{"first": int64(42), "second": "hello", "third": true},
+				{"first": int64(12), "second": "hello", "third": true},
+				{"first": int64(12), "second": "hello", "third": false},
+			},
+		},
+		{
+			"relationship context is inlined",
+			caveatexprWithContext(t, "limitCaveat", map[string]any{"limit": 10}),
+			nil,
+			`{"caveat": "limitCaveat", "expression": "count < 10", "parameters": {"count": "int"}}`,
+			[]map[string]any{{"count": int64(5)}, {"count": int64(15)}},
+		},
+		{
+			"values which cannot be inlined are bound",
+			caveatexprWithContext(t, "ipCaveat", map[string]any{"allowed_ip": "10.0.0.1"}),
+			nil,
+			`{"caveat": "ipCaveat", "expression": "user_ip == allowed_ip", "parameters": {"user_ip": "ipaddress", "allowed_ip": "ipaddress"}, "context": {"allowed_ip": "10.0.0.1"}}`,
+			[]map[string]any{{"user_ip": "10.0.0.1"}, {"user_ip": "10.0.0.2"}},
+		},
+	}
+
+	t.Run("comprehensions cannot be exported", func(t *testing.T) {
+		expression := caveatexprWithContext(t, "listCaveat", map[string]any{"names": []any{"anne", "bob"}})
+		_, err := caveats.ComputeResidual(context.Background(), expression, nil, reader)
+		require.ErrorContains(t, err, "unable to export the residual of caveat `listCaveat`")
+	})
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			residual, err := caveats.ComputeResidual(context.Background(), tc.expression, tc.context, reader)
+			require.NoError(t, err)
+
+			marshaled, err := json.Marshal(residual)
+			require.NoError(t, err)
+			require.JSONEq(t, tc.expectedResidual, string(marshaled))
+
+			// Evaluating the residual over the rest of the context must give the same result
+			// as running the whole expression.
+			for _, fullContext := range tc.fullContexts {
+				expected, err := caveats.RunCaveatExpression(context.Background(), tc.expression, fullContext, reader, caveats.RunCaveatExpressionNoDebugging)
+				require.NoError(t, err)
+
+				var decoded pkgcaveats.Residual
+				require.NoError(t, json.Unmarshal(marshaled, &decoded))
+				result, err := pkgcaveats.EvaluateResidual(&decoded, fullContext)
+				require.NoError(t, err)
+				require.False(t, result.IsPartial())
+				require.Equal(t, expected.Value(), result.Value, fullContext)
+			}
+		})
+	}
+}
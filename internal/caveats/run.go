@@ -7,8 +7,10 @@ import (
 
 	"golang.org/x/exp/maps"
 
+	"github.com/authzed/spicedb/internal/util"
 	"github.com/authzed/spicedb/pkg/caveats"
 	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
@@ -32,8 +34,41 @@ func RunCaveatExpression(
 	reader datastore.CaveatReader,
 	debugOption RunCaveatExpressionDebugOption,
 ) (ExpressionResult, error) {
+	caveatDefs, err := loadCaveats(ctx, expr, reader)
+	if err != nil {
+		return nil, err
+	}
+
 	env := caveats.NewEnvironment()
-	return runExpression(ctx, env, expr, context, reader, debugOption)
+	return runExpression(env, expr, context, caveatDefs, debugOption)
+}
+
+// loadCaveats loads every caveat referenced by the expression in a single read.
+func loadCaveats(ctx context.Context, expr *v1.CaveatExpression, reader datastore.CaveatReader) (map[string]*core.CaveatDefinition, error) {
+	caveatNames := util.NewSet[string]()
+	collectCaveatNames(expr, caveatNames)
+
+	loaded, err := reader.ListCaveats(ctx, caveatNames.AsSlice()...)
+	if err != nil {
+		return nil, err
+	}
+
+	caveatDefs := make(map[string]*core.CaveatDefinition, len(loaded))
+	for _, caveatDef := range loaded {
+		caveatDefs[caveatDef.Name] = caveatDef
+	}
+	return caveatDefs, nil
+}
+
+func collectCaveatNames(expr *v1.CaveatExpression, caveatNames *util.Set[string]) {
+	if expr.GetCaveat() != nil {
+		caveatNames.Add(expr.GetCaveat().CaveatName)
+		return
+	}
+
+	for _, child := range expr.GetOperation().GetChildren() {
+		collectCaveatNames(child, caveatNames)
+	}
 }
 
 // ExpressionResult is the result of a caveat expression being run.
@@ -81,17 +116,16 @@ func (sr syntheticResult) ExpressionString() (string, error) {
 }
 
 func runExpression(
-	ctx context.Context,
 	env *caveats.Environment,
 	expr *v1.CaveatExpression,
 	context map[string]any,
-	reader datastore.CaveatReader,
+	caveatDefs map[string]*core.CaveatDefinition,
 	debugOption RunCaveatExpressionDebugOption,
 ) (ExpressionResult, error) {
 	if expr.GetCaveat() != nil {
-		caveat, _, err := reader.ReadCaveatByName(ctx, expr.GetCaveat().CaveatName)
-		if err != nil {
-			return nil, err
+		caveat, ok := caveatDefs[expr.GetCaveat().CaveatName]
+		if !ok {
+			return nil, datastore.NewCaveatNameNotFoundErr(expr.GetCaveat().CaveatName)
 		}
 
 		compiled, err := caveats.DeserializeCaveat(caveat.SerializedExpression)
@@ -152,7 +186,7 @@ func runExpression(
 	}
 
 	for _, child := range cop.Children {
-		childResult, err := runExpression(ctx, env, child, context, reader, debugOption)
+		childResult, err := runExpression(env, child, context, caveatDefs, debugOption)
 		if err != nil {
 			return nil, err
 		}
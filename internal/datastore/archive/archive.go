@@ -0,0 +1,148 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+// ObjectStore stores archived files.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// NewS3Store returns a store writing objects to the bucket, with keys under the prefix.
+func NewS3Store(bucket, prefix string, config *aws.Config) (ObjectStore, error) {
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{client: s3.New(sess), bucket: bucket, prefix: prefix}, nil
+}
+
+type s3Store struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+func (ss *s3Store) Put(ctx context.Context, key string, data []byte) error {
+	_, err := ss.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(ss.bucket),
+		Key:         aws.String(path.Join(ss.prefix, key)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType(key)),
+	})
+	return err
+}
+
+// contentType returns the media type of an object by the extension of its key.
+func contentType(key string) string {
+	switch path.Ext(key) {
+	case ".ndjson":
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+// NewDirectoryStore returns a store writing objects as files under the directory.
+func NewDirectoryStore(dir string) ObjectStore {
+	return directoryStore(dir)
+}
+
+type directoryStore string
+
+func (ds directoryStore) Put(_ context.Context, key string, data []byte) error {
+	location := filepath.Join(string(ds), filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(location), 0o755); err != nil {
+		return err
+	}
+
+	// Write under a temporary name, so that no partial file is ever found at the key.
+	partial := location + ".partial"
+	if err := os.WriteFile(partial, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(partial, location)
+}
+
+// NewStoreForURI returns the store for an `s3://bucket/prefix` or `file:///path` URI. S3
+// credentials are read from the environment or shared configuration, as by the AWS CLI;
+// endpoint and region may be empty to use the defaults.
+func NewStoreForURI(uri, endpoint, region string) (ObjectStore, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive URI: %w", err)
+	}
+
+	switch parsed.Scheme {
+	case "s3":
+		if parsed.Host == "" {
+			return nil, fmt.Errorf("archive URI `%s` is missing a bucket", uri)
+		}
+
+		config := &aws.Config{}
+		if endpoint != "" {
+			config.Endpoint = aws.String(endpoint)
+			config.S3ForcePathStyle = aws.Bool(true)
+		}
+		if region != "" {
+			config.Region = aws.String(region)
+		}
+		return NewS3Store(parsed.Host, strings.TrimPrefix(parsed.Path, "/"), config)
+
+	case "file":
+		if parsed.Path == "" {
+			return nil, fmt.Errorf("archive URI `%s` is missing a path", uri)
+		}
+		return NewDirectoryStore(parsed.Path), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported archive URI scheme `%s`; expected s3 or file", parsed.Scheme)
+	}
+}
+
+// NewParquetArchiver returns an archiver writing each batch of relationship versions to
+// the store as a Parquet file. Files are keyed by the date and time at which they are
+// written, as `relationships/<yyyy>/<mm>/<dd>/<unix nanoseconds>-<sequence>.parquet`.
+func NewParquetArchiver(store ObjectStore) common.RelationshipArchiver {
+	return &parquetArchiver{store: store, now: time.Now}
+}
+
+type parquetArchiver struct {
+	store    ObjectStore
+	sequence atomic.Uint64
+	now      func() time.Time
+}
+
+func (pa *parquetArchiver) ArchiveRelationships(ctx context.Context, versions []common.RelationshipVersion) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, versions); err != nil {
+		return fmt.Errorf("unable to encode archived relationships: %w", err)
+	}
+
+	now := pa.now().UTC()
+	key := fmt.Sprintf("relationships/%s/%d-%06d.parquet", now.Format("2006/01/02"), now.UnixNano(), pa.sequence.Add(1))
+	if err := pa.store.Put(ctx, key, buf.Bytes()); err != nil {
+		return fmt.Errorf("unable to store archived relationships: %w", err)
+	}
+	return nil
+}
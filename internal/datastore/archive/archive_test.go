@@ -0,0 +1,102 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func testVersions() []common.RelationshipVersion {
+	return []common.RelationshipVersion{{
+		Relationship:    tuple.MustParse("document:plan#viewer@user:tom"),
+		CreatedRevision: revision.NewFromDecimal(decimal.NewFromInt(1)),
+		DeletedRevision: revision.NewFromDecimal(decimal.NewFromInt(2)),
+	}}
+}
+
+func TestDirectoryArchiver(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStoreForURI("file://"+dir, "", "")
+	require.NoError(t, err)
+
+	archiver := NewParquetArchiver(store).(*parquetArchiver)
+	archiver.now = func() time.Time { return time.Unix(1_664_625_600, 0) }
+
+	ctx := context.Background()
+	require.NoError(t, archiver.ArchiveRelationships(ctx, testVersions()))
+	require.NoError(t, archiver.ArchiveRelationships(ctx, testVersions()))
+	require.NoError(t, archiver.ArchiveRelationships(ctx, nil))
+
+	files, err := filepath.Glob(filepath.Join(dir, "relationships", "2022", "10", "01", "*"))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "relationships", "2022", "10", "01", "1664625600000000000-000001.parquet"),
+		filepath.Join(dir, "relationships", "2022", "10", "01", "1664625600000000000-000002.parquet"),
+	}, files)
+
+	contents, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	rows := readParquet(t, contents)
+	require.Len(t, rows, 1)
+	require.Equal(t, "tom", rows[0]["subject_id"])
+}
+
+func TestS3Store(t *testing.T) {
+	ts := httptest.NewServer(gofakes3.New(s3mem.New()).Server())
+	defer ts.Close()
+
+	config := &aws.Config{
+		Credentials:      credentials.NewStaticCredentials("YOUR-ACCESSKEYID", "YOUR-SECRETACCESSKEY", ""),
+		Endpoint:         aws.String(ts.URL),
+		Region:           aws.String("eu-central-1"),
+		DisableSSL:       aws.Bool(true),
+		S3ForcePathStyle: aws.Bool(true),
+	}
+	store, err := NewS3Store("archive", "spicedb", config)
+	require.NoError(t, err)
+
+	client := store.(*s3Store).client
+	_, err = client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String("archive")})
+	require.NoError(t, err)
+
+	require.NoError(t, NewParquetArchiver(store).ArchiveRelationships(context.Background(), testVersions()))
+
+	listed, err := client.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String("archive"), Prefix: aws.String("spicedb/relationships/")})
+	require.NoError(t, err)
+	require.Len(t, listed.Contents, 1)
+
+	object, err := client.GetObject(&s3.GetObjectInput{Bucket: aws.String("archive"), Key: listed.Contents[0].Key})
+	require.NoError(t, err)
+	defer object.Body.Close()
+	contents, err := io.ReadAll(object.Body)
+	require.NoError(t, err)
+	require.Len(t, readParquet(t, contents), 1)
+}
+
+func TestNewStoreForURI(t *testing.T) {
+	for _, uri := range []string{"s3:///prefix", "file://", "gs://bucket/prefix", "://"} {
+		_, err := NewStoreForURI(uri, "", "")
+		require.Error(t, err, uri)
+	}
+
+	store, err := NewStoreForURI("s3://bucket/some/prefix", "http://localhost:9000", "us-east-1")
+	require.NoError(t, err)
+	require.Equal(t, "bucket", store.(*s3Store).bucket)
+	require.Equal(t, "some/prefix", store.(*s3Store).prefix)
+}
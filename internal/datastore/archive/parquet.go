@@ -0,0 +1,290 @@
+package archive
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+const parquetMagic = "PAR1"
+
+// Parquet physical types, repetitions, converted types and encodings, as numbered by the
+// Parquet format.
+const (
+	typeInt64     int32 = 2
+	typeByteArray int32 = 6
+
+	repetitionRequired int32 = 0
+	repetitionOptional int32 = 1
+
+	convertedUTF8            int32 = 0
+	convertedTimestampMicros int32 = 10
+	convertedUint64          int32 = 14
+	convertedJSON            int32 = 19
+
+	encodingPlain int32 = 0
+	encodingRLE   int32 = 3
+
+	pageTypeData int32 = 0
+)
+
+// Columns are the columns of the archived relationship versions, in the order written.
+var Columns = []string{
+	"resource_type",
+	"resource_id",
+	"relation",
+	"subject_type",
+	"subject_id",
+	"subject_relation",
+	"caveat_name",
+	"caveat_context",
+	"created_revision",
+	"deleted_revision",
+	"created_at",
+	"deleted_at",
+}
+
+// ParquetColumn is a column of a table written as a Parquet file by WriteParquetTable.
+type ParquetColumn struct {
+	name      string
+	physical  int32
+	converted int32
+	optional  bool
+
+	// values holds a string or int64 for each row, or nil for null values of optional
+	// columns.
+	values []any
+}
+
+// StringParquetColumn returns a column of UTF-8 strings.
+func StringParquetColumn(name string, optional bool) *ParquetColumn {
+	return &ParquetColumn{name: name, physical: typeByteArray, converted: convertedUTF8, optional: optional}
+}
+
+// JSONParquetColumn returns a column of JSON documents, as strings.
+func JSONParquetColumn(name string, optional bool) *ParquetColumn {
+	return &ParquetColumn{name: name, physical: typeByteArray, converted: convertedJSON, optional: optional}
+}
+
+// TimestampParquetColumn returns a column of times, as int64 microseconds since the epoch.
+func TimestampParquetColumn(name string, optional bool) *ParquetColumn {
+	return &ParquetColumn{name: name, physical: typeInt64, converted: convertedTimestampMicros, optional: optional}
+}
+
+// Uint64ParquetColumn returns a column of unsigned integers, as int64.
+func Uint64ParquetColumn(name string, optional bool) *ParquetColumn {
+	return &ParquetColumn{name: name, physical: typeInt64, converted: convertedUint64, optional: optional}
+}
+
+// Append adds the value of the column for the next row: a string or int64 as for the
+// type of the column, or nil for null in optional columns.
+func (col *ParquetColumn) Append(value any) {
+	col.values = append(col.values, value)
+}
+
+// WriteParquet writes the relationship versions as a Parquet file, with a single row group
+// in which each column is a single uncompressed, plain encoded page. Caveat contexts are
+// JSON, and the times at which versions were created and deleted are null where unknown.
+func WriteParquet(w io.Writer, versions []common.RelationshipVersion) error {
+	columns := []*ParquetColumn{
+		StringParquetColumn(Columns[0], false),
+		StringParquetColumn(Columns[1], false),
+		StringParquetColumn(Columns[2], false),
+		StringParquetColumn(Columns[3], false),
+		StringParquetColumn(Columns[4], false),
+		StringParquetColumn(Columns[5], false),
+		StringParquetColumn(Columns[6], true),
+		JSONParquetColumn(Columns[7], true),
+		StringParquetColumn(Columns[8], false),
+		StringParquetColumn(Columns[9], false),
+		TimestampParquetColumn(Columns[10], true),
+		TimestampParquetColumn(Columns[11], true),
+	}
+
+	for _, version := range versions {
+		rel := version.Relationship
+		var caveatName, caveatContext any
+		if rel.Caveat != nil {
+			caveatName = rel.Caveat.CaveatName
+			serialized, err := json.Marshal(rel.Caveat.Context.AsMap())
+			if err != nil {
+				return fmt.Errorf("unable to serialize caveat context: %w", err)
+			}
+			caveatContext = string(serialized)
+		}
+
+		var createdAt, deletedAt any
+		if !version.CreatedAt.IsZero() {
+			createdAt = version.CreatedAt.UnixMicro()
+		}
+		if !version.DeletedAt.IsZero() {
+			deletedAt = version.DeletedAt.UnixMicro()
+		}
+
+		row := []any{
+			rel.ResourceAndRelation.Namespace,
+			rel.ResourceAndRelation.ObjectId,
+			rel.ResourceAndRelation.Relation,
+			rel.Subject.Namespace,
+			rel.Subject.ObjectId,
+			rel.Subject.Relation,
+			caveatName,
+			caveatContext,
+			version.CreatedRevision.String(),
+			version.DeletedRevision.String(),
+			createdAt,
+			deletedAt,
+		}
+		for index, value := range row {
+			columns[index].Append(value)
+		}
+	}
+
+	return WriteParquetTable(w, "relationship_version", columns, len(versions))
+}
+
+// WriteParquetTable writes the columns, each holding a value for every one of the rows,
+// as a Parquet file with a single row group in which each column is a single
+// uncompressed, plain encoded page.
+func WriteParquetTable(w io.Writer, name string, columns []*ParquetColumn, numRows int) error {
+	body := []byte(parquetMagic)
+	offsets := make([]int64, 0, len(columns))
+	sizes := make([]int64, 0, len(columns))
+	for _, col := range columns {
+		if len(col.values) != numRows {
+			return fmt.Errorf("column %s has %d values for %d rows", col.name, len(col.values), numRows)
+		}
+
+		page := col.page()
+		header := &compactWriter{}
+		header.beginStruct()
+		header.i32Field(1, pageTypeData)
+		header.i32Field(2, int32(len(page)))
+		header.i32Field(3, int32(len(page)))
+		header.structField(5)
+		header.i32Field(1, int32(numRows))
+		header.i32Field(2, encodingPlain)
+		header.i32Field(3, encodingRLE)
+		header.i32Field(4, encodingRLE)
+		header.endStruct()
+		header.endStruct()
+
+		offsets = append(offsets, int64(len(body)))
+		sizes = append(sizes, int64(len(header.buf)+len(page)))
+		body = append(body, header.buf...)
+		body = append(body, page...)
+	}
+
+	footer := fileMetadata(name, columns, int64(numRows), offsets, sizes)
+	body = append(body, footer...)
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(footer)))
+	body = append(body, parquetMagic...)
+
+	_, err := w.Write(body)
+	return err
+}
+
+// page returns the contents of a data page holding every value of the column: the
+// definition levels of optional columns, followed by the plain encoded non-null values.
+func (col *ParquetColumn) page() []byte {
+	var page []byte
+	if col.optional {
+		levels := definitionLevels(col.values)
+		page = binary.LittleEndian.AppendUint32(page, uint32(len(levels)))
+		page = append(page, levels...)
+	}
+
+	for _, value := range col.values {
+		switch typed := value.(type) {
+		case string:
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(typed)))
+			page = append(page, typed...)
+		case int64:
+			page = binary.LittleEndian.AppendUint64(page, uint64(typed))
+		}
+	}
+	return page
+}
+
+// definitionLevels encodes whether each value is present as runs of the RLE/bit-packing
+// hybrid encoding, with a bit width of one.
+func definitionLevels(values []any) []byte {
+	var levels []byte
+	for start := 0; start < len(values); {
+		present := values[start] != nil
+		end := start + 1
+		for end < len(values) && (values[end] != nil) == present {
+			end++
+		}
+
+		levels = binary.AppendUvarint(levels, uint64(end-start)<<1)
+		if present {
+			levels = append(levels, 1)
+		} else {
+			levels = append(levels, 0)
+		}
+		start = end
+	}
+	return levels
+}
+
+func fileMetadata(name string, columns []*ParquetColumn, numRows int64, offsets, sizes []int64) []byte {
+	meta := &compactWriter{}
+	meta.beginStruct()
+	meta.i32Field(1, 1)
+
+	meta.listField(2, compactStruct, len(columns)+1)
+	meta.beginStruct()
+	meta.stringField(4, name)
+	meta.i32Field(5, int32(len(columns)))
+	meta.endStruct()
+	for _, col := range columns {
+		repetition := repetitionRequired
+		if col.optional {
+			repetition = repetitionOptional
+		}
+
+		meta.beginStruct()
+		meta.i32Field(1, col.physical)
+		meta.i32Field(3, repetition)
+		meta.stringField(4, col.name)
+		meta.i32Field(6, col.converted)
+		meta.endStruct()
+	}
+
+	meta.i64Field(3, numRows)
+
+	var totalSize int64
+	meta.listField(4, compactStruct, 1)
+	meta.beginStruct()
+	meta.listField(1, compactStruct, len(columns))
+	for index, col := range columns {
+		meta.beginStruct()
+		meta.i64Field(2, offsets[index])
+		meta.structField(3)
+		meta.i32Field(1, col.physical)
+		meta.listField(2, compactI32, 2)
+		meta.i32(encodingPlain)
+		meta.i32(encodingRLE)
+		meta.listField(3, compactBinary, 1)
+		meta.binary(col.name)
+		meta.i32Field(4, 0) // uncompressed
+		meta.i64Field(5, numRows)
+		meta.i64Field(6, sizes[index])
+		meta.i64Field(7, sizes[index])
+		meta.i64Field(9, offsets[index])
+		meta.endStruct()
+		meta.endStruct()
+		totalSize += sizes[index]
+	}
+	meta.i64Field(2, totalSize)
+	meta.i64Field(3, numRows)
+	meta.endStruct()
+
+	meta.stringField(6, "spicedb")
+	meta.endStruct()
+	return meta.buf
+}
@@ -0,0 +1,211 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// compactReader decodes the Thrift compact protocol into maps of field IDs to values.
+type compactReader struct {
+	buf []byte
+	pos int
+}
+
+func (cr *compactReader) varint() uint64 {
+	value, n := binary.Uvarint(cr.buf[cr.pos:])
+	cr.pos += n
+	return value
+}
+
+func (cr *compactReader) zigzag() int64 {
+	value := cr.varint()
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+func (cr *compactReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+	var last int16
+	for {
+		header := cr.buf[cr.pos]
+		cr.pos++
+		if header == 0 {
+			return fields
+		}
+
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(cr.zigzag())
+		}
+		last = id
+		fields[id] = cr.readValue(header & 0x0f)
+	}
+}
+
+func (cr *compactReader) readValue(valueType byte) any {
+	switch valueType {
+	case compactI32, compactI64:
+		return cr.zigzag()
+	case compactBinary:
+		size := int(cr.varint())
+		value := string(cr.buf[cr.pos : cr.pos+size])
+		cr.pos += size
+		return value
+	case compactList:
+		header := cr.buf[cr.pos]
+		cr.pos++
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(cr.varint())
+		}
+		list := make([]any, 0, size)
+		for i := 0; i < size; i++ {
+			list = append(list, cr.readValue(header&0x0f))
+		}
+		return list
+	case compactStruct:
+		return cr.readStruct()
+	default:
+		panic("unsupported compact type")
+	}
+}
+
+// readParquet reads back the rows of a file written by WriteParquet, keyed by column name.
+func readParquet(t *testing.T, data []byte) []map[string]any {
+	require.Equal(t, parquetMagic, string(data[:4]))
+	require.Equal(t, parquetMagic, string(data[len(data)-4:]))
+	footerSize := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &compactReader{buf: data[:len(data)-8], pos: len(data) - 8 - footerSize}
+	meta := footer.readStruct()
+
+	numRows := int(meta[3].(int64))
+	rows := make([]map[string]any, numRows)
+	for index := range rows {
+		rows[index] = make(map[string]any)
+	}
+
+	schema := meta[2].([]any)
+	rowGroup := meta[4].([]any)[0].(map[int16]any)
+	require.Equal(t, int64(numRows), rowGroup[3])
+
+	for index, chunk := range rowGroup[1].([]any) {
+		element := schema[index+1].(map[int16]any)
+		name := element[4].(string)
+		optional := element[3].(int64) == int64(repetitionOptional)
+		columnMeta := chunk.(map[int16]any)[3].(map[int16]any)
+		require.Equal(t, []any{name}, columnMeta[3])
+
+		reader := &compactReader{buf: data, pos: int(columnMeta[9].(int64))}
+		header := reader.readStruct()
+		page := data[reader.pos : reader.pos+int(header[3].(int64))]
+		require.Equal(t, columnMeta[7], int64(reader.pos)-columnMeta[9].(int64)+header[3].(int64))
+
+		present := make([]bool, numRows)
+		for i := range present {
+			present[i] = true
+		}
+		if optional {
+			levelsSize := int(binary.LittleEndian.Uint32(page))
+			levels := &compactReader{buf: page[4 : 4+levelsSize]}
+			for row := 0; levels.pos < len(levels.buf); {
+				count := int(levels.varint() >> 1)
+				value := levels.buf[levels.pos]
+				levels.pos++
+				for i := 0; i < count; i++ {
+					present[row] = value == 1
+					row++
+				}
+			}
+			page = page[4+levelsSize:]
+		}
+
+		for row := 0; row < numRows; row++ {
+			if !present[row] {
+				rows[row][name] = nil
+				continue
+			}
+			switch element[1].(int64) {
+			case int64(typeByteArray):
+				size := int(binary.LittleEndian.Uint32(page))
+				rows[row][name] = string(page[4 : 4+size])
+				page = page[4+size:]
+			case int64(typeInt64):
+				rows[row][name] = int64(binary.LittleEndian.Uint64(page))
+				page = page[8:]
+			}
+		}
+		require.Empty(t, page)
+	}
+	return rows
+}
+
+func TestWriteParquet(t *testing.T) {
+	caveated := tuple.WithCaveat(tuple.MustParse("document:plan#viewer@user:tom"), "ipcheck")
+	caveatContext, err := structpb.NewStruct(map[string]any{"allowed": "10.0.0.0/8"})
+	require.NoError(t, err)
+	caveated.Caveat.Context = caveatContext
+
+	createdAt := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	deletedAt := createdAt.Add(time.Hour)
+
+	// Enough versions for list headers and definition level runs of more than one byte.
+	var versions []common.RelationshipVersion
+	for i := 0; i < 40; i++ {
+		version := common.RelationshipVersion{
+			Relationship:    tuple.MustParse("document:plan#viewer@team:eng#member"),
+			CreatedRevision: revision.NewFromDecimal(decimal.NewFromInt(int64(i))),
+			DeletedRevision: revision.NewFromDecimal(decimal.NewFromInt(int64(i + 1))),
+			DeletedAt:       deletedAt,
+		}
+		if i%3 == 0 {
+			version.Relationship = caveated
+			version.CreatedAt = createdAt
+		}
+		versions = append(versions, version)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteParquet(&buf, versions))
+
+	rows := readParquet(t, buf.Bytes())
+	require.Len(t, rows, len(versions))
+	require.Equal(t, map[string]any{
+		"resource_type":    "document",
+		"resource_id":      "plan",
+		"relation":         "viewer",
+		"subject_type":     "user",
+		"subject_id":       "tom",
+		"subject_relation": "...",
+		"caveat_name":      "ipcheck",
+		"caveat_context":   `{"allowed":"10.0.0.0/8"}`,
+		"created_revision": "0",
+		"deleted_revision": "1",
+		"created_at":       createdAt.UnixMicro(),
+		"deleted_at":       deletedAt.UnixMicro(),
+	}, rows[0])
+	require.Equal(t, map[string]any{
+		"resource_type":    "document",
+		"resource_id":      "plan",
+		"relation":         "viewer",
+		"subject_type":     "team",
+		"subject_id":       "eng",
+		"subject_relation": "member",
+		"caveat_name":      nil,
+		"caveat_context":   nil,
+		"created_revision": "1",
+		"deleted_revision": "2",
+		"created_at":       nil,
+		"deleted_at":       deletedAt.UnixMicro(),
+	}, rows[1])
+	require.Equal(t, "ipcheck", rows[39]["caveat_name"])
+	require.Equal(t, "40", rows[39]["deleted_revision"])
+}
@@ -0,0 +1,91 @@
+package archive
+
+import (
+	"encoding/binary"
+)
+
+// Types of the Thrift compact protocol, in which Parquet encodes its metadata.
+const (
+	compactI32    byte = 5
+	compactI64    byte = 6
+	compactBinary byte = 8
+	compactList   byte = 9
+	compactStruct byte = 12
+)
+
+// compactWriter encodes structs in the Thrift compact protocol. Only the types used by
+// the Parquet metadata written by this package are supported. Each struct, including the
+// outermost, is written between beginStruct and endStruct.
+type compactWriter struct {
+	buf       []byte
+	lastField []int16
+}
+
+func (cw *compactWriter) varint(value uint64) {
+	cw.buf = binary.AppendUvarint(cw.buf, value)
+}
+
+func (cw *compactWriter) zigzag(value int64) {
+	cw.varint(uint64((value << 1) ^ (value >> 63)))
+}
+
+func (cw *compactWriter) fieldHeader(id int16, fieldType byte) {
+	last := &cw.lastField[len(cw.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		cw.buf = append(cw.buf, byte(delta)<<4|fieldType)
+	} else {
+		cw.buf = append(cw.buf, fieldType)
+		cw.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (cw *compactWriter) i32Field(id int16, value int32) {
+	cw.fieldHeader(id, compactI32)
+	cw.zigzag(int64(value))
+}
+
+func (cw *compactWriter) i64Field(id int16, value int64) {
+	cw.fieldHeader(id, compactI64)
+	cw.zigzag(value)
+}
+
+func (cw *compactWriter) stringField(id int16, value string) {
+	cw.fieldHeader(id, compactBinary)
+	cw.binary(value)
+}
+
+func (cw *compactWriter) i32(value int32) {
+	cw.zigzag(int64(value))
+}
+
+func (cw *compactWriter) binary(value string) {
+	cw.varint(uint64(len(value)))
+	cw.buf = append(cw.buf, value...)
+}
+
+func (cw *compactWriter) listField(id int16, elemType byte, size int) {
+	cw.fieldHeader(id, compactList)
+	if size < 15 {
+		cw.buf = append(cw.buf, byte(size)<<4|elemType)
+		return
+	}
+	cw.buf = append(cw.buf, 0xf0|elemType)
+	cw.varint(uint64(size))
+}
+
+// structField begins a struct valued field, which must be closed with endStruct.
+func (cw *compactWriter) structField(id int16) {
+	cw.fieldHeader(id, compactStruct)
+	cw.beginStruct()
+}
+
+// beginStruct begins the outermost struct, or a struct which is an element of a list.
+func (cw *compactWriter) beginStruct() {
+	cw.lastField = append(cw.lastField, 0)
+}
+
+func (cw *compactWriter) endStruct() {
+	cw.buf = append(cw.buf, 0)
+	cw.lastField = cw.lastField[:len(cw.lastField)-1]
+}
@@ -0,0 +1,297 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// DefaultRestoreBatchSize is the default number of relationships written per transaction
+// when restoring a backup.
+const DefaultRestoreBatchSize = 1_000
+
+// ErrDatastoreNotEmpty is returned when restoring into a datastore which already has a
+// schema, without overwriting having been requested.
+var ErrDatastoreNotEmpty = errors.New("datastore already contains a schema")
+
+// Backup writes a consistent snapshot of the datastore, read at its head revision, to
+// the writer. The revision is recorded in the backup's metadata if includeRevision is set.
+func Backup(ctx context.Context, ds datastore.Datastore, engine string, includeRevision bool, w io.Writer) (Counts, error) {
+	rev, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return Counts{}, fmt.Errorf("unable to determine head revision: %w", err)
+	}
+
+	metadata := Metadata{Engine: engine, CreatedAt: time.Now().UTC()}
+	if includeRevision {
+		metadata.Revision = rev.String()
+	}
+
+	return backupAt(ctx, ds.SnapshotReader(rev), metadata, w)
+}
+
+func backupAt(ctx context.Context, reader datastore.Reader, metadata Metadata, w io.Writer) (Counts, error) {
+	encoder, err := NewEncoder(w, metadata)
+	if err != nil {
+		return Counts{}, err
+	}
+
+	caveats, err := reader.ListCaveats(ctx)
+	if err != nil {
+		return Counts{}, fmt.Errorf("unable to read caveats: %w", err)
+	}
+	for _, caveat := range caveats {
+		if err := encoder.WriteCaveat(caveat); err != nil {
+			return Counts{}, err
+		}
+	}
+
+	namespaces, err := reader.ListNamespaces(ctx)
+	if err != nil {
+		return Counts{}, fmt.Errorf("unable to read namespaces: %w", err)
+	}
+	for _, ns := range namespaces {
+		if err := encoder.WriteNamespace(ns); err != nil {
+			return Counts{}, err
+		}
+	}
+
+	for _, ns := range namespaces {
+		if err := backupRelationships(ctx, reader, ns.Name, encoder); err != nil {
+			return Counts{}, fmt.Errorf("unable to read relationships for `%s`: %w", ns.Name, err)
+		}
+		log.Ctx(ctx).Debug().Str("namespace", ns.Name).Uint64("relationships", encoder.Counts().Relationships).Msg("backed up namespace")
+	}
+
+	if err := encoder.Close(); err != nil {
+		return Counts{}, err
+	}
+	return encoder.Counts(), nil
+}
+
+func backupRelationships(ctx context.Context, reader datastore.Reader, namespace string, encoder *Encoder) error {
+	iter, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: namespace})
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for rel := iter.Next(); rel != nil; rel = iter.Next() {
+		if err := encoder.WriteRelationship(rel); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+// Restore writes the contents of the backup read from r into the datastore. Caveats and
+// namespaces are written in a single transaction, followed by relationships in
+// transactions of batchSize. Restoring into a datastore which already has a schema fails
+// with ErrDatastoreNotEmpty unless overwrite is set, in which case its relationships,
+// namespaces and caveats are first deleted, so that the datastore holds only the contents
+// of the backup.
+//
+// The epoch of the datastore is bumped before anything is written, so that zedtokens
+// minted before the restore are rejected once it starts. Datastores which do not store an
+// epoch keep accepting them.
+//
+// An interrupted restore can safely be run again with overwrite set. As the integrity of a
+// backup is only known once it has been read in full, batches written before a truncated
+// backup is detected are kept.
+func Restore(ctx context.Context, ds datastore.Datastore, r io.Reader, batchSize int, overwrite bool) (Metadata, Counts, error) {
+	decoder, err := NewDecoder(r)
+	if err != nil {
+		return Metadata{}, Counts{}, err
+	}
+
+	counts, err := restoreDecoded(ctx, ds, decoder, batchSize, overwrite)
+	if err != nil {
+		return Metadata{}, Counts{}, err
+	}
+	return decoder.Metadata(), counts, nil
+}
+
+func restoreDecoded(ctx context.Context, ds datastore.Datastore, decoder *Decoder, batchSize int, overwrite bool) (Counts, error) {
+	rev, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return Counts{}, fmt.Errorf("unable to determine head revision: %w", err)
+	}
+	existing, err := ds.SnapshotReader(rev).ListNamespaces(ctx)
+	if err != nil {
+		return Counts{}, fmt.Errorf("unable to read existing namespaces: %w", err)
+	}
+	if len(existing) > 0 && !overwrite {
+		return Counts{}, ErrDatastoreNotEmpty
+	}
+
+	if err := bumpEpoch(ctx, ds); err != nil {
+		return Counts{}, err
+	}
+
+	if len(existing) > 0 {
+		if err := clearDatastore(ctx, ds, existing, batchSize); err != nil {
+			return Counts{}, err
+		}
+	}
+
+	var caveats []*core.CaveatDefinition
+	var namespaces []*core.NamespaceDefinition
+	var batch []*core.RelationTupleUpdate
+	schemaWritten := false
+
+	flush := func() error {
+		if !schemaWritten {
+			if _, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+				if len(caveats) > 0 {
+					if err := rwt.WriteCaveats(ctx, caveats); err != nil {
+						return err
+					}
+				}
+				return rwt.WriteNamespaces(ctx, namespaces...)
+			}); err != nil {
+				return fmt.Errorf("unable to write schema: %w", err)
+			}
+			schemaWritten = true
+		}
+
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships(ctx, batch)
+		}); err != nil {
+			return fmt.Errorf("unable to write relationships: %w", err)
+		}
+		batch = nil
+		return nil
+	}
+
+	for {
+		msg, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Counts{}, err
+		}
+
+		switch typed := msg.(type) {
+		case *core.CaveatDefinition:
+			if schemaWritten {
+				return Counts{}, errors.New("caveat found after relationships in backup")
+			}
+			caveats = append(caveats, typed)
+
+		case *core.NamespaceDefinition:
+			if schemaWritten {
+				return Counts{}, errors.New("namespace found after relationships in backup")
+			}
+			namespaces = append(namespaces, typed)
+
+		case *core.RelationTuple:
+			batch = append(batch, tuple.Touch(typed))
+			if len(batch) >= batchSize {
+				if err := flush(); err != nil {
+					return Counts{}, err
+				}
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return Counts{}, err
+	}
+	return decoder.Counts(), nil
+}
+
+// bumpEpoch bumps the epoch of the datastore, if it stores one.
+func bumpEpoch(ctx context.Context, ds datastore.Datastore) error {
+	store, ok := datastore.UnwrapAs[common.EpochStore](ds)
+	if !ok {
+		log.Ctx(ctx).Warn().Msg("datastore does not store an epoch; zedtokens minted before the restore remain accepted")
+		return nil
+	}
+
+	epoch, err := store.BumpEpoch(ctx)
+	if errors.Is(err, common.ErrEpochsUnsupported) {
+		log.Ctx(ctx).Warn().Msg("datastore epochs disabled, run the datastore migrations to enable them; zedtokens minted before the restore remain accepted")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Ctx(ctx).Info().Uint64("epoch", epoch).Msg("bumped datastore epoch")
+	return nil
+}
+
+// clearDatastore deletes the relationships of the namespaces, in transactions of batchSize, and
+// then the namespaces and every caveat.
+func clearDatastore(ctx context.Context, ds datastore.Datastore, namespaces []*core.NamespaceDefinition, batchSize int) error {
+	limit := uint64(batchSize)
+	names := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		names = append(names, ns.Name)
+
+		for {
+			var deleted int
+			if _, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+				iter, err := rwt.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: ns.Name}, options.WithLimit(&limit))
+				if err != nil {
+					return err
+				}
+				defer iter.Close()
+
+				var updates []*core.RelationTupleUpdate
+				for rel := iter.Next(); rel != nil; rel = iter.Next() {
+					updates = append(updates, tuple.Delete(rel))
+				}
+				if err := iter.Err(); err != nil {
+					return err
+				}
+
+				deleted = len(updates)
+				if deleted == 0 {
+					return nil
+				}
+				return rwt.WriteRelationships(ctx, updates)
+			}); err != nil {
+				return fmt.Errorf("unable to delete existing relationships: %w", err)
+			}
+			if deleted == 0 {
+				break
+			}
+		}
+	}
+
+	if _, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		if err := rwt.DeleteNamespaces(ctx, names...); err != nil {
+			return err
+		}
+
+		caveats, err := rwt.ListCaveats(ctx)
+		if err != nil {
+			return err
+		}
+		if len(caveats) == 0 {
+			return nil
+		}
+		caveatNames := make([]string, 0, len(caveats))
+		for _, caveat := range caveats {
+			caveatNames = append(caveatNames, caveat.Name)
+		}
+		return rwt.DeleteCaveats(ctx, caveatNames)
+	}); err != nil {
+		return fmt.Errorf("unable to delete existing schema: %w", err)
+	}
+	return nil
+}
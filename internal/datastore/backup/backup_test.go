@@ -0,0 +1,158 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func readAll(t *testing.T, ds datastore.Datastore) (caveats, namespaces, relationships []string) {
+	ctx := context.Background()
+	rev, err := ds.HeadRevision(ctx)
+	require.NoError(t, err)
+	reader := ds.SnapshotReader(rev)
+
+	caveatDefs, err := reader.ListCaveats(ctx)
+	require.NoError(t, err)
+	for _, caveat := range caveatDefs {
+		caveats = append(caveats, caveat.Name)
+	}
+
+	nsDefs, err := reader.ListNamespaces(ctx)
+	require.NoError(t, err)
+	for _, ns := range nsDefs {
+		namespaces = append(namespaces, ns.Name)
+
+		iter, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: ns.Name})
+		require.NoError(t, err)
+		for rel := iter.Next(); rel != nil; rel = iter.Next() {
+			relationships = append(relationships, tuple.String(rel))
+		}
+		require.NoError(t, iter.Err())
+		iter.Close()
+	}
+
+	sort.Strings(caveats)
+	sort.Strings(namespaces)
+	sort.Strings(relationships)
+	return
+}
+
+func backupStandardData(t *testing.T) (datastore.Datastore, []byte) {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	source, _ := testfixtures.StandardDatastoreWithCaveatedData(rawDS, require.New(t))
+
+	var buf bytes.Buffer
+	counts, err := Backup(context.Background(), source, "memory", true, &buf)
+	require.NoError(t, err)
+	require.Greater(t, counts.Relationships, uint64(0))
+	return source, buf.Bytes()
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	source, backup := backupStandardData(t)
+
+	target, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	metadata, counts, err := Restore(context.Background(), target, bytes.NewReader(backup), 7, false)
+	require.NoError(t, err)
+	require.Equal(t, "memory", metadata.Engine)
+	require.NotEmpty(t, metadata.Revision)
+
+	expectedCaveats, expectedNamespaces, expectedRelationships := readAll(t, source)
+	caveats, namespaces, relationships := readAll(t, target)
+	require.Equal(t, expectedCaveats, caveats)
+	require.Equal(t, expectedNamespaces, namespaces)
+	require.Equal(t, expectedRelationships, relationships)
+	require.Equal(t, uint64(len(expectedRelationships)), counts.Relationships)
+}
+
+func TestRestoreRequiresOverwriteForExistingSchema(t *testing.T) {
+	source, backup := backupStandardData(t)
+
+	_, _, err := Restore(context.Background(), source, bytes.NewReader(backup), DefaultRestoreBatchSize, false)
+	require.ErrorIs(t, err, ErrDatastoreNotEmpty)
+
+	// Rejected restores change nothing, including the epoch.
+	epoch, err := common.DatastoreEpoch(context.Background(), source)
+	require.NoError(t, err)
+	require.Zero(t, epoch)
+
+	_, _, err = Restore(context.Background(), source, bytes.NewReader(backup), DefaultRestoreBatchSize, true)
+	require.NoError(t, err)
+
+	epoch, err = common.DatastoreEpoch(context.Background(), source)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), epoch)
+}
+
+func TestRestoreOverwriteReplacesExistingData(t *testing.T) {
+	source, backup := backupStandardData(t)
+	expectedCaveats, expectedNamespaces, expectedRelationships := readAll(t, source)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	target, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		caveat stale(value int) { value == 1 }
+		definition user {}
+		definition document {
+			relation viewer: user
+		}
+		definition legacy {
+			relation owner: user
+		}`, []*core.RelationTuple{
+		tuple.MustParse("document:stale#viewer@user:tom"),
+		tuple.MustParse("legacy:first#owner@user:tom"),
+		tuple.MustParse("legacy:second#owner@user:tom"),
+	}, require.New(t))
+
+	_, _, err = Restore(context.Background(), target, bytes.NewReader(backup), 1, true)
+	require.NoError(t, err)
+
+	caveats, namespaces, relationships := readAll(t, target)
+	require.Equal(t, expectedCaveats, caveats)
+	require.Equal(t, expectedNamespaces, namespaces)
+	require.Equal(t, expectedRelationships, relationships)
+}
+
+func TestRestoreInvalidBackups(t *testing.T) {
+	_, backup := backupStandardData(t)
+
+	wrongVersion := append([]byte{}, backup...)
+	binary.BigEndian.PutUint16(wrongVersion[len(magic):], FormatVersion+1)
+
+	testCases := []struct {
+		name          string
+		contents      []byte
+		expectedError string
+	}{
+		{"empty", nil, "unable to read backup header"},
+		{"not a backup", []byte("definition user {}"), "not a SpiceDB backup"},
+		{"unsupported version", wrongVersion, "unsupported backup format version 2"},
+		{"truncated", backup[:len(backup)-20], ErrTruncated.Error()},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			target, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(t, err)
+
+			_, _, err = Restore(context.Background(), target, bytes.NewReader(tc.contents), DefaultRestoreBatchSize, false)
+			require.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+}
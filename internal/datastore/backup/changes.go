@@ -0,0 +1,416 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// DefaultChangesIdleTimeout is the default time for which BackupChanges waits for a
+// further transaction before ending a change segment short of the head revision.
+const DefaultChangesIdleTimeout = 5 * time.Second
+
+var (
+	// ErrNoRevision is returned when a change segment is requested to follow a snapshot
+	// backup which did not record its revision.
+	ErrNoRevision = errors.New("backup does not record its revision")
+
+	// ErrBrokenChain is returned when a change segment does not follow the revision at
+	// which the preceding backup or segment ended.
+	ErrBrokenChain = errors.New("change segment does not follow the preceding backup")
+
+	// ErrTargetNotFound is returned when restoring to a revision which is not that of the
+	// snapshot nor of any checkpoint in its change segments.
+	ErrTargetNotFound = errors.New("restore target not found in backup or its change segments")
+
+	// ErrTargetBeforeBackup is returned when restoring to a time before the snapshot
+	// backup was taken.
+	ErrTargetBeforeBackup = errors.New("restore target precedes the backup")
+
+	// ErrNoCommitTimes is returned when restoring to a time with a change segment which
+	// does not record the commit times of its transactions.
+	ErrNoCommitTimes = errors.New("change segment does not record commit times")
+)
+
+// LastRevision returns the metadata of the backup or change segment read from r, and the
+// revision of the source datastore up to which it holds changes, which is the revision
+// from which the next change segment should be taken. Change segments are read in full,
+// so that a truncated segment is never built upon.
+func LastRevision(r io.Reader) (Metadata, string, error) {
+	decoder, err := NewDecoder(r)
+	if err != nil {
+		return Metadata{}, "", err
+	}
+
+	metadata := decoder.Metadata()
+	if !metadata.IsChangeSegment() {
+		if metadata.Revision == "" {
+			return Metadata{}, "", ErrNoRevision
+		}
+		return metadata, metadata.Revision, nil
+	}
+
+	for {
+		_, _, err := decoder.NextTransaction()
+		if errors.Is(err, io.EOF) {
+			return metadata, decoder.Checkpoint().Revision, nil
+		}
+		if err != nil {
+			return Metadata{}, "", err
+		}
+	}
+}
+
+// BackupChanges writes a change segment to the writer, holding the relationship changes
+// of every transaction committed to the datastore after the given revision, as reported
+// by its Watch API. Each transaction is followed by a checkpoint at its revision, which
+// allows a restore to stop at any of them.
+//
+// The segment ends at the first transaction at or past the head revision at the time of
+// the call, or once no transaction has been received for idleTimeout, since transactions
+// without relationship changes are not reported. In either case the segment is complete up
+// to its last checkpoint, which is returned. As schema changes are not reported, they
+// require a new snapshot backup. Checkpoints record the commit times of transactions if
+// the datastore reports them, and otherwise the segment can only be restored to a
+// revision.
+func BackupChanges(ctx context.Context, ds datastore.Datastore, engine string, after string, idleTimeout time.Duration, w io.Writer) (string, Counts, error) {
+	features, err := ds.Features(ctx)
+	if err != nil {
+		return "", Counts{}, fmt.Errorf("unable to determine datastore features: %w", err)
+	}
+	if !features.Watch.Enabled {
+		return "", Counts{}, fmt.Errorf("change segments require the Watch API, which is disabled: %s", features.Watch.Reason)
+	}
+
+	afterRevision, err := ds.RevisionFromString(after)
+	if err != nil {
+		return "", Counts{}, fmt.Errorf("invalid revision `%s`: %w", after, err)
+	}
+
+	head, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return "", Counts{}, fmt.Errorf("unable to determine head revision: %w", err)
+	}
+
+	// Every revision of a datastore records its commit time if its head revision does.
+	_, commitTimes := common.RevisionCommitTime(ds, head)
+	encoder, err := NewEncoder(w, Metadata{Engine: engine, CreatedAt: time.Now().UTC(), After: after, CommitTimes: commitTimes})
+	if err != nil {
+		return "", Counts{}, err
+	}
+
+	last := after
+	if head.GreaterThan(afterRevision) {
+		last, err = watchChanges(ctx, ds, afterRevision, head, idleTimeout, encoder)
+		if err != nil {
+			return "", Counts{}, err
+		}
+	}
+
+	if err := encoder.Close(); err != nil {
+		return "", Counts{}, err
+	}
+	return last, encoder.Counts(), nil
+}
+
+func watchChanges(ctx context.Context, ds datastore.Datastore, after, head datastore.Revision, idleTimeout time.Duration, encoder *Encoder) (string, error) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changes, errs := ds.Watch(watchCtx, after)
+
+	idle := time.NewTimer(idleTimeout)
+	defer idle.Stop()
+
+	last := after.String()
+	for {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				// The cause, if any, is reported on the error channel.
+				changes = nil
+				continue
+			}
+
+			checkpoint := Checkpoint{Revision: change.Revision.String()}
+			if committedAt, ok := common.RevisionCommitTime(ds, change.Revision); ok {
+				checkpoint.CommittedAt = committedAt
+			}
+			if err := encoder.WriteTransaction(checkpoint, change.Changes); err != nil {
+				return "", err
+			}
+			last = change.Revision.String()
+			if !change.Revision.LessThan(head) {
+				return last, nil
+			}
+
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(idleTimeout)
+
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			return "", fmt.Errorf("unable to watch changes: %w", err)
+
+		case <-idle.C:
+			log.Ctx(ctx).Debug().Str("revision", last).Msg("no further changes before idle timeout")
+			return last, nil
+		}
+	}
+}
+
+// Target is the state of the source datastore to which a restore is made: either as of
+// the checkpoint at Revision, or as of Time, including every transaction committed by
+// then. The zero Target applies every change available. Restores to a Time require change
+// segments which record commit times.
+type Target struct {
+	Revision string
+	Time     time.Time
+}
+
+// IsZero returns whether the target is unset.
+func (t Target) IsZero() bool {
+	return t.Revision == "" && t.Time.IsZero()
+}
+
+func (t Target) includes(checkpoint Checkpoint) bool {
+	return t.Time.IsZero() || !checkpoint.CommittedAt.After(t.Time)
+}
+
+func (t Target) isAt(checkpoint Checkpoint) bool {
+	return t.Revision != "" && checkpoint.Revision == t.Revision
+}
+
+// AppliedChanges is the outcome of applying a change segment.
+type AppliedChanges struct {
+	Counts
+
+	// Checkpoint is the checkpoint of the last transaction applied, or one at the
+	// revision which the segment follows if none were.
+	Checkpoint Checkpoint
+
+	// ReachedTarget is set once the target of the restore has been reached, after which
+	// no further segments should be applied.
+	ReachedTarget bool
+}
+
+// ApplyChanges applies the transactions of the change segment read from r to the
+// datastore, each in a transaction of its own. The datastore must hold the contents of
+// the source datastore as of the revision which the segment follows, which is checked
+// against previousRevision. Transactions past the target are not applied.
+func ApplyChanges(ctx context.Context, ds datastore.Datastore, r io.Reader, previousRevision string, target Target) (AppliedChanges, error) {
+	decoder, err := NewDecoder(r)
+	if err != nil {
+		return AppliedChanges{}, err
+	}
+
+	metadata := decoder.Metadata()
+	if !metadata.IsChangeSegment() {
+		return AppliedChanges{}, errors.New("backup is a snapshot rather than a change segment")
+	}
+	if metadata.After != previousRevision {
+		return AppliedChanges{}, fmt.Errorf("%w: segment follows revision `%s`, expected `%s`", ErrBrokenChain, metadata.After, previousRevision)
+	}
+	if !target.Time.IsZero() && !metadata.CommitTimes {
+		return AppliedChanges{}, fmt.Errorf("%w, as its %s datastore does not report them: restore to a revision instead", ErrNoCommitTimes, metadata.Engine)
+	}
+
+	applied := AppliedChanges{Checkpoint: decoder.Checkpoint()}
+	applied.ReachedTarget = target.isAt(applied.Checkpoint)
+	for !applied.ReachedTarget {
+		checkpoint, changes, err := decoder.NextTransaction()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return AppliedChanges{}, err
+		}
+		if !target.includes(checkpoint) {
+			applied.ReachedTarget = true
+			break
+		}
+
+		if len(changes) > 0 {
+			// Changes are replayed as touches, so that a segment can be reapplied.
+			updates := make([]*core.RelationTupleUpdate, 0, len(changes))
+			for _, change := range changes {
+				if change.Operation == core.RelationTupleUpdate_CREATE {
+					change = tuple.Touch(change.Tuple)
+				}
+				updates = append(updates, change)
+			}
+
+			if _, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+				return rwt.WriteRelationships(ctx, updates)
+			}); err != nil {
+				return AppliedChanges{}, fmt.Errorf("unable to apply changes at revision `%s`: %w", checkpoint.Revision, err)
+			}
+		}
+
+		applied.Changes += uint64(len(changes))
+		applied.Checkpoints++
+		applied.Checkpoint = checkpoint
+		applied.ReachedTarget = target.isAt(checkpoint)
+	}
+
+	return applied, nil
+}
+
+// RestoreTo restores the snapshot backup found at the first of the locations, then
+// applies the change segments found at the remaining locations, in order, until the
+// target is reached. It returns the metadata of the snapshot, the number of objects
+// restored and changes applied, and the checkpoint at which the restore ended.
+//
+// Restores to a revision fail with ErrTargetNotFound if no checkpoint is at that
+// revision. Restores to a time end at the last checkpoint available if the segments end
+// before it, which callers can detect from the returned checkpoint.
+func RestoreTo(ctx context.Context, ds datastore.Datastore, locations []string, batchSize int, overwrite bool, target Target) (Metadata, Counts, Checkpoint, error) {
+	if len(locations) == 0 {
+		return Metadata{}, Counts{}, Checkpoint{}, errors.New("no backup to restore")
+	}
+
+	r, err := Open(ctx, locations[0])
+	if err != nil {
+		return Metadata{}, Counts{}, Checkpoint{}, fmt.Errorf("unable to open backup: %w", err)
+	}
+	defer r.Close()
+
+	decoder, err := NewDecoder(r)
+	if err != nil {
+		return Metadata{}, Counts{}, Checkpoint{}, err
+	}
+
+	metadata := decoder.Metadata()
+	if len(locations) > 1 || !target.IsZero() {
+		if metadata.Revision == "" {
+			return Metadata{}, Counts{}, Checkpoint{}, ErrNoRevision
+		}
+		if !target.Time.IsZero() && target.Time.Before(metadata.CreatedAt) {
+			return Metadata{}, Counts{}, Checkpoint{}, fmt.Errorf("%w: the backup was taken at %s", ErrTargetBeforeBackup, metadata.CreatedAt)
+		}
+	}
+
+	// Segments taken from the same datastore all record commit times or none do, so a time
+	// target is checked against the first before anything is restored.
+	if !target.Time.IsZero() && len(locations) > 1 {
+		segment, err := segmentMetadataAt(ctx, locations[1])
+		if err != nil {
+			return Metadata{}, Counts{}, Checkpoint{}, fmt.Errorf("unable to read change segment %s: %w", locations[1], err)
+		}
+		if !segment.CommitTimes {
+			return Metadata{}, Counts{}, Checkpoint{}, fmt.Errorf("%w, as its %s datastore does not report them: restore to a revision instead", ErrNoCommitTimes, segment.Engine)
+		}
+	}
+
+	counts, err := restoreDecoded(ctx, ds, decoder, batchSize, overwrite)
+	if err != nil {
+		return Metadata{}, Counts{}, Checkpoint{}, err
+	}
+
+	checkpoint := Checkpoint{Revision: metadata.Revision, CommittedAt: metadata.CreatedAt}
+	reached := target.isAt(checkpoint)
+	for _, location := range locations[1:] {
+		if reached {
+			break
+		}
+
+		applied, err := applyChangesAt(ctx, ds, location, checkpoint.Revision, target)
+		if err != nil {
+			return Metadata{}, Counts{}, Checkpoint{}, fmt.Errorf("unable to apply change segment %s: %w", location, err)
+		}
+		log.Ctx(ctx).Info().
+			Str("segment", location).
+			Str("revision", applied.Checkpoint.Revision).
+			Uint64("transactions", applied.Checkpoints).
+			Uint64("changes", applied.Changes).
+			Msg("change segment applied")
+
+		counts.Changes += applied.Changes
+		counts.Checkpoints += applied.Checkpoints
+		if applied.Checkpoints > 0 {
+			checkpoint = applied.Checkpoint
+		}
+		reached = applied.ReachedTarget
+	}
+
+	if target.Revision != "" && !reached {
+		return Metadata{}, Counts{}, Checkpoint{}, fmt.Errorf("%w: revision `%s`", ErrTargetNotFound, target.Revision)
+	}
+	return metadata, counts, checkpoint, nil
+}
+
+// TransactionSummary describes a transaction found in a change segment.
+type TransactionSummary struct {
+	Checkpoint
+
+	// Touches and Deletes are the number of relationships created or touched, and deleted.
+	Touches uint64
+	Deletes uint64
+}
+
+// SummarizeTransactions reads the change segment from r and summarizes each of its
+// transactions, which helps to pick the target of a restore, such as the revision before
+// an accidental bulk deletion.
+func SummarizeTransactions(r io.Reader) (Metadata, []TransactionSummary, error) {
+	decoder, err := NewDecoder(r)
+	if err != nil {
+		return Metadata{}, nil, err
+	}
+
+	var summaries []TransactionSummary
+	for {
+		checkpoint, changes, err := decoder.NextTransaction()
+		if errors.Is(err, io.EOF) {
+			return decoder.Metadata(), summaries, nil
+		}
+		if err != nil {
+			return Metadata{}, nil, err
+		}
+
+		summary := TransactionSummary{Checkpoint: checkpoint}
+		for _, change := range changes {
+			if change.Operation == core.RelationTupleUpdate_DELETE {
+				summary.Deletes++
+			} else {
+				summary.Touches++
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+}
+
+func applyChangesAt(ctx context.Context, ds datastore.Datastore, location, previousRevision string, target Target) (AppliedChanges, error) {
+	r, err := Open(ctx, location)
+	if err != nil {
+		return AppliedChanges{}, err
+	}
+	defer r.Close()
+
+	return ApplyChanges(ctx, ds, r, previousRevision, target)
+}
+
+func segmentMetadataAt(ctx context.Context, location string) (Metadata, error) {
+	r, err := Open(ctx, location)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer r.Close()
+
+	decoder, err := NewDecoder(r)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return decoder.Metadata(), nil
+}
@@ -0,0 +1,61 @@
+//go:build ci && docker
+// +build ci,docker
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/testfixtures"
+	testdatastore "github.com/authzed/spicedb/internal/testserver/datastore"
+	"github.com/authzed/spicedb/internal/testserver/datastore/config"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// TestMySQLChangeSegment backs up the changes of MySQL, whose revisions are transaction IDs
+// rather than timestamps, so its segments record no commit times and restore only to
+// revisions.
+func TestMySQLChangeSegment(t *testing.T) {
+	ctx := context.Background()
+	b := testdatastore.RunMySQLForTesting(t, "")
+	source, _ := testfixtures.StandardDatastoreWithCaveatedData(b.NewDatastore(t, config.DatastoreConfigInitFunc(t)), require.New(t))
+
+	var base bytes.Buffer
+	_, err := Backup(ctx, source, "mysql", true, &base)
+	require.NoError(t, err)
+	_, after, err := LastRevision(bytes.NewReader(base.Bytes()))
+	require.NoError(t, err)
+
+	rev := writeUpdates(t, source, tuple.Create(tuple.MustParse("document:newdoc#viewer@user:tom")))
+
+	var segment bytes.Buffer
+	last, _, err := BackupChanges(ctx, source, "mysql", after, time.Second, &segment)
+	require.NoError(t, err)
+	require.Equal(t, rev, last)
+
+	metadata, transactions, err := SummarizeTransactions(bytes.NewReader(segment.Bytes()))
+	require.NoError(t, err)
+	require.False(t, metadata.CommitTimes)
+	require.Len(t, transactions, 1)
+	require.True(t, transactions[0].CommittedAt.IsZero())
+
+	dir := t.TempDir()
+	locations := []string{filepath.Join(dir, "backup-0"), filepath.Join(dir, "backup-1")}
+	require.NoError(t, os.WriteFile(locations[0], base.Bytes(), 0o600))
+	require.NoError(t, os.WriteFile(locations[1], segment.Bytes(), 0o600))
+
+	target := b.NewDatastore(t, config.DatastoreConfigInitFunc(t))
+	_, _, _, err = RestoreTo(ctx, target, locations, DefaultRestoreBatchSize, false, Target{Time: time.Now()})
+	require.ErrorIs(t, err, ErrNoCommitTimes)
+
+	_, _, checkpoint, err := RestoreTo(ctx, target, locations, DefaultRestoreBatchSize, false, Target{Revision: rev})
+	require.NoError(t, err)
+	require.Equal(t, rev, checkpoint.Revision)
+}
@@ -0,0 +1,236 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func writeUpdates(t *testing.T, ds datastore.Datastore, updates ...*core.RelationTupleUpdate) string {
+	rev, err := ds.ReadWriteTx(context.Background(), func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(context.Background(), updates)
+	})
+	require.NoError(t, err)
+	return rev.String()
+}
+
+func backupChanges(t *testing.T, ds datastore.Datastore, previous []byte) ([]byte, string, Counts) {
+	_, after, err := LastRevision(bytes.NewReader(previous))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	last, counts, err := BackupChanges(context.Background(), ds, "memory", after, 50*time.Millisecond, &buf)
+	require.NoError(t, err)
+
+	_, segmentLast, err := LastRevision(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, last, segmentLast)
+	return buf.Bytes(), last, counts
+}
+
+func TestChangeSegments(t *testing.T) {
+	ctx := context.Background()
+	rawSource, err := memdb.NewMemdbDatastore(128, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	source, _ := testfixtures.StandardDatastoreWithCaveatedData(rawSource, require.New(t))
+
+	var base bytes.Buffer
+	_, err = Backup(ctx, source, "memory", true, &base)
+	require.NoError(t, err)
+
+	added := tuple.MustParse("document:newdoc#viewer@user:tom")
+	removed := tuple.MustParse("document:masterplan#owner@user:product_manager")
+	firstRev := writeUpdates(t, source, tuple.Create(added))
+	secondRev := writeUpdates(t, source, tuple.Delete(removed))
+
+	first, last, counts := backupChanges(t, source, base.Bytes())
+	require.Equal(t, secondRev, last)
+	require.Equal(t, uint64(2), counts.Checkpoints)
+	require.Equal(t, uint64(2), counts.Changes)
+
+	// Without further changes, a segment ends where it began. The head revision of memdb
+	// is the current time, so segments taken from it always end on the idle timeout.
+	empty, last, counts := backupChanges(t, source, first)
+	require.Equal(t, secondRev, last)
+	require.Equal(t, uint64(0), counts.Checkpoints)
+
+	thirdRev := writeUpdates(t, source, tuple.Touch(removed))
+	second, last, _ := backupChanges(t, source, empty)
+	require.Equal(t, thirdRev, last)
+
+	dir := t.TempDir()
+	locations := make([]string, 0, 4)
+	for index, contents := range [][]byte{base.Bytes(), first, empty, second} {
+		location := filepath.Join(dir, fmt.Sprintf("backup-%d", index))
+		require.NoError(t, os.WriteFile(location, contents, 0o600))
+		locations = append(locations, location)
+	}
+
+	restore := func(t *testing.T, target Target) (datastore.Datastore, Checkpoint) {
+		ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+		require.NoError(t, err)
+
+		_, _, checkpoint, err := RestoreTo(ctx, ds, locations, DefaultRestoreBatchSize, false, target)
+		require.NoError(t, err)
+		return ds, checkpoint
+	}
+
+	hasRelationship := func(t *testing.T, ds datastore.Datastore, rel *core.RelationTuple) bool {
+		_, _, relationships := readAll(t, ds)
+		for _, found := range relationships {
+			if found == tuple.String(rel) {
+				return true
+			}
+		}
+		return false
+	}
+
+	_, transactions, err := SummarizeTransactions(bytes.NewReader(first))
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+	require.Equal(t, firstRev, transactions[0].Revision)
+	require.Equal(t, uint64(1), transactions[0].Touches)
+	require.Equal(t, uint64(1), transactions[1].Deletes)
+
+	t.Run("all segments", func(t *testing.T) {
+		target, checkpoint := restore(t, Target{})
+		require.Equal(t, thirdRev, checkpoint.Revision)
+
+		_, _, expectedRelationships := readAll(t, source)
+		_, _, relationships := readAll(t, target)
+		require.Equal(t, expectedRelationships, relationships)
+	})
+
+	t.Run("to revision", func(t *testing.T) {
+		target, checkpoint := restore(t, Target{Revision: firstRev})
+		require.Equal(t, firstRev, checkpoint.Revision)
+		require.True(t, hasRelationship(t, target, added))
+		require.True(t, hasRelationship(t, target, removed))
+
+		target, checkpoint = restore(t, Target{Revision: secondRev})
+		require.Equal(t, secondRev, checkpoint.Revision)
+		require.True(t, hasRelationship(t, target, added))
+		require.False(t, hasRelationship(t, target, removed))
+	})
+
+	t.Run("to time", func(t *testing.T) {
+		// Restoring to just before the deletion recovers the deleted relationship.
+		deletedAt := transactions[1].CommittedAt
+		target, checkpoint := restore(t, Target{Time: deletedAt.Add(-time.Nanosecond)})
+		require.Equal(t, firstRev, checkpoint.Revision)
+		require.True(t, hasRelationship(t, target, added))
+		require.True(t, hasRelationship(t, target, removed))
+
+		target, checkpoint = restore(t, Target{Time: deletedAt})
+		require.Equal(t, secondRev, checkpoint.Revision)
+		require.False(t, hasRelationship(t, target, removed))
+
+		// Times past the last segment restore every change.
+		_, checkpoint = restore(t, Target{Time: time.Now().Add(time.Hour)})
+		require.Equal(t, thirdRev, checkpoint.Revision)
+	})
+
+	t.Run("invalid targets", func(t *testing.T) {
+		ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+		require.NoError(t, err)
+		_, _, _, err = RestoreTo(ctx, ds, locations, DefaultRestoreBatchSize, false, Target{Revision: "1"})
+		require.ErrorIs(t, err, ErrTargetNotFound)
+
+		ds, err = memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+		require.NoError(t, err)
+		_, _, _, err = RestoreTo(ctx, ds, locations, DefaultRestoreBatchSize, false, Target{Time: time.Unix(0, 0)})
+		require.ErrorIs(t, err, ErrTargetBeforeBackup)
+	})
+
+	t.Run("broken chain", func(t *testing.T) {
+		target, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+		require.NoError(t, err)
+		metadata, _, err := Restore(ctx, target, bytes.NewReader(base.Bytes()), DefaultRestoreBatchSize, false)
+		require.NoError(t, err)
+
+		_, err = ApplyChanges(ctx, target, bytes.NewReader(second), metadata.Revision, Target{})
+		require.ErrorIs(t, err, ErrBrokenChain)
+	})
+
+	t.Run("segments are not snapshots", func(t *testing.T) {
+		target, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+		require.NoError(t, err)
+		_, _, err = Restore(ctx, target, bytes.NewReader(first), DefaultRestoreBatchSize, false)
+		require.ErrorContains(t, err, "change segment rather than a snapshot")
+	})
+
+	t.Run("truncated segment", func(t *testing.T) {
+		_, _, err := LastRevision(bytes.NewReader(first[:len(first)-5]))
+		require.ErrorIs(t, err, ErrTruncated)
+	})
+}
+
+func TestChangeSegmentRequiresRevision(t *testing.T) {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	ds, _ := testfixtures.StandardDatastoreWithCaveatedData(rawDS, require.New(t))
+
+	var buf bytes.Buffer
+	_, err = Backup(context.Background(), ds, "memory", false, &buf)
+	require.NoError(t, err)
+
+	_, _, err = LastRevision(bytes.NewReader(buf.Bytes()))
+	require.ErrorIs(t, err, ErrNoRevision)
+}
+
+// noCommitTimes hides the commit times reported by its delegate, as MySQL reports none.
+type noCommitTimes struct {
+	datastore.Datastore
+}
+
+func TestChangeSegmentWithoutCommitTimes(t *testing.T) {
+	ctx := context.Background()
+	rawSource, err := memdb.NewMemdbDatastore(128, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	source, _ := testfixtures.StandardDatastoreWithCaveatedData(rawSource, require.New(t))
+
+	var base bytes.Buffer
+	_, err = Backup(ctx, source, "memory", true, &base)
+	require.NoError(t, err)
+
+	rev := writeUpdates(t, source, tuple.Create(tuple.MustParse("document:newdoc#viewer@user:tom")))
+	segment, _, _ := backupChanges(t, noCommitTimes{source}, base.Bytes())
+
+	metadata, transactions, err := SummarizeTransactions(bytes.NewReader(segment))
+	require.NoError(t, err)
+	require.False(t, metadata.CommitTimes)
+	require.Len(t, transactions, 1)
+	require.True(t, transactions[0].CommittedAt.IsZero())
+
+	dir := t.TempDir()
+	locations := make([]string, 0, 2)
+	for index, contents := range [][]byte{base.Bytes(), segment} {
+		location := filepath.Join(dir, fmt.Sprintf("backup-%d", index))
+		require.NoError(t, os.WriteFile(location, contents, 0o600))
+		locations = append(locations, location)
+	}
+
+	// Restores to a time are rejected before the snapshot is restored.
+	target, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	_, _, _, err = RestoreTo(ctx, target, locations, DefaultRestoreBatchSize, false, Target{Time: time.Now()})
+	require.ErrorIs(t, err, ErrNoCommitTimes)
+	_, _, relationships := readAll(t, target)
+	require.Empty(t, relationships)
+
+	_, _, checkpoint, err := RestoreTo(ctx, target, locations, DefaultRestoreBatchSize, false, Target{Revision: rev})
+	require.NoError(t, err)
+	require.Equal(t, rev, checkpoint.Revision)
+}
@@ -0,0 +1,207 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// ErrVerificationFailed is returned when the contents of a copy do not match its source.
+var ErrVerificationFailed = errors.New("copy does not match source")
+
+// Copy copies the schema, caveats and relationships found at the head revision of the
+// source datastore into the target, which may use a different engine. The snapshot is
+// streamed through the backup format and written as Restore would write it, so the same
+// batching and overwrite semantics apply. The revision of the source snapshot is
+// returned, so that the copy can be verified against it.
+func Copy(ctx context.Context, source, target datastore.Datastore, engine string, batchSize int, overwrite bool) (datastore.Revision, Counts, error) {
+	rev, err := source.HeadRevision(ctx)
+	if err != nil {
+		return nil, Counts{}, fmt.Errorf("unable to determine source head revision: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	g, gCtx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		metadata := Metadata{Engine: engine, CreatedAt: time.Now().UTC(), Revision: rev.String()}
+		_, err := backupAt(gCtx, source.SnapshotReader(rev), metadata, pw)
+		pw.CloseWithError(err)
+		return err
+	})
+
+	var counts Counts
+	g.Go(func() error {
+		var err error
+		_, counts, err = Restore(gCtx, target, pr, batchSize, overwrite)
+		// Unblock the reading side if the target failed first.
+		pr.CloseWithError(err)
+		return err
+	})
+
+	if err := g.Wait(); err != nil {
+		return nil, Counts{}, err
+	}
+	return rev, counts, nil
+}
+
+// Summary is an order-independent digest of the contents of a datastore at a revision.
+// Checksums are sums of the hashes of each object, so they do not depend on the order in
+// which an engine returns objects.
+type Summary struct {
+	Counts
+
+	// SchemaChecksum covers every caveat and namespace definition.
+	SchemaChecksum uint64
+
+	// Relationships summarizes the relationships of each namespace.
+	Relationships map[string]RelationshipSummary
+}
+
+// RelationshipSummary is the number and checksum of the relationships of a namespace.
+type RelationshipSummary struct {
+	Count    uint64
+	Checksum uint64
+}
+
+// Summarize computes the summary of the contents of the reader.
+func Summarize(ctx context.Context, reader datastore.Reader) (Summary, error) {
+	summary := Summary{Relationships: make(map[string]RelationshipSummary)}
+
+	caveats, err := reader.ListCaveats(ctx)
+	if err != nil {
+		return Summary{}, fmt.Errorf("unable to read caveats: %w", err)
+	}
+	for _, caveat := range caveats {
+		sum, err := hashMessage(caveat)
+		if err != nil {
+			return Summary{}, err
+		}
+		summary.Caveats++
+		summary.SchemaChecksum += sum
+	}
+
+	namespaces, err := reader.ListNamespaces(ctx)
+	if err != nil {
+		return Summary{}, fmt.Errorf("unable to read namespaces: %w", err)
+	}
+	for _, ns := range namespaces {
+		sum, err := hashMessage(ns)
+		if err != nil {
+			return Summary{}, err
+		}
+		summary.Namespaces++
+		summary.SchemaChecksum += sum
+
+		rels, err := summarizeRelationships(ctx, reader, ns.Name)
+		if err != nil {
+			return Summary{}, fmt.Errorf("unable to read relationships for `%s`: %w", ns.Name, err)
+		}
+		summary.Relationships[ns.Name] = rels
+		summary.Counts.Relationships += rels.Count
+	}
+	return summary, nil
+}
+
+func summarizeRelationships(ctx context.Context, reader datastore.Reader, namespace string) (RelationshipSummary, error) {
+	iter, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: namespace})
+	if err != nil {
+		return RelationshipSummary{}, err
+	}
+	defer iter.Close()
+
+	var summary RelationshipSummary
+	for rel := iter.Next(); rel != nil; rel = iter.Next() {
+		sum, err := hashRelationship(rel)
+		if err != nil {
+			return RelationshipSummary{}, err
+		}
+		summary.Count++
+		summary.Checksum += sum
+	}
+	return summary, iter.Err()
+}
+
+func hashMessage(msg proto.Message) (uint64, error) {
+	encoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return 0, err
+	}
+	return xxhash.Sum64(encoded), nil
+}
+
+// hashRelationship hashes the fields of a relationship which are copied, ignoring any
+// engine-specific representation of it.
+func hashRelationship(rel *core.RelationTuple) (uint64, error) {
+	digest := xxhash.New()
+	_, _ = digest.WriteString(tuple.String(rel))
+	if rel.Caveat != nil {
+		_, _ = digest.WriteString("[" + rel.Caveat.CaveatName + "]")
+		if rel.Caveat.Context != nil {
+			encoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(rel.Caveat.Context)
+			if err != nil {
+				return 0, err
+			}
+			_, _ = digest.Write(encoded)
+		}
+	}
+	return digest.Sum64(), nil
+}
+
+// Verify compares the summaries of the source and the target, returning an error
+// wrapping ErrVerificationFailed which describes each difference found.
+func Verify(ctx context.Context, source, target datastore.Reader) error {
+	expected, err := Summarize(ctx, source)
+	if err != nil {
+		return fmt.Errorf("unable to summarize source: %w", err)
+	}
+	actual, err := Summarize(ctx, target)
+	if err != nil {
+		return fmt.Errorf("unable to summarize target: %w", err)
+	}
+
+	var differences []string
+	if expected.Caveats != actual.Caveats || expected.Namespaces != actual.Namespaces {
+		differences = append(differences, fmt.Sprintf("expected %d caveats and %d namespaces, found %d and %d",
+			expected.Caveats, expected.Namespaces, actual.Caveats, actual.Namespaces))
+	} else if expected.SchemaChecksum != actual.SchemaChecksum {
+		differences = append(differences, "schema checksum mismatch")
+	}
+
+	names := make([]string, 0, len(expected.Relationships))
+	for name := range expected.Relationships {
+		names = append(names, name)
+	}
+	for name := range actual.Relationships {
+		if _, ok := expected.Relationships[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		want, got := expected.Relationships[name], actual.Relationships[name]
+		switch {
+		case want.Count != got.Count:
+			differences = append(differences, fmt.Sprintf("expected %d relationships for `%s`, found %d", want.Count, name, got.Count))
+		case want.Checksum != got.Checksum:
+			differences = append(differences, fmt.Sprintf("relationship checksum mismatch for `%s`", name))
+		}
+	}
+
+	if len(differences) > 0 {
+		return fmt.Errorf("%w: %s", ErrVerificationFailed, strings.Join(differences, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package backup
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	rawSource, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	source, _ := testfixtures.StandardDatastoreWithCaveatedData(rawSource, require.New(t))
+
+	target, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	rev, counts, err := Copy(ctx, source, target, "memory", 7, false)
+	require.NoError(t, err)
+
+	expectedCaveats, expectedNamespaces, expectedRelationships := readAll(t, source)
+	caveats, namespaces, relationships := readAll(t, target)
+	require.Equal(t, expectedCaveats, caveats)
+	require.Equal(t, expectedNamespaces, namespaces)
+	require.Equal(t, expectedRelationships, relationships)
+	require.Equal(t, uint64(len(expectedRelationships)), counts.Relationships)
+
+	targetRev, err := target.HeadRevision(ctx)
+	require.NoError(t, err)
+	require.NoError(t, Verify(ctx, source.SnapshotReader(rev), target.SnapshotReader(targetRev)))
+
+	// Copying again into the now populated target requires overwriting.
+	_, _, err = Copy(ctx, source, target, "memory", 7, false)
+	require.ErrorIs(t, err, ErrDatastoreNotEmpty)
+
+	// A relationship missing from the target is found by verification.
+	targetRev, err = target.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{
+			tuple.Delete(tuple.MustParse("document:companyplan#parent@folder:company")),
+		})
+	})
+	require.NoError(t, err)
+	err = Verify(ctx, source.SnapshotReader(rev), target.SnapshotReader(targetRev))
+	require.ErrorIs(t, err, ErrVerificationFailed)
+	require.ErrorContains(t, err, "for `document`")
+}
@@ -0,0 +1,405 @@
+package backup
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// FormatVersion is the version of the backup format written by this package.
+const FormatVersion uint16 = 1
+
+// magic prefixes every backup, allowing unrelated files to be rejected up front.
+var magic = [8]byte{'S', 'P', 'D', 'B', 'B', 'K', 'U', 'P'}
+
+// maxRecordSize bounds the size of a single record, protecting restores from corrupt
+// length prefixes.
+const maxRecordSize = 64 << 20
+
+type recordKind byte
+
+const (
+	recordMetadata     recordKind = 1
+	recordCaveat       recordKind = 2
+	recordNamespace    recordKind = 3
+	recordRelationship recordKind = 4
+	recordChange       recordKind = 5
+	recordCheckpoint   recordKind = 6
+	recordTrailer      recordKind = 0xff
+)
+
+// ErrTruncated is returned when a backup ends before its trailer, or the trailer does
+// not match the records which preceded it.
+var ErrTruncated = errors.New("backup is truncated or corrupt")
+
+// Metadata describes the snapshot contained in a backup.
+type Metadata struct {
+	// Engine is the datastore engine from which the backup was taken.
+	Engine string `json:"engine"`
+
+	// CreatedAt is the time at which the backup was started.
+	CreatedAt time.Time `json:"created_at"`
+
+	// Revision is the engine-specific revision at which the snapshot was read, if it was
+	// requested to be recorded. It is informational only, as revisions are not portable
+	// across datastores.
+	Revision string `json:"revision,omitempty"`
+
+	// After is set on change segments, which hold the transactions committed after this
+	// revision of the source datastore rather than a snapshot. It is the revision of the
+	// snapshot or the last checkpoint of the segment which the segment follows.
+	After string `json:"after,omitempty"`
+
+	// CommitTimes is set on change segments whose checkpoints record the commit times of
+	// their transactions, which restores to a time require. It is unset if the source
+	// datastore does not report commit times, as for MySQL, whose revisions are
+	// transaction IDs.
+	CommitTimes bool `json:"commit_times,omitempty"`
+}
+
+// IsChangeSegment returns whether the backup is a change segment rather than a snapshot.
+func (m Metadata) IsChangeSegment() bool {
+	return m.After != ""
+}
+
+// Counts is the number of each kind of object found in a backup.
+type Counts struct {
+	Caveats       uint64
+	Namespaces    uint64
+	Relationships uint64
+
+	// Changes and Checkpoints are only found in change segments, and count the
+	// relationship updates and the transactions to which they belong.
+	Changes     uint64
+	Checkpoints uint64
+}
+
+// Checkpoint marks the end of a transaction in a change segment.
+type Checkpoint struct {
+	// Revision is the revision of the transaction in the source datastore.
+	Revision string
+
+	// CommittedAt is the time at which the transaction was committed, or the zero time if
+	// the change segment does not record commit times.
+	CommittedAt time.Time
+}
+
+// Encoder writes the records of a backup, in the order metadata, caveats, namespaces
+// and relationships, followed by a trailer written by Close. Change segments instead
+// hold transactions, each written as its changes followed by a checkpoint.
+type Encoder struct {
+	w       *bufio.Writer
+	counts  Counts
+	buf     []byte
+	segment bool
+}
+
+// NewEncoder writes the header and metadata of a backup to the writer and returns an
+// encoder for the remaining records.
+func NewEncoder(w io.Writer, metadata Metadata) (*Encoder, error) {
+	e := &Encoder{w: bufio.NewWriter(w), segment: metadata.IsChangeSegment()}
+
+	header := make([]byte, len(magic)+2)
+	copy(header, magic[:])
+	binary.BigEndian.PutUint16(header[len(magic):], FormatVersion)
+	if _, err := e.w.Write(header); err != nil {
+		return nil, err
+	}
+
+	encodedMetadata, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.writeRecord(recordMetadata, encodedMetadata); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// WriteCaveat appends a caveat definition to the backup.
+func (e *Encoder) WriteCaveat(caveat *core.CaveatDefinition) error {
+	e.counts.Caveats++
+	return e.writeMessage(recordCaveat, caveat)
+}
+
+// WriteNamespace appends a namespace definition to the backup.
+func (e *Encoder) WriteNamespace(ns *core.NamespaceDefinition) error {
+	e.counts.Namespaces++
+	return e.writeMessage(recordNamespace, ns)
+}
+
+// WriteRelationship appends a relationship to the backup.
+func (e *Encoder) WriteRelationship(rel *core.RelationTuple) error {
+	e.counts.Relationships++
+	return e.writeMessage(recordRelationship, rel)
+}
+
+// WriteTransaction appends the changes of a transaction to a change segment, followed by
+// its checkpoint.
+func (e *Encoder) WriteTransaction(checkpoint Checkpoint, changes []*core.RelationTupleUpdate) error {
+	if !e.segment {
+		return errors.New("transactions can only be written to change segments")
+	}
+	for _, change := range changes {
+		e.counts.Changes++
+		if err := e.writeMessage(recordChange, change); err != nil {
+			return err
+		}
+	}
+	e.counts.Checkpoints++
+	var committedAt int64
+	if !checkpoint.CommittedAt.IsZero() {
+		committedAt = checkpoint.CommittedAt.UnixNano()
+	}
+	payload := binary.AppendVarint(nil, committedAt)
+	return e.writeRecord(recordCheckpoint, append(payload, checkpoint.Revision...))
+}
+
+// Counts returns the number of objects written so far.
+func (e *Encoder) Counts() Counts {
+	return e.counts
+}
+
+// Close writes the trailer, which records the number of objects in the backup, and
+// flushes any buffered data. It does not close the underlying writer.
+func (e *Encoder) Close() error {
+	trailer := binary.AppendUvarint(nil, e.counts.Caveats)
+	trailer = binary.AppendUvarint(trailer, e.counts.Namespaces)
+	trailer = binary.AppendUvarint(trailer, e.counts.Relationships)
+	if e.segment {
+		trailer = binary.AppendUvarint(trailer, e.counts.Changes)
+		trailer = binary.AppendUvarint(trailer, e.counts.Checkpoints)
+	}
+	if err := e.writeRecord(recordTrailer, trailer); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (e *Encoder) writeMessage(kind recordKind, msg proto.Message) error {
+	var err error
+	e.buf, err = proto.MarshalOptions{Deterministic: true}.MarshalAppend(e.buf[:0], msg)
+	if err != nil {
+		return err
+	}
+	return e.writeRecord(kind, e.buf)
+}
+
+func (e *Encoder) writeRecord(kind recordKind, payload []byte) error {
+	if err := e.w.WriteByte(byte(kind)); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(binary.AppendUvarint(nil, uint64(len(payload)))); err != nil {
+		return err
+	}
+	_, err := e.w.Write(payload)
+	return err
+}
+
+// Decoder reads the records of a backup written by an Encoder.
+type Decoder struct {
+	r          *bufio.Reader
+	metadata   Metadata
+	counts     Counts
+	done       bool
+	checkpoint *Checkpoint
+}
+
+// NewDecoder reads and validates the header and metadata of a backup.
+func NewDecoder(r io.Reader) (*Decoder, error) {
+	d := &Decoder{r: bufio.NewReader(r)}
+
+	header := make([]byte, len(magic)+2)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		return nil, fmt.Errorf("unable to read backup header: %w", err)
+	}
+	if string(header[:len(magic)]) != string(magic[:]) {
+		return nil, errors.New("not a SpiceDB backup")
+	}
+	if version := binary.BigEndian.Uint16(header[len(magic):]); version != FormatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d, expected %d", version, FormatVersion)
+	}
+
+	kind, payload, err := d.readRecord()
+	if err != nil {
+		return nil, err
+	}
+	if kind != recordMetadata {
+		return nil, fmt.Errorf("%w: missing metadata", ErrTruncated)
+	}
+	if err := json.Unmarshal(payload, &d.metadata); err != nil {
+		return nil, fmt.Errorf("invalid backup metadata: %w", err)
+	}
+	return d, nil
+}
+
+// Metadata returns the metadata of the backup.
+func (d *Decoder) Metadata() Metadata {
+	return d.metadata
+}
+
+// Counts returns the number of objects read so far.
+func (d *Decoder) Counts() Counts {
+	return d.counts
+}
+
+// Checkpoint returns the checkpoint of the last transaction read from a change segment,
+// or one at the revision which the segment follows, without a time, if none have been
+// read.
+func (d *Decoder) Checkpoint() Checkpoint {
+	if d.checkpoint == nil {
+		return Checkpoint{Revision: d.metadata.After}
+	}
+	return *d.checkpoint
+}
+
+// Next returns the next object in a snapshot backup, which is one of
+// *core.CaveatDefinition, *core.NamespaceDefinition or *core.RelationTuple, or io.EOF once
+// the trailer has been read and verified.
+func (d *Decoder) Next() (proto.Message, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+	if d.metadata.IsChangeSegment() {
+		return nil, errors.New("backup is a change segment rather than a snapshot")
+	}
+
+	kind, payload, err := d.readRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	var msg proto.Message
+	switch kind {
+	case recordCaveat:
+		d.counts.Caveats++
+		msg = &core.CaveatDefinition{}
+	case recordNamespace:
+		d.counts.Namespaces++
+		msg = &core.NamespaceDefinition{}
+	case recordRelationship:
+		d.counts.Relationships++
+		msg = &core.RelationTuple{}
+	case recordTrailer:
+		if err := d.verifyTrailer(payload); err != nil {
+			return nil, err
+		}
+		d.done = true
+		return nil, io.EOF
+	default:
+		return nil, fmt.Errorf("unexpected backup record kind %d", kind)
+	}
+
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("invalid backup record: %w", err)
+	}
+	return msg, nil
+}
+
+// NextTransaction returns the checkpoint and changes of the next transaction in a change
+// segment, or io.EOF once the trailer has been read and verified.
+func (d *Decoder) NextTransaction() (Checkpoint, []*core.RelationTupleUpdate, error) {
+	if d.done {
+		return Checkpoint{}, nil, io.EOF
+	}
+	if !d.metadata.IsChangeSegment() {
+		return Checkpoint{}, nil, errors.New("backup is a snapshot rather than a change segment")
+	}
+
+	var changes []*core.RelationTupleUpdate
+	for {
+		kind, payload, err := d.readRecord()
+		if err != nil {
+			return Checkpoint{}, nil, err
+		}
+
+		switch kind {
+		case recordChange:
+			d.counts.Changes++
+			change := &core.RelationTupleUpdate{}
+			if err := proto.Unmarshal(payload, change); err != nil {
+				return Checkpoint{}, nil, fmt.Errorf("invalid backup record: %w", err)
+			}
+			changes = append(changes, change)
+
+		case recordCheckpoint:
+			d.counts.Checkpoints++
+			nanos, n := binary.Varint(payload)
+			if n <= 0 {
+				return Checkpoint{}, nil, errors.New("invalid backup checkpoint")
+			}
+			d.checkpoint = &Checkpoint{Revision: string(payload[n:])}
+			if nanos != 0 {
+				d.checkpoint.CommittedAt = time.Unix(0, nanos).UTC()
+			}
+			return *d.checkpoint, changes, nil
+
+		case recordTrailer:
+			if len(changes) > 0 {
+				return Checkpoint{}, nil, fmt.Errorf("%w: changes found after the last checkpoint", ErrTruncated)
+			}
+			if err := d.verifyTrailer(payload); err != nil {
+				return Checkpoint{}, nil, err
+			}
+			d.done = true
+			return Checkpoint{}, nil, io.EOF
+
+		default:
+			return Checkpoint{}, nil, fmt.Errorf("unexpected backup record kind %d", kind)
+		}
+	}
+}
+
+func (d *Decoder) verifyTrailer(payload []byte) error {
+	// Snapshots record three counts, and change segments two more.
+	expected := make([]uint64, 0, 5)
+	for len(payload) > 0 && len(expected) < cap(expected) {
+		value, n := binary.Uvarint(payload)
+		if n <= 0 {
+			return fmt.Errorf("%w: invalid trailer", ErrTruncated)
+		}
+		expected = append(expected, value)
+		payload = payload[n:]
+	}
+	if len(expected) != 3 && len(expected) != 5 {
+		return fmt.Errorf("%w: invalid trailer", ErrTruncated)
+	}
+	expected = append(expected, 0, 0)
+
+	if (Counts{expected[0], expected[1], expected[2], expected[3], expected[4]}) != d.counts {
+		return fmt.Errorf("%w: trailer does not match contents", ErrTruncated)
+	}
+	return nil
+}
+
+func (d *Decoder) readRecord() (recordKind, []byte, error) {
+	kind, err := d.r.ReadByte()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil, ErrTruncated
+		}
+		return 0, nil, err
+	}
+
+	length, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: %s", ErrTruncated, err)
+	}
+	if length > maxRecordSize {
+		return 0, nil, fmt.Errorf("backup record of %d bytes exceeds maximum size", length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(d.r, payload); err != nil {
+		return 0, nil, fmt.Errorf("%w: %s", ErrTruncated, err)
+	}
+	return recordKind(kind), payload, nil
+}
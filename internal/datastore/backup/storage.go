@@ -0,0 +1,179 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// StdioLocation is the location which refers to stdout for backups and stdin for restores.
+const StdioLocation = "-"
+
+// transferStallTimeout is the time after which an upload or download of a backup which
+// has stopped progressing is abandoned. Transfers are otherwise unbounded in time, as
+// backups may be arbitrarily large.
+var transferStallTimeout = 2 * time.Minute
+
+// httpClient bounds the time spent connecting to the locations of backups and waiting for
+// their responses.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: time.Minute,
+		ExpectContinueTimeout: time.Second,
+		IdleConnTimeout:       90 * time.Second,
+	},
+}
+
+// Create opens the location to which a backup will be written. Locations are either a
+// local file path, StdioLocation, or an http(s) URL such as a presigned object storage
+// URL, to which the backup is uploaded with a PUT once the returned writer is closed.
+func Create(ctx context.Context, location string) (io.WriteCloser, error) {
+	switch {
+	case location == StdioLocation:
+		return nopWriteCloser{os.Stdout}, nil
+
+	case isHTTPLocation(location):
+		// Object stores require the length of an upload up front, so the backup is
+		// spooled to a temporary file before being sent.
+		spool, err := os.CreateTemp("", "spicedb-backup-*")
+		if err != nil {
+			return nil, fmt.Errorf("unable to create temporary file for upload: %w", err)
+		}
+		return &httpUploader{ctx: ctx, url: location, spool: spool}, nil
+
+	default:
+		return os.Create(location)
+	}
+}
+
+// Open opens the location from which a backup will be read, which takes the same forms
+// as those accepted by Create. http(s) URLs are fetched with a GET, which is abandoned if
+// the context is canceled or no data is received for transferStallTimeout.
+func Open(ctx context.Context, location string) (io.ReadCloser, error) {
+	switch {
+	case location == StdioLocation:
+		return io.NopCloser(os.Stdin), nil
+
+	case isHTTPLocation(location):
+		ctx, cancel := context.WithCancel(ctx)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("unable to download backup: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			cancel()
+			return nil, fmt.Errorf("unable to download backup: unexpected status %s", resp.Status)
+		}
+		return &stallingReader{body: resp.Body, cancel: cancel}, nil
+
+	default:
+		return os.Open(location)
+	}
+}
+
+func isHTTPLocation(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+type httpUploader struct {
+	ctx   context.Context
+	url   string
+	spool *os.File
+}
+
+func (u *httpUploader) Write(p []byte) (int, error) {
+	return u.spool.Write(p)
+}
+
+func (u *httpUploader) Close() error {
+	defer os.Remove(u.spool.Name())
+	defer u.spool.Close()
+
+	size, err := u.spool.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := u.spool.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	// The spool is read as the upload is sent, so an upload which is not read from for
+	// transferStallTimeout has stalled.
+	ctx, cancel := context.WithCancel(u.ctx)
+	defer cancel()
+	stall := time.AfterFunc(transferStallTimeout, cancel)
+	defer stall.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.url, progressReader{u.spool, stall})
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to upload backup: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unable to upload backup: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// progressReader resets the stall timer of an upload as its body is read.
+type progressReader struct {
+	r     io.Reader
+	stall *time.Timer
+}
+
+func (r progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.stall.Reset(transferStallTimeout)
+	return n, err
+}
+
+// stallingReader reads the body of a download, canceling it when a read waits for data
+// for transferStallTimeout. Time spent by the caller between reads is not counted.
+type stallingReader struct {
+	body   io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *stallingReader) Read(p []byte) (int, error) {
+	stall := time.AfterFunc(transferStallTimeout, r.cancel)
+	n, err := r.body.Read(p)
+	if !stall.Stop() && err != nil {
+		return n, fmt.Errorf("unable to download backup: no data received for %s: %w", transferStallTimeout, err)
+	}
+	return n, err
+}
+
+func (r *stallingReader) Close() error {
+	defer r.cancel()
+	return r.body.Close()
+}
@@ -0,0 +1,82 @@
+package backup
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStalledDownloadIsAbandoned(t *testing.T) {
+	defaultTimeout := transferStallTimeout
+	transferStallTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { transferStallTimeout = defaultTimeout })
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	r, err := Open(context.Background(), server.URL)
+	require.NoError(t, err)
+	defer r.Close()
+
+	done := make(chan error)
+	go func() {
+		_, err := io.ReadAll(r)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		require.ErrorContains(t, err, "no data received")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "stalled download was not abandoned")
+	}
+}
+
+func TestStalledUploadIsAbandoned(t *testing.T) {
+	defaultTimeout := transferStallTimeout
+	transferStallTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { transferStallTimeout = defaultTimeout })
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	w, err := Create(context.Background(), server.URL)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("backup"))
+	require.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- w.Close()
+	}()
+
+	select {
+	case err := <-done:
+		require.ErrorContains(t, err, "unable to upload backup")
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "stalled upload was not abandoned")
+	}
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// BulkLoadBatchSize is the number of relationships created by each call to
+// WriteRelationships when bulk loading into a transaction which is not a BulkLoader.
+const BulkLoadBatchSize = 1000
+
+// BulkRelationshipSource provides the relationships to be bulk loaded.
+type BulkRelationshipSource interface {
+	// Next returns the next relationship, or nil once every relationship has been
+	// returned.
+	Next(ctx context.Context) (*core.RelationTuple, error)
+}
+
+// BulkLoader is implemented by the read-write transactions of datastores which can create
+// large numbers of relationships much faster than through WriteRelationships, such as by
+// streaming them to the database with a single command.
+type BulkLoader interface {
+	// BulkLoad creates the relationships of the source, failing as a CREATE would if any of
+	// them already exists, and returns the number created.
+	BulkLoad(ctx context.Context, source BulkRelationshipSource) (uint64, error)
+}
+
+// BulkLoad creates the relationships of the source in the transaction, with the first
+// BulkLoader found in its chain of proxies, if any, or otherwise by creating them in
+// batches with WriteRelationships. It returns the number of relationships created.
+func BulkLoad(ctx context.Context, rwt datastore.ReadWriteTransaction, source BulkRelationshipSource) (uint64, error) {
+	if loader, ok := datastore.UnwrapTransactionAs[BulkLoader](rwt); ok {
+		return loader.BulkLoad(ctx, source)
+	}
+
+	var loaded uint64
+	batch := make([]*core.RelationTupleUpdate, 0, BulkLoadBatchSize)
+	for {
+		tpl, err := source.Next(ctx)
+		if err != nil {
+			return loaded, err
+		}
+
+		if tpl != nil {
+			batch = append(batch, &core.RelationTupleUpdate{Operation: core.RelationTupleUpdate_CREATE, Tuple: tpl})
+			if len(batch) < BulkLoadBatchSize {
+				continue
+			}
+		}
+
+		if len(batch) > 0 {
+			if err := rwt.WriteRelationships(ctx, batch); err != nil {
+				return loaded, err
+			}
+			loaded += uint64(len(batch))
+			batch = make([]*core.RelationTupleUpdate, 0, BulkLoadBatchSize)
+		}
+
+		if tpl == nil {
+			return loaded, nil
+		}
+	}
+}
+
+// SliceRelationshipSource is a BulkRelationshipSource of the relationships of a slice.
+type SliceRelationshipSource []*core.RelationTuple
+
+func (s *SliceRelationshipSource) Next(context.Context) (*core.RelationTuple, error) {
+	if len(*s) == 0 {
+		return nil, nil
+	}
+
+	tpl := (*s)[0]
+	*s = (*s)[1:]
+	return tpl, nil
+}
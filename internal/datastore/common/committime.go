@@ -0,0 +1,36 @@
+package common
+
+import (
+	"time"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+)
+
+// CommitTimeReporter is implemented by datastores whose revisions record the times at
+// which their transactions were committed.
+type CommitTimeReporter interface {
+	// RevisionCommitTime returns the time at which the transaction of the revision was
+	// committed, or false if the revision does not record it.
+	RevisionCommitTime(rev datastore.Revision) (time.Time, bool)
+}
+
+// RevisionCommitTime returns the time at which the transaction of a revision of the
+// datastore was committed, or false if the datastore does not report it.
+func RevisionCommitTime(ds datastore.Datastore, rev datastore.Revision) (time.Time, bool) {
+	reporter, ok := datastore.UnwrapAs[CommitTimeReporter](ds)
+	if !ok {
+		return time.Time{}, false
+	}
+	return reporter.RevisionCommitTime(rev)
+}
+
+// DecimalCommitTime returns the commit time of a decimal revision whose integer part is
+// the Unix time of its commit in nanoseconds, such as a hybrid logical clock.
+func DecimalCommitTime(rev datastore.Revision) (time.Time, bool) {
+	decimalRev, ok := rev.(revision.Decimal)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, decimalRev.IntPart()).UTC(), true
+}
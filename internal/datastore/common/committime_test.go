@@ -0,0 +1,29 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+)
+
+type txIDRevision struct {
+	datastore.Revision
+}
+
+func TestDecimalCommitTime(t *testing.T) {
+	committedAt := time.Date(2022, 11, 3, 10, 15, 0, 123456789, time.UTC)
+
+	// CockroachDB HLC revisions carry the logical clock as the fractional part.
+	hlc := revision.NewFromDecimal(decimal.NewFromInt(committedAt.UnixNano()).Add(decimal.RequireFromString("0.0000000003")))
+	found, ok := DecimalCommitTime(hlc)
+	require.True(t, ok)
+	require.True(t, committedAt.Equal(found))
+
+	_, ok = DecimalCommitTime(txIDRevision{})
+	require.False(t, ok)
+}
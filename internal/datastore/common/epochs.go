@@ -0,0 +1,35 @@
+package common
+
+import (
+	"context"
+	"errors"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// ErrEpochsUnsupported is returned when bumping the epoch of a datastore which cannot store
+// one.
+var ErrEpochsUnsupported = errors.New("datastore does not support storing epochs")
+
+// EpochStore is implemented by datastores which persist an epoch of their contents, which
+// is bumped by every restore into them so that zedtokens minted before a restore can be
+// rejected after it.
+type EpochStore interface {
+	// Epoch returns the current epoch of the datastore, which is zero until it is first
+	// restored. It is read from the datastore on every call, as the datastore may have
+	// been restored by another process.
+	Epoch(ctx context.Context) (uint64, error)
+
+	// BumpEpoch increments the epoch of the datastore, returning the new epoch.
+	BumpEpoch(ctx context.Context) (uint64, error)
+}
+
+// DatastoreEpoch returns the current epoch of the datastore, or zero if it does not store
+// one.
+func DatastoreEpoch(ctx context.Context, ds datastore.Datastore) (uint64, error) {
+	store, ok := datastore.UnwrapAs[EpochStore](ds)
+	if !ok {
+		return 0, nil
+	}
+	return store.Epoch(ctx)
+}
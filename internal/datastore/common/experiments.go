@@ -0,0 +1,29 @@
+package common
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// NamespaceExperimentStore is implemented by datastores which can store the experimental
+// behaviors enabled per namespace.
+type NamespaceExperimentStore interface {
+	// SetNamespaceExperiment enables or disables the experiment for the resources of the
+	// namespace.
+	SetNamespaceExperiment(ctx context.Context, namespace, experiment string, enabled bool) error
+
+	// ListNamespaceExperiments lists the experiments enabled per namespace, ordered by
+	// namespace and experiment.
+	ListNamespaceExperiments(ctx context.Context) ([]datastore.NamespaceExperiment, error)
+}
+
+// NamespaceExperimentStoreOf returns the NamespaceExperimentStore of the datastore, or a
+// datastore.ErrNamespaceExperimentsUnsupported if it cannot store experiments.
+func NamespaceExperimentStoreOf(ds datastore.Datastore) (NamespaceExperimentStore, error) {
+	store, ok := datastore.UnwrapAs[NamespaceExperimentStore](ds)
+	if !ok {
+		return nil, datastore.NewNamespaceExperimentsUnsupportedErr("configured")
+	}
+	return store, nil
+}
@@ -2,6 +2,8 @@ package common
 
 import (
 	"context"
+	"errors"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -9,6 +11,7 @@ import (
 
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
 var (
@@ -59,6 +62,24 @@ func RegisterGCMetrics() error {
 	return nil
 }
 
+var gcIntervalOverride atomic.Int64
+
+// SetGarbageCollectionIntervalOverride overrides the interval configured for every
+// running garbage collection worker, taking effect after the pending pass. An interval
+// of zero removes the override.
+func SetGarbageCollectionIntervalOverride(interval time.Duration) {
+	gcIntervalOverride.Store(int64(interval))
+}
+
+// EffectiveGarbageCollectionInterval returns the interval in use by a worker configured
+// with the given interval.
+func EffectiveGarbageCollectionInterval(configured time.Duration) time.Duration {
+	if override := time.Duration(gcIntervalOverride.Load()); override > 0 {
+		return override
+	}
+	return configured
+}
+
 // GarbageCollector represents any datastore that supports external garbage
 // collection.
 type GarbageCollector interface {
@@ -68,6 +89,25 @@ type GarbageCollector interface {
 	DeleteBeforeTx(ctx context.Context, txID datastore.Revision) (DeletionCounts, error)
 }
 
+// RelationshipVersion is a version of a relationship which has been deleted, along with
+// the revisions at which it was written and deleted.
+type RelationshipVersion struct {
+	Relationship    *core.RelationTuple
+	CreatedRevision datastore.Revision
+	DeletedRevision datastore.Revision
+
+	// CreatedAt and DeletedAt are the times of the transactions which wrote and deleted
+	// the version, or zero if their records have already been garbage collected.
+	CreatedAt time.Time
+	DeletedAt time.Time
+}
+
+// RelationshipArchiver preserves relationship versions which garbage collection is about
+// to delete. Versions are only deleted once archived successfully.
+type RelationshipArchiver interface {
+	ArchiveRelationships(ctx context.Context, versions []RelationshipVersion) error
+}
+
 // DeletionCounts tracks the amount of deletions that occurred when calling
 // DeleteBeforeTx.
 type DeletionCounts struct {
@@ -97,7 +137,7 @@ func StartGarbageCollector(ctx context.Context, gc GarbageCollector, interval, w
 				Msg("shutting down datastore garbage collection worker")
 			return ctx.Err()
 
-		case <-time.After(interval):
+		case <-time.After(EffectiveGarbageCollectionInterval(interval)):
 			err := collect(gc, window, timeout)
 			if err != nil {
 				log.Ctx(ctx).Warn().Err(err).
@@ -111,15 +151,29 @@ func collect(gc GarbageCollector, window, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	_, err := CollectGarbage(ctx, gc, window)
+	if errors.Is(err, ErrNotReadyForGarbageCollection) {
+		log.Ctx(ctx).Warn().
+			Msg("datastore wasn't ready when attempting garbage collection")
+		return nil
+	}
+	return err
+}
+
+// ErrNotReadyForGarbageCollection is returned when collecting garbage of a datastore which
+// is not ready.
+var ErrNotReadyForGarbageCollection = errors.New("datastore is not ready for garbage collection")
+
+// CollectGarbage performs a single pass of garbage collection, deleting the data of the
+// revisions older than the window, and returns the counts of what was deleted.
+func CollectGarbage(ctx context.Context, gc GarbageCollector, window time.Duration) (DeletionCounts, error) {
 	// Before attempting anything, check if the datastore is ready.
 	ready, err := gc.IsReady(ctx)
 	if err != nil {
-		return err
+		return DeletionCounts{}, err
 	}
 	if !ready {
-		log.Ctx(ctx).Warn().
-			Msg("datastore wasn't ready when attempting garbage collection")
-		return nil
+		return DeletionCounts{}, ErrNotReadyForGarbageCollection
 	}
 
 	var (
@@ -145,14 +199,14 @@ func collect(gc GarbageCollector, window, timeout time.Duration) error {
 
 	now, err := gc.Now(ctx)
 	if err != nil {
-		return err
+		return collected, err
 	}
 
 	watermark, err = gc.TxIDBefore(ctx, now.Add(-1*window))
 	if err != nil {
-		return err
+		return collected, err
 	}
 
 	collected, err = gc.DeleteBeforeTx(ctx, watermark)
-	return err
+	return collected, err
 }
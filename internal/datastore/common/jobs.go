@@ -0,0 +1,68 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrJobsUnsupported is returned when running jobs over a datastore which cannot store
+	// them.
+	ErrJobsUnsupported = errors.New("datastore does not support storing jobs")
+
+	// ErrJobNotFound is returned when reading or canceling a job which is not stored.
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// JobState is the state of a job.
+type JobState string
+
+const (
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+	JobCanceled  JobState = "canceled"
+)
+
+// Job is a long-running operation whose state is stored in the datastore, so that it
+// can be followed and canceled from any server.
+type Job struct {
+	ID   string
+	Kind string
+
+	// Parameters and Result are opaque to the datastore.
+	Parameters []byte
+	Result     []byte
+
+	State    JobState
+	Progress uint64
+	Error    string
+
+	// CancelRequested is set once the job is requested to be canceled, and is observed
+	// by the server running it.
+	CancelRequested bool
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// JobStore is implemented by datastores which can store jobs.
+type JobStore interface {
+	// CreateJob stores a new job.
+	CreateJob(ctx context.Context, job Job) error
+
+	// UpdateJob stores the state, progress, result and error of a job, and its update
+	// time. Its cancellation request is left as-is.
+	UpdateJob(ctx context.Context, job Job) error
+
+	// RequestJobCancellation marks a job as requested to be canceled.
+	RequestJobCancellation(ctx context.Context, id string) error
+
+	// ReadJob returns a job, or ErrJobNotFound.
+	ReadJob(ctx context.Context, id string) (Job, error)
+
+	// ListJobs returns up to limit jobs, or all of them if limit is zero, most recently
+	// created first.
+	ListJobs(ctx context.Context, limit uint64) ([]Job, error)
+}
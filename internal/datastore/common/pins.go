@@ -0,0 +1,31 @@
+package common
+
+import (
+	"context"
+	"time"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// RevisionPinner is implemented by datastores which can retain revisions past their
+// garbage collection window.
+type RevisionPinner interface {
+	// PinRevision pins the revision under the given name until expiresAt, so that it
+	// remains valid, and is not garbage collected, past the garbage collection window.
+	// Pinning a name again replaces its pin.
+	PinRevision(ctx context.Context, name string, revision datastore.Revision, expiresAt time.Time) error
+
+	// ReleasePinnedRevision releases the pin with the given name, returning
+	// datastore.ErrPinnedRevisionNotFound if there is no such unexpired pin.
+	ReleasePinnedRevision(ctx context.Context, name string) error
+}
+
+// RevisionPinnerOf returns the RevisionPinner of the datastore, or a
+// datastore.ErrRevisionPinningUnsupported if it cannot pin revisions.
+func RevisionPinnerOf(ds datastore.Datastore) (RevisionPinner, error) {
+	pinner, ok := datastore.UnwrapAs[RevisionPinner](ds)
+	if !ok {
+		return nil, datastore.NewRevisionPinningUnsupportedErr("configured")
+	}
+	return pinner, nil
+}
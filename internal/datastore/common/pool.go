@@ -0,0 +1,184 @@
+package common
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+// PoolStats is a snapshot of the statistics of a connection pool, common to every SQL
+// datastore.
+type PoolStats struct {
+	// WaitCount is the cumulative number of acquires which had to wait for a connection.
+	WaitCount int64
+
+	// AcquireWaitDuration is the cumulative time spent waiting to acquire connections.
+	AcquireWaitDuration time.Duration
+
+	// InUseConns is the number of connections currently acquired.
+	InUseConns int64
+
+	// IdleConns is the number of connections currently idle in the pool.
+	IdleConns int64
+
+	// MaxConns is the current maximum size of the pool.
+	MaxConns int64
+
+	// ClosedConns is the cumulative number of connections closed by the pool, such as
+	// those recycled after reaching their maximum lifetime or idle time.
+	ClosedConns int64
+}
+
+// PoolStatsFunc returns the current statistics of a connection pool.
+type PoolStatsFunc func() PoolStats
+
+var poolLabels = []string{"db_name", "pool"}
+
+// poolCollector exports the statistics of a connection pool as metrics.
+type poolCollector struct {
+	stats       PoolStatsFunc
+	labelValues []string
+
+	waitCount           *prometheus.Desc
+	acquireWaitDuration *prometheus.Desc
+	inUseConns          *prometheus.Desc
+	idleConns           *prometheus.Desc
+	maxConns            *prometheus.Desc
+	closedConns         *prometheus.Desc
+}
+
+// NewPoolCollector creates a collector which exports the statistics of the named pool.
+func NewPoolCollector(dbName, pool string, stats PoolStatsFunc) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("spicedb", "datastore_pool", name), help, poolLabels, nil)
+	}
+
+	return &poolCollector{
+		stats:       stats,
+		labelValues: []string{dbName, pool},
+
+		waitCount:           desc("acquire_waits_total", "Cumulative number of acquires which had to wait for a connection from the pool."),
+		acquireWaitDuration: desc("acquire_wait_seconds_total", "Cumulative time spent waiting to acquire connections from the pool."),
+		inUseConns:          desc("in_use_conns", "Number of connections currently acquired from the pool."),
+		idleConns:           desc("idle_conns", "Number of connections currently idle in the pool."),
+		maxConns:            desc("max_conns", "Current maximum size of the pool."),
+		closedConns:         desc("closed_conns_total", "Cumulative number of connections closed by the pool, such as those recycled after reaching their maximum lifetime."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount), c.labelValues...)
+	ch <- prometheus.MustNewConstMetric(c.acquireWaitDuration, prometheus.CounterValue, stats.AcquireWaitDuration.Seconds(), c.labelValues...)
+	ch <- prometheus.MustNewConstMetric(c.inUseConns, prometheus.GaugeValue, float64(stats.InUseConns), c.labelValues...)
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns), c.labelValues...)
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stats.MaxConns), c.labelValues...)
+	ch <- prometheus.MustNewConstMetric(c.closedConns, prometheus.CounterValue, float64(stats.ClosedConns), c.labelValues...)
+}
+
+// AdaptivePoolSizer adjusts the maximum size of a connection pool between a minimum and a
+// maximum, based on the average time spent by acquires which had to wait for a connection:
+// the pool grows while they wait longer than the target latency, and shrinks while no
+// acquire has to wait at all.
+type AdaptivePoolSizer struct {
+	minConns      int
+	maxConns      int
+	targetLatency time.Duration
+	stats         PoolStatsFunc
+	resize        func(maxConns int)
+
+	current         int
+	lastWaits       int64
+	lastWaitingTime time.Duration
+}
+
+// NewAdaptivePoolSizer creates a sizer which calls resize with each new maximum size of
+// the pool, starting with minConns.
+func NewAdaptivePoolSizer(minConns, maxConns int, targetLatency time.Duration, stats PoolStatsFunc, resize func(maxConns int)) *AdaptivePoolSizer {
+	if minConns < 1 {
+		minConns = 1
+	}
+	if maxConns < minConns {
+		maxConns = minConns
+	}
+
+	sizer := &AdaptivePoolSizer{
+		minConns:      minConns,
+		maxConns:      maxConns,
+		targetLatency: targetLatency,
+		stats:         stats,
+		resize:        resize,
+		current:       minConns,
+	}
+
+	initial := stats()
+	sizer.lastWaits = initial.WaitCount
+	sizer.lastWaitingTime = initial.AcquireWaitDuration
+	resize(sizer.current)
+	return sizer
+}
+
+// Start adjusts the size of the pool every interval, until the context is canceled.
+func (s *AdaptivePoolSizer) Start(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.adjust()
+		}
+	}
+}
+
+// adjust resizes the pool based on the acquires which waited since it was last called.
+func (s *AdaptivePoolSizer) adjust() {
+	stats := s.stats()
+	waits := stats.WaitCount - s.lastWaits
+	waitingTime := stats.AcquireWaitDuration - s.lastWaitingTime
+	s.lastWaits = stats.WaitCount
+	s.lastWaitingTime = stats.AcquireWaitDuration
+
+	// Grow or shrink by a quarter of the range between the bounds, so that the pool reaches
+	// either bound within a few intervals.
+	step := (s.maxConns - s.minConns + 3) / 4
+	if step < 1 {
+		step = 1
+	}
+
+	next := s.current
+	switch {
+	case waits > 0 && waitingTime/time.Duration(waits) > s.targetLatency:
+		next = s.current + step
+		if next > s.maxConns {
+			next = s.maxConns
+		}
+	case waits == 0 && stats.InUseConns < int64(s.current-step):
+		next = s.current - step
+		if next < s.minConns {
+			next = s.minConns
+		}
+	}
+
+	if next == s.current {
+		return
+	}
+
+	log.Debug().Int("from", s.current).Int("to", next).Msg("resizing datastore connection pool")
+	s.current = next
+	s.resize(next)
+}
+
+// MaxConns returns the current maximum size of the pool.
+func (s *AdaptivePoolSizer) MaxConns() int {
+	return s.current
+}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptivePoolSizer(t *testing.T) {
+	type interval struct {
+		waits       int64
+		waitingTime time.Duration
+		inUse       int64
+		expectedMax int
+	}
+
+	testCases := []struct {
+		name      string
+		minConns  int
+		maxConns  int
+		intervals []interval
+	}{
+		{
+			"no waits stays at minimum",
+			4, 20,
+			[]interval{
+				{0, 0, 1, 4},
+				{0, 0, 0, 4},
+			},
+		},
+		{
+			"grows up to maximum",
+			4, 20,
+			[]interval{
+				{10, 100 * time.Millisecond, 4, 8},
+				{10, 100 * time.Millisecond, 8, 12},
+				{10, 100 * time.Millisecond, 12, 16},
+				{10, 100 * time.Millisecond, 16, 20},
+				{10, 100 * time.Millisecond, 20, 20},
+			},
+		},
+		{
+			"waits under target do not grow",
+			4, 20,
+			[]interval{
+				{10, 10 * time.Millisecond, 4, 4},
+			},
+		},
+		{
+			"shrinks down to minimum",
+			4, 20,
+			[]interval{
+				{10, 100 * time.Millisecond, 4, 8},
+				{10, 100 * time.Millisecond, 8, 12},
+				{0, 0, 1, 8},
+				{0, 0, 1, 4},
+				{0, 0, 1, 4},
+			},
+		},
+		{
+			"does not shrink below connections in use",
+			4, 20,
+			[]interval{
+				{10, 100 * time.Millisecond, 4, 8},
+				{0, 0, 6, 8},
+			},
+		},
+		{
+			"maximum below minimum",
+			4, 2,
+			[]interval{
+				{10, 100 * time.Millisecond, 4, 4},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			var stats PoolStats
+			var resized []int
+			sizer := NewAdaptivePoolSizer(tc.minConns, tc.maxConns, 5*time.Millisecond, func() PoolStats {
+				return stats
+			}, func(maxConns int) {
+				resized = append(resized, maxConns)
+			})
+			require.Equal([]int{tc.minConns}, resized)
+
+			for _, interval := range tc.intervals {
+				stats.WaitCount += interval.waits
+				stats.AcquireWaitDuration += interval.waitingTime
+				stats.InUseConns = interval.inUse
+
+				sizer.adjust()
+				require.Equal(interval.expectedMax, sizer.MaxConns())
+				require.Equal(interval.expectedMax, resized[len(resized)-1])
+			}
+		})
+	}
+}
+
+func TestPoolCollector(t *testing.T) {
+	collector := NewPoolCollector("spicedb", "default", func() PoolStats {
+		return PoolStats{
+			WaitCount:           3,
+			AcquireWaitDuration: 1500 * time.Millisecond,
+			InUseConns:          2,
+			IdleConns:           5,
+			MaxConns:            7,
+			ClosedConns:         11,
+		}
+	})
+
+	expected := `
+# HELP spicedb_datastore_pool_acquire_wait_seconds_total Cumulative time spent waiting to acquire connections from the pool.
+# TYPE spicedb_datastore_pool_acquire_wait_seconds_total counter
+spicedb_datastore_pool_acquire_wait_seconds_total{db_name="spicedb",pool="default"} 1.5
+# HELP spicedb_datastore_pool_acquire_waits_total Cumulative number of acquires which had to wait for a connection from the pool.
+# TYPE spicedb_datastore_pool_acquire_waits_total counter
+spicedb_datastore_pool_acquire_waits_total{db_name="spicedb",pool="default"} 3
+# HELP spicedb_datastore_pool_in_use_conns Number of connections currently acquired from the pool.
+# TYPE spicedb_datastore_pool_in_use_conns gauge
+spicedb_datastore_pool_in_use_conns{db_name="spicedb",pool="default"} 2
+# HELP spicedb_datastore_pool_idle_conns Number of connections currently idle in the pool.
+# TYPE spicedb_datastore_pool_idle_conns gauge
+spicedb_datastore_pool_idle_conns{db_name="spicedb",pool="default"} 5
+# HELP spicedb_datastore_pool_max_conns Current maximum size of the pool.
+# TYPE spicedb_datastore_pool_max_conns gauge
+spicedb_datastore_pool_max_conns{db_name="spicedb",pool="default"} 7
+`
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"spicedb_datastore_pool_acquire_wait_seconds_total",
+		"spicedb_datastore_pool_acquire_waits_total",
+		"spicedb_datastore_pool_in_use_conns",
+		"spicedb_datastore_pool_idle_conns",
+		"spicedb_datastore_pool_max_conns",
+	))
+	require.Equal(t, 6, testutil.CollectAndCount(collector))
+}
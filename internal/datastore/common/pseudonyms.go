@@ -0,0 +1,21 @@
+package common
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPseudonymsUnsupported is returned when pseudonymizing object IDs over a datastore
+// which cannot store the sealed IDs of pseudonyms.
+var ErrPseudonymsUnsupported = errors.New("datastore does not support storing pseudonymized object IDs")
+
+// PseudonymStore is implemented by datastores which can store the sealed object IDs of
+// pseudonyms, from which the pseudonyms of relationships read are reversed.
+type PseudonymStore interface {
+	// WritePseudonyms stores the sealed object IDs by pseudonym. Pseudonyms which are
+	// already stored are left as-is.
+	WritePseudonyms(ctx context.Context, sealed map[string][]byte) error
+
+	// ReadPseudonyms returns the sealed object IDs of those of the pseudonyms found.
+	ReadPseudonyms(ctx context.Context, pseudonyms []string) (map[string][]byte, error)
+}
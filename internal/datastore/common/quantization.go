@@ -0,0 +1,10 @@
+package common
+
+import "github.com/authzed/spicedb/internal/datastore/common/revisions"
+
+// QuantizedDatastore is implemented by datastores whose optimized revisions are
+// quantized to a window which can adapt to their load.
+type QuantizedDatastore interface {
+	// RevisionQuantization returns the window to which optimized revisions are quantized.
+	RevisionQuantization() *revisions.Quantization
+}
@@ -0,0 +1,79 @@
+package common
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"github.com/authzed/spicedb/pkg/middleware/requestid"
+)
+
+// QueryAnnotator annotates SQL with a comment attributing it to the API request which
+// caused it to be issued, so that the statements recorded by the database can be
+// correlated with SpiceDB logs and traces. When tagging callers, the comment also carries
+// the API method and the SpiceDB node issuing it, so that database load can be attributed
+// to call sites.
+//
+// Comments follow the sqlcommenter format and are appended to the query, e.g.
+// `SELECT 1 /*method='%2Fauthzed.api.v1.PermissionsService%2FCheckPermission',node_id='spicedb-0',request_id='abc'*/`.
+// Databases which aggregate statements by their normalized text, such as Postgres with
+// pg_stat_statements, strip comments, so the comments of an aggregated statement are at
+// most those of one of its executions: per-call annotations are read from the statements
+// in flight, such as in pg_stat_activity, or from the statement logs.
+//
+// A nil QueryAnnotator leaves queries unannotated.
+type QueryAnnotator struct {
+	tagCallers bool
+	nodeID     string
+}
+
+// NewQueryAnnotator returns the annotator of the queries of a datastore, which annotates
+// queries with their request ID if requestIDs is set, and additionally with their method
+// and the given node if tagCallers is set. It returns nil if neither is set.
+func NewQueryAnnotator(requestIDs, tagCallers bool, nodeID string) *QueryAnnotator {
+	if !requestIDs && !tagCallers {
+		return nil
+	}
+	if !tagCallers {
+		return &QueryAnnotator{}
+	}
+	return &QueryAnnotator{tagCallers: true, nodeID: nodeID}
+}
+
+// Annotate appends the comment annotating the SQL issued under the context. Tags whose
+// value is unknown, such as the request ID of queries issued by background work, are
+// omitted.
+func (qa *QueryAnnotator) Annotate(ctx context.Context, sql string) string {
+	if qa == nil {
+		return sql
+	}
+
+	// Tags are serialized sorted by key, as required by the sqlcommenter format.
+	tags := make([]string, 0, 3)
+	if method, ok := grpc.Method(ctx); ok && qa.tagCallers {
+		tags = append(tags, queryTag("method", method))
+	}
+	if qa.tagCallers && qa.nodeID != "" {
+		tags = append(tags, queryTag("node_id", qa.nodeID))
+	}
+	if requestID, ok := requestid.FromContext(ctx); ok && len(requestID) <= maxAnnotatedRequestIDLength {
+		tags = append(tags, queryTag("request_id", requestID))
+	}
+	if len(tags) == 0 {
+		return sql
+	}
+
+	return sql + " /*" + strings.Join(tags, ",") + "*/"
+}
+
+// maxAnnotatedRequestIDLength is the length of the longest request ID with which queries
+// are annotated, as IDs are supplied by clients.
+const maxAnnotatedRequestIDLength = 128
+
+// queryTag serializes a tag, whose value is URL encoded such that it cannot contain a
+// quote or terminate the comment.
+func queryTag(key, value string) string {
+	return key + "='" + url.QueryEscape(value) + "'"
+}
@@ -0,0 +1,106 @@
+package common
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/pkg/middleware/requestid"
+)
+
+type fakeServerTransportStream struct {
+	grpc.ServerTransportStream
+	method string
+}
+
+func (s fakeServerTransportStream) Method() string {
+	return s.method
+}
+
+func TestQueryAnnotator(t *testing.T) {
+	const query = "SELECT * FROM relation_tuple"
+	checkMethod := fakeServerTransportStream{method: "/authzed.api.v1.PermissionsService/CheckPermission"}
+
+	tests := []struct {
+		name        string
+		annotator   *QueryAnnotator
+		stream      grpc.ServerTransportStream
+		md          metadata.MD
+		expectedSQL string
+	}{
+		{
+			"disabled",
+			NewQueryAnnotator(false, false, "spicedb-0"),
+			checkMethod,
+			metadata.Pairs(requestid.RequestIDMetadataKey, "d1c9a4f2e7"),
+			query,
+		},
+		{
+			"request IDs without request ID",
+			NewQueryAnnotator(true, false, "spicedb-0"),
+			checkMethod,
+			nil,
+			query,
+		},
+		{
+			"request IDs",
+			NewQueryAnnotator(true, false, "spicedb-0"),
+			checkMethod,
+			metadata.Pairs(requestid.RequestIDMetadataKey, "d1c9a4f2e7"),
+			query + " /*request_id='d1c9a4f2e7'*/",
+		},
+		{
+			"no tags",
+			NewQueryAnnotator(false, true, ""),
+			nil,
+			nil,
+			query,
+		},
+		{
+			"background work",
+			NewQueryAnnotator(false, true, "spicedb-0"),
+			nil,
+			nil,
+			query + " /*node_id='spicedb-0'*/",
+		},
+		{
+			"API call",
+			NewQueryAnnotator(false, true, "spicedb-0"),
+			checkMethod,
+			metadata.Pairs(requestid.RequestIDMetadataKey, "d1c9a4f2e7"),
+			query + " /*method='%2Fauthzed.api.v1.PermissionsService%2FCheckPermission',node_id='spicedb-0',request_id='d1c9a4f2e7'*/",
+		},
+		{
+			"request ID terminating the comment",
+			NewQueryAnnotator(true, true, ""),
+			nil,
+			metadata.Pairs(requestid.RequestIDMetadataKey, "abc' */ DROP TABLE relation_tuple; /*"),
+			query + " /*request_id='abc%27+%2A%2F+DROP+TABLE+relation_tuple%3B+%2F%2A'*/",
+		},
+		{
+			"overly long request ID",
+			NewQueryAnnotator(false, true, "spicedb-0"),
+			nil,
+			metadata.Pairs(requestid.RequestIDMetadataKey, strings.Repeat("a", 129)),
+			query + " /*node_id='spicedb-0'*/",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.stream != nil {
+				ctx = grpc.NewContextWithServerTransportStream(ctx, test.stream)
+			}
+			if test.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, test.md)
+			}
+			require.Equal(t, test.expectedSQL, test.annotator.Annotate(ctx, query))
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// RelationshipTypeLister is implemented by datastores which can enumerate the resource
+// types found on living relationships, including types which no longer have a namespace
+// definition.
+type RelationshipTypeLister interface {
+	ListRelationshipResourceTypes(ctx context.Context, rev datastore.Revision) ([]string, error)
+}
+
+// MVCCAnomaly is a stored relationship row whose MVCC metadata could not have been
+// produced by a valid sequence of transactions.
+type MVCCAnomaly struct {
+	Relationship *core.RelationTuple
+	Reason       string
+}
+
+// MVCCRepairer is implemented by datastores which track MVCC metadata on relationship
+// rows and can find and remove rows whose metadata is impossible.
+type MVCCRepairer interface {
+	// FindMVCCAnomalies returns up to limit anomalous rows.
+	FindMVCCAnomalies(ctx context.Context, limit uint64) ([]MVCCAnomaly, error)
+
+	// DeleteMVCCAnomalies deletes up to limit anomalous rows, returning the number deleted.
+	DeleteMVCCAnomalies(ctx context.Context, limit uint64) (int64, error)
+}
@@ -0,0 +1,143 @@
+package revisions
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+// targetCacheHitRatio is the hit ratio of the dispatch cache under which an adaptive
+// quantization window widens while writes are frequent.
+const targetCacheHitRatio = 0.5
+
+// CacheStatsFunc returns the cumulative number of hits and misses of a cache.
+type CacheStatsFunc func() (hits, misses uint64)
+
+// Quantization is the window to which the optimized revisions of a datastore are
+// quantized, either static or adapting between a minimum and a maximum.
+//
+// An adaptive window widens while writes are frequent and the hit ratio of the dispatch
+// cache is under its target, or unknown, so that more requests share each revision. It
+// narrows while revisions change less than once per window, or the hit ratio meets its
+// target, so that revisions are fresher when a wider window would not improve caching.
+// Windows are the minimum multiplied by a power of two, capped at the maximum, so that
+// the revisions of nodes whose windows differ often still coincide.
+type Quantization struct {
+	minimum time.Duration
+	maximum time.Duration
+
+	// level is the power of two by which the minimum is multiplied.
+	level  atomic.Int32
+	writes atomic.Uint64
+	stats  atomic.Pointer[CacheStatsFunc]
+
+	lastWrites uint64
+	lastHits   uint64
+	lastMisses uint64
+}
+
+// NewStaticQuantization returns a Quantization whose window never changes.
+func NewStaticQuantization(window time.Duration) *Quantization {
+	return NewAdaptiveQuantization(window, window)
+}
+
+// NewAdaptiveQuantization returns a Quantization whose window adapts between minimum and
+// maximum once started, starting at the minimum.
+func NewAdaptiveQuantization(minimum, maximum time.Duration) *Quantization {
+	if maximum < minimum {
+		maximum = minimum
+	}
+	return &Quantization{minimum: minimum, maximum: maximum}
+}
+
+// Window returns the current quantization window.
+func (q *Quantization) Window() time.Duration {
+	window := q.minimum << q.level.Load()
+	if window > q.maximum {
+		return q.maximum
+	}
+	return window
+}
+
+// IsAdaptive returns whether the window adapts between a minimum and a larger maximum.
+func (q *Quantization) IsAdaptive() bool {
+	return q.minimum > 0 && q.maximum > q.minimum
+}
+
+// RecordWrite records a write committed to the datastore.
+func (q *Quantization) RecordWrite() {
+	q.writes.Add(1)
+}
+
+// SetCacheStats sets the function returning the statistics of the dispatch cache whose
+// hit ratio the window adapts to.
+func (q *Quantization) SetCacheStats(stats CacheStatsFunc) {
+	q.stats.Store(&stats)
+}
+
+// Start adjusts the window every interval, until the context is canceled.
+func (q *Quantization) Start(ctx context.Context, interval time.Duration) error {
+	if !q.IsAdaptive() {
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastAdjusted := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			q.adjust(now.Sub(lastAdjusted))
+			lastAdjusted = now
+		}
+	}
+}
+
+// adjust widens or narrows the window based on the writes and cache lookups since it was
+// last called, elapsed ago.
+func (q *Quantization) adjust(elapsed time.Duration) {
+	writes := q.writes.Load()
+	recentWrites := writes - q.lastWrites
+	q.lastWrites = writes
+
+	hitRatio, hitRatioKnown := 0.0, false
+	if stats := q.stats.Load(); stats != nil {
+		hits, misses := (*stats)()
+		recentHits, recentMisses := hits-q.lastHits, misses-q.lastMisses
+		q.lastHits, q.lastMisses = hits, misses
+		if lookups := recentHits + recentMisses; lookups > 0 {
+			hitRatio, hitRatioKnown = float64(recentHits)/float64(lookups), true
+		}
+	}
+
+	current := q.Window()
+	level := q.level.Load()
+	switch {
+	case float64(recentWrites)*float64(current) < float64(elapsed),
+		hitRatioKnown && hitRatio >= targetCacheHitRatio:
+		if level > 0 {
+			level--
+		}
+	default:
+		if current < q.maximum {
+			level++
+		}
+	}
+
+	if level == q.level.Load() {
+		return
+	}
+
+	q.level.Store(level)
+	log.Debug().
+		Stringer("from", current).
+		Stringer("to", q.Window()).
+		Uint64("writes", recentWrites).
+		Float64("cacheHitRatio", hitRatio).
+		Msg("adjusted revision quantization window")
+}
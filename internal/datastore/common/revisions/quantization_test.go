@@ -0,0 +1,68 @@
+package revisions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticQuantization(t *testing.T) {
+	require := require.New(t)
+
+	q := NewStaticQuantization(5 * time.Second)
+	require.False(q.IsAdaptive())
+
+	for i := 0; i < 100; i++ {
+		q.RecordWrite()
+	}
+	q.adjust(time.Second)
+	require.Equal(5*time.Second, q.Window())
+}
+
+func TestAdaptiveQuantization(t *testing.T) {
+	require := require.New(t)
+
+	var hits, misses uint64
+	q := NewAdaptiveQuantization(time.Second, 5*time.Second)
+	require.True(q.IsAdaptive())
+	require.Equal(time.Second, q.Window())
+
+	writes := func(count int) {
+		for i := 0; i < count; i++ {
+			q.RecordWrite()
+		}
+	}
+
+	// Frequent writes with an unknown cache hit ratio widen the window, up to the maximum.
+	expected := []time.Duration{2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for _, window := range expected {
+		writes(100)
+		q.adjust(10 * time.Second)
+		require.Equal(window, q.Window())
+	}
+
+	// Fewer than one write per window narrows it, to the widths it had widened through.
+	writes(1)
+	q.adjust(10 * time.Second)
+	require.Equal(4*time.Second, q.Window())
+
+	// A cache hit ratio meeting its target narrows the window despite frequent writes.
+	q.SetCacheStats(func() (uint64, uint64) { return hits, misses })
+	hits, misses = 80, 20
+	writes(100)
+	q.adjust(10 * time.Second)
+	require.Equal(2*time.Second, q.Window())
+
+	// A cache hit ratio under its target widens it.
+	hits, misses = 90, 100
+	writes(100)
+	q.adjust(10 * time.Second)
+	require.Equal(4*time.Second, q.Window())
+
+	// No writes narrow it down to the minimum.
+	for i := 0; i < 4; i++ {
+		q.adjust(10 * time.Second)
+	}
+	require.Equal(time.Second, q.Window())
+}
@@ -22,11 +22,11 @@ type RemoteClockRevisions struct {
 	gcWindowNanos          int64
 	nowFunc                RemoteNowFunction
 	followerReadDelayNanos int64
-	quantizationNanos      int64
+	quantization           *Quantization
 }
 
 // NewRemoteClockRevisions returns a RemoteClockRevisions for the given configuration
-func NewRemoteClockRevisions(gcWindow, maxRevisionStaleness, followerReadDelay, quantization time.Duration) *RemoteClockRevisions {
+func NewRemoteClockRevisions(gcWindow, maxRevisionStaleness, followerReadDelay time.Duration, quantization *Quantization) *RemoteClockRevisions {
 	rev := atomicRevision{}
 	rev.set(validRevision{datastore.NoRevision, time.Time{}})
 	revisions := &RemoteClockRevisions{
@@ -35,7 +35,7 @@ func NewRemoteClockRevisions(gcWindow, maxRevisionStaleness, followerReadDelay,
 		),
 		gcWindowNanos:          gcWindow.Nanoseconds(),
 		followerReadDelayNanos: followerReadDelay.Nanoseconds(),
-		quantizationNanos:      quantization.Nanoseconds(),
+		quantization:           quantization,
 	}
 
 	revisions.SetOptimizedRevisionFunc(revisions.optimizedRevisionFunc)
@@ -52,16 +52,21 @@ func (rcr *RemoteClockRevisions) optimizedRevisionFunc(ctx context.Context) (dat
 	delayedNow := nowHLC.IntPart() - rcr.followerReadDelayNanos
 	quantized := delayedNow
 	validForNanos := int64(0)
-	if rcr.quantizationNanos > 0 {
-		afterLastQuantization := delayedNow % rcr.quantizationNanos
+	if quantizationNanos := rcr.quantization.Window().Nanoseconds(); quantizationNanos > 0 {
+		afterLastQuantization := delayedNow % quantizationNanos
 		quantized -= afterLastQuantization
-		validForNanos = rcr.quantizationNanos - afterLastQuantization
+		validForNanos = quantizationNanos - afterLastQuantization
 	}
 	log.Debug().Int64("readSkew", rcr.followerReadDelayNanos).Int64("totalSkew", nowHLC.IntPart()-quantized).Msg("revision skews")
 
 	return revision.NewFromDecimal(decimal.NewFromInt(quantized)), time.Duration(validForNanos) * time.Nanosecond, nil
 }
 
+// RevisionQuantization returns the window to which optimized revisions are quantized.
+func (rcr *RemoteClockRevisions) RevisionQuantization() *Quantization {
+	return rcr.quantization
+}
+
 // SetNowFunc sets the function used to determine the head revision
 func (rcr *RemoteClockRevisions) SetNowFunc(nowFunc RemoteNowFunction) {
 	rcr.nowFunc = nowFunc
@@ -75,7 +75,7 @@ func TestRemoteClockOptimizedRevisions(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			require := require.New(t)
 
-			rcr := NewRemoteClockRevisions(1*time.Hour, 0, tc.followerReadDelay, tc.quantization)
+			rcr := NewRemoteClockRevisions(1*time.Hour, 0, tc.followerReadDelay, NewStaticQuantization(tc.quantization))
 
 			remoteClock := clock.NewMock()
 			rcr.clockFn = remoteClock
@@ -126,7 +126,7 @@ func TestRemoteClockCheckRevisions(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			require := require.New(t)
 
-			rcr := NewRemoteClockRevisions(tc.gcWindow, 0, 0, 0)
+			rcr := NewRemoteClockRevisions(tc.gcWindow, 0, 0, NewStaticQuantization(0))
 
 			remoteClock := clock.NewMock()
 			rcr.clockFn = remoteClock
@@ -0,0 +1,33 @@
+package common
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// SchemaHistoryStore is implemented by datastores which can record the versions of the
+// schema as it is written.
+type SchemaHistoryStore interface {
+	// AddSchemaVersion records the schema written at the revision as the next version of
+	// the schema.
+	AddSchemaVersion(ctx context.Context, revision datastore.Revision, schemaText, author string) (datastore.SchemaVersion, error)
+
+	// ListSchemaVersions lists the recorded versions of the schema, oldest first, without
+	// their schema text.
+	ListSchemaVersions(ctx context.Context) ([]datastore.SchemaVersion, error)
+
+	// ReadSchemaVersion reads a recorded version of the schema, returning
+	// datastore.ErrSchemaVersionNotFound if there is no such version.
+	ReadSchemaVersion(ctx context.Context, version uint64) (datastore.SchemaVersion, error)
+}
+
+// SchemaHistoryStoreOf returns the SchemaHistoryStore of the datastore, or a
+// datastore.ErrSchemaHistoryUnsupported if it cannot record the history of the schema.
+func SchemaHistoryStoreOf(ds datastore.Datastore) (SchemaHistoryStore, error) {
+	store, ok := datastore.UnwrapAs[SchemaHistoryStore](ds)
+	if !ok {
+		return nil, datastore.NewSchemaHistoryUnsupportedErr("configured")
+	}
+	return store, nil
+}
@@ -0,0 +1,37 @@
+package common
+
+import (
+	"context"
+	"errors"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// ErrRelationshipSourcesUnsupported is returned when recording the sources of
+// relationships in a datastore which cannot store them.
+var ErrRelationshipSourcesUnsupported = errors.New("datastore does not support recording the sources of relationships")
+
+// RelationshipSourceTracker is implemented by the read-write transactions of datastores
+// which can record the source from which each relationship was last written, such as the
+// identity provider synchronized by an import, so that the relationships of a source can
+// be found again. Only the relationship, and not its caveat, identifies a record.
+type RelationshipSourceTracker interface {
+	// SetRelationshipSources records the source of the relationships, replacing any
+	// source recorded for them. An empty source removes their records.
+	SetRelationshipSources(ctx context.Context, source string, relationships []*core.RelationTuple) error
+
+	// ClearRelationshipSources removes the records of the relationships matching the
+	// filter, as deleted by DeleteRelationships.
+	ClearRelationshipSources(ctx context.Context, filter *v1.RelationshipFilter) error
+
+	// HasRelationshipSources returns whether the source of any relationship is recorded,
+	// so that the records of relationships written without a source need not be removed
+	// while there are none.
+	HasRelationshipSources(ctx context.Context) (bool, error)
+
+	// RelationshipsFromSource returns the relationships recorded as written from the
+	// source, without their caveats.
+	RelationshipsFromSource(ctx context.Context, source string) ([]*core.RelationTuple, error)
+}
@@ -2,9 +2,11 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"runtime"
+	"unicode/utf8"
 
 	sq "github.com/Masterminds/squirrel"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
@@ -32,6 +34,10 @@ var (
 	// ObjIDKey is a tracing attribute representing the resource object ID.
 	ObjIDKey = attribute.Key("authzed.com/spicedb/sql/objId")
 
+	// ObjIDPrefixKey is a tracing attribute representing a prefix of the resource
+	// object ID.
+	ObjIDPrefixKey = attribute.Key("authzed.com/spicedb/sql/objIdPrefix")
+
 	// SubNamespaceNameKey is a tracing attribute representing the subject object
 	// type.
 	SubNamespaceNameKey = attribute.Key("authzed.com/spicedb/sql/subNamespaceName")
@@ -44,6 +50,10 @@ var (
 	// ID.
 	SubObjectIDKey = attribute.Key("authzed.com/spicedb/sql/subObjectId")
 
+	// SubObjectIDPrefixKey is a tracing attribute representing a prefix of the
+	// subject object ID.
+	SubObjectIDPrefixKey = attribute.Key("authzed.com/spicedb/sql/subObjectIdPrefix")
+
 	limitKey = attribute.Key("authzed.com/spicedb/sql/limit")
 
 	tracer = otel.Tracer("spicedb/internal/datastore/common")
@@ -59,6 +69,11 @@ type SchemaInformation struct {
 	ColUsersetObjectID  string
 	ColUsersetRelation  string
 	ColCaveatName       string
+
+	// SortCollation, if not empty, is the collation with which the columns are ordered
+	// when relationships are sorted, for datastores whose default collations do not order
+	// strings by their bytes.
+	SortCollation string
 }
 
 // SchemaQueryFilterer wraps a SchemaInformation and SelectBuilder to give an opinionated
@@ -123,6 +138,54 @@ func (sqf SchemaQueryFilterer) FilterToResourceIDs(resourceIds []string) SchemaQ
 	return sqf
 }
 
+// FilterToResourceIDPrefix returns a new SchemaQueryFilterer that is limited to resources with
+// IDs starting with the specified prefix.
+func (sqf SchemaQueryFilterer) FilterToResourceIDPrefix(prefix string) SchemaQueryFilterer {
+	sqf.queryBuilder = sqf.queryBuilder.Where(prefixRange(sqf.schema.ColObjectID, prefix))
+	sqf.tracerAttributes = append(sqf.tracerAttributes, ObjIDPrefixKey.String(prefix))
+	return sqf
+}
+
+// prefixRange returns the condition matching the values of the column starting with prefix,
+// as the range of values from prefix up to its successor. Unlike a LIKE pattern, which
+// Postgres can only match with an index in the C collation, a range is evaluated as a scan
+// of any index on the column. The range is ordered by the collation of the column, which
+// orders the strings starting with the prefix before its successor, as the binary and C
+// collations of the datastores do.
+func prefixRange(column, prefix string) sq.Sqlizer {
+	successor, ok := prefixSuccessor(prefix)
+	if !ok {
+		return sq.GtOrEq{column: prefix}
+	}
+	return sq.And{sq.GtOrEq{column: prefix}, sq.Lt{column: successor}}
+}
+
+// prefixSuccessor returns the least string greater than all those starting with prefix, if
+// any, by incrementing its last code point which can be incremented. Code points are
+// ordered as their UTF-8 encodings are, so the successor is valid UTF-8.
+func prefixSuccessor(prefix string) (string, bool) {
+	runes := []rune(prefix)
+	for i := len(runes) - 1; i >= 0; i-- {
+		switch r := runes[i]; {
+		case r == utf8.MaxRune:
+			continue
+		case r == surrogateMin-1:
+			runes[i] = surrogateMax + 1
+		default:
+			runes[i] = r + 1
+		}
+		return string(runes[:i+1]), true
+	}
+	return "", false
+}
+
+// surrogateMin and surrogateMax bound the UTF-16 surrogate code points, which cannot be
+// encoded as UTF-8.
+const (
+	surrogateMin = 0xD800
+	surrogateMax = 0xDFFF
+)
+
 // FilterToRelation returns a new SchemaQueryFilterer that is limited to resources with the
 // specified relation.
 func (sqf SchemaQueryFilterer) FilterToRelation(relation string) SchemaQueryFilterer {
@@ -144,6 +207,10 @@ func (sqf SchemaQueryFilterer) FilterWithRelationshipsFilter(filter datastore.Re
 		sqf = sqf.FilterToResourceIDs(filter.OptionalResourceIds)
 	}
 
+	if filter.OptionalResourceIDPrefix != "" {
+		sqf = sqf.FilterToResourceIDPrefix(filter.OptionalResourceIDPrefix)
+	}
+
 	if filter.OptionalSubjectsFilter != nil {
 		sqf = sqf.FilterWithSubjectsFilter(*filter.OptionalSubjectsFilter)
 	}
@@ -188,6 +255,11 @@ func (sqf SchemaQueryFilterer) FilterWithSubjectsFilter(filter datastore.Subject
 		sqf.queryBuilder = sqf.queryBuilder.Where(inClause+")", args...)
 	}
 
+	if filter.OptionalSubjectIDPrefix != "" {
+		sqf.queryBuilder = sqf.queryBuilder.Where(prefixRange(sqf.schema.ColUsersetObjectID, filter.OptionalSubjectIDPrefix))
+		sqf.tracerAttributes = append(sqf.tracerAttributes, SubObjectIDPrefixKey.String(filter.OptionalSubjectIDPrefix))
+	}
+
 	if !filter.RelationFilter.IsEmpty() {
 		relations := make([]string, 0, 2)
 		if filter.RelationFilter.IncludeEllipsisRelation {
@@ -273,6 +345,25 @@ func (sqf SchemaQueryFilterer) limit(limit uint64) SchemaQueryFilterer {
 	return sqf
 }
 
+// orderByResource returns a new SchemaQueryFilterer which returns the relationships in the
+// order of options.ByResource.
+func (sqf SchemaQueryFilterer) orderByResource() SchemaQueryFilterer {
+	for _, column := range []string{
+		sqf.schema.ColNamespace,
+		sqf.schema.ColObjectID,
+		sqf.schema.ColRelation,
+		sqf.schema.ColUsersetNamespace,
+		sqf.schema.ColUsersetObjectID,
+		sqf.schema.ColUsersetRelation,
+	} {
+		if sqf.schema.SortCollation != "" {
+			column += " COLLATE " + sqf.schema.SortCollation
+		}
+		sqf.queryBuilder = sqf.queryBuilder.OrderBy(column)
+	}
+	return sqf
+}
+
 // TupleQuerySplitter is a tuple query runner shared by SQL implementations of the datastore.
 type TupleQuerySplitter struct {
 	Executor         ExecuteQueryFunc
@@ -286,51 +377,166 @@ func (tqs TupleQuerySplitter) SplitAndExecuteQuery(
 	query SchemaQueryFilterer,
 	opts ...options.QueryOptionsOption,
 ) (datastore.RelationshipIterator, error) {
-	ctx, span := tracer.Start(ctx, "SplitAndExecuteQuery")
-	defer span.End()
 	queryOpts := options.NewQueryOptionsWithOptions(opts...)
 
-	var tuples []*core.RelationTuple
 	remainingLimit := math.MaxInt
 	if queryOpts.Limit != nil {
 		remainingLimit = int(*queryOpts.Limit)
 	}
 
-	remainingUsersets := queryOpts.Usersets
-	for remaining := 1; remaining > 0; remaining = len(remainingUsersets) {
-		upperBound := uint16(len(remainingUsersets))
-		if upperBound > tqs.UsersetBatchSize {
-			upperBound = tqs.UsersetBatchSize
+	if queryOpts.Sort == options.ByResource {
+		// The relationships are only ordered within the query of each batch of usersets.
+		if len(queryOpts.Usersets) > int(tqs.UsersetBatchSize) {
+			return nil, fmt.Errorf("unable to sort the relationships of more than %d usersets", tqs.UsersetBatchSize)
 		}
+		query = query.orderByResource()
+	}
 
-		batch := remainingUsersets[:upperBound]
-		toExecute := query.limit(uint64(remainingLimit)).filterToUsersets(batch)
+	iter := &splitQueryIterator{
+		ctx:               ctx,
+		tqs:               tqs,
+		query:             query,
+		remainingUsersets: queryOpts.Usersets,
+		remainingLimit:    remainingLimit,
+	}
 
-		sql, args, err := toExecute.queryBuilder.ToSql()
-		if err != nil {
-			return nil, err
+	if queryOpts.StreamRows {
+		runtime.SetFinalizer(iter, func(iter *splitQueryIterator) {
+			if !iter.closed {
+				panic("Tuple iterator garbage collected before Close() was called")
+			}
+		})
+		return iter, nil
+	}
+
+	ctx, span := tracer.Start(ctx, "SplitAndExecuteQuery")
+	defer span.End()
+	iter.ctx = ctx
+	defer iter.Close()
+
+	var tuples []*core.RelationTuple
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		tuples = append(tuples, tpl)
+	}
+	if iter.err != nil {
+		return nil, iter.err
+	}
+
+	sliceIter := datastore.NewSliceRelationshipIterator(tuples)
+	runtime.SetFinalizer(sliceIter, datastore.BuildFinalizerFunction())
+	return sliceIter, nil
+}
+
+// executeBatch executes the query for a single batch of usersets.
+func (tqs TupleQuerySplitter) executeBatch(
+	ctx context.Context,
+	query SchemaQueryFilterer,
+	limit int,
+	usersets []*core.ObjectAndRelation,
+) (TupleRows, error) {
+	toExecute := query.limit(uint64(limit)).filterToUsersets(usersets)
+
+	sql, args, err := toExecute.queryBuilder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	return tqs.Executor(ctx, sql, args)
+}
+
+var errClosedIterator = errors.New("unable to iterate: iterator closed")
+
+// splitQueryIterator executes the query for each batch of usersets in turn, reading the
+// relationships from the rows of each as it is advanced.
+type splitQueryIterator struct {
+	ctx   context.Context
+	tqs   TupleQuerySplitter
+	query SchemaQueryFilterer
+
+	executed          bool
+	remainingUsersets []*core.ObjectAndRelation
+	remainingLimit    int
+
+	rows   TupleRows
+	err    error
+	closed bool
+}
+
+// Next implements datastore.RelationshipIterator
+func (sqi *splitQueryIterator) Next() *core.RelationTuple {
+	if sqi.closed {
+		sqi.err = errClosedIterator
+		return nil
+	}
+
+	for sqi.err == nil && sqi.remainingLimit > 0 {
+		if sqi.rows == nil {
+			if sqi.executed && len(sqi.remainingUsersets) == 0 {
+				return nil
+			}
+
+			upperBound := uint16(len(sqi.remainingUsersets))
+			if upperBound > sqi.tqs.UsersetBatchSize {
+				upperBound = sqi.tqs.UsersetBatchSize
+			}
+
+			sqi.rows, sqi.err = sqi.tqs.executeBatch(sqi.ctx, sqi.query, sqi.remainingLimit, sqi.remainingUsersets[:upperBound])
+			sqi.executed = true
+			sqi.remainingUsersets = sqi.remainingUsersets[upperBound:]
+			continue
 		}
 
-		queryTuples, err := tqs.Executor(ctx, sql, args)
+		tpl, err := sqi.rows.Next()
 		if err != nil {
-			return nil, err
+			sqi.err = err
+			return nil
 		}
 
-		if len(queryTuples) > remainingLimit {
-			queryTuples = queryTuples[:remainingLimit]
+		if tpl == nil {
+			sqi.rows.Close()
+			sqi.rows = nil
+			continue
 		}
 
-		tuples = append(tuples, queryTuples...)
-		remainingUsersets = remainingUsersets[upperBound:]
+		sqi.remainingLimit--
+		return tpl
 	}
 
-	iter := datastore.NewSliceRelationshipIterator(tuples)
-	runtime.SetFinalizer(iter, datastore.BuildFinalizerFunction())
-	return iter, nil
+	return nil
+}
+
+// Err implements datastore.RelationshipIterator
+func (sqi *splitQueryIterator) Err() error {
+	return sqi.err
+}
+
+// Close implements datastore.RelationshipIterator
+func (sqi *splitQueryIterator) Close() {
+	if sqi.closed {
+		panic("tuple iterator double closed")
+	}
+
+	if sqi.rows != nil {
+		sqi.rows.Close()
+		sqi.rows = nil
+	}
+	sqi.closed = true
 }
 
 // ExecuteQueryFunc is a function that can be used to execute a single rendered SQL query.
-type ExecuteQueryFunc func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, error)
+// The relationships are read from the rows of the result as the TupleRows returned are
+// advanced, so it must be closed once done with.
+type ExecuteQueryFunc func(ctx context.Context, sql string, args []any) (TupleRows, error)
+
+// TupleRows is a cursor over the relationships read from the rows of an executed query.
+type TupleRows interface {
+	// Next reads the relationship from the next row, returning nil once the rows have been
+	// exhausted.
+	Next() (*core.RelationTuple, error)
+
+	// Close releases the rows, along with the connection or transaction they are read from.
+	Close()
+}
 
 // TxCleanupFunc is a function that should be executed when the caller of
 // TransactionFactory is done with the transaction.
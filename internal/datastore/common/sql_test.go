@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"testing"
 
 	"github.com/authzed/spicedb/pkg/tuple"
@@ -9,6 +10,7 @@ import (
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/stretchr/testify/require"
 
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
@@ -44,6 +46,14 @@ func TestSchemaQueryFilterer(t *testing.T) {
 			"SELECT * WHERE object_id IN (?, ?)",
 			[]any{"someresourceid", "anotherresourceid"},
 		},
+		{
+			"resource ID prefix filter",
+			func(filterer SchemaQueryFilterer) SchemaQueryFilterer {
+				return filterer.FilterToResourceIDPrefix("tenant_1%-")
+			},
+			"SELECT * WHERE (object_id >= ? AND object_id < ?)",
+			[]any{"tenant_1%-", "tenant_1%."},
+		},
 		{
 			"resource type filter",
 			func(filterer SchemaQueryFilterer) SchemaQueryFilterer {
@@ -287,6 +297,23 @@ func TestSchemaQueryFilterer(t *testing.T) {
 			"SELECT * WHERE ns = ? AND relation = ? AND object_id IN (?, ?) AND subject_ns = ? AND subject_object_id IN (?, ?) AND (subject_relation = ? OR subject_relation = ?)",
 			[]any{"someresourcetype", "somerelation", "someid", "anotherid", "somesubjectype", "somesubjectid", "anothersubjectid", "...", "somesubrel"},
 		},
+		{
+			"relationships filter with ID prefixes",
+			func(filterer SchemaQueryFilterer) SchemaQueryFilterer {
+				return filterer.FilterWithRelationshipsFilter(
+					datastore.RelationshipsFilter{
+						ResourceType:             "someresourcetype",
+						OptionalResourceIDPrefix: "tenant1-",
+						OptionalSubjectsFilter: &datastore.SubjectsFilter{
+							SubjectType:             "somesubjectype",
+							OptionalSubjectIDPrefix: "tenant1-",
+						},
+					},
+				)
+			},
+			"SELECT * WHERE ns = ? AND (object_id >= ? AND object_id < ?) AND subject_ns = ? AND (subject_object_id >= ? AND subject_object_id < ?)",
+			[]any{"someresourcetype", "tenant1-", "tenant1.", "somesubjectype", "tenant1-", "tenant1."},
+		},
 	}
 
 	for _, test := range tests {
@@ -309,3 +336,113 @@ func TestSchemaQueryFilterer(t *testing.T) {
 		})
 	}
 }
+
+type fakeRows struct {
+	tuples []*core.RelationTuple
+	closed *int
+}
+
+func (r *fakeRows) Next() (*core.RelationTuple, error) {
+	if len(r.tuples) == 0 {
+		return nil, nil
+	}
+	next := r.tuples[0]
+	r.tuples = r.tuples[1:]
+	return next, nil
+}
+
+func (r *fakeRows) Close() {
+	*r.closed++
+}
+
+func TestSplitAndExecuteQuery(t *testing.T) {
+	usersets := []*core.ObjectAndRelation{
+		{Namespace: "user", ObjectId: "tom", Relation: "..."},
+		{Namespace: "user", ObjectId: "fred", Relation: "..."},
+		{Namespace: "user", ObjectId: "sarah", Relation: "..."},
+	}
+
+	tests := []struct {
+		name             string
+		opts             []options.QueryOptionsOption
+		expectedTuples   int
+		expectedExecuted int
+	}{
+		{"no usersets", nil, 2, 1},
+		{"no usersets streamed", []options.QueryOptionsOption{options.WithStreamRows(true)}, 2, 1},
+		{"batched usersets", []options.QueryOptionsOption{options.SetUsersets(usersets)}, 4, 2},
+		{"batched usersets streamed", []options.QueryOptionsOption{options.SetUsersets(usersets), options.WithStreamRows(true)}, 4, 2},
+		{"limit across batches", []options.QueryOptionsOption{options.SetUsersets(usersets), options.WithLimit(limitOf(3))}, 3, 2},
+		{"limit within batch streamed", []options.QueryOptionsOption{options.SetUsersets(usersets), options.WithLimit(limitOf(1)), options.WithStreamRows(true)}, 1, 1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			executed, closed := 0, 0
+			splitter := TupleQuerySplitter{
+				UsersetBatchSize: 2,
+				Executor: func(ctx context.Context, sql string, args []any) (TupleRows, error) {
+					executed++
+					return &fakeRows{
+						tuples: []*core.RelationTuple{
+							tuple.MustParse("document:first#viewer@user:tom"),
+							tuple.MustParse("document:second#viewer@user:tom"),
+						},
+						closed: &closed,
+					}, nil
+				},
+			}
+
+			query := NewSchemaQueryFilterer(SchemaInformation{
+				ColNamespace:        "ns",
+				ColObjectID:         "object_id",
+				ColRelation:         "relation",
+				ColUsersetNamespace: "subject_ns",
+				ColUsersetObjectID:  "subject_object_id",
+				ColUsersetRelation:  "subject_relation",
+			}, sq.Select("*").From("tuple"))
+
+			iter, err := splitter.SplitAndExecuteQuery(context.Background(), query, test.opts...)
+			require.NoError(err)
+
+			found := 0
+			for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+				found++
+			}
+			require.NoError(iter.Err())
+			iter.Close()
+
+			require.Equal(test.expectedTuples, found)
+			require.Equal(test.expectedExecuted, executed)
+			require.Equal(executed, closed, "every set of rows must be closed")
+		})
+	}
+}
+
+func limitOf(limit uint64) *uint64 {
+	return &limit
+}
+
+func TestPrefixSuccessor(t *testing.T) {
+	for _, tc := range []struct {
+		prefix    string
+		successor string
+		ok        bool
+	}{
+		{"tenant1-", "tenant1.", true},
+		{"a\U0010FFFF", "b", true},
+		{"a\uD7FF", "a\uE000", true},
+		{"\U0010FFFF", "", false},
+	} {
+		successor, ok := prefixSuccessor(tc.prefix)
+		require.Equal(t, tc.ok, ok, tc.prefix)
+		require.Equal(t, tc.successor, successor, tc.prefix)
+		if ok {
+			require.Less(t, tc.prefix, successor)
+			require.Less(t, tc.prefix+"\U0010FFFF", successor)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Package sqlgolden renders the SQL generated by a datastore for a fixed set of
+// relationship queries and deletions to a golden file, so that changes to the shape of
+// the generated SQL, such as a lost index prefix or an unbounded join, show up in review.
+//
+// Set REGEN=true when running the tests to rewrite the golden files.
+package sqlgolden
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// Recorder records SQL statements in place of executing them.
+type Recorder struct {
+	statements []string
+}
+
+// ExecuteQuery is a common.ExecuteQueryFunc which records the query and returns no
+// relationships.
+func (r *Recorder) ExecuteQuery(_ context.Context, sql string, args []any) (common.TupleRows, error) {
+	r.record(sql, args)
+	return noRows{}, nil
+}
+
+type noRows struct{}
+
+func (noRows) Next() (*core.RelationTuple, error) { return nil, nil }
+
+func (noRows) Close() {}
+
+// Explainer records the plans of queries in place of executing them, so that tests run
+// against a database can check the indexes it chooses for them.
+type Explainer struct {
+	// Explain returns the plan of a query, as rendered by the database.
+	Explain func(ctx context.Context, sql string, args []any) (string, error)
+
+	Plans []string
+}
+
+// ExecuteQuery is a common.ExecuteQueryFunc which records the plan of the query and
+// returns no relationships.
+func (e *Explainer) ExecuteQuery(ctx context.Context, sql string, args []any) (common.TupleRows, error) {
+	plan, err := e.Explain(ctx, sql, args)
+	if err != nil {
+		return nil, err
+	}
+	e.Plans = append(e.Plans, plan)
+	return noRows{}, nil
+}
+
+// PgxTx returns a pgx.Tx which records the statements passed to Exec. Any other method
+// panics.
+func (r *Recorder) PgxTx() pgx.Tx {
+	return &recordingTx{recorder: r}
+}
+
+type recordingTx struct {
+	pgx.Tx
+	recorder *Recorder
+}
+
+func (tx *recordingTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx.recorder.record(sql, args)
+	return pgconn.CommandTag("DELETE 0"), nil
+}
+
+func (r *Recorder) record(sql string, args []any) {
+	rendered := make([]string, 0, len(args))
+	for _, arg := range args {
+		if valuer, ok := arg.(driver.Valuer); ok {
+			if value, err := valuer.Value(); err == nil {
+				arg = value
+			}
+		}
+		if s, ok := arg.(string); ok {
+			rendered = append(rendered, fmt.Sprintf("%q", s))
+			continue
+		}
+		rendered = append(rendered, fmt.Sprintf("%v", arg))
+	}
+	r.statements = append(r.statements, sql+"\nargs: ["+strings.Join(rendered, ", ")+"]")
+}
+
+func (r *Recorder) drain() string {
+	statements := r.statements
+	r.statements = nil
+	return strings.Join(statements, "\n")
+}
+
+type queryCase struct {
+	name string
+	run  func(ctx context.Context, reader datastore.Reader) error
+}
+
+func query(filter datastore.RelationshipsFilter, opts ...options.QueryOptionsOption) func(context.Context, datastore.Reader) error {
+	return func(ctx context.Context, reader datastore.Reader) error {
+		iter, err := reader.QueryRelationships(ctx, filter, opts...)
+		if err != nil {
+			return err
+		}
+		iter.Close()
+		return nil
+	}
+}
+
+func reverseQuery(filter datastore.SubjectsFilter, opts ...options.ReverseQueryOptionsOption) func(context.Context, datastore.Reader) error {
+	return func(ctx context.Context, reader datastore.Reader) error {
+		iter, err := reader.ReverseQueryRelationships(ctx, filter, opts...)
+		if err != nil {
+			return err
+		}
+		iter.Close()
+		return nil
+	}
+}
+
+func limit(l uint64) *uint64 { return &l }
+
+var queryCases = []queryCase{
+	{"query by resource type", query(datastore.RelationshipsFilter{ResourceType: "document"})},
+	{"query by resource ID", query(datastore.RelationshipsFilter{
+		ResourceType:        "document",
+		OptionalResourceIds: []string{"first"},
+	})},
+	{"query by resource IDs", query(datastore.RelationshipsFilter{
+		ResourceType:        "document",
+		OptionalResourceIds: []string{"first", "second", "third"},
+	})},
+	{"query by resource ID prefix", query(datastore.RelationshipsFilter{
+		ResourceType:             "document",
+		OptionalResourceIDPrefix: "tenant1-",
+	})},
+	{"query by resource and relation", query(datastore.RelationshipsFilter{
+		ResourceType:             "document",
+		OptionalResourceIds:      []string{"first"},
+		OptionalResourceRelation: "viewer",
+	})},
+	{"query by caveat name", query(datastore.RelationshipsFilter{
+		ResourceType:       "document",
+		OptionalCaveatName: "somecaveat",
+	})},
+	{"query by subject type", query(datastore.RelationshipsFilter{
+		ResourceType:           "document",
+		OptionalSubjectsFilter: &datastore.SubjectsFilter{SubjectType: "user"},
+	})},
+	{"query by full relationship", query(datastore.RelationshipsFilter{
+		ResourceType:             "document",
+		OptionalResourceIds:      []string{"first"},
+		OptionalResourceRelation: "viewer",
+		OptionalSubjectsFilter: &datastore.SubjectsFilter{
+			SubjectType:        "user",
+			OptionalSubjectIds: []string{"tom"},
+			RelationFilter:     datastore.SubjectRelationFilter{}.WithEllipsisRelation(),
+		},
+	})},
+	{"query with limit", query(
+		datastore.RelationshipsFilter{ResourceType: "document", OptionalResourceRelation: "viewer"},
+		options.WithLimit(limit(100)),
+	)},
+	{"query with usersets", query(
+		datastore.RelationshipsFilter{ResourceType: "document", OptionalResourceRelation: "viewer"},
+		options.SetUsersets([]*core.ObjectAndRelation{
+			{Namespace: "user", ObjectId: "tom", Relation: datastore.Ellipsis},
+			{Namespace: "group", ObjectId: "eng", Relation: "member"},
+		}),
+	)},
+	{"reverse query by subject type", reverseQuery(datastore.SubjectsFilter{SubjectType: "user"})},
+	{"reverse query by subject IDs", reverseQuery(datastore.SubjectsFilter{
+		SubjectType:        "user",
+		OptionalSubjectIds: []string{"tom", "fred"},
+	})},
+	{"reverse query by subject ID prefix", reverseQuery(datastore.SubjectsFilter{
+		SubjectType:             "user",
+		OptionalSubjectIDPrefix: "tenant1-",
+	})},
+	{"reverse query by subject relation", reverseQuery(datastore.SubjectsFilter{
+		SubjectType:    "group",
+		RelationFilter: datastore.SubjectRelationFilter{NonEllipsisRelation: "member"},
+	})},
+	{"reverse query by subject relation or ellipsis", reverseQuery(datastore.SubjectsFilter{
+		SubjectType:        "group",
+		OptionalSubjectIds: []string{"eng"},
+		RelationFilter:     datastore.SubjectRelationFilter{NonEllipsisRelation: "member"}.WithEllipsisRelation(),
+	})},
+	{"reverse query with resource relation and limit", reverseQuery(
+		datastore.SubjectsFilter{SubjectType: "user", OptionalSubjectIds: []string{"tom"}},
+		options.WithResRelation(&options.ResourceRelation{Namespace: "document", Relation: "viewer"}),
+		options.WithReverseLimit(limit(100)),
+	)},
+}
+
+var deleteCases = []struct {
+	name   string
+	filter *v1.RelationshipFilter
+}{
+	{"delete by resource type", &v1.RelationshipFilter{ResourceType: "document"}},
+	{"delete by resource", &v1.RelationshipFilter{
+		ResourceType:       "document",
+		OptionalResourceId: "first",
+		OptionalRelation:   "viewer",
+	}},
+	{"delete by subject type", &v1.RelationshipFilter{
+		ResourceType:          "document",
+		OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: "user"},
+	}},
+	{"delete by subject", &v1.RelationshipFilter{
+		ResourceType: "document",
+		OptionalSubjectFilter: &v1.SubjectFilter{
+			SubjectType:       "group",
+			OptionalSubjectId: "eng",
+			OptionalRelation:  &v1.SubjectFilter_RelationFilter{Relation: "member"},
+		},
+	}},
+	{"delete by subject with ellipsis", &v1.RelationshipFilter{
+		ResourceType: "document",
+		OptionalSubjectFilter: &v1.SubjectFilter{
+			SubjectType:       "user",
+			OptionalSubjectId: "tom",
+			OptionalRelation:  &v1.SubjectFilter_RelationFilter{},
+		},
+	}},
+}
+
+// Assert runs each relationship query against the reader and, if rwt is not nil, each
+// deletion against rwt, both of which must send their SQL to the recorder, then compares
+// the statements recorded with the golden file at path.
+func Assert(t *testing.T, path string, recorder *Recorder, reader datastore.Reader, rwt datastore.ReadWriteTransaction) {
+	ctx := context.Background()
+	var sections []string
+	for _, qc := range queryCases {
+		require.NoError(t, qc.run(ctx, reader), qc.name)
+		sections = append(sections, fmt.Sprintf("-- %s --\n%s\n", qc.name, recorder.drain()))
+	}
+
+	if rwt != nil {
+		for _, dc := range deleteCases {
+			require.NoError(t, rwt.DeleteRelationships(ctx, dc.filter), dc.name)
+			sections = append(sections, fmt.Sprintf("-- %s --\n%s\n", dc.name, recorder.drain()))
+		}
+	}
+
+	found := strings.Join(sections, "\n")
+	if os.Getenv("REGEN") == "true" {
+		require.NoError(t, os.WriteFile(path, []byte(found), 0o600))
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err, "golden file missing; run with REGEN=true to create it")
+	require.Equal(t, string(expected), found, "generated SQL changed; if intended, run with REGEN=true and review the golden file")
+}
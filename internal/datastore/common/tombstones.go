@@ -0,0 +1,58 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+var (
+	// ErrNamespaceTombstonesUnsupported is returned when retaining removed namespaces in a
+	// datastore which cannot store their tombstones.
+	ErrNamespaceTombstonesUnsupported = errors.New("datastore does not support retaining removed namespaces")
+
+	// ErrNamespaceTombstoneNotFound is returned when reading or deleting the tombstone of a
+	// namespace which is not retained, or no longer is.
+	ErrNamespaceTombstoneNotFound = errors.New("namespace tombstone not found")
+)
+
+// NamespaceTombstone records a namespace removed from the schema, whose definition and
+// relationships are retained at a pinned revision until it expires.
+type NamespaceTombstone struct {
+	Namespace string
+
+	// Revision is the revision, from before the namespace was removed, at which it is
+	// retained.
+	Revision datastore.Revision
+
+	DeletedAt time.Time
+	ExpiresAt time.Time
+}
+
+// NamespaceTombstoneStore is implemented by datastores which can store the tombstones of
+// removed namespaces.
+type NamespaceTombstoneStore interface {
+	// WriteNamespaceTombstone stores the tombstone of a namespace, replacing any existing
+	// tombstone of it. Expired tombstones are deleted.
+	WriteNamespaceTombstone(ctx context.Context, tombstone NamespaceTombstone) error
+
+	// ReadNamespaceTombstone returns the unexpired tombstone of a namespace, or
+	// ErrNamespaceTombstoneNotFound.
+	ReadNamespaceTombstone(ctx context.Context, namespace string) (NamespaceTombstone, error)
+
+	// ListNamespaceTombstones returns the unexpired tombstones, most recently deleted
+	// first.
+	ListNamespaceTombstones(ctx context.Context) ([]NamespaceTombstone, error)
+
+	// DeleteNamespaceTombstone deletes the tombstone of a namespace, or returns
+	// ErrNamespaceTombstoneNotFound.
+	DeleteNamespaceTombstone(ctx context.Context, namespace string) error
+}
+
+// NamespaceTombstonePinName returns the name under which the revision of the tombstone of
+// a namespace is pinned.
+func NamespaceTombstonePinName(namespace string) string {
+	return "namespace-tombstone:" + namespace
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RelationshipHistoryCompactor is implemented by datastores which keep the earlier
+// versions of relationships, and can merge consecutive versions which are identical.
+type RelationshipHistoryCompactor interface {
+	// CompactRelationshipHistory merges up to limit pairs of identical consecutive versions,
+	// returning the number merged.
+	CompactRelationshipHistory(ctx context.Context, limit uint64) (int64, error)
+}
+
+// TouchedRelationships returns the relationships TOUCHed by the mutations.
+func TouchedRelationships(mutations []*core.RelationTupleUpdate) []*core.RelationTuple {
+	var touched []*core.RelationTuple
+	for _, mut := range mutations {
+		if mut.Operation == core.RelationTupleUpdate_TOUCH {
+			touched = append(touched, mut.Tuple)
+		}
+	}
+	return touched
+}
+
+// WithoutNoopTouches returns the mutations less those TOUCHing a relationship which is
+// already stored, as found in living, with the same caveat name and context. Rewriting such
+// a relationship changes nothing but its MVCC metadata, so the write can be skipped.
+//
+// TOUCHes of relationships referenced by any other mutation are always kept.
+func WithoutNoopTouches(mutations []*core.RelationTupleUpdate, living []*core.RelationTuple) []*core.RelationTupleUpdate {
+	if len(living) == 0 {
+		return mutations
+	}
+
+	stored := make(map[string]*core.RelationTuple, len(living))
+	for _, tpl := range living {
+		stored[tuple.String(tpl)] = tpl
+	}
+
+	references := make(map[string]int, len(mutations))
+	for _, mut := range mutations {
+		references[tuple.String(mut.Tuple)]++
+	}
+
+	filtered := make([]*core.RelationTupleUpdate, 0, len(mutations))
+	for _, mut := range mutations {
+		if mut.Operation == core.RelationTupleUpdate_TOUCH {
+			key := tuple.String(mut.Tuple)
+			if existing, ok := stored[key]; ok && references[key] == 1 && sameCaveat(existing.Caveat, mut.Tuple.Caveat) {
+				continue
+			}
+		}
+		filtered = append(filtered, mut)
+	}
+	return filtered
+}
+
+func sameCaveat(first, second *core.ContextualizedCaveat) bool {
+	if first.GetCaveatName() != second.GetCaveatName() {
+		return false
+	}
+
+	firstContext, secondContext := first.GetContext(), second.GetContext()
+	if firstContext == nil {
+		firstContext = &structpb.Struct{}
+	}
+	if secondContext == nil {
+		secondContext = &structpb.Struct{}
+	}
+	return proto.Equal(firstContext, secondContext)
+}
@@ -0,0 +1,81 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func withCaveatContext(tpl *core.RelationTuple, name string, context map[string]any) *core.RelationTuple {
+	tpl = tuple.WithCaveat(tpl, name)
+	strct, err := structpb.NewStruct(context)
+	if err != nil {
+		panic(err)
+	}
+	tpl.Caveat.Context = strct
+	return tpl
+}
+
+func TestWithoutNoopTouches(t *testing.T) {
+	plain := tuple.MustParse(tuple1)
+	caveated := withCaveatContext(tuple.MustParse(tuple2), "somecaveat", map[string]any{"limit": 2})
+
+	testCases := []struct {
+		name      string
+		mutations []*core.RelationTupleUpdate
+		living    []*core.RelationTuple
+		expected  []*core.RelationTupleUpdate
+	}{
+		{
+			"nothing stored",
+			[]*core.RelationTupleUpdate{tuple.Touch(plain)},
+			nil,
+			[]*core.RelationTupleUpdate{tuple.Touch(plain)},
+		},
+		{
+			"identical relationships",
+			[]*core.RelationTupleUpdate{tuple.Touch(plain), tuple.Touch(caveated)},
+			[]*core.RelationTuple{plain, caveated},
+			[]*core.RelationTupleUpdate{},
+		},
+		{
+			"empty caveat context",
+			[]*core.RelationTupleUpdate{tuple.Touch(tuple.WithCaveat(plain, "somecaveat"))},
+			[]*core.RelationTuple{withCaveatContext(plain, "somecaveat", nil)},
+			[]*core.RelationTupleUpdate{},
+		},
+		{
+			"caveat added",
+			[]*core.RelationTupleUpdate{tuple.Touch(tuple.WithCaveat(plain, "somecaveat"))},
+			[]*core.RelationTuple{plain},
+			[]*core.RelationTupleUpdate{tuple.Touch(tuple.WithCaveat(plain, "somecaveat"))},
+		},
+		{
+			"caveat context changed",
+			[]*core.RelationTupleUpdate{tuple.Touch(withCaveatContext(caveated, "somecaveat", map[string]any{"limit": 3}))},
+			[]*core.RelationTuple{caveated},
+			[]*core.RelationTupleUpdate{tuple.Touch(withCaveatContext(caveated, "somecaveat", map[string]any{"limit": 3}))},
+		},
+		{
+			"other operations kept",
+			[]*core.RelationTupleUpdate{tuple.Create(caveated), tuple.Delete(plain), tuple.Touch(plain)},
+			[]*core.RelationTuple{plain, caveated},
+			[]*core.RelationTupleUpdate{tuple.Create(caveated), tuple.Delete(plain), tuple.Touch(plain)},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			filtered := WithoutNoopTouches(tc.mutations, tc.living)
+			require.Len(t, filtered, len(tc.expected))
+			for index, mut := range tc.expected {
+				require.True(t, mut.EqualVT(filtered[index]), "unexpected mutation #%d", index)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package compact
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// DefaultBatchSize is the default number of pairs of relationship versions merged per
+// statement.
+const DefaultBatchSize = 1_000
+
+// ErrUnsupported is returned for datastores which do not keep the earlier versions of
+// relationships, or cannot merge them.
+var ErrUnsupported = errors.New("datastore does not support compacting relationship history")
+
+// Options configure a compaction.
+type Options struct {
+	// BatchSize is the number of pairs of relationship versions merged per statement.
+	BatchSize int
+
+	// BatchInterval is the minimum time between statements, which bounds the write load
+	// of compacting a large history.
+	BatchInterval time.Duration
+
+	// OnProgress, if set, is invoked after each batch with the number of pairs merged so
+	// far.
+	OnProgress func(merged uint64)
+}
+
+// Run merges the consecutive identical versions of relationships left by rewrites which
+// changed nothing, such as repeated TOUCHes, until none remain. It requires the datastore,
+// or one it wraps, to implement common.RelationshipHistoryCompactor.
+func Run(ctx context.Context, ds datastore.Datastore, opts Options) (uint64, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	compactor, ok := datastore.UnwrapAs[common.RelationshipHistoryCompactor](ds)
+	if !ok {
+		return 0, ErrUnsupported
+	}
+
+	var merged uint64
+	for {
+		count, err := compactor.CompactRelationshipHistory(ctx, uint64(opts.BatchSize))
+		if err != nil {
+			return merged, err
+		}
+		if count == 0 {
+			return merged, nil
+		}
+
+		merged += uint64(count)
+		log.Ctx(ctx).Debug().Uint64("merged", merged).Msg("compacted relationship history")
+		if opts.OnProgress != nil {
+			opts.OnProgress(merged)
+		}
+
+		if err := wait(ctx, opts.BatchInterval); err != nil {
+			return merged, err
+		}
+	}
+}
+
+func wait(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
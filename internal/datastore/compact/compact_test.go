@@ -0,0 +1,70 @@
+package compact
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/proxy"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// fakeCompactor merges versions from a fixed number of chains of the given lengths, one
+// version of each chain per call, as the postgres datastore does.
+type fakeCompactor struct {
+	datastore.Datastore
+	chains []int
+	calls  int
+}
+
+func (fc *fakeCompactor) CompactRelationshipHistory(_ context.Context, limit uint64) (int64, error) {
+	fc.calls++
+	var merged int64
+	for index, remaining := range fc.chains {
+		if remaining > 0 && uint64(merged) < limit {
+			fc.chains[index]--
+			merged++
+		}
+	}
+	return merged, nil
+}
+
+func TestRun(t *testing.T) {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	compactor := &fakeCompactor{Datastore: rawDS, chains: []int{3, 1, 1}}
+	var progress []uint64
+	merged, err := Run(context.Background(), proxy.NewObservableDatastoreProxy(compactor), Options{
+		BatchSize: 2,
+		OnProgress: func(merged uint64) {
+			progress = append(progress, merged)
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), merged)
+	require.Equal(t, []uint64{2, 4, 5}, progress)
+	require.Equal(t, 4, compactor.calls)
+}
+
+func TestRunBatchInterval(t *testing.T) {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	start := time.Now()
+	merged, err := Run(context.Background(), &fakeCompactor{Datastore: rawDS, chains: []int{2}}, Options{BatchInterval: 25 * time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), merged)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestRunUnsupported(t *testing.T) {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	_, err = Run(context.Background(), rawDS, Options{})
+	require.ErrorIs(t, err, ErrUnsupported)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/IBM/pgxpoolprometheus"
@@ -45,6 +46,8 @@ const (
 	tableTransactions = "transactions"
 	tableCaveat       = "caveat"
 
+	indexTupleBySubjectType = "ix_relation_tuple_by_subject_type"
+
 	colNamespace         = "namespace"
 	colConfig            = "serialized_config"
 	colTimestamp         = "timestamp"
@@ -92,6 +95,10 @@ func newCRDBDatastore(url string, options ...Option) (datastore.Datastore, error
 		if err := prometheus.Register(collector); err != nil {
 			return nil, fmt.Errorf(errUnableToInstantiate, err)
 		}
+		poolCollector := common.NewPoolCollector("spicedb", "default", pgxcommon.PoolStats(pool))
+		if err := prometheus.Register(poolCollector); err != nil {
+			return nil, fmt.Errorf(errUnableToInstantiate, err)
+		}
 		if err := common.RegisterGCMetrics(); err != nil {
 			return nil, fmt.Errorf(errUnableToInstantiate, err)
 		}
@@ -134,21 +141,24 @@ func newCRDBDatastore(url string, options ...Option) (datastore.Datastore, error
 	maxRevisionStaleness := time.Duration(float64(config.revisionQuantization.Nanoseconds())*
 		config.maxRevisionStalenessPercent) * time.Nanosecond
 
+	annotator := common.NewQueryAnnotator(config.requestIDQueryComments, config.queryTagging, config.queryTagNodeID)
+
 	ds := &crdbDatastore{
 		revisions.NewRemoteClockRevisions(
 			config.gcWindow,
 			maxRevisionStaleness,
 			config.followerReadDelay,
-			config.revisionQuantization,
+			revisions.NewAdaptiveQuantization(config.revisionQuantization, config.maxRevisionQuantization),
 		),
 		revision.DecimalDecoder{},
 		url,
-		pool,
+		pgxcommon.NewAnnotatedPool(pool, annotator),
 		config.watchBufferLength,
 		keyer,
 		config.splitAtUsersetCount,
 		executeWithMaxRetries(config.maxRetries),
 		config.disableStats,
+		atomic.Pointer[string]{},
 	}
 
 	ds.RemoteClockRevisions.SetNowFunc(ds.headRevisionInternal)
@@ -195,12 +205,13 @@ type crdbDatastore struct {
 	revision.DecimalDecoder
 
 	dburl             string
-	pool              *pgxpool.Pool
+	pool              *pgxcommon.AnnotatedPool
 	watchBufferLength uint16
 	writeOverlapKeyer overlapKeyer
 	usersetBatchSize  uint16
 	execute           executeTxRetryFunc
 	disableStats      bool
+	uniqueID          atomic.Pointer[string]
 }
 
 func (cds *crdbDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
@@ -300,6 +311,7 @@ func (cds *crdbDatastore) ReadWriteTx(
 		return datastore.NoRevision, err
 	}
 
+	cds.RevisionQuantization().RecordWrite()
 	return commitTimestamp, nil
 }
 
@@ -411,3 +423,11 @@ func readClusterTTLNanos(conn *pgxpool.Pool) (int64, error) {
 func revisionFromTimestamp(t time.Time) revision.Decimal {
 	return revision.NewFromDecimal(decimal.NewFromInt(t.UnixNano()))
 }
+
+// RevisionCommitTime returns the wall time of the revision, which is the HLC commit
+// timestamp of its transaction.
+func (cds *crdbDatastore) RevisionCommitTime(rev datastore.Revision) (time.Time, bool) {
+	return common.DecimalCommitTime(rev)
+}
+
+var _ common.CommitTimeReporter = &crdbDatastore{}
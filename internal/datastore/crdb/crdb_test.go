@@ -16,6 +16,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -24,6 +25,8 @@ import (
 	"github.com/ory/dockertest/v3"
 	"github.com/stretchr/testify/require"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/common/sqlgolden"
 	crdbmigrations "github.com/authzed/spicedb/internal/datastore/crdb/migrations"
 	testdatastore "github.com/authzed/spicedb/internal/testserver/datastore"
 	"github.com/authzed/spicedb/pkg/datastore"
@@ -95,6 +98,71 @@ func TestCRDBDatastoreWithFollowerReads(t *testing.T) {
 	}
 }
 
+// TestSubjectTypeIndex checks that the add-subject-type-index migration creates the
+// index by subject type, and that reverse queries for all the subjects of a type use it.
+func TestSubjectTypeIndex(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	uri := testdatastore.RunCRDBForTesting(t, "").NewDatabase(t)
+	conn, err := pgx.Connect(ctx, uri)
+	require.NoError(err)
+	defer conn.Close(ctx)
+
+	hasIndex := func() bool {
+		var exists bool
+		require.NoError(conn.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM information_schema.statistics WHERE table_name = $1 AND index_name = $2)",
+			tableTuple, indexTupleBySubjectType,
+		).Scan(&exists))
+		return exists
+	}
+
+	migrationDriver, err := crdbmigrations.NewCRDBDriver(uri)
+	require.NoError(err)
+	require.NoError(crdbmigrations.CRDBMigrations.Run(ctx, migrationDriver, "add-caveats", migrate.LiveRun))
+	require.False(hasIndex())
+
+	require.NoError(crdbmigrations.CRDBMigrations.Run(ctx, migrationDriver, "add-subject-type-index", migrate.LiveRun))
+	require.True(hasIndex())
+
+	require.NoError(crdbmigrations.CRDBMigrations.Run(ctx, migrationDriver, migrate.Head, migrate.LiveRun))
+	require.True(hasIndex())
+
+	explainer := &sqlgolden.Explainer{
+		Explain: func(ctx context.Context, sql string, args []any) (string, error) {
+			rows, err := conn.Query(ctx, "EXPLAIN "+sql, args...)
+			if err != nil {
+				return "", err
+			}
+			defer rows.Close()
+
+			var plan []string
+			for rows.Next() {
+				var line string
+				if err := rows.Scan(&line); err != nil {
+					return "", err
+				}
+				plan = append(plan, line)
+			}
+			return strings.Join(plan, "\n"), rows.Err()
+		},
+	}
+	reader := &crdbReader{
+		querySplitter: common.TupleQuerySplitter{Executor: explainer.ExecuteQuery, UsersetBatchSize: 100},
+		keyer:         noOverlapKeyer,
+		overlapKeySet: make(keySet),
+		execute:       executeOnce,
+	}
+
+	iter, err := reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{SubjectType: "user"})
+	require.NoError(err)
+	iter.Close()
+
+	require.Len(explainer.Plans, 1)
+	require.Contains(explainer.Plans[0], tableTuple+"@"+indexTupleBySubjectType)
+}
+
 func TestWatchFeatureDetection(t *testing.T) {
 	pool, err := dockertest.NewPool("")
 	require.NoError(t, err)
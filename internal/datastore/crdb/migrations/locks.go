@@ -0,0 +1,30 @@
+package migrations
+
+import "strings"
+
+func init() {
+	CRDBMigrations.SetLockEstimator(estimateLockImpact)
+}
+
+// estimateLockImpact estimates the impact of a migration statement on concurrent traffic.
+// CockroachDB performs schema changes online, so none of them block reads or writes.
+func estimateLockImpact(statement string) string {
+	stmt := strings.ToUpper(strings.TrimSpace(statement))
+
+	switch {
+	case strings.HasPrefix(stmt, "CREATE TABLE"):
+		return "none: the table is new"
+
+	case strings.HasPrefix(stmt, "CREATE INDEX"):
+		return "online schema change: reads and writes continue while the index is backfilled"
+
+	case strings.HasPrefix(stmt, "ALTER TABLE"):
+		return "online schema change: reads and writes continue"
+
+	case strings.HasPrefix(stmt, "INSERT"), strings.HasPrefix(stmt, "UPDATE"), strings.HasPrefix(stmt, "DELETE"):
+		return "row write intents: concurrent writes to the affected rows wait"
+
+	default:
+		return "unknown"
+	}
+}
@@ -54,4 +54,9 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := CRDBMigrations.Describe("initial", createNamespaceConfig, createRelationTuple, createSchemaVersion,
+		insertEmptyVersion, createReverseQueryIndex, createReverseCheckIndex); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
@@ -20,4 +20,8 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := CRDBMigrations.Describe("add-transactions-table", createTransactions); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
@@ -35,4 +35,8 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := CRDBMigrations.Describe("add-metadata-and-counters", createMetadataTable, createCounters, insertUniqueID); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
@@ -24,6 +24,10 @@ func init() {
 	if err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := CRDBMigrations.Describe("add-caveats", createCaveatTable, addRelationshipCaveatContext); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
 
 func addCaveatFunc(ctx context.Context, conn *pgx.Conn) error {
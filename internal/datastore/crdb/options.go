@@ -14,6 +14,7 @@ type crdbOptions struct {
 
 	watchBufferLength           uint16
 	revisionQuantization        time.Duration
+	maxRevisionQuantization     time.Duration
 	followerReadDelay           time.Duration
 	maxRevisionStalenessPercent float64
 	gcWindow                    time.Duration
@@ -22,6 +23,9 @@ type crdbOptions struct {
 	overlapStrategy             string
 	overlapKey                  string
 	disableStats                bool
+	requestIDQueryComments      bool
+	queryTagging                bool
+	queryTagNodeID              string
 
 	enablePrometheusStats bool
 }
@@ -78,6 +82,14 @@ func generateConfig(options []Option) (crdbOptions, error) {
 		)
 	}
 
+	if computed.maxRevisionQuantization >= computed.gcWindow {
+		return computed, fmt.Errorf(
+			errQuantizationTooLarge,
+			computed.maxRevisionQuantization,
+			computed.gcWindow,
+		)
+	}
+
 	return computed, nil
 }
 
@@ -169,6 +181,18 @@ func RevisionQuantization(bucketSize time.Duration) Option {
 	}
 }
 
+// MaxRevisionQuantization is the largest time bucket size to which advertised
+// revisions will be rounded. If above RevisionQuantization, the bucket size adapts
+// between the two, widening while writes are frequent and the dispatch cache hit ratio
+// is low.
+//
+// This value defaults to 0, for a bucket size which does not adapt.
+func MaxRevisionQuantization(bucketSize time.Duration) Option {
+	return func(po *crdbOptions) {
+		po.maxRevisionQuantization = bucketSize
+	}
+}
+
 // FollowerReadDelay is the time delay to apply to enable historial reads.
 //
 // This value defaults to 0 seconds.
@@ -240,3 +264,37 @@ func WithEnablePrometheusStats(enablePrometheusStats bool) Option {
 		po.enablePrometheusStats = enablePrometheusStats
 	}
 }
+
+// RequestIDQueryComments marks whether queries are annotated with a SQL comment carrying
+// the ID of the API request which caused them, so that they can be correlated with
+// entries in CockroachDB's statement diagnostics and slow query log.
+//
+// Disabled by default.
+func RequestIDQueryComments(enabled bool) Option {
+	return func(po *crdbOptions) {
+		po.requestIDQueryComments = enabled
+	}
+}
+
+// QueryTagging marks whether queries are annotated, besides the request ID of
+// RequestIDQueryComments, with the API method which caused them and the ID of the node
+// issuing them, so that database load can be attributed to call sites from CockroachDB's
+// active statements and slow query log. Statement statistics are aggregated by
+// fingerprints which strip comments, and so do not carry the annotations.
+//
+// Disabled by default.
+func QueryTagging(enabled bool) Option {
+	return func(po *crdbOptions) {
+		po.queryTagging = enabled
+	}
+}
+
+// QueryTagNodeID is the ID of the node with which queries are tagged when QueryTagging
+// is enabled.
+//
+// Empty by default, in which case queries are not tagged with a node ID.
+func QueryTagNodeID(nodeID string) Option {
+	return func(po *crdbOptions) {
+		po.queryTagNodeID = nodeID
+	}
+}
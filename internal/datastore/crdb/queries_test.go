@@ -0,0 +1,21 @@
+package crdb
+
+import (
+	"testing"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/common/sqlgolden"
+)
+
+func TestQueryShapes(t *testing.T) {
+	recorder := &sqlgolden.Recorder{}
+	reader := &crdbReader{
+		querySplitter: common.TupleQuerySplitter{Executor: recorder.ExecuteQuery, UsersetBatchSize: 100},
+		keyer:         noOverlapKeyer,
+		overlapKeySet: make(keySet),
+		execute:       executeOnce,
+	}
+	rwt := &crdbReadWriteTXN{reader, recorder.PgxTx(), 0}
+
+	sqlgolden.Assert(t, "testdata/queries.golden", recorder, reader, rwt)
+}
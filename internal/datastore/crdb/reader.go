@@ -35,6 +35,10 @@ var (
 		colCaveatContext,
 	).From(tableTuple)
 
+	// queryTuplesBySubject reads relationships through the index by subject type, which
+	// serves the subject-first scans of reverse queries.
+	queryTuplesBySubject = queryTuples.From(fmt.Sprintf("%s@%s", tableTuple, indexTupleBySubjectType))
+
 	schema = common.SchemaInformation{
 		ColNamespace:        colNamespace,
 		ColObjectID:         colObjectID,
@@ -161,7 +165,7 @@ func (cr *crdbReader) ReverseQueryRelationships(
 	subjectsFilter datastore.SubjectsFilter,
 	opts ...options.ReverseQueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuples).
+	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuplesBySubject).
 		FilterWithSubjectsFilter(subjectsFilter)
 
 	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
@@ -0,0 +1,284 @@
+// Package differential runs the same randomized sequence of writes, reads and checks
+// against two datastores, and fails if their results differ at matching revisions. One
+// datastore, typically memdb, acts as the oracle for the other.
+package differential
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const schema = `definition user {}
+
+definition group {
+	relation member: user | group#member
+}
+
+definition document {
+	relation owner: user
+	relation viewer: user | group#member
+	permission edit = owner
+	permission view = viewer + edit
+}`
+
+const (
+	numObjects = 6
+	maxDepth   = 50
+)
+
+// Config describes the sequence of operations run against the datastores.
+type Config struct {
+	// Seed seeds the random sequence, so that a failing sequence can be reproduced.
+	Seed int64
+
+	// Steps is the number of operations in the sequence.
+	Steps int
+}
+
+// side is one of the two datastores under comparison, along with the revision produced by
+// each of its writes.
+type side struct {
+	name       string
+	ctx        context.Context
+	ds         datastore.Datastore
+	dispatcher dispatch.Dispatcher
+	revisions  []datastore.Revision
+}
+
+func newSide(t *testing.T, name string, ds datastore.Datastore) *side {
+	ds, rev := testfixtures.DatastoreFromSchemaAndTestRelationships(ds, schema, nil, require.New(t))
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(t, datastoremw.SetInContext(ctx, ds))
+
+	return &side{
+		name:       name,
+		ctx:        ctx,
+		ds:         ds,
+		dispatcher: graph.NewLocalOnlyDispatcher(10),
+		revisions:  []datastore.Revision{rev},
+	}
+}
+
+// Run writes the same schema to both datastores, which must be empty, then runs the
+// sequence of operations described by the config against both. Reads and checks are run
+// at revisions chosen from those produced by earlier writes, in each datastore, so that
+// the visibility of every past write is compared, not only that of the latest.
+func Run(t *testing.T, config Config, oracle, candidate datastore.Datastore) {
+	sides := []*side{newSide(t, "oracle", oracle), newSide(t, "candidate", candidate)}
+	rnd := rand.New(rand.NewSource(config.Seed))
+
+	for step := 0; step < config.Steps; step++ {
+		op := randomOperation(rnd, len(sides[0].revisions))
+
+		results := make([]string, len(sides))
+		for i, s := range sides {
+			results[i] = op.apply(s)
+		}
+
+		if results[0] != results[1] {
+			t.Fatalf("seed %d, step %d: %s\noracle:\n%s\ncandidate:\n%s", config.Seed, step, op, results[0], results[1])
+		}
+	}
+}
+
+type operation interface {
+	fmt.Stringer
+
+	// apply runs the operation against the side, returning a description of its result
+	// which must be identical for both sides.
+	apply(s *side) string
+}
+
+func randomOperation(rnd *rand.Rand, numRevisions int) operation {
+	switch n := rnd.Intn(10); {
+	case n < 4:
+		return randomWrite(rnd)
+	case n < 5:
+		return deleteOperation{filter: randomFilter(rnd)}
+	case n < 7:
+		return queryOperation{revision: rnd.Intn(numRevisions), filter: randomFilter(rnd)}
+	default:
+		return checkOperation{
+			revision:   rnd.Intn(numRevisions),
+			resourceID: randomID(rnd),
+			permission: []string{"owner", "viewer", "edit", "view"}[rnd.Intn(4)],
+			userID:     randomID(rnd),
+		}
+	}
+}
+
+func randomID(rnd *rand.Rand) string {
+	return fmt.Sprintf("o%d", rnd.Intn(numObjects))
+}
+
+func randomRelationship(rnd *rand.Rand) *core.RelationTuple {
+	switch rnd.Intn(4) {
+	case 0:
+		return tuple.MustParse(fmt.Sprintf("document:%s#owner@user:%s", randomID(rnd), randomID(rnd)))
+	case 1:
+		return tuple.MustParse(fmt.Sprintf("document:%s#viewer@group:%s#member", randomID(rnd), randomID(rnd)))
+	case 2:
+		return tuple.MustParse(fmt.Sprintf("group:%s#member@user:%s", randomID(rnd), randomID(rnd)))
+	default:
+		return tuple.MustParse(fmt.Sprintf("document:%s#viewer@user:%s", randomID(rnd), randomID(rnd)))
+	}
+}
+
+func randomFilter(rnd *rand.Rand) *v1.RelationshipFilter {
+	filter := &v1.RelationshipFilter{ResourceType: []string{"document", "group"}[rnd.Intn(2)]}
+	if rnd.Intn(2) == 0 {
+		filter.OptionalResourceId = randomID(rnd)
+	}
+	if rnd.Intn(3) == 0 {
+		filter.OptionalSubjectFilter = &v1.SubjectFilter{SubjectType: "user", OptionalSubjectId: randomID(rnd)}
+	}
+	return filter
+}
+
+// writeOperation applies a batch of updates to distinct relationships in a transaction.
+type writeOperation struct {
+	updates []*core.RelationTupleUpdate
+}
+
+func randomWrite(rnd *rand.Rand) writeOperation {
+	seen := make(map[string]struct{})
+	var updates []*core.RelationTupleUpdate
+	for i := rnd.Intn(5); i >= 0; i-- {
+		rel := randomRelationship(rnd)
+		if _, ok := seen[tuple.String(rel)]; ok {
+			continue
+		}
+		seen[tuple.String(rel)] = struct{}{}
+
+		switch rnd.Intn(5) {
+		case 0:
+			updates = append(updates, tuple.Create(rel))
+		case 1, 2:
+			updates = append(updates, tuple.Delete(rel))
+		default:
+			updates = append(updates, tuple.Touch(rel))
+		}
+	}
+	return writeOperation{updates: updates}
+}
+
+func (op writeOperation) String() string {
+	updates := make([]string, 0, len(op.updates))
+	for _, update := range op.updates {
+		updates = append(updates, fmt.Sprintf("%s(%s)", update.Operation, tuple.String(update.Tuple)))
+	}
+	return "write " + strings.Join(updates, ", ")
+}
+
+func (op writeOperation) apply(s *side) string {
+	rev, err := s.ds.ReadWriteTx(s.ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(s.ctx, op.updates)
+	})
+	if err != nil {
+		// Only whether the write failed is compared, as each engine reports errors in its own words.
+		return "failed"
+	}
+	s.revisions = append(s.revisions, rev)
+	return "written"
+}
+
+// deleteOperation deletes the relationships matching a filter.
+type deleteOperation struct {
+	filter *v1.RelationshipFilter
+}
+
+func (op deleteOperation) String() string {
+	return fmt.Sprintf("delete %v", op.filter)
+}
+
+func (op deleteOperation) apply(s *side) string {
+	rev, err := s.ds.ReadWriteTx(s.ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.DeleteRelationships(s.ctx, op.filter)
+	})
+	if err != nil {
+		return "failed"
+	}
+	s.revisions = append(s.revisions, rev)
+	return "deleted"
+}
+
+// queryOperation reads the relationships matching a filter, at the revision produced by
+// an earlier write.
+type queryOperation struct {
+	revision int
+	filter   *v1.RelationshipFilter
+}
+
+func (op queryOperation) String() string {
+	return fmt.Sprintf("query %v at revision %d", op.filter, op.revision)
+}
+
+func (op queryOperation) apply(s *side) string {
+	iter, err := s.ds.SnapshotReader(s.revisions[op.revision]).QueryRelationships(s.ctx, datastore.RelationshipsFilterFromPublicFilter(op.filter))
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	defer iter.Close()
+
+	var found []string
+	for rel := iter.Next(); rel != nil; rel = iter.Next() {
+		found = append(found, tuple.String(rel))
+	}
+	if iter.Err() != nil {
+		return "error: " + iter.Err().Error()
+	}
+
+	sort.Strings(found)
+	return strings.Join(found, "\n")
+}
+
+// checkOperation checks a permission of a document for a user, at the revision produced
+// by an earlier write.
+type checkOperation struct {
+	revision   int
+	resourceID string
+	permission string
+	userID     string
+}
+
+func (op checkOperation) String() string {
+	return fmt.Sprintf("check document:%s#%s@user:%s at revision %d", op.resourceID, op.permission, op.userID, op.revision)
+}
+
+func (op checkOperation) apply(s *side) string {
+	resp, err := s.dispatcher.DispatchCheck(s.ctx, &dispatchv1.DispatchCheckRequest{
+		ResourceRelation: &core.RelationReference{Namespace: "document", Relation: op.permission},
+		ResourceIds:      []string{op.resourceID},
+		ResultsSetting:   dispatchv1.DispatchCheckRequest_ALLOW_SINGLE_RESULT,
+		Subject:          &core.ObjectAndRelation{Namespace: "user", ObjectId: op.userID, Relation: tuple.Ellipsis},
+		Metadata: &dispatchv1.ResolverMeta{
+			AtRevision:     s.revisions[op.revision].String(),
+			DepthRemaining: maxDepth,
+		},
+	})
+	if err != nil {
+		return "error: " + err.Error()
+	}
+
+	if result, ok := resp.ResultsByResourceId[op.resourceID]; ok {
+		return result.Membership.String()
+	}
+	return dispatchv1.ResourceCheckResult_NOT_MEMBER.String()
+}
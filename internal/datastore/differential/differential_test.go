@@ -0,0 +1,20 @@
+package differential
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+)
+
+func TestRunAgainstItself(t *testing.T) {
+	for seed := int64(0); seed < 5; seed++ {
+		oracle, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+		require.NoError(t, err)
+		candidate, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+		require.NoError(t, err)
+
+		Run(t, Config{Seed: seed, Steps: 200}, oracle, candidate)
+	}
+}
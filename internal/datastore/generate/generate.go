@@ -0,0 +1,395 @@
+// Package generate writes a schema and a synthetic graph of relationships for it, shaped
+// by statistical parameters, directly into a datastore, for load testing and capacity
+// planning against a realistic volume of data.
+package generate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/services/shared"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// DefaultBatchSize is the default number of relationships written per transaction.
+const DefaultBatchSize = 1_000
+
+// Parameters shape the generated graph.
+type Parameters struct {
+	// ObjectsPerType is the number of objects generated of each object type, by the name of
+	// the type. Types not given have DefaultObjects objects.
+	ObjectsPerType map[string]int
+
+	// DefaultObjects is the number of objects generated of the types not in ObjectsPerType.
+	DefaultObjects int
+
+	// Densities are the mean number of subjects of each relation of each resource, by the
+	// relation, as `type#relation`. Relations not given have a density of DefaultDensity.
+	// The number of subjects of each resource is drawn from an exponential distribution
+	// with that mean, so that a few resources have many more subjects than most.
+	Densities map[string]float64
+
+	// DefaultDensity is the density of the relations not in Densities.
+	DefaultDensity float64
+
+	// MaxDepth is the longest chain of objects of a type nested in one another, through
+	// relations whose subjects are of the same type as their resource, such as the parent
+	// of a folder or the members of a group. Objects are divided into MaxDepth+1 levels,
+	// and their subjects of their own type drawn from the level beneath them, so the graph
+	// also has no cycles through such relations.
+	MaxDepth int
+
+	// Skew, if set, is the exponent of the Zipf distribution from which subjects are
+	// drawn, which must be greater than 1: the larger it is, the more the relationships
+	// of a relation are concentrated on its most popular subjects. Subjects are drawn
+	// uniformly if it is zero.
+	Skew float64
+
+	// WildcardRatio is the fraction, from 0 to 1, of the resources of relations allowing
+	// a wildcard subject which are given one, in addition to their other subjects.
+	WildcardRatio float64
+
+	// Seed seeds the generation of the graph. The same schema, parameters and seed always
+	// generate the same graph.
+	Seed int64
+}
+
+// Options configure the writing of a generated graph.
+type Options struct {
+	// BatchSize is the number of relationships written per transaction.
+	BatchSize int
+
+	// OnProgress, if set, is invoked after each transaction with the number of
+	// relationships written so far.
+	OnProgress func(written uint64)
+}
+
+// Result summarizes a generated graph.
+type Result struct {
+	// Relationships is the number of relationships written.
+	Relationships uint64
+
+	// RelationshipsPerRelation is the number of relationships written of each relation, as
+	// `type#relation`.
+	RelationshipsPerRelation map[string]uint64
+}
+
+// Run writes the schema to the datastore, replacing its schema as WriteSchema would, and
+// then writes a graph of relationships for it generated with the parameters. Only the
+// relations of the schema are populated; its permissions are computed from them.
+//
+// Relationships are touched rather than created, so a generation interrupted can be run
+// again with the same seed to write the relationships it had not.
+func Run(ctx context.Context, ds datastore.Datastore, schemaText string, params Parameters, opts Options) (Result, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	emptyDefaultPrefix := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("schema"),
+		SchemaString: schemaText,
+	}, &emptyDefaultPrefix)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if err := params.validate(compiled); err != nil {
+		return Result{}, err
+	}
+
+	validated, err := shared.ValidateSchemaChanges(ctx, compiled, false)
+	if err != nil {
+		return Result{}, err
+	}
+	if _, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		_, err := shared.ApplySchemaChanges(ctx, rwt, validated)
+		return err
+	}); err != nil {
+		return Result{}, fmt.Errorf("unable to write schema: %w", err)
+	}
+
+	g := &generator{
+		ds:      ds,
+		params:  params,
+		opts:    opts,
+		rng:     rand.New(rand.NewSource(params.Seed)),
+		zipfs:   make(map[int]*rand.Zipf),
+		objects: make(map[string]int, len(compiled.ObjectDefinitions)),
+		result:  Result{RelationshipsPerRelation: make(map[string]uint64)},
+		started: time.Now(),
+	}
+	for _, def := range compiled.ObjectDefinitions {
+		g.objects[def.Name] = params.objectCount(def.Name)
+	}
+
+	for _, def := range compiled.ObjectDefinitions {
+		for _, relation := range def.Relation {
+			if relation.UsersetRewrite != nil || relation.TypeInformation == nil {
+				continue
+			}
+			if err := g.relation(ctx, def.Name, relation); err != nil {
+				return g.result, err
+			}
+		}
+	}
+	return g.result, g.flush(ctx)
+}
+
+func (p Parameters) objectCount(objectType string) int {
+	if count, ok := p.ObjectsPerType[objectType]; ok {
+		return count
+	}
+	return p.DefaultObjects
+}
+
+func (p Parameters) density(objectType, relation string) float64 {
+	if density, ok := p.Densities[objectType+"#"+relation]; ok {
+		return density
+	}
+	return p.DefaultDensity
+}
+
+// validate returns an error if the parameters cannot be applied to the schema, including
+// if they name types or relations which it does not define.
+func (p Parameters) validate(compiled *compiler.CompiledSchema) error {
+	switch {
+	case p.DefaultObjects < 0:
+		return errors.New("default objects must not be negative")
+	case p.DefaultDensity < 0:
+		return errors.New("default density must not be negative")
+	case p.MaxDepth < 0:
+		return errors.New("max depth must not be negative")
+	case p.Skew != 0 && p.Skew <= 1:
+		return errors.New("skew must be zero or greater than 1")
+	case p.WildcardRatio < 0 || p.WildcardRatio > 1:
+		return errors.New("wildcard ratio must be between 0 and 1")
+	}
+
+	relations := make(map[string]struct{})
+	types := make(map[string]struct{}, len(compiled.ObjectDefinitions))
+	for _, def := range compiled.ObjectDefinitions {
+		types[def.Name] = struct{}{}
+		for _, relation := range def.Relation {
+			if relation.UsersetRewrite == nil {
+				relations[def.Name+"#"+relation.Name] = struct{}{}
+			}
+		}
+	}
+
+	for objectType, count := range p.ObjectsPerType {
+		if _, ok := types[objectType]; !ok {
+			return fmt.Errorf("object type `%s` is not defined by the schema", objectType)
+		}
+		if count < 0 {
+			return fmt.Errorf("objects of `%s` must not be negative", objectType)
+		}
+	}
+	for relation, density := range p.Densities {
+		if _, ok := relations[relation]; !ok {
+			return fmt.Errorf("relation `%s` is not defined by the schema", relation)
+		}
+		if density < 0 {
+			return fmt.Errorf("density of `%s` must not be negative", relation)
+		}
+	}
+	return nil
+}
+
+type generator struct {
+	ds     datastore.Datastore
+	params Parameters
+	opts   Options
+	rng    *rand.Rand
+
+	// zipfs are the Zipf distributions from which subjects are drawn, by the number of
+	// objects drawn from.
+	zipfs map[int]*rand.Zipf
+
+	// objects is the number of objects of each object type.
+	objects map[string]int
+
+	pending []*core.RelationTupleUpdate
+	result  Result
+	started time.Time
+}
+
+// candidate is an allowed type of the subjects of a relation, with the range of the
+// objects of that type from which the subjects of a resource are drawn.
+type candidate struct {
+	allowed *core.AllowedRelation
+	lo, hi  int
+}
+
+// relation generates the relationships of a relation of every object of a type.
+func (g *generator) relation(ctx context.Context, objectType string, relation *core.Relation) error {
+	density := g.params.density(objectType, relation.Name)
+	allowedRelations := relation.TypeInformation.AllowedDirectRelations
+
+	var wildcards []*core.AllowedRelation
+	for _, allowed := range allowedRelations {
+		if allowed.GetPublicWildcard() != nil {
+			wildcards = append(wildcards, allowed)
+		}
+	}
+
+	resources := g.objects[objectType]
+	for id := 0; id < resources; id++ {
+		candidates := g.candidates(objectType, id, allowedRelations)
+		available := 0
+		for _, c := range candidates {
+			available += c.hi - c.lo
+		}
+
+		count := int(math.Round(g.rng.ExpFloat64() * density))
+		if count > available {
+			count = available
+		}
+
+		resource := &core.ObjectAndRelation{
+			Namespace: objectType,
+			ObjectId:  objectID(objectType, id),
+			Relation:  relation.Name,
+		}
+
+		chosen := make(map[string]struct{}, count)
+		for attempts := 0; len(chosen) < count && attempts < 4*count; attempts++ {
+			c := candidates[g.rng.Intn(len(candidates))]
+			if c.hi == c.lo {
+				continue
+			}
+
+			subject := &core.ObjectAndRelation{
+				Namespace: c.allowed.Namespace,
+				ObjectId:  objectID(c.allowed.Namespace, c.lo+g.draw(c.hi-c.lo)),
+				Relation:  c.allowed.GetRelation(),
+			}
+			key := tuple.StringONR(subject)
+			if _, ok := chosen[key]; ok {
+				continue
+			}
+			chosen[key] = struct{}{}
+
+			if err := g.write(ctx, resource, subject, c.allowed); err != nil {
+				return err
+			}
+		}
+
+		if len(wildcards) > 0 && g.rng.Float64() < g.params.WildcardRatio {
+			allowed := wildcards[g.rng.Intn(len(wildcards))]
+			subject := &core.ObjectAndRelation{
+				Namespace: allowed.Namespace,
+				ObjectId:  tuple.PublicWildcard,
+				Relation:  tuple.Ellipsis,
+			}
+			if err := g.write(ctx, resource, subject, allowed); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// candidates returns the allowed types of the subjects of a relation of an object,
+// excluding wildcards, with the objects from which its subjects of each are drawn.
+func (g *generator) candidates(objectType string, id int, allowedRelations []*core.AllowedRelation) []candidate {
+	candidates := make([]candidate, 0, len(allowedRelations))
+	for _, allowed := range allowedRelations {
+		if allowed.GetPublicWildcard() != nil {
+			continue
+		}
+
+		count := g.objects[allowed.Namespace]
+		if allowed.Namespace != objectType {
+			candidates = append(candidates, candidate{allowed: allowed, lo: 0, hi: count})
+			continue
+		}
+
+		// Objects nested in objects of their own type are drawn from the level beneath
+		// theirs, which bounds the depth of nesting and prevents cycles.
+		level := id * (g.params.MaxDepth + 1) / count
+		if level == 0 {
+			continue
+		}
+		lo, hi := g.levelRange(level-1, count)
+		candidates = append(candidates, candidate{allowed: allowed, lo: lo, hi: hi})
+	}
+	return candidates
+}
+
+// levelRange returns the range of the IDs of the objects of a level, out of count.
+func (g *generator) levelRange(level, count int) (int, int) {
+	levels := g.params.MaxDepth + 1
+	return (level*count + levels - 1) / levels, ((level+1)*count + levels - 1) / levels
+}
+
+// draw returns the index of a subject drawn from n.
+func (g *generator) draw(n int) int {
+	if g.params.Skew == 0 || n == 1 {
+		return g.rng.Intn(n)
+	}
+
+	zipf, ok := g.zipfs[n]
+	if !ok {
+		zipf = rand.NewZipf(g.rng, g.params.Skew, 1, uint64(n-1))
+		g.zipfs[n] = zipf
+	}
+	return int(zipf.Uint64())
+}
+
+func (g *generator) write(ctx context.Context, resource, subject *core.ObjectAndRelation, allowed *core.AllowedRelation) error {
+	if subject.Relation == "" {
+		subject.Relation = tuple.Ellipsis
+	}
+
+	rel := &core.RelationTuple{ResourceAndRelation: resource, Subject: subject}
+	if caveat := allowed.GetRequiredCaveat(); caveat != nil {
+		rel.Caveat = &core.ContextualizedCaveat{CaveatName: caveat.CaveatName}
+	}
+
+	g.pending = append(g.pending, tuple.Touch(rel))
+	g.result.RelationshipsPerRelation[resource.Namespace+"#"+resource.Relation]++
+	if len(g.pending) >= g.opts.BatchSize {
+		return g.flush(ctx)
+	}
+	return nil
+}
+
+func (g *generator) flush(ctx context.Context) error {
+	if len(g.pending) == 0 {
+		return nil
+	}
+
+	if _, err := g.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, g.pending)
+	}); err != nil {
+		return fmt.Errorf("unable to write relationships: %w", err)
+	}
+
+	g.result.Relationships += uint64(len(g.pending))
+	log.Ctx(ctx).Debug().
+		Uint64("written", g.result.Relationships).
+		Stringer("elapsed", time.Since(g.started)).
+		Msg("wrote generated relationships")
+	if g.opts.OnProgress != nil {
+		g.opts.OnProgress(g.result.Relationships)
+	}
+	g.pending = nil
+	return nil
+}
+
+// objectID returns the ID of the generated object of a type with an index, named after
+// the type without its prefix, if any.
+func objectID(objectType string, index int) string {
+	return fmt.Sprintf("%s_%d", objectType[strings.LastIndex(objectType, "/")+1:], index)
+}
@@ -0,0 +1,167 @@
+package generate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const testSchema = `definition user {}
+
+caveat on_weekdays(weekday bool) {
+	weekday
+}
+
+definition group {
+	relation member: user | group#member
+}
+
+definition folder {
+	relation parent: folder
+	relation viewer: user | user:* | group#member
+	permission view = viewer + parent->view
+}
+
+definition document {
+	relation parent: folder
+	relation viewer: user with on_weekdays
+	permission view = viewer + parent->view
+}
+`
+
+func TestRun(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	params := Parameters{
+		ObjectsPerType: map[string]int{"user": 100, "group": 10, "folder": 30},
+		DefaultObjects: 50,
+		Densities:      map[string]float64{"folder#parent": 1, "document#parent": 1},
+		DefaultDensity: 3,
+		MaxDepth:       2,
+		Skew:           1.5,
+		WildcardRatio:  0.2,
+		Seed:           42,
+	}
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	var progress []uint64
+	result, err := Run(ctx, ds, testSchema, params, Options{
+		BatchSize: 50,
+		OnProgress: func(written uint64) {
+			progress = append(progress, written)
+		},
+	})
+	require.NoError(err)
+	require.NotZero(result.Relationships)
+	require.Equal(result.Relationships, progress[len(progress)-1])
+
+	var perRelation uint64
+	for _, count := range result.RelationshipsPerRelation {
+		perRelation += count
+	}
+	require.Equal(result.Relationships, perRelation)
+
+	rev, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	reader := ds.SnapshotReader(rev)
+
+	namespaces, err := reader.ListNamespaces(ctx)
+	require.NoError(err)
+	require.Len(namespaces, 4)
+
+	relationships := readAll(t, reader)
+	require.Len(relationships, int(result.Relationships))
+
+	// Folders are nested at most two deep.
+	parents := make(map[string][]string)
+	for _, rel := range relationships {
+		switch {
+		case rel.ResourceAndRelation.Namespace == "folder" && rel.ResourceAndRelation.Relation == "parent":
+			parents[rel.ResourceAndRelation.ObjectId] = append(parents[rel.ResourceAndRelation.ObjectId], rel.Subject.ObjectId)
+		case rel.ResourceAndRelation.Namespace == "document" && rel.ResourceAndRelation.Relation == "viewer":
+			require.Equal("on_weekdays", rel.Caveat.CaveatName)
+		}
+	}
+	require.NotEmpty(parents)
+
+	var depth func(folder string) int
+	depth = func(folder string) int {
+		deepest := 0
+		for _, parent := range parents[folder] {
+			if d := depth(parent) + 1; d > deepest {
+				deepest = d
+			}
+		}
+		return deepest
+	}
+	deepest := 0
+	for folder := range parents {
+		if d := depth(folder); d > deepest {
+			deepest = d
+		}
+	}
+	require.Equal(2, deepest)
+
+	// The same parameters and seed generate the same graph.
+	other, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	_, err = Run(ctx, other, testSchema, params, Options{})
+	require.NoError(err)
+
+	otherRev, err := other.HeadRevision(ctx)
+	require.NoError(err)
+	require.ElementsMatch(tupleStrings(relationships), tupleStrings(readAll(t, other.SnapshotReader(otherRev))))
+}
+
+func TestRunInvalidParameters(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		params Parameters
+		err    string
+	}{
+		{"unknown type", Parameters{ObjectsPerType: map[string]int{"team": 1}}, "object type `team` is not defined by the schema"},
+		{"unknown relation", Parameters{Densities: map[string]float64{"folder#view": 1}}, "relation `folder#view` is not defined by the schema"},
+		{"negative density", Parameters{Densities: map[string]float64{"folder#viewer": -1}}, "density of `folder#viewer` must not be negative"},
+		{"invalid skew", Parameters{Skew: 0.5}, "skew must be zero or greater than 1"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(t, err)
+
+			_, err = Run(context.Background(), ds, testSchema, tc.params, Options{})
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func readAll(t *testing.T, reader datastore.Reader) []*core.RelationTuple {
+	var found []*core.RelationTuple
+	for _, objectType := range []string{"group", "folder", "document"} {
+		iter, err := reader.QueryRelationships(context.Background(), datastore.RelationshipsFilter{ResourceType: objectType})
+		require.NoError(t, err)
+		for rel := iter.Next(); rel != nil; rel = iter.Next() {
+			found = append(found, rel)
+		}
+		require.NoError(t, iter.Err())
+		iter.Close()
+	}
+	return found
+}
+
+func tupleStrings(relationships []*core.RelationTuple) []string {
+	strs := make([]string, 0, len(relationships))
+	for _, rel := range relationships {
+		strs = append(strs, tuple.String(rel))
+	}
+	return strs
+}
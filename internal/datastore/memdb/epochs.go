@@ -0,0 +1,24 @@
+package memdb
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+func (mdb *memdbDatastore) Epoch(_ context.Context) (uint64, error) {
+	mdb.RLock()
+	defer mdb.RUnlock()
+
+	return mdb.epoch, nil
+}
+
+func (mdb *memdbDatastore) BumpEpoch(_ context.Context) (uint64, error) {
+	mdb.Lock()
+	defer mdb.Unlock()
+
+	mdb.epoch++
+	return mdb.epoch, nil
+}
+
+var _ common.EpochStore = &memdbDatastore{}
@@ -0,0 +1,45 @@
+package memdb
+
+import (
+	"context"
+	"sort"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+func (mdb *memdbDatastore) SetNamespaceExperiment(_ context.Context, namespace, experiment string, enabled bool) error {
+	mdb.Lock()
+	defer mdb.Unlock()
+
+	key := datastore.NamespaceExperiment{Namespace: namespace, Experiment: experiment}
+	if !enabled {
+		delete(mdb.experiments, key)
+		return nil
+	}
+
+	if mdb.experiments == nil {
+		mdb.experiments = make(map[datastore.NamespaceExperiment]struct{})
+	}
+	mdb.experiments[key] = struct{}{}
+	return nil
+}
+
+func (mdb *memdbDatastore) ListNamespaceExperiments(_ context.Context) ([]datastore.NamespaceExperiment, error) {
+	mdb.RLock()
+	defer mdb.RUnlock()
+
+	experiments := make([]datastore.NamespaceExperiment, 0, len(mdb.experiments))
+	for experiment := range mdb.experiments {
+		experiments = append(experiments, experiment)
+	}
+	sort.Slice(experiments, func(i, j int) bool {
+		if experiments[i].Namespace != experiments[j].Namespace {
+			return experiments[i].Namespace < experiments[j].Namespace
+		}
+		return experiments[i].Experiment < experiments[j].Experiment
+	})
+	return experiments, nil
+}
+
+var _ common.NamespaceExperimentStore = &memdbDatastore{}
@@ -0,0 +1,82 @@
+package memdb
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+func (mdb *memdbDatastore) CreateJob(_ context.Context, job common.Job) error {
+	mdb.Lock()
+	defer mdb.Unlock()
+
+	if mdb.jobs == nil {
+		mdb.jobs = make(map[string]common.Job)
+	}
+	now := time.Now()
+	job.CreatedAt, job.UpdatedAt = now, now
+	mdb.jobs[job.ID] = job
+	return nil
+}
+
+func (mdb *memdbDatastore) UpdateJob(_ context.Context, job common.Job) error {
+	mdb.Lock()
+	defer mdb.Unlock()
+
+	stored, ok := mdb.jobs[job.ID]
+	if !ok {
+		return common.ErrJobNotFound
+	}
+	stored.State = job.State
+	stored.Progress = job.Progress
+	stored.Result = job.Result
+	stored.Error = job.Error
+	stored.UpdatedAt = time.Now()
+	mdb.jobs[job.ID] = stored
+	return nil
+}
+
+func (mdb *memdbDatastore) RequestJobCancellation(_ context.Context, id string) error {
+	mdb.Lock()
+	defer mdb.Unlock()
+
+	stored, ok := mdb.jobs[id]
+	if !ok {
+		return common.ErrJobNotFound
+	}
+	stored.CancelRequested = true
+	mdb.jobs[id] = stored
+	return nil
+}
+
+func (mdb *memdbDatastore) ReadJob(_ context.Context, id string) (common.Job, error) {
+	mdb.RLock()
+	defer mdb.RUnlock()
+
+	stored, ok := mdb.jobs[id]
+	if !ok {
+		return common.Job{}, common.ErrJobNotFound
+	}
+	return stored, nil
+}
+
+func (mdb *memdbDatastore) ListJobs(_ context.Context, limit uint64) ([]common.Job, error) {
+	mdb.RLock()
+	defer mdb.RUnlock()
+
+	jobs := make([]common.Job, 0, len(mdb.jobs))
+	for _, job := range mdb.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	if limit > 0 && uint64(len(jobs)) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+var _ common.JobStore = &memdbDatastore{}
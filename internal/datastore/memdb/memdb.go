@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/go-memdb"
 	"github.com/shopspring/decimal"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 	corev1 "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -86,6 +87,13 @@ type memdbDatastore struct {
 	quantizationPeriod decimal.Decimal
 	watchBufferLength  uint16
 	uniqueID           string
+	epoch              uint64
+	pins               map[string]pinnedRevision
+	schemaVersions     []datastore.SchemaVersion
+	experiments        map[datastore.NamespaceExperiment]struct{}
+	pseudonyms         map[string][]byte
+	jobs               map[string]common.Job
+	tombstones         map[string]common.NamespaceTombstone
 }
 
 type snapshot struct {
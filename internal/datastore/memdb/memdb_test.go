@@ -77,3 +77,100 @@ func TestConcurrentWritePanic(t *testing.T) {
 	}, 1*time.Second, 10*time.Millisecond)
 	require.ErrorIs(err, recoverErr)
 }
+
+func TestPinnedRevisions(t *testing.T) {
+	require := require.New(t)
+
+	gcWindow := 10 * time.Millisecond
+	ds, err := NewMemdbDatastore(0, 0, gcWindow)
+	require.NoError(err)
+	mds := ds.(*memdbDatastore)
+
+	ctx := context.Background()
+	pinned, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	unpinned, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	require.NoError(mds.PinRevision(ctx, "first", pinned, time.Now().Add(time.Hour)))
+	require.NoError(mds.PinRevision(ctx, "expired", unpinned, time.Now().Add(-time.Second)))
+
+	time.Sleep(2 * gcWindow)
+
+	require.NoError(ds.CheckRevision(ctx, pinned))
+	require.ErrorAs(ds.CheckRevision(ctx, unpinned), &datastore.ErrInvalidRevision{})
+
+	require.ErrorAs(mds.ReleasePinnedRevision(ctx, "expired"), &datastore.ErrPinnedRevisionNotFound{})
+	require.NoError(mds.ReleasePinnedRevision(ctx, "first"))
+	require.ErrorAs(ds.CheckRevision(ctx, pinned), &datastore.ErrInvalidRevision{})
+	require.ErrorAs(mds.ReleasePinnedRevision(ctx, "first"), &datastore.ErrPinnedRevisionNotFound{})
+}
+
+func TestSchemaVersions(t *testing.T) {
+	require := require.New(t)
+
+	ds, err := NewMemdbDatastore(0, 0, DisableGC)
+	require.NoError(err)
+	mds := ds.(*memdbDatastore)
+
+	ctx := context.Background()
+	versions, err := mds.ListSchemaVersions(ctx)
+	require.NoError(err)
+	require.Empty(versions)
+
+	revision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	first, err := mds.AddSchemaVersion(ctx, revision, "definition user {}", "tom")
+	require.NoError(err)
+	require.Equal(uint64(1), first.Version)
+
+	second, err := mds.AddSchemaVersion(ctx, revision, "definition user {}\n\ndefinition document {}", "")
+	require.NoError(err)
+	require.Equal(uint64(2), second.Version)
+
+	versions, err = mds.ListSchemaVersions(ctx)
+	require.NoError(err)
+	require.Len(versions, 2)
+	require.Equal("tom", versions[0].Author)
+	require.Empty(versions[0].SchemaText)
+	require.True(revision.Equal(versions[1].Revision))
+
+	read, err := mds.ReadSchemaVersion(ctx, 1)
+	require.NoError(err)
+	require.Equal(first, read)
+
+	_, err = mds.ReadSchemaVersion(ctx, 3)
+	require.ErrorAs(err, &datastore.ErrSchemaVersionNotFound{})
+}
+
+func TestNamespaceExperiments(t *testing.T) {
+	require := require.New(t)
+
+	ds, err := NewMemdbDatastore(0, 0, DisableGC)
+	require.NoError(err)
+	mds := ds.(*memdbDatastore)
+
+	ctx := context.Background()
+	require.NoError(mds.SetNamespaceExperiment(ctx, "document", "wildcard-index", true))
+	require.NoError(mds.SetNamespaceExperiment(ctx, "document", "new-planner", true))
+	require.NoError(mds.SetNamespaceExperiment(ctx, "folder", "new-planner", true))
+	require.NoError(mds.SetNamespaceExperiment(ctx, "folder", "new-planner", true))
+	require.NoError(mds.SetNamespaceExperiment(ctx, "folder", "unknown", false))
+
+	experiments, err := mds.ListNamespaceExperiments(ctx)
+	require.NoError(err)
+	require.Equal([]datastore.NamespaceExperiment{
+		{Namespace: "document", Experiment: "new-planner"},
+		{Namespace: "document", Experiment: "wildcard-index"},
+		{Namespace: "folder", Experiment: "new-planner"},
+	}, experiments)
+
+	require.NoError(mds.SetNamespaceExperiment(ctx, "document", "new-planner", false))
+	experiments, err = mds.ListNamespaceExperiments(ctx)
+	require.NoError(err)
+	require.Equal([]datastore.NamespaceExperiment{
+		{Namespace: "document", Experiment: "wildcard-index"},
+		{Namespace: "folder", Experiment: "new-planner"},
+	}, experiments)
+}
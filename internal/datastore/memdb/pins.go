@@ -0,0 +1,58 @@
+package memdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+)
+
+type pinnedRevision struct {
+	revision  revision.Decimal
+	expiresAt time.Time
+}
+
+func (mdb *memdbDatastore) PinRevision(_ context.Context, name string, revisionRaw datastore.Revision, expiresAt time.Time) error {
+	dr, ok := revisionRaw.(revision.Decimal)
+	if !ok {
+		return datastore.NewInvalidRevisionErr(revisionRaw, datastore.CouldNotDetermineRevision)
+	}
+
+	mdb.Lock()
+	defer mdb.Unlock()
+
+	if mdb.pins == nil {
+		mdb.pins = make(map[string]pinnedRevision)
+	}
+	mdb.pins[name] = pinnedRevision{dr, expiresAt}
+	return nil
+}
+
+func (mdb *memdbDatastore) ReleasePinnedRevision(_ context.Context, name string) error {
+	mdb.Lock()
+	defer mdb.Unlock()
+
+	pin, ok := mdb.pins[name]
+	if !ok || !pin.expiresAt.After(time.Now()) {
+		return datastore.NewPinnedRevisionNotFoundErr(name)
+	}
+
+	delete(mdb.pins, name)
+	return nil
+}
+
+// isPinnedLocal returns whether the revision is held by an unexpired pin. The caller must
+// hold the datastore lock.
+func (mdb *memdbDatastore) isPinnedLocal(revisionRaw revision.Decimal) bool {
+	now := time.Now()
+	for _, pin := range mdb.pins {
+		if pin.revision.Equal(revisionRaw) && pin.expiresAt.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+var _ common.RevisionPinner = &memdbDatastore{}
@@ -0,0 +1,37 @@
+package memdb
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+func (mdb *memdbDatastore) WritePseudonyms(_ context.Context, sealed map[string][]byte) error {
+	mdb.Lock()
+	defer mdb.Unlock()
+
+	if mdb.pseudonyms == nil {
+		mdb.pseudonyms = make(map[string][]byte, len(sealed))
+	}
+	for pseudonym, sealedID := range sealed {
+		if _, ok := mdb.pseudonyms[pseudonym]; !ok {
+			mdb.pseudonyms[pseudonym] = sealedID
+		}
+	}
+	return nil
+}
+
+func (mdb *memdbDatastore) ReadPseudonyms(_ context.Context, pseudonyms []string) (map[string][]byte, error) {
+	mdb.RLock()
+	defer mdb.RUnlock()
+
+	found := make(map[string][]byte, len(pseudonyms))
+	for _, pseudonym := range pseudonyms {
+		if sealedID, ok := mdb.pseudonyms[pseudonym]; ok {
+			found[pseudonym] = sealedID
+		}
+	}
+	return found, nil
+}
+
+var _ common.PseudonymStore = &memdbDatastore{}
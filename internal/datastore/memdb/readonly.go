@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"strings"
 
 	"github.com/hashicorp/go-memdb"
 	"github.com/jzelinskie/stringz"
@@ -41,7 +42,14 @@ func (r *memdbReader) QueryRelationships(
 
 	queryOpts := options.NewQueryOptionsWithOptions(opts...)
 
-	bestIterator, err := iteratorForFilter(tx, filter)
+	var bestIterator memdb.ResultIterator
+	if queryOpts.Sort == options.ByResource {
+		// The relationships are scanned in the order of the fields of the primary index,
+		// whose keys order their strings by their bytes.
+		bestIterator, err = tx.Get(tableRelationship, indexID+"_prefix", filter.ResourceType)
+	} else {
+		bestIterator, err = iteratorForFilter(tx, filter)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -49,6 +57,7 @@ func (r *memdbReader) QueryRelationships(
 	matchingRelationshipsFilterFunc := filterFuncForFilters(
 		filter.ResourceType,
 		filter.OptionalResourceIds,
+		filter.OptionalResourceIDPrefix,
 		filter.OptionalResourceRelation,
 		filter.OptionalSubjectsFilter,
 		filter.OptionalCaveatName,
@@ -90,10 +99,12 @@ func (r *memdbReader) ReverseQueryRelationships(
 
 	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
 
+	// Subjects are scanned by type and, if any, by the prefix of their IDs.
 	iterator, err := tx.Get(
 		tableRelationship,
-		indexSubjectNamespace,
+		indexSubjectNamespaceAndID+"_prefix",
 		subjectsFilter.SubjectType,
+		subjectsFilter.OptionalSubjectIDPrefix,
 	)
 	if err != nil {
 		return nil, err
@@ -108,6 +119,7 @@ func (r *memdbReader) ReverseQueryRelationships(
 	matchingRelationshipsFilterFunc := filterFuncForFilters(
 		filterObjectType,
 		nil,
+		"",
 		filterRelation,
 		&subjectsFilter,
 		"",
@@ -252,9 +264,14 @@ func (r *memdbReader) lockOrPanic() {
 func iteratorForFilter(txn *memdb.Txn, filter datastore.RelationshipsFilter) (memdb.ResultIterator, error) {
 	index := indexNamespace
 	args := []any{filter.ResourceType}
-	if filter.OptionalResourceRelation != "" {
+	switch {
+	case filter.OptionalResourceRelation != "":
 		args = append(args, filter.OptionalResourceRelation)
 		index = indexNamespaceAndRelation
+	case filter.OptionalResourceIDPrefix != "":
+		// Resource IDs are matched by prefix with a range scan of the index.
+		args = append(args, filter.OptionalResourceIDPrefix)
+		index = indexNamespaceAndResourceID + "_prefix"
 	}
 
 	iter, err := txn.Get(tableRelationship, index, args...)
@@ -268,6 +285,7 @@ func iteratorForFilter(txn *memdb.Txn, filter datastore.RelationshipsFilter) (me
 func filterFuncForFilters(
 	optionalResourceType string,
 	optionalResourceIds []string,
+	optionalResourceIDPrefix string,
 	optionalRelation string,
 	optionalSubjectsFilter *datastore.SubjectsFilter,
 	optionalCaveatFilter string,
@@ -281,6 +299,8 @@ func filterFuncForFilters(
 			return true
 		case len(optionalResourceIds) > 0 && !stringz.SliceContains(optionalResourceIds, tuple.resourceID):
 			return true
+		case !strings.HasPrefix(tuple.resourceID, optionalResourceIDPrefix):
+			return true
 		case optionalRelation != "" && optionalRelation != tuple.relation:
 			return true
 		case optionalCaveatFilter != "" && (tuple.caveat == nil || tuple.caveat.caveatName != optionalCaveatFilter):
@@ -302,6 +322,8 @@ func filterFuncForFilters(
 				return true
 			case len(optionalSubjectsFilter.OptionalSubjectIds) > 0 && !stringz.SliceContains(optionalSubjectsFilter.OptionalSubjectIds, tuple.subjectObjectID):
 				return true
+			case !strings.HasPrefix(tuple.subjectObjectID, optionalSubjectsFilter.OptionalSubjectIDPrefix):
+				return true
 			case len(relations) > 0 && !stringz.SliceContains(relations, tuple.subjectRelation):
 				return true
 			}
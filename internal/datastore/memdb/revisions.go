@@ -6,6 +6,7 @@ import (
 
 	"github.com/shopspring/decimal"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 )
@@ -28,6 +29,10 @@ func (mdb *memdbDatastore) CheckRevision(ctx context.Context, revisionRaw datast
 	if !ok {
 		return datastore.NewInvalidRevisionErr(revisionRaw, datastore.CouldNotDetermineRevision)
 	}
+
+	mdb.RLock()
+	defer mdb.RUnlock()
+
 	return mdb.checkRevisionLocal(dr)
 }
 
@@ -39,9 +44,17 @@ func (mdb *memdbDatastore) checkRevisionLocal(revisionRaw revision.Decimal) erro
 	}
 
 	oldest := revision.NewFromDecimal(now.Add(mdb.negativeGCWindow))
-	if revisionRaw.LessThan(oldest) {
+	if revisionRaw.LessThan(oldest) && !mdb.isPinnedLocal(revisionRaw) {
 		return datastore.NewInvalidRevisionErr(revisionRaw, datastore.RevisionStale)
 	}
 
 	return nil
 }
+
+// RevisionCommitTime returns the time of the revision, which is the time at which its
+// transaction was committed.
+func (mdb *memdbDatastore) RevisionCommitTime(rev datastore.Revision) (time.Time, bool) {
+	return common.DecimalCommitTime(rev)
+}
+
+var _ common.CommitTimeReporter = &memdbDatastore{}
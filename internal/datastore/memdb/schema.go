@@ -22,10 +22,13 @@ const (
 	indexNamespaceAndResourceID = "namespaceAndResourceID"
 	indexNamespaceAndRelation   = "namespaceAndRelation"
 	indexNamespaceAndSubjectID  = "namespaceAndSubjectID"
-	indexSubjectNamespace       = "subjectNamespace"
+	indexSubjectNamespaceAndID  = "subjectNamespaceAndID"
 
 	tableChangelog = "changelog"
 	indexRevision  = "id"
+
+	tableRelationshipSource = "relationshipSource"
+	indexSource             = "source"
 )
 
 type namespace struct {
@@ -206,10 +209,39 @@ var schema = &memdb.DBSchema{
 						},
 					},
 				},
-				indexSubjectNamespace: {
-					Name:    indexSubjectNamespace,
+				indexSubjectNamespaceAndID: {
+					Name:   indexSubjectNamespaceAndID,
+					Unique: false,
+					Indexer: &memdb.CompoundIndex{
+						Indexes: []memdb.Indexer{
+							&memdb.StringFieldIndex{Field: "subjectNamespace"},
+							&memdb.StringFieldIndex{Field: "subjectObjectID"},
+						},
+					},
+				},
+			},
+		},
+		tableRelationshipSource: {
+			Name: tableRelationshipSource,
+			Indexes: map[string]*memdb.IndexSchema{
+				indexID: {
+					Name:   indexID,
+					Unique: true,
+					Indexer: &memdb.CompoundIndex{
+						Indexes: []memdb.Indexer{
+							&memdb.StringFieldIndex{Field: "namespace"},
+							&memdb.StringFieldIndex{Field: "resourceID"},
+							&memdb.StringFieldIndex{Field: "relation"},
+							&memdb.StringFieldIndex{Field: "subjectNamespace"},
+							&memdb.StringFieldIndex{Field: "subjectObjectID"},
+							&memdb.StringFieldIndex{Field: "subjectRelation"},
+						},
+					},
+				},
+				indexSource: {
+					Name:    indexSource,
 					Unique:  false,
-					Indexer: &memdb.StringFieldIndex{Field: "subjectNamespace"},
+					Indexer: &memdb.StringFieldIndex{Field: "source"},
 				},
 			},
 		},
@@ -0,0 +1,48 @@
+package memdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+func (mdb *memdbDatastore) AddSchemaVersion(_ context.Context, revision datastore.Revision, schemaText, author string) (datastore.SchemaVersion, error) {
+	mdb.Lock()
+	defer mdb.Unlock()
+
+	version := datastore.SchemaVersion{
+		Version:    uint64(len(mdb.schemaVersions)) + 1,
+		Revision:   revision,
+		SchemaText: schemaText,
+		Author:     author,
+		CreatedAt:  time.Now().UTC(),
+	}
+	mdb.schemaVersions = append(mdb.schemaVersions, version)
+	return version, nil
+}
+
+func (mdb *memdbDatastore) ListSchemaVersions(_ context.Context) ([]datastore.SchemaVersion, error) {
+	mdb.RLock()
+	defer mdb.RUnlock()
+
+	versions := make([]datastore.SchemaVersion, 0, len(mdb.schemaVersions))
+	for _, version := range mdb.schemaVersions {
+		version.SchemaText = ""
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func (mdb *memdbDatastore) ReadSchemaVersion(_ context.Context, version uint64) (datastore.SchemaVersion, error) {
+	mdb.RLock()
+	defer mdb.RUnlock()
+
+	if version == 0 || version > uint64(len(mdb.schemaVersions)) {
+		return datastore.SchemaVersion{}, datastore.NewSchemaVersionNotFoundErr(version)
+	}
+	return mdb.schemaVersions[version-1], nil
+}
+
+var _ common.SchemaHistoryStore = &memdbDatastore{}
@@ -0,0 +1,154 @@
+package memdb
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+type relationshipSource struct {
+	namespace        string
+	resourceID       string
+	relation         string
+	subjectNamespace string
+	subjectObjectID  string
+	subjectRelation  string
+	source           string
+}
+
+// relationship returns the relationship of the record, without its caveat.
+func (r *relationshipSource) relationship() *core.RelationTuple {
+	return &core.RelationTuple{
+		ResourceAndRelation: &core.ObjectAndRelation{
+			Namespace: r.namespace,
+			ObjectId:  r.resourceID,
+			Relation:  r.relation,
+		},
+		Subject: &core.ObjectAndRelation{
+			Namespace: r.subjectNamespace,
+			ObjectId:  r.subjectObjectID,
+			Relation:  r.subjectRelation,
+		},
+	}
+}
+
+func (rwt *memdbReadWriteTx) SetRelationshipSources(_ context.Context, source string, relationships []*core.RelationTuple) error {
+	rwt.lockOrPanic()
+	defer rwt.Unlock()
+
+	tx, err := rwt.txSource()
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range relationships {
+		record := &relationshipSource{
+			rel.ResourceAndRelation.Namespace,
+			rel.ResourceAndRelation.ObjectId,
+			rel.ResourceAndRelation.Relation,
+			rel.Subject.Namespace,
+			rel.Subject.ObjectId,
+			rel.Subject.Relation,
+			source,
+		}
+
+		if source == "" {
+			if _, err := tx.DeleteAll(
+				tableRelationshipSource,
+				indexID,
+				record.namespace,
+				record.resourceID,
+				record.relation,
+				record.subjectNamespace,
+				record.subjectObjectID,
+				record.subjectRelation,
+			); err != nil {
+				return fmt.Errorf("error deleting relationship source: %w", err)
+			}
+			continue
+		}
+
+		if err := tx.Insert(tableRelationshipSource, record); err != nil {
+			return fmt.Errorf("error inserting relationship source: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (rwt *memdbReadWriteTx) ClearRelationshipSources(_ context.Context, filter *v1.RelationshipFilter) error {
+	rwt.lockOrPanic()
+	defer rwt.Unlock()
+
+	tx, err := rwt.txSource()
+	if err != nil {
+		return err
+	}
+
+	it, err := tx.Get(tableRelationshipSource, indexID)
+	if err != nil {
+		return fmt.Errorf("error reading relationship sources: %w", err)
+	}
+
+	// The records are all read before any is deleted, as deleting them would invalidate
+	// the iterator.
+	matches := datastore.RelationshipsFilterFromPublicFilter(filter)
+	var cleared []*relationshipSource
+	for found := it.Next(); found != nil; found = it.Next() {
+		record := found.(*relationshipSource)
+		if matches.Test(record.relationship()) {
+			cleared = append(cleared, record)
+		}
+	}
+
+	for _, record := range cleared {
+		if err := tx.Delete(tableRelationshipSource, record); err != nil {
+			return fmt.Errorf("error deleting relationship source: %w", err)
+		}
+	}
+	return nil
+}
+
+func (rwt *memdbReadWriteTx) HasRelationshipSources(_ context.Context) (bool, error) {
+	rwt.lockOrPanic()
+	defer rwt.Unlock()
+
+	tx, err := rwt.txSource()
+	if err != nil {
+		return false, err
+	}
+
+	found, err := tx.First(tableRelationshipSource, indexID)
+	if err != nil {
+		return false, fmt.Errorf("error reading relationship sources: %w", err)
+	}
+	return found != nil, nil
+}
+
+func (rwt *memdbReadWriteTx) RelationshipsFromSource(_ context.Context, source string) ([]*core.RelationTuple, error) {
+	rwt.lockOrPanic()
+	defer rwt.Unlock()
+
+	tx, err := rwt.txSource()
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := tx.Get(tableRelationshipSource, indexSource, source)
+	if err != nil {
+		return nil, fmt.Errorf("error reading relationship sources: %w", err)
+	}
+
+	var relationships []*core.RelationTuple
+	for found := it.Next(); found != nil; found = it.Next() {
+		relationships = append(relationships, found.(*relationshipSource).relationship())
+	}
+	return relationships, nil
+}
+
+var _ common.RelationshipSourceTracker = &memdbReadWriteTx{}
@@ -7,6 +7,10 @@ import (
 	"github.com/authzed/spicedb/pkg/datastore"
 )
 
+func (mdb *memdbDatastore) UniqueID(_ context.Context) (string, error) {
+	return mdb.uniqueID, nil
+}
+
 func (mdb *memdbDatastore) Statistics(ctx context.Context) (datastore.Stats, error) {
 	head, err := mdb.HeadRevision(ctx)
 	if err != nil {
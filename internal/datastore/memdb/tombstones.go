@@ -0,0 +1,69 @@
+package memdb
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+func (mdb *memdbDatastore) WriteNamespaceTombstone(_ context.Context, tombstone common.NamespaceTombstone) error {
+	mdb.Lock()
+	defer mdb.Unlock()
+
+	if mdb.tombstones == nil {
+		mdb.tombstones = make(map[string]common.NamespaceTombstone)
+	}
+
+	now := time.Now()
+	for namespace, existing := range mdb.tombstones {
+		if !existing.ExpiresAt.After(now) {
+			delete(mdb.tombstones, namespace)
+		}
+	}
+
+	mdb.tombstones[tombstone.Namespace] = tombstone
+	return nil
+}
+
+func (mdb *memdbDatastore) ReadNamespaceTombstone(_ context.Context, namespace string) (common.NamespaceTombstone, error) {
+	mdb.RLock()
+	defer mdb.RUnlock()
+
+	tombstone, ok := mdb.tombstones[namespace]
+	if !ok || !tombstone.ExpiresAt.After(time.Now()) {
+		return common.NamespaceTombstone{}, common.ErrNamespaceTombstoneNotFound
+	}
+	return tombstone, nil
+}
+
+func (mdb *memdbDatastore) ListNamespaceTombstones(_ context.Context) ([]common.NamespaceTombstone, error) {
+	mdb.RLock()
+	defer mdb.RUnlock()
+
+	now := time.Now()
+	tombstones := make([]common.NamespaceTombstone, 0, len(mdb.tombstones))
+	for _, tombstone := range mdb.tombstones {
+		if tombstone.ExpiresAt.After(now) {
+			tombstones = append(tombstones, tombstone)
+		}
+	}
+	sort.Slice(tombstones, func(i, j int) bool {
+		return tombstones[i].DeletedAt.After(tombstones[j].DeletedAt)
+	})
+	return tombstones, nil
+}
+
+func (mdb *memdbDatastore) DeleteNamespaceTombstone(_ context.Context, namespace string) error {
+	mdb.Lock()
+	defer mdb.Unlock()
+
+	if _, ok := mdb.tombstones[namespace]; !ok {
+		return common.ErrNamespaceTombstoneNotFound
+	}
+	delete(mdb.tombstones, namespace)
+	return nil
+}
+
+var _ common.NamespaceTombstoneStore = &memdbDatastore{}
@@ -7,8 +7,11 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	log "github.com/authzed/spicedb/internal/logging"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/ngrok/sqlmw"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -122,3 +125,34 @@ func addSessionVariables(c driver.Connector, variables map[string]string) (drive
 		statements: statements,
 	}, nil
 }
+
+// openConnector opens a connector to the database at the URI, annotating the queries
+// issued over its connections if an annotator is given.
+func openConnector(uri string, annotator *common.QueryAnnotator) (driver.Connector, error) {
+	if annotator == nil {
+		return mysql.MySQLDriver{}.OpenConnector(uri)
+	}
+
+	drv := sqlmw.Driver(mysql.MySQLDriver{}, &queryAnnotationInterceptor{annotator: annotator})
+	return drv.(driver.DriverContext).OpenConnector(uri)
+}
+
+// queryAnnotationInterceptor annotates the queries issued over a connection. Queries with
+// arguments are prepared unless parameters are interpolated, so prepared statements are
+// annotated too.
+type queryAnnotationInterceptor struct {
+	sqlmw.NullInterceptor
+	annotator *common.QueryAnnotator
+}
+
+func (qai *queryAnnotationInterceptor) ConnPrepareContext(ctx context.Context, conn driver.ConnPrepareContext, query string) (driver.Stmt, error) {
+	return conn.PrepareContext(ctx, qai.annotator.Annotate(ctx, query))
+}
+
+func (qai *queryAnnotationInterceptor) ConnExecContext(ctx context.Context, conn driver.ExecerContext, query string, args []driver.NamedValue) (driver.Result, error) {
+	return conn.ExecContext(ctx, qai.annotator.Annotate(ctx, query), args)
+}
+
+func (qai *queryAnnotationInterceptor) ConnQueryContext(ctx context.Context, conn driver.QueryerContext, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return conn.QueryContext(ctx, qai.annotator.Annotate(ctx, query), args)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sync/atomic"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -47,6 +48,8 @@ const (
 	colCaveatName       = "caveat_name"
 	colCaveatContext    = "caveat_context"
 
+	indexTupleBySubjectType = "ix_relation_tuple_by_subject_type"
+
 	errUnableToInstantiate = "unable to instantiate datastore: %w"
 	liveDeletedTxnID       = uint64(math.MaxInt64)
 	batchDeleteSize        = 1000
@@ -106,7 +109,9 @@ func newMySQLDatastore(uri string, options ...Option) (*Datastore, error) {
 	if err != nil {
 		return nil, fmt.Errorf(errUnableToInstantiate, err)
 	}
-	connector, err := mysql.MySQLDriver{}.OpenConnector(uri)
+	annotator := common.NewQueryAnnotator(config.requestIDQueryComments, config.queryTagging, config.queryTagNodeID)
+
+	connector, err := openConnector(uri, annotator)
 	if err != nil {
 		return nil, fmt.Errorf("NewMySQLDatastore: failed to create connector: %w", err)
 	}
@@ -134,6 +139,11 @@ func newMySQLDatastore(uri string, options ...Option) (*Datastore, error) {
 			return nil, fmt.Errorf(errUnableToInstantiate, err)
 		}
 
+		poolCollector := common.NewPoolCollector("spicedb", "default", poolStats(db))
+		if err := prometheus.Register(poolCollector); err != nil {
+			return nil, fmt.Errorf(errUnableToInstantiate, err)
+		}
+
 		if err := common.RegisterGCMetrics(); err != nil {
 			return nil, fmt.Errorf(errUnableToInstantiate, err)
 		}
@@ -163,19 +173,20 @@ func newMySQLDatastore(uri string, options ...Option) (*Datastore, error) {
 	maxRevisionStaleness := time.Duration(float64(config.revisionQuantization.Nanoseconds())*
 		config.maxRevisionStalenessPercent) * time.Nanosecond
 
-	quantizationPeriodNanos := config.revisionQuantization.Nanoseconds()
-	if quantizationPeriodNanos < 1 {
-		quantizationPeriodNanos = 1
+	revisionQuery := func(quantization time.Duration) string {
+		quantizationPeriodNanos := quantization.Nanoseconds()
+		if quantizationPeriodNanos < 1 {
+			quantizationPeriodNanos = 1
+		}
+		return fmt.Sprintf(
+			querySelectRevision,
+			colID,
+			driver.RelationTupleTransaction(),
+			colTimestamp,
+			quantizationPeriodNanos,
+		)
 	}
 
-	revisionQuery := fmt.Sprintf(
-		querySelectRevision,
-		colID,
-		driver.RelationTupleTransaction(),
-		colTimestamp,
-		quantizationPeriodNanos,
-	)
-
 	validTransactionQuery := fmt.Sprintf(
 		queryValidTransaction,
 		colID,
@@ -188,7 +199,7 @@ func newMySQLDatastore(uri string, options ...Option) (*Datastore, error) {
 		db:                     db,
 		driver:                 driver,
 		url:                    uri,
-		revisionQuantization:   config.revisionQuantization,
+		quantization:           revisions.NewAdaptiveQuantization(config.revisionQuantization, config.maxRevisionQuantization),
 		gcWindow:               config.gcWindow,
 		gcInterval:             config.gcInterval,
 		gcTimeout:              config.gcMaxOperationTime,
@@ -196,6 +207,7 @@ func newMySQLDatastore(uri string, options ...Option) (*Datastore, error) {
 		cancelGc:               cancelGc,
 		watchBufferLength:      config.watchBufferLength,
 		usersetBatchSize:       config.splitAtUsersetCount,
+		skipNoopTouches:        config.skipNoopTouches,
 		optimizedRevisionQuery: revisionQuery,
 		validTransactionQuery:  validTransactionQuery,
 		createTxn:              createTxn,
@@ -234,9 +246,44 @@ func newMySQLDatastore(uri string, options ...Option) (*Datastore, error) {
 		log.Warn().Msg("datastore garbage collection disabled")
 	}
 
+	// Start a goroutine for resizing the connection pool.
+	if config.targetAcquireLatency > 0 {
+		sizer := common.NewAdaptivePoolSizer(
+			config.minOpenConns,
+			config.maxOpenConns,
+			config.targetAcquireLatency,
+			poolStats(db),
+			func(maxConns int) {
+				db.SetMaxOpenConns(maxConns)
+				db.SetMaxIdleConns(maxConns)
+			},
+		)
+
+		var poolSizerCtx context.Context
+		poolSizerCtx, store.cancelPoolSizer = context.WithCancel(context.Background())
+		store.poolSizerGroup.Go(func() error {
+			return sizer.Start(poolSizerCtx, defaultPoolResizeInterval)
+		})
+	}
+
 	return store, nil
 }
 
+// poolStats returns a function which reads the statistics of a database/sql connection pool.
+func poolStats(db *sql.DB) common.PoolStatsFunc {
+	return func() common.PoolStats {
+		stats := db.Stats()
+		return common.PoolStats{
+			WaitCount:           stats.WaitCount,
+			AcquireWaitDuration: stats.WaitDuration,
+			InUseConns:          int64(stats.InUse),
+			IdleConns:           int64(stats.Idle),
+			MaxConns:            int64(stats.MaxOpenConnections),
+			ClosedConns:         stats.MaxIdleClosed + stats.MaxIdleTimeClosed + stats.MaxLifetimeClosed,
+		}
+	}
+}
+
 // TODO (@vroldanbet) dupe from postgres datastore - need to refactor
 func (mds *Datastore) SnapshotReader(revisionRaw datastore.Revision) datastore.Reader {
 	rev := revisionRaw.(revision.Decimal)
@@ -299,6 +346,7 @@ func (mds *Datastore) ReadWriteTx(
 				},
 				tx,
 				newTxnID,
+				mds.skipNoopTouches,
 			}
 
 			if err := fn(rwt); err != nil {
@@ -314,6 +362,7 @@ func (mds *Datastore) ReadWriteTx(
 			return datastore.NoRevision, err
 		}
 
+		mds.quantization.RecordWrite()
 		return revisionFromTransaction(newTxnID), nil
 	}
 	return datastore.NoRevision, fmt.Errorf("max retries exceeded: %w", err)
@@ -349,53 +398,68 @@ func newMySQLExecutor(tx querier) common.ExecuteQueryFunc {
 	//
 	// Prepared statements are also not used given they perform poorly on environments where connections have
 	// short lifetime (e.g. to gracefully handle load-balancer connection drain)
-	return func(ctx context.Context, sqlQuery string, args []interface{}) ([]*core.RelationTuple, error) {
+	return func(ctx context.Context, sqlQuery string, args []interface{}) (common.TupleRows, error) {
 		span := trace.SpanFromContext(ctx)
 
 		rows, err := tx.QueryContext(ctx, sqlQuery, args...)
 		if err != nil {
 			return nil, fmt.Errorf(errUnableToQueryTuples, err)
 		}
-		defer common.LogOnError(ctx, rows.Close)
 
 		span.AddEvent("Query issued to database")
+		return &mysqlTupleRows{ctx: ctx, span: span, rows: rows}, nil
+	}
+}
 
-		var tuples []*core.RelationTuple
-		for rows.Next() {
-			nextTuple := &core.RelationTuple{
-				ResourceAndRelation: &core.ObjectAndRelation{},
-				Subject:             &core.ObjectAndRelation{},
-			}
-
-			var caveatName string
-			var caveatContext caveatContextWrapper
-			err := rows.Scan(
-				&nextTuple.ResourceAndRelation.Namespace,
-				&nextTuple.ResourceAndRelation.ObjectId,
-				&nextTuple.ResourceAndRelation.Relation,
-				&nextTuple.Subject.Namespace,
-				&nextTuple.Subject.ObjectId,
-				&nextTuple.Subject.Relation,
-				&caveatName,
-				&caveatContext,
-			)
-			if err != nil {
-				return nil, fmt.Errorf(errUnableToQueryTuples, err)
-			}
-
-			nextTuple.Caveat, err = common.ContextualizedCaveatFrom(caveatName, caveatContext)
-			if err != nil {
-				return nil, fmt.Errorf(errUnableToQueryTuples, err)
-			}
+// mysqlTupleRows reads tuples from the rows of a query.
+type mysqlTupleRows struct {
+	ctx    context.Context
+	span   trace.Span
+	rows   *sql.Rows
+	loaded int
+}
 
-			tuples = append(tuples, nextTuple)
-		}
-		if err := rows.Err(); err != nil {
+func (r *mysqlTupleRows) Next() (*core.RelationTuple, error) {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
 			return nil, fmt.Errorf(errUnableToQueryTuples, err)
 		}
-		span.AddEvent("Tuples loaded", trace.WithAttributes(attribute.Int("tupleCount", len(tuples))))
-		return tuples, nil
+		return nil, nil
+	}
+
+	nextTuple := &core.RelationTuple{
+		ResourceAndRelation: &core.ObjectAndRelation{},
+		Subject:             &core.ObjectAndRelation{},
+	}
+
+	var caveatName string
+	var caveatContext caveatContextWrapper
+	err := r.rows.Scan(
+		&nextTuple.ResourceAndRelation.Namespace,
+		&nextTuple.ResourceAndRelation.ObjectId,
+		&nextTuple.ResourceAndRelation.Relation,
+		&nextTuple.Subject.Namespace,
+		&nextTuple.Subject.ObjectId,
+		&nextTuple.Subject.Relation,
+		&caveatName,
+		&caveatContext,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(errUnableToQueryTuples, err)
+	}
+
+	nextTuple.Caveat, err = common.ContextualizedCaveatFrom(caveatName, caveatContext)
+	if err != nil {
+		return nil, fmt.Errorf(errUnableToQueryTuples, err)
 	}
+
+	r.loaded++
+	return nextTuple, nil
+}
+
+func (r *mysqlTupleRows) Close() {
+	common.LogOnError(r.ctx, r.rows.Close)
+	r.span.AddEvent("Tuples loaded", trace.WithAttributes(attribute.Int("tupleCount", r.loaded)))
 }
 
 // Datastore is a MySQL-based implementation of the datastore.Datastore interface
@@ -406,21 +470,26 @@ type Datastore struct {
 	url                string
 	analyzeBeforeStats bool
 
-	revisionQuantization time.Duration
-	gcWindow             time.Duration
-	gcInterval           time.Duration
-	gcTimeout            time.Duration
-	watchBufferLength    uint16
-	usersetBatchSize     uint16
-	maxRetries           uint8
-
-	optimizedRevisionQuery string
+	quantization      *revisions.Quantization
+	gcWindow          time.Duration
+	gcInterval        time.Duration
+	gcTimeout         time.Duration
+	watchBufferLength uint16
+	usersetBatchSize  uint16
+	skipNoopTouches   bool
+	maxRetries        uint8
+	uniqueID          atomic.Pointer[string]
+
+	optimizedRevisionQuery func(quantization time.Duration) string
 	validTransactionQuery  string
 
 	gcGroup  *errgroup.Group
 	gcCtx    context.Context
 	cancelGc context.CancelFunc
 
+	poolSizerGroup  errgroup.Group
+	cancelPoolSizer context.CancelFunc
+
 	createTxn     string
 	createBaseTxn string
 
@@ -438,6 +507,10 @@ func (mds *Datastore) Close() error {
 			log.Error().Err(err).Msg("error waiting for garbage collector to shutdown")
 		}
 	}
+	if mds.cancelPoolSizer != nil {
+		mds.cancelPoolSizer()
+		_ = mds.poolSizerGroup.Wait()
+	}
 	return mds.db.Close()
 }
 
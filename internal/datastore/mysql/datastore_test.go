@@ -7,18 +7,22 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/common/sqlgolden"
 	"github.com/authzed/spicedb/internal/datastore/mysql/migrations"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	testdatastore "github.com/authzed/spicedb/internal/testserver/datastore"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
 	"github.com/authzed/spicedb/pkg/datastore/test"
 	"github.com/authzed/spicedb/pkg/migrate"
 	"github.com/authzed/spicedb/pkg/namespace"
@@ -628,6 +632,77 @@ func TestMySQLMigrationsWithPrefix(t *testing.T) {
 	req.NoError(rows.Err())
 }
 
+// TestSubjectTypeIndex checks that the add_subject_type_index migration creates the
+// index by subject type, and that reverse queries for all the subjects of a type use it.
+func TestSubjectTypeIndex(t *testing.T) {
+	req := require.New(t)
+	ctx := context.Background()
+
+	db := datastoreDB(t, false)
+	driver := migrations.NewMySQLDriverFromDB(db, "")
+
+	hasIndex := func() bool {
+		var exists bool
+		req.NoError(db.QueryRowContext(ctx,
+			"SELECT COUNT(*) > 0 FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?",
+			driver.RelationTuple(), indexTupleBySubjectType,
+		).Scan(&exists))
+		return exists
+	}
+
+	req.NoError(migrations.Manager.Run(ctx, driver, "add_caveat", migrate.LiveRun))
+	req.False(hasIndex())
+
+	req.NoError(migrations.Manager.Run(ctx, driver, "add_subject_type_index", migrate.LiveRun))
+	req.True(hasIndex())
+
+	req.NoError(migrations.Manager.Run(ctx, driver, migrate.Head, migrate.LiveRun))
+	req.True(hasIndex())
+
+	explainer := &sqlgolden.Explainer{
+		Explain: func(ctx context.Context, query string, args []any) (string, error) {
+			rows, err := db.QueryContext(ctx, "EXPLAIN "+query, args...)
+			if err != nil {
+				return "", err
+			}
+			defer rows.Close()
+
+			columns, err := rows.Columns()
+			if err != nil {
+				return "", err
+			}
+
+			var plan []string
+			for rows.Next() {
+				values := make([]sql.NullString, len(columns))
+				dest := make([]any, len(columns))
+				for i := range values {
+					dest[i] = &values[i]
+				}
+				if err := rows.Scan(dest...); err != nil {
+					return "", err
+				}
+				for i, column := range columns {
+					plan = append(plan, column+": "+values[i].String)
+				}
+			}
+			return strings.Join(plan, "\n"), rows.Err()
+		},
+	}
+	reader := &mysqlReader{
+		QueryBuilder:  NewQueryBuilder(driver),
+		querySplitter: common.TupleQuerySplitter{Executor: explainer.ExecuteQuery, UsersetBatchSize: 100},
+		filterer:      buildLivingObjectFilterForRevision(revision.NewFromDecimal(decimal.NewFromInt(1))),
+	}
+
+	iter, err := reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{SubjectType: "user"})
+	req.NoError(err)
+	iter.Close()
+
+	req.Len(explainer.Plans, 1)
+	req.Contains(explainer.Plans[0], "key: "+indexTupleBySubjectType)
+}
+
 func datastoreDB(t *testing.T, migrate bool) *sql.DB {
 	var databaseURI string
 	testdatastore.RunMySQLForTestingWithOptions(t, testdatastore.MySQLTesterOptions{MigrateForNewDatastore: migrate}, "").NewDatastore(t, func(engine, uri string) datastore.Datastore {
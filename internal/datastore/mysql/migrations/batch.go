@@ -18,6 +18,15 @@ func newStatementBatch(statements ...templatedStatement) statementBatch {
 	}
 }
 
+// render returns the statements of the batch for the given tables.
+func (e statementBatch) render(t *tables) []string {
+	rendered := make([]string, 0, len(e.statements))
+	for _, stmt := range e.statements {
+		rendered = append(rendered, stmt(t))
+	}
+	return rendered
+}
+
 func (e statementBatch) execute(ctx context.Context, wrapper TxWrapper) error {
 	if len(e.statements) == 0 {
 		return errors.New("executor.migrate: No statements to migrate")
@@ -0,0 +1,29 @@
+package migrations
+
+import "strings"
+
+func init() {
+	Manager.SetLockEstimator(estimateLockImpact)
+}
+
+// estimateLockImpact estimates the locks InnoDB online DDL takes for a migration statement.
+func estimateLockImpact(statement string) string {
+	stmt := strings.ToUpper(strings.Join(strings.Fields(statement), " "))
+
+	switch {
+	case strings.HasPrefix(stmt, "CREATE TABLE"):
+		return "none: the table is new"
+
+	case strings.HasPrefix(stmt, "ALTER TABLE"):
+		if strings.Contains(stmt, "PRIMARY KEY") || strings.Contains(stmt, "AUTO_INCREMENT") {
+			return "table rebuild with a shared lock: writes are blocked until the table is copied"
+		}
+		return "in-place table rebuild: reads and writes continue, with a brief exclusive metadata lock"
+
+	case strings.HasPrefix(stmt, "INSERT"), strings.HasPrefix(stmt, "UPDATE"), strings.HasPrefix(stmt, "DELETE"):
+		return "row locks: concurrent writes to the affected rows wait"
+
+	default:
+		return "unknown"
+	}
+}
@@ -38,6 +38,14 @@ func mustRegisterMigration(version, replaces string, up migrate.MigrationFunc[Wr
 	}
 }
 
+// mustDescribeMigration describes a migration by its statements, rendered against the
+// default (unprefixed) table names.
+func mustDescribeMigration(version string, batch statementBatch) {
+	if err := Manager.Describe(version, batch.render(newTables(""))...); err != nil {
+		panic("failed to describe migration " + err.Error())
+	}
+}
+
 func registerMigration(version, replaces string, up migrate.MigrationFunc[Wrapper], upTx migrate.TxMigrationFunc[TxWrapper]) error {
 	// validate migration names to ensure they are compatible with mysql column names
 	for _, v := range []string{version, replaces} {
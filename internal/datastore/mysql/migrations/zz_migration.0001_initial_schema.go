@@ -60,12 +60,12 @@ func createRelationTupleTransaction(t *tables) string {
 }
 
 func init() {
-	mustRegisterMigration("initial", "", noNonatomicMigration,
-		newStatementBatch(
-			createMigrationVersion,
-			createNamespaceConfig,
-			createRelationTuple,
-			createRelationTupleTransaction,
-		).execute,
+	batch := newStatementBatch(
+		createMigrationVersion,
+		createNamespaceConfig,
+		createRelationTuple,
+		createRelationTupleTransaction,
 	)
+	mustRegisterMigration("initial", "", noNonatomicMigration, batch.execute)
+	mustDescribeMigration("initial", batch)
 }
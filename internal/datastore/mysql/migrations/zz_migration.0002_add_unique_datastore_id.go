@@ -13,9 +13,9 @@ func createMetadataTable(t *tables) string {
 }
 
 func init() {
-	mustRegisterMigration("add_unique_datastore_id", "initial", noNonatomicMigration,
-		newStatementBatch(
-			createMetadataTable,
-		).execute,
+	batch := newStatementBatch(
+		createMetadataTable,
 	)
+	mustRegisterMigration("add_unique_datastore_id", "initial", noNonatomicMigration, batch.execute)
+	mustDescribeMigration("add_unique_datastore_id", batch)
 }
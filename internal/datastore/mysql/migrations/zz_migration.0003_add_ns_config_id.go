@@ -17,10 +17,10 @@ func createNSConfigID(t *tables) string {
 }
 
 func init() {
-	mustRegisterMigration("add_ns_config_id", "add_unique_datastore_id", noNonatomicMigration,
-		newStatementBatch(
-			dropNSConfigPK,
-			createNSConfigID,
-		).execute,
+	batch := newStatementBatch(
+		dropNSConfigPK,
+		createNSConfigID,
 	)
+	mustRegisterMigration("add_ns_config_id", "add_unique_datastore_id", noNonatomicMigration, batch.execute)
+	mustDescribeMigration("add_ns_config_id", batch)
 }
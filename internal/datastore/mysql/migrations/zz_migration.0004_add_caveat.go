@@ -29,10 +29,10 @@ func addCaveatToRelationTuplesTable(t *tables) string {
 }
 
 func init() {
-	mustRegisterMigration("add_caveat", "add_ns_config_id", noNonatomicMigration,
-		newStatementBatch(
-			createCaveatTable,
-			addCaveatToRelationTuplesTable,
-		).execute,
+	batch := newStatementBatch(
+		createCaveatTable,
+		addCaveatToRelationTuplesTable,
 	)
+	mustRegisterMigration("add_caveat", "add_ns_config_id", noNonatomicMigration, batch.execute)
+	mustDescribeMigration("add_caveat", batch)
 }
@@ -0,0 +1,20 @@
+package migrations
+
+import "fmt"
+
+// addSubjectTypeIndex adds the index of relationships by subject for reverse queries.
+// Unlike ix_relation_tuple_by_subject, which leads with the subject ID, it leads with the
+// subject type, so it also serves queries for all the subjects of a type or for subject
+// IDs matched by prefix.
+func addSubjectTypeIndex(t *tables) string {
+	return fmt.Sprintf(`ALTER TABLE %s
+		ADD INDEX ix_relation_tuple_by_subject_type (userset_namespace, userset_object_id, userset_relation, namespace, relation);`,
+		t.RelationTuple(),
+	)
+}
+
+func init() {
+	batch := newStatementBatch(addSubjectTypeIndex)
+	mustRegisterMigration("add_subject_type_index", "add_caveat", noNonatomicMigration, batch.execute)
+	mustDescribeMigration("add_subject_type_index", batch)
+}
@@ -21,10 +21,12 @@ const (
 	defaultEnablePrometheusStats             = false
 	defaultMaxRetries                        = 8
 	defaultGCEnabled                         = true
+	defaultPoolResizeInterval                = 30 * time.Second
 )
 
 type mysqlOptions struct {
 	revisionQuantization        time.Duration
+	maxRevisionQuantization     time.Duration
 	gcWindow                    time.Duration
 	gcInterval                  time.Duration
 	gcMaxOperationTime          time.Duration
@@ -33,6 +35,8 @@ type mysqlOptions struct {
 	tablePrefix                 string
 	enablePrometheusStats       bool
 	maxOpenConns                int
+	minOpenConns                int
+	targetAcquireLatency        time.Duration
 	connMaxIdleTime             time.Duration
 	connMaxLifetime             time.Duration
 	splitAtUsersetCount         uint16
@@ -40,6 +44,10 @@ type mysqlOptions struct {
 	maxRetries                  uint8
 	lockWaitTimeoutSeconds      *uint8
 	gcEnabled                   bool
+	requestIDQueryComments      bool
+	queryTagging                bool
+	queryTagNodeID              string
+	skipNoopTouches             bool
 }
 
 // Option provides the facility to configure how clients within the
@@ -76,6 +84,14 @@ func generateConfig(options []Option) (mysqlOptions, error) {
 		)
 	}
 
+	if computed.maxRevisionQuantization >= computed.gcWindow {
+		return computed, fmt.Errorf(
+			errQuantizationTooLarge,
+			computed.maxRevisionQuantization,
+			computed.gcWindow,
+		)
+	}
+
 	return computed, nil
 }
 
@@ -99,6 +115,18 @@ func RevisionQuantization(quantization time.Duration) Option {
 	}
 }
 
+// MaxRevisionQuantization is the largest time bucket size to which advertised
+// revisions will be rounded. If above RevisionQuantization, the bucket size adapts
+// between the two, widening while writes are frequent and the dispatch cache hit ratio
+// is low.
+//
+// This value defaults to 0, for a bucket size which does not adapt.
+func MaxRevisionQuantization(quantization time.Duration) Option {
+	return func(mo *mysqlOptions) {
+		mo.maxRevisionQuantization = quantization
+	}
+}
+
 // MaxRevisionStalenessPercent is the amount of time, expressed as a percentage of
 // the revision quantization window, that a previously computed rounded revision
 // can still be advertised after the next rounded revision would otherwise be ready.
@@ -158,6 +186,51 @@ func SplitAtUsersetCount(splitAtUsersetCount uint16) Option {
 	}
 }
 
+// RequestIDQueryComments marks whether queries are annotated with a SQL comment carrying
+// the ID of the API request which caused them, so that they can be correlated with
+// entries in the MySQL slow query log.
+//
+// Disabled by default.
+func RequestIDQueryComments(enabled bool) Option {
+	return func(mo *mysqlOptions) {
+		mo.requestIDQueryComments = enabled
+	}
+}
+
+// QueryTagging marks whether queries are annotated, besides the request ID of
+// RequestIDQueryComments, with the API method which caused them and the ID of the node
+// issuing them, so that database load can be attributed to call sites from the MySQL
+// slow query log and statement history. Statement digests strip comments, and so do
+// not carry the annotations.
+//
+// Disabled by default.
+func QueryTagging(enabled bool) Option {
+	return func(mo *mysqlOptions) {
+		mo.queryTagging = enabled
+	}
+}
+
+// QueryTagNodeID is the ID of the node with which queries are tagged when QueryTagging
+// is enabled.
+//
+// Empty by default, in which case queries are not tagged with a node ID.
+func QueryTagNodeID(nodeID string) Option {
+	return func(mo *mysqlOptions) {
+		mo.queryTagNodeID = nodeID
+	}
+}
+
+// SkipNoopTouches marks whether TOUCHes of relationships which are already stored with
+// the same caveat are skipped, rather than replacing the stored row with an identical one.
+// Skipped TOUCHes do not appear in the changes returned by Watch.
+//
+// Disabled by default.
+func SkipNoopTouches(enabled bool) Option {
+	return func(mo *mysqlOptions) {
+		mo.skipNoopTouches = enabled
+	}
+}
+
 // WithEnablePrometheusStats marks whether Prometheus metrics provided by Go's database/sql package
 // are enabled.
 //
@@ -200,6 +273,19 @@ func MaxOpenConns(conns int) Option {
 	}
 }
 
+// AdaptivePoolSizing enables resizing the connection pool between minOpenConns and
+// MaxOpenConns, growing it while the average time spent waiting to acquire a connection
+// exceeds targetAcquireLatency and shrinking it while connections are acquired without
+// waiting.
+//
+// Adaptive pool sizing is disabled by default.
+func AdaptivePoolSizing(minOpenConns int, targetAcquireLatency time.Duration) Option {
+	return func(mo *mysqlOptions) {
+		mo.minOpenConns = minOpenConns
+		mo.targetAcquireLatency = targetAcquireLatency
+	}
+}
+
 // DebugAnalyzeBeforeStatistics signals to the Statistics method that it should
 // run Analyze Table on the relationships table before returning statistics.
 // This should only be used for debug and testing.
@@ -0,0 +1,25 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/common/sqlgolden"
+	"github.com/authzed/spicedb/internal/datastore/mysql/migrations"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+)
+
+// Deletions run against a *sql.Tx, which cannot be recorded, so only the relationship
+// queries are covered for MySQL.
+func TestQueryShapes(t *testing.T) {
+	recorder := &sqlgolden.Recorder{}
+	reader := &mysqlReader{
+		QueryBuilder:  NewQueryBuilder(migrations.NewMySQLDriverFromDB(nil, "")),
+		querySplitter: common.TupleQuerySplitter{Executor: recorder.ExecuteQuery, UsersetBatchSize: 100},
+		filterer:      buildLivingObjectFilterForRevision(revision.NewFromDecimal(decimal.NewFromInt(12345))),
+	}
+
+	sqlgolden.Assert(t, "testdata/queries.golden", recorder, reader, nil)
+}
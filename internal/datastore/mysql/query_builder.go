@@ -1,6 +1,8 @@
 package mysql
 
 import (
+	"fmt"
+
 	"github.com/authzed/spicedb/internal/datastore/mysql/migrations"
 
 	sq "github.com/Masterminds/squirrel"
@@ -17,13 +19,14 @@ type QueryBuilder struct {
 	DeleteNamespaceQuery       sq.UpdateBuilder
 	DeleteNamespaceTuplesQuery sq.UpdateBuilder
 
-	QueryTupleIdsQuery    sq.SelectBuilder
-	QueryTuplesQuery      sq.SelectBuilder
-	DeleteTupleQuery      sq.UpdateBuilder
-	QueryTupleExistsQuery sq.SelectBuilder
-	WriteTupleQuery       sq.InsertBuilder
-	QueryChangedQuery     sq.SelectBuilder
-	CountTupleQuery       sq.SelectBuilder
+	QueryTupleIdsQuery        sq.SelectBuilder
+	QueryTuplesQuery          sq.SelectBuilder
+	QueryTuplesBySubjectQuery sq.SelectBuilder
+	DeleteTupleQuery          sq.UpdateBuilder
+	QueryTupleExistsQuery     sq.SelectBuilder
+	WriteTupleQuery           sq.InsertBuilder
+	QueryChangedQuery         sq.SelectBuilder
+	CountTupleQuery           sq.SelectBuilder
 
 	WriteCaveatQuery  sq.InsertBuilder
 	ReadCaveatQuery   sq.SelectBuilder
@@ -49,6 +52,7 @@ func NewQueryBuilder(driver *migrations.MySQLDriver) *QueryBuilder {
 	builder.QueryTupleIdsQuery = queryTupleIds(driver.RelationTuple())
 	builder.DeleteNamespaceTuplesQuery = deleteNamespaceTuples(driver.RelationTuple())
 	builder.QueryTuplesQuery = queryTuples(driver.RelationTuple())
+	builder.QueryTuplesBySubjectQuery = queryTuplesBySubject(driver.RelationTuple())
 	builder.DeleteTupleQuery = deleteTuple(driver.RelationTuple())
 	builder.QueryTupleExistsQuery = queryTupleExists(driver.RelationTuple())
 	builder.WriteTupleQuery = writeTuple(driver.RelationTuple())
@@ -131,6 +135,12 @@ func queryTuples(tableTuple string) sq.SelectBuilder {
 	).From(tableTuple)
 }
 
+// queryTuplesBySubject reads relationships through the index by subject type, which
+// serves the subject-first scans of reverse queries.
+func queryTuplesBySubject(tableTuple string) sq.SelectBuilder {
+	return queryTuples(tableTuple).From(fmt.Sprintf("%s FORCE INDEX (%s)", tableTuple, indexTupleBySubjectType))
+}
+
 func countTuples(tableTuple string) sq.SelectBuilder {
 	return sb.Select(
 		"count(*)",
@@ -45,6 +45,7 @@ var schema = common.SchemaInformation{
 	ColUsersetObjectID:  colUsersetObjectID,
 	ColUsersetRelation:  colUsersetRelation,
 	ColCaveatName:       colCaveatName,
+	SortCollation:       "utf8mb4_bin",
 }
 
 func (mr *mysqlReader) QueryRelationships(
@@ -63,7 +64,7 @@ func (mr *mysqlReader) ReverseQueryRelationships(
 	opts ...options.ReverseQueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
 	// TODO (@vroldanbet) dupe from postgres datastore - need to refactor
-	qBuilder := common.NewSchemaQueryFilterer(schema, mr.filterer(mr.QueryTuplesQuery)).
+	qBuilder := common.NewSchemaQueryFilterer(schema, mr.filterer(mr.QueryTuplesBySubjectQuery)).
 		FilterWithSubjectsFilter(subjectsFilter)
 
 	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
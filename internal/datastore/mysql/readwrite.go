@@ -33,8 +33,9 @@ var duplicateEntryRegx = regexp.MustCompile(`^Duplicate entry '(.+)' for key 'uq
 type mysqlReadWriteTXN struct {
 	*mysqlReader
 
-	tx       *sql.Tx
-	newTxnID uint64
+	tx              *sql.Tx
+	newTxnID        uint64
+	skipNoopTouches bool
 }
 
 // caveatContextWrapper is used to marshall maps into MySQLs JSON data type
@@ -58,6 +59,14 @@ func (rwt *mysqlReadWriteTXN) WriteRelationships(ctx context.Context, mutations
 	// TODO (@vroldanbet) dupe from postgres datastore - need to refactor
 	// there are some fundamental changes introduced to prevent a deadlock in MySQL
 
+	if rwt.skipNoopTouches {
+		living, err := rwt.loadTouched(ctx, mutations)
+		if err != nil {
+			return fmt.Errorf(errUnableToWriteRelationships, err)
+		}
+		mutations = common.WithoutNoopTouches(mutations, living)
+	}
+
 	bulkWrite := rwt.WriteTupleQuery
 	bulkWriteHasValues := false
 
@@ -156,6 +165,42 @@ func (rwt *mysqlReadWriteTXN) WriteRelationships(ctx context.Context, mutations
 	return nil
 }
 
+// loadTouched returns the living relationships TOUCHed by the mutations.
+func (rwt *mysqlReadWriteTXN) loadTouched(ctx context.Context, mutations []*core.RelationTupleUpdate) ([]*core.RelationTuple, error) {
+	touched := common.TouchedRelationships(mutations)
+	if len(touched) == 0 {
+		return nil, nil
+	}
+
+	clauses := make(sq.Or, 0, len(touched))
+	for _, tpl := range touched {
+		clauses = append(clauses, exactRelationshipClause(tpl))
+	}
+
+	query, args, err := rwt.filterer(rwt.QueryTuplesQuery).Where(clauses).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := rwt.querySplitter.Executor(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var living []*core.RelationTuple
+	for {
+		tpl, err := rows.Next()
+		if err != nil {
+			return nil, err
+		}
+		if tpl == nil {
+			return living, nil
+		}
+		living = append(living, tpl)
+	}
+}
+
 func (rwt *mysqlReadWriteTXN) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
 	// TODO (@vroldanbet) dupe from postgres datastore - need to refactor
 	// Add clauses for the ResourceFilter
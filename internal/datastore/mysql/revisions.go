@@ -10,6 +10,8 @@ import (
 
 	"github.com/shopspring/decimal"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/common/revisions"
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 )
@@ -61,13 +63,18 @@ const (
 func (mds *Datastore) optimizedRevisionFunc(ctx context.Context) (datastore.Revision, time.Duration, error) {
 	var rev uint64
 	var validForNanos time.Duration
-	if err := mds.db.QueryRowContext(ctx, mds.optimizedRevisionQuery).
+	if err := mds.db.QueryRowContext(ctx, mds.optimizedRevisionQuery(mds.quantization.Window())).
 		Scan(&rev, &validForNanos); err != nil {
 		return revision.NoRevision, 0, fmt.Errorf(errRevision, err)
 	}
 	return revisionFromTransaction(rev), validForNanos, nil
 }
 
+// RevisionQuantization returns the window to which optimized revisions are quantized.
+func (mds *Datastore) RevisionQuantization() *revisions.Quantization {
+	return mds.quantization
+}
+
 func (mds *Datastore) HeadRevision(ctx context.Context) (datastore.Revision, error) {
 	// implementation deviates slightly from PSQL implementation in order to support
 	// database seeding in runtime, instead of through migrate command
@@ -181,3 +188,5 @@ func revisionFromTransaction(txID uint64) revision.Decimal {
 func transactionFromRevision(revision revision.Decimal) uint64 {
 	return uint64(revision.IntPart())
 }
+
+var _ common.QuantizedDatastore = (*Datastore)(nil)
@@ -82,6 +82,21 @@ func (mds *Datastore) Statistics(ctx context.Context) (datastore.Stats, error) {
 	}, nil
 }
 
+// UniqueID returns the unique ID of the datastore, which is only read once.
+func (mds *Datastore) UniqueID(ctx context.Context) (string, error) {
+	if uniqueID := mds.uniqueID.Load(); uniqueID != nil {
+		return *uniqueID, nil
+	}
+
+	uniqueID, err := mds.getUniqueID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	mds.uniqueID.Store(&uniqueID)
+	return uniqueID, nil
+}
+
 func (mds *Datastore) getUniqueID(ctx context.Context) (string, error) {
 	sql, args, err := sb.Select(metadataUniqueIDColumn).From(mds.driver.Metadata()).ToSql()
 	if err != nil {
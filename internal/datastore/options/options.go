@@ -1,6 +1,8 @@
 package options
 
 import (
+	"strings"
+
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
@@ -10,8 +12,27 @@ import (
 type QueryOptions struct {
 	Limit    *uint64
 	Usersets []*core.ObjectAndRelation
+
+	// StreamRows, if true, reads the relationships from the datastore as the iterator is
+	// advanced, rather than loading all of them before it is returned. The iterator then
+	// holds a connection until closed, so no other reads should be made while iterating.
+	StreamRows bool
+
+	// Sort is the order in which the relationships are returned.
+	Sort SortOrder
 }
 
+// SortOrder is an order in which a query returns relationships.
+type SortOrder int8
+
+const (
+	// Unsorted returns the relationships in any order.
+	Unsorted SortOrder = iota
+
+	// ByResource returns the relationships in the order of CompareByResource.
+	ByResource
+)
+
 // ReverseQueryOptions are the options that can affect the results of a reverse query.
 type ReverseQueryOptions struct {
 	ReverseLimit *uint64
@@ -24,6 +45,26 @@ type ResourceRelation struct {
 	Relation  string
 }
 
+// CompareByResource compares relationships by their resource type, resource ID,
+// relation, subject type, subject ID and subject relation in turn, comparing strings by
+// their bytes. It returns a negative number if a sorts before b, a positive number if
+// after, and zero if they are the same relationship, whatever their caveats.
+func CompareByResource(a, b *core.RelationTuple) int {
+	for _, pair := range [...][2]string{
+		{a.ResourceAndRelation.Namespace, b.ResourceAndRelation.Namespace},
+		{a.ResourceAndRelation.ObjectId, b.ResourceAndRelation.ObjectId},
+		{a.ResourceAndRelation.Relation, b.ResourceAndRelation.Relation},
+		{a.Subject.Namespace, b.Subject.Namespace},
+		{a.Subject.ObjectId, b.Subject.ObjectId},
+		{a.Subject.Relation, b.Subject.Relation},
+	} {
+		if cmp := strings.Compare(pair[0], pair[1]); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
 var (
 	one = uint64(1)
 
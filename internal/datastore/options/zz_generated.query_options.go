@@ -19,6 +19,8 @@ func (q *QueryOptions) ToOption() QueryOptionsOption {
 	return func(to *QueryOptions) {
 		to.Limit = q.Limit
 		to.Usersets = q.Usersets
+		to.StreamRows = q.StreamRows
+		to.Sort = q.Sort
 	}
 }
 
@@ -51,6 +53,20 @@ func SetUsersets(usersets []*v1.ObjectAndRelation) QueryOptionsOption {
 	}
 }
 
+// WithStreamRows returns an option that can set StreamRows on a QueryOptions
+func WithStreamRows(streamRows bool) QueryOptionsOption {
+	return func(q *QueryOptions) {
+		q.StreamRows = streamRows
+	}
+}
+
+// WithSort returns an option that can set Sort on a QueryOptions
+func WithSort(sort SortOrder) QueryOptionsOption {
+	return func(q *QueryOptions) {
+		q.Sort = sort
+	}
+}
+
 type ReverseQueryOptionsOption func(r *ReverseQueryOptions)
 
 // NewReverseQueryOptionsWithOptions creates a new ReverseQueryOptions with the passed in options set
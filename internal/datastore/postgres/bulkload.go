@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+var (
+	copyTupleColumns = []string{
+		colNamespace,
+		colObjectID,
+		colRelation,
+		colUsersetNamespace,
+		colUsersetObjectID,
+		colUsersetRelation,
+		colCaveatContextName,
+		colCaveatContext,
+	}
+
+	// TODO remove once the ID->XID migrations are all complete
+	copyTupleColumnsDeprecated = append(copyTupleColumns[:len(copyTupleColumns):len(copyTupleColumns)],
+		colCreatedTxnDeprecated,
+		colCreatedXid,
+	)
+)
+
+// BulkLoad creates the relationships of the source with a single COPY into the tuple table,
+// which avoids building, sending and planning an INSERT statement for every chunk of them.
+func (rwt *pgReadWriteTXN) BulkLoad(ctx context.Context, source common.BulkRelationshipSource) (uint64, error) {
+	columns := copyTupleColumns
+	if rwt.migrationPhase == writeBothReadNew || rwt.migrationPhase == writeBothReadOld {
+		columns = copyTupleColumnsDeprecated
+	}
+
+	copySource := &copyRelationshipSource{ctx: ctx, rwt: rwt, source: source}
+	loaded, err := rwt.tx.CopyFrom(ctx, pgx.Identifier{tableTuple}, columns, copySource)
+	if err != nil {
+		// Errors of the source are returned as they are, so that the caller can tell its own
+		// failures apart from those of the datastore.
+		if copySource.err != nil {
+			return 0, copySource.err
+		}
+		return 0, convertInsertError(err)
+	}
+	return uint64(loaded), nil
+}
+
+// copyRelationshipSource adapts a BulkRelationshipSource to the rows of a COPY.
+type copyRelationshipSource struct {
+	ctx    context.Context
+	rwt    *pgReadWriteTXN
+	source common.BulkRelationshipSource
+
+	current *core.RelationTuple
+	err     error
+}
+
+func (s *copyRelationshipSource) Next() bool {
+	s.current, s.err = s.source.Next(s.ctx)
+	return s.err == nil && s.current != nil
+}
+
+func (s *copyRelationshipSource) Values() ([]any, error) {
+	return s.rwt.tupleValues(s.current), nil
+}
+
+func (s *copyRelationshipSource) Err() error {
+	return s.err
+}
+
+var _ common.BulkLoader = &pgReadWriteTXN{}
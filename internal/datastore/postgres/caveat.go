@@ -69,11 +69,11 @@ func (r *pgReader) ReadCaveatByName(ctx context.Context, name string) (*core.Cav
 	if err != nil {
 		return nil, datastore.NoRevision, fmt.Errorf(errReadCaveat, err)
 	}
-	rev := postgresRevision{txID, noXmin}
+	rev := postgresRevision{tx: txID, xmin: noXmin}
 
 	// TODO remove once the ID->XID migrations are all complete
 	if r.migrationPhase == writeBothReadOld {
-		rev = postgresRevision{xid8{Uint: versionTxDeprecated, Status: pgtype.Present}, noXmin}
+		rev = postgresRevision{tx: xid8{Uint: versionTxDeprecated, Status: pgtype.Present}, xmin: noXmin}
 	}
 
 	return &def, rev, nil
@@ -0,0 +1,97 @@
+package common
+
+import (
+	"context"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+// AnnotatedPool is a connection pool which annotates the queries issued through it, and
+// through the transactions begun on it, with a QueryAnnotator. With a nil QueryAnnotator,
+// queries are issued as-is.
+type AnnotatedPool struct {
+	*pgxpool.Pool
+	annotator *common.QueryAnnotator
+}
+
+// NewAnnotatedPool wraps the pool so that queries are annotated with the annotator.
+func NewAnnotatedPool(pool *pgxpool.Pool, annotator *common.QueryAnnotator) *AnnotatedPool {
+	return &AnnotatedPool{pool, annotator}
+}
+
+func (ap *AnnotatedPool) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return ap.Pool.Exec(ctx, ap.annotator.Annotate(ctx, sql), args...)
+}
+
+func (ap *AnnotatedPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return ap.Pool.Query(ctx, ap.annotator.Annotate(ctx, sql), args...)
+}
+
+func (ap *AnnotatedPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return ap.Pool.QueryRow(ctx, ap.annotator.Annotate(ctx, sql), args...)
+}
+
+func (ap *AnnotatedPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return ap.BeginTx(ctx, pgx.TxOptions{})
+}
+
+func (ap *AnnotatedPool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	tx, err := ap.Pool.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+	return annotateTx(tx, ap.annotator), nil
+}
+
+func (ap *AnnotatedPool) BeginTxFunc(ctx context.Context, txOptions pgx.TxOptions, f func(pgx.Tx) error) error {
+	return ap.Pool.BeginTxFunc(ctx, txOptions, func(tx pgx.Tx) error {
+		return f(annotateTx(tx, ap.annotator))
+	})
+}
+
+// annotatedTx annotates the queries issued through a transaction. Queries queued in a
+// batch are not rewritten, and must be annotated when queued.
+type annotatedTx struct {
+	pgx.Tx
+	annotator *common.QueryAnnotator
+}
+
+func annotateTx(tx pgx.Tx, annotator *common.QueryAnnotator) pgx.Tx {
+	if annotator == nil {
+		return tx
+	}
+	return &annotatedTx{tx, annotator}
+}
+
+func (at *annotatedTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := at.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return annotateTx(tx, at.annotator), nil
+}
+
+func (at *annotatedTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return at.Tx.Exec(ctx, at.annotator.Annotate(ctx, sql), args...)
+}
+
+func (at *annotatedTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return at.Tx.Query(ctx, at.annotator.Annotate(ctx, sql), args...)
+}
+
+func (at *annotatedTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return at.Tx.QueryRow(ctx, at.annotator.Annotate(ctx, sql), args...)
+}
+
+// AnnotateBatchedQuery annotates SQL to be queued in a batch sent over the transaction,
+// if it was begun on an AnnotatedPool.
+func AnnotateBatchedQuery(ctx context.Context, tx pgx.Tx, sql string) string {
+	if at, ok := tx.(*annotatedTx); ok {
+		return at.annotator.Annotate(ctx, sql)
+	}
+	return sql
+}
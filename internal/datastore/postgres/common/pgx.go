@@ -12,6 +12,7 @@ import (
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/log/zerologadapter"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -22,63 +23,76 @@ const (
 
 // NewPGXExecutor creates an executor that uses the pgx library to make the specified queries.
 func NewPGXExecutor(txSource TxFactory) common.ExecuteQueryFunc {
-	return func(ctx context.Context, sql string, args []any) ([]*corev1.RelationTuple, error) {
+	return func(ctx context.Context, sql string, args []any) (common.TupleRows, error) {
 		span := trace.SpanFromContext(ctx)
 
 		tx, txCleanup, err := txSource(ctx)
 		if err != nil {
 			return nil, fmt.Errorf(errUnableToQueryTuples, err)
 		}
-		defer txCleanup(ctx)
-		return queryTuples(ctx, sql, args, span, tx)
-	}
-}
 
-// queryTuples queries tuples for the given query and transaction.
-func queryTuples(ctx context.Context, sqlStatement string, args []any, span trace.Span, tx pgx.Tx) ([]*corev1.RelationTuple, error) {
-	span.AddEvent("DB transaction established")
-	rows, err := tx.Query(ctx, sqlStatement, args...)
-	if err != nil {
-		return nil, fmt.Errorf(errUnableToQueryTuples, err)
+		span.AddEvent("DB transaction established")
+		rows, err := tx.Query(ctx, sql, args...)
+		if err != nil {
+			txCleanup(ctx)
+			return nil, fmt.Errorf(errUnableToQueryTuples, err)
+		}
+
+		span.AddEvent("Query issued to database")
+		return &pgxTupleRows{ctx: ctx, span: span, rows: rows, txCleanup: txCleanup}, nil
 	}
-	defer rows.Close()
+}
 
-	span.AddEvent("Query issued to database")
+// pgxTupleRows reads tuples from the rows of a query, holding its transaction until closed.
+type pgxTupleRows struct {
+	ctx       context.Context
+	span      trace.Span
+	rows      pgx.Rows
+	txCleanup common.TxCleanupFunc
+	loaded    int
+}
 
-	var tuples []*corev1.RelationTuple
-	for rows.Next() {
-		nextTuple := &corev1.RelationTuple{
-			ResourceAndRelation: &corev1.ObjectAndRelation{},
-			Subject:             &corev1.ObjectAndRelation{},
-		}
-		var caveatName sql.NullString
-		var caveatCtx map[string]any
-		err := rows.Scan(
-			&nextTuple.ResourceAndRelation.Namespace,
-			&nextTuple.ResourceAndRelation.ObjectId,
-			&nextTuple.ResourceAndRelation.Relation,
-			&nextTuple.Subject.Namespace,
-			&nextTuple.Subject.ObjectId,
-			&nextTuple.Subject.Relation,
-			&caveatName,
-			&caveatCtx,
-		)
-		if err != nil {
+func (r *pgxTupleRows) Next() (*corev1.RelationTuple, error) {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
 			return nil, fmt.Errorf(errUnableToQueryTuples, err)
 		}
+		return nil, nil
+	}
 
-		nextTuple.Caveat, err = common.ContextualizedCaveatFrom(caveatName.String, caveatCtx)
-		if err != nil {
-			return nil, fmt.Errorf("unable to fetch caveat context: %w", err)
-		}
-		tuples = append(tuples, nextTuple)
+	nextTuple := &corev1.RelationTuple{
+		ResourceAndRelation: &corev1.ObjectAndRelation{},
+		Subject:             &corev1.ObjectAndRelation{},
 	}
-	if err := rows.Err(); err != nil {
+	var caveatName sql.NullString
+	var caveatCtx map[string]any
+	err := r.rows.Scan(
+		&nextTuple.ResourceAndRelation.Namespace,
+		&nextTuple.ResourceAndRelation.ObjectId,
+		&nextTuple.ResourceAndRelation.Relation,
+		&nextTuple.Subject.Namespace,
+		&nextTuple.Subject.ObjectId,
+		&nextTuple.Subject.Relation,
+		&caveatName,
+		&caveatCtx,
+	)
+	if err != nil {
 		return nil, fmt.Errorf(errUnableToQueryTuples, err)
 	}
 
-	span.AddEvent("Tuples loaded", trace.WithAttributes(attribute.Int("tupleCount", len(tuples))))
-	return tuples, nil
+	nextTuple.Caveat, err = common.ContextualizedCaveatFrom(caveatName.String, caveatCtx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch caveat context: %w", err)
+	}
+
+	r.loaded++
+	return nextTuple, nil
+}
+
+func (r *pgxTupleRows) Close() {
+	r.rows.Close()
+	r.span.AddEvent("Tuples loaded", trace.WithAttributes(attribute.Int("tupleCount", r.loaded)))
+	r.txCleanup(r.ctx)
 }
 
 // ConfigurePGXLogger sets zerolog global logger into the connection pool configuration, and maps
@@ -104,6 +118,21 @@ func ConfigurePGXLogger(connConfig *pgx.ConnConfig) {
 	connConfig.Logger = levelMappingFn(l)
 }
 
+// PoolStats returns a function which reads the statistics of a pgx connection pool.
+func PoolStats(pool *pgxpool.Pool) common.PoolStatsFunc {
+	return func() common.PoolStats {
+		stat := pool.Stat()
+		return common.PoolStats{
+			WaitCount:           stat.EmptyAcquireCount(),
+			AcquireWaitDuration: stat.AcquireDuration(),
+			InUseConns:          int64(stat.AcquiredConns()),
+			IdleConns:           int64(stat.IdleConns()),
+			MaxConns:            int64(stat.MaxConns()),
+			ClosedConns:         stat.MaxLifetimeDestroyCount() + stat.MaxIdleDestroyCount(),
+		}
+	}
+}
+
 // TxFactory returns a transaction, cleanup function, and any errors that may have
 // occurred when building the transaction.
 type TxFactory func(context.Context) (pgx.Tx, common.TxCleanupFunc, error)
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+var (
+	_ common.RelationshipHistoryCompactor = (*pgDatastore)(nil)
+
+	errCompactDuringMigration = errors.New("compaction is unavailable until the xid migration has completed")
+
+	// sameRelationshipCols are the columns which are equal across the versions of a
+	// relationship which were rewritten without change.
+	sameRelationshipCols = []string{
+		colNamespace,
+		colObjectID,
+		colRelation,
+		colUsersetNamespace,
+		colUsersetObjectID,
+		colUsersetRelation,
+		colCaveatContextName,
+		colCaveatContext,
+	}
+
+	// mergedCols identify an earlier version of a relationship, and the later version
+	// identical to it.
+	mergedCols = append(append([]string{}, relationTuplePKCols...), colCaveatContextName, colCaveatContext)
+
+	// The parameters to this format string are:
+	// 1: the relation tuple table name
+	// 2: the columns selected for each merged pair, qualified by the table alias `older`
+	// 3: a join condition on sameRelationshipCols between the aliases `older` and `newer`
+	// 4: the same condition between the aliases `previous` and `older`
+	// 5: the same condition between the aliases `merged` and `rt`
+	// 6: the primary key columns, qualified by the table alias `rt`
+	// 7: the primary key columns, qualified by the table alias `merged`
+	// 8: the created_xid column name
+	// 9: the deleted_xid column name
+	//
+	// Only pairs starting a chain of identical versions are merged, so that no row is both
+	// deleted and updated by the statement; longer chains are merged by repeating it.
+	compactRelationshipHistoryQuery = `WITH merged AS (
+		SELECT %[2]s
+		FROM %[1]s older
+		JOIN %[1]s newer ON %[3]s AND newer.%[8]s = older.%[9]s
+		WHERE NOT EXISTS (
+			SELECT 1 FROM %[1]s previous WHERE %[4]s AND previous.%[9]s = older.%[8]s
+		)
+		LIMIT $1
+	), deleted AS (
+		DELETE FROM %[1]s rt
+		USING merged
+		WHERE (%[6]s) = (%[7]s)
+	)
+	UPDATE %[1]s rt
+	SET %[8]s = merged.%[8]s
+	FROM merged
+	WHERE %[5]s AND rt.%[8]s = merged.%[9]s;`
+)
+
+func joinSameRelationship(first, second string) string {
+	conditions := make([]string, 0, len(sameRelationshipCols))
+	for _, col := range sameRelationshipCols {
+		conditions = append(conditions, fmt.Sprintf("%[1]s.%[3]s IS NOT DISTINCT FROM %[2]s.%[3]s", first, second, col))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+func qualifiedCols(table string, cols []string) string {
+	qualified := make([]string, 0, len(cols))
+	for _, col := range cols {
+		qualified = append(qualified, table+"."+col)
+	}
+	return strings.Join(qualified, ", ")
+}
+
+// CompactRelationshipHistory merges consecutive versions of relationships which are
+// identical, such as those left by TOUCHing a relationship without changing it: the
+// earlier version is removed, and the later one takes its place from the revision at
+// which the earlier version was created. Reads at any revision are unaffected, but the
+// rewrites are no longer reported as changes by Watch.
+func (pgd *pgDatastore) CompactRelationshipHistory(ctx context.Context, limit uint64) (int64, error) {
+	if pgd.migrationPhase != complete {
+		return 0, errCompactDuringMigration
+	}
+
+	query := fmt.Sprintf(compactRelationshipHistoryQuery,
+		tableTuple,
+		qualifiedCols("older", mergedCols),
+		joinSameRelationship("older", "newer"),
+		joinSameRelationship("previous", "older"),
+		joinSameRelationship("merged", "rt"),
+		qualifiedCols("rt", relationTuplePKCols),
+		qualifiedCols("merged", relationTuplePKCols),
+		colCreatedXid,
+		colDeletedXid,
+	)
+
+	result, err := pgd.dbpool.Exec(ctx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("unable to compact relationship history: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+const (
+	colEpoch = "epoch"
+
+	errReadEpoch = "unable to read datastore epoch: %w"
+	errBumpEpoch = "unable to bump datastore epoch: %w"
+)
+
+var (
+	hasEpochColumn = fmt.Sprintf(
+		"SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = '%s' AND column_name = '%s')",
+		tableMetadata, colEpoch,
+	)
+
+	queryEpoch = psql.Select(colEpoch).From(tableMetadata)
+
+	bumpEpoch = fmt.Sprintf("UPDATE %[1]s SET %[2]s = %[2]s + 1 RETURNING %[2]s", tableMetadata, colEpoch)
+)
+
+func (pgd *pgDatastore) Epoch(ctx context.Context) (uint64, error) {
+	// Datastores migrated before epochs were introduced have never been restored since.
+	if !pgd.epochsEnabled {
+		return 0, nil
+	}
+
+	sql, args, err := queryEpoch.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf(errReadEpoch, err)
+	}
+
+	var epoch uint64
+	if err := pgd.dbpool.QueryRow(ctx, sql, args...).Scan(&epoch); err != nil {
+		return 0, fmt.Errorf(errReadEpoch, err)
+	}
+	return epoch, nil
+}
+
+func (pgd *pgDatastore) BumpEpoch(ctx context.Context) (uint64, error) {
+	if !pgd.epochsEnabled {
+		return 0, common.ErrEpochsUnsupported
+	}
+
+	var epoch uint64
+	if err := pgd.dbpool.QueryRow(ctx, bumpEpoch).Scan(&epoch); err != nil {
+		return 0, fmt.Errorf(errBumpEpoch, err)
+	}
+	return epoch, nil
+}
+
+var _ common.EpochStore = &pgDatastore{}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+const (
+	tableNamespaceExperiment = "namespace_experiment"
+
+	colExperiment = "experiment"
+
+	errSetNamespaceExperiment   = "unable to set namespace experiment: %w"
+	errListNamespaceExperiments = "unable to list namespace experiments: %w"
+)
+
+var (
+	hasNamespaceExperimentTable = fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", tableNamespaceExperiment)
+
+	enableNamespaceExperiment = psql.
+					Insert(tableNamespaceExperiment).
+					Columns(colNamespace, colExperiment).
+					Suffix(fmt.Sprintf("ON CONFLICT (%s, %s) DO NOTHING", colNamespace, colExperiment))
+
+	disableNamespaceExperiment = psql.Delete(tableNamespaceExperiment)
+
+	listNamespaceExperiments = psql.
+					Select(colNamespace, colExperiment).
+					From(tableNamespaceExperiment).
+					OrderBy(colNamespace, colExperiment)
+)
+
+func (pgd *pgDatastore) SetNamespaceExperiment(ctx context.Context, namespace, experiment string, enabled bool) error {
+	if !pgd.experimentsEnabled {
+		return datastore.NewNamespaceExperimentsUnsupportedErr(Engine)
+	}
+
+	query := sq.Sqlizer(enableNamespaceExperiment.Values(namespace, experiment))
+	if !enabled {
+		query = disableNamespaceExperiment.Where(sq.Eq{colNamespace: namespace, colExperiment: experiment})
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf(errSetNamespaceExperiment, err)
+	}
+
+	if _, err := pgd.dbpool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf(errSetNamespaceExperiment, err)
+	}
+	return nil
+}
+
+func (pgd *pgDatastore) ListNamespaceExperiments(ctx context.Context) ([]datastore.NamespaceExperiment, error) {
+	if !pgd.experimentsEnabled {
+		return nil, datastore.NewNamespaceExperimentsUnsupportedErr(Engine)
+	}
+
+	sql, args, err := listNamespaceExperiments.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf(errListNamespaceExperiments, err)
+	}
+
+	rows, err := pgd.dbpool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf(errListNamespaceExperiments, err)
+	}
+	defer rows.Close()
+
+	var experiments []datastore.NamespaceExperiment
+	for rows.Next() {
+		var experiment datastore.NamespaceExperiment
+		if err := rows.Scan(&experiment.Namespace, &experiment.Experiment); err != nil {
+			return nil, fmt.Errorf(errListNamespaceExperiments, err)
+		}
+		experiments = append(experiments, experiment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errListNamespaceExperiments, err)
+	}
+	return experiments, nil
+}
+
+var _ common.NamespaceExperimentStore = &pgDatastore{}
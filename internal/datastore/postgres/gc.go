@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	gosql "database/sql"
 	"fmt"
 	"strings"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/common"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
 var (
@@ -31,6 +33,26 @@ var (
 	namespacePKCols = []string{colNamespace, colCreatedXid, colDeletedXid}
 
 	transactionPKCols = []string{colXID}
+
+	// archivedRelationships selects deleted relationship rows, with the times of the
+	// transactions which wrote and deleted them where those have not yet been collected.
+	archivedRelationships = psql.Select(
+		"rt."+colNamespace,
+		"rt."+colObjectID,
+		"rt."+colRelation,
+		"rt."+colUsersetNamespace,
+		"rt."+colUsersetObjectID,
+		"rt."+colUsersetRelation,
+		"rt."+colCaveatContextName,
+		"rt."+colCaveatContext,
+		"rt."+colCreatedXid,
+		"rt."+colDeletedXid,
+		"created."+colTimestamp,
+		"deleted."+colTimestamp,
+	).
+		From(tableTuple + " rt").
+		LeftJoin(fmt.Sprintf("%[1]s created ON created.%[2]s = rt.%[3]s", tableTransaction, colXID, colCreatedXid)).
+		LeftJoin(fmt.Sprintf("%[1]s deleted ON deleted.%[2]s = rt.%[3]s", tableTransaction, colXID, colDeletedXid))
 )
 
 func (pgd *pgDatastore) Now(ctx context.Context) (time.Time, error) {
@@ -52,8 +74,19 @@ func (pgd *pgDatastore) Now(ctx context.Context) (time.Time, error) {
 }
 
 func (pgd *pgDatastore) TxIDBefore(ctx context.Context, before time.Time) (datastore.Revision, error) {
-	// Find the highest transaction ID before the GC window.
-	sql, args, err := getRevision.Where(sq.Lt{colTimestamp: before}).ToSql()
+	// Find the highest transaction ID before the GC window, and before any pinned
+	// transaction, which must be retained along with the rows alive at it.
+	query := getRevision.Where(sq.Lt{colTimestamp: before})
+
+	pinnedXID, pinnedAt, err := pgd.oldestPin(ctx)
+	if err != nil {
+		return datastore.NoRevision, err
+	}
+	if pinnedXID.Status == pgtype.Present {
+		query = query.Where(sq.Lt{colXID: pinnedXID, colTimestamp: pinnedAt})
+	}
+
+	sql, args, err := query.ToSql()
 	if err != nil {
 		return datastore.NoRevision, err
 	}
@@ -69,7 +102,7 @@ func (pgd *pgDatastore) TxIDBefore(ctx context.Context, before time.Time) (datas
 		return datastore.NoRevision, err
 	}
 
-	return postgresRevision{value, xmin}, nil
+	return postgresRevision{tx: value, xmin: xmin}, nil
 }
 
 func (pgd *pgDatastore) DeleteBeforeTx(ctx context.Context, txID datastore.Revision) (removed common.DeletionCounts, err error) {
@@ -81,12 +114,16 @@ func (pgd *pgDatastore) DeleteBeforeTx(ctx context.Context, txID datastore.Revis
 	}
 
 	// Delete any relationship rows that were already dead when this transaction started
-	removed.Relationships, err = pgd.batchDelete(
-		ctx,
-		tableTuple,
-		relationTuplePKCols,
-		sq.Lt{colDeletedXid: minTxAlive},
-	)
+	if pgd.gcArchiver != nil {
+		removed.Relationships, err = pgd.archiveAndDeleteRelationships(ctx, minTxAlive)
+	} else {
+		removed.Relationships, err = pgd.batchDelete(
+			ctx,
+			tableTuple,
+			relationTuplePKCols,
+			sq.Lt{colDeletedXid: minTxAlive},
+		)
+	}
 	if err != nil {
 		return
 	}
@@ -105,6 +142,17 @@ func (pgd *pgDatastore) DeleteBeforeTx(ctx context.Context, txID datastore.Revis
 		return
 	}
 
+	// Delete the pins which have expired, releasing their transactions for the next run.
+	if pgd.pinningEnabled {
+		sql, args, err := deleteExpiredPins.ToSql()
+		if err != nil {
+			return removed, err
+		}
+		if _, err := pgd.dbpool.Exec(ctx, sql, args...); err != nil {
+			return removed, err
+		}
+	}
+
 	// Delete any namespace rows with deleted_transaction <= the transaction ID.
 	removed.Namespaces, err = pgd.batchDelete(
 		ctx,
@@ -153,3 +201,110 @@ func (pgd *pgDatastore) batchDelete(
 
 	return deletedCount, nil
 }
+
+// archiveAndDeleteRelationships passes the relationship rows deleted before minTxAlive to
+// the archiver in batches, deleting each batch once archived.
+func (pgd *pgDatastore) archiveAndDeleteRelationships(ctx context.Context, minTxAlive xid8) (int64, error) {
+	sql, args, err := archivedRelationships.Where(sq.Lt{"rt." + colDeletedXid: minTxAlive}).Limit(batchDeleteSize).ToSql()
+	if err != nil {
+		return -1, err
+	}
+
+	var deletedCount int64
+	for {
+		versions, keys, err := pgd.loadArchivedRelationships(ctx, sql, args)
+		if err != nil {
+			return deletedCount, err
+		}
+		if len(versions) == 0 {
+			return deletedCount, nil
+		}
+
+		if err := pgd.gcArchiver.ArchiveRelationships(ctx, versions); err != nil {
+			return deletedCount, err
+		}
+
+		deleteSQL, deleteArgs, err := psql.Delete(tableTuple).Where(keys).ToSql()
+		if err != nil {
+			return deletedCount, err
+		}
+		cr, err := pgd.dbpool.Exec(ctx, deleteSQL, deleteArgs...)
+		if err != nil {
+			return deletedCount, err
+		}
+
+		deletedCount += cr.RowsAffected()
+		if len(versions) < batchDeleteSize {
+			return deletedCount, nil
+		}
+	}
+}
+
+// loadArchivedRelationships returns the relationship versions read by the query, along
+// with a filter matching their rows by primary key.
+func (pgd *pgDatastore) loadArchivedRelationships(ctx context.Context, sql string, args []any) ([]common.RelationshipVersion, sq.Or, error) {
+	rows, err := pgd.dbpool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var versions []common.RelationshipVersion
+	var keys sq.Or
+	for rows.Next() {
+		rel := &core.RelationTuple{
+			ResourceAndRelation: &core.ObjectAndRelation{},
+			Subject:             &core.ObjectAndRelation{},
+		}
+		var caveatName gosql.NullString
+		var caveatCtx map[string]any
+		var created, deleted xid8
+		var createdAt, deletedAt pgtype.Timestamp
+		if err := rows.Scan(
+			&rel.ResourceAndRelation.Namespace,
+			&rel.ResourceAndRelation.ObjectId,
+			&rel.ResourceAndRelation.Relation,
+			&rel.Subject.Namespace,
+			&rel.Subject.ObjectId,
+			&rel.Subject.Relation,
+			&caveatName,
+			&caveatCtx,
+			&created,
+			&deleted,
+			&createdAt,
+			&deletedAt,
+		); err != nil {
+			return nil, nil, err
+		}
+
+		rel.Caveat, err = common.ContextualizedCaveatFrom(caveatName.String, caveatCtx)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		version := common.RelationshipVersion{
+			Relationship:    rel,
+			CreatedRevision: postgresRevision{tx: created, xmin: noXmin},
+			DeletedRevision: postgresRevision{tx: deleted, xmin: noXmin},
+		}
+		if createdAt.Status == pgtype.Present {
+			version.CreatedAt = createdAt.Time
+		}
+		if deletedAt.Status == pgtype.Present {
+			version.DeletedAt = deletedAt.Time
+		}
+		versions = append(versions, version)
+
+		keys = append(keys, sq.Eq{
+			colNamespace:        rel.ResourceAndRelation.Namespace,
+			colObjectID:         rel.ResourceAndRelation.ObjectId,
+			colRelation:         rel.ResourceAndRelation.Relation,
+			colUsersetNamespace: rel.Subject.Namespace,
+			colUsersetObjectID:  rel.Subject.ObjectId,
+			colUsersetRelation:  rel.Subject.Relation,
+			colCreatedXid:       created,
+			colDeletedXid:       deleted,
+		})
+	}
+	return versions, keys, rows.Err()
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+const (
+	tableJob = "job"
+
+	colJobID           = "id"
+	colJobKind         = "kind"
+	colJobParameters   = "parameters"
+	colJobState        = "state"
+	colJobProgress     = "progress"
+	colJobResult       = "result"
+	colJobError        = "error"
+	colCancelRequested = "cancel_requested"
+	colUpdatedAt       = "updated_at"
+
+	errWriteJob = "unable to write job: %w"
+	errReadJobs = "unable to read jobs: %w"
+)
+
+var (
+	hasJobTable = fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", tableJob)
+
+	createJob = psql.
+			Insert(tableJob).
+			Columns(colJobID, colJobKind, colJobParameters, colJobState, colJobProgress, colJobResult, colJobError)
+
+	readJobs = psql.
+			Select(colJobID, colJobKind, colJobParameters, colJobState, colJobProgress, colJobResult, colJobError, colCancelRequested, colCreatedAt, colUpdatedAt).
+			From(tableJob)
+)
+
+func (pgd *pgDatastore) CreateJob(ctx context.Context, job common.Job) error {
+	if !pgd.jobsEnabled {
+		return common.ErrJobsUnsupported
+	}
+
+	sql, args, err := createJob.Values(job.ID, job.Kind, job.Parameters, string(job.State), job.Progress, job.Result, job.Error).ToSql()
+	if err != nil {
+		return fmt.Errorf(errWriteJob, err)
+	}
+	if _, err := pgd.dbpool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf(errWriteJob, err)
+	}
+	return nil
+}
+
+func (pgd *pgDatastore) UpdateJob(ctx context.Context, job common.Job) error {
+	return pgd.updateJob(ctx, job.ID, map[string]any{
+		colJobState:    string(job.State),
+		colJobProgress: job.Progress,
+		colJobResult:   job.Result,
+		colJobError:    job.Error,
+		colUpdatedAt:   sq.Expr("NOW()"),
+	})
+}
+
+func (pgd *pgDatastore) RequestJobCancellation(ctx context.Context, id string) error {
+	return pgd.updateJob(ctx, id, map[string]any{colCancelRequested: true})
+}
+
+func (pgd *pgDatastore) updateJob(ctx context.Context, id string, values map[string]any) error {
+	if !pgd.jobsEnabled {
+		return common.ErrJobsUnsupported
+	}
+
+	sql, args, err := psql.Update(tableJob).SetMap(values).Where(sq.Eq{colJobID: id}).ToSql()
+	if err != nil {
+		return fmt.Errorf(errWriteJob, err)
+	}
+	result, err := pgd.dbpool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf(errWriteJob, err)
+	}
+	if result.RowsAffected() == 0 {
+		return common.ErrJobNotFound
+	}
+	return nil
+}
+
+func (pgd *pgDatastore) ReadJob(ctx context.Context, id string) (common.Job, error) {
+	jobs, err := pgd.readJobs(ctx, readJobs.Where(sq.Eq{colJobID: id}))
+	if err != nil {
+		return common.Job{}, err
+	}
+	if len(jobs) == 0 {
+		return common.Job{}, common.ErrJobNotFound
+	}
+	return jobs[0], nil
+}
+
+func (pgd *pgDatastore) ListJobs(ctx context.Context, limit uint64) ([]common.Job, error) {
+	query := readJobs.OrderBy(colCreatedAt + " DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	return pgd.readJobs(ctx, query)
+}
+
+func (pgd *pgDatastore) readJobs(ctx context.Context, query sq.SelectBuilder) ([]common.Job, error) {
+	if !pgd.jobsEnabled {
+		return nil, common.ErrJobsUnsupported
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf(errReadJobs, err)
+	}
+
+	rows, err := pgd.dbpool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf(errReadJobs, err)
+	}
+	defer rows.Close()
+
+	var jobs []common.Job
+	for rows.Next() {
+		var job common.Job
+		var state string
+		if err := rows.Scan(&job.ID, &job.Kind, &job.Parameters, &state, &job.Progress, &job.Result, &job.Error, &job.CancelRequested, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return nil, fmt.Errorf(errReadJobs, err)
+		}
+		job.State = common.JobState(state)
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errReadJobs, err)
+	}
+	return jobs, nil
+}
+
+var _ common.JobStore = &pgDatastore{}
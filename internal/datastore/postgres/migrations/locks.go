@@ -0,0 +1,41 @@
+package migrations
+
+import "strings"
+
+func init() {
+	DatabaseMigrations.SetLockEstimator(estimateLockImpact)
+}
+
+// estimateLockImpact estimates the table locks Postgres takes for a migration statement.
+func estimateLockImpact(statement string) string {
+	stmt := strings.ToUpper(strings.Join(strings.Fields(statement), " "))
+
+	switch {
+	case strings.HasPrefix(stmt, "CREATE TABLE"):
+		return "none: the table is new"
+
+	case strings.HasPrefix(stmt, "CREATE INDEX CONCURRENTLY"),
+		strings.HasPrefix(stmt, "CREATE UNIQUE INDEX CONCURRENTLY"):
+		return "SHARE UPDATE EXCLUSIVE: reads and writes continue while the index is built"
+
+	case strings.HasPrefix(stmt, "CREATE INDEX"), strings.HasPrefix(stmt, "CREATE UNIQUE INDEX"):
+		return "SHARE: writes to the table are blocked until the index is built"
+
+	case strings.HasPrefix(stmt, "DROP INDEX"):
+		return "ACCESS EXCLUSIVE, held briefly: reads and writes to the table wait for the lock"
+
+	case strings.HasPrefix(stmt, "ALTER TABLE"):
+		if strings.Contains(stmt, "SET NOT NULL") ||
+			strings.Contains(stmt, "SERIAL") ||
+			(strings.Contains(stmt, "ADD CONSTRAINT") && !strings.Contains(stmt, "USING INDEX")) {
+			return "ACCESS EXCLUSIVE, held while the table is scanned or rewritten: reads and writes are blocked"
+		}
+		return "ACCESS EXCLUSIVE, held briefly: reads and writes to the table wait for the lock"
+
+	case strings.HasPrefix(stmt, "INSERT"), strings.HasPrefix(stmt, "UPDATE"), strings.HasPrefix(stmt, "DELETE"):
+		return "ROW EXCLUSIVE: concurrent writes to the affected rows wait"
+
+	default:
+		return "unknown"
+	}
+}
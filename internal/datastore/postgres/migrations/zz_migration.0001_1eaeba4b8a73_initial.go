@@ -62,4 +62,9 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("1eaeba4b8a73", createRelationTupleTransaction, createNamespaceConfig, createRelationTuple,
+		insertFirstTransaction, createAlembicVersion, insertEmptyVersion); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
@@ -25,4 +25,8 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("add-reverse-index", createReverseQueryIndex, createReverseCheckIndex); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
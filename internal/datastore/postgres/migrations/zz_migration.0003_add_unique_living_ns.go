@@ -30,4 +30,8 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("add-unique-living-ns", deleteAllButNewestNamespace, createUniqueLivingNamespaceConstraint); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
@@ -17,4 +17,8 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("add-transaction-timestamp-index", createIndexOnTupleTransactionTimestamp); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
@@ -19,4 +19,8 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("change-transaction-timestamp-default", alterTimestampDefaultValue); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
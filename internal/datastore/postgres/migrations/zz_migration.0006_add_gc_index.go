@@ -20,4 +20,8 @@ func init() {
 	); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("add-gc-index", createDeletedTransactionIndex); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
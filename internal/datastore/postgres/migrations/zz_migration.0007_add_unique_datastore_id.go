@@ -27,4 +27,8 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("add-unique-datastore-id", createUniqueIDTable, insertUniqueID); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
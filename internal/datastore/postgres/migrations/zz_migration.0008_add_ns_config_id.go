@@ -29,4 +29,8 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("add-ns-config-id", dropNSConfigPK, createNSConfigID); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
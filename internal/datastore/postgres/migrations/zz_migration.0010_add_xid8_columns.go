@@ -53,4 +53,9 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("add-xid-columns", addTransactionXIDColumns, addTupleXIDColumns, addNamespaceXIDColumns,
+		addCaveatXIDColumns, addTransactionDefault); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
@@ -154,4 +154,12 @@ func init() {
 	); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	var statements []string
+	for _, group := range [][]string{addBackfillIndices, backfills, addXIDIndices, dropBackfillIndices} {
+		statements = append(statements, group...)
+	}
+	if err := DatabaseMigrations.Describe("backfill-xid-add-indices", statements...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
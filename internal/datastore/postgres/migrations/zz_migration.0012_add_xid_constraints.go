@@ -73,4 +73,8 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("add-xid-constraints", append([]string{dropNSConfigIDPkey}, addXIDConstraints...)...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
@@ -36,4 +36,8 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("drop-id-constraints", dropIDConstraints...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
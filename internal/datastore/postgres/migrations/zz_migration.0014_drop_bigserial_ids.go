@@ -33,4 +33,8 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := DatabaseMigrations.Describe("drop-bigserial-ids", dropIDStmts...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var addTransactionHLCStmts = []string{
+	`ALTER TABLE relation_tuple_transaction
+		ADD COLUMN hlc DECIMAL NULL;`,
+	`CREATE UNIQUE INDEX ix_relation_tuple_transaction_by_hlc
+		ON relation_tuple_transaction (hlc);`,
+}
+
+func init() {
+	if err := DatabaseMigrations.Register("add-transaction-hlc", "drop-bigserial-ids",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			for _, stmt := range addTransactionHLCStmts {
+				if _, err := tx.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := DatabaseMigrations.Describe("add-transaction-hlc", addTransactionHLCStmts...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var addPinnedRevisionsStmts = []string{
+	`CREATE TABLE pinned_revision (
+		name VARCHAR NOT NULL,
+		xid xid8 NOT NULL,
+		timestamp TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		CONSTRAINT pk_pinned_revision PRIMARY KEY (name));`,
+}
+
+func init() {
+	if err := DatabaseMigrations.Register("add-pinned-revisions", "add-transaction-hlc",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			for _, stmt := range addPinnedRevisionsStmts {
+				if _, err := tx.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := DatabaseMigrations.Describe("add-pinned-revisions", addPinnedRevisionsStmts...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
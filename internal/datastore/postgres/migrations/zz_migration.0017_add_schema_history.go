@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var addSchemaHistoryStmts = []string{
+	`CREATE TABLE schema_version_history (
+		version BIGINT GENERATED ALWAYS AS IDENTITY,
+		revision VARCHAR NOT NULL,
+		schema_text TEXT NOT NULL,
+		author VARCHAR NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		CONSTRAINT pk_schema_version_history PRIMARY KEY (version));`,
+}
+
+func init() {
+	if err := DatabaseMigrations.Register("add-schema-history", "add-pinned-revisions",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			for _, stmt := range addSchemaHistoryStmts {
+				if _, err := tx.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := DatabaseMigrations.Describe("add-schema-history", addSchemaHistoryStmts...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var addNamespaceExperimentsStmts = []string{
+	`CREATE TABLE namespace_experiment (
+		namespace VARCHAR NOT NULL,
+		experiment VARCHAR NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		CONSTRAINT pk_namespace_experiment PRIMARY KEY (namespace, experiment));`,
+}
+
+func init() {
+	if err := DatabaseMigrations.Register("add-namespace-experiments", "add-schema-history",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			for _, stmt := range addNamespaceExperimentsStmts {
+				if _, err := tx.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := DatabaseMigrations.Describe("add-namespace-experiments", addNamespaceExperimentsStmts...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
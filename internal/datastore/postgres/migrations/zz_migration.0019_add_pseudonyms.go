@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var addPseudonymsStmts = []string{
+	`CREATE TABLE pseudonym (
+		pseudonym VARCHAR NOT NULL,
+		sealed_id BYTEA NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		CONSTRAINT pk_pseudonym PRIMARY KEY (pseudonym));`,
+}
+
+func init() {
+	if err := DatabaseMigrations.Register("add-pseudonyms", "add-namespace-experiments",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			for _, stmt := range addPseudonymsStmts {
+				if _, err := tx.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := DatabaseMigrations.Describe("add-pseudonyms", addPseudonymsStmts...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
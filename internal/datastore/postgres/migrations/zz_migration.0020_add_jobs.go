@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var addJobsStmts = []string{
+	`CREATE TABLE job (
+		id VARCHAR NOT NULL,
+		kind VARCHAR NOT NULL,
+		parameters BYTEA NOT NULL,
+		state VARCHAR NOT NULL,
+		progress BIGINT NOT NULL DEFAULT 0,
+		result BYTEA,
+		error TEXT NOT NULL DEFAULT '',
+		cancel_requested BOOLEAN NOT NULL DEFAULT false,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		CONSTRAINT pk_job PRIMARY KEY (id));`,
+	`CREATE INDEX ix_job_by_created_at ON job (created_at);`,
+}
+
+func init() {
+	if err := DatabaseMigrations.Register("add-jobs", "add-pseudonyms",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			for _, stmt := range addJobsStmts {
+				if _, err := tx.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := DatabaseMigrations.Describe("add-jobs", addJobsStmts...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var addNamespaceTombstonesStmts = []string{
+	`CREATE TABLE namespace_tombstone (
+		namespace VARCHAR NOT NULL,
+		revision VARCHAR NOT NULL,
+		deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMPTZ NOT NULL,
+		CONSTRAINT pk_namespace_tombstone PRIMARY KEY (namespace));`,
+}
+
+func init() {
+	if err := DatabaseMigrations.Register("add-namespace-tombstones", "add-jobs",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			for _, stmt := range addNamespaceTombstonesStmts {
+				if _, err := tx.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := DatabaseMigrations.Describe("add-namespace-tombstones", addNamespaceTombstonesStmts...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var addRelationshipSourcesStmts = []string{
+	`CREATE TABLE relationship_source (
+		namespace VARCHAR NOT NULL,
+		object_id VARCHAR NOT NULL,
+		relation VARCHAR NOT NULL,
+		userset_namespace VARCHAR NOT NULL,
+		userset_object_id VARCHAR NOT NULL,
+		userset_relation VARCHAR NOT NULL,
+		source VARCHAR NOT NULL,
+		CONSTRAINT pk_relationship_source PRIMARY KEY (namespace, object_id, relation, userset_namespace, userset_object_id, userset_relation));`,
+	`CREATE INDEX ix_relationship_source_by_source ON relationship_source (source);`,
+}
+
+func init() {
+	if err := DatabaseMigrations.Register("add-relationship-sources", "add-namespace-tombstones",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			for _, stmt := range addRelationshipSourcesStmts {
+				if _, err := tx.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := DatabaseMigrations.Describe("add-relationship-sources", addRelationshipSourcesStmts...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
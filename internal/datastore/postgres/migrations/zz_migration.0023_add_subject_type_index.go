@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// createSubjectTypeIndex creates the index of relationships by subject for reverse
+// queries. Unlike ix_relation_tuple_by_subject, which leads with the subject ID, it leads
+// with the subject type, so it also serves queries for all the subjects of a type or for
+// subject IDs matched by prefix.
+const createSubjectTypeIndex = `CREATE INDEX CONCURRENTLY IF NOT EXISTS ix_relation_tuple_by_subject_type
+	ON relation_tuple (userset_namespace, userset_object_id, userset_relation, namespace, relation)`
+
+func init() {
+	if err := DatabaseMigrations.Register("add-subject-type-index", "add-relationship-sources",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			// CREATE INDEX CONCURRENTLY cannot run inside a transaction block (SQLSTATE 25001)
+			_, err := conn.Exec(ctx, createSubjectTypeIndex)
+			return err
+		}, noTxMigration,
+	); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := DatabaseMigrations.Describe("add-subject-type-index", createSubjectTypeIndex); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
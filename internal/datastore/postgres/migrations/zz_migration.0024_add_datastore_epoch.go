@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const addDatastoreEpoch = `ALTER TABLE metadata ADD COLUMN epoch BIGINT NOT NULL DEFAULT 0;`
+
+func init() {
+	if err := DatabaseMigrations.Register("add-datastore-epoch", "add-subject-type-index",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, addDatastoreEpoch)
+			return err
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := DatabaseMigrations.Describe("add-datastore-epoch", addDatastoreEpoch); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
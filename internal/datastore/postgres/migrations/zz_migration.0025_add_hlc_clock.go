@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// The single row of hlc_clock holds the last HLC assigned to a transaction. Writers lock
+// it to assign the next HLC, and hold the lock until they commit.
+var addHLCClockStmts = []string{
+	`CREATE TABLE hlc_clock (
+		single_row BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (single_row),
+		hlc DECIMAL NOT NULL
+	);`,
+	`INSERT INTO hlc_clock (hlc)
+		SELECT COALESCE(MAX(hlc), 0) FROM relation_tuple_transaction;`,
+}
+
+func init() {
+	if err := DatabaseMigrations.Register("add-hlc-clock", "add-datastore-epoch",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			for _, stmt := range addHLCClockStmts {
+				if _, err := tx.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := DatabaseMigrations.Describe("add-hlc-clock", addHLCClockStmts...); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"fmt"
 	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
 )
 
 type postgresOptions struct {
@@ -13,21 +15,29 @@ type postgresOptions struct {
 	minOpenConns                *int
 	maxRevisionStalenessPercent float64
 
-	watchBufferLength    uint16
-	revisionQuantization time.Duration
-	gcWindow             time.Duration
-	gcInterval           time.Duration
-	gcMaxOperationTime   time.Duration
-	splitAtUsersetCount  uint16
-	maxRetries           uint8
+	watchBufferLength       uint16
+	revisionQuantization    time.Duration
+	maxRevisionQuantization time.Duration
+	gcWindow                time.Duration
+	gcInterval              time.Duration
+	gcMaxOperationTime      time.Duration
+	splitAtUsersetCount     uint16
+	maxRetries              uint8
 
 	enablePrometheusStats   bool
 	analyzeBeforeStatistics bool
 	gcEnabled               bool
+	requestIDQueryComments  bool
+	queryTagging            bool
+	skipNoopTouches         bool
+
+	queryTagNodeID string
 
 	migrationPhase string
+	revisionScheme string
 
-	logger *tracingLogger
+	logger     *tracingLogger
+	gcArchiver common.RelationshipArchiver
 }
 
 type migrationPhase uint8
@@ -44,6 +54,19 @@ var migrationPhases = map[string]migrationPhase{
 	"":                    complete,
 }
 
+type revisionScheme uint8
+
+const (
+	xidRevisions revisionScheme = iota
+	hlcRevisions
+)
+
+var revisionSchemes = map[string]revisionScheme{
+	"xid": xidRevisions,
+	"hlc": hlcRevisions,
+	"":    xidRevisions,
+}
+
 const (
 	errQuantizationTooLarge = "revision quantization interval (%s) must be less than GC window (%s)"
 
@@ -90,10 +113,26 @@ func generateConfig(options []Option) (postgresOptions, error) {
 		)
 	}
 
+	if computed.maxRevisionQuantization >= computed.gcWindow {
+		return computed, fmt.Errorf(
+			errQuantizationTooLarge,
+			computed.maxRevisionQuantization,
+			computed.gcWindow,
+		)
+	}
+
 	if _, ok := migrationPhases[computed.migrationPhase]; !ok {
 		return computed, fmt.Errorf("unknown migration phase: %s", computed.migrationPhase)
 	}
 
+	scheme, ok := revisionSchemes[computed.revisionScheme]
+	if !ok {
+		return computed, fmt.Errorf("unknown revision scheme: %s", computed.revisionScheme)
+	}
+	if scheme == hlcRevisions && migrationPhases[computed.migrationPhase] != complete {
+		return computed, fmt.Errorf("hlc revisions cannot be used during the %s migration phase", computed.migrationPhase)
+	}
+
 	return computed, nil
 }
 
@@ -175,6 +214,18 @@ func RevisionQuantization(quantization time.Duration) Option {
 	}
 }
 
+// MaxRevisionQuantization is the largest time bucket size to which advertised
+// revisions will be rounded. If above RevisionQuantization, the bucket size adapts
+// between the two, widening while writes are frequent and the dispatch cache hit ratio
+// is low.
+//
+// This value defaults to 0, for a bucket size which does not adapt.
+func MaxRevisionQuantization(quantization time.Duration) Option {
+	return func(po *postgresOptions) {
+		po.maxRevisionQuantization = quantization
+	}
+}
+
 // MaxRevisionStalenessPercent is the amount of time, expressed as a percentage of
 // the revision quantization window, that a previously computed rounded revision
 // can still be advertised after the next rounded revision would otherwise be ready.
@@ -244,6 +295,17 @@ func EnableTracing() Option {
 	}
 }
 
+// GCArchiver sets an archiver to which garbage collection passes each batch of deleted
+// relationship versions before removing them. Should archiving fail, the versions are
+// kept, and collection is retried on the next run.
+//
+// Deleted versions are removed without being archived by default.
+func GCArchiver(archiver common.RelationshipArchiver) Option {
+	return func(po *postgresOptions) {
+		po.gcArchiver = archiver
+	}
+}
+
 // GCEnabled indicates whether garbage collection is enabled.
 //
 // GC is enabled by default.
@@ -253,6 +315,58 @@ func GCEnabled(isGCEnabled bool) Option {
 	}
 }
 
+// RequestIDQueryComments marks whether queries are annotated with a SQL comment carrying
+// the ID of the API request which caused them, so that they can be correlated with
+// entries in the Postgres slow query log and pg_stat_activity.
+//
+// As the comment makes the text of each query unique, enabling this option defeats the
+// prepared statement cache of the Postgres client.
+//
+// Disabled by default.
+func RequestIDQueryComments(enabled bool) Option {
+	return func(po *postgresOptions) {
+		po.requestIDQueryComments = enabled
+	}
+}
+
+// QueryTagging marks whether queries are annotated, besides the request ID of
+// RequestIDQueryComments, with the API method which caused them and the ID of the node
+// issuing them, so that database load can be attributed to call sites from
+// pg_stat_activity and the statement log. pg_stat_statements strips comments when
+// normalizing queries, and records each under the text of its first execution, so its
+// entries carry the annotations of a single call only.
+//
+// As the comment makes the text of each query unique, enabling this option defeats the
+// prepared statement cache of the Postgres client.
+//
+// Disabled by default.
+func QueryTagging(enabled bool) Option {
+	return func(po *postgresOptions) {
+		po.queryTagging = enabled
+	}
+}
+
+// QueryTagNodeID is the ID of the node with which queries are tagged when QueryTagging
+// is enabled.
+//
+// Empty by default, in which case queries are not tagged with a node ID.
+func QueryTagNodeID(nodeID string) Option {
+	return func(po *postgresOptions) {
+		po.queryTagNodeID = nodeID
+	}
+}
+
+// SkipNoopTouches marks whether TOUCHes of relationships which are already stored with
+// the same caveat are skipped, rather than replacing the stored row with an identical one.
+// Skipped TOUCHes do not appear in the changes returned by Watch.
+//
+// Disabled by default.
+func SkipNoopTouches(enabled bool) Option {
+	return func(po *postgresOptions) {
+		po.skipNoopTouches = enabled
+	}
+}
+
 // DebugAnalyzeBeforeStatistics signals to the Statistics method that it should
 // run Analyze on the database before returning statistics. This should only be
 // used for debug and testing.
@@ -273,3 +387,18 @@ func MigrationPhase(phase string) Option {
 		po.migrationPhase = phase
 	}
 }
+
+// RevisionScheme configures how the revisions of the datastore are derived:
+// `xid` revisions are the Postgres IDs of the transactions which wrote them, while
+// `hlc` revisions are hybrid logical clock timestamps recorded for each transaction.
+// HLC revisions are ordered independently of transaction IDs, and in the same form
+// as the revisions of CockroachDB, so that they can be compared across datastores.
+//
+// Revisions issued under one scheme are not understood under the other.
+//
+// Defaults to `xid`.
+func RevisionScheme(scheme string) Option {
+	return func(po *postgresOptions) {
+		po.revisionScheme = scheme
+	}
+}
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+const (
+	tablePinnedRevision = "pinned_revision"
+
+	colPinName   = "name"
+	colExpiresAt = "expires_at"
+
+	errPinRevision     = "unable to pin revision: %w"
+	errReleaseRevision = "unable to release pinned revision: %w"
+
+	// queryPinRevision pins the transaction of a revision under a name, replacing any
+	// existing pin of the name. No row is written if the transaction no longer exists.
+	//
+	//   %[1] Pinned revision table
+	//   %[2] Name of pin name column
+	//   %[3] Name of xid column
+	//   %[4] Name of timestamp column
+	//   %[5] Name of expiration column
+	//   %[6] Relationship tuple transaction table
+	//   %[7] Name of the column identifying the transaction of the revision
+	queryPinRevision = `
+	INSERT INTO %[1]s (%[2]s, %[3]s, %[4]s, %[5]s)
+	SELECT $1, %[3]s, %[4]s, $3 FROM %[6]s WHERE %[7]s = $2
+	ON CONFLICT (%[2]s) DO UPDATE SET
+		%[3]s = EXCLUDED.%[3]s,
+		%[4]s = EXCLUDED.%[4]s,
+		%[5]s = EXCLUDED.%[5]s;`
+
+	// queryPinnedTransaction returns whether the transaction with the specified ID (or
+	// HLC) is held by an unexpired pin.
+	//
+	//   %[1] Pinned revision table
+	//   %[2] Relationship tuple transaction table
+	//   %[3] Name of xid column
+	//   %[4] Name of xid (or hlc) column
+	//   %[5] Name of expiration column
+	queryPinnedTransaction = `
+	SELECT EXISTS (
+		SELECT 1 FROM %[1]s INNER JOIN %[2]s ON %[1]s.%[3]s = %[2]s.%[3]s
+		WHERE %[2]s.%[4]s = $1 AND %[1]s.%[5]s > NOW()
+	);`
+)
+
+var (
+	hasPinnedRevisionTable = fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", tablePinnedRevision)
+
+	getOldestPin = psql.
+			Select(colXID, colTimestamp).
+			From(tablePinnedRevision).
+			Where(sq.Expr(colExpiresAt + " > NOW()")).
+			OrderByClause(fmt.Sprintf("%s ASC", colXID)).
+			Limit(1)
+
+	deleteExpiredPins = psql.
+				Delete(tablePinnedRevision).
+				Where(sq.Expr(colExpiresAt + " <= NOW()"))
+
+	deletePin = psql.
+			Delete(tablePinnedRevision).
+			Where(sq.Expr(colExpiresAt + " > NOW()"))
+)
+
+func (pgd *pgDatastore) PinRevision(ctx context.Context, name string, revisionRaw datastore.Revision, expiresAt time.Time) error {
+	if !pgd.pinningEnabled {
+		return datastore.NewRevisionPinningUnsupportedErr(Engine)
+	}
+
+	revision, ok := revisionRaw.(postgresRevision)
+	if !ok {
+		return datastore.NewInvalidRevisionErr(revisionRaw, datastore.CouldNotDetermineRevision)
+	}
+
+	query, compared := pgd.pinRevisionByXIDQuery, any(revision.tx)
+	if revision.tx.Status != pgtype.Present {
+		if !revision.hlc.Valid {
+			return datastore.NewInvalidRevisionErr(revision, datastore.CouldNotDetermineRevision)
+		}
+		query, compared = pgd.pinRevisionByHLCQuery, revision.hlc.Decimal
+	}
+
+	result, err := pgd.dbpool.Exec(ctx, query, name, compared, expiresAt)
+	if err != nil {
+		return fmt.Errorf(errPinRevision, err)
+	}
+
+	// The transaction of the revision was garbage collected since it was checked.
+	if result.RowsAffected() == 0 {
+		return datastore.NewInvalidRevisionErr(revision, datastore.RevisionStale)
+	}
+
+	return nil
+}
+
+func (pgd *pgDatastore) ReleasePinnedRevision(ctx context.Context, name string) error {
+	if !pgd.pinningEnabled {
+		return datastore.NewRevisionPinningUnsupportedErr(Engine)
+	}
+
+	sql, args, err := deletePin.Where(sq.Eq{colPinName: name}).ToSql()
+	if err != nil {
+		return fmt.Errorf(errReleaseRevision, err)
+	}
+
+	result, err := pgd.dbpool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf(errReleaseRevision, err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return datastore.NewPinnedRevisionNotFoundErr(name)
+	}
+
+	return nil
+}
+
+// isPinned returns whether the transaction identified by the compared value, per the
+// revision scheme of the datastore, is held by an unexpired pin.
+func (pgd *pgDatastore) isPinned(ctx context.Context, compared any) (bool, error) {
+	if !pgd.pinningEnabled {
+		return false, nil
+	}
+
+	var pinned bool
+	if err := pgd.dbpool.QueryRow(ctx, pgd.pinnedTransactionQuery, compared).Scan(&pinned); err != nil {
+		return false, err
+	}
+	return pinned, nil
+}
+
+// oldestPin returns the transaction ID and timestamp of the oldest unexpired pin. The
+// returned ID is not present if there are no such pins.
+func (pgd *pgDatastore) oldestPin(ctx context.Context) (xid8, time.Time, error) {
+	if !pgd.pinningEnabled {
+		return noXmin, time.Time{}, nil
+	}
+
+	sql, args, err := getOldestPin.ToSql()
+	if err != nil {
+		return noXmin, time.Time{}, err
+	}
+
+	var xid xid8
+	var timestamp time.Time
+	if err := pgd.dbpool.QueryRow(ctx, sql, args...).Scan(&xid, &timestamp); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return noXmin, time.Time{}, nil
+		}
+		return noXmin, time.Time{}, err
+	}
+	return xid, timestamp, nil
+}
+
+var _ common.RevisionPinner = &pgDatastore{}
@@ -5,16 +5,19 @@ import (
 	dbsql "database/sql"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/IBM/pgxpoolprometheus"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgconn"
+	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/jackc/pgx/v4/stdlib"
 	"github.com/ngrok/sqlmw"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/shopspring/decimal"
 	"go.opentelemetry.io/otel"
 	"golang.org/x/sync/errgroup"
 
@@ -37,6 +40,7 @@ const (
 	tableTransaction = "relation_tuple_transaction"
 	tableTuple       = "relation_tuple"
 	tableCaveat      = "caveat"
+	tableHLCClock    = "hlc_clock"
 
 	colCreatedTxnDeprecated = "created_transaction"
 	colDeletedTxnDeprecated = "deleted_transaction"
@@ -48,6 +52,7 @@ const (
 	colCreatedXid        = "created_xid"
 	colDeletedXid        = "deleted_xid"
 	colSnapshot          = "snapshot"
+	colHLC               = "hlc"
 	colObjectID          = "object_id"
 	colRelation          = "relation"
 	colUsersetNamespace  = "userset_namespace"
@@ -68,6 +73,15 @@ const (
 	// 5: a squirrel library placeholder string, i.e. `?`
 	snapshotAlive = "pg_visible_in_snapshot(%[1]s, (SELECT %[2]s FROM %[3]s WHERE %[4]s = %[5]s)) = %[5]s"
 
+	// transactionAtHLC selects a column of the transaction of an HLC revision, which
+	// is the last transaction with an HLC no later than the revision. The parameters
+	// to this format string are:
+	// 1: the selected column name
+	// 2: the transaction table name
+	// 3: the transaction table's hlc column name
+	// 4: a squirrel library placeholder string, i.e. `?`
+	transactionAtHLC = "(SELECT %[1]s FROM %[2]s WHERE %[3]s <= %[4]s ORDER BY %[3]s DESC LIMIT 1)"
+
 	// This is the largest positive integer possible in postgresql
 	liveDeletedTxnID = uint64(9223372036854775807)
 
@@ -95,6 +109,13 @@ var (
 			OrderByClause(fmt.Sprintf("%s DESC", colXID)).
 			Limit(1)
 
+	getHLCRevision = psql.
+			Select(colXID, fmt.Sprintf("pg_snapshot_xmin(%s)", colSnapshot), colHLC).
+			From(tableTransaction).
+			Where(sq.NotEq{colHLC: nil}).
+			OrderByClause(fmt.Sprintf("%s DESC", colHLC)).
+			Limit(1)
+
 	createTxn = fmt.Sprintf(
 		"INSERT INTO %s DEFAULT VALUES RETURNING %s, pg_snapshot_xmin(%s)",
 		tableTransaction,
@@ -102,6 +123,22 @@ var (
 		colSnapshot,
 	)
 
+	advanceHLC = fmt.Sprintf(queryAdvanceHLC, colHLC, tableHLCClock)
+
+	createHLCTxn = fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES ($1) RETURNING %s, pg_snapshot_xmin(%s)",
+		tableTransaction,
+		colHLC,
+		colXID,
+		colSnapshot,
+	)
+
+	hasHLCTxn = fmt.Sprintf(
+		"SELECT EXISTS (SELECT 1 FROM %s WHERE %s IS NOT NULL)",
+		tableTransaction,
+		colHLC,
+	)
+
 	getNow = psql.Select("NOW()")
 
 	tracer = otel.Tracer("spicedb/internal/datastore/postgres")
@@ -171,11 +208,91 @@ func newPostgresDatastore(
 		log.Warn().Msg("watch API disabled, postgres must be run with track_commit_timestamp=on")
 	}
 
+	// Pinning revisions requires the pinned revision table of the migrations.
+	var pinningEnabled bool
+	if err := dbpool.
+		QueryRow(initializationContext, hasPinnedRevisionTable).
+		Scan(&pinningEnabled); err != nil {
+		return nil, fmt.Errorf(errUnableToInstantiate, err)
+	}
+	if !pinningEnabled {
+		log.Warn().Msg("revision pinning disabled, run the datastore migrations to enable it")
+	}
+
+	// Recording schema versions requires the schema version history table of the migrations.
+	var schemaHistoryEnabled bool
+	if err := dbpool.
+		QueryRow(initializationContext, hasSchemaVersionHistoryTable).
+		Scan(&schemaHistoryEnabled); err != nil {
+		return nil, fmt.Errorf(errUnableToInstantiate, err)
+	}
+	if !schemaHistoryEnabled {
+		log.Warn().Msg("schema history disabled, run the datastore migrations to enable it")
+	}
+
+	// Storing namespace experiments requires the namespace experiment table of the migrations.
+	var experimentsEnabled bool
+	if err := dbpool.
+		QueryRow(initializationContext, hasNamespaceExperimentTable).
+		Scan(&experimentsEnabled); err != nil {
+		return nil, fmt.Errorf(errUnableToInstantiate, err)
+	}
+	if !experimentsEnabled {
+		log.Warn().Msg("namespace experiments disabled, run the datastore migrations to enable them")
+	}
+
+	// Storing the sealed IDs of pseudonyms requires the pseudonym table of the migrations.
+	var pseudonymsEnabled bool
+	if err := dbpool.
+		QueryRow(initializationContext, hasPseudonymTable).
+		Scan(&pseudonymsEnabled); err != nil {
+		return nil, fmt.Errorf(errUnableToInstantiate, err)
+	}
+
+	// Storing jobs requires the job table of the migrations.
+	var jobsEnabled bool
+	if err := dbpool.
+		QueryRow(initializationContext, hasJobTable).
+		Scan(&jobsEnabled); err != nil {
+		return nil, fmt.Errorf(errUnableToInstantiate, err)
+	}
+
+	// Retaining removed namespaces requires the namespace tombstone table of the migrations.
+	var tombstonesEnabled bool
+	if err := dbpool.
+		QueryRow(initializationContext, hasNamespaceTombstoneTable).
+		Scan(&tombstonesEnabled); err != nil {
+		return nil, fmt.Errorf(errUnableToInstantiate, err)
+	}
+
+	// Recording relationship sources requires the relationship source table of the migrations.
+	var sourcesEnabled bool
+	if err := dbpool.
+		QueryRow(initializationContext, hasRelationshipSourceTable).
+		Scan(&sourcesEnabled); err != nil {
+		return nil, fmt.Errorf(errUnableToInstantiate, err)
+	}
+
+	// Rejecting the zedtokens minted before a restore requires the epoch column of the migrations.
+	var epochsEnabled bool
+	if err := dbpool.
+		QueryRow(initializationContext, hasEpochColumn).
+		Scan(&epochsEnabled); err != nil {
+		return nil, fmt.Errorf(errUnableToInstantiate, err)
+	}
+	if !epochsEnabled {
+		log.Warn().Msg("datastore epochs disabled, run the datastore migrations to enable them")
+	}
+
 	if config.enablePrometheusStats {
 		collector := pgxpoolprometheus.NewCollector(dbpool, map[string]string{"db_name": "spicedb"})
 		if err := prometheus.Register(collector); err != nil {
 			return nil, fmt.Errorf(errUnableToInstantiate, err)
 		}
+		poolCollector := common.NewPoolCollector("spicedb", "default", pgxcommon.PoolStats(dbpool))
+		if err := prometheus.Register(poolCollector); err != nil {
+			return nil, fmt.Errorf(errUnableToInstantiate, err)
+		}
 		if err := common.RegisterGCMetrics(); err != nil {
 			return nil, fmt.Errorf(errUnableToInstantiate, err)
 		}
@@ -183,54 +300,112 @@ func newPostgresDatastore(
 
 	gcCtx, cancelGc := context.WithCancel(context.Background())
 
-	quantizationPeriodNanos := config.revisionQuantization.Nanoseconds()
-	if quantizationPeriodNanos < 1 {
-		quantizationPeriodNanos = 1
+	// Under the HLC revision scheme, only the transactions with an HLC are revisions.
+	scheme := revisionSchemes[config.revisionScheme]
+	revisionCondition, revisionHLC, comparedColumn, latestColumn := "TRUE", "NULL", colXID, colTimestamp
+	if scheme == hlcRevisions {
+		revisionCondition, revisionHLC, comparedColumn, latestColumn = colHLC+" IS NOT NULL", colHLC, colHLC, colHLC
+	}
+
+	revisionQuery := func(quantization time.Duration) string {
+		quantizationPeriodNanos := quantization.Nanoseconds()
+		if quantizationPeriodNanos < 1 {
+			quantizationPeriodNanos = 1
+		}
+		return fmt.Sprintf(
+			querySelectRevision,
+			colXID,
+			tableTransaction,
+			colTimestamp,
+			quantizationPeriodNanos,
+			colSnapshot,
+			revisionCondition,
+			revisionHLC,
+		)
 	}
-	revisionQuery := fmt.Sprintf(
-		querySelectRevision,
-		colXID,
-		tableTransaction,
-		colTimestamp,
-		quantizationPeriodNanos,
-		colSnapshot,
-	)
 
 	validTransactionQuery := fmt.Sprintf(
 		queryValidTransaction,
-		colXID,
+		comparedColumn,
 		tableTransaction,
 		colTimestamp,
 		config.gcWindow.Seconds(),
+		revisionCondition,
+		latestColumn,
+	)
+
+	pinRevisionQuery := func(column string) string {
+		return fmt.Sprintf(
+			queryPinRevision,
+			tablePinnedRevision,
+			colPinName,
+			colXID,
+			colTimestamp,
+			colExpiresAt,
+			tableTransaction,
+			column,
+		)
+	}
+
+	pinnedTransactionQuery := fmt.Sprintf(
+		queryPinnedTransaction,
+		tablePinnedRevision,
+		tableTransaction,
+		colXID,
+		comparedColumn,
+		colExpiresAt,
 	)
 
 	maxRevisionStaleness := time.Duration(float64(config.revisionQuantization.Nanoseconds())*
 		config.maxRevisionStalenessPercent) * time.Nanosecond
 
+	annotator := common.NewQueryAnnotator(config.requestIDQueryComments, config.queryTagging, config.queryTagNodeID)
+
 	datastore := &pgDatastore{
 		CachedOptimizedRevisions: revisions.NewCachedOptimizedRevisions(
 			maxRevisionStaleness,
 		),
+		quantization:            revisions.NewAdaptiveQuantization(config.revisionQuantization, config.maxRevisionQuantization),
 		dburl:                   url,
-		dbpool:                  dbpool,
+		dbpool:                  pgxcommon.NewAnnotatedPool(dbpool, annotator),
 		watchBufferLength:       config.watchBufferLength,
 		optimizedRevisionQuery:  revisionQuery,
 		validTransactionQuery:   validTransactionQuery,
+		pinRevisionByXIDQuery:   pinRevisionQuery(colXID),
+		pinRevisionByHLCQuery:   pinRevisionQuery(colHLC),
+		pinnedTransactionQuery:  pinnedTransactionQuery,
 		gcWindow:                config.gcWindow,
 		gcInterval:              config.gcInterval,
 		gcTimeout:               config.gcMaxOperationTime,
+		gcArchiver:              config.gcArchiver,
 		analyzeBeforeStatistics: config.analyzeBeforeStatistics,
 		usersetBatchSize:        config.splitAtUsersetCount,
+		skipNoopTouches:         config.skipNoopTouches,
 		watchEnabled:            watchEnabled,
 		gcCtx:                   gcCtx,
 		cancelGc:                cancelGc,
 		readTxOptions:           pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly},
 		maxRetries:              config.maxRetries,
 		migrationPhase:          migrationPhases[config.migrationPhase],
+		revisionScheme:          scheme,
+		pinningEnabled:          pinningEnabled,
+		schemaHistoryEnabled:    schemaHistoryEnabled,
+		experimentsEnabled:      experimentsEnabled,
+		pseudonymsEnabled:       pseudonymsEnabled,
+		jobsEnabled:             jobsEnabled,
+		tombstonesEnabled:       tombstonesEnabled,
+		sourcesEnabled:          sourcesEnabled,
+		epochsEnabled:           epochsEnabled,
 	}
 
 	datastore.SetOptimizedRevisionFunc(datastore.optimizedRevisionFunc)
 
+	if scheme == hlcRevisions {
+		if err := datastore.ensureHLCRevision(initializationContext); err != nil {
+			log.Warn().Err(err).Msg("unable to write the first transaction with an hlc; run the datastore migrations before using hlc revisions")
+		}
+	}
+
 	// Start a goroutine for garbage collection.
 	if datastore.gcInterval > 0*time.Minute && config.gcEnabled {
 		datastore.gcGroup, datastore.gcCtx = errgroup.WithContext(datastore.gcCtx)
@@ -273,20 +448,36 @@ func configurePool(config postgresOptions, pgxConfig *pgxpool.Config) {
 type pgDatastore struct {
 	*revisions.CachedOptimizedRevisions
 
+	quantization            *revisions.Quantization
 	dburl                   string
-	dbpool                  *pgxpool.Pool
+	dbpool                  *pgxcommon.AnnotatedPool
 	watchBufferLength       uint16
-	optimizedRevisionQuery  string
+	optimizedRevisionQuery  func(quantization time.Duration) string
 	validTransactionQuery   string
+	pinRevisionByXIDQuery   string
+	pinRevisionByHLCQuery   string
+	pinnedTransactionQuery  string
 	gcWindow                time.Duration
 	gcInterval              time.Duration
+	gcArchiver              common.RelationshipArchiver
 	gcTimeout               time.Duration
 	usersetBatchSize        uint16
+	skipNoopTouches         bool
 	analyzeBeforeStatistics bool
 	readTxOptions           pgx.TxOptions
 	maxRetries              uint8
 	watchEnabled            bool
 	migrationPhase          migrationPhase
+	revisionScheme          revisionScheme
+	pinningEnabled          bool
+	schemaHistoryEnabled    bool
+	experimentsEnabled      bool
+	pseudonymsEnabled       bool
+	jobsEnabled             bool
+	tombstonesEnabled       bool
+	sourcesEnabled          bool
+	epochsEnabled           bool
+	uniqueID                atomic.Pointer[string]
 
 	gcGroup  *errgroup.Group
 	gcCtx    context.Context
@@ -344,10 +535,10 @@ func (pgd *pgDatastore) ReadWriteTx(
 ) (datastore.Revision, error) {
 	var err error
 	for i := uint8(0); i <= pgd.maxRetries; i++ {
-		var newXID, newXmin xid8
+		var newRevision postgresRevision
 		err = pgd.dbpool.BeginTxFunc(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable}, func(tx pgx.Tx) error {
 			var err error
-			newXID, newXmin, err = createNewTransaction(ctx, tx)
+			newRevision, err = pgd.createNewTransaction(ctx, tx)
 			if err != nil {
 				return err
 			}
@@ -369,8 +560,10 @@ func (pgd *pgDatastore) ReadWriteTx(
 					pgd.migrationPhase,
 				},
 				tx,
-				newXID,
+				newRevision.tx,
 				pgd.migrationPhase,
+				pgd.skipNoopTouches,
+				pgd.sourcesEnabled,
 			}
 
 			return fn(rwt)
@@ -382,11 +575,29 @@ func (pgd *pgDatastore) ReadWriteTx(
 			return datastore.NoRevision, err
 		}
 
-		return postgresRevision{newXID, newXmin}, nil
+		pgd.quantization.RecordWrite()
+		return newRevision, nil
 	}
 	return datastore.NoRevision, fmt.Errorf("max retries exceeded: %w", err)
 }
 
+// ensureHLCRevision writes an empty transaction if none has an HLC yet, such as after
+// switching from the xid revision scheme, so that there is a revision to read at.
+func (pgd *pgDatastore) ensureHLCRevision(ctx context.Context) error {
+	var exists bool
+	if err := pgd.dbpool.QueryRow(ctx, hasHLCTxn).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	_, err := pgd.ReadWriteTx(ctx, func(datastore.ReadWriteTransaction) error {
+		return nil
+	})
+	return err
+}
+
 func (pgd *pgDatastore) Close() error {
 	pgd.cancelGc()
 
@@ -429,6 +640,12 @@ func (pgd *pgDatastore) IsReady(ctx context.Context) (bool, error) {
 		return false, err
 	}
 
+	// HLC revisions require the clock added by the add-hlc-clock migration, and so
+	// the head migration.
+	if pgd.revisionScheme == hlcRevisions {
+		return version == headMigration, nil
+	}
+
 	// TODO remove once the ID->XID migrations are all complete
 	switch pgd.migrationPhase {
 	case writeBothReadOld:
@@ -447,6 +664,10 @@ func (pgd *pgDatastore) Features(ctx context.Context) (*datastore.Features, erro
 }
 
 func buildLivingObjectFilterForRevision(revision postgresRevision) queryFilterer {
+	if revision.tx.Status != pgtype.Present && revision.hlc.Valid {
+		return buildLivingObjectFilterForHLC(revision.hlc.Decimal)
+	}
+
 	createdBeforeTXN := sq.Expr(fmt.Sprintf(
 		snapshotAlive,
 		colCreatedXid,
@@ -479,6 +700,26 @@ func buildLivingObjectFilterForRevision(revision postgresRevision) queryFilterer
 	}
 }
 
+// buildLivingObjectFilterForHLC filters the objects alive at the transaction of an HLC
+// revision, which is resolved by the query itself.
+func buildLivingObjectFilterForHLC(hlc decimal.Decimal) queryFilterer {
+	snapshotAtHLC := fmt.Sprintf(transactionAtHLC, colSnapshot, tableTransaction, colHLC, sq.Placeholders(1))
+	xidAtHLC := fmt.Sprintf(transactionAtHLC, colXID, tableTransaction, colHLC, sq.Placeholders(1))
+
+	alreadyAlive := sq.Or{
+		sq.Expr(fmt.Sprintf("pg_visible_in_snapshot(%s, %s) = %s", colCreatedXid, snapshotAtHLC, sq.Placeholders(1)), hlc, true),
+		sq.Expr(colCreatedXid+" = "+xidAtHLC, hlc),
+	}
+	notYetDead := sq.And{
+		sq.Expr(fmt.Sprintf("pg_visible_in_snapshot(%s, %s) = %s", colDeletedXid, snapshotAtHLC, sq.Placeholders(1)), hlc, false),
+		sq.Expr(colDeletedXid+" <> "+xidAtHLC, hlc),
+	}
+
+	return func(original sq.SelectBuilder) sq.SelectBuilder {
+		return original.Where(alreadyAlive).Where(notYetDead)
+	}
+}
+
 // TODO remove once the ID->XID migrations are all complete
 func buildLivingObjectFilterForRevisionDeprecated(revision postgresRevision) queryFilterer {
 	return func(original sq.SelectBuilder) sq.SelectBuilder {
@@ -5,8 +5,10 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,14 +16,17 @@ import (
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/common/sqlgolden"
 	"github.com/authzed/spicedb/internal/datastore/postgres/migrations"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	testdatastore "github.com/authzed/spicedb/internal/testserver/datastore"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
 	"github.com/authzed/spicedb/pkg/datastore/test"
 	"github.com/authzed/spicedb/pkg/migrate"
 	"github.com/authzed/spicedb/pkg/namespace"
@@ -118,6 +123,24 @@ func TestPostgresDatastore(t *testing.T) {
 				MigrationPhase(config.migrationPhase),
 			))
 
+			t.Run("ChunkedWrite", createDatastoreTest(
+				b,
+				ChunkedWriteTest,
+				RevisionQuantization(0),
+				GCWindow(1*time.Millisecond),
+				WatchBufferLength(1),
+				MigrationPhase(config.migrationPhase),
+			))
+
+			t.Run("BulkLoad", createDatastoreTest(
+				b,
+				BulkLoadTest,
+				RevisionQuantization(0),
+				GCWindow(1*time.Millisecond),
+				WatchBufferLength(1),
+				MigrationPhase(config.migrationPhase),
+			))
+
 			t.Run("QuantizedRevisions", func(t *testing.T) {
 				QuantizedRevisionTest(t, b)
 			})
@@ -131,6 +154,30 @@ func TestPostgresDatastore(t *testing.T) {
 					WatchBufferLength(1),
 					MigrationPhase(config.migrationPhase),
 				))
+
+				t.Run("SkipNoopTouches", createDatastoreTest(
+					b,
+					SkipNoopTouchesTest,
+					RevisionQuantization(0),
+					GCWindow(24*time.Hour),
+					SkipNoopTouches(true),
+				))
+
+				t.Run("CompactRelationshipHistory", createDatastoreTest(
+					b,
+					CompactRelationshipHistoryTest,
+					RevisionQuantization(0),
+					GCWindow(24*time.Hour),
+				))
+
+				archiver := &recordingArchiver{}
+				t.Run("GarbageCollectionArchive", createDatastoreTest(
+					b,
+					archiver.test,
+					RevisionQuantization(0),
+					GCWindow(1*time.Millisecond),
+					GCArchiver(archiver),
+				))
 			}
 		})
 	}
@@ -140,6 +187,75 @@ func TestPostgresDatastore(t *testing.T) {
 		b := testdatastore.RunPostgresForTesting(t, "", "")
 		XIDMigrationAssumptionsTest(t, b)
 	})
+
+	t.Run("SubjectTypeIndex", func(t *testing.T) {
+		b := testdatastore.RunPostgresForTesting(t, "", "")
+		SubjectTypeIndexTest(t, b)
+	})
+
+	t.Run("HLCRevisions", func(t *testing.T) {
+		b := testdatastore.RunPostgresForTesting(t, "", migrate.Head)
+
+		test.All(t, test.DatastoreTesterFunc(func(revisionQuantization, gcWindow time.Duration, watchBufferLength uint16) (datastore.Datastore, error) {
+			ds := b.NewDatastore(t, func(engine, uri string) datastore.Datastore {
+				ds, err := newPostgresDatastore(uri,
+					RevisionQuantization(revisionQuantization),
+					GCWindow(gcWindow),
+					WatchBufferLength(watchBufferLength),
+					RevisionScheme("hlc"),
+				)
+				require.NoError(t, err)
+				return ds
+			})
+			return ds, nil
+		}))
+
+		t.Run("HLCRevision", createDatastoreTest(
+			b,
+			HLCRevisionTest,
+			RevisionQuantization(0),
+			RevisionScheme("hlc"),
+		))
+
+		t.Run("HLCConcurrentWriters", createDatastoreTest(
+			b,
+			HLCConcurrentWritersTest,
+			RevisionQuantization(0),
+			RevisionScheme("hlc"),
+			MaxRetries(50),
+		))
+	})
+
+	t.Run("PinnedRevisions", func(t *testing.T) {
+		b := testdatastore.RunPostgresForTesting(t, "", migrate.Head)
+
+		for _, scheme := range []string{"xid", "hlc"} {
+			t.Run(scheme, createDatastoreTest(
+				b,
+				PinnedRevisionTest,
+				RevisionQuantization(0),
+				GCWindow(1*time.Millisecond),
+				RevisionScheme(scheme),
+			))
+		}
+	})
+
+	t.Run("SchemaVersions", func(t *testing.T) {
+		b := testdatastore.RunPostgresForTesting(t, "", migrate.Head)
+
+		for _, scheme := range []string{"xid", "hlc"} {
+			t.Run(scheme, createDatastoreTest(
+				b,
+				SchemaVersionsTest,
+				RevisionScheme(scheme),
+			))
+		}
+	})
+
+	t.Run("NamespaceExperiments", func(t *testing.T) {
+		b := testdatastore.RunPostgresForTesting(t, "", migrate.Head)
+		createDatastoreTest(b, NamespaceExperimentsTest)(t)
+	})
 }
 
 type datastoreTestFunc func(t *testing.T, ds datastore.Datastore)
@@ -324,14 +440,14 @@ func TransactionTimestampsTest(t *testing.T, ds datastore.Datastore) {
 	tx, err := pgd.dbpool.Begin(ctx)
 	require.NoError(err)
 
-	txXID, _, err := createNewTransaction(ctx, tx)
+	txRevision, err := pgd.createNewTransaction(ctx, tx)
 	require.NoError(err)
 
 	err = tx.Commit(ctx)
 	require.NoError(err)
 
 	var ts time.Time
-	sql, args, err := psql.Select("timestamp").From(tableTransaction).Where(sq.Eq{"xid": txXID}).ToSql()
+	sql, args, err := psql.Select("timestamp").From(tableTransaction).Where(sq.Eq{"xid": txRevision.tx}).ToSql()
 	require.NoError(err)
 	err = pgd.dbpool.QueryRow(ctx, sql, args...).Scan(&ts)
 	require.NoError(err)
@@ -501,6 +617,276 @@ func ChunkedGarbageCollectionTest(t *testing.T, ds datastore.Datastore) {
 	require.Zero(removed.Namespaces)
 }
 
+func ChunkedWriteTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+
+	ctx := context.Background()
+	_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, namespace.Namespace(
+			"resource",
+			namespace.Relation("reader", nil),
+		), namespace.Namespace("user"))
+	})
+	require.NoError(err)
+
+	count := writeChunkSize*2 + writeChunkSize/2
+	tpl := func(i int) *core.RelationTuple {
+		return tuple.Parse(fmt.Sprintf("resource:resource-%d#reader@user:someuser#...", i))
+	}
+
+	// Create enough relationships to span several chunks.
+	updates := make([]*core.RelationTupleUpdate, 0, count)
+	for i := 0; i < count; i++ {
+		updates = append(updates, tuple.Create(tpl(i)))
+	}
+	_, err = common.UpdateTuplesInDatastore(ctx, ds, updates...)
+	require.NoError(err)
+
+	// Touch the first half and delete every other relationship of the second, so that each
+	// chunk both deletes and inserts, then create the same number again.
+	updates = updates[:0]
+	for i := 0; i < count/2; i++ {
+		updates = append(updates, tuple.Touch(tpl(i)))
+	}
+	for i := count / 2; i < count; i += 2 {
+		updates = append(updates, tuple.Delete(tpl(i)))
+	}
+	for i := count; i < count*2; i++ {
+		updates = append(updates, tuple.Create(tpl(i)))
+	}
+	writtenAt, err := common.UpdateTuplesInDatastore(ctx, ds, updates...)
+	require.NoError(err)
+
+	iter, err := ds.SnapshotReader(writtenAt).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType: "resource",
+	})
+	require.NoError(err)
+	require.Equal(count*2-count/4, countIterator(require, iter))
+
+	// A relationship created twice in different chunks fails the whole write.
+	updates = updates[:0]
+	for i := count * 2; i < count*3; i++ {
+		updates = append(updates, tuple.Create(tpl(i)))
+	}
+	updates = append(updates, tuple.Create(tpl(count*2)))
+	_, err = common.UpdateTuplesInDatastore(ctx, ds, updates...)
+	require.ErrorAs(err, &common.CreateRelationshipExistsError{})
+
+	iter, err = ds.SnapshotReader(writtenAt).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:        "resource",
+		OptionalResourceIds: []string{fmt.Sprintf("resource-%d", count*2)},
+	})
+	require.NoError(err)
+	require.Zero(countIterator(require, iter))
+}
+
+// sliceBulkSource is a BulkRelationshipSource of the relationships of a slice.
+type sliceBulkSource []*core.RelationTuple
+
+func (s *sliceBulkSource) Next(_ context.Context) (*core.RelationTuple, error) {
+	if len(*s) == 0 {
+		return nil, nil
+	}
+	tpl := (*s)[0]
+	*s = (*s)[1:]
+	return tpl, nil
+}
+
+func BulkLoadTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+
+	ctx := context.Background()
+	writeTouchTestSchema(t, ds)
+
+	count := writeChunkSize*2 + writeChunkSize/2
+	tpl := func(i int) *core.RelationTuple {
+		return tuple.Parse(fmt.Sprintf("resource:resource-%d#reader@user:someuser#...", i))
+	}
+
+	source := make(sliceBulkSource, 0, count)
+	for i := 0; i < count; i++ {
+		source = append(source, tpl(i))
+	}
+
+	var loaded uint64
+	loadedAt, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		var err error
+		loaded, err = common.BulkLoad(ctx, rwt, &source)
+		return err
+	})
+	require.NoError(err)
+	require.Equal(uint64(count), loaded)
+
+	iter, err := ds.SnapshotReader(loadedAt).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType: "resource",
+	})
+	require.NoError(err)
+	require.Equal(count, countIterator(require, iter))
+
+	// Loading an existing relationship fails the whole load.
+	source = sliceBulkSource{tpl(count), tpl(0)}
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		_, err := common.BulkLoad(ctx, rwt, &source)
+		return err
+	})
+	require.ErrorAs(err, &common.CreateRelationshipExistsError{})
+
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	iter, err = ds.SnapshotReader(headRevision).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:        "resource",
+		OptionalResourceIds: []string{fmt.Sprintf("resource-%d", count)},
+	})
+	require.NoError(err)
+	require.Zero(countIterator(require, iter))
+}
+
+// storedVersions returns the number of rows, living or not, storing the relationship.
+func storedVersions(t *testing.T, ds datastore.Datastore, tpl *core.RelationTuple) int {
+	sql, args, err := psql.Select("COUNT(*)").From(tableTuple).Where(exactRelationshipClause(tpl)).ToSql()
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, ds.(*pgDatastore).dbpool.QueryRow(context.Background(), sql, args...).Scan(&count))
+	return count
+}
+
+func writeTouchTestSchema(t *testing.T, ds datastore.Datastore) {
+	ctx := context.Background()
+	_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, namespace.Namespace(
+			"resource",
+			namespace.Relation("reader", nil),
+		), namespace.Namespace("user"))
+	})
+	require.NoError(t, err)
+}
+
+func SkipNoopTouchesTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+	ctx := context.Background()
+	writeTouchTestSchema(t, ds)
+
+	plain := tuple.Parse("resource:first#reader@user:tom#...")
+	caveated := tuple.WithCaveat(tuple.Parse("resource:second#reader@user:tom#..."), "somecaveat")
+	_, err := common.UpdateTuplesInDatastore(ctx, ds, tuple.Create(plain), tuple.Create(caveated))
+	require.NoError(err)
+
+	// Touching unchanged relationships leaves their rows in place.
+	_, err = common.UpdateTuplesInDatastore(ctx, ds, tuple.Touch(plain), tuple.Touch(caveated))
+	require.NoError(err)
+	require.Equal(1, storedVersions(t, ds, plain))
+	require.Equal(1, storedVersions(t, ds, caveated))
+
+	// Changing the caveat rewrites the relationship.
+	writtenAt, err := common.UpdateTuplesInDatastore(ctx, ds, tuple.Touch(tuple.WithCaveat(plain, "somecaveat")))
+	require.NoError(err)
+	require.Equal(2, storedVersions(t, ds, plain))
+
+	iter, err := ds.SnapshotReader(writtenAt).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:        "resource",
+		OptionalResourceIds: []string{"first"},
+	})
+	require.NoError(err)
+	defer iter.Close()
+	found := iter.Next()
+	require.NotNil(found)
+	require.Equal("somecaveat", found.Caveat.GetCaveatName())
+}
+
+func CompactRelationshipHistoryTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+	ctx := context.Background()
+	writeTouchTestSchema(t, ds)
+
+	rewritten := tuple.Parse("resource:first#reader@user:tom#...")
+	changed := tuple.Parse("resource:second#reader@user:tom#...")
+	createdAt, err := common.UpdateTuplesInDatastore(ctx, ds, tuple.Create(rewritten), tuple.Create(changed))
+	require.NoError(err)
+
+	for i := 0; i < 3; i++ {
+		_, err = common.UpdateTuplesInDatastore(ctx, ds, tuple.Touch(rewritten))
+		require.NoError(err)
+	}
+	_, err = common.UpdateTuplesInDatastore(ctx, ds, tuple.Touch(tuple.WithCaveat(changed, "somecaveat")))
+	require.NoError(err)
+	require.Equal(4, storedVersions(t, ds, rewritten))
+	require.Equal(2, storedVersions(t, ds, changed))
+
+	pgd := ds.(*pgDatastore)
+	var merged int64
+	for {
+		count, err := pgd.CompactRelationshipHistory(ctx, 10)
+		require.NoError(err)
+		if count == 0 {
+			break
+		}
+		merged += count
+	}
+	require.Equal(int64(3), merged)
+	require.Equal(1, storedVersions(t, ds, rewritten))
+	require.Equal(2, storedVersions(t, ds, changed))
+
+	// The remaining version is visible from the revision at which the first was created.
+	iter, err := ds.SnapshotReader(createdAt).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType: "resource",
+	})
+	require.NoError(err)
+	require.Equal(2, countIterator(require, iter))
+}
+
+// recordingArchiver records the versions archived by garbage collection, failing to archive
+// them while failing is set.
+type recordingArchiver struct {
+	failing  bool
+	archived []common.RelationshipVersion
+}
+
+func (ra *recordingArchiver) ArchiveRelationships(_ context.Context, versions []common.RelationshipVersion) error {
+	if ra.failing {
+		return errors.New("archive unavailable")
+	}
+	ra.archived = append(ra.archived, versions...)
+	return nil
+}
+
+func (ra *recordingArchiver) test(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+	ctx := context.Background()
+	writeTouchTestSchema(t, ds)
+
+	tpl := tuple.WithCaveat(tuple.Parse("resource:someresource#reader@user:someuser#..."), "somecaveat")
+	createdAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, tpl)
+	require.NoError(err)
+	deletedAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_DELETE, tpl)
+	require.NoError(err)
+	collectAt, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error { return nil })
+	require.NoError(err)
+
+	// Deleted versions are kept while they cannot be archived.
+	pds := ds.(*pgDatastore)
+	ra.failing = true
+	_, err = pds.DeleteBeforeTx(ctx, collectAt)
+	require.ErrorContains(err, "archive unavailable")
+	require.Equal(1, storedVersions(t, ds, tpl))
+
+	ra.failing = false
+	removed, err := pds.DeleteBeforeTx(ctx, collectAt)
+	require.NoError(err)
+	require.Equal(int64(1), removed.Relationships)
+	require.Zero(storedVersions(t, ds, tpl))
+
+	require.Len(ra.archived, 1)
+	archived := ra.archived[0]
+	require.Equal(tuple.String(tpl), tuple.String(archived.Relationship))
+	require.Equal("somecaveat", archived.Relationship.Caveat.GetCaveatName())
+	require.True(createdAt.Equal(archived.CreatedRevision))
+	require.True(deletedAt.Equal(archived.DeletedRevision))
+	require.False(archived.CreatedAt.IsZero())
+	require.False(archived.DeletedAt.Before(archived.CreatedAt))
+}
+
 func QuantizedRevisionTest(t *testing.T, b testdatastore.RunningEngineForTest) {
 	testCases := []struct {
 		testName      string
@@ -593,11 +979,14 @@ func QuantizedRevisionTest(t *testing.T, b testdatastore.RunningEngineForTest) {
 				colTimestamp,
 				tc.quantization.Nanoseconds(),
 				colSnapshot,
+				"TRUE",
+				"NULL",
 			)
 
 			var revision, xmin xid8
+			var hlc decimal.NullDecimal
 			var validFor time.Duration
-			err = conn.QueryRow(ctx, queryRevision).Scan(&revision, &xmin, &validFor)
+			err = conn.QueryRow(ctx, queryRevision).Scan(&revision, &xmin, &hlc, &validFor)
 			require.NoError(err)
 
 			queryFmt := "SELECT COUNT(%[1]s) FROM %[2]s WHERE %[1]s %[3]s $1;"
@@ -693,6 +1082,241 @@ func RevisionInversionTest(t *testing.T, ds datastore.Datastore) {
 	require.False(commitFirstRev.Equal(commitLastRev))
 }
 
+func HLCRevisionTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ok, err := ds.IsReady(ctx)
+	require.NoError(err)
+	require.True(ok)
+
+	before := revision.NewFromDecimal(decimal.NewFromInt(time.Now().UnixNano()))
+
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, namespace.Namespace(
+			"resource",
+			namespace.Relation("reader", nil),
+		), namespace.Namespace("user"))
+	})
+	require.NoError(err)
+
+	emptyRev, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	tpl := tuple.MustParse("resource:123#reader@user:456")
+	writtenRev, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_TOUCH, tpl)
+	require.NoError(err)
+	require.True(writtenRev.GreaterThan(emptyRev))
+
+	// HLC revisions are comparable with other HLCs, such as those of CockroachDB.
+	require.True(writtenRev.GreaterThan(before))
+
+	headRev, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	require.True(headRev.Equal(writtenRev))
+
+	// Revisions parsed from their string form read at the same transaction.
+	for _, tc := range []struct {
+		rev      datastore.Revision
+		expected int
+	}{
+		{emptyRev, 0},
+		{writtenRev, 1},
+	} {
+		parsed, err := ds.RevisionFromString(tc.rev.String())
+		require.NoError(err)
+		require.True(parsed.Equal(tc.rev))
+		require.NoError(ds.CheckRevision(ctx, parsed))
+
+		iter, err := ds.SnapshotReader(parsed).QueryRelationships(ctx, datastore.RelationshipsFilter{
+			ResourceType: "resource",
+		})
+		require.NoError(err)
+		require.Equal(tc.expected, countIterator(require, iter))
+	}
+
+	// Revisions past the latest transaction are unknown.
+	future, err := ds.RevisionFromString(decimal.NewFromInt(time.Now().Add(time.Hour).UnixNano()).String())
+	require.NoError(err)
+	require.Error(ds.CheckRevision(ctx, future))
+}
+
+// HLCConcurrentWritersTest checks that the HLCs of concurrent writers are ordered as
+// they become visible, so that reading at each HLC sees exactly the writes of the HLCs
+// no later than it.
+func HLCConcurrentWritersTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, namespace.Namespace(
+			"resource",
+			namespace.Relation("reader", nil),
+		), namespace.Namespace("user"))
+	})
+	require.NoError(err)
+
+	const writers = 20
+	revisions := make([]datastore.Revision, writers)
+	g := errgroup.Group{}
+	for i := 0; i < writers; i++ {
+		i := i
+		g.Go(func() error {
+			var err error
+			revisions[i], err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_TOUCH,
+				tuple.MustParse(fmt.Sprintf("resource:%d#reader@user:%d", i, i)))
+			return err
+		})
+	}
+	require.NoError(g.Wait())
+
+	for _, rev := range revisions {
+		written := 0
+		for _, other := range revisions {
+			if !other.GreaterThan(rev) {
+				written++
+			}
+		}
+
+		parsed, err := ds.RevisionFromString(rev.String())
+		require.NoError(err)
+
+		iter, err := ds.SnapshotReader(parsed).QueryRelationships(ctx, datastore.RelationshipsFilter{
+			ResourceType: "resource",
+		})
+		require.NoError(err)
+		require.Equal(written, countIterator(require, iter))
+	}
+}
+
+func PinnedRevisionTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+	pds := ds.(*pgDatastore)
+	ctx := context.Background()
+
+	_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, namespace.Namespace(
+			"resource",
+			namespace.Relation("reader", nil),
+		), namespace.Namespace("user"))
+	})
+	require.NoError(err)
+
+	tpl := tuple.MustParse("resource:123#reader@user:456")
+	writtenAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_TOUCH, tpl)
+	require.NoError(err)
+
+	require.NoError(pds.PinRevision(ctx, "export", writtenAt, time.Now().Add(time.Hour)))
+
+	deletedAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_DELETE, tpl)
+	require.NoError(err)
+
+	// Move the GC window past both writes.
+	time.Sleep(5 * time.Millisecond)
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_TOUCH, tuple.MustParse("resource:456#reader@user:456"))
+	require.NoError(err)
+
+	require.NoError(ds.CheckRevision(ctx, writtenAt))
+	require.ErrorAs(ds.CheckRevision(ctx, deletedAt), &datastore.ErrInvalidRevision{})
+
+	// The pin holds back garbage collection, keeping the deleted relationship readable.
+	now, err := pds.Now(ctx)
+	require.NoError(err)
+
+	gcAt, err := pds.TxIDBefore(ctx, now)
+	require.NoError(err)
+	if gcAt != datastore.NoRevision {
+		removed, err := pds.DeleteBeforeTx(ctx, gcAt)
+		require.NoError(err)
+		require.Zero(removed.Relationships)
+	}
+
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+	tRequire.TupleExists(ctx, tpl, writtenAt)
+
+	// Once released, the revision is stale and can be collected.
+	require.NoError(pds.ReleasePinnedRevision(ctx, "export"))
+	require.ErrorAs(pds.ReleasePinnedRevision(ctx, "export"), &datastore.ErrPinnedRevisionNotFound{})
+	require.ErrorAs(ds.CheckRevision(ctx, writtenAt), &datastore.ErrInvalidRevision{})
+
+	gcAt, err = pds.TxIDBefore(ctx, now)
+	require.NoError(err)
+	removed, err := pds.DeleteBeforeTx(ctx, gcAt)
+	require.NoError(err)
+	require.Equal(int64(1), removed.Relationships)
+}
+
+func SchemaVersionsTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+	pds := ds.(*pgDatastore)
+	ctx := context.Background()
+
+	versions, err := pds.ListSchemaVersions(ctx)
+	require.NoError(err)
+	require.Empty(versions)
+
+	writtenAt, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, namespace.Namespace("user"))
+	})
+	require.NoError(err)
+
+	first, err := pds.AddSchemaVersion(ctx, writtenAt, "definition user {}", "tom")
+	require.NoError(err)
+	second, err := pds.AddSchemaVersion(ctx, writtenAt, "definition user {}\n\ndefinition document {}", "")
+	require.NoError(err)
+	require.Greater(second.Version, first.Version)
+
+	versions, err = pds.ListSchemaVersions(ctx)
+	require.NoError(err)
+	require.Len(versions, 2)
+	require.Equal(first.Version, versions[0].Version)
+	require.Equal("tom", versions[0].Author)
+	require.Empty(versions[0].SchemaText)
+	require.True(writtenAt.Equal(versions[1].Revision))
+
+	read, err := pds.ReadSchemaVersion(ctx, second.Version)
+	require.NoError(err)
+	require.Equal(second.SchemaText, read.SchemaText)
+	require.True(writtenAt.Equal(read.Revision))
+	require.WithinDuration(second.CreatedAt, read.CreatedAt, time.Millisecond)
+
+	_, err = pds.ReadSchemaVersion(ctx, second.Version+1)
+	require.ErrorAs(err, &datastore.ErrSchemaVersionNotFound{})
+}
+
+func NamespaceExperimentsTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+	pds := ds.(*pgDatastore)
+	ctx := context.Background()
+
+	experiments, err := pds.ListNamespaceExperiments(ctx)
+	require.NoError(err)
+	require.Empty(experiments)
+
+	require.NoError(pds.SetNamespaceExperiment(ctx, "folder", "new-planner", true))
+	require.NoError(pds.SetNamespaceExperiment(ctx, "document", "new-planner", true))
+	require.NoError(pds.SetNamespaceExperiment(ctx, "document", "new-planner", true))
+	require.NoError(pds.SetNamespaceExperiment(ctx, "document", "wildcard-index", true))
+
+	experiments, err = pds.ListNamespaceExperiments(ctx)
+	require.NoError(err)
+	require.Equal([]datastore.NamespaceExperiment{
+		{Namespace: "document", Experiment: "new-planner"},
+		{Namespace: "document", Experiment: "wildcard-index"},
+		{Namespace: "folder", Experiment: "new-planner"},
+	}, experiments)
+
+	require.NoError(pds.SetNamespaceExperiment(ctx, "document", "new-planner", false))
+	require.NoError(pds.SetNamespaceExperiment(ctx, "document", "new-planner", false))
+
+	experiments, err = pds.ListNamespaceExperiments(ctx)
+	require.NoError(err)
+	require.Equal([]datastore.NamespaceExperiment{
+		{Namespace: "document", Experiment: "wildcard-index"},
+		{Namespace: "folder", Experiment: "new-planner"},
+	}, experiments)
+}
+
 func XIDMigrationAssumptionsTest(t *testing.T, b testdatastore.RunningEngineForTest) {
 	ctx, cancel := context.WithTimeout(
 		context.WithValue(context.Background(), migrate.BackfillBatchSize, uint64(1000)),
@@ -821,7 +1445,7 @@ func XIDMigrationAssumptionsTest(t *testing.T, b testdatastore.RunningEngineForT
 		return nil
 	})
 	require.NoError(err)
-	writtenAtOne := postgresRevision{xid8{Uint: oldTxIDs[len(oldTxIDs)-1], Status: pgtype.Present}, noXmin}
+	writtenAtOne := postgresRevision{tx: xid8{Uint: oldTxIDs[len(oldTxIDs)-1], Status: pgtype.Present}, xmin: noXmin}
 	require.True(writtenAtTwo.GreaterThan(writtenAtOne))
 
 	verifyProperAlive(ctx, require, dsWriteBothReadOld, writtenAtTwo,
@@ -1109,6 +1733,101 @@ func XIDMigrationAssumptionsTest(t *testing.T, b testdatastore.RunningEngineForT
 		})
 }
 
+// SubjectTypeIndexTest checks that the add-subject-type-index migration creates the
+// index by subject type, and that reverse queries for all the subjects of a type use it.
+func SubjectTypeIndexTest(t *testing.T, b testdatastore.RunningEngineForTest) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	uri := b.NewDatabase(t)
+	conn, err := pgx.Connect(ctx, uri)
+	require.NoError(err)
+	defer conn.Close(ctx)
+
+	hasIndex := func() bool {
+		var exists bool
+		require.NoError(conn.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM pg_indexes WHERE tablename = $1 AND indexname = $2)",
+			tableTuple, "ix_relation_tuple_by_subject_type",
+		).Scan(&exists))
+		return exists
+	}
+
+	migrationDriver, err := migrations.NewAlembicPostgresDriver(uri)
+	require.NoError(err)
+	require.NoError(migrations.DatabaseMigrations.Run(ctx, migrationDriver, "add-relationship-sources", migrate.LiveRun))
+	require.False(hasIndex())
+
+	require.NoError(migrations.DatabaseMigrations.Run(ctx, migrationDriver, "add-subject-type-index", migrate.LiveRun))
+	require.True(hasIndex())
+
+	require.NoError(migrations.DatabaseMigrations.Run(ctx, migrationDriver, migrate.Head, migrate.LiveRun))
+	require.True(hasIndex())
+
+	ds, err := newPostgresDatastore(uri, RevisionQuantization(0))
+	require.NoError(err)
+	defer ds.Close()
+
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, namespace.Namespace(
+			"resource",
+			namespace.Relation("reader", nil),
+		), namespace.Namespace("user"), namespace.Namespace("team"))
+	})
+	require.NoError(err)
+
+	updates := make([]*core.RelationTupleUpdate, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		subjectType := "user"
+		if i%10 != 0 {
+			subjectType = "team"
+		}
+		updates = append(updates, tuple.Touch(tuple.MustParse(
+			fmt.Sprintf("resource:%d#reader@%s:%d", i, subjectType, i))))
+	}
+	rev, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, updates)
+	})
+	require.NoError(err)
+
+	_, err = conn.Exec(ctx, "ANALYZE "+tableTuple)
+	require.NoError(err)
+	_, err = conn.Exec(ctx, "SET enable_seqscan = off")
+	require.NoError(err)
+
+	explainer := &sqlgolden.Explainer{
+		Explain: func(ctx context.Context, sql string, args []any) (string, error) {
+			rows, err := conn.Query(ctx, "EXPLAIN "+sql, args...)
+			if err != nil {
+				return "", err
+			}
+			defer rows.Close()
+
+			var plan []string
+			for rows.Next() {
+				var line string
+				if err := rows.Scan(&line); err != nil {
+					return "", err
+				}
+				plan = append(plan, line)
+			}
+			return strings.Join(plan, "\n"), rows.Err()
+		},
+	}
+	reader := &pgReader{
+		querySplitter:  common.TupleQuerySplitter{Executor: explainer.ExecuteQuery, UsersetBatchSize: 100},
+		filterer:       buildLivingObjectFilterForRevision(rev.(postgresRevision)),
+		migrationPhase: complete,
+	}
+
+	iter, err := reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{SubjectType: "user"})
+	require.NoError(err)
+	iter.Close()
+
+	require.Len(explainer.Plans, 1)
+	require.Contains(explainer.Plans[0], "ix_relation_tuple_by_subject_type")
+}
+
 func countIterator(require *require.Assertions, iter datastore.RelationshipIterator) int {
 	defer iter.Close()
 	var count int
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+const (
+	tablePseudonym = "pseudonym"
+
+	colPseudonym = "pseudonym"
+	colSealedID  = "sealed_id"
+
+	errWritePseudonyms = "unable to write pseudonyms: %w"
+	errReadPseudonyms  = "unable to read pseudonyms: %w"
+)
+
+var (
+	hasPseudonymTable = fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", tablePseudonym)
+
+	writePseudonyms = psql.
+			Insert(tablePseudonym).
+			Columns(colPseudonym, colSealedID).
+			Suffix(fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", colPseudonym))
+
+	readPseudonyms = psql.
+			Select(colPseudonym, colSealedID).
+			From(tablePseudonym)
+)
+
+func (pgd *pgDatastore) WritePseudonyms(ctx context.Context, sealed map[string][]byte) error {
+	if !pgd.pseudonymsEnabled {
+		return common.ErrPseudonymsUnsupported
+	}
+	if len(sealed) == 0 {
+		return nil
+	}
+
+	query := writePseudonyms
+	for pseudonym, sealedID := range sealed {
+		query = query.Values(pseudonym, sealedID)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf(errWritePseudonyms, err)
+	}
+
+	if _, err := pgd.dbpool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf(errWritePseudonyms, err)
+	}
+	return nil
+}
+
+func (pgd *pgDatastore) ReadPseudonyms(ctx context.Context, pseudonyms []string) (map[string][]byte, error) {
+	if !pgd.pseudonymsEnabled {
+		return nil, common.ErrPseudonymsUnsupported
+	}
+
+	sql, args, err := readPseudonyms.Where(sq.Eq{colPseudonym: pseudonyms}).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf(errReadPseudonyms, err)
+	}
+
+	rows, err := pgd.dbpool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf(errReadPseudonyms, err)
+	}
+	defer rows.Close()
+
+	found := make(map[string][]byte, len(pseudonyms))
+	for rows.Next() {
+		var pseudonym string
+		var sealedID []byte
+		if err := rows.Scan(&pseudonym, &sealedID); err != nil {
+			return nil, fmt.Errorf(errReadPseudonyms, err)
+		}
+		found[pseudonym] = sealedID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errReadPseudonyms, err)
+	}
+	return found, nil
+}
+
+var _ common.PseudonymStore = &pgDatastore{}
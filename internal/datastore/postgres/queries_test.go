@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/jackc/pgtype"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/common/sqlgolden"
+)
+
+func TestQueryShapes(t *testing.T) {
+	revision := postgresRevision{
+		tx:   xid8{Uint: 12345, Status: pgtype.Present},
+		xmin: noXmin,
+	}
+	hlcRevision, err := parseHLCRevision("1665000000000000000.0000000001")
+	require.NoError(t, err)
+	newXID := xid8{Uint: 12346, Status: pgtype.Present}
+
+	for _, tc := range []struct {
+		name           string
+		migrationPhase migrationPhase
+		filterer       queryFilterer
+		golden         string
+	}{
+		{"complete", complete, buildLivingObjectFilterForRevision(revision), "testdata/queries.golden"},
+		{"write both read old", writeBothReadOld, buildLivingObjectFilterForRevisionDeprecated(revision), "testdata/queries-write-both-read-old.golden"},
+		{"hlc", complete, buildLivingObjectFilterForRevision(hlcRevision.(postgresRevision)), "testdata/queries-hlc.golden"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := &sqlgolden.Recorder{}
+			reader := &pgReader{
+				querySplitter:  common.TupleQuerySplitter{Executor: recorder.ExecuteQuery, UsersetBatchSize: 100},
+				filterer:       tc.filterer,
+				migrationPhase: tc.migrationPhase,
+			}
+			rwt := &pgReadWriteTXN{reader, recorder.PgxTx(), newXID, tc.migrationPhase, false, false}
+
+			sqlgolden.Assert(t, tc.golden, recorder, reader, rwt)
+		})
+	}
+}
@@ -45,6 +45,7 @@ var (
 		ColUsersetObjectID:  colUsersetObjectID,
 		ColUsersetRelation:  colUsersetRelation,
 		ColCaveatName:       colCaveatContextName,
+		SortCollation:       `"C"`,
 	}
 
 	readNamespace = psql.Select(colConfig, colCreatedXid).From(tableNamespace)
@@ -224,11 +225,11 @@ func loadAllNamespaces(
 			return nil, fmt.Errorf(errUnableToReadConfig, err)
 		}
 
-		revision := postgresRevision{version, noXmin}
+		revision := postgresRevision{tx: version, xmin: noXmin}
 
 		// TODO remove once the ID->XID migrations are all complete
 		if migrationPhase == writeBothReadOld {
-			revision = postgresRevision{xid8{Uint: versionTxDeprecated, Status: pgtype.Present}, noXmin}
+			revision = postgresRevision{tx: xid8{Uint: versionTxDeprecated, Status: pgtype.Present}, xmin: noXmin}
 		}
 
 		nsDefs = append(nsDefs, nsAndVersion{loaded, revision})
@@ -11,6 +11,7 @@ import (
 	"github.com/jzelinskie/stringz"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	pgxcommon "github.com/authzed/spicedb/internal/datastore/postgres/common"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -21,6 +22,10 @@ const (
 	errUnableToDeleteConfig        = "unable to delete namespace config: %w"
 	errUnableToWriteRelationships  = "unable to write relationships: %w"
 	errUnableToDeleteRelationships = "unable to delete relationships: %w"
+
+	// writeChunkSize is the number of mutations written by each statement of a large write,
+	// which keeps each statement well under the limit of 65535 parameters.
+	writeChunkSize = 1000
 )
 
 var (
@@ -73,113 +78,248 @@ var (
 
 type pgReadWriteTXN struct {
 	*pgReader
-	tx             pgx.Tx
-	newXID         xid8
-	migrationPhase migrationPhase
+	tx              pgx.Tx
+	newXID          xid8
+	migrationPhase  migrationPhase
+	skipNoopTouches bool
+	sourcesEnabled  bool
 }
 
 func (rwt *pgReadWriteTXN) WriteRelationships(ctx context.Context, mutations []*core.RelationTupleUpdate) error {
-	bulkWrite := writeTuple
-
-	// TODO remove once the ID->XID migrations are all complete
-	if rwt.migrationPhase == writeBothReadNew || rwt.migrationPhase == writeBothReadOld {
-		bulkWrite = writeTupleDeprecated
+	if rwt.skipNoopTouches {
+		living, err := rwt.loadTouched(ctx, mutations)
+		if err != nil {
+			return fmt.Errorf(errUnableToWriteRelationships, err)
+		}
+		mutations = common.WithoutNoopTouches(mutations, living)
 	}
 
-	bulkWriteHasValues := false
-
-	deleteClauses := sq.Or{}
+	if len(mutations) <= writeChunkSize {
+		return rwt.writeRelationships(ctx, mutations)
+	}
 
-	// Process the actual updates
-	for _, mut := range mutations {
-		tpl := mut.Tuple
+	// Large writes are split into chunks, each of which becomes a statement sent in a single
+	// batch, which pgx pipelines over the transaction's connection. All deletions are queued
+	// ahead of all insertions, so the result is the same as for a single chunk.
+	chunks := make([][]*core.RelationTupleUpdate, 0, len(mutations)/writeChunkSize+1)
+	for remaining := mutations; len(remaining) > 0; {
+		size := writeChunkSize
+		if len(remaining) < size {
+			size = len(remaining)
+		}
+		chunks = append(chunks, remaining[:size])
+		remaining = remaining[size:]
+	}
 
-		if mut.Operation == core.RelationTupleUpdate_TOUCH || mut.Operation == core.RelationTupleUpdate_DELETE {
-			deleteClauses = append(deleteClauses, exactRelationshipClause(tpl))
+	batch := &pgx.Batch{}
+	var isInsert []bool
+	for _, chunk := range chunks {
+		sql, args, ok, err := rwt.deleteStatement(chunk)
+		if err != nil {
+			return fmt.Errorf(errUnableToWriteRelationships, err)
 		}
+		if ok {
+			batch.Queue(pgxcommon.AnnotateBatchedQuery(ctx, rwt.tx, sql), args...)
+			isInsert = append(isInsert, false)
+		}
+	}
+	for _, chunk := range chunks {
+		sql, args, ok, err := rwt.insertStatement(chunk)
+		if err != nil {
+			return fmt.Errorf(errUnableToWriteRelationships, err)
+		}
+		if ok {
+			batch.Queue(pgxcommon.AnnotateBatchedQuery(ctx, rwt.tx, sql), args...)
+			isInsert = append(isInsert, true)
+		}
+	}
 
-		if mut.Operation == core.RelationTupleUpdate_TOUCH || mut.Operation == core.RelationTupleUpdate_CREATE {
-			var caveatName string
-			var caveatContext map[string]any
-			if tpl.Caveat != nil {
-				caveatName = tpl.Caveat.CaveatName
-				caveatContext = tpl.Caveat.Context.AsMap()
-			}
-			valuesToWrite := []interface{}{
-				tpl.ResourceAndRelation.Namespace,
-				tpl.ResourceAndRelation.ObjectId,
-				tpl.ResourceAndRelation.Relation,
-				tpl.Subject.Namespace,
-				tpl.Subject.ObjectId,
-				tpl.Subject.Relation,
-				caveatName,
-				caveatContext, // PGX driver serializes map[string]any to JSONB type columns
+	results := rwt.tx.SendBatch(ctx, batch)
+	for _, insert := range isInsert {
+		if _, err := results.Exec(); err != nil {
+			_ = results.Close()
+			if insert {
+				return convertInsertError(err)
 			}
+			return fmt.Errorf(errUnableToWriteRelationships, err)
+		}
+	}
 
-			// TODO remove once the ID->XID migrations are all complete
-			if rwt.migrationPhase == writeBothReadNew || rwt.migrationPhase == writeBothReadOld {
-				valuesToWrite = append(valuesToWrite, rwt.newXID.Uint, rwt.newXID)
-			}
+	if err := results.Close(); err != nil {
+		return fmt.Errorf(errUnableToWriteRelationships, err)
+	}
+	return nil
+}
 
-			bulkWrite = bulkWrite.Values(valuesToWrite...)
-			bulkWriteHasValues = true
+func (rwt *pgReadWriteTXN) writeRelationships(ctx context.Context, mutations []*core.RelationTupleUpdate) error {
+	sql, args, ok, err := rwt.deleteStatement(mutations)
+	if err != nil {
+		return fmt.Errorf(errUnableToWriteRelationships, err)
+	}
+	if ok {
+		if _, err := rwt.tx.Exec(ctx, sql, args...); err != nil {
+			return fmt.Errorf(errUnableToWriteRelationships, err)
 		}
 	}
 
-	if len(deleteClauses) > 0 {
-		sql, args, err := deleteTuple.
-			Where(deleteClauses).
-			Set(colDeletedXid, rwt.newXID).
-			ToSql()
-		if err != nil {
-			return fmt.Errorf(errUnableToWriteRelationships, err)
+	sql, args, ok, err = rwt.insertStatement(mutations)
+	if err != nil {
+		return fmt.Errorf(errUnableToWriteRelationships, err)
+	}
+	if ok {
+		if _, err := rwt.tx.Exec(ctx, sql, args...); err != nil {
+			return convertInsertError(err)
 		}
+	}
 
-		// TODO remove once the ID->XID migrations are all complete
-		if rwt.migrationPhase == writeBothReadNew || rwt.migrationPhase == writeBothReadOld {
-			baseQuery := deleteTuple
-			if rwt.migrationPhase == writeBothReadOld {
-				baseQuery = deleteTupleDeprecated
-			}
+	return nil
+}
+
+// loadTouched returns the living relationships TOUCHed by the mutations.
+func (rwt *pgReadWriteTXN) loadTouched(ctx context.Context, mutations []*core.RelationTupleUpdate) ([]*core.RelationTuple, error) {
+	var living []*core.RelationTuple
+	touched := common.TouchedRelationships(mutations)
+	for len(touched) > 0 {
+		size := writeChunkSize
+		if len(touched) < size {
+			size = len(touched)
+		}
+
+		clauses := make(sq.Or, 0, size)
+		for _, tpl := range touched[:size] {
+			clauses = append(clauses, exactRelationshipClause(tpl))
+		}
+		touched = touched[size:]
+
+		sql, args, err := rwt.filterer(queryTuples).Where(clauses).ToSql()
+		if err != nil {
+			return nil, err
+		}
 
-			sql, args, err = baseQuery.
-				Where(deleteClauses).
-				Set(colDeletedTxnDeprecated, rwt.newXID.Uint).
-				Set(colDeletedXid, rwt.newXID).
-				ToSql()
+		rows, err := rwt.querySplitter.Executor(ctx, sql, args)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			tpl, err := rows.Next()
 			if err != nil {
-				return fmt.Errorf(errUnableToWriteRelationships, err)
+				rows.Close()
+				return nil, err
+			}
+			if tpl == nil {
+				break
 			}
+			living = append(living, tpl)
 		}
+		rows.Close()
+	}
+	return living, nil
+}
 
-		if _, err := rwt.tx.Exec(ctx, sql, args...); err != nil {
-			return fmt.Errorf(errUnableToWriteRelationships, err)
+// deleteStatement returns the statement marking as deleted the relationships touched or
+// deleted by the mutations, if any.
+func (rwt *pgReadWriteTXN) deleteStatement(mutations []*core.RelationTupleUpdate) (string, []any, bool, error) {
+	deleteClauses := sq.Or{}
+	for _, mut := range mutations {
+		if mut.Operation == core.RelationTupleUpdate_TOUCH || mut.Operation == core.RelationTupleUpdate_DELETE {
+			deleteClauses = append(deleteClauses, exactRelationshipClause(mut.Tuple))
 		}
 	}
 
-	if bulkWriteHasValues {
-		sql, args, err := bulkWrite.ToSql()
-		if err != nil {
-			return fmt.Errorf(errUnableToWriteRelationships, err)
+	if len(deleteClauses) == 0 {
+		return "", nil, false, nil
+	}
+
+	// TODO remove once the ID->XID migrations are all complete
+	if rwt.migrationPhase == writeBothReadNew || rwt.migrationPhase == writeBothReadOld {
+		baseQuery := deleteTuple
+		if rwt.migrationPhase == writeBothReadOld {
+			baseQuery = deleteTupleDeprecated
 		}
 
-		if _, err := rwt.tx.Exec(ctx, sql, args...); err != nil {
-			// If a unique constraint violation is returned, then its likely that the cause
-			// was an existing relationship given as a CREATE.
-			if cerr := pgxcommon.ConvertToWriteConstraintError(livingTupleConstraint, err); cerr != nil {
-				return cerr
-			}
+		sql, args, err := baseQuery.
+			Where(deleteClauses).
+			Set(colDeletedTxnDeprecated, rwt.newXID.Uint).
+			Set(colDeletedXid, rwt.newXID).
+			ToSql()
+		return sql, args, true, err
+	}
 
-			// TODO remove once the ID->XID migrations are all complete
-			if cerr := pgxcommon.ConvertToWriteConstraintError(livingTupleConstraintOld, err); cerr != nil {
-				return cerr
-			}
+	sql, args, err := deleteTuple.
+		Where(deleteClauses).
+		Set(colDeletedXid, rwt.newXID).
+		ToSql()
+	return sql, args, true, err
+}
 
-			return fmt.Errorf(errUnableToWriteRelationships, err)
+// insertStatement returns the statement inserting the relationships created or touched by
+// the mutations, if any.
+func (rwt *pgReadWriteTXN) insertStatement(mutations []*core.RelationTupleUpdate) (string, []any, bool, error) {
+	bulkWrite := writeTuple
+
+	// TODO remove once the ID->XID migrations are all complete
+	if rwt.migrationPhase == writeBothReadNew || rwt.migrationPhase == writeBothReadOld {
+		bulkWrite = writeTupleDeprecated
+	}
+
+	bulkWriteHasValues := false
+	for _, mut := range mutations {
+		tpl := mut.Tuple
+
+		if mut.Operation == core.RelationTupleUpdate_TOUCH || mut.Operation == core.RelationTupleUpdate_CREATE {
+			bulkWrite = bulkWrite.Values(rwt.tupleValues(tpl)...)
+			bulkWriteHasValues = true
 		}
 	}
 
-	return nil
+	if !bulkWriteHasValues {
+		return "", nil, false, nil
+	}
+
+	sql, args, err := bulkWrite.ToSql()
+	return sql, args, true, err
+}
+
+// tupleValues returns the values of the columns of writeTuple, or of writeTupleDeprecated
+// during the ID->XID migrations, for a relationship created by the transaction.
+func (rwt *pgReadWriteTXN) tupleValues(tpl *core.RelationTuple) []any {
+	var caveatName string
+	var caveatContext map[string]any
+	if tpl.Caveat != nil {
+		caveatName = tpl.Caveat.CaveatName
+		caveatContext = tpl.Caveat.Context.AsMap()
+	}
+	valuesToWrite := []interface{}{
+		tpl.ResourceAndRelation.Namespace,
+		tpl.ResourceAndRelation.ObjectId,
+		tpl.ResourceAndRelation.Relation,
+		tpl.Subject.Namespace,
+		tpl.Subject.ObjectId,
+		tpl.Subject.Relation,
+		caveatName,
+		caveatContext, // PGX driver serializes map[string]any to JSONB type columns
+	}
+
+	// TODO remove once the ID->XID migrations are all complete
+	if rwt.migrationPhase == writeBothReadNew || rwt.migrationPhase == writeBothReadOld {
+		valuesToWrite = append(valuesToWrite, rwt.newXID.Uint, rwt.newXID)
+	}
+	return valuesToWrite
+}
+
+func convertInsertError(err error) error {
+	// If a unique constraint violation is returned, then its likely that the cause
+	// was an existing relationship given as a CREATE.
+	if cerr := pgxcommon.ConvertToWriteConstraintError(livingTupleConstraint, err); cerr != nil {
+		return cerr
+	}
+
+	// TODO remove once the ID->XID migrations are all complete
+	if cerr := pgxcommon.ConvertToWriteConstraintError(livingTupleConstraintOld, err); cerr != nil {
+		return cerr
+	}
+
+	return fmt.Errorf(errUnableToWriteRelationships, err)
 }
 
 func (rwt *pgReadWriteTXN) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
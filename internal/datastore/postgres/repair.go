@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+var (
+	_ common.RelationshipTypeLister = (*pgDatastore)(nil)
+	_ common.MVCCRepairer           = (*pgDatastore)(nil)
+
+	errRepairDuringMigration = errors.New("MVCC repair is unavailable until the xid migration has completed")
+
+	// currentXmax is the first transaction ID which had not yet been assigned when the
+	// current snapshot was taken; no committed row can reference it or any later ID.
+	currentXmax = "pg_snapshot_xmax(pg_current_snapshot())"
+
+	mvccAnomalyFilter = sq.Or{
+		sq.Expr(colDeletedXid + " <= " + colCreatedXid),
+		sq.Expr(colCreatedXid + " >= " + currentXmax),
+		sq.And{
+			sq.NotEq{colDeletedXid: liveDeletedTxnID},
+			sq.Expr(colDeletedXid + " >= " + currentXmax),
+		},
+	}
+)
+
+func (pgd *pgDatastore) ListRelationshipResourceTypes(ctx context.Context, revRaw datastore.Revision) ([]string, error) {
+	rev := revRaw.(postgresRevision)
+	filterer := buildLivingObjectFilterForRevision(rev)
+	if pgd.migrationPhase == writeBothReadOld {
+		filterer = buildLivingObjectFilterForRevisionDeprecated(rev)
+	}
+
+	sql, args, err := filterer(psql.Select(colNamespace).Distinct().From(tableTuple)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pgd.dbpool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list relationship resource types: %w", err)
+	}
+	defer rows.Close()
+
+	var resourceTypes []string
+	for rows.Next() {
+		var resourceType string
+		if err := rows.Scan(&resourceType); err != nil {
+			return nil, err
+		}
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	return resourceTypes, rows.Err()
+}
+
+func (pgd *pgDatastore) FindMVCCAnomalies(ctx context.Context, limit uint64) ([]common.MVCCAnomaly, error) {
+	if pgd.migrationPhase != complete {
+		return nil, errRepairDuringMigration
+	}
+
+	sql, args, err := psql.Select(
+		colNamespace,
+		colObjectID,
+		colRelation,
+		colUsersetNamespace,
+		colUsersetObjectID,
+		colUsersetRelation,
+		colCreatedXid,
+		colDeletedXid,
+		currentXmax,
+	).From(tableTuple).Where(mvccAnomalyFilter).Limit(limit).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pgd.dbpool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find MVCC anomalies: %w", err)
+	}
+	defer rows.Close()
+
+	var anomalies []common.MVCCAnomaly
+	for rows.Next() {
+		rel := &core.RelationTuple{
+			ResourceAndRelation: &core.ObjectAndRelation{},
+			Subject:             &core.ObjectAndRelation{},
+		}
+		var created, deleted, xmax xid8
+		if err := rows.Scan(
+			&rel.ResourceAndRelation.Namespace,
+			&rel.ResourceAndRelation.ObjectId,
+			&rel.ResourceAndRelation.Relation,
+			&rel.Subject.Namespace,
+			&rel.Subject.ObjectId,
+			&rel.Subject.Relation,
+			&created,
+			&deleted,
+			&xmax,
+		); err != nil {
+			return nil, err
+		}
+
+		var reasons []string
+		if deleted.Uint <= created.Uint {
+			reasons = append(reasons, fmt.Sprintf("deleted in transaction %d, not after its creation in %d", deleted.Uint, created.Uint))
+		}
+		if created.Uint >= xmax.Uint {
+			reasons = append(reasons, fmt.Sprintf("created in transaction %d, which has not yet started", created.Uint))
+		}
+		if deleted.Uint != liveDeletedTxnID && deleted.Uint >= xmax.Uint {
+			reasons = append(reasons, fmt.Sprintf("deleted in transaction %d, which has not yet started", deleted.Uint))
+		}
+		anomalies = append(anomalies, common.MVCCAnomaly{Relationship: rel, Reason: strings.Join(reasons, "; ")})
+	}
+	return anomalies, rows.Err()
+}
+
+func (pgd *pgDatastore) DeleteMVCCAnomalies(ctx context.Context, limit uint64) (int64, error) {
+	if pgd.migrationPhase != complete {
+		return 0, errRepairDuringMigration
+	}
+
+	sql, args, err := psql.Select(relationTuplePKCols...).From(tableTuple).Where(mvccAnomalyFilter).Limit(limit).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	pkColsExpression := strings.Join(relationTuplePKCols, ", ")
+	query := fmt.Sprintf(`WITH rows AS (%[1]s)
+		  DELETE FROM %[2]s
+		  WHERE (%[3]s) IN (SELECT %[3]s FROM rows);
+	`, sql, tableTuple, pkColsExpression)
+
+	result, err := pgd.dbpool.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete MVCC anomalies: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
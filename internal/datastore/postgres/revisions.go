@@ -12,7 +12,10 @@ import (
 	"github.com/jackc/pgx/v4"
 	"github.com/shopspring/decimal"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/common/revisions"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
 )
 
 const (
@@ -32,52 +35,92 @@ const (
 	//   %[3] Name of timestamp column
 	//   %[4] Quantization period (in nanoseconds)
 	//   %[5] Name of snapshot column
+	//   %[6] Condition on the transactions which can be selected
+	//   %[7] Expression for the HLC of the selected transaction
 	querySelectRevision = `
 	
 	WITH selected AS (SELECT COALESCE(
-		(SELECT %[1]s FROM %[2]s WHERE %[3]s >= TO_TIMESTAMP(FLOOR(EXTRACT(EPOCH FROM NOW() AT TIME ZONE 'utc') * 1000000000 / %[4]d) * %[4]d / 1000000000) AT TIME ZONE 'utc' ORDER BY %[3]s ASC LIMIT 1),
-		(SELECT %[1]s FROM %[2]s ORDER BY %[3]s DESC LIMIT 1)
+		(SELECT %[1]s FROM %[2]s WHERE %[3]s >= TO_TIMESTAMP(FLOOR(EXTRACT(EPOCH FROM NOW() AT TIME ZONE 'utc') * 1000000000 / %[4]d) * %[4]d / 1000000000) AT TIME ZONE 'utc' AND %[6]s ORDER BY %[3]s ASC LIMIT 1),
+		(SELECT %[1]s FROM %[2]s WHERE %[6]s ORDER BY %[3]s DESC LIMIT 1)
 	) as xid)
 	SELECT selected.xid,
 	pg_snapshot_xmin(%[5]s),
+	%[7]s,
 	%[4]d - CAST(EXTRACT(EPOCH FROM NOW() AT TIME ZONE 'utc') * 1000000000 as bigint) %% %[4]d
 	FROM selected INNER JOIN %[2]s ON selected.xid = %[2]s.%[1]s;`
 
 	// queryValidTransaction will return a single row with two values, one boolean
-	// for whether the specified transaction ID is newer than the garbage collection
-	// window, and one boolean for whether the transaction ID represents a transaction
-	// that will occur in the future.
+	// for whether the specified transaction ID (or HLC) is newer than the garbage
+	// collection window, and one boolean for whether the transaction ID (or HLC)
+	// represents a transaction that will occur in the future.
 	//
-	//   %[1] Name of xid column
+	//   %[1] Name of xid (or hlc) column
 	//   %[2] Relationship tuple transaction table
 	//   %[3] Name of timestamp column
 	//   %[4] Inverse of GC window (in seconds)
+	//   %[5] Condition on the transactions which can be compared
+	//   %[6] Name of the column ordering the latest transaction
 	queryValidTransaction = `
 	SELECT $1 >= (
-		SELECT %[1]s FROM %[2]s WHERE %[3]s >= NOW() - INTERVAL '%[4]f seconds' ORDER BY %[3]s ASC LIMIT 1
+		SELECT %[1]s FROM %[2]s WHERE %[3]s >= NOW() - INTERVAL '%[4]f seconds' AND %[5]s ORDER BY %[3]s ASC LIMIT 1
 	) as fresh, $1 > (
-		SELECT %[1]s FROM %[2]s ORDER BY %[3]s DESC LIMIT 1
+		SELECT %[1]s FROM %[2]s WHERE %[5]s ORDER BY %[6]s DESC LIMIT 1
 	) as unknown;`
+
+	// hlcLogicalTick is the increment of the logical component of an HLC, which is
+	// stored in the fractional part of the decimal as it is by CockroachDB.
+	hlcLogicalTick = "0.0000000001"
+
+	// queryAdvanceHLC assigns the HLC of a new transaction: the current time in
+	// nanoseconds if the clock has moved past the last HLC, or the last HLC with its
+	// logical component incremented otherwise. Updating the row of the clock locks it
+	// until the transaction commits, so that concurrent writers are assigned HLCs in the
+	// order in which they become visible, and a writer whose snapshot predates the
+	// commit of another fails to serialize and is retried.
+	//
+	//   %[1] Name of hlc column
+	//   %[2] HLC clock table
+	queryAdvanceHLC = `UPDATE %[2]s SET %[1]s = GREATEST(
+		FLOOR(EXTRACT(EPOCH FROM clock_timestamp()) * 1000000000)::DECIMAL,
+		%[1]s + ` + hlcLogicalTick + `
+	) RETURNING %[1]s`
 )
 
 func (pgd *pgDatastore) optimizedRevisionFunc(ctx context.Context) (datastore.Revision, time.Duration, error) {
 	var revision, xmin xid8
+	var hlc decimal.NullDecimal
 	var validForNanos time.Duration
-	if err := pgd.dbpool.QueryRow(ctx, pgd.optimizedRevisionQuery).
-		Scan(&revision, &xmin, &validForNanos); err != nil {
+	if err := pgd.dbpool.QueryRow(ctx, pgd.optimizedRevisionQuery(pgd.quantization.Window())).
+		Scan(&revision, &xmin, &hlc, &validForNanos); err != nil {
 		return datastore.NoRevision, 0, fmt.Errorf(errRevision, err)
 	}
 
-	return postgresRevision{revision, xmin}, validForNanos, nil
+	return postgresRevision{tx: revision, xmin: xmin, hlc: hlc}, validForNanos, nil
+}
+
+// RevisionQuantization returns the window to which optimized revisions are quantized.
+func (pgd *pgDatastore) RevisionQuantization() *revisions.Quantization {
+	return pgd.quantization
 }
 
 func (pgd *pgDatastore) HeadRevision(ctx context.Context) (datastore.Revision, error) {
-	revision, xmin, err := pgd.loadRevision(ctx)
+	revision, err := pgd.loadRevision(ctx)
 	if err != nil {
 		return datastore.NoRevision, err
 	}
 
-	return postgresRevision{revision, xmin}, nil
+	return revision, nil
+}
+
+// RevisionCommitTime returns the wall time of the HLC of the revision under the HLC
+// revision scheme, which is the time at which its transaction was assigned the HLC.
+// Revisions under the transaction ID scheme record no time.
+func (pgd *pgDatastore) RevisionCommitTime(revisionRaw datastore.Revision) (time.Time, bool) {
+	revision, ok := revisionRaw.(postgresRevision)
+	if !ok || !revision.hlc.Valid {
+		return time.Time{}, false
+	}
+	return time.Unix(0, revision.hlc.Decimal.IntPart()).UTC(), true
 }
 
 func (pgd *pgDatastore) CheckRevision(ctx context.Context, revisionRaw datastore.Revision) error {
@@ -86,8 +129,16 @@ func (pgd *pgDatastore) CheckRevision(ctx context.Context, revisionRaw datastore
 		return datastore.NewInvalidRevisionErr(revisionRaw, datastore.CouldNotDetermineRevision)
 	}
 
+	var compared any = revision.tx
+	if pgd.revisionScheme == hlcRevisions {
+		if !revision.hlc.Valid {
+			return datastore.NewInvalidRevisionErr(revision, datastore.CouldNotDetermineRevision)
+		}
+		compared = revision.hlc.Decimal
+	}
+
 	var freshEnough, unknown bool
-	if err := pgd.dbpool.QueryRow(ctx, pgd.validTransactionQuery, revision.tx).
+	if err := pgd.dbpool.QueryRow(ctx, pgd.validTransactionQuery, compared).
 		Scan(&freshEnough, &unknown); err != nil {
 		return fmt.Errorf(errCheckRevision, err)
 	}
@@ -96,16 +147,40 @@ func (pgd *pgDatastore) CheckRevision(ctx context.Context, revisionRaw datastore
 		return datastore.NewInvalidRevisionErr(revision, datastore.CouldNotDetermineRevision)
 	}
 	if !freshEnough {
-		return datastore.NewInvalidRevisionErr(revision, datastore.RevisionStale)
+		// Pinned revisions are retained past the garbage collection window.
+		pinned, err := pgd.isPinned(ctx, compared)
+		if err != nil {
+			return fmt.Errorf(errCheckRevision, err)
+		}
+		if !pinned {
+			return datastore.NewInvalidRevisionErr(revision, datastore.RevisionStale)
+		}
 	}
 
 	return nil
 }
 
 func (pgd *pgDatastore) RevisionFromString(revisionStr string) (datastore.Revision, error) {
+	if pgd.revisionScheme == hlcRevisions {
+		return parseHLCRevision(revisionStr)
+	}
 	return parseRevision(revisionStr)
 }
 
+// parseHLCRevision parses the decimal form of an HLC revision. The transaction of
+// the revision is left undefined, to be resolved by the queries reading at it.
+func parseHLCRevision(revisionStr string) (datastore.Revision, error) {
+	hlc, err := decimal.NewFromString(revisionStr)
+	if err != nil {
+		return datastore.NoRevision, fmt.Errorf(errRevisionFormat, err)
+	}
+	if hlc.IsNegative() {
+		return datastore.NoRevision, fmt.Errorf(errRevisionFormat, errors.New("hlc is negative"))
+	}
+
+	return postgresRevision{tx: noXmin, xmin: noXmin, hlc: decimal.NewNullDecimal(hlc)}, nil
+}
+
 func parseRevision(revisionStr string) (datastore.Revision, error) {
 	components := strings.Split(revisionStr, ".")
 	numComponents := len(components)
@@ -146,41 +221,65 @@ func parseRevision(revisionStr string) (datastore.Revision, error) {
 		}
 	}
 
-	return postgresRevision{xid8{Uint: uint64(xid), Status: pgtype.Present}, xmin}, nil
+	return postgresRevision{tx: xid8{Uint: uint64(xid), Status: pgtype.Present}, xmin: xmin}, nil
 }
 
-func (pgd *pgDatastore) loadRevision(ctx context.Context) (xid8, xid8, error) {
+func (pgd *pgDatastore) loadRevision(ctx context.Context) (postgresRevision, error) {
 	ctx, span := tracer.Start(ctx, "loadRevision")
 	defer span.End()
 
-	sql, args, err := getRevision.ToSql()
+	var revision, xmin xid8
+	var hlc decimal.NullDecimal
+	query, dest := getRevision, []any{&revision, &xmin}
+	if pgd.revisionScheme == hlcRevisions {
+		query, dest = getHLCRevision, append(dest, &hlc)
+	}
+
+	sql, args, err := query.ToSql()
 	if err != nil {
-		return xid8{}, xid8{}, fmt.Errorf(errRevision, err)
+		return postgresRevision{}, fmt.Errorf(errRevision, err)
 	}
 
-	var revision, xmin xid8
-	err = pgd.dbpool.QueryRow(ctx, sql, args...).Scan(&revision, &xmin)
+	err = pgd.dbpool.QueryRow(ctx, sql, args...).Scan(dest...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return xid8{}, xid8{}, nil
+			return postgresRevision{}, nil
 		}
-		return xid8{}, xid8{}, fmt.Errorf(errRevision, err)
+		return postgresRevision{}, fmt.Errorf(errRevision, err)
 	}
 
-	return revision, xmin, nil
+	return postgresRevision{tx: revision, xmin: xmin, hlc: hlc}, nil
 }
 
-func createNewTransaction(ctx context.Context, tx pgx.Tx) (newXID, newXmin xid8, err error) {
+func (pgd *pgDatastore) createNewTransaction(ctx context.Context, tx pgx.Tx) (postgresRevision, error) {
 	ctx, span := tracer.Start(ctx, "createNewTransaction")
 	defer span.End()
 
-	err = tx.QueryRow(ctx, createTxn).Scan(&newXID, &newXmin)
-	return
+	var newXID, newXmin xid8
+	if pgd.revisionScheme != hlcRevisions {
+		err := tx.QueryRow(ctx, createTxn).Scan(&newXID, &newXmin)
+		return postgresRevision{tx: newXID, xmin: newXmin}, err
+	}
+
+	// The HLC is assigned by the first statement of the transaction, which takes its
+	// snapshot: as a writer committing after the snapshot fails the update of the clock
+	// to serialize, the snapshot recorded with the HLC holds every earlier HLC.
+	var hlc decimal.NullDecimal
+	if err := tx.QueryRow(ctx, advanceHLC).Scan(&hlc); err != nil {
+		return postgresRevision{}, err
+	}
+
+	err := tx.QueryRow(ctx, createHLCTxn, hlc).Scan(&newXID, &newXmin)
+	return postgresRevision{tx: newXID, xmin: newXmin, hlc: hlc}, err
 }
 
+// postgresRevision is the revision of a transaction. Under the HLC revision scheme, the
+// revision also holds the HLC of the transaction, by which it is ordered; revisions
+// parsed from their string form hold only the HLC.
 type postgresRevision struct {
 	tx   xid8
 	xmin xid8
+	hlc  decimal.NullDecimal
 }
 
 var noXmin = xid8{
@@ -189,7 +288,11 @@ var noXmin = xid8{
 }
 
 func (pr postgresRevision) Equal(rhsRaw datastore.Revision) bool {
-	rhs, ok := validateRHS(rhsRaw)
+	if lhsHLC, rhsHLC, ok := pr.comparableHLCs(rhsRaw); ok {
+		return lhsHLC.Equal(rhsHLC)
+	}
+
+	rhs, ok := pr.validateRHS(rhsRaw)
 	return ok && pr.tx.Uint == rhs.tx.Uint
 }
 
@@ -198,20 +301,31 @@ func (pr postgresRevision) GreaterThan(rhsRaw datastore.Revision) bool {
 		return true
 	}
 
-	rhs, ok := validateRHS(rhsRaw)
+	if lhsHLC, rhsHLC, ok := pr.comparableHLCs(rhsRaw); ok {
+		return lhsHLC.GreaterThan(rhsHLC)
+	}
+
+	rhs, ok := pr.validateRHS(rhsRaw)
 	return ok && pr.tx.Uint > rhs.tx.Uint &&
 		((pr.xmin.Status == pgtype.Present && pr.xmin.Uint > rhs.tx.Uint) ||
 			pr.xmin.Status != pgtype.Present)
 }
 
 func (pr postgresRevision) LessThan(rhsRaw datastore.Revision) bool {
-	rhs, ok := validateRHS(rhsRaw)
+	if lhsHLC, rhsHLC, ok := pr.comparableHLCs(rhsRaw); ok {
+		return lhsHLC.LessThan(rhsHLC)
+	}
+
+	rhs, ok := pr.validateRHS(rhsRaw)
 	return ok && pr.tx.Uint < rhs.tx.Uint &&
 		((rhs.xmin.Status == pgtype.Present && rhs.xmin.Uint > pr.tx.Uint) ||
 			rhs.xmin.Status != pgtype.Present)
 }
 
 func (pr postgresRevision) String() string {
+	if pr.hlc.Valid {
+		return pr.hlc.Decimal.String()
+	}
 	if pr.xmin.Status == pgtype.Present {
 		return fmt.Sprintf("%d.%d", pr.tx.Uint, pr.xmin.Uint)
 	}
@@ -219,13 +333,42 @@ func (pr postgresRevision) String() string {
 }
 
 func (pr postgresRevision) MarshalBinary() ([]byte, error) {
+	if pr.hlc.Valid {
+		return pr.hlc.Decimal.MarshalBinary()
+	}
+
 	// We use the decimal library for this to keep it backward compatible with the old version.
 	return decimal.NewFromInt(int64(pr.tx.Uint)).MarshalBinary()
 }
 
-func validateRHS(rhsRaw datastore.Revision) (postgresRevision, bool) {
+// comparableHLCs returns the HLCs of both revisions, if they both have one. The
+// revisions of other datastores which are HLCs, such as those of CockroachDB, are
+// compared with HLC revisions as well.
+func (pr postgresRevision) comparableHLCs(rhsRaw datastore.Revision) (decimal.Decimal, decimal.Decimal, bool) {
+	if !pr.hlc.Valid {
+		return decimal.Decimal{}, decimal.Decimal{}, false
+	}
+
+	switch rhs := rhsRaw.(type) {
+	case postgresRevision:
+		if rhs.hlc.Valid {
+			return pr.hlc.Decimal, rhs.hlc.Decimal, true
+		}
+	case revision.Decimal:
+		return pr.hlc.Decimal, rhs.Decimal, true
+	}
+	return decimal.Decimal{}, decimal.Decimal{}, false
+}
+
+// validateRHS returns the right hand side of a comparison by transaction ID, if both
+// revisions have one.
+func (pr postgresRevision) validateRHS(rhsRaw datastore.Revision) (postgresRevision, bool) {
 	rhs, ok := rhsRaw.(postgresRevision)
-	return rhs, ok && rhs.tx.Status == pgtype.Present
+	return rhs, ok && rhs.tx.Status == pgtype.Present && pr.tx.Status == pgtype.Present
 }
 
 var _ datastore.Revision = postgresRevision{}
+
+var _ common.QuantizedDatastore = &pgDatastore{}
+
+var _ common.CommitTimeReporter = &pgDatastore{}
@@ -5,7 +5,11 @@ import (
 	"testing"
 
 	"github.com/jackc/pgtype"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
 )
 
 const (
@@ -129,11 +133,89 @@ func TestRevisionDeserializationErrors(t *testing.T) {
 	}
 }
 
+func TestHLCRevisionOrdering(t *testing.T) {
+	testCases := []struct {
+		lhs          datastore.Revision
+		rhs          datastore.Revision
+		relationship comparisonResult
+	}{
+		{testHLCRevision(5, "1000.0000000001"), testHLCRevision(5, "1000.0000000001"), equal},
+		{testHLCRevision(5, "1000.0000000001"), testHLCRevision(6, "1000.0000000002"), lt},
+		{testHLCRevision(6, "1001"), testHLCRevision(5, "1000.0000000002"), gt},
+
+		// The HLC takes precedence over the xid.
+		{testHLCRevision(6, "1000"), testHLCRevision(5, "1001"), lt},
+
+		// Revisions parsed from strings have no xid.
+		{testHLCRevision(5, "1000"), mustParseHLCRevision(t, "1000"), equal},
+		{mustParseHLCRevision(t, "999.5"), testHLCRevision(5, "1000"), lt},
+
+		// Decimal revisions, such as those of CockroachDB, compare with HLC revisions.
+		{testHLCRevision(5, "1000"), revision.NewFromDecimal(decimal.RequireFromString("1000")), equal},
+		{testHLCRevision(5, "1000.0000000001"), revision.NewFromDecimal(decimal.RequireFromString("1000")), gt},
+		{testHLCRevision(5, "1000"), revision.NewFromDecimal(decimal.RequireFromString("1000.0000000001")), lt},
+
+		// HLC revisions are not comparable with those without an HLC.
+		{mustParseHLCRevision(t, "1000"), testRevision(5, 4), concurrent},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s:%s", tc.lhs, tc.rhs), func(t *testing.T) {
+			require := require.New(t)
+
+			lhs := tc.lhs.(postgresRevision)
+			require.Equal(tc.relationship == equal, lhs.Equal(tc.rhs))
+			require.Equal(tc.relationship == lt, lhs.LessThan(tc.rhs))
+			require.Equal(tc.relationship == gt, lhs.GreaterThan(tc.rhs))
+		})
+	}
+}
+
+func TestHLCRevisionSerDe(t *testing.T) {
+	for _, hlc := range []string{"0", "1000", "1665000000000000000.0000000001"} {
+		t.Run(hlc, func(t *testing.T) {
+			require := require.New(t)
+
+			rev := testHLCRevision(500, hlc)
+			serialized := rev.String()
+			require.Equal(hlc, serialized)
+
+			parsed, err := parseHLCRevision(serialized)
+			require.NoError(err)
+			require.True(parsed.Equal(rev))
+			require.Equal(serialized, parsed.String())
+
+			marshaled, err := rev.MarshalBinary()
+			require.NoError(err)
+			parsedMarshaled, err := parsed.(postgresRevision).MarshalBinary()
+			require.NoError(err)
+			require.Equal(marshaled, parsedMarshaled)
+		})
+	}
+
+	for _, invalid := range []string{"", "abc", "-1", "1.0.0"} {
+		_, err := parseHLCRevision(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func mustParseHLCRevision(t *testing.T, hlc string) postgresRevision {
+	rev, err := parseHLCRevision(hlc)
+	require.NoError(t, err)
+	return rev.(postgresRevision)
+}
+
+func testHLCRevision(tx uint64, hlc string) postgresRevision {
+	rev := testRevision(tx, -1)
+	rev.hlc = decimal.NewNullDecimal(decimal.RequireFromString(hlc))
+	return rev
+}
+
 func testRevision(tx uint64, xmin int64) postgresRevision {
 	revXmin := noXmin
 	if xmin >= 0 {
 		revXmin = xid8{Uint: uint64(xmin), Status: pgtype.Present}
 	}
 
-	return postgresRevision{xid8{tx, pgtype.Present}, revXmin}
+	return postgresRevision{tx: xid8{tx, pgtype.Present}, xmin: revXmin}
 }
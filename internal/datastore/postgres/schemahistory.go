@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+const (
+	tableSchemaVersionHistory = "schema_version_history"
+
+	colSchemaVersion = "version"
+	colRevision      = "revision"
+	colSchemaText    = "schema_text"
+	colAuthor        = "author"
+	colCreatedAt     = "created_at"
+
+	errAddSchemaVersion   = "unable to add schema version: %w"
+	errListSchemaVersions = "unable to list schema versions: %w"
+	errReadSchemaVersion  = "unable to read schema version: %w"
+)
+
+var (
+	hasSchemaVersionHistoryTable = fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", tableSchemaVersionHistory)
+
+	addSchemaVersion = psql.
+				Insert(tableSchemaVersionHistory).
+				Columns(colRevision, colSchemaText, colAuthor).
+				Suffix(fmt.Sprintf("RETURNING %s, %s", colSchemaVersion, colCreatedAt))
+
+	listSchemaVersions = psql.
+				Select(colSchemaVersion, colRevision, colAuthor, colCreatedAt).
+				From(tableSchemaVersionHistory).
+				OrderBy(colSchemaVersion)
+
+	readSchemaVersion = psql.
+				Select(colSchemaVersion, colRevision, colSchemaText, colAuthor, colCreatedAt).
+				From(tableSchemaVersionHistory)
+)
+
+func (pgd *pgDatastore) AddSchemaVersion(ctx context.Context, revision datastore.Revision, schemaText, author string) (datastore.SchemaVersion, error) {
+	if !pgd.schemaHistoryEnabled {
+		return datastore.SchemaVersion{}, datastore.NewSchemaHistoryUnsupportedErr(Engine)
+	}
+
+	sql, args, err := addSchemaVersion.Values(revision.String(), schemaText, author).ToSql()
+	if err != nil {
+		return datastore.SchemaVersion{}, fmt.Errorf(errAddSchemaVersion, err)
+	}
+
+	version := datastore.SchemaVersion{
+		Revision:   revision,
+		SchemaText: schemaText,
+		Author:     author,
+	}
+	if err := pgd.dbpool.QueryRow(ctx, sql, args...).Scan(&version.Version, &version.CreatedAt); err != nil {
+		return datastore.SchemaVersion{}, fmt.Errorf(errAddSchemaVersion, err)
+	}
+	version.CreatedAt = version.CreatedAt.UTC()
+	return version, nil
+}
+
+func (pgd *pgDatastore) ListSchemaVersions(ctx context.Context) ([]datastore.SchemaVersion, error) {
+	if !pgd.schemaHistoryEnabled {
+		return nil, datastore.NewSchemaHistoryUnsupportedErr(Engine)
+	}
+
+	sql, args, err := listSchemaVersions.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf(errListSchemaVersions, err)
+	}
+
+	rows, err := pgd.dbpool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf(errListSchemaVersions, err)
+	}
+	defer rows.Close()
+
+	var versions []datastore.SchemaVersion
+	for rows.Next() {
+		var version datastore.SchemaVersion
+		var revision string
+		if err := rows.Scan(&version.Version, &revision, &version.Author, &version.CreatedAt); err != nil {
+			return nil, fmt.Errorf(errListSchemaVersions, err)
+		}
+		if version.Revision, err = pgd.RevisionFromString(revision); err != nil {
+			return nil, fmt.Errorf(errListSchemaVersions, err)
+		}
+		version.CreatedAt = version.CreatedAt.UTC()
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errListSchemaVersions, err)
+	}
+	return versions, nil
+}
+
+func (pgd *pgDatastore) ReadSchemaVersion(ctx context.Context, versionNumber uint64) (datastore.SchemaVersion, error) {
+	if !pgd.schemaHistoryEnabled {
+		return datastore.SchemaVersion{}, datastore.NewSchemaHistoryUnsupportedErr(Engine)
+	}
+
+	sql, args, err := readSchemaVersion.Where(sq.Eq{colSchemaVersion: versionNumber}).ToSql()
+	if err != nil {
+		return datastore.SchemaVersion{}, fmt.Errorf(errReadSchemaVersion, err)
+	}
+
+	var version datastore.SchemaVersion
+	var revision string
+	if err := pgd.dbpool.QueryRow(ctx, sql, args...).Scan(&version.Version, &revision, &version.SchemaText, &version.Author, &version.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return datastore.SchemaVersion{}, datastore.NewSchemaVersionNotFoundErr(versionNumber)
+		}
+		return datastore.SchemaVersion{}, fmt.Errorf(errReadSchemaVersion, err)
+	}
+	if version.Revision, err = pgd.RevisionFromString(revision); err != nil {
+		return datastore.SchemaVersion{}, fmt.Errorf(errReadSchemaVersion, err)
+	}
+	version.CreatedAt = version.CreatedAt.UTC()
+	return version, nil
+}
+
+var _ common.SchemaHistoryStore = &pgDatastore{}
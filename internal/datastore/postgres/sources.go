@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/jzelinskie/stringz"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+const (
+	tableRelationshipSource = "relationship_source"
+
+	colSource = "source"
+
+	errWriteRelationshipSources = "unable to write relationship sources: %w"
+	errReadRelationshipSources  = "unable to read relationship sources: %w"
+)
+
+var (
+	hasRelationshipSourceTable = fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", tableRelationshipSource)
+
+	hasRelationshipSources = fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s)", tableRelationshipSource)
+
+	writeRelationshipSource = psql.Insert(tableRelationshipSource).Columns(
+		colNamespace,
+		colObjectID,
+		colRelation,
+		colUsersetNamespace,
+		colUsersetObjectID,
+		colUsersetRelation,
+		colSource,
+	)
+
+	readRelationshipSources = psql.Select(
+		colNamespace,
+		colObjectID,
+		colRelation,
+		colUsersetNamespace,
+		colUsersetObjectID,
+		colUsersetRelation,
+	).From(tableRelationshipSource)
+)
+
+func (rwt *pgReadWriteTXN) SetRelationshipSources(ctx context.Context, source string, relationships []*core.RelationTuple) error {
+	if !rwt.sourcesEnabled {
+		return common.ErrRelationshipSourcesUnsupported
+	}
+
+	for remaining := relationships; len(remaining) > 0; {
+		size := writeChunkSize
+		if len(remaining) < size {
+			size = len(remaining)
+		}
+		chunk := remaining[:size]
+		remaining = remaining[size:]
+
+		clauses := make(sq.Or, 0, len(chunk))
+		for _, rel := range chunk {
+			clauses = append(clauses, exactRelationshipClause(rel))
+		}
+
+		sql, args, err := psql.Delete(tableRelationshipSource).Where(clauses).ToSql()
+		if err != nil {
+			return fmt.Errorf(errWriteRelationshipSources, err)
+		}
+		if _, err := rwt.tx.Exec(ctx, sql, args...); err != nil {
+			return fmt.Errorf(errWriteRelationshipSources, err)
+		}
+
+		if source == "" {
+			continue
+		}
+
+		insert := writeRelationshipSource
+		for _, rel := range chunk {
+			insert = insert.Values(
+				rel.ResourceAndRelation.Namespace,
+				rel.ResourceAndRelation.ObjectId,
+				rel.ResourceAndRelation.Relation,
+				rel.Subject.Namespace,
+				rel.Subject.ObjectId,
+				rel.Subject.Relation,
+				source,
+			)
+		}
+
+		sql, args, err = insert.ToSql()
+		if err != nil {
+			return fmt.Errorf(errWriteRelationshipSources, err)
+		}
+		if _, err := rwt.tx.Exec(ctx, sql, args...); err != nil {
+			return fmt.Errorf(errWriteRelationshipSources, err)
+		}
+	}
+
+	return nil
+}
+
+func (rwt *pgReadWriteTXN) ClearRelationshipSources(ctx context.Context, filter *v1.RelationshipFilter) error {
+	if !rwt.sourcesEnabled {
+		return common.ErrRelationshipSourcesUnsupported
+	}
+
+	query := psql.Delete(tableRelationshipSource).Where(sq.Eq{colNamespace: filter.ResourceType})
+	if filter.OptionalResourceId != "" {
+		query = query.Where(sq.Eq{colObjectID: filter.OptionalResourceId})
+	}
+	if filter.OptionalRelation != "" {
+		query = query.Where(sq.Eq{colRelation: filter.OptionalRelation})
+	}
+	if subjectFilter := filter.OptionalSubjectFilter; subjectFilter != nil {
+		query = query.Where(sq.Eq{colUsersetNamespace: subjectFilter.SubjectType})
+		if subjectFilter.OptionalSubjectId != "" {
+			query = query.Where(sq.Eq{colUsersetObjectID: subjectFilter.OptionalSubjectId})
+		}
+		if relationFilter := subjectFilter.OptionalRelation; relationFilter != nil {
+			query = query.Where(sq.Eq{colUsersetRelation: stringz.DefaultEmpty(relationFilter.Relation, datastore.Ellipsis)})
+		}
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf(errWriteRelationshipSources, err)
+	}
+	if _, err := rwt.tx.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf(errWriteRelationshipSources, err)
+	}
+	return nil
+}
+
+func (rwt *pgReadWriteTXN) HasRelationshipSources(ctx context.Context) (bool, error) {
+	if !rwt.sourcesEnabled {
+		return false, common.ErrRelationshipSourcesUnsupported
+	}
+
+	var found bool
+	if err := rwt.tx.QueryRow(ctx, hasRelationshipSources).Scan(&found); err != nil {
+		return false, fmt.Errorf(errReadRelationshipSources, err)
+	}
+	return found, nil
+}
+
+func (rwt *pgReadWriteTXN) RelationshipsFromSource(ctx context.Context, source string) ([]*core.RelationTuple, error) {
+	if !rwt.sourcesEnabled {
+		return nil, common.ErrRelationshipSourcesUnsupported
+	}
+
+	sql, args, err := readRelationshipSources.Where(sq.Eq{colSource: source}).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf(errReadRelationshipSources, err)
+	}
+
+	rows, err := rwt.tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf(errReadRelationshipSources, err)
+	}
+	defer rows.Close()
+
+	var relationships []*core.RelationTuple
+	for rows.Next() {
+		rel := &core.RelationTuple{
+			ResourceAndRelation: &core.ObjectAndRelation{},
+			Subject:             &core.ObjectAndRelation{},
+		}
+		if err := rows.Scan(
+			&rel.ResourceAndRelation.Namespace,
+			&rel.ResourceAndRelation.ObjectId,
+			&rel.ResourceAndRelation.Relation,
+			&rel.Subject.Namespace,
+			&rel.Subject.ObjectId,
+			&rel.Subject.Relation,
+		); err != nil {
+			return nil, fmt.Errorf(errReadRelationshipSources, err)
+		}
+		relationships = append(relationships, rel)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errReadRelationshipSources, err)
+	}
+	return relationships, nil
+}
+
+var _ common.RelationshipSourceTracker = &pgReadWriteTXN{}
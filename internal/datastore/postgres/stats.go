@@ -28,6 +28,25 @@ var (
 				Where(sq.Eq{colRelname: tableTuple})
 )
 
+func (pgd *pgDatastore) UniqueID(ctx context.Context) (string, error) {
+	if uniqueID := pgd.uniqueID.Load(); uniqueID != nil {
+		return *uniqueID, nil
+	}
+
+	sql, args, err := queryUniqueID.ToSql()
+	if err != nil {
+		return "", fmt.Errorf("unable to generate query sql: %w", err)
+	}
+
+	var uniqueID string
+	if err := pgd.dbpool.QueryRow(ctx, sql, args...).Scan(&uniqueID); err != nil {
+		return "", fmt.Errorf("unable to query unique ID: %w", err)
+	}
+
+	pgd.uniqueID.Store(&uniqueID)
+	return uniqueID, nil
+}
+
 func (pgd *pgDatastore) Statistics(ctx context.Context) (datastore.Stats, error) {
 	idSQL, idArgs, err := queryUniqueID.ToSql()
 	if err != nil {
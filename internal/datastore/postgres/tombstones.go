@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+const (
+	tableNamespaceTombstone = "namespace_tombstone"
+
+	colTombstoneRevision = "revision"
+	colDeletedAt         = "deleted_at"
+
+	errWriteNamespaceTombstone = "unable to write namespace tombstone: %w"
+	errReadNamespaceTombstones = "unable to read namespace tombstones: %w"
+)
+
+var (
+	hasNamespaceTombstoneTable = fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", tableNamespaceTombstone)
+
+	writeNamespaceTombstone = psql.
+				Insert(tableNamespaceTombstone).
+				Columns(colNamespace, colTombstoneRevision, colDeletedAt, colExpiresAt).
+				Suffix(fmt.Sprintf(
+			"ON CONFLICT (%[1]s) DO UPDATE SET %[2]s = EXCLUDED.%[2]s, %[3]s = EXCLUDED.%[3]s, %[4]s = EXCLUDED.%[4]s",
+			colNamespace, colTombstoneRevision, colDeletedAt, colExpiresAt,
+		))
+
+	deleteExpiredNamespaceTombstones = psql.
+						Delete(tableNamespaceTombstone).
+						Where(sq.Expr(colExpiresAt + " <= NOW()"))
+
+	readNamespaceTombstones = psql.
+				Select(colNamespace, colTombstoneRevision, colDeletedAt, colExpiresAt).
+				From(tableNamespaceTombstone).
+				Where(sq.Expr(colExpiresAt + " > NOW()"))
+)
+
+func (pgd *pgDatastore) WriteNamespaceTombstone(ctx context.Context, tombstone common.NamespaceTombstone) error {
+	if !pgd.tombstonesEnabled {
+		return common.ErrNamespaceTombstonesUnsupported
+	}
+
+	sql, args, err := deleteExpiredNamespaceTombstones.ToSql()
+	if err != nil {
+		return fmt.Errorf(errWriteNamespaceTombstone, err)
+	}
+	if _, err := pgd.dbpool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf(errWriteNamespaceTombstone, err)
+	}
+
+	sql, args, err = writeNamespaceTombstone.
+		Values(tombstone.Namespace, tombstone.Revision.String(), tombstone.DeletedAt, tombstone.ExpiresAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf(errWriteNamespaceTombstone, err)
+	}
+	if _, err := pgd.dbpool.Exec(ctx, sql, args...); err != nil {
+		return fmt.Errorf(errWriteNamespaceTombstone, err)
+	}
+	return nil
+}
+
+func (pgd *pgDatastore) ReadNamespaceTombstone(ctx context.Context, namespace string) (common.NamespaceTombstone, error) {
+	tombstones, err := pgd.readNamespaceTombstones(ctx, readNamespaceTombstones.Where(sq.Eq{colNamespace: namespace}))
+	if err != nil {
+		return common.NamespaceTombstone{}, err
+	}
+	if len(tombstones) == 0 {
+		return common.NamespaceTombstone{}, common.ErrNamespaceTombstoneNotFound
+	}
+	return tombstones[0], nil
+}
+
+func (pgd *pgDatastore) ListNamespaceTombstones(ctx context.Context) ([]common.NamespaceTombstone, error) {
+	return pgd.readNamespaceTombstones(ctx, readNamespaceTombstones.OrderBy(colDeletedAt+" DESC"))
+}
+
+func (pgd *pgDatastore) DeleteNamespaceTombstone(ctx context.Context, namespace string) error {
+	if !pgd.tombstonesEnabled {
+		return common.ErrNamespaceTombstonesUnsupported
+	}
+
+	sql, args, err := psql.Delete(tableNamespaceTombstone).Where(sq.Eq{colNamespace: namespace}).ToSql()
+	if err != nil {
+		return fmt.Errorf(errWriteNamespaceTombstone, err)
+	}
+	result, err := pgd.dbpool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf(errWriteNamespaceTombstone, err)
+	}
+	if result.RowsAffected() == 0 {
+		return common.ErrNamespaceTombstoneNotFound
+	}
+	return nil
+}
+
+func (pgd *pgDatastore) readNamespaceTombstones(ctx context.Context, query sq.SelectBuilder) ([]common.NamespaceTombstone, error) {
+	if !pgd.tombstonesEnabled {
+		return nil, common.ErrNamespaceTombstonesUnsupported
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf(errReadNamespaceTombstones, err)
+	}
+
+	rows, err := pgd.dbpool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf(errReadNamespaceTombstones, err)
+	}
+	defer rows.Close()
+
+	var tombstones []common.NamespaceTombstone
+	for rows.Next() {
+		var tombstone common.NamespaceTombstone
+		var revision string
+		if err := rows.Scan(&tombstone.Namespace, &revision, &tombstone.DeletedAt, &tombstone.ExpiresAt); err != nil {
+			return nil, fmt.Errorf(errReadNamespaceTombstones, err)
+		}
+
+		tombstone.Revision, err = pgd.RevisionFromString(revision)
+		if err != nil {
+			return nil, fmt.Errorf(errReadNamespaceTombstones, err)
+		}
+		tombstones = append(tombstones, tombstone)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf(errReadNamespaceTombstones, err)
+	}
+	return tombstones, nil
+}
+
+var _ common.NamespaceTombstoneStore = &pgDatastore{}
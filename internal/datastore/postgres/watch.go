@@ -7,6 +7,8 @@ import (
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgtype"
+	"github.com/shopspring/decimal"
 	"google.golang.org/protobuf/types/known/structpb"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
@@ -23,12 +25,14 @@ var (
 	// xid8 is one of the last ~2 billion transaction IDs generated. We should be garbage
 	// collecting these transactions long before we get to that point.
 	newRevisionsQuery = fmt.Sprintf(`
-	SELECT %[1]s from %[2]s
+	SELECT %[1]s, %[3]s from %[2]s
 	WHERE pg_xact_commit_timestamp(%[1]s::xid) > (
 		SELECT pg_xact_commit_timestamp(%[1]s::xid) FROM relation_tuple_transaction where %[1]s = $1
 	) AND %[1]s < pg_snapshot_xmin(pg_current_snapshot())
 	ORDER BY pg_xact_commit_timestamp(%[1]s::xid);
-`, colXID, tableTransaction)
+`, colXID, tableTransaction, colHLC)
+
+	queryXIDAtHLC = fmt.Sprintf("SELECT %s", fmt.Sprintf(transactionAtHLC, colXID, tableTransaction, colHLC, "$1"))
 
 	queryChanged = psql.Select(
 		colNamespace,
@@ -58,6 +62,12 @@ func (pgd *pgDatastore) Watch(
 		defer close(errs)
 
 		currentTxn := afterRevision.tx
+		if currentTxn.Status != pgtype.Present && afterRevision.hlc.Valid {
+			if err := pgd.dbpool.QueryRow(ctx, queryXIDAtHLC, afterRevision.hlc.Decimal).Scan(&currentTxn); err != nil {
+				errs <- fmt.Errorf("unable to find the transaction of revision %s: %w", afterRevision, err)
+				return
+			}
+		}
 
 		for {
 			newTxns, err := pgd.getNewRevisions(ctx, currentTxn)
@@ -88,7 +98,7 @@ func (pgd *pgDatastore) Watch(
 					return
 				}
 
-				currentTxn = revision
+				currentTxn = revision.tx
 			}
 
 			if len(newTxns) == 0 {
@@ -111,21 +121,27 @@ func (pgd *pgDatastore) Watch(
 func (pgd *pgDatastore) getNewRevisions(
 	ctx context.Context,
 	afterTX xid8,
-) ([]xid8, error) {
+) ([]postgresRevision, error) {
 	rows, err := pgd.dbpool.Query(context.Background(), newRevisionsQuery, afterTX)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load new revisions: %w", err)
 	}
 	defer rows.Close()
 
-	var ids []xid8
+	var ids []postgresRevision
 	for rows.Next() {
 		var nextXID xid8
-		if err := rows.Scan(&nextXID); err != nil {
+		var nextHLC decimal.NullDecimal
+		if err := rows.Scan(&nextXID, &nextHLC); err != nil {
 			return nil, fmt.Errorf("unable to decode new revision: %w", err)
 		}
 
-		ids = append(ids, nextXID)
+		// Transactions written under the other scheme are only known by their xid.
+		if pgd.revisionScheme != hlcRevisions {
+			nextHLC = decimal.NullDecimal{}
+		}
+
+		ids = append(ids, postgresRevision{tx: nextXID, xmin: noXmin, hlc: nextHLC})
 	}
 	if rows.Err() != nil {
 		return nil, fmt.Errorf("unable to load new revisions: %w", err)
@@ -134,10 +150,10 @@ func (pgd *pgDatastore) getNewRevisions(
 	return ids, nil
 }
 
-func (pgd *pgDatastore) loadChanges(ctx context.Context, revision xid8) (*datastore.RevisionChanges, error) {
+func (pgd *pgDatastore) loadChanges(ctx context.Context, revision postgresRevision) (*datastore.RevisionChanges, error) {
 	sql, args, err := queryChanged.Where(sq.Or{
-		sq.Eq{colCreatedXid: revision},
-		sq.Eq{colDeletedXid: revision},
+		sq.Eq{colCreatedXid: revision.tx},
+		sq.Eq{colDeletedXid: revision.tx},
 	}).ToSql()
 	if err != nil {
 		return nil, fmt.Errorf("unable to prepare changes SQL: %w", err)
@@ -184,10 +200,10 @@ func (pgd *pgDatastore) loadChanges(ctx context.Context, revision xid8) (*datast
 			}
 		}
 
-		if createdXID.Uint == revision.Uint {
-			tracked.AddChange(ctx, postgresRevision{revision, noXmin}, nextTuple, core.RelationTupleUpdate_TOUCH)
-		} else if deletedXID.Uint == revision.Uint {
-			tracked.AddChange(ctx, postgresRevision{revision, noXmin}, nextTuple, core.RelationTupleUpdate_DELETE)
+		if createdXID.Uint == revision.tx.Uint {
+			tracked.AddChange(ctx, revision, nextTuple, core.RelationTupleUpdate_TOUCH)
+		} else if deletedXID.Uint == revision.tx.Uint {
+			tracked.AddChange(ctx, revision, nextTuple, core.RelationTupleUpdate_DELETE)
 		}
 	}
 	if changes.Err() != nil {
@@ -197,7 +213,7 @@ func (pgd *pgDatastore) loadChanges(ctx context.Context, revision xid8) (*datast
 	reconciledChanges := tracked.AsRevisionChanges(pgd)
 	if len(reconciledChanges) == 0 {
 		return &datastore.RevisionChanges{
-			Revision: postgresRevision{revision, noXmin},
+			Revision: revision,
 		}, nil
 	}
 	return reconciledChanges[0], nil
@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// transformingBulkSource returns the relationships of a source after transforming them in
+// batches, as proxies transform the relationships they write, so that a bulk load through
+// a proxy writes the same relationships as WriteRelationships would.
+type transformingBulkSource struct {
+	source    common.BulkRelationshipSource
+	transform func(ctx context.Context, batch []*core.RelationTuple) ([]*core.RelationTuple, error)
+
+	pending []*core.RelationTuple
+	done    bool
+}
+
+func (s *transformingBulkSource) Next(ctx context.Context) (*core.RelationTuple, error) {
+	if len(s.pending) == 0 && !s.done {
+		batch := make([]*core.RelationTuple, 0, common.BulkLoadBatchSize)
+		for len(batch) < common.BulkLoadBatchSize {
+			tpl, err := s.source.Next(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if tpl == nil {
+				s.done = true
+				break
+			}
+			batch = append(batch, tpl)
+		}
+
+		if len(batch) > 0 {
+			transformed, err := s.transform(ctx, batch)
+			if err != nil {
+				return nil, err
+			}
+			s.pending = transformed
+		}
+	}
+
+	if len(s.pending) == 0 {
+		return nil, nil
+	}
+	tpl := s.pending[0]
+	s.pending = s.pending[1:]
+	return tpl, nil
+}
@@ -44,6 +44,10 @@ type nsCachingProxy struct {
 	readNsGroup singleflight.Group
 }
 
+func (p *nsCachingProxy) Unwrap() datastore.Datastore {
+	return p.Datastore
+}
+
 func (p *nsCachingProxy) SnapshotReader(rev datastore.Revision) datastore.Reader {
 	delegateReader := p.Datastore.SnapshotReader(rev)
 	return &nsCachingReader{delegateReader, sync.Mutex{}, rev, p}
@@ -74,6 +78,9 @@ func (r *nsCachingReader) ReadNamespace(
 	nsRevisionKey := nsName + "@" + r.rev.String()
 
 	loadedRaw, found := r.p.c.Get(nsRevisionKey)
+	if found && loadedRaw.(*cacheEntry).revisionUnknown {
+		found = false
+	}
 	if !found {
 		// We couldn't use the cached entry, load one
 		var err error
@@ -92,7 +99,7 @@ func (r *nsCachingReader) ReadNamespace(
 				return nil, err
 			}
 
-			entry := &cacheEntry{marshalledNsDef, updatedRev, err}
+			entry := &cacheEntry{marshalledNsDef, updatedRev, err, false}
 			r.p.c.Set(nsRevisionKey, entry, entry.Size())
 
 			// We have to call wait here or else Ristretto may not have the key
@@ -117,11 +124,63 @@ func (r *nsCachingReader) ReadNamespace(
 	return &def, loaded.updated, loaded.notFound
 }
 
+// LookupNamespaces returns the cached definitions and reads those not yet cached in a single
+// lookup, caching them in turn.
+func (r *nsCachingReader) LookupNamespaces(
+	ctx context.Context,
+	nsNames []string,
+) ([]*core.NamespaceDefinition, error) {
+	found := make([]*core.NamespaceDefinition, 0, len(nsNames))
+	missing := make([]string, 0, len(nsNames))
+	for _, nsName := range nsNames {
+		loadedRaw, ok := r.p.c.Get(nsName + "@" + r.rev.String())
+		if !ok {
+			missing = append(missing, nsName)
+			continue
+		}
+
+		loaded := loadedRaw.(*cacheEntry)
+		if loaded.notFound != nil {
+			continue
+		}
+
+		var def core.NamespaceDefinition
+		if err := def.UnmarshalVT(loaded.marshalledNsDef); err != nil {
+			return nil, err
+		}
+		found = append(found, &def)
+	}
+
+	if len(missing) == 0 {
+		return found, nil
+	}
+
+	loaded, err := r.Reader.LookupNamespaces(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, nsDef := range loaded {
+		marshalledNsDef, err := nsDef.MarshalVT()
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &cacheEntry{marshalledNsDef, datastore.NoRevision, nil, true}
+		r.p.c.Set(nsDef.Name+"@"+r.rev.String(), entry, entry.Size())
+	}
+	r.p.c.Wait()
+
+	return append(found, loaded...), nil
+}
+
 type nsCachingRWT struct {
 	datastore.ReadWriteTransaction
 	namespaceCache *sync.Map
 }
 
+func (rwt *nsCachingRWT) Unwrap() datastore.ReadWriteTransaction { return rwt.ReadWriteTransaction }
+
 type rwtCacheEntry struct {
 	loaded   *core.NamespaceDefinition
 	updated  datastore.Revision
@@ -151,6 +210,37 @@ func (rwt *nsCachingRWT) ReadNamespace(
 	return entry.loaded, entry.updated, entry.notFound
 }
 
+// LookupNamespaces returns the definitions already read in the transaction and reads the
+// remainder in a single lookup.
+func (rwt *nsCachingRWT) LookupNamespaces(
+	ctx context.Context,
+	nsNames []string,
+) ([]*core.NamespaceDefinition, error) {
+	found := make([]*core.NamespaceDefinition, 0, len(nsNames))
+	missing := make([]string, 0, len(nsNames))
+	for _, nsName := range nsNames {
+		untypedEntry, ok := rwt.namespaceCache.Load(nsName)
+		if !ok {
+			missing = append(missing, nsName)
+			continue
+		}
+
+		if entry := untypedEntry.(rwtCacheEntry); entry.notFound == nil {
+			found = append(found, entry.loaded)
+		}
+	}
+
+	if len(missing) == 0 {
+		return found, nil
+	}
+
+	loaded, err := rwt.ReadWriteTransaction.LookupNamespaces(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	return append(found, loaded...), nil
+}
+
 func (rwt *nsCachingRWT) WriteNamespaces(ctx context.Context, newConfigs ...*core.NamespaceDefinition) error {
 	if err := rwt.ReadWriteTransaction.WriteNamespaces(ctx, newConfigs...); err != nil {
 		return err
@@ -163,10 +253,26 @@ func (rwt *nsCachingRWT) WriteNamespaces(ctx context.Context, newConfigs ...*cor
 	return nil
 }
 
+func (rwt *nsCachingRWT) DeleteNamespaces(ctx context.Context, nsNames ...string) error {
+	if err := rwt.ReadWriteTransaction.DeleteNamespaces(ctx, nsNames...); err != nil {
+		return err
+	}
+
+	for _, nsName := range nsNames {
+		rwt.namespaceCache.Delete(nsName)
+	}
+
+	return nil
+}
+
 type cacheEntry struct {
 	marshalledNsDef []byte
 	updated         datastore.Revision
 	notFound        error
+
+	// revisionUnknown is set for entries cached by a lookup, which does not return the
+	// revision at which the definition was last written.
+	revisionUnknown bool
 }
 
 func (c *cacheEntry) Size() int64 {
@@ -136,6 +136,74 @@ func TestRWTNamespaceCaching(t *testing.T) {
 	rwtMock.AssertExpectations(t)
 }
 
+func TestSnapshotNamespaceLookupCaching(t *testing.T) {
+	dsMock := &proxy_test.MockDatastore{}
+
+	defA := ns.Namespace(nsA)
+	defB := ns.Namespace(nsB)
+
+	oneReader := &proxy_test.MockReader{}
+	dsMock.On("SnapshotReader", one).Return(oneReader)
+	oneReader.On("ReadNamespace", nsA).Return(defA, zero, nil).Once()
+	oneReader.On("LookupNamespaces", []string{nsB}).Return([]*core.NamespaceDefinition{defB}, nil).Once()
+	oneReader.On("ReadNamespace", nsB).Return(defB, old, nil).Once()
+
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds := NewCachingDatastoreProxy(dsMock, DatastoreProxyTestCache(t))
+
+	_, _, err := ds.SnapshotReader(one).ReadNamespace(ctx, nsA)
+	require.NoError(err)
+
+	// Only the namespace not already cached is looked up.
+	found, err := ds.SnapshotReader(one).LookupNamespaces(ctx, []string{nsA, nsB})
+	require.NoError(err)
+	require.Len(found, 2)
+
+	// Both are now cached.
+	found, err = ds.SnapshotReader(one).LookupNamespaces(ctx, []string{nsA, nsB})
+	require.NoError(err)
+	require.Len(found, 2)
+
+	// The lookup did not return the revision at which nsB was written, so it is read again.
+	_, updatedB, err := ds.SnapshotReader(one).ReadNamespace(ctx, nsB)
+	require.NoError(err)
+	require.True(old.Equal(updatedB))
+
+	dsMock.AssertExpectations(t)
+	oneReader.AssertExpectations(t)
+}
+
+func TestRWTNamespaceLookupCaching(t *testing.T) {
+	dsMock := &proxy_test.MockDatastore{}
+	rwtMock := &proxy_test.MockReadWriteTransaction{}
+
+	require := require.New(t)
+
+	dsMock.On("ReadWriteTx").Return(rwtMock, one, nil).Once()
+	rwtMock.On("ReadNamespace", nsA).Return(ns.Namespace(nsA), zero, nil).Once()
+	rwtMock.On("LookupNamespaces", []string{nsB}).Return([]*core.NamespaceDefinition{ns.Namespace(nsB)}, nil).Once()
+
+	ctx := context.Background()
+
+	ds := NewCachingDatastoreProxy(dsMock, nil)
+
+	_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		_, _, err := rwt.ReadNamespace(ctx, nsA)
+		require.NoError(err)
+
+		found, err := rwt.LookupNamespaces(ctx, []string{nsA, nsB})
+		require.NoError(err)
+		require.Len(found, 2)
+		return nil
+	})
+	require.NoError(err)
+
+	dsMock.AssertExpectations(t)
+	rwtMock.AssertExpectations(t)
+}
+
 func TestRWTNamespaceCacheWithWrites(t *testing.T) {
 	dsMock := &proxy_test.MockDatastore{}
 	rwtMock := &proxy_test.MockReadWriteTransaction{}
@@ -172,6 +240,14 @@ func TestRWTNamespaceCacheWithWrites(t *testing.T) {
 		require.NotNil(def)
 		require.NoError(err)
 
+		// Delete nsA and we should flow through to the mock again
+		rwtMock.On("DeleteNamespaces", nsA).Return(nil).Once()
+		require.NoError(rwt.DeleteNamespaces(ctx, nsA))
+
+		rwtMock.On("ReadNamespace", nsA).Return(nil, zero, notFoundErr).Once()
+		_, _, err = rwt.ReadNamespace(ctx, nsA)
+		require.ErrorIs(err, notFoundErr)
+
 		return nil
 	})
 	require.True(one.Equal(rev))
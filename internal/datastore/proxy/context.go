@@ -70,12 +70,18 @@ func (p *ctxProxy) Statistics(ctx context.Context) (datastore.Stats, error) {
 	return p.delegate.Statistics(SeparateContextWithTracing(ctx))
 }
 
+func (p *ctxProxy) UniqueID(ctx context.Context) (string, error) {
+	return p.delegate.UniqueID(SeparateContextWithTracing(ctx))
+}
+
 func (p *ctxProxy) IsReady(ctx context.Context) (bool, error) {
 	return p.delegate.IsReady(SeparateContextWithTracing(ctx))
 }
 
 func (p *ctxProxy) Close() error { return p.delegate.Close() }
 
+func (p *ctxProxy) Unwrap() datastore.Datastore { return p.delegate }
+
 func (p *ctxProxy) SnapshotReader(rev datastore.Revision) datastore.Reader {
 	delegateReader := p.delegate.SnapshotReader(rev)
 	return &ctxReader{delegateReader}
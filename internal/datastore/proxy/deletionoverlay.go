@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+type deletionOverlayDatastore struct {
+	datastore.Datastore
+	deleted datastore.RelationshipsFilter
+}
+
+// NewDeletionOverlayDatastore creates a proxy whose readers read the schema and the
+// relationships of the delegate datastore, except for the relationships matching the
+// filter, as if they had been deleted. Write operations are disabled, as the relationships
+// read are not those stored.
+func NewDeletionOverlayDatastore(delegate datastore.Datastore, deleted datastore.RelationshipsFilter) datastore.Datastore {
+	return deletionOverlayDatastore{Datastore: NewReadonlyDatastore(delegate), deleted: deleted}
+}
+
+func (dod deletionOverlayDatastore) Unwrap() datastore.Datastore {
+	return dod.Datastore
+}
+
+func (dod deletionOverlayDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return deletionOverlayReader{dod.Datastore.SnapshotReader(rev), dod.deleted}
+}
+
+type deletionOverlayReader struct {
+	datastore.Reader
+	deleted datastore.RelationshipsFilter
+}
+
+func (dor deletionOverlayReader) QueryRelationships(ctx context.Context, filter datastore.RelationshipsFilter, opts ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	it, err := dor.Reader.QueryRelationships(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &skippingIterator{delegate: it, skip: dor.deleted.Test}, nil
+}
+
+func (dor deletionOverlayReader) ReverseQueryRelationships(ctx context.Context, subjectsFilter datastore.SubjectsFilter, opts ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	it, err := dor.Reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &skippingIterator{delegate: it, skip: dor.deleted.Test}, nil
+}
+
+// skippingIterator iterates the relationships of its delegate which are not skipped.
+type skippingIterator struct {
+	delegate datastore.RelationshipIterator
+	skip     func(*core.RelationTuple) bool
+}
+
+func (i *skippingIterator) Next() *core.RelationTuple {
+	for next := i.delegate.Next(); next != nil; next = i.delegate.Next() {
+		if !i.skip(next) {
+			return next
+		}
+	}
+	return nil
+}
+
+func (i *skippingIterator) Err() error { return i.delegate.Err() }
+
+func (i *skippingIterator) Close() { i.delegate.Close() }
@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestDeletionOverlay(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	revision, err := rawDS.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		if err := rwt.WriteNamespaces(ctx, ns.Namespace("user"), ns.Namespace("document", ns.Relation("viewer", nil))); err != nil {
+			return err
+		}
+		return rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{
+			tuple.Create(tuple.MustParse("document:first#viewer@user:tom")),
+			tuple.Create(tuple.MustParse("document:first#viewer@user:sarah")),
+			tuple.Create(tuple.MustParse("document:second#viewer@user:tom")),
+		})
+	})
+	require.NoError(err)
+
+	ds := NewDeletionOverlayDatastore(rawDS, datastore.RelationshipsFilter{
+		ResourceType:        "document",
+		OptionalResourceIds: []string{"first"},
+		OptionalSubjectsFilter: &datastore.SubjectsFilter{
+			SubjectType:        "user",
+			OptionalSubjectIds: []string{"tom"},
+		},
+	})
+	reader := ds.SnapshotReader(revision)
+
+	read := func(it datastore.RelationshipIterator, err error) []string {
+		require.NoError(err)
+		defer it.Close()
+
+		var found []string
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			found = append(found, tuple.String(tpl))
+		}
+		require.NoError(it.Err())
+		return found
+	}
+
+	// Relationships matching the filter are not read.
+	require.ElementsMatch([]string{
+		"document:first#viewer@user:sarah",
+		"document:second#viewer@user:tom",
+	}, read(reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})))
+
+	require.Equal([]string{"document:second#viewer@user:tom"},
+		read(reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{SubjectType: "user", OptionalSubjectIds: []string{"tom"}})))
+
+	_, _, err = reader.ReadNamespace(ctx, "document")
+	require.NoError(err)
+
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, tuple.MustParse("document:third#viewer@user:tom"))
+	require.ErrorAs(err, &datastore.ErrReadOnly{})
+}
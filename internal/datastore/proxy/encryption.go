@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/encryption"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// NewEncryptingProxy creates a proxy which encrypts the caveat contexts of the
+// relationships written to the delegate datastore, and decrypts them when they are read
+// or watched.
+func NewEncryptingProxy(delegate datastore.Datastore, encryptor *encryption.Encryptor) datastore.Datastore {
+	return &encryptingProxy{Datastore: delegate, encryptor: encryptor}
+}
+
+type encryptingProxy struct {
+	datastore.Datastore
+	encryptor *encryption.Encryptor
+}
+
+func (p *encryptingProxy) Unwrap() datastore.Datastore {
+	return p.Datastore
+}
+
+func (p *encryptingProxy) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return &encryptingReader{p.Datastore.SnapshotReader(rev), p.encryptor}
+}
+
+func (p *encryptingProxy) ReadWriteTx(ctx context.Context, f datastore.TxUserFunc) (datastore.Revision, error) {
+	return p.Datastore.ReadWriteTx(ctx, func(delegateRWT datastore.ReadWriteTransaction) error {
+		return f(&encryptingRWT{delegateRWT, &encryptingReader{delegateRWT, p.encryptor}})
+	})
+}
+
+func (p *encryptingProxy) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+	delegateChanges, delegateErrs := p.Datastore.Watch(ctx, afterRevision)
+	return mapWatchedRelationships(ctx, delegateChanges, delegateErrs, p.encryptor.DecryptRelationship)
+}
+
+// mapWatchedRelationships maps the relationships of the changes watched, stopping with
+// the first error returned by the mapping.
+func mapWatchedRelationships(
+	ctx context.Context,
+	delegateChanges <-chan *datastore.RevisionChanges,
+	delegateErrs <-chan error,
+	mapping func(context.Context, *core.RelationTuple) (*core.RelationTuple, error),
+) (<-chan *datastore.RevisionChanges, <-chan error) {
+	changes := make(chan *datastore.RevisionChanges, cap(delegateChanges))
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(changes)
+		defer close(errs)
+
+		for {
+			select {
+			case revChanges, ok := <-delegateChanges:
+				if !ok {
+					return
+				}
+
+				mapped := make([]*core.RelationTupleUpdate, 0, len(revChanges.Changes))
+				for _, change := range revChanges.Changes {
+					tpl, err := mapping(ctx, change.Tuple)
+					if err != nil {
+						errs <- err
+						return
+					}
+					mapped = append(mapped, &core.RelationTupleUpdate{Operation: change.Operation, Tuple: tpl})
+				}
+
+				select {
+				case changes <- &datastore.RevisionChanges{Revision: revChanges.Revision, Changes: mapped}:
+				case <-ctx.Done():
+					errs <- datastore.NewWatchCanceledErr()
+					return
+				}
+
+			case err, ok := <-delegateErrs:
+				if ok {
+					errs <- err
+				}
+				return
+			}
+		}
+	}()
+
+	return changes, errs
+}
+
+type encryptingReader struct {
+	datastore.Reader
+	encryptor *encryption.Encryptor
+}
+
+func (r *encryptingReader) QueryRelationships(ctx context.Context, filter datastore.RelationshipsFilter, opts ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	it, err := r.Reader.QueryRelationships(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &mappingIterator{ctx: ctx, delegate: it, mapping: r.encryptor.DecryptRelationship}, nil
+}
+
+func (r *encryptingReader) ReverseQueryRelationships(ctx context.Context, subjectsFilter datastore.SubjectsFilter, opts ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	it, err := r.Reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &mappingIterator{ctx: ctx, delegate: it, mapping: r.encryptor.DecryptRelationship}, nil
+}
+
+// mappingIterator maps the relationships it iterates, stopping at the first one which
+// cannot be mapped.
+type mappingIterator struct {
+	ctx      context.Context
+	delegate datastore.RelationshipIterator
+	mapping  func(context.Context, *core.RelationTuple) (*core.RelationTuple, error)
+	err      error
+}
+
+func (i *mappingIterator) Next() *core.RelationTuple {
+	if i.err != nil {
+		return nil
+	}
+
+	next := i.delegate.Next()
+	if next == nil {
+		return nil
+	}
+
+	mapped, err := i.mapping(i.ctx, next)
+	if err != nil {
+		i.err = err
+		return nil
+	}
+	return mapped
+}
+
+func (i *mappingIterator) Err() error {
+	if i.err != nil {
+		return i.err
+	}
+	return i.delegate.Err()
+}
+
+func (i *mappingIterator) Close() { i.delegate.Close() }
+
+type encryptingRWT struct {
+	datastore.ReadWriteTransaction
+	reader *encryptingReader
+}
+
+func (rwt *encryptingRWT) Unwrap() datastore.ReadWriteTransaction { return rwt.ReadWriteTransaction }
+
+func (rwt *encryptingRWT) QueryRelationships(ctx context.Context, filter datastore.RelationshipsFilter, opts ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	return rwt.reader.QueryRelationships(ctx, filter, opts...)
+}
+
+func (rwt *encryptingRWT) ReverseQueryRelationships(ctx context.Context, subjectsFilter datastore.SubjectsFilter, opts ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	return rwt.reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+}
+
+// WriteRelationships encrypts the caveat contexts of the relationships created and
+// touched. Those of deleted relationships are left as-is, as deletes match regardless.
+func (rwt *encryptingRWT) WriteRelationships(ctx context.Context, mutations []*core.RelationTupleUpdate) error {
+	written := make([]*core.RelationTuple, 0, len(mutations))
+	for _, mutation := range mutations {
+		if mutation.Operation != core.RelationTupleUpdate_DELETE {
+			written = append(written, mutation.Tuple)
+		}
+	}
+
+	encrypted, err := rwt.reader.encryptor.EncryptRelationships(ctx, written)
+	if err != nil {
+		return err
+	}
+
+	encryptedMutations := make([]*core.RelationTupleUpdate, 0, len(mutations))
+	for _, mutation := range mutations {
+		if mutation.Operation != core.RelationTupleUpdate_DELETE {
+			mutation = &core.RelationTupleUpdate{Operation: mutation.Operation, Tuple: encrypted[0]}
+			encrypted = encrypted[1:]
+		}
+		encryptedMutations = append(encryptedMutations, mutation)
+	}
+	return rwt.ReadWriteTransaction.WriteRelationships(ctx, encryptedMutations)
+}
+
+// BulkLoad encrypts the caveat contexts of the relationships loaded, a batch at a time.
+func (rwt *encryptingRWT) BulkLoad(ctx context.Context, source common.BulkRelationshipSource) (uint64, error) {
+	return common.BulkLoad(ctx, rwt.ReadWriteTransaction, &transformingBulkSource{
+		source:    source,
+		transform: rwt.reader.encryptor.EncryptRelationships,
+	})
+}
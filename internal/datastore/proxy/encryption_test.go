@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/encryption"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestEncryptingProxy(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	keyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	require.NoError(err)
+	ds := NewEncryptingProxy(rawDS, encryption.NewEncryptor(keyring))
+
+	caveatContext, err := structpb.NewStruct(map[string]any{"ip": "10.0.0.1"})
+	require.NoError(err)
+	tpl := tuple.MustParse("document:first#viewer@user:tom")
+	tpl.Caveat = &core.ContextualizedCaveat{CaveatName: "somecaveat", Context: caveatContext}
+
+	head, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	changes, errs := ds.Watch(ctx, head)
+
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{tuple.Create(tpl)})
+	})
+	require.NoError(err)
+
+	read := func(reader datastore.Reader) *core.RelationTuple {
+		iter, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})
+		require.NoError(err)
+		defer iter.Close()
+
+		found := iter.Next()
+		require.NotNil(found)
+		require.Nil(iter.Next())
+		require.NoError(iter.Err())
+		return found
+	}
+
+	stored := read(rawDS.SnapshotReader(revision))
+	require.Equal(tuple.String(tpl), tuple.String(stored))
+	require.NotContains(stored.Caveat.Context.String(), "10.0.0.1")
+
+	require.True(tpl.EqualVT(read(ds.SnapshotReader(revision))))
+
+	select {
+	case change := <-changes:
+		require.Len(change.Changes, 1)
+		require.True(tpl.EqualVT(change.Changes[0].Tuple))
+	case err := <-errs:
+		require.NoError(err)
+	}
+
+	// Contexts which cannot be decrypted fail the read rather than being returned.
+	otherKeyring, err := encryption.NewKeyring("k2", map[string][]byte{"k2": bytes.Repeat([]byte{2}, 32)})
+	require.NoError(err)
+	iter, err := NewEncryptingProxy(rawDS, encryption.NewEncryptor(otherKeyring)).SnapshotReader(revision).
+		QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})
+	require.NoError(err)
+	defer iter.Close()
+	require.Nil(iter.Next())
+	require.ErrorIs(iter.Err(), encryption.ErrDecryptionFailed)
+}
+
+func TestEncryptingProxyBulkLoad(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	keyring, err := encryption.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	require.NoError(err)
+	ds := NewEncryptingProxy(rawDS, encryption.NewEncryptor(keyring))
+
+	caveatContext, err := structpb.NewStruct(map[string]any{"ip": "10.0.0.1"})
+	require.NoError(err)
+	tpl := tuple.MustParse("document:first#viewer@user:tom")
+	tpl.Caveat = &core.ContextualizedCaveat{CaveatName: "somecaveat", Context: caveatContext}
+
+	var loaded uint64
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		var err error
+		loaded, err = common.BulkLoad(ctx, rwt, &sliceBulkSource{tpl})
+		return err
+	})
+	require.NoError(err)
+	require.Equal(uint64(1), loaded)
+
+	// Bulk loaded contexts are encrypted as written ones are.
+	iter, err := rawDS.SnapshotReader(revision).QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})
+	require.NoError(err)
+	defer iter.Close()
+
+	stored := iter.Next()
+	require.NotNil(stored)
+	require.NotContains(stored.Caveat.Context.String(), "10.0.0.1")
+}
+
+// sliceBulkSource is a BulkRelationshipSource of the relationships of a slice.
+type sliceBulkSource []*core.RelationTuple
+
+func (s *sliceBulkSource) Next(_ context.Context) (*core.RelationTuple, error) {
+	if len(*s) == 0 {
+		return nil, nil
+	}
+	tpl := (*s)[0]
+	*s = (*s)[1:]
+	return tpl, nil
+}
@@ -0,0 +1,290 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+var (
+	// ErrInjectedSerializationFailure is returned in place of running a read-write
+	// transaction, as a datastore would when the transaction conflicted with another.
+	ErrInjectedSerializationFailure = errors.New("injected serialization failure")
+
+	// ErrInjectedPartialWrite is returned after a read-write transaction has been
+	// committed, as a datastore would when the connection was lost before the commit was
+	// acknowledged.
+	ErrInjectedPartialWrite = errors.New("injected partial write: transaction may have been committed")
+)
+
+// recentRevisionCount is the number of revisions remembered from which stale revisions
+// are chosen.
+const recentRevisionCount = 16
+
+// FaultPolicy configures the faults injected by a fault injector. Each probability is in
+// the range [0, 1] and is evaluated independently for every operation.
+type FaultPolicy struct {
+	// Seed seeds the choice of faults, so that a failing sequence can be reproduced.
+	Seed int64
+
+	// Latency is added before every operation with probability LatencyProbability, along
+	// with a random amount of up to LatencyJitter.
+	Latency            time.Duration
+	LatencyJitter      time.Duration
+	LatencyProbability float64
+
+	// SerializationFailureProbability is the probability that a read-write transaction
+	// fails with ErrInjectedSerializationFailure without being run.
+	SerializationFailureProbability float64
+
+	// PartialWriteProbability is the probability that a read-write transaction is
+	// committed, but fails with ErrInjectedPartialWrite.
+	PartialWriteProbability float64
+
+	// StaleRevisionProbability is the probability that OptimizedRevision or HeadRevision
+	// returns a revision previously returned by the datastore, rather than the current one.
+	StaleRevisionProbability float64
+}
+
+// NewFaultInjector creates a new datastore proxy which injects latency, serialization
+// failures, partial writes and stale revisions according to the policy, for use in chaos
+// testing the retry and consistency handling of callers.
+func NewFaultInjector(delegate datastore.Datastore, policy FaultPolicy) datastore.Datastore {
+	return &faultInjector{
+		Datastore: delegate,
+		policy:    policy,
+		rnd:       rand.New(rand.NewSource(policy.Seed)),
+	}
+}
+
+type faultInjector struct {
+	datastore.Datastore
+	policy FaultPolicy
+
+	sync.Mutex
+	rnd    *rand.Rand
+	recent []datastore.Revision
+}
+
+func (fi *faultInjector) Unwrap() datastore.Datastore {
+	return fi.Datastore
+}
+
+// roll returns true with the given probability.
+func (fi *faultInjector) roll(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+
+	fi.Lock()
+	defer fi.Unlock()
+	return fi.rnd.Float64() < probability
+}
+
+// delay sleeps for the injected latency, if any, returning early if the context is
+// canceled.
+func (fi *faultInjector) delay(ctx context.Context) error {
+	if !fi.roll(fi.policy.LatencyProbability) {
+		return nil
+	}
+
+	latency := fi.policy.Latency
+	if fi.policy.LatencyJitter > 0 {
+		fi.Lock()
+		latency += time.Duration(fi.rnd.Int63n(int64(fi.policy.LatencyJitter)))
+		fi.Unlock()
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// revision records a revision returned by the delegate and, with the configured
+// probability, replaces it with one recorded earlier.
+func (fi *faultInjector) revision(rev datastore.Revision) datastore.Revision {
+	stale := fi.roll(fi.policy.StaleRevisionProbability)
+
+	fi.Lock()
+	defer fi.Unlock()
+
+	var chosen datastore.Revision
+	if stale && len(fi.recent) > 0 {
+		chosen = fi.recent[fi.rnd.Intn(len(fi.recent))]
+	}
+
+	if len(fi.recent) == recentRevisionCount {
+		fi.recent = fi.recent[1:]
+	}
+	fi.recent = append(fi.recent, rev)
+
+	if chosen != nil && chosen.LessThan(rev) {
+		return chosen
+	}
+	return rev
+}
+
+func (fi *faultInjector) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return &faultInjectorReader{fi.Datastore.SnapshotReader(rev), fi}
+}
+
+func (fi *faultInjector) ReadWriteTx(ctx context.Context, f datastore.TxUserFunc) (datastore.Revision, error) {
+	if err := fi.delay(ctx); err != nil {
+		return datastore.NoRevision, err
+	}
+
+	if fi.roll(fi.policy.SerializationFailureProbability) {
+		return datastore.NoRevision, ErrInjectedSerializationFailure
+	}
+
+	rev, err := fi.Datastore.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return f(&faultInjectorRWT{&faultInjectorReader{rwt, fi}, rwt})
+	})
+	if err != nil {
+		return rev, err
+	}
+
+	if fi.roll(fi.policy.PartialWriteProbability) {
+		return datastore.NoRevision, ErrInjectedPartialWrite
+	}
+	return rev, nil
+}
+
+func (fi *faultInjector) OptimizedRevision(ctx context.Context) (datastore.Revision, error) {
+	if err := fi.delay(ctx); err != nil {
+		return datastore.NoRevision, err
+	}
+
+	rev, err := fi.Datastore.OptimizedRevision(ctx)
+	if err != nil {
+		return rev, err
+	}
+	return fi.revision(rev), nil
+}
+
+func (fi *faultInjector) HeadRevision(ctx context.Context) (datastore.Revision, error) {
+	if err := fi.delay(ctx); err != nil {
+		return datastore.NoRevision, err
+	}
+
+	rev, err := fi.Datastore.HeadRevision(ctx)
+	if err != nil {
+		return rev, err
+	}
+	return fi.revision(rev), nil
+}
+
+func (fi *faultInjector) CheckRevision(ctx context.Context, revision datastore.Revision) error {
+	if err := fi.delay(ctx); err != nil {
+		return err
+	}
+	return fi.Datastore.CheckRevision(ctx, revision)
+}
+
+type faultInjectorReader struct {
+	delegate datastore.Reader
+	fi       *faultInjector
+}
+
+func (r *faultInjectorReader) ReadCaveatByName(ctx context.Context, name string) (*core.CaveatDefinition, datastore.Revision, error) {
+	if err := r.fi.delay(ctx); err != nil {
+		return nil, datastore.NoRevision, err
+	}
+	return r.delegate.ReadCaveatByName(ctx, name)
+}
+
+func (r *faultInjectorReader) ListCaveats(ctx context.Context, caveatNamesForFiltering ...string) ([]*core.CaveatDefinition, error) {
+	if err := r.fi.delay(ctx); err != nil {
+		return nil, err
+	}
+	return r.delegate.ListCaveats(ctx, caveatNamesForFiltering...)
+}
+
+func (r *faultInjectorReader) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefinition, error) {
+	if err := r.fi.delay(ctx); err != nil {
+		return nil, err
+	}
+	return r.delegate.ListNamespaces(ctx)
+}
+
+func (r *faultInjectorReader) LookupNamespaces(ctx context.Context, nsNames []string) ([]*core.NamespaceDefinition, error) {
+	if err := r.fi.delay(ctx); err != nil {
+		return nil, err
+	}
+	return r.delegate.LookupNamespaces(ctx, nsNames)
+}
+
+func (r *faultInjectorReader) ReadNamespace(ctx context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
+	if err := r.fi.delay(ctx); err != nil {
+		return nil, datastore.NoRevision, err
+	}
+	return r.delegate.ReadNamespace(ctx, nsName)
+}
+
+func (r *faultInjectorReader) QueryRelationships(ctx context.Context, filter datastore.RelationshipsFilter, options ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	if err := r.fi.delay(ctx); err != nil {
+		return nil, err
+	}
+	return r.delegate.QueryRelationships(ctx, filter, options...)
+}
+
+func (r *faultInjectorReader) ReverseQueryRelationships(ctx context.Context, subjectFilter datastore.SubjectsFilter, options ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	if err := r.fi.delay(ctx); err != nil {
+		return nil, err
+	}
+	return r.delegate.ReverseQueryRelationships(ctx, subjectFilter, options...)
+}
+
+// faultInjectorRWT delays reads within a transaction, but leaves writes to fail or
+// succeed as a whole when the transaction completes.
+type faultInjectorRWT struct {
+	*faultInjectorReader
+	datastore.ReadWriteTransaction
+}
+
+func (rwt *faultInjectorRWT) Unwrap() datastore.ReadWriteTransaction { return rwt.ReadWriteTransaction }
+
+func (rwt *faultInjectorRWT) ReadCaveatByName(ctx context.Context, name string) (*core.CaveatDefinition, datastore.Revision, error) {
+	return rwt.faultInjectorReader.ReadCaveatByName(ctx, name)
+}
+
+func (rwt *faultInjectorRWT) ListCaveats(ctx context.Context, caveatNamesForFiltering ...string) ([]*core.CaveatDefinition, error) {
+	return rwt.faultInjectorReader.ListCaveats(ctx, caveatNamesForFiltering...)
+}
+
+func (rwt *faultInjectorRWT) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefinition, error) {
+	return rwt.faultInjectorReader.ListNamespaces(ctx)
+}
+
+func (rwt *faultInjectorRWT) LookupNamespaces(ctx context.Context, nsNames []string) ([]*core.NamespaceDefinition, error) {
+	return rwt.faultInjectorReader.LookupNamespaces(ctx, nsNames)
+}
+
+func (rwt *faultInjectorRWT) ReadNamespace(ctx context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
+	return rwt.faultInjectorReader.ReadNamespace(ctx, nsName)
+}
+
+func (rwt *faultInjectorRWT) QueryRelationships(ctx context.Context, filter datastore.RelationshipsFilter, options ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	return rwt.faultInjectorReader.QueryRelationships(ctx, filter, options...)
+}
+
+func (rwt *faultInjectorRWT) ReverseQueryRelationships(ctx context.Context, subjectFilter datastore.SubjectsFilter, options ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	return rwt.faultInjectorReader.ReverseQueryRelationships(ctx, subjectFilter, options...)
+}
+
+var (
+	_ datastore.Datastore            = (*faultInjector)(nil)
+	_ datastore.Reader               = (*faultInjectorReader)(nil)
+	_ datastore.ReadWriteTransaction = (*faultInjectorRWT)(nil)
+)
@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func writeRelationship(ctx context.Context, ds datastore.Datastore, rel string) (datastore.Revision, error) {
+	return ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{tuple.Touch(tuple.MustParse(rel))})
+	})
+}
+
+func countRelationships(t *testing.T, ds datastore.Datastore) int {
+	ctx := context.Background()
+	rev, err := ds.HeadRevision(ctx)
+	require.NoError(t, err)
+
+	iter, err := ds.SnapshotReader(rev).QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})
+	require.NoError(t, err)
+	defer iter.Close()
+
+	count := 0
+	for rel := iter.Next(); rel != nil; rel = iter.Next() {
+		count++
+	}
+	require.NoError(t, iter.Err())
+	return count
+}
+
+func TestFaultInjectorWrites(t *testing.T) {
+	testCases := []struct {
+		name          string
+		policy        FaultPolicy
+		expectedErr   error
+		expectedCount int
+	}{
+		{"no faults", FaultPolicy{}, nil, 1},
+		{"serialization failure", FaultPolicy{SerializationFailureProbability: 1}, ErrInjectedSerializationFailure, 0},
+		{"partial write", FaultPolicy{PartialWriteProbability: 1}, ErrInjectedPartialWrite, 1},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			delegate, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(t, err)
+
+			ds := NewFaultInjector(delegate, tc.policy)
+			_, err = writeRelationship(context.Background(), ds, "document:first#viewer@user:tom")
+			require.ErrorIs(t, err, tc.expectedErr)
+
+			// The relationship is visible in the delegate if the transaction was committed,
+			// even if the write was reported as failed.
+			require.Equal(t, tc.expectedCount, countRelationships(t, delegate))
+		})
+	}
+}
+
+func TestFaultInjectorStaleRevisions(t *testing.T) {
+	ctx := context.Background()
+	delegate, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	ds := NewFaultInjector(delegate, FaultPolicy{StaleRevisionProbability: 1})
+	first, err := ds.HeadRevision(ctx)
+	require.NoError(t, err)
+
+	written, err := writeRelationship(ctx, delegate, "document:first#viewer@user:tom")
+	require.NoError(t, err)
+
+	// The only revision previously returned is older, so it is always chosen.
+	rev, err := ds.HeadRevision(ctx)
+	require.NoError(t, err)
+	require.True(t, rev.Equal(first))
+	require.True(t, rev.LessThan(written))
+}
+
+func TestFaultInjectorLatency(t *testing.T) {
+	delegate, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	ds := NewFaultInjector(delegate, FaultPolicy{
+		Latency:            20 * time.Millisecond,
+		LatencyJitter:      5 * time.Millisecond,
+		LatencyProbability: 1,
+	})
+
+	started := time.Now()
+	rev, err := ds.HeadRevision(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(started), 20*time.Millisecond)
+
+	// Injected latency gives way to cancelation.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ds.SnapshotReader(rev).ListNamespaces(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/internal/middleware/querystats"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
@@ -106,6 +107,14 @@ func (p *observableProxy) Statistics(ctx context.Context) (datastore.Stats, erro
 	return p.delegate.Statistics(ctx)
 }
 
+func (p *observableProxy) UniqueID(ctx context.Context) (string, error) {
+	var span trace.Span
+	ctx, span = tracer.Start(ctx, "UniqueID")
+	defer span.End()
+
+	return p.delegate.UniqueID(ctx)
+}
+
 func (p *observableProxy) IsReady(ctx context.Context) (bool, error) {
 	var span trace.Span
 	ctx, span = tracer.Start(ctx, "IsReady")
@@ -116,6 +125,8 @@ func (p *observableProxy) IsReady(ctx context.Context) (bool, error) {
 
 func (p *observableProxy) Close() error { return p.delegate.Close() }
 
+func (p *observableProxy) Unwrap() datastore.Datastore { return p.delegate }
+
 type observableReader struct{ delegate datastore.Reader }
 
 func (r *observableReader) ReadCaveatByName(ctx context.Context, name string) (*core.CaveatDefinition, datastore.Revision, error) {
@@ -169,31 +180,45 @@ func (r *observableReader) QueryRelationships(ctx context.Context, filter datast
 	var span trace.Span
 	ctx, span = tracer.Start(ctx, "QueryRelationships")
 
+	stats := querystats.FromContext(ctx)
+	stats.RelationshipsQueried()
+
 	iterator, err := r.delegate.QueryRelationships(ctx, filter, options...)
 	if err != nil {
 		return iterator, err
 	}
-	return observableRelationshipIterator{span, iterator}, nil
+	return observableRelationshipIterator{span, iterator, stats}, nil
 }
 
 type observableRelationshipIterator struct {
 	span     trace.Span
 	delegate datastore.RelationshipIterator
+	stats    *querystats.Stats
+}
+
+func (i observableRelationshipIterator) Next() *core.RelationTuple {
+	next := i.delegate.Next()
+	if next != nil {
+		i.stats.RelationshipRead()
+	}
+	return next
 }
 
-func (i observableRelationshipIterator) Next() *core.RelationTuple { return i.delegate.Next() }
-func (i observableRelationshipIterator) Err() error                { return i.delegate.Err() }
-func (i observableRelationshipIterator) Close()                    { i.span.End(); i.delegate.Close() }
+func (i observableRelationshipIterator) Err() error { return i.delegate.Err() }
+func (i observableRelationshipIterator) Close()     { i.span.End(); i.delegate.Close() }
 
 func (r *observableReader) ReverseQueryRelationships(ctx context.Context, subjectFilter datastore.SubjectsFilter, options ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
 	var span trace.Span
 	ctx, span = tracer.Start(ctx, "ReverseQueryRelationships")
 
+	stats := querystats.FromContext(ctx)
+	stats.RelationshipsQueried()
+
 	iterator, err := r.delegate.ReverseQueryRelationships(ctx, subjectFilter, options...)
 	if err != nil {
 		return iterator, err
 	}
-	return observableRelationshipIterator{span, iterator}, nil
+	return observableRelationshipIterator{span, iterator, stats}, nil
 }
 
 type observableRWT struct {
@@ -201,6 +226,8 @@ type observableRWT struct {
 	delegate datastore.ReadWriteTransaction
 }
 
+func (rwt *observableRWT) Unwrap() datastore.ReadWriteTransaction { return rwt.delegate }
+
 func (rwt *observableRWT) WriteCaveats(ctx context.Context, caveats []*core.CaveatDefinition) error {
 	caveatNames := make([]string, 0, len(caveats))
 	for _, caveat := range caveats {
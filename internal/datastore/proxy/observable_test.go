@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/middleware/querystats"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+func TestObservableQueryStatistics(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
+	reader := NewObservableDatastoreProxy(ds).SnapshotReader(revision)
+
+	read := func(ctx context.Context) int {
+		iter, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})
+		require.NoError(err)
+		defer iter.Close()
+
+		count := 0
+		for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+			count++
+		}
+		require.NoError(iter.Err())
+		return count
+	}
+
+	// Reads without statistics are not counted.
+	read(context.Background())
+
+	ctx, stats := querystats.ContextWithStats(context.Background())
+	count := read(ctx)
+	require.Greater(count, 0)
+
+	iter, err := reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{SubjectType: "user"})
+	require.NoError(err)
+	iter.Close()
+
+	summary := stats.Summary()
+	require.Equal(uint64(2), summary.RelationshipQueries)
+	require.Equal(uint64(count), summary.RelationshipsRead)
+}
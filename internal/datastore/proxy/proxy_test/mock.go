@@ -2,6 +2,7 @@ package proxy_test
 
 import (
 	"context"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/stretchr/testify/mock"
@@ -74,6 +75,46 @@ func (dm *MockDatastore) Statistics(ctx context.Context) (datastore.Stats, error
 	return args.Get(0).(datastore.Stats), args.Error(1)
 }
 
+func (dm *MockDatastore) UniqueID(ctx context.Context) (string, error) {
+	args := dm.Called()
+	return args.String(0), args.Error(1)
+}
+
+func (dm *MockDatastore) PinRevision(ctx context.Context, name string, revision datastore.Revision, expiresAt time.Time) error {
+	args := dm.Called(name, revision, expiresAt)
+	return args.Error(0)
+}
+
+func (dm *MockDatastore) ReleasePinnedRevision(ctx context.Context, name string) error {
+	args := dm.Called(name)
+	return args.Error(0)
+}
+
+func (dm *MockDatastore) AddSchemaVersion(ctx context.Context, revision datastore.Revision, schemaText, author string) (datastore.SchemaVersion, error) {
+	args := dm.Called(revision, schemaText, author)
+	return args.Get(0).(datastore.SchemaVersion), args.Error(1)
+}
+
+func (dm *MockDatastore) ListSchemaVersions(ctx context.Context) ([]datastore.SchemaVersion, error) {
+	args := dm.Called()
+	return args.Get(0).([]datastore.SchemaVersion), args.Error(1)
+}
+
+func (dm *MockDatastore) ReadSchemaVersion(ctx context.Context, version uint64) (datastore.SchemaVersion, error) {
+	args := dm.Called(version)
+	return args.Get(0).(datastore.SchemaVersion), args.Error(1)
+}
+
+func (dm *MockDatastore) SetNamespaceExperiment(ctx context.Context, namespace, experiment string, enabled bool) error {
+	args := dm.Called(namespace, experiment, enabled)
+	return args.Error(0)
+}
+
+func (dm *MockDatastore) ListNamespaceExperiments(ctx context.Context) ([]datastore.NamespaceExperiment, error) {
+	args := dm.Called()
+	return args.Get(0).([]datastore.NamespaceExperiment), args.Error(1)
+}
+
 func (dm *MockDatastore) Close() error {
 	args := dm.Called()
 	return args.Error(0)
@@ -143,7 +184,7 @@ func (dm *MockReader) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefi
 }
 
 func (dm *MockReader) LookupNamespaces(ctx context.Context, nsNames []string) ([]*core.NamespaceDefinition, error) {
-	args := dm.Called()
+	args := dm.Called(nsNames)
 	return args.Get(0).([]*core.NamespaceDefinition), args.Error(1)
 }
 
@@ -221,7 +262,7 @@ func (dm *MockReadWriteTransaction) ListNamespaces(ctx context.Context) ([]*core
 }
 
 func (dm *MockReadWriteTransaction) LookupNamespaces(ctx context.Context, nsNames []string) ([]*core.NamespaceDefinition, error) {
-	args := dm.Called()
+	args := dm.Called(nsNames)
 	return args.Get(0).([]*core.NamespaceDefinition), args.Error(1)
 }
 
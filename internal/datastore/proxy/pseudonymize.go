@@ -0,0 +1,384 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/pseudonym"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// maxResolvedPseudonyms bounds the number of reversed pseudonyms held in memory, to avoid
+// reading the sealed ID of every pseudonymized object read from the datastore.
+const maxResolvedPseudonyms = 10_000
+
+// NewPseudonymizingProxy creates a proxy which replaces the IDs of objects of the types
+// pseudonymized with their pseudonyms, both in the relationships written to the delegate
+// datastore and in the filters of those read, and reverses the pseudonyms of the
+// relationships read or watched. The delegate datastore, or one it wraps, must implement
+// common.PseudonymStore to hold the sealed IDs from which pseudonyms are reversed.
+func NewPseudonymizingProxy(delegate datastore.Datastore, pseudonymizer *pseudonym.Pseudonymizer) (datastore.Datastore, error) {
+	store, ok := datastore.UnwrapAs[common.PseudonymStore](delegate)
+	if !ok {
+		return nil, common.ErrPseudonymsUnsupported
+	}
+
+	return &pseudonymizingProxy{
+		Datastore: delegate,
+		resolver: &pseudonymResolver{
+			pseudonymizer: pseudonymizer,
+			store:         store,
+			resolved:      make(map[string]string),
+		},
+	}, nil
+}
+
+type pseudonymizingProxy struct {
+	datastore.Datastore
+	resolver *pseudonymResolver
+}
+
+func (p *pseudonymizingProxy) Unwrap() datastore.Datastore {
+	return p.Datastore
+}
+
+func (p *pseudonymizingProxy) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return &pseudonymizingReader{p.Datastore.SnapshotReader(rev), p.resolver}
+}
+
+func (p *pseudonymizingProxy) ReadWriteTx(ctx context.Context, f datastore.TxUserFunc) (datastore.Revision, error) {
+	return p.Datastore.ReadWriteTx(ctx, func(delegateRWT datastore.ReadWriteTransaction) error {
+		return f(&pseudonymizingRWT{delegateRWT, &pseudonymizingReader{delegateRWT, p.resolver}})
+	})
+}
+
+func (p *pseudonymizingProxy) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+	delegateChanges, delegateErrs := p.Datastore.Watch(ctx, afterRevision)
+	return mapWatchedRelationships(ctx, delegateChanges, delegateErrs, p.resolver.reverseRelationship)
+}
+
+type pseudonymizingReader struct {
+	datastore.Reader
+	resolver *pseudonymResolver
+}
+
+func (r *pseudonymizingReader) QueryRelationships(ctx context.Context, filter datastore.RelationshipsFilter, opts ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	pseudonymizer := r.resolver.pseudonymizer
+	if err := checkNoPrefix(pseudonymizer, filter.ResourceType, filter.OptionalResourceIDPrefix); err != nil {
+		return nil, err
+	}
+
+	filter.OptionalResourceIds = pseudonyms(pseudonymizer, filter.ResourceType, filter.OptionalResourceIds)
+	if filter.OptionalSubjectsFilter != nil {
+		subjectsFilter, err := pseudonymizeSubjectsFilter(pseudonymizer, *filter.OptionalSubjectsFilter)
+		if err != nil {
+			return nil, err
+		}
+		filter.OptionalSubjectsFilter = &subjectsFilter
+	}
+
+	queryOpts := options.NewQueryOptionsWithOptions(opts...)
+	if queryOpts.Sort != options.Unsorted && len(pseudonymizer.ObjectTypes()) > 0 {
+		// The delegate would sort the relationships by their pseudonyms rather than their IDs.
+		return nil, errors.New("relationships cannot be sorted when object IDs are pseudonymized")
+	}
+	if len(queryOpts.Usersets) > 0 {
+		usersets := make([]*core.ObjectAndRelation, 0, len(queryOpts.Usersets))
+		for _, userset := range queryOpts.Usersets {
+			usersets = append(usersets, &core.ObjectAndRelation{
+				Namespace: userset.Namespace,
+				ObjectId:  pseudonymizer.Pseudonym(userset.Namespace, userset.ObjectId),
+				Relation:  userset.Relation,
+			})
+		}
+		queryOpts.Usersets = usersets
+	}
+
+	it, err := r.Reader.QueryRelationships(ctx, filter, queryOpts.ToOption())
+	if err != nil {
+		return nil, err
+	}
+	return &mappingIterator{ctx: ctx, delegate: it, mapping: r.resolver.reverseRelationship}, nil
+}
+
+func (r *pseudonymizingReader) ReverseQueryRelationships(ctx context.Context, subjectsFilter datastore.SubjectsFilter, opts ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	subjectsFilter, err := pseudonymizeSubjectsFilter(r.resolver.pseudonymizer, subjectsFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	it, err := r.Reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &mappingIterator{ctx: ctx, delegate: it, mapping: r.resolver.reverseRelationship}, nil
+}
+
+type pseudonymizingRWT struct {
+	datastore.ReadWriteTransaction
+	reader *pseudonymizingReader
+}
+
+func (rwt *pseudonymizingRWT) Unwrap() datastore.ReadWriteTransaction {
+	return rwt.ReadWriteTransaction
+}
+
+func (rwt *pseudonymizingRWT) QueryRelationships(ctx context.Context, filter datastore.RelationshipsFilter, opts ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	return rwt.reader.QueryRelationships(ctx, filter, opts...)
+}
+
+func (rwt *pseudonymizingRWT) ReverseQueryRelationships(ctx context.Context, subjectsFilter datastore.SubjectsFilter, opts ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	return rwt.reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+}
+
+// WriteRelationships pseudonymizes the relationships of the mutations, storing the sealed
+// IDs of the pseudonyms of those created and touched before writing them. Sealed IDs left
+// by transactions which then fail are harmless, as they are only read for the pseudonyms
+// of relationships found.
+func (rwt *pseudonymizingRWT) WriteRelationships(ctx context.Context, mutations []*core.RelationTupleUpdate) error {
+	pseudonymizer := rwt.reader.resolver.pseudonymizer
+	sealed := make(map[string][]byte)
+	pseudonymized := make([]*core.RelationTupleUpdate, 0, len(mutations))
+	for _, mutation := range mutations {
+		tpl, sealedIDs, err := pseudonymizer.PseudonymizeRelationship(mutation.Tuple)
+		if err != nil {
+			return err
+		}
+		if mutation.Operation != core.RelationTupleUpdate_DELETE {
+			for pseudonym, sealedID := range sealedIDs {
+				sealed[pseudonym] = sealedID
+			}
+		}
+		pseudonymized = append(pseudonymized, &core.RelationTupleUpdate{Operation: mutation.Operation, Tuple: tpl})
+	}
+
+	if len(sealed) > 0 {
+		if err := rwt.reader.resolver.store.WritePseudonyms(ctx, sealed); err != nil {
+			return err
+		}
+	}
+	return rwt.ReadWriteTransaction.WriteRelationships(ctx, pseudonymized)
+}
+
+// BulkLoad pseudonymizes the relationships loaded, storing the sealed IDs of the
+// pseudonyms of each batch before it is loaded.
+func (rwt *pseudonymizingRWT) BulkLoad(ctx context.Context, source common.BulkRelationshipSource) (uint64, error) {
+	pseudonymizer := rwt.reader.resolver.pseudonymizer
+	return common.BulkLoad(ctx, rwt.ReadWriteTransaction, &transformingBulkSource{
+		source: source,
+		transform: func(ctx context.Context, batch []*core.RelationTuple) ([]*core.RelationTuple, error) {
+			sealed := make(map[string][]byte)
+			pseudonymized := make([]*core.RelationTuple, 0, len(batch))
+			for _, tpl := range batch {
+				pseudonymizedTpl, sealedIDs, err := pseudonymizer.PseudonymizeRelationship(tpl)
+				if err != nil {
+					return nil, err
+				}
+				for pseudonym, sealedID := range sealedIDs {
+					sealed[pseudonym] = sealedID
+				}
+				pseudonymized = append(pseudonymized, pseudonymizedTpl)
+			}
+
+			if len(sealed) > 0 {
+				if err := rwt.reader.resolver.store.WritePseudonyms(ctx, sealed); err != nil {
+					return nil, err
+				}
+			}
+			return pseudonymized, nil
+		},
+	})
+}
+
+func (rwt *pseudonymizingRWT) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
+	return rwt.ReadWriteTransaction.DeleteRelationships(ctx, pseudonymizeRelationshipFilter(rwt.reader.resolver.pseudonymizer, filter))
+}
+
+// SetRelationshipSources records the sources of the pseudonymized relationships, as
+// written by WriteRelationships, so that no cleartext ID is stored in the records. The
+// sealed IDs of their pseudonyms are stored by the write of the relationships.
+func (rwt *pseudonymizingRWT) SetRelationshipSources(ctx context.Context, source string, relationships []*core.RelationTuple) error {
+	tracker, err := rwt.sourceTracker()
+	if err != nil {
+		return err
+	}
+
+	pseudonymizer := rwt.reader.resolver.pseudonymizer
+	pseudonymized := make([]*core.RelationTuple, 0, len(relationships))
+	for _, tpl := range relationships {
+		pseudonymizedTpl, _, err := pseudonymizer.PseudonymizeRelationship(tpl)
+		if err != nil {
+			return err
+		}
+		pseudonymized = append(pseudonymized, pseudonymizedTpl)
+	}
+	return tracker.SetRelationshipSources(ctx, source, pseudonymized)
+}
+
+func (rwt *pseudonymizingRWT) ClearRelationshipSources(ctx context.Context, filter *v1.RelationshipFilter) error {
+	tracker, err := rwt.sourceTracker()
+	if err != nil {
+		return err
+	}
+	return tracker.ClearRelationshipSources(ctx, pseudonymizeRelationshipFilter(rwt.reader.resolver.pseudonymizer, filter))
+}
+
+func (rwt *pseudonymizingRWT) HasRelationshipSources(ctx context.Context) (bool, error) {
+	tracker, err := rwt.sourceTracker()
+	if err != nil {
+		return false, err
+	}
+	return tracker.HasRelationshipSources(ctx)
+}
+
+// RelationshipsFromSource returns the relationships recorded as written from the source,
+// with the original IDs of their pseudonyms.
+func (rwt *pseudonymizingRWT) RelationshipsFromSource(ctx context.Context, source string) ([]*core.RelationTuple, error) {
+	tracker, err := rwt.sourceTracker()
+	if err != nil {
+		return nil, err
+	}
+
+	relationships, err := tracker.RelationshipsFromSource(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	reversed := make([]*core.RelationTuple, 0, len(relationships))
+	for _, tpl := range relationships {
+		reversedTpl, err := rwt.reader.resolver.reverseRelationship(ctx, tpl)
+		if err != nil {
+			return nil, err
+		}
+		reversed = append(reversed, reversedTpl)
+	}
+	return reversed, nil
+}
+
+func (rwt *pseudonymizingRWT) sourceTracker() (common.RelationshipSourceTracker, error) {
+	tracker, ok := datastore.UnwrapTransactionAs[common.RelationshipSourceTracker](rwt.ReadWriteTransaction)
+	if !ok {
+		return nil, common.ErrRelationshipSourcesUnsupported
+	}
+	return tracker, nil
+}
+
+var _ common.RelationshipSourceTracker = &pseudonymizingRWT{}
+
+// pseudonymizeRelationshipFilter returns a copy of the filter matching the pseudonyms of
+// the IDs it matches.
+func pseudonymizeRelationshipFilter(pseudonymizer *pseudonym.Pseudonymizer, filter *v1.RelationshipFilter) *v1.RelationshipFilter {
+	filter = proto.Clone(filter).(*v1.RelationshipFilter)
+	if filter.OptionalResourceId != "" {
+		filter.OptionalResourceId = pseudonymizer.Pseudonym(filter.ResourceType, filter.OptionalResourceId)
+	}
+	if subjectFilter := filter.OptionalSubjectFilter; subjectFilter != nil && subjectFilter.OptionalSubjectId != "" {
+		subjectFilter.OptionalSubjectId = pseudonymizer.Pseudonym(subjectFilter.SubjectType, subjectFilter.OptionalSubjectId)
+	}
+	return filter
+}
+
+func pseudonyms(pseudonymizer *pseudonym.Pseudonymizer, objectType string, objectIDs []string) []string {
+	if len(objectIDs) == 0 || !pseudonymizer.Applies(objectType) {
+		return objectIDs
+	}
+
+	pseudonymized := make([]string, 0, len(objectIDs))
+	for _, objectID := range objectIDs {
+		pseudonymized = append(pseudonymized, pseudonymizer.Pseudonym(objectType, objectID))
+	}
+	return pseudonymized
+}
+
+func pseudonymizeSubjectsFilter(pseudonymizer *pseudonym.Pseudonymizer, filter datastore.SubjectsFilter) (datastore.SubjectsFilter, error) {
+	if err := checkNoPrefix(pseudonymizer, filter.SubjectType, filter.OptionalSubjectIDPrefix); err != nil {
+		return filter, err
+	}
+
+	filter.OptionalSubjectIds = pseudonyms(pseudonymizer, filter.SubjectType, filter.OptionalSubjectIds)
+	return filter, nil
+}
+
+// checkNoPrefix returns an error if the IDs of a pseudonymized type are matched by prefix,
+// since their pseudonyms do not share the prefixes of the IDs.
+func checkNoPrefix(pseudonymizer *pseudonym.Pseudonymizer, objectType string, prefix string) error {
+	if prefix != "" && pseudonymizer.Applies(objectType) {
+		return fmt.Errorf("object IDs of pseudonymized type `%s` cannot be matched by prefix", objectType)
+	}
+	return nil
+}
+
+// pseudonymResolver reverses the pseudonyms of relationships from their sealed IDs.
+type pseudonymResolver struct {
+	pseudonymizer *pseudonym.Pseudonymizer
+	store         common.PseudonymStore
+
+	lock     sync.Mutex
+	resolved map[string]string
+}
+
+// reverseRelationship returns the relationship with the original IDs of its pseudonyms,
+// leaving the relationship given unmodified.
+func (r *pseudonymResolver) reverseRelationship(ctx context.Context, tpl *core.RelationTuple) (*core.RelationTuple, error) {
+	resourcePseudonymized := r.isPseudonymized(tpl.ResourceAndRelation)
+	subjectPseudonymized := r.isPseudonymized(tpl.Subject)
+	if !resourcePseudonymized && !subjectPseudonymized {
+		return tpl, nil
+	}
+
+	reversed := tpl.CloneVT()
+	for _, onr := range []*core.ObjectAndRelation{reversed.ResourceAndRelation, reversed.Subject} {
+		if !r.isPseudonymized(onr) {
+			continue
+		}
+
+		objectID, err := r.resolve(ctx, onr.Namespace, onr.ObjectId)
+		if err != nil {
+			return nil, err
+		}
+		onr.ObjectId = objectID
+	}
+	return reversed, nil
+}
+
+func (r *pseudonymResolver) isPseudonymized(onr *core.ObjectAndRelation) bool {
+	return r.pseudonymizer.Applies(onr.Namespace) && pseudonym.IsPseudonym(onr.ObjectId)
+}
+
+func (r *pseudonymResolver) resolve(ctx context.Context, objectType, pseudonymized string) (string, error) {
+	r.lock.Lock()
+	objectID, ok := r.resolved[pseudonymized]
+	r.lock.Unlock()
+	if ok {
+		return objectID, nil
+	}
+
+	found, err := r.store.ReadPseudonyms(ctx, []string{pseudonymized})
+	if err != nil {
+		return "", err
+	}
+	sealed, ok := found[pseudonymized]
+	if !ok {
+		return "", fmt.Errorf("no object ID stored for pseudonym `%s` of type `%s`", pseudonymized, objectType)
+	}
+	objectID, err = r.pseudonymizer.Unseal(objectType, pseudonymized, sealed)
+	if err != nil {
+		return "", err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.resolved) >= maxResolvedPseudonyms {
+		r.resolved = make(map[string]string)
+	}
+	r.resolved[pseudonymized] = objectID
+	return objectID, nil
+}
@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/pseudonym"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestPseudonymizingProxy(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	pseudonymizer, err := pseudonym.NewPseudonymizer(bytes.Repeat([]byte{1}, 32), []string{"user"})
+	require.NoError(err)
+	ds, err := NewPseudonymizingProxy(rawDS, pseudonymizer)
+	require.NoError(err)
+
+	head, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	changes, errs := ds.Watch(ctx, head)
+
+	written := []*core.RelationTuple{
+		tuple.MustParse("document:first#viewer@user:tom"),
+		tuple.MustParse("document:first#viewer@user:*"),
+		tuple.MustParse("user:tom#manager@user:sarah"),
+	}
+	updates := make([]*core.RelationTupleUpdate, 0, len(written))
+	for _, tpl := range written {
+		updates = append(updates, tuple.Create(tpl))
+	}
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, updates)
+	})
+	require.NoError(err)
+
+	readAll := func(it datastore.RelationshipIterator, err error) []string {
+		require.NoError(err)
+		defer it.Close()
+
+		var found []string
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			found = append(found, tuple.String(tpl))
+		}
+		require.NoError(it.Err())
+		return found
+	}
+
+	// The datastore holds no cleartext ID of the pseudonymized type.
+	reader := rawDS.SnapshotReader(revision)
+	for _, stored := range append(
+		readAll(reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})),
+		readAll(reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "user"}))...,
+	) {
+		require.NotContains(stored, "tom")
+		require.NotContains(stored, "sarah")
+	}
+
+	reader = ds.SnapshotReader(revision)
+	require.ElementsMatch([]string{"document:first#viewer@user:tom", "document:first#viewer@user:*"},
+		readAll(reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})))
+	require.Equal([]string{"user:tom#manager@user:sarah"},
+		readAll(reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "user", OptionalResourceIds: []string{"tom"}})))
+	require.Equal([]string{"document:first#viewer@user:tom"},
+		readAll(reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+			SubjectType:        "user",
+			OptionalSubjectIds: []string{"tom"},
+			RelationFilter:     datastore.SubjectRelationFilter{}.WithEllipsisRelation(),
+		}, options.WithResRelation(&options.ResourceRelation{Namespace: "document", Relation: "viewer"}))))
+	require.Equal([]string{"document:first#viewer@user:tom"},
+		readAll(reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"},
+			options.WithUsersets(tuple.ParseSubjectONR("user:tom")))))
+
+	select {
+	case change := <-changes:
+		found := make([]string, 0, len(change.Changes))
+		for _, update := range change.Changes {
+			found = append(found, tuple.String(update.Tuple))
+		}
+		require.ElementsMatch([]string{
+			"document:first#viewer@user:tom",
+			"document:first#viewer@user:*",
+			"user:tom#manager@user:sarah",
+		}, found)
+	case err := <-errs:
+		require.NoError(err)
+	}
+
+	// Pseudonyms do not share the prefixes of the IDs they seal.
+	_, err = ds.SnapshotReader(revision).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:           "document",
+		OptionalSubjectsFilter: &datastore.SubjectsFilter{SubjectType: "user", OptionalSubjectIDPrefix: "to"},
+	})
+	require.ErrorContains(err, "cannot be matched by prefix")
+	require.ElementsMatch([]string{"document:first#viewer@user:tom", "document:first#viewer@user:*"},
+		readAll(ds.SnapshotReader(revision).QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document", OptionalResourceIDPrefix: "fir"})))
+
+	// Nor do they sort as the IDs they seal.
+	_, err = ds.SnapshotReader(revision).QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"}, options.WithSort(options.ByResource))
+	require.ErrorContains(err, "cannot be sorted")
+
+	// Deletes by ID match the pseudonymized relationships.
+	revision, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		if err := rwt.DeleteRelationships(ctx, &v1.RelationshipFilter{
+			ResourceType:          "document",
+			OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: "user", OptionalSubjectId: "tom"},
+		}); err != nil {
+			return err
+		}
+		return rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{tuple.Delete(written[2])})
+	})
+	require.NoError(err)
+	require.Equal([]string{"document:first#viewer@user:*"},
+		readAll(ds.SnapshotReader(revision).QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})))
+	require.Empty(readAll(ds.SnapshotReader(revision).QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "user"})))
+}
+
+func TestPseudonymizingProxyUnsupported(t *testing.T) {
+	pseudonymizer, err := pseudonym.NewPseudonymizer(bytes.Repeat([]byte{1}, 32), []string{"user"})
+	require.NoError(t, err)
+
+	_, err = NewPseudonymizingProxy(&proxy_test.MockDatastore{}, pseudonymizer)
+	require.ErrorIs(t, err, common.ErrPseudonymsUnsupported)
+}
+
+func TestPseudonymizingProxySources(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	pseudonymizer, err := pseudonym.NewPseudonymizer(bytes.Repeat([]byte{1}, 32), []string{"user"})
+	require.NoError(err)
+	ds, err := NewPseudonymizingProxy(rawDS, pseudonymizer)
+	require.NoError(err)
+
+	sourcesOf := func(ds datastore.Datastore) (found []string) {
+		_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+			tracker, ok := datastore.UnwrapTransactionAs[common.RelationshipSourceTracker](rwt)
+			require.True(ok)
+
+			relationships, err := tracker.RelationshipsFromSource(ctx, "idp")
+			found = nil
+			for _, tpl := range relationships {
+				found = append(found, tuple.String(tpl))
+			}
+			return err
+		})
+		require.NoError(err)
+		return found
+	}
+
+	written := tuple.MustParse("document:first#viewer@user:tom")
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		if err := rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{tuple.Create(written)}); err != nil {
+			return err
+		}
+
+		tracker, ok := datastore.UnwrapTransactionAs[common.RelationshipSourceTracker](rwt)
+		require.True(ok)
+		return tracker.SetRelationshipSources(ctx, "idp", []*core.RelationTuple{written})
+	})
+	require.NoError(err)
+
+	// The records hold the pseudonyms, and are reversed when read through the proxy.
+	stored := sourcesOf(rawDS)
+	require.Len(stored, 1)
+	require.NotContains(stored[0], "tom")
+	require.Equal([]string{"document:first#viewer@user:tom"}, sourcesOf(ds))
+
+	// Clearing by ID matches the pseudonymized records.
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		tracker, ok := datastore.UnwrapTransactionAs[common.RelationshipSourceTracker](rwt)
+		require.True(ok)
+		return tracker.ClearRelationshipSources(ctx, &v1.RelationshipFilter{
+			ResourceType:          "document",
+			OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: "user", OptionalSubjectId: "tom"},
+		})
+	})
+	require.NoError(err)
+	require.Empty(sourcesOf(rawDS))
+}
@@ -2,12 +2,18 @@ package proxy
 
 import (
 	"context"
+	"time"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/common/revisions"
 	"github.com/authzed/spicedb/pkg/datastore"
 )
 
 var errReadOnly = datastore.NewReadonlyErr()
 
+// roDatastore does not unwrap to its delegate, as the optional interfaces found by
+// unwrapping would write to it. It instead implements those interfaces itself, reading
+// from the delegate and failing every write.
 type roDatastore struct {
 	datastore.Datastore
 }
@@ -21,3 +27,153 @@ func NewReadonlyDatastore(delegate datastore.Datastore) datastore.Datastore {
 func (rd roDatastore) ReadWriteTx(context.Context, datastore.TxUserFunc) (datastore.Revision, error) {
 	return datastore.NoRevision, errReadOnly
 }
+
+// RevisionQuantization returns the quantization of the delegate, or a static one if it has
+// none.
+func (rd roDatastore) RevisionQuantization() *revisions.Quantization {
+	if quantized, ok := datastore.UnwrapAs[common.QuantizedDatastore](rd.Datastore); ok {
+		return quantized.RevisionQuantization()
+	}
+	return revisions.NewStaticQuantization(0)
+}
+
+// RevisionCommitTime returns the commit time of the revision reported by the delegate, if
+// any.
+func (rd roDatastore) RevisionCommitTime(rev datastore.Revision) (time.Time, bool) {
+	return common.RevisionCommitTime(rd.Datastore, rev)
+}
+
+// ListRelationshipResourceTypes lists the types of the delegate, or none if it cannot list
+// them, as if no types beyond those of the schema were stored.
+func (rd roDatastore) ListRelationshipResourceTypes(ctx context.Context, rev datastore.Revision) ([]string, error) {
+	if lister, ok := datastore.UnwrapAs[common.RelationshipTypeLister](rd.Datastore); ok {
+		return lister.ListRelationshipResourceTypes(ctx, rev)
+	}
+	return nil, nil
+}
+
+// FindMVCCAnomalies finds the anomalies of the delegate, or none if it cannot find them.
+func (rd roDatastore) FindMVCCAnomalies(ctx context.Context, limit uint64) ([]common.MVCCAnomaly, error) {
+	if repairer, ok := datastore.UnwrapAs[common.MVCCRepairer](rd.Datastore); ok {
+		return repairer.FindMVCCAnomalies(ctx, limit)
+	}
+	return nil, nil
+}
+
+func (rd roDatastore) DeleteMVCCAnomalies(context.Context, uint64) (int64, error) {
+	return 0, errReadOnly
+}
+
+func (rd roDatastore) CreateJob(context.Context, common.Job) error {
+	return errReadOnly
+}
+
+func (rd roDatastore) UpdateJob(context.Context, common.Job) error {
+	return errReadOnly
+}
+
+func (rd roDatastore) RequestJobCancellation(context.Context, string) error {
+	return errReadOnly
+}
+
+func (rd roDatastore) ReadJob(ctx context.Context, id string) (common.Job, error) {
+	store, ok := datastore.UnwrapAs[common.JobStore](rd.Datastore)
+	if !ok {
+		return common.Job{}, common.ErrJobsUnsupported
+	}
+	return store.ReadJob(ctx, id)
+}
+
+func (rd roDatastore) ListJobs(ctx context.Context, limit uint64) ([]common.Job, error) {
+	store, ok := datastore.UnwrapAs[common.JobStore](rd.Datastore)
+	if !ok {
+		return nil, common.ErrJobsUnsupported
+	}
+	return store.ListJobs(ctx, limit)
+}
+
+func (rd roDatastore) WriteNamespaceTombstone(context.Context, common.NamespaceTombstone) error {
+	return errReadOnly
+}
+
+func (rd roDatastore) ReadNamespaceTombstone(ctx context.Context, namespace string) (common.NamespaceTombstone, error) {
+	store, ok := datastore.UnwrapAs[common.NamespaceTombstoneStore](rd.Datastore)
+	if !ok {
+		return common.NamespaceTombstone{}, common.ErrNamespaceTombstonesUnsupported
+	}
+	return store.ReadNamespaceTombstone(ctx, namespace)
+}
+
+func (rd roDatastore) ListNamespaceTombstones(ctx context.Context) ([]common.NamespaceTombstone, error) {
+	store, ok := datastore.UnwrapAs[common.NamespaceTombstoneStore](rd.Datastore)
+	if !ok {
+		return nil, common.ErrNamespaceTombstonesUnsupported
+	}
+	return store.ListNamespaceTombstones(ctx)
+}
+
+func (rd roDatastore) DeleteNamespaceTombstone(context.Context, string) error {
+	return errReadOnly
+}
+
+// Epoch returns the epoch of the delegate, or zero if it does not store one.
+func (rd roDatastore) Epoch(ctx context.Context) (uint64, error) {
+	return common.DatastoreEpoch(ctx, rd.Datastore)
+}
+
+func (rd roDatastore) BumpEpoch(context.Context) (uint64, error) {
+	return 0, errReadOnly
+}
+
+func (rd roDatastore) PinRevision(context.Context, string, datastore.Revision, time.Time) error {
+	return errReadOnly
+}
+
+func (rd roDatastore) ReleasePinnedRevision(context.Context, string) error {
+	return errReadOnly
+}
+
+func (rd roDatastore) AddSchemaVersion(context.Context, datastore.Revision, string, string) (datastore.SchemaVersion, error) {
+	return datastore.SchemaVersion{}, errReadOnly
+}
+
+func (rd roDatastore) ListSchemaVersions(ctx context.Context) ([]datastore.SchemaVersion, error) {
+	store, err := common.SchemaHistoryStoreOf(rd.Datastore)
+	if err != nil {
+		return nil, err
+	}
+	return store.ListSchemaVersions(ctx)
+}
+
+func (rd roDatastore) ReadSchemaVersion(ctx context.Context, version uint64) (datastore.SchemaVersion, error) {
+	store, err := common.SchemaHistoryStoreOf(rd.Datastore)
+	if err != nil {
+		return datastore.SchemaVersion{}, err
+	}
+	return store.ReadSchemaVersion(ctx, version)
+}
+
+func (rd roDatastore) SetNamespaceExperiment(context.Context, string, string, bool) error {
+	return errReadOnly
+}
+
+func (rd roDatastore) ListNamespaceExperiments(ctx context.Context) ([]datastore.NamespaceExperiment, error) {
+	store, err := common.NamespaceExperimentStoreOf(rd.Datastore)
+	if err != nil {
+		return nil, err
+	}
+	return store.ListNamespaceExperiments(ctx)
+}
+
+var (
+	_ common.QuantizedDatastore       = roDatastore{}
+	_ common.CommitTimeReporter       = roDatastore{}
+	_ common.RelationshipTypeLister   = roDatastore{}
+	_ common.MVCCRepairer             = roDatastore{}
+	_ common.JobStore                 = roDatastore{}
+	_ common.NamespaceTombstoneStore  = roDatastore{}
+	_ common.EpochStore               = roDatastore{}
+	_ common.RevisionPinner           = roDatastore{}
+	_ common.SchemaHistoryStore       = roDatastore{}
+	_ common.NamespaceExperimentStore = roDatastore{}
+)
@@ -3,12 +3,14 @@ package proxy
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
 	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
@@ -141,3 +143,61 @@ func TestSnapshotReaderPassthrough(t *testing.T) {
 	delegate.AssertExpectations(t)
 	reader.AssertExpectations(t)
 }
+
+func TestCapabilitiesReadOnly(t *testing.T) {
+	require := require.New(t)
+
+	delegate, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds := NewReadonlyDatastore(delegate)
+	ctx := context.Background()
+
+	// The delegate is not unwrapped to, so that its capabilities cannot be written through.
+	_, ok := ds.(datastore.UnwrappableDatastore)
+	require.False(ok)
+
+	rev, err := delegate.HeadRevision(ctx)
+	require.NoError(err)
+
+	history, err := common.SchemaHistoryStoreOf(delegate)
+	require.NoError(err)
+	_, err = history.AddSchemaVersion(ctx, rev, "definition user {}", "")
+	require.NoError(err)
+
+	jobs, ok := datastore.UnwrapAs[common.JobStore](ds)
+	require.True(ok)
+	require.ErrorAs(jobs.CreateJob(ctx, common.Job{ID: "job"}), &datastore.ErrReadOnly{})
+	require.ErrorAs(jobs.RequestJobCancellation(ctx, "job"), &datastore.ErrReadOnly{})
+	_, err = jobs.ReadJob(ctx, "job")
+	require.ErrorIs(err, common.ErrJobNotFound)
+
+	tombstones, ok := datastore.UnwrapAs[common.NamespaceTombstoneStore](ds)
+	require.True(ok)
+	require.ErrorAs(tombstones.WriteNamespaceTombstone(ctx, common.NamespaceTombstone{Namespace: "user"}), &datastore.ErrReadOnly{})
+	require.ErrorAs(tombstones.DeleteNamespaceTombstone(ctx, "user"), &datastore.ErrReadOnly{})
+
+	pinner, err := common.RevisionPinnerOf(ds)
+	require.NoError(err)
+	require.ErrorAs(pinner.PinRevision(ctx, "export", rev, time.Now().Add(time.Hour)), &datastore.ErrReadOnly{})
+	require.ErrorAs(pinner.ReleasePinnedRevision(ctx, "export"), &datastore.ErrReadOnly{})
+
+	roHistory, err := common.SchemaHistoryStoreOf(ds)
+	require.NoError(err)
+	_, err = roHistory.AddSchemaVersion(ctx, rev, "definition user {}", "")
+	require.ErrorAs(err, &datastore.ErrReadOnly{})
+	versions, err := roHistory.ListSchemaVersions(ctx)
+	require.NoError(err)
+	require.Len(versions, 1)
+
+	experiments, err := common.NamespaceExperimentStoreOf(ds)
+	require.NoError(err)
+	require.ErrorAs(experiments.SetNamespaceExperiment(ctx, "user", "new-planner", true), &datastore.ErrReadOnly{})
+
+	repairer, ok := datastore.UnwrapAs[common.MVCCRepairer](ds)
+	require.True(ok)
+	anomalies, err := repairer.FindMVCCAnomalies(ctx, 10)
+	require.NoError(err)
+	require.Empty(anomalies)
+	_, err = repairer.DeleteMVCCAnomalies(ctx, 10)
+	require.ErrorAs(err, &datastore.ErrReadOnly{})
+}
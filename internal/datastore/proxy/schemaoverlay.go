@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+type schemaOverlayDatastore struct {
+	datastore.Datastore
+	namespaces map[string]*core.NamespaceDefinition
+	caveats    map[string]*core.CaveatDefinition
+}
+
+// NewSchemaOverlayDatastore creates a proxy whose readers read the given definitions as the
+// schema, in place of the schema of the delegate datastore, and the relationships of the
+// delegate. Write operations are disabled, as the schema read is not the one stored.
+func NewSchemaOverlayDatastore(delegate datastore.Datastore, namespaces []*core.NamespaceDefinition, caveats []*core.CaveatDefinition) datastore.Datastore {
+	overlay := schemaOverlayDatastore{
+		Datastore:  NewReadonlyDatastore(delegate),
+		namespaces: make(map[string]*core.NamespaceDefinition, len(namespaces)),
+		caveats:    make(map[string]*core.CaveatDefinition, len(caveats)),
+	}
+	for _, ns := range namespaces {
+		overlay.namespaces[ns.Name] = ns
+	}
+	for _, caveat := range caveats {
+		overlay.caveats[caveat.Name] = caveat
+	}
+	return overlay
+}
+
+func (sod schemaOverlayDatastore) Unwrap() datastore.Datastore {
+	return sod.Datastore
+}
+
+func (sod schemaOverlayDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return schemaOverlayReader{sod.Datastore.SnapshotReader(rev), rev, sod}
+}
+
+type schemaOverlayReader struct {
+	datastore.Reader
+	rev     datastore.Revision
+	overlay schemaOverlayDatastore
+}
+
+func (sor schemaOverlayReader) ReadNamespace(_ context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
+	ns, ok := sor.overlay.namespaces[nsName]
+	if !ok {
+		return nil, datastore.NoRevision, datastore.NewNamespaceNotFoundErr(nsName)
+	}
+	return ns, sor.rev, nil
+}
+
+func (sor schemaOverlayReader) ListNamespaces(context.Context) ([]*core.NamespaceDefinition, error) {
+	namespaces := make([]*core.NamespaceDefinition, 0, len(sor.overlay.namespaces))
+	for _, ns := range sor.overlay.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, nil
+}
+
+func (sor schemaOverlayReader) LookupNamespaces(_ context.Context, nsNames []string) ([]*core.NamespaceDefinition, error) {
+	namespaces := make([]*core.NamespaceDefinition, 0, len(nsNames))
+	for _, nsName := range nsNames {
+		if ns, ok := sor.overlay.namespaces[nsName]; ok {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces, nil
+}
+
+func (sor schemaOverlayReader) ReadCaveatByName(_ context.Context, name string) (*core.CaveatDefinition, datastore.Revision, error) {
+	caveat, ok := sor.overlay.caveats[name]
+	if !ok {
+		return nil, datastore.NoRevision, datastore.NewCaveatNameNotFoundErr(name)
+	}
+	return caveat, sor.rev, nil
+}
+
+func (sor schemaOverlayReader) ListCaveats(_ context.Context, caveatNamesForFiltering ...string) ([]*core.CaveatDefinition, error) {
+	if len(caveatNamesForFiltering) == 0 {
+		caveats := make([]*core.CaveatDefinition, 0, len(sor.overlay.caveats))
+		for _, caveat := range sor.overlay.caveats {
+			caveats = append(caveats, caveat)
+		}
+		return caveats, nil
+	}
+
+	caveats := make([]*core.CaveatDefinition, 0, len(caveatNamesForFiltering))
+	for _, name := range caveatNamesForFiltering {
+		if caveat, ok := sor.overlay.caveats[name]; ok {
+			caveats = append(caveats, caveat)
+		}
+	}
+	return caveats, nil
+}
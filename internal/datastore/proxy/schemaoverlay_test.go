@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestSchemaOverlay(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	revision, err := rawDS.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		if err := rwt.WriteNamespaces(ctx, ns.Namespace("user"), ns.Namespace("document", ns.Relation("viewer", nil))); err != nil {
+			return err
+		}
+		return rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{
+			tuple.Create(tuple.MustParse("document:first#viewer@user:tom")),
+		})
+	})
+	require.NoError(err)
+
+	ds := NewSchemaOverlayDatastore(rawDS, []*core.NamespaceDefinition{
+		ns.Namespace("user"),
+		ns.Namespace("folder"),
+		ns.Namespace("document", ns.Relation("reader", nil)),
+	}, []*core.CaveatDefinition{{Name: "somecaveat"}})
+	reader := ds.SnapshotReader(revision)
+
+	document, _, err := reader.ReadNamespace(ctx, "document")
+	require.NoError(err)
+	require.Equal("reader", document.Relation[0].Name)
+
+	_, _, err = reader.ReadNamespace(ctx, "team")
+	require.ErrorAs(err, &datastore.ErrNamespaceNotFound{})
+
+	listed, err := reader.ListNamespaces(ctx)
+	require.NoError(err)
+	require.Len(listed, 3)
+
+	found, err := reader.LookupNamespaces(ctx, []string{"folder", "team"})
+	require.NoError(err)
+	require.Len(found, 1)
+
+	_, _, err = reader.ReadCaveatByName(ctx, "somecaveat")
+	require.NoError(err)
+	_, _, err = reader.ReadCaveatByName(ctx, "othercaveat")
+	require.ErrorAs(err, &datastore.ErrCaveatNameNotFound{})
+
+	// Relationships are those of the delegate.
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})
+	require.NoError(err)
+	defer it.Close()
+	require.NotNil(it.Next())
+
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, tuple.MustParse("document:second#viewer@user:tom"))
+	require.ErrorAs(err, &datastore.ErrReadOnly{})
+}
@@ -0,0 +1,320 @@
+package proxy
+
+import (
+	"context"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/benbjohnson/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+var slowQueryCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "datastore",
+	Name:      "slow_queries_total",
+	Help:      "total number of datastore operations which exceeded the slow query threshold",
+}, []string{"operation"})
+
+// NewSlowQueryLogProxy creates a new datastore proxy which logs any datastore
+// operation that takes longer than the provided threshold to complete, along with
+// the shape of the operation, the revision at which it was performed and the API
+// method which caused it.
+func NewSlowQueryLogProxy(d datastore.Datastore, threshold time.Duration) datastore.Datastore {
+	return newSlowQueryLogProxyWithClock(d, threshold, clock.New())
+}
+
+func newSlowQueryLogProxyWithClock(d datastore.Datastore, threshold time.Duration, timeSource clock.Clock) datastore.Datastore {
+	return &slowQueryLogProxy{
+		delegate: d,
+		logger:   slowQueryLogger{threshold: threshold, timeSource: timeSource},
+	}
+}
+
+type slowQueryLogger struct {
+	threshold  time.Duration
+	timeSource clock.Clock
+}
+
+// start begins timing an operation and returns a function which must be invoked when the
+// operation completes. The supplied fields function is only invoked if the operation was slow.
+func (sql slowQueryLogger) start(ctx context.Context, operation string, rev datastore.Revision) func(fields func(e *zerolog.Event)) {
+	started := sql.timeSource.Now()
+	return func(fields func(e *zerolog.Event)) {
+		duration := sql.timeSource.Since(started)
+		if duration < sql.threshold {
+			return
+		}
+
+		slowQueryCount.WithLabelValues(operation).Inc()
+
+		event := log.Ctx(ctx).Warn().
+			Str("operation", operation).
+			Dur("duration", duration).
+			Dur("threshold", sql.threshold)
+
+		if method, ok := grpc.Method(ctx); ok {
+			event = event.Str("method", method)
+		}
+
+		if rev != nil && rev != datastore.NoRevision {
+			event = event.Stringer("revision", rev)
+		}
+
+		if fields != nil {
+			fields(event)
+		}
+
+		event.Msg("slow datastore operation")
+	}
+}
+
+type slowQueryLogProxy struct {
+	delegate datastore.Datastore
+	logger   slowQueryLogger
+}
+
+func (p *slowQueryLogProxy) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return &slowQueryLogReader{p.delegate.SnapshotReader(rev), p.logger, rev}
+}
+
+func (p *slowQueryLogProxy) ReadWriteTx(ctx context.Context, f datastore.TxUserFunc) (datastore.Revision, error) {
+	done := p.logger.start(ctx, "ReadWriteTx", datastore.NoRevision)
+	rev, err := p.delegate.ReadWriteTx(ctx, func(delegateRWT datastore.ReadWriteTransaction) error {
+		return f(&slowQueryLogRWT{&slowQueryLogReader{delegateRWT, p.logger, datastore.NoRevision}, delegateRWT})
+	})
+	done(func(e *zerolog.Event) {
+		if rev != nil && rev != datastore.NoRevision {
+			e.Stringer("committedRevision", rev)
+		}
+	})
+	return rev, err
+}
+
+func (p *slowQueryLogProxy) OptimizedRevision(ctx context.Context) (datastore.Revision, error) {
+	defer p.logger.start(ctx, "OptimizedRevision", datastore.NoRevision)(nil)
+	return p.delegate.OptimizedRevision(ctx)
+}
+
+func (p *slowQueryLogProxy) CheckRevision(ctx context.Context, revision datastore.Revision) error {
+	defer p.logger.start(ctx, "CheckRevision", revision)(nil)
+	return p.delegate.CheckRevision(ctx, revision)
+}
+
+func (p *slowQueryLogProxy) HeadRevision(ctx context.Context) (datastore.Revision, error) {
+	defer p.logger.start(ctx, "HeadRevision", datastore.NoRevision)(nil)
+	return p.delegate.HeadRevision(ctx)
+}
+
+func (p *slowQueryLogProxy) RevisionFromString(serialized string) (datastore.Revision, error) {
+	return p.delegate.RevisionFromString(serialized)
+}
+
+func (p *slowQueryLogProxy) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+	return p.delegate.Watch(ctx, afterRevision)
+}
+
+func (p *slowQueryLogProxy) Features(ctx context.Context) (*datastore.Features, error) {
+	return p.delegate.Features(ctx)
+}
+
+func (p *slowQueryLogProxy) Statistics(ctx context.Context) (datastore.Stats, error) {
+	defer p.logger.start(ctx, "Statistics", datastore.NoRevision)(nil)
+	return p.delegate.Statistics(ctx)
+}
+
+func (p *slowQueryLogProxy) UniqueID(ctx context.Context) (string, error) {
+	return p.delegate.UniqueID(ctx)
+}
+
+func (p *slowQueryLogProxy) IsReady(ctx context.Context) (bool, error) {
+	return p.delegate.IsReady(ctx)
+}
+
+func (p *slowQueryLogProxy) Close() error { return p.delegate.Close() }
+
+func (p *slowQueryLogProxy) Unwrap() datastore.Datastore { return p.delegate }
+
+type slowQueryLogReader struct {
+	delegate datastore.Reader
+	logger   slowQueryLogger
+	rev      datastore.Revision
+}
+
+func (r *slowQueryLogReader) ReadCaveatByName(ctx context.Context, name string) (*core.CaveatDefinition, datastore.Revision, error) {
+	defer r.logger.start(ctx, "ReadCaveatByName", r.rev)(func(e *zerolog.Event) {
+		e.Str("name", name)
+	})
+	return r.delegate.ReadCaveatByName(ctx, name)
+}
+
+func (r *slowQueryLogReader) ListCaveats(ctx context.Context, caveatNamesForFiltering ...string) ([]*core.CaveatDefinition, error) {
+	defer r.logger.start(ctx, "ListCaveats", r.rev)(func(e *zerolog.Event) {
+		e.Strs("names", caveatNamesForFiltering)
+	})
+	return r.delegate.ListCaveats(ctx, caveatNamesForFiltering...)
+}
+
+func (r *slowQueryLogReader) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefinition, error) {
+	defer r.logger.start(ctx, "ListNamespaces", r.rev)(nil)
+	return r.delegate.ListNamespaces(ctx)
+}
+
+func (r *slowQueryLogReader) LookupNamespaces(ctx context.Context, nsNames []string) ([]*core.NamespaceDefinition, error) {
+	defer r.logger.start(ctx, "LookupNamespaces", r.rev)(func(e *zerolog.Event) {
+		e.Strs("names", nsNames)
+	})
+	return r.delegate.LookupNamespaces(ctx, nsNames)
+}
+
+func (r *slowQueryLogReader) ReadNamespace(ctx context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
+	defer r.logger.start(ctx, "ReadNamespace", r.rev)(func(e *zerolog.Event) {
+		e.Str("name", nsName)
+	})
+	return r.delegate.ReadNamespace(ctx, nsName)
+}
+
+func (r *slowQueryLogReader) QueryRelationships(ctx context.Context, filter datastore.RelationshipsFilter, options ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	done := r.logger.start(ctx, "QueryRelationships", r.rev)
+	fields := func(e *zerolog.Event) {
+		e.Str("resourceType", filter.ResourceType).
+			Int("resourceIDCount", len(filter.OptionalResourceIds)).
+			Str("resourceIDPrefix", filter.OptionalResourceIDPrefix).
+			Str("resourceRelation", filter.OptionalResourceRelation).
+			Str("caveatName", filter.OptionalCaveatName)
+		if filter.OptionalSubjectsFilter != nil {
+			subjectsFilterToFields(e, *filter.OptionalSubjectsFilter)
+		}
+	}
+
+	iterator, err := r.delegate.QueryRelationships(ctx, filter, options...)
+	if err != nil {
+		done(fields)
+		return iterator, err
+	}
+	return &slowQueryLogIterator{delegate: iterator, done: done, fields: fields}, nil
+}
+
+func (r *slowQueryLogReader) ReverseQueryRelationships(ctx context.Context, subjectFilter datastore.SubjectsFilter, options ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	done := r.logger.start(ctx, "ReverseQueryRelationships", r.rev)
+	fields := func(e *zerolog.Event) {
+		subjectsFilterToFields(e, subjectFilter)
+	}
+
+	iterator, err := r.delegate.ReverseQueryRelationships(ctx, subjectFilter, options...)
+	if err != nil {
+		done(fields)
+		return iterator, err
+	}
+	return &slowQueryLogIterator{delegate: iterator, done: done, fields: fields}, nil
+}
+
+func subjectsFilterToFields(e *zerolog.Event, filter datastore.SubjectsFilter) {
+	e.Str("subjectType", filter.SubjectType).
+		Int("subjectIDCount", len(filter.OptionalSubjectIds)).
+		Str("subjectIDPrefix", filter.OptionalSubjectIDPrefix).
+		Str("subjectRelation", filter.RelationFilter.NonEllipsisRelation).
+		Bool("subjectEllipsis", filter.RelationFilter.IncludeEllipsisRelation)
+}
+
+// slowQueryLogIterator measures the full lifetime of a relationship query, since the
+// cost of most queries is paid while the results are being iterated.
+type slowQueryLogIterator struct {
+	delegate datastore.RelationshipIterator
+	done     func(fields func(e *zerolog.Event))
+	fields   func(e *zerolog.Event)
+	rowCount int
+	closed   bool
+}
+
+func (i *slowQueryLogIterator) Next() *core.RelationTuple {
+	next := i.delegate.Next()
+	if next != nil {
+		i.rowCount++
+	}
+	return next
+}
+
+func (i *slowQueryLogIterator) Err() error { return i.delegate.Err() }
+
+func (i *slowQueryLogIterator) Close() {
+	i.delegate.Close()
+	if i.closed {
+		return
+	}
+	i.closed = true
+	i.done(func(e *zerolog.Event) {
+		i.fields(e)
+		e.Int("rows", i.rowCount)
+	})
+}
+
+type slowQueryLogRWT struct {
+	*slowQueryLogReader
+	delegate datastore.ReadWriteTransaction
+}
+
+func (rwt *slowQueryLogRWT) Unwrap() datastore.ReadWriteTransaction { return rwt.delegate }
+
+func (rwt *slowQueryLogRWT) WriteCaveats(ctx context.Context, caveats []*core.CaveatDefinition) error {
+	defer rwt.logger.start(ctx, "WriteCaveats", datastore.NoRevision)(func(e *zerolog.Event) {
+		e.Int("caveats", len(caveats))
+	})
+	return rwt.delegate.WriteCaveats(ctx, caveats)
+}
+
+func (rwt *slowQueryLogRWT) DeleteCaveats(ctx context.Context, names []string) error {
+	defer rwt.logger.start(ctx, "DeleteCaveats", datastore.NoRevision)(func(e *zerolog.Event) {
+		e.Strs("names", names)
+	})
+	return rwt.delegate.DeleteCaveats(ctx, names)
+}
+
+func (rwt *slowQueryLogRWT) WriteRelationships(ctx context.Context, mutations []*core.RelationTupleUpdate) error {
+	defer rwt.logger.start(ctx, "WriteRelationships", datastore.NoRevision)(func(e *zerolog.Event) {
+		e.Int("mutations", len(mutations))
+	})
+	return rwt.delegate.WriteRelationships(ctx, mutations)
+}
+
+func (rwt *slowQueryLogRWT) WriteNamespaces(ctx context.Context, newConfigs ...*core.NamespaceDefinition) error {
+	defer rwt.logger.start(ctx, "WriteNamespaces", datastore.NoRevision)(func(e *zerolog.Event) {
+		e.Int("namespaces", len(newConfigs))
+	})
+	return rwt.delegate.WriteNamespaces(ctx, newConfigs...)
+}
+
+func (rwt *slowQueryLogRWT) DeleteNamespaces(ctx context.Context, nsNames ...string) error {
+	defer rwt.logger.start(ctx, "DeleteNamespaces", datastore.NoRevision)(func(e *zerolog.Event) {
+		e.Strs("names", nsNames)
+	})
+	return rwt.delegate.DeleteNamespaces(ctx, nsNames...)
+}
+
+func (rwt *slowQueryLogRWT) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
+	defer rwt.logger.start(ctx, "DeleteRelationships", datastore.NoRevision)(func(e *zerolog.Event) {
+		e.Str("resourceType", filter.ResourceType).
+			Str("resourceID", filter.OptionalResourceId).
+			Str("resourceRelation", filter.OptionalRelation)
+		if filter.OptionalSubjectFilter != nil {
+			e.Str("subjectType", filter.OptionalSubjectFilter.SubjectType).
+				Str("subjectID", filter.OptionalSubjectFilter.OptionalSubjectId)
+		}
+	})
+	return rwt.delegate.DeleteRelationships(ctx, filter)
+}
+
+var (
+	_ datastore.Datastore            = (*slowQueryLogProxy)(nil)
+	_ datastore.Reader               = (*slowQueryLogReader)(nil)
+	_ datastore.ReadWriteTransaction = (*slowQueryLogRWT)(nil)
+	_ datastore.RelationshipIterator = (*slowQueryLogIterator)(nil)
+)
@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+func TestSlowQueryLogProxy(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opDuration  time.Duration
+		expectedLog bool
+	}{
+		{"fast operation", 5 * time.Millisecond, false},
+		{"slow operation", 500 * time.Millisecond, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			mockTime := clock.NewMock()
+			delegate := &proxy_test.MockDatastore{}
+			reader := &proxy_test.MockReader{}
+			delegate.On("SnapshotReader", mock.Anything).Return(reader)
+			reader.On("ReadNamespace", "document").Run(func(args mock.Arguments) {
+				mockTime.Add(tc.opDuration)
+			}).Return(&core.NamespaceDefinition{Name: "document"}, expectedRevision, nil)
+
+			var buf bytes.Buffer
+			logger := zerolog.New(&buf)
+			ctx := logger.WithContext(context.Background())
+
+			ds := newSlowQueryLogProxyWithClock(delegate, 100*time.Millisecond, mockTime)
+			_, _, err := ds.SnapshotReader(expectedRevision).ReadNamespace(ctx, "document")
+			require.NoError(err)
+
+			if tc.expectedLog {
+				require.Contains(buf.String(), "slow datastore operation")
+				require.Contains(buf.String(), `"operation":"ReadNamespace"`)
+				require.Contains(buf.String(), `"name":"document"`)
+				require.Contains(buf.String(), `"revision":"123"`)
+			} else {
+				require.Empty(buf.String())
+			}
+
+			reader.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package pseudonymize
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/pseudonym"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// DefaultBatchSize is the default number of relationships rewritten per transaction.
+const DefaultBatchSize = 1_000
+
+// Options configure a migration of relationships to pseudonymized object IDs.
+type Options struct {
+	// BatchSize is the number of relationships rewritten per transaction.
+	BatchSize int
+
+	// BatchInterval is the minimum time between transactions, which bounds the write load
+	// of migrating a large number of relationships.
+	BatchInterval time.Duration
+
+	// OnProgress, if set, is invoked after each transaction with the number of
+	// relationships rewritten so far.
+	OnProgress func(migrated uint64)
+}
+
+// Run rewrites the relationships stored with the cleartext IDs of objects of the types
+// pseudonymized, replacing them with relationships with the pseudonyms of those IDs. It
+// requires the datastore, or one it wraps, to implement common.PseudonymStore, and must be
+// given the datastore without a pseudonymizing proxy, so that cleartext IDs can be read.
+func Run(ctx context.Context, ds datastore.Datastore, pseudonymizer *pseudonym.Pseudonymizer, opts Options) (uint64, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	store, ok := datastore.UnwrapAs[common.PseudonymStore](ds)
+	if !ok {
+		return 0, common.ErrPseudonymsUnsupported
+	}
+
+	m := &migrator{ds: ds, store: store, pseudonymizer: pseudonymizer, opts: opts}
+	for _, objectType := range pseudonymizer.ObjectTypes() {
+		if err := m.migrate(ctx, func(reader datastore.Reader) (datastore.RelationshipIterator, error) {
+			return reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: objectType})
+		}); err != nil {
+			return m.migrated, err
+		}
+
+		// Relationships whose resource was of the type were rewritten with their subject
+		// pseudonymized as well, and so are skipped at the new head revision.
+		if err := m.migrate(ctx, func(reader datastore.Reader) (datastore.RelationshipIterator, error) {
+			return reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{SubjectType: objectType})
+		}); err != nil {
+			return m.migrated, err
+		}
+	}
+	return m.migrated, nil
+}
+
+type migrator struct {
+	ds            datastore.Datastore
+	store         common.PseudonymStore
+	pseudonymizer *pseudonym.Pseudonymizer
+	opts          Options
+
+	pending   []*core.RelationTuple
+	migrated  uint64
+	lastFlush time.Time
+}
+
+// migrate rewrites the relationships with cleartext IDs returned by the query at the
+// head revision.
+func (m *migrator) migrate(ctx context.Context, query func(datastore.Reader) (datastore.RelationshipIterator, error)) error {
+	rev, err := m.ds.HeadRevision(ctx)
+	if err != nil {
+		return err
+	}
+
+	iter, err := query(m.ds.SnapshotReader(rev))
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for rel := iter.Next(); rel != nil; rel = iter.Next() {
+		if !m.hasCleartextID(rel.ResourceAndRelation) && !m.hasCleartextID(rel.Subject) {
+			continue
+		}
+
+		m.pending = append(m.pending, rel)
+		if len(m.pending) >= m.opts.BatchSize {
+			if err := m.flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	return m.flush(ctx)
+}
+
+func (m *migrator) hasCleartextID(onr *core.ObjectAndRelation) bool {
+	return m.pseudonymizer.Pseudonym(onr.Namespace, onr.ObjectId) != onr.ObjectId
+}
+
+// flush replaces the pending relationships with their pseudonymized versions, after
+// storing the sealed IDs of their pseudonyms.
+func (m *migrator) flush(ctx context.Context) error {
+	if len(m.pending) == 0 {
+		return nil
+	}
+
+	if err := m.waitForBatchInterval(ctx); err != nil {
+		return err
+	}
+
+	sealed := make(map[string][]byte)
+	mutations := make([]*core.RelationTupleUpdate, 0, 2*len(m.pending))
+	for _, rel := range m.pending {
+		pseudonymized, sealedIDs, err := m.pseudonymizer.PseudonymizeRelationship(rel)
+		if err != nil {
+			return err
+		}
+		for pseudonym, sealedID := range sealedIDs {
+			sealed[pseudonym] = sealedID
+		}
+		mutations = append(mutations, tuple.Delete(rel), tuple.Touch(pseudonymized))
+	}
+
+	if err := m.store.WritePseudonyms(ctx, sealed); err != nil {
+		return err
+	}
+	if _, err := m.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, mutations)
+	}); err != nil {
+		return fmt.Errorf("unable to rewrite relationships: %w", err)
+	}
+
+	m.migrated += uint64(len(m.pending))
+	log.Ctx(ctx).Debug().Uint64("migrated", m.migrated).Msg("pseudonymized relationships")
+	if m.opts.OnProgress != nil {
+		m.opts.OnProgress(m.migrated)
+	}
+	m.pending = nil
+	m.lastFlush = time.Now()
+	return nil
+}
+
+func (m *migrator) waitForBatchInterval(ctx context.Context) error {
+	if m.opts.BatchInterval <= 0 || m.lastFlush.IsZero() {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Until(m.lastFlush.Add(m.opts.BatchInterval)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
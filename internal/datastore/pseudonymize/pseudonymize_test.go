@@ -0,0 +1,87 @@
+package pseudonymize
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/proxy"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/pseudonym"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestRun(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	existing := []string{
+		"document:first#viewer@user:tom",
+		"document:second#viewer@user:sarah",
+		"document:second#viewer@user:*",
+		"document:third#parent@folder:root",
+		"user:tom#manager@user:sarah",
+		"group:admins#member@user:fred",
+	}
+	updates := make([]*core.RelationTupleUpdate, 0, len(existing))
+	for _, tpl := range existing {
+		updates = append(updates, tuple.Create(tuple.MustParse(tpl)))
+	}
+	_, err = rawDS.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, updates)
+	})
+	require.NoError(err)
+
+	pseudonymizer, err := pseudonym.NewPseudonymizer(bytes.Repeat([]byte{1}, 32), []string{"user"})
+	require.NoError(err)
+
+	var progress []uint64
+	migrated, err := Run(ctx, rawDS, pseudonymizer, Options{
+		BatchSize: 2,
+		OnProgress: func(migrated uint64) {
+			progress = append(progress, migrated)
+		},
+	})
+	require.NoError(err)
+	require.Equal(uint64(4), migrated)
+	require.Equal([]uint64{1, 3, 4}, progress)
+
+	// Running again finds nothing left to migrate.
+	migrated, err = Run(ctx, rawDS, pseudonymizer, Options{})
+	require.NoError(err)
+	require.Zero(migrated)
+
+	readAll := func(ds datastore.Datastore) []string {
+		head, err := ds.HeadRevision(ctx)
+		require.NoError(err)
+
+		var found []string
+		for _, resourceType := range []string{"document", "user", "group"} {
+			it, err := ds.SnapshotReader(head).QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: resourceType})
+			require.NoError(err)
+			for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+				found = append(found, tuple.String(tpl))
+			}
+			require.NoError(it.Err())
+			it.Close()
+		}
+		return found
+	}
+
+	for _, stored := range readAll(rawDS) {
+		for _, cleartext := range []string{"tom", "sarah", "fred"} {
+			require.NotContains(stored, cleartext)
+		}
+	}
+
+	ds, err := proxy.NewPseudonymizingProxy(rawDS, pseudonymizer)
+	require.NoError(err)
+	require.ElementsMatch(existing, readAll(ds))
+}
@@ -0,0 +1,294 @@
+package rename
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// DefaultBatchSize is the default number of relationships moved per transaction.
+const DefaultBatchSize = 1_000
+
+// ErrTargetExists is returned when renaming to a type which already exists, and which is
+// not the result of an interrupted rename of the same type.
+var ErrTargetExists = errors.New("target type already exists")
+
+// Phase is a step of a rename.
+type Phase string
+
+const (
+	// SchemaPhase writes the renamed definition alongside the original, and allows the
+	// renamed type wherever the original is allowed.
+	SchemaPhase Phase = "schema"
+
+	// RelationshipsPhase moves the relationships of the original type to the renamed one.
+	RelationshipsPhase Phase = "relationships"
+
+	// CleanupPhase removes the original definition, and references to it.
+	CleanupPhase Phase = "cleanup"
+)
+
+// Options configure a rename.
+type Options struct {
+	// BatchSize is the number of relationships moved per transaction.
+	BatchSize int
+
+	// OnProgress, if set, is invoked once each phase completes, and after each batch of
+	// relationships is moved.
+	OnProgress func(Progress)
+}
+
+// Progress reports how far a rename has come.
+type Progress struct {
+	Phase Phase
+
+	// Moved is the number of relationships moved so far by this run.
+	Moved uint64
+}
+
+// Run renames the type `from` to `to`, rewriting the schema and every relationship whose
+// resource or subject is of the type. Relationships are moved in batches of separate
+// transactions, so while a rename is in progress both types are defined and relationships
+// are found under either; writes to the type should be paused until it completes.
+//
+// Every step can be repeated, so an interrupted rename is resumed by running it again.
+// Once complete, running it again does nothing.
+func Run(ctx context.Context, ds datastore.Datastore, from, to string, opts Options) (uint64, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if from == to {
+		return 0, fmt.Errorf("cannot rename `%s` to itself", from)
+	}
+
+	r := &renamer{ds: ds, from: from, to: to, opts: opts}
+
+	done, err := r.writeSchema(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if done {
+		log.Ctx(ctx).Info().Str("from", from).Str("to", to).Msg("type was already renamed")
+		return 0, nil
+	}
+	r.progress(SchemaPhase)
+
+	if err := r.moveRelationships(ctx); err != nil {
+		return r.moved, err
+	}
+	r.progress(RelationshipsPhase)
+
+	if err := r.cleanup(ctx); err != nil {
+		return r.moved, err
+	}
+	r.progress(CleanupPhase)
+	return r.moved, nil
+}
+
+type renamer struct {
+	ds       datastore.Datastore
+	from, to string
+	opts     Options
+	moved    uint64
+}
+
+func (r *renamer) progress(phase Phase) {
+	if r.opts.OnProgress != nil {
+		r.opts.OnProgress(Progress{Phase: phase, Moved: r.moved})
+	}
+}
+
+// writeSchema writes the renamed definition and allows it alongside the original type,
+// returning true if the rename had already completed.
+func (r *renamer) writeSchema(ctx context.Context) (bool, error) {
+	done := false
+	_, err := r.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		namespaces, err := rwt.ListNamespaces(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to read namespaces: %w", err)
+		}
+
+		var original, existing *core.NamespaceDefinition
+		for _, ns := range namespaces {
+			switch ns.Name {
+			case r.from:
+				original = ns
+			case r.to:
+				existing = ns
+			}
+		}
+
+		if original == nil {
+			if existing != nil {
+				done = true
+				return nil
+			}
+			return fmt.Errorf("type `%s` is not defined", r.from)
+		}
+
+		renamed := renameDefinition(original, r.from, r.to, false)
+		renamed.Name = r.to
+		if err := renamed.Validate(); err != nil {
+			return fmt.Errorf("invalid type name `%s`: %w", r.to, err)
+		}
+		if existing != nil && !existing.EqualVT(renamed) {
+			return fmt.Errorf("%w: `%s` is defined, and not by an interrupted rename of `%s`", ErrTargetExists, r.to, r.from)
+		}
+
+		updated := []*core.NamespaceDefinition{renamed}
+		for _, ns := range namespaces {
+			if ns.Name == r.to {
+				continue
+			}
+			if allowing := renameDefinition(ns, r.from, r.to, true); !allowing.EqualVT(ns) {
+				updated = append(updated, allowing)
+			}
+		}
+		return rwt.WriteNamespaces(ctx, updated...)
+	})
+	return done, err
+}
+
+// moveRelationships moves, in batches, relationships whose resource is of the original
+// type, then the remaining relationships whose subject is.
+func (r *renamer) moveRelationships(ctx context.Context) error {
+	limit := uint64(r.opts.BatchSize)
+	queries := []func(context.Context, datastore.Reader) (datastore.RelationshipIterator, error){
+		func(ctx context.Context, reader datastore.Reader) (datastore.RelationshipIterator, error) {
+			return reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: r.from}, options.WithLimit(&limit))
+		},
+		func(ctx context.Context, reader datastore.Reader) (datastore.RelationshipIterator, error) {
+			return reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{SubjectType: r.from}, options.WithReverseLimit(&limit))
+		},
+	}
+
+	for _, query := range queries {
+		for {
+			moved, err := r.moveBatch(ctx, query)
+			if err != nil {
+				return err
+			}
+			if moved == 0 {
+				break
+			}
+
+			r.moved += moved
+			log.Ctx(ctx).Debug().Uint64("moved", r.moved).Msg("moved relationships")
+			r.progress(RelationshipsPhase)
+		}
+	}
+	return nil
+}
+
+func (r *renamer) moveBatch(ctx context.Context, query func(context.Context, datastore.Reader) (datastore.RelationshipIterator, error)) (uint64, error) {
+	var moved uint64
+	_, err := r.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		iter, err := query(ctx, rwt)
+		if err != nil {
+			return err
+		}
+
+		var updates []*core.RelationTupleUpdate
+		for rel := iter.Next(); rel != nil; rel = iter.Next() {
+			renamed := rel.CloneVT()
+			if renamed.ResourceAndRelation.Namespace == r.from {
+				renamed.ResourceAndRelation.Namespace = r.to
+			}
+			if renamed.Subject.Namespace == r.from {
+				renamed.Subject.Namespace = r.to
+			}
+			updates = append(updates, tuple.Delete(rel), tuple.Touch(renamed))
+		}
+		if err := iter.Err(); err != nil {
+			iter.Close()
+			return err
+		}
+		iter.Close()
+
+		moved = uint64(len(updates) / 2)
+		if len(updates) == 0 {
+			return nil
+		}
+		return rwt.WriteRelationships(ctx, updates)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to move relationships: %w", err)
+	}
+	return moved, nil
+}
+
+// cleanup removes the original definition, and the references to it which writeSchema
+// extended with the renamed type.
+func (r *renamer) cleanup(ctx context.Context) error {
+	_, err := r.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		namespaces, err := rwt.ListNamespaces(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to read namespaces: %w", err)
+		}
+
+		var updated []*core.NamespaceDefinition
+		for _, ns := range namespaces {
+			if ns.Name == r.from {
+				continue
+			}
+			if renamed := renameDefinition(ns, r.from, r.to, false); !renamed.EqualVT(ns) {
+				updated = append(updated, renamed)
+			}
+		}
+		if len(updated) > 0 {
+			if err := rwt.WriteNamespaces(ctx, updated...); err != nil {
+				return err
+			}
+		}
+		return rwt.DeleteNamespaces(ctx, r.from)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to remove `%s`: %w", r.from, err)
+	}
+	return nil
+}
+
+// renameDefinition returns a copy of the definition in which the subject types of its
+// relations which are of type `from` are of type `to`, or with keepOriginal, in which both
+// types are allowed.
+func renameDefinition(ns *core.NamespaceDefinition, from, to string, keepOriginal bool) *core.NamespaceDefinition {
+	renamed := ns.CloneVT()
+	for _, relation := range renamed.Relation {
+		typeInfo := relation.GetTypeInformation()
+		if typeInfo == nil {
+			continue
+		}
+
+		allowed := make([]*core.AllowedRelation, 0, len(typeInfo.AllowedDirectRelations))
+		add := func(candidate *core.AllowedRelation) {
+			for _, existing := range allowed {
+				if existing.EqualVT(candidate) {
+					return
+				}
+			}
+			allowed = append(allowed, candidate)
+		}
+
+		for _, allowedRelation := range typeInfo.AllowedDirectRelations {
+			if allowedRelation.Namespace != from {
+				add(allowedRelation)
+				continue
+			}
+
+			if keepOriginal {
+				add(allowedRelation)
+			}
+			renamedRelation := allowedRelation.CloneVT()
+			renamedRelation.Namespace = to
+			add(renamedRelation)
+		}
+		typeInfo.AllowedDirectRelations = allowed
+	}
+	return renamed
+}
@@ -0,0 +1,143 @@
+package rename
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func standardDatastore(t *testing.T) datastore.Datastore {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	ds, _ := testfixtures.StandardDatastoreWithData(rawDS, require.New(t))
+	return ds
+}
+
+// readState returns the names of the namespaces, the allowed subject types of each of
+// their relations, and the relationships of the datastore.
+func readState(t *testing.T, ds datastore.Datastore) (namespaces, allowed, relationships []string) {
+	ctx := context.Background()
+	rev, err := ds.HeadRevision(ctx)
+	require.NoError(t, err)
+	reader := ds.SnapshotReader(rev)
+
+	nsDefs, err := reader.ListNamespaces(ctx)
+	require.NoError(t, err)
+	for _, ns := range nsDefs {
+		namespaces = append(namespaces, ns.Name)
+		for _, relation := range ns.Relation {
+			for _, allowedRelation := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+				allowed = append(allowed, ns.Name+"#"+relation.Name+":"+allowedRelation.Namespace)
+			}
+		}
+
+		iter, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: ns.Name})
+		require.NoError(t, err)
+		for rel := iter.Next(); rel != nil; rel = iter.Next() {
+			relationships = append(relationships, tuple.String(rel))
+		}
+		require.NoError(t, iter.Err())
+		iter.Close()
+	}
+
+	sort.Strings(namespaces)
+	sort.Strings(allowed)
+	sort.Strings(relationships)
+	return
+}
+
+func renamed(values []string, from, to string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, strings.ReplaceAll(value, from, to))
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestRename(t *testing.T) {
+	ds := standardDatastore(t)
+	namespaces, allowed, relationships := readState(t, ds)
+
+	var phases []Phase
+	moved, err := Run(context.Background(), ds, "folder", "directory", Options{
+		BatchSize: 3,
+		OnProgress: func(progress Progress) {
+			if len(phases) == 0 || phases[len(phases)-1] != progress.Phase {
+				phases = append(phases, progress.Phase)
+			}
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Phase{SchemaPhase, RelationshipsPhase, CleanupPhase}, phases)
+
+	renamedNamespaces, renamedAllowed, renamedRelationships := readState(t, ds)
+	require.Equal(t, renamed(namespaces, "folder", "directory"), renamedNamespaces)
+	require.Equal(t, renamed(allowed, "folder", "directory"), renamedAllowed)
+	require.Equal(t, renamed(relationships, "folder", "directory"), renamedRelationships)
+
+	var expectedMoved uint64
+	for _, rel := range relationships {
+		if strings.Contains(rel, "folder:") {
+			expectedMoved++
+		}
+	}
+	require.Equal(t, expectedMoved, moved)
+
+	// Running the rename again once complete does nothing.
+	moved, err = Run(context.Background(), ds, "folder", "directory", Options{})
+	require.NoError(t, err)
+	require.Zero(t, moved)
+}
+
+func TestRenameResumes(t *testing.T) {
+	ctx := context.Background()
+	ds := standardDatastore(t)
+	namespaces, allowed, relationships := readState(t, ds)
+
+	// Interrupt a rename after the schema and a first batch of relationships.
+	r := &renamer{ds: ds, from: "folder", to: "directory", opts: Options{BatchSize: 2}}
+	done, err := r.writeSchema(ctx)
+	require.NoError(t, err)
+	require.False(t, done)
+
+	limit := uint64(2)
+	moved, err := r.moveBatch(ctx, func(ctx context.Context, reader datastore.Reader) (datastore.RelationshipIterator, error) {
+		return reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "folder"}, options.WithLimit(&limit))
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), moved)
+
+	_, err = Run(ctx, ds, "folder", "directory", Options{BatchSize: 2})
+	require.NoError(t, err)
+
+	renamedNamespaces, renamedAllowed, renamedRelationships := readState(t, ds)
+	require.Equal(t, renamed(namespaces, "folder", "directory"), renamedNamespaces)
+	require.Equal(t, renamed(allowed, "folder", "directory"), renamedAllowed)
+	require.Equal(t, renamed(relationships, "folder", "directory"), renamedRelationships)
+}
+
+func TestRenameErrors(t *testing.T) {
+	ds := standardDatastore(t)
+
+	_, err := Run(context.Background(), ds, "folder", "user", Options{})
+	require.ErrorIs(t, err, ErrTargetExists)
+
+	_, err = Run(context.Background(), ds, "missing", "other", Options{})
+	require.ErrorContains(t, err, "type `missing` is not defined")
+
+	_, err = Run(context.Background(), ds, "folder", "Not A Type", Options{})
+	require.ErrorContains(t, err, "invalid type name")
+
+	_, err = Run(context.Background(), ds, "folder", "folder", Options{})
+	require.Error(t, err)
+}
@@ -0,0 +1,370 @@
+package repair
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// DefaultBatchSize is the default number of relationships deleted per transaction when
+// fixing issues.
+const DefaultBatchSize = 1_000
+
+// IssueKind is the kind of a consistency issue found in a datastore.
+type IssueKind string
+
+const (
+	// UndefinedResourceType is a relationship whose resource type has no definition.
+	UndefinedResourceType IssueKind = "undefined-resource-type"
+
+	// UndefinedRelation is a relationship on a relation which is not defined on its
+	// resource type, or which is defined as a permission.
+	UndefinedRelation IssueKind = "undefined-relation"
+
+	// UndefinedSubjectType is a relationship whose subject type has no definition.
+	UndefinedSubjectType IssueKind = "undefined-subject-type"
+
+	// UndefinedSubjectRelation is a relationship whose subject relation is not defined
+	// on the subject type.
+	UndefinedSubjectRelation IssueKind = "undefined-subject-relation"
+
+	// DanglingCaveat is a relationship whose caveat has no definition.
+	DanglingCaveat IssueKind = "dangling-caveat"
+
+	// DanglingSchemaCaveat is a relation whose allowed subject types require a caveat
+	// which has no definition. It must be fixed by writing a corrected schema.
+	DanglingSchemaCaveat IssueKind = "dangling-schema-caveat"
+
+	// MVCCAnomaly is a stored row whose MVCC metadata is impossible.
+	MVCCAnomaly IssueKind = "mvcc-anomaly"
+)
+
+// Issue is a single consistency issue found in a datastore.
+type Issue struct {
+	Kind IssueKind
+
+	// Relationship is the affected relationship, if any.
+	Relationship *core.RelationTuple
+
+	// Detail describes the issue.
+	Detail string
+
+	// Fixable is true if the issue can be fixed by deleting the relationship.
+	Fixable bool
+}
+
+// Options configure a consistency check.
+type Options struct {
+	// Fix deletes the relationships of fixable issues.
+	Fix bool
+
+	// BatchSize is the number of relationships deleted per transaction, and the number of
+	// MVCC anomalies read per query.
+	BatchSize int
+
+	// BatchInterval is the minimum time between the transactions deleting relationships,
+	// which bounds the write load of fixing a large number of issues.
+	BatchInterval time.Duration
+
+	// Limit, if non-zero, stops the check once this many issues have been found.
+	Limit uint64
+
+	// SkipMVCC skips checking for MVCC anomalies, leaving only the checks of relationships
+	// against the schema.
+	SkipMVCC bool
+
+	// OnIssue, if set, is invoked for each issue found.
+	OnIssue func(Issue)
+}
+
+// Summary is the result of a consistency check.
+type Summary struct {
+	// Issues is the number of issues found of each kind.
+	Issues map[IssueKind]uint64
+
+	// Fixed is the number of rows which were deleted.
+	Fixed uint64
+}
+
+// Run checks the relationships stored in the datastore against its schema, and if the
+// datastore, or one it wraps, implements the optional interfaces in common, for
+// relationships on types without a definition and for MVCC anomalies. Checks are
+// performed at the head revision, and fixes are written in batches of separate
+// transactions.
+func Run(ctx context.Context, ds datastore.Datastore, opts Options) (Summary, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	r := &runner{
+		ds:      ds,
+		opts:    opts,
+		summary: Summary{Issues: make(map[IssueKind]uint64)},
+	}
+
+	if err := r.checkRelationships(ctx); err != nil {
+		if errors.Is(err, errLimitReached) {
+			return r.summary, r.flush(ctx)
+		}
+		return r.summary, err
+	}
+	if opts.SkipMVCC {
+		return r.summary, nil
+	}
+
+	if repairer, ok := datastore.UnwrapAs[common.MVCCRepairer](ds); ok {
+		if err := r.checkMVCC(ctx, repairer); err != nil && !errors.Is(err, errLimitReached) {
+			return r.summary, err
+		}
+	}
+	return r.summary, nil
+}
+
+var errLimitReached = errors.New("issue limit reached")
+
+type runner struct {
+	ds        datastore.Datastore
+	opts      Options
+	summary   Summary
+	pending   []*core.RelationTupleUpdate
+	found     uint64
+	lastFlush time.Time
+}
+
+// report records the issue, returning errLimitReached once the limit of issues has been
+// found.
+func (r *runner) report(issue Issue) error {
+	r.summary.Issues[issue.Kind]++
+	if r.opts.OnIssue != nil {
+		r.opts.OnIssue(issue)
+	}
+
+	r.found++
+	if r.opts.Limit > 0 && r.found >= r.opts.Limit {
+		return errLimitReached
+	}
+	return nil
+}
+
+func (r *runner) checkRelationships(ctx context.Context) error {
+	rev, err := r.ds.HeadRevision(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to determine head revision: %w", err)
+	}
+	reader := r.ds.SnapshotReader(rev)
+
+	caveatDefs, err := reader.ListCaveats(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to read caveats: %w", err)
+	}
+	caveats := make(map[string]struct{}, len(caveatDefs))
+	for _, caveat := range caveatDefs {
+		caveats[caveat.Name] = struct{}{}
+	}
+
+	nsDefs, err := reader.ListNamespaces(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to read namespaces: %w", err)
+	}
+	namespaces := make(map[string]*core.NamespaceDefinition, len(nsDefs))
+	resourceTypes := make([]string, 0, len(nsDefs))
+	for _, ns := range nsDefs {
+		namespaces[ns.Name] = ns
+		resourceTypes = append(resourceTypes, ns.Name)
+		if err := r.checkSchemaCaveats(ns, caveats); err != nil {
+			return err
+		}
+	}
+
+	if lister, ok := datastore.UnwrapAs[common.RelationshipTypeLister](r.ds); ok {
+		storedTypes, err := lister.ListRelationshipResourceTypes(ctx, rev)
+		if err != nil {
+			return err
+		}
+		for _, resourceType := range storedTypes {
+			if _, ok := namespaces[resourceType]; !ok {
+				resourceTypes = append(resourceTypes, resourceType)
+			}
+		}
+	}
+
+	for _, resourceType := range resourceTypes {
+		if err := r.checkResourceType(ctx, reader, resourceType, namespaces, caveats); err != nil {
+			return fmt.Errorf("unable to check relationships for `%s`: %w", resourceType, err)
+		}
+	}
+	return r.flush(ctx)
+}
+
+func (r *runner) checkSchemaCaveats(ns *core.NamespaceDefinition, caveats map[string]struct{}) error {
+	for _, relation := range ns.Relation {
+		for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+			caveatName := allowed.GetRequiredCaveat().GetCaveatName()
+			if caveatName == "" {
+				continue
+			}
+			if _, ok := caveats[caveatName]; !ok {
+				if err := r.report(Issue{
+					Kind:   DanglingSchemaCaveat,
+					Detail: fmt.Sprintf("relation `%s#%s` allows subjects of type `%s` with undefined caveat `%s`", ns.Name, relation.Name, allowed.Namespace, caveatName),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *runner) checkResourceType(
+	ctx context.Context,
+	reader datastore.Reader,
+	resourceType string,
+	namespaces map[string]*core.NamespaceDefinition,
+	caveats map[string]struct{},
+) error {
+	iter, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: resourceType})
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for rel := iter.Next(); rel != nil; rel = iter.Next() {
+		kind, detail := checkRelationship(rel, namespaces, caveats)
+		if kind == "" {
+			continue
+		}
+
+		reportErr := r.report(Issue{Kind: kind, Relationship: rel, Detail: detail, Fixable: true})
+		if r.opts.Fix {
+			r.pending = append(r.pending, tuple.Delete(rel))
+			if len(r.pending) >= r.opts.BatchSize {
+				if err := r.flush(ctx); err != nil {
+					return err
+				}
+			}
+		}
+		if reportErr != nil {
+			return reportErr
+		}
+	}
+	return iter.Err()
+}
+
+func (r *runner) flush(ctx context.Context) error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+
+	if err := r.waitForBatchInterval(ctx); err != nil {
+		return err
+	}
+
+	if _, err := r.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, r.pending)
+	}); err != nil {
+		return fmt.Errorf("unable to delete relationships: %w", err)
+	}
+
+	r.summary.Fixed += uint64(len(r.pending))
+	log.Ctx(ctx).Info().Int("count", len(r.pending)).Msg("deleted inconsistent relationships")
+	r.pending = nil
+	r.lastFlush = time.Now()
+	return nil
+}
+
+func (r *runner) waitForBatchInterval(ctx context.Context) error {
+	if r.opts.BatchInterval <= 0 || r.lastFlush.IsZero() {
+		return nil
+	}
+
+	wait := time.Until(r.lastFlush.Add(r.opts.BatchInterval))
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func checkRelationship(rel *core.RelationTuple, namespaces map[string]*core.NamespaceDefinition, caveats map[string]struct{}) (IssueKind, string) {
+	resource := rel.ResourceAndRelation
+	ns, ok := namespaces[resource.Namespace]
+	if !ok {
+		return UndefinedResourceType, fmt.Sprintf("resource type `%s` is not defined", resource.Namespace)
+	}
+
+	relation := findRelation(ns, resource.Relation)
+	if relation == nil {
+		return UndefinedRelation, fmt.Sprintf("relation `%s` is not defined on `%s`", resource.Relation, ns.Name)
+	}
+	if relation.UsersetRewrite != nil {
+		return UndefinedRelation, fmt.Sprintf("`%s` is a permission on `%s`, not a relation", resource.Relation, ns.Name)
+	}
+
+	subjectNS, ok := namespaces[rel.Subject.Namespace]
+	if !ok {
+		return UndefinedSubjectType, fmt.Sprintf("subject type `%s` is not defined", rel.Subject.Namespace)
+	}
+	if rel.Subject.Relation != tuple.Ellipsis && findRelation(subjectNS, rel.Subject.Relation) == nil {
+		return UndefinedSubjectRelation, fmt.Sprintf("subject relation `%s` is not defined on `%s`", rel.Subject.Relation, subjectNS.Name)
+	}
+
+	if caveatName := rel.GetCaveat().GetCaveatName(); caveatName != "" {
+		if _, ok := caveats[caveatName]; !ok {
+			return DanglingCaveat, fmt.Sprintf("caveat `%s` is not defined", caveatName)
+		}
+	}
+	return "", ""
+}
+
+func findRelation(ns *core.NamespaceDefinition, name string) *core.Relation {
+	for _, relation := range ns.Relation {
+		if relation.Name == name {
+			return relation
+		}
+	}
+	return nil
+}
+
+func (r *runner) checkMVCC(ctx context.Context, repairer common.MVCCRepairer) error {
+	limit := uint64(r.opts.BatchSize)
+	for {
+		anomalies, err := repairer.FindMVCCAnomalies(ctx, limit)
+		if err != nil {
+			return err
+		}
+		for _, anomaly := range anomalies {
+			if err := r.report(Issue{Kind: MVCCAnomaly, Relationship: anomaly.Relationship, Detail: anomaly.Reason, Fixable: true}); err != nil {
+				return err
+			}
+		}
+
+		// Without deleting the anomalies found, there is no way to page past them.
+		if !r.opts.Fix || len(anomalies) == 0 {
+			return nil
+		}
+
+		deleted, err := repairer.DeleteMVCCAnomalies(ctx, limit)
+		if err != nil {
+			return err
+		}
+		r.summary.Fixed += uint64(deleted)
+		if uint64(len(anomalies)) < limit {
+			return nil
+		}
+	}
+}
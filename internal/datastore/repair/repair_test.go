@@ -0,0 +1,180 @@
+package repair
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/proxy"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// listingDatastore reports relationships on undefined resource types, as SQL datastores do.
+type listingDatastore struct {
+	datastore.Datastore
+	extraTypes []string
+}
+
+func (ld listingDatastore) ListRelationshipResourceTypes(_ context.Context, _ datastore.Revision) ([]string, error) {
+	return append([]string{"document", "folder", "user"}, ld.extraTypes...), nil
+}
+
+type fakeRepairer struct {
+	datastore.Datastore
+	remaining int
+}
+
+func (fr *fakeRepairer) FindMVCCAnomalies(_ context.Context, limit uint64) ([]common.MVCCAnomaly, error) {
+	var anomalies []common.MVCCAnomaly
+	for i := 0; i < fr.remaining && uint64(i) < limit; i++ {
+		anomalies = append(anomalies, common.MVCCAnomaly{
+			Relationship: tuple.MustParse("document:broken#viewer@user:tom"),
+			Reason:       "deleted before created",
+		})
+	}
+	return anomalies, nil
+}
+
+func (fr *fakeRepairer) DeleteMVCCAnomalies(_ context.Context, limit uint64) (int64, error) {
+	deleted := fr.remaining
+	if uint64(deleted) > limit {
+		deleted = int(limit)
+	}
+	fr.remaining -= deleted
+	return int64(deleted), nil
+}
+
+func inconsistentDatastore(t *testing.T) datastore.Datastore {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	testfixtures.StandardDatastoreWithData(rawDS, require.New(t))
+
+	danglingCaveat := tuple.MustParse("document:caveated#viewer@user:tom")
+	danglingCaveat.Caveat = &core.ContextualizedCaveat{CaveatName: "missing"}
+
+	_, err = common.WriteTuples(context.Background(), rawDS, core.RelationTupleUpdate_CREATE,
+		tuple.MustParse("document:first#unknownrel@user:tom"),
+		tuple.MustParse("document:first#view@user:tom"),
+		tuple.MustParse("document:first#viewer@team:eng"),
+		tuple.MustParse("document:first#viewer@folder:company#unknownrel"),
+		tuple.MustParse("deleted:first#viewer@user:tom"),
+		danglingCaveat,
+	)
+	require.NoError(t, err)
+	return listingDatastore{rawDS, []string{"deleted"}}
+}
+
+func TestRunReportsIssues(t *testing.T) {
+	ds := inconsistentDatastore(t)
+
+	var issues []Issue
+	summary, err := Run(context.Background(), ds, Options{OnIssue: func(issue Issue) {
+		issues = append(issues, issue)
+	}})
+	require.NoError(t, err)
+	require.Len(t, issues, 6)
+	require.Equal(t, map[IssueKind]uint64{
+		UndefinedRelation:        2,
+		UndefinedSubjectType:     1,
+		UndefinedSubjectRelation: 1,
+		UndefinedResourceType:    1,
+		DanglingCaveat:           1,
+	}, summary.Issues)
+	require.Zero(t, summary.Fixed)
+
+	// Reporting alone must leave the datastore untouched.
+	summary, err = Run(context.Background(), ds, Options{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), sumIssues(summary))
+}
+
+func TestRunFixesIssuesInBatches(t *testing.T) {
+	ds := inconsistentDatastore(t)
+
+	summary, err := Run(context.Background(), ds, Options{Fix: true, BatchSize: 4})
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), sumIssues(summary))
+	require.Equal(t, uint64(6), summary.Fixed)
+
+	summary, err = Run(context.Background(), ds, Options{})
+	require.NoError(t, err)
+	require.Zero(t, sumIssues(summary))
+}
+
+func TestRunThroughProxies(t *testing.T) {
+	ds := proxy.NewObservableDatastoreProxy(proxy.NewReadonlyDatastore(inconsistentDatastore(t)))
+
+	summary, err := Run(context.Background(), ds, Options{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), summary.Issues[UndefinedResourceType])
+}
+
+func TestRunLimit(t *testing.T) {
+	ds := inconsistentDatastore(t)
+
+	var issues []Issue
+	summary, err := Run(context.Background(), ds, Options{Limit: 2, OnIssue: func(issue Issue) {
+		issues = append(issues, issue)
+	}})
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	require.Equal(t, uint64(2), sumIssues(summary))
+
+	summary, err = Run(context.Background(), ds, Options{Fix: true, Limit: 2})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), summary.Fixed)
+
+	summary, err = Run(context.Background(), ds, Options{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), sumIssues(summary))
+}
+
+func TestRunBatchInterval(t *testing.T) {
+	ds := inconsistentDatastore(t)
+
+	start := time.Now()
+	summary, err := Run(context.Background(), ds, Options{Fix: true, BatchSize: 2, BatchInterval: 25 * time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, uint64(6), summary.Fixed)
+
+	// Three batches are separated by two intervals.
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestRunMVCCAnomalies(t *testing.T) {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	repairer := &fakeRepairer{Datastore: rawDS, remaining: 5}
+	summary, err := Run(context.Background(), repairer, Options{BatchSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), summary.Issues[MVCCAnomaly])
+	require.Equal(t, 5, repairer.remaining)
+
+	summary, err = Run(context.Background(), repairer, Options{Fix: true, BatchSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), summary.Issues[MVCCAnomaly])
+	require.Equal(t, uint64(5), summary.Fixed)
+	require.Zero(t, repairer.remaining)
+
+	repairer.remaining = 3
+	summary, err = Run(context.Background(), repairer, Options{Fix: true, SkipMVCC: true})
+	require.NoError(t, err)
+	require.Zero(t, summary.Issues[MVCCAnomaly])
+	require.Equal(t, 3, repairer.remaining)
+}
+
+func sumIssues(summary Summary) uint64 {
+	var total uint64
+	for _, count := range summary.Issues {
+		total += count
+	}
+	return total
+}
@@ -92,7 +92,14 @@ func (rwt spannerReadWriteTXN) WriteCaveats(ctx context.Context, caveats []*core
 		))
 	}
 
-	return rwt.spannerRWT.BufferWrite(mutations)
+	if err := rwt.spannerRWT.BufferWrite(mutations); err != nil {
+		return err
+	}
+
+	for _, caveat := range caveats {
+		rwt.pending.caveats[caveat.Name] = caveat
+	}
+	return nil
 }
 
 func (rwt spannerReadWriteTXN) DeleteCaveats(ctx context.Context, names []string) error {
@@ -107,7 +114,10 @@ func (rwt spannerReadWriteTXN) DeleteCaveats(ctx context.Context, names []string
 		return fmt.Errorf(errUnableToDeleteCaveat, err)
 	}
 
-	return err
+	for _, n := range names {
+		rwt.pending.caveats[n] = nil
+	}
+	return nil
 }
 
 func ContextualizedCaveatFrom(name spanner.NullString, context spanner.NullJSON) (*core.ContextualizedCaveat, error) {
@@ -0,0 +1,30 @@
+package migrations
+
+import "strings"
+
+func init() {
+	SpannerMigrations.SetLockEstimator(estimateLockImpact)
+}
+
+// estimateLockImpact estimates the impact of a migration statement on concurrent traffic.
+// Spanner applies schema updates as long-running operations without downtime.
+func estimateLockImpact(statement string) string {
+	stmt := strings.ToUpper(strings.TrimSpace(statement))
+
+	switch {
+	case strings.HasPrefix(stmt, "CREATE TABLE"):
+		return "none: the table is new"
+
+	case strings.HasPrefix(stmt, "CREATE INDEX"):
+		return "schema update: reads and writes continue while the index is backfilled"
+
+	case strings.HasPrefix(stmt, "ALTER TABLE"):
+		return "schema update: reads and writes continue"
+
+	case strings.HasPrefix(stmt, "INSERT"), strings.HasPrefix(stmt, "UPDATE"), strings.HasPrefix(stmt, "DELETE"):
+		return "row locks: concurrent transactions touching the affected rows wait"
+
+	default:
+		return "unknown"
+	}
+}
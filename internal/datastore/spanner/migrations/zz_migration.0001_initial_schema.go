@@ -71,4 +71,9 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := SpannerMigrations.Describe("initial", createNamespaceConfig, createRelationTuple, createSchemaVersion, createChangelog,
+		createReverseQueryIndex, createReverseCheckIndex, insertEmptyVersion); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
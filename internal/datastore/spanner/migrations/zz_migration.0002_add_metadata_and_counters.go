@@ -40,4 +40,8 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := SpannerMigrations.Describe("add-metadata-and-counters", createMetadata, createCounters); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
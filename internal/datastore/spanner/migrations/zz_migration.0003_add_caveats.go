@@ -43,4 +43,9 @@ func init() {
 	}, nil); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	if err := SpannerMigrations.Describe("add-caveats", createCaveatTable, addRelationshipCaveatName, addRelationshipCaveatContext,
+		addChangelogCaveatName, addChangelogCaveatContext); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/spanner/admin/database/v1"
+)
+
+// createSubjectTypeIndex creates the index of relationships by subject for reverse
+// queries. Unlike ix_relation_tuple_by_subject, which leads with the subject ID, it leads
+// with the subject type, so it also serves queries for all the subjects of a type or for
+// subject IDs matched by prefix. It stores the caveat of relationships so that reverse
+// queries do not join back to the table.
+const createSubjectTypeIndex = `CREATE INDEX ix_relation_tuple_by_subject_type
+	ON relation_tuple (userset_namespace, userset_object_id, userset_relation, namespace, relation)
+	STORING (caveat_name, caveat_context)`
+
+func init() {
+	if err := SpannerMigrations.Register("add-subject-type-index", "add-caveats", func(ctx context.Context, w Wrapper) error {
+		updateOp, err := w.adminClient.UpdateDatabaseDdl(ctx, &database.UpdateDatabaseDdlRequest{
+			Database:   w.client.DatabaseName(),
+			Statements: []string{createSubjectTypeIndex},
+		})
+		if err != nil {
+			return err
+		}
+		return updateOp.Wait(ctx)
+	}, nil); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+
+	if err := SpannerMigrations.Describe("add-subject-type-index", createSubjectTypeIndex); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
@@ -8,6 +8,7 @@ import (
 type spannerOptions struct {
 	watchBufferLength           uint16
 	revisionQuantization        time.Duration
+	maxRevisionQuantization     time.Duration
 	followerReadDelay           time.Duration
 	maxRevisionStalenessPercent float64
 	gcWindow                    time.Duration
@@ -57,6 +58,14 @@ func generateConfig(options []Option) (spannerOptions, error) {
 		)
 	}
 
+	if computed.maxRevisionQuantization >= computed.gcWindow {
+		return computed, fmt.Errorf(
+			errQuantizationTooLarge,
+			computed.maxRevisionQuantization,
+			computed.gcWindow,
+		)
+	}
+
 	return computed, nil
 }
 
@@ -80,6 +89,18 @@ func RevisionQuantization(bucketSize time.Duration) Option {
 	}
 }
 
+// MaxRevisionQuantization is the largest time bucket size to which advertised
+// revisions will be rounded. If above RevisionQuantization, the bucket size adapts
+// between the two, widening while writes are frequent and the dispatch cache hit ratio
+// is low.
+//
+// This value defaults to 0, for a bucket size which does not adapt.
+func MaxRevisionQuantization(bucketSize time.Duration) Option {
+	return func(so *spannerOptions) {
+		so.maxRevisionQuantization = bucketSize
+	}
+}
+
 // FollowerReadDelay is the time delay to apply to enable historial reads.
 //
 // This value defaults to 0 seconds.
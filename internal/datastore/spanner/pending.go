@@ -0,0 +1,207 @@
+package spanner
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// pendingWrites holds the writes buffered as mutations in a read-write transaction.
+// Spanner applies buffered mutations only when the transaction commits, so the reads of
+// the transaction overlay them on what they read for the writes made earlier in the
+// transaction to be observed. A nil value records a deletion.
+type pendingWrites struct {
+	relationships map[string]*core.RelationTuple
+	namespaces    map[string]*core.NamespaceDefinition
+	caveats       map[string]*core.CaveatDefinition
+}
+
+func newPendingWrites() *pendingWrites {
+	return &pendingWrites{
+		relationships: make(map[string]*core.RelationTuple),
+		namespaces:    make(map[string]*core.NamespaceDefinition),
+		caveats:       make(map[string]*core.CaveatDefinition),
+	}
+}
+
+func (pw *pendingWrites) writeRelationship(mutation *core.RelationTupleUpdate) {
+	if mutation.Operation == core.RelationTupleUpdate_DELETE {
+		pw.relationships[tuple.String(mutation.Tuple)] = nil
+		return
+	}
+	pw.relationships[tuple.String(mutation.Tuple)] = mutation.Tuple
+}
+
+// overlayRelationships returns an iterator of the relationships read by iter, except
+// those written or deleted in the transaction, and of the relationships written in the
+// transaction which match, up to the limit, if any.
+func (pw *pendingWrites) overlayRelationships(
+	iter datastore.RelationshipIterator,
+	matches func(tpl *core.RelationTuple) bool,
+	limit *uint64,
+) (datastore.RelationshipIterator, error) {
+	defer iter.Close()
+
+	var found []*core.RelationTuple
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		if _, ok := pw.relationships[tuple.String(tpl)]; !ok {
+			found = append(found, tpl)
+		}
+	}
+	if iter.Err() != nil {
+		return nil, iter.Err()
+	}
+
+	for _, tpl := range pw.relationships {
+		if tpl != nil && matches(tpl) {
+			found = append(found, tpl)
+		}
+	}
+
+	if limit != nil && uint64(len(found)) > *limit {
+		found = found[:*limit]
+	}
+	return datastore.NewSliceRelationshipIterator(found), nil
+}
+
+func (rwt spannerReadWriteTXN) QueryRelationships(
+	ctx context.Context,
+	filter datastore.RelationshipsFilter,
+	opts ...options.QueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	if len(rwt.pending.relationships) == 0 {
+		return rwt.spannerReader.QueryRelationships(ctx, filter, opts...)
+	}
+
+	// The limit is applied once the pending relationships are overlaid, as some of those
+	// read may have since been deleted.
+	queryOpts := options.NewQueryOptionsWithOptions(opts...)
+	iter, err := rwt.spannerReader.QueryRelationships(ctx, filter, options.SetUsersets(queryOpts.Usersets))
+	if err != nil {
+		return nil, err
+	}
+
+	return rwt.pending.overlayRelationships(iter, func(tpl *core.RelationTuple) bool {
+		if !filter.Test(tpl) {
+			return false
+		}
+		if len(queryOpts.Usersets) == 0 {
+			return true
+		}
+		for _, userset := range queryOpts.Usersets {
+			if userset.Namespace == tpl.Subject.Namespace &&
+				userset.ObjectId == tpl.Subject.ObjectId &&
+				userset.Relation == tpl.Subject.Relation {
+				return true
+			}
+		}
+		return false
+	}, queryOpts.Limit)
+}
+
+func (rwt spannerReadWriteTXN) ReverseQueryRelationships(
+	ctx context.Context,
+	subjectsFilter datastore.SubjectsFilter,
+	opts ...options.ReverseQueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	if len(rwt.pending.relationships) == 0 {
+		return rwt.spannerReader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+	}
+
+	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
+	iter, err := rwt.spannerReader.ReverseQueryRelationships(ctx, subjectsFilter, options.WithResRelation(queryOpts.ResRelation))
+	if err != nil {
+		return nil, err
+	}
+
+	return rwt.pending.overlayRelationships(iter, func(tpl *core.RelationTuple) bool {
+		if !subjectsFilter.Test(tpl) {
+			return false
+		}
+		return queryOpts.ResRelation == nil ||
+			(tpl.ResourceAndRelation.Namespace == queryOpts.ResRelation.Namespace &&
+				tpl.ResourceAndRelation.Relation == queryOpts.ResRelation.Relation)
+	}, queryOpts.ReverseLimit)
+}
+
+// ReadNamespace returns the namespaces written in the transaction with no revision, as
+// they have none until it commits.
+func (rwt spannerReadWriteTXN) ReadNamespace(ctx context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
+	if ns, ok := rwt.pending.namespaces[nsName]; ok {
+		if ns == nil {
+			return nil, datastore.NoRevision, datastore.NewNamespaceNotFoundErr(nsName)
+		}
+		return ns, datastore.NoRevision, nil
+	}
+	return rwt.spannerReader.ReadNamespace(ctx, nsName)
+}
+
+func (rwt spannerReadWriteTXN) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefinition, error) {
+	found, err := rwt.spannerReader.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return overlayDefinitions(found, rwt.pending.namespaces, nil), nil
+}
+
+func (rwt spannerReadWriteTXN) LookupNamespaces(ctx context.Context, nsNames []string) ([]*core.NamespaceDefinition, error) {
+	found, err := rwt.spannerReader.LookupNamespaces(ctx, nsNames)
+	if err != nil {
+		return nil, err
+	}
+	return overlayDefinitions(found, rwt.pending.namespaces, nsNames), nil
+}
+
+func (rwt spannerReadWriteTXN) ReadCaveatByName(ctx context.Context, name string) (*core.CaveatDefinition, datastore.Revision, error) {
+	if caveat, ok := rwt.pending.caveats[name]; ok {
+		if caveat == nil {
+			return nil, datastore.NoRevision, datastore.NewCaveatNameNotFoundErr(name)
+		}
+		return caveat, datastore.NoRevision, nil
+	}
+	return rwt.spannerReader.ReadCaveatByName(ctx, name)
+}
+
+func (rwt spannerReadWriteTXN) ListCaveats(ctx context.Context, caveatNames ...string) ([]*core.CaveatDefinition, error) {
+	found, err := rwt.spannerReader.ListCaveats(ctx, caveatNames...)
+	if err != nil {
+		return nil, err
+	}
+	return overlayDefinitions(found, rwt.pending.caveats, caveatNames), nil
+}
+
+type definition interface {
+	comparable
+	GetName() string
+}
+
+// overlayDefinitions returns the definitions read, except those written or deleted in
+// the transaction, and the definitions written in the transaction, restricted to the
+// names given, if any.
+func overlayDefinitions[T definition](found []T, pending map[string]T, names []string) []T {
+	if len(pending) == 0 {
+		return found
+	}
+
+	overlaid := make([]T, 0, len(found)+len(pending))
+	for _, def := range found {
+		if _, ok := pending[def.GetName()]; !ok {
+			overlaid = append(overlaid, def)
+		}
+	}
+
+	wanted := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		wanted[name] = struct{}{}
+	}
+	var deleted T
+	for name, def := range pending {
+		if _, ok := wanted[name]; def != deleted && (len(names) == 0 || ok) {
+			overlaid = append(overlaid, def)
+		}
+	}
+	return overlaid
+}
@@ -2,10 +2,13 @@ package spanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"cloud.google.com/go/spanner"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
@@ -46,7 +49,7 @@ func (sr spannerReader) ReverseQueryRelationships(
 	subjectsFilter datastore.SubjectsFilter,
 	opts ...options.ReverseQueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuples).
+	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuplesBySubject).
 		FilterWithSubjectsFilter(subjectsFilter)
 
 	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
@@ -68,49 +71,60 @@ func queryExecutor(txSource txFactory) common.ExecuteQueryFunc {
 		ctx context.Context,
 		sql string,
 		args []interface{},
-	) ([]*core.RelationTuple, error) {
+	) (common.TupleRows, error) {
 		ctx, span := tracer.Start(ctx, "ExecuteQuery")
-		defer span.End()
-
-		iter := txSource().Query(ctx, statementFromSQL(sql, args))
-
-		var tuples []*core.RelationTuple
-
-		if err := iter.Do(func(row *spanner.Row) error {
-			nextTuple := &core.RelationTuple{
-				ResourceAndRelation: &core.ObjectAndRelation{},
-				Subject:             &core.ObjectAndRelation{},
-			}
-			var caveatName spanner.NullString
-			var caveatCtx spanner.NullJSON
-			err := row.Columns(
-				&nextTuple.ResourceAndRelation.Namespace,
-				&nextTuple.ResourceAndRelation.ObjectId,
-				&nextTuple.ResourceAndRelation.Relation,
-				&nextTuple.Subject.Namespace,
-				&nextTuple.Subject.ObjectId,
-				&nextTuple.Subject.Relation,
-				&caveatName,
-				&caveatCtx,
-			)
-			if err != nil {
-				return err
-			}
-
-			nextTuple.Caveat, err = ContextualizedCaveatFrom(caveatName, caveatCtx)
-			if err != nil {
-				return err
-			}
-
-			tuples = append(tuples, nextTuple)
-
-			return nil
-		}); err != nil {
-			return nil, err
-		}
+		return &spannerTupleRows{
+			span: span,
+			iter: txSource().Query(ctx, statementFromSQL(sql, args)),
+		}, nil
+	}
+}
 
-		return tuples, nil
+// spannerTupleRows reads tuples from the rows of a query.
+type spannerTupleRows struct {
+	span trace.Span
+	iter *spanner.RowIterator
+}
+
+func (r *spannerTupleRows) Next() (*core.RelationTuple, error) {
+	row, err := r.iter.Next()
+	if errors.Is(err, iterator.Done) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	nextTuple := &core.RelationTuple{
+		ResourceAndRelation: &core.ObjectAndRelation{},
+		Subject:             &core.ObjectAndRelation{},
 	}
+	var caveatName spanner.NullString
+	var caveatCtx spanner.NullJSON
+	err = row.Columns(
+		&nextTuple.ResourceAndRelation.Namespace,
+		&nextTuple.ResourceAndRelation.ObjectId,
+		&nextTuple.ResourceAndRelation.Relation,
+		&nextTuple.Subject.Namespace,
+		&nextTuple.Subject.ObjectId,
+		&nextTuple.Subject.Relation,
+		&caveatName,
+		&caveatCtx,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	nextTuple.Caveat, err = ContextualizedCaveatFrom(caveatName, caveatCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	return nextTuple, nil
+}
+
+func (r *spannerTupleRows) Close() {
+	r.iter.Stop()
+	r.span.End()
 }
 
 func (sr spannerReader) ReadNamespace(ctx context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
@@ -217,6 +231,10 @@ var queryTuples = sql.Select(
 	colCaveatContext,
 ).From(tableRelationship)
 
+// queryTuplesBySubject reads relationships through the index by subject type, which
+// serves the subject-first scans of reverse queries.
+var queryTuplesBySubject = queryTuples.From(fmt.Sprintf("%s@{FORCE_INDEX=%s}", tableRelationship, indexRelationshipBySubjectType))
+
 var schema = common.SchemaInformation{
 	ColNamespace:        colNamespace,
 	ColObjectID:         colObjectID,
@@ -14,11 +14,13 @@ import (
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 type spannerReadWriteTXN struct {
 	spannerReader
 	spannerRWT *spanner.ReadWriteTransaction
+	pending    *pendingWrites
 }
 
 func (rwt spannerReadWriteTXN) WriteRelationships(ctx context.Context, mutations []*core.RelationTupleUpdate) error {
@@ -54,6 +56,7 @@ func (rwt spannerReadWriteTXN) WriteRelationships(ctx context.Context, mutations
 		if err := rwt.spannerRWT.BufferWrite([]*spanner.Mutation{txnMut, changelogMut}); err != nil {
 			return fmt.Errorf(errUnableToWriteRelationships, err)
 		}
+		rwt.pending.writeRelationship(mutation)
 	}
 
 	if err := updateCounter(ctx, rwt.spannerRWT, rowCountChange); err != nil {
@@ -64,13 +67,42 @@ func (rwt spannerReadWriteTXN) WriteRelationships(ctx context.Context, mutations
 }
 
 func (rwt spannerReadWriteTXN) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
-	err := deleteWithFilter(ctx, rwt.spannerRWT, filter)
+	err := rwt.deleteRelationships(ctx, filter)
 	if err != nil {
 		return fmt.Errorf(errUnableToDeleteRelationships, err)
 	}
 	return nil
 }
 
+// deleteRelationships deletes the relationships matching the filter. A DML delete does not
+// see the relationships written earlier in the transaction, which are still buffered as
+// mutations, so if there are any, the relationships are read through the transaction and
+// deleted with mutations instead.
+func (rwt spannerReadWriteTXN) deleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
+	if len(rwt.pending.relationships) == 0 {
+		return deleteWithFilter(ctx, rwt.spannerRWT, filter)
+	}
+
+	iter, err := rwt.QueryRelationships(ctx, datastore.RelationshipsFilterFromPublicFilter(filter))
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	var mutations []*core.RelationTupleUpdate
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		mutations = append(mutations, tuple.Delete(tpl))
+	}
+	if iter.Err() != nil {
+		return iter.Err()
+	}
+
+	if len(mutations) == 0 {
+		return nil
+	}
+	return rwt.WriteRelationships(ctx, mutations)
+}
+
 type selectAndDelete struct {
 	sel sq.SelectBuilder
 	del sq.DeleteBuilder
@@ -235,12 +267,19 @@ func (rwt spannerReadWriteTXN) WriteNamespaces(ctx context.Context, newConfigs .
 		))
 	}
 
-	return rwt.spannerRWT.BufferWrite(mutations)
+	if err := rwt.spannerRWT.BufferWrite(mutations); err != nil {
+		return err
+	}
+
+	for _, newConfig := range newConfigs {
+		rwt.pending.namespaces[newConfig.Name] = newConfig
+	}
+	return nil
 }
 
 func (rwt spannerReadWriteTXN) DeleteNamespaces(ctx context.Context, nsNames ...string) error {
 	for _, nsName := range nsNames {
-		if err := deleteWithFilter(ctx, rwt.spannerRWT, &v1.RelationshipFilter{
+		if err := rwt.deleteRelationships(ctx, &v1.RelationshipFilter{
 			ResourceType: nsName,
 		}); err != nil {
 			return fmt.Errorf(errUnableToDeleteConfig, err)
@@ -252,6 +291,7 @@ func (rwt spannerReadWriteTXN) DeleteNamespaces(ctx context.Context, nsNames ...
 		if err != nil {
 			return fmt.Errorf(errUnableToDeleteConfig, err)
 		}
+		rwt.pending.namespaces[nsName] = nil
 	}
 
 	return nil
@@ -8,6 +8,7 @@ import (
 	"cloud.google.com/go/spanner"
 	"github.com/shopspring/decimal"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 )
@@ -52,3 +53,11 @@ func revisionFromTimestamp(t time.Time) revision.Decimal {
 func timestampFromRevision(r revision.Decimal) time.Time {
 	return time.Unix(0, r.IntPart())
 }
+
+// RevisionCommitTime returns the time of the revision, which is the commit timestamp of
+// its transaction.
+func (sd spannerDatastore) RevisionCommitTime(rev datastore.Revision) (time.Time, bool) {
+	return common.DecimalCommitTime(rev)
+}
+
+var _ common.CommitTimeReporter = spannerDatastore{}
@@ -21,6 +21,8 @@ const (
 	colCaveatName       = "caveat_name"
 	colCaveatContext    = "caveat_context"
 
+	indexRelationshipBySubjectType = "ix_relation_tuple_by_subject_type"
+
 	tableChangelog            = "changelog"
 	colChangeUUID             = "uuid"
 	colChangeTS               = "timestamp"
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/spanner"
@@ -67,6 +68,8 @@ type spannerDatastore struct {
 	client *spanner.Client
 	config spannerOptions
 	stopGC context.CancelFunc
+
+	uniqueID *atomic.Pointer[string]
 }
 
 // NewSpannerDatastore returns a datastore backed by cloud spanner
@@ -99,10 +102,11 @@ func NewSpannerDatastore(database string, opts ...Option) (datastore.Datastore,
 			config.gcWindow,
 			maxRevisionStaleness,
 			config.followerReadDelay,
-			config.revisionQuantization,
+			revisions.NewAdaptiveQuantization(config.revisionQuantization, config.maxRevisionQuantization),
 		),
-		client: client,
-		config: config,
+		client:   client,
+		config:   config,
+		uniqueID: &atomic.Pointer[string]{},
 	}
 	ds.RemoteClockRevisions.SetNowFunc(ds.headRevisionInternal)
 
@@ -147,7 +151,7 @@ func (sd spannerDatastore) ReadWriteTx(
 			Executor:         queryExecutor(txSource),
 			UsersetBatchSize: usersetBatchsize,
 		}
-		rwt := spannerReadWriteTXN{spannerReader{querySplitter, txSource}, spannerRWT}
+		rwt := spannerReadWriteTXN{spannerReader{querySplitter, txSource}, spannerRWT, newPendingWrites()}
 		return fn(rwt)
 	})
 	if err != nil {
@@ -157,6 +161,7 @@ func (sd spannerDatastore) ReadWriteTx(
 		return datastore.NoRevision, err
 	}
 
+	sd.RevisionQuantization().RecordWrite()
 	return revisionFromTimestamp(ts), nil
 }
 
@@ -4,14 +4,23 @@
 package spanner
 
 import (
+	"context"
+	"fmt"
+	"os"
 	"testing"
 	"time"
 
+	"cloud.google.com/go/spanner"
 	"github.com/stretchr/testify/require"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/spanner/migrations"
 	testdatastore "github.com/authzed/spicedb/internal/testserver/datastore"
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/test"
+	"github.com/authzed/spicedb/pkg/migrate"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 func TestSpannerDatastore(t *testing.T) {
@@ -25,3 +34,77 @@ func TestSpannerDatastore(t *testing.T) {
 		return ds, nil
 	}))
 }
+
+// TestSubjectTypeIndex checks that the add-subject-type-index migration creates the
+// index by subject type, and that reverse queries for all the subjects of a type read
+// through it. The emulator returns no query plans, so the queries are checked to force
+// the index instead, which fails them if it does not exist.
+func TestSubjectTypeIndex(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	db := testdatastore.RunSpannerForTesting(t, "").NewDatabase(t)
+	client, err := spanner.NewClient(ctx, db)
+	require.NoError(err)
+	defer client.Close()
+
+	hasIndex := func() bool {
+		var count int64
+		require.NoError(client.Single().Query(ctx, spanner.Statement{
+			SQL:    "SELECT COUNT(*) FROM information_schema.indexes WHERE table_name = @table AND index_name = @index",
+			Params: map[string]any{"table": tableRelationship, "index": indexRelationshipBySubjectType},
+		}).Do(func(row *spanner.Row) error {
+			return row.Columns(&count)
+		}))
+		return count > 0
+	}
+
+	migrationDriver, err := migrations.NewSpannerDriver(db, "", os.Getenv("SPANNER_EMULATOR_HOST"))
+	require.NoError(err)
+	require.NoError(migrations.SpannerMigrations.Run(ctx, migrationDriver, "add-caveats", migrate.LiveRun))
+	require.False(hasIndex())
+
+	require.NoError(migrations.SpannerMigrations.Run(ctx, migrationDriver, "add-subject-type-index", migrate.LiveRun))
+	require.True(hasIndex())
+
+	require.NoError(migrations.SpannerMigrations.Run(ctx, migrationDriver, migrate.Head, migrate.LiveRun))
+	require.True(hasIndex())
+
+	ds, err := NewSpannerDatastore(db)
+	require.NoError(err)
+	defer ds.Close()
+
+	tuples := make([]*core.RelationTuple, 0, 10)
+	for i := 0; i < 10; i++ {
+		subjectType := "user"
+		if i%2 != 0 {
+			subjectType = "team"
+		}
+		tuples = append(tuples, tuple.MustParse(fmt.Sprintf("resource:%d#reader@%s:%d", i, subjectType, i)))
+	}
+	rev, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, tuples...)
+	require.NoError(err)
+
+	var queries []string
+	reader := ds.SnapshotReader(rev).(spannerReader)
+	executor := reader.querySplitter.Executor
+	reader.querySplitter.Executor = func(ctx context.Context, sql string, args []any) (common.TupleRows, error) {
+		queries = append(queries, sql)
+		return executor(ctx, sql, args)
+	}
+
+	iter, err := reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{SubjectType: "user"})
+	require.NoError(err)
+	defer iter.Close()
+
+	found := 0
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		require.Equal("user", tpl.Subject.Namespace)
+		found++
+	}
+	require.NoError(iter.Err())
+	require.Equal(5, found)
+
+	require.Len(queries, 1)
+	require.Contains(queries[0], fmt.Sprintf("%s@{FORCE_INDEX=%s}", tableRelationship, indexRelationshipBySubjectType))
+}
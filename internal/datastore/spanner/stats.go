@@ -14,6 +14,37 @@ import (
 
 var queryRelationshipEstimate = fmt.Sprintf("SELECT SUM(%s) FROM %s", colCount, tableCounters)
 
+func (sd spannerDatastore) UniqueID(ctx context.Context) (string, error) {
+	if uniqueID := sd.uniqueID.Load(); uniqueID != nil {
+		return *uniqueID, nil
+	}
+
+	uniqueID, err := sd.readUniqueID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	sd.uniqueID.Store(&uniqueID)
+	return uniqueID, nil
+}
+
+func (sd spannerDatastore) readUniqueID(ctx context.Context) (string, error) {
+	rows := sd.client.Single().Read(ctx, tableMetadata, spanner.AllKeys(), []string{colUniqueID})
+	defer rows.Stop()
+
+	row, err := rows.Next()
+	if err != nil {
+		return "", fmt.Errorf("unable to read metadata table: %w", err)
+	}
+
+	var uniqueID string
+	if err := row.Columns(&uniqueID); err != nil {
+		return "", fmt.Errorf("unable to read unique ID: %w", err)
+	}
+
+	return uniqueID, nil
+}
+
 func (sd spannerDatastore) Statistics(ctx context.Context) (datastore.Stats, error) {
 	idRows := sd.client.Single().Read(
 		context.Background(),
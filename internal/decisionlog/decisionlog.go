@@ -0,0 +1,391 @@
+// Package decisionlog records a sample of the decisions of checks, with their inputs and
+// the revision and version of the schema they were decided at, as files in an object
+// store, so that the behavior of authorization can be analyzed offline and compared over
+// time. Unlike an audit log, the decision log is sampled and may drop decisions under
+// load rather than delay the checks it records.
+package decisionlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/authzed/spicedb/internal/datastore/archive"
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// Format is the format of the files of a decision log.
+type Format string
+
+const (
+	// FormatParquet writes each batch of decisions as a Parquet file, for analysis with
+	// columnar query engines.
+	FormatParquet Format = "parquet"
+
+	// FormatNDJSON writes each batch of decisions as newline-delimited JSON, with one
+	// decision per line and keys in a stable order, so that logs can be compared with
+	// line-oriented tools such as diff.
+	FormatNDJSON Format = "ndjson"
+)
+
+const (
+	// DefaultFlushInterval is the default maximum interval between the writes of the
+	// decisions logged.
+	DefaultFlushInterval = time.Minute
+
+	// DefaultBatchSize is the default maximum number of decisions written per file.
+	DefaultBatchSize = 10_000
+
+	// flushTimeout bounds the write of the last batch once the log is stopped.
+	flushTimeout = 10 * time.Second
+)
+
+var decisionsLogged = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "decisionlog",
+	Name:      "decisions_total",
+	Help:      "number of sampled decisions, by whether they were written, dropped because the log was full, or failed to be written",
+}, []string{"result"})
+
+// Decision is a decision of a check and its inputs.
+type Decision struct {
+	CheckedAt       time.Time
+	ResourceType    string
+	ResourceID      string
+	Permission      string
+	SubjectType     string
+	SubjectID       string
+	SubjectRelation string
+	CaveatContext   map[string]any
+
+	// Permissionship is `has_permission`, `no_permission` or `conditional_permission`,
+	// or empty if the check failed with Error.
+	Permissionship string
+	Error          string
+
+	// Revision is the revision at which the check was decided, if known.
+	Revision datastore.Revision
+}
+
+// Config configures a decision log.
+type Config struct {
+	// Store is where the files of the log are written.
+	Store archive.ObjectStore
+
+	// Datastore is read for the versions of the schema at which decisions are made, if
+	// it records them.
+	Datastore datastore.Datastore
+
+	// SampleRate is the fraction of decisions recorded, in (0, 1].
+	SampleRate float64
+
+	// Format is the format of the files, defaulting to FormatParquet.
+	Format Format
+
+	// FlushInterval is the maximum interval between writes, defaulting to
+	// DefaultFlushInterval.
+	FlushInterval time.Duration
+
+	// BatchSize is the maximum number of decisions per file, defaulting to
+	// DefaultBatchSize. Decisions logged while a full batch is being written are dropped.
+	BatchSize int
+}
+
+// Logger records a sample of decisions, and writes them in batches once started. A nil
+// Logger records no decisions.
+type Logger struct {
+	config    Config
+	decisions chan Decision
+	sequence  atomic.Uint64
+	now       func() time.Time
+}
+
+// NewLogger creates a decision log with the configuration.
+func NewLogger(config Config) (*Logger, error) {
+	if config.Store == nil {
+		return nil, errors.New("a decision log requires a store")
+	}
+	if config.SampleRate <= 0 || config.SampleRate > 1 {
+		return nil, fmt.Errorf("decision log sample rate must be in (0, 1], got %v", config.SampleRate)
+	}
+
+	switch config.Format {
+	case "":
+		config.Format = FormatParquet
+	case FormatParquet, FormatNDJSON:
+	default:
+		return nil, fmt.Errorf("unknown decision log format `%s`; expected %s or %s", config.Format, FormatParquet, FormatNDJSON)
+	}
+
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+
+	return &Logger{
+		config:    config,
+		decisions: make(chan Decision, config.BatchSize),
+		now:       time.Now,
+	}, nil
+}
+
+// Log records the decision, if sampled. It never blocks: the decision is dropped if the
+// log is full.
+func (l *Logger) Log(decision Decision) {
+	if l == nil || rand.Float64() >= l.config.SampleRate { //nolint:gosec
+		return
+	}
+
+	select {
+	case l.decisions <- decision:
+	default:
+		decisionsLogged.WithLabelValues("dropped").Inc()
+	}
+}
+
+// Start writes the decisions logged in batches, whenever a batch is full or the flush
+// interval has passed, until the context is canceled, when the last batch is written.
+func (l *Logger) Start(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(l.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Decision, 0, l.config.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			// Write whatever was logged before the cancellation.
+			for len(l.decisions) > 0 && len(batch) < l.config.BatchSize {
+				batch = append(batch, <-l.decisions)
+			}
+			flushCtx, cancel := context.WithTimeout(log.Ctx(ctx).WithContext(context.Background()), flushTimeout)
+			l.flush(flushCtx, batch)
+			cancel()
+			return nil
+
+		case decision := <-l.decisions:
+			batch = append(batch, decision)
+			if len(batch) < l.config.BatchSize {
+				continue
+			}
+
+		case <-ticker.C:
+		}
+
+		l.flush(ctx, batch)
+		batch = batch[:0]
+	}
+}
+
+// flush writes the batch of decisions as a file, logging rather than returning failures.
+func (l *Logger) flush(ctx context.Context, batch []Decision) {
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := l.write(ctx, batch); err != nil {
+		decisionsLogged.WithLabelValues("failed").Add(float64(len(batch)))
+		log.Ctx(ctx).Warn().Err(err).Int("decisions", len(batch)).Msg("unable to write decision log")
+		return
+	}
+	decisionsLogged.WithLabelValues("written").Add(float64(len(batch)))
+}
+
+func (l *Logger) write(ctx context.Context, batch []Decision) error {
+	versions, err := l.schemaVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to read schema versions: %w", err)
+	}
+
+	records := make([]record, 0, len(batch))
+	for _, decision := range batch {
+		records = append(records, newRecord(decision, versions))
+	}
+
+	var buf bytes.Buffer
+	switch l.config.Format {
+	case FormatNDJSON:
+		err = writeNDJSON(&buf, records)
+	default:
+		err = writeParquet(&buf, records)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to encode decisions: %w", err)
+	}
+
+	now := l.now().UTC()
+	key := fmt.Sprintf("decisions/%s/%d-%06d.%s", now.Format("2006/01/02"), now.UnixNano(), l.sequence.Add(1), l.config.Format)
+	return l.config.Store.Put(ctx, key, buf.Bytes())
+}
+
+// schemaVersions returns the versions of the schema, oldest first, or none if the
+// datastore does not record them.
+func (l *Logger) schemaVersions(ctx context.Context) ([]datastore.SchemaVersion, error) {
+	if l.config.Datastore == nil {
+		return nil, nil
+	}
+
+	store, err := common.SchemaHistoryStoreOf(l.config.Datastore)
+	if err != nil {
+		return nil, nil
+	}
+
+	versions, err := store.ListSchemaVersions(ctx)
+	if errors.As(err, &datastore.ErrSchemaHistoryUnsupported{}) {
+		return nil, nil
+	}
+	return versions, err
+}
+
+// schemaVersionAt returns the latest of the versions written at or before the revision.
+func schemaVersionAt(versions []datastore.SchemaVersion, revision datastore.Revision) *uint64 {
+	if !hasRevision(revision) {
+		return nil
+	}
+
+	var found *uint64
+	for index := range versions {
+		if versions[index].Revision.GreaterThan(revision) {
+			break
+		}
+		found = &versions[index].Version
+	}
+	return found
+}
+
+func hasRevision(revision datastore.Revision) bool {
+	return revision != nil && revision != datastore.NoRevision
+}
+
+// record is a decision as written, with fields in the order of their columns.
+type record struct {
+	CheckedAt       time.Time      `json:"checked_at"`
+	ResourceType    string         `json:"resource_type"`
+	ResourceID      string         `json:"resource_id"`
+	Permission      string         `json:"permission"`
+	SubjectType     string         `json:"subject_type"`
+	SubjectID       string         `json:"subject_id"`
+	SubjectRelation string         `json:"subject_relation,omitempty"`
+	CaveatContext   map[string]any `json:"caveat_context,omitempty"`
+	Permissionship  string         `json:"permissionship,omitempty"`
+	Error           string         `json:"error,omitempty"`
+	Revision        string         `json:"revision,omitempty"`
+	SchemaVersion   *uint64        `json:"schema_version,omitempty"`
+}
+
+func newRecord(decision Decision, versions []datastore.SchemaVersion) record {
+	var revision string
+	if hasRevision(decision.Revision) {
+		revision = decision.Revision.String()
+	}
+
+	return record{
+		CheckedAt:       decision.CheckedAt.UTC(),
+		ResourceType:    decision.ResourceType,
+		ResourceID:      decision.ResourceID,
+		Permission:      decision.Permission,
+		SubjectType:     decision.SubjectType,
+		SubjectID:       decision.SubjectID,
+		SubjectRelation: decision.SubjectRelation,
+		CaveatContext:   decision.CaveatContext,
+		Permissionship:  decision.Permissionship,
+		Error:           decision.Error,
+		Revision:        revision,
+		SchemaVersion:   schemaVersionAt(versions, decision.Revision),
+	}
+}
+
+// writeNDJSON writes the records as newline-delimited JSON. Maps, such as the caveat
+// context, are encoded with sorted keys.
+func writeNDJSON(buf *bytes.Buffer, records []record) error {
+	encoder := json.NewEncoder(buf)
+	for _, rec := range records {
+		if err := encoder.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeParquet writes the records as a Parquet file, with null values for the empty
+// optional fields.
+func writeParquet(buf *bytes.Buffer, records []record) error {
+	checkedAt := archive.TimestampParquetColumn("checked_at", false)
+	resourceType := archive.StringParquetColumn("resource_type", false)
+	resourceID := archive.StringParquetColumn("resource_id", false)
+	permission := archive.StringParquetColumn("permission", false)
+	subjectType := archive.StringParquetColumn("subject_type", false)
+	subjectID := archive.StringParquetColumn("subject_id", false)
+	subjectRelation := archive.StringParquetColumn("subject_relation", true)
+	caveatContext := archive.JSONParquetColumn("caveat_context", true)
+	permissionship := archive.StringParquetColumn("permissionship", true)
+	decisionError := archive.StringParquetColumn("error", true)
+	revision := archive.StringParquetColumn("revision", true)
+	schemaVersion := archive.Uint64ParquetColumn("schema_version", true)
+
+	for _, rec := range records {
+		checkedAt.Append(rec.CheckedAt.UnixMicro())
+		resourceType.Append(rec.ResourceType)
+		resourceID.Append(rec.ResourceID)
+		permission.Append(rec.Permission)
+		subjectType.Append(rec.SubjectType)
+		subjectID.Append(rec.SubjectID)
+		subjectRelation.Append(optionalString(rec.SubjectRelation))
+		permissionship.Append(optionalString(rec.Permissionship))
+		decisionError.Append(optionalString(rec.Error))
+		revision.Append(optionalString(rec.Revision))
+
+		if len(rec.CaveatContext) > 0 {
+			serialized, err := json.Marshal(rec.CaveatContext)
+			if err != nil {
+				return fmt.Errorf("unable to serialize caveat context: %w", err)
+			}
+			caveatContext.Append(string(serialized))
+		} else {
+			caveatContext.Append(nil)
+		}
+
+		if rec.SchemaVersion != nil {
+			schemaVersion.Append(int64(*rec.SchemaVersion))
+		} else {
+			schemaVersion.Append(nil)
+		}
+	}
+
+	return archive.WriteParquetTable(buf, "decision", []*archive.ParquetColumn{
+		checkedAt,
+		resourceType,
+		resourceID,
+		permission,
+		subjectType,
+		subjectID,
+		subjectRelation,
+		caveatContext,
+		permissionship,
+		decisionError,
+		revision,
+		schemaVersion,
+	}, len(records))
+}
+
+func optionalString(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,152 @@
+package decisionlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+type fakeStore struct {
+	sync.Mutex
+	objects map[string][]byte
+}
+
+func (fs *fakeStore) Put(_ context.Context, key string, data []byte) error {
+	fs.Lock()
+	defer fs.Unlock()
+	fs.objects[key] = data
+	return nil
+}
+
+func (fs *fakeStore) keys() []string {
+	fs.Lock()
+	defer fs.Unlock()
+	keys := make([]string, 0, len(fs.objects))
+	for key := range fs.objects {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestNewLogger(t *testing.T) {
+	store := &fakeStore{objects: map[string][]byte{}}
+
+	_, err := NewLogger(Config{SampleRate: 1})
+	require.Error(t, err)
+	_, err = NewLogger(Config{Store: store})
+	require.Error(t, err)
+	_, err = NewLogger(Config{Store: store, SampleRate: 1.5})
+	require.Error(t, err)
+	_, err = NewLogger(Config{Store: store, SampleRate: 1, Format: "csv"})
+	require.Error(t, err)
+
+	logger, err := NewLogger(Config{Store: store, SampleRate: 1})
+	require.NoError(t, err)
+	require.Equal(t, FormatParquet, logger.config.Format)
+	require.Equal(t, DefaultBatchSize, logger.config.BatchSize)
+
+	var nilLogger *Logger
+	nilLogger.Log(Decision{})
+	require.NoError(t, nilLogger.Start(context.Background()))
+}
+
+func TestLogger(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	first, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	store, err := common.SchemaHistoryStoreOf(ds)
+	require.NoError(err)
+	_, err = store.AddSchemaVersion(ctx, first, "definition user {}", "")
+	require.NoError(err)
+	second, err := ds.ReadWriteTx(ctx, func(datastore.ReadWriteTransaction) error { return nil })
+	require.NoError(err)
+
+	for _, format := range []Format{FormatNDJSON, FormatParquet} {
+		t.Run(string(format), func(t *testing.T) {
+			store := &fakeStore{objects: map[string][]byte{}}
+			logger, err := NewLogger(Config{Store: store, Datastore: ds, SampleRate: 1, Format: format, BatchSize: 2, FlushInterval: time.Hour})
+			require.NoError(err)
+
+			runCtx, cancel := context.WithCancel(ctx)
+			stopped := make(chan error)
+			go func() { stopped <- logger.Start(runCtx) }()
+
+			checkedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+			logger.Log(Decision{
+				CheckedAt:      checkedAt,
+				ResourceType:   "document",
+				ResourceID:     "first",
+				Permission:     "view",
+				SubjectType:    "user",
+				SubjectID:      "tom",
+				CaveatContext:  map[string]any{"b": 1, "a": "x"},
+				Permissionship: "has_permission",
+				Revision:       second,
+			})
+			logger.Log(Decision{
+				CheckedAt:    checkedAt,
+				ResourceType: "document",
+				ResourceID:   "first",
+				Permission:   "view",
+				SubjectType:  "user",
+				SubjectID:    "fred",
+				Error:        "object definition `user` not found",
+				Revision:     datastore.NoRevision,
+			})
+
+			// A full batch is written without waiting for the flush interval.
+			require.Eventually(func() bool { return len(store.keys()) == 1 }, time.Second, time.Millisecond)
+
+			logger.Log(Decision{CheckedAt: checkedAt, ResourceType: "document", ResourceID: "second", Permission: "view", SubjectType: "user", SubjectID: "tom", Permissionship: "no_permission", Revision: second})
+			cancel()
+			require.NoError(<-stopped)
+
+			keys := store.keys()
+			require.Len(keys, 2)
+			for _, key := range keys {
+				require.True(strings.HasPrefix(key, "decisions/"), key)
+				require.True(strings.HasSuffix(key, "."+string(format)), key)
+			}
+
+			if format == FormatParquet {
+				for _, key := range keys {
+					require.True(bytes.HasPrefix(store.objects[key], []byte("PAR1")))
+					require.True(bytes.HasSuffix(store.objects[key], []byte("PAR1")))
+				}
+				return
+			}
+
+			var lines []string
+			for _, key := range keys {
+				lines = append(lines, strings.Split(strings.TrimSpace(string(store.objects[key])), "\n")...)
+			}
+			require.Len(lines, 3)
+			require.Contains(lines, `{"checked_at":"2024-03-01T12:00:00Z","resource_type":"document","resource_id":"first","permission":"view","subject_type":"user","subject_id":"tom","caveat_context":{"a":"x","b":1},"permissionship":"has_permission","revision":"`+second.String()+`","schema_version":1}`)
+
+			var failed map[string]any
+			for _, line := range lines {
+				if strings.Contains(line, "fred") {
+					require.NoError(json.Unmarshal([]byte(line), &failed))
+				}
+			}
+			require.Equal("object definition `user` not found", failed["error"])
+			require.NotContains(failed, "permissionship")
+			require.NotContains(failed, "revision")
+			require.NotContains(failed, "schema_version")
+		})
+	}
+}
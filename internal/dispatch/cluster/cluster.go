@@ -17,6 +17,7 @@ type optionState struct {
 	prometheusSubsystem string
 	cache               cache.Cache
 	concurrencyLimit    uint16
+	superNodeThreshold  uint64
 }
 
 // PrometheusSubsystem sets the subsystem name for the prometheus metrics
@@ -40,11 +41,20 @@ func ConcurrencyLimit(limit uint16) Option {
 	}
 }
 
+// SuperNodeThreshold sets the number of relationships of a resource on a relation past
+// which checks of the resource start from the subject. Zero disables the detection of
+// super-nodes.
+func SuperNodeThreshold(threshold uint64) Option {
+	return func(state *optionState) {
+		state.superNodeThreshold = threshold
+	}
+}
+
 // NewClusterDispatcher takes a dispatcher (such as one created by
 // combined.NewDispatcher) and returns a cluster dispatcher suitable for use as
 // the dispatcher for the dispatch grpc server.
 func NewClusterDispatcher(dispatch dispatch.Dispatcher, options ...Option) (dispatch.Dispatcher, error) {
-	var opts optionState
+	opts := optionState{superNodeThreshold: graph.DefaultSuperNodeThreshold}
 	for _, fn := range options {
 		fn(&opts)
 	}
@@ -54,7 +64,7 @@ func NewClusterDispatcher(dispatch dispatch.Dispatcher, options ...Option) (disp
 		concurrencyLimit = opts.concurrencyLimit
 	}
 
-	clusterDispatch := graph.NewDispatcher(dispatch, concurrencyLimit)
+	clusterDispatch := graph.NewDispatcher(dispatch, concurrencyLimit, opts.superNodeThreshold)
 
 	if opts.prometheusSubsystem == "" {
 		opts.prometheusSubsystem = "dispatch"
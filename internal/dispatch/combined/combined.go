@@ -9,6 +9,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
+	"github.com/authzed/spicedb/internal/auth"
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/dispatch/caching"
 	"github.com/authzed/spicedb/internal/dispatch/graph"
@@ -28,10 +29,11 @@ type optionState struct {
 	prometheusSubsystem string
 	upstreamAddr        string
 	upstreamCAPath      string
-	grpcPresharedKey    string
+	grpcPresharedKeys   func() []string
 	grpcDialOpts        []grpc.DialOption
 	cache               cache.Cache
 	concurrencyLimit    uint16
+	superNodeThreshold  uint64
 }
 
 // PrometheusSubsystem sets the subsystem name for the prometheus metrics
@@ -56,11 +58,11 @@ func UpstreamCAPath(path string) Option {
 	}
 }
 
-// GrpcPresharedKey sets the preshared key used to authenticate for optional
-// cluster dispatching.
-func GrpcPresharedKey(key string) Option {
+// GrpcPresharedKeys sets the preshared keys, the first of which at the time of each
+// request is used to authenticate for optional cluster dispatching.
+func GrpcPresharedKeys(keys func() []string) Option {
 	return func(state *optionState) {
-		state.grpcPresharedKey = key
+		state.grpcPresharedKeys = keys
 	}
 }
 
@@ -86,10 +88,19 @@ func ConcurrencyLimit(limit uint16) Option {
 	}
 }
 
+// SuperNodeThreshold sets the number of relationships of a resource on a relation past
+// which checks of the resource start from the subject. Zero disables the detection of
+// super-nodes.
+func SuperNodeThreshold(threshold uint64) Option {
+	return func(state *optionState) {
+		state.superNodeThreshold = threshold
+	}
+}
+
 // NewDispatcher initializes a Dispatcher that caches and redispatches
 // optionally to the provided upstream.
 func NewDispatcher(options ...Option) (dispatch.Dispatcher, error) {
-	var opts optionState
+	opts := optionState{superNodeThreshold: graph.DefaultSuperNodeThreshold}
 	for _, fn := range options {
 		fn(&opts)
 	}
@@ -109,19 +120,24 @@ func NewDispatcher(options ...Option) (dispatch.Dispatcher, error) {
 		concurrencyLimit = opts.concurrencyLimit
 	}
 
-	redispatch := graph.NewDispatcher(cachingRedispatch, concurrencyLimit)
+	redispatch := graph.NewDispatcher(cachingRedispatch, concurrencyLimit, opts.superNodeThreshold)
 
 	// If an upstream is specified, create a cluster dispatcher.
 	if opts.upstreamAddr != "" {
+		presharedKeys := opts.grpcPresharedKeys
+		if presharedKeys == nil {
+			presharedKeys = func() []string { return nil }
+		}
+
 		if opts.upstreamCAPath != "" {
 			// Ensure that the CA path exists.
 			if _, err := os.Stat(opts.upstreamCAPath); err != nil {
 				return nil, err
 			}
 			opts.grpcDialOpts = append(opts.grpcDialOpts, grpcutil.WithCustomCerts(opts.upstreamCAPath, grpcutil.VerifyCA))
-			opts.grpcDialOpts = append(opts.grpcDialOpts, grpcutil.WithBearerToken(opts.grpcPresharedKey))
+			opts.grpcDialOpts = append(opts.grpcDialOpts, grpc.WithPerRPCCredentials(auth.PresharedKeyCredentials(presharedKeys, true)))
 		} else {
-			opts.grpcDialOpts = append(opts.grpcDialOpts, grpcutil.WithInsecureBearerToken(opts.grpcPresharedKey))
+			opts.grpcDialOpts = append(opts.grpcDialOpts, grpc.WithPerRPCCredentials(auth.PresharedKeyCredentials(presharedKeys, false)))
 			opts.grpcDialOpts = append(opts.grpcDialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 		}
 
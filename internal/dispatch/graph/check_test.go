@@ -104,46 +104,51 @@ func TestSimpleCheck(t *testing.T) {
 		}},
 	}
 
-	for _, tc := range testCases {
-		for _, userset := range tc.usersets {
-			for _, expected := range userset.expected {
-				name := fmt.Sprintf(
-					"simple::%s:%s#%s@%s:%s#%s=>%t",
-					tc.namespace,
-					tc.objectID,
-					expected.relation,
-					userset.userset.Namespace,
-					userset.userset.ObjectId,
-					userset.userset.Relation,
-					expected.isMember,
-				)
-
-				t.Run(name, func(t *testing.T) {
-					require := require.New(t)
-
-					ctx, dispatch, revision := newLocalDispatcher(t)
-
-					checkResult, err := dispatch.DispatchCheck(ctx, &v1.DispatchCheckRequest{
-						ResourceRelation: RR(tc.namespace, expected.relation),
-						ResourceIds:      []string{tc.objectID},
-						ResultsSetting:   v1.DispatchCheckRequest_ALLOW_SINGLE_RESULT,
-						Subject:          userset.userset,
-						Metadata: &v1.ResolverMeta{
-							AtRevision:     revision.String(),
-							DepthRemaining: 50,
-						},
+	// A super-node threshold of one checks every resource with more than one relationship on
+	// a relation starting from the subject, which must find the same results.
+	for _, superNodeThreshold := range []uint64{DefaultSuperNodeThreshold, 1} {
+		for _, tc := range testCases {
+			for _, userset := range tc.usersets {
+				for _, expected := range userset.expected {
+					name := fmt.Sprintf(
+						"simple::%d::%s:%s#%s@%s:%s#%s=>%t",
+						superNodeThreshold,
+						tc.namespace,
+						tc.objectID,
+						expected.relation,
+						userset.userset.Namespace,
+						userset.userset.ObjectId,
+						userset.userset.Relation,
+						expected.isMember,
+					)
+
+					t.Run(name, func(t *testing.T) {
+						require := require.New(t)
+
+						ctx, dispatch, revision := newLocalDispatcherWithSuperNodeThreshold(t, superNodeThreshold)
+
+						checkResult, err := dispatch.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+							ResourceRelation: RR(tc.namespace, expected.relation),
+							ResourceIds:      []string{tc.objectID},
+							ResultsSetting:   v1.DispatchCheckRequest_ALLOW_SINGLE_RESULT,
+							Subject:          userset.userset,
+							Metadata: &v1.ResolverMeta{
+								AtRevision:     revision.String(),
+								DepthRemaining: 50,
+							},
+						})
+
+						require.NoError(err)
+
+						isMember := false
+						if found, ok := checkResult.ResultsByResourceId[tc.objectID]; ok {
+							isMember = found.Membership == v1.ResourceCheckResult_MEMBER
+						}
+
+						require.Equal(expected.isMember, isMember, "For object %s in %v: ", tc.objectID, checkResult.ResultsByResourceId)
+						require.GreaterOrEqual(checkResult.Metadata.DepthRequired, uint32(1))
 					})
-
-					require.NoError(err)
-
-					isMember := false
-					if found, ok := checkResult.ResultsByResourceId[tc.objectID]; ok {
-						isMember = found.Membership == v1.ResourceCheckResult_MEMBER
-					}
-
-					require.Equal(expected.isMember, isMember, "For object %s in %v: ", tc.objectID, checkResult.ResultsByResourceId)
-					require.GreaterOrEqual(checkResult.Metadata.DepthRequired, uint32(1))
-				})
+				}
 			}
 		}
 	}
@@ -301,3 +306,19 @@ func newLocalDispatcher(t testing.TB) (context.Context, dispatch.Dispatcher, dat
 
 	return ctx, cachingDispatcher, revision
 }
+
+func newLocalDispatcherWithSuperNodeThreshold(t testing.TB, superNodeThreshold uint64) (context.Context, dispatch.Dispatcher, datastore.Revision) {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	ds, revision := testfixtures.StandardDatastoreWithData(rawDS, require.New(t))
+
+	cachingDispatcher, err := caching.NewCachingDispatcher(caching.DispatchTestCache(t), "", &keys.CanonicalKeyHandler{})
+	require.NoError(t, err)
+	cachingDispatcher.SetDelegate(NewDispatcher(cachingDispatcher, 10, superNodeThreshold))
+
+	ctx := log.Logger.WithContext(datastoremw.ContextWithHandle(context.Background()))
+	require.NoError(t, datastoremw.SetInContext(ctx, ds))
+
+	return ctx, cachingDispatcher, revision
+}
@@ -22,11 +22,15 @@ const errDispatch = "error dispatching request: %w"
 
 var tracer = otel.Tracer("spicedb/internal/dispatch/local")
 
+// DefaultSuperNodeThreshold is the default number of relationships of a resource on a
+// relation past which checks of the resource start from the subject.
+const DefaultSuperNodeThreshold = graph.DefaultSuperNodeThreshold
+
 // NewLocalOnlyDispatcher creates a dispatcher that consults with the graph to formulate a response.
 func NewLocalOnlyDispatcher(concurrencyLimit uint16) dispatch.Dispatcher {
 	d := &localDispatcher{}
 
-	d.checker = graph.NewConcurrentChecker(d, concurrencyLimit)
+	d.checker = graph.NewConcurrentChecker(d, concurrencyLimit, DefaultSuperNodeThreshold)
 	d.expander = graph.NewConcurrentExpander(d)
 	d.lookupHandler = graph.NewConcurrentLookup(d, d, concurrencyLimit)
 	d.reachableResourcesHandler = graph.NewConcurrentReachableResources(d, concurrencyLimit)
@@ -36,9 +40,10 @@ func NewLocalOnlyDispatcher(concurrencyLimit uint16) dispatch.Dispatcher {
 }
 
 // NewDispatcher creates a dispatcher that consults with the graph and redispatches subproblems to
-// the provided redispatcher.
-func NewDispatcher(redispatcher dispatch.Dispatcher, concurrencyLimit uint16) dispatch.Dispatcher {
-	checker := graph.NewConcurrentChecker(redispatcher, concurrencyLimit)
+// the provided redispatcher. Checks of resources with more direct relationships on a relation
+// than the superNodeThreshold start from the subject, unless the threshold is zero.
+func NewDispatcher(redispatcher dispatch.Dispatcher, concurrencyLimit uint16, superNodeThreshold uint64) dispatch.Dispatcher {
+	checker := graph.NewConcurrentChecker(redispatcher, concurrencyLimit, superNodeThreshold)
 	expander := graph.NewConcurrentExpander(redispatcher)
 	lookupHandler := graph.NewConcurrentLookup(redispatcher, redispatcher, concurrencyLimit)
 	reachableResourcesHandler := graph.NewConcurrentReachableResources(redispatcher, concurrencyLimit)
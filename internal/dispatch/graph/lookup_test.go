@@ -11,6 +11,7 @@ import (
 	"go.uber.org/goleak"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -186,3 +187,41 @@ func (a OrderedResolved) Less(i, j int) bool {
 }
 
 func (a OrderedResolved) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+func TestLookupMemoryCeiling(t *testing.T) {
+	defer goleak.VerifyNone(t, goleakIgnores...)
+	require := require.New(t)
+
+	ctx, dis, revision := newLocalDispatcher(t)
+	budget := dispatch.NewMemoryBudget(1, "")
+
+	lookupResult, err := dis.DispatchLookup(dispatch.ContextWithMemoryBudget(ctx, budget), &v1.DispatchLookupRequest{
+		ObjectRelation: RR("document", "view"),
+		Subject:        ONR("user", "owner", "..."),
+		Metadata: &v1.ResolverMeta{
+			AtRevision:     revision.String(),
+			DepthRemaining: 50,
+		},
+		Limit: 10,
+	})
+
+	require.ErrorIs(err, dispatch.ErrMemoryCeilingExceeded)
+	require.Empty(lookupResult.ResolvedResources)
+	require.Zero(budget.Used())
+
+	// Ensure the lookup completes once the budget allows for its results.
+	budget = dispatch.NewMemoryBudget(1<<20, "")
+	lookupResult, err = dis.DispatchLookup(dispatch.ContextWithMemoryBudget(ctx, budget), &v1.DispatchLookupRequest{
+		ObjectRelation: RR("document", "view"),
+		Subject:        ONR("user", "owner", "..."),
+		Metadata: &v1.ResolverMeta{
+			AtRevision:     revision.String(),
+			DepthRemaining: 50,
+		},
+		Limit: 10,
+	})
+
+	require.NoError(err)
+	require.NotEmpty(lookupResult.ResolvedResources)
+	require.Zero(budget.Used())
+}
@@ -3,6 +3,7 @@ package graph
 import (
 	"context"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 	"testing"
@@ -739,3 +740,56 @@ func caveatInvert(ce *v1.CaveatExpression) *v1.CaveatExpression {
 		},
 	}
 }
+
+func TestLookupSubjectsSpillsOverMemoryCeiling(t *testing.T) {
+	defer goleak.VerifyNone(t, goleakIgnores...)
+
+	testCases := []struct {
+		permission       string
+		expectedSubjects []string
+	}{
+		{"view", []string{"auditor", "chief_financial_officer", "eng_lead", "legal", "owner", "product_manager", "vp_product"}},
+		{"edit", []string{"product_manager"}},
+		{"view_and_edit", []string{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.permission, func(t *testing.T) {
+			require := require.New(t)
+
+			spillDir := t.TempDir()
+			budget := dispatch.NewMemoryBudget(1, spillDir)
+
+			ctx, dis, revision := newLocalDispatcher(t)
+			stream := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](dispatch.ContextWithMemoryBudget(ctx, budget))
+
+			err := dis.DispatchLookupSubjects(&v1.DispatchLookupSubjectsRequest{
+				ResourceRelation: RR("document", tc.permission),
+				ResourceIds:      []string{"masterplan"},
+				SubjectRelation:  RR("user", "..."),
+				Metadata: &v1.ResolverMeta{
+					AtRevision:     revision.String(),
+					DepthRemaining: 50,
+				},
+			}, stream)
+			require.NoError(err)
+
+			foundSubjectIds := []string{}
+			for _, result := range stream.Results() {
+				for _, found := range result.FoundSubjectsByResourceId["masterplan"].GetFoundSubjects() {
+					foundSubjectIds = append(foundSubjectIds, found.SubjectId)
+				}
+			}
+
+			sort.Strings(foundSubjectIds)
+			require.Equal(tc.expectedSubjects, foundSubjectIds)
+
+			// Ensure the spilled results were removed and their memory returned.
+			spilled, err := os.ReadDir(spillDir)
+			require.NoError(err)
+			require.Empty(spilled)
+			require.Zero(budget.Used())
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package dispatch
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrMemoryCeilingExceeded is returned when the results held for a request exceed the
+// ceiling of its memory budget.
+var ErrMemoryCeilingExceeded = errors.New("request exceeded its memory ceiling")
+
+// MemoryBudget accounts for the approximate memory held by the intermediate results of a
+// single request, such as the sets collected by LookupResources and LookupSubjects. A nil
+// budget places no limit on memory.
+type MemoryBudget struct {
+	ceiling  int64
+	spillDir string
+	used     atomic.Int64
+}
+
+// NewMemoryBudget creates a budget allowing up to ceiling bytes to be held in memory.
+// Intermediate results which may be spilled are written to temporary files in spillDir,
+// or in the default directory for temporary files if empty.
+func NewMemoryBudget(ceiling uint64, spillDir string) *MemoryBudget {
+	return &MemoryBudget{ceiling: int64(ceiling), spillDir: spillDir}
+}
+
+// Reserve accounts for size bytes if doing so keeps the budget within its ceiling,
+// returning whether it did.
+func (b *MemoryBudget) Reserve(size int64) bool {
+	if b == nil {
+		return true
+	}
+
+	if b.used.Add(size) > b.ceiling {
+		b.used.Add(-size)
+		return false
+	}
+	return true
+}
+
+// Release returns size bytes, previously reserved, to the budget.
+func (b *MemoryBudget) Release(size int64) {
+	if b == nil {
+		return
+	}
+	b.used.Add(-size)
+}
+
+// Used returns the number of bytes currently reserved.
+func (b *MemoryBudget) Used() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.used.Load()
+}
+
+type memoryBudgetKey struct{}
+
+// ContextWithMemoryBudget returns a context carrying the memory budget of a request. The
+// budget applies to the work done in this process; dispatches handled by other nodes of a
+// cluster are governed by their own configuration.
+func ContextWithMemoryBudget(ctx context.Context, budget *MemoryBudget) context.Context {
+	return context.WithValue(ctx, memoryBudgetKey{}, budget)
+}
+
+// MemoryBudgetFromContext returns the memory budget of the request, or nil if it has none.
+func MemoryBudgetFromContext(ctx context.Context) *MemoryBudget {
+	budget, _ := ctx.Value(memoryBudgetKey{}).(*MemoryBudget)
+	return budget
+}
@@ -0,0 +1,202 @@
+package simulation
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/dispatch/caching"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/internal/dispatch/keys"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/pkg/cache"
+	"github.com/authzed/spicedb/pkg/consistent"
+	"github.com/authzed/spicedb/pkg/datastore"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+// ErrNodeUnavailable is returned for a dispatch sent to a node which has left the
+// cluster, as a connection to it would fail.
+var ErrNodeUnavailable = errors.New("node unavailable")
+
+var errUnsupported = errors.New("only check is supported by the simulated network")
+
+const (
+	hashringReplicationFactor = 20
+	concurrencyLimit          = 10
+)
+
+// network delivers dispatches between nodes. The delay of each leg of a dispatch is
+// derived from the seed, the current epoch, the nodes involved and the request, so the
+// same dispatch is delayed identically when a sequence is replayed, while distinct
+// dispatches sent together complete in an order unrelated to the order they were sent.
+type network struct {
+	seed     int64
+	maxDelay time.Duration
+	ds       datastore.Datastore
+	epoch    atomic.Int64
+}
+
+func (n *network) delay(ctx context.Context, leg string, from, to *node, key []byte) error {
+	if n.maxDelay <= 0 {
+		return nil
+	}
+
+	digest := xxhash.New()
+	var header [16]byte
+	binary.BigEndian.PutUint64(header[:8], uint64(n.seed))
+	binary.BigEndian.PutUint64(header[8:], uint64(n.epoch.Load()))
+	_, _ = digest.Write(header[:])
+	_, _ = digest.WriteString(leg + "/" + from.name + "/" + to.name + "/")
+	_, _ = digest.Write(key)
+
+	timer := time.NewTimer(time.Duration(digest.Sum64() % uint64(n.maxDelay)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliverCheck sends a check from one node to another and returns the response. The
+// request and response are copied, as they would be serialized by a real network, and
+// the request is handled with a datastore of the receiving node's own.
+func (n *network) deliverCheck(ctx context.Context, from, to *node, key []byte, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
+	if err := n.delay(ctx, "request", from, to, key); err != nil {
+		return nil, err
+	}
+	if to.stopped.Load() {
+		return nil, fmt.Errorf("%w: %s", ErrNodeUnavailable, to.name)
+	}
+
+	remoteCtx := datastoremw.ContextWithHandle(ctx)
+	if err := datastoremw.SetInContext(remoteCtx, n.ds); err != nil {
+		return nil, err
+	}
+
+	resp, err := to.server.DispatchCheck(remoteCtx, req.CloneVT())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := n.delay(ctx, "response", to, from, key); err != nil {
+		return nil, err
+	}
+	return resp.CloneVT(), nil
+}
+
+// node is a single member of the simulated cluster. Like a SpiceDB server, it has a
+// client dispatcher which routes each dispatch to the member of the hashring responsible
+// for it, and a server dispatcher which resolves the dispatches routed to it.
+type node struct {
+	name    string
+	network *network
+	stopped atomic.Bool
+
+	// view is the membership of the cluster as known by this node, which lags behind the
+	// actual membership while changes propagate.
+	view *consistent.Hashring
+
+	client dispatch.Dispatcher
+	server dispatch.Dispatcher
+}
+
+func (n *node) Key() string { return n.name }
+
+func newNode(name string, network *network, members []*node) (*node, error) {
+	n := &node{
+		name:    name,
+		network: network,
+		view:    consistent.NewHashring(xxhash.Sum64, hashringReplicationFactor),
+	}
+	for _, member := range append(members, n) {
+		if err := n.view.Add(member); err != nil {
+			return nil, err
+		}
+	}
+
+	// The dispatchers are assembled as by the combined and cluster dispatchers, without
+	// registering metrics, so that many nodes may run in a single process.
+	client, err := newCachingDispatcher()
+	if err != nil {
+		return nil, err
+	}
+	client.SetDelegate(&router{node: n, keyHandler: &keys.CanonicalKeyHandler{}})
+
+	server, err := newCachingDispatcher()
+	if err != nil {
+		return nil, err
+	}
+	server.SetDelegate(graph.NewDispatcher(client, concurrencyLimit, graph.DefaultSuperNodeThreshold))
+
+	n.client = client
+	n.server = server
+	return n, nil
+}
+
+func newCachingDispatcher() (*caching.Dispatcher, error) {
+	c, err := cache.NewCache(&cache.Config{NumCounters: 10_000, MaxCost: 1 << 20})
+	if err != nil {
+		return nil, err
+	}
+	return caching.NewCachingDispatcher(c, "", &keys.CanonicalKeyHandler{})
+}
+
+// router is the client side of a node's dispatches, which stands in for the remote
+// cluster dispatcher and its hashring balancer.
+type router struct {
+	node       *node
+	keyHandler keys.Handler
+}
+
+func (r *router) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
+	if err := dispatch.CheckDepth(ctx, req); err != nil {
+		return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
+	}
+
+	key, err := r.keyHandler.CheckDispatchKey(ctx, req)
+	if err != nil {
+		return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
+	}
+
+	members, err := r.node.view.FindN(key, 1)
+	if err != nil {
+		return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
+	}
+
+	resp, err := r.node.network.deliverCheck(ctx, r.node, members[0].(*node), key, req)
+	if err != nil {
+		return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
+	}
+	return resp, nil
+}
+
+func (r *router) DispatchExpand(context.Context, *v1.DispatchExpandRequest) (*v1.DispatchExpandResponse, error) {
+	return &v1.DispatchExpandResponse{Metadata: &v1.ResponseMeta{}}, errUnsupported
+}
+
+func (r *router) DispatchLookup(context.Context, *v1.DispatchLookupRequest) (*v1.DispatchLookupResponse, error) {
+	return &v1.DispatchLookupResponse{Metadata: &v1.ResponseMeta{}}, errUnsupported
+}
+
+func (r *router) DispatchReachableResources(*v1.DispatchReachableResourcesRequest, dispatch.ReachableResourcesStream) error {
+	return errUnsupported
+}
+
+func (r *router) DispatchLookupSubjects(*v1.DispatchLookupSubjectsRequest, dispatch.LookupSubjectsStream) error {
+	return errUnsupported
+}
+
+func (r *router) Close() error { return nil }
+
+func (r *router) IsReady() bool { return !r.node.stopped.Load() }
+
+var _ dispatch.Dispatcher = (*router)(nil)
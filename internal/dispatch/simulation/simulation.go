@@ -0,0 +1,387 @@
+// Package simulation runs a cluster of in-process dispatch nodes over a simulated
+// network, which delays and reorders dispatches while nodes join and leave the hashring,
+// and verifies that checks made through any node agree with a single local dispatcher.
+//
+// Every choice made by a simulation, from the relationships written and the checks made
+// to the membership changes and network delays, is derived from its seed, so a failing
+// sequence can be replayed. Goroutine scheduling is not simulated, so the invariants
+// checked must hold under any interleaving of the dispatches in flight.
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const schema = `definition user {}
+
+definition group {
+	relation member: user | group#member
+}
+
+definition folder {
+	relation parent: folder
+	relation viewer: user | group#member
+	permission view = viewer + parent->view
+}
+
+definition document {
+	relation parent: folder
+	relation viewer: user | group#member
+	permission view = viewer + parent->view
+}`
+
+const (
+	numObjects = 6
+	maxDepth   = 50
+)
+
+// Config describes a simulation.
+type Config struct {
+	// Seed seeds every random choice made by the simulation.
+	Seed int64
+
+	// Steps is the number of steps run. Each step may write a relationship and change the
+	// membership of the cluster, while a batch of checks is in flight.
+	Steps int
+
+	// ChecksPerStep is the number of checks made concurrently in each step.
+	ChecksPerStep int
+
+	// InitialNodes and MaxNodes bound the size of the cluster.
+	InitialNodes int
+	MaxNodes     int
+
+	// MaxDelay bounds the delay of each leg of a dispatch over the network.
+	MaxDelay time.Duration
+
+	// MaxViewLag is the number of steps by which each node's view of the membership may
+	// lag behind a change.
+	MaxViewLag int
+}
+
+// viewUpdate is a membership change which will be seen by a node at a later step.
+type viewUpdate struct {
+	step   int
+	node   *node
+	member *node
+	joined bool
+}
+
+type simulation struct {
+	t       *testing.T
+	config  Config
+	rnd     *rand.Rand
+	ds      datastore.Datastore
+	network *network
+	oracle  dispatch.Dispatcher
+
+	nodes     []*node
+	nextNode  int
+	pending   []viewUpdate
+	revisions []datastore.Revision
+
+	unavailable int
+}
+
+// Run writes the schema to the datastore, which must be empty, then runs the simulation
+// described by the config against it. A check may fail only because it was routed to a
+// node which had left the cluster; any other error, or any result which differs from that
+// of a local dispatcher at the same revision, fails the test. Once the steps are complete,
+// every node is allowed to see the final membership, after which every check must succeed
+// through every node.
+func Run(t *testing.T, config Config, ds datastore.Datastore) {
+	ds, rev := testfixtures.DatastoreFromSchemaAndTestRelationships(ds, schema, nil, require.New(t))
+
+	s := &simulation{
+		t:         t,
+		config:    config,
+		rnd:       rand.New(rand.NewSource(config.Seed)),
+		ds:        ds,
+		network:   &network{seed: config.Seed, maxDelay: config.MaxDelay, ds: ds},
+		oracle:    graph.NewLocalOnlyDispatcher(concurrencyLimit),
+		revisions: []datastore.Revision{rev},
+	}
+
+	for i := 0; i < config.InitialNodes; i++ {
+		s.join(0, false)
+	}
+
+	for step := 0; step < config.Steps; step++ {
+		s.step(step)
+	}
+
+	s.settle()
+	t.Logf("seed %d: %d checks failed while routed to departed nodes", config.Seed, s.unavailable)
+}
+
+func (s *simulation) step(step int) {
+	s.network.epoch.Store(int64(step))
+	s.applyViewUpdates(step)
+
+	if s.rnd.Intn(3) == 0 {
+		s.write()
+	}
+
+	checks := make([]check, 0, s.config.ChecksPerStep)
+	for i := 0; i < s.config.ChecksPerStep; i++ {
+		checks = append(checks, s.randomCheck(s.nodes[s.rnd.Intn(len(s.nodes))]))
+	}
+
+	// The membership change, if any, is made while the checks are in flight.
+	var change func()
+	switch n := s.rnd.Intn(4); {
+	case n == 0 && len(s.nodes) < s.config.MaxNodes:
+		change = func() { s.join(step, true) }
+	case n == 1 && len(s.nodes) > 1:
+		leaving := s.nodes[s.rnd.Intn(len(s.nodes))]
+		change = func() { s.leave(step, leaving) }
+	}
+	changeAfter := time.Duration(0)
+	if s.config.MaxDelay > 0 {
+		changeAfter = time.Duration(s.rnd.Int63n(int64(s.config.MaxDelay)))
+	}
+
+	results := make([]string, len(checks))
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		i, c := i, c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.dispatchCheck(c.entry.client, c)
+		}()
+	}
+	if change != nil {
+		time.Sleep(changeAfter)
+		change()
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		if errors.Is(errs[i], ErrNodeUnavailable) {
+			s.unavailable++
+			continue
+		}
+		s.verify(fmt.Sprintf("step %d", step), c, results[i], errs[i])
+	}
+}
+
+// settle lets every node see the final membership, then makes every check through every
+// node at the latest revision.
+func (s *simulation) settle() {
+	s.applyViewUpdates(s.config.Steps + s.config.MaxViewLag)
+	for _, n := range s.nodes {
+		for _, resourceType := range []string{"folder", "document"} {
+			for resource := 0; resource < numObjects; resource++ {
+				for user := 0; user < numObjects; user++ {
+					c := check{
+						entry:        n,
+						revision:     len(s.revisions) - 1,
+						resourceType: resourceType,
+						resourceID:   objectID(resource),
+						userID:       objectID(user),
+					}
+					result, err := s.dispatchCheck(n.client, c)
+					s.verify("settled", c, result, err)
+				}
+			}
+		}
+	}
+}
+
+func (s *simulation) verify(when string, c check, result string, err error) {
+	if err != nil {
+		s.t.Fatalf("seed %d, %s: %s failed: %v", s.config.Seed, when, c, err)
+	}
+
+	expected, err := s.dispatchCheck(s.oracle, c)
+	require.NoError(s.t, err)
+	if result != expected {
+		s.t.Fatalf("seed %d, %s: %s returned %s, expected %s", s.config.Seed, when, c, result, expected)
+	}
+}
+
+// join starts a new node, which knows the current membership, while every other node
+// sees it join after a lag.
+func (s *simulation) join(step int, lagged bool) {
+	name := fmt.Sprintf("node-%d", s.nextNode)
+	s.nextNode++
+
+	n, err := newNode(name, s.network, s.nodes)
+	require.NoError(s.t, err)
+
+	for _, other := range s.nodes {
+		update := viewUpdate{step: step, node: other, member: n, joined: true}
+		if lagged {
+			update.step += s.rnd.Intn(s.config.MaxViewLag + 1)
+		}
+		s.pending = append(s.pending, update)
+	}
+	s.nodes = append(s.nodes, n)
+	s.applyViewUpdates(step)
+}
+
+// leave stops a node, which refuses any dispatch delivered to it from then on, while
+// every other node sees it leave after a lag. A node which had yet to see the departed
+// node join never sees it at all.
+func (s *simulation) leave(step int, leaving *node) {
+	leaving.stopped.Store(true)
+
+	unseen := make(map[*node]struct{})
+	pending := s.pending[:0]
+	for _, update := range s.pending {
+		if update.member == leaving {
+			unseen[update.node] = struct{}{}
+			continue
+		}
+		pending = append(pending, update)
+	}
+	s.pending = pending
+
+	remaining := make([]*node, 0, len(s.nodes)-1)
+	for _, n := range s.nodes {
+		if n == leaving {
+			continue
+		}
+		remaining = append(remaining, n)
+		if _, ok := unseen[n]; ok {
+			continue
+		}
+		s.pending = append(s.pending, viewUpdate{
+			step:   step + 1 + s.rnd.Intn(s.config.MaxViewLag+1),
+			node:   n,
+			member: leaving,
+		})
+	}
+	s.nodes = remaining
+}
+
+func (s *simulation) applyViewUpdates(step int) {
+	remaining := s.pending[:0]
+	for _, update := range s.pending {
+		if update.step > step {
+			remaining = append(remaining, update)
+			continue
+		}
+
+		if update.joined {
+			require.NoError(s.t, update.node.view.Add(update.member))
+		} else {
+			require.NoError(s.t, update.node.view.Remove(update.member))
+		}
+	}
+	s.pending = remaining
+}
+
+// write touches or deletes a relationship. Folder parents and group members only
+// reference objects with lower IDs, so that the graph is free of cycles.
+func (s *simulation) write() {
+	from, to := s.rnd.Intn(numObjects), s.rnd.Intn(numObjects)
+	var rel string
+	switch s.rnd.Intn(4) {
+	case 0:
+		if to >= from {
+			return
+		}
+		rel = fmt.Sprintf("folder:%s#parent@folder:%s", objectID(from), objectID(to))
+	case 1:
+		if to >= from {
+			return
+		}
+		rel = fmt.Sprintf("group:%s#member@group:%s#member", objectID(from), objectID(to))
+	case 2:
+		rel = fmt.Sprintf("document:%s#parent@folder:%s", objectID(from), objectID(to))
+	default:
+		resourceType := []string{"group", "folder", "document"}[s.rnd.Intn(3)]
+		relation := "viewer"
+		if resourceType == "group" {
+			relation = "member"
+		}
+		rel = fmt.Sprintf("%s:%s#%s@user:%s", resourceType, objectID(from), relation, objectID(to))
+	}
+
+	update := tuple.Touch(tuple.MustParse(rel))
+	if s.rnd.Intn(3) == 0 {
+		update = tuple.Delete(update.Tuple)
+	}
+
+	ctx := context.Background()
+	rev, err := s.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{update})
+	})
+	require.NoError(s.t, err)
+	s.revisions = append(s.revisions, rev)
+}
+
+func objectID(i int) string {
+	return fmt.Sprintf("o%d", i)
+}
+
+// check is a check of the view permission of a user, made at the revision produced by an
+// earlier write through one of the nodes.
+type check struct {
+	entry        *node
+	revision     int
+	resourceType string
+	resourceID   string
+	userID       string
+}
+
+func (c check) String() string {
+	return fmt.Sprintf("check %s:%s#view@user:%s at revision %d through %s",
+		c.resourceType, c.resourceID, c.userID, c.revision, c.entry.name)
+}
+
+func (s *simulation) randomCheck(entry *node) check {
+	return check{
+		entry:        entry,
+		revision:     s.rnd.Intn(len(s.revisions)),
+		resourceType: []string{"folder", "document"}[s.rnd.Intn(2)],
+		resourceID:   objectID(s.rnd.Intn(numObjects)),
+		userID:       objectID(s.rnd.Intn(numObjects)),
+	}
+}
+
+func (s *simulation) dispatchCheck(d dispatch.Check, c check) (string, error) {
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	if err := datastoremw.SetInContext(ctx, s.ds); err != nil {
+		return "", err
+	}
+
+	resp, err := d.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+		ResourceRelation: &core.RelationReference{Namespace: c.resourceType, Relation: "view"},
+		ResourceIds:      []string{c.resourceID},
+		ResultsSetting:   v1.DispatchCheckRequest_ALLOW_SINGLE_RESULT,
+		Subject:          &core.ObjectAndRelation{Namespace: "user", ObjectId: c.userID, Relation: tuple.Ellipsis},
+		Metadata: &v1.ResolverMeta{
+			AtRevision:     s.revisions[c.revision].String(),
+			DepthRemaining: maxDepth,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if result, ok := resp.ResultsByResourceId[c.resourceID]; ok {
+		return result.Membership.String(), nil
+	}
+	return v1.ResourceCheckResult_NOT_MEMBER.String(), nil
+}
@@ -0,0 +1,33 @@
+package simulation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+)
+
+func TestSimulation(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3, 4} {
+		seed := seed
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			t.Parallel()
+
+			ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(t, err)
+
+			Run(t, Config{
+				Seed:          seed,
+				Steps:         40,
+				ChecksPerStep: 8,
+				InitialNodes:  3,
+				MaxNodes:      6,
+				MaxDelay:      time.Millisecond,
+				MaxViewLag:    3,
+			}, ds)
+		})
+	}
+}
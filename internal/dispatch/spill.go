@@ -0,0 +1,172 @@
+package dispatch
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// SpillableMessage is a message which can be written to and read back from a spill file.
+type SpillableMessage interface {
+	SizeVT() int
+	MarshalVT() ([]byte, error)
+	UnmarshalVT([]byte) error
+}
+
+// NewSpillingDispatchStream creates a new SpillingDispatchStream, which uses newMessage to
+// allocate the messages read back from its spill file.
+func NewSpillingDispatchStream[T SpillableMessage](ctx context.Context, newMessage func() T) *SpillingDispatchStream[T] {
+	return &SpillingDispatchStream[T]{
+		ctx:        ctx,
+		budget:     MemoryBudgetFromContext(ctx),
+		newMessage: newMessage,
+	}
+}
+
+// SpillingDispatchStream is a dispatch stream that collects results in memory while the
+// memory budget of the request allows, and writes them to a temporary file once it does
+// not. Results are read back in the order in which they were published, and Close must be
+// called once they have been read to release the memory and file held.
+type SpillingDispatchStream[T SpillableMessage] struct {
+	ctx        context.Context
+	budget     *MemoryBudget
+	newMessage func() T
+
+	mu       sync.Mutex
+	results  []T
+	reserved int64
+
+	spillFile   *os.File
+	spillWriter *bufio.Writer
+}
+
+func (s *SpillingDispatchStream[T]) Context() context.Context {
+	return s.ctx
+}
+
+func (s *SpillingDispatchStream[T]) Publish(result T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Once spilling has begun, every later result is spilled too, to preserve their order.
+	if s.spillFile == nil {
+		if s.budget == nil {
+			s.results = append(s.results, result)
+			return nil
+		}
+
+		size := int64(result.SizeVT())
+		if s.budget.Reserve(size) {
+			s.reserved += size
+			s.results = append(s.results, result)
+			return nil
+		}
+
+		spillFile, err := os.CreateTemp(s.budget.spillDir, "spicedb-dispatch-spill-*")
+		if err != nil {
+			return fmt.Errorf("unable to create spill file: %w", err)
+		}
+		s.spillFile = spillFile
+		s.spillWriter = bufio.NewWriter(spillFile)
+	}
+
+	marshalled, err := result.MarshalVT()
+	if err != nil {
+		return err
+	}
+
+	var length [binary.MaxVarintLen64]byte
+	if _, err := s.spillWriter.Write(length[:binary.PutUvarint(length[:], uint64(len(marshalled)))]); err != nil {
+		return fmt.Errorf("unable to write spill file: %w", err)
+	}
+	if _, err := s.spillWriter.Write(marshalled); err != nil {
+		return fmt.Errorf("unable to write spill file: %w", err)
+	}
+	return nil
+}
+
+// ForEach invokes fn with each result, in the order in which they were published. No
+// result may be published once ForEach has been called.
+func (s *SpillingDispatchStream[T]) ForEach(fn func(T) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, result := range s.results {
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+
+	if s.spillFile == nil {
+		return nil
+	}
+
+	if err := s.spillWriter.Flush(); err != nil {
+		return fmt.Errorf("unable to write spill file: %w", err)
+	}
+	if _, err := s.spillFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("unable to read spill file: %w", err)
+	}
+
+	reader := bufio.NewReader(s.spillFile)
+	var buf []byte
+	for {
+		length, err := binary.ReadUvarint(reader)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to read spill file: %w", err)
+		}
+
+		if uint64(cap(buf)) < length {
+			buf = make([]byte, length)
+		}
+		buf = buf[:length]
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return fmt.Errorf("unable to read spill file: %w", err)
+		}
+
+		result := s.newMessage()
+		if err := result.UnmarshalVT(buf); err != nil {
+			return err
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+}
+
+// Spilled returns whether any results have been written to the spill file.
+func (s *SpillingDispatchStream[T]) Spilled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.spillFile != nil
+}
+
+// Close releases the memory reserved for the results held and removes the spill file, if
+// any.
+func (s *SpillingDispatchStream[T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.budget.Release(s.reserved)
+	s.reserved = 0
+	s.results = nil
+
+	if s.spillFile == nil {
+		return nil
+	}
+
+	name := s.spillFile.Name()
+	closeErr := s.spillFile.Close()
+	s.spillFile = nil
+	s.spillWriter = nil
+	if err := os.Remove(name); err != nil {
+		return err
+	}
+	return closeErr
+}
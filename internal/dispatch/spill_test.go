@@ -0,0 +1,94 @@
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+func newResponse(resourceID string) *v1.DispatchLookupSubjectsResponse {
+	return &v1.DispatchLookupSubjectsResponse{
+		FoundSubjectsByResourceId: map[string]*v1.FoundSubjects{
+			resourceID: {FoundSubjects: []*v1.FoundSubject{{SubjectId: "tom"}}},
+		},
+		Metadata: &v1.ResponseMeta{DispatchCount: 1},
+	}
+}
+
+func TestSpillingDispatchStream(t *testing.T) {
+	testCases := []struct {
+		name            string
+		ceiling         uint64
+		published       int
+		expectedSpilled bool
+	}{
+		{"no budget", 0, 10, false},
+		{"within budget", 1 << 20, 10, false},
+		{"spills all", 1, 10, true},
+		{"spills after first", uint64(newResponse("0").SizeVT()), 10, true},
+		{"nothing published", 1, 0, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			spillDir := t.TempDir()
+			var budget *MemoryBudget
+			ctx := context.Background()
+			if tc.ceiling > 0 {
+				budget = NewMemoryBudget(tc.ceiling, spillDir)
+				ctx = ContextWithMemoryBudget(ctx, budget)
+			}
+
+			stream := NewSpillingDispatchStream(ctx, func() *v1.DispatchLookupSubjectsResponse {
+				return &v1.DispatchLookupSubjectsResponse{}
+			})
+			for i := 0; i < tc.published; i++ {
+				require.NoError(stream.Publish(newResponse(fmt.Sprintf("%d", i))))
+			}
+			require.Equal(tc.expectedSpilled, stream.Spilled())
+
+			// Results must be returned in the order published, whether or not they were spilled.
+			var found []*v1.DispatchLookupSubjectsResponse
+			require.NoError(stream.ForEach(func(result *v1.DispatchLookupSubjectsResponse) error {
+				found = append(found, result)
+				return nil
+			}))
+			require.Len(found, tc.published)
+			for i, result := range found {
+				require.True(newResponse(fmt.Sprintf("%d", i)).EqualVT(result))
+			}
+
+			require.NoError(stream.Close())
+			require.Zero(budget.Used())
+
+			spilled, err := os.ReadDir(spillDir)
+			require.NoError(err)
+			require.Empty(spilled)
+		})
+	}
+}
+
+func TestMemoryBudgetReserve(t *testing.T) {
+	require := require.New(t)
+
+	budget := NewMemoryBudget(10, "")
+	require.True(budget.Reserve(6))
+	require.False(budget.Reserve(5))
+	require.Equal(int64(6), budget.Used())
+	require.True(budget.Reserve(4))
+
+	budget.Release(10)
+	require.Zero(budget.Used())
+
+	var unlimited *MemoryBudget
+	require.True(unlimited.Reserve(1 << 40))
+	require.Zero(unlimited.Used())
+}
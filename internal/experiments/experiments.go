@@ -0,0 +1,102 @@
+// Package experiments tracks the experimental behaviors enabled per namespace, so that
+// risky optimizations can be rolled out to a few namespaces before all of them. The
+// experiments are stored in the datastore, and every server reloads them periodically.
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+var experimentNameRegex = regexp.MustCompile(`^[a-z][a-z0-9-]{0,62}[a-z0-9]$`)
+
+// ValidateName returns an error if the name cannot name an experiment.
+func ValidateName(experiment string) error {
+	if !experimentNameRegex.MatchString(experiment) {
+		return fmt.Errorf("invalid experiment name `%s`: must match %s", experiment, experimentNameRegex)
+	}
+	return nil
+}
+
+type enabledSet map[datastore.NamespaceExperiment]struct{}
+
+// Registry holds the experiments enabled per namespace, as last loaded from the
+// datastore.
+type Registry struct {
+	ds      datastore.Datastore
+	enabled atomic.Pointer[enabledSet]
+}
+
+// NewRegistry creates a registry of the experiments stored in the datastore, with none
+// enabled until it is first refreshed.
+func NewRegistry(ds datastore.Datastore) *Registry {
+	return &Registry{ds: ds}
+}
+
+// Enabled returns whether the experiment is enabled for the namespace. A nil registry
+// has no experiments enabled.
+func (r *Registry) Enabled(namespace, experiment string) bool {
+	if r == nil {
+		return false
+	}
+
+	enabled := r.enabled.Load()
+	if enabled == nil {
+		return false
+	}
+	_, ok := (*enabled)[datastore.NamespaceExperiment{Namespace: namespace, Experiment: experiment}]
+	return ok
+}
+
+// Refresh reloads the experiments from the datastore.
+func (r *Registry) Refresh(ctx context.Context) error {
+	store, err := common.NamespaceExperimentStoreOf(r.ds)
+	if err != nil {
+		return err
+	}
+
+	experiments, err := store.ListNamespaceExperiments(ctx)
+	if err != nil {
+		return err
+	}
+
+	enabled := make(enabledSet, len(experiments))
+	for _, experiment := range experiments {
+		enabled[experiment] = struct{}{}
+	}
+	r.enabled.Store(&enabled)
+	return nil
+}
+
+// Start refreshes the experiments every interval until the context is canceled, or
+// immediately returns if the datastore cannot store experiments.
+func (r *Registry) Start(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Refresh(ctx); err != nil {
+			if errors.As(err, &datastore.ErrNamespaceExperimentsUnsupported{}) {
+				log.Ctx(ctx).Debug().Err(err).Msg("namespace experiments disabled")
+				return nil
+			}
+			if ctx.Err() == nil {
+				log.Ctx(ctx).Warn().Err(err).Msg("unable to refresh namespace experiments")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package experiments
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"new-planner", "leopard-index", "v2"} {
+		require.NoError(t, ValidateName(name), name)
+	}
+	for _, name := range []string{"", "a", "New-Planner", "new_planner", "-planner", "planner-", "2planner"} {
+		require.Error(t, ValidateName(name), name)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	require := require.New(t)
+
+	var nilRegistry *Registry
+	require.False(nilRegistry.Enabled("document", "new-planner"))
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	store, err := common.NamespaceExperimentStoreOf(ds)
+	require.NoError(err)
+
+	ctx := context.Background()
+	require.NoError(store.SetNamespaceExperiment(ctx, "document", "new-planner", true))
+
+	registry := NewRegistry(ds)
+	require.False(registry.Enabled("document", "new-planner"))
+
+	require.NoError(registry.Refresh(ctx))
+	require.True(registry.Enabled("document", "new-planner"))
+	require.False(registry.Enabled("folder", "new-planner"))
+	require.False(registry.Enabled("document", "leopard-index"))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		_ = registry.Start(ctx, 10*time.Millisecond)
+	}()
+
+	require.NoError(store.SetNamespaceExperiment(ctx, "document", "new-planner", false))
+	require.NoError(store.SetNamespaceExperiment(ctx, "folder", "new-planner", true))
+	require.Eventually(func() bool {
+		return !registry.Enabled("document", "new-planner") && registry.Enabled("folder", "new-planner")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRegistryUnsupported(t *testing.T) {
+	ds := &proxy_test.MockDatastore{}
+	ds.On("ListNamespaceExperiments").Return([]datastore.NamespaceExperiment(nil), datastore.NewNamespaceExperimentsUnsupportedErr("test")).Once()
+
+	// Start returns once the datastore cannot store experiments, rather than polling.
+	registry := NewRegistry(ds)
+	require.NoError(t, registry.Start(context.Background(), time.Hour))
+	require.False(t, registry.Enabled("document", "new-planner"))
+	ds.AssertExpectations(t)
+}
@@ -0,0 +1,245 @@
+package gateway
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"github.com/authzed/authzed-go/pkg/responsemeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// CheckTracePath is the path at which the check trace visualization endpoint is served.
+const CheckTracePath = "/debug/checktrace"
+
+const maxCheckTraceRequestBytes = 1 << 20
+
+// TraceFormat is the format in which a check trace is rendered.
+type TraceFormat string
+
+const (
+	// TraceFormatJSON renders the trace as the JSON form of the API's DebugInformation.
+	TraceFormatJSON TraceFormat = "json"
+
+	// TraceFormatDOT renders the trace as a Graphviz DOT digraph.
+	TraceFormatDOT TraceFormat = "dot"
+
+	// TraceFormatSVG renders the trace as a standalone SVG image.
+	TraceFormatSVG TraceFormat = "svg"
+)
+
+// NewCheckTraceHandler returns an HTTP handler which runs the CheckPermissionRequest found
+// in the body of a POST with debugging enabled, and renders the resulting resolution tree
+// in the format given by the `format` query parameter.
+//
+// The Authorization header of the incoming request is forwarded to the upstream, so the
+// endpoint is subject to the same authentication as the API itself.
+func NewCheckTraceHandler(client v1.PermissionsServiceClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		format := TraceFormat(strings.ToLower(r.URL.Query().Get("format")))
+		if format == "" {
+			format = TraceFormatJSON
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxCheckTraceRequestBytes))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not read request: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		req := &v1.CheckPermissionRequest{}
+		if err := protojson.Unmarshal(body, req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid CheckPermissionRequest: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		ctx := requestmeta.AddRequestHeaders(r.Context(), requestmeta.RequestDebugInformation)
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", auth)
+		}
+
+		var trailer metadata.MD
+		if _, err := client.CheckPermission(ctx, req, grpc.Trailer(&trailer)); err != nil {
+			http.Error(w, err.Error(), runtime.HTTPStatusFromCode(status.Code(err)))
+			return
+		}
+
+		encodedDebugInfo, err := responsemeta.GetResponseTrailerMetadata(trailer, responsemeta.DebugInformation)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("missing debug information: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		debugInfo := &v1.DebugInformation{}
+		if err := protojson.Unmarshal([]byte(encodedDebugInfo), debugInfo); err != nil {
+			http.Error(w, fmt.Sprintf("invalid debug information: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		rendered, contentType, err := RenderCheckTrace(debugInfo, format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		_, _ = io.WriteString(w, rendered)
+	})
+}
+
+// RenderCheckTrace renders the check trace found in the debug information in the given
+// format, returning the rendered form and its content type.
+func RenderCheckTrace(debugInfo *v1.DebugInformation, format TraceFormat) (string, string, error) {
+	switch format {
+	case TraceFormatJSON:
+		marshaled, err := protojson.Marshal(debugInfo)
+		if err != nil {
+			return "", "", err
+		}
+		return string(marshaled), "application/json", nil
+
+	case TraceFormatDOT:
+		return renderDOT(debugInfo.Check), "text/vnd.graphviz", nil
+
+	case TraceFormatSVG:
+		return renderSVG(debugInfo.Check), "image/svg+xml", nil
+
+	default:
+		return "", "", fmt.Errorf("unknown trace format `%s`: must be one of json, dot or svg", format)
+	}
+}
+
+func traceLabel(trace *v1.CheckDebugTrace) string {
+	subject := trace.Subject.Object.ObjectType + ":" + trace.Subject.Object.ObjectId
+	if trace.Subject.OptionalRelation != "" {
+		subject += "#" + trace.Subject.OptionalRelation
+	}
+
+	return fmt.Sprintf("%s:%s#%s @ %s",
+		trace.Resource.ObjectType,
+		trace.Resource.ObjectId,
+		trace.Permission,
+		subject,
+	)
+}
+
+func traceResult(trace *v1.CheckDebugTrace) (string, string) {
+	suffix := ""
+	if trace.GetWasCachedResult() {
+		suffix = " (cached)"
+	}
+
+	switch trace.Result {
+	case v1.CheckDebugTrace_PERMISSIONSHIP_HAS_PERMISSION:
+		return "has permission" + suffix, "#2e7d32"
+	default:
+		return "no permission" + suffix, "#c62828"
+	}
+}
+
+func subTraces(trace *v1.CheckDebugTrace) []*v1.CheckDebugTrace {
+	if subProblems := trace.GetSubProblems(); subProblems != nil {
+		return subProblems.Traces
+	}
+	return nil
+}
+
+func renderDOT(root *v1.CheckDebugTrace) string {
+	var sb strings.Builder
+	sb.WriteString("digraph checktrace {\n")
+	sb.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+
+	counter := 0
+	var visit func(trace *v1.CheckDebugTrace) string
+	visit = func(trace *v1.CheckDebugTrace) string {
+		id := fmt.Sprintf("n%d", counter)
+		counter++
+
+		result, color := traceResult(trace)
+		fmt.Fprintf(&sb, "\t%s [label=%q, color=%q];\n", id, traceLabel(trace)+"\n"+result, color)
+		for _, sub := range subTraces(trace) {
+			subID := visit(sub)
+			fmt.Fprintf(&sb, "\t%s -> %s;\n", id, subID)
+		}
+		return id
+	}
+
+	if root != nil {
+		visit(root)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+const (
+	svgRowHeight   = 28
+	svgIndentWidth = 24
+	svgCharWidth   = 8
+	svgMargin      = 10
+)
+
+// renderSVG renders the trace as an indented tree, one node per row, with connectors
+// drawn from each parent to its children.
+func renderSVG(root *v1.CheckDebugTrace) string {
+	type row struct {
+		depth  int
+		label  string
+		color  string
+		parent int
+	}
+
+	var rows []row
+	var visit func(trace *v1.CheckDebugTrace, depth, parent int)
+	visit = func(trace *v1.CheckDebugTrace, depth, parent int) {
+		result, color := traceResult(trace)
+		rows = append(rows, row{depth, traceLabel(trace) + " → " + result, color, parent})
+		index := len(rows) - 1
+		for _, sub := range subTraces(trace) {
+			visit(sub, depth+1, index)
+		}
+	}
+	if root != nil {
+		visit(root, 0, -1)
+	}
+
+	width := 0
+	for _, r := range rows {
+		rowWidth := r.depth*svgIndentWidth + len(r.label)*svgCharWidth
+		if rowWidth > width {
+			width = rowWidth
+		}
+	}
+	width += 2 * svgMargin
+	height := len(rows)*svgRowHeight + 2*svgMargin
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="13">`, width, height)
+	sb.WriteString("\n")
+	for i, r := range rows {
+		x := svgMargin + r.depth*svgIndentWidth
+		y := svgMargin + i*svgRowHeight + svgRowHeight/2
+		if r.parent >= 0 {
+			parentX := svgMargin + rows[r.parent].depth*svgIndentWidth + svgIndentWidth/2
+			parentY := svgMargin + r.parent*svgRowHeight + svgRowHeight/2
+			fmt.Fprintf(&sb, `<polyline points="%d,%d %d,%d %d,%d" fill="none" stroke="#999"/>`, parentX, parentY+6, parentX, y, x-4, y)
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, `<text x="%d" y="%d" fill="%s" dominant-baseline="middle">%s</text>`, x, y, r.color, html.EscapeString(r.label))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("</svg>\n")
+	return sb.String()
+}
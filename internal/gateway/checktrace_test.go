@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+)
+
+var testTrace = &v1.DebugInformation{
+	Check: &v1.CheckDebugTrace{
+		Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: "first"},
+		Permission: "view",
+		Subject:    &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "tom"}},
+		Result:     v1.CheckDebugTrace_PERMISSIONSHIP_HAS_PERMISSION,
+		Resolution: &v1.CheckDebugTrace_SubProblems_{
+			SubProblems: &v1.CheckDebugTrace_SubProblems{
+				Traces: []*v1.CheckDebugTrace{
+					{
+						Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: "first"},
+						Permission: "viewer",
+						Subject:    &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "tom"}},
+						Result:     v1.CheckDebugTrace_PERMISSIONSHIP_HAS_PERMISSION,
+						Resolution: &v1.CheckDebugTrace_WasCachedResult{WasCachedResult: true},
+					},
+					{
+						Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: "first"},
+						Permission: "owner",
+						Subject:    &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "tom"}},
+						Result:     v1.CheckDebugTrace_PERMISSIONSHIP_NO_PERMISSION,
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestRenderCheckTrace(t *testing.T) {
+	testCases := []struct {
+		format              TraceFormat
+		expectedContentType string
+		expectedContains    []string
+	}{
+		{
+			TraceFormatJSON,
+			"application/json",
+			[]string{`"permission":"view"`, `"wasCachedResult":true`},
+		},
+		{
+			TraceFormatDOT,
+			"text/vnd.graphviz",
+			[]string{
+				"digraph checktrace {",
+				`n0 [label="document:first#view @ user:tom\nhas permission"`,
+				`n1 [label="document:first#viewer @ user:tom\nhas permission (cached)"`,
+				`n2 [label="document:first#owner @ user:tom\nno permission"`,
+				"n0 -> n1;",
+				"n0 -> n2;",
+			},
+		},
+		{
+			TraceFormatSVG,
+			"image/svg+xml",
+			[]string{
+				"<svg ",
+				"document:first#view @ user:tom → has permission</text>",
+				"document:first#owner @ user:tom → no permission</text>",
+				"<polyline ",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(string(tc.format), func(t *testing.T) {
+			rendered, contentType, err := RenderCheckTrace(testTrace, tc.format)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedContentType, contentType)
+			for _, expected := range tc.expectedContains {
+				require.Contains(t, rendered, expected)
+			}
+		})
+	}
+}
+
+func TestRenderCheckTraceUnknownFormat(t *testing.T) {
+	_, _, err := RenderCheckTrace(testTrace, TraceFormat("png"))
+	require.Error(t, err)
+}
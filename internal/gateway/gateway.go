@@ -4,10 +4,8 @@ package gateway
 
 import (
 	"context"
-	"io"
 	"net/http"
 
-	"github.com/authzed/authzed-go/proto"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/authzed/grpcutil"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
@@ -22,6 +20,10 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/gateway/graphql"
+	"github.com/authzed/spicedb/internal/gateway/openfga"
+	"github.com/authzed/spicedb/internal/gateway/scim"
 )
 
 var histogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
@@ -31,9 +33,23 @@ var histogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
 	Help:      "A histogram of the duration spent processing requests to the SpiceDB REST Gateway.",
 }, []string{"method"})
 
+// Options select the APIs served by the gateway besides the v1 API.
+type Options struct {
+	// OpenFGAEnabled serves the OpenFGA API under openfga.PathPrefix.
+	OpenFGAEnabled bool
+
+	// GraphQLEnabled serves the GraphQL endpoint at graphql.Path.
+	GraphQLEnabled bool
+
+	// SCIM, if set, serves the SCIM provisioning API under scim.PathPrefix, with users and
+	// groups mapped onto relationships as configured.
+	SCIM *scim.Config
+}
+
 // NewHandler creates an REST gateway HTTP Handler with the provided upstream
-// configuration.
-func NewHandler(ctx context.Context, upstreamAddr, upstreamTLSCertPath string) (http.Handler, error) {
+// configuration. The responses of streaming RPCs are written as newline-delimited JSON, or
+// as server-sent events to requests accepting EventStreamContentType.
+func NewHandler(ctx context.Context, upstreamAddr, upstreamTLSCertPath string, options Options) (http.Handler, error) {
 	opts := []grpc.DialOption{
 		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
 		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
@@ -44,12 +60,16 @@ func NewHandler(ctx context.Context, upstreamAddr, upstreamTLSCertPath string) (
 		opts = append(opts, grpcutil.WithCustomCerts(upstreamTLSCertPath, grpcutil.SkipVerifyCA))
 	}
 
-	healthConn, err := grpc.Dial(upstreamAddr, opts...)
+	upstreamConn, err := grpc.Dial(upstreamAddr, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	gwMux := runtime.NewServeMux(runtime.WithMetadata(OtelAnnotator), runtime.WithHealthzEndpoint(healthpb.NewHealthClient(healthConn)))
+	gwMux := runtime.NewServeMux(
+		runtime.WithMetadata(OtelAnnotator),
+		runtime.WithHealthzEndpoint(healthpb.NewHealthClient(upstreamConn)),
+		runtime.WithMarshalerOption(EventStreamContentType, newEventStreamMarshaler()),
+	)
 	if err := v1.RegisterSchemaServiceHandlerFromEndpoint(ctx, gwMux, upstreamAddr, opts); err != nil {
 		return nil, err
 	}
@@ -60,10 +80,27 @@ func NewHandler(ctx context.Context, upstreamAddr, upstreamTLSCertPath string) (
 		return nil, err
 	}
 
+	openAPIDocument, err := OpenAPIDocument(options)
+	if err != nil {
+		return nil, err
+	}
+
 	mux := http.NewServeMux()
-	mux.Handle("/openapi.json", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		_, _ = io.WriteString(w, proto.OpenAPISchema)
+	mux.Handle(OpenAPIPath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPIDocument)
 	}))
+	mux.Handle(CheckTracePath, NewCheckTraceHandler(v1.NewPermissionsServiceClient(upstreamConn)))
+	mux.Handle(ResidualCheckPath, NewResidualCheckHandler(v1.NewPermissionsServiceClient(upstreamConn)))
+	if options.OpenFGAEnabled {
+		mux.Handle(openfga.PathPrefix, openfga.NewHandler(v1.NewPermissionsServiceClient(upstreamConn)))
+	}
+	if options.GraphQLEnabled {
+		mux.Handle(graphql.Path, graphql.NewHandler(v1.NewPermissionsServiceClient(upstreamConn)))
+	}
+	if options.SCIM != nil {
+		mux.Handle(scim.PathPrefix, scim.NewHandler(v1.NewPermissionsServiceClient(upstreamConn), *options.SCIM))
+	}
 	mux.Handle("/", gwMux)
 
 	return promhttp.InstrumentHandlerDuration(histogram, otelhttp.NewHandler(mux, "gateway")), nil
@@ -0,0 +1,596 @@
+// Package graphql implements a GraphQL endpoint with queries for checking permissions,
+// looking up resources and reading relationships, on top of the SpiceDB permissions
+// service, for applications that query their backends through a GraphQL gateway.
+//
+// The permission checks of a request are batched and deduplicated: see checkLoader.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	gql "github.com/graph-gophers/graphql-go"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Path is the path at which the GraphQL endpoint is served.
+const Path = "/graphql"
+
+const maxRequestBytes = 4 << 20
+
+// Schema is the GraphQL schema of the endpoint.
+const Schema = `
+schema {
+	query: Query
+}
+
+type Query {
+	"Checks whether the subject has the permission on the resource."
+	checkPermission(
+		resource: ObjectInput!
+		permission: String!
+		subject: SubjectInput!
+		context: JSON
+		consistency: ConsistencyInput
+	): CheckResult!
+
+	"Returns the resources of the type on which the subject has the permission."
+	lookupResources(
+		resourceType: String!
+		permission: String!
+		subject: SubjectInput!
+		context: JSON
+		consistency: ConsistencyInput
+		limit: Int = 1000
+	): [LookupResult!]!
+
+	"Returns the relationships matching the filter."
+	readRelationships(
+		filter: RelationshipFilterInput!
+		consistency: ConsistencyInput
+		limit: Int = 1000
+	): [Relationship!]!
+}
+
+"A JSON object, such as the context of a caveat."
+scalar JSON
+
+input ObjectInput {
+	type: String!
+	id: String!
+}
+
+input SubjectInput {
+	type: String!
+	id: String!
+	relation: String
+}
+
+"The consistency of a query. Exactly one field may be set; queries minimize latency by default."
+input ConsistencyInput {
+	minimizeLatency: Boolean
+	fullyConsistent: Boolean
+	atLeastAsFresh: String
+	atExactSnapshot: String
+}
+
+input RelationshipFilterInput {
+	resourceType: String!
+	resourceId: String
+	relation: String
+	subjectFilter: SubjectFilterInput
+}
+
+input SubjectFilterInput {
+	type: String!
+	id: String
+	relation: String
+}
+
+enum Permissionship {
+	NO_PERMISSION
+	HAS_PERMISSION
+	CONDITIONAL_PERMISSION
+}
+
+type CheckResult {
+	permissionship: Permissionship!
+	"The ZedToken of the revision at which the check was made."
+	checkedAt: String!
+	"The context parameters missing to resolve a conditional permission."
+	missingContext: [String!]!
+}
+
+type LookupResult {
+	resourceId: String!
+	permissionship: Permissionship!
+	"The ZedToken of the revision at which the lookup was made."
+	lookedUpAt: String!
+}
+
+type Object {
+	type: String!
+	id: String!
+}
+
+type Subject {
+	type: String!
+	id: String!
+	relation: String
+}
+
+type Caveat {
+	name: String!
+	context: JSON
+}
+
+type Relationship {
+	resource: Object!
+	relation: String!
+	subject: Subject!
+	caveat: Caveat
+}
+`
+
+// Request is a GraphQL request. A batch of requests is sent as a JSON array of requests.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// NewHandler returns an HTTP handler serving the GraphQL endpoint.
+//
+// The Authorization header of the incoming request is forwarded to the upstream, so
+// clients authenticate with the preshared key as a bearer token.
+func NewHandler(client v1.PermissionsServiceClient) http.Handler {
+	// Every field of a query is resolved concurrently, so that its checks share a batch.
+	schema := gql.MustParseSchema(Schema, &resolver{client}, gql.UseStringDescriptions(), gql.MaxParallelism(maxBatchSize))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		batched := bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+		var requests []Request
+		if batched {
+			err = json.Unmarshal(body, &requests)
+		} else {
+			requests = make([]Request, 1)
+			err = json.Unmarshal(body, &requests[0])
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		// The requests of a batch share the loader, and so their checks.
+		ctx := forwardAuthorization(r)
+		ctx = context.WithValue(ctx, loaderKey{}, newCheckLoader(ctx, client))
+
+		responses := make([]*gql.Response, len(requests))
+		var wg sync.WaitGroup
+		for i, req := range requests {
+			i, req := i, req
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				responses[i] = schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+			}()
+		}
+		wg.Wait()
+
+		w.Header().Set("Content-Type", "application/json")
+		if batched {
+			_ = json.NewEncoder(w).Encode(responses)
+		} else {
+			_ = json.NewEncoder(w).Encode(responses[0])
+		}
+	})
+}
+
+type loaderKey struct{}
+
+func forwardAuthorization(r *http.Request) context.Context {
+	ctx := r.Context()
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", auth)
+	}
+	return ctx
+}
+
+// upstreamError is an error returned by the permissions service, reported with its gRPC
+// code in the extensions of the GraphQL error.
+type upstreamError struct {
+	err error
+}
+
+func (e upstreamError) Error() string {
+	return status.Convert(e.err).Message()
+}
+
+func (e upstreamError) Extensions() map[string]any {
+	return map[string]any{"code": status.Code(e.err).String()}
+}
+
+// JSON is the GraphQL scalar of a JSON object.
+type JSON map[string]any
+
+// ImplementsGraphQLType implements the scalar of the schema.
+func (JSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+// UnmarshalGraphQL reads a JSON object of a query.
+func (j *JSON) UnmarshalGraphQL(input any) error {
+	object, ok := input.(map[string]any)
+	if !ok {
+		return fmt.Errorf("expected a JSON object, got %T", input)
+	}
+	*j = object
+	return nil
+}
+
+// MarshalJSON writes the JSON object.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any(j))
+}
+
+func (j *JSON) toStruct() (*structpb.Struct, error) {
+	if j == nil || len(*j) == 0 {
+		return nil, nil
+	}
+	return structpb.NewStruct(*j)
+}
+
+func jsonOf(s *structpb.Struct) *JSON {
+	if s == nil {
+		return nil
+	}
+	j := JSON(s.AsMap())
+	return &j
+}
+
+type objectInput struct {
+	Type string
+	ID   string
+}
+
+type subjectInput struct {
+	Type     string
+	ID       string
+	Relation *string
+}
+
+func (s subjectInput) toReference() *v1.SubjectReference {
+	return &v1.SubjectReference{
+		Object:           &v1.ObjectReference{ObjectType: s.Type, ObjectId: s.ID},
+		OptionalRelation: deref(s.Relation),
+	}
+}
+
+type consistencyInput struct {
+	MinimizeLatency *bool
+	FullyConsistent *bool
+	AtLeastAsFresh  *string
+	AtExactSnapshot *string
+}
+
+func (c *consistencyInput) toConsistency() (*v1.Consistency, error) {
+	if c == nil {
+		return &v1.Consistency{Requirement: &v1.Consistency_MinimizeLatency{MinimizeLatency: true}}, nil
+	}
+
+	var requirements []*v1.Consistency
+	if c.MinimizeLatency != nil && *c.MinimizeLatency {
+		requirements = append(requirements, &v1.Consistency{Requirement: &v1.Consistency_MinimizeLatency{MinimizeLatency: true}})
+	}
+	if c.FullyConsistent != nil && *c.FullyConsistent {
+		requirements = append(requirements, &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}})
+	}
+	if c.AtLeastAsFresh != nil {
+		requirements = append(requirements, &v1.Consistency{Requirement: &v1.Consistency_AtLeastAsFresh{AtLeastAsFresh: &v1.ZedToken{Token: *c.AtLeastAsFresh}}})
+	}
+	if c.AtExactSnapshot != nil {
+		requirements = append(requirements, &v1.Consistency{Requirement: &v1.Consistency_AtExactSnapshot{AtExactSnapshot: &v1.ZedToken{Token: *c.AtExactSnapshot}}})
+	}
+
+	switch len(requirements) {
+	case 0:
+		return &v1.Consistency{Requirement: &v1.Consistency_MinimizeLatency{MinimizeLatency: true}}, nil
+	case 1:
+		return requirements[0], nil
+	default:
+		return nil, errors.New("at most one consistency requirement may be set")
+	}
+}
+
+type resolver struct {
+	client v1.PermissionsServiceClient
+}
+
+type checkPermissionArgs struct {
+	Resource    objectInput
+	Permission  string
+	Subject     subjectInput
+	Context     *JSON
+	Consistency *consistencyInput
+}
+
+func (r *resolver) CheckPermission(ctx context.Context, args checkPermissionArgs) (*checkResult, error) {
+	consistency, err := args.Consistency.toConsistency()
+	if err != nil {
+		return nil, err
+	}
+	caveatContext, err := args.Context.toStruct()
+	if err != nil {
+		return nil, err
+	}
+
+	loader, ok := ctx.Value(loaderKey{}).(*checkLoader)
+	if !ok {
+		return nil, errors.New("missing check loader")
+	}
+
+	resp, err := loader.Load(ctx, &v1.CheckPermissionRequest{
+		Consistency: consistency,
+		Resource:    &v1.ObjectReference{ObjectType: args.Resource.Type, ObjectId: args.Resource.ID},
+		Permission:  args.Permission,
+		Subject:     args.Subject.toReference(),
+		Context:     caveatContext,
+	})
+	if err != nil {
+		return nil, upstreamError{err}
+	}
+	return &checkResult{resp}, nil
+}
+
+type checkResult struct {
+	resp *v1.CheckPermissionResponse
+}
+
+func (c *checkResult) Permissionship() string {
+	switch c.resp.Permissionship {
+	case v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION:
+		return "HAS_PERMISSION"
+	case v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION:
+		return "CONDITIONAL_PERMISSION"
+	default:
+		return "NO_PERMISSION"
+	}
+}
+
+func (c *checkResult) CheckedAt() string {
+	return c.resp.CheckedAt.GetToken()
+}
+
+func (c *checkResult) MissingContext() []string {
+	missing := c.resp.PartialCaveatInfo.GetMissingRequiredContext()
+	if missing == nil {
+		return []string{}
+	}
+	return missing
+}
+
+type lookupResourcesArgs struct {
+	ResourceType string
+	Permission   string
+	Subject      subjectInput
+	Context      *JSON
+	Consistency  *consistencyInput
+	Limit        int32
+}
+
+func (r *resolver) LookupResources(ctx context.Context, args lookupResourcesArgs) ([]*lookupResult, error) {
+	consistency, err := args.Consistency.toConsistency()
+	if err != nil {
+		return nil, err
+	}
+	caveatContext, err := args.Context.toStruct()
+	if err != nil {
+		return nil, err
+	}
+
+	// Stop the lookup once the limit has been reached.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := r.client.LookupResources(ctx, &v1.LookupResourcesRequest{
+		Consistency:        consistency,
+		ResourceObjectType: args.ResourceType,
+		Permission:         args.Permission,
+		Subject:            args.Subject.toReference(),
+		Context:            caveatContext,
+	})
+	if err != nil {
+		return nil, upstreamError{err}
+	}
+
+	results := make([]*lookupResult, 0)
+	for len(results) < int(args.Limit) {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, upstreamError{err}
+		}
+		results = append(results, &lookupResult{resp})
+	}
+	return results, nil
+}
+
+type lookupResult struct {
+	resp *v1.LookupResourcesResponse
+}
+
+func (l *lookupResult) ResourceID() string {
+	return l.resp.ResourceObjectId
+}
+
+func (l *lookupResult) Permissionship() string {
+	if l.resp.Permissionship == v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION {
+		return "CONDITIONAL_PERMISSION"
+	}
+	return "HAS_PERMISSION"
+}
+
+func (l *lookupResult) LookedUpAt() string {
+	return l.resp.LookedUpAt.GetToken()
+}
+
+type relationshipFilterInput struct {
+	ResourceType  string
+	ResourceID    *string
+	Relation      *string
+	SubjectFilter *subjectFilterInput
+}
+
+type subjectFilterInput struct {
+	Type     string
+	ID       *string
+	Relation *string
+}
+
+type readRelationshipsArgs struct {
+	Filter      relationshipFilterInput
+	Consistency *consistencyInput
+	Limit       int32
+}
+
+func (r *resolver) ReadRelationships(ctx context.Context, args readRelationshipsArgs) ([]*relationship, error) {
+	consistency, err := args.Consistency.toConsistency()
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &v1.RelationshipFilter{
+		ResourceType:       args.Filter.ResourceType,
+		OptionalResourceId: deref(args.Filter.ResourceID),
+		OptionalRelation:   deref(args.Filter.Relation),
+	}
+	if subjectFilter := args.Filter.SubjectFilter; subjectFilter != nil {
+		filter.OptionalSubjectFilter = &v1.SubjectFilter{
+			SubjectType:       subjectFilter.Type,
+			OptionalSubjectId: deref(subjectFilter.ID),
+		}
+		if subjectFilter.Relation != nil {
+			filter.OptionalSubjectFilter.OptionalRelation = &v1.SubjectFilter_RelationFilter{Relation: *subjectFilter.Relation}
+		}
+	}
+
+	// Stop reading once the limit has been reached.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := r.client.ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+		Consistency:        consistency,
+		RelationshipFilter: filter,
+	})
+	if err != nil {
+		return nil, upstreamError{err}
+	}
+
+	relationships := make([]*relationship, 0)
+	for len(relationships) < int(args.Limit) {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, upstreamError{err}
+		}
+		relationships = append(relationships, &relationship{resp.Relationship})
+	}
+	return relationships, nil
+}
+
+type relationship struct {
+	rel *v1.Relationship
+}
+
+func (r *relationship) Resource() *object {
+	return &object{r.rel.Resource}
+}
+
+func (r *relationship) Relation() string {
+	return r.rel.Relation
+}
+
+func (r *relationship) Subject() *subject {
+	return &subject{r.rel.Subject}
+}
+
+func (r *relationship) Caveat() *caveat {
+	if r.rel.OptionalCaveat == nil {
+		return nil
+	}
+	return &caveat{r.rel.OptionalCaveat}
+}
+
+type object struct {
+	ref *v1.ObjectReference
+}
+
+func (o *object) Type() string {
+	return o.ref.ObjectType
+}
+
+func (o *object) ID() string {
+	return o.ref.ObjectId
+}
+
+type subject struct {
+	ref *v1.SubjectReference
+}
+
+func (s *subject) Type() string {
+	return s.ref.Object.ObjectType
+}
+
+func (s *subject) ID() string {
+	return s.ref.Object.ObjectId
+}
+
+func (s *subject) Relation() *string {
+	if s.ref.OptionalRelation == "" {
+		return nil
+	}
+	return &s.ref.OptionalRelation
+}
+
+type caveat struct {
+	caveat *v1.ContextualizedCaveat
+}
+
+func (c *caveat) Name() string {
+	return c.caveat.CaveatName
+}
+
+func (c *caveat) Context() *JSON {
+	return jsonOf(c.caveat.Context)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
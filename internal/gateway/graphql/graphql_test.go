@@ -0,0 +1,183 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/gateway/graphql"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+)
+
+const testSchema = `definition user {}
+
+caveat only_on(day int) {
+	day == 1
+}
+
+definition document {
+	relation writer: user
+	relation reader: user | user with only_on
+	permission view = reader + writer
+}`
+
+func TestGraphQLHandler(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	_, err := v1.NewSchemaServiceClient(conn).WriteSchema(context.Background(), &v1.WriteSchemaRequest{Schema: testSchema})
+	require.NoError(t, err)
+
+	var updates []*v1.RelationshipUpdate
+	for _, rel := range []*v1.Relationship{
+		{Resource: &v1.ObjectReference{ObjectType: "document", ObjectId: "budget"}, Relation: "writer", Subject: &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "anne"}}},
+		{Resource: &v1.ObjectReference{ObjectType: "document", ObjectId: "roadmap"}, Relation: "reader", Subject: &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "anne"}}},
+		{Resource: &v1.ObjectReference{ObjectType: "document", ObjectId: "notes"}, Relation: "reader", Subject: &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "anne"}}, OptionalCaveat: &v1.ContextualizedCaveat{CaveatName: "only_on"}},
+	} {
+		updates = append(updates, &v1.RelationshipUpdate{Operation: v1.RelationshipUpdate_OPERATION_TOUCH, Relationship: rel})
+	}
+	_, err = v1.NewPermissionsServiceClient(conn).WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{Updates: updates})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(graphql.NewHandler(v1.NewPermissionsServiceClient(conn)))
+	t.Cleanup(server.Close)
+
+	testCases := []struct {
+		name         string
+		body         string
+		expectedData string
+	}{
+		{
+			"check permissions",
+			`{"query": "{
+				budget: checkPermission(resource: {type: \"document\", id: \"budget\"}, permission: \"view\", subject: {type: \"user\", id: \"anne\"}, consistency: {fullyConsistent: true}) { permissionship missingContext }
+				denied: checkPermission(resource: {type: \"document\", id: \"budget\"}, permission: \"view\", subject: {type: \"user\", id: \"bob\"}) { permissionship }
+				notes: checkPermission(resource: {type: \"document\", id: \"notes\"}, permission: \"view\", subject: {type: \"user\", id: \"anne\"}) { permissionship missingContext }
+			}"}`,
+			`{
+				"budget": {"permissionship": "HAS_PERMISSION", "missingContext": []},
+				"denied": {"permissionship": "NO_PERMISSION"},
+				"notes": {"permissionship": "CONDITIONAL_PERMISSION", "missingContext": ["day"]}
+			}`,
+		},
+		{
+			"check with variables and context",
+			`{
+				"query": "query Check($day: JSON) { checkPermission(resource: {type: \"document\", id: \"notes\"}, permission: \"view\", subject: {type: \"user\", id: \"anne\"}, context: $day) { permissionship } }",
+				"operationName": "Check",
+				"variables": {"day": {"day": 1}}
+			}`,
+			`{"checkPermission": {"permissionship": "HAS_PERMISSION"}}`,
+		},
+		{
+			"read relationships",
+			`{"query": "{ readRelationships(filter: {resourceType: \"document\", subjectFilter: {type: \"user\", id: \"anne\"}}) { resource { id } relation subject { type id relation } caveat { name context } } }"}`,
+			`{"readRelationships": [
+				{"resource": {"id": "budget"}, "relation": "writer", "subject": {"type": "user", "id": "anne", "relation": null}, "caveat": null},
+				{"resource": {"id": "notes"}, "relation": "reader", "subject": {"type": "user", "id": "anne", "relation": null}, "caveat": {"name": "only_on", "context": {}}},
+				{"resource": {"id": "roadmap"}, "relation": "reader", "subject": {"type": "user", "id": "anne", "relation": null}, "caveat": null}
+			]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			// GraphQL strings cannot span lines, but the queries are easier to read if they do.
+			body := strings.NewReplacer("\n", " ", "\t", "").Replace(tc.body)
+			resp, err := http.Post(server.URL+graphql.Path, "application/json", strings.NewReader(body))
+			require.NoError(err)
+			defer resp.Body.Close()
+
+			var decoded struct {
+				Data   json.RawMessage `json:"data"`
+				Errors []any           `json:"errors"`
+			}
+			require.NoError(json.NewDecoder(resp.Body).Decode(&decoded))
+			require.Empty(decoded.Errors)
+			require.JSONEq(tc.expectedData, string(decoded.Data))
+		})
+	}
+
+	t.Run("lookup resources", func(t *testing.T) {
+		require := require.New(t)
+		for _, tc := range []struct {
+			limit           string
+			expectedResults int
+		}{
+			{"", 3},
+			{"limit: 1", 1},
+		} {
+			resp, err := http.Post(server.URL+graphql.Path, "application/json", strings.NewReader(
+				`{"query": "{ lookupResources(resourceType: \"document\", permission: \"view\", subject: {type: \"user\", id: \"anne\"}, `+tc.limit+`) { resourceId permissionship } }"}`,
+			))
+			require.NoError(err)
+
+			var decoded struct {
+				Data struct {
+					LookupResources []struct {
+						ResourceID     string `json:"resourceId"`
+						Permissionship string `json:"permissionship"`
+					} `json:"lookupResources"`
+				} `json:"data"`
+			}
+			require.NoError(json.NewDecoder(resp.Body).Decode(&decoded))
+			resp.Body.Close()
+			require.Len(decoded.Data.LookupResources, tc.expectedResults)
+
+			for _, result := range decoded.Data.LookupResources {
+				expected := "HAS_PERMISSION"
+				if result.ResourceID == "notes" {
+					expected = "CONDITIONAL_PERMISSION"
+				}
+				require.Equal(expected, result.Permissionship, result.ResourceID)
+			}
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		require := require.New(t)
+		resp, err := http.Post(server.URL+graphql.Path, "application/json", strings.NewReader(`[
+			{"query": "{ checkPermission(resource: {type: \"document\", id: \"roadmap\"}, permission: \"view\", subject: {type: \"user\", id: \"anne\"}) { permissionship } }"},
+			{"query": "{ checkPermission(resource: {type: \"document\", id: \"roadmap\"}, permission: \"writer\", subject: {type: \"user\", id: \"anne\"}) { permissionship } }"}
+		]`))
+		require.NoError(err)
+		defer resp.Body.Close()
+
+		var decoded []map[string]any
+		require.NoError(json.NewDecoder(resp.Body).Decode(&decoded))
+		require.Len(decoded, 2)
+		require.Equal(map[string]any{"checkPermission": map[string]any{"permissionship": "HAS_PERMISSION"}}, decoded[0]["data"])
+		require.Equal(map[string]any{"checkPermission": map[string]any{"permissionship": "NO_PERMISSION"}}, decoded[1]["data"])
+	})
+
+	t.Run("upstream error", func(t *testing.T) {
+		require := require.New(t)
+		resp, err := http.Post(server.URL+graphql.Path, "application/json", strings.NewReader(
+			`{"query": "{ checkPermission(resource: {type: \"document\", id: \"budget\"}, permission: \"unknown\", subject: {type: \"user\", id: \"anne\"}) { permissionship } }"}`,
+		))
+		require.NoError(err)
+		defer resp.Body.Close()
+
+		var decoded struct {
+			Errors []struct {
+				Message    string         `json:"message"`
+				Extensions map[string]any `json:"extensions"`
+			} `json:"errors"`
+		}
+		require.NoError(json.NewDecoder(resp.Body).Decode(&decoded))
+		require.Len(decoded.Errors, 1)
+		require.Equal("FailedPrecondition", decoded.Errors[0].Extensions["code"])
+		require.Contains(decoded.Errors[0].Message, "unknown")
+	})
+}
@@ -0,0 +1,99 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// batchWait is how long a batch of checks is held open for more checks before it is
+	// dispatched, so that the fields of a query resolved concurrently share a batch.
+	batchWait = 2 * time.Millisecond
+
+	// maxBatchSize is the maximum number of checks in a batch, which are also the maximum
+	// number of concurrent checks of a batch.
+	maxBatchSize = 100
+)
+
+// checkLoader batches and caches the permission checks of a request, with the semantics of
+// a DataLoader: checks requested while a batch is open are dispatched together, and every
+// distinct check is only made once per request, however many fields ask for it.
+//
+// The permissions service has no bulk check, so the checks of a batch are made concurrently.
+type checkLoader struct {
+	ctx    context.Context
+	client v1.PermissionsServiceClient
+
+	mu      sync.Mutex
+	calls   map[string]*checkCall
+	pending []*checkCall
+}
+
+type checkCall struct {
+	req  *v1.CheckPermissionRequest
+	done chan struct{}
+	resp *v1.CheckPermissionResponse
+	err  error
+}
+
+func newCheckLoader(ctx context.Context, client v1.PermissionsServiceClient) *checkLoader {
+	return &checkLoader{ctx: ctx, client: client, calls: make(map[string]*checkCall)}
+}
+
+// Load returns the response to the check, once the batch it was added to is dispatched.
+func (l *checkLoader) Load(ctx context.Context, req *v1.CheckPermissionRequest) (*v1.CheckPermissionResponse, error) {
+	key, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	call, ok := l.calls[string(key)]
+	if !ok {
+		call = &checkCall{req: req, done: make(chan struct{})}
+		l.calls[string(key)] = call
+		l.pending = append(l.pending, call)
+
+		switch len(l.pending) {
+		case maxBatchSize:
+			l.dispatch(l.takePending())
+		case 1:
+			time.AfterFunc(batchWait, func() {
+				l.mu.Lock()
+				batch := l.takePending()
+				l.mu.Unlock()
+				l.dispatch(batch)
+			})
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.resp, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// takePending returns the checks of the open batch and closes it. It must be called with
+// the lock held.
+func (l *checkLoader) takePending() []*checkCall {
+	batch := l.pending
+	l.pending = nil
+	return batch
+}
+
+func (l *checkLoader) dispatch(batch []*checkCall) {
+	for _, call := range batch {
+		call := call
+		go func() {
+			call.resp, call.err = l.client.CheckPermission(l.ctx, call.req)
+			close(call.done)
+		}()
+	}
+}
@@ -0,0 +1,65 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type countingClient struct {
+	v1.PermissionsServiceClient
+
+	mu     sync.Mutex
+	checks map[string]int
+}
+
+func (c *countingClient) CheckPermission(_ context.Context, req *v1.CheckPermissionRequest, _ ...grpc.CallOption) (*v1.CheckPermissionResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[req.Resource.ObjectId]++
+
+	permissionship := v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION
+	if req.Resource.ObjectId == "allowed" {
+		permissionship = v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION
+	}
+	return &v1.CheckPermissionResponse{Permissionship: permissionship}, nil
+}
+
+func TestCheckLoaderDeduplicates(t *testing.T) {
+	client := &countingClient{checks: make(map[string]int)}
+	loader := newCheckLoader(context.Background(), client)
+
+	// More checks than fit in a batch, with every distinct check requested several times.
+	resources := []string{"allowed", "denied"}
+	for i := 0; i < maxBatchSize; i++ {
+		resources = append(resources, "document"+string(rune('a'+i%26)))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		for _, resource := range resources {
+			resource := resource
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := loader.Load(context.Background(), &v1.CheckPermissionRequest{
+					Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: resource},
+					Permission: "view",
+					Subject:    &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "anne"}},
+				})
+				require.NoError(t, err)
+				require.Equal(t, resource == "allowed", resp.Permissionship == v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION)
+			}()
+		}
+	}
+	wg.Wait()
+
+	require.Len(t, client.checks, 28)
+	for resource, count := range client.checks {
+		require.Equal(t, 1, count, resource)
+	}
+}
@@ -0,0 +1,229 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/authzed/authzed-go/proto"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/authzed/spicedb/internal/gateway/graphql"
+	"github.com/authzed/spicedb/internal/gateway/openfga"
+	"github.com/authzed/spicedb/internal/gateway/scim"
+)
+
+// OpenAPIPath is the path at which the OpenAPI document of the gateway is served.
+const OpenAPIPath = "/openapi.json"
+
+// servedFiles are the files declaring the services forwarded by the gateway.
+var servedFiles = []protoreflect.FileDescriptor{
+	v1.File_authzed_api_v1_permission_service_proto,
+	v1.File_authzed_api_v1_schema_service_proto,
+	v1.File_authzed_api_v1_watch_service_proto,
+}
+
+// Route is an HTTP route of the gateway, bound to an RPC of the API.
+type Route struct {
+	// HTTPMethod is the HTTP method of the route, such as POST.
+	HTTPMethod string
+
+	// Path is the path of the route.
+	Path string
+
+	// FullMethod is the full name of the RPC, such as /authzed.api.v1.WatchService/Watch.
+	FullMethod string
+
+	// ServerStreaming is true if the RPC streams its responses, which are then written as
+	// newline-delimited JSON, or as server-sent events if requested with an Accept header
+	// of text/event-stream.
+	ServerStreaming bool
+}
+
+// Routes returns the HTTP route of every RPC of the services forwarded by the gateway, as
+// declared by the google.api.http annotations of the RPCs. RPCs without an annotation
+// have no route, and are returned with an empty path.
+func Routes() []Route {
+	var routes []Route
+	for _, file := range servedFiles {
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				method := methods.Get(j)
+				route := Route{
+					FullMethod:      fmt.Sprintf("/%s/%s", services.Get(i).FullName(), method.Name()),
+					ServerStreaming: method.IsStreamingServer(),
+				}
+
+				if rule, ok := protov2.GetExtension(method.Options(), annotations.E_Http).(*annotations.HttpRule); ok && rule != nil {
+					route.HTTPMethod, route.Path = httpPattern(rule)
+				}
+				routes = append(routes, route)
+			}
+		}
+	}
+	return routes
+}
+
+func httpPattern(rule *annotations.HttpRule) (string, string) {
+	switch pattern := rule.Pattern.(type) {
+	case *annotations.HttpRule_Get:
+		return http.MethodGet, pattern.Get
+	case *annotations.HttpRule_Put:
+		return http.MethodPut, pattern.Put
+	case *annotations.HttpRule_Post:
+		return http.MethodPost, pattern.Post
+	case *annotations.HttpRule_Delete:
+		return http.MethodDelete, pattern.Delete
+	case *annotations.HttpRule_Patch:
+		return http.MethodPatch, pattern.Patch
+	case *annotations.HttpRule_Custom:
+		return pattern.Custom.GetKind(), pattern.Custom.GetPath()
+	default:
+		return "", ""
+	}
+}
+
+// OpenAPIDocument returns the OpenAPI document describing the gateway. It is the document
+// generated from the API definitions, restricted to the routes served by the gateway, with
+// the endpoints specific to the gateway added.
+func OpenAPIDocument(options Options) ([]byte, error) {
+	var document map[string]any
+	if err := json.Unmarshal([]byte(proto.OpenAPISchema), &document); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	paths, _ := document["paths"].(map[string]any)
+	if paths == nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: missing paths")
+	}
+
+	served := make(map[string]Route)
+	for _, route := range Routes() {
+		if route.Path != "" {
+			served[strings.ToLower(route.HTTPMethod)+" "+route.Path] = route
+		}
+	}
+
+	// Remove the operations of services which are described by the document, but not
+	// forwarded by the gateway, and note that streaming operations can be consumed as
+	// server-sent events.
+	for path, item := range paths {
+		operations, _ := item.(map[string]any)
+		for method, operation := range operations {
+			route, ok := served[method+" "+path]
+			if !ok {
+				delete(operations, method)
+				continue
+			}
+
+			if route.ServerStreaming {
+				if operation, ok := operation.(map[string]any); ok {
+					operation["produces"] = []string{"application/json", "text/event-stream"}
+				}
+			}
+		}
+		if len(operations) == 0 {
+			delete(paths, path)
+		}
+	}
+
+	paths[CheckTracePath] = map[string]any{
+		"post": map[string]any{
+			"summary":     "Renders the resolution tree of a permission check.",
+			"description": "Runs the CheckPermissionRequest in the body with debugging enabled, and renders its resolution tree in the format given by the format query parameter.",
+			"operationId": "Gateway_CheckTrace",
+			"tags":        []string{"Gateway"},
+			"parameters": []any{
+				map[string]any{"name": "body", "in": "body", "required": true, "schema": map[string]any{"$ref": "#/definitions/v1CheckPermissionRequest"}},
+				map[string]any{"name": "format", "in": "query", "type": "string", "enum": []string{string(TraceFormatJSON), string(TraceFormatDOT), string(TraceFormatSVG)}},
+			},
+			"produces":  []string{"application/json", "text/vnd.graphviz", "image/svg+xml"},
+			"responses": map[string]any{"200": map[string]any{"description": "The rendered resolution tree."}},
+		},
+	}
+
+	paths[ResidualCheckPath] = map[string]any{
+		"post": map[string]any{
+			"summary":     "Checks a permission, returning the residual of the caveats of a conditional result.",
+			"description": "Runs the CheckPermissionRequest in the body. For conditional results, the residual field holds the caveat expressions which could not be evaluated for lack of context, for the caller to evaluate once the missing context is known.",
+			"operationId": "Gateway_CheckResidual",
+			"tags":        []string{"Gateway"},
+			"parameters": []any{
+				map[string]any{"name": "body", "in": "body", "required": true, "schema": map[string]any{"$ref": "#/definitions/v1CheckPermissionRequest"}},
+			},
+			"responses": map[string]any{"200": map[string]any{
+				"description": "The CheckPermissionResponse, with the residual of a conditional result.",
+				"schema": map[string]any{
+					"allOf": []any{
+						map[string]any{"$ref": "#/definitions/v1CheckPermissionResponse"},
+						map[string]any{"type": "object", "properties": map[string]any{"residual": map[string]any{"type": "object"}}},
+					},
+				},
+			}},
+		},
+	}
+
+	if options.OpenFGAEnabled {
+		for method, summary := range map[string]string{
+			"check":        "Checks whether a user has a relation to an object, using the OpenFGA API.",
+			"list-objects": "Lists the objects of a type to which a user has a relation, using the OpenFGA API.",
+			"write":        "Writes and deletes relationships, using the OpenFGA API.",
+		} {
+			paths[openfga.PathPrefix+"{store_id}/"+method] = map[string]any{
+				"post": map[string]any{
+					"summary":     summary,
+					"operationId": "OpenFGA_" + strings.ReplaceAll(method, "-", "_"),
+					"tags":        []string{"OpenFGA"},
+					"parameters": []any{
+						map[string]any{"name": "store_id", "in": "path", "required": true, "type": "string"},
+						map[string]any{"name": "body", "in": "body", "required": true, "schema": map[string]any{"type": "object"}},
+					},
+					"responses": map[string]any{"200": map[string]any{"description": "The OpenFGA response.", "schema": map[string]any{"type": "object"}}},
+				},
+			}
+		}
+	}
+
+	if options.GraphQLEnabled {
+		paths[graphql.Path] = map[string]any{
+			"post": map[string]any{
+				"summary":     "Runs a GraphQL query, or a batch of queries, checking permissions, looking up resources or reading relationships.",
+				"operationId": "GraphQL_Query",
+				"tags":        []string{"GraphQL"},
+				"parameters": []any{
+					map[string]any{"name": "body", "in": "body", "required": true, "schema": map[string]any{"type": "object"}},
+				},
+				"responses": map[string]any{"200": map[string]any{"description": "The GraphQL response.", "schema": map[string]any{"type": "object"}}},
+			},
+		}
+	}
+
+	if options.SCIM != nil {
+		for path, methods := range map[string][]string{
+			scim.PathPrefix + "Users":                 {"get", "post"},
+			scim.PathPrefix + "Users/{id}":            {"get", "put", "patch", "delete"},
+			scim.PathPrefix + "Groups":                {"get", "post"},
+			scim.PathPrefix + "Groups/{id}":           {"get", "put", "patch", "delete"},
+			scim.PathPrefix + "ServiceProviderConfig": {"get"},
+		} {
+			operations := make(map[string]any, len(methods))
+			for _, method := range methods {
+				operations[method] = map[string]any{
+					"summary":   "SCIM v2 provisioning of users and groups, as defined by RFC 7644.",
+					"tags":      []string{"SCIM"},
+					"produces":  []string{"application/scim+json"},
+					"responses": map[string]any{"200": map[string]any{"description": "The SCIM response.", "schema": map[string]any{"type": "object"}}},
+				}
+			}
+			paths[path] = operations
+		}
+	}
+
+	return json.Marshal(document)
+}
@@ -0,0 +1,183 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/authzed/spicedb/internal/gateway/scim"
+)
+
+type watchServer struct {
+	v1.UnimplementedWatchServiceServer
+}
+
+func (watchServer) Watch(_ *v1.WatchRequest, stream v1.WatchService_WatchServer) error {
+	for _, token := range []string{"first", "second"} {
+		if err := stream.Send(&v1.WatchResponse{ChangesThrough: &v1.ZedToken{Token: token}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTestGateway(t *testing.T, options Options) *httptest.Server {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	v1.RegisterSchemaServiceServer(srv, v1.UnimplementedSchemaServiceServer{})
+	v1.RegisterPermissionsServiceServer(srv, v1.UnimplementedPermissionsServiceServer{})
+	v1.RegisterWatchServiceServer(srv, watchServer{})
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	handler, err := NewHandler(ctx, lis.Addr().String(), "", options)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestEveryRPCIsRouted(t *testing.T) {
+	server := newTestGateway(t, Options{})
+
+	routes := Routes()
+	require.Len(t, routes, 10)
+	for _, route := range routes {
+		route := route
+		t.Run(route.FullMethod, func(t *testing.T) {
+			require.NotEmpty(t, route.Path, "RPC has no HTTP route")
+			if route.FullMethod == "/authzed.api.v1.WatchService/Watch" {
+				return
+			}
+
+			req, err := http.NewRequest(route.HTTPMethod, server.URL+route.Path, strings.NewReader("{}"))
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			// The upstream server implements none of the RPCs, so reaching it proves that
+			// the route is forwarded. Streaming RPCs write the error as a chunk of the stream.
+			type rpcStatus struct {
+				Code codes.Code `json:"code"`
+			}
+			var body struct {
+				rpcStatus
+				Error *rpcStatus `json:"error"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			if route.ServerStreaming {
+				require.NotNil(t, body.Error)
+				body.rpcStatus = *body.Error
+			}
+			require.Equal(t, codes.Unimplemented, body.Code)
+		})
+	}
+}
+
+func TestWatchStreaming(t *testing.T) {
+	server := newTestGateway(t, Options{})
+
+	for _, tc := range []struct {
+		name                string
+		accept              string
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			"newline-delimited JSON",
+			"",
+			"application/json",
+			`{"result":{"updates":[],"changesThrough":{"token":"first"}}}` + "\n" +
+				`{"result":{"updates":[],"changesThrough":{"token":"second"}}}` + "\n",
+		},
+		{
+			"server-sent events",
+			EventStreamContentType,
+			EventStreamContentType,
+			`data: {"result":{"updates":[],"changesThrough":{"token":"first"}}}` + "\n\n" +
+				`data: {"result":{"updates":[],"changesThrough":{"token":"second"}}}` + "\n\n",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/watch", strings.NewReader(`{"optional_object_types": ["document"]}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, tc.expectedContentType, resp.Header.Get("Content-Type"))
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedBody, string(body))
+		})
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	for _, options := range []Options{{}, {OpenFGAEnabled: true, GraphQLEnabled: true, SCIM: &scim.Config{}}} {
+		server := newTestGateway(t, options)
+
+		resp, err := http.Get(server.URL + OpenAPIPath)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		var document struct {
+			Swagger string                               `json:"swagger"`
+			Paths   map[string]map[string]map[string]any `json:"paths"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&document))
+		require.Equal(t, "2.0", document.Swagger)
+
+		for _, route := range Routes() {
+			require.Contains(t, document.Paths, route.Path)
+			require.Contains(t, document.Paths[route.Path], strings.ToLower(route.HTTPMethod))
+		}
+		require.Contains(t, document.Paths, CheckTracePath)
+		require.Contains(t, document.Paths, ResidualCheckPath)
+		expectedPaths := len(Routes()) + 2
+		if options.OpenFGAEnabled {
+			expectedPaths += 3
+		}
+		if options.GraphQLEnabled {
+			expectedPaths++
+		}
+		if options.SCIM != nil {
+			expectedPaths += 5
+		}
+		require.Len(t, document.Paths, expectedPaths)
+		if options.OpenFGAEnabled {
+			require.Contains(t, document.Paths, "/stores/{store_id}/check")
+		}
+
+		require.Equal(t, []any{"application/json", EventStreamContentType}, document.Paths["/v1/watch"]["post"]["produces"])
+		require.NotContains(t, document.Paths["/v1/permissions/check"]["post"], "produces")
+	}
+}
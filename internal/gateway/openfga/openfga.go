@@ -0,0 +1,400 @@
+// Package openfga implements the core of the OpenFGA HTTP API, Check, ListObjects and
+// Write, on top of the SpiceDB permissions service, so that applications using an OpenFGA
+// SDK can be pointed at SpiceDB without code changes.
+//
+// SpiceDB has a single schema, so the store and authorization model IDs of requests are
+// accepted but ignored, and OpenFGA relations are resolved as SpiceDB relations or
+// permissions of the same name. Conditions are mapped onto caveats of the same name.
+// Contextual tuples are not supported.
+package openfga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// PathPrefix is the prefix of the paths at which the OpenFGA API is served.
+const PathPrefix = "/stores/"
+
+const (
+	maxRequestBytes = 4 << 20
+
+	// maxListObjectsResults matches the default limit on the number of objects returned
+	// by ListObjects in OpenFGA.
+	maxListObjectsResults = 1000
+
+	higherConsistency = "HIGHER_CONSISTENCY"
+)
+
+// TupleKey is a relationship in OpenFGA notation.
+type TupleKey struct {
+	User      string                 `json:"user"`
+	Relation  string                 `json:"relation"`
+	Object    string                 `json:"object"`
+	Condition *RelationshipCondition `json:"condition,omitempty"`
+}
+
+// RelationshipCondition is the condition of a relationship, mapped onto a caveat.
+type RelationshipCondition struct {
+	Name    string         `json:"name"`
+	Context map[string]any `json:"context,omitempty"`
+}
+
+// TupleKeys is a list of relationships.
+type TupleKeys struct {
+	TupleKeys []TupleKey `json:"tuple_keys"`
+}
+
+// CheckRequest is the body of a Check request.
+type CheckRequest struct {
+	TupleKey             TupleKey       `json:"tuple_key"`
+	ContextualTuples     *TupleKeys     `json:"contextual_tuples,omitempty"`
+	AuthorizationModelID string         `json:"authorization_model_id,omitempty"`
+	Context              map[string]any `json:"context,omitempty"`
+	Consistency          string         `json:"consistency,omitempty"`
+}
+
+// CheckResponse is the body of a Check response.
+type CheckResponse struct {
+	Allowed    bool   `json:"allowed"`
+	Resolution string `json:"resolution"`
+}
+
+// ListObjectsRequest is the body of a ListObjects request.
+type ListObjectsRequest struct {
+	Type                 string         `json:"type"`
+	Relation             string         `json:"relation"`
+	User                 string         `json:"user"`
+	ContextualTuples     *TupleKeys     `json:"contextual_tuples,omitempty"`
+	AuthorizationModelID string         `json:"authorization_model_id,omitempty"`
+	Context              map[string]any `json:"context,omitempty"`
+	Consistency          string         `json:"consistency,omitempty"`
+}
+
+// ListObjectsResponse is the body of a ListObjects response.
+type ListObjectsResponse struct {
+	Objects []string `json:"objects"`
+}
+
+// WriteRequest is the body of a Write request.
+type WriteRequest struct {
+	Writes               *TupleKeys `json:"writes,omitempty"`
+	Deletes              *TupleKeys `json:"deletes,omitempty"`
+	AuthorizationModelID string     `json:"authorization_model_id,omitempty"`
+}
+
+// ErrorResponse is the body of a failed request.
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewHandler returns an HTTP handler serving the OpenFGA API under PathPrefix.
+//
+// The Authorization header of the incoming request is forwarded to the upstream, so
+// OpenFGA SDKs configured with an API token authenticate with the preshared key.
+func NewHandler(client v1.PermissionsServiceClient) http.Handler {
+	h := &handler{client}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		storeAndMethod := strings.TrimPrefix(r.URL.Path, PathPrefix)
+		storeID, method, ok := strings.Cut(storeAndMethod, "/")
+		if !ok || storeID == "" {
+			writeError(w, http.StatusNotFound, "undefined_endpoint", "unknown endpoint")
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "undefined_endpoint", "only POST is supported")
+			return
+		}
+
+		switch method {
+		case "check":
+			serve(w, r, h.check)
+		case "list-objects":
+			serve(w, r, h.listObjects)
+		case "write":
+			serve(w, r, h.write)
+		default:
+			writeError(w, http.StatusNotFound, "undefined_endpoint", fmt.Sprintf("unsupported endpoint `%s`", method))
+		}
+	})
+}
+
+type handler struct {
+	client v1.PermissionsServiceClient
+}
+
+// validationError is an error caused by an invalid request.
+type validationError struct {
+	error
+}
+
+func invalid(format string, args ...any) error {
+	return validationError{fmt.Errorf(format, args...)}
+}
+
+// serve decodes the request body, calls the method, and encodes its response or error.
+func serve[Req any, Resp any](w http.ResponseWriter, r *http.Request, method func(*http.Request, *Req) (*Resp, error)) {
+	req := new(Req)
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxRequestBytes))
+	if err := decoder.Decode(req); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", fmt.Sprintf("invalid request: %s", err))
+		return
+	}
+
+	resp, err := method(r, req)
+	if err != nil {
+		var vErr validationError
+		if errors.As(err, &vErr) {
+			writeError(w, http.StatusBadRequest, "validation_error", vErr.Error())
+			return
+		}
+
+		code := status.Code(err)
+		writeError(w, runtime.HTTPStatusFromCode(code), errorCode(code), status.Convert(err).Message())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func writeError(w http.ResponseWriter, httpStatus int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Code: code, Message: message})
+}
+
+// errorCode returns the OpenFGA error code closest to the gRPC code of an upstream error.
+func errorCode(code codes.Code) string {
+	switch code {
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		return "validation_error"
+	case codes.Unauthenticated:
+		return "unauthenticated"
+	case codes.PermissionDenied:
+		return "forbidden"
+	case codes.NotFound:
+		return "not_found"
+	case codes.DeadlineExceeded, codes.Canceled:
+		return "deadline_exceeded"
+	case codes.ResourceExhausted:
+		return "rate_limit_exceeded"
+	default:
+		return "internal_error"
+	}
+}
+
+func (h *handler) check(r *http.Request, req *CheckRequest) (*CheckResponse, error) {
+	if err := rejectContextualTuples(req.ContextualTuples); err != nil {
+		return nil, err
+	}
+
+	resource, err := parseObject(req.TupleKey.Object)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := parseUser(req.TupleKey.User)
+	if err != nil {
+		return nil, err
+	}
+	if req.TupleKey.Relation == "" {
+		return nil, invalid("missing relation")
+	}
+	caveatContext, err := toStruct(req.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.client.CheckPermission(forwardAuthorization(r), &v1.CheckPermissionRequest{
+		Consistency: consistency(req.Consistency),
+		Resource:    resource,
+		Permission:  req.TupleKey.Relation,
+		Subject:     subject,
+		Context:     caveatContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.Permissionship {
+	case v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION:
+		return &CheckResponse{Allowed: true}, nil
+	case v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION:
+		return nil, invalid("missing context parameters: %s", strings.Join(resp.PartialCaveatInfo.GetMissingRequiredContext(), ", "))
+	default:
+		return &CheckResponse{Allowed: false}, nil
+	}
+}
+
+func (h *handler) listObjects(r *http.Request, req *ListObjectsRequest) (*ListObjectsResponse, error) {
+	if err := rejectContextualTuples(req.ContextualTuples); err != nil {
+		return nil, err
+	}
+
+	subject, err := parseUser(req.User)
+	if err != nil {
+		return nil, err
+	}
+	if req.Type == "" || req.Relation == "" {
+		return nil, invalid("missing type or relation")
+	}
+	caveatContext, err := toStruct(req.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stop the lookup once the maximum number of objects has been found.
+	ctx, cancel := context.WithCancel(forwardAuthorization(r))
+	defer cancel()
+
+	stream, err := h.client.LookupResources(ctx, &v1.LookupResourcesRequest{
+		Consistency:        consistency(req.Consistency),
+		ResourceObjectType: req.Type,
+		Permission:         req.Relation,
+		Subject:            subject,
+		Context:            caveatContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]string, 0)
+	for len(objects) < maxListObjectsResults {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Objects only conditionally related to the user are omitted, as they are in OpenFGA.
+		if resp.Permissionship == v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION {
+			objects = append(objects, req.Type+":"+resp.ResourceObjectId)
+		}
+	}
+	return &ListObjectsResponse{Objects: objects}, nil
+}
+
+func (h *handler) write(r *http.Request, req *WriteRequest) (*struct{}, error) {
+	var updates []*v1.RelationshipUpdate
+	for _, write := range tupleKeys(req.Writes) {
+		rel, err := toRelationship(write)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, &v1.RelationshipUpdate{Operation: v1.RelationshipUpdate_OPERATION_CREATE, Relationship: rel})
+	}
+	for _, del := range tupleKeys(req.Deletes) {
+		rel, err := toRelationship(del)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, &v1.RelationshipUpdate{Operation: v1.RelationshipUpdate_OPERATION_DELETE, Relationship: rel})
+	}
+	if len(updates) == 0 {
+		return nil, invalid("no tuples to write or delete")
+	}
+
+	if _, err := h.client.WriteRelationships(forwardAuthorization(r), &v1.WriteRelationshipsRequest{Updates: updates}); err != nil {
+		return nil, err
+	}
+	return &struct{}{}, nil
+}
+
+func forwardAuthorization(r *http.Request) context.Context {
+	ctx := r.Context()
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", auth)
+	}
+	return ctx
+}
+
+func consistency(requested string) *v1.Consistency {
+	if requested == higherConsistency {
+		return &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}
+	}
+	return &v1.Consistency{Requirement: &v1.Consistency_MinimizeLatency{MinimizeLatency: true}}
+}
+
+func rejectContextualTuples(contextual *TupleKeys) error {
+	if len(tupleKeys(contextual)) > 0 {
+		return invalid("contextual tuples are not supported")
+	}
+	return nil
+}
+
+func tupleKeys(keys *TupleKeys) []TupleKey {
+	if keys == nil {
+		return nil
+	}
+	return keys.TupleKeys
+}
+
+// parseObject parses an object of the form `type:id`.
+func parseObject(object string) (*v1.ObjectReference, error) {
+	objectType, objectID, ok := strings.Cut(object, ":")
+	if !ok || objectType == "" || objectID == "" {
+		return nil, invalid("invalid object `%s`: expected type:id", object)
+	}
+	return &v1.ObjectReference{ObjectType: objectType, ObjectId: objectID}, nil
+}
+
+// parseUser parses a user of the form `type:id`, `type:*` or `type:id#relation`.
+func parseUser(user string) (*v1.SubjectReference, error) {
+	object, relation, _ := strings.Cut(user, "#")
+	ref, err := parseObject(object)
+	if err != nil {
+		return nil, invalid("invalid user `%s`: expected type:id or type:id#relation", user)
+	}
+	return &v1.SubjectReference{Object: ref, OptionalRelation: relation}, nil
+}
+
+func toRelationship(key TupleKey) (*v1.Relationship, error) {
+	resource, err := parseObject(key.Object)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := parseUser(key.User)
+	if err != nil {
+		return nil, err
+	}
+
+	rel := &v1.Relationship{Resource: resource, Relation: key.Relation, Subject: subject}
+	if key.Condition != nil {
+		caveatContext, err := toStruct(key.Condition.Context)
+		if err != nil {
+			return nil, err
+		}
+		rel.OptionalCaveat = &v1.ContextualizedCaveat{CaveatName: key.Condition.Name, Context: caveatContext}
+	}
+
+	if err := rel.Validate(); err != nil {
+		return nil, invalid("invalid tuple: %s", err)
+	}
+	return rel, nil
+}
+
+func toStruct(values map[string]any) (*structpb.Struct, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	converted, err := structpb.NewStruct(values)
+	if err != nil {
+		return nil, invalid("invalid context: %s", err)
+	}
+	return converted, nil
+}
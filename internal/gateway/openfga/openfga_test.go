@@ -0,0 +1,176 @@
+package openfga_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/gateway/openfga"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+)
+
+const testSchema = `definition user {}
+
+definition group {
+	relation member: user
+}
+
+caveat only_on(day int) {
+	day == 1
+}
+
+definition document {
+	relation writer: user
+	relation reader: user | user:* | group#member | user with only_on
+	permission can_read = reader + writer
+}`
+
+func TestOpenFGAHandler(t *testing.T) {
+	require := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	_, err := v1.NewSchemaServiceClient(conn).WriteSchema(context.Background(), &v1.WriteSchemaRequest{Schema: testSchema})
+	require.NoError(err)
+
+	server := httptest.NewServer(openfga.NewHandler(v1.NewPermissionsServiceClient(conn)))
+	t.Cleanup(server.Close)
+
+	steps := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"write",
+			"/stores/01GXSA8YR785C4FYS3C0RTG7B1/write",
+			`{"writes": {"tuple_keys": [
+				{"user": "user:anne", "relation": "writer", "object": "document:budget"},
+				{"user": "group:eng#member", "relation": "reader", "object": "document:roadmap"},
+				{"user": "user:bob", "relation": "member", "object": "group:eng"},
+				{"user": "user:*", "relation": "reader", "object": "document:handbook"},
+				{"user": "user:carl", "relation": "reader", "object": "document:notes", "condition": {"name": "only_on"}}
+			]}, "authorization_model_id": "01GXSA8YR785C4FYS3C0RTG7B1"}`,
+			http.StatusOK,
+			`{}`,
+		},
+		{
+			"check allowed",
+			"/stores/any/check",
+			`{"tuple_key": {"user": "user:anne", "relation": "can_read", "object": "document:budget"}}`,
+			http.StatusOK,
+			`{"allowed": true, "resolution": ""}`,
+		},
+		{
+			"check through userset",
+			"/stores/any/check",
+			`{"tuple_key": {"user": "user:bob", "relation": "reader", "object": "document:roadmap"}, "consistency": "HIGHER_CONSISTENCY"}`,
+			http.StatusOK,
+			`{"allowed": true, "resolution": ""}`,
+		},
+		{
+			"check denied",
+			"/stores/any/check",
+			`{"tuple_key": {"user": "user:bob", "relation": "can_read", "object": "document:budget"}}`,
+			http.StatusOK,
+			`{"allowed": false, "resolution": ""}`,
+		},
+		{
+			"check with condition context",
+			"/stores/any/check",
+			`{"tuple_key": {"user": "user:carl", "relation": "reader", "object": "document:notes"}, "context": {"day": 1}}`,
+			http.StatusOK,
+			`{"allowed": true, "resolution": ""}`,
+		},
+		{
+			"check missing condition context",
+			"/stores/any/check",
+			`{"tuple_key": {"user": "user:carl", "relation": "reader", "object": "document:notes"}}`,
+			http.StatusBadRequest,
+			`{"code": "validation_error", "message": "missing context parameters: day"}`,
+		},
+		{
+			"list objects",
+			"/stores/any/list-objects",
+			`{"type": "document", "relation": "can_read", "user": "user:bob"}`,
+			http.StatusOK,
+			`{"objects": ["document:handbook", "document:roadmap"]}`,
+		},
+		{
+			"delete",
+			"/stores/any/write",
+			`{"deletes": {"tuple_keys": [{"user": "user:bob", "relation": "member", "object": "group:eng"}]}}`,
+			http.StatusOK,
+			`{}`,
+		},
+		{
+			"list objects after delete",
+			"/stores/any/list-objects",
+			`{"type": "document", "relation": "can_read", "user": "user:bob", "consistency": "HIGHER_CONSISTENCY"}`,
+			http.StatusOK,
+			`{"objects": ["document:handbook"]}`,
+		},
+		{
+			"contextual tuples",
+			"/stores/any/check",
+			`{"tuple_key": {"user": "user:bob", "relation": "reader", "object": "document:budget"}, "contextual_tuples": {"tuple_keys": [{"user": "user:bob", "relation": "reader", "object": "document:budget"}]}}`,
+			http.StatusBadRequest,
+			`{"code": "validation_error", "message": "contextual tuples are not supported"}`,
+		},
+		{
+			"invalid user",
+			"/stores/any/check",
+			`{"tuple_key": {"user": "bob", "relation": "reader", "object": "document:budget"}}`,
+			http.StatusBadRequest,
+			`{"code": "validation_error", "message": "invalid user ` + "`bob`" + `: expected type:id or type:id#relation"}`,
+		},
+		{
+			"unknown relation",
+			"/stores/any/check",
+			`{"tuple_key": {"user": "user:bob", "relation": "owner", "object": "document:budget"}}`,
+			http.StatusBadRequest,
+			"",
+		},
+		{
+			"unsupported endpoint",
+			"/stores/any/expand",
+			`{}`,
+			http.StatusNotFound,
+			`{"code": "undefined_endpoint", "message": "unsupported endpoint ` + "`expand`" + `"}`,
+		},
+	}
+
+	for _, step := range steps {
+		resp, err := http.Post(server.URL+step.path, "application/json", strings.NewReader(step.body))
+		require.NoError(err, step.name)
+
+		var body map[string]any
+		require.NoError(json.NewDecoder(resp.Body).Decode(&body), step.name)
+		resp.Body.Close()
+
+		require.Equal(step.expectedStatus, resp.StatusCode, "%s: %v", step.name, body)
+
+		// Objects are listed in no particular order.
+		if objects, ok := body["objects"].([]any); ok {
+			sort.Slice(objects, func(i, j int) bool { return objects[i].(string) < objects[j].(string) })
+		}
+		if step.expectedBody != "" {
+			encoded, err := json.Marshal(body)
+			require.NoError(err)
+			require.JSONEq(step.expectedBody, string(encoded), step.name)
+		} else {
+			require.Equal("validation_error", body["code"], step.name)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"github.com/authzed/authzed-go/pkg/responsemeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/authzed/spicedb/pkg/caveats"
+)
+
+// ResidualCheckPath is the path at which permission checks returning the residual of
+// their caveats are served.
+const ResidualCheckPath = "/v1/permissions/check/residual"
+
+// NewResidualCheckHandler returns an HTTP handler which runs the CheckPermissionRequest
+// found in the body of a POST, and responds with the JSON form of the CheckPermissionResponse
+// with an additional `residual` field. For conditional results, the field holds the
+// caveats.Residual which the caller can evaluate once the missing context is known.
+//
+// The Authorization header of the incoming request is forwarded to the upstream, so the
+// endpoint is subject to the same authentication as the API itself.
+func NewResidualCheckHandler(client v1.PermissionsServiceClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxCheckTraceRequestBytes))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("could not read request: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		req := &v1.CheckPermissionRequest{}
+		if err := protojson.Unmarshal(body, req); err != nil {
+			http.Error(w, fmt.Sprintf("invalid CheckPermissionRequest: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		ctx := requestmeta.AddRequestHeaders(r.Context(), caveats.RequestResidual)
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", auth)
+		}
+
+		var trailer metadata.MD
+		resp, err := client.CheckPermission(ctx, req, grpc.Trailer(&trailer))
+		if err != nil {
+			http.Error(w, err.Error(), runtime.HTTPStatusFromCode(status.Code(err)))
+			return
+		}
+
+		marshaled, err := protojson.Marshal(resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		fields := make(map[string]json.RawMessage)
+		if err := json.Unmarshal(marshaled, &fields); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fields["residual"] = json.RawMessage("null")
+		if residual, err := responsemeta.GetResponseTrailerMetadata(trailer, caveats.ResidualTrailer); err == nil {
+			fields["residual"] = json.RawMessage(residual)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(fields)
+	})
+}
@@ -0,0 +1,696 @@
+// Package scim implements the SCIM v2 provisioning protocol (RFC 7643 and RFC 7644) for
+// users and groups on top of the SpiceDB permissions service, so that identity providers
+// can synchronize group memberships into relationships directly.
+//
+// SpiceDB stores relationships, not directories: the membership of a user in a group is the
+// relationship `group:<group id>#member@user:<user id>`, with the object types and relation
+// configured in Config, and nothing else is stored. Every user and group therefore exists,
+// with no members until some are provisioned; the attributes of users are not kept, and
+// deactivating or deleting a user removes all of its memberships.
+package scim
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// PathPrefix is the prefix of the paths at which the SCIM API is served.
+const PathPrefix = "/scim/v2/"
+
+const (
+	maxRequestBytes = 4 << 20
+
+	// maxUpdatesPerWrite keeps the membership changes of a single SCIM request within the
+	// default limit of updates of a single WriteRelationships call.
+	maxUpdatesPerWrite = 1000
+
+	// encodedIDPrefix marks the object IDs encoding names that are not valid object IDs.
+	encodedIDPrefix = "b64|"
+
+	contentType = "application/scim+json"
+
+	// relationshipSourceHeader is the request header of the source recorded for the
+	// relationships written by a request.
+	relationshipSourceHeader = "io.spicedb.relationshipsource"
+
+	userSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	groupSchema        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	listResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	errorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	configSchema       = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// Config maps the SCIM resources onto the object types and relation of the schema.
+type Config struct {
+	// UserType is the object type of users.
+	UserType string
+
+	// GroupType is the object type of groups.
+	GroupType string
+
+	// MemberRelation is the relation of a group to its member users.
+	MemberRelation string
+
+	// Source, if not empty, is recorded as the source of the memberships written, so that
+	// they can be deleted together with the experimental DeleteRelationshipsFromSource API.
+	Source string
+}
+
+// User is a SCIM user. Only the attributes used to identify it are read.
+type User struct {
+	Schemas    []string `json:"schemas"`
+	ID         string   `json:"id,omitempty"`
+	ExternalID string   `json:"externalId,omitempty"`
+	UserName   string   `json:"userName"`
+	Active     *bool    `json:"active,omitempty"`
+	Meta       *Meta    `json:"meta,omitempty"`
+}
+
+// Group is a SCIM group.
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// Member is a member of a SCIM group, identified by the id of the user in Value.
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// Meta is the metadata of a SCIM resource.
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+}
+
+// ListResponse is the response to a query of resources.
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+// PatchRequest is a request to modify a resource.
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is an operation of a PatchRequest.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ErrorResponse is the body of a failed request.
+type ErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// scimError is an error with the HTTP status and SCIM error type of its response.
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e scimError) Error() string {
+	return e.detail
+}
+
+func invalidValue(format string, args ...any) error {
+	return scimError{http.StatusBadRequest, "invalidValue", fmt.Sprintf(format, args...)}
+}
+
+// NewHandler returns an HTTP handler serving the SCIM API under PathPrefix.
+//
+// The Authorization header of the incoming request is forwarded to the upstream, so
+// identity providers authenticate with the preshared key as a bearer token.
+func NewHandler(client v1.PermissionsServiceClient, config Config) http.Handler {
+	h := &handler{client, config}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resourceType, id, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, PathPrefix), "/")
+
+		var (
+			resp       any
+			httpStatus = http.StatusOK
+			err        error
+		)
+		switch {
+		case resourceType == "ServiceProviderConfig" && id == "" && r.Method == http.MethodGet:
+			resp = serviceProviderConfig()
+		case resourceType == "Users" && id == "" && r.Method == http.MethodGet:
+			resp, err = h.listUsers(r)
+		case resourceType == "Users" && id == "" && r.Method == http.MethodPost:
+			httpStatus = http.StatusCreated
+			resp, err = h.createUser(r)
+		case resourceType == "Users" && id != "" && r.Method == http.MethodGet:
+			resp = h.user(id, nil)
+		case resourceType == "Users" && id != "" && (r.Method == http.MethodPut || r.Method == http.MethodPatch):
+			resp, err = h.updateUser(r, id)
+		case resourceType == "Users" && id != "" && r.Method == http.MethodDelete:
+			httpStatus = http.StatusNoContent
+			err = h.removeMemberships(r, id)
+		case resourceType == "Groups" && id == "" && r.Method == http.MethodGet:
+			resp, err = h.listGroups(r)
+		case resourceType == "Groups" && id == "" && r.Method == http.MethodPost:
+			httpStatus = http.StatusCreated
+			resp, err = h.createGroup(r)
+		case resourceType == "Groups" && id != "" && r.Method == http.MethodGet:
+			resp, err = h.group(r, id)
+		case resourceType == "Groups" && id != "" && r.Method == http.MethodPut:
+			resp, err = h.replaceGroup(r, id)
+		case resourceType == "Groups" && id != "" && r.Method == http.MethodPatch:
+			resp, err = h.patchGroup(r, id)
+		case resourceType == "Groups" && id != "" && r.Method == http.MethodDelete:
+			httpStatus = http.StatusNoContent
+			err = h.deleteGroup(r, id)
+		default:
+			err = scimError{http.StatusNotFound, "", fmt.Sprintf("unsupported endpoint `%s %s`", r.Method, r.URL.Path)}
+		}
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(httpStatus)
+		if resp != nil {
+			_ = json.NewEncoder(w).Encode(resp)
+		}
+	})
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var sErr scimError
+	if !errors.As(err, &sErr) {
+		sErr = scimError{runtime.HTTPStatusFromCode(status.Code(err)), "", status.Convert(err).Message()}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(sErr.status)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{
+		Schemas:  []string{errorSchema},
+		Status:   fmt.Sprint(sErr.status),
+		SCIMType: sErr.scimType,
+		Detail:   sErr.detail,
+	})
+}
+
+func serviceProviderConfig() any {
+	supported := func(supported bool) map[string]bool {
+		return map[string]bool{"supported": supported}
+	}
+	return map[string]any{
+		"schemas":        []string{configSchema},
+		"patch":          supported(true),
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": 1},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "Authentication with a preshared key of SpiceDB.",
+		}},
+	}
+}
+
+// ObjectID returns the object ID of the user or group with the name: the name itself if
+// it is a valid object ID, or else its encoding as one.
+func ObjectID(name string) (string, error) {
+	if name == "" {
+		return "", invalidValue("missing name")
+	}
+	if tuple.ValidateResourceID(name) == nil && !strings.HasPrefix(name, encodedIDPrefix) {
+		return name, nil
+	}
+
+	id := encodedIDPrefix + base64.RawURLEncoding.EncodeToString([]byte(name))
+	if err := tuple.ValidateResourceID(id); err != nil {
+		return "", invalidValue("name `%s` is too long", name)
+	}
+	return id, nil
+}
+
+// nameOf returns the name of which id is the object ID.
+func nameOf(id string) string {
+	if !strings.HasPrefix(id, encodedIDPrefix) {
+		return id
+	}
+	name, err := base64.RawURLEncoding.DecodeString(id[len(encodedIDPrefix):])
+	if err != nil {
+		return id
+	}
+	return string(name)
+}
+
+type handler struct {
+	client v1.PermissionsServiceClient
+	config Config
+}
+
+func (h *handler) user(id string, active *bool) *User {
+	return &User{
+		Schemas:  []string{userSchema},
+		ID:       id,
+		UserName: nameOf(id),
+		Active:   active,
+		Meta:     &Meta{ResourceType: "User"},
+	}
+}
+
+func (h *handler) listUsers(r *http.Request) (*ListResponse, error) {
+	name, err := filterValue(r, "userName")
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return listResponse(), nil
+	}
+
+	id, err := ObjectID(name)
+	if err != nil {
+		return nil, err
+	}
+	return listResponse(h.user(id, nil)), nil
+}
+
+func (h *handler) createUser(r *http.Request) (*User, error) {
+	var user User
+	if err := decode(r, &user); err != nil {
+		return nil, err
+	}
+	id, err := ObjectID(user.UserName)
+	if err != nil {
+		return nil, err
+	}
+	return h.user(id, user.Active), nil
+}
+
+// updateUser only acts on the deactivation of the user, by removing its memberships.
+func (h *handler) updateUser(r *http.Request, id string) (*User, error) {
+	active, err := requestedActive(r)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil && !*active {
+		if err := h.removeMemberships(r, id); err != nil {
+			return nil, err
+		}
+	}
+	return h.user(id, active), nil
+}
+
+// requestedActive returns the active attribute set by a PUT or PATCH of a user, if any.
+func requestedActive(r *http.Request) (*bool, error) {
+	if r.Method == http.MethodPut {
+		var user User
+		if err := decode(r, &user); err != nil {
+			return nil, err
+		}
+		return user.Active, nil
+	}
+
+	var patch PatchRequest
+	if err := decode(r, &patch); err != nil {
+		return nil, err
+	}
+
+	var active *bool
+	for _, op := range patch.Operations {
+		if !strings.EqualFold(op.Op, "replace") && !strings.EqualFold(op.Op, "add") {
+			continue
+		}
+
+		// Providers either set the attribute by its path, or set several attributes
+		// through an object without a path.
+		switch {
+		case strings.EqualFold(op.Path, "active"):
+			var value bool
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return nil, invalidValue("invalid active value: %s", err)
+			}
+			active = &value
+		case op.Path == "":
+			var values struct {
+				Active *bool `json:"active"`
+			}
+			if err := json.Unmarshal(op.Value, &values); err == nil && values.Active != nil {
+				active = values.Active
+			}
+		}
+	}
+	return active, nil
+}
+
+func (h *handler) removeMemberships(r *http.Request, userID string) error {
+	_, err := h.client.DeleteRelationships(h.forwardAuthorization(r), &v1.DeleteRelationshipsRequest{
+		RelationshipFilter: &v1.RelationshipFilter{
+			ResourceType:     h.config.GroupType,
+			OptionalRelation: h.config.MemberRelation,
+			OptionalSubjectFilter: &v1.SubjectFilter{
+				SubjectType:       h.config.UserType,
+				OptionalSubjectId: userID,
+			},
+		},
+	})
+	return err
+}
+
+func (h *handler) listGroups(r *http.Request) (*ListResponse, error) {
+	name, err := filterValue(r, "displayName")
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return listResponse(), nil
+	}
+
+	id, err := ObjectID(name)
+	if err != nil {
+		return nil, err
+	}
+	group, err := h.group(r, id)
+	if err != nil {
+		return nil, err
+	}
+	return listResponse(group), nil
+}
+
+func (h *handler) group(r *http.Request, id string) (*Group, error) {
+	members, err := h.members(h.forwardAuthorization(r), id)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &Group{
+		Schemas:     []string{groupSchema},
+		ID:          id,
+		DisplayName: nameOf(id),
+		Members:     make([]Member, 0, len(members)),
+		Meta:        &Meta{ResourceType: "Group"},
+	}
+	for _, member := range members {
+		group.Members = append(group.Members, Member{Value: member})
+	}
+	return group, nil
+}
+
+// members returns the IDs of the members of the group.
+func (h *handler) members(ctx context.Context, groupID string) ([]string, error) {
+	stream, err := h.client.ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+		Consistency: &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+		RelationshipFilter: &v1.RelationshipFilter{
+			ResourceType:          h.config.GroupType,
+			OptionalResourceId:    groupID,
+			OptionalRelation:      h.config.MemberRelation,
+			OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: h.config.UserType},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var members []string
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return members, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, resp.Relationship.Subject.Object.ObjectId)
+	}
+}
+
+func (h *handler) createGroup(r *http.Request) (*Group, error) {
+	var group Group
+	if err := decode(r, &group); err != nil {
+		return nil, err
+	}
+	id, err := ObjectID(group.DisplayName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.updateMembers(r, id, memberIDs(group.Members), v1.RelationshipUpdate_OPERATION_TOUCH); err != nil {
+		return nil, err
+	}
+	return h.group(r, id)
+}
+
+func (h *handler) replaceGroup(r *http.Request, id string) (*Group, error) {
+	var group Group
+	if err := decode(r, &group); err != nil {
+		return nil, err
+	}
+	if err := h.replaceMembers(r, id, memberIDs(group.Members)); err != nil {
+		return nil, err
+	}
+	return h.group(r, id)
+}
+
+func (h *handler) patchGroup(r *http.Request, id string) (*Group, error) {
+	var patch PatchRequest
+	if err := decode(r, &patch); err != nil {
+		return nil, err
+	}
+
+	for _, op := range patch.Operations {
+		path := strings.TrimSpace(op.Path)
+		switch {
+		case strings.EqualFold(op.Op, "add") && strings.EqualFold(path, "members"):
+			members, err := patchMembers(op.Value)
+			if err != nil {
+				return nil, err
+			}
+			if err := h.updateMembers(r, id, members, v1.RelationshipUpdate_OPERATION_TOUCH); err != nil {
+				return nil, err
+			}
+
+		case strings.EqualFold(op.Op, "replace") && strings.EqualFold(path, "members"):
+			members, err := patchMembers(op.Value)
+			if err != nil {
+				return nil, err
+			}
+			if err := h.replaceMembers(r, id, members); err != nil {
+				return nil, err
+			}
+
+		case strings.EqualFold(op.Op, "remove") && strings.EqualFold(path, "members"):
+			// Without a value, every member is removed.
+			members, err := patchMembers(op.Value)
+			if err != nil {
+				return nil, err
+			}
+			if len(op.Value) == 0 {
+				err = h.replaceMembers(r, id, nil)
+			} else {
+				err = h.updateMembers(r, id, members, v1.RelationshipUpdate_OPERATION_DELETE)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+		case strings.EqualFold(op.Op, "remove") && strings.HasPrefix(strings.ToLower(path), "members["):
+			member, err := memberOfPath(path)
+			if err != nil {
+				return nil, err
+			}
+			if err := h.updateMembers(r, id, []string{member}, v1.RelationshipUpdate_OPERATION_DELETE); err != nil {
+				return nil, err
+			}
+
+		default:
+			// The other attributes of groups, such as their display name, are not stored.
+		}
+	}
+	return h.group(r, id)
+}
+
+func (h *handler) deleteGroup(r *http.Request, id string) error {
+	_, err := h.client.DeleteRelationships(h.forwardAuthorization(r), &v1.DeleteRelationshipsRequest{
+		RelationshipFilter: &v1.RelationshipFilter{
+			ResourceType:       h.config.GroupType,
+			OptionalResourceId: id,
+			OptionalRelation:   h.config.MemberRelation,
+		},
+	})
+	return err
+}
+
+// replaceMembers makes the members of the group exactly the given users.
+func (h *handler) replaceMembers(r *http.Request, groupID string, members []string) error {
+	current, err := h.members(h.forwardAuthorization(r), groupID)
+	if err != nil {
+		return err
+	}
+
+	requested := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		requested[member] = struct{}{}
+	}
+
+	var removed []string
+	for _, member := range current {
+		if _, ok := requested[member]; !ok {
+			removed = append(removed, member)
+		}
+	}
+
+	if err := h.updateMembers(r, groupID, removed, v1.RelationshipUpdate_OPERATION_DELETE); err != nil {
+		return err
+	}
+	return h.updateMembers(r, groupID, members, v1.RelationshipUpdate_OPERATION_TOUCH)
+}
+
+// updateMembers touches or deletes the memberships of the users in the group, in as many
+// writes as the number of memberships requires.
+func (h *handler) updateMembers(r *http.Request, groupID string, members []string, operation v1.RelationshipUpdate_Operation) error {
+	updates := make([]*v1.RelationshipUpdate, 0, len(members))
+	for _, member := range members {
+		rel := &v1.Relationship{
+			Resource: &v1.ObjectReference{ObjectType: h.config.GroupType, ObjectId: groupID},
+			Relation: h.config.MemberRelation,
+			Subject:  &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: h.config.UserType, ObjectId: member}},
+		}
+		if err := rel.Validate(); err != nil {
+			return invalidValue("invalid member `%s` of group `%s`: %s", member, groupID, err)
+		}
+		updates = append(updates, &v1.RelationshipUpdate{Operation: operation, Relationship: rel})
+	}
+
+	for len(updates) > 0 {
+		batch := updates
+		if len(batch) > maxUpdatesPerWrite {
+			batch = batch[:maxUpdatesPerWrite]
+		}
+		if _, err := h.client.WriteRelationships(h.forwardAuthorization(r), &v1.WriteRelationshipsRequest{Updates: batch}); err != nil {
+			return err
+		}
+		updates = updates[len(batch):]
+	}
+	return nil
+}
+
+func memberIDs(members []Member) []string {
+	ids := make([]string, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.Value)
+	}
+	return ids
+}
+
+func patchMembers(value json.RawMessage) ([]string, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	var members []Member
+	if err := json.Unmarshal(value, &members); err != nil {
+		return nil, invalidValue("invalid members: %s", err)
+	}
+	return memberIDs(members), nil
+}
+
+// memberOfPath returns the member selected by a path of the form `members[value eq "id"]`.
+func memberOfPath(path string) (string, error) {
+	filter := strings.TrimSuffix(path[len("members["):], "]")
+	attribute, value, err := parseFilter(filter)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(attribute, "value") {
+		return "", scimError{http.StatusBadRequest, "invalidPath", fmt.Sprintf("unsupported path `%s`", path)}
+	}
+	return value, nil
+}
+
+// filterValue returns the value compared to the attribute by the filter of the request, or
+// an empty string if there is no filter, or it filters on another attribute.
+func filterValue(r *http.Request, attribute string) (string, error) {
+	filter := r.URL.Query().Get("filter")
+	if filter == "" {
+		return "", nil
+	}
+
+	filtered, value, err := parseFilter(filter)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(filtered, attribute) {
+		return "", nil
+	}
+	return value, nil
+}
+
+// parseFilter parses the only filters supported, of the form `attribute eq "value"`.
+func parseFilter(filter string) (string, string, error) {
+	fields := strings.SplitN(strings.TrimSpace(filter), " ", 3)
+	if len(fields) != 3 || !strings.EqualFold(fields[1], "eq") {
+		return "", "", scimError{http.StatusBadRequest, "invalidFilter", fmt.Sprintf("unsupported filter `%s`: only `attribute eq \"value\"` is supported", filter)}
+	}
+
+	var value string
+	if err := json.Unmarshal([]byte(fields[2]), &value); err != nil {
+		return "", "", scimError{http.StatusBadRequest, "invalidFilter", fmt.Sprintf("invalid value in filter `%s`", filter)}
+	}
+	return fields[0], value, nil
+}
+
+func listResponse(resources ...any) *ListResponse {
+	if resources == nil {
+		resources = []any{}
+	}
+	return &ListResponse{
+		Schemas:      []string{listResponseSchema},
+		TotalResults: len(resources),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func decode(r *http.Request, v any) error {
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBytes)).Decode(v); err != nil {
+		return scimError{http.StatusBadRequest, "invalidSyntax", fmt.Sprintf("invalid request: %s", err)}
+	}
+	return nil
+}
+
+func (h *handler) forwardAuthorization(r *http.Request) context.Context {
+	ctx := r.Context()
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", auth)
+	}
+	if h.config.Source != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, relationshipSourceHeader, h.config.Source)
+	}
+	return ctx
+}
@@ -0,0 +1,116 @@
+package scim_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/gateway/scim"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+)
+
+const testSchema = `definition user {}
+
+definition team {
+	relation member: user
+}`
+
+func TestSCIMHandler(t *testing.T) {
+	require := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	_, err := v1.NewSchemaServiceClient(conn).WriteSchema(context.Background(), &v1.WriteSchemaRequest{Schema: testSchema})
+	require.NoError(err)
+
+	server := httptest.NewServer(scim.NewHandler(v1.NewPermissionsServiceClient(conn), scim.Config{
+		UserType:       "user",
+		GroupType:      "team",
+		MemberRelation: "member",
+	}))
+	t.Cleanup(server.Close)
+
+	call := func(method, path, body string, expectedStatus int) map[string]any {
+		req, err := http.NewRequest(method, server.URL+scim.PathPrefix+path, strings.NewReader(body))
+		require.NoError(err)
+		req.Header.Set("Content-Type", "application/scim+json")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(err)
+		defer resp.Body.Close()
+		require.Equal(expectedStatus, resp.StatusCode, "%s %s", method, path)
+
+		var decoded map[string]any
+		if resp.StatusCode != http.StatusNoContent {
+			require.NoError(json.NewDecoder(resp.Body).Decode(&decoded))
+		}
+		return decoded
+	}
+
+	members := func(group map[string]any) []string {
+		var ids []string
+		for _, member := range group["members"].([]any) {
+			ids = append(ids, member.(map[string]any)["value"].(string))
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	// Users named with invalid object IDs are identified by an encoding of their name.
+	anne := call(http.MethodPost, "Users", `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "anne@example.com", "active": true}`, http.StatusCreated)
+	anneID := anne["id"].(string)
+	require.Equal("b64|YW5uZUBleGFtcGxlLmNvbQ", anneID)
+	require.Equal("anne@example.com", anne["userName"])
+	require.Equal("bob", call(http.MethodPost, "Users", `{"userName": "bob"}`, http.StatusCreated)["id"])
+
+	users := call(http.MethodGet, "Users?filter="+url.QueryEscape(`userName eq "anne@example.com"`), "", http.StatusOK)
+	require.Equal(1.0, users["totalResults"])
+	require.Equal(anneID, users["Resources"].([]any)[0].(map[string]any)["id"])
+
+	group := call(http.MethodPost, "Groups", `{"displayName": "Engineering Team", "members": [{"value": "`+anneID+`"}, {"value": "bob"}]}`, http.StatusCreated)
+	groupID := group["id"].(string)
+	require.Equal("Engineering Team", group["displayName"])
+	require.Equal([]string{anneID, "bob"}, members(group))
+
+	groups := call(http.MethodGet, "Groups?filter="+url.QueryEscape(`displayName eq "Engineering Team"`), "", http.StatusOK)
+	require.Equal([]string{anneID, "bob"}, members(groups["Resources"].([]any)[0].(map[string]any)))
+
+	group = call(http.MethodPatch, "Groups/"+groupID, `{"Operations": [{"op": "remove", "path": "members[value eq \"bob\"]"}]}`, http.StatusOK)
+	require.Equal([]string{anneID}, members(group))
+
+	group = call(http.MethodPatch, "Groups/"+groupID, `{"Operations": [{"op": "Add", "path": "members", "value": [{"value": "bob"}, {"value": "carl"}]}]}`, http.StatusOK)
+	require.Equal([]string{anneID, "bob", "carl"}, members(group))
+
+	group = call(http.MethodPut, "Groups/"+groupID, `{"displayName": "Engineering Team", "members": [{"value": "bob"}, {"value": "carl"}]}`, http.StatusOK)
+	require.Equal([]string{"bob", "carl"}, members(group))
+
+	// Deactivating a user removes its memberships.
+	call(http.MethodPatch, "Users/bob", `{"Operations": [{"op": "replace", "value": {"active": false}}]}`, http.StatusOK)
+	require.Equal([]string{"carl"}, members(call(http.MethodGet, "Groups/"+groupID, "", http.StatusOK)))
+
+	call(http.MethodDelete, "Users/carl", "", http.StatusNoContent)
+	require.Empty(call(http.MethodGet, "Groups/"+groupID, "", http.StatusOK)["members"])
+
+	call(http.MethodPatch, "Groups/"+groupID, `{"Operations": [{"op": "add", "path": "members", "value": [{"value": "bob"}]}]}`, http.StatusOK)
+	call(http.MethodDelete, "Groups/"+groupID, "", http.StatusNoContent)
+	require.Empty(call(http.MethodGet, "Groups/"+groupID, "", http.StatusOK)["members"])
+
+	invalid := call(http.MethodGet, "Users?filter="+url.QueryEscape(`userName sw "a"`), "", http.StatusBadRequest)
+	require.Equal("invalidFilter", invalid["scimType"])
+
+	invalid = call(http.MethodPost, "Groups", `{"displayName": "admins", "members": [{"value": "not valid"}]}`, http.StatusBadRequest)
+	require.Equal("invalidValue", invalid["scimType"])
+
+	call(http.MethodGet, "Schemas", "", http.StatusNotFound)
+	require.Equal(true, call(http.MethodGet, "ServiceProviderConfig", "", http.StatusOK)["patch"].(map[string]any)["supported"])
+}
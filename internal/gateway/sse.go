@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"bytes"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// EventStreamContentType is the content type with which clients can request the responses
+// of streaming RPCs, such as Watch, as server-sent events.
+const EventStreamContentType = "text/event-stream"
+
+// eventStreamMarshaler writes every message as the data of a server-sent event, in the
+// JSON form written by the gateway by default. Requests are read as JSON.
+type eventStreamMarshaler struct {
+	runtime.Marshaler
+}
+
+func newEventStreamMarshaler() runtime.Marshaler {
+	return eventStreamMarshaler{&runtime.HTTPBodyMarshaler{
+		Marshaler: &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		},
+	}}
+}
+
+func (m eventStreamMarshaler) ContentType(_ interface{}) string {
+	return EventStreamContentType
+}
+
+func (m eventStreamMarshaler) Marshal(v interface{}) ([]byte, error) {
+	data, err := m.Marshaler.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every line of the data is a data field of the event, which ends with a blank line.
+	var event bytes.Buffer
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		event.WriteString("data: ")
+		event.Write(line)
+		event.WriteByte('\n')
+	}
+	event.WriteByte('\n')
+	return event.Bytes(), nil
+}
+
+// Delimiter returns no delimiter, since every event is terminated by Marshal.
+func (m eventStreamMarshaler) Delimiter() []byte {
+	return nil
+}
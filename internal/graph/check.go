@@ -19,9 +19,11 @@ import (
 	"github.com/authzed/spicedb/pkg/tuple"
 )
 
-// NewConcurrentChecker creates an instance of ConcurrentChecker.
-func NewConcurrentChecker(d dispatch.Check, concurrencyLimit uint16) *ConcurrentChecker {
-	return &ConcurrentChecker{d, concurrencyLimit}
+// NewConcurrentChecker creates an instance of ConcurrentChecker. Resources with more direct
+// relationships on a relation than the superNodeThreshold are checked starting from the
+// subject, unless the threshold is zero.
+func NewConcurrentChecker(d dispatch.Check, concurrencyLimit uint16, superNodeThreshold uint64) *ConcurrentChecker {
+	return &ConcurrentChecker{d, concurrencyLimit, newSuperNodes(superNodeThreshold)}
 }
 
 // ConcurrentChecker exposes a method to perform Check requests, and delegates subproblems to the
@@ -29,6 +31,7 @@ func NewConcurrentChecker(d dispatch.Check, concurrencyLimit uint16) *Concurrent
 type ConcurrentChecker struct {
 	d                dispatch.Check
 	concurrencyLimit uint16
+	superNodes       *superNodes
 }
 
 // ValidatedCheckRequest represents a request after it has been validated and parsed for internal
@@ -150,7 +153,7 @@ func (cc *ConcurrentChecker) checkInternal(ctx context.Context, req ValidatedChe
 	}
 
 	if relation.UsersetRewrite == nil {
-		return combineResultWithFoundResources(cc.checkDirect(ctx, crc), membershipSet)
+		return combineResultWithFoundResources(cc.checkDirect(ctx, crc, relation), membershipSet)
 	}
 
 	return combineResultWithFoundResources(cc.checkUsersetRewrite(ctx, crc, relation.UsersetRewrite), membershipSet)
@@ -170,7 +173,11 @@ type directDispatch struct {
 	resourceIds  []string
 }
 
-func (cc *ConcurrentChecker) checkDirect(ctx context.Context, crc currentRequestContext) CheckResult {
+func (cc *ConcurrentChecker) checkDirect(ctx context.Context, crc currentRequestContext, relation *core.Relation) CheckResult {
+	if cc.superNodes.anyKnown(crc.parentReq.ResourceRelation, crc.filteredResourceIDs) {
+		return cc.checkDirectFromSubject(ctx, crc, relation)
+	}
+
 	log.Ctx(ctx).Trace().Object("direct", crc.parentReq).Send()
 	ds := datastoremw.MustFromContext(ctx).SnapshotReader(crc.parentReq.Revision)
 
@@ -186,35 +193,37 @@ func (cc *ConcurrentChecker) checkDirect(ctx context.Context, crc currentRequest
 	defer it.Close()
 
 	// Find the subjects over which to dispatch.
-	foundResources := NewMembershipSet()
-	subjectsToDispatch := tuple.NewONRByTypeSet()
-	relationshipsBySubjectONR := util.NewMultiMap[string, *core.RelationTuple]()
+	direct := newDirectRelationships(crc)
+	countsByResourceID := make(map[string]uint64)
 
 	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
 		if it.Err() != nil {
 			return checkResultError(NewCheckFailureErr(it.Err()), emptyMetadata)
 		}
 
-		// If the subject of the relationship matches the target subject, then we've found
-		// a result.
-		if onrEqualOrWildcard(tpl.Subject, crc.parentReq.Subject) {
-			foundResources.AddDirectMember(tpl.ResourceAndRelation.ObjectId, tpl.Caveat)
-			if crc.resultsSetting == v1.DispatchCheckRequest_ALLOW_SINGLE_RESULT && foundResources.HasDeterminedMember() {
-				return checkResultsForMembership(foundResources, emptyMetadata)
-			}
-			continue
+		// Once a resource is found to be a super-node, stop reading its relationships and
+		// instead start from the subject.
+		if cc.superNodes.observe(ctx, countsByResourceID, crc.parentReq.ResourceRelation, tpl.ResourceAndRelation.ObjectId) {
+			it.Close()
+			return cc.checkDirectFromSubject(ctx, crc, relation)
 		}
 
-		// If the subject of the relationship is a non-terminal, add to be dispatched.
-		if tpl.Subject.Relation != Ellipsis {
-			subjectsToDispatch.Add(tpl.Subject)
-			relationshipsBySubjectONR.Add(tuple.StringONR(tpl.Subject), tpl)
+		if direct.add(tpl) {
+			return checkResultsForMembership(direct.foundResources, emptyMetadata)
 		}
 	}
 
+	return cc.dispatchDirect(ctx, direct)
+}
+
+// dispatchDirect dispatches over the sets of subjects of the direct relationships found,
+// and combines the resources found through them with those found directly.
+func (cc *ConcurrentChecker) dispatchDirect(ctx context.Context, direct *directRelationships) CheckResult {
+	crc := direct.crc
+
 	// Convert the subjects into batched requests.
-	toDispatch := make([]directDispatch, 0, subjectsToDispatch.Len())
-	subjectsToDispatch.ForEachType(func(rr *core.RelationReference, resourceIds []string) {
+	toDispatch := make([]directDispatch, 0, direct.subjectsToDispatch.Len())
+	direct.subjectsToDispatch.ForEachType(func(rr *core.RelationReference, resourceIds []string) {
 		util.ForEachChunk(resourceIds, maxDispatchChunkSize, func(resourceIdChunk []string) {
 			toDispatch = append(toDispatch, directDispatch{
 				resourceType: rr,
@@ -241,10 +250,10 @@ func (cc *ConcurrentChecker) checkDirect(ctx context.Context, crc currentRequest
 			return childResult
 		}
 
-		return mapFoundResources(childResult, dd.resourceType, relationshipsBySubjectONR)
-	}, cc.concurrencyLimit)
+		return mapFoundResources(childResult, dd.resourceType, direct.relationshipsBySubjectONR)
+	}, EffectiveConcurrencyLimit(cc.concurrencyLimit))
 
-	return combineResultWithFoundResources(result, foundResources)
+	return combineResultWithFoundResources(result, direct.foundResources)
 }
 
 func mapFoundResources(result CheckResult, resourceType *core.RelationReference, relationshipsBySubjectONR *util.MultiMap[string, *core.RelationTuple]) CheckResult {
@@ -273,11 +282,11 @@ func mapFoundResources(result CheckResult, resourceType *core.RelationReference,
 func (cc *ConcurrentChecker) checkUsersetRewrite(ctx context.Context, crc currentRequestContext, rewrite *core.UsersetRewrite) CheckResult {
 	switch rw := rewrite.RewriteOperation.(type) {
 	case *core.UsersetRewrite_Union:
-		return union(ctx, crc, rw.Union.Child, cc.runSetOperation, cc.concurrencyLimit)
+		return union(ctx, crc, rw.Union.Child, cc.runSetOperation, EffectiveConcurrencyLimit(cc.concurrencyLimit))
 	case *core.UsersetRewrite_Intersection:
-		return all(ctx, crc, rw.Intersection.Child, cc.runSetOperation, cc.concurrencyLimit)
+		return all(ctx, crc, rw.Intersection.Child, cc.runSetOperation, EffectiveConcurrencyLimit(cc.concurrencyLimit))
 	case *core.UsersetRewrite_Exclusion:
-		return difference(ctx, crc, rw.Exclusion.Child, cc.runSetOperation, cc.concurrencyLimit)
+		return difference(ctx, crc, rw.Exclusion.Child, cc.runSetOperation, EffectiveConcurrencyLimit(cc.concurrencyLimit))
 	default:
 		return checkResultError(fmt.Errorf("unknown userset rewrite operator"), emptyMetadata)
 	}
@@ -434,7 +443,7 @@ func (cc *ConcurrentChecker) checkTupleToUserset(ctx context.Context, crc curren
 
 			return mapFoundResources(childResult, dd.resourceType, relationshipsBySubjectONR)
 		},
-		cc.concurrencyLimit,
+		EffectiveConcurrencyLimit(cc.concurrencyLimit),
 	)
 }
 
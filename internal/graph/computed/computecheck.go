@@ -112,6 +112,7 @@ func computeCaveatedCheckResult(ctx context.Context, params CheckParameters, res
 		missingFields, _ := caveatResult.MissingVarNames()
 		return &v1.ResourceCheckResult{
 			Membership:        v1.ResourceCheckResult_CAVEATED_MEMBER,
+			Expression:        result.Expression,
 			MissingExprFields: missingFields,
 		}, nil
 	}
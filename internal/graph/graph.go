@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"sync/atomic"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
@@ -20,6 +21,24 @@ const maxDispatchChunkSize = datastore.FilterMaximumIDCount
 // must be less than or equal to the maximum ID count for filters in the datastore.
 var progressiveDispatchChunkSizes = []int{5, 10, 25, 50, maxDispatchChunkSize}
 
+var concurrencyLimitOverride atomic.Uint32
+
+// SetConcurrencyLimitOverride overrides the concurrency limit configured on every
+// dispatcher in the process for requests started afterward. A limit of zero removes
+// the override.
+func SetConcurrencyLimitOverride(limit uint16) {
+	concurrencyLimitOverride.Store(uint32(limit))
+}
+
+// EffectiveConcurrencyLimit returns the concurrency limit in use by a dispatcher
+// configured with the given limit.
+func EffectiveConcurrencyLimit(configured uint16) uint16 {
+	if override := concurrencyLimitOverride.Load(); override > 0 {
+		return uint16(override)
+	}
+	return configured
+}
+
 // CheckResult is the data that is returned by a single check or sub-check.
 type CheckResult struct {
 	Resp *v1.DispatchCheckResponse
@@ -86,7 +86,8 @@ func (cl *ConcurrentLookup) LookupViaReachability(ctx context.Context, req Valid
 	cancelCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	checker := newParallelChecker(cancelCtx, cancel, cl.c, req, cl.concurrencyLimit)
+	checker := newParallelChecker(cancelCtx, cancel, cl.c, req, EffectiveConcurrencyLimit(cl.concurrencyLimit))
+	defer checker.ReleaseMemory()
 	stream := &collectingStream{checker, req, cancelCtx, 0, 0, 0, sync.Mutex{}}
 
 	// Start the checker.
@@ -105,14 +106,15 @@ func (cl *ConcurrentLookup) LookupViaReachability(ctx context.Context, req Valid
 		SubjectIds: []string{req.Subject.ObjectId},
 		Metadata:   req.Metadata,
 	}, stream)
-	if err != nil {
+	if err != nil && !checker.CeilingExceeded() {
 		resp := lookupResultError(err, emptyMetadata)
 		return resp.Resp, resp.Err
 	}
 
-	// Wait for the checker to finish.
+	// Wait for the checker to finish. If the memory budget of the request was exhausted, the
+	// resources found until then are returned along with the error.
 	allowed, err := checker.Wait()
-	if err != nil {
+	if err != nil && !errors.Is(err, dispatch.ErrMemoryCeilingExceeded) {
 		resp := lookupResultError(err, emptyMetadata)
 		return resp.Resp, resp.Err
 	}
@@ -122,7 +124,7 @@ func (cl *ConcurrentLookup) LookupViaReachability(ctx context.Context, req Valid
 		CachedDispatchCount: stream.cachedDispatchCount + checker.CachedDispatchCount(),
 		DepthRequired:       max(stream.depthRequired, checker.DepthRequired()) + 1, // +1 for the lookup
 	})
-	return res.Resp, res.Err
+	return res.Resp, err
 }
 
 func lookupResult(foundResources []*v1.ResolvedResource, req ValidatedLookupRequest, subProblemMetadata *v1.ResponseMeta) LookupResult {
@@ -268,11 +268,13 @@ func (cl *ConcurrentLookupSubjects) lookupSetOperation(
 	so *core.SetOperation,
 	reducer lookupSubjectsReducer,
 ) error {
+	defer reducer.Close()
+
 	cancelCtx, checkCancel := context.WithCancel(ctx)
 	defer checkCancel()
 
 	g, subCtx := errgroup.WithContext(cancelCtx)
-	g.SetLimit(int(cl.concurrencyLimit))
+	g.SetLimit(int(EffectiveConcurrencyLimit(cl.concurrencyLimit)))
 
 	for index, childOneof := range so.Child {
 		stream := reducer.ForIndex(subCtx, index)
@@ -328,7 +330,7 @@ func (cl *ConcurrentLookupSubjects) dispatchTo(
 	defer checkCancel()
 
 	g, subCtx := errgroup.WithContext(cancelCtx)
-	g.SetLimit(int(cl.concurrencyLimit))
+	g.SetLimit(int(EffectiveConcurrencyLimit(cl.concurrencyLimit)))
 
 	toDispatchByType.ForEachType(func(resourceType *core.RelationReference, foundSubjects util.SubjectSet) {
 		slice := foundSubjects.AsSlice()
@@ -426,27 +428,45 @@ func combineFoundSubjects(existing *v1.FoundSubjects, toAdd *v1.FoundSubjects) *
 type lookupSubjectsReducer interface {
 	ForIndex(ctx context.Context, setOperationIndex int) dispatch.LookupSubjectsStream
 	CompletedChildOperations() error
+	Close()
+}
+
+func newLookupSubjectsResponse() *v1.DispatchLookupSubjectsResponse {
+	return &v1.DispatchLookupSubjectsResponse{}
+}
+
+// closeCollectors releases the memory and spill files held by the collectors of a reducer.
+func closeCollectors(collectors map[int]*dispatch.SpillingDispatchStream[*v1.DispatchLookupSubjectsResponse]) {
+	for _, collector := range collectors {
+		if err := collector.Close(); err != nil {
+			log.Warn().Err(err).Msg("unable to close lookup subjects collector")
+		}
+	}
 }
 
 // Union
 type lookupSubjectsUnion struct {
 	parentStream dispatch.LookupSubjectsStream
-	collectors   map[int]*dispatch.CollectingDispatchStream[*v1.DispatchLookupSubjectsResponse]
+	collectors   map[int]*dispatch.SpillingDispatchStream[*v1.DispatchLookupSubjectsResponse]
 }
 
 func newLookupSubjectsUnion(parentStream dispatch.LookupSubjectsStream) *lookupSubjectsUnion {
 	return &lookupSubjectsUnion{
 		parentStream: parentStream,
-		collectors:   map[int]*dispatch.CollectingDispatchStream[*v1.DispatchLookupSubjectsResponse]{},
+		collectors:   map[int]*dispatch.SpillingDispatchStream[*v1.DispatchLookupSubjectsResponse]{},
 	}
 }
 
 func (lsu *lookupSubjectsUnion) ForIndex(ctx context.Context, setOperationIndex int) dispatch.LookupSubjectsStream {
-	collector := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](ctx)
+	collector := dispatch.NewSpillingDispatchStream(ctx, newLookupSubjectsResponse)
 	lsu.collectors[setOperationIndex] = collector
 	return collector
 }
 
+func (lsu *lookupSubjectsUnion) Close() {
+	closeCollectors(lsu.collectors)
+}
+
 func (lsu *lookupSubjectsUnion) CompletedChildOperations() error {
 	foundSubjects := util.NewSubjectSetByResourceID()
 	metadata := emptyMetadata
@@ -457,9 +477,12 @@ func (lsu *lookupSubjectsUnion) CompletedChildOperations() error {
 			return fmt.Errorf("missing collector for index %d", index)
 		}
 
-		for _, result := range collector.Results() {
+		if err := collector.ForEach(func(result *v1.DispatchLookupSubjectsResponse) error {
 			metadata = combineResponseMetadata(metadata, result.Metadata)
 			foundSubjects.UnionWith(result.FoundSubjectsByResourceId)
+			return nil
+		}); err != nil {
+			return err
 		}
 	}
 
@@ -476,22 +499,26 @@ func (lsu *lookupSubjectsUnion) CompletedChildOperations() error {
 // Intersection
 type lookupSubjectsIntersection struct {
 	parentStream dispatch.LookupSubjectsStream
-	collectors   map[int]*dispatch.CollectingDispatchStream[*v1.DispatchLookupSubjectsResponse]
+	collectors   map[int]*dispatch.SpillingDispatchStream[*v1.DispatchLookupSubjectsResponse]
 }
 
 func newLookupSubjectsIntersection(parentStream dispatch.LookupSubjectsStream) *lookupSubjectsIntersection {
 	return &lookupSubjectsIntersection{
 		parentStream: parentStream,
-		collectors:   map[int]*dispatch.CollectingDispatchStream[*v1.DispatchLookupSubjectsResponse]{},
+		collectors:   map[int]*dispatch.SpillingDispatchStream[*v1.DispatchLookupSubjectsResponse]{},
 	}
 }
 
 func (lsi *lookupSubjectsIntersection) ForIndex(ctx context.Context, setOperationIndex int) dispatch.LookupSubjectsStream {
-	collector := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](ctx)
+	collector := dispatch.NewSpillingDispatchStream(ctx, newLookupSubjectsResponse)
 	lsi.collectors[setOperationIndex] = collector
 	return collector
 }
 
+func (lsi *lookupSubjectsIntersection) Close() {
+	closeCollectors(lsi.collectors)
+}
+
 func (lsi *lookupSubjectsIntersection) CompletedChildOperations() error {
 	var foundSubjects util.SubjectSetByResourceID
 	metadata := emptyMetadata
@@ -503,9 +530,12 @@ func (lsi *lookupSubjectsIntersection) CompletedChildOperations() error {
 		}
 
 		results := util.NewSubjectSetByResourceID()
-		for _, result := range collector.Results() {
+		if err := collector.ForEach(func(result *v1.DispatchLookupSubjectsResponse) error {
 			metadata = combineResponseMetadata(metadata, result.Metadata)
 			results.UnionWith(result.FoundSubjectsByResourceId)
+			return nil
+		}); err != nil {
+			return err
 		}
 
 		if index == 0 {
@@ -527,22 +557,26 @@ func (lsi *lookupSubjectsIntersection) CompletedChildOperations() error {
 // Exclusion
 type lookupSubjectsExclusion struct {
 	parentStream dispatch.LookupSubjectsStream
-	collectors   map[int]*dispatch.CollectingDispatchStream[*v1.DispatchLookupSubjectsResponse]
+	collectors   map[int]*dispatch.SpillingDispatchStream[*v1.DispatchLookupSubjectsResponse]
 }
 
 func newLookupSubjectsExclusion(parentStream dispatch.LookupSubjectsStream) *lookupSubjectsExclusion {
 	return &lookupSubjectsExclusion{
 		parentStream: parentStream,
-		collectors:   map[int]*dispatch.CollectingDispatchStream[*v1.DispatchLookupSubjectsResponse]{},
+		collectors:   map[int]*dispatch.SpillingDispatchStream[*v1.DispatchLookupSubjectsResponse]{},
 	}
 }
 
 func (lse *lookupSubjectsExclusion) ForIndex(ctx context.Context, setOperationIndex int) dispatch.LookupSubjectsStream {
-	collector := dispatch.NewCollectingDispatchStream[*v1.DispatchLookupSubjectsResponse](ctx)
+	collector := dispatch.NewSpillingDispatchStream(ctx, newLookupSubjectsResponse)
 	lse.collectors[setOperationIndex] = collector
 	return collector
 }
 
+func (lse *lookupSubjectsExclusion) Close() {
+	closeCollectors(lse.collectors)
+}
+
 func (lse *lookupSubjectsExclusion) CompletedChildOperations() error {
 	var foundSubjects util.SubjectSetByResourceID
 	metadata := emptyMetadata
@@ -550,9 +584,12 @@ func (lse *lookupSubjectsExclusion) CompletedChildOperations() error {
 	for index := 0; index < len(lse.collectors); index++ {
 		collector := lse.collectors[index]
 		results := util.NewSubjectSetByResourceID()
-		for _, result := range collector.Results() {
+		if err := collector.ForEach(func(result *v1.DispatchLookupSubjectsResponse) error {
 			metadata = combineResponseMetadata(metadata, result.Metadata)
 			results.UnionWith(result.FoundSubjectsByResourceId)
+			return nil
+		}); err != nil {
+			return err
 		}
 
 		if index == 0 {
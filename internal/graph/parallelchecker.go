@@ -34,9 +34,17 @@ type parallelChecker struct {
 	cachedDispatchCount uint32
 	depthRequired       uint32
 
+	budget          *dispatch.MemoryBudget
+	reserved        int64
+	ceilingExceeded bool
+
 	mu sync.Mutex
 }
 
+// enqueuedOverhead approximates the memory held for each resource ID queued for a check,
+// beyond the ID itself.
+const enqueuedOverhead = 32
+
 // newParallelChecker creates a new parallel checker, for a given subject.
 func newParallelChecker(ctx context.Context, cancel func(), c dispatch.Check, req ValidatedLookupRequest, maxConcurrent uint16) *parallelChecker {
 	g, checkCtx := errgroup.WithContext(ctx)
@@ -59,6 +67,8 @@ func newParallelChecker(ctx context.Context, cancel func(), c dispatch.Check, re
 		cachedDispatchCount: 0,
 		depthRequired:       0,
 
+		budget: dispatch.MemoryBudgetFromContext(ctx),
+
 		mu: sync.Mutex{},
 	}
 }
@@ -94,6 +104,10 @@ func (pc *parallelChecker) addResultsUnsafe(resolvedResource *v1.ResolvedResourc
 		}
 	}
 
+	if _, ok := pc.foundResourceIDs[resolvedResource.ResourceId]; !ok && !pc.reserveUnsafe(int64(resolvedResource.SizeVT())) {
+		return
+	}
+
 	pc.foundResourceIDs[resolvedResource.ResourceId] = resolvedResource
 	if len(pc.foundResourceIDs) >= int(pc.lookupRequest.Limit) {
 		// Cancel any further work
@@ -102,6 +116,40 @@ func (pc *parallelChecker) addResultsUnsafe(resolvedResource *v1.ResolvedResourc
 	}
 }
 
+// reserveUnsafe reserves size bytes from the memory budget of the request. If the budget
+// is exhausted, further work is canceled and the results found so far are returned from
+// Wait.
+func (pc *parallelChecker) reserveUnsafe(size int64) bool {
+	if pc.ceilingExceeded {
+		return false
+	}
+
+	if !pc.budget.Reserve(size) {
+		pc.ceilingExceeded = true
+		pc.cancel()
+		return false
+	}
+
+	pc.reserved += size
+	return true
+}
+
+// CeilingExceeded returns whether the checker stopped because the memory budget of the
+// request was exhausted.
+func (pc *parallelChecker) CeilingExceeded() bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.ceilingExceeded
+}
+
+// ReleaseMemory returns the memory reserved by the checker to the budget of the request.
+func (pc *parallelChecker) ReleaseMemory() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.budget.Release(pc.reserved)
+	pc.reserved = 0
+}
+
 func (pc *parallelChecker) updateStatsUnsafe(metadata *v1.ResponseMeta) {
 	pc.dispatchCount += metadata.DispatchCount
 	pc.cachedDispatchCount += metadata.CachedDispatchCount
@@ -118,14 +166,24 @@ func (pc *parallelChecker) QueueToCheck(resourceID string) bool {
 			return false
 		}
 
+		if pc.enqueuedToCheck.Has(resourceID) || !pc.reserveUnsafe(int64(len(resourceID)+enqueuedOverhead)) {
+			return false
+		}
+
 		return pc.enqueuedToCheck.Add(resourceID)
 	}()
 	if !queue {
 		return false
 	}
 
-	pc.toCheck <- resourceID
-	return true
+	// The checks stop once the budget of the request is exhausted, so the send must not
+	// block past cancelation.
+	select {
+	case pc.toCheck <- resourceID:
+		return true
+	case <-pc.checkCtx.Done():
+		return false
+	}
 }
 
 // Start starts the parallel checks over those items added via QueueToCheck.
@@ -210,9 +268,20 @@ func (pc *parallelChecker) Start() {
 // Wait waits for the parallel checker to finish performing all of its
 // checks and returns the set of resources that checked, along with whether an
 // error occurred. Once called, no new items can be added via QueueToCheck.
+//
+// If the memory budget of the request was exhausted, the resources found before it was
+// are returned along with dispatch.ErrMemoryCeilingExceeded.
 func (pc *parallelChecker) Wait() ([]*v1.ResolvedResource, error) {
 	close(pc.toCheck)
-	if err := pc.g.Wait(); err != nil {
+	err := pc.g.Wait()
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if pc.ceilingExceeded {
+		return maps.Values(pc.foundResourceIDs), dispatch.ErrMemoryCeilingExceeded
+	}
+
+	if err != nil {
 		return nil, err
 	}
 
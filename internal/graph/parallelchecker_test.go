@@ -2,10 +2,12 @@ package graph
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/authzed/spicedb/internal/dispatch"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
@@ -56,3 +58,37 @@ func TestQueueToCheckLimit(t *testing.T) {
 	// Queue a second and ensure it is ignored.
 	require.False(t, pc.QueueToCheck("bar"))
 }
+
+func TestParallelCheckerMemoryCeiling(t *testing.T) {
+	ctx := dispatch.ContextWithMemoryBudget(context.Background(), dispatch.NewMemoryBudget(64, ""))
+	canceled := false
+	pc := newParallelChecker(ctx, func() { canceled = true }, nil, ValidatedLookupRequest{
+		DispatchLookupRequest: &v1.DispatchLookupRequest{
+			Limit: 50,
+		},
+	}, 10)
+
+	pc.addResultsUnsafe(&v1.ResolvedResource{
+		ResourceId:     "foo",
+		Permissionship: v1.ResolvedResource_HAS_PERMISSION,
+	})
+	require.False(t, pc.CeilingExceeded())
+
+	// Add an item too large for the budget and ensure the checker stops.
+	pc.addResultsUnsafe(&v1.ResolvedResource{
+		ResourceId:     strings.Repeat("a", 100),
+		Permissionship: v1.ResolvedResource_HAS_PERMISSION,
+	})
+	require.True(t, pc.CeilingExceeded())
+	require.True(t, canceled)
+	require.Len(t, pc.foundResourceIDs, 1)
+	require.False(t, pc.QueueToCheck("bar"))
+
+	// Ensure the results found before the ceiling are returned.
+	found, err := pc.Wait()
+	require.ErrorIs(t, err, dispatch.ErrMemoryCeilingExceeded)
+	require.Len(t, found, 1)
+
+	pc.ReleaseMemory()
+	require.Zero(t, pc.budget.Used())
+}
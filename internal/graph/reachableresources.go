@@ -20,7 +20,7 @@ import (
 
 // NewConcurrentReachableResources creates an instance of ConcurrentReachableResources.
 func NewConcurrentReachableResources(d dispatch.ReachableResources, concurrencyLimit uint16) *ConcurrentReachableResources {
-	return &ConcurrentReachableResources{d, concurrencyLimit}
+	return &ConcurrentReachableResources{d, concurrencyLimit, newWildcardIndex()}
 }
 
 // ConcurrentReachableResources exposes a method to perform ReachableResources requests, and
@@ -28,6 +28,7 @@ func NewConcurrentReachableResources(d dispatch.ReachableResources, concurrencyL
 type ConcurrentReachableResources struct {
 	d                dispatch.ReachableResources
 	concurrencyLimit uint16
+	wildcards        *wildcardIndex
 }
 
 // ValidatedReachableResourcesRequest represents a request after it has been validated and parsed for internal
@@ -97,17 +98,34 @@ func (crr *ConcurrentReachableResources) ReachableResources(
 		return err
 	}
 
+	// Load the type systems of the namespaces containing the entrypoints in a single read.
+	entrypointNamespaces := make([]string, 0, len(entrypoints))
+	for _, entrypoint := range entrypoints {
+		switch entrypoint.EntrypointKind() {
+		case core.ReachabilityEntrypoint_RELATION_ENTRYPOINT:
+			entrypointNamespaces = append(entrypointNamespaces, entrypoint.DirectRelation().Namespace)
+
+		case core.ReachabilityEntrypoint_TUPLESET_TO_USERSET_ENTRYPOINT:
+			entrypointNamespaces = append(entrypointNamespaces, entrypoint.ContainingRelationOrPermission().Namespace)
+		}
+	}
+
+	typeSystems, err := namespace.ReadNamespacesAndTypes(ctx, entrypointNamespaces, reader)
+	if err != nil {
+		return err
+	}
+
 	cancelCtx, checkCancel := context.WithCancel(ctx)
 	defer checkCancel()
 
 	g, subCtx := errgroup.WithContext(cancelCtx)
-	g.SetLimit(int(crr.concurrencyLimit))
+	g.SetLimit(int(EffectiveConcurrencyLimit(crr.concurrencyLimit)))
 
 	// For each entrypoint, load the necessary data and re-dispatch if a subproblem was found.
 	for _, entrypoint := range entrypoints {
 		switch entrypoint.EntrypointKind() {
 		case core.ReachabilityEntrypoint_RELATION_ENTRYPOINT:
-			err := crr.lookupRelationEntrypoint(subCtx, entrypoint, rg, g, reader, req, stream, dispatched)
+			err := crr.lookupRelationEntrypoint(subCtx, entrypoint, rg, g, reader, typeSystems, req, stream, dispatched)
 			if err != nil {
 				return err
 			}
@@ -135,7 +153,7 @@ func (crr *ConcurrentReachableResources) ReachableResources(
 			}
 
 		case core.ReachabilityEntrypoint_TUPLESET_TO_USERSET_ENTRYPOINT:
-			err := crr.lookupTTUEntrypoint(subCtx, entrypoint, rg, g, reader, req, stream, dispatched)
+			err := crr.lookupTTUEntrypoint(subCtx, entrypoint, rg, g, reader, typeSystems, req, stream, dispatched)
 			if err != nil {
 				return err
 			}
@@ -153,15 +171,13 @@ func (crr *ConcurrentReachableResources) lookupRelationEntrypoint(ctx context.Co
 	rg *namespace.ReachabilityGraph,
 	g *errgroup.Group,
 	reader datastore.Reader,
+	typeSystems map[string]*namespace.TypeSystem,
 	req ValidatedReachableResourcesRequest,
 	stream dispatch.ReachableResourcesStream,
 	dispatched *syncONRSet,
 ) error {
 	relationReference := entrypoint.DirectRelation()
-	_, relTypeSystem, err := namespace.ReadNamespaceAndTypes(ctx, relationReference.Namespace, reader)
-	if err != nil {
-		return err
-	}
+	relTypeSystem := typeSystems[relationReference.Namespace]
 
 	// Build the list of subjects to lookup based on the type information available.
 	isDirectAllowed, err := relTypeSystem.IsAllowedDirectRelation(
@@ -185,10 +201,23 @@ func (crr *ConcurrentReachableResources) lookupRelationEntrypoint(ctx context.Co
 		}
 
 		if isWildcardAllowed == namespace.PublicSubjectAllowed {
-			subjectIds = append(subjectIds, "*")
+			// Only search for wildcard subjects if any have been written for the relation.
+			hasWildcard, err := crr.wildcards.hasWildcard(ctx, reader, req.Revision, relationReference, req.SubjectRelation.Namespace)
+			if err != nil {
+				return err
+			}
+
+			if hasWildcard {
+				subjectIds = append(subjectIds, tuple.PublicWildcard)
+			}
 		}
 	}
 
+	// An empty list of subject IDs would match any subject, so skip the lookup entirely.
+	if len(subjectIds) == 0 {
+		return nil
+	}
+
 	// Lookup the subjects and then redispatch/report results.
 	subjectsFilter := datastore.SubjectsFilter{
 		SubjectType: req.SubjectRelation.Namespace,
@@ -266,16 +295,13 @@ func (crr *ConcurrentReachableResources) lookupTTUEntrypoint(ctx context.Context
 	rg *namespace.ReachabilityGraph,
 	g *errgroup.Group,
 	reader datastore.Reader,
+	typeSystems map[string]*namespace.TypeSystem,
 	req ValidatedReachableResourcesRequest,
 	stream dispatch.ReachableResourcesStream,
 	dispatched *syncONRSet,
 ) error {
 	containingRelation := entrypoint.ContainingRelationOrPermission()
-
-	_, ttuTypeSystem, err := namespace.ReadNamespaceAndTypes(ctx, containingRelation.Namespace, reader)
-	if err != nil {
-		return err
-	}
+	ttuTypeSystem := typeSystems[containingRelation.Namespace]
 
 	tuplesetRelation := entrypoint.TuplesetRelation()
 
@@ -0,0 +1,205 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// DefaultSuperNodeThreshold is the default number of relationships of a resource on a
+// relation past which the resource is checked as a super-node.
+const DefaultSuperNodeThreshold = 10_000
+
+// maxTrackedSuperNodes bounds the number of super-nodes remembered by a checker.
+const maxTrackedSuperNodes = 10_000
+
+var superNodesDetected = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "check",
+	Name:      "super_nodes_detected_total",
+	Help:      "number of resources found to have more relationships on a relation than the super-node threshold, after which they are checked starting from the subject",
+}, []string{"resource_type", "relation"})
+
+// superNodes remembers the resources found to have more direct relationships on a
+// relation than the threshold, such as groups with millions of members.
+//
+// Checking such a resource by reading all of its relationships can take longer than any
+// request deadline, so super-nodes are instead checked starting from the subject: only
+// the relationships to the subject itself, and those to sets of subjects which must be
+// dispatched, are read. Both are found by index, and the latter are usually few even for
+// resources with millions of relationships.
+type superNodes struct {
+	threshold uint64
+
+	lock  sync.RWMutex
+	known map[string]struct{}
+}
+
+// newSuperNodes returns a tracker of super-nodes with the given threshold, or nil if the
+// threshold is zero, disabling detection.
+func newSuperNodes(threshold uint64) *superNodes {
+	if threshold == 0 {
+		return nil
+	}
+	return &superNodes{threshold: threshold, known: make(map[string]struct{})}
+}
+
+func superNodeKey(resourceRelation *core.RelationReference, resourceID string) string {
+	return resourceRelation.Namespace + ":" + resourceID + "#" + resourceRelation.Relation
+}
+
+// anyKnown returns whether any of the resources is a known super-node.
+func (sn *superNodes) anyKnown(resourceRelation *core.RelationReference, resourceIDs []string) bool {
+	if sn == nil {
+		return false
+	}
+
+	sn.lock.RLock()
+	defer sn.lock.RUnlock()
+	for _, resourceID := range resourceIDs {
+		if _, ok := sn.known[superNodeKey(resourceRelation, resourceID)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// observe records a direct relationship read for the resource, returning true once the
+// resource has more than the threshold, at which point it is remembered as a super-node.
+func (sn *superNodes) observe(ctx context.Context, counts map[string]uint64, resourceRelation *core.RelationReference, resourceID string) bool {
+	if sn == nil {
+		return false
+	}
+
+	counts[resourceID]++
+	if counts[resourceID] <= sn.threshold {
+		return false
+	}
+
+	superNodesDetected.WithLabelValues(resourceRelation.Namespace, resourceRelation.Relation).Inc()
+	log.Ctx(ctx).Info().
+		Str("resource", tuple.StringONR(&core.ObjectAndRelation{
+			Namespace: resourceRelation.Namespace,
+			ObjectId:  resourceID,
+			Relation:  resourceRelation.Relation,
+		})).
+		Uint64("threshold", sn.threshold).
+		Msg("detected super-node, checking it starting from the subject")
+
+	sn.lock.Lock()
+	defer sn.lock.Unlock()
+	if len(sn.known) >= maxTrackedSuperNodes {
+		sn.known = make(map[string]struct{})
+	}
+	sn.known[superNodeKey(resourceRelation, resourceID)] = struct{}{}
+	return true
+}
+
+// directRelationships accumulates the direct relationships of the resources being
+// checked: those of the subject itself are found members, and those to sets of subjects
+// are to be dispatched.
+type directRelationships struct {
+	crc                       currentRequestContext
+	foundResources            *MembershipSet
+	subjectsToDispatch        *tuple.ONRByTypeSet
+	relationshipsBySubjectONR *util.MultiMap[string, *core.RelationTuple]
+}
+
+func newDirectRelationships(crc currentRequestContext) *directRelationships {
+	return &directRelationships{
+		crc:                       crc,
+		foundResources:            NewMembershipSet(),
+		subjectsToDispatch:        tuple.NewONRByTypeSet(),
+		relationshipsBySubjectONR: util.NewMultiMap[string, *core.RelationTuple](),
+	}
+}
+
+// add adds a direct relationship, returning true if it determines the result of the
+// check on its own.
+func (dr *directRelationships) add(tpl *core.RelationTuple) bool {
+	// If the subject of the relationship matches the target subject, then we've found
+	// a result.
+	if onrEqualOrWildcard(tpl.Subject, dr.crc.parentReq.Subject) {
+		dr.foundResources.AddDirectMember(tpl.ResourceAndRelation.ObjectId, tpl.Caveat)
+		return dr.crc.resultsSetting == v1.DispatchCheckRequest_ALLOW_SINGLE_RESULT && dr.foundResources.HasDeterminedMember()
+	}
+
+	// If the subject of the relationship is a non-terminal, add to be dispatched.
+	if tpl.Subject.Relation != Ellipsis {
+		dr.subjectsToDispatch.Add(tpl.Subject)
+		dr.relationshipsBySubjectONR.Add(tuple.StringONR(tpl.Subject), tpl)
+	}
+	return false
+}
+
+// checkDirectFromSubject checks the direct relationships of resources starting from the
+// subject, as is done for super-nodes: rather than reading all of the relationships of
+// the resources, only those to the subject, or a wildcard of its type, and those to sets
+// of subjects allowed on the relation are read.
+func (cc *ConcurrentChecker) checkDirectFromSubject(ctx context.Context, crc currentRequestContext, relation *core.Relation) CheckResult {
+	log.Ctx(ctx).Trace().Object("directFromSubject", crc.parentReq).Send()
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(crc.parentReq.Revision)
+
+	subject := crc.parentReq.Subject
+	subjectRelationFilter := datastore.SubjectRelationFilter{}.WithEllipsisRelation()
+	if subject.Relation != Ellipsis {
+		subjectRelationFilter = subjectRelationFilter.WithNonEllipsisRelation(subject.Relation)
+	}
+	subjectsFilters := []datastore.SubjectsFilter{{
+		SubjectType:        subject.Namespace,
+		OptionalSubjectIds: []string{subject.ObjectId, tuple.PublicWildcard},
+		RelationFilter:     subjectRelationFilter,
+	}}
+	for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+		if allowedRelation := allowed.GetRelation(); allowedRelation != "" && allowedRelation != Ellipsis {
+			subjectsFilters = append(subjectsFilters, datastore.SubjectsFilter{
+				SubjectType:    allowed.Namespace,
+				RelationFilter: datastore.SubjectRelationFilter{}.WithNonEllipsisRelation(allowedRelation),
+			})
+		}
+	}
+
+	direct := newDirectRelationships(crc)
+	directMatches := util.NewSet[string]()
+	for index := range subjectsFilters {
+		it, err := ds.QueryRelationships(ctx, datastore.RelationshipsFilter{
+			ResourceType:             crc.parentReq.ResourceRelation.Namespace,
+			OptionalResourceIds:      crc.filteredResourceIDs,
+			OptionalResourceRelation: crc.parentReq.ResourceRelation.Relation,
+			OptionalSubjectsFilter:   &subjectsFilters[index],
+		})
+		if err != nil {
+			return checkResultError(NewCheckFailureErr(err), emptyMetadata)
+		}
+
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			// A relationship to a set of subjects which is the subject itself is matched
+			// by more than one of the filters.
+			if onrEqual(tpl.Subject, subject) && !directMatches.Add(tpl.ResourceAndRelation.ObjectId) {
+				continue
+			}
+
+			if direct.add(tpl) {
+				it.Close()
+				return checkResultsForMembership(direct.foundResources, emptyMetadata)
+			}
+		}
+		err = it.Err()
+		it.Close()
+		if err != nil {
+			return checkResultError(NewCheckFailureErr(err), emptyMetadata)
+		}
+	}
+
+	return cc.dispatchDirect(ctx, direct)
+}
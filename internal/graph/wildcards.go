@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// maxWildcardIndexRevisions is the number of revisions for which wildcard presence is
+// retained. Revisions are quantized, so only a handful are in use at any given time.
+const maxWildcardIndexRevisions = 16
+
+// wildcardIndex records, per revision, whether any relationship of a relation has a
+// wildcard subject of a given type, so that lookups can skip searching for wildcard
+// subjects in the common case of none having been written.
+type wildcardIndex struct {
+	sync.Mutex
+	byRevision map[string]map[string]bool
+	revisions  []string
+	group      singleflight.Group
+}
+
+func newWildcardIndex() *wildcardIndex {
+	return &wildcardIndex{byRevision: make(map[string]map[string]bool, maxWildcardIndexRevisions)}
+}
+
+// hasWildcard returns whether any relationship of the relation at the revision has a
+// wildcard subject of the subject type.
+func (wi *wildcardIndex) hasWildcard(
+	ctx context.Context,
+	reader datastore.Reader,
+	revision datastore.Revision,
+	relation *core.RelationReference,
+	subjectType string,
+) (bool, error) {
+	revisionKey := revision.String()
+	relationKey := tuple.StringRR(relation) + "@" + subjectType
+
+	if found, ok := wi.get(revisionKey, relationKey); ok {
+		return found, nil
+	}
+
+	found, err, _ := wi.group.Do(revisionKey+"/"+relationKey, func() (any, error) {
+		it, err := reader.ReverseQueryRelationships(
+			ctx,
+			datastore.SubjectsFilter{
+				SubjectType:        subjectType,
+				OptionalSubjectIds: []string{tuple.PublicWildcard},
+				RelationFilter: datastore.SubjectRelationFilter{
+					NonEllipsisRelation: tuple.Ellipsis,
+				},
+			},
+			options.WithResRelation(&options.ResourceRelation{
+				Namespace: relation.Namespace,
+				Relation:  relation.Relation,
+			}),
+			options.WithReverseLimit(options.LimitOne),
+		)
+		if err != nil {
+			return false, err
+		}
+		defer it.Close()
+
+		found := it.Next() != nil
+		if it.Err() != nil {
+			return false, it.Err()
+		}
+
+		wi.set(revisionKey, relationKey, found)
+		return found, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return found.(bool), nil
+}
+
+func (wi *wildcardIndex) get(revisionKey, relationKey string) (found bool, ok bool) {
+	wi.Lock()
+	defer wi.Unlock()
+
+	found, ok = wi.byRevision[revisionKey][relationKey]
+	return
+}
+
+func (wi *wildcardIndex) set(revisionKey, relationKey string, found bool) {
+	wi.Lock()
+	defer wi.Unlock()
+
+	relations, ok := wi.byRevision[revisionKey]
+	if !ok {
+		if len(wi.revisions) >= maxWildcardIndexRevisions {
+			delete(wi.byRevision, wi.revisions[0])
+			wi.revisions = wi.revisions[1:]
+		}
+
+		relations = make(map[string]bool)
+		wi.byRevision[revisionKey] = relations
+		wi.revisions = append(wi.revisions, revisionKey)
+	}
+	relations[relationKey] = found
+}
@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+type countingReader struct {
+	datastore.Reader
+	reverseQueries int
+}
+
+func (r *countingReader) ReverseQueryRelationships(ctx context.Context, subjectsFilter datastore.SubjectsFilter, opts ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	r.reverseQueries++
+	return r.Reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+}
+
+func TestWildcardIndex(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, revision := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		definition user {}
+
+		definition document {
+			relation viewer: user | user:*
+			relation editor: user | user:*
+		}
+	`, []*core.RelationTuple{
+		tuple.MustParse("document:first#viewer@user:*"),
+		tuple.MustParse("document:first#editor@user:tom"),
+	}, require)
+
+	reader := &countingReader{Reader: ds.SnapshotReader(revision)}
+	index := newWildcardIndex()
+
+	testCases := []struct {
+		relation    string
+		subjectType string
+		expected    bool
+	}{
+		{"viewer", "user", true},
+		{"editor", "user", false},
+		{"viewer", "document", false},
+	}
+
+	for _, tc := range testCases {
+		for i := 0; i < 2; i++ {
+			found, err := index.hasWildcard(context.Background(), reader, revision, &core.RelationReference{
+				Namespace: "document",
+				Relation:  tc.relation,
+			}, tc.subjectType)
+			require.NoError(err)
+			require.Equal(tc.expected, found, "%s@%s", tc.relation, tc.subjectType)
+		}
+	}
+
+	// Each relation and subject type is only queried once at the revision.
+	require.Equal(len(testCases), reader.reverseQueries)
+}
+
+func TestWildcardIndexEvictsOldestRevision(t *testing.T) {
+	require := require.New(t)
+
+	index := newWildcardIndex()
+	for i := 0; i <= maxWildcardIndexRevisions; i++ {
+		index.set(string(rune('a'+i)), "document#viewer@user", true)
+	}
+
+	require.Len(index.revisions, maxWildcardIndexRevisions)
+	_, ok := index.get("a", "document#viewer@user")
+	require.False(ok)
+
+	found, ok := index.get(string(rune('a'+maxWildcardIndexRevisions)), "document#viewer@user")
+	require.True(ok)
+	require.True(found)
+}
@@ -0,0 +1,189 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc"
+
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// AccessReportClient is a client of the service producing access reports.
+type AccessReportClient interface {
+	AccessReport(ctx context.Context, in *experimental.AccessReportRequest, opts ...grpc.CallOption) (*experimental.AccessReportResponse, error)
+}
+
+// AccessReportResult is the outcome of writing an access report.
+type AccessReportResult struct {
+	// Zookie is the zedtoken at which the report was produced.
+	Zookie string
+
+	// Entries is the number of subjects of resources written.
+	Entries uint64
+}
+
+// accessReportColumns are the columns of an access report in the CSV format.
+var accessReportColumns = []string{
+	"resource_type", "resource_id", "permission",
+	"subject_type", "subject_id", "subject_relation",
+	"permissionship", "excluded_subject_ids",
+	"path_kind", "path_caveated", "path",
+}
+
+// WriteAccessReport writes every page of the access report of the request, either in the
+// CSV format, with one row per path of a subject, or in the NDJSON format, with one object
+// per subject of a resource. The first page is read at the consistency of the request, or
+// fully consistent if it has none, and later pages at the revision of the first.
+func WriteAccessReport(ctx context.Context, client AccessReportClient, w io.Writer, format Format, req *experimental.AccessReportRequest) (AccessReportResult, error) {
+	var write func(*experimental.AccessReportRequest, *experimental.AccessReportEntry) error
+	var flush func() error
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(accessReportColumns); err != nil {
+			return AccessReportResult{}, fmt.Errorf("unable to write report: %w", err)
+		}
+		write = func(req *experimental.AccessReportRequest, entry *experimental.AccessReportEntry) error {
+			return writeAccessReportRows(cw, req, entry)
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case FormatNDJSON:
+		encoder := json.NewEncoder(w)
+		write = func(req *experimental.AccessReportRequest, entry *experimental.AccessReportEntry) error {
+			return encoder.Encode(newAccessReportObject(req, entry))
+		}
+		flush = func() error { return nil }
+	default:
+		return AccessReportResult{}, fmt.Errorf("unknown format `%s`: expected csv or ndjson", format)
+	}
+
+	req = req.CloneVT()
+	if req.Consistency == nil {
+		req.Consistency = &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}
+	}
+
+	var result AccessReportResult
+	for {
+		resp, err := client.AccessReport(ctx, req)
+		if err != nil {
+			return result, fmt.Errorf("unable to read access report: %w", err)
+		}
+
+		if result.Zookie == "" {
+			result.Zookie = resp.ReadAt.GetToken()
+			req.Consistency = &v1.Consistency{Requirement: &v1.Consistency_AtExactSnapshot{AtExactSnapshot: resp.ReadAt}}
+		}
+
+		for _, entry := range resp.Entries {
+			if err := write(req, entry); err != nil {
+				return result, fmt.Errorf("unable to write report: %w", err)
+			}
+			result.Entries++
+		}
+
+		if resp.NextCursor == "" {
+			break
+		}
+		req.OptionalCursor = resp.NextCursor
+	}
+
+	if err := flush(); err != nil {
+		return result, fmt.Errorf("unable to write report: %w", err)
+	}
+	return result, nil
+}
+
+func writeAccessReportRows(cw *csv.Writer, req *experimental.AccessReportRequest, entry *experimental.AccessReportEntry) error {
+	row := []string{
+		req.ResourceObjectType, entry.ResourceObjectId, req.Permission,
+		entry.Subject.Object.ObjectType, entry.Subject.Object.ObjectId, entry.Subject.OptionalRelation,
+		accessReportPermissionship(entry.Permissionship), strings.Join(entry.ExcludedSubjectIds, " "),
+	}
+
+	// Subjects without a known path still have a row, with empty path columns.
+	if len(entry.Paths) == 0 {
+		return cw.Write(append(row, "", "", ""))
+	}
+
+	for _, path := range entry.Paths {
+		pathRow := append(append([]string(nil), row...),
+			accessPathKind(path.Kind), fmt.Sprint(path.Caveated), strings.Join(accessPathRelationships(path), " "))
+		if err := cw.Write(pathRow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type accessReportObject struct {
+	ResourceType       string             `json:"resource_type"`
+	ResourceID         string             `json:"resource_id"`
+	Permission         string             `json:"permission"`
+	SubjectType        string             `json:"subject_type"`
+	SubjectID          string             `json:"subject_id"`
+	SubjectRelation    string             `json:"subject_relation,omitempty"`
+	Permissionship     string             `json:"permissionship"`
+	ExcludedSubjectIDs []string           `json:"excluded_subject_ids,omitempty"`
+	Paths              []accessPathObject `json:"paths"`
+}
+
+type accessPathObject struct {
+	Kind          string   `json:"kind"`
+	Caveated      bool     `json:"caveated"`
+	Relationships []string `json:"relationships"`
+}
+
+func newAccessReportObject(req *experimental.AccessReportRequest, entry *experimental.AccessReportEntry) accessReportObject {
+	paths := make([]accessPathObject, 0, len(entry.Paths))
+	for _, path := range entry.Paths {
+		paths = append(paths, accessPathObject{
+			Kind:          accessPathKind(path.Kind),
+			Caveated:      path.Caveated,
+			Relationships: accessPathRelationships(path),
+		})
+	}
+
+	return accessReportObject{
+		ResourceType:       req.ResourceObjectType,
+		ResourceID:         entry.ResourceObjectId,
+		Permission:         req.Permission,
+		SubjectType:        entry.Subject.Object.ObjectType,
+		SubjectID:          entry.Subject.Object.ObjectId,
+		SubjectRelation:    entry.Subject.OptionalRelation,
+		Permissionship:     accessReportPermissionship(entry.Permissionship),
+		ExcludedSubjectIDs: entry.ExcludedSubjectIds,
+		Paths:              paths,
+	}
+}
+
+func accessReportPermissionship(permissionship v1.LookupPermissionship) string {
+	if permissionship == v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION {
+		return "conditional"
+	}
+	return "has_permission"
+}
+
+func accessPathKind(kind experimental.AccessPath_Kind) string {
+	if kind == experimental.AccessPath_KIND_DIRECT {
+		return "direct"
+	}
+	return "indirect"
+}
+
+func accessPathRelationships(path *experimental.AccessPath) []string {
+	rels := make([]string, 0, len(path.Relationships))
+	for _, rel := range path.Relationships {
+		rels = append(rels, tuple.StringRelationship(rel))
+	}
+	return rels
+}
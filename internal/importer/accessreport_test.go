@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+const accessReportSchema = `definition user {}
+
+definition group {
+	relation member: user
+}
+
+definition document {
+	relation viewer: user | group#member
+	permission view = viewer
+}`
+
+func TestWriteAccessReport(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	_, err := v1.NewSchemaServiceClient(conn).WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: accessReportSchema})
+	require.NoError(err)
+	touch(t, v1.NewPermissionsServiceClient(conn),
+		"document:a#viewer@user:alice",
+		"document:a#viewer@group:eng#member",
+		"group:eng#member@user:bob",
+		"document:b#viewer@user:carol",
+		"document:c#viewer@user:alice",
+	)
+
+	client := experimental.NewExperimentalServiceClient(conn)
+	req := &experimental.AccessReportRequest{
+		ResourceObjectType: "document",
+		Permission:         "view",
+		SubjectObjectType:  "user",
+		OptionalPageSize:   1,
+	}
+
+	var report strings.Builder
+	result, err := WriteAccessReport(ctx, client, &report, FormatCSV, req)
+	require.NoError(err)
+	require.NotEmpty(result.Zookie)
+	require.Equal(uint64(4), result.Entries)
+	require.Equal(`resource_type,resource_id,permission,subject_type,subject_id,subject_relation,permissionship,excluded_subject_ids,path_kind,path_caveated,path
+document,a,view,user,alice,,has_permission,,direct,false,document:a#viewer@user:alice
+document,a,view,user,bob,,has_permission,,indirect,false,document:a#viewer@group:eng#member group:eng#member@user:bob
+document,b,view,user,carol,,has_permission,,direct,false,document:b#viewer@user:carol
+document,c,view,user,alice,,has_permission,,direct,false,document:c#viewer@user:alice
+`, report.String())
+
+	report.Reset()
+	_, err = WriteAccessReport(ctx, client, &report, FormatNDJSON, req)
+	require.NoError(err)
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	require.Len(lines, 4)
+	require.JSONEq(`{
+		"resource_type": "document",
+		"resource_id": "a",
+		"permission": "view",
+		"subject_type": "user",
+		"subject_id": "bob",
+		"permissionship": "has_permission",
+		"paths": [{
+			"kind": "indirect",
+			"caveated": false,
+			"relationships": ["document:a#viewer@group:eng#member", "group:eng#member@user:bob"]
+		}]
+	}`, lines[1])
+
+	_, err = WriteAccessReport(ctx, client, &report, FormatZanzibar, req)
+	require.EqualError(err, "unknown format `zanzibar`: expected csv or ndjson")
+}
@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+// ExportClient is a client of the services read by an export.
+type ExportClient interface {
+	v1.SchemaServiceClient
+	v1.PermissionsServiceClient
+}
+
+// ExportResult is the outcome of an export.
+type ExportResult struct {
+	// Zookie is the zedtoken at which relationships were exported, or empty if no
+	// relationships were found.
+	Zookie string
+
+	// Exported is the number of relationships exported.
+	Exported uint64
+}
+
+// Export writes every relationship of every definition in the schema of the server in the
+// Zanzibar format. If zookie is not empty, relationships are read at the revision of that
+// zedtoken; otherwise they are read fully consistent, at the revision of the first
+// relationship found, which is recorded as the zookie of the file.
+func Export(ctx context.Context, client ExportClient, w io.Writer, zookie string) (ExportResult, error) {
+	schema, err := client.ReadSchema(ctx, &v1.ReadSchemaRequest{})
+	if err != nil {
+		return ExportResult{}, fmt.Errorf("unable to read schema: %w", err)
+	}
+
+	emptyDefaultPrefix := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("schema"),
+		SchemaString: schema.SchemaText,
+	}, &emptyDefaultPrefix)
+	if err != nil {
+		return ExportResult{}, fmt.Errorf("unable to compile schema: %w", err)
+	}
+
+	exporter := &exporter{client: client, w: w, result: ExportResult{Zookie: zookie}}
+	if zookie != "" {
+		if err := exporter.writeHeader(); err != nil {
+			return exporter.result, err
+		}
+	}
+
+	// Until the first relationship is found, types are read fully consistent. Those found
+	// to be empty are read again at the revision of the first relationship, so that every
+	// type is exported at the same revision.
+	var emptyTypes []string
+	for _, definition := range compiled.ObjectDefinitions {
+		if err := exporter.exportType(ctx, definition.Name); err != nil {
+			return exporter.result, err
+		}
+
+		if exporter.result.Zookie == "" {
+			emptyTypes = append(emptyTypes, definition.Name)
+			continue
+		}
+
+		for _, resourceType := range emptyTypes {
+			if err := exporter.exportType(ctx, resourceType); err != nil {
+				return exporter.result, err
+			}
+		}
+		emptyTypes = nil
+	}
+
+	if exporter.zw == nil {
+		if err := exporter.writeHeader(); err != nil {
+			return exporter.result, err
+		}
+	}
+	if err := exporter.zw.Flush(); err != nil {
+		return exporter.result, fmt.Errorf("unable to write tuples: %w", err)
+	}
+	return exporter.result, nil
+}
+
+type exporter struct {
+	client ExportClient
+	w      io.Writer
+	zw     *ZanzibarWriter
+	result ExportResult
+}
+
+func (e *exporter) writeHeader() error {
+	zw, err := NewZanzibarWriter(e.w, e.result.Zookie)
+	if err != nil {
+		return fmt.Errorf("unable to write tuples: %w", err)
+	}
+	e.zw = zw
+	return nil
+}
+
+func (e *exporter) exportType(ctx context.Context, resourceType string) error {
+	consistency := &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}
+	if e.result.Zookie != "" {
+		consistency = &v1.Consistency{Requirement: &v1.Consistency_AtExactSnapshot{
+			AtExactSnapshot: &v1.ZedToken{Token: e.result.Zookie},
+		}}
+	}
+
+	stream, err := e.client.ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+		Consistency:        consistency,
+		RelationshipFilter: &v1.RelationshipFilter{ResourceType: resourceType},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to read relationships of `%s`: %w", resourceType, err)
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read relationships of `%s`: %w", resourceType, err)
+		}
+
+		if e.zw == nil {
+			e.result.Zookie = resp.ReadAt.GetToken()
+			if err := e.writeHeader(); err != nil {
+				return err
+			}
+		}
+
+		if err := e.zw.Write(resp.Relationship); err != nil {
+			return fmt.Errorf("unable to write tuples: %w", err)
+		}
+		e.result.Exported++
+	}
+}
@@ -0,0 +1,267 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/cenkalti/backoff/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+// DefaultBatchSize is the default number of relationships imported per bulk import.
+const DefaultBatchSize = 10_000
+
+// touchBatchSize is the number of relationships touched per write when a batch cannot be
+// bulk imported, which matches the default limit on updates per write of the server.
+const touchBatchSize = 1_000
+
+// relationshipSourceHeader is the request header of the source recorded for the
+// relationships written by a request.
+const relationshipSourceHeader = "io.spicedb.relationshipsource"
+
+// ImportClient is a client of the services an import writes relationships with.
+type ImportClient interface {
+	BulkImportRelationships(ctx context.Context, opts ...grpc.CallOption) (experimental.ExperimentalService_BulkImportRelationshipsClient, error)
+	WriteRelationships(ctx context.Context, in *v1.WriteRelationshipsRequest, opts ...grpc.CallOption) (*v1.WriteRelationshipsResponse, error)
+}
+
+type importClient struct {
+	experimental.ExperimentalServiceClient
+	v1.PermissionsServiceClient
+}
+
+// NewImportClient returns an ImportClient of the services served over the connection.
+func NewImportClient(conn grpc.ClientConnInterface) ImportClient {
+	return importClient{
+		ExperimentalServiceClient: experimental.NewExperimentalServiceClient(conn),
+		PermissionsServiceClient:  v1.NewPermissionsServiceClient(conn),
+	}
+}
+
+// Options configure an import.
+type Options struct {
+	// BatchSize is the number of relationships imported per bulk import.
+	BatchSize int
+
+	// MaxRetries is the number of times a batch is retried after a transient error before
+	// the import fails.
+	MaxRetries uint64
+
+	// CheckpointPath is the path of a file recording how many records have been written.
+	// If the file exists when the import starts, those records are skipped, so that a
+	// failed import can be resumed. It is removed once the import completes. If empty,
+	// progress is not recorded.
+	CheckpointPath string
+
+	// ProgressInterval is how often progress is logged. If zero, progress is not logged.
+	ProgressInterval time.Duration
+
+	// Source, if not empty, is recorded by the server as the source of the relationships
+	// written, so that they can be deleted together once the source is imported again.
+	Source string
+}
+
+// Result is the outcome of an import.
+type Result struct {
+	// Skipped is the number of records skipped because a checkpoint recorded them as
+	// already written.
+	Skipped uint64
+
+	// Written is the number of relationships written by this import.
+	Written uint64
+}
+
+type checkpoint struct {
+	Records uint64 `json:"records"`
+}
+
+// Import reads every relationship from the reader and bulk imports them to the server in
+// batches. A bulk import creates its relationships, so a batch of which any relationship
+// already exists, such as one retried after its import committed but its response was
+// lost, or one replayed after resuming from a checkpoint, is instead touched in writes
+// of at most touchBatchSize relationships.
+func Import(ctx context.Context, client ImportClient, reader Reader, opts Options) (Result, error) {
+	if opts.BatchSize < 1 {
+		return Result{}, errors.New("batch size must be at least 1")
+	}
+
+	if opts.Source != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, relationshipSourceHeader, opts.Source)
+	}
+
+	var result Result
+	if opts.CheckpointPath != "" {
+		resumed, err := readCheckpoint(opts.CheckpointPath)
+		if err != nil {
+			return result, err
+		}
+
+		for ; result.Skipped < resumed.Records; result.Skipped++ {
+			if _, err := reader.Read(); err != nil {
+				if errors.Is(err, io.EOF) {
+					return result, fmt.Errorf("checkpoint records %d records, but the input has only %d", resumed.Records, result.Skipped)
+				}
+				return result, err
+			}
+		}
+		if result.Skipped > 0 {
+			log.Ctx(ctx).Info().Uint64("skipped", result.Skipped).Msg("resuming import from checkpoint")
+		}
+	}
+
+	start := time.Now()
+	lastProgress := start
+	batch := make([]*v1.Relationship, 0, opts.BatchSize)
+	for {
+		rel, err := reader.Read()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return result, err
+		}
+		if rel != nil {
+			batch = append(batch, rel)
+		}
+
+		done := errors.Is(err, io.EOF)
+		if len(batch) == opts.BatchSize || (done && len(batch) > 0) {
+			if err := importBatch(ctx, client, batch, opts.MaxRetries); err != nil {
+				return result, err
+			}
+			result.Written += uint64(len(batch))
+			batch = batch[:0]
+
+			if opts.CheckpointPath != "" {
+				if err := writeCheckpoint(opts.CheckpointPath, checkpoint{Records: result.Skipped + result.Written}); err != nil {
+					return result, err
+				}
+			}
+
+			if opts.ProgressInterval > 0 && time.Since(lastProgress) >= opts.ProgressInterval {
+				lastProgress = time.Now()
+				log.Ctx(ctx).Info().
+					Uint64("written", result.Written).
+					Float64("perSecond", float64(result.Written)/time.Since(start).Seconds()).
+					Msg("import progress")
+			}
+		}
+
+		if done {
+			break
+		}
+	}
+
+	if opts.CheckpointPath != "" {
+		if err := os.Remove(opts.CheckpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return result, fmt.Errorf("unable to remove checkpoint: %w", err)
+		}
+	}
+	return result, nil
+}
+
+func importBatch(ctx context.Context, client ImportClient, batch []*v1.Relationship, maxRetries uint64) error {
+	err := retry(ctx, maxRetries, func() error {
+		stream, err := client.BulkImportRelationships(ctx)
+		if err != nil {
+			return err
+		}
+		// An error sending is that of the stream, which is returned by CloseAndRecv.
+		_ = stream.Send(&experimental.BulkImportRelationshipsRequest{Relationships: batch})
+		_, err = stream.CloseAndRecv()
+		return err
+	})
+	if status.Code(err) != codes.AlreadyExists {
+		return err
+	}
+
+	log.Ctx(ctx).Debug().Err(err).Msg("batch has existing relationships; touching them instead")
+	for start := 0; start < len(batch); start += touchBatchSize {
+		end := start + touchBatchSize
+		if end > len(batch) {
+			end = len(batch)
+		}
+
+		updates := make([]*v1.RelationshipUpdate, 0, end-start)
+		for _, rel := range batch[start:end] {
+			updates = append(updates, &v1.RelationshipUpdate{
+				Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+				Relationship: rel,
+			})
+		}
+		if err := retry(ctx, maxRetries, func() error {
+			_, err := client.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{Updates: updates})
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retry calls the function until it succeeds, returns an error which is not retryable,
+// or has been retried maxRetries times.
+func retry(ctx context.Context, maxRetries uint64, f func() error) error {
+	policy := backoff.WithContext(backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries), ctx)
+	return backoff.RetryNotify(func() error {
+		err := f()
+		if err != nil && !isRetryable(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}, policy, func(err error, wait time.Duration) {
+		log.Ctx(ctx).Warn().Err(err).Stringer("retryIn", wait).Msg("unable to write relationships; retrying")
+	})
+}
+
+func isRetryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+func readCheckpoint(path string) (checkpoint, error) {
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint{}, nil
+	}
+	if err != nil {
+		return checkpoint{}, fmt.Errorf("unable to read checkpoint: %w", err)
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(contents, &cp); err != nil {
+		return checkpoint{}, fmt.Errorf("invalid checkpoint `%s`: %w", path, err)
+	}
+	return cp, nil
+}
+
+// writeCheckpoint replaces the checkpoint by renaming, so that an interrupted import
+// never leaves a partially written checkpoint behind.
+func writeCheckpoint(path string, cp checkpoint) error {
+	contents, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, contents, 0o600); err != nil {
+		return fmt.Errorf("unable to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("unable to write checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestParseMapping(t *testing.T) {
+	testCases := []struct {
+		name     string
+		spec     string
+		expected map[Field]Source
+		err      string
+	}{
+		{"empty", "", nil, ""},
+		{
+			"columns and literals",
+			"resource_type='document', resource_id=doc_id,subject_id=user",
+			map[Field]Source{
+				FieldResourceType: {Literal: "document"},
+				FieldResourceID:   {Column: "doc_id"},
+				FieldSubjectID:    {Column: "user"},
+			},
+			"",
+		},
+		{"unknown field", "resource=doc", nil, "unknown field `resource`"},
+		{"missing column", "resource_id=", nil, "missing column for field `resource_id`"},
+		{"not a pair", "resource_id", nil, "expected field=column"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mapping, err := ParseMapping(tc.spec)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			expected := DefaultMapping()
+			for field, source := range tc.expected {
+				expected[field] = source
+			}
+			require.Equal(t, expected, mapping)
+		})
+	}
+}
+
+func TestReaders(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   Format
+		mapping  string
+		input    string
+		expected []string
+		err      string
+	}{
+		{
+			"csv",
+			FormatCSV,
+			"",
+			`resource_type,resource_id,relation,subject_type,subject_id,subject_relation
+document,first,viewer,user,tom,
+document,first,viewer,group,eng,member
+`,
+			[]string{"document:first#viewer@user:tom", "document:first#viewer@group:eng#member"},
+			"",
+		},
+		{
+			"csv with mapping and caveat",
+			FormatCSV,
+			"resource_type='document',resource_id=doc,relation='viewer',subject_type='user',subject_id=user,caveat_name=caveat,caveat_context=ctx",
+			`doc,user,caveat,ctx
+first,tom,,
+second,fred,only_on,"{""day"": 1}"
+`,
+			[]string{"document:first#viewer@user:tom", "document:second#viewer@user:fred[only_on:{\"day\":1}]"},
+			"",
+		},
+		{
+			"csv missing column",
+			FormatCSV,
+			"",
+			"resource_type,resource_id\n",
+			nil,
+			"CSV header has no column `relation`",
+		},
+		{
+			"csv missing value",
+			FormatCSV,
+			"",
+			"resource_type,resource_id,relation,subject_type,subject_id\ndocument,first,,user,tom\n",
+			nil,
+			"record 1: missing value for `relation`",
+		},
+		{
+			"ndjson",
+			FormatNDJSON,
+			"",
+			`{"resource_type": "document", "resource_id": 42, "relation": "viewer", "subject_type": "user", "subject_id": "tom"}
+
+{"resource_type": "document", "resource_id": "2", "relation": "viewer", "subject_type": "user", "subject_id": "fred", "caveat_name": "only_on", "caveat_context": {"day": 1}}
+`,
+			[]string{"document:42#viewer@user:tom", "document:2#viewer@user:fred[only_on:{\"day\":1}]"},
+			"",
+		},
+		{
+			"ndjson invalid",
+			FormatNDJSON,
+			"",
+			"{\"resource_type\": \"document\"\n",
+			nil,
+			"line 1: invalid JSON object",
+		},
+		{
+			"ndjson invalid relationship",
+			FormatNDJSON,
+			"",
+			`{"resource_type": "document", "resource_id": "a b", "relation": "viewer", "subject_type": "user", "subject_id": "tom"}`,
+			nil,
+			"line 1: invalid relationship",
+		},
+		{
+			"zanzibar",
+			FormatZanzibar,
+			"",
+			`# zanzibar-tuples v1
+# zookie: sometoken
+
+document:readme#owner@user:10
+document:readme#parent@folder:a#...
+document:readme#viewer@group:eng#member
+document:readme#viewer@user:*
+document:readme#viewer@user:fred[only_on:{"day":1}]
+document:readme#viewer@user:tom[only_on]
+`,
+			[]string{
+				"document:readme#owner@user:10",
+				"document:readme#parent@folder:a",
+				"document:readme#viewer@group:eng#member",
+				"document:readme#viewer@user:*",
+				"document:readme#viewer@user:fred[only_on:{\"day\":1}]",
+				"document:readme#viewer@user:tom[only_on:{}]",
+			},
+			"",
+		},
+		{
+			"zanzibar bare user ids",
+			FormatZanzibar,
+			"subject_type='user'",
+			"document:readme#owner@10\n",
+			[]string{"document:readme#owner@user:10"},
+			"",
+		},
+		{
+			"zanzibar bare user id without default type",
+			FormatZanzibar,
+			"",
+			"document:readme#owner@10\n",
+			nil,
+			"line 1: user `10` has no type",
+		},
+		{
+			"zanzibar unsupported version",
+			FormatZanzibar,
+			"",
+			"# zanzibar-tuples v2\ndocument:readme#owner@user:10\n",
+			nil,
+			"line 1: unsupported format version `v2`",
+		},
+		{
+			"zanzibar invalid tuple",
+			FormatZanzibar,
+			"",
+			"document:readme@user:10\n",
+			nil,
+			"line 1: invalid tuple",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			mapping, err := ParseMapping(tc.mapping)
+			require.NoError(t, err)
+
+			reader, err := NewReader(tc.format, strings.NewReader(tc.input), mapping)
+			if err == nil {
+				var read []string
+				read, err = readAll(reader)
+				if err == nil {
+					require.Equal(t, tc.expected, read)
+				}
+			}
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func readAll(reader Reader) ([]string, error) {
+	var read []string
+	for {
+		rel, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return read, nil
+			}
+			return nil, err
+		}
+
+		relString := tuple.StringRelationship(rel)
+		if rel.OptionalCaveat != nil {
+			caveatContext, err := json.Marshal(rel.OptionalCaveat.Context.AsMap())
+			if err != nil {
+				return nil, err
+			}
+			relString += fmt.Sprintf("[%s:%s]", rel.OptionalCaveat.CaveatName, caveatContext)
+		}
+		read = append(read, relString)
+	}
+}
+
+func TestImport(t *testing.T) {
+	require := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	_, err := v1.NewSchemaServiceClient(conn).WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: `definition user {}
+
+definition document {
+	relation viewer: user
+}`})
+	require.NoError(err)
+
+	var input strings.Builder
+	input.WriteString("resource_id,subject_id\n")
+	for i := 0; i < 25; i++ {
+		input.WriteString("doc" + string(rune('a'+i)) + ",tom\n")
+	}
+	mapping, err := ParseMapping("resource_type='document',relation='viewer',subject_type='user'")
+	require.NoError(err)
+
+	// Resume from a checkpoint left behind by an earlier import of the first ten records,
+	// which was interrupted after it imported the twelfth.
+	checkpointPath := filepath.Join(t.TempDir(), "import.checkpoint")
+	require.NoError(os.WriteFile(checkpointPath, []byte(`{"records": 10}`), 0o600))
+
+	client := v1.NewPermissionsServiceClient(conn)
+	_, err = client.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{Updates: []*v1.RelationshipUpdate{{
+		Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+		Relationship: tuple.MustToRelationship(tuple.MustParse("document:docl#viewer@user:tom")),
+	}}})
+	require.NoError(err)
+
+	reader, err := NewCSVReader(strings.NewReader(input.String()), mapping)
+	require.NoError(err)
+	result, err := Import(ctx, NewImportClient(conn), reader, Options{BatchSize: 4, CheckpointPath: checkpointPath})
+	require.NoError(err)
+	require.Equal(Result{Skipped: 10, Written: 15}, result)
+	require.NoFileExists(checkpointPath)
+
+	stream, err := client.ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+		Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+		RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document"},
+	})
+	require.NoError(err)
+	count := 0
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+		count++
+	}
+	require.Equal(15, count)
+}
+
+type flakyClient struct {
+	errs    []error
+	imports int
+	writes  int
+}
+
+func (fc *flakyClient) nextErr() error {
+	if len(fc.errs) == 0 {
+		return nil
+	}
+	err := fc.errs[0]
+	fc.errs = fc.errs[1:]
+	return err
+}
+
+func (fc *flakyClient) BulkImportRelationships(_ context.Context, _ ...grpc.CallOption) (experimental.ExperimentalService_BulkImportRelationshipsClient, error) {
+	fc.imports++
+	return &flakyImportStream{err: fc.nextErr()}, nil
+}
+
+func (fc *flakyClient) WriteRelationships(_ context.Context, _ *v1.WriteRelationshipsRequest, _ ...grpc.CallOption) (*v1.WriteRelationshipsResponse, error) {
+	fc.writes++
+	if err := fc.nextErr(); err != nil {
+		return nil, err
+	}
+	return &v1.WriteRelationshipsResponse{}, nil
+}
+
+type flakyImportStream struct {
+	grpc.ClientStream
+	err error
+}
+
+func (fs *flakyImportStream) Send(*experimental.BulkImportRelationshipsRequest) error {
+	return nil
+}
+
+func (fs *flakyImportStream) CloseAndRecv() (*experimental.BulkImportRelationshipsResponse, error) {
+	if fs.err != nil {
+		return nil, fs.err
+	}
+	return &experimental.BulkImportRelationshipsResponse{}, nil
+}
+
+func TestImportRetries(t *testing.T) {
+	input := `resource_type,resource_id,relation,subject_type,subject_id
+document,first,viewer,user,tom
+document,second,viewer,user,tom
+document,third,viewer,user,tom
+`
+	checkpointPath := filepath.Join(t.TempDir(), "import.checkpoint")
+
+	// A transient error is retried.
+	reader, err := NewCSVReader(strings.NewReader(input), DefaultMapping())
+	require.NoError(t, err)
+	client := &flakyClient{errs: []error{nil, status.Error(codes.Unavailable, "unavailable")}}
+	result, err := Import(context.Background(), client, reader, Options{BatchSize: 2, MaxRetries: 1})
+	require.NoError(t, err)
+	require.Equal(t, Result{Written: 3}, result)
+	require.Equal(t, 3, client.imports)
+	require.Zero(t, client.writes)
+
+	// A batch with existing relationships is touched instead.
+	reader, err = NewCSVReader(strings.NewReader(input), DefaultMapping())
+	require.NoError(t, err)
+	client = &flakyClient{errs: []error{status.Error(codes.AlreadyExists, "exists")}}
+	result, err = Import(context.Background(), client, reader, Options{BatchSize: 2})
+	require.NoError(t, err)
+	require.Equal(t, Result{Written: 3}, result)
+	require.Equal(t, 2, client.imports)
+	require.Equal(t, 1, client.writes)
+
+	// Any other error fails the import, leaving the checkpoint at the last written batch.
+	reader, err = NewCSVReader(strings.NewReader(input), DefaultMapping())
+	require.NoError(t, err)
+	client = &flakyClient{errs: []error{nil, status.Error(codes.InvalidArgument, "invalid")}}
+	_, err = Import(context.Background(), client, reader, Options{BatchSize: 2, MaxRetries: 5, CheckpointPath: checkpointPath})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, 2, client.imports)
+
+	contents, err := os.ReadFile(checkpointPath)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"records": 2}`, string(contents))
+}
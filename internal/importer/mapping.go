@@ -0,0 +1,95 @@
+// Package importer reads relationships from CSV, NDJSON and Zanzibar tuple files and writes
+// them to a running server in batches, and exports relationships as Zanzibar tuples.
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Field is a part of a relationship which is read from a record.
+type Field string
+
+const (
+	FieldResourceType    Field = "resource_type"
+	FieldResourceID      Field = "resource_id"
+	FieldRelation        Field = "relation"
+	FieldSubjectType     Field = "subject_type"
+	FieldSubjectID       Field = "subject_id"
+	FieldSubjectRelation Field = "subject_relation"
+	FieldCaveatName      Field = "caveat_name"
+	FieldCaveatContext   Field = "caveat_context"
+)
+
+var (
+	requiredFields = []Field{FieldResourceType, FieldResourceID, FieldRelation, FieldSubjectType, FieldSubjectID}
+	optionalFields = []Field{FieldSubjectRelation, FieldCaveatName, FieldCaveatContext}
+)
+
+// Source is where the value of a field comes from: either a column of the record, or a
+// literal value shared by every record.
+type Source struct {
+	Column  string
+	Literal string
+}
+
+// IsLiteral returns true if the source is a literal value.
+func (s Source) IsLiteral() bool {
+	return s.Column == ""
+}
+
+// Mapping maps the fields of a relationship to the columns of a CSV file or the keys of
+// an NDJSON object. Optional fields which are not mapped are left empty.
+type Mapping map[Field]Source
+
+// DefaultMapping maps every field to the column or key of the same name.
+func DefaultMapping() Mapping {
+	mapping := make(Mapping, len(requiredFields)+len(optionalFields))
+	for _, field := range append(append([]Field{}, requiredFields...), optionalFields...) {
+		mapping[field] = Source{Column: string(field)}
+	}
+	return mapping
+}
+
+// ParseMapping parses a comma-separated list of `field=column` pairs. A value wrapped in
+// single quotes, such as `resource_type='document'`, is a literal used for every record.
+// Fields which are not listed keep their default mapping to the column of the same name.
+func ParseMapping(spec string) (Mapping, error) {
+	mapping := DefaultMapping()
+	if strings.TrimSpace(spec) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mapping `%s`: expected field=column", pair)
+		}
+
+		field := Field(strings.TrimSpace(name))
+		if _, ok := mapping[field]; !ok {
+			return nil, fmt.Errorf("unknown field `%s` in mapping: expected one of %s", field, fieldNames())
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'"):
+			mapping[field] = Source{Literal: value[1 : len(value)-1]}
+		case value == "":
+			return nil, fmt.Errorf("missing column for field `%s` in mapping", field)
+		default:
+			mapping[field] = Source{Column: value}
+		}
+	}
+	return mapping, nil
+}
+
+func fieldNames() string {
+	names := make([]string, 0, len(requiredFields)+len(optionalFields))
+	for _, field := range append(append([]Field{}, requiredFields...), optionalFields...) {
+		names = append(names, string(field))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
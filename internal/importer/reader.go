@@ -0,0 +1,224 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// maxNDJSONLineSize is the size of the longest line accepted in an NDJSON file.
+const maxNDJSONLineSize = 1024 * 1024
+
+// Format is the format of a file being imported.
+type Format string
+
+const (
+	FormatCSV      Format = "csv"
+	FormatNDJSON   Format = "ndjson"
+	FormatZanzibar Format = "zanzibar"
+)
+
+// Reader reads relationships from a file.
+type Reader interface {
+	// Read returns the next relationship, or io.EOF once the file has been read.
+	Read() (*v1.Relationship, error)
+}
+
+// NewReader returns a reader for a file in the given format.
+func NewReader(format Format, r io.Reader, mapping Mapping) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVReader(r, mapping)
+	case FormatNDJSON:
+		return NewNDJSONReader(r, mapping), nil
+	case FormatZanzibar:
+		// Tuples name every field themselves, so only a literal subject type applies, as the
+		// type of users given as a bare ID.
+		var defaultSubjectType string
+		if source := mapping[FieldSubjectType]; source.IsLiteral() {
+			defaultSubjectType = source.Literal
+		}
+		return NewZanzibarReader(r, defaultSubjectType), nil
+	default:
+		return nil, fmt.Errorf("unknown format `%s`: expected csv, ndjson or zanzibar", format)
+	}
+}
+
+// CSVReader reads relationships from a CSV file with a header row naming its columns.
+type CSVReader struct {
+	csv     *csv.Reader
+	mapping Mapping
+	columns map[string]int
+	record  int
+}
+
+// NewCSVReader reads the header row of the CSV file, and returns an error if it lacks a
+// column required by the mapping.
+func NewCSVReader(r io.Reader, mapping Mapping) (*CSVReader, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.TrimSpace(column)] = i
+	}
+	for _, field := range requiredFields {
+		source := mapping[field]
+		if _, ok := columns[source.Column]; !source.IsLiteral() && !ok {
+			return nil, fmt.Errorf("CSV header has no column `%s` for field `%s`", source.Column, field)
+		}
+	}
+
+	return &CSVReader{csv: reader, mapping: mapping, columns: columns}, nil
+}
+
+func (cr *CSVReader) Read() (*v1.Relationship, error) {
+	row, err := cr.csv.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("unable to read CSV: %w", err)
+	}
+	cr.record++
+
+	rel, err := toRelationship(cr.mapping, func(column string) (string, bool) {
+		i, ok := cr.columns[column]
+		if !ok {
+			return "", false
+		}
+		return row[i], true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("record %d: %w", cr.record, err)
+	}
+	return rel, nil
+}
+
+// NDJSONReader reads relationships from a file containing one JSON object per line.
+// Values must be strings or numbers, except for a caveat context, which may be an object.
+type NDJSONReader struct {
+	scanner *bufio.Scanner
+	mapping Mapping
+	line    int
+}
+
+// NewNDJSONReader returns a reader for an NDJSON file. Blank lines are skipped.
+func NewNDJSONReader(r io.Reader, mapping Mapping) *NDJSONReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineSize)
+	return &NDJSONReader{scanner: scanner, mapping: mapping}
+}
+
+func (nr *NDJSONReader) Read() (*v1.Relationship, error) {
+	for nr.scanner.Scan() {
+		nr.line++
+		line := bytes.TrimSpace(nr.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		var object map[string]any
+		if err := decoder.Decode(&object); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON object: %w", nr.line, err)
+		}
+
+		var valueErr error
+		rel, err := toRelationship(nr.mapping, func(key string) (string, bool) {
+			switch value := object[key].(type) {
+			case nil:
+				return "", false
+			case string:
+				return value, true
+			case json.Number:
+				return value.String(), true
+			case map[string]any:
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					valueErr = err
+				}
+				return string(encoded), true
+			default:
+				valueErr = fmt.Errorf("unsupported value for key `%s`: %v", key, value)
+				return "", false
+			}
+		})
+		if valueErr != nil {
+			return nil, fmt.Errorf("line %d: %w", nr.line, valueErr)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", nr.line, err)
+		}
+		return rel, nil
+	}
+
+	if err := nr.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read NDJSON: %w", err)
+	}
+	return nil, io.EOF
+}
+
+func toRelationship(mapping Mapping, lookup func(column string) (string, bool)) (*v1.Relationship, error) {
+	values := make(map[Field]string, len(mapping))
+	for field, source := range mapping {
+		if source.IsLiteral() {
+			values[field] = source.Literal
+			continue
+		}
+		if value, ok := lookup(source.Column); ok {
+			values[field] = strings.TrimSpace(value)
+		}
+	}
+
+	for _, field := range requiredFields {
+		if values[field] == "" {
+			return nil, fmt.Errorf("missing value for `%s`", field)
+		}
+	}
+
+	rel := &v1.Relationship{
+		Resource: &v1.ObjectReference{ObjectType: values[FieldResourceType], ObjectId: values[FieldResourceID]},
+		Relation: values[FieldRelation],
+		Subject: &v1.SubjectReference{
+			Object:           &v1.ObjectReference{ObjectType: values[FieldSubjectType], ObjectId: values[FieldSubjectID]},
+			OptionalRelation: values[FieldSubjectRelation],
+		},
+	}
+
+	if caveatName := values[FieldCaveatName]; caveatName != "" {
+		rel.OptionalCaveat = &v1.ContextualizedCaveat{CaveatName: caveatName}
+		if caveatContext := values[FieldCaveatContext]; caveatContext != "" {
+			var contextMap map[string]any
+			if err := json.Unmarshal([]byte(caveatContext), &contextMap); err != nil {
+				return nil, fmt.Errorf("invalid caveat context: %w", err)
+			}
+			structContext, err := structpb.NewStruct(contextMap)
+			if err != nil {
+				return nil, fmt.Errorf("invalid caveat context: %w", err)
+			}
+			rel.OptionalCaveat.Context = structContext
+		}
+	} else if values[FieldCaveatContext] != "" {
+		return nil, errors.New("caveat context given without a caveat name")
+	}
+
+	if err := rel.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid relationship: %w", err)
+	}
+	return rel, nil
+}
@@ -0,0 +1,231 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// The Zanzibar format holds one relationship per line in the tuple notation of the
+// Zanzibar paper, preceded by a header naming the version of the format:
+//
+//	# zanzibar-tuples v1
+//	# zookie: GhUKEzE2NzA1NTQzMzU5MTI3NzgwMDA=
+//	document:readme#owner@user:10
+//	document:readme#parent@folder:a#...
+//	document:readme#viewer@group:eng#member
+//	document:readme#viewer@user:*
+//	document:readme#viewer@user:fred[only_on:{"day":1}]
+//
+// Each tuple is written as `object#relation@user`, where the object is `type:id` and the
+// user is either a subject `type:id` or a userset `type:id#relation`. A userset relation
+// of `...` refers to the subject itself. Users given as a bare ID, without a type, are
+// read as subjects of the default subject type, if one was given. A relationship with a
+// caveat is followed by the caveat name and optionally its JSON context in brackets.
+//
+// Lines starting with `#` are comments, and blank lines are ignored. The optional zookie
+// comment records the zedtoken at which relationships were exported; it identifies a
+// revision of the source and is not used when importing. A file without a version header
+// is read as version 1, so that bare lists of tuples can be imported.
+const (
+	ZanzibarVersion = "v1"
+
+	zanzibarHeaderPrefix = "zanzibar-tuples "
+	zanzibarZookiePrefix = "zookie: "
+	ellipsisRelation     = "..."
+)
+
+// ZanzibarReader reads relationships from a file in the Zanzibar format.
+type ZanzibarReader struct {
+	scanner            *bufio.Scanner
+	defaultSubjectType string
+	line               int
+	zookie             string
+}
+
+// NewZanzibarReader returns a reader for a file in the Zanzibar format. If not empty,
+// defaultSubjectType is the type of users given as a bare ID.
+func NewZanzibarReader(r io.Reader, defaultSubjectType string) *ZanzibarReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineSize)
+	return &ZanzibarReader{scanner: scanner, defaultSubjectType: defaultSubjectType}
+}
+
+// Zookie returns the zookie recorded in the header of the file, if it has been read.
+func (zr *ZanzibarReader) Zookie() string {
+	return zr.zookie
+}
+
+func (zr *ZanzibarReader) Read() (*v1.Relationship, error) {
+	for zr.scanner.Scan() {
+		zr.line++
+		line := strings.TrimSpace(zr.scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			if err := zr.readComment(strings.TrimSpace(line[1:])); err != nil {
+				return nil, fmt.Errorf("line %d: %w", zr.line, err)
+			}
+			continue
+		}
+
+		rel, err := ParseZanzibarTuple(line, zr.defaultSubjectType)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", zr.line, err)
+		}
+		return rel, nil
+	}
+
+	if err := zr.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read tuples: %w", err)
+	}
+	return nil, io.EOF
+}
+
+func (zr *ZanzibarReader) readComment(comment string) error {
+	if strings.HasPrefix(comment, zanzibarHeaderPrefix) {
+		if version := strings.TrimSpace(comment[len(zanzibarHeaderPrefix):]); version != ZanzibarVersion {
+			return fmt.Errorf("unsupported format version `%s`: expected %s", version, ZanzibarVersion)
+		}
+		return nil
+	}
+	if strings.HasPrefix(comment, zanzibarZookiePrefix) {
+		zr.zookie = strings.TrimSpace(comment[len(zanzibarZookiePrefix):])
+	}
+	return nil
+}
+
+// ParseZanzibarTuple parses a single relationship in the Zanzibar format. If not empty,
+// defaultSubjectType is the type of a user given as a bare ID.
+func ParseZanzibarTuple(line, defaultSubjectType string) (*v1.Relationship, error) {
+	tpl, caveat, err := splitCaveat(line)
+	if err != nil {
+		return nil, err
+	}
+
+	object, user, ok := strings.Cut(tpl, "@")
+	if !ok {
+		return nil, fmt.Errorf("invalid tuple `%s`: expected object#relation@user", tpl)
+	}
+	object, relation, ok := strings.Cut(object, "#")
+	if !ok {
+		return nil, fmt.Errorf("invalid tuple `%s`: expected object#relation@user", tpl)
+	}
+	objectType, objectID, ok := strings.Cut(object, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid object `%s`: expected type:id", object)
+	}
+
+	user, userRelation, _ := strings.Cut(user, "#")
+	if userRelation == ellipsisRelation {
+		userRelation = ""
+	}
+	userType, userID, ok := strings.Cut(user, ":")
+	if !ok {
+		if defaultSubjectType == "" {
+			return nil, fmt.Errorf("user `%s` has no type and no default subject type was given", user)
+		}
+		userType, userID = defaultSubjectType, user
+	}
+
+	rel := &v1.Relationship{
+		Resource: &v1.ObjectReference{ObjectType: objectType, ObjectId: objectID},
+		Relation: relation,
+		Subject: &v1.SubjectReference{
+			Object:           &v1.ObjectReference{ObjectType: userType, ObjectId: userID},
+			OptionalRelation: userRelation,
+		},
+		OptionalCaveat: caveat,
+	}
+	if err := rel.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid relationship: %w", err)
+	}
+	return rel, nil
+}
+
+// splitCaveat splits the optional caveat in brackets from the end of a tuple.
+func splitCaveat(line string) (string, *v1.ContextualizedCaveat, error) {
+	start := strings.IndexByte(line, '[')
+	if start < 0 {
+		return line, nil, nil
+	}
+	if !strings.HasSuffix(line, "]") {
+		return "", nil, errors.New("invalid caveat: expected closing bracket")
+	}
+
+	name, caveatContext, hasContext := strings.Cut(line[start+1:len(line)-1], ":")
+	caveat := &v1.ContextualizedCaveat{CaveatName: name}
+	if hasContext {
+		var contextMap map[string]any
+		if err := json.Unmarshal([]byte(caveatContext), &contextMap); err != nil {
+			return "", nil, fmt.Errorf("invalid caveat context: %w", err)
+		}
+		structContext, err := structpb.NewStruct(contextMap)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid caveat context: %w", err)
+		}
+		caveat.Context = structContext
+	}
+	return line[:start], caveat, nil
+}
+
+// ZanzibarWriter writes relationships to a file in the Zanzibar format.
+type ZanzibarWriter struct {
+	w *bufio.Writer
+}
+
+// NewZanzibarWriter writes the header of the file, recording the zookie if not empty.
+func NewZanzibarWriter(w io.Writer, zookie string) (*ZanzibarWriter, error) {
+	zw := &ZanzibarWriter{bufio.NewWriter(w)}
+	if _, err := fmt.Fprintf(zw.w, "# %s%s\n", zanzibarHeaderPrefix, ZanzibarVersion); err != nil {
+		return nil, err
+	}
+	if zookie != "" {
+		if _, err := fmt.Fprintf(zw.w, "# %s%s\n", zanzibarZookiePrefix, zookie); err != nil {
+			return nil, err
+		}
+	}
+	return zw, nil
+}
+
+// Write writes a relationship as a single line.
+func (zw *ZanzibarWriter) Write(rel *v1.Relationship) error {
+	if _, err := zw.w.WriteString(tuple.StringRelationship(rel)); err != nil {
+		return err
+	}
+
+	if rel.OptionalCaveat != nil {
+		if _, err := fmt.Fprintf(zw.w, "[%s", rel.OptionalCaveat.CaveatName); err != nil {
+			return err
+		}
+		if len(rel.OptionalCaveat.Context.GetFields()) > 0 {
+			caveatContext, err := json.Marshal(rel.OptionalCaveat.Context.AsMap())
+			if err != nil {
+				return fmt.Errorf("unable to encode caveat context: %w", err)
+			}
+			if _, err := fmt.Fprintf(zw.w, ":%s", caveatContext); err != nil {
+				return err
+			}
+		}
+		if err := zw.w.WriteByte(']'); err != nil {
+			return err
+		}
+	}
+
+	return zw.w.WriteByte('\n')
+}
+
+// Flush writes any buffered relationships.
+func (zw *ZanzibarWriter) Flush() error {
+	return zw.w.Flush()
+}
@@ -0,0 +1,214 @@
+// Package jobs runs long-running administrative operations in the background, storing
+// their state in the datastore so that they can be followed and canceled from any server
+// rather than holding a single call open for their duration.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+// DefaultHeartbeatInterval is the default interval at which the progress of running jobs is
+// stored and their cancellation requests are read.
+const DefaultHeartbeatInterval = 5 * time.Second
+
+// staleHeartbeats is the number of heartbeats a running job may miss before it is reported
+// as interrupted, such as by its server stopping.
+const staleHeartbeats = 3
+
+// ErrInterrupted is the error of jobs whose server stopped while they were running.
+var ErrInterrupted = errors.New("job was interrupted before completing")
+
+// Func runs a job until it completes or its context is canceled, reporting the progress
+// made so far, and returns its result.
+type Func func(ctx context.Context, progress func(uint64)) ([]byte, error)
+
+// Runner runs jobs in the background of a server.
+type Runner struct {
+	heartbeatInterval time.Duration
+
+	lock    sync.Mutex
+	running map[string]*runningJob
+	wg      sync.WaitGroup
+}
+
+type runningJob struct {
+	cancel      context.CancelFunc
+	canceled    atomic.Bool
+	interrupted atomic.Bool
+}
+
+// NewRunner returns a Runner storing the progress of its jobs at the given interval. An
+// interval of zero uses DefaultHeartbeatInterval.
+func NewRunner(heartbeatInterval time.Duration) *Runner {
+	if heartbeatInterval <= 0 {
+		heartbeatInterval = DefaultHeartbeatInterval
+	}
+	return &Runner{heartbeatInterval: heartbeatInterval, running: make(map[string]*runningJob)}
+}
+
+// Start stores a new job of the kind with the given parameters and runs it in the
+// background, returning once it is stored. The job outlives the context given.
+func (r *Runner) Start(ctx context.Context, store common.JobStore, kind string, parameters []byte, run Func) (common.Job, error) {
+	job := common.Job{
+		ID:         uuid.NewString(),
+		Kind:       kind,
+		Parameters: parameters,
+		State:      common.JobRunning,
+	}
+	if err := store.CreateJob(ctx, job); err != nil {
+		return common.Job{}, err
+	}
+
+	jobCtx, cancel := context.WithCancel(log.Ctx(ctx).WithContext(context.Background()))
+	running := &runningJob{cancel: cancel}
+	r.lock.Lock()
+	r.running[job.ID] = running
+	r.lock.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			r.lock.Lock()
+			delete(r.running, job.ID)
+			r.lock.Unlock()
+			cancel()
+		}()
+		r.run(jobCtx, store, job, running, run)
+	}()
+
+	return store.ReadJob(ctx, job.ID)
+}
+
+func (r *Runner) run(ctx context.Context, store common.JobStore, job common.Job, running *runningJob, run Func) {
+	var progress atomic.Uint64
+	done := make(chan struct{})
+	heartbeatsStopped := make(chan struct{})
+	go func() {
+		defer close(heartbeatsStopped)
+		ticker := time.NewTicker(r.heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				r.heartbeat(ctx, store, job, running, progress.Load())
+			}
+		}
+	}()
+
+	result, err := run(ctx, func(made uint64) { progress.Store(made) })
+	close(done)
+	<-heartbeatsStopped
+
+	job.Progress = progress.Load()
+	switch {
+	case err == nil:
+		job.State = common.JobSucceeded
+		job.Result = result
+	case running.canceled.Load():
+		job.State = common.JobCanceled
+	case running.interrupted.Load():
+		job.State = common.JobFailed
+		job.Error = ErrInterrupted.Error()
+	default:
+		job.State = common.JobFailed
+		job.Error = err.Error()
+	}
+
+	// The job's context may be canceled, while its final state must still be stored.
+	if err := store.UpdateJob(context.Background(), job); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("job", job.ID).Msg("unable to store the final state of job")
+	}
+	log.Ctx(ctx).Info().Str("job", job.ID).Str("kind", job.Kind).Str("state", string(job.State)).Uint64("progress", job.Progress).Msg("job completed")
+}
+
+// heartbeat stores the progress of a running job, and cancels it if its cancellation has
+// been requested from another server.
+func (r *Runner) heartbeat(ctx context.Context, store common.JobStore, job common.Job, running *runningJob, progress uint64) {
+	job.Progress = progress
+	if err := store.UpdateJob(ctx, job); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("job", job.ID).Msg("unable to store the progress of job")
+		return
+	}
+
+	stored, err := store.ReadJob(ctx, job.ID)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Str("job", job.ID).Msg("unable to read job")
+		return
+	}
+	if stored.CancelRequested {
+		running.canceled.Store(true)
+		running.cancel()
+	}
+}
+
+// Read returns a job. Running jobs whose progress has not been stored for several
+// heartbeats are returned as failed, as their server stopped before they completed.
+func (r *Runner) Read(ctx context.Context, store common.JobStore, id string) (common.Job, error) {
+	job, err := store.ReadJob(ctx, id)
+	if err != nil {
+		return common.Job{}, err
+	}
+	return r.reportInterrupted(job), nil
+}
+
+// List returns up to limit jobs, or all of them if limit is zero, most recently created
+// first.
+func (r *Runner) List(ctx context.Context, store common.JobStore, limit uint64) ([]common.Job, error) {
+	jobs, err := store.ListJobs(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs {
+		jobs[i] = r.reportInterrupted(jobs[i])
+	}
+	return jobs, nil
+}
+
+func (r *Runner) reportInterrupted(job common.Job) common.Job {
+	if job.State == common.JobRunning && time.Since(job.UpdatedAt) > staleHeartbeats*r.heartbeatInterval {
+		job.State = common.JobFailed
+		job.Error = ErrInterrupted.Error()
+	}
+	return job
+}
+
+// Cancel requests a job to be canceled. It is canceled immediately if run by this
+// Runner, and otherwise at the next heartbeat of the server running it.
+func (r *Runner) Cancel(ctx context.Context, store common.JobStore, id string) (common.Job, error) {
+	if err := store.RequestJobCancellation(ctx, id); err != nil {
+		return common.Job{}, err
+	}
+
+	r.lock.Lock()
+	if running, ok := r.running[id]; ok {
+		running.canceled.Store(true)
+		running.cancel()
+	}
+	r.lock.Unlock()
+
+	return r.Read(ctx, store, id)
+}
+
+// Close interrupts the running jobs and waits for their state to be stored.
+func (r *Runner) Close() {
+	r.lock.Lock()
+	for _, running := range r.running {
+		running.interrupted.Store(true)
+		running.cancel()
+	}
+	r.lock.Unlock()
+
+	r.wg.Wait()
+}
@@ -0,0 +1,155 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+func newStore(t *testing.T) common.JobStore {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	t.Cleanup(func() { ds.Close() })
+
+	store, ok := datastore.UnwrapAs[common.JobStore](ds)
+	require.True(t, ok)
+	return store
+}
+
+func waitForState(t *testing.T, runner *Runner, store common.JobStore, id string, state common.JobState) common.Job {
+	var job common.Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = runner.Read(context.Background(), store, id)
+		require.NoError(t, err)
+		return job.State == state
+	}, 5*time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestRunnerSucceeded(t *testing.T) {
+	store := newStore(t)
+	runner := NewRunner(10 * time.Millisecond)
+	defer runner.Close()
+
+	job, err := runner.Start(context.Background(), store, "test", []byte("params"), func(_ context.Context, progress func(uint64)) ([]byte, error) {
+		progress(3)
+		return []byte("result"), nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, "test", job.Kind)
+	require.Equal(t, []byte("params"), job.Parameters)
+
+	job = waitForState(t, runner, store, job.ID, common.JobSucceeded)
+	require.Equal(t, []byte("result"), job.Result)
+	require.Equal(t, uint64(3), job.Progress)
+	require.Empty(t, job.Error)
+}
+
+func TestRunnerFailed(t *testing.T) {
+	store := newStore(t)
+	runner := NewRunner(10 * time.Millisecond)
+	defer runner.Close()
+
+	job, err := runner.Start(context.Background(), store, "test", nil, func(context.Context, func(uint64)) ([]byte, error) {
+		return nil, errors.New("boom")
+	})
+	require.NoError(t, err)
+
+	job = waitForState(t, runner, store, job.ID, common.JobFailed)
+	require.Equal(t, "boom", job.Error)
+}
+
+func TestRunnerProgressAndCancel(t *testing.T) {
+	store := newStore(t)
+	runner := NewRunner(10 * time.Millisecond)
+	defer runner.Close()
+
+	job, err := runner.Start(context.Background(), store, "test", nil, func(ctx context.Context, progress func(uint64)) ([]byte, error) {
+		progress(7)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		stored, err := runner.Read(context.Background(), store, job.ID)
+		require.NoError(t, err)
+		return stored.Progress == 7
+	}, 5*time.Second, 5*time.Millisecond)
+
+	canceled, err := runner.Cancel(context.Background(), store, job.ID)
+	require.NoError(t, err)
+	require.True(t, canceled.CancelRequested)
+
+	job = waitForState(t, runner, store, job.ID, common.JobCanceled)
+	require.Empty(t, job.Error)
+}
+
+func TestRunnerCancelFromAnotherRunner(t *testing.T) {
+	store := newStore(t)
+	runner := NewRunner(10 * time.Millisecond)
+	defer runner.Close()
+
+	job, err := runner.Start(context.Background(), store, "test", nil, func(ctx context.Context, _ func(uint64)) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.NoError(t, err)
+
+	// The cancellation is observed by the running server at its next heartbeat.
+	other := NewRunner(10 * time.Millisecond)
+	_, err = other.Cancel(context.Background(), store, job.ID)
+	require.NoError(t, err)
+
+	waitForState(t, runner, store, job.ID, common.JobCanceled)
+}
+
+func TestRunnerClose(t *testing.T) {
+	store := newStore(t)
+	runner := NewRunner(time.Hour)
+
+	job, err := runner.Start(context.Background(), store, "test", nil, func(ctx context.Context, _ func(uint64)) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	require.NoError(t, err)
+
+	runner.Close()
+
+	job, err = runner.Read(context.Background(), store, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, common.JobFailed, job.State)
+	require.Equal(t, ErrInterrupted.Error(), job.Error)
+}
+
+func TestRunnerReportsStaleJobsAsInterrupted(t *testing.T) {
+	store := newStore(t)
+	require.NoError(t, store.CreateJob(context.Background(), common.Job{ID: "stale", Kind: "test", State: common.JobRunning}))
+
+	runner := NewRunner(time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+
+	job, err := runner.Read(context.Background(), store, "stale")
+	require.NoError(t, err)
+	require.Equal(t, common.JobFailed, job.State)
+	require.Equal(t, ErrInterrupted.Error(), job.Error)
+
+	listed, err := runner.List(context.Background(), store, 0)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	require.Equal(t, common.JobFailed, listed[0].State)
+}
+
+func TestRunnerReadUnknownJob(t *testing.T) {
+	store := newStore(t)
+	_, err := NewRunner(0).Read(context.Background(), store, "unknown")
+	require.ErrorIs(t, err, common.ErrJobNotFound)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/authzed/spicedb/internal/services/shared"
 
@@ -16,9 +17,29 @@ import (
 	log "github.com/authzed/spicedb/internal/logging"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
 	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
+// Option configures the consistency middleware.
+type Option func(*options)
+
+type options struct {
+	maximumRequestedStaleness  time.Duration
+	peerDatastoreIDs           []string
+	maximumPeerClockSkew       time.Duration
+	substituteExpiredRevisions bool
+	readYourWrites             *callerWrites
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{maximumPeerClockSkew: defaultMaximumPeerClockSkew}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
 type hasConsistency interface {
 	GetConsistency() *v1.Consistency
 }
@@ -31,6 +52,8 @@ var errInvalidZedToken = errors.New("invalid revision requested")
 
 type revisionHandle struct {
 	revision datastore.Revision
+	ds       datastore.Datastore
+	binding  zedtoken.Binding
 }
 
 // ContextWithHandle adds a placeholder to a context that will later be
@@ -50,22 +73,29 @@ func RevisionFromContext(ctx context.Context) datastore.Revision {
 }
 
 // MustRevisionFromContext reads the selected revision out of a context.Context, computes a zedtoken
-// from it, and panics if it has not been set on the context.
+// bound to the datastore from it, and panics if it has not been set on the context.
 func MustRevisionFromContext(ctx context.Context) (datastore.Revision, *v1.ZedToken) {
-	rev := RevisionFromContext(ctx)
-	if rev == nil {
+	var handle *revisionHandle
+	if c := ctx.Value(revisionKey); c != nil {
+		handle = c.(*revisionHandle)
+	}
+	if handle == nil || handle.revision == nil {
 		panic("consistency middleware did not inject revision")
 	}
 
-	return rev, zedtoken.NewFromRevision(rev)
+	return handle.revision, zedtoken.NewFromDatastoreRevision(handle.revision, handle.ds, handle.binding)
 }
 
 // AddRevisionToContext adds a revision to the given context, based on the consistency block found
 // in the given request (if applicable).
 func AddRevisionToContext(ctx context.Context, req interface{}, ds datastore.Datastore) error {
+	return addRevisionToContext(ctx, req, ds, nil, nil, nil, nil)
+}
+
+func addRevisionToContext(ctx context.Context, req interface{}, ds datastore.Datastore, stale *staleRevisions, peers *peerTokens, substitution *revisionSubstitution, writes *callerWrites) error {
 	switch req := req.(type) {
 	case hasConsistency:
-		return addRevisionToContextFromConsistency(ctx, req, ds)
+		return addRevisionToContextFromConsistency(ctx, req, ds, stale, peers, substitution, writes)
 	default:
 		return addHeadRevision(ctx, ds)
 	}
@@ -78,33 +108,55 @@ func addHeadRevision(ctx context.Context, ds datastore.Datastore) error {
 		return nil
 	}
 
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return rewriteDatastoreError(ctx, err)
+	}
+
 	revision, err := ds.HeadRevision(ctx)
 	if err != nil {
 		return rewriteDatastoreError(ctx, err)
 	}
 	handle.(*revisionHandle).revision = revision
+	handle.(*revisionHandle).ds = ds
+	handle.(*revisionHandle).binding = binding
 	return nil
 }
 
 // addRevisionToContextFromConsistency adds a revision to the given context, based on the consistency block found
 // in the given request (if applicable).
-func addRevisionToContextFromConsistency(ctx context.Context, req hasConsistency, ds datastore.Datastore) error {
+func addRevisionToContextFromConsistency(ctx context.Context, req hasConsistency, ds datastore.Datastore, stale *staleRevisions, peers *peerTokens, substitution *revisionSubstitution, writes *callerWrites) error {
 	handle := ctx.Value(revisionKey)
 	if handle == nil {
 		return nil
 	}
 
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return rewriteDatastoreError(ctx, err)
+	}
+
 	var revision datastore.Revision
 	consistency := req.GetConsistency()
+	session := requestedSession(ctx)
 
 	switch {
+	case session != "" && session != NewSession && (consistency == nil || consistency.GetMinimizeLatency()):
+		// Session: Use the revision at which the session was started.
+		sessionRev, err := sessionRevision(ctx, session, ds, binding, substitution)
+		if err != nil {
+			return rewriteDatastoreError(ctx, err)
+		}
+		revision = sessionRev
+
 	case consistency == nil || consistency.GetMinimizeLatency():
-		// Minimize Latency: Use the datastore's current revision, whatever it may be.
-		databaseRev, err := ds.OptimizedRevision(ctx)
+		// Minimize Latency: Use the datastore's current revision, whatever it may be, or
+		// one as stale as requested by the caller, unless the caller has since written.
+		databaseRev, err := stale.optimizedRevision(ctx, ds, binding)
 		if err != nil {
 			return rewriteDatastoreError(ctx, err)
 		}
-		revision = databaseRev
+		revision = writes.atLeastLastWrite(ctx, databaseRev, binding)
 
 	case consistency.GetFullyConsistent():
 		// Fully Consistent: Use the datastore's synchronized revision.
@@ -116,32 +168,41 @@ func addRevisionToContextFromConsistency(ctx context.Context, req hasConsistency
 
 	case consistency.GetAtLeastAsFresh() != nil:
 		// At least as fresh as: Pick one of the datastore's revision and that specified, which
-		// ever is later.
-		picked, err := pickBestRevision(ctx, consistency.GetAtLeastAsFresh(), ds)
+		// ever is later, or one at least as recent as the zedtoken of a peer datastore.
+		picked, err := pickBestRevision(ctx, consistency.GetAtLeastAsFresh(), ds, binding, peers)
 		if err != nil {
 			return rewriteDatastoreError(ctx, err)
 		}
 		revision = picked
 
 	case consistency.GetAtExactSnapshot() != nil:
-		// Exact snapshot: Use the revision as encoded in the zed token.
-		requestedRev, err := zedtoken.DecodeRevision(consistency.GetAtExactSnapshot(), ds)
+		// Exact snapshot: Use the revision as encoded in the zed token, or the nearest one
+		// still available if it was garbage collected and the caller accepts a substitute.
+		requestedRev, err := decodeRevision(consistency.GetAtExactSnapshot(), ds, binding)
 		if err != nil {
-			return errInvalidZedToken
+			return rewriteDatastoreError(ctx, err)
 		}
 
-		err = ds.CheckRevision(ctx, requestedRev)
+		checkedRev, err := substitution.checkedRevision(ctx, ds, requestedRev, binding)
 		if err != nil {
 			return rewriteDatastoreError(ctx, err)
 		}
 
-		revision = requestedRev
+		revision = checkedRev
 
 	default:
 		return fmt.Errorf("missing handling of consistency case in %v", consistency)
 	}
 
+	if session == NewSession {
+		if err := startSession(ctx, revision, ds, binding); err != nil {
+			return rewriteDatastoreError(ctx, err)
+		}
+	}
+
 	handle.(*revisionHandle).revision = revision
+	handle.(*revisionHandle).ds = ds
+	handle.(*revisionHandle).binding = binding
 	return nil
 }
 
@@ -152,7 +213,8 @@ var bypassServiceWhitelist = map[string]struct{}{
 
 // UnaryServerInterceptor returns a new unary server interceptor that performs per-request exchange of
 // the specified consistency configuration for the revision at which to perform the request.
-func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	stale, peers, substitution, writes := newStaleRevisions(opts...), newPeerTokens(opts...), newRevisionSubstitution(opts...), newCallerWrites(opts...)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		for bypass := range bypassServiceWhitelist {
 			if strings.HasPrefix(info.FullMethod, bypass) {
@@ -161,51 +223,74 @@ func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
 		}
 		ds := datastoremw.MustFromContext(ctx)
 		newCtx := ContextWithHandle(ctx)
-		if err := AddRevisionToContext(newCtx, req, ds); err != nil {
+		if err := addRevisionToContext(newCtx, req, ds, stale, peers, substitution, writes); err != nil {
 			return nil, err
 		}
 
-		return handler(newCtx, req)
+		resp, err := handler(newCtx, req)
+		if err == nil {
+			writes.record(newCtx, resp, ds, newCtx.Value(revisionKey).(*revisionHandle).binding)
+		}
+		return resp, err
 	}
 }
 
 // StreamServerInterceptor returns a new stream server interceptor that performs per-request exchange of
 // the specified consistency configuration for the revision at which to perform the request.
-func StreamServerInterceptor() grpc.StreamServerInterceptor {
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	stale, peers, substitution, writes := newStaleRevisions(opts...), newPeerTokens(opts...), newRevisionSubstitution(opts...), newCallerWrites(opts...)
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		for bypass := range bypassServiceWhitelist {
 			if strings.HasPrefix(info.FullMethod, bypass) {
 				return handler(srv, stream)
 			}
 		}
-		wrapper := &recvWrapper{stream, ContextWithHandle(stream.Context())}
+		wrapper := &recvWrapper{stream, ContextWithHandle(stream.Context()), stale, peers, substitution, writes}
 		return handler(srv, wrapper)
 	}
 }
 
 type recvWrapper struct {
 	grpc.ServerStream
-	ctx context.Context
+	ctx          context.Context
+	stale        *staleRevisions
+	peers        *peerTokens
+	substitution *revisionSubstitution
+	writes       *callerWrites
 }
 
 func (s *recvWrapper) Context() context.Context {
 	return s.ctx
 }
 
+// SendMsg records the revisions written by the responses of the stream, such as that of
+// a bulk import.
+func (s *recvWrapper) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	if s.writes != nil {
+		if handle, ok := s.ctx.Value(revisionKey).(*revisionHandle); ok && handle.binding.DatastoreID != "" {
+			s.writes.record(s.ctx, m, datastoremw.MustFromContext(s.ctx), handle.binding)
+		}
+	}
+	return nil
+}
+
 func (s *recvWrapper) RecvMsg(m interface{}) error {
 	if err := s.ServerStream.RecvMsg(m); err != nil {
 		return err
 	}
 	ds := datastoremw.MustFromContext(s.ctx)
 
-	if err := AddRevisionToContext(s.ctx, m, ds); err != nil {
+	if err := addRevisionToContext(s.ctx, m, ds, s.stale, s.peers, s.substitution, s.writes); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func pickBestRevision(ctx context.Context, requested *v1.ZedToken, ds datastore.Datastore) (datastore.Revision, error) {
+func pickBestRevision(ctx context.Context, requested *v1.ZedToken, ds datastore.Datastore, binding zedtoken.Binding, peers *peerTokens) (datastore.Revision, error) {
 	// Calculate a revision as we see fit
 	databaseRev, err := ds.OptimizedRevision(ctx)
 	if err != nil {
@@ -213,9 +298,13 @@ func pickBestRevision(ctx context.Context, requested *v1.ZedToken, ds datastore.
 	}
 
 	if requested != nil {
-		requestedRev, err := zedtoken.DecodeRevision(requested, ds)
+		requestedRev, err := decodeRevision(requested, ds, binding)
+		var mismatched zedtoken.ErrMismatchedDatastore
+		if errors.As(err, &mismatched) {
+			return peers.revisionForMismatched(ctx, ds, databaseRev, mismatched)
+		}
 		if err != nil {
-			return datastore.NoRevision, errInvalidZedToken
+			return datastore.NoRevision, err
 		}
 
 		if databaseRev.GreaterThan(requestedRev) {
@@ -227,15 +316,39 @@ func pickBestRevision(ctx context.Context, requested *v1.ZedToken, ds datastore.
 	return databaseRev, nil
 }
 
+// decodeRevision decodes the revision of a zedtoken, which must have been minted by
+// the datastore with the given binding, if minted by any in particular.
+func decodeRevision(requested *v1.ZedToken, ds datastore.Datastore, binding zedtoken.Binding) (datastore.Revision, error) {
+	requestedRev, err := zedtoken.DecodeDatastoreRevision(requested, ds, binding)
+	if err != nil {
+		if errors.As(err, &zedtoken.ErrMismatchedDatastore{}) {
+			return datastore.NoRevision, err
+		}
+		return datastore.NoRevision, errInvalidZedToken
+	}
+	return requestedRev, nil
+}
+
 func rewriteDatastoreError(ctx context.Context, err error) error {
 	// Check if the error can be directly used.
 	if _, ok := status.FromError(err); ok {
 		return err
 	}
 
+	var invalidRevisionError datastore.ErrInvalidRevision
 	switch {
-	case errors.As(err, &datastore.ErrInvalidRevision{}):
-		return status.Errorf(codes.OutOfRange, "invalid revision: %s", err)
+	case errors.As(err, &invalidRevisionError):
+		reason := spiceerrors.ReasonInvalidRevision
+		if invalidRevisionError.Reason() == datastore.RevisionStale {
+			reason = spiceerrors.ReasonRevisionExpired
+		}
+		return spiceerrors.WithCodeAndExtendedReason(fmt.Errorf("invalid revision: %w", err), codes.OutOfRange, reason, nil)
+
+	case errors.Is(err, errInvalidZedToken):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.InvalidArgument, spiceerrors.ReasonInvalidRevision, nil)
+
+	case errors.As(err, &zedtoken.ErrMismatchedDatastore{}):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.InvalidArgument, spiceerrors.ReasonMismatchedDatastore, nil)
 
 	case errors.As(err, &datastore.ErrReadOnly{}):
 		return shared.ErrServiceReadOnly
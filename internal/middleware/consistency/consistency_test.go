@@ -17,6 +17,7 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
 	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
@@ -27,10 +28,15 @@ var (
 	head      = revision.NewFromDecimal(decimal.NewFromInt(145))
 )
 
+const datastoreID = "somedatastore"
+
+var binding = zedtoken.Binding{DatastoreID: datastoreID}
+
 func TestAddRevisionToContextNoneSupplied(t *testing.T) {
 	require := require.New(t)
 
 	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
 	ds.On("OptimizedRevision").Return(optimized, nil).Once()
 
 	updated := ContextWithHandle(context.Background())
@@ -44,6 +50,7 @@ func TestAddRevisionToContextMinimizeLatency(t *testing.T) {
 	require := require.New(t)
 
 	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
 	ds.On("OptimizedRevision").Return(optimized, nil).Once()
 
 	updated := ContextWithHandle(context.Background())
@@ -63,6 +70,7 @@ func TestAddRevisionToContextFullyConsistent(t *testing.T) {
 	require := require.New(t)
 
 	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
 	ds.On("HeadRevision").Return(head, nil).Once()
 
 	updated := ContextWithHandle(context.Background())
@@ -82,6 +90,7 @@ func TestAddRevisionToContextAtLeastAsFresh(t *testing.T) {
 	require := require.New(t)
 
 	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
 	ds.On("OptimizedRevision").Return(optimized, nil).Once()
 	ds.On("RevisionFromString", exact.String()).Return(exact, nil).Once()
 
@@ -102,6 +111,7 @@ func TestAddRevisionToContextAtValidExactSnapshot(t *testing.T) {
 	require := require.New(t)
 
 	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
 	ds.On("CheckRevision", exact).Return(nil).Times(1)
 	ds.On("RevisionFromString", exact.String()).Return(exact, nil).Once()
 
@@ -122,6 +132,7 @@ func TestAddRevisionToContextAtInvalidExactSnapshot(t *testing.T) {
 	require := require.New(t)
 
 	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
 	ds.On("CheckRevision", zero).Return(errors.New("bad revision")).Times(1)
 	ds.On("RevisionFromString", zero.String()).Return(zero, nil).Once()
 
@@ -137,10 +148,49 @@ func TestAddRevisionToContextAtInvalidExactSnapshot(t *testing.T) {
 	ds.AssertExpectations(t)
 }
 
+func TestAddRevisionToContextMismatchedDatastore(t *testing.T) {
+	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
+	ds.On("OptimizedRevision").Return(optimized, nil).Once()
+
+	updated := ContextWithHandle(context.Background())
+	err := AddRevisionToContext(updated, &v1.ReadRelationshipsRequest{
+		Consistency: &v1.Consistency{
+			Requirement: &v1.Consistency_AtLeastAsFresh{
+				AtLeastAsFresh: zedtoken.NewFromDatastoreRevision(exact, ds, zedtoken.Binding{DatastoreID: "anotherdatastore"}),
+			},
+		},
+	}, ds)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonMismatchedDatastore, err)
+	ds.AssertExpectations(t)
+}
+
+func TestMustRevisionFromContextBoundToDatastore(t *testing.T) {
+	require := require.New(t)
+
+	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
+	ds.On("HeadRevision").Return(head, nil).Once()
+	ds.On("RevisionFromString", head.String()).Return(head, nil).Once()
+
+	updated := ContextWithHandle(context.Background())
+	require.NoError(AddRevisionToContext(updated, &v1.WriteSchemaRequest{}, ds))
+
+	_, token := MustRevisionFromContext(updated)
+	decoded, err := zedtoken.DecodeDatastoreRevision(token, ds, binding)
+	require.NoError(err)
+	require.True(head.Equal(decoded))
+
+	_, err = zedtoken.DecodeDatastoreRevision(token, ds, zedtoken.Binding{DatastoreID: "anotherdatastore"})
+	require.ErrorAs(err, &zedtoken.ErrMismatchedDatastore{})
+	ds.AssertExpectations(t)
+}
+
 func TestAddRevisionToContextAPIAlwaysFullyConsistent(t *testing.T) {
 	require := require.New(t)
 
 	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
 	ds.On("HeadRevision").Return(head, nil).Once()
 
 	updated := ContextWithHandle(context.Background())
@@ -152,6 +202,7 @@ func TestAddRevisionToContextAPIAlwaysFullyConsistent(t *testing.T) {
 
 func TestMiddlewareConsistencyTestSuite(t *testing.T) {
 	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
 	ds.On("HeadRevision").Return(head, nil)
 
 	s := &ConsistencyTestSuite{
@@ -0,0 +1,109 @@
+package consistency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+// WithPeerDatastores sets the unique IDs of the datastores, kept in sync with the
+// datastore of the server by external replication, whose zedtokens are accepted with
+// at_least_as_fresh consistency. Such zedtokens are served at a revision of the
+// datastore of the server which is at least as recent as the time by which their
+// revision was committed, so that callers moving between deployments read their
+// writes once they have been replicated.
+//
+// default: none, which rejects the zedtokens of any other datastore
+func WithPeerDatastores(datastoreIDs ...string) Option {
+	return func(o *options) {
+		o.peerDatastoreIDs = append(o.peerDatastoreIDs, datastoreIDs...)
+	}
+}
+
+// WithMaximumPeerClockSkew sets how far ahead of the clock of the server the time
+// carried by the zedtoken of a peer datastore may be. Calls wait for the clock of the
+// server to reach such times, and fail if they are further ahead.
+//
+// default: 500ms
+func WithMaximumPeerClockSkew(skew time.Duration) Option {
+	return func(o *options) {
+		o.maximumPeerClockSkew = skew
+	}
+}
+
+const defaultMaximumPeerClockSkew = 500 * time.Millisecond
+
+// peerTokens serves the zedtokens minted by peer datastores.
+type peerTokens struct {
+	datastoreIDs map[string]struct{}
+	maxSkew      time.Duration
+	now          func() time.Time
+}
+
+func newPeerTokens(opts ...Option) *peerTokens {
+	o := newOptions(opts...)
+
+	datastoreIDs := make(map[string]struct{}, len(o.peerDatastoreIDs))
+	for _, datastoreID := range o.peerDatastoreIDs {
+		datastoreIDs[datastoreID] = struct{}{}
+	}
+
+	return &peerTokens{
+		datastoreIDs: datastoreIDs,
+		maxSkew:      o.maximumPeerClockSkew,
+		now:          time.Now,
+	}
+}
+
+// revisionForMismatched returns the revision at which to serve a zedtoken minted by
+// another datastore, which is the given optimized revision if it is recent enough, or
+// the mismatch error itself if that datastore is not a peer or the zedtoken carries no
+// time.
+func (pt *peerTokens) revisionForMismatched(ctx context.Context, ds datastore.Datastore, optimized datastore.Revision, mismatched zedtoken.ErrMismatchedDatastore) (datastore.Revision, error) {
+	if pt == nil {
+		return datastore.NoRevision, mismatched
+	}
+
+	if _, ok := pt.datastoreIDs[mismatched.TokenDatastoreID()]; !ok || mismatched.Restored() {
+		return datastore.NoRevision, mismatched
+	}
+
+	minimum, ok := mismatched.MinimumTimestamp()
+	if !ok {
+		return datastore.NoRevision, mismatched
+	}
+
+	if ahead := minimum.Sub(pt.now()); ahead > 0 {
+		if ahead > pt.maxSkew {
+			return datastore.NoRevision, spiceerrors.WithCodeAndExtendedReason(
+				fmt.Errorf("zedtoken of peer datastore `%s` is %s ahead of the clock of this server", mismatched.TokenDatastoreID(), ahead),
+				codes.FailedPrecondition,
+				spiceerrors.ReasonFailedPrecondition,
+				nil,
+			)
+		}
+
+		timer := time.NewTimer(ahead)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return datastore.NoRevision, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	// The optimized revision is preferred, if recent enough, as it is shared by more calls.
+	// Datastores which do not report commit times cannot tell, so serve the head revision.
+	if committedAt, ok := common.RevisionCommitTime(ds, optimized); ok && !committedAt.Before(minimum) {
+		return optimized, nil
+	}
+
+	return ds.HeadRevision(ctx)
+}
@@ -0,0 +1,153 @@
+package consistency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+const peerDatastoreID = "peerdatastore"
+
+var peerBinding = zedtoken.Binding{DatastoreID: peerDatastoreID}
+
+func atLeastAsFresh(token *v1.ZedToken) *v1.ReadRelationshipsRequest {
+	return &v1.ReadRelationshipsRequest{
+		Consistency: &v1.Consistency{
+			Requirement: &v1.Consistency_AtLeastAsFresh{AtLeastAsFresh: token},
+		},
+	}
+}
+
+// commitTimes reports the integer parts of decimal revisions as their commit times, as
+// CockroachDB does.
+type commitTimes struct {
+	*proxy_test.MockDatastore
+}
+
+func (commitTimes) RevisionCommitTime(rev datastore.Revision) (time.Time, bool) {
+	return common.DecimalCommitTime(rev)
+}
+
+func TestPeerTokens(t *testing.T) {
+	now := time.Unix(0, 110)
+	peer := commitTimes{&proxy_test.MockDatastore{}}
+
+	testCases := []struct {
+		name             string
+		token            *v1.ZedToken
+		expectedRevision datastore.Revision
+		expectedReason   spiceerrors.ExtendedReason
+	}{
+		{
+			"committed before the optimized revision",
+			zedtoken.NewFromDatastoreRevision(revision.NewFromDecimal(decimal.NewFromInt(50)), peer, peerBinding),
+			optimized,
+			"",
+		},
+		{
+			"committed after the optimized revision",
+			zedtoken.NewFromDatastoreRevision(revision.NewFromDecimal(decimal.NewFromInt(105)), peer, peerBinding),
+			head,
+			"",
+		},
+		{
+			"ahead of the clock within the skew",
+			zedtoken.NewFromDatastoreRevision(exact, peer, peerBinding),
+			head,
+			"",
+		},
+		{
+			"ahead of the clock beyond the skew",
+			zedtoken.NewFromDatastoreRevision(revision.NewFromDecimal(decimal.NewFromInt(now.Add(time.Hour).UnixNano())), peer, peerBinding),
+			nil,
+			spiceerrors.ReasonFailedPrecondition,
+		},
+		{
+			"not a peer",
+			zedtoken.NewFromDatastoreRevision(exact, peer, zedtoken.Binding{DatastoreID: "anotherdatastore"}),
+			nil,
+			spiceerrors.ReasonMismatchedDatastore,
+		},
+		{
+			"without time",
+			zedtoken.NewFromDatastoreRevision(zero, peer, peerBinding),
+			nil,
+			spiceerrors.ReasonMismatchedDatastore,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ds := &proxy_test.MockDatastore{}
+			ds.On("UniqueID").Return(datastoreID, nil)
+			ds.On("OptimizedRevision").Return(optimized, nil).Once()
+			ds.On("HeadRevision").Return(head, nil).Maybe()
+
+			peers := newPeerTokens(WithPeerDatastores(peerDatastoreID), WithMaximumPeerClockSkew(time.Second))
+			peers.now = func() time.Time { return now }
+
+			updated := ContextWithHandle(context.Background())
+			err := addRevisionToContext(updated, atLeastAsFresh(tc.token), commitTimes{ds}, nil, peers, nil, nil)
+			if tc.expectedReason != "" {
+				spiceerrors.RequireExtendedReason(t, tc.expectedReason, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.True(t, tc.expectedRevision.Equal(RevisionFromContext(updated)))
+			ds.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPeerTokensMintedWithTime(t *testing.T) {
+	require := require.New(t)
+
+	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(peerDatastoreID, nil)
+	ds.On("HeadRevision").Return(exact, nil).Once()
+
+	updated := ContextWithHandle(context.Background())
+	require.NoError(AddRevisionToContext(updated, &v1.WriteSchemaRequest{}, commitTimes{ds}))
+
+	_, token := MustRevisionFromContext(updated)
+	_, err := zedtoken.DecodeDatastoreRevision(token, ds, binding)
+
+	var mismatched zedtoken.ErrMismatchedDatastore
+	require.ErrorAs(err, &mismatched)
+	minimum, ok := mismatched.MinimumTimestamp()
+	require.True(ok)
+	require.Equal(exact.IntPart(), minimum.UnixNano())
+}
+
+func TestPeerTokensWithoutCommitTimes(t *testing.T) {
+	peer := commitTimes{&proxy_test.MockDatastore{}}
+	token := zedtoken.NewFromDatastoreRevision(revision.NewFromDecimal(decimal.NewFromInt(50)), peer, peerBinding)
+
+	// Datastores which do not report commit times, such as MySQL, cannot tell whether the
+	// optimized revision is recent enough, so serve the head revision.
+	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
+	ds.On("OptimizedRevision").Return(optimized, nil).Once()
+	ds.On("HeadRevision").Return(head, nil).Once()
+
+	peers := newPeerTokens(WithPeerDatastores(peerDatastoreID), WithMaximumPeerClockSkew(time.Second))
+	peers.now = func() time.Time { return time.Unix(0, 110) }
+
+	updated := ContextWithHandle(context.Background())
+	require.NoError(t, addRevisionToContext(updated, atLeastAsFresh(token), ds, nil, peers, nil, nil))
+	require.True(t, head.Equal(RevisionFromContext(updated)))
+	ds.AssertExpectations(t)
+}
@@ -0,0 +1,171 @@
+package consistency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+// RequestCaller, if specified in the request header of a call, distinguishes callers
+// sharing a bearer token, whose writes are otherwise all read by each other's calls
+// when read-your-writes is enabled.
+const RequestCaller requestmeta.RequestMetadataHeaderKey = "io.spicedb.requestcaller"
+
+// WithReadYourWrites enables read-your-writes for callers which do not pass the
+// zedtokens of their writes to their reads: the revision of the last write of each
+// caller, identified by its bearer token and RequestCaller header, is tracked for the
+// TTL, and calls of the caller with no consistency or with minimize_latency
+// consistency within it are served as if at_least_as_fresh that revision. Calls in a
+// session are served at the revision of the session.
+//
+// Writes are tracked in the memory of this server only, and are shared by the unary and
+// stream interceptors given the same option: a caller whose calls are balanced across
+// several servers reads its writes only from the server which served them, and must
+// pass zedtokens to read them from the others.
+//
+// default: 0, which tracks no writes
+func WithReadYourWrites(ttl time.Duration) Option {
+	writes := newCallerWritesWithTTL(ttl)
+	return func(o *options) {
+		o.readYourWrites = writes
+	}
+}
+
+type hasWrittenAt interface {
+	GetWrittenAt() *v1.ZedToken
+}
+
+type hasDeletedAt interface {
+	GetDeletedAt() *v1.ZedToken
+}
+
+type hasImportedAt interface {
+	GetImportedAt() *v1.ZedToken
+}
+
+type callerWrite struct {
+	revision  datastore.Revision
+	binding   zedtoken.Binding
+	expiresAt time.Time
+}
+
+// callerWrites tracks the revision of the last write of each caller, so that the
+// calls of a caller read its writes without the caller passing zedtokens.
+type callerWrites struct {
+	ttl time.Duration
+	now func() time.Time
+
+	lock       sync.Mutex
+	byCaller   map[string]callerWrite
+	lastPruned time.Time
+}
+
+func newCallerWrites(opts ...Option) *callerWrites {
+	return newOptions(opts...).readYourWrites
+}
+
+func newCallerWritesWithTTL(ttl time.Duration) *callerWrites {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &callerWrites{
+		ttl:      ttl,
+		now:      time.Now,
+		byCaller: make(map[string]callerWrite),
+	}
+}
+
+// callerIdentity identifies the caller of a call by a hash of its bearer token, so that
+// tokens are not retained, and its RequestCaller header.
+func callerIdentity(ctx context.Context) string {
+	token, _ := grpcauth.AuthFromMD(ctx, "bearer")
+
+	var caller string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(string(RequestCaller)); len(values) > 0 {
+			caller = values[0]
+		}
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:]) + "/" + caller
+}
+
+// record tracks the revision written by the response of a call, if any.
+func (cw *callerWrites) record(ctx context.Context, resp any, ds datastore.Datastore, binding zedtoken.Binding) {
+	if cw == nil {
+		return
+	}
+
+	var written *v1.ZedToken
+	switch resp := resp.(type) {
+	case hasWrittenAt:
+		written = resp.GetWrittenAt()
+	case hasDeletedAt:
+		written = resp.GetDeletedAt()
+	case hasImportedAt:
+		written = resp.GetImportedAt()
+	}
+	if written == nil {
+		return
+	}
+
+	revision, err := zedtoken.DecodeDatastoreRevision(written, ds, binding)
+	if err != nil {
+		return
+	}
+
+	caller := callerIdentity(ctx)
+	now := cw.now()
+
+	cw.lock.Lock()
+	defer cw.lock.Unlock()
+
+	// Expired writes are removed once per TTL, bounding those tracked to the callers
+	// which wrote within the last two TTLs.
+	if now.Sub(cw.lastPruned) >= cw.ttl {
+		for key, write := range cw.byCaller {
+			if !now.Before(write.expiresAt) {
+				delete(cw.byCaller, key)
+			}
+		}
+		cw.lastPruned = now
+	}
+
+	existing, ok := cw.byCaller[caller]
+	if ok && existing.binding == binding && existing.revision.GreaterThan(revision) && now.Before(existing.expiresAt) {
+		return
+	}
+	cw.byCaller[caller] = callerWrite{revision: revision, binding: binding, expiresAt: now.Add(cw.ttl)}
+}
+
+// atLeastLastWrite returns the revision, or the revision of the last write of the
+// caller if later and still tracked.
+func (cw *callerWrites) atLeastLastWrite(ctx context.Context, revision datastore.Revision, binding zedtoken.Binding) datastore.Revision {
+	if cw == nil {
+		return revision
+	}
+
+	caller := callerIdentity(ctx)
+	now := cw.now()
+
+	cw.lock.Lock()
+	write, ok := cw.byCaller[caller]
+	cw.lock.Unlock()
+
+	if !ok || write.binding != binding || !now.Before(write.expiresAt) || !write.revision.GreaterThan(revision) {
+		return revision
+	}
+	return write.revision
+}
@@ -0,0 +1,100 @@
+package consistency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/pkg/datastore"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func withCaller(token string, pairs ...string) context.Context {
+	return ContextWithHandle(metadata.NewIncomingContext(
+		context.Background(),
+		metadata.Pairs(append([]string{"authorization", "bearer " + token}, pairs...)...),
+	))
+}
+
+func TestReadYourWrites(t *testing.T) {
+	require := require.New(t)
+
+	require.Nil(newCallerWrites())
+
+	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
+	ds.On("OptimizedRevision").Return(optimized, nil)
+	ds.On("RevisionFromString", exact.String()).Return(exact, nil)
+	ds.On("RevisionFromString", zero.String()).Return(zero, nil)
+
+	now := time.Unix(1_000_000_000, 0)
+	writes := newCallerWrites(WithReadYourWrites(time.Minute))
+	writes.now = func() time.Time { return now }
+
+	readAt := func(ctx context.Context, consistency *v1.Consistency) datastore.Revision {
+		require.NoError(addRevisionToContext(ctx, &v1.ReadRelationshipsRequest{Consistency: consistency}, ds, nil, nil, nil, writes))
+		return RevisionFromContext(ctx)
+	}
+
+	writes.record(withCaller("alice"), &v1.WriteRelationshipsResponse{WrittenAt: zedtoken.NewFromDatastoreRevision(exact, ds, binding)}, ds, binding)
+
+	// An older write does not replace the last one.
+	writes.record(withCaller("alice"), &v1.DeleteRelationshipsResponse{DeletedAt: zedtoken.NewFromDatastoreRevision(zero, ds, binding)}, ds, binding)
+
+	require.True(exact.Equal(readAt(withCaller("alice"), nil)))
+	require.True(exact.Equal(readAt(withCaller("alice"), &v1.Consistency{Requirement: &v1.Consistency_MinimizeLatency{MinimizeLatency: true}})))
+
+	// Other callers, including those sharing the token under another name, are not upgraded.
+	require.True(optimized.Equal(readAt(withCaller("bob"), nil)))
+	require.True(optimized.Equal(readAt(withCaller("alice", string(RequestCaller), "reports"), nil)))
+
+	// Writes are forgotten after the TTL.
+	now = now.Add(time.Minute)
+	require.True(optimized.Equal(readAt(withCaller("alice"), nil)))
+
+	writes.record(withCaller("bob"), &v1.WriteRelationshipsResponse{WrittenAt: zedtoken.NewFromDatastoreRevision(exact, ds, binding)}, ds, binding)
+	require.Len(writes.byCaller, 1)
+}
+
+type sentStream struct {
+	grpc.ServerStream
+}
+
+func (sentStream) SendMsg(any) error { return nil }
+
+func TestReadYourWritesOfStreams(t *testing.T) {
+	require := require.New(t)
+
+	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
+	ds.On("OptimizedRevision").Return(optimized, nil)
+	ds.On("RevisionFromString", exact.String()).Return(exact, nil)
+
+	// The unary and stream interceptors given the same option share the writes tracked.
+	opt := WithReadYourWrites(time.Minute)
+	writes := newCallerWrites(opt)
+	require.Same(writes, newCallerWrites(opt))
+
+	ctx := datastoremw.ContextWithHandle(withCaller("alice"))
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+	ctx.Value(revisionKey).(*revisionHandle).binding = binding
+
+	// The revision of a bulk import, sent as the response of its stream, is tracked.
+	stream := &recvWrapper{ServerStream: sentStream{}, ctx: ctx, writes: writes}
+	require.NoError(stream.SendMsg(&experimental.BulkImportRelationshipsResponse{
+		NumLoaded:  1,
+		ImportedAt: zedtoken.NewFromDatastoreRevision(exact, ds, binding),
+	}))
+
+	readCtx := withCaller("alice")
+	require.NoError(addRevisionToContext(readCtx, &v1.ReadRelationshipsRequest{}, ds, nil, nil, nil, writes))
+	require.True(exact.Equal(RevisionFromContext(readCtx)))
+}
@@ -0,0 +1,74 @@
+package consistency
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"github.com/authzed/authzed-go/pkg/responsemeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+const (
+	// RequestSession, if specified in the request header of a call, places the call in a
+	// snapshot-consistent session: calls of the session with no consistency or with
+	// minimize_latency consistency are all served at the same revision. A value of
+	// NewSession starts a session at the revision picked for the call, which is returned
+	// in the SessionHeader response header; that value is then specified by the later
+	// calls of the session. Sessions hold no state on the server, and last until their
+	// revision is garbage collected, unless it is pinned.
+	RequestSession requestmeta.RequestMetadataHeaderKey = "io.spicedb.session"
+
+	// NewSession is the value of the RequestSession request header starting a session.
+	NewSession = "new"
+
+	// SessionHeader is the response header holding the session started by a call.
+	SessionHeader responsemeta.ResponseMetadataHeaderKey = "io.spicedb.respmeta.session"
+)
+
+// requestedSession returns the value of the RequestSession request header, if any.
+func requestedSession(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(string(RequestSession))
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// sessionRevision returns the revision of an existing session, which must be readable
+// unless the call accepts a substitute for it.
+func sessionRevision(ctx context.Context, session string, ds datastore.Datastore, binding zedtoken.Binding, substitution *revisionSubstitution) (datastore.Revision, error) {
+	revision, err := decodeRevision(&v1.ZedToken{Token: session}, ds, binding)
+	if err != nil {
+		if err == errInvalidZedToken {
+			return datastore.NoRevision, spiceerrors.WithCodeAndExtendedReason(
+				fmt.Errorf("invalid value for request header %s: must be `%s` or a session returned in response header %s", RequestSession, NewSession, SessionHeader),
+				codes.InvalidArgument,
+				spiceerrors.ReasonInvalidArgument,
+				nil,
+			)
+		}
+		return datastore.NoRevision, err
+	}
+
+	return substitution.checkedRevision(ctx, ds, revision, binding)
+}
+
+// startSession returns the session at the revision picked for the first call of the
+// session in its response header.
+func startSession(ctx context.Context, revision datastore.Revision, ds datastore.Datastore, binding zedtoken.Binding) error {
+	return responsemeta.SetResponseHeaderMetadata(ctx, map[responsemeta.ResponseMetadataHeaderKey]string{
+		SessionHeader: zedtoken.NewFromDatastoreRevision(revision, ds, binding).Token,
+	})
+}
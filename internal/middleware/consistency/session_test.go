@@ -0,0 +1,88 @@
+package consistency
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+// headerStream records the response headers set by the middleware.
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (hs *headerStream) SetHeader(md metadata.MD) error {
+	hs.header = metadata.Join(hs.header, md)
+	return nil
+}
+
+func withSession(session string, stream grpc.ServerTransportStream) context.Context {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(string(RequestSession), session))
+	return ContextWithHandle(grpc.NewContextWithServerTransportStream(ctx, stream))
+}
+
+func TestSession(t *testing.T) {
+	require := require.New(t)
+
+	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
+	ds.On("OptimizedRevision").Return(optimized, nil).Once()
+	ds.On("HeadRevision").Return(head, nil).Once()
+	ds.On("RevisionFromString", optimized.String()).Return(optimized, nil)
+	ds.On("CheckRevision", optimized).Return(nil)
+
+	// The first call starts the session at its revision.
+	stream := &headerStream{}
+	ctx := withSession(NewSession, stream)
+	require.NoError(AddRevisionToContext(ctx, &v1.ReadRelationshipsRequest{}, ds))
+	require.True(optimized.Equal(RevisionFromContext(ctx)))
+
+	sessions := stream.header.Get(string(SessionHeader))
+	require.Len(sessions, 1)
+
+	// Later calls of the session are served at its revision, unless they request
+	// another consistency.
+	for _, tc := range []struct {
+		consistency *v1.Consistency
+		expected    datastore.Revision
+	}{
+		{nil, optimized},
+		{&v1.Consistency{Requirement: &v1.Consistency_MinimizeLatency{MinimizeLatency: true}}, optimized},
+		{&v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}, head},
+	} {
+		ctx := withSession(sessions[0], &headerStream{})
+		require.NoError(AddRevisionToContext(ctx, &v1.ReadRelationshipsRequest{Consistency: tc.consistency}, ds))
+		require.True(tc.expected.Equal(RevisionFromContext(ctx)))
+	}
+
+	ds.AssertExpectations(t)
+}
+
+func TestSessionErrors(t *testing.T) {
+	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
+	ds.On("RevisionFromString", zero.String()).Return(zero, nil)
+	ds.On("CheckRevision", zero).Return(datastore.NewInvalidRevisionErr(zero, datastore.RevisionStale))
+
+	for _, tc := range []struct {
+		session        string
+		expectedReason spiceerrors.ExtendedReason
+	}{
+		{"invalid", spiceerrors.ReasonInvalidArgument},
+		{zedtoken.NewFromDatastoreRevision(zero, ds, binding).Token, spiceerrors.ReasonRevisionExpired},
+		{zedtoken.NewFromDatastoreRevision(exact, ds, zedtoken.Binding{DatastoreID: "anotherdatastore"}).Token, spiceerrors.ReasonMismatchedDatastore},
+	} {
+		err := AddRevisionToContext(withSession(tc.session, &headerStream{}), &v1.ReadRelationshipsRequest{}, ds)
+		spiceerrors.RequireExtendedReason(t, tc.expectedReason, err)
+	}
+}
@@ -0,0 +1,133 @@
+package consistency
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+// RequestMaximumStaleness, if specified in the request header of a call with
+// minimize_latency consistency, is the maximum staleness the caller tolerates for the
+// revision of the call, as a duration such as `30s`. It is clamped by the maximum
+// configured on the server, and ignored if the server has none.
+const RequestMaximumStaleness requestmeta.RequestMetadataHeaderKey = "io.spicedb.requestmaxstaleness"
+
+// WithMaximumRequestedStaleness sets the maximum staleness callers can request with the
+// RequestMaximumStaleness header. It should be well under the garbage collection window
+// of the datastore, as revisions are served for up to that long.
+//
+// default: 0, which ignores the header
+func WithMaximumRequestedStaleness(maximum time.Duration) Option {
+	return func(o *options) {
+		o.maximumRequestedStaleness = maximum
+	}
+}
+
+type staleWindowKey struct {
+	binding zedtoken.Binding
+	window  time.Duration
+}
+
+type staleWindowRevision struct {
+	start    time.Time
+	revision datastore.Revision
+}
+
+// staleRevisions serves the revisions of calls requesting a maximum staleness. Such calls
+// share the first optimized revision picked within the current window of the requested
+// staleness, so that latency-tolerant callers see the revision change, and so miss the
+// caches, once per window rather than once per quantization interval of the datastore.
+type staleRevisions struct {
+	maximum time.Duration
+	now     func() time.Time
+
+	lock     sync.Mutex
+	byWindow map[staleWindowKey]staleWindowRevision
+}
+
+func newStaleRevisions(opts ...Option) *staleRevisions {
+	return &staleRevisions{
+		maximum:  newOptions(opts...).maximumRequestedStaleness,
+		now:      time.Now,
+		byWindow: make(map[staleWindowKey]staleWindowRevision),
+	}
+}
+
+// optimizedRevision returns the revision of a call with minimize_latency consistency,
+// honoring the maximum staleness requested by the caller, if any.
+func (sr *staleRevisions) optimizedRevision(ctx context.Context, ds datastore.Datastore, binding zedtoken.Binding) (datastore.Revision, error) {
+	if sr == nil || sr.maximum <= 0 {
+		return ds.OptimizedRevision(ctx)
+	}
+
+	requested, err := requestedStaleness(ctx)
+	if err != nil {
+		return datastore.NoRevision, err
+	}
+
+	window := requested
+	if window > sr.maximum {
+		window = sr.maximum
+	}
+
+	// Windows are whole seconds, which bounds the number of them which are tracked.
+	window = window.Truncate(time.Second)
+	if window <= 0 {
+		return ds.OptimizedRevision(ctx)
+	}
+
+	key := staleWindowKey{binding, window}
+	start := sr.now().Truncate(window)
+
+	sr.lock.Lock()
+	current, ok := sr.byWindow[key]
+	sr.lock.Unlock()
+	if ok && current.start.Equal(start) {
+		return current.revision, nil
+	}
+
+	revision, err := ds.OptimizedRevision(ctx)
+	if err != nil {
+		return datastore.NoRevision, err
+	}
+
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	if current, ok := sr.byWindow[key]; ok && current.start.Equal(start) {
+		return current.revision, nil
+	}
+	sr.byWindow[key] = staleWindowRevision{start, revision}
+	return revision, nil
+}
+
+func requestedStaleness(ctx context.Context) (time.Duration, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, nil
+	}
+
+	values := md.Get(string(RequestMaximumStaleness))
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	staleness, err := time.ParseDuration(values[0])
+	if err != nil || staleness < 0 {
+		return 0, spiceerrors.WithCodeAndExtendedReason(
+			fmt.Errorf("invalid value `%s` for request header %s: must be a non-negative duration", values[0], RequestMaximumStaleness),
+			codes.InvalidArgument,
+			spiceerrors.ReasonInvalidArgument,
+			nil,
+		)
+	}
+	return staleness, nil
+}
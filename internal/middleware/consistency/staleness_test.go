@@ -0,0 +1,134 @@
+package consistency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+)
+
+func withRequestedStaleness(staleness string) context.Context {
+	return ContextWithHandle(metadata.NewIncomingContext(
+		context.Background(),
+		metadata.Pairs(string(RequestMaximumStaleness), staleness),
+	))
+}
+
+func TestRequestedStaleness(t *testing.T) {
+	testCases := []struct {
+		name              string
+		maximum           time.Duration
+		staleness         string
+		elapsed           time.Duration
+		expectedRevisions []datastore.Revision
+	}{
+		{
+			"ignored without maximum",
+			0,
+			"30s",
+			time.Second,
+			[]datastore.Revision{optimized, exact},
+		},
+		{
+			"shared within window",
+			time.Minute,
+			"30s",
+			time.Second,
+			[]datastore.Revision{optimized, optimized},
+		},
+		{
+			"renewed after window",
+			time.Minute,
+			"30s",
+			30 * time.Second,
+			[]datastore.Revision{optimized, exact},
+		},
+		{
+			"clamped by maximum",
+			10 * time.Second,
+			"30s",
+			10 * time.Second,
+			[]datastore.Revision{optimized, exact},
+		},
+		{
+			"below a second",
+			time.Minute,
+			"500ms",
+			0,
+			[]datastore.Revision{optimized, exact},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			ds := &proxy_test.MockDatastore{}
+			ds.On("UniqueID").Return(datastoreID, nil)
+			ds.On("OptimizedRevision").Return(optimized, nil).Once()
+			ds.On("OptimizedRevision").Return(exact, nil).Once()
+
+			now := time.Unix(1_000_000_020, 0)
+			stale := newStaleRevisions(WithMaximumRequestedStaleness(tc.maximum))
+			stale.now = func() time.Time { return now }
+
+			for index, expected := range tc.expectedRevisions {
+				if index > 0 {
+					now = now.Add(tc.elapsed)
+				}
+
+				ctx := withRequestedStaleness(tc.staleness)
+				require.NoError(addRevisionToContext(ctx, &v1.ReadRelationshipsRequest{}, ds, stale, nil, nil, nil))
+				require.True(expected.Equal(RevisionFromContext(ctx)), "unexpected revision for request #%d", index)
+			}
+		})
+	}
+}
+
+func TestRequestedStalenessPerDatastore(t *testing.T) {
+	require := require.New(t)
+
+	first := &proxy_test.MockDatastore{}
+	first.On("UniqueID").Return("first", nil)
+	first.On("OptimizedRevision").Return(optimized, nil).Once()
+
+	second := &proxy_test.MockDatastore{}
+	second.On("UniqueID").Return("second", nil)
+	second.On("OptimizedRevision").Return(exact, nil).Once()
+
+	stale := newStaleRevisions(WithMaximumRequestedStaleness(time.Minute))
+	for _, tc := range []struct {
+		ds       datastore.Datastore
+		expected datastore.Revision
+	}{
+		{first, optimized},
+		{second, exact},
+		{first, optimized},
+	} {
+		ctx := withRequestedStaleness("1m")
+		require.NoError(addRevisionToContext(ctx, &v1.ReadRelationshipsRequest{}, tc.ds, stale, nil, nil, nil))
+		require.True(tc.expected.Equal(RevisionFromContext(ctx)))
+	}
+
+	first.AssertExpectations(t)
+	second.AssertExpectations(t)
+}
+
+func TestInvalidRequestedStaleness(t *testing.T) {
+	ds := &proxy_test.MockDatastore{}
+	ds.On("UniqueID").Return(datastoreID, nil)
+
+	stale := newStaleRevisions(WithMaximumRequestedStaleness(time.Minute))
+	for _, staleness := range []string{"soon", "-1s"} {
+		err := addRevisionToContext(withRequestedStaleness(staleness), &v1.ReadRelationshipsRequest{}, ds, stale, nil, nil, nil)
+		spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonInvalidArgument, err)
+	}
+}
@@ -0,0 +1,95 @@
+package consistency
+
+import (
+	"context"
+	"errors"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"github.com/authzed/authzed-go/pkg/responsemeta"
+	"google.golang.org/grpc/metadata"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+const (
+	// RequestRevisionSubstitution, if specified in the request header of a call at an
+	// exact snapshot or in a session whose revision has been garbage collected, serves the
+	// call at the nearest revision still available rather than failing it, and flags the
+	// substitution in the RevisionSubstitutedHeader response header.
+	// Value: `1`
+	RequestRevisionSubstitution requestmeta.BoolRequestMetadataHeaderKey = "io.spicedb.requestrevisionsubstitution"
+
+	// RevisionSubstitutedHeader is the response header set on calls served at a revision
+	// substituted for the garbage collected one requested, holding the zedtoken of the
+	// revision at which the call was served.
+	RevisionSubstitutedHeader responsemeta.ResponseMetadataHeaderKey = "io.spicedb.respmeta.revisionsubstituted"
+)
+
+// WithExpiredRevisionSubstitution sets whether all calls at a garbage collected revision
+// are served at the nearest revision still available, as if they had specified the
+// RequestRevisionSubstitution header.
+//
+// default: false
+func WithExpiredRevisionSubstitution(enabled bool) Option {
+	return func(o *options) {
+		o.substituteExpiredRevisions = enabled
+	}
+}
+
+// revisionSubstitution substitutes revisions for those of calls which have been garbage
+// collected, for calls which requested it or on servers where it is enabled for all.
+type revisionSubstitution struct {
+	serverWide bool
+}
+
+func newRevisionSubstitution(opts ...Option) *revisionSubstitution {
+	return &revisionSubstitution{serverWide: newOptions(opts...).substituteExpiredRevisions}
+}
+
+func (rs *revisionSubstitution) enabled(ctx context.Context) bool {
+	if rs == nil {
+		return false
+	}
+	if rs.serverWide {
+		return true
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	_, ok = md[string(RequestRevisionSubstitution)]
+	return ok
+}
+
+// checkedRevision returns the requested revision if it can still be read, and otherwise
+// the datastore's optimized revision if the requested one was garbage collected and the
+// call accepts a substitution. The optimized revision is used as the nearest available
+// one, since revisions older than it are themselves next to be garbage collected.
+func (rs *revisionSubstitution) checkedRevision(ctx context.Context, ds datastore.Datastore, requested datastore.Revision, binding zedtoken.Binding) (datastore.Revision, error) {
+	err := ds.CheckRevision(ctx, requested)
+	if err == nil {
+		return requested, nil
+	}
+
+	var invalidRevisionError datastore.ErrInvalidRevision
+	if !errors.As(err, &invalidRevisionError) || invalidRevisionError.Reason() != datastore.RevisionStale || !rs.enabled(ctx) {
+		return datastore.NoRevision, err
+	}
+
+	substituted, err := ds.OptimizedRevision(ctx)
+	if err != nil {
+		return datastore.NoRevision, err
+	}
+
+	log.Ctx(ctx).Debug().Stringer("requested", requested).Stringer("substituted", substituted).Msg("substituted revision for garbage collected revision")
+	err = responsemeta.SetResponseHeaderMetadata(ctx, map[responsemeta.ResponseMetadataHeaderKey]string{
+		RevisionSubstitutedHeader: zedtoken.NewFromDatastoreRevision(substituted, ds, binding).Token,
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("consistency: could not flag substituted revision")
+	}
+	return substituted, nil
+}
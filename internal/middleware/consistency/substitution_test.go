@@ -0,0 +1,73 @@
+package consistency
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func TestRevisionSubstitution(t *testing.T) {
+	ds := commitTimes{&proxy_test.MockDatastore{}}
+	ds.On("UniqueID").Return(datastoreID, nil)
+	ds.On("OptimizedRevision").Return(optimized, nil)
+	ds.On("RevisionFromString", zero.String()).Return(zero, nil)
+	ds.On("RevisionFromString", exact.String()).Return(exact, nil)
+	ds.On("CheckRevision", zero).Return(datastore.NewInvalidRevisionErr(zero, datastore.RevisionStale))
+	ds.On("CheckRevision", exact).Return(datastore.NewInvalidRevisionErr(exact, datastore.CouldNotDetermineRevision))
+
+	atExactSnapshot := func(revision datastore.Revision) *v1.ReadRelationshipsRequest {
+		return &v1.ReadRelationshipsRequest{Consistency: &v1.Consistency{
+			Requirement: &v1.Consistency_AtExactSnapshot{AtExactSnapshot: zedtoken.NewFromDatastoreRevision(revision, ds, binding)},
+		}}
+	}
+	substitutedToken := zedtoken.NewFromDatastoreRevision(optimized, ds, binding).Token
+
+	for _, tc := range []struct {
+		name           string
+		serverWide     bool
+		requested      bool
+		session        string
+		req            *v1.ReadRelationshipsRequest
+		expectedReason spiceerrors.ExtendedReason
+	}{
+		{"not accepted", false, false, "", atExactSnapshot(zero), spiceerrors.ReasonRevisionExpired},
+		{"requested", false, true, "", atExactSnapshot(zero), ""},
+		{"server-wide", true, false, "", atExactSnapshot(zero), ""},
+		{"session", false, true, zedtoken.NewFromDatastoreRevision(zero, ds, binding).Token, &v1.ReadRelationshipsRequest{}, ""},
+		{"revision not stale", true, true, "", atExactSnapshot(exact), spiceerrors.ReasonInvalidRevision},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			md := metadata.MD{}
+			if tc.requested {
+				md.Set(string(RequestRevisionSubstitution), "1")
+			}
+			if tc.session != "" {
+				md.Set(string(RequestSession), tc.session)
+			}
+			stream := &headerStream{}
+			ctx := ContextWithHandle(grpc.NewContextWithServerTransportStream(metadata.NewIncomingContext(context.Background(), md), stream))
+
+			substitution := newRevisionSubstitution(WithExpiredRevisionSubstitution(tc.serverWide))
+			err := addRevisionToContext(ctx, tc.req, ds, nil, nil, substitution, nil)
+			if tc.expectedReason != "" {
+				spiceerrors.RequireExtendedReason(t, tc.expectedReason, err)
+				require.Empty(t, stream.header.Get(string(RevisionSubstitutedHeader)))
+				return
+			}
+
+			require.NoError(t, err)
+			require.True(t, optimized.Equal(RevisionFromContext(ctx)))
+			require.Equal(t, []string{substitutedToken}, stream.header.Get(string(RevisionSubstitutedHeader)))
+		})
+	}
+}
@@ -0,0 +1,35 @@
+// Package errorinfo provides middleware which guarantees that every error returned by
+// the API carries a google.rpc.ErrorInfo detail, so that clients can branch on the
+// reason of an error without matching on its message.
+package errorinfo
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+)
+
+// UnaryServerInterceptor returns a new unary server interceptor which adds an ErrorInfo
+// detail with a generic reason to any returned error lacking one.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, spiceerrors.EnsureErrorInfo(err)
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor which adds an ErrorInfo
+// detail with a generic reason to any returned error lacking one.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, stream); err != nil {
+			return spiceerrors.EnsureErrorInfo(err)
+		}
+		return nil
+	}
+}
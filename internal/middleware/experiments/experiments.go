@@ -0,0 +1,46 @@
+package experiments
+
+import (
+	"context"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+
+	"github.com/authzed/spicedb/internal/experiments"
+)
+
+type ctxKeyType struct{}
+
+var registryKey ctxKeyType = struct{}{}
+
+// ContextWithRegistry adds the registry of namespace experiments to the context.
+func ContextWithRegistry(ctx context.Context, registry *experiments.Registry) context.Context {
+	return context.WithValue(ctx, registryKey, registry)
+}
+
+// FromContext reads the registry of namespace experiments out of a context.Context,
+// returning nil, which has no experiments enabled, if it does not exist.
+func FromContext(ctx context.Context) *experiments.Registry {
+	if registry, ok := ctx.Value(registryKey).(*experiments.Registry); ok {
+		return registry
+	}
+	return nil
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that adds the
+// registry of namespace experiments to the context
+func UnaryServerInterceptor(registry *experiments.Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(ContextWithRegistry(ctx, registry), req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that adds the
+// registry of namespace experiments to the context
+func StreamServerInterceptor(registry *experiments.Registry) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = ContextWithRegistry(wrapped.WrappedContext, registry)
+		return handler(srv, wrapped)
+	}
+}
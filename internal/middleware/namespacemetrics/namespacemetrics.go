@@ -0,0 +1,229 @@
+// Package namespacemetrics reports the count and latency of API requests labeled with the
+// resource object type they target, alongside the gRPC server metrics of
+// go-grpc-prometheus. The label values are bounded by the object types defined in the
+// schema, with any other type reported as `other`.
+package namespacemetrics
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+const (
+	// unknownObjectType is the label value used when the object type of a request
+	// cannot be determined.
+	unknownObjectType = "unknown"
+
+	// multipleObjectTypes is the label value used for requests which touch more than
+	// a single object type, such as WriteRelationships calls with mixed updates.
+	multipleObjectTypes = "multiple"
+
+	// otherObjectType is the label value used for object types which are not defined in
+	// the schema, as last loaded.
+	otherObjectType = "other"
+)
+
+var (
+	requestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "api",
+		Name:      "object_type_requests_total",
+		Help:      "Total number of API requests made, by method and resource object type.",
+	}, []string{"method", "object_type", "code"})
+
+	requestDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "spicedb",
+		Subsystem: "api",
+		Name:      "object_type_request_duration_seconds",
+		Help:      "Histogram of API request latency, by method and resource object type.",
+		Buckets:   []float64{.006, .010, .018, .024, .032, .042, .056, .075, .100, .178, .316, .562, 1.000},
+	}, []string{"method", "object_type"})
+)
+
+// RegisterMetrics registers the metrics of the API requests with the default Prometheus
+// registry. It must be called at most once per process.
+func RegisterMetrics() error {
+	for _, metric := range []prometheus.Collector{requestsCounter, requestDurationHistogram} {
+		if err := prometheus.Register(metric); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type objectTypeSet map[string]struct{}
+
+// ObjectTypes holds the object types defined in the schema, as last loaded from the
+// datastore, which are the object types reported as label values.
+type ObjectTypes struct {
+	ds      datastore.Datastore
+	defined atomic.Pointer[objectTypeSet]
+}
+
+// NewObjectTypes creates a set of the object types defined in the schema stored in the
+// datastore, with none defined until it is first refreshed.
+func NewObjectTypes(ds datastore.Datastore) *ObjectTypes {
+	return &ObjectTypes{ds: ds}
+}
+
+// label returns the label value reported for the object type. A nil set defines no
+// object types.
+func (ot *ObjectTypes) label(objectType string) string {
+	if objectType == unknownObjectType || objectType == multipleObjectTypes {
+		return objectType
+	}
+	if ot == nil {
+		return otherObjectType
+	}
+
+	defined := ot.defined.Load()
+	if defined == nil {
+		return otherObjectType
+	}
+	if _, ok := (*defined)[objectType]; !ok {
+		return otherObjectType
+	}
+	return objectType
+}
+
+// Refresh reloads the object types from the schema at the head revision.
+func (ot *ObjectTypes) Refresh(ctx context.Context) error {
+	headRevision, err := ot.ds.HeadRevision(ctx)
+	if err != nil {
+		return err
+	}
+
+	definitions, err := ot.ds.SnapshotReader(headRevision).ListNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	defined := make(objectTypeSet, len(definitions))
+	for _, definition := range definitions {
+		defined[definition.Name] = struct{}{}
+	}
+	ot.defined.Store(&defined)
+	return nil
+}
+
+// Start refreshes the object types every interval until the context is canceled.
+func (ot *ObjectTypes) Start(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := ot.Refresh(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Ctx(ctx).Warn().Err(err).Msg("unable to refresh the object types of the request metrics")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+type reporter struct {
+	objectTypes *ObjectTypes
+}
+
+func (r *reporter) ServerReporter(ctx context.Context, callMeta interceptors.CallMeta) (interceptors.Reporter, context.Context) {
+	sr := &serverReporter{objectTypes: r.objectTypes, methodName: callMeta.Method, objectType: unknownObjectType}
+	if callMeta.ReqProtoOrNil != nil {
+		sr.objectType = ObjectTypeForRequest(callMeta.ReqProtoOrNil)
+	}
+	return sr, ctx
+}
+
+type serverReporter struct {
+	interceptors.NoopReporter
+	objectTypes *ObjectTypes
+	methodName  string
+	objectType  string
+}
+
+func (sr *serverReporter) PostMsgReceive(req interface{}, err error, _ time.Duration) {
+	if err == nil && sr.objectType == unknownObjectType {
+		sr.objectType = ObjectTypeForRequest(req)
+	}
+}
+
+func (sr *serverReporter) PostCall(err error, duration time.Duration) {
+	objectType := sr.objectTypes.label(sr.objectType)
+	requestsCounter.WithLabelValues(sr.methodName, objectType, status.Code(err).String()).Inc()
+	requestDurationHistogram.WithLabelValues(sr.methodName, objectType).Observe(duration.Seconds())
+}
+
+type hasResource interface {
+	GetResource() *v1.ObjectReference
+}
+
+type hasResourceObjectType interface {
+	GetResourceObjectType() string
+}
+
+type hasRelationshipFilter interface {
+	GetRelationshipFilter() *v1.RelationshipFilter
+}
+
+// ObjectTypeForRequest returns the resource object type targeted by an API request,
+// `multiple` if the request targets more than one type, or `unknown` if it cannot be
+// determined.
+func ObjectTypeForRequest(req interface{}) string {
+	switch typed := req.(type) {
+	case hasResource:
+		return nonEmptyOrUnknown(typed.GetResource().GetObjectType())
+
+	case hasResourceObjectType:
+		return nonEmptyOrUnknown(typed.GetResourceObjectType())
+
+	case hasRelationshipFilter:
+		return nonEmptyOrUnknown(typed.GetRelationshipFilter().GetResourceType())
+
+	case *v1.WriteRelationshipsRequest:
+		objectType := ""
+		for _, update := range typed.GetUpdates() {
+			updateType := update.GetRelationship().GetResource().GetObjectType()
+			if objectType != "" && objectType != updateType {
+				return multipleObjectTypes
+			}
+			objectType = updateType
+		}
+		return nonEmptyOrUnknown(objectType)
+
+	default:
+		return unknownObjectType
+	}
+}
+
+func nonEmptyOrUnknown(objectType string) string {
+	if objectType == "" {
+		return unknownObjectType
+	}
+	return objectType
+}
+
+// UnaryServerInterceptor returns a new interceptor which reports the count and latency of
+// requests, labeled with their resource object type if it is one of the object types.
+func UnaryServerInterceptor(objectTypes *ObjectTypes) grpc.UnaryServerInterceptor {
+	return interceptors.UnaryServerInterceptor(&reporter{objectTypes: objectTypes})
+}
+
+// StreamServerInterceptor returns a new interceptor which reports the count and latency of
+// requests, labeled with their resource object type if it is one of the object types.
+func StreamServerInterceptor(objectTypes *ObjectTypes) grpc.StreamServerInterceptor {
+	return interceptors.StreamServerInterceptor(&reporter{objectTypes: objectTypes})
+}
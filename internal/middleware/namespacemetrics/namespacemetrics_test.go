@@ -0,0 +1,131 @@
+package namespacemetrics
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestObjectTypeForRequest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		req      interface{}
+		expected string
+	}{
+		{
+			"check",
+			&v1.CheckPermissionRequest{Resource: &v1.ObjectReference{ObjectType: "document", ObjectId: "1"}},
+			"document",
+		},
+		{
+			"lookup resources",
+			&v1.LookupResourcesRequest{ResourceObjectType: "folder"},
+			"folder",
+		},
+		{
+			"read relationships",
+			&v1.ReadRelationshipsRequest{RelationshipFilter: &v1.RelationshipFilter{ResourceType: "org"}},
+			"org",
+		},
+		{
+			"write relationships single type",
+			&v1.WriteRelationshipsRequest{Updates: []*v1.RelationshipUpdate{
+				tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.MustParse("document:1#viewer@user:tom"))),
+				tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.MustParse("document:2#viewer@user:tom"))),
+			}},
+			"document",
+		},
+		{
+			"write relationships multiple types",
+			&v1.WriteRelationshipsRequest{Updates: []*v1.RelationshipUpdate{
+				tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.MustParse("document:1#viewer@user:tom"))),
+				tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.MustParse("folder:2#viewer@user:tom"))),
+			}},
+			"multiple",
+		},
+		{
+			"missing resource",
+			&v1.CheckPermissionRequest{},
+			"unknown",
+		},
+		{
+			"unrelated request",
+			&v1.ReadSchemaRequest{},
+			"unknown",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, ObjectTypeForRequest(tc.req))
+		})
+	}
+}
+
+func TestLabelsAreSchemaDerived(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	objectTypes := NewObjectTypes(ds)
+	require.Equal("other", objectTypes.label("document"))
+
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, ns.Namespace("user"), ns.Namespace("document"))
+	})
+	require.NoError(err)
+	require.NoError(objectTypes.Refresh(ctx))
+
+	require.Equal("document", objectTypes.label("document"))
+	require.Equal("other", objectTypes.label("folder"))
+	require.Equal("unknown", objectTypes.label("unknown"))
+	require.Equal("multiple", objectTypes.label("multiple"))
+
+	var noObjectTypes *ObjectTypes
+	require.Equal("other", noObjectTypes.label("document"))
+	require.Equal("unknown", noObjectTypes.label("unknown"))
+}
+
+func TestInterceptorReportsObjectType(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, ns.Namespace("user"), ns.Namespace("document"))
+	})
+	require.NoError(err)
+
+	objectTypes := NewObjectTypes(ds)
+	require.NoError(objectTypes.Refresh(ctx))
+
+	interceptor := UnaryServerInterceptor(objectTypes)
+	info := &grpc.UnaryServerInfo{FullMethod: "/authzed.api.v1.PermissionsService/CheckPermission"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &v1.CheckPermissionResponse{}, nil
+	}
+
+	for _, objectType := range []string{"document", "folder"} {
+		_, err := interceptor(ctx, &v1.CheckPermissionRequest{
+			Resource: &v1.ObjectReference{ObjectType: objectType, ObjectId: "1"},
+		}, info, handler)
+		require.NoError(err)
+	}
+
+	require.Equal(1.0, testutil.ToFloat64(requestsCounter.WithLabelValues("CheckPermission", "document", "OK")))
+	require.Equal(1.0, testutil.ToFloat64(requestsCounter.WithLabelValues("CheckPermission", "other", "OK")))
+}
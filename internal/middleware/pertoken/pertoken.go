@@ -2,7 +2,12 @@ package pertoken
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -10,6 +15,7 @@ import (
 	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
 	"google.golang.org/grpc"
 
+	"github.com/authzed/spicedb/internal/datastore/backup"
 	"github.com/authzed/spicedb/internal/datastore/memdb"
 	log "github.com/authzed/spicedb/internal/logging"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
@@ -22,27 +28,54 @@ const (
 	revisionQuantization = 10 * time.Millisecond
 )
 
+// persistedFileExtension is the extension of the files to which datastores are persisted.
+const persistedFileExtension = ".backup"
+
 // MiddlewareForTesting is used to create a unique datastore for each token. It is intended for use in the
 // testserver only.
 type MiddlewareForTesting struct {
 	datastoreByToken *sync.Map
 	configFilePaths  []string
+	persistDir       string
+}
+
+type tokenDatastore struct {
+	datastore.Datastore
+
+	// persistedRevision is the head revision of the datastore when it was last persisted. It is
+	// only accessed by Persist.
+	persistedRevision datastore.Revision
 }
 
 // NewMiddleware returns a new per-token datastore middleware that initializes each datastore with the data in the
 // config files.
 func NewMiddleware(configFilePaths []string) *MiddlewareForTesting {
+	return NewPersistentMiddleware(configFilePaths, "")
+}
+
+// NewPersistentMiddleware returns a new per-token datastore middleware whose datastores are restored from the
+// directory when first used, if they were persisted to it by an earlier call to Persist. Datastores which were not
+// persisted are initialized with the data in the config files. If the directory is empty, nothing is persisted.
+func NewPersistentMiddleware(configFilePaths []string, persistDir string) *MiddlewareForTesting {
 	return &MiddlewareForTesting{
 		datastoreByToken: &sync.Map{},
 		configFilePaths:  configFilePaths,
+		persistDir:       persistDir,
 	}
 }
 
+// persistedPath returns the path of the file to which the datastore of the token is persisted. Tokens are hashed,
+// so that they are not written to disk.
+func (m *MiddlewareForTesting) persistedPath(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return filepath.Join(m.persistDir, hex.EncodeToString(sum[:])+persistedFileExtension)
+}
+
 func (m *MiddlewareForTesting) getOrCreateDatastore(ctx context.Context) (datastore.Datastore, error) {
 	tokenStr, _ := grpcauth.AuthFromMD(ctx, "bearer")
-	tokenDatastore, ok := m.datastoreByToken.Load(tokenStr)
+	existing, ok := m.datastoreByToken.Load(tokenStr)
 	if ok {
-		return tokenDatastore.(datastore.Datastore), nil
+		return existing.(*tokenDatastore), nil
 	}
 
 	log.Debug().Str("token", tokenStr).Msg("initializing new upstream for token")
@@ -51,14 +84,90 @@ func (m *MiddlewareForTesting) getOrCreateDatastore(ctx context.Context) (datast
 		return nil, fmt.Errorf("failed to init datastore: %w", err)
 	}
 
-	_, _, err = validationfile.PopulateFromFiles(ds, m.configFilePaths)
+	restored, err := m.restore(ctx, tokenStr, ds)
+	if err != nil {
+		return nil, err
+	}
+
+	if !restored {
+		_, _, err = validationfile.PopulateFromFiles(ds, m.configFilePaths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config files: %w", err)
+		}
+	}
+
+	// Another request for the same token may have won the race to create its datastore.
+	actual, _ := m.datastoreByToken.LoadOrStore(tokenStr, &tokenDatastore{Datastore: ds})
+	return actual.(*tokenDatastore), nil
+}
+
+// restore loads the persisted datastore of the token, if any, returning whether one was found.
+func (m *MiddlewareForTesting) restore(ctx context.Context, token string, ds datastore.Datastore) (bool, error) {
+	if m.persistDir == "" {
+		return false, nil
+	}
+
+	f, err := os.Open(m.persistedPath(token))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to load config files: %w", err)
+		return false, fmt.Errorf("failed to open persisted datastore: %w", err)
 	}
+	defer f.Close()
+
+	if _, _, err := backup.Restore(ctx, ds, f, backup.DefaultRestoreBatchSize, false); err != nil {
+		return false, fmt.Errorf("failed to restore persisted datastore: %w", err)
+	}
+	return true, nil
+}
+
+// Persist writes each datastore which has changed since it was last persisted to the persistence directory. It
+// must not be called concurrently.
+func (m *MiddlewareForTesting) Persist(ctx context.Context) error {
+	if m.persistDir == "" {
+		return nil
+	}
+
+	var persistErr error
+	m.datastoreByToken.Range(func(key, value any) bool {
+		token, ds := key.(string), value.(*tokenDatastore)
+		rev, err := ds.HeadRevision(ctx)
+		if err != nil {
+			persistErr = err
+			return false
+		}
+		if ds.persistedRevision != nil && rev.Equal(ds.persistedRevision) {
+			return true
+		}
 
-	m.datastoreByToken.Store(tokenStr, ds)
+		if err := m.persist(ctx, token, ds); err != nil {
+			persistErr = err
+			return false
+		}
+		ds.persistedRevision = rev
+		return true
+	})
+	return persistErr
+}
 
-	return ds, nil
+// persist writes the datastore to a temporary file, which is renamed over the previous one, so that a failure never
+// leaves a partially written datastore behind.
+func (m *MiddlewareForTesting) persist(ctx context.Context, token string, ds datastore.Datastore) error {
+	f, err := os.CreateTemp(m.persistDir, "*"+persistedFileExtension+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create persisted datastore: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := backup.Backup(ctx, ds, "memory", false, f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to persist datastore: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to persist datastore: %w", err)
+	}
+	return os.Rename(f.Name(), m.persistedPath(token))
 }
 
 // UnaryServerInterceptor returns a new unary server interceptor that sets a separate in-memory datastore per token
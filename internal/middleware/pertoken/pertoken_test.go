@@ -0,0 +1,88 @@
+package pertoken
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const seedConfig = `---
+schema: |+
+    definition user {}
+
+    definition document {
+        relation viewer: user
+    }
+
+relationships: >-
+    document:seeded#viewer@user:tom
+`
+
+func contextWithToken(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer "+token))
+}
+
+func relationships(t *testing.T, ds datastore.Datastore) []string {
+	ctx := context.Background()
+	rev, err := ds.HeadRevision(ctx)
+	require.NoError(t, err)
+
+	iter, err := ds.SnapshotReader(rev).QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})
+	require.NoError(t, err)
+	defer iter.Close()
+
+	var rels []string
+	for rel := iter.Next(); rel != nil; rel = iter.Next() {
+		rels = append(rels, tuple.String(rel))
+	}
+	require.NoError(t, iter.Err())
+	sort.Strings(rels)
+	return rels
+}
+
+func TestPersistentMiddleware(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "seed.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(seedConfig), 0o600))
+	persistDir := filepath.Join(dir, "persisted")
+	require.NoError(t, os.Mkdir(persistDir, 0o700))
+
+	m := NewPersistentMiddleware([]string{configPath}, persistDir)
+	ds, err := m.getOrCreateDatastore(contextWithToken("first"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"document:seeded#viewer@user:tom"}, relationships(t, ds))
+
+	_, err = ds.ReadWriteTx(context.Background(), func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(context.Background(), []*core.RelationTupleUpdate{
+			tuple.Create(tuple.MustParse("document:written#viewer@user:fred")),
+		})
+	})
+	require.NoError(t, err)
+
+	_, err = m.getOrCreateDatastore(contextWithToken("second"))
+	require.NoError(t, err)
+	require.NoError(t, m.Persist(context.Background()))
+
+	files, err := os.ReadDir(persistDir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	// A restarted middleware restores each token's datastore, rather than seeding it again.
+	restarted := NewPersistentMiddleware([]string{configPath}, persistDir)
+	ds, err = restarted.getOrCreateDatastore(contextWithToken("first"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"document:seeded#viewer@user:tom", "document:written#viewer@user:fred"}, relationships(t, ds))
+
+	ds, err = restarted.getOrCreateDatastore(contextWithToken("second"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"document:seeded#viewer@user:tom"}, relationships(t, ds))
+}
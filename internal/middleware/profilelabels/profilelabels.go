@@ -0,0 +1,89 @@
+// Package profilelabels provides middleware which attaches pprof labels to the
+// goroutines serving API requests, allowing CPU and heap profiles to be sliced
+// by method, caller and resource object type.
+package profilelabels
+
+import (
+	"context"
+	"runtime/pprof"
+	"sync"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors"
+	"google.golang.org/grpc"
+
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/middleware/namespacemetrics"
+)
+
+const (
+	methodLabel     = "method"
+	callerLabel     = "caller"
+	objectTypeLabel = "object_type"
+
+	unknownCaller = "unknown"
+)
+
+func labelsFor(ctx context.Context, fullMethod string, req interface{}) pprof.LabelSet {
+	_, methodName := interceptors.SplitMethodName(fullMethod)
+
+	caller := auth.PresharedKeyNameFromContext(ctx)
+	if caller == "" {
+		caller = unknownCaller
+	}
+
+	if req == nil {
+		return pprof.Labels(methodLabel, methodName, callerLabel, caller)
+	}
+
+	return pprof.Labels(
+		methodLabel, methodName,
+		callerLabel, caller,
+		objectTypeLabel, namespacemetrics.ObjectTypeForRequest(req),
+	)
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor which runs the
+// handler with pprof labels describing the request.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		pprof.Do(ctx, labelsFor(ctx, info.FullMethod, req), func(ctx context.Context) {
+			resp, err = handler(ctx, req)
+		})
+		return
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor which runs the
+// handler with pprof labels describing the request. The object type label is
+// added once the first request message has been received.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx := stream.Context()
+		pprof.Do(ctx, labelsFor(ctx, info.FullMethod, nil), func(ctx context.Context) {
+			err = handler(srv, &labeledStream{ServerStream: stream, ctx: ctx, fullMethod: info.FullMethod})
+		})
+		return
+	}
+}
+
+type labeledStream struct {
+	grpc.ServerStream
+	ctx        context.Context
+	fullMethod string
+	once       sync.Once
+}
+
+func (s *labeledStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *labeledStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.once.Do(func() {
+			s.ctx = pprof.WithLabels(s.ctx, labelsFor(s.ctx, s.fullMethod, m))
+			pprof.SetGoroutineLabels(s.ctx)
+		})
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package profilelabels
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/auth"
+)
+
+func TestUnaryLabels(t *testing.T) {
+	md := metadata.Pairs("authorization", "bearer second")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	ctx, err := auth.RequirePresharedKey([]string{"first", "second"})(ctx)
+	require.NoError(t, err)
+
+	req := &v1.CheckPermissionRequest{Resource: &v1.ObjectReference{ObjectType: "document", ObjectId: "1"}}
+	info := &grpc.UnaryServerInfo{FullMethod: "/authzed.api.v1.PermissionsService/CheckPermission"}
+
+	labels := map[string]string{}
+	_, err = UnaryServerInterceptor()(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		pprof.ForLabels(ctx, func(key, value string) bool {
+			labels[key] = value
+			return true
+		})
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		methodLabel:     "CheckPermission",
+		callerLabel:     "preshared-key-2",
+		objectTypeLabel: "document",
+	}, labels)
+}
+
+func TestUnaryLabelsUnauthenticated(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/authzed.api.v1.SchemaService/ReadSchema"}
+
+	labels := map[string]string{}
+	_, err := UnaryServerInterceptor()(context.Background(), &v1.ReadSchemaRequest{}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		pprof.ForLabels(ctx, func(key, value string) bool {
+			labels[key] = value
+			return true
+		})
+		return nil, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, unknownCaller, labels[callerLabel])
+	require.Equal(t, "ReadSchema", labels[methodLabel])
+}
@@ -0,0 +1,173 @@
+// Package querystats collects statistics of the execution of the requests which ask
+// for them, returning them in a response trailer so that callers can diagnose
+// expensive access patterns themselves.
+package querystats
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"github.com/authzed/authzed-go/pkg/responsemeta"
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+const (
+	// RequestQueryStatistics, if specified in the request header of a call, returns the
+	// statistics of its execution in the QueryStatisticsTrailer response trailer.
+	RequestQueryStatistics requestmeta.BoolRequestMetadataHeaderKey = "io.spicedb.requestquerystats"
+
+	// QueryStatisticsTrailer is the response trailer holding the statistics of the
+	// execution of a call, as a JSON Summary.
+	QueryStatisticsTrailer responsemeta.ResponseMetadataTrailerKey = "io.spicedb.respmeta.querystats"
+)
+
+// Summary summarizes the execution of a call. The relationships queried and read are
+// those of the server handling the call, including for the dispatches it resolves
+// itself, but not those of the peers it dispatches to.
+type Summary struct {
+	// RelationshipQueries is the number of queries for relationships issued to the
+	// datastore.
+	RelationshipQueries uint64 `json:"relationship_queries"`
+
+	// RelationshipsRead is the number of relationships read from the datastore by those
+	// queries.
+	RelationshipsRead uint64 `json:"relationships_read"`
+
+	// DispatchCount is the number of dispatches resolved for the call.
+	DispatchCount uint32 `json:"dispatch_count"`
+
+	// CachedDispatchCount is the number of dispatches resolved from cache.
+	CachedDispatchCount uint32 `json:"cached_dispatch_count"`
+
+	// DepthRequired is the deepest dispatch depth reached.
+	DepthRequired uint32 `json:"depth_required"`
+}
+
+// Stats collects the statistics of a call. The methods of a nil Stats do nothing, so
+// that calls not collecting statistics need not check for them.
+type Stats struct {
+	relationshipQueries atomic.Uint64
+	relationshipsRead   atomic.Uint64
+
+	mu       sync.Mutex
+	dispatch *dispatch.ResponseMeta
+}
+
+// RelationshipsQueried records a query for relationships.
+func (s *Stats) RelationshipsQueried() {
+	if s != nil {
+		s.relationshipQueries.Add(1)
+	}
+}
+
+// RelationshipRead records a relationship read by a query.
+func (s *Stats) RelationshipRead() {
+	if s != nil {
+		s.relationshipsRead.Add(1)
+	}
+}
+
+// SetDispatchMetadata records the metadata of the dispatches of the call.
+func (s *Stats) SetDispatchMetadata(meta *dispatch.ResponseMeta) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatch = meta
+}
+
+// Summary summarizes the statistics collected so far.
+func (s *Stats) Summary() Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return Summary{
+		RelationshipQueries: s.relationshipQueries.Load(),
+		RelationshipsRead:   s.relationshipsRead.Load(),
+		DispatchCount:       s.dispatch.GetDispatchCount(),
+		CachedDispatchCount: s.dispatch.GetCachedDispatchCount(),
+		DepthRequired:       s.dispatch.GetDepthRequired(),
+	}
+}
+
+type ctxKeyType struct{}
+
+var statsKey ctxKeyType = struct{}{}
+
+// ContextWithStats adds new statistics to the context, returning them.
+func ContextWithStats(ctx context.Context) (context.Context, *Stats) {
+	stats := &Stats{}
+	return context.WithValue(ctx, statsKey, stats), stats
+}
+
+// FromContext reads the statistics out of a context.Context, returning nil if the call
+// is not collecting them.
+func FromContext(ctx context.Context) *Stats {
+	if stats, ok := ctx.Value(statsKey).(*Stats); ok {
+		return stats
+	}
+	return nil
+}
+
+func requested(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	_, ok = md[string(RequestQueryStatistics)]
+	return ok
+}
+
+func setTrailer(ctx context.Context, stats *Stats) {
+	marshaled, err := json.Marshal(stats.Summary())
+	if err == nil {
+		err = responsemeta.SetResponseTrailerMetadata(ctx, map[responsemeta.ResponseMetadataTrailerKey]string{
+			QueryStatisticsTrailer: string(marshaled),
+		})
+	}
+	if err != nil && ctx.Err() == nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("querystats: could not return query statistics")
+	}
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that collects the
+// statistics of the calls requesting them, returning them in the response trailer.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !requested(ctx) {
+			return handler(ctx, req)
+		}
+
+		ctx, stats := ContextWithStats(ctx)
+		resp, err := handler(ctx, req)
+		setTrailer(ctx, stats)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that collects the
+// statistics of the calls requesting them, returning them in the response trailer.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !requested(stream.Context()) {
+			return handler(srv, stream)
+		}
+
+		wrapped := middleware.WrapServerStream(stream)
+		ctx, stats := ContextWithStats(wrapped.WrappedContext)
+		wrapped.WrappedContext = ctx
+		err := handler(srv, wrapped)
+		setTrailer(ctx, stats)
+		return err
+	}
+}
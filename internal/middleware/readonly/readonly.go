@@ -3,16 +3,49 @@ package readonly
 import (
 	"context"
 
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
 	"google.golang.org/grpc"
 
 	"github.com/authzed/spicedb/internal/datastore/proxy"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/services/shared"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
 )
 
-// UnaryServerInterceptor returns a new unary server interceptor that sets the datastore to readonly
+// mutatingMethods are the full names of the API methods which write to the datastore.
+// They are rejected before any validation, so that every write fails the same way in
+// read-only mode, whatever the request.
+var mutatingMethods = map[string]struct{}{
+	"/" + v1.PermissionsService_ServiceDesc.ServiceName + "/WriteRelationships":  {},
+	"/" + v1.PermissionsService_ServiceDesc.ServiceName + "/DeleteRelationships": {},
+	"/" + v1.SchemaService_ServiceDesc.ServiceName + "/WriteSchema":              {},
+
+	experimentalMethod("PinRevision"):                   {},
+	experimentalMethod("ReleasePinnedRevision"):         {},
+	experimentalMethod("DeleteOrphanedRelationships"):   {},
+	experimentalMethod("RestoreSchemaVersion"):          {},
+	experimentalMethod("SetNamespaceExperiment"):        {},
+	experimentalMethod("StartJob"):                      {},
+	experimentalMethod("CancelJob"):                     {},
+	experimentalMethod("RestoreNamespace"):              {},
+	experimentalMethod("DeleteRelationshipsFromSource"): {},
+	experimentalMethod("SetCanarySchema"):               {},
+	experimentalMethod("BulkImportRelationships"):       {},
+}
+
+func experimentalMethod(name string) string {
+	return "/" + experimental.ExperimentalService_ServiceDesc.ServiceName + "/" + name
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that rejects mutating
+// methods with shared.ErrServiceReadOnly and sets the datastore to readonly
 func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if _, ok := mutatingMethods[info.FullMethod]; ok {
+			return nil, shared.ErrServiceReadOnly
+		}
+
 		if err := datastoremw.SetInContext(ctx, proxy.NewReadonlyDatastore(datastoremw.MustFromContext(ctx))); err != nil {
 			return nil, err
 		}
@@ -21,9 +54,14 @@ func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	}
 }
 
-// StreamServerInterceptor returns a new stream server interceptor  that sets the datastore to readonly
+// StreamServerInterceptor returns a new stream server interceptor that rejects mutating
+// methods with shared.ErrServiceReadOnly and sets the datastore to readonly
 func StreamServerInterceptor() grpc.StreamServerInterceptor {
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, ok := mutatingMethods[info.FullMethod]; ok {
+			return shared.ErrServiceReadOnly
+		}
+
 		wrapped := middleware.WrapServerStream(stream)
 		if err := datastoremw.SetInContext(wrapped.WrappedContext, proxy.NewReadonlyDatastore(datastoremw.MustFromContext(stream.Context()))); err != nil {
 			return err
@@ -0,0 +1,126 @@
+package readonly
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/pkg/datastore"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		method   string
+		rejected bool
+	}{
+		{"/authzed.api.v1.PermissionsService/WriteRelationships", true},
+		{"/authzed.api.v1.PermissionsService/DeleteRelationships", true},
+		{"/authzed.api.v1.SchemaService/WriteSchema", true},
+		{"/experimental.v1.ExperimentalService/PinRevision", true},
+		{"/experimental.v1.ExperimentalService/BulkImportRelationships", true},
+		{"/experimental.v1.ExperimentalService/GetJob", false},
+		{"/authzed.api.v1.PermissionsService/CheckPermission", false},
+		{"/authzed.api.v1.SchemaService/ReadSchema", false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.method, func(t *testing.T) {
+			ctx := datastoremw.ContextWithHandle(context.Background())
+			require.NoError(t, datastoremw.SetInContext(ctx, ds))
+
+			called := false
+			// An invalid request is still rejected as read-only, rather than as invalid.
+			_, err := UnaryServerInterceptor()(ctx, &v1.WriteRelationshipsRequest{}, &grpc.UnaryServerInfo{FullMethod: tc.method},
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					called = true
+					_, err := datastoremw.MustFromContext(ctx).ReadWriteTx(ctx, func(datastore.ReadWriteTransaction) error {
+						return nil
+					})
+					require.ErrorAs(t, err, &datastore.ErrReadOnly{})
+					return nil, nil
+				})
+
+			require.Equal(t, !tc.rejected, called)
+			if !tc.rejected {
+				require.NoError(t, err)
+				return
+			}
+
+			s, ok := status.FromError(err)
+			require.True(t, ok)
+			require.Equal(t, codes.FailedPrecondition, s.Code())
+			require.Len(t, s.Details(), 1)
+			require.Equal(t, "ERROR_REASON_SERVICE_READ_ONLY", s.Details()[0].(*errdetails.ErrorInfo).Reason)
+		})
+	}
+}
+
+// readOnlyMethods are the API methods which do not write to the datastore.
+var readOnlyMethods = map[string]struct{}{
+	"/authzed.api.v1.PermissionsService/CheckPermission":             {},
+	"/authzed.api.v1.PermissionsService/ExpandPermissionTree":        {},
+	"/authzed.api.v1.PermissionsService/ReadRelationships":           {},
+	"/authzed.api.v1.PermissionsService/LookupResources":             {},
+	"/authzed.api.v1.PermissionsService/LookupSubjects":              {},
+	"/authzed.api.v1.SchemaService/ReadSchema":                       {},
+	"/authzed.api.v1.WatchService/Watch":                             {},
+	"/experimental.v1.ExperimentalService/FindOrphanedRelationships": {},
+	"/experimental.v1.ExperimentalService/ReachableResources":        {},
+	"/experimental.v1.ExperimentalService/LookupResourcesSet":        {},
+	"/experimental.v1.ExperimentalService/LookupResourcesFilter":     {},
+	"/experimental.v1.ExperimentalService/ListSchemaVersions":        {},
+	"/experimental.v1.ExperimentalService/ReadSchemaVersion":         {},
+	"/experimental.v1.ExperimentalService/DiffSchemaVersions":        {},
+	"/experimental.v1.ExperimentalService/ListNamespaceExperiments":  {},
+	"/experimental.v1.ExperimentalService/StreamingCheckPermission":  {},
+	"/experimental.v1.ExperimentalService/PrefetchChecks":            {},
+	"/experimental.v1.ExperimentalService/BulkCheckPermission":       {},
+	"/experimental.v1.ExperimentalService/GetJob":                    {},
+	"/experimental.v1.ExperimentalService/ListJobs":                  {},
+	"/experimental.v1.ExperimentalService/ListNamespaceTombstones":   {},
+	"/experimental.v1.ExperimentalService/GetCanarySchema":           {},
+	"/experimental.v1.ExperimentalService/AccessReport":              {},
+	"/experimental.v1.ExperimentalService/SubjectEntitlements":       {},
+	"/experimental.v1.ExperimentalService/DiffRelationships":         {},
+	"/experimental.v1.ExperimentalService/DeleteRelationshipsImpact": {},
+}
+
+// TestEveryMethodClassified requires each method of the served APIs to be either
+// rejected in read-only mode or known not to write, so that new methods which write are
+// not left out of mutatingMethods.
+func TestEveryMethodClassified(t *testing.T) {
+	for _, desc := range []grpc.ServiceDesc{
+		v1.PermissionsService_ServiceDesc,
+		v1.SchemaService_ServiceDesc,
+		v1.WatchService_ServiceDesc,
+		experimental.ExperimentalService_ServiceDesc,
+	} {
+		names := make([]string, 0, len(desc.Methods)+len(desc.Streams))
+		for _, method := range desc.Methods {
+			names = append(names, method.MethodName)
+		}
+		for _, stream := range desc.Streams {
+			names = append(names, stream.StreamName)
+		}
+
+		for _, name := range names {
+			fullMethod := "/" + desc.ServiceName + "/" + name
+			_, mutating := mutatingMethods[fullMethod]
+			_, readOnly := readOnlyMethods[fullMethod]
+			require.True(t, mutating != readOnly, "%s must be classified as either mutating or read-only", fullMethod)
+		}
+	}
+}
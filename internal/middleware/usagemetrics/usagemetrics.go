@@ -12,6 +12,7 @@ import (
 	"google.golang.org/grpc"
 
 	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/middleware/querystats"
 	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
@@ -80,6 +81,7 @@ func StreamServerInterceptor() grpc.StreamServerInterceptor {
 func annotateAndReportForMetadata(ctx context.Context, methodName string, metadata *dispatch.ResponseMeta) error {
 	DispatchedCountHistogram.WithLabelValues(methodName, "false").Observe(float64(metadata.DispatchCount))
 	DispatchedCountHistogram.WithLabelValues(methodName, "true").Observe(float64(metadata.CachedDispatchCount))
+	querystats.FromContext(ctx).SetDispatchMetadata(metadata)
 
 	return responsemeta.SetResponseTrailerMetadata(ctx, map[responsemeta.ResponseMetadataTrailerKey]string{
 		responsemeta.DispatchedOperationsCount: strconv.Itoa(int(metadata.DispatchCount)),
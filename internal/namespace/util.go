@@ -3,6 +3,8 @@ package namespace
 import (
 	"context"
 
+	"golang.org/x/exp/maps"
+
 	"github.com/authzed/spicedb/internal/util"
 
 	"github.com/authzed/spicedb/pkg/datastore"
@@ -53,6 +55,47 @@ func CheckNamespaceAndRelation(
 		return err
 	}
 
+	return checkRelation(config, relation, allowEllipsis)
+}
+
+// RelationToCheck is a namespace and relation whose existence is checked by
+// CheckNamespacesAndRelations.
+type RelationToCheck struct {
+	Namespace     string
+	Relation      string
+	AllowEllipsis bool
+}
+
+// CheckNamespacesAndRelations checks that each of the specified namespaces and relations
+// exist in the datastore, reading all of the namespaces in a single lookup.
+//
+// Returns datastore.ErrNamespaceNotFound for the first namespace which cannot be found.
+// Returns ErrRelationNotFound for the first relation not found in its namespace.
+// Returns the direct downstream error for all other unknown error.
+func CheckNamespacesAndRelations(
+	ctx context.Context,
+	ds datastore.Reader,
+	toCheck ...RelationToCheck,
+) error {
+	nsNames := make([]string, 0, len(toCheck))
+	for _, check := range toCheck {
+		nsNames = append(nsNames, check.Namespace)
+	}
+
+	nsDefs, err := ReadNamespaces(ctx, nsNames, ds)
+	if err != nil {
+		return err
+	}
+
+	for _, check := range toCheck {
+		if err := checkRelation(nsDefs[check.Namespace], check.Relation, check.AllowEllipsis); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkRelation(config *core.NamespaceDefinition, relation string, allowEllipsis bool) error {
 	if allowEllipsis && relation == datastore.Ellipsis {
 		return nil
 	}
@@ -63,7 +106,65 @@ func CheckNamespaceAndRelation(
 		}
 	}
 
-	return NewRelationNotFoundErr(namespace, relation)
+	return NewRelationNotFoundErr(config.Name, relation)
+}
+
+// ReadNamespaces reads the namespace definitions with the given names in a single lookup,
+// returning them keyed by name.
+//
+// Returns datastore.ErrNamespaceNotFound for the first namespace which cannot be found.
+func ReadNamespaces(
+	ctx context.Context,
+	nsNames []string,
+	ds datastore.Reader,
+) (map[string]*core.NamespaceDefinition, error) {
+	uniqueNames := util.NewSet(nsNames...).AsSlice()
+	found, err := ds.LookupNamespaces(ctx, uniqueNames)
+	if err != nil {
+		return nil, err
+	}
+
+	nsDefs := make(map[string]*core.NamespaceDefinition, len(found))
+	for _, nsDef := range found {
+		nsDefs[nsDef.Name] = nsDef
+	}
+
+	for _, nsName := range nsNames {
+		if _, ok := nsDefs[nsName]; !ok {
+			return nil, datastore.NewNamespaceNotFoundErr(nsName)
+		}
+	}
+	return nsDefs, nil
+}
+
+// ReadNamespacesAndTypes reads the namespace definitions with the given names in a single
+// lookup and returns the type system for each, keyed by name. The type systems resolve
+// the namespaces read together without returning to the datastore.
+//
+// Returns datastore.ErrNamespaceNotFound for the first namespace which cannot be found.
+func ReadNamespacesAndTypes(
+	ctx context.Context,
+	nsNames []string,
+	ds datastore.Reader,
+) (map[string]*TypeSystem, error) {
+	nsDefs, err := ReadNamespaces(ctx, nsNames, ds)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := ResolverForDatastoreReader(ds).WithPredefinedElements(PredefinedElements{
+		Namespaces: maps.Values(nsDefs),
+	})
+
+	typeSystems := make(map[string]*TypeSystem, len(nsDefs))
+	for name, nsDef := range nsDefs {
+		ts, err := NewNamespaceTypeSystem(nsDef, resolver)
+		if err != nil {
+			return nil, err
+		}
+		typeSystems[name] = ts
+	}
+	return typeSystems, nil
 }
 
 // ReadNamespaceAndTypes reads a namespace definition, version, and type system and returns it if found.
@@ -1,11 +1,14 @@
 package namespace
 
 import (
+	"context"
 	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
 	ns "github.com/authzed/spicedb/pkg/namespace"
@@ -66,3 +69,105 @@ func TestListReferencedNamespaces(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckNamespacesAndRelations(t *testing.T) {
+	testCases := []struct {
+		name          string
+		toCheck       []RelationToCheck
+		expectedError string
+	}{
+		{
+			"valid",
+			[]RelationToCheck{
+				{Namespace: "document", Relation: "viewer"},
+				{Namespace: "user", Relation: datastore.Ellipsis, AllowEllipsis: true},
+			},
+			"",
+		},
+		{
+			"same namespace twice",
+			[]RelationToCheck{
+				{Namespace: "document", Relation: "viewer"},
+				{Namespace: "document", Relation: "parent"},
+			},
+			"",
+		},
+		{
+			"missing namespace",
+			[]RelationToCheck{
+				{Namespace: "document", Relation: "viewer"},
+				{Namespace: "folder", Relation: "viewer"},
+			},
+			"object definition `folder` not found",
+		},
+		{
+			"missing relation",
+			[]RelationToCheck{
+				{Namespace: "document", Relation: "viewer"},
+				{Namespace: "document", Relation: "owner"},
+			},
+			"relation/permission `owner` not found under definition `document`",
+		},
+		{
+			"ellipsis not allowed",
+			[]RelationToCheck{
+				{Namespace: "user", Relation: datastore.Ellipsis},
+			},
+			"relation/permission `...` not found under definition `user`",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			reader := readerWithNamespaces(t,
+				ns.Namespace("user"),
+				ns.Namespace("document",
+					ns.Relation("viewer", nil, ns.AllowedRelation("user", "...")),
+					ns.Relation("parent", nil, ns.AllowedRelation("document", "...")),
+				),
+			)
+
+			err := CheckNamespacesAndRelations(context.Background(), reader, tc.toCheck...)
+			if tc.expectedError == "" {
+				require.NoError(err)
+				return
+			}
+			require.EqualError(err, tc.expectedError)
+		})
+	}
+}
+
+func TestReadNamespacesAndTypes(t *testing.T) {
+	require := require.New(t)
+	reader := readerWithNamespaces(t,
+		ns.Namespace("user"),
+		ns.Namespace("document",
+			ns.Relation("viewer", nil, ns.AllowedRelation("user", "...")),
+		),
+	)
+
+	typeSystems, err := ReadNamespacesAndTypes(context.Background(), []string{"document", "user", "document"}, reader)
+	require.NoError(err)
+	require.Len(typeSystems, 2)
+	require.True(typeSystems["document"].HasRelation("viewer"))
+
+	_, err = typeSystems["document"].Validate(context.Background())
+	require.NoError(err)
+
+	_, err = ReadNamespacesAndTypes(context.Background(), []string{"document", "folder"}, reader)
+	require.ErrorAs(err, &datastore.ErrNamespaceNotFound{})
+}
+
+func readerWithNamespaces(t *testing.T, nsDefs ...*core.NamespaceDefinition) datastore.Reader {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, nsDefs...)
+	})
+	require.NoError(t, err)
+	return ds.SnapshotReader(revision)
+}
@@ -0,0 +1,161 @@
+// Package profiling implements continuous profiling by periodically capturing
+// CPU and heap profiles and pushing them to a Pyroscope-compatible ingestion
+// endpoint.
+//
+// Pull-based profilers, such as the Parca agent, do not need this package and
+// can instead scrape the pprof endpoints exposed on the metrics server.
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"runtime/pprof"
+	"strconv"
+	"time"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+const (
+	// DefaultInterval is the default length of each profile pushed.
+	DefaultInterval = 10 * time.Second
+
+	// MinimumAllowedInterval is the minimum length of each profile pushed.
+	MinimumAllowedInterval = 1 * time.Second
+
+	// DefaultApplicationName is the default name under which profiles are pushed.
+	DefaultApplicationName = "spicedb"
+
+	cpuSampleRate = 100
+)
+
+// Pusher captures and pushes profiles until its context is canceled.
+type Pusher func(ctx context.Context) error
+
+// DisabledPusher is a Pusher which does nothing.
+func DisabledPusher(_ context.Context) error { return nil }
+
+// NewPusher creates a Pusher which captures a CPU profile over each interval, along
+// with a heap profile at its end, and pushes them to the ingestion endpoint.
+//
+// The Go runtime only supports a single CPU profile at a time, so while the pusher
+// is running, CPU profiles cannot be requested from the pprof endpoints.
+func NewPusher(endpoint, applicationName string, interval time.Duration) (Pusher, error) {
+	ingestURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profiling endpoint: %w", err)
+	}
+	if ingestURL.Scheme == "" || ingestURL.Host == "" {
+		return nil, fmt.Errorf("invalid profiling endpoint `%s`: must be an absolute URL", endpoint)
+	}
+	if interval < MinimumAllowedInterval {
+		return nil, fmt.Errorf("invalid profiling interval: %s < %s", interval, MinimumAllowedInterval)
+	}
+	if applicationName == "" {
+		applicationName = DefaultApplicationName
+	}
+
+	ingestURL = ingestURL.JoinPath("ingest")
+	client := &http.Client{Timeout: interval}
+
+	return func(ctx context.Context) error {
+		log.Info().
+			Stringer("interval", interval).
+			Str("endpoint", endpoint).
+			Msg("continuous profiling started")
+
+		for {
+			from := time.Now()
+			var cpuProfile bytes.Buffer
+			if err := pprof.StartCPUProfile(&cpuProfile); err != nil {
+				// Another CPU profile is running, such as one requested from the pprof
+				// endpoints, which must not be stopped, so the interval is skipped.
+				log.Error().Err(err).Msg("unable to start CPU profile; skipping interval")
+				select {
+				case <-time.After(interval):
+					continue
+				case <-ctx.Done():
+					log.Info().Msg("continuous profiling stopped")
+					return nil
+				}
+			}
+
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				pprof.StopCPUProfile()
+				log.Info().Msg("continuous profiling stopped")
+				return nil
+			}
+
+			pprof.StopCPUProfile()
+			until := time.Now()
+
+			var heapProfile bytes.Buffer
+			if err := pprof.Lookup("heap").WriteTo(&heapProfile, 0); err != nil {
+				log.Warn().Err(err).Msg("unable to capture heap profile")
+			}
+
+			for name, profile := range map[string]*bytes.Buffer{
+				applicationName + ".cpu":  &cpuProfile,
+				applicationName + ".heap": &heapProfile,
+			} {
+				if profile.Len() == 0 {
+					continue
+				}
+				if err := upload(ctx, client, ingestURL, name, from, until, profile); err != nil {
+					log.Warn().Err(err).Str("endpoint", endpoint).Str("profile", name).Msg("failed to push profile")
+				}
+			}
+		}
+	}, nil
+}
+
+func upload(ctx context.Context, client *http.Client, ingestURL *url.URL, name string, from, until time.Time, profile io.Reader) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("profile", "profile.pprof")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, profile); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("name", name)
+	query.Set("from", strconv.FormatInt(from.Unix(), 10))
+	query.Set("until", strconv.FormatInt(until.Unix(), 10))
+	query.Set("format", "pprof")
+	query.Set("spyName", "gospy")
+	query.Set("sampleRate", strconv.Itoa(cpuSampleRate))
+
+	pushURL := *ingestURL
+	pushURL.RawQuery = query.Encode()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, pushURL.String(), &body)
+	if err != nil {
+		return fmt.Errorf("failed to create profile push request: %w", err)
+	}
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := client.Do(r)
+	if err != nil {
+		return fmt.Errorf("failed to push profile: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected profile push response: %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
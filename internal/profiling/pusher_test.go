@@ -0,0 +1,100 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPusherValidation(t *testing.T) {
+	_, err := NewPusher("not a url", "", DefaultInterval)
+	require.Error(t, err)
+
+	_, err = NewPusher("http://localhost:4040", "", 10*time.Millisecond)
+	require.Error(t, err)
+
+	_, err = NewPusher("http://localhost:4040", "", DefaultInterval)
+	require.NoError(t, err)
+}
+
+func TestPusherPushesProfiles(t *testing.T) {
+	var lock sync.Mutex
+	names := map[string]struct{}{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/ingest", r.URL.Path)
+		require.Equal(t, "pprof", r.URL.Query().Get("format"))
+
+		file, _, err := r.FormFile("profile")
+		require.NoError(t, err)
+		file.Close()
+
+		lock.Lock()
+		names[r.URL.Query().Get("name")] = struct{}{}
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	pusher, err := NewPusher(server.URL, "testapp", MinimumAllowedInterval)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- pusher(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		_, ok := names["testapp.heap"]
+		return ok
+	}, 5*time.Second, 50*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestPusherSkipsIntervalsWhileProfiling(t *testing.T) {
+	var lock sync.Mutex
+	pushed := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		pushed++
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	// A CPU profile already running, as requested from the pprof endpoints, is not
+	// stopped by the pusher.
+	var running bytes.Buffer
+	require.NoError(t, pprof.StartCPUProfile(&running))
+	defer pprof.StopCPUProfile()
+
+	pusher, err := NewPusher(server.URL, "testapp", MinimumAllowedInterval)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- pusher(ctx)
+	}()
+
+	time.Sleep(MinimumAllowedInterval + 500*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	require.Error(t, pprof.StartCPUProfile(&bytes.Buffer{}))
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Zero(t, pushed)
+}
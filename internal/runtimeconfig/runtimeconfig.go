@@ -0,0 +1,249 @@
+// Package runtimeconfig implements an admin endpoint which reports the resolved
+// configuration of a running server and allows a vetted subset of settings to be
+// changed without a restart.
+package runtimeconfig
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+const redactedValue = "(redacted)"
+
+// sensitiveAnnotation is the annotation of the flags whose values must never be
+// reported.
+const sensitiveAnnotation = "runtimeconfig_sensitive"
+
+// MarkSensitive marks the named flags of the set as sensitive, so that their values
+// are redacted when reported. Every flag which may hold a secret must be marked.
+func MarkSensitive(flags *pflag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if err := flags.SetAnnotation(name, sensitiveAnnotation, []string{"true"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Source is where the resolved value of a flag came from.
+type Source string
+
+const (
+	// SourceDefault indicates the flag was left at its default value.
+	SourceDefault Source = "default"
+
+	// SourceFlag indicates the flag was set on the command line.
+	SourceFlag Source = "flag"
+
+	// SourceEnvironment indicates the flag was set from an environment variable.
+	SourceEnvironment Source = "env"
+)
+
+// Flag is the resolved value of a command-line flag at startup.
+type Flag struct {
+	Name         string `json:"name"`
+	Value        string `json:"value"`
+	DefaultValue string `json:"default"`
+	Source       Source `json:"source"`
+}
+
+// ResolveFlags returns the resolved value and source of every flag in the set,
+// redacting the values of flags marked sensitive and the userinfo of URI values,
+// which may hold credentials. The env prefix must match the one
+// used to sync environment variables onto the flags.
+func ResolveFlags(flags *pflag.FlagSet, envPrefix string) []Flag {
+	envPrefix = strings.ReplaceAll(strings.ToUpper(envPrefix), "-", "_")
+
+	var resolved []Flag
+	flags.VisitAll(func(f *pflag.Flag) {
+		source := SourceDefault
+		if f.Changed {
+			source = SourceFlag
+			envName := envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+			if envValue, ok := os.LookupEnv(envName); ok && envValue == f.Value.String() {
+				source = SourceEnvironment
+			}
+		}
+
+		value, defaultValue := redact(f, f.Value.String()), redact(f, f.DefValue)
+
+		resolved = append(resolved, Flag{
+			Name:         f.Name,
+			Value:        value,
+			DefaultValue: defaultValue,
+			Source:       source,
+		})
+	})
+	return resolved
+}
+
+// redact returns the value of the flag as it can be reported.
+func redact(f *pflag.Flag, value string) string {
+	if value == "" || value == "[]" {
+		return value
+	}
+	if _, ok := f.Annotations[sensitiveAnnotation]; ok {
+		return redactedValue
+	}
+
+	uri, err := url.Parse(value)
+	if err != nil || uri.User == nil {
+		return value
+	}
+	uri.User = nil
+	return uri.String()
+}
+
+// Setting is a configuration value which can be read and changed at runtime.
+type Setting struct {
+	// Name is the name of the setting, matching its flag where one exists.
+	Name string
+
+	// Description describes the setting and the format of its value.
+	Description string
+
+	// Get returns the current value of the setting.
+	Get func() string
+
+	// Set validates and applies a new value for the setting.
+	Set func(value string) error
+}
+
+// Registry holds the settings which can be changed at runtime.
+type Registry struct {
+	sync.Mutex
+	settings map[string]Setting
+	flags    []Flag
+}
+
+// NewRegistry creates a new registry, reporting the given resolved flags.
+func NewRegistry(flags []Flag) *Registry {
+	return &Registry{
+		settings: make(map[string]Setting),
+		flags:    flags,
+	}
+}
+
+// Register adds a mutable setting to the registry.
+func (r *Registry) Register(setting Setting) {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.settings[setting.Name]; ok {
+		panic(fmt.Sprintf("runtime setting `%s` registered twice", setting.Name))
+	}
+	r.settings[setting.Name] = setting
+}
+
+type settingValue struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Value       string `json:"value"`
+}
+
+type configResponse struct {
+	Flags    []Flag         `json:"flags"`
+	Settings []settingValue `json:"settings"`
+}
+
+func (r *Registry) currentSettings() []settingValue {
+	values := make([]settingValue, 0, len(r.settings))
+	for _, setting := range r.settings {
+		values = append(values, settingValue{setting.Name, setting.Description, setting.Get()})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+	return values
+}
+
+// Update changes the value of a registered setting.
+func (r *Registry) Update(name, value string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	setting, ok := r.settings[name]
+	if !ok {
+		return fmt.Errorf("unknown or immutable setting `%s`", name)
+	}
+
+	previous := setting.Get()
+	if err := setting.Set(value); err != nil {
+		return fmt.Errorf("invalid value for setting `%s`: %w", name, err)
+	}
+
+	log.Info().
+		Str("setting", name).
+		Str("previous", previous).
+		Str("value", setting.Get()).
+		Msg("runtime setting changed")
+	return nil
+}
+
+// Handler returns an HTTP handler which reports the configuration on GET, and
+// updates a setting on PUT, given a JSON body of the form
+// `{"name": "log-level", "value": "debug"}`.
+//
+// Requests must carry one of the preshared keys as a bearer token.
+func (r *Registry) Handler(presharedKeys []string) http.Handler {
+	return r.DynamicHandler(func() []string { return presharedKeys })
+}
+
+// DynamicHandler is Handler for preshared keys which may change while the server is
+// running; presharedKeys is called for every request.
+func (r *Registry) DynamicHandler(presharedKeys func() []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !authorized(req, presharedKeys()) {
+			http.Error(w, "missing or invalid preshared key", http.StatusUnauthorized)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var update settingValue
+			if err := json.NewDecoder(req.Body).Decode(&update); err != nil {
+				http.Error(w, fmt.Sprintf("invalid update: %s", err), http.StatusBadRequest)
+				return
+			}
+			if err := r.Update(update.Name, update.Value); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "only GET and PUT are supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		r.Lock()
+		resp := configResponse{Flags: r.flags, Settings: r.currentSettings()}
+		r.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Warn().Err(err).Msg("failed to write runtime config response")
+		}
+	})
+}
+
+func authorized(req *http.Request, presharedKeys []string) bool {
+	scheme, token, ok := strings.Cut(req.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return false
+	}
+
+	for _, presharedKey := range presharedKeys {
+		if subtle.ConstantTimeCompare([]byte(presharedKey), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,334 @@
+// Package schemacheck compiles, type-checks and lints schemas without a running server.
+package schemacheck
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/authzed/spicedb/internal/caveats"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/development"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	devinterface "github.com/authzed/spicedb/pkg/proto/developer/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity string
+
+const (
+	// SeverityError is a diagnostic which prevents the schema from being written.
+	SeverityError Severity = "error"
+
+	// SeverityWarning is a diagnostic for a valid schema which is likely to be mistaken.
+	SeverityWarning Severity = "warning"
+)
+
+// Lint rules.
+const (
+	// RuleUnusedCaveat reports caveats which no relation allows.
+	RuleUnusedCaveat = "unused-caveat"
+
+	// RuleUnusedDefinition reports definitions without relations which no relation
+	// allows as a subject.
+	RuleUnusedDefinition = "unused-definition"
+
+	// RuleArrowOnSubjectRelation reports arrows walking a relation which allows subjects
+	// with a relation, such as `folder#member`; arrows only follow the subject object, so
+	// the subject relation is silently ignored.
+	RuleArrowOnSubjectRelation = "arrow-on-subject-relation"
+)
+
+// Diagnostic is a single problem found in a schema.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+
+	// Line and Column are 1-indexed, or zero if unknown.
+	Line   uint32 `json:"line,omitempty"`
+	Column uint32 `json:"column,omitempty"`
+}
+
+// Change is a single difference between two schemas.
+type Change struct {
+	// Definition is the name of the object or caveat definition which changed.
+	Definition string `json:"definition"`
+
+	// Type is the type of the change, such as `added-relation`.
+	Type string `json:"type"`
+
+	// Name is the name of the relation, permission or parameter which changed, if any.
+	Name string `json:"name,omitempty"`
+}
+
+// Result is the result of checking a schema.
+type Result struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+
+	// Changes holds the changes from the schema the checked schema was diffed against,
+	// if any.
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// HasErrors returns true if any diagnostic is an error.
+func (r Result) HasErrors() bool {
+	return r.count(SeverityError) > 0
+}
+
+// HasWarnings returns true if any diagnostic is a warning.
+func (r Result) HasWarnings() bool {
+	return r.count(SeverityWarning) > 0
+}
+
+func (r Result) count(severity Severity) int {
+	count := 0
+	for _, diagnostic := range r.Diagnostics {
+		if diagnostic.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Check compiles and type-checks the schema, and if it is valid, lints it. The returned
+// error is non-nil only if the check itself could not be performed.
+func Check(ctx context.Context, schema string) (Result, error) {
+	devCtx, devErrs, err := development.NewDevContext(ctx, &devinterface.RequestContext{Schema: schema})
+	if err != nil {
+		return Result{}, err
+	}
+
+	if devErrs != nil {
+		result := Result{Diagnostics: make([]Diagnostic, 0, len(devErrs.InputErrors))}
+		for _, devErr := range devErrs.InputErrors {
+			result.Diagnostics = append(result.Diagnostics, Diagnostic{
+				Severity: SeverityError,
+				Rule:     strings.ToLower(devErr.Kind.String()),
+				Message:  devErr.Message,
+				Line:     devErr.Line,
+				Column:   devErr.Column,
+			})
+		}
+		return result, nil
+	}
+
+	devCtx.Dispose()
+
+	// Loading the schema into the development datastore strips its source positions, so
+	// it is compiled again for linting.
+	compiled, err := compile("schema", schema)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Diagnostics: Lint(compiled)}, nil
+}
+
+// Lint returns warnings for constructs in a valid schema which are likely to be
+// mistaken, ordered by position.
+func Lint(compiled *compiler.CompiledSchema) []Diagnostic {
+	diagnostics := make([]Diagnostic, 0)
+
+	usedCaveats := make(map[string]struct{})
+	usedDefinitions := make(map[string]struct{})
+	for _, def := range compiled.ObjectDefinitions {
+		for _, relation := range def.Relation {
+			for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+				usedDefinitions[allowed.Namespace] = struct{}{}
+				if caveatName := allowed.GetRequiredCaveat().GetCaveatName(); caveatName != "" {
+					usedCaveats[caveatName] = struct{}{}
+				}
+			}
+		}
+	}
+
+	for _, caveat := range compiled.CaveatDefinitions {
+		if _, ok := usedCaveats[caveat.Name]; !ok {
+			diagnostics = append(diagnostics, warning(RuleUnusedCaveat, caveat.SourcePosition,
+				"caveat `%s` is not used by any relation", caveat.Name))
+		}
+	}
+
+	for _, def := range compiled.ObjectDefinitions {
+		if _, ok := usedDefinitions[def.Name]; !ok && len(def.Relation) == 0 {
+			diagnostics = append(diagnostics, warning(RuleUnusedDefinition, def.SourcePosition,
+				"definition `%s` has no relations and is not used as a subject type", def.Name))
+		}
+
+		for _, relation := range def.Relation {
+			walkArrows(relation.UsersetRewrite, func(arrow *core.TupleToUserset, position *core.SourcePosition) {
+				tuplesetName := arrow.GetTupleset().GetRelation()
+				tupleset := findRelation(def, tuplesetName)
+				if tupleset == nil {
+					return
+				}
+
+				for _, allowed := range tupleset.GetTypeInformation().GetAllowedDirectRelations() {
+					if subjectRelation := allowed.GetRelation(); subjectRelation != "" && subjectRelation != tuple.Ellipsis {
+						diagnostics = append(diagnostics, warning(RuleArrowOnSubjectRelation, position,
+							"arrow `%s->%s` in `%s#%s` ignores the subject relation of `%s#%s` allowed on `%s`",
+							tuplesetName, arrow.GetComputedUserset().GetRelation(), def.Name, relation.Name,
+							allowed.Namespace, subjectRelation, tuplesetName))
+					}
+				}
+			})
+		}
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Column < diagnostics[j].Column
+	})
+	return diagnostics
+}
+
+func warning(rule string, position *core.SourcePosition, format string, args ...any) Diagnostic {
+	diagnostic := Diagnostic{
+		Severity: SeverityWarning,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if position != nil {
+		diagnostic.Line = uint32(position.ZeroIndexedLineNumber) + 1
+		diagnostic.Column = uint32(position.ZeroIndexedColumnPosition) + 1
+	}
+	return diagnostic
+}
+
+func walkArrows(rewrite *core.UsersetRewrite, fn func(*core.TupleToUserset, *core.SourcePosition)) {
+	if rewrite == nil {
+		return
+	}
+
+	var operation *core.SetOperation
+	switch {
+	case rewrite.GetUnion() != nil:
+		operation = rewrite.GetUnion()
+	case rewrite.GetIntersection() != nil:
+		operation = rewrite.GetIntersection()
+	case rewrite.GetExclusion() != nil:
+		operation = rewrite.GetExclusion()
+	}
+
+	for _, child := range operation.GetChild() {
+		if arrow := child.GetTupleToUserset(); arrow != nil {
+			fn(arrow, child.SourcePosition)
+		}
+		walkArrows(child.GetUsersetRewrite(), fn)
+	}
+}
+
+func findRelation(def *core.NamespaceDefinition, name string) *core.Relation {
+	for _, relation := range def.Relation {
+		if relation.Name == name {
+			return relation
+		}
+	}
+	return nil
+}
+
+// Diff returns the changes from the existing schema to the updated schema, ordered by
+// definition name.
+func Diff(existing, updated string) ([]Change, error) {
+	existingCompiled, err := compile("existing", existing)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile existing schema: %w", err)
+	}
+	updatedCompiled, err := compile("updated", updated)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile updated schema: %w", err)
+	}
+
+	changes := make([]Change, 0)
+
+	existingDefs := make(map[string]*core.NamespaceDefinition, len(existingCompiled.ObjectDefinitions))
+	updatedDefs := make(map[string]*core.NamespaceDefinition, len(updatedCompiled.ObjectDefinitions))
+	for _, def := range existingCompiled.ObjectDefinitions {
+		clearSourcePositions(def.ProtoReflect())
+		existingDefs[def.Name] = def
+	}
+	for _, def := range updatedCompiled.ObjectDefinitions {
+		clearSourcePositions(def.ProtoReflect())
+		updatedDefs[def.Name] = def
+	}
+	for _, name := range unionKeys(existingDefs, updatedDefs) {
+		diff, err := namespace.DiffNamespaces(existingDefs[name], updatedDefs[name])
+		if err != nil {
+			return nil, err
+		}
+		for _, delta := range diff.Deltas() {
+			changes = append(changes, Change{Definition: name, Type: string(delta.Type), Name: delta.RelationName})
+		}
+	}
+
+	existingCaveats := make(map[string]*core.CaveatDefinition, len(existingCompiled.CaveatDefinitions))
+	updatedCaveats := make(map[string]*core.CaveatDefinition, len(updatedCompiled.CaveatDefinitions))
+	for _, caveat := range existingCompiled.CaveatDefinitions {
+		existingCaveats[caveat.Name] = caveat
+	}
+	for _, caveat := range updatedCompiled.CaveatDefinitions {
+		updatedCaveats[caveat.Name] = caveat
+	}
+	for _, name := range unionKeys(existingCaveats, updatedCaveats) {
+		diff, err := caveats.DiffCaveats(existingCaveats[name], updatedCaveats[name])
+		if err != nil {
+			return nil, err
+		}
+		for _, delta := range diff.Deltas() {
+			changes = append(changes, Change{Definition: name, Type: string(delta.Type), Name: delta.ParameterName})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Definition < changes[j].Definition
+	})
+	return changes, nil
+}
+
+// clearSourcePositions clears the source positions within the message, which would
+// otherwise make permissions differ between schemas which only differ in formatting.
+func clearSourcePositions(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Name() == "source_position":
+			m.Clear(fd)
+		case fd.IsList() && fd.Message() != nil:
+			for i := 0; i < v.List().Len(); i++ {
+				clearSourcePositions(v.List().Get(i).Message())
+			}
+		case !fd.IsMap() && fd.Message() != nil:
+			clearSourcePositions(v.Message())
+		}
+		return true
+	})
+}
+
+func compile(source, schema string) (*compiler.CompiledSchema, error) {
+	empty := ""
+	return compiler.Compile(compiler.InputSchema{
+		Source:       input.Source(source),
+		SchemaString: schema,
+	}, &empty)
+}
+
+func unionKeys[T any](a, b map[string]T) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,122 @@
+package schemacheck
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schema   string
+		expected []Diagnostic
+	}{
+		{
+			"valid schema",
+			`definition user {}
+
+definition document {
+	relation viewer: user
+	permission view = viewer
+}`,
+			[]Diagnostic{},
+		},
+		{
+			"parse error",
+			`definition user {
+	relation viewer user
+}`,
+			[]Diagnostic{{SeverityError, "schema_issue", "Expected one of: [TokenTypeColon], found: TokenTypeIdentifier", 2, 18}},
+		},
+		{
+			"type error",
+			`definition document {
+	relation viewer: user
+}`,
+			[]Diagnostic{{SeverityError, "schema_issue", "could not lookup definition `user` for relation `viewer`: object definition `user` not found", 2, 19}},
+		},
+		{
+			"lint warnings",
+			`definition user {}
+
+definition unused {}
+
+caveat never_used(flag bool) {
+	flag
+}
+
+definition group {
+	relation member: user
+}
+
+definition document {
+	relation owner_group: group#member
+	permission view = owner_group->member
+}`,
+			[]Diagnostic{
+				{SeverityWarning, RuleUnusedDefinition, "definition `unused` has no relations and is not used as a subject type", 3, 1},
+				{SeverityWarning, RuleUnusedCaveat, "caveat `never_used` is not used by any relation", 5, 1},
+				{SeverityWarning, RuleArrowOnSubjectRelation, "arrow `owner_group->member` in `document#view` ignores the subject relation of `group#member` allowed on `owner_group`", 15, 20},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Check(context.Background(), tc.schema)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, result.Diagnostics)
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	changes, err := Diff(`definition user {}
+
+definition document {
+	relation viewer: user
+	permission view = viewer
+}
+
+caveat only_on(day int) {
+	day == 1
+}`, `definition user {}
+
+definition document {
+	relation viewer: user
+	relation editor: user
+	permission view = viewer + editor
+}
+
+definition folder {}`)
+	require.NoError(t, err)
+	require.Equal(t, []Change{
+		{Definition: "document", Type: "added-relation", Name: "editor"},
+		{Definition: "document", Type: "changed-permission-implementation", Name: "view"},
+		{Definition: "folder", Type: "namespace-added"},
+		{Definition: "only_on", Type: "caveat-removed"},
+	}, changes)
+}
+
+func TestDiffIgnoresFormatting(t *testing.T) {
+	changes, err := Diff(`definition user {}
+
+definition document {
+	relation viewer: user
+	relation editor: user
+	permission view = viewer + editor
+}`, `definition user {}
+definition document {
+	relation viewer: user
+	relation editor: user
+
+	// Editors can also view.
+	permission view = viewer +
+		editor
+}`)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
@@ -12,12 +12,14 @@ import (
 )
 
 // RegisterGrpcServices registers an internal dispatch service with the specified server.
+// The health server reports the dispatch service as serving until it is shut down, which
+// removes the server from the hashrings of peers dispatching to it.
 func RegisterGrpcServices(
 	srv *grpc.Server,
 	d dispatch.Dispatcher,
+	healthSrv *grpcutil.AuthlessHealthServer,
 ) {
 	srv.RegisterService(&dispatchv1.DispatchService_ServiceDesc, dispatch_v1.NewDispatchServer(d))
-	healthSrv := grpcutil.NewAuthlessHealthServer()
 	healthSrv.SetServicesHealthy(&dispatchv1.DispatchService_ServiceDesc)
 	healthpb.RegisterHealthServer(srv, healthSrv)
 	reflection.Register(srv)
@@ -10,16 +10,46 @@ import (
 
 	"github.com/authzed/spicedb/internal/dispatch"
 	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
 )
 
 const datastoreReadyTimeout = time.Millisecond * 500
 
+// Option configures a health manager.
+type Option func(*healthManager)
+
+// WithFreshnessChecks continues checking the datastore on the given interval once it
+// has first become ready, reporting the services as not serving while the datastore is
+// unreachable or its head revision has moved backwards, as happens when reading from a
+// replica which has fallen behind.
+//
+// If maxRevisionStall is non-zero, the services are also reported as not serving when
+// the head revision has not advanced for longer than that duration. This should only be
+// enabled for datastores whose revisions advance with time, or which receive a
+// continuous stream of writes.
+func WithFreshnessChecks(interval, maxRevisionStall time.Duration) Option {
+	return func(hm *healthManager) {
+		hm.freshnessInterval = interval
+		hm.maxRevisionStall = maxRevisionStall
+	}
+}
+
 // NewHealthManager creates and returns a new health manager that checks the IsReady
 // status of the given dispatcher and datastore checker and sets the health check to
 // return healthy once both have gone to true.
-func NewHealthManager(dispatcher dispatch.Dispatcher, dsc DatastoreChecker) Manager {
+func NewHealthManager(dispatcher dispatch.Dispatcher, dsc DatastoreChecker, opts ...Option) Manager {
 	healthSvc := grpcutil.NewAuthlessHealthServer()
-	return &healthManager{healthSvc, dispatcher, dsc, map[string]struct{}{}}
+	hm := &healthManager{
+		healthSvc:    healthSvc,
+		dispatcher:   dispatcher,
+		dsc:          dsc,
+		serviceNames: map[string]struct{}{},
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(hm)
+	}
+	return hm
 }
 
 // DatastoreChecker is an interface for determining if the datastore is ready for
@@ -29,6 +59,12 @@ type DatastoreChecker interface {
 	IsReady(ctx context.Context) (bool, error)
 }
 
+// RevisionChecker is implemented by datastore checkers which can also report their
+// head revision, allowing the freshness of the datastore to be checked.
+type RevisionChecker interface {
+	HeadRevision(ctx context.Context) (datastore.Revision, error)
+}
+
 // Manager is a system which manages the health service statuses.
 type Manager interface {
 	// RegisterReportedService registers the name of service under the same server
@@ -40,6 +76,11 @@ type Manager interface {
 
 	// Checker returns a function that can be run via an errgroup to perform the health checks.
 	Checker(ctx context.Context) func() error
+
+	// Drain reports every service as not serving from now on, regardless of the health
+	// checks, so that load balancers stop routing new requests to the server ahead of
+	// its shutdown.
+	Drain()
 }
 
 type healthManager struct {
@@ -47,6 +88,13 @@ type healthManager struct {
 	dispatcher   dispatch.Dispatcher
 	dsc          DatastoreChecker
 	serviceNames map[string]struct{}
+
+	freshnessInterval time.Duration
+	maxRevisionStall  time.Duration
+	now               func() time.Time
+
+	lastRevision        datastore.Revision
+	lastRevisionChanged time.Time
 }
 
 func (hm *healthManager) HealthSvc() *grpcutil.AuthlessHealthServer {
@@ -79,8 +127,9 @@ func (hm *healthManager) Checker(ctx context.Context) func() error {
 
 			isReady := hm.checkIsReady(ctx)
 			if isReady {
-				for serviceName := range hm.serviceNames {
-					hm.healthSvc.Server.SetServingStatus(serviceName, healthpb.HealthCheckResponse_SERVING)
+				hm.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+				if hm.freshnessInterval > 0 {
+					return hm.checkFreshness(ctx)
 				}
 				return nil
 			}
@@ -110,3 +159,91 @@ func (hm *healthManager) checkIsReady(ctx context.Context) bool {
 	log.Debug().Bool("datastoreReady", dsReady).Bool("dispatchReady", dispatchReady).Msg("completed dispatcher and datastore readiness checks")
 	return dsReady && dispatchReady
 }
+
+func (hm *healthManager) Drain() {
+	hm.healthSvc.Server.Shutdown()
+}
+
+func (hm *healthManager) setServingStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	for serviceName := range hm.serviceNames {
+		hm.healthSvc.Server.SetServingStatus(serviceName, status)
+	}
+}
+
+// checkFreshness periodically checks that the datastore remains reachable and fresh
+// until the context is canceled, updating the serving status on each change.
+func (hm *healthManager) checkFreshness(ctx context.Context) error {
+	isServing := true
+	ticker := time.NewTicker(hm.freshnessInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+
+		isFresh := hm.checkIsFresh(ctx)
+		if isFresh == isServing {
+			continue
+		}
+
+		isServing = isFresh
+		if isServing {
+			log.Info().Msg("datastore is reachable and fresh again; resuming serving")
+			hm.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+		} else {
+			log.Warn().Msg("datastore is unreachable or stale; reporting not serving")
+			hm.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		}
+	}
+}
+
+func (hm *healthManager) checkIsFresh(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, datastoreReadyTimeout)
+	defer cancel()
+
+	dsReady, err := hm.dsc.IsReady(ctx)
+	if err != nil || !dsReady {
+		log.Warn().Err(err).Bool("datastoreReady", dsReady).Msg("datastore readiness check failed")
+		return false
+	}
+
+	rc, ok := hm.dsc.(RevisionChecker)
+	if !ok {
+		return true
+	}
+
+	headRevision, err := rc.HeadRevision(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("could not load the datastore head revision")
+		return false
+	}
+
+	now := hm.now()
+	switch {
+	case hm.lastRevision == nil || headRevision.GreaterThan(hm.lastRevision):
+		hm.lastRevision = headRevision
+		hm.lastRevisionChanged = now
+
+	case headRevision.LessThan(hm.lastRevision):
+		log.Warn().
+			Stringer("headRevision", headRevision).
+			Stringer("previousHeadRevision", hm.lastRevision).
+			Msg("datastore head revision moved backwards")
+		return false
+	}
+
+	if hm.maxRevisionStall > 0 {
+		if stalledFor := now.Sub(hm.lastRevisionChanged); stalledFor > hm.maxRevisionStall {
+			log.Warn().
+				Stringer("headRevision", headRevision).
+				Dur("stalledFor", stalledFor).
+				Msg("datastore head revision has not advanced")
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+)
+
+type fakeDatastoreChecker struct {
+	ready    bool
+	revision int64
+}
+
+func (f *fakeDatastoreChecker) IsReady(_ context.Context) (bool, error) {
+	return f.ready, nil
+}
+
+func (f *fakeDatastoreChecker) HeadRevision(_ context.Context) (datastore.Revision, error) {
+	return revision.NewFromDecimal(decimal.NewFromInt(f.revision)), nil
+}
+
+func TestCheckIsFresh(t *testing.T) {
+	now := time.Now()
+	dsc := &fakeDatastoreChecker{ready: true, revision: 1}
+	hm := NewHealthManager(nil, dsc, WithFreshnessChecks(time.Second, time.Minute)).(*healthManager)
+	hm.now = func() time.Time { return now }
+
+	require.True(t, hm.checkIsFresh(context.Background()))
+
+	// An unreachable datastore is not fresh.
+	dsc.ready = false
+	require.False(t, hm.checkIsFresh(context.Background()))
+	dsc.ready = true
+
+	// Advancing revisions are fresh.
+	dsc.revision = 2
+	now = now.Add(30 * time.Second)
+	require.True(t, hm.checkIsFresh(context.Background()))
+
+	// A revision which has moved backwards is not fresh until it catches up.
+	dsc.revision = 1
+	require.False(t, hm.checkIsFresh(context.Background()))
+	dsc.revision = 2
+	require.True(t, hm.checkIsFresh(context.Background()))
+
+	// A stalled revision is fresh until the maximum stall has passed.
+	now = now.Add(59 * time.Second)
+	require.True(t, hm.checkIsFresh(context.Background()))
+	now = now.Add(2 * time.Second)
+	require.False(t, hm.checkIsFresh(context.Background()))
+
+	dsc.revision = 3
+	require.True(t, hm.checkIsFresh(context.Background()))
+}
+
+func TestCheckIsFreshWithoutStallLimit(t *testing.T) {
+	now := time.Now()
+	dsc := &fakeDatastoreChecker{ready: true, revision: 1}
+	hm := NewHealthManager(nil, dsc, WithFreshnessChecks(time.Second, 0)).(*healthManager)
+	hm.now = func() time.Time { return now }
+
+	require.True(t, hm.checkIsFresh(context.Background()))
+	now = now.Add(24 * time.Hour)
+	require.True(t, hm.checkIsFresh(context.Background()))
+}
+
+func TestDrain(t *testing.T) {
+	hm := NewHealthManager(nil, &fakeDatastoreChecker{ready: true}).(*healthManager)
+	hm.RegisterReportedService("test")
+
+	status := func() healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := hm.healthSvc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "test"})
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	hm.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, status())
+
+	// Once draining, later health checks cannot report the service as serving again.
+	hm.Drain()
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status())
+	hm.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status())
+}
@@ -0,0 +1,95 @@
+//go:build ci && docker && !skipintegrationtests
+// +build ci,docker,!skipintegrationtests
+
+package integrationtesting_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	testdatastore "github.com/authzed/spicedb/internal/testserver/datastore"
+	"github.com/authzed/spicedb/internal/testserver/datastore/config"
+	"github.com/authzed/spicedb/pkg/migrate"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// TestPostgresBulkImportRelationships imports over Postgres, whose transactions load with a
+// COPY, so that the validation of each request runs in the transaction between COPYs.
+func TestPostgresBulkImportRelationships(t *testing.T) {
+	require := require.New(t)
+
+	b := testdatastore.RunPostgresForTesting(t, "", migrate.Head)
+	ds, _ := tf.StandardDatastoreWithSchema(b.NewDatastore(t, config.DatastoreConfigInitFunc(t)), require)
+
+	conn, cleanup := testserver.NewTestServerForDatastore(require, ds)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+
+	relationships := func(from, to int, relation string) []*v1.Relationship {
+		var rels []*v1.Relationship
+		for i := from; i < to; i++ {
+			rels = append(rels, tuple.MustToRelationship(tuple.MustParse(fmt.Sprintf("document:doc%d#%s@user:user%d", i, relation, i))))
+		}
+		return rels
+	}
+
+	bulkImport := func(ctx context.Context, batches ...[]*v1.Relationship) (*experimental.BulkImportRelationshipsResponse, error) {
+		stream, err := client.BulkImportRelationships(ctx)
+		require.NoError(err)
+		for _, batch := range batches {
+			if err := stream.Send(&experimental.BulkImportRelationshipsRequest{Relationships: batch}); err != nil {
+				break
+			}
+		}
+		return stream.CloseAndRecv()
+	}
+
+	countDocuments := func() int {
+		stream, err := v1.NewPermissionsServiceClient(conn).ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+			Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+			RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document"},
+		})
+		require.NoError(err)
+
+		count := 0
+		for {
+			_, err := stream.Recv()
+			if err == io.EOF {
+				return count
+			}
+			require.NoError(err)
+			count++
+		}
+	}
+
+	// A request which fails validation after others were loaded fails the whole import.
+	_, err := bulkImport(ctx, relationships(0, 100, "viewer"), relationships(100, 200, "unknown"))
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	require.Zero(countDocuments())
+
+	idpCtx := metadata.AppendToOutgoingContext(ctx, string(v1svc.RequestRelationshipSource), "idp")
+	resp, err := bulkImport(idpCtx, relationships(0, 100, "viewer"), relationships(100, 250, "viewer"), relationships(250, 300, "viewer"))
+	require.NoError(err)
+	require.Equal(uint64(300), resp.NumLoaded)
+	require.Equal(300, countDocuments())
+
+	// The sources of the relationships of every request are recorded.
+	deleted, err := client.DeleteRelationshipsFromSource(ctx, &experimental.DeleteRelationshipsFromSourceRequest{Source: "idp"})
+	require.NoError(err)
+	require.Equal(uint64(300), deleted.DeletedRelationshipCount)
+	require.Zero(countDocuments())
+}
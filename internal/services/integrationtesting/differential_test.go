@@ -0,0 +1,44 @@
+//go:build ci && docker && !skipintegrationtests
+// +build ci,docker,!skipintegrationtests
+
+package integrationtesting_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/differential"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	testdatastore "github.com/authzed/spicedb/internal/testserver/datastore"
+	"github.com/authzed/spicedb/internal/testserver/datastore/config"
+	dsconfig "github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// TestDifferentialConsistency compares each engine against memdb over randomized
+// sequences of operations.
+func TestDifferentialConsistency(t *testing.T) {
+	for _, engine := range datastore.Engines {
+		if engine == "memory" {
+			continue
+		}
+
+		engine := engine
+		b := testdatastore.RunDatastoreEngine(t, engine)
+		t.Run(engine, func(t *testing.T) {
+			for seed := int64(0); seed < 3; seed++ {
+				oracle, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+				require.NoError(t, err)
+
+				candidate := b.NewDatastore(t, config.DatastoreConfigInitFunc(t,
+					dsconfig.WithWatchBufferLength(0),
+					dsconfig.WithGCWindow(time.Duration(90_000_000_000_000)),
+					dsconfig.WithRevisionQuantization(0)))
+
+				differential.Run(t, differential.Config{Seed: seed, Steps: 300}, oracle, candidate)
+			}
+		})
+	}
+}
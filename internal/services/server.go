@@ -10,6 +10,8 @@ import (
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/services/health"
 	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	"github.com/authzed/spicedb/pkg/admission"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
 )
 
 // SchemaServiceOption defines the options for enabling or disabling the V1 Schema service.
@@ -59,19 +61,38 @@ func RegisterGrpcServices(
 	watchServiceOption WatchServiceOption,
 	caveatsOption CaveatsOption,
 	permSysConfig v1svc.PermissionsServerConfig,
+	experimentalConfig v1svc.ExperimentalServerConfig,
+	admissionHook admission.Hook,
 ) {
 	healthManager.RegisterReportedService(OverallServerHealthCheckKey)
 
+	permSysConfig.AdmissionHook = admissionHook
+
+	// The canary schema set on the experimental server is evaluated by the permissions
+	// server.
+	if experimentalConfig.CanarySchema == nil {
+		experimentalConfig.CanarySchema = v1svc.NewCanarySchema(0, permSysConfig.MaximumAPIDepth)
+	}
+	permSysConfig.CanarySchema = experimentalConfig.CanarySchema
+
 	v1.RegisterPermissionsServiceServer(srv, v1svc.NewPermissionsServer(dispatch, permSysConfig, caveatsOption == CaveatsEnabled))
 	healthManager.RegisterReportedService(v1.PermissionsService_ServiceDesc.ServiceName)
 
+	experimentalConfig.SchemaWritesDisabled = schemaServiceOption == V1SchemaServiceDisabled
+	experimentalConfig.SchemaAdditiveOnly = schemaServiceOption == V1SchemaServiceAdditiveOnly
+	experimentalConfig.CaveatsEnabled = caveatsOption == CaveatsEnabled
+	experimentalConfig.AdmissionHook = admissionHook
+	experimentalConfig.CardinalityLimits = permSysConfig.CardinalityLimits
+	experimental.RegisterExperimentalServiceServer(srv, v1svc.NewExperimentalServer(dispatch, experimentalConfig))
+	healthManager.RegisterReportedService(experimental.ExperimentalService_ServiceDesc.ServiceName)
+
 	if watchServiceOption == WatchServiceEnabled {
 		v1.RegisterWatchServiceServer(srv, v1svc.NewWatchServer())
 		healthManager.RegisterReportedService(v1.WatchService_ServiceDesc.ServiceName)
 	}
 
 	if schemaServiceOption == V1SchemaServiceEnabled || schemaServiceOption == V1SchemaServiceAdditiveOnly {
-		v1.RegisterSchemaServiceServer(srv, v1svc.NewSchemaServer(schemaServiceOption == V1SchemaServiceAdditiveOnly, caveatsOption == CaveatsEnabled))
+		v1.RegisterSchemaServiceServer(srv, v1svc.NewSchemaServer(schemaServiceOption == V1SchemaServiceAdditiveOnly, caveatsOption == CaveatsEnabled, experimentalConfig.RemovedDefinitionRetention, admissionHook))
 		healthManager.RegisterReportedService(v1.SchemaService_ServiceDesc.ServiceName)
 	}
 
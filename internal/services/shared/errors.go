@@ -10,11 +10,14 @@ import (
 	"github.com/authzed/spicedb/pkg/spiceerrors"
 )
 
-// ErrServiceReadOnly is an extended GRPC error returned when a service is in read-only mode.
+// ErrServiceReadOnly is an extended GRPC error returned for every request which would write
+// to the datastore while the service is in read-only mode. It has the code
+// FAILED_PRECONDITION, rather than UNAVAILABLE, as retrying the request cannot succeed until
+// the service leaves read-only mode, and the ErrorInfo reason ERROR_REASON_SERVICE_READ_ONLY.
 var ErrServiceReadOnly = mustMakeStatusReadonly()
 
 func mustMakeStatusReadonly() error {
-	status, err := status.New(codes.Unavailable, "service read-only").WithDetails(&errdetails.ErrorInfo{
+	status, err := status.New(codes.FailedPrecondition, "service read-only").WithDetails(&errdetails.ErrorInfo{
 		Reason: v1.ErrorReason_name[int32(v1.ErrorReason_ERROR_REASON_SERVICE_READ_ONLY)],
 		Domain: spiceerrors.Domain,
 	})
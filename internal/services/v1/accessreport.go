@@ -0,0 +1,420 @@
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/jzelinskie/stringz"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	dispatchpkg "github.com/authzed/spicedb/internal/dispatch"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/middleware/consistency"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const (
+	// defaultAccessReportPageSize is the number of resources of a page of AccessReport
+	// whose request gives none.
+	defaultAccessReportPageSize = 100
+
+	// maxAccessPathsPerSubject is the largest number of paths reported for a subject of a
+	// resource by AccessReport.
+	maxAccessPathsPerSubject = 10
+)
+
+func (es *experimentalServer) AccessReport(ctx context.Context, req *experimental.AccessReportRequest) (*experimental.AccessReportResponse, error) {
+	atRevision, readAt := consistency.MustRevisionFromContext(ctx)
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	caveatContext, err := getCaveatContext(ctx, req.Context)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	subjectRelation := stringz.DefaultEmpty(req.OptionalSubjectRelation, tuple.Ellipsis)
+	if err := namespace.CheckNamespacesAndRelations(ctx, ds,
+		namespace.RelationToCheck{Namespace: req.ResourceObjectType, Relation: req.Permission, AllowEllipsis: false},
+		namespace.RelationToCheck{Namespace: req.SubjectObjectType, Relation: subjectRelation, AllowEllipsis: true},
+	); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	after, err := base64.RawURLEncoding.DecodeString(req.OptionalCursor)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid cursor: %s", err)
+	}
+
+	pageSize := int(req.OptionalPageSize)
+	if pageSize == 0 {
+		pageSize = defaultAccessReportPageSize
+	}
+
+	resourceIDs, more, err := accessReportResourceIDs(ctx, ds, req.ResourceObjectType, string(after), pageSize)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	foundSubjects, err := es.lookupSubjectsOfResources(ctx, es.dispatch, atRevision, req.ResourceObjectType, req.Permission, resourceIDs,
+		&core.RelationReference{Namespace: req.SubjectObjectType, Relation: subjectRelation})
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	entries := make([][]*experimental.AccessReportEntry, len(resourceIDs))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(es.config.AccessReportConcurrency)
+	for index, resourceID := range resourceIDs {
+		index, resourceID := index, resourceID
+		subjects := foundSubjects[resourceID]
+		if len(subjects) == 0 {
+			continue
+		}
+
+		g.Go(func() error {
+			finder := &accessPathFinder{
+				reader:          ds,
+				subjectType:     req.SubjectObjectType,
+				subjectRelation: subjectRelation,
+				maxDepth:        es.config.MaximumAPIDepth,
+				paths:           make(map[string][]*experimental.AccessPath),
+				visiting:        make(map[string]struct{}),
+			}
+			if err := finder.walk(gctx, &core.ObjectAndRelation{
+				Namespace: req.ResourceObjectType,
+				ObjectId:  resourceID,
+				Relation:  req.Permission,
+			}, nil, 0); err != nil {
+				return err
+			}
+
+			for _, foundSubject := range subjects {
+				resolved, err := foundSubjectToResolvedSubject(gctx, foundSubject, caveatContext, ds)
+				if err != nil {
+					return err
+				}
+				if resolved == nil {
+					continue
+				}
+
+				excludedSubjectIDs := make([]string, 0, len(foundSubject.ExcludedSubjects))
+				for _, excludedSubject := range foundSubject.ExcludedSubjects {
+					excludedSubjectIDs = append(excludedSubjectIDs, excludedSubject.SubjectId)
+				}
+
+				entries[index] = append(entries[index], &experimental.AccessReportEntry{
+					ResourceObjectId: resourceID,
+					Subject: &v1.SubjectReference{
+						Object: &v1.ObjectReference{
+							ObjectType: req.SubjectObjectType,
+							ObjectId:   foundSubject.SubjectId,
+						},
+						OptionalRelation: denormalizeSubjectRelation(subjectRelation),
+					},
+					Permissionship:     resolved.Permissionship,
+					ExcludedSubjectIds: excludedSubjectIDs,
+					Paths:              finder.paths[foundSubject.SubjectId],
+				})
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	resp := &experimental.AccessReportResponse{ReadAt: readAt}
+	for _, resourceEntries := range entries {
+		resp.Entries = append(resp.Entries, resourceEntries...)
+	}
+	if more {
+		resp.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(resourceIDs[len(resourceIDs)-1]))
+	}
+	return resp, nil
+}
+
+// accessReportResourceIDs returns the IDs of the resources of a page of AccessReport: the
+// first of those of the type, in order, after the ID given. A resource with no
+// relationships has no permissions, and so is not reported. It also returns whether
+// there are more resources after the page.
+func accessReportResourceIDs(ctx context.Context, reader datastore.Reader, resourceType, after string, pageSize int) ([]string, bool, error) {
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: resourceType})
+	if err != nil {
+		return nil, false, err
+	}
+	defer it.Close()
+
+	found := util.NewSet[string]()
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		if tpl.ResourceAndRelation.ObjectId > after {
+			found.Add(tpl.ResourceAndRelation.ObjectId)
+		}
+	}
+	if it.Err() != nil {
+		return nil, false, it.Err()
+	}
+
+	resourceIDs := found.AsSlice()
+	sort.Strings(resourceIDs)
+	if len(resourceIDs) > pageSize {
+		return resourceIDs[:pageSize], true, nil
+	}
+	return resourceIDs, false, nil
+}
+
+// lookupSubjectsOfResources returns the subjects found with the permission on each of the
+// resources by the dispatcher, ordered by their ID, by the ID of their resource.
+func (es *experimentalServer) lookupSubjectsOfResources(
+	ctx context.Context,
+	dispatcher dispatchpkg.Dispatcher,
+	atRevision datastore.Revision,
+	resourceType string,
+	permission string,
+	resourceIDs []string,
+	subjectRelation *core.RelationReference,
+) (map[string][]*dispatch.FoundSubject, error) {
+	var mu sync.Mutex
+	found := make(map[string]map[string]*dispatch.FoundSubject, len(resourceIDs))
+	stream := dispatchpkg.NewHandlingDispatchStream(ctx, func(result *dispatch.DispatchLookupSubjectsResponse) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for resourceID, foundSubjects := range result.FoundSubjectsByResourceId {
+			if found[resourceID] == nil {
+				found[resourceID] = make(map[string]*dispatch.FoundSubject)
+			}
+			for _, foundSubject := range foundSubjects.FoundSubjects {
+				// A subject found unconditionally takes precedence over the same subject
+				// found through caveats.
+				if existing, ok := found[resourceID][foundSubject.SubjectId]; !ok || existing.CaveatExpression != nil {
+					found[resourceID][foundSubject.SubjectId] = foundSubject
+				}
+			}
+		}
+		return nil
+	})
+
+	var dispatchErr error
+	util.ForEachChunk(resourceIDs, datastore.FilterMaximumIDCount, func(chunk []string) {
+		if dispatchErr != nil {
+			return
+		}
+		dispatchErr = dispatcher.DispatchLookupSubjects(&dispatch.DispatchLookupSubjectsRequest{
+			Metadata: &dispatch.ResolverMeta{
+				AtRevision:     atRevision.String(),
+				DepthRemaining: es.config.MaximumAPIDepth,
+			},
+			ResourceRelation: &core.RelationReference{
+				Namespace: resourceType,
+				Relation:  permission,
+			},
+			ResourceIds:     chunk,
+			SubjectRelation: subjectRelation,
+		}, stream)
+	})
+	if dispatchErr != nil {
+		return nil, dispatchErr
+	}
+
+	subjects := make(map[string][]*dispatch.FoundSubject, len(found))
+	for resourceID, bySubject := range found {
+		for _, foundSubject := range bySubject {
+			subjects[resourceID] = append(subjects[resourceID], foundSubject)
+		}
+		sort.Slice(subjects[resourceID], func(i, j int) bool {
+			return subjects[resourceID][i].SubjectId < subjects[resourceID][j].SubjectId
+		})
+	}
+	return subjects, nil
+}
+
+// accessPathFinder finds the paths of relationships from a resource to the subjects of a
+// type through which they have a permission on it.
+type accessPathFinder struct {
+	reader          datastore.Reader
+	subjectType     string
+	subjectRelation string
+	maxDepth        uint32
+
+	// paths are the paths found, by the ID of their subject.
+	paths map[string][]*experimental.AccessPath
+
+	// visiting are the relations of the objects on the path being walked, so that cycles
+	// are not walked.
+	visiting map[string]struct{}
+}
+
+// walk finds the paths from the relation of an object, reached through the relationships
+// given.
+func (f *accessPathFinder) walk(ctx context.Context, onr *core.ObjectAndRelation, via []*core.RelationTuple, depth uint32) error {
+	if depth >= f.maxDepth {
+		return nil
+	}
+
+	key := tuple.StringONR(onr)
+	if _, ok := f.visiting[key]; ok {
+		return nil
+	}
+	f.visiting[key] = struct{}{}
+	defer delete(f.visiting, key)
+
+	_, relation, err := namespace.ReadNamespaceAndRelation(ctx, onr.Namespace, onr.Relation, f.reader)
+	if err != nil {
+		// Arrows walk the relation of every subject of their tupleset, which some types of
+		// subject may not define.
+		if errors.As(err, &namespace.ErrRelationNotFound{}) {
+			return nil
+		}
+		return err
+	}
+
+	if relation.UsersetRewrite == nil {
+		return f.walkRelationships(ctx, onr, via, depth)
+	}
+	return f.walkRewrite(ctx, onr, relation.UsersetRewrite, via, depth)
+}
+
+func (f *accessPathFinder) walkRelationships(ctx context.Context, onr *core.ObjectAndRelation, via []*core.RelationTuple, depth uint32) error {
+	it, err := f.reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             onr.Namespace,
+		OptionalResourceIds:      []string{onr.ObjectId},
+		OptionalResourceRelation: onr.Relation,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The relationships are read before walking them, so that no more than one query is
+	// open at a time.
+	var tuples []*core.RelationTuple
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		tuples = append(tuples, tpl)
+	}
+	it.Close()
+	if it.Err() != nil {
+		return it.Err()
+	}
+
+	for _, tpl := range tuples {
+		path := append(append(make([]*core.RelationTuple, 0, len(via)+1), via...), tpl)
+		if tpl.Subject.Namespace == f.subjectType && tpl.Subject.Relation == f.subjectRelation {
+			f.record(tpl.Subject.ObjectId, path)
+			continue
+		}
+
+		if tpl.Subject.Relation != tuple.Ellipsis {
+			if err := f.walk(ctx, tpl.Subject, path, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// walkRewrite walks the branches of a permission through which it can be granted: all of
+// those of unions and intersections, and the first of exclusions.
+func (f *accessPathFinder) walkRewrite(ctx context.Context, onr *core.ObjectAndRelation, rewrite *core.UsersetRewrite, via []*core.RelationTuple, depth uint32) error {
+	var children []*core.SetOperation_Child
+	switch rw := rewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		children = rw.Union.Child
+	case *core.UsersetRewrite_Intersection:
+		children = rw.Intersection.Child
+	case *core.UsersetRewrite_Exclusion:
+		if len(rw.Exclusion.Child) > 0 {
+			children = rw.Exclusion.Child[:1]
+		}
+	default:
+		return fmt.Errorf("unknown userset rewrite operation `%T`", rw)
+	}
+
+	for _, childOneof := range children {
+		switch child := childOneof.ChildType.(type) {
+		case *core.SetOperation_Child_ComputedUserset:
+			if err := f.walk(ctx, &core.ObjectAndRelation{
+				Namespace: onr.Namespace,
+				ObjectId:  onr.ObjectId,
+				Relation:  child.ComputedUserset.Relation,
+			}, via, depth+1); err != nil {
+				return err
+			}
+
+		case *core.SetOperation_Child_UsersetRewrite:
+			if err := f.walkRewrite(ctx, onr, child.UsersetRewrite, via, depth+1); err != nil {
+				return err
+			}
+
+		case *core.SetOperation_Child_TupleToUserset:
+			if err := f.walkTupleToUserset(ctx, onr, child.TupleToUserset, via, depth); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *accessPathFinder) walkTupleToUserset(ctx context.Context, onr *core.ObjectAndRelation, ttu *core.TupleToUserset, via []*core.RelationTuple, depth uint32) error {
+	it, err := f.reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             onr.Namespace,
+		OptionalResourceIds:      []string{onr.ObjectId},
+		OptionalResourceRelation: ttu.Tupleset.Relation,
+	})
+	if err != nil {
+		return err
+	}
+
+	var tuples []*core.RelationTuple
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		tuples = append(tuples, tpl)
+	}
+	it.Close()
+	if it.Err() != nil {
+		return it.Err()
+	}
+
+	for _, tpl := range tuples {
+		path := append(append(make([]*core.RelationTuple, 0, len(via)+1), via...), tpl)
+		if err := f.walk(ctx, &core.ObjectAndRelation{
+			Namespace: tpl.Subject.Namespace,
+			ObjectId:  tpl.Subject.ObjectId,
+			Relation:  ttu.ComputedUserset.Relation,
+		}, path, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record records a path to a subject, unless as many as are reported were already found.
+func (f *accessPathFinder) record(subjectID string, path []*core.RelationTuple) {
+	if len(f.paths[subjectID]) >= maxAccessPathsPerSubject {
+		return
+	}
+
+	accessPath := &experimental.AccessPath{
+		Kind:          experimental.AccessPath_KIND_DIRECT,
+		Relationships: make([]*v1.Relationship, 0, len(path)),
+	}
+	if len(path) > 1 {
+		accessPath.Kind = experimental.AccessPath_KIND_INDIRECT
+	}
+	for _, tpl := range path {
+		accessPath.Relationships = append(accessPath.Relationships, tuple.MustToRelationship(tpl))
+		if tpl.Caveat != nil {
+			accessPath.Caveated = true
+		}
+	}
+	f.paths[subjectID] = append(f.paths[subjectID], accessPath)
+}
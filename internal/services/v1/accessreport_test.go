@@ -0,0 +1,134 @@
+package v1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/internal/middleware/consistency"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const accessReportSchema = `definition user {}
+
+caveat only_on_weekdays(weekday bool) {
+	weekday
+}
+
+definition group {
+	relation member: user | group#member
+}
+
+definition folder {
+	relation viewer: user
+	permission view = viewer
+}
+
+definition document {
+	relation parent: folder
+	relation viewer: user | user with only_on_weekdays | group#member
+	relation banned: user
+	permission view = (viewer + parent->view) - banned
+}`
+
+func TestAccessReport(t *testing.T) {
+	require := require.New(t)
+
+	var relationships []*core.RelationTuple
+	for _, rel := range []string{
+		"document:a#viewer@user:alice",
+		"document:a#viewer@group:eng#member",
+		"group:eng#member@user:bob",
+		"group:eng#member@group:leads#member",
+		"group:leads#member@user:carol",
+		"document:a#parent@folder:f",
+		"folder:f#viewer@user:alice",
+		"document:b#viewer@user:dave",
+		"document:b#viewer@user:bob",
+		"document:b#banned@user:bob",
+		"document:c#banned@user:erin",
+	} {
+		relationships = append(relationships, tuple.MustParse(rel))
+	}
+	relationships[7].Caveat = &core.ContextualizedCaveat{CaveatName: "only_on_weekdays"}
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, accessReportSchema, relationships, require)
+
+	server := NewExperimentalServer(graph.NewLocalOnlyDispatcher(10), ExperimentalServerConfig{}).(*experimentalServer)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	readPage := func(cursor string) *experimental.AccessReportResponse {
+		req := &experimental.AccessReportRequest{
+			ResourceObjectType: "document",
+			Permission:         "view",
+			SubjectObjectType:  "user",
+			OptionalPageSize:   2,
+			OptionalCursor:     cursor,
+		}
+		ctx := consistency.ContextWithHandle(ctx)
+		require.NoError(consistency.AddRevisionToContext(ctx, req, ds))
+		resp, err := server.AccessReport(ctx, req)
+		require.NoError(err)
+		require.NotNil(resp.ReadAt)
+		return resp
+	}
+
+	// The first page holds documents a and b. Bob is banned from b, and Dave can view it
+	// only on weekdays.
+	first := readPage("")
+	require.NotEmpty(first.NextCursor)
+	require.Equal([]string{
+		"a@alice HAS direct[document:a#viewer@user:alice] indirect[document:a#parent@folder:f folder:f#viewer@user:alice]",
+		"a@bob HAS indirect[document:a#viewer@group:eng#member group:eng#member@user:bob]",
+		"a@carol HAS indirect[document:a#viewer@group:eng#member group:eng#member@group:leads#member group:leads#member@user:carol]",
+		"b@dave CONDITIONAL caveated-direct[document:b#viewer@user:dave]",
+	}, accessReportEntryStrings(first.Entries))
+
+	// No subject can view document c, which ends the report.
+	second := readPage(first.NextCursor)
+	require.Empty(second.NextCursor)
+	require.Empty(second.Entries)
+}
+
+func accessReportEntryStrings(entries []*experimental.AccessReportEntry) []string {
+	strs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		str := entry.ResourceObjectId + "@" + entry.Subject.Object.ObjectId
+		if entry.Permissionship == v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION {
+			str += " HAS"
+		} else {
+			str += " CONDITIONAL"
+		}
+
+		for _, path := range entry.Paths {
+			rels := make([]string, 0, len(path.Relationships))
+			for _, rel := range path.Relationships {
+				rels = append(rels, tuple.MustRelString(rel))
+			}
+
+			kind := "direct"
+			if path.Kind == experimental.AccessPath_KIND_INDIRECT {
+				kind = "indirect"
+			}
+			if path.Caveated {
+				kind = "caveated-" + kind
+			}
+			str += " " + kind + "[" + strings.Join(rels, " ") + "]"
+		}
+		strs = append(strs, str)
+	}
+	return strs
+}
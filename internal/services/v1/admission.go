@@ -0,0 +1,67 @@
+package v1
+
+import (
+	"context"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/admission"
+	admissionv1 "github.com/authzed/spicedb/pkg/proto/admission/v1"
+)
+
+// AdmissionAnnotationHeaderPrefix prefixes the annotations of the admission hooks of a
+// mutation, which are returned as response headers.
+const AdmissionAnnotationHeaderPrefix = "io.spicedb.admission."
+
+// admitRelationships calls the admission hook, if any, with the updates and
+// preconditions of a WriteRelationships call.
+func admitRelationships(ctx context.Context, hook admission.Hook, req *v1.WriteRelationshipsRequest) error {
+	if hook == nil {
+		return nil
+	}
+
+	resp, err := hook.AdmitRelationships(ctx, &admissionv1.AdmitRelationshipsRequest{
+		Updates:               req.Updates,
+		OptionalPreconditions: req.OptionalPreconditions,
+	})
+	return admitted(ctx, resp, err)
+}
+
+// admitSchema calls the admission hook, if any, with a schema which is to be written.
+func admitSchema(ctx context.Context, hook admission.Hook, schemaText string) error {
+	if hook == nil {
+		return nil
+	}
+
+	resp, err := hook.AdmitSchema(ctx, &admissionv1.AdmitSchemaRequest{
+		Schema: schemaText,
+		Author: schemaAuthor(ctx),
+	})
+	return admitted(ctx, resp, err)
+}
+
+// admitted returns an error if the mutation was not admitted, and otherwise sets its
+// annotations as response headers.
+func admitted(ctx context.Context, resp *admissionv1.AdmissionResponse, err error) error {
+	if err != nil {
+		return err
+	}
+	if !resp.Allowed {
+		return admission.NewMutationRejectedErr(resp.Reason)
+	}
+	if len(resp.Annotations) == 0 {
+		return nil
+	}
+
+	md := metadata.MD{}
+	for key, value := range resp.Annotations {
+		md.Set(AdmissionAnnotationHeaderPrefix+key, value)
+	}
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("failed to set admission annotations")
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package v1_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/admission"
+	admissionv1 "github.com/authzed/spicedb/pkg/proto/admission/v1"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// bannedSubjectHook rejects relationships to the banned subject and schemas mentioning
+// it, annotating the mutations it admits.
+type bannedSubjectHook struct {
+	banned string
+}
+
+func (h bannedSubjectHook) AdmitRelationships(_ context.Context, req *admissionv1.AdmitRelationshipsRequest) (*admissionv1.AdmissionResponse, error) {
+	for _, update := range req.Updates {
+		if update.Relationship.Subject.Object.ObjectId == h.banned {
+			return &admissionv1.AdmissionResponse{Reason: h.banned + " is banned"}, nil
+		}
+	}
+	return &admissionv1.AdmissionResponse{Allowed: true, Annotations: map[string]string{"reviewer": "bannedsubject"}}, nil
+}
+
+func (h bannedSubjectHook) AdmitSchema(_ context.Context, req *admissionv1.AdmitSchemaRequest) (*admissionv1.AdmissionResponse, error) {
+	if strings.Contains(req.Schema, h.banned) {
+		return &admissionv1.AdmissionResponse{Reason: h.banned + " is banned"}, nil
+	}
+	return &admissionv1.AdmissionResponse{Allowed: true, Annotations: map[string]string{"author": req.Author}}, nil
+}
+
+func TestAdmissionHooks(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServerWithConfig(
+		require,
+		0,
+		memdb.DisableGC,
+		true,
+		testserver.ServerConfig{
+			MaxPreconditionsCount: 1000,
+			MaxUpdatesPerWrite:    1000,
+			AdmissionHooks:        []admission.Hook{bannedSubjectHook{banned: "mallory"}},
+		},
+		tf.StandardDatastoreWithSchema,
+	)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := v1.NewPermissionsServiceClient(conn)
+	schemaClient := v1.NewSchemaServiceClient(conn)
+
+	_, err := client.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse("document:plan#viewer@user:tom"))),
+			tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse("document:plan#viewer@user:mallory"))),
+		},
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonAdmissionRejected, err, "reason")
+
+	var header metadata.MD
+	_, err = client.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse("document:plan#viewer@user:tom"))),
+		},
+	}, grpc.Header(&header))
+	require.NoError(err)
+	require.Equal([]string{"bannedsubject"}, header.Get(v1svc.AdmissionAnnotationHeaderPrefix+"reviewer"))
+
+	_, err = schemaClient.WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: `definition mallory {}`})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonAdmissionRejected, err, "reason")
+
+	readResp, err := schemaClient.ReadSchema(ctx, &v1.ReadSchemaRequest{})
+	require.NoError(err)
+	require.NotContains(readResp.SchemaText, "mallory")
+
+	authorCtx := metadata.AppendToOutgoingContext(ctx, string(v1svc.RequestSchemaAuthor), "alice")
+	_, err = schemaClient.WriteSchema(authorCtx, &v1.WriteSchemaRequest{Schema: readResp.SchemaText}, grpc.Header(&header))
+	require.NoError(err)
+	require.Equal([]string{"alice"}, header.Get(v1svc.AdmissionAnnotationHeaderPrefix+"author"))
+}
@@ -0,0 +1,165 @@
+package v1
+
+import (
+	"context"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/authzed/spicedb/internal/graph/computed"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/middleware/consistency"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+// maxBulkCheckDispatchSize is the largest number of resources checked by a single
+// dispatch of BulkCheckPermission, whose relationships the datastore reads with a single
+// query.
+const maxBulkCheckDispatchSize = datastore.FilterMaximumIDCount
+
+func (es *experimentalServer) BulkCheckPermission(ctx context.Context, req *experimental.BulkCheckPermissionRequest) (*experimental.BulkCheckPermissionResponse, error) {
+	atRevision, checkedAt := consistency.MustRevisionFromContext(ctx)
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	groups, err := groupBulkCheckItems(req.Items)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	results := make([]*experimental.BulkCheckPermissionResponseItem, len(req.Items))
+	g := errgroup.Group{}
+	g.SetLimit(es.config.BulkCheckConcurrency)
+	for _, group := range groups {
+		group := group
+		util.ForEachChunk(group.resourceIDs, maxBulkCheckDispatchSize, func(resourceIDs []string) {
+			g.Go(func() error {
+				// Each item is given the result of its resource, or the error of the
+				// dispatch of its chunk.
+				checked, err := es.bulkCheck(ctx, ds, atRevision, group.item, resourceIDs)
+				for _, resourceID := range resourceIDs {
+					result := bulkCheckResult(ctx, checked[resourceID], checkedAt, err)
+					for _, index := range group.itemIndexes[resourceID] {
+						results[index] = result
+					}
+				}
+				return nil
+			})
+		})
+	}
+	_ = g.Wait()
+
+	return &experimental.BulkCheckPermissionResponse{
+		CheckedAt: checkedAt,
+		Results:   results,
+	}, nil
+}
+
+// bulkCheckGroup is the items of a BulkCheckPermission call which check the same
+// permission of resources of the same type, for the same subject and with the same
+// context, and can therefore be dispatched together.
+type bulkCheckGroup struct {
+	// item is the first item of the group.
+	item *experimental.BulkCheckPermissionRequestItem
+
+	// resourceIDs are the distinct IDs of the resources of the items, in their order.
+	resourceIDs []string
+
+	// itemIndexes are the indexes of the items of the group by the ID of their resource.
+	itemIndexes map[string][]int
+}
+
+// groupBulkCheckItems groups the items of a BulkCheckPermission call, in the order of
+// their first item.
+func groupBulkCheckItems(items []*experimental.BulkCheckPermissionRequestItem) ([]*bulkCheckGroup, error) {
+	var groups []*bulkCheckGroup
+	groupsByKey := make(map[string]*bulkCheckGroup)
+	for index, item := range items {
+		serializedContext, err := proto.MarshalOptions{Deterministic: true}.Marshal(item.Context)
+		if err != nil {
+			return nil, err
+		}
+
+		key := item.Resource.ObjectType + "#" + item.Permission + "@" +
+			item.Subject.Object.ObjectType + ":" + item.Subject.Object.ObjectId + "#" + normalizeSubjectRelation(item.Subject) +
+			"/" + string(serializedContext)
+		group, ok := groupsByKey[key]
+		if !ok {
+			group = &bulkCheckGroup{item: item, itemIndexes: make(map[string][]int)}
+			groupsByKey[key] = group
+			groups = append(groups, group)
+		}
+
+		resourceID := item.Resource.ObjectId
+		if _, ok := group.itemIndexes[resourceID]; !ok {
+			group.resourceIDs = append(group.resourceIDs, resourceID)
+		}
+		group.itemIndexes[resourceID] = append(group.itemIndexes[resourceID], index)
+	}
+	return groups, nil
+}
+
+// bulkCheck checks the permission of the item on each of the resources, with a single
+// dispatch.
+func (es *experimentalServer) bulkCheck(
+	ctx context.Context,
+	ds datastore.Reader,
+	atRevision datastore.Revision,
+	item *experimental.BulkCheckPermissionRequestItem,
+	resourceIDs []string,
+) (map[string]*dispatch.ResourceCheckResult, error) {
+	caveatContext, err := getCaveatContext(ctx, item.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := namespace.CheckNamespacesAndRelations(ctx, ds,
+		namespace.RelationToCheck{Namespace: item.Resource.ObjectType, Relation: item.Permission, AllowEllipsis: false},
+		namespace.RelationToCheck{Namespace: item.Subject.Object.ObjectType, Relation: normalizeSubjectRelation(item.Subject), AllowEllipsis: true},
+	); err != nil {
+		return nil, err
+	}
+
+	checked, _, err := computed.ComputeBulkCheck(ctx, es.dispatch,
+		computed.CheckParameters{
+			ResourceType: &core.RelationReference{
+				Namespace: item.Resource.ObjectType,
+				Relation:  item.Permission,
+			},
+			Subject: &core.ObjectAndRelation{
+				Namespace: item.Subject.Object.ObjectType,
+				ObjectId:  item.Subject.Object.ObjectId,
+				Relation:  normalizeSubjectRelation(item.Subject),
+			},
+			CaveatContext: caveatContext,
+			AtRevision:    atRevision,
+			MaximumDepth:  es.config.MaximumAPIDepth,
+		},
+		resourceIDs,
+	)
+	return checked, err
+}
+
+// bulkCheckResult converts the result of a check of BulkCheckPermission, or its error,
+// into its result.
+func bulkCheckResult(ctx context.Context, cr *dispatch.ResourceCheckResult, checkedAt *v1.ZedToken, err error) *experimental.BulkCheckPermissionResponseItem {
+	if err != nil {
+		return &experimental.BulkCheckPermissionResponseItem{
+			Result: &experimental.BulkCheckPermissionResponseItem_Error{
+				Error: status.Convert(rewriteError(ctx, err)).Proto(),
+			},
+		}
+	}
+
+	return &experimental.BulkCheckPermissionResponseItem{
+		Result: &experimental.BulkCheckPermissionResponseItem_Response{
+			Response: checkPermissionResponse(cr, checkedAt),
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/internal/middleware/consistency"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+func bulkCheckItem(resourceID, permission, subjectID string) *experimental.BulkCheckPermissionRequestItem {
+	return &experimental.BulkCheckPermissionRequestItem{
+		Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: resourceID},
+		Permission: permission,
+		Subject:    &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: subjectID}},
+	}
+}
+
+func TestBulkCheckPermission(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, _ := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	dispatcher := &countingDispatcher{Dispatcher: graph.NewLocalOnlyDispatcher(10)}
+	server := NewExperimentalServer(dispatcher, ExperimentalServerConfig{BulkCheckConcurrency: 2}).(*experimentalServer)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+	ctx = consistency.ContextWithHandle(ctx)
+
+	req := &experimental.BulkCheckPermissionRequest{
+		Items: []*experimental.BulkCheckPermissionRequestItem{
+			bulkCheckItem("masterplan", "view", "eng_lead"),
+			bulkCheckItem("healthplan", "view", "eng_lead"),
+			bulkCheckItem("masterplan", "view", "villain"),
+			bulkCheckItem("masterplan", "unknown", "eng_lead"),
+			bulkCheckItem("companyplan", "view", "eng_lead"),
+			bulkCheckItem("masterplan", "view", "eng_lead"),
+		},
+	}
+	require.NoError(consistency.AddRevisionToContext(ctx, req, ds))
+	resp, err := server.BulkCheckPermission(ctx, req)
+	require.NoError(err)
+	require.NotNil(resp.CheckedAt)
+	require.Len(resp.Results, len(req.Items))
+
+	expected := []v1.CheckPermissionResponse_Permissionship{
+		v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+		v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+		v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+		v1.CheckPermissionResponse_PERMISSIONSHIP_UNSPECIFIED,
+		v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+		v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+	}
+	for index, permissionship := range expected {
+		require.Equal(permissionship, resp.Results[index].GetResponse().GetPermissionship(), "item %d", index)
+	}
+	require.Equal(int32(codes.FailedPrecondition), resp.Results[3].GetError().GetCode())
+
+	// The checks of eng_lead and of villain are each dispatched once, and the check of an
+	// unknown permission is not dispatched.
+	require.Equal(int32(2), dispatcher.checks.Load())
+}
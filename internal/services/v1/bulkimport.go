@@ -0,0 +1,137 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func (es *experimentalServer) BulkImportRelationships(stream experimental.ExperimentalService_BulkImportRelationshipsServer) error {
+	ctx := stream.Context()
+	ds := datastoremw.MustFromContext(ctx)
+	sourceName := relationshipSource(ctx)
+
+	var loaded uint64
+	var received bool
+	var exceededSoftLimits []exceededCardinality
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		// The relationships received cannot be received again, so a transaction retried by
+		// the datastore after receiving any would import only those which remain.
+		if received {
+			return status.Errorf(codes.Aborted, "the import transaction failed after relationships were received; the import must be retried")
+		}
+
+		tracker, err := trackedRelationshipSources(ctx, rwt, sourceName)
+		if err != nil {
+			return err
+		}
+
+		// The relationships of each request are validated before they are loaded, rather
+		// than as they are loaded, as a bulk loader may not run other statements in the
+		// transaction while it loads.
+		var limited []*v1.RelationshipUpdate
+		for {
+			req, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			received = true
+
+			updates, err := es.validateImported(ctx, rwt, req.Relationships)
+			if err != nil {
+				return err
+			}
+
+			creates := make([]*core.RelationTupleUpdate, 0, len(updates))
+			batch := make(common.SliceRelationshipSource, 0, len(updates))
+			for _, update := range updates {
+				tpl := tuple.FromRelationship(update.Relationship)
+				creates = append(creates, tuple.Create(tpl))
+				batch = append(batch, tpl)
+				if es.config.CardinalityLimits.limits(update.Relationship.Resource.ObjectType, update.Relationship.Relation) {
+					limited = append(limited, update)
+				}
+			}
+
+			count, err := common.BulkLoad(ctx, rwt, &batch)
+			if err != nil {
+				return err
+			}
+			loaded += count
+
+			if tracker != nil {
+				if err := recordRelationshipSources(ctx, rwt, sourceName, creates); err != nil {
+					return err
+				}
+			}
+		}
+
+		exceededSoftLimits, err = es.config.CardinalityLimits.check(ctx, rwt, limited)
+		return err
+	})
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	for _, exceeded := range exceededSoftLimits {
+		exceeded.report(ctx)
+	}
+
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	return stream.SendAndClose(&experimental.BulkImportRelationshipsResponse{
+		NumLoaded:  loaded,
+		ImportedAt: zedtoken.NewFromDatastoreRevision(revision, ds, binding),
+	})
+}
+
+// validateImported validates and admits the relationships of a BulkImportRelationships
+// request as WriteRelationships does those it creates, and returns them as updates.
+func (es *experimentalServer) validateImported(ctx context.Context, rwt datastore.ReadWriteTransaction, relationships []*v1.Relationship) ([]*v1.RelationshipUpdate, error) {
+	updates := make([]*v1.RelationshipUpdate, 0, len(relationships))
+	relationshipSet := util.NewSet[string]()
+	for _, relationship := range relationships {
+		tupleStr := tuple.StringRelationship(relationship)
+		if !relationshipSet.Add(tupleStr) {
+			return nil, status.Errorf(codes.InvalidArgument, "found duplicate relationship %s", tupleStr)
+		}
+
+		update := &v1.RelationshipUpdate{
+			Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+			Relationship: relationship,
+		}
+		if hasNonEmptyCaveatContext(update) && !es.config.CaveatsEnabled {
+			return nil, fmt.Errorf("caveats are currently not supported")
+		}
+		updates = append(updates, update)
+	}
+
+	if err := admitRelationships(ctx, es.config.AdmissionHook, &v1.WriteRelationshipsRequest{Updates: updates}); err != nil {
+		return nil, err
+	}
+
+	if err := validateRelationshipUpdates(ctx, rwt, updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
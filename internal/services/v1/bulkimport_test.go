@@ -0,0 +1,155 @@
+package v1_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/admission"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestBulkImportRelationships(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+	permsClient := v1.NewPermissionsServiceClient(conn)
+
+	_, err := v1.NewSchemaServiceClient(conn).WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: diffSchema})
+	require.NoError(err)
+
+	relationships := func(from, to int) []*v1.Relationship {
+		var rels []*v1.Relationship
+		for i := from; i < to; i++ {
+			rels = append(rels, tuple.MustToRelationship(tuple.MustParse(fmt.Sprintf("document:doc%d#viewer@user:user%d", i, i))))
+		}
+		return rels
+	}
+
+	bulkImport := func(batches ...[]*v1.Relationship) (*experimental.BulkImportRelationshipsResponse, error) {
+		stream, err := client.BulkImportRelationships(ctx)
+		require.NoError(err)
+		for _, batch := range batches {
+			if err := stream.Send(&experimental.BulkImportRelationshipsRequest{Relationships: batch}); err != nil {
+				break
+			}
+		}
+		return stream.CloseAndRecv()
+	}
+
+	countRelationships := func() int {
+		stream, err := permsClient.ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+			Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+			RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document"},
+		})
+		require.NoError(err)
+
+		count := 0
+		for {
+			_, err := stream.Recv()
+			if err != nil {
+				return count
+			}
+			count++
+		}
+	}
+
+	resp, err := bulkImport(relationships(0, 1500), relationships(1500, 2500))
+	require.NoError(err)
+	require.Equal(uint64(2500), resp.NumLoaded)
+	require.NotNil(resp.ImportedAt)
+	require.Equal(2500, countRelationships())
+
+	t.Run("existing relationship", func(t *testing.T) {
+		_, err := bulkImport(relationships(2500, 2600), relationships(2499, 2500))
+		grpcutil.RequireStatus(t, codes.AlreadyExists, err)
+		require.ErrorContains(err, "already existed")
+		require.Equal(2500, countRelationships())
+	})
+
+	t.Run("duplicate relationship", func(t *testing.T) {
+		_, err := bulkImport(append(relationships(2500, 2501), relationships(2500, 2501)...))
+		grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+		require.Equal(2500, countRelationships())
+	})
+
+	t.Run("unknown relation", func(t *testing.T) {
+		_, err := bulkImport(relationships(2500, 2510), []*v1.Relationship{
+			tuple.MustToRelationship(tuple.MustParse("document:doc#editor@user:alice")),
+		})
+		grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+		require.Equal(2500, countRelationships())
+	})
+
+	t.Run("empty request", func(t *testing.T) {
+		_, err := bulkImport(nil)
+		grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	})
+}
+
+func TestBulkImportRelationshipsIsAdmittedAndLimited(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServerWithConfig(
+		require,
+		0,
+		memdb.DisableGC,
+		true,
+		testserver.ServerConfig{
+			MaxPreconditionsCount: 1000,
+			MaxUpdatesPerWrite:    1000,
+			AdmissionHooks:        []admission.Hook{bannedSubjectHook{banned: "mallory"}},
+			CardinalityHardLimits: []string{"document#viewer=2"},
+		},
+		tf.StandardDatastoreWithSchema,
+	)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+
+	bulkImport := func(ctx context.Context, rels ...string) error {
+		var relationships []*v1.Relationship
+		for _, rel := range rels {
+			relationships = append(relationships, tuple.MustToRelationship(tuple.MustParse(rel)))
+		}
+
+		stream, err := client.BulkImportRelationships(ctx)
+		require.NoError(err)
+		require.NoError(stream.Send(&experimental.BulkImportRelationshipsRequest{Relationships: relationships}))
+		_, err = stream.CloseAndRecv()
+		return err
+	}
+
+	err := bulkImport(ctx, "document:imported#viewer@user:tom", "document:imported#viewer@user:mallory")
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonAdmissionRejected, err, "reason")
+
+	err = bulkImport(ctx, "document:imported#viewer@user:tom", "document:imported#viewer@user:sarah", "document:imported#viewer@user:fred")
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonCardinalityLimitExceeded, err, "resource_type", "resource_id", "relation", "limit")
+
+	// The sources of the relationships imported are recorded.
+	idpCtx := metadata.AppendToOutgoingContext(ctx, string(v1svc.RequestRelationshipSource), "idp")
+	require.NoError(bulkImport(idpCtx, "document:imported#viewer@user:tom", "document:imported#viewer@user:sarah"))
+
+	resp, err := client.DeleteRelationshipsFromSource(ctx, &experimental.DeleteRelationshipsFromSourceRequest{Source: "idp"})
+	require.NoError(err)
+	require.Equal(uint64(2), resp.DeletedRelationshipCount)
+}
@@ -0,0 +1,244 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/authzed/spicedb/internal/datastore/proxy"
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/services/shared"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const (
+	// DefaultCanarySchemaConcurrency is the default number of checks evaluated against
+	// the canary schema at once.
+	DefaultCanarySchemaConcurrency = 10
+
+	// canaryCheckTimeout bounds the evaluation of a check against the canary schema,
+	// which outlives the call it was sampled from.
+	canaryCheckTimeout = 10 * time.Second
+)
+
+var canarySchemaChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "services",
+	Name:      "canary_schema_checks_total",
+	Help:      "number of checks evaluated against the canary schema, by whether its decision matched that of the live schema and by the decision of each",
+}, []string{"result", "live", "canary"})
+
+// CanarySchema evaluates a sampled fraction of the checks of CheckPermission against a
+// canary schema in parallel with the live schema, reporting whether their decisions
+// agree, so that a change to the schema can be validated against production traffic
+// before it is written. Checks are evaluated in the background, and are skipped rather
+// than delaying the call when too many are being evaluated already. A nil CanarySchema
+// evaluates no checks.
+type CanarySchema struct {
+	dispatch        dispatch.Dispatcher
+	maximumAPIDepth uint32
+	slots           chan struct{}
+	current         atomic.Pointer[canaryState]
+}
+
+// canaryState is a canary schema and the counts of the checks evaluated against it.
+type canaryState struct {
+	schemaText string
+	sampleRate float64
+	setAt      time.Time
+	namespaces []*core.NamespaceDefinition
+	caveats    []*core.CaveatDefinition
+
+	compared   atomic.Uint64
+	mismatched atomic.Uint64
+	failed     atomic.Uint64
+	skipped    atomic.Uint64
+}
+
+// NewCanarySchema creates a CanarySchema evaluating up to concurrency checks at once,
+// with no canary schema set. Zero concurrency uses DefaultCanarySchemaConcurrency.
+func NewCanarySchema(concurrency int, maximumAPIDepth uint32) *CanarySchema {
+	if concurrency <= 0 {
+		concurrency = DefaultCanarySchemaConcurrency
+	}
+	if maximumAPIDepth == 0 {
+		maximumAPIDepth = DefaultMaximumAPIDepth
+	}
+
+	// Checks are dispatched locally and without caching, as cached results are keyed
+	// without the schema they were computed with.
+	return &CanarySchema{
+		dispatch:        graph.NewLocalOnlyDispatcher(uint16(concurrency)),
+		maximumAPIDepth: maximumAPIDepth,
+		slots:           make(chan struct{}, concurrency),
+	}
+}
+
+// set compiles and validates the schema and makes it the canary schema, resetting the
+// counts of checks. An empty schema removes the canary schema.
+func (cs *CanarySchema) set(ctx context.Context, schemaText string, sampleRate float64, caveatsEnabled bool) error {
+	if schemaText == "" {
+		cs.current.Store(nil)
+		return nil
+	}
+
+	if sampleRate <= 0 {
+		return status.Errorf(codes.InvalidArgument, "sample rate must be greater than zero")
+	}
+
+	emptyDefaultPrefix := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("canary"),
+		SchemaString: schemaText,
+	}, &emptyDefaultPrefix)
+	if err != nil {
+		return err
+	}
+
+	if !caveatsEnabled && len(compiled.CaveatDefinitions) > 0 {
+		return fmt.Errorf("caveats are currently not supported")
+	}
+
+	// Validation annotates the definitions as for writing them.
+	if _, err := shared.ValidateSchemaChanges(ctx, compiled, false); err != nil {
+		return err
+	}
+
+	cs.current.Store(&canaryState{
+		schemaText: schemaText,
+		sampleRate: sampleRate,
+		setAt:      time.Now(),
+		namespaces: compiled.ObjectDefinitions,
+		caveats:    compiled.CaveatDefinitions,
+	})
+	return nil
+}
+
+// sample evaluates the check against the canary schema in the background, if sampled,
+// comparing its decision with that of the live schema.
+func (cs *CanarySchema) sample(
+	ctx context.Context,
+	ds datastore.Datastore,
+	atRevision datastore.Revision,
+	req *v1.CheckPermissionRequest,
+	caveatContext map[string]any,
+	live *dispatchv1.ResourceCheckResult,
+) {
+	if cs == nil {
+		return
+	}
+
+	state := cs.current.Load()
+	if state == nil || rand.Float64() >= state.sampleRate { //nolint:gosec
+		return
+	}
+
+	select {
+	case cs.slots <- struct{}{}:
+	default:
+		state.skipped.Add(1)
+		return
+	}
+
+	canaryCtx, cancel := context.WithTimeout(datastoremw.ContextWithHandle(log.Ctx(ctx).WithContext(context.Background())), canaryCheckTimeout)
+	canaryDS := proxy.NewSchemaOverlayDatastore(ds, state.namespaces, state.caveats)
+	if err := datastoremw.SetInContext(canaryCtx, canaryDS); err != nil {
+		cancel()
+		<-cs.slots
+		return
+	}
+
+	go func() {
+		defer func() {
+			cancel()
+			<-cs.slots
+		}()
+
+		liveDecision := checkDecision(live)
+		canary, _, err := computeCheckPermission(canaryCtx, cs.dispatch, canaryDS.SnapshotReader(atRevision), atRevision, req, caveatContext, cs.maximumAPIDepth, false)
+		if err != nil {
+			state.failed.Add(1)
+			canarySchemaChecks.WithLabelValues("error", liveDecision, "error").Inc()
+			log.Ctx(canaryCtx).Debug().Err(err).Str("check", checkString(req)).Msg("check failed under the canary schema")
+			return
+		}
+
+		state.compared.Add(1)
+		canaryDecision := checkDecision(canary)
+		if canaryDecision == liveDecision {
+			canarySchemaChecks.WithLabelValues("match", liveDecision, canaryDecision).Inc()
+			return
+		}
+
+		state.mismatched.Add(1)
+		canarySchemaChecks.WithLabelValues("mismatch", liveDecision, canaryDecision).Inc()
+		log.Ctx(canaryCtx).Info().
+			Str("check", checkString(req)).
+			Str("live", liveDecision).
+			Str("canary", canaryDecision).
+			Msg("check decided differently under the canary schema")
+	}()
+}
+
+func checkDecision(cr *dispatchv1.ResourceCheckResult) string {
+	switch cr.Membership {
+	case dispatchv1.ResourceCheckResult_MEMBER:
+		return "has_permission"
+	case dispatchv1.ResourceCheckResult_CAVEATED_MEMBER:
+		return "conditional_permission"
+	default:
+		return "no_permission"
+	}
+}
+
+func checkString(req *v1.CheckPermissionRequest) string {
+	return tuple.StringObjectRef(req.Resource) + "#" + req.Permission + "@" + tuple.StringSubjectRef(req.Subject)
+}
+
+func (es *experimentalServer) SetCanarySchema(ctx context.Context, req *experimental.SetCanarySchemaRequest) (*experimental.SetCanarySchemaResponse, error) {
+	if err := es.config.CanarySchema.set(ctx, req.Schema, req.SampleRate, es.config.CaveatsEnabled); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	if req.Schema == "" {
+		log.Ctx(ctx).Info().Msg("canary schema removed")
+	} else {
+		log.Ctx(ctx).Info().Float64("sampleRate", req.SampleRate).Msg("canary schema set")
+	}
+	return &experimental.SetCanarySchemaResponse{}, nil
+}
+
+func (es *experimentalServer) GetCanarySchema(_ context.Context, _ *experimental.GetCanarySchemaRequest) (*experimental.GetCanarySchemaResponse, error) {
+	state := es.config.CanarySchema.current.Load()
+	if state == nil {
+		return &experimental.GetCanarySchemaResponse{}, nil
+	}
+
+	return &experimental.GetCanarySchemaResponse{
+		Schema:               state.schemaText,
+		SampleRate:           state.sampleRate,
+		SetAt:                timestamppb.New(state.setAt),
+		ComparedCheckCount:   state.compared.Load(),
+		MismatchedCheckCount: state.mismatched.Load(),
+		FailedCheckCount:     state.failed.Load(),
+		SkippedCheckCount:    state.skipped.Load(),
+	}, nil
+}
@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/internal/middleware/consistency"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const canaryLiveSchema = `
+definition user {}
+
+definition document {
+	relation viewer: user
+	relation editor: user
+	permission view = viewer + editor
+}`
+
+const canaryRefactoredSchema = `
+definition user {}
+
+definition document {
+	relation viewer: user
+	relation editor: user
+	permission view = viewer
+}`
+
+func TestCanarySchema(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, canaryLiveSchema, []*core.RelationTuple{
+		tuple.MustParse("document:first#viewer@user:alice"),
+		tuple.MustParse("document:first#editor@user:bob"),
+	}, require)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+	ctx = consistency.ContextWithHandle(ctx)
+	require.NoError(consistency.AddRevisionToContext(ctx, &v1.CheckPermissionRequest{
+		Consistency: &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+	}, ds))
+
+	dispatcher := graph.NewLocalOnlyDispatcher(10)
+	canary := NewCanarySchema(1, 0)
+	permissionServer := NewPermissionsServer(dispatcher, PermissionsServerConfig{CanarySchema: canary}, false)
+	server := NewExperimentalServer(dispatcher, ExperimentalServerConfig{CanarySchema: canary}).(*experimentalServer)
+
+	_, err = server.SetCanarySchema(ctx, &experimental.SetCanarySchemaRequest{Schema: canaryRefactoredSchema})
+	require.Equal(codes.InvalidArgument, status.Code(err))
+
+	_, err = server.SetCanarySchema(ctx, &experimental.SetCanarySchemaRequest{Schema: "definition document { permission view = nil }", SampleRate: 1})
+	require.Error(err)
+
+	_, err = server.SetCanarySchema(ctx, &experimental.SetCanarySchemaRequest{Schema: canaryRefactoredSchema, SampleRate: 1})
+	require.NoError(err)
+
+	for _, subject := range []string{"alice", "bob", "carol"} {
+		resp, err := permissionServer.CheckPermission(ctx, &v1.CheckPermissionRequest{
+			Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: "first"},
+			Permission: "view",
+			Subject:    &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: subject}},
+		})
+		require.NoError(err)
+		require.Equal(subject != "carol", resp.Permissionship == v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION)
+
+		// Waiting for each check keeps it from being skipped for the next.
+		require.Eventually(func() bool { return len(canary.slots) == 0 }, time.Second, time.Millisecond)
+	}
+
+	got, err := server.GetCanarySchema(ctx, &experimental.GetCanarySchemaRequest{})
+	require.NoError(err)
+	require.Equal(canaryRefactoredSchema, got.Schema)
+	require.Equal(uint64(3), got.ComparedCheckCount)
+	require.Equal(uint64(1), got.MismatchedCheckCount)
+	require.Zero(got.FailedCheckCount)
+	require.Zero(got.SkippedCheckCount)
+
+	_, err = server.SetCanarySchema(ctx, &experimental.SetCanarySchemaRequest{})
+	require.NoError(err)
+	got, err = server.GetCanarySchema(ctx, &experimental.GetCanarySchemaRequest{})
+	require.NoError(err)
+	require.Empty(got.Schema)
+	require.Zero(got.ComparedCheckCount)
+}
@@ -0,0 +1,198 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+var cardinalityLimitsExceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "services",
+	Name:      "relationship_cardinality_limit_exceeded_total",
+	Help:      "number of writes leaving, or which would have left, a resource with more relationships on a relation than its soft or hard limit",
+}, []string{"resource_type", "relation", "limit"})
+
+// CardinalityLimits bound the number of relationships of a single resource on a relation,
+// such as the members of a group, protecting dispatch from resources with so many
+// relationships that every check or lookup through them becomes expensive. Limits are
+// keyed by `resource_type#relation`.
+type CardinalityLimits struct {
+	// Soft limits are reported in metrics and logs when exceeded by a write, which
+	// still succeeds.
+	Soft map[string]uint64
+
+	// Hard limits fail the writes which would exceed them.
+	Hard map[string]uint64
+}
+
+// limits returns whether the relation of the resource type has a soft or hard limit.
+func (cl CardinalityLimits) limits(resourceType, relation string) bool {
+	key := resourceType + "#" + relation
+	_, hasSoft := cl.Soft[key]
+	_, hasHard := cl.Hard[key]
+	return hasSoft || hasHard
+}
+
+// ParseCardinalityLimits parses limits of the form `resource_type#relation=limit`.
+func ParseCardinalityLimits(limits []string) (map[string]uint64, error) {
+	parsed := make(map[string]uint64, len(limits))
+	for _, limit := range limits {
+		key, value, hasValue := strings.Cut(limit, "=")
+		resourceType, relation, hasRelation := strings.Cut(key, "#")
+		if !hasValue || !hasRelation || resourceType == "" || relation == "" {
+			return nil, fmt.Errorf("invalid cardinality limit `%s`: must be of the form `resource_type#relation=limit`", limit)
+		}
+
+		count, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || count == 0 {
+			return nil, fmt.Errorf("invalid cardinality limit `%s`: limit must be a positive integer", limit)
+		}
+		parsed[key] = count
+	}
+	return parsed, nil
+}
+
+// exceededCardinality is a resource left with more relationships on a relation than its
+// soft limit.
+type exceededCardinality struct {
+	resource *v1.ObjectReference
+	relation string
+	limit    uint64
+}
+
+// check counts the relationships of each resource on each relation created or touched by
+// the updates, which must have been written with the reader. It fails with an
+// ErrCardinalityLimitExceeded for the first count over its hard limit, and otherwise
+// returns those over their soft limits.
+func (cl CardinalityLimits) check(ctx context.Context, reader datastore.Reader, updates []*v1.RelationshipUpdate) ([]exceededCardinality, error) {
+	if len(cl.Soft) == 0 && len(cl.Hard) == 0 {
+		return nil, nil
+	}
+
+	checked := util.NewSet[string]()
+	var exceeded []exceededCardinality
+	for _, update := range updates {
+		if update.Operation == v1.RelationshipUpdate_OPERATION_DELETE {
+			continue
+		}
+
+		resource, relation := update.Relationship.Resource, update.Relationship.Relation
+		key := resource.ObjectType + "#" + relation
+		soft, hasSoft := cl.Soft[key]
+		hard, hasHard := cl.Hard[key]
+		if (!hasSoft && !hasHard) || !checked.Add(tuple.StringObjectRef(resource)+"#"+relation) {
+			continue
+		}
+
+		// Counting stops past the greatest limit, so that writes to resources far over
+		// their limits are no more expensive than those right at them.
+		countLimit := soft
+		if hard > countLimit {
+			countLimit = hard
+		}
+		countLimit++
+
+		count, err := countRelationships(ctx, reader, resource, relation, countLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		if hasHard && count > hard {
+			cardinalityLimitsExceeded.WithLabelValues(resource.ObjectType, relation, "hard").Inc()
+			return nil, NewCardinalityLimitExceededErr(resource, relation, hard)
+		}
+		if hasSoft && count > soft {
+			exceeded = append(exceeded, exceededCardinality{resource, relation, soft})
+		}
+	}
+	return exceeded, nil
+}
+
+// report reports the resources written over their soft limits.
+func (ec exceededCardinality) report(ctx context.Context) {
+	cardinalityLimitsExceeded.WithLabelValues(ec.resource.ObjectType, ec.relation, "soft").Inc()
+	log.Ctx(ctx).Warn().
+		Str("resource", tuple.StringObjectRef(ec.resource)).
+		Str("relation", ec.relation).
+		Uint64("softLimit", ec.limit).
+		Msg("resource has more relationships than the soft cardinality limit of its relation")
+}
+
+func countRelationships(ctx context.Context, reader datastore.Reader, resource *v1.ObjectReference, relation string, limit uint64) (uint64, error) {
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             resource.ObjectType,
+		OptionalResourceIds:      []string{resource.ObjectId},
+		OptionalResourceRelation: relation,
+	}, options.WithLimit(&limit))
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	var count uint64
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		count++
+	}
+	return count, it.Err()
+}
+
+// ErrCardinalityLimitExceeded occurs when a write would leave a resource with more
+// relationships on a relation than its hard limit.
+type ErrCardinalityLimitExceeded struct {
+	error
+	resource *v1.ObjectReference
+	relation string
+	limit    uint64
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrCardinalityLimitExceeded) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Str("resource", tuple.StringObjectRef(err.resource)).Str("relation", err.relation).Uint64("limit", err.limit)
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrCardinalityLimitExceeded) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.FailedPrecondition,
+		spiceerrors.ForExtendedReason(
+			spiceerrors.ReasonCardinalityLimitExceeded,
+			map[string]string{
+				"resource_type": err.resource.ObjectType,
+				"resource_id":   err.resource.ObjectId,
+				"relation":      err.relation,
+				"limit":         strconv.FormatUint(err.limit, 10),
+			},
+		),
+	)
+}
+
+// NewCardinalityLimitExceededErr creates a new error representing that a write would leave
+// a resource with more relationships on a relation than its hard limit.
+func NewCardinalityLimitExceededErr(resource *v1.ObjectReference, relation string, limit uint64) ErrCardinalityLimitExceeded {
+	return ErrCardinalityLimitExceeded{
+		error: fmt.Errorf(
+			"write would leave `%s` with more than the limit of %d relationships on relation `%s`",
+			tuple.StringObjectRef(resource), limit, relation,
+		),
+		resource: resource,
+		relation: relation,
+		limit:    limit,
+	}
+}
@@ -0,0 +1,84 @@
+package v1_test
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestCardinalityLimits(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServerWithConfig(
+		require,
+		0,
+		memdb.DisableGC,
+		true,
+		testserver.ServerConfig{
+			MaxPreconditionsCount: 1000,
+			MaxUpdatesPerWrite:    1000,
+			CardinalitySoftLimits: []string{"document#viewer=1"},
+			CardinalityHardLimits: []string{"document#viewer=2"},
+		},
+		tf.StandardDatastoreWithSchema,
+	)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := v1.NewPermissionsServiceClient(conn)
+	write := func(updates ...*core.RelationTupleUpdate) error {
+		req := &v1.WriteRelationshipsRequest{}
+		for _, update := range updates {
+			req.Updates = append(req.Updates, tuple.UpdateToRelationshipUpdate(update))
+		}
+		_, err := client.WriteRelationships(ctx, req)
+		return err
+	}
+
+	// Writes over the soft limit succeed.
+	require.NoError(write(
+		tuple.Create(tuple.MustParse("document:limited#viewer@user:tom")),
+		tuple.Create(tuple.MustParse("document:limited#viewer@user:sarah")),
+	))
+
+	err := write(tuple.Create(tuple.MustParse("document:limited#viewer@user:fred")))
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonCardinalityLimitExceeded, err, "resource_type", "resource_id", "relation", "limit")
+
+	// Touching an existing relationship, or replacing one in the same call, does not
+	// change the count.
+	require.NoError(write(tuple.Touch(tuple.MustParse("document:limited#viewer@user:tom"))))
+	require.NoError(write(
+		tuple.Delete(tuple.MustParse("document:limited#viewer@user:sarah")),
+		tuple.Create(tuple.MustParse("document:limited#viewer@user:fred")),
+	))
+
+	// Limits apply per resource and relation.
+	require.NoError(write(
+		tuple.Create(tuple.MustParse("document:other#viewer@user:fred")),
+		tuple.Create(tuple.MustParse("document:limited#owner@user:sarah")),
+	))
+}
+
+func TestParseCardinalityLimits(t *testing.T) {
+	limits, err := v1svc.ParseCardinalityLimits([]string{"group#member=100000", "document#viewer=5"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint64{"group#member": 100000, "document#viewer": 5}, limits)
+
+	for _, invalid := range []string{"group#member", "group=5", "#member=5", "group#member=0", "group#member=many"} {
+		_, err := v1svc.ParseCardinalityLimits([]string{invalid})
+		require.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,400 @@
+package v1
+
+import (
+	"context"
+	"sort"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/jzelinskie/stringz"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/authzed/spicedb/internal/datastore/proxy"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/middleware/consistency"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const (
+	// maxDeletionImpactRelationships is the largest number of relationships whose deletion
+	// DeleteRelationshipsImpact analyzes.
+	maxDeletionImpactRelationships = 1000
+
+	// maxDeletionImpactLookups is the largest number of lookups of the resources on which
+	// the deletion may change permissions which DeleteRelationshipsImpact runs.
+	maxDeletionImpactLookups = 5000
+
+	// maxDeletionImpactResources is the largest number of resources found by those lookups
+	// on which DeleteRelationshipsImpact compares the permissions.
+	maxDeletionImpactResources = 10000
+)
+
+func (es *experimentalServer) DeleteRelationshipsImpact(ctx context.Context, req *experimental.DeleteRelationshipsImpactRequest) (*experimental.DeleteRelationshipsImpactResponse, error) {
+	atRevision, readAt := consistency.MustRevisionFromContext(ctx)
+	ds := datastoremw.MustFromContext(ctx)
+	reader := ds.SnapshotReader(atRevision)
+
+	caveatContext, err := getCaveatContext(ctx, req.Context)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	subjectRelation := stringz.DefaultEmpty(req.OptionalSubjectRelation, tuple.Ellipsis)
+	if err := namespace.CheckNamespacesAndRelations(ctx, reader,
+		namespace.RelationToCheck{Namespace: req.SubjectObjectType, Relation: subjectRelation, AllowEllipsis: true},
+	); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	filter := datastore.RelationshipsFilterFromPublicFilter(req.RelationshipFilter)
+	deleted, err := readDeletedRelationships(ctx, reader, filter)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	definitions, err := reader.ListNamespaces(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	candidates, err := es.deletionImpactCandidates(ctx, atRevision, definitions, deleted, req.Context)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	// The permissions without the relationships are those read through a datastore which
+	// hides them.
+	overlayCtx := datastoremw.ContextWithHandle(ctx)
+	if err := datastoremw.SetInContext(overlayCtx, proxy.NewDeletionOverlayDatastore(ds, filter)); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	subjectType := &core.RelationReference{Namespace: req.SubjectObjectType, Relation: subjectRelation}
+	resp := &experimental.DeleteRelationshipsImpactResponse{
+		ReadAt:            readAt,
+		RelationshipCount: uint32(len(deleted)),
+	}
+	for _, candidate := range candidates {
+		before, err := es.lookupSubjectsOfResources(ctx, es.dispatch, atRevision,
+			candidate.target.Namespace, candidate.target.Relation, candidate.resourceIDs, subjectType)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+
+		after, err := es.lookupSubjectsOfResources(overlayCtx, es.overlayDispatch, atRevision,
+			candidate.target.Namespace, candidate.target.Relation, candidate.resourceIDs, subjectType)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+
+		for _, resourceID := range candidate.resourceIDs {
+			lost, err := lostPermissionships(ctx, reader, caveatContext, before[resourceID], after[resourceID])
+			if err != nil {
+				return nil, rewriteError(ctx, err)
+			}
+
+			for _, loss := range lost {
+				resp.Losses = append(resp.Losses, &experimental.PermissionLoss{
+					ResourceObjectType: candidate.target.Namespace,
+					ResourceObjectId:   resourceID,
+					Permission:         candidate.target.Relation,
+					Subject: &v1.SubjectReference{
+						Object: &v1.ObjectReference{
+							ObjectType: req.SubjectObjectType,
+							ObjectId:   loss.subjectID,
+						},
+						OptionalRelation: denormalizeSubjectRelation(subjectRelation),
+					},
+					Permissionship:              loss.before,
+					PermissionshipAfterDeletion: loss.after,
+				})
+			}
+		}
+	}
+	return resp, nil
+}
+
+// readDeletedRelationships returns the relationships matching the filter, failing if there
+// are more than DeleteRelationshipsImpact analyzes.
+func readDeletedRelationships(ctx context.Context, reader datastore.Reader, filter datastore.RelationshipsFilter) ([]*core.RelationTuple, error) {
+	it, err := reader.QueryRelationships(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var deleted []*core.RelationTuple
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		if len(deleted) == maxDeletionImpactRelationships {
+			return nil, status.Errorf(codes.InvalidArgument, "the relationship filter matches more than %d relationships", maxDeletionImpactRelationships)
+		}
+		deleted = append(deleted, tpl)
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+	return deleted, nil
+}
+
+// deletionImpactCandidate is a permission or relation of the resources of a type on which
+// subjects may lose it.
+type deletionImpactCandidate struct {
+	target      *core.RelationReference
+	resourceIDs []string
+}
+
+// deletionImpactCandidates returns the permissions and relations which the deletion of the
+// relationships may change, ordered by type and name, with the IDs of the resources on
+// which it may change them, in order. A relationship can change its relation on its
+// resource, the permissions of its resource with arrows over that relation, and the
+// permissions and relations reachable from either in the schema.
+func (es *experimentalServer) deletionImpactCandidates(
+	ctx context.Context,
+	atRevision datastore.Revision,
+	definitions []*core.NamespaceDefinition,
+	deleted []*core.RelationTuple,
+	caveatContext *structpb.Struct,
+) ([]deletionImpactCandidate, error) {
+	resolver := namespace.ResolverForPredefinedDefinitions(namespace.PredefinedElements{Namespaces: definitions})
+	byName := make(map[string]*core.NamespaceDefinition, len(definitions))
+	graphs := make(map[string]*namespace.ReachabilityGraph, len(definitions))
+	var targets []*core.RelationReference
+	for _, definition := range definitions {
+		ts, err := namespace.NewNamespaceTypeSystem(definition, resolver)
+		if err != nil {
+			return nil, err
+		}
+		byName[definition.Name] = definition
+		graphs[definition.Name] = namespace.ReachabilityGraphFor(ts.AsValidated())
+
+		for _, relation := range definition.Relation {
+			targets = append(targets, &core.RelationReference{Namespace: definition.Name, Relation: relation.Name})
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Namespace != targets[j].Namespace {
+			return targets[i].Namespace < targets[j].Namespace
+		}
+		return targets[i].Relation < targets[j].Relation
+	})
+
+	// The sources are grouped by their type and relation, from which the same permissions
+	// and relations are reachable.
+	sourceTypes := make(map[string]*core.RelationReference)
+	sourcesByType := make(map[string][]*core.ObjectAndRelation)
+	seen := util.NewSet[string]()
+	addSource := func(source *core.ObjectAndRelation) {
+		if !seen.Add(tuple.StringONR(source)) {
+			return
+		}
+		sourceType := &core.RelationReference{Namespace: source.Namespace, Relation: source.Relation}
+		key := tuple.StringRR(sourceType)
+		sourceTypes[key] = sourceType
+		sourcesByType[key] = append(sourcesByType[key], source)
+	}
+	for _, tpl := range deleted {
+		addSource(tpl.ResourceAndRelation)
+		definition, ok := byName[tpl.ResourceAndRelation.Namespace]
+		if !ok {
+			continue
+		}
+		for _, relation := range definition.Relation {
+			if hasArrowOver(relation.UsersetRewrite, tpl.ResourceAndRelation.Relation) {
+				addSource(&core.ObjectAndRelation{
+					Namespace: tpl.ResourceAndRelation.Namespace,
+					ObjectId:  tpl.ResourceAndRelation.ObjectId,
+					Relation:  relation.Name,
+				})
+			}
+		}
+	}
+
+	// Each target is looked up from the sources of the types from which it is reachable,
+	// with the number of lookups bounded before any is run.
+	sourcesByTarget := make(map[string][]*core.ObjectAndRelation)
+	lookups := 0
+	for key, sourceType := range sourceTypes {
+		for _, target := range targets {
+			reachable := target.Namespace == sourceType.Namespace && target.Relation == sourceType.Relation
+			if !reachable {
+				entrypoints, err := graphs[target.Namespace].AllEntrypointsForSubjectToResource(ctx, sourceType, target)
+				if err != nil {
+					return nil, err
+				}
+				reachable = len(entrypoints) > 0
+			}
+			if !reachable {
+				continue
+			}
+
+			targetKey := tuple.StringRR(target)
+			sourcesByTarget[targetKey] = append(sourcesByTarget[targetKey], sourcesByType[key]...)
+			lookups += len(sourcesByType[key])
+			if lookups > maxDeletionImpactLookups {
+				return nil, status.Errorf(codes.ResourceExhausted, "the deletion of the relationships may change more permissions than can be analyzed, requiring more than %d lookups", maxDeletionImpactLookups)
+			}
+		}
+	}
+
+	var candidates []deletionImpactCandidate
+	found := 0
+	for _, target := range targets {
+		resourceIDs := util.NewSet[string]()
+		for _, source := range sourcesByTarget[tuple.StringRR(target)] {
+			resources, err := es.lookupResources(ctx, atRevision, target.Namespace, target.Relation, source, caveatContext)
+			if err != nil {
+				return nil, err
+			}
+			for _, resource := range resources {
+				resourceIDs.Add(resource.ResourceId)
+			}
+		}
+		if resourceIDs.IsEmpty() {
+			continue
+		}
+
+		found += resourceIDs.Len()
+		if found > maxDeletionImpactResources {
+			return nil, status.Errorf(codes.ResourceExhausted, "the deletion of the relationships may change permissions on more than %d resources", maxDeletionImpactResources)
+		}
+
+		sorted := resourceIDs.AsSlice()
+		sort.Strings(sorted)
+		candidates = append(candidates, deletionImpactCandidate{target: target, resourceIDs: sorted})
+	}
+	return candidates, nil
+}
+
+// hasArrowOver returns whether the rewrite has an arrow over the tupleset relation.
+func hasArrowOver(rewrite *core.UsersetRewrite, tupleset string) bool {
+	var children []*core.SetOperation_Child
+	switch rw := rewrite.GetRewriteOperation().(type) {
+	case *core.UsersetRewrite_Union:
+		children = rw.Union.Child
+	case *core.UsersetRewrite_Intersection:
+		children = rw.Intersection.Child
+	case *core.UsersetRewrite_Exclusion:
+		children = rw.Exclusion.Child
+	}
+
+	for _, child := range children {
+		switch typed := child.ChildType.(type) {
+		case *core.SetOperation_Child_TupleToUserset:
+			if typed.TupleToUserset.Tupleset.Relation == tupleset {
+				return true
+			}
+		case *core.SetOperation_Child_UsersetRewrite:
+			if hasArrowOver(typed.UsersetRewrite, tupleset) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// permissionLoss is a subject whose permissionship would be lessened.
+type permissionLoss struct {
+	subjectID     string
+	before, after v1.CheckPermissionResponse_Permissionship
+}
+
+// lostPermissionships returns the subjects, ordered by ID, whose permissionship is less
+// among those found after than among those found before.
+func lostPermissionships(ctx context.Context, reader datastore.CaveatReader, caveatContext map[string]any, before, after []*dispatch.FoundSubject) ([]permissionLoss, error) {
+	beforeSubjects, err := resolvePermissionships(ctx, reader, caveatContext, before)
+	if err != nil {
+		return nil, err
+	}
+	afterSubjects, err := resolvePermissionships(ctx, reader, caveatContext, after)
+	if err != nil {
+		return nil, err
+	}
+
+	// Subjects which had the permission through a wildcard may lose it by being excluded
+	// from it.
+	subjectIDs := util.NewSet[string]()
+	for subjectID := range beforeSubjects.bySubjectID {
+		subjectIDs.Add(subjectID)
+	}
+	if _, ok := beforeSubjects.bySubjectID[tuple.PublicWildcard]; ok {
+		subjectIDs.Extend(afterSubjects.excluded.AsSlice())
+	}
+
+	sorted := subjectIDs.AsSlice()
+	sort.Strings(sorted)
+
+	var lost []permissionLoss
+	for _, subjectID := range sorted {
+		beforePermissionship := beforeSubjects.permissionship(subjectID)
+		afterPermissionship := afterSubjects.permissionship(subjectID)
+		if permissionshipRank(afterPermissionship) < permissionshipRank(beforePermissionship) {
+			lost = append(lost, permissionLoss{subjectID: subjectID, before: beforePermissionship, after: afterPermissionship})
+		}
+	}
+	return lost, nil
+}
+
+// resolvedPermissionships are the permissionships of the subjects found with a permission.
+type resolvedPermissionships struct {
+	bySubjectID map[string]v1.CheckPermissionResponse_Permissionship
+
+	// excluded are the IDs of the subjects excluded from the permission of the wildcard.
+	excluded *util.Set[string]
+}
+
+func resolvePermissionships(ctx context.Context, reader datastore.CaveatReader, caveatContext map[string]any, found []*dispatch.FoundSubject) (resolvedPermissionships, error) {
+	resolved := resolvedPermissionships{
+		bySubjectID: make(map[string]v1.CheckPermissionResponse_Permissionship, len(found)),
+		excluded:    util.NewSet[string](),
+	}
+	for _, foundSubject := range found {
+		subject, err := foundSubjectToResolvedSubject(ctx, foundSubject, caveatContext, reader)
+		if err != nil {
+			return resolved, err
+		}
+		if subject == nil {
+			continue
+		}
+
+		permissionship := v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION
+		if subject.Permissionship == v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION {
+			permissionship = v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION
+		}
+		resolved.bySubjectID[foundSubject.SubjectId] = permissionship
+
+		for _, excludedSubject := range foundSubject.ExcludedSubjects {
+			resolved.excluded.Add(excludedSubject.SubjectId)
+		}
+	}
+	return resolved, nil
+}
+
+// permissionship returns the permissionship of the subject, which it has if found itself,
+// or through the wildcard if it is not excluded from it.
+func (rp resolvedPermissionships) permissionship(subjectID string) v1.CheckPermissionResponse_Permissionship {
+	if permissionship, ok := rp.bySubjectID[subjectID]; ok {
+		return permissionship
+	}
+	if permissionship, ok := rp.bySubjectID[tuple.PublicWildcard]; ok && !rp.excluded.Has(subjectID) {
+		return permissionship
+	}
+	return v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION
+}
+
+func permissionshipRank(permissionship v1.CheckPermissionResponse_Permissionship) int {
+	switch permissionship {
+	case v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION:
+		return 2
+	case v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION:
+		return 1
+	default:
+		return 0
+	}
+}
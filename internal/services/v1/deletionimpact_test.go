@@ -0,0 +1,195 @@
+package v1_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const deletionImpactSchema = `definition user {}
+
+definition group {
+	relation member: user
+}
+
+definition folder {
+	relation viewer: user | group#member
+	permission view = viewer
+}
+
+definition document {
+	relation parent: folder
+	relation viewer: user | user:*
+	permission view = viewer + parent->view
+}`
+
+func TestDeleteRelationshipsImpact(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+
+	_, err := v1.NewSchemaServiceClient(conn).WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: deletionImpactSchema})
+	require.NoError(err)
+
+	var updates []*v1.RelationshipUpdate
+	for _, rel := range []string{
+		"group:eng#member@user:alice",
+		"group:eng#member@user:bob",
+		"folder:plans#viewer@group:eng#member",
+		"folder:plans#viewer@user:carol",
+		"document:spec#parent@folder:plans",
+		"document:spec#viewer@user:bob",
+		"document:public#viewer@user:*",
+	} {
+		updates = append(updates, &v1.RelationshipUpdate{
+			Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+			Relationship: tuple.MustToRelationship(tuple.MustParse(rel)),
+		})
+	}
+	_, err = v1.NewPermissionsServiceClient(conn).WriteRelationships(ctx, &v1.WriteRelationshipsRequest{Updates: updates})
+	require.NoError(err)
+
+	impact := func(filter *v1.RelationshipFilter) (*experimental.DeleteRelationshipsImpactResponse, []string) {
+		resp, err := client.DeleteRelationshipsImpact(ctx, &experimental.DeleteRelationshipsImpactRequest{
+			Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+			RelationshipFilter: filter,
+			SubjectObjectType:  "user",
+		})
+		require.NoError(err)
+		require.NotNil(resp.ReadAt)
+
+		var losses []string
+		for _, loss := range resp.Losses {
+			require.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION, loss.Permissionship)
+			require.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION, loss.PermissionshipAfterDeletion)
+			losses = append(losses, loss.ResourceObjectType+":"+loss.ResourceObjectId+"#"+loss.Permission+"@"+tuple.StringSubjectRef(loss.Subject))
+		}
+		return resp, losses
+	}
+
+	for _, tc := range []struct {
+		name     string
+		filter   *v1.RelationshipFilter
+		count    uint32
+		expected []string
+	}{
+		{
+			"membership",
+			&v1.RelationshipFilter{
+				ResourceType:          "group",
+				OptionalResourceId:    "eng",
+				OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: "user", OptionalSubjectId: "alice"},
+			},
+			1,
+			[]string{
+				"document:spec#view@user:alice",
+				"folder:plans#view@user:alice",
+				"folder:plans#viewer@user:alice",
+				"group:eng#member@user:alice",
+			},
+		},
+		{
+			// Bob keeps view on the document, which he is a viewer of.
+			"parent",
+			&v1.RelationshipFilter{ResourceType: "document", OptionalRelation: "parent"},
+			1,
+			[]string{
+				"document:spec#view@user:alice",
+				"document:spec#view@user:carol",
+			},
+		},
+		{
+			"wildcard",
+			&v1.RelationshipFilter{ResourceType: "document", OptionalResourceId: "public"},
+			1,
+			[]string{
+				"document:public#view@user:*",
+				"document:public#viewer@user:*",
+			},
+		},
+		{
+			"no relationships",
+			&v1.RelationshipFilter{ResourceType: "document", OptionalResourceId: "missing"},
+			0,
+			nil,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			resp, losses := impact(tc.filter)
+			require.Equal(tc.count, resp.RelationshipCount)
+			require.Equal(tc.expected, losses)
+		})
+	}
+
+	// Nothing was deleted by the analyses, so the memberships of both users are found.
+	resp, _ := impact(&v1.RelationshipFilter{ResourceType: "group"})
+	require.Equal(uint32(2), resp.RelationshipCount)
+
+	_, err = client.DeleteRelationshipsImpact(ctx, &experimental.DeleteRelationshipsImpactRequest{
+		RelationshipFilter: &v1.RelationshipFilter{ResourceType: "group"},
+		SubjectObjectType:  "unknown",
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+}
+
+func TestDeleteRelationshipsImpactIsBounded(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+
+	// Each viewer relationship may change six permissions and relations, which are looked
+	// up for each relationship deleted.
+	_, err := v1.NewSchemaServiceClient(conn).WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: `definition user {}
+
+definition document {
+	relation viewer: user
+	permission view = viewer
+	permission comment = viewer
+	permission download = viewer
+	permission print = viewer
+	permission share = viewer
+}`})
+	require.NoError(err)
+
+	var relationships []*v1.Relationship
+	for i := 0; i < 1000; i++ {
+		relationships = append(relationships, tuple.MustToRelationship(tuple.MustParse(fmt.Sprintf("document:doc%d#viewer@user:tom", i))))
+	}
+	stream, err := client.BulkImportRelationships(ctx)
+	require.NoError(err)
+	require.NoError(stream.Send(&experimental.BulkImportRelationshipsRequest{Relationships: relationships}))
+	_, err = stream.CloseAndRecv()
+	require.NoError(err)
+
+	impact := func(filter *v1.RelationshipFilter) error {
+		_, err := client.DeleteRelationshipsImpact(ctx, &experimental.DeleteRelationshipsImpactRequest{
+			Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+			RelationshipFilter: filter,
+			SubjectObjectType:  "user",
+		})
+		return err
+	}
+
+	require.NoError(impact(&v1.RelationshipFilter{ResourceType: "document", OptionalResourceId: "doc1"}))
+	grpcutil.RequireStatus(t, codes.ResourceExhausted, impact(&v1.RelationshipFilter{ResourceType: "document"}))
+}
@@ -0,0 +1,176 @@
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"sort"
+	"strings"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/middleware/consistency"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+// defaultSubjectEntitlementsLimit is the number of entitlements of a page of
+// SubjectEntitlements whose request gives no limit.
+const defaultSubjectEntitlementsLimit = 1000
+
+// entitlementsCursor is the position of the last entitlement of a page of
+// SubjectEntitlements, after which the next page starts.
+type entitlementsCursor struct {
+	resourceType string
+	permission   string
+	resourceID   string
+}
+
+// encode returns the cursor as the next_cursor of a page. Neither object types nor
+// relations can contain `#` or `@`, nor can object IDs.
+func (c entitlementsCursor) encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.resourceType + "#" + c.permission + "@" + c.resourceID))
+}
+
+func decodeEntitlementsCursor(encoded string) (entitlementsCursor, error) {
+	if encoded == "" {
+		return entitlementsCursor{}, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return entitlementsCursor{}, status.Errorf(codes.InvalidArgument, "invalid cursor: %s", err)
+	}
+
+	resourceType, rest, ok := strings.Cut(string(decoded), "#")
+	if !ok {
+		return entitlementsCursor{}, status.Errorf(codes.InvalidArgument, "invalid cursor")
+	}
+	permission, resourceID, ok := strings.Cut(rest, "@")
+	if !ok {
+		return entitlementsCursor{}, status.Errorf(codes.InvalidArgument, "invalid cursor")
+	}
+	return entitlementsCursor{resourceType, permission, resourceID}, nil
+}
+
+func (es *experimentalServer) SubjectEntitlements(ctx context.Context, req *experimental.SubjectEntitlementsRequest) (*experimental.SubjectEntitlementsResponse, error) {
+	atRevision, readAt := consistency.MustRevisionFromContext(ctx)
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	if _, err := getCaveatContext(ctx, req.Context); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	subject := &core.ObjectAndRelation{
+		Namespace: req.Subject.Object.ObjectType,
+		ObjectId:  req.Subject.Object.ObjectId,
+		Relation:  normalizeSubjectRelation(req.Subject),
+	}
+	if err := namespace.CheckNamespacesAndRelations(ctx, ds,
+		namespace.RelationToCheck{Namespace: subject.Namespace, Relation: subject.Relation, AllowEllipsis: true},
+	); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	after, err := decodeEntitlementsCursor(req.OptionalCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int(req.OptionalLimit)
+	if limit == 0 {
+		limit = defaultSubjectEntitlementsLimit
+	}
+
+	pairs, err := entitlementPermissions(ctx, ds, req.OptionalResourceObjectTypes, req.OptionalPermissions)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	resp := &experimental.SubjectEntitlementsResponse{ReadAt: readAt}
+	for _, pair := range pairs {
+		if pair.Namespace < after.resourceType || (pair.Namespace == after.resourceType && pair.Relation < after.permission) {
+			continue
+		}
+		samePair := pair.Namespace == after.resourceType && pair.Relation == after.permission
+
+		// Lookups dispatch reachable resources and then check those which may not have the
+		// permission, returning only those which do.
+		resources, err := es.lookupResources(ctx, atRevision, pair.Namespace, pair.Relation, subject, req.Context)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+
+		for _, resource := range deduplicateResolvedResources(resources, lookupOrdering{sorted: true}) {
+			if samePair && resource.ResourceId <= after.resourceID {
+				continue
+			}
+
+			if len(resp.Entitlements) == limit {
+				last := resp.Entitlements[limit-1]
+				resp.NextCursor = entitlementsCursor{last.ResourceObjectType, last.Permission, last.ResourceObjectId}.encode()
+				return resp, nil
+			}
+
+			entitlement := &experimental.SubjectEntitlement{
+				ResourceObjectType: pair.Namespace,
+				Permission:         pair.Relation,
+				ResourceObjectId:   resource.ResourceId,
+				Permissionship:     v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION,
+			}
+			if resource.Permissionship == dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION {
+				entitlement.Permissionship = v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION
+				entitlement.MissingRequiredContext = resource.MissingRequiredContext
+			}
+			resp.Entitlements = append(resp.Entitlements, entitlement)
+		}
+	}
+	return resp, nil
+}
+
+// entitlementPermissions returns the permissions whose entitlements are returned by
+// SubjectEntitlements, ordered by type and name: those of the given types, or of every type
+// if none are given, which are named, or every permission of a type if none are named.
+// Relations are returned only if named.
+func entitlementPermissions(ctx context.Context, reader datastore.Reader, resourceTypes, permissions []string) ([]*core.RelationReference, error) {
+	var definitions []*core.NamespaceDefinition
+	if len(resourceTypes) == 0 {
+		found, err := reader.ListNamespaces(ctx)
+		if err != nil {
+			return nil, err
+		}
+		definitions = found
+	} else {
+		found, err := namespace.ReadNamespaces(ctx, resourceTypes, reader)
+		if err != nil {
+			return nil, err
+		}
+		for _, definition := range found {
+			definitions = append(definitions, definition)
+		}
+	}
+
+	named := util.NewSet(permissions...)
+	var pairs []*core.RelationReference
+	for _, definition := range definitions {
+		for _, relation := range definition.Relation {
+			if (named.IsEmpty() && relation.UsersetRewrite != nil) || named.Has(relation.Name) {
+				pairs = append(pairs, &core.RelationReference{Namespace: definition.Name, Relation: relation.Name})
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Namespace != pairs[j].Namespace {
+			return pairs[i].Namespace < pairs[j].Namespace
+		}
+		return pairs[i].Relation < pairs[j].Relation
+	})
+	return pairs, nil
+}
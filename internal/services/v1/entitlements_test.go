@@ -0,0 +1,126 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/internal/middleware/consistency"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const entitlementsSchema = `definition user {}
+
+caveat only_on_weekdays(weekday bool) {
+	weekday
+}
+
+definition group {
+	relation member: user
+}
+
+definition folder {
+	relation viewer: user with only_on_weekdays
+	permission view = viewer
+}
+
+definition document {
+	relation parent: folder
+	relation viewer: user | group#member
+	relation editor: user
+	permission edit = editor
+	permission view = viewer + edit + parent->view
+}`
+
+func TestSubjectEntitlements(t *testing.T) {
+	var relationships []*core.RelationTuple
+	for _, rel := range []string{
+		"group:eng#member@user:alice",
+		"document:a#viewer@group:eng#member",
+		"document:b#editor@user:alice",
+		"document:c#parent@folder:f",
+		"folder:f#viewer@user:alice",
+		"document:d#viewer@user:bob",
+	} {
+		relationships = append(relationships, tuple.MustParse(rel))
+	}
+	relationships[4].Caveat = &core.ContextualizedCaveat{CaveatName: "only_on_weekdays"}
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, entitlementsSchema, relationships, require.New(t))
+
+	server := NewExperimentalServer(graph.NewLocalOnlyDispatcher(10), ExperimentalServerConfig{}).(*experimentalServer)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(t, datastoremw.SetInContext(ctx, ds))
+
+	entitlements := func(t *testing.T, req *experimental.SubjectEntitlementsRequest) []string {
+		req.Subject = &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "alice"}}
+
+		var found []string
+		for {
+			ctx := consistency.ContextWithHandle(ctx)
+			require.NoError(t, consistency.AddRevisionToContext(ctx, req, ds))
+			resp, err := server.SubjectEntitlements(ctx, req)
+			require.NoError(t, err)
+			require.NotNil(t, resp.ReadAt)
+			if req.OptionalLimit > 0 {
+				require.LessOrEqual(t, len(resp.Entitlements), int(req.OptionalLimit))
+			}
+
+			for _, entitlement := range resp.Entitlements {
+				str := entitlement.ResourceObjectType + ":" + entitlement.ResourceObjectId + "#" + entitlement.Permission
+				if entitlement.Permissionship == v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION {
+					str += "?"
+				}
+				found = append(found, str)
+			}
+
+			if resp.NextCursor == "" {
+				return found
+			}
+			req.OptionalCursor = resp.NextCursor
+		}
+	}
+
+	everything := []string{
+		"document:b#edit",
+		"document:a#view",
+		"document:b#view",
+		"document:c#view?",
+		"folder:f#view?",
+	}
+
+	for _, tc := range []struct {
+		name     string
+		req      *experimental.SubjectEntitlementsRequest
+		expected []string
+	}{
+		{"every permission", &experimental.SubjectEntitlementsRequest{}, everything},
+		{"paginated", &experimental.SubjectEntitlementsRequest{OptionalLimit: 2}, everything},
+		{
+			"of a type",
+			&experimental.SubjectEntitlementsRequest{OptionalResourceObjectTypes: []string{"folder"}},
+			[]string{"folder:f#view?"},
+		},
+		{
+			"named permissions and relations",
+			&experimental.SubjectEntitlementsRequest{OptionalPermissions: []string{"edit", "member"}},
+			[]string{"document:b#edit", "group:eng#member"},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, entitlements(t, tc.req))
+		})
+	}
+}
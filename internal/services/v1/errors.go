@@ -12,16 +12,20 @@ import (
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/graph"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/internal/sharederrors"
+	"github.com/authzed/spicedb/pkg/admission"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
 	"github.com/authzed/spicedb/pkg/spiceerrors"
 	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
 // ErrExceedsMaximumUpdates occurs when too many updates are given to a call.
@@ -235,6 +239,10 @@ func rewriteError(ctx context.Context, err error) error {
 	var compilerError compiler.BaseCompilerError
 	var sourceError spiceerrors.ErrorWithSource
 	var typeError namespace.TypeError
+	var invalidRevisionError datastore.ErrInvalidRevision
+	var pinNotFoundError datastore.ErrPinnedRevisionNotFound
+	var schemaVersionNotFoundError datastore.ErrSchemaVersionNotFound
+	var mutationRejectedError admission.ErrMutationRejected
 
 	switch {
 	case errors.As(err, &typeError):
@@ -251,28 +259,62 @@ func rewriteError(ctx context.Context, err error) error {
 
 	case errors.As(err, &datastore.ErrReadOnly{}):
 		return shared.ErrServiceReadOnly
-	case errors.As(err, &datastore.ErrInvalidRevision{}):
-		return status.Errorf(codes.OutOfRange, "invalid zedtoken: %s", err)
+	case errors.As(err, &invalidRevisionError):
+		reason := spiceerrors.ReasonInvalidRevision
+		if invalidRevisionError.Reason() == datastore.RevisionStale {
+			reason = spiceerrors.ReasonRevisionExpired
+		}
+		return spiceerrors.WithCodeAndExtendedReason(fmt.Errorf("invalid zedtoken: %w", err), codes.OutOfRange, reason, nil)
+	case errors.As(err, &zedtoken.ErrMismatchedDatastore{}):
+		return spiceerrors.WithCodeAndExtendedReason(fmt.Errorf("invalid zedtoken: %w", err), codes.InvalidArgument, spiceerrors.ReasonMismatchedDatastore, nil)
 	case errors.As(err, &datastore.ErrReadOnly{}):
 		return shared.ErrServiceReadOnly
+	case errors.As(err, &pinNotFoundError):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.NotFound, spiceerrors.ReasonPinnedRevisionNotFound, pinNotFoundError.DetailsMetadata())
+	case errors.As(err, &datastore.ErrRevisionPinningUnsupported{}):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.Unimplemented, spiceerrors.ReasonUnimplemented, nil)
+	case errors.As(err, &schemaVersionNotFoundError):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.NotFound, spiceerrors.ReasonSchemaVersionNotFound, schemaVersionNotFoundError.DetailsMetadata())
+	case errors.As(err, &datastore.ErrSchemaHistoryUnsupported{}):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.Unimplemented, spiceerrors.ReasonUnimplemented, nil)
+	case errors.As(err, &datastore.ErrNamespaceExperimentsUnsupported{}):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.Unimplemented, spiceerrors.ReasonUnimplemented, nil)
+	case errors.Is(err, common.ErrJobNotFound):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.NotFound, spiceerrors.ReasonJobNotFound, nil)
+	case errors.Is(err, common.ErrJobsUnsupported):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.Unimplemented, spiceerrors.ReasonUnimplemented, nil)
+	case errors.Is(err, common.ErrNamespaceTombstoneNotFound):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.NotFound, spiceerrors.ReasonNamespaceTombstoneNotFound, nil)
+	case errors.Is(err, common.ErrNamespaceTombstonesUnsupported):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.Unimplemented, spiceerrors.ReasonUnimplemented, nil)
+	case errors.Is(err, common.ErrRelationshipSourcesUnsupported):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.Unimplemented, spiceerrors.ReasonUnimplemented, nil)
+	case errors.As(err, &common.CreateRelationshipExistsError{}):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.AlreadyExists, spiceerrors.ReasonRelationshipExists, nil)
+	case errors.As(err, &mutationRejectedError):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.FailedPrecondition, spiceerrors.ReasonAdmissionRejected, mutationRejectedError.DetailsMetadata())
 	case errors.As(err, &datastore.ErrCaveatNameNotFound{}):
 		return spiceerrors.WithCodeAndReason(err, codes.FailedPrecondition, v1.ErrorReason_ERROR_REASON_UNKNOWN_CAVEAT)
 
+	case errors.Is(err, dispatch.ErrMaxDepth):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.ResourceExhausted, spiceerrors.ReasonMaximumDepthExceeded, nil)
+	case errors.Is(err, dispatch.ErrMemoryCeilingExceeded):
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.ResourceExhausted, spiceerrors.ReasonResourceExhausted, nil)
 	case errors.As(err, &graph.ErrInvalidArgument{}):
-		return status.Errorf(codes.InvalidArgument, "%s", err)
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.InvalidArgument, spiceerrors.ReasonInvalidArgument, nil)
 	case errors.As(err, &graph.ErrRequestCanceled{}):
-		return status.Errorf(codes.Canceled, "request canceled: %s", err)
+		return spiceerrors.WithCodeAndExtendedReason(fmt.Errorf("request canceled: %w", err), codes.Canceled, spiceerrors.ReasonRequestCanceled, nil)
 	case errors.As(err, &graph.ErrRelationMissingTypeInfo{}):
-		return status.Errorf(codes.FailedPrecondition, "failed precondition: %s", err)
+		return spiceerrors.WithCodeAndExtendedReason(fmt.Errorf("failed precondition: %w", err), codes.FailedPrecondition, spiceerrors.ReasonRelationMissingTypeInfo, nil)
 	case errors.As(err, &graph.ErrAlwaysFail{}):
 		log.Ctx(ctx).Err(err).Msg("received internal error")
-		return status.Errorf(codes.Internal, "internal error: %s", err)
+		return spiceerrors.WithCodeAndExtendedReason(fmt.Errorf("internal error: %w", err), codes.Internal, spiceerrors.ReasonInternal, nil)
 	case errors.As(err, &graph.ErrUnimplemented{}):
-		return status.Errorf(codes.Unimplemented, "%s", err)
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.Unimplemented, spiceerrors.ReasonUnimplemented, nil)
 	case errors.Is(err, context.DeadlineExceeded):
-		return status.Errorf(codes.DeadlineExceeded, "%s", err)
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.DeadlineExceeded, spiceerrors.ReasonDeadlineExceeded, nil)
 	case errors.Is(err, context.Canceled):
-		return status.Errorf(codes.Canceled, "%s", err)
+		return spiceerrors.WithCodeAndExtendedReason(err, codes.Canceled, spiceerrors.ReasonRequestCanceled, nil)
 	default:
 		log.Ctx(ctx).Err(err).Msg("received unexpected error")
 		return err
@@ -2,11 +2,16 @@ package v1
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/authzed/grpcutil"
 	"google.golang.org/grpc/codes"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
 )
 
 func TestRewriteCanceledError(t *testing.T) {
@@ -14,6 +19,7 @@ func TestRewriteCanceledError(t *testing.T) {
 	cancelFunc()
 	errorRewritten := rewriteError(ctx, ctx.Err())
 	grpcutil.RequireStatus(t, codes.Canceled, errorRewritten)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonRequestCanceled, errorRewritten)
 }
 
 func TestRewriteDeadlineExceededError(t *testing.T) {
@@ -22,3 +28,19 @@ func TestRewriteDeadlineExceededError(t *testing.T) {
 	errorRewritten := rewriteError(ctx, ctx.Err())
 	grpcutil.RequireStatus(t, codes.DeadlineExceeded, errorRewritten)
 }
+
+func TestRewriteMaxDepthError(t *testing.T) {
+	errorRewritten := rewriteError(context.Background(), fmt.Errorf("dispatch failed: %w", dispatch.ErrMaxDepth))
+	grpcutil.RequireStatus(t, codes.ResourceExhausted, errorRewritten)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonMaximumDepthExceeded, errorRewritten)
+}
+
+func TestRewriteInvalidRevisionError(t *testing.T) {
+	errorRewritten := rewriteError(context.Background(), datastore.NewInvalidRevisionErr(datastore.NoRevision, datastore.RevisionStale))
+	grpcutil.RequireStatus(t, codes.OutOfRange, errorRewritten)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonRevisionExpired, errorRewritten)
+
+	errorRewritten = rewriteError(context.Background(), datastore.NewInvalidRevisionErr(datastore.NoRevision, datastore.CouldNotDetermineRevision))
+	grpcutil.RequireStatus(t, codes.OutOfRange, errorRewritten)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonInvalidRevision, errorRewritten)
+}
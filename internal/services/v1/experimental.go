@@ -0,0 +1,585 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
+	"golang.org/x/exp/maps"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/repair"
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/internal/jobs"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/services/shared"
+	"github.com/authzed/spicedb/pkg/admission"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/middleware/consistency"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+const (
+	// DefaultMaximumPinTTL is the default maximum TTL of revisions pinned by PinRevision.
+	DefaultMaximumPinTTL = 1 * time.Hour
+
+	// DefaultOrphanDeletionBatchInterval is the default minimum time between the batches
+	// of DeleteOrphanedRelationships.
+	DefaultOrphanDeletionBatchInterval = 1 * time.Second
+
+	// DefaultMaximumAPIDepth is the default depth remaining for the dispatches of
+	// ReachableResources.
+	DefaultMaximumAPIDepth = 50
+
+	// DefaultMaximumFilterResourceIDs is the default largest number of resource IDs
+	// returned by LookupResourcesFilter, above which it returns relationship predicates.
+	DefaultMaximumFilterResourceIDs = 1000
+
+	// DefaultStreamingCheckConcurrency is the default number of checks of a single
+	// StreamingCheckPermission call run concurrently.
+	DefaultStreamingCheckConcurrency = 50
+
+	// DefaultBulkCheckConcurrency is the default number of dispatches of a single
+	// BulkCheckPermission call run concurrently.
+	DefaultBulkCheckConcurrency = 50
+
+	// DefaultAccessReportConcurrency is the default number of resources of a single
+	// AccessReport call whose paths are found concurrently.
+	DefaultAccessReportConcurrency = 50
+
+	// DefaultDeletionImpactConcurrency is the default number of dispatches of each lookup
+	// of a DeleteRelationshipsImpact call without the relationships run concurrently.
+	DefaultDeletionImpactConcurrency = 50
+
+	// DefaultPrefetchConcurrency is the default number of checks prefetched by
+	// PrefetchChecks run concurrently.
+	DefaultPrefetchConcurrency = 10
+
+	// DefaultJobBatchSize is the default number of relationships deleted per transaction
+	// by the jobs which delete relationships.
+	DefaultJobBatchSize = 1000
+)
+
+// ExperimentalServerConfig is configuration for the experimental server.
+type ExperimentalServerConfig struct {
+	// MaximumPinTTL is the maximum TTL of revisions pinned by PinRevision; longer TTLs
+	// are clamped to it. Zero uses DefaultMaximumPinTTL.
+	MaximumPinTTL time.Duration
+
+	// OrphanDeletionBatchSize is the number of relationships deleted per transaction by
+	// DeleteOrphanedRelationships. Zero uses repair.DefaultBatchSize.
+	OrphanDeletionBatchSize int
+
+	// OrphanDeletionBatchInterval is the minimum time between the transactions of
+	// DeleteOrphanedRelationships, limiting the rate at which it writes. Zero uses
+	// DefaultOrphanDeletionBatchInterval.
+	OrphanDeletionBatchInterval time.Duration
+
+	// MaximumAPIDepth is the depth remaining for the dispatches of ReachableResources.
+	// Zero uses DefaultMaximumAPIDepth.
+	MaximumAPIDepth uint32
+
+	// SchemaWritesDisabled, SchemaAdditiveOnly and CaveatsEnabled configure the schemas
+	// RestoreSchemaVersion may write, as for the schema service.
+	SchemaWritesDisabled bool
+	SchemaAdditiveOnly   bool
+	CaveatsEnabled       bool
+
+	// AdmissionHook, if set, admits the schemas written by RestoreSchemaVersion and the
+	// relationships imported by BulkImportRelationships.
+	AdmissionHook admission.Hook
+
+	// CardinalityLimits bound the relationships imported by BulkImportRelationships, as
+	// for WriteRelationships.
+	CardinalityLimits CardinalityLimits
+
+	// RemovedDefinitionRetention is the period for which definitions removed from the
+	// schema are retained with their relationships, to be restored by RestoreNamespace.
+	// Zero removes definitions only once they have no relationships, without retaining
+	// them.
+	RemovedDefinitionRetention time.Duration
+
+	// StreamingCheckConcurrency is the number of checks of a single
+	// StreamingCheckPermission call run concurrently; further requests are not received
+	// until one completes. Zero uses DefaultStreamingCheckConcurrency.
+	StreamingCheckConcurrency int
+
+	// BulkCheckConcurrency is the number of dispatches of a single BulkCheckPermission
+	// call run concurrently. Zero uses DefaultBulkCheckConcurrency.
+	BulkCheckConcurrency int
+
+	// AccessReportConcurrency is the number of resources of a single AccessReport call
+	// whose paths are found concurrently. Zero uses DefaultAccessReportConcurrency.
+	AccessReportConcurrency int
+
+	// DeletionImpactConcurrency is the number of dispatches of each lookup of a
+	// DeleteRelationshipsImpact call without the relationships run concurrently. Zero
+	// uses DefaultDeletionImpactConcurrency.
+	DeletionImpactConcurrency int
+
+	// PrefetchConcurrency is the number of checks prefetched by PrefetchChecks run
+	// concurrently, across all calls. Zero uses DefaultPrefetchConcurrency.
+	PrefetchConcurrency int
+
+	// CanarySchema holds the canary schema set by SetCanarySchema, which must be shared
+	// with the permissions server evaluating checks against it. Nil uses one of its own.
+	CanarySchema *CanarySchema
+
+	// JobRunner runs the jobs started by StartJob, and must be closed when the server
+	// stops. Nil uses a runner with the default heartbeat interval.
+	JobRunner *jobs.Runner
+
+	// JobBatchSize is the number of relationships deleted or moved per transaction by
+	// jobs, and written back per transaction by RestoreNamespace. Zero uses
+	// DefaultJobBatchSize.
+	JobBatchSize int
+
+	// GCWindow and DatastoreEngine are those of the datastore, used by the jobs which
+	// collect its garbage and back it up.
+	GCWindow        time.Duration
+	DatastoreEngine string
+}
+
+// NewExperimentalServer creates an ExperimentalServiceServer instance.
+func NewExperimentalServer(dispatch dispatch.Dispatcher, config ExperimentalServerConfig) experimental.ExperimentalServiceServer {
+	if config.MaximumPinTTL <= 0 {
+		config.MaximumPinTTL = DefaultMaximumPinTTL
+	}
+	if config.OrphanDeletionBatchSize <= 0 {
+		config.OrphanDeletionBatchSize = repair.DefaultBatchSize
+	}
+	if config.OrphanDeletionBatchInterval <= 0 {
+		config.OrphanDeletionBatchInterval = DefaultOrphanDeletionBatchInterval
+	}
+	if config.MaximumAPIDepth == 0 {
+		config.MaximumAPIDepth = DefaultMaximumAPIDepth
+	}
+	if config.StreamingCheckConcurrency <= 0 {
+		config.StreamingCheckConcurrency = DefaultStreamingCheckConcurrency
+	}
+	if config.BulkCheckConcurrency <= 0 {
+		config.BulkCheckConcurrency = DefaultBulkCheckConcurrency
+	}
+	if config.AccessReportConcurrency <= 0 {
+		config.AccessReportConcurrency = DefaultAccessReportConcurrency
+	}
+	if config.DeletionImpactConcurrency <= 0 {
+		config.DeletionImpactConcurrency = DefaultDeletionImpactConcurrency
+	}
+	if config.PrefetchConcurrency <= 0 {
+		config.PrefetchConcurrency = DefaultPrefetchConcurrency
+	}
+	if config.CanarySchema == nil {
+		config.CanarySchema = NewCanarySchema(0, config.MaximumAPIDepth)
+	}
+	if config.JobRunner == nil {
+		config.JobRunner = jobs.NewRunner(0)
+	}
+	if config.JobBatchSize <= 0 {
+		config.JobBatchSize = DefaultJobBatchSize
+	}
+
+	return &experimentalServer{
+		WithServiceSpecificInterceptors: shared.WithServiceSpecificInterceptors{
+			Unary:  grpcvalidate.UnaryServerInterceptor(true),
+			Stream: grpcvalidate.StreamServerInterceptor(true),
+		},
+		dispatch:      dispatch,
+		config:        config,
+		prefetchSlots: make(chan struct{}, config.PrefetchConcurrency),
+
+		// Lookups without relationships are dispatched locally and without caching, as
+		// cached results are keyed without the relationships they were computed with.
+		overlayDispatch: graph.NewLocalOnlyDispatcher(uint16(config.DeletionImpactConcurrency)),
+	}
+}
+
+type experimentalServer struct {
+	experimental.UnimplementedExperimentalServiceServer
+	shared.WithServiceSpecificInterceptors
+
+	dispatch dispatch.Dispatcher
+	config   ExperimentalServerConfig
+
+	// prefetchSlots holds a value for each check being prefetched.
+	prefetchSlots chan struct{}
+
+	// overlayDispatch dispatches the lookups of DeleteRelationshipsImpact against a
+	// datastore without the relationships it analyzes.
+	overlayDispatch dispatch.Dispatcher
+}
+
+func (es *experimentalServer) PinRevision(ctx context.Context, req *experimental.PinRevisionRequest) (*experimental.PinRevisionResponse, error) {
+	ds := datastoremw.MustFromContext(ctx)
+
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	var revision datastore.Revision
+	if req.OptionalAtRevision != nil {
+		revision, err = decodeCheckedRevision(ctx, ds, binding, req.OptionalAtRevision)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		revision, err = ds.HeadRevision(ctx)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+	}
+
+	ttl := req.Ttl.AsDuration()
+	if ttl > es.config.MaximumPinTTL {
+		ttl = es.config.MaximumPinTTL
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	pinner, err := common.RevisionPinnerOf(ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	if err := pinner.PinRevision(ctx, req.Name, revision, expiresAt); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &experimental.PinRevisionResponse{
+		PinnedAt:  zedtoken.NewFromDatastoreRevision(revision, ds, binding),
+		ExpiresAt: timestamppb.New(expiresAt),
+	}, nil
+}
+
+func (es *experimentalServer) ReleasePinnedRevision(ctx context.Context, req *experimental.ReleasePinnedRevisionRequest) (*experimental.ReleasePinnedRevisionResponse, error) {
+	pinner, err := common.RevisionPinnerOf(datastoremw.MustFromContext(ctx))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	if err := pinner.ReleasePinnedRevision(ctx, req.Name); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &experimental.ReleasePinnedRevisionResponse{}, nil
+}
+
+func (es *experimentalServer) FindOrphanedRelationships(req *experimental.FindOrphanedRelationshipsRequest, resp experimental.ExperimentalService_FindOrphanedRelationshipsServer) error {
+	ctx, cancel := context.WithCancel(resp.Context())
+	defer cancel()
+
+	// Sending happens within the check, which is stopped by cancelation if the stream
+	// breaks.
+	var sendErr error
+	_, err := repair.Run(ctx, datastoremw.MustFromContext(ctx), repair.Options{
+		Limit:    uint64(req.OptionalLimit),
+		SkipMVCC: true,
+		OnIssue: func(issue repair.Issue) {
+			if issue.Relationship == nil || sendErr != nil {
+				return
+			}
+
+			sendErr = resp.Send(&experimental.FindOrphanedRelationshipsResponse{
+				Relationship: tuple.ToRelationship(issue.Relationship),
+				Kind:         string(issue.Kind),
+				Detail:       issue.Detail,
+			})
+			if sendErr != nil {
+				cancel()
+			}
+		},
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+	return nil
+}
+
+func (es *experimentalServer) DeleteOrphanedRelationships(ctx context.Context, _ *experimental.DeleteOrphanedRelationshipsRequest) (*experimental.DeleteOrphanedRelationshipsResponse, error) {
+	summary, err := repair.Run(ctx, datastoremw.MustFromContext(ctx), repair.Options{
+		Fix:           true,
+		BatchSize:     es.config.OrphanDeletionBatchSize,
+		BatchInterval: es.config.OrphanDeletionBatchInterval,
+		SkipMVCC:      true,
+	})
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	found := make(map[string]uint64, len(summary.Issues))
+	for kind, count := range summary.Issues {
+		if kind != repair.DanglingSchemaCaveat {
+			found[string(kind)] = count
+		}
+	}
+
+	return &experimental.DeleteOrphanedRelationshipsResponse{
+		DeletedCount: summary.Fixed,
+		FoundByKind:  found,
+	}, nil
+}
+
+func (es *experimentalServer) ReachableResources(req *experimental.ReachableResourcesRequest, resp experimental.ExperimentalService_ReachableResourcesServer) error {
+	ctx := resp.Context()
+	atRevision, revisionReadAt := consistency.MustRevisionFromContext(ctx)
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	if err := namespace.CheckNamespacesAndRelations(ctx, ds,
+		namespace.RelationToCheck{Namespace: req.Subject.Object.ObjectType, Relation: normalizeSubjectRelation(req.Subject), AllowEllipsis: true},
+		namespace.RelationToCheck{Namespace: req.ResourceObjectType, Relation: req.Permission, AllowEllipsis: false},
+	); err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The dispatch may reach a resource many times, so each is only sent again if it is
+	// found to have the permission after being sent as requiring a check.
+	sent := make(map[string]dispatchv1.ReachableResource_ResultStatus)
+	limitReached := false
+	stream := dispatch.NewHandlingDispatchStream(ctx, func(result *dispatchv1.DispatchReachableResourcesResponse) error {
+		for _, found := range result.Resources {
+			if limitReached {
+				return nil
+			}
+
+			previous, ok := sent[found.ResourceId]
+			if ok && (previous == dispatchv1.ReachableResource_HAS_PERMISSION || found.ResultStatus == previous) {
+				continue
+			}
+
+			reachability := experimental.ReachableResourcesResponse_REACHABILITY_REQUIRES_CHECK
+			if found.ResultStatus == dispatchv1.ReachableResource_HAS_PERMISSION {
+				reachability = experimental.ReachableResourcesResponse_REACHABILITY_HAS_PERMISSION
+			}
+
+			if err := resp.Send(&experimental.ReachableResourcesResponse{
+				ReachedAt:        revisionReadAt,
+				ResourceObjectId: found.ResourceId,
+				Reachability:     reachability,
+			}); err != nil {
+				return err
+			}
+
+			sent[found.ResourceId] = found.ResultStatus
+			if req.OptionalLimit > 0 && uint32(len(sent)) >= req.OptionalLimit {
+				limitReached = true
+				cancel()
+			}
+		}
+		return nil
+	})
+
+	err := es.dispatch.DispatchReachableResources(&dispatchv1.DispatchReachableResourcesRequest{
+		Metadata: &dispatchv1.ResolverMeta{
+			AtRevision:     atRevision.String(),
+			DepthRemaining: es.config.MaximumAPIDepth,
+		},
+		ResourceRelation: &core.RelationReference{
+			Namespace: req.ResourceObjectType,
+			Relation:  req.Permission,
+		},
+		SubjectRelation: &core.RelationReference{
+			Namespace: req.Subject.Object.ObjectType,
+			Relation:  normalizeSubjectRelation(req.Subject),
+		},
+		SubjectIds: []string{req.Subject.Object.ObjectId},
+	}, stream)
+	if err != nil && !limitReached {
+		return rewriteError(ctx, err)
+	}
+	return nil
+}
+
+func (es *experimentalServer) LookupResourcesSet(req *experimental.LookupResourcesSetRequest, resp experimental.ExperimentalService_LookupResourcesSetServer) error {
+	ctx := resp.Context()
+	atRevision, revisionReadAt := consistency.MustRevisionFromContext(ctx)
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	var found map[string]*dispatchv1.ResolvedResource
+	for index, operand := range req.Operands {
+		if err := namespace.CheckNamespacesAndRelations(ctx, ds,
+			namespace.RelationToCheck{Namespace: operand.Subject.Object.ObjectType, Relation: normalizeSubjectRelation(operand.Subject), AllowEllipsis: true},
+			namespace.RelationToCheck{Namespace: req.ResourceObjectType, Relation: operand.Permission, AllowEllipsis: false},
+		); err != nil {
+			return rewriteError(ctx, err)
+		}
+
+		resources, err := es.lookupResources(ctx, atRevision, req.ResourceObjectType, operand.Permission, &core.ObjectAndRelation{
+			Namespace: operand.Subject.Object.ObjectType,
+			ObjectId:  operand.Subject.Object.ObjectId,
+			Relation:  normalizeSubjectRelation(operand.Subject),
+		}, operand.Context)
+		if err != nil {
+			return rewriteError(ctx, err)
+		}
+
+		operandFound := make(map[string]*dispatchv1.ResolvedResource, len(resources))
+		for _, resource := range resources {
+			operandFound[resource.ResourceId] = resource
+		}
+
+		if index == 0 {
+			found = operandFound
+		} else {
+			found = combineResolvedResources(req.Operation, found, operandFound)
+		}
+
+		// Nothing more can be found by an intersection once it is empty.
+		if len(found) == 0 && req.Operation == experimental.LookupResourcesSetRequest_OPERATION_INTERSECTION {
+			break
+		}
+	}
+
+	resourceIDs := maps.Keys(found)
+	sort.Strings(resourceIDs)
+	for _, resourceID := range resourceIDs {
+		resource := found[resourceID]
+		permissionship := experimental.LookupResourcesSetResponse_PERMISSIONSHIP_HAS_PERMISSION
+		if resource.Permissionship == dispatchv1.ResolvedResource_CONDITIONALLY_HAS_PERMISSION {
+			permissionship = experimental.LookupResourcesSetResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION
+		}
+
+		if err := resp.Send(&experimental.LookupResourcesSetResponse{
+			LookedUpAt:             revisionReadAt,
+			ResourceObjectId:       resourceID,
+			Permissionship:         permissionship,
+			MissingRequiredContext: resource.MissingRequiredContext,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (es *experimentalServer) LookupResourcesFilter(ctx context.Context, req *experimental.LookupResourcesFilterRequest) (*experimental.LookupResourcesFilterResponse, error) {
+	atRevision, revisionReadAt := consistency.MustRevisionFromContext(ctx)
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
+
+	subject := &core.ObjectAndRelation{
+		Namespace: req.Subject.Object.ObjectType,
+		ObjectId:  req.Subject.Object.ObjectId,
+		Relation:  normalizeSubjectRelation(req.Subject),
+	}
+	if err := namespace.CheckNamespacesAndRelations(ctx, ds,
+		namespace.RelationToCheck{Namespace: subject.Namespace, Relation: subject.Relation, AllowEllipsis: true},
+		namespace.RelationToCheck{Namespace: req.ResourceObjectType, Relation: req.Permission, AllowEllipsis: false},
+	); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	lookup := func(resourceType, permission string) ([]*dispatchv1.ResolvedResource, error) {
+		return es.lookupResources(ctx, atRevision, resourceType, permission, subject, nil)
+	}
+
+	resources, err := lookup(req.ResourceObjectType, req.Permission)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	maxResourceIDs := int(req.OptionalMaxResourceIds)
+	if maxResourceIDs == 0 {
+		maxResourceIDs = DefaultMaximumFilterResourceIDs
+	}
+
+	// Resources are only listed if none is conditional, as a list of IDs cannot express it.
+	if len(resources) <= maxResourceIDs {
+		resourceIDs := make([]string, 0, len(resources))
+		for _, resource := range resources {
+			if resource.Permissionship != dispatchv1.ResolvedResource_HAS_PERMISSION {
+				break
+			}
+			resourceIDs = append(resourceIDs, resource.ResourceId)
+		}
+
+		if len(resourceIDs) == len(resources) {
+			sort.Strings(resourceIDs)
+			return &experimental.LookupResourcesFilterResponse{
+				LookedUpAt: revisionReadAt,
+				Filter: &experimental.LookupResourcesFilterResponse_ResourceIds{
+					ResourceIds: &experimental.ResourceIdsFilter{ResourceObjectIds: resourceIDs},
+				},
+			}, nil
+		}
+	}
+
+	predicates, err := relationshipPredicates(ctx, ds, req.ResourceObjectType, req.Permission, subject, lookup)
+	if errors.Is(err, errNotExpressibleAsPredicates) {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s", err)
+	}
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &experimental.LookupResourcesFilterResponse{
+		LookedUpAt: revisionReadAt,
+		Filter: &experimental.LookupResourcesFilterResponse_RelationshipPredicates{
+			RelationshipPredicates: &experimental.RelationshipPredicatesFilter{Predicates: predicates},
+		},
+	}, nil
+}
+
+// lookupResources returns the resources of the type on which the subject has the permission.
+func (es *experimentalServer) lookupResources(ctx context.Context, atRevision datastore.Revision, resourceType, permission string, subject *core.ObjectAndRelation, caveatContext *structpb.Struct) ([]*dispatchv1.ResolvedResource, error) {
+	lookupResp, err := es.dispatch.DispatchLookup(ctx, &dispatchv1.DispatchLookupRequest{
+		Metadata: &dispatchv1.ResolverMeta{
+			AtRevision:     atRevision.String(),
+			DepthRemaining: es.config.MaximumAPIDepth,
+		},
+		ObjectRelation: &core.RelationReference{
+			Namespace: resourceType,
+			Relation:  permission,
+		},
+		Subject: subject,
+		Context: caveatContext,
+		Limit:   ^uint32(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lookupResp.ResolvedResources, nil
+}
+
+// combineResolvedResources returns the intersection or union of two sets of resolved
+// resources. A resource in both sets has the permission if it has it in both sets for an
+// intersection, or in either set for a union.
+func combineResolvedResources(operation experimental.LookupResourcesSetRequest_Operation, left, right map[string]*dispatchv1.ResolvedResource) map[string]*dispatchv1.ResolvedResource {
+	combined := make(map[string]*dispatchv1.ResolvedResource)
+	switch operation {
+	case experimental.LookupResourcesSetRequest_OPERATION_INTERSECTION:
+		for resourceID, l := range left {
+			if r, ok := right[resourceID]; ok {
+				combined[resourceID] = mergeResolvedResources(l, r, hasPermission(l) && hasPermission(r))
+			}
+		}
+
+	default:
+		for resourceID, l := range left {
+			combined[resourceID] = l
+		}
+		for resourceID, r := range right {
+			if l, ok := combined[resourceID]; ok {
+				combined[resourceID] = mergeResolvedResources(l, r, hasPermission(l) || hasPermission(r))
+			} else {
+				combined[resourceID] = r
+			}
+		}
+	}
+	return combined
+}
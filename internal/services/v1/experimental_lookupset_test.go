@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+func TestCombineResolvedResources(t *testing.T) {
+	has := func(resourceID string) *dispatch.ResolvedResource {
+		return &dispatch.ResolvedResource{ResourceId: resourceID, Permissionship: dispatch.ResolvedResource_HAS_PERMISSION}
+	}
+	conditional := func(resourceID string, missing ...string) *dispatch.ResolvedResource {
+		return &dispatch.ResolvedResource{
+			ResourceId:             resourceID,
+			Permissionship:         dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION,
+			MissingRequiredContext: missing,
+		}
+	}
+
+	left := map[string]*dispatch.ResolvedResource{
+		"first":  has("first"),
+		"second": conditional("second", "ip"),
+		"third":  conditional("third", "ip"),
+		"fourth": has("fourth"),
+	}
+	right := map[string]*dispatch.ResolvedResource{
+		"first":  has("first"),
+		"second": has("second"),
+		"third":  conditional("third", "time", "ip"),
+		"fifth":  conditional("fifth", "time"),
+	}
+
+	require.Equal(t, map[string]*dispatch.ResolvedResource{
+		"first":  has("first"),
+		"second": conditional("second", "ip"),
+		"third":  conditional("third", "ip", "time"),
+	}, combineResolvedResources(experimental.LookupResourcesSetRequest_OPERATION_INTERSECTION, left, right))
+
+	require.Equal(t, map[string]*dispatch.ResolvedResource{
+		"first":  has("first"),
+		"second": has("second"),
+		"third":  conditional("third", "ip", "time"),
+		"fourth": has("fourth"),
+		"fifth":  conditional("fifth", "time"),
+	}, combineResolvedResources(experimental.LookupResourcesSetRequest_OPERATION_UNION, left, right))
+}
@@ -0,0 +1,782 @@
+package v1_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/proxy"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func readDocumentsAt(ctx context.Context, client v1.PermissionsServiceClient, token *v1.ZedToken) (int, error) {
+	stream, err := client.ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+		Consistency: &v1.Consistency{
+			Requirement: &v1.Consistency_AtExactSnapshot{AtExactSnapshot: token},
+		},
+		RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document"},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+}
+
+func TestPinRevision(t *testing.T) {
+	require := require.New(t)
+
+	gcWindow := 100 * time.Millisecond
+	conn, cleanup, _, revision := testserver.NewTestServer(require, 0, gcWindow, true, tf.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+	permsClient := v1.NewPermissionsServiceClient(conn)
+
+	unpinned := zedtoken.NewFromRevision(revision)
+	expected, err := readDocumentsAt(ctx, permsClient, unpinned)
+	require.NoError(err)
+	require.Greater(expected, 0)
+
+	resp, err := client.PinRevision(ctx, &experimental.PinRevisionRequest{
+		Name:               "export",
+		OptionalAtRevision: unpinned,
+		Ttl:                durationpb.New(time.Hour),
+	})
+	require.NoError(err)
+	require.NotNil(resp.PinnedAt)
+	require.True(resp.ExpiresAt.AsTime().After(time.Now()))
+
+	written, err := permsClient.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{tuple.UpdateToRelationshipUpdate(
+			tuple.Touch(tuple.MustParse("document:pinned#viewer@user:tom")),
+		)},
+	})
+	require.NoError(err)
+
+	// Once the garbage collection window has passed, only the pinned revision can be read.
+	time.Sleep(2 * gcWindow)
+
+	_, err = readDocumentsAt(ctx, permsClient, written.WrittenAt)
+	grpcutil.RequireStatus(t, codes.OutOfRange, err)
+
+	count, err := readDocumentsAt(ctx, permsClient, resp.PinnedAt)
+	require.NoError(err)
+	require.Equal(expected, count)
+
+	_, err = client.ReleasePinnedRevision(ctx, &experimental.ReleasePinnedRevisionRequest{Name: "export"})
+	require.NoError(err)
+
+	_, err = readDocumentsAt(ctx, permsClient, resp.PinnedAt)
+	grpcutil.RequireStatus(t, codes.OutOfRange, err)
+
+	_, err = client.ReleasePinnedRevision(ctx, &experimental.ReleasePinnedRevisionRequest{Name: "export"})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonPinnedRevisionNotFound, err)
+}
+
+func TestPinRevisionErrors(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, 100*time.Millisecond, true, tf.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+
+	for _, tc := range []struct {
+		name         string
+		request      *experimental.PinRevisionRequest
+		expectedCode codes.Code
+	}{
+		{
+			"invalid name",
+			&experimental.PinRevisionRequest{Name: "not a name", Ttl: durationpb.New(time.Minute)},
+			codes.InvalidArgument,
+		},
+		{
+			"missing ttl",
+			&experimental.PinRevisionRequest{Name: "export"},
+			codes.InvalidArgument,
+		},
+		{
+			"invalid revision",
+			&experimental.PinRevisionRequest{
+				Name:               "export",
+				OptionalAtRevision: &v1.ZedToken{Token: "invalid"},
+				Ttl:                durationpb.New(time.Minute),
+			},
+			codes.InvalidArgument,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := client.PinRevision(ctx, tc.request)
+			grpcutil.RequireStatus(t, tc.expectedCode, err)
+		})
+	}
+}
+
+func orphanedDatastore(ds datastore.Datastore, require *require.Assertions) (datastore.Datastore, datastore.Revision) {
+	ds, _ = tf.StandardDatastoreWithData(ds, require)
+
+	// Datastores do not validate relationships against the schema, so orphans can be
+	// written directly.
+	revision, err := common.WriteTuples(context.Background(), ds, core.RelationTupleUpdate_CREATE,
+		tuple.MustParse("document:first#unknownrel@user:tom"),
+		tuple.MustParse("document:first#viewer@team:eng"),
+		tuple.MustParse("removed:first#viewer@user:tom"),
+	)
+	require.NoError(err)
+	return ds, revision
+}
+
+func TestOrphanedRelationships(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, orphanedDatastore)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+
+	findOrphans := func(limit uint32) map[string]string {
+		stream, err := client.FindOrphanedRelationships(ctx, &experimental.FindOrphanedRelationshipsRequest{OptionalLimit: limit})
+		require.NoError(err)
+
+		found := make(map[string]string)
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return found
+			}
+			require.NoError(err)
+			found[tuple.MustRelString(resp.Relationship)] = resp.Kind
+		}
+	}
+
+	// Relationships on types without a definition are only found by datastores which
+	// can list the types of stored relationships, which memdb does not.
+	require.Equal(map[string]string{
+		"document:first#unknownrel@user:tom": "undefined-relation",
+		"document:first#viewer@team:eng":     "undefined-subject-type",
+	}, findOrphans(0))
+	require.Len(findOrphans(1), 1)
+
+	resp, err := client.DeleteOrphanedRelationships(ctx, &experimental.DeleteOrphanedRelationshipsRequest{})
+	require.NoError(err)
+	require.Equal(uint64(2), resp.DeletedCount)
+	require.Equal(map[string]uint64{
+		"undefined-relation":     1,
+		"undefined-subject-type": 1,
+	}, resp.FoundByKind)
+
+	require.Empty(findOrphans(0))
+}
+
+// readOnlyDatastore is an orphaned datastore, with a schema version, a running job and the
+// tombstone of a removed definition, behind a read-only proxy.
+func readOnlyDatastore(ds datastore.Datastore, require *require.Assertions) (datastore.Datastore, datastore.Revision) {
+	ctx := context.Background()
+	ds, revision := orphanedDatastore(ds, require)
+
+	history, err := common.SchemaHistoryStoreOf(ds)
+	require.NoError(err)
+	_, err = history.AddSchemaVersion(ctx, revision, "definition user {}", "")
+	require.NoError(err)
+
+	jobs, ok := datastore.UnwrapAs[common.JobStore](ds)
+	require.True(ok)
+	require.NoError(jobs.CreateJob(ctx, common.Job{ID: "running", Kind: "delete-relationships", State: common.JobRunning}))
+
+	removedAt, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(ctx, namespace.Namespace("removed",
+			namespace.Relation("viewer", nil, namespace.AllowedRelation("user", "...")),
+		))
+	})
+	require.NoError(err)
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.DeleteNamespaces(ctx, "removed")
+	})
+	require.NoError(err)
+
+	tombstones, ok := datastore.UnwrapAs[common.NamespaceTombstoneStore](ds)
+	require.True(ok)
+	require.NoError(tombstones.WriteNamespaceTombstone(ctx, common.NamespaceTombstone{
+		Namespace: "removed",
+		Revision:  removedAt,
+		DeletedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}))
+
+	return proxy.NewReadonlyDatastore(ds), revision
+}
+
+func TestMutatingMethodsOverReadOnlyDatastore(t *testing.T) {
+	require := require.New(t)
+
+	// The read-only middleware is not installed, so that the writes reach the proxy.
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, readOnlyDatastore)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+
+	// SetCanarySchema is not included, as it changes the state of the server rather than
+	// of the datastore, and so is only rejected by the read-only middleware.
+	testCases := []struct {
+		method string
+		call   func() error
+	}{
+		{"PinRevision", func() error {
+			_, err := client.PinRevision(ctx, &experimental.PinRevisionRequest{Name: "export", Ttl: durationpb.New(time.Hour)})
+			return err
+		}},
+		{"ReleasePinnedRevision", func() error {
+			_, err := client.ReleasePinnedRevision(ctx, &experimental.ReleasePinnedRevisionRequest{Name: "export"})
+			return err
+		}},
+		{"DeleteOrphanedRelationships", func() error {
+			_, err := client.DeleteOrphanedRelationships(ctx, &experimental.DeleteOrphanedRelationshipsRequest{})
+			return err
+		}},
+		{"RestoreSchemaVersion", func() error {
+			_, err := client.RestoreSchemaVersion(ctx, &experimental.RestoreSchemaVersionRequest{Version: 1})
+			return err
+		}},
+		{"SetNamespaceExperiment", func() error {
+			_, err := client.SetNamespaceExperiment(ctx, &experimental.SetNamespaceExperimentRequest{
+				Experiment: &experimental.NamespaceExperiment{Namespace: "document", Experiment: "new-planner"},
+				Enabled:    true,
+			})
+			return err
+		}},
+		{"StartJob", func() error {
+			_, err := client.StartJob(ctx, &experimental.StartJobRequest{
+				Job: &experimental.StartJobRequest_DeleteRelationships{
+					DeleteRelationships: &experimental.DeleteRelationshipsJob{
+						RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document"},
+					},
+				},
+			})
+			return err
+		}},
+		{"CancelJob", func() error {
+			_, err := client.CancelJob(ctx, &experimental.CancelJobRequest{Id: "running"})
+			return err
+		}},
+		{"RestoreNamespace", func() error {
+			_, err := client.RestoreNamespace(ctx, &experimental.RestoreNamespaceRequest{Namespace: "removed"})
+			return err
+		}},
+		{"DeleteRelationshipsFromSource", func() error {
+			_, err := client.DeleteRelationshipsFromSource(ctx, &experimental.DeleteRelationshipsFromSourceRequest{Source: "import"})
+			return err
+		}},
+		{"BulkImportRelationships", func() error {
+			stream, err := client.BulkImportRelationships(ctx)
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(&experimental.BulkImportRelationshipsRequest{
+				Relationships: []*v1.Relationship{tuple.MustToRelationship(tuple.MustParse("document:imported#viewer@user:tom"))},
+			}); err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			_, err = stream.CloseAndRecv()
+			return err
+		}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.method, func(t *testing.T) {
+			err := tc.call()
+			grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+			spiceerrors.RequireExtendedReason(t, spiceerrors.ExtendedReason(v1.ErrorReason_ERROR_REASON_SERVICE_READ_ONLY.String()), err)
+		})
+	}
+}
+
+func TestReachableResources(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+
+	client := experimental.NewExperimentalServiceClient(conn)
+
+	reachable := func(permission, subjectID string, limit uint32) (map[string]experimental.ReachableResourcesResponse_Reachability, error) {
+		stream, err := client.ReachableResources(context.Background(), &experimental.ReachableResourcesRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true},
+			},
+			ResourceObjectType: "document",
+			Permission:         permission,
+			Subject: &v1.SubjectReference{
+				Object: &v1.ObjectReference{ObjectType: "user", ObjectId: subjectID},
+			},
+			OptionalLimit: limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		found := make(map[string]experimental.ReachableResourcesResponse_Reachability)
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return found, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			require.NotNil(t, resp.ReachedAt)
+			require.Less(t, found[resp.ResourceObjectId], resp.Reachability, "resource sent again without changing")
+			found[resp.ResourceObjectId] = resp.Reachability
+		}
+	}
+
+	found, err := reachable("view", "owner", 0)
+	require.NoError(t, err)
+	require.Equal(t, map[string]experimental.ReachableResourcesResponse_Reachability{
+		"companyplan": experimental.ReachableResourcesResponse_REACHABILITY_HAS_PERMISSION,
+		"masterplan":  experimental.ReachableResourcesResponse_REACHABILITY_HAS_PERMISSION,
+	}, found)
+
+	// Intersections are not computed, so the resource is reachable even though the subject
+	// does not have the permission.
+	found, err = reachable("view_and_edit", "missingrolegal", 0)
+	require.NoError(t, err)
+	require.Equal(t, map[string]experimental.ReachableResourcesResponse_Reachability{
+		"specialplan": experimental.ReachableResourcesResponse_REACHABILITY_REQUIRES_CHECK,
+	}, found)
+
+	found, err = reachable("view", "owner", 1)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+
+	_, err = reachable("unknown", "owner", 0)
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+}
+
+func TestLookupResourcesSet(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+
+	client := experimental.NewExperimentalServiceClient(conn)
+
+	lookupSet := func(operation experimental.LookupResourcesSetRequest_Operation, permission string, subjectIDs ...string) ([]string, error) {
+		operands := make([]*experimental.LookupResourcesSetOperand, 0, len(subjectIDs))
+		for _, subjectID := range subjectIDs {
+			operands = append(operands, &experimental.LookupResourcesSetOperand{
+				Permission: permission,
+				Subject: &v1.SubjectReference{
+					Object: &v1.ObjectReference{ObjectType: "user", ObjectId: subjectID},
+				},
+			})
+		}
+
+		stream, err := client.LookupResourcesSet(context.Background(), &experimental.LookupResourcesSetRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true},
+			},
+			ResourceObjectType: "document",
+			Operation:          operation,
+			Operands:           operands,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var found []string
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return found, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			require.NotNil(t, resp.LookedUpAt)
+			require.Equal(t, experimental.LookupResourcesSetResponse_PERMISSIONSHIP_HAS_PERMISSION, resp.Permissionship)
+			found = append(found, resp.ResourceObjectId)
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		operation  experimental.LookupResourcesSetRequest_Operation
+		permission string
+		subjectIDs []string
+		expected   []string
+	}{
+		{
+			"single operand",
+			experimental.LookupResourcesSetRequest_OPERATION_INTERSECTION,
+			"view",
+			[]string{"owner"},
+			[]string{"companyplan", "masterplan"},
+		},
+		{
+			"intersection",
+			experimental.LookupResourcesSetRequest_OPERATION_INTERSECTION,
+			"view",
+			[]string{"owner", "chief_financial_officer"},
+			[]string{"masterplan"},
+		},
+		{
+			"empty intersection",
+			experimental.LookupResourcesSetRequest_OPERATION_INTERSECTION,
+			"view",
+			[]string{"owner", "villain", "chief_financial_officer"},
+			nil,
+		},
+		{
+			"union",
+			experimental.LookupResourcesSetRequest_OPERATION_UNION,
+			"view",
+			[]string{"owner", "chief_financial_officer", "villain"},
+			[]string{"companyplan", "healthplan", "masterplan"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := lookupSet(tc.operation, tc.permission, tc.subjectIDs...)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, found)
+		})
+	}
+
+	_, err := lookupSet(experimental.LookupResourcesSetRequest_OPERATION_UNSPECIFIED, "view", "owner")
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	_, err = lookupSet(experimental.LookupResourcesSetRequest_OPERATION_UNION, "unknown", "owner")
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+}
+
+func TestLookupResourcesFilter(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+
+	client := experimental.NewExperimentalServiceClient(conn)
+
+	filter := func(permission string, maxResourceIDs uint32) (*experimental.LookupResourcesFilterResponse, error) {
+		return client.LookupResourcesFilter(context.Background(), &experimental.LookupResourcesFilterRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true},
+			},
+			ResourceObjectType: "document",
+			Permission:         permission,
+			Subject: &v1.SubjectReference{
+				Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "owner"},
+			},
+			OptionalMaxResourceIds: maxResourceIDs,
+		})
+	}
+
+	resp, err := filter("view", 0)
+	require.NoError(t, err)
+	require.NotNil(t, resp.LookedUpAt)
+	require.Equal(t, []string{"companyplan", "masterplan"}, resp.GetResourceIds().ResourceObjectIds)
+
+	resp, err = filter("view", 1)
+	require.NoError(t, err)
+	require.Nil(t, resp.GetResourceIds())
+
+	var predicates []string
+	for _, predicate := range resp.GetRelationshipPredicates().Predicates {
+		require.Empty(t, predicate.OptionalSubjectRelation)
+		for _, subjectID := range predicate.SubjectObjectIds {
+			predicates = append(predicates, predicate.Relation+"@"+predicate.SubjectObjectType+":"+subjectID)
+		}
+	}
+	require.Equal(t, []string{
+		"viewer@user:owner",
+		"owner@user:owner",
+		"editor@user:owner",
+		"parent@folder:company",
+		"parent@folder:strategy",
+	}, predicates)
+
+	_, err = filter("unknown", 0)
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+}
+
+func TestSchemaVersions(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+	schemaClient := v1.NewSchemaServiceClient(conn)
+
+	_, err := client.ReadSchemaVersion(ctx, &experimental.ReadSchemaVersionRequest{Version: 1})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonSchemaVersionNotFound, err)
+
+	firstSchema := `definition user {}
+
+definition document {
+	relation viewer: user
+	permission view = viewer
+}`
+	authorCtx := metadata.AppendToOutgoingContext(ctx, string(v1svc.RequestSchemaAuthor), "tom")
+	_, err = schemaClient.WriteSchema(authorCtx, &v1.WriteSchemaRequest{Schema: firstSchema})
+	require.NoError(err)
+
+	secondSchema := `definition user {}
+
+definition document {
+	relation editor: user
+	relation viewer: user
+	permission view = viewer + editor
+}
+
+definition folder {}`
+	written, err := schemaClient.WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: secondSchema})
+	require.NoError(err)
+	require.NotNil(written)
+
+	listed, err := client.ListSchemaVersions(ctx, &experimental.ListSchemaVersionsRequest{})
+	require.NoError(err)
+	require.Len(listed.Versions, 2)
+	require.Equal("tom", listed.Versions[0].Author)
+	require.Empty(listed.Versions[1].Author)
+	require.Less(listed.Versions[0].Version, listed.Versions[1].Version)
+	require.NotNil(listed.Versions[0].WrittenAt)
+
+	read, err := client.ReadSchemaVersion(ctx, &experimental.ReadSchemaVersionRequest{Version: listed.Versions[0].Version})
+	require.NoError(err)
+	require.Equal(firstSchema, read.SchemaText)
+	require.Equal(listed.Versions[0].WrittenAt.Token, read.Version.WrittenAt.Token)
+
+	diff, err := client.DiffSchemaVersions(ctx, &experimental.DiffSchemaVersionsRequest{
+		FromVersion: listed.Versions[0].Version,
+		ToVersion:   listed.Versions[1].Version,
+	})
+	require.NoError(err)
+
+	changes := make([]string, 0, len(diff.Changes))
+	for _, change := range diff.Changes {
+		changes = append(changes, change.DefinitionName+":"+change.Kind+":"+change.RelationName)
+	}
+	require.Equal([]string{
+		"document:added-relation:editor",
+		"document:changed-permission-implementation:view",
+		"folder:namespace-added:",
+	}, changes)
+
+	_, err = client.DiffSchemaVersions(ctx, &experimental.DiffSchemaVersionsRequest{FromVersion: 1, ToVersion: 3})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+}
+
+func TestRestoreSchemaVersion(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+	schemaClient := v1.NewSchemaServiceClient(conn)
+	permissionsClient := v1.NewPermissionsServiceClient(conn)
+
+	_, err := client.RestoreSchemaVersion(ctx, &experimental.RestoreSchemaVersionRequest{Version: 1})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+
+	firstSchema := `definition document {
+	relation viewer: user
+}
+
+definition user {}`
+	_, err = schemaClient.WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: firstSchema})
+	require.NoError(err)
+
+	_, err = schemaClient.WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: `definition user {}
+
+definition document {
+	relation editor: user
+	relation viewer: user
+}`})
+	require.NoError(err)
+
+	listed, err := client.ListSchemaVersions(ctx, &experimental.ListSchemaVersionsRequest{})
+	require.NoError(err)
+	require.Len(listed.Versions, 2)
+
+	// Restore the first version, removing the editor relation.
+	authorCtx := metadata.AppendToOutgoingContext(ctx, string(v1svc.RequestSchemaAuthor), "tom")
+	restored, err := client.RestoreSchemaVersion(authorCtx, &experimental.RestoreSchemaVersionRequest{Version: listed.Versions[0].Version})
+	require.NoError(err)
+	require.NotNil(restored.WrittenAt)
+
+	read, err := schemaClient.ReadSchema(ctx, &v1.ReadSchemaRequest{})
+	require.NoError(err)
+	require.Equal(firstSchema, read.SchemaText)
+
+	listed, err = client.ListSchemaVersions(ctx, &experimental.ListSchemaVersionsRequest{})
+	require.NoError(err)
+	require.Len(listed.Versions, 3)
+	require.Equal("tom", listed.Versions[2].Author)
+	require.Equal(restored.WrittenAt.Token, listed.Versions[2].WrittenAt.Token)
+
+	// Restoring the second version and writing an editor makes the first version
+	// invalid to restore.
+	_, err = client.RestoreSchemaVersion(ctx, &experimental.RestoreSchemaVersionRequest{Version: listed.Versions[1].Version})
+	require.NoError(err)
+
+	_, err = permissionsClient.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{tuple.UpdateToRelationshipUpdate(
+			tuple.Create(tuple.MustParse("document:first#editor@user:tom")),
+		)},
+	})
+	require.NoError(err)
+
+	_, err = client.RestoreSchemaVersion(ctx, &experimental.RestoreSchemaVersionRequest{Version: listed.Versions[0].Version})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	listed, err = client.ListSchemaVersions(ctx, &experimental.ListSchemaVersionsRequest{})
+	require.NoError(err)
+	require.Len(listed.Versions, 4)
+}
+
+func TestNamespaceExperiments(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, tf.StandardDatastoreWithSchema)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+
+	_, err := client.SetNamespaceExperiment(ctx, &experimental.SetNamespaceExperimentRequest{
+		Experiment: &experimental.NamespaceExperiment{Namespace: "unknown", Experiment: "new-planner"},
+		Enabled:    true,
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	_, err = client.SetNamespaceExperiment(ctx, &experimental.SetNamespaceExperimentRequest{
+		Experiment: &experimental.NamespaceExperiment{Namespace: "document", Experiment: "New_Planner"},
+		Enabled:    true,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	for _, experiment := range []*experimental.NamespaceExperiment{
+		{Namespace: "folder", Experiment: "new-planner"},
+		{Namespace: "document", Experiment: "new-planner"},
+	} {
+		_, err := client.SetNamespaceExperiment(ctx, &experimental.SetNamespaceExperimentRequest{Experiment: experiment, Enabled: true})
+		require.NoError(err)
+	}
+
+	listed, err := client.ListNamespaceExperiments(ctx, &experimental.ListNamespaceExperimentsRequest{})
+	require.NoError(err)
+	require.Len(listed.Experiments, 2)
+	require.Equal("document", listed.Experiments[0].Namespace)
+	require.Equal("folder", listed.Experiments[1].Namespace)
+
+	// Experiments of namespaces not in the schema can still be disabled.
+	for _, namespace := range []string{"document", "unknown"} {
+		_, err := client.SetNamespaceExperiment(ctx, &experimental.SetNamespaceExperimentRequest{
+			Experiment: &experimental.NamespaceExperiment{Namespace: namespace, Experiment: "new-planner"},
+		})
+		require.NoError(err)
+	}
+
+	listed, err = client.ListNamespaceExperiments(ctx, &experimental.ListNamespaceExperimentsRequest{})
+	require.NoError(err)
+	require.Len(listed.Experiments, 1)
+	require.Equal("folder", listed.Experiments[0].Namespace)
+}
+
+func TestStreamingCheckPermission(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, revision := testserver.NewTestServer(require, 0, memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+
+	client := experimental.NewExperimentalServiceClient(conn)
+	stream, err := client.StreamingCheckPermission(context.Background())
+	require.NoError(err)
+
+	checks := map[string]*experimental.StreamingCheckPermissionRequest{
+		"eng_lead": {
+			Resource:   obj("document", "masterplan"),
+			Permission: "view",
+			Subject:    sub("user", "eng_lead", ""),
+		},
+		"villain": {
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_AtExactSnapshot{AtExactSnapshot: zedtoken.NewFromRevision(revision)},
+			},
+			Resource:   obj("document", "masterplan"),
+			Permission: "view",
+			Subject:    sub("user", "villain", ""),
+		},
+		"unknown": {
+			Resource:   obj("document", "masterplan"),
+			Permission: "unknown",
+			Subject:    sub("user", "eng_lead", ""),
+		},
+	}
+	for correlationID, req := range checks {
+		req.CorrelationId = correlationID
+		require.NoError(stream.Send(req))
+	}
+	require.NoError(stream.CloseSend())
+
+	results := make(map[string]*experimental.StreamingCheckPermissionResponse, len(checks))
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(err)
+		results[resp.CorrelationId] = resp
+	}
+	require.Len(results, len(checks))
+
+	require.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION, results["eng_lead"].GetResponse().Permissionship)
+	require.NotNil(results["eng_lead"].GetResponse().CheckedAt)
+	require.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION, results["villain"].GetResponse().Permissionship)
+	require.Equal(int32(codes.FailedPrecondition), results["unknown"].GetError().GetCode())
+
+	// Invalid requests end the stream.
+	stream, err = client.StreamingCheckPermission(context.Background())
+	require.NoError(err)
+	require.NoError(stream.Send(&experimental.StreamingCheckPermissionRequest{CorrelationId: "invalid", Permission: "view"}))
+	_, err = stream.Recv()
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
@@ -0,0 +1,335 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/authzed/spicedb/internal/datastore/backup"
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/internal/datastore/rename"
+	"github.com/authzed/spicedb/internal/datastore/repair"
+	"github.com/authzed/spicedb/internal/jobs"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// The kinds of jobs started by StartJob.
+const (
+	deleteRelationshipsJobKind         = "delete-relationships"
+	renameObjectTypeJobKind            = "rename-object-type"
+	collectGarbageJobKind              = "collect-garbage"
+	backupJobKind                      = "backup"
+	deleteOrphanedRelationshipsJobKind = "delete-orphaned-relationships"
+)
+
+var jobStates = map[common.JobState]experimental.Job_State{
+	common.JobRunning:   experimental.Job_STATE_RUNNING,
+	common.JobSucceeded: experimental.Job_STATE_SUCCEEDED,
+	common.JobFailed:    experimental.Job_STATE_FAILED,
+	common.JobCanceled:  experimental.Job_STATE_CANCELED,
+}
+
+func (es *experimentalServer) StartJob(ctx context.Context, req *experimental.StartJobRequest) (*experimental.StartJobResponse, error) {
+	ds := datastoremw.MustFromContext(ctx)
+	store, err := jobStore(ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	kind, run, err := es.jobFunc(ds, req)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	parameters, err := req.MarshalVT()
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	job, err := es.config.JobRunner.Start(ctx, store, kind, parameters, run)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+	return &experimental.StartJobResponse{Job: jobToProto(job)}, nil
+}
+
+func (es *experimentalServer) GetJob(ctx context.Context, req *experimental.GetJobRequest) (*experimental.GetJobResponse, error) {
+	store, err := jobStore(datastoremw.MustFromContext(ctx))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	job, err := es.config.JobRunner.Read(ctx, store, req.Id)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+	return &experimental.GetJobResponse{Job: jobToProto(job)}, nil
+}
+
+func (es *experimentalServer) ListJobs(ctx context.Context, req *experimental.ListJobsRequest) (*experimental.ListJobsResponse, error) {
+	store, err := jobStore(datastoremw.MustFromContext(ctx))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	found, err := es.config.JobRunner.List(ctx, store, uint64(req.OptionalLimit))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	resp := &experimental.ListJobsResponse{Jobs: make([]*experimental.Job, 0, len(found))}
+	for _, job := range found {
+		resp.Jobs = append(resp.Jobs, jobToProto(job))
+	}
+	return resp, nil
+}
+
+func (es *experimentalServer) CancelJob(ctx context.Context, req *experimental.CancelJobRequest) (*experimental.CancelJobResponse, error) {
+	store, err := jobStore(datastoremw.MustFromContext(ctx))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	job, err := es.config.JobRunner.Cancel(ctx, store, req.Id)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+	return &experimental.CancelJobResponse{Job: jobToProto(job)}, nil
+}
+
+func jobStore(ds datastore.Datastore) (common.JobStore, error) {
+	store, ok := datastore.UnwrapAs[common.JobStore](ds)
+	if !ok {
+		return nil, common.ErrJobsUnsupported
+	}
+	return store, nil
+}
+
+// jobFunc returns the kind and the function of the job requested, whose result is the
+// counts of the items it processed.
+func (es *experimentalServer) jobFunc(ds datastore.Datastore, req *experimental.StartJobRequest) (string, jobs.Func, error) {
+	var kind string
+	var run func(ctx context.Context, progress func(uint64)) (map[string]uint64, error)
+
+	switch job := req.Job.(type) {
+	case *experimental.StartJobRequest_DeleteRelationships:
+		filter, err := deleteRelationshipsJobFilter(job.DeleteRelationships)
+		if err != nil {
+			return "", nil, spiceerrors.WithCodeAndExtendedReason(
+				err,
+				codes.InvalidArgument,
+				spiceerrors.ReasonInvalidArgument,
+				nil,
+			)
+		}
+
+		kind = deleteRelationshipsJobKind
+		run = func(ctx context.Context, progress func(uint64)) (map[string]uint64, error) {
+			deleted, err := deleteRelationshipsInBatches(ctx, ds, filter, es.config.JobBatchSize, progress)
+			return map[string]uint64{"relationships": deleted}, err
+		}
+
+	case *experimental.StartJobRequest_RenameObjectType:
+		if job.RenameObjectType.From == job.RenameObjectType.To {
+			return "", nil, spiceerrors.WithCodeAndExtendedReason(
+				fmt.Errorf("cannot rename `%s` to itself", job.RenameObjectType.From),
+				codes.InvalidArgument,
+				spiceerrors.ReasonInvalidArgument,
+				nil,
+			)
+		}
+
+		kind = renameObjectTypeJobKind
+		run = func(ctx context.Context, progress func(uint64)) (map[string]uint64, error) {
+			moved, err := rename.Run(ctx, ds, job.RenameObjectType.From, job.RenameObjectType.To, rename.Options{
+				BatchSize:  es.config.JobBatchSize,
+				OnProgress: func(p rename.Progress) { progress(p.Moved) },
+			})
+			return map[string]uint64{"relationships": moved}, err
+		}
+
+	case *experimental.StartJobRequest_CollectGarbage:
+		gc, ok := datastore.UnwrapAs[common.GarbageCollector](ds)
+		if !ok {
+			return "", nil, spiceerrors.WithCodeAndExtendedReason(
+				errors.New("datastore does not support garbage collection"),
+				codes.Unimplemented,
+				spiceerrors.ReasonUnimplemented,
+				nil,
+			)
+		}
+
+		kind = collectGarbageJobKind
+		run = func(ctx context.Context, _ func(uint64)) (map[string]uint64, error) {
+			collected, err := common.CollectGarbage(ctx, gc, es.config.GCWindow)
+			return map[string]uint64{
+				"relationships": uint64(collected.Relationships),
+				"transactions":  uint64(collected.Transactions),
+				"namespaces":    uint64(collected.Namespaces),
+			}, err
+		}
+
+	case *experimental.StartJobRequest_Backup:
+		// The job runs in the background of the server, so there is no caller to which the
+		// backup could be written.
+		if job.Backup.Location == backup.StdioLocation {
+			return "", nil, spiceerrors.WithCodeAndExtendedReason(
+				errors.New("backups of jobs must be written to a path or URL"),
+				codes.InvalidArgument,
+				spiceerrors.ReasonInvalidArgument,
+				nil,
+			)
+		}
+
+		kind = backupJobKind
+		run = func(ctx context.Context, _ func(uint64)) (map[string]uint64, error) {
+			w, err := backup.Create(ctx, job.Backup.Location)
+			if err != nil {
+				return nil, err
+			}
+
+			counts, err := backup.Backup(ctx, ds, es.config.DatastoreEngine, true, w)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+			return map[string]uint64{
+				"caveats":       counts.Caveats,
+				"namespaces":    counts.Namespaces,
+				"relationships": counts.Relationships,
+			}, err
+		}
+
+	case *experimental.StartJobRequest_DeleteOrphanedRelationships:
+		kind = deleteOrphanedRelationshipsJobKind
+		run = func(ctx context.Context, progress func(uint64)) (map[string]uint64, error) {
+			var found uint64
+			summary, err := repair.Run(ctx, ds, repair.Options{
+				Fix:           true,
+				BatchSize:     es.config.OrphanDeletionBatchSize,
+				BatchInterval: es.config.OrphanDeletionBatchInterval,
+				SkipMVCC:      true,
+				OnIssue: func(repair.Issue) {
+					found++
+					progress(found)
+				},
+			})
+
+			counts := map[string]uint64{"deleted": summary.Fixed}
+			for kind, count := range summary.Issues {
+				if kind != repair.DanglingSchemaCaveat {
+					counts[string(kind)] = count
+				}
+			}
+			return counts, err
+		}
+
+	default:
+		return "", nil, fmt.Errorf("unknown job `%T`", req.Job)
+	}
+
+	return kind, func(ctx context.Context, progress func(uint64)) ([]byte, error) {
+		counts, err := run(ctx, progress)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(counts)
+	}, nil
+}
+
+// deleteRelationshipsJobFilter returns the filter of the relationships deleted by a job,
+// including the prefixes of their object IDs.
+func deleteRelationshipsJobFilter(job *experimental.DeleteRelationshipsJob) (datastore.RelationshipsFilter, error) {
+	filter := datastore.RelationshipsFilterFromPublicFilter(job.RelationshipFilter)
+
+	if job.OptionalResourceIdPrefix != "" {
+		if job.RelationshipFilter.OptionalResourceId != "" {
+			return filter, errors.New("a resource ID prefix cannot be combined with a resource ID")
+		}
+		filter.OptionalResourceIDPrefix = job.OptionalResourceIdPrefix
+	}
+
+	if job.OptionalSubjectIdPrefix != "" {
+		subjectFilter := job.RelationshipFilter.OptionalSubjectFilter
+		if subjectFilter == nil || subjectFilter.OptionalSubjectId != "" {
+			return filter, errors.New("a subject ID prefix requires a subject filter without a subject ID")
+		}
+		filter.OptionalSubjectsFilter.OptionalSubjectIDPrefix = job.OptionalSubjectIdPrefix
+	}
+
+	return filter, nil
+}
+
+// deleteRelationshipsInBatches deletes the relationships matching the filter in separate
+// transactions of up to batchSize relationships each, returning the number deleted.
+func deleteRelationshipsInBatches(ctx context.Context, ds datastore.Datastore, dsFilter datastore.RelationshipsFilter, batchSize int, progress func(uint64)) (uint64, error) {
+	limit := uint64(batchSize)
+
+	var deleted uint64
+	for {
+		var batch uint64
+		_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+			iter, err := rwt.QueryRelationships(ctx, dsFilter, options.WithLimit(&limit))
+			if err != nil {
+				return err
+			}
+
+			var updates []*core.RelationTupleUpdate
+			for rel := iter.Next(); rel != nil; rel = iter.Next() {
+				updates = append(updates, tuple.Delete(rel))
+			}
+			if err := iter.Err(); err != nil {
+				iter.Close()
+				return err
+			}
+			iter.Close()
+
+			batch = uint64(len(updates))
+			if len(updates) == 0 {
+				return nil
+			}
+			if err := rwt.WriteRelationships(ctx, updates); err != nil {
+				return err
+			}
+			return recordRelationshipSources(ctx, rwt, "", updates)
+		})
+		if err != nil {
+			return deleted, fmt.Errorf("unable to delete relationships: %w", err)
+		}
+		if batch == 0 {
+			return deleted, nil
+		}
+
+		deleted += batch
+		progress(deleted)
+	}
+}
+
+func jobToProto(job common.Job) *experimental.Job {
+	converted := &experimental.Job{
+		Id:              job.ID,
+		Kind:            job.Kind,
+		State:           jobStates[job.State],
+		Progress:        job.Progress,
+		Error:           job.Error,
+		CancelRequested: job.CancelRequested,
+		CreatedAt:       timestamppb.New(job.CreatedAt),
+		UpdatedAt:       timestamppb.New(job.UpdatedAt),
+	}
+
+	// Results are only stored by this server, so one which cannot be read is left out
+	// rather than failing the call.
+	if len(job.Result) > 0 {
+		_ = json.Unmarshal(job.Result, &converted.ResultCounts)
+	}
+	return converted
+}
@@ -0,0 +1,151 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/internal/jobs"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+func countTypeRelationships(t *testing.T, ds datastore.Datastore, resourceType string) uint64 {
+	headRevision, err := ds.HeadRevision(context.Background())
+	require.NoError(t, err)
+
+	iter, err := ds.SnapshotReader(headRevision).QueryRelationships(context.Background(), datastore.RelationshipsFilter{ResourceType: resourceType})
+	require.NoError(t, err)
+	defer iter.Close()
+
+	var count uint64
+	for rel := iter.Next(); rel != nil; rel = iter.Next() {
+		count++
+	}
+	require.NoError(t, iter.Err())
+	return count
+}
+
+func TestDeleteRelationshipsJob(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, _ := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	runner := jobs.NewRunner(10 * time.Millisecond)
+	defer runner.Close()
+	server := NewExperimentalServer(graph.NewLocalOnlyDispatcher(10), ExperimentalServerConfig{
+		JobRunner:    runner,
+		JobBatchSize: 2,
+	}).(*experimentalServer)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	documents := countTypeRelationships(t, ds, "document")
+	require.Greater(documents, uint64(2))
+	folders := countTypeRelationships(t, ds, "folder")
+
+	started, err := server.StartJob(ctx, &experimental.StartJobRequest{
+		Job: &experimental.StartJobRequest_DeleteRelationships{
+			DeleteRelationships: &experimental.DeleteRelationshipsJob{
+				RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document"},
+			},
+		},
+	})
+	require.NoError(err)
+	require.Equal("delete-relationships", started.Job.Kind)
+
+	var job *experimental.Job
+	require.Eventually(func() bool {
+		resp, err := server.GetJob(ctx, &experimental.GetJobRequest{Id: started.Job.Id})
+		require.NoError(err)
+		job = resp.Job
+		return job.State == experimental.Job_STATE_SUCCEEDED
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Equal(map[string]uint64{"relationships": documents}, job.ResultCounts)
+	require.Equal(documents, job.Progress)
+	require.Zero(countTypeRelationships(t, ds, "document"))
+	require.Equal(folders, countTypeRelationships(t, ds, "folder"))
+
+	listed, err := server.ListJobs(ctx, &experimental.ListJobsRequest{})
+	require.NoError(err)
+	require.Len(listed.Jobs, 1)
+	require.Equal(started.Job.Id, listed.Jobs[0].Id)
+}
+
+func TestJobErrors(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, _ := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	server := NewExperimentalServer(graph.NewLocalOnlyDispatcher(10), ExperimentalServerConfig{}).(*experimentalServer)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	_, err = server.GetJob(ctx, &experimental.GetJobRequest{Id: "unknown"})
+	require.Equal(codes.NotFound, status.Code(err))
+
+	_, err = server.CancelJob(ctx, &experimental.CancelJobRequest{Id: "unknown"})
+	require.Equal(codes.NotFound, status.Code(err))
+
+	_, err = server.StartJob(ctx, &experimental.StartJobRequest{
+		Job: &experimental.StartJobRequest_Backup{Backup: &experimental.BackupJob{Location: "-"}},
+	})
+	require.Equal(codes.InvalidArgument, status.Code(err))
+
+	_, err = server.StartJob(ctx, &experimental.StartJobRequest{
+		Job: &experimental.StartJobRequest_RenameObjectType{
+			RenameObjectType: &experimental.RenameObjectTypeJob{From: "document", To: "document"},
+		},
+	})
+	require.Equal(codes.InvalidArgument, status.Code(err))
+
+	_, err = server.StartJob(ctx, &experimental.StartJobRequest{
+		Job: &experimental.StartJobRequest_DeleteRelationships{
+			DeleteRelationships: &experimental.DeleteRelationshipsJob{
+				RelationshipFilter:       &v1.RelationshipFilter{ResourceType: "document", OptionalResourceId: "masterplan"},
+				OptionalResourceIdPrefix: "master",
+			},
+		},
+	})
+	require.Equal(codes.InvalidArgument, status.Code(err))
+
+	_, err = server.StartJob(ctx, &experimental.StartJobRequest{
+		Job: &experimental.StartJobRequest_DeleteRelationships{
+			DeleteRelationships: &experimental.DeleteRelationshipsJob{
+				RelationshipFilter:      &v1.RelationshipFilter{ResourceType: "document"},
+				OptionalSubjectIdPrefix: "tenant1-",
+			},
+		},
+	})
+	require.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func TestDeleteRelationshipsJobFilter(t *testing.T) {
+	filter, err := deleteRelationshipsJobFilter(&experimental.DeleteRelationshipsJob{
+		RelationshipFilter: &v1.RelationshipFilter{
+			ResourceType:          "document",
+			OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: "user"},
+		},
+		OptionalResourceIdPrefix: "tenant1-",
+		OptionalSubjectIdPrefix:  "tenant1-",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "tenant1-", filter.OptionalResourceIDPrefix)
+	require.Equal(t, "tenant1-", filter.OptionalSubjectsFilter.OptionalSubjectIDPrefix)
+}
@@ -0,0 +1,213 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// errNotExpressibleAsPredicates is returned when a permission cannot be expressed as
+// predicates over the relationships of its resources.
+var errNotExpressibleAsPredicates = errors.New("permission cannot be expressed as relationship predicates")
+
+// lookupFunc returns the resources of the type on which the subject of a filter has the
+// permission.
+type lookupFunc func(resourceType, permission string) ([]*dispatch.ResolvedResource, error)
+
+// predicatePlanner derives the relationship predicates matching the resources on which a
+// subject has a permission. Only permissions made of unions of relations, other
+// permissions and arrows, without caveats, can be expressed as predicates.
+type predicatePlanner struct {
+	ctx     context.Context
+	reader  datastore.Reader
+	subject *core.ObjectAndRelation
+	lookup  lookupFunc
+
+	predicates []*experimental.RelationshipPredicate
+	byKey      map[string]*experimental.RelationshipPredicate
+	subjectIDs map[string]*util.Set[string]
+	visited    *util.Set[string]
+}
+
+// relationshipPredicates returns the predicates matching the resources of the type on
+// which the subject has the permission, or an error wrapping errNotExpressibleAsPredicates.
+func relationshipPredicates(ctx context.Context, reader datastore.Reader, resourceType, permission string, subject *core.ObjectAndRelation, lookup lookupFunc) ([]*experimental.RelationshipPredicate, error) {
+	planner := &predicatePlanner{
+		ctx:        ctx,
+		reader:     reader,
+		subject:    subject,
+		lookup:     lookup,
+		byKey:      make(map[string]*experimental.RelationshipPredicate),
+		subjectIDs: make(map[string]*util.Set[string]),
+		visited:    util.NewSet[string](),
+	}
+	if err := planner.addRelation(resourceType, permission); err != nil {
+		return nil, err
+	}
+	return planner.predicates, nil
+}
+
+func (pp *predicatePlanner) addRelation(resourceType, relationName string) error {
+	if !pp.visited.Add(relationKey(resourceType, relationName)) {
+		return nil
+	}
+
+	_, relation, err := namespace.ReadNamespaceAndRelation(pp.ctx, resourceType, relationName, pp.reader)
+	if err != nil {
+		return err
+	}
+
+	if relation.UsersetRewrite == nil {
+		return pp.addDirect(resourceType, relation)
+	}
+	return pp.addRewrite(resourceType, relation, relation.UsersetRewrite)
+}
+
+func (pp *predicatePlanner) addRewrite(resourceType string, relation *core.Relation, rewrite *core.UsersetRewrite) error {
+	union := rewrite.GetUnion()
+	if union == nil {
+		return fmt.Errorf("%w: `%s` has an intersection or exclusion", errNotExpressibleAsPredicates, relationKey(resourceType, relation.Name))
+	}
+
+	for _, child := range union.Child {
+		var err error
+		switch typed := child.ChildType.(type) {
+		case *core.SetOperation_Child_XThis:
+			err = pp.addDirect(resourceType, relation)
+		case *core.SetOperation_Child_ComputedUserset:
+			err = pp.addRelation(resourceType, typed.ComputedUserset.Relation)
+		case *core.SetOperation_Child_TupleToUserset:
+			err = pp.addArrow(resourceType, typed.TupleToUserset)
+		case *core.SetOperation_Child_UsersetRewrite:
+			err = pp.addRewrite(resourceType, relation, typed.UsersetRewrite)
+		case *core.SetOperation_Child_XNil:
+		default:
+			err = fmt.Errorf("unknown set operation child `%T`", typed)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addDirect adds the predicates matching the resources with a relationship of the
+// relation to the subject, or to a subject set containing it.
+func (pp *predicatePlanner) addDirect(resourceType string, relation *core.Relation) error {
+	for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+		if allowed.RequiredCaveat != nil {
+			return fmt.Errorf("%w: `%s` allows caveated relationships", errNotExpressibleAsPredicates, relationKey(resourceType, relation.Name))
+		}
+
+		if allowed.GetPublicWildcard() != nil {
+			if allowed.Namespace == pp.subject.Namespace && pp.subject.Relation == tuple.Ellipsis {
+				pp.addPredicate(relation.Name, allowed.Namespace, tuple.Ellipsis, tuple.PublicWildcard)
+			}
+			continue
+		}
+
+		subjectRelation := allowed.GetRelation()
+		if allowed.Namespace == pp.subject.Namespace && subjectRelation == pp.subject.Relation {
+			pp.addPredicate(relation.Name, allowed.Namespace, subjectRelation, pp.subject.ObjectId)
+		}
+
+		if subjectRelation != tuple.Ellipsis {
+			subjectIDs, err := pp.lookupIDs(allowed.Namespace, subjectRelation)
+			if err != nil {
+				return err
+			}
+			pp.addPredicate(relation.Name, allowed.Namespace, subjectRelation, subjectIDs...)
+		}
+	}
+	return nil
+}
+
+// addArrow adds the predicates matching the resources with a relationship of the
+// tupleset relation to an object on which the subject has the computed relation.
+func (pp *predicatePlanner) addArrow(resourceType string, ttu *core.TupleToUserset) error {
+	_, tupleset, err := namespace.ReadNamespaceAndRelation(pp.ctx, resourceType, ttu.Tupleset.Relation, pp.reader)
+	if err != nil {
+		return err
+	}
+
+	for _, allowed := range tupleset.GetTypeInformation().GetAllowedDirectRelations() {
+		if allowed.RequiredCaveat != nil || allowed.GetPublicWildcard() != nil {
+			return fmt.Errorf("%w: `%s` allows caveated or wildcard relationships", errNotExpressibleAsPredicates, relationKey(resourceType, tupleset.Name))
+		}
+
+		// Arrows to types without the computed relation are ignored, as by Check.
+		err := namespace.CheckNamespaceAndRelation(pp.ctx, allowed.Namespace, ttu.ComputedUserset.Relation, false, pp.reader)
+		if errors.As(err, &namespace.ErrRelationNotFound{}) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		objectIDs, err := pp.lookupIDs(allowed.Namespace, ttu.ComputedUserset.Relation)
+		if err != nil {
+			return err
+		}
+		pp.addPredicate(tupleset.Name, allowed.Namespace, allowed.GetRelation(), objectIDs...)
+	}
+	return nil
+}
+
+func (pp *predicatePlanner) lookupIDs(resourceType, permission string) ([]string, error) {
+	resources, err := pp.lookup(resourceType, permission)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceIDs := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		if resource.Permissionship != dispatch.ResolvedResource_HAS_PERMISSION {
+			return nil, fmt.Errorf("%w: `%s` is conditional on caveats", errNotExpressibleAsPredicates, relationKey(resourceType, permission))
+		}
+		resourceIDs = append(resourceIDs, resource.ResourceId)
+	}
+	sort.Strings(resourceIDs)
+	return resourceIDs, nil
+}
+
+// addPredicate adds the subject IDs to the predicate of the relation and subject type and
+// relation, creating it if there are any.
+func (pp *predicatePlanner) addPredicate(relation, subjectType, subjectRelation string, subjectIDs ...string) {
+	if len(subjectIDs) == 0 {
+		return
+	}
+
+	key := relation + "@" + relationKey(subjectType, subjectRelation)
+	predicate, ok := pp.byKey[key]
+	if !ok {
+		predicate = &experimental.RelationshipPredicate{
+			Relation:          relation,
+			SubjectObjectType: subjectType,
+		}
+		if subjectRelation != tuple.Ellipsis {
+			predicate.OptionalSubjectRelation = subjectRelation
+		}
+		pp.byKey[key] = predicate
+		pp.subjectIDs[key] = util.NewSet[string]()
+		pp.predicates = append(pp.predicates, predicate)
+	}
+
+	for _, subjectID := range subjectIDs {
+		if pp.subjectIDs[key].Add(subjectID) {
+			predicate.SubjectObjectIds = append(predicate.SubjectObjectIds, subjectID)
+		}
+	}
+}
+
+func relationKey(objectType, relation string) string {
+	return objectType + "#" + relation
+}
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestRelationshipPredicates(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, revision := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		caveat somecaveat(somevalue int) {
+			somevalue == 42
+		}
+
+		definition user {}
+
+		definition team {
+			relation member: user | team#member
+		}
+
+		definition folder {
+			relation viewer: user
+			relation parent: folder
+			permission view = viewer + parent->view
+		}
+
+		definition document {
+			relation owner: user
+			relation viewer: user | user:* | team#member
+			relation banned: user
+			relation parent: folder | team
+			relation caveated: user with somecaveat
+			permission edit = owner
+			permission view = viewer + edit + parent->view
+			permission view_unbanned = view - banned
+			permission caveated_view = caveated + view
+		}
+	`, nil, require)
+	reader := ds.SnapshotReader(revision)
+
+	found := map[string][]*dispatch.ResolvedResource{
+		"folder#view": {
+			{ResourceId: "strategy", Permissionship: dispatch.ResolvedResource_HAS_PERMISSION},
+			{ResourceId: "company", Permissionship: dispatch.ResolvedResource_HAS_PERMISSION},
+		},
+		"team#member": {
+			{ResourceId: "eng", Permissionship: dispatch.ResolvedResource_HAS_PERMISSION},
+		},
+	}
+	lookup := func(resourceType, permission string) ([]*dispatch.ResolvedResource, error) {
+		return found[resourceType+"#"+permission], nil
+	}
+
+	subject := tuple.ParseSubjectONR("user:tom")
+	predicates, err := relationshipPredicates(context.Background(), reader, "document", "view", subject, lookup)
+	require.NoError(err)
+	require.Equal([]*experimental.RelationshipPredicate{
+		{Relation: "viewer", SubjectObjectType: "user", SubjectObjectIds: []string{"tom", "*"}},
+		{Relation: "viewer", SubjectObjectType: "team", OptionalSubjectRelation: "member", SubjectObjectIds: []string{"eng"}},
+		{Relation: "owner", SubjectObjectType: "user", SubjectObjectIds: []string{"tom"}},
+		{Relation: "parent", SubjectObjectType: "folder", SubjectObjectIds: []string{"company", "strategy"}},
+	}, predicates)
+
+	for _, permission := range []string{"view_unbanned", "caveated_view"} {
+		_, err := relationshipPredicates(context.Background(), reader, "document", permission, subject, lookup)
+		require.ErrorIs(err, errNotExpressibleAsPredicates, permission)
+	}
+
+	found["folder#view"] = []*dispatch.ResolvedResource{
+		{ResourceId: "strategy", Permissionship: dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION},
+	}
+	_, err = relationshipPredicates(context.Background(), reader, "document", "view", subject, lookup)
+	require.ErrorIs(err, errNotExpressibleAsPredicates)
+
+	_, err = relationshipPredicates(context.Background(), reader, "document", "view", &core.ObjectAndRelation{
+		Namespace: "user",
+		ObjectId:  "tom",
+		Relation:  tuple.Ellipsis,
+	}, func(string, string) ([]*dispatch.ResolvedResource, error) {
+		return nil, context.Canceled
+	})
+	require.ErrorIs(err, context.Canceled)
+}
@@ -0,0 +1,204 @@
+package v1
+
+import (
+	"context"
+	"sort"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/caveats"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func hasPermission(resource *dispatch.ResolvedResource) bool {
+	return resource.Permissionship == dispatch.ResolvedResource_HAS_PERMISSION
+}
+
+// mergeResolvedResources returns a resource found twice, which either has the permission
+// or is conditional on the context missing from either.
+func mergeResolvedResources(l, r *dispatch.ResolvedResource, hasPermission bool) *dispatch.ResolvedResource {
+	if hasPermission {
+		return &dispatch.ResolvedResource{
+			ResourceId:     l.ResourceId,
+			Permissionship: dispatch.ResolvedResource_HAS_PERMISSION,
+		}
+	}
+
+	return &dispatch.ResolvedResource{
+		ResourceId:             l.ResourceId,
+		Permissionship:         dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION,
+		MissingRequiredContext: mergeMissingContext(l.MissingRequiredContext, r.MissingRequiredContext),
+	}
+}
+
+func mergeMissingContext(l, r []string) []string {
+	missing := util.NewSet(l...)
+	missing.Extend(r)
+	merged := missing.AsSlice()
+	sort.Strings(merged)
+	return merged
+}
+
+// lookupOrdering is the order in which the results of a lookup are sent.
+type lookupOrdering struct {
+	// sorted sends results sorted by ID, once the lookup has completed.
+	sorted bool
+
+	// partitioned sends the results having the permission before the conditional results.
+	partitioned bool
+}
+
+// lookupOrderingFromContext returns the ordering configured on the server, partitioned if
+// the request asks for it.
+func lookupOrderingFromContext(ctx context.Context, sorted bool) lookupOrdering {
+	ordering := lookupOrdering{sorted: sorted}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		_, ordering.partitioned = md[string(caveats.RequestPartitionedLookup)]
+	}
+	return ordering
+}
+
+// deduplicateResolvedResources returns each resource once, having the permission if it was
+// found with it at least once, in the order given.
+func deduplicateResolvedResources(resources []*dispatch.ResolvedResource, ordering lookupOrdering) []*dispatch.ResolvedResource {
+	deduplicated := make([]*dispatch.ResolvedResource, 0, len(resources))
+	indexes := make(map[string]int, len(resources))
+	for _, resource := range resources {
+		index, ok := indexes[resource.ResourceId]
+		if !ok {
+			indexes[resource.ResourceId] = len(deduplicated)
+			deduplicated = append(deduplicated, resource)
+			continue
+		}
+
+		existing := deduplicated[index]
+		deduplicated[index] = mergeResolvedResources(existing, resource, hasPermission(existing) || hasPermission(resource))
+	}
+
+	if ordering.sorted {
+		sort.Slice(deduplicated, func(i, j int) bool {
+			return deduplicated[i].ResourceId < deduplicated[j].ResourceId
+		})
+	}
+	if ordering.partitioned {
+		sort.SliceStable(deduplicated, func(i, j int) bool {
+			return hasPermission(deduplicated[i]) && !hasPermission(deduplicated[j])
+		})
+	}
+	return deduplicated
+}
+
+// lookupSubjectsSender sends each subject found by LookupSubjects once. Subjects with the
+// permission are sent as soon as they are found, unless sorting; conditional subjects and
+// wildcards are held until the lookup completes, as a later result for the same subject
+// may change them. If partitioned, the subjects held are sent with those having the
+// permission first, so that the conditional subjects are sent last.
+type lookupSubjectsSender struct {
+	send     func(*v1.LookupSubjectsResponse) error
+	ordering lookupOrdering
+
+	sent    *util.Set[string]
+	pending map[string]*v1.LookupSubjectsResponse
+}
+
+func newLookupSubjectsSender(send func(*v1.LookupSubjectsResponse) error, ordering lookupOrdering) *lookupSubjectsSender {
+	return &lookupSubjectsSender{
+		send:     send,
+		ordering: ordering,
+		sent:     util.NewSet[string](),
+		pending:  make(map[string]*v1.LookupSubjectsResponse),
+	}
+}
+
+func (lss *lookupSubjectsSender) add(resp *v1.LookupSubjectsResponse) error {
+	subjectID := resp.Subject.SubjectObjectId
+	if lss.sent.Has(subjectID) {
+		return nil
+	}
+
+	if existing, ok := lss.pending[subjectID]; ok {
+		resp = mergeLookupSubjectsResponses(existing, resp)
+	}
+
+	if lss.ordering.sorted || subjectID == tuple.PublicWildcard || resp.Subject.Permissionship != v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION {
+		lss.pending[subjectID] = resp
+		return nil
+	}
+
+	delete(lss.pending, subjectID)
+	lss.sent.Add(subjectID)
+	return lss.send(resp)
+}
+
+// flush sends the subjects held until the lookup completed, sorted by ID.
+func (lss *lookupSubjectsSender) flush() error {
+	subjectIDs := make([]string, 0, len(lss.pending))
+	for subjectID := range lss.pending {
+		subjectIDs = append(subjectIDs, subjectID)
+	}
+	sort.Strings(subjectIDs)
+	if lss.ordering.partitioned {
+		sort.SliceStable(subjectIDs, func(i, j int) bool {
+			return lss.pending[subjectIDs[i]].Subject.Permissionship == v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION &&
+				lss.pending[subjectIDs[j]].Subject.Permissionship != v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION
+		})
+	}
+
+	for _, subjectID := range subjectIDs {
+		if err := lss.send(lss.pending[subjectID]); err != nil {
+			return err
+		}
+		delete(lss.pending, subjectID)
+		lss.sent.Add(subjectID)
+	}
+	return nil
+}
+
+// mergeLookupSubjectsResponses returns a subject found twice, which has the permission if
+// either has it, and excludes only the subjects excluded by both.
+func mergeLookupSubjectsResponses(existing, added *v1.LookupSubjectsResponse) *v1.LookupSubjectsResponse {
+	subject := existing.Subject
+	switch {
+	case subject.Permissionship == v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION:
+	case added.Subject.Permissionship == v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION:
+		subject = added.Subject
+	default:
+		subject = &v1.ResolvedSubject{
+			SubjectObjectId: subject.SubjectObjectId,
+			Permissionship:  v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION,
+			PartialCaveatInfo: &v1.PartialCaveatInfo{
+				MissingRequiredContext: mergeMissingContext(
+					subject.PartialCaveatInfo.GetMissingRequiredContext(),
+					added.Subject.PartialCaveatInfo.GetMissingRequiredContext(),
+				),
+			},
+		}
+	}
+
+	addedExcluded := util.NewSet[string]()
+	for _, excluded := range added.ExcludedSubjects {
+		addedExcluded.Add(excluded.SubjectObjectId)
+	}
+
+	excludedSubjects := make([]*v1.ResolvedSubject, 0, len(existing.ExcludedSubjects))
+	excludedSubjectIDs := make([]string, 0, len(existing.ExcludedSubjects))
+	for _, excluded := range existing.ExcludedSubjects {
+		if addedExcluded.Has(excluded.SubjectObjectId) {
+			excludedSubjects = append(excludedSubjects, excluded)
+			excludedSubjectIDs = append(excludedSubjectIDs, excluded.SubjectObjectId)
+		}
+	}
+
+	return &v1.LookupSubjectsResponse{
+		Subject:            subject,
+		ExcludedSubjects:   excludedSubjects,
+		LookedUpAt:         existing.LookedUpAt,
+		SubjectObjectId:    subject.SubjectObjectId,   // Deprecated
+		ExcludedSubjectIds: excludedSubjectIDs,        // Deprecated
+		Permissionship:     subject.Permissionship,    // Deprecated
+		PartialCaveatInfo:  subject.PartialCaveatInfo, // Deprecated
+	}
+}
@@ -0,0 +1,153 @@
+package v1
+
+import (
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+func TestDeduplicateResolvedResources(t *testing.T) {
+	resources := []*dispatch.ResolvedResource{
+		{ResourceId: "second", Permissionship: dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION, MissingRequiredContext: []string{"ip"}},
+		{ResourceId: "first", Permissionship: dispatch.ResolvedResource_HAS_PERMISSION},
+		{ResourceId: "third", Permissionship: dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION, MissingRequiredContext: []string{"time"}},
+		{ResourceId: "second", Permissionship: dispatch.ResolvedResource_HAS_PERMISSION},
+		{ResourceId: "third", Permissionship: dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION, MissingRequiredContext: []string{"ip"}},
+		{ResourceId: "first", Permissionship: dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION, MissingRequiredContext: []string{"ip"}},
+	}
+
+	expected := []*dispatch.ResolvedResource{
+		{ResourceId: "second", Permissionship: dispatch.ResolvedResource_HAS_PERMISSION},
+		{ResourceId: "first", Permissionship: dispatch.ResolvedResource_HAS_PERMISSION},
+		{ResourceId: "third", Permissionship: dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION, MissingRequiredContext: []string{"ip", "time"}},
+	}
+	require.Equal(t, expected, deduplicateResolvedResources(resources, lookupOrdering{}))
+
+	expected[0], expected[1] = expected[1], expected[0]
+	require.Equal(t, expected, deduplicateResolvedResources(resources, lookupOrdering{sorted: true}))
+
+	resources = append(resources, &dispatch.ResolvedResource{ResourceId: "fourth", Permissionship: dispatch.ResolvedResource_HAS_PERMISSION})
+	require.Equal(t, []string{"first", "fourth", "second", "third"}, resourceIDs(deduplicateResolvedResources(resources, lookupOrdering{sorted: true})))
+	require.Equal(t, []string{"second", "first", "fourth", "third"}, resourceIDs(deduplicateResolvedResources(resources, lookupOrdering{partitioned: true})))
+	require.Equal(t, []string{"first", "fourth", "second", "third"}, resourceIDs(deduplicateResolvedResources(resources, lookupOrdering{sorted: true, partitioned: true})))
+
+	resources = append(resources, &dispatch.ResolvedResource{ResourceId: "alpha", Permissionship: dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION})
+	require.Equal(t, []string{"first", "fourth", "second", "alpha", "third"}, resourceIDs(deduplicateResolvedResources(resources, lookupOrdering{sorted: true, partitioned: true})))
+}
+
+func resourceIDs(resources []*dispatch.ResolvedResource) []string {
+	ids := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		ids = append(ids, resource.ResourceId)
+	}
+	return ids
+}
+
+func lookupSubjectsResponse(subjectID string, permissionship v1.LookupPermissionship, missing []string, excludedIDs ...string) *v1.LookupSubjectsResponse {
+	subject := &v1.ResolvedSubject{SubjectObjectId: subjectID, Permissionship: permissionship}
+	if missing != nil {
+		subject.PartialCaveatInfo = &v1.PartialCaveatInfo{MissingRequiredContext: missing}
+	}
+
+	excluded := make([]*v1.ResolvedSubject, 0, len(excludedIDs))
+	for _, excludedID := range excludedIDs {
+		excluded = append(excluded, &v1.ResolvedSubject{
+			SubjectObjectId: excludedID,
+			Permissionship:  v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION,
+		})
+	}
+
+	return &v1.LookupSubjectsResponse{
+		Subject:            subject,
+		ExcludedSubjects:   excluded,
+		SubjectObjectId:    subjectID,
+		ExcludedSubjectIds: append([]string{}, excludedIDs...),
+		Permissionship:     permissionship,
+		PartialCaveatInfo:  subject.PartialCaveatInfo,
+	}
+}
+
+func TestLookupSubjectsSender(t *testing.T) {
+	has := v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION
+	conditional := v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION
+
+	found := []*v1.LookupSubjectsResponse{
+		lookupSubjectsResponse("tom", has, nil),
+		lookupSubjectsResponse("sarah", conditional, []string{"ip"}),
+		lookupSubjectsResponse("*", has, nil, "fred", "jill"),
+		lookupSubjectsResponse("tom", conditional, []string{"ip"}),
+		lookupSubjectsResponse("fred", conditional, []string{"ip"}),
+		lookupSubjectsResponse("*", has, nil, "jill", "tom"),
+		lookupSubjectsResponse("sarah", conditional, []string{"time"}),
+		lookupSubjectsResponse("fred", has, nil),
+		lookupSubjectsResponse("fred", has, nil),
+	}
+
+	testCases := []struct {
+		name                string
+		ordering            lookupOrdering
+		expectedBeforeFlush []string
+		expected            []*v1.LookupSubjectsResponse
+	}{
+		{
+			"unsorted",
+			lookupOrdering{},
+			[]string{"tom", "fred"},
+			[]*v1.LookupSubjectsResponse{
+				lookupSubjectsResponse("tom", has, nil),
+				lookupSubjectsResponse("fred", has, nil),
+				lookupSubjectsResponse("*", has, nil, "jill"),
+				lookupSubjectsResponse("sarah", conditional, []string{"ip", "time"}),
+			},
+		},
+		{
+			"sorted",
+			lookupOrdering{sorted: true},
+			nil,
+			[]*v1.LookupSubjectsResponse{
+				lookupSubjectsResponse("*", has, nil, "jill"),
+				lookupSubjectsResponse("fred", has, nil),
+				lookupSubjectsResponse("sarah", conditional, []string{"ip", "time"}),
+				lookupSubjectsResponse("tom", has, nil),
+			},
+		},
+		{
+			"sorted and partitioned",
+			lookupOrdering{sorted: true, partitioned: true},
+			nil,
+			[]*v1.LookupSubjectsResponse{
+				lookupSubjectsResponse("*", has, nil, "jill"),
+				lookupSubjectsResponse("fred", has, nil),
+				lookupSubjectsResponse("tom", has, nil),
+				lookupSubjectsResponse("sarah", conditional, []string{"ip", "time"}),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var sent []*v1.LookupSubjectsResponse
+			sender := newLookupSubjectsSender(func(resp *v1.LookupSubjectsResponse) error {
+				sent = append(sent, resp)
+				return nil
+			}, tc.ordering)
+
+			for _, resp := range found {
+				require.NoError(t, sender.add(resp))
+			}
+
+			var sentBeforeFlush []string
+			for _, resp := range sent {
+				sentBeforeFlush = append(sentBeforeFlush, resp.Subject.SubjectObjectId)
+			}
+			require.Equal(t, tc.expectedBeforeFlush, sentBeforeFlush)
+
+			require.NoError(t, sender.flush())
+			require.Equal(t, tc.expected, sent)
+		})
+	}
+}
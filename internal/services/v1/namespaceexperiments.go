@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	experimentsmw "github.com/authzed/spicedb/internal/middleware/experiments"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+func (es *experimentalServer) SetNamespaceExperiment(ctx context.Context, req *experimental.SetNamespaceExperimentRequest) (*experimental.SetNamespaceExperimentResponse, error) {
+	ds := datastoremw.MustFromContext(ctx)
+	store, err := common.NamespaceExperimentStoreOf(ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	namespace, experiment := req.Experiment.Namespace, req.Experiment.Experiment
+
+	// Experiments can only be enabled for namespaces in the schema, but remain possible
+	// to disable once their namespace has been removed.
+	if req.Enabled {
+		headRevision, err := ds.HeadRevision(ctx)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+		if _, _, err := ds.SnapshotReader(headRevision).ReadNamespace(ctx, namespace); err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+	}
+
+	if err := store.SetNamespaceExperiment(ctx, namespace, experiment, req.Enabled); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	log.Ctx(ctx).Info().
+		Str("namespace", namespace).
+		Str("experiment", experiment).
+		Bool("enabled", req.Enabled).
+		Msg("namespace experiment changed")
+
+	// Other servers pick up the change when they next reload the experiments.
+	if registry := experimentsmw.FromContext(ctx); registry != nil {
+		if err := registry.Refresh(ctx); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("unable to refresh namespace experiments")
+		}
+	}
+
+	return &experimental.SetNamespaceExperimentResponse{}, nil
+}
+
+func (es *experimentalServer) ListNamespaceExperiments(ctx context.Context, _ *experimental.ListNamespaceExperimentsRequest) (*experimental.ListNamespaceExperimentsResponse, error) {
+	store, err := common.NamespaceExperimentStoreOf(datastoremw.MustFromContext(ctx))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	experiments, err := store.ListNamespaceExperiments(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	resp := &experimental.ListNamespaceExperimentsResponse{
+		Experiments: make([]*experimental.NamespaceExperiment, 0, len(experiments)),
+	}
+	for _, experiment := range experiments {
+		resp.Experiments = append(resp.Experiments, &experimental.NamespaceExperiment{
+			Namespace:  experiment.Namespace,
+			Experiment: experiment.Experiment,
+		})
+	}
+	return resp, nil
+}
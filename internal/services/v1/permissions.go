@@ -2,7 +2,10 @@ package v1
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/authzed/spicedb/pkg/datastore"
 
@@ -10,7 +13,6 @@ import (
 	"github.com/authzed/authzed-go/pkg/responsemeta"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/jzelinskie/stringz"
-	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -19,12 +21,15 @@ import (
 	"google.golang.org/protobuf/types/known/structpb"
 
 	cexpr "github.com/authzed/spicedb/internal/caveats"
+	"github.com/authzed/spicedb/internal/decisionlog"
 	dispatchpkg "github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/graph"
 	"github.com/authzed/spicedb/internal/graph/computed"
+	log "github.com/authzed/spicedb/internal/logging"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
 	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/caveats"
 	"github.com/authzed/spicedb/pkg/middleware/consistency"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
@@ -42,56 +47,18 @@ func (ps *permissionServer) CheckPermission(ctx context.Context, req *v1.CheckPe
 		return nil, rewriteError(ctx, err)
 	}
 
-	// Perform our preflight checks in parallel
-	errG, checksCtx := errgroup.WithContext(ctx)
-	errG.Go(func() error {
-		return namespace.CheckNamespaceAndRelation(
-			checksCtx,
-			req.Resource.ObjectType,
-			req.Permission,
-			false,
-			ds,
-		)
-	})
-	errG.Go(func() error {
-		return namespace.CheckNamespaceAndRelation(
-			checksCtx,
-			req.Subject.Object.ObjectType,
-			normalizeSubjectRelation(req.Subject),
-			true,
-			ds,
-		)
-	})
-	if err := errG.Wait(); err != nil {
-		return nil, rewriteError(ctx, err)
-	}
-
 	isDebuggingEnabled := false
+	isResidualRequested := false
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		_, isDebuggingEnabled = md[string(requestmeta.RequestDebugInformation)]
+		_, isResidualRequested = md[string(caveats.RequestResidual)]
 	}
 
-	cr, metadata, err := computed.ComputeCheck(ctx, ps.dispatch,
-		computed.CheckParameters{
-			ResourceType: &core.RelationReference{
-				Namespace: req.Resource.ObjectType,
-				Relation:  req.Permission,
-			},
-			Subject: &core.ObjectAndRelation{
-				Namespace: req.Subject.Object.ObjectType,
-				ObjectId:  req.Subject.Object.ObjectId,
-				Relation:  normalizeSubjectRelation(req.Subject),
-			},
-			CaveatContext:      caveatContext,
-			AtRevision:         atRevision,
-			MaximumDepth:       ps.config.MaximumAPIDepth,
-			IsDebuggingEnabled: isDebuggingEnabled,
-		},
-		req.Resource.ObjectId,
-	)
+	cr, metadata, err := computeCheckPermission(ctx, ps.dispatch, ds, atRevision, req, caveatContext, ps.config.MaximumAPIDepth, isDebuggingEnabled)
 	usagemetrics.SetInContext(ctx, metadata)
+	ps.logDecision(req, caveatContext, atRevision, cr, err)
 
-	if isDebuggingEnabled && metadata.DebugInfo != nil {
+	if isDebuggingEnabled && metadata != nil && metadata.DebugInfo != nil {
 		// Convert the dispatch debug information into API debug information and marshal into
 		// the footer.
 		converted, cerr := dispatchpkg.ConvertDispatchDebugInformation(ctx, metadata, ds)
@@ -116,6 +83,86 @@ func (ps *permissionServer) CheckPermission(ctx context.Context, req *v1.CheckPe
 		return nil, rewriteError(ctx, err)
 	}
 
+	ps.config.CanarySchema.sample(ctx, datastoremw.MustFromContext(ctx), atRevision, req, caveatContext, cr)
+
+	if isResidualRequested && cr.Membership == dispatch.ResourceCheckResult_CAVEATED_MEMBER {
+		// The result stands without its residual, which the caller can do without by
+		// checking again with the full context.
+		if err := setResidualTrailer(ctx, cr.Expression, caveatContext, ds); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("unable to return the residual of a conditional check")
+		}
+	}
+
+	return checkPermissionResponse(cr, checkedAt), nil
+}
+
+// logDecision records the decision of a check, or its failure, in the decision log.
+func (ps *permissionServer) logDecision(req *v1.CheckPermissionRequest, caveatContext map[string]any, atRevision datastore.Revision, cr *dispatch.ResourceCheckResult, err error) {
+	if ps.config.DecisionLog == nil {
+		return
+	}
+
+	decision := decisionlog.Decision{
+		CheckedAt:       time.Now(),
+		ResourceType:    req.Resource.ObjectType,
+		ResourceID:      req.Resource.ObjectId,
+		Permission:      req.Permission,
+		SubjectType:     req.Subject.Object.ObjectType,
+		SubjectID:       req.Subject.Object.ObjectId,
+		SubjectRelation: req.Subject.OptionalRelation,
+		CaveatContext:   caveatContext,
+		Revision:        atRevision,
+	}
+	if err != nil {
+		decision.Error = err.Error()
+	} else {
+		decision.Permissionship = checkDecision(cr)
+	}
+	ps.config.DecisionLog.Log(decision)
+}
+
+// computeCheckPermission checks the namespaces and relations of a check, and computes it
+// at the revision.
+func computeCheckPermission(
+	ctx context.Context,
+	dispatcher dispatchpkg.Dispatcher,
+	ds datastore.Reader,
+	atRevision datastore.Revision,
+	req *v1.CheckPermissionRequest,
+	caveatContext map[string]any,
+	maximumAPIDepth uint32,
+	isDebuggingEnabled bool,
+) (*dispatch.ResourceCheckResult, *dispatch.ResponseMeta, error) {
+	// Perform our preflight checks, which read both namespaces together.
+	if err := namespace.CheckNamespacesAndRelations(ctx, ds,
+		namespace.RelationToCheck{Namespace: req.Resource.ObjectType, Relation: req.Permission, AllowEllipsis: false},
+		namespace.RelationToCheck{Namespace: req.Subject.Object.ObjectType, Relation: normalizeSubjectRelation(req.Subject), AllowEllipsis: true},
+	); err != nil {
+		return nil, nil, err
+	}
+
+	return computed.ComputeCheck(ctx, dispatcher,
+		computed.CheckParameters{
+			ResourceType: &core.RelationReference{
+				Namespace: req.Resource.ObjectType,
+				Relation:  req.Permission,
+			},
+			Subject: &core.ObjectAndRelation{
+				Namespace: req.Subject.Object.ObjectType,
+				ObjectId:  req.Subject.Object.ObjectId,
+				Relation:  normalizeSubjectRelation(req.Subject),
+			},
+			CaveatContext:      caveatContext,
+			AtRevision:         atRevision,
+			MaximumDepth:       maximumAPIDepth,
+			IsDebuggingEnabled: isDebuggingEnabled,
+		},
+		req.Resource.ObjectId,
+	)
+}
+
+// checkPermissionResponse converts the result of a check into its response.
+func checkPermissionResponse(cr *dispatch.ResourceCheckResult, checkedAt *v1.ZedToken) *v1.CheckPermissionResponse {
 	var partialCaveat *v1.PartialCaveatInfo
 	permissionship := v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION
 	if cr.Membership == dispatch.ResourceCheckResult_MEMBER {
@@ -131,7 +178,25 @@ func (ps *permissionServer) CheckPermission(ctx context.Context, req *v1.CheckPe
 		CheckedAt:         checkedAt,
 		Permissionship:    permissionship,
 		PartialCaveatInfo: partialCaveat,
-	}, nil
+	}
+}
+
+// setResidualTrailer sets the residual of the caveat expression of a conditional result
+// in the response trailer.
+func setResidualTrailer(ctx context.Context, expr *dispatch.CaveatExpression, caveatContext map[string]any, reader datastore.CaveatReader) error {
+	residual, err := cexpr.ComputeResidual(ctx, expr, caveatContext, reader)
+	if err != nil || residual == nil {
+		return err
+	}
+
+	marshaled, err := json.Marshal(residual)
+	if err != nil {
+		return err
+	}
+
+	return responsemeta.SetResponseTrailerMetadata(ctx, map[responsemeta.ResponseMetadataTrailerKey]string{
+		caveats.ResidualTrailer: string(marshaled),
+	})
 }
 
 func (ps *permissionServer) ExpandPermissionTree(ctx context.Context, req *v1.ExpandPermissionTreeRequest) (*v1.ExpandPermissionTreeResponse, error) {
@@ -316,32 +381,16 @@ func (ps *permissionServer) LookupResources(req *v1.LookupResourcesRequest, resp
 	atRevision, revisionReadAt := consistency.MustRevisionFromContext(ctx)
 	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
 
-	// Perform our preflight checks in parallel
-	errG, checksCtx := errgroup.WithContext(ctx)
-	errG.Go(func() error {
-		return namespace.CheckNamespaceAndRelation(
-			checksCtx,
-			req.Subject.Object.ObjectType,
-			normalizeSubjectRelation(req.Subject),
-			true,
-			ds,
-		)
-	})
-	errG.Go(func() error {
-		return namespace.CheckNamespaceAndRelation(
-			ctx,
-			req.ResourceObjectType,
-			req.Permission,
-			false,
-			ds,
-		)
-	})
-	if err := errG.Wait(); err != nil {
+	// Perform our preflight checks, which read both namespaces together.
+	if err := namespace.CheckNamespacesAndRelations(ctx, ds,
+		namespace.RelationToCheck{Namespace: req.Subject.Object.ObjectType, Relation: normalizeSubjectRelation(req.Subject), AllowEllipsis: true},
+		namespace.RelationToCheck{Namespace: req.ResourceObjectType, Relation: req.Permission, AllowEllipsis: false},
+	); err != nil {
 		return rewriteError(ctx, err)
 	}
 
 	// TODO(jschorr): Change the internal dispatched lookup to also be streamed.
-	lookupResp, err := ps.dispatch.DispatchLookup(ctx, &dispatch.DispatchLookupRequest{
+	lookupResp, err := ps.dispatch.DispatchLookup(ps.withLookupMemoryBudget(ctx), &dispatch.DispatchLookupRequest{
 		Metadata: &dispatch.ResolverMeta{
 			AtRevision:     atRevision.String(),
 			DepthRemaining: ps.config.MaximumAPIDepth,
@@ -359,11 +408,14 @@ func (ps *permissionServer) LookupResources(req *v1.LookupResourcesRequest, resp
 		Limit:   ^uint32(0), // Set no limit for now
 	})
 	usagemetrics.SetInContext(ctx, lookupResp.Metadata)
-	if err != nil {
+
+	// If the memory ceiling was reached, the resources found until then are sent before
+	// the error, so that the caller receives a partial result.
+	if err != nil && !errors.Is(err, dispatchpkg.ErrMemoryCeilingExceeded) {
 		return rewriteError(ctx, err)
 	}
 
-	for _, found := range lookupResp.ResolvedResources {
+	for _, found := range deduplicateResolvedResources(lookupResp.ResolvedResources, lookupOrderingFromContext(ctx, ps.config.SortLookupResults)) {
 		var partial *v1.PartialCaveatInfo
 		permissionship := v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION
 		if found.Permissionship == dispatch.ResolvedResource_CONDITIONALLY_HAS_PERMISSION {
@@ -383,9 +435,22 @@ func (ps *permissionServer) LookupResources(req *v1.LookupResourcesRequest, resp
 			return err
 		}
 	}
+
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
 	return nil
 }
 
+// withLookupMemoryBudget returns a context carrying a new memory budget for a lookup, if a
+// ceiling is configured.
+func (ps *permissionServer) withLookupMemoryBudget(ctx context.Context) context.Context {
+	if ps.config.MaxLookupMemoryBytes == 0 {
+		return ctx
+	}
+	return dispatchpkg.ContextWithMemoryBudget(ctx, dispatchpkg.NewMemoryBudget(ps.config.MaxLookupMemoryBytes, ps.config.LookupSpillDirectory))
+}
+
 func (ps *permissionServer) LookupSubjects(req *v1.LookupSubjectsRequest, resp v1.PermissionsService_LookupSubjectsServer) error {
 	ctx := resp.Context()
 	atRevision, revisionReadAt := consistency.MustRevisionFromContext(ctx)
@@ -397,27 +462,11 @@ func (ps *permissionServer) LookupSubjects(req *v1.LookupSubjectsRequest, resp v
 		return rewriteError(ctx, err)
 	}
 
-	// Perform our preflight checks in parallel
-	errG, checksCtx := errgroup.WithContext(ctx)
-	errG.Go(func() error {
-		return namespace.CheckNamespaceAndRelation(
-			checksCtx,
-			req.Resource.ObjectType,
-			req.Permission,
-			false,
-			ds,
-		)
-	})
-	errG.Go(func() error {
-		return namespace.CheckNamespaceAndRelation(
-			ctx,
-			req.SubjectObjectType,
-			stringz.DefaultEmpty(req.OptionalSubjectRelation, tuple.Ellipsis),
-			true,
-			ds,
-		)
-	})
-	if err := errG.Wait(); err != nil {
+	// Perform our preflight checks, which read both namespaces together.
+	if err := namespace.CheckNamespacesAndRelations(ctx, ds,
+		namespace.RelationToCheck{Namespace: req.Resource.ObjectType, Relation: req.Permission, AllowEllipsis: false},
+		namespace.RelationToCheck{Namespace: req.SubjectObjectType, Relation: stringz.DefaultEmpty(req.OptionalSubjectRelation, tuple.Ellipsis), AllowEllipsis: true},
+	); err != nil {
 		return rewriteError(ctx, err)
 	}
 
@@ -429,7 +478,8 @@ func (ps *permissionServer) LookupSubjects(req *v1.LookupSubjectsRequest, resp v
 	}
 	usagemetrics.SetInContext(ctx, respMetadata)
 
-	stream := dispatchpkg.NewHandlingDispatchStream(ctx, func(result *dispatch.DispatchLookupSubjectsResponse) error {
+	sender := newLookupSubjectsSender(resp.Send, lookupOrderingFromContext(ctx, ps.config.SortLookupResults))
+	stream := dispatchpkg.NewHandlingDispatchStream(ps.withLookupMemoryBudget(ctx), func(result *dispatch.DispatchLookupSubjectsResponse) error {
 		foundSubjects, ok := result.FoundSubjectsByResourceId[req.Resource.ObjectId]
 		if !ok {
 			return fmt.Errorf("missing resource ID in returned LS")
@@ -463,7 +513,7 @@ func (ps *permissionServer) LookupSubjects(req *v1.LookupSubjectsRequest, resp v
 				continue
 			}
 
-			err = resp.Send(&v1.LookupSubjectsResponse{
+			err = sender.add(&v1.LookupSubjectsResponse{
 				Subject:            subject,
 				ExcludedSubjects:   excludedSubjects,
 				LookedUpAt:         revisionReadAt,
@@ -502,7 +552,7 @@ func (ps *permissionServer) LookupSubjects(req *v1.LookupSubjectsRequest, resp v
 		return rewriteError(ctx, err)
 	}
 
-	return nil
+	return sender.flush()
 }
 
 func foundSubjectToResolvedSubject(ctx context.Context, foundSubject *dispatch.FoundSubject, caveatContext map[string]any, ds datastore.CaveatReader) (*v1.ResolvedSubject, error) {
@@ -2,6 +2,7 @@ package v1_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -26,9 +27,11 @@ import (
 	"google.golang.org/protobuf/types/known/structpb"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/middleware/querystats"
 	v1svc "github.com/authzed/spicedb/internal/services/v1"
 	tf "github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/caveats"
 	pgraph "github.com/authzed/spicedb/pkg/graph"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
@@ -851,6 +854,26 @@ func TestCheckWithCaveats(t *testing.T) {
 	req.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION, checkResp.Permissionship)
 	req.EqualValues([]string{"secret"}, checkResp.PartialCaveatInfo.MissingRequiredContext)
 
+	// residual of the caveats returned for local evaluation
+	var trailer metadata.MD
+	checkResp, err = client.CheckPermission(requestmeta.AddRequestHeaders(ctx, caveats.RequestResidual), request, grpc.Trailer(&trailer))
+	req.NoError(err)
+	req.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION, checkResp.Permissionship)
+
+	encodedResidual, err := responsemeta.GetResponseTrailerMetadata(trailer, caveats.ResidualTrailer)
+	req.NoError(err)
+
+	var residual caveats.Residual
+	req.NoError(json.Unmarshal([]byte(encodedResidual), &residual))
+	req.Equal([]string{"secret"}, residual.MissingParameters())
+
+	for secret, expected := range map[string]bool{"1234": true, "incorrect_value": false} {
+		result, err := caveats.EvaluateResidual(&residual, map[string]any{"secret": secret})
+		req.NoError(err)
+		req.False(result.IsPartial())
+		req.Equal(expected, result.Value, secret)
+	}
+
 	// context exceeds length limit
 	request.Context, err = structpb.NewStruct(generateMap(64))
 	req.NoError(err)
@@ -1273,3 +1296,126 @@ func randString(length int) string {
 	}
 	return string(b)
 }
+
+func TestPartitionedLookups(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServer(req, testTimedeltas[0], memdb.DisableGC, true,
+		func(ds datastore.Datastore, require *require.Assertions) (datastore.Datastore, datastore.Revision) {
+			return tf.DatastoreFromSchemaAndTestRelationships(ds, `
+				definition user {}
+
+				caveat testcaveat(somecondition int) {
+					somecondition == 42
+				}
+
+				definition document {
+					relation viewer: user | user with testcaveat
+					permission view = viewer
+				}
+			`, []*core.RelationTuple{
+				tuple.WithCaveat(tuple.MustParse("document:first#viewer@user:tom"), "testcaveat"),
+				tuple.MustParse("document:second#viewer@user:tom"),
+				tuple.WithCaveat(tuple.MustParse("document:third#viewer@user:tom"), "testcaveat"),
+				tuple.MustParse("document:fourth#viewer@user:tom"),
+				tuple.WithCaveat(tuple.MustParse("document:first#viewer@user:amy"), "testcaveat"),
+				tuple.MustParse("document:first#viewer@user:bob"),
+				tuple.WithCaveat(tuple.MustParse("document:first#viewer@user:cat"), "testcaveat"),
+				tuple.MustParse("document:first#viewer@user:dan"),
+			}, require)
+		})
+	t.Cleanup(cleanup)
+
+	client := v1.NewPermissionsServiceClient(conn)
+	ctx := requestmeta.AddRequestHeaders(context.Background(), caveats.RequestPartitionedLookup)
+	consistency := &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}
+
+	requirePartitioned := func(permissionships []v1.LookupPermissionship, missing [][]string, hasCount, conditionalCount int) {
+		req.Len(permissionships, hasCount+conditionalCount)
+		for index, permissionship := range permissionships {
+			if index < hasCount {
+				req.Equal(v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION, permissionship)
+				req.Empty(missing[index])
+			} else {
+				req.Equal(v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION, permissionship)
+				req.Equal([]string{"somecondition"}, missing[index])
+			}
+		}
+	}
+
+	resources, err := client.LookupResources(ctx, &v1.LookupResourcesRequest{
+		Consistency:        consistency,
+		ResourceObjectType: "document",
+		Permission:         "view",
+		Subject:            sub("user", "tom", ""),
+	})
+	req.NoError(err)
+
+	var permissionships []v1.LookupPermissionship
+	var missing [][]string
+	for {
+		resp, err := resources.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		req.NoError(err)
+		permissionships = append(permissionships, resp.Permissionship)
+		missing = append(missing, resp.PartialCaveatInfo.GetMissingRequiredContext())
+	}
+	requirePartitioned(permissionships, missing, 2, 2)
+
+	subjects, err := client.LookupSubjects(ctx, &v1.LookupSubjectsRequest{
+		Consistency:       consistency,
+		Resource:          obj("document", "first"),
+		Permission:        "view",
+		SubjectObjectType: "user",
+	})
+	req.NoError(err)
+
+	permissionships, missing = nil, nil
+	for {
+		resp, err := subjects.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		req.NoError(err)
+		permissionships = append(permissionships, resp.Subject.Permissionship)
+		missing = append(missing, resp.Subject.PartialCaveatInfo.GetMissingRequiredContext())
+	}
+	requirePartitioned(permissionships, missing, 2, 3)
+}
+
+func TestCheckPermissionWithQueryStatistics(t *testing.T) {
+	require := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServer(require, testTimedeltas[0], memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := v1.NewPermissionsServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	check := func(ctx context.Context) metadata.MD {
+		var trailer metadata.MD
+		checkResp, err := client.CheckPermission(ctx, &v1.CheckPermissionRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true},
+			},
+			Resource:   obj("document", "masterplan"),
+			Permission: "view",
+			Subject:    sub("user", "auditor", ""),
+		}, grpc.Trailer(&trailer))
+		require.NoError(err)
+		require.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION, checkResp.Permissionship)
+		return trailer
+	}
+
+	encoded, err := responsemeta.GetResponseTrailerMetadataOrNil(check(context.Background()), querystats.QueryStatisticsTrailer)
+	require.NoError(err)
+	require.Nil(encoded)
+
+	ctx := requestmeta.AddRequestHeaders(context.Background(), querystats.RequestQueryStatistics)
+	encoded, err = responsemeta.GetResponseTrailerMetadataOrNil(check(ctx), querystats.QueryStatisticsTrailer)
+	require.NoError(err)
+	require.NotNil(encoded)
+
+	var summary querystats.Summary
+	require.NoError(json.Unmarshal([]byte(*encoded), &summary))
+	require.Greater(summary.DispatchCount, uint32(0))
+	require.Greater(summary.DepthRequired, uint32(0))
+}
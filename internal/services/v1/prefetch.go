@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/middleware/consistency"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+func (es *experimentalServer) PrefetchChecks(ctx context.Context, req *experimental.PrefetchChecksRequest) (*experimental.PrefetchChecksResponse, error) {
+	atRevision, prefetchingAt := consistency.MustRevisionFromContext(ctx)
+	ds := datastoremw.MustFromContext(ctx)
+	reader := ds.SnapshotReader(atRevision)
+
+	// The namespaces and relations of the checks are checked before returning, so that
+	// invalid checks are reported rather than silently failing in the background.
+	checked := make(map[string]struct{}, len(req.Checks))
+	for _, check := range req.Checks {
+		key := check.Resource.ObjectType + "#" + check.Permission + "@" + check.Subject.Object.ObjectType + "#" + normalizeSubjectRelation(check.Subject)
+		if _, ok := checked[key]; ok {
+			continue
+		}
+		checked[key] = struct{}{}
+
+		if err := namespace.CheckNamespacesAndRelations(ctx, reader,
+			namespace.RelationToCheck{Namespace: check.Resource.ObjectType, Relation: check.Permission, AllowEllipsis: false},
+			namespace.RelationToCheck{Namespace: check.Subject.Object.ObjectType, Relation: normalizeSubjectRelation(check.Subject), AllowEllipsis: true},
+		); err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+	}
+
+	// The checks outlive the call, so they run in a context of their own.
+	prefetchCtx := datastoremw.ContextWithHandle(log.Ctx(ctx).WithContext(context.Background()))
+	if err := datastoremw.SetInContext(prefetchCtx, ds); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+	go es.prefetch(prefetchCtx, reader, atRevision, req.Checks)
+
+	return &experimental.PrefetchChecksResponse{PrefetchingAt: prefetchingAt}, nil
+}
+
+// prefetch resolves the checks at the revision, each as CheckPermission would, so that
+// their dispatches are cached, waiting for a slot before starting each.
+func (es *experimentalServer) prefetch(ctx context.Context, ds datastore.Reader, atRevision datastore.Revision, checks []*experimental.PrefetchCheck) {
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	var failed atomic.Uint32
+	for _, check := range checks {
+		es.prefetchSlots <- struct{}{}
+		wg.Add(1)
+		go func(check *experimental.PrefetchCheck) {
+			defer func() {
+				<-es.prefetchSlots
+				wg.Done()
+			}()
+
+			if _, _, err := computeCheckPermission(ctx, es.dispatch, ds, atRevision, &v1.CheckPermissionRequest{
+				Resource:   check.Resource,
+				Permission: check.Permission,
+				Subject:    check.Subject,
+			}, nil, es.config.MaximumAPIDepth, false); err != nil {
+				failed.Add(1)
+				log.Ctx(ctx).Debug().Err(err).Str("resource", check.Resource.ObjectType+":"+check.Resource.ObjectId).Msg("failed to prefetch check")
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	log.Ctx(ctx).Debug().
+		Int("checks", len(checks)).
+		Uint32("failed", failed.Load()).
+		Dur("duration", time.Since(start)).
+		Msg("prefetched checks")
+}
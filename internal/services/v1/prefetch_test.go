@@ -0,0 +1,81 @@
+package v1
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/internal/middleware/consistency"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+type countingDispatcher struct {
+	dispatch.Dispatcher
+	checks atomic.Int32
+}
+
+func (cd *countingDispatcher) DispatchCheck(ctx context.Context, req *dispatchv1.DispatchCheckRequest) (*dispatchv1.DispatchCheckResponse, error) {
+	cd.checks.Add(1)
+	return cd.Dispatcher.DispatchCheck(ctx, req)
+}
+
+func prefetchCheck(resourceType, resourceID, permission, subjectID string) *experimental.PrefetchCheck {
+	return &experimental.PrefetchCheck{
+		Resource:   &v1.ObjectReference{ObjectType: resourceType, ObjectId: resourceID},
+		Permission: permission,
+		Subject:    &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: subjectID}},
+	}
+}
+
+func TestPrefetchChecks(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, _ := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	dispatcher := &countingDispatcher{Dispatcher: graph.NewLocalOnlyDispatcher(10)}
+	server := NewExperimentalServer(dispatcher, ExperimentalServerConfig{PrefetchConcurrency: 1}).(*experimentalServer)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	prefetch := func(req *experimental.PrefetchChecksRequest) (*experimental.PrefetchChecksResponse, error) {
+		ctx := consistency.ContextWithHandle(ctx)
+		require.NoError(consistency.AddRevisionToContext(ctx, req, ds))
+		return server.PrefetchChecks(ctx, req)
+	}
+
+	resp, err := prefetch(&experimental.PrefetchChecksRequest{
+		Checks: []*experimental.PrefetchCheck{
+			prefetchCheck("document", "masterplan", "view", "eng_lead"),
+			prefetchCheck("document", "masterplan", "view", "villain"),
+			prefetchCheck("document", "healthplan", "view", "chief_financial_officer"),
+		},
+	})
+	require.NoError(err)
+	require.NotNil(resp.PrefetchingAt)
+	require.Eventually(func() bool {
+		return dispatcher.checks.Load() == 3
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = prefetch(&experimental.PrefetchChecksRequest{
+		Checks: []*experimental.PrefetchCheck{
+			prefetchCheck("document", "masterplan", "view", "eng_lead"),
+			prefetchCheck("document", "masterplan", "unknown", "eng_lead"),
+		},
+	})
+	require.Equal(codes.FailedPrecondition, status.Code(err))
+}
@@ -0,0 +1,191 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func (es *experimentalServer) DiffRelationships(req *experimental.DiffRelationshipsRequest, resp experimental.ExperimentalService_DiffRelationshipsServer) error {
+	ctx := resp.Context()
+	ds := datastoremw.MustFromContext(ctx)
+
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	fromRevision, err := decodeCheckedRevision(ctx, ds, binding, req.FromRevision)
+	if err != nil {
+		return err
+	}
+
+	var toRevision datastore.Revision
+	if req.OptionalToRevision != nil {
+		toRevision, err = decodeCheckedRevision(ctx, ds, binding, req.OptionalToRevision)
+		if err != nil {
+			return err
+		}
+	} else {
+		toRevision, err = ds.HeadRevision(ctx)
+		if err != nil {
+			return rewriteError(ctx, err)
+		}
+	}
+
+	if fromRevision.GreaterThan(toRevision) {
+		return status.Errorf(codes.InvalidArgument, "from_revision must not be later than the revision to which it is diffed")
+	}
+
+	fromReader := ds.SnapshotReader(fromRevision)
+	toReader := ds.SnapshotReader(toRevision)
+
+	var filters []datastore.RelationshipsFilter
+	if req.OptionalRelationshipFilter != nil {
+		filters = append(filters, datastore.RelationshipsFilterFromPublicFilter(req.OptionalRelationshipFilter))
+	} else {
+		// The relationships of types defined at either revision are diffed, so that those of
+		// types defined or deleted between them are included.
+		resourceTypes := util.NewSet[string]()
+		for _, reader := range []datastore.Reader{fromReader, toReader} {
+			definitions, err := reader.ListNamespaces(ctx)
+			if err != nil {
+				return rewriteError(ctx, err)
+			}
+			for _, definition := range definitions {
+				resourceTypes.Add(definition.Name)
+			}
+		}
+
+		sorted := resourceTypes.AsSlice()
+		sort.Strings(sorted)
+		for _, resourceType := range sorted {
+			filters = append(filters, datastore.RelationshipsFilter{ResourceType: resourceType})
+		}
+	}
+
+	diffedTo := zedtoken.NewFromDatastoreRevision(toRevision, ds, binding)
+	send := func(operation experimental.DiffRelationshipsResponse_Operation, tpl *core.RelationTuple) error {
+		return resp.Send(&experimental.DiffRelationshipsResponse{
+			DiffedTo:     diffedTo,
+			Operation:    operation,
+			Relationship: tuple.ToRelationship(tpl),
+		})
+	}
+
+	for _, filter := range filters {
+		if err := diffRelationships(ctx, fromReader, toReader, filter, send); err != nil {
+			return rewriteError(ctx, err)
+		}
+	}
+	return nil
+}
+
+// diffRelationships merges the relationships matching the filter at the earlier and later
+// revisions, each read in order, sending those found only at the later revision as added
+// and those found only at the earlier as removed, in order. A relationship found at both
+// with different caveats is sent as removed and then added.
+func diffRelationships(
+	ctx context.Context,
+	fromReader, toReader datastore.Reader,
+	filter datastore.RelationshipsFilter,
+	send func(experimental.DiffRelationshipsResponse_Operation, *core.RelationTuple) error,
+) error {
+	fromIt, err := fromReader.QueryRelationships(ctx, filter, options.WithSort(options.ByResource), options.WithStreamRows(true))
+	if err != nil {
+		return err
+	}
+	defer fromIt.Close()
+
+	toIt, err := toReader.QueryRelationships(ctx, filter, options.WithSort(options.ByResource), options.WithStreamRows(true))
+	if err != nil {
+		return err
+	}
+	defer toIt.Close()
+
+	// An iterator which fails returns no more relationships, so its error is checked as soon
+	// as it returns none, before those remaining in the other are sent.
+	next := func(it datastore.RelationshipIterator) (*core.RelationTuple, error) {
+		tpl := it.Next()
+		if tpl == nil {
+			return nil, it.Err()
+		}
+		return tpl, nil
+	}
+
+	from, err := next(fromIt)
+	if err != nil {
+		return err
+	}
+	to, err := next(toIt)
+	if err != nil {
+		return err
+	}
+
+	for from != nil || to != nil {
+		var cmp int
+		switch {
+		case to == nil:
+			cmp = -1
+		case from == nil:
+			cmp = 1
+		default:
+			cmp = options.CompareByResource(from, to)
+		}
+		recaveated := cmp == 0 && !from.Caveat.EqualVT(to.Caveat)
+
+		if cmp < 0 || recaveated {
+			if err := send(experimental.DiffRelationshipsResponse_OPERATION_REMOVED, from); err != nil {
+				return err
+			}
+		}
+		if cmp > 0 || recaveated {
+			if err := send(experimental.DiffRelationshipsResponse_OPERATION_ADDED, to); err != nil {
+				return err
+			}
+		}
+
+		if cmp <= 0 {
+			if from, err = next(fromIt); err != nil {
+				return err
+			}
+		}
+		if cmp >= 0 {
+			if to, err = next(toIt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeCheckedRevision decodes the revision of a zedtoken of the datastore, and checks
+// that it is still valid and has not been garbage collected. Errors are returned
+// rewritten as statuses.
+func decodeCheckedRevision(ctx context.Context, ds datastore.Datastore, binding zedtoken.Binding, token *v1.ZedToken) (datastore.Revision, error) {
+	revision, err := zedtoken.DecodeDatastoreRevision(token, ds, binding)
+	if err != nil {
+		if errors.As(err, &zedtoken.ErrMismatchedDatastore{}) {
+			return nil, rewriteError(ctx, err)
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "failed to decode revision: %s", err)
+	}
+
+	if err := ds.CheckRevision(ctx, revision); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+	return revision, nil
+}
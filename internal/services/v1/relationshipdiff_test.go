@@ -0,0 +1,142 @@
+package v1_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const diffSchema = `definition user {}
+
+caveat only_on(day int) {
+	day == 1
+}
+
+definition document {
+	relation viewer: user | user with only_on
+}`
+
+func TestDiffRelationships(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	ctx := context.Background()
+	client := experimental.NewExperimentalServiceClient(conn)
+	permsClient := v1.NewPermissionsServiceClient(conn)
+
+	_, err := v1.NewSchemaServiceClient(conn).WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: diffSchema})
+	require.NoError(err)
+
+	write := func(operation v1.RelationshipUpdate_Operation, rel string, caveatName string) *v1.ZedToken {
+		relationship := tuple.MustToRelationship(tuple.MustParse(rel))
+		if caveatName != "" {
+			relationship.OptionalCaveat = &v1.ContextualizedCaveat{CaveatName: caveatName}
+		}
+
+		resp, err := permsClient.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+			Updates: []*v1.RelationshipUpdate{{Operation: operation, Relationship: relationship}},
+		})
+		require.NoError(err)
+		return resp.WrittenAt
+	}
+
+	write(v1.RelationshipUpdate_OPERATION_TOUCH, "document:a#viewer@user:alice", "")
+	write(v1.RelationshipUpdate_OPERATION_TOUCH, "document:b#viewer@user:bob", "")
+	from := write(v1.RelationshipUpdate_OPERATION_TOUCH, "document:c#viewer@user:carol", "")
+
+	middle := write(v1.RelationshipUpdate_OPERATION_TOUCH, "document:d#viewer@user:dave", "")
+	write(v1.RelationshipUpdate_OPERATION_DELETE, "document:b#viewer@user:bob", "")
+	write(v1.RelationshipUpdate_OPERATION_TOUCH, "document:c#viewer@user:carol", "only_on")
+	write(v1.RelationshipUpdate_OPERATION_TOUCH, "document:e#viewer@user:erin", "")
+	write(v1.RelationshipUpdate_OPERATION_DELETE, "document:e#viewer@user:erin", "")
+	write(v1.RelationshipUpdate_OPERATION_TOUCH, "document:a#viewer@user:alice", "")
+
+	diff := func(req *experimental.DiffRelationshipsRequest) ([]string, error) {
+		stream, err := client.DiffRelationships(ctx, req)
+		require.NoError(err)
+
+		var changes []string
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return changes, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			require.NotNil(resp.DiffedTo)
+
+			change := "+"
+			if resp.Operation == experimental.DiffRelationshipsResponse_OPERATION_REMOVED {
+				change = "-"
+			}
+			change += tuple.MustRelString(resp.Relationship)
+			if resp.Relationship.OptionalCaveat != nil {
+				change += "[" + resp.Relationship.OptionalCaveat.CaveatName + "]"
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		req      *experimental.DiffRelationshipsRequest
+		expected []string
+	}{
+		{
+			"to head",
+			&experimental.DiffRelationshipsRequest{FromRevision: from},
+			[]string{
+				"-document:b#viewer@user:bob",
+				"-document:c#viewer@user:carol",
+				"+document:c#viewer@user:carol[only_on]",
+				"+document:d#viewer@user:dave",
+			},
+		},
+		{
+			"to a revision",
+			&experimental.DiffRelationshipsRequest{FromRevision: from, OptionalToRevision: middle},
+			[]string{"+document:d#viewer@user:dave"},
+		},
+		{
+			"filtered",
+			&experimental.DiffRelationshipsRequest{
+				FromRevision:               from,
+				OptionalRelationshipFilter: &v1.RelationshipFilter{ResourceType: "document", OptionalResourceId: "b"},
+			},
+			[]string{"-document:b#viewer@user:bob"},
+		},
+		{
+			"unchanged",
+			&experimental.DiffRelationshipsRequest{FromRevision: middle, OptionalToRevision: middle},
+			nil,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			changes, err := diff(tc.req)
+			require.NoError(err)
+			require.Equal(tc.expected, changes)
+		})
+	}
+
+	_, err = diff(&experimental.DiffRelationshipsRequest{FromRevision: middle, OptionalToRevision: from})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	_, err = diff(&experimental.DiffRelationshipsRequest{FromRevision: &v1.ZedToken{Token: "invalid"}})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
@@ -0,0 +1,158 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RequestRelationshipFilter, if specified in the request header of a ReadRelationships
+// or DeleteRelationships call, is a relationship filter expression, such as
+// `document:tenant1-*#viewer@user:alice*`, which replaces the structured filter of the
+// request. The structured filter must then hold only the resource type of the
+// expression. See tuple.ParseRelationshipFilter for the syntax of expressions.
+const RequestRelationshipFilter requestmeta.RequestMetadataHeaderKey = "io.spicedb.relationshipfilter"
+
+// filterDeletePageSize is the number of relationships read and deleted at a time when
+// deleting the relationships matching a filter expression.
+var filterDeletePageSize = 1000
+
+// requestedRelationshipFilter returns the filter expression of the call, if any.
+func requestedRelationshipFilter(ctx context.Context, structured *v1.RelationshipFilter) (*tuple.RelationshipFilter, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	values := md.Get(string(RequestRelationshipFilter))
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	filter, err := tuple.ParseRelationshipFilter(values[0])
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request header %s: %s", RequestRelationshipFilter, err)
+	}
+
+	if structured.ResourceType != filter.ResourceType || structured.OptionalResourceId != "" || structured.OptionalRelation != "" || structured.OptionalSubjectFilter != nil {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"the relationship filter of a call with request header %s must hold only its resource type `%s`",
+			RequestRelationshipFilter,
+			filter.ResourceType,
+		)
+	}
+	return filter, nil
+}
+
+// checkFilterExpressionNamespaces checks the types and relations of a filter expression.
+func (ps *permissionServer) checkFilterExpressionNamespaces(ctx context.Context, filter *tuple.RelationshipFilter, ds datastore.Reader) error {
+	if err := ps.checkFilterComponent(ctx, filter.ResourceType, filter.Relation, ds); err != nil {
+		return err
+	}
+
+	if filter.Subject == nil {
+		return nil
+	}
+
+	// Subjects without a relation are checked as with no relation filtered.
+	relation := filter.Subject.Relation
+	if relation == tuple.Ellipsis {
+		relation = ""
+	}
+	return ps.checkFilterComponent(ctx, filter.Subject.SubjectType, relation, ds)
+}
+
+// datastoreFilterForExpression returns the datastore filter selecting the relationships
+// of a filter expression.
+func datastoreFilterForExpression(filter *tuple.RelationshipFilter) datastore.RelationshipsFilter {
+	dsFilter := datastore.RelationshipsFilter{
+		ResourceType:             filter.ResourceType,
+		OptionalResourceRelation: filter.Relation,
+		OptionalCaveatName:       filter.Caveat,
+	}
+	switch {
+	case filter.ResourceID.IsAny():
+	case filter.ResourceID.IsPrefix:
+		dsFilter.OptionalResourceIDPrefix = filter.ResourceID.ID
+	default:
+		dsFilter.OptionalResourceIds = []string{filter.ResourceID.ID}
+	}
+
+	if filter.Subject != nil {
+		subjectsFilter := &datastore.SubjectsFilter{SubjectType: filter.Subject.SubjectType}
+		switch {
+		case filter.Subject.SubjectID.IsAny():
+		case filter.Subject.SubjectID.IsPrefix:
+			subjectsFilter.OptionalSubjectIDPrefix = filter.Subject.SubjectID.ID
+		default:
+			subjectsFilter.OptionalSubjectIds = []string{filter.Subject.SubjectID.ID}
+		}
+
+		switch filter.Subject.Relation {
+		case "":
+		case tuple.Ellipsis:
+			subjectsFilter.RelationFilter = subjectsFilter.RelationFilter.WithEllipsisRelation()
+		default:
+			subjectsFilter.RelationFilter = subjectsFilter.RelationFilter.WithNonEllipsisRelation(filter.Subject.Relation)
+		}
+		dsFilter.OptionalSubjectsFilter = subjectsFilter
+	}
+
+	return dsFilter
+}
+
+// queryFilterExpression returns an iterator of the relationships matching a filter
+// expression.
+func queryFilterExpression(ctx context.Context, reader datastore.Reader, filter *tuple.RelationshipFilter, opts ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	return reader.QueryRelationships(ctx, datastoreFilterForExpression(filter), opts...)
+}
+
+// deleteFilterExpression deletes the relationships matching a filter expression, in pages
+// of up to filterDeletePageSize relationships so that the relationships to delete are
+// never all held at once.
+func deleteFilterExpression(ctx context.Context, rwt datastore.ReadWriteTransaction, filter *tuple.RelationshipFilter) error {
+	limit := uint64(filterDeletePageSize)
+	for {
+		it, err := queryFilterExpression(ctx, rwt, filter, options.WithLimit(&limit))
+		if err != nil {
+			return err
+		}
+
+		// Each page is read before it is deleted, as datastores do not allow writes in a
+		// transaction while it is reading. The relationships deleted are no longer read
+		// by the transaction, so the next page starts where this one ends.
+		updates := make([]*core.RelationTupleUpdate, 0, filterDeletePageSize)
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			updates = append(updates, tuple.Delete(tpl))
+		}
+		if it.Err() != nil {
+			it.Close()
+			return fmt.Errorf("unable to read relationships to delete: %w", it.Err())
+		}
+		it.Close()
+
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := rwt.WriteRelationships(ctx, updates); err != nil {
+			return err
+		}
+		if err := recordRelationshipSources(ctx, rwt, "", updates); err != nil {
+			return err
+		}
+		if len(updates) < filterDeletePageSize {
+			return nil
+		}
+	}
+}
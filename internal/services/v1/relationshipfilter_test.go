@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestRelationshipFilterExpression(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, tombstoneTestSchema, nil, require)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+	withFilter := func(expr string) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(string(RequestRelationshipFilter), expr))
+	}
+
+	server := NewPermissionsServer(graph.NewLocalOnlyDispatcher(10), PermissionsServerConfig{}, false)
+	updates := make([]*v1.RelationshipUpdate, 0, 4)
+	for _, rel := range []string{
+		"document:tenant1-first#viewer@user:alice",
+		"document:tenant1-second#viewer@user:bob",
+		"document:tenant1-third#viewer@user:alicia",
+		"document:tenant2-first#viewer@user:alice",
+	} {
+		updates = append(updates, &v1.RelationshipUpdate{
+			Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+			Relationship: tuple.MustToRelationship(tuple.MustParse(rel)),
+		})
+	}
+	_, err = server.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{Updates: updates})
+	require.NoError(err)
+
+	documentFilter := &v1.RelationshipFilter{ResourceType: "document"}
+	filter, err := requestedRelationshipFilter(withFilter("document:tenant1-*@user:ali*"), documentFilter)
+	require.NoError(err)
+
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	it, err := queryFilterExpression(ctx, ds.SnapshotReader(headRevision), filter)
+	require.NoError(err)
+	var found []string
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		found = append(found, tuple.String(tpl))
+	}
+	require.NoError(it.Err())
+	it.Close()
+	require.ElementsMatch([]string{
+		"document:tenant1-first#viewer@user:alice",
+		"document:tenant1-third#viewer@user:alicia",
+	}, found)
+
+	// The structured filter must hold only the resource type of the expression.
+	_, err = server.DeleteRelationships(withFilter("document:tenant1-*"), &v1.DeleteRelationshipsRequest{
+		RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document", OptionalRelation: "viewer"},
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	_, err = server.DeleteRelationships(withFilter("document:tenant1-*#"), &v1.DeleteRelationshipsRequest{
+		RelationshipFilter: documentFilter,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	_, err = server.DeleteRelationships(withFilter("document#unknown"), &v1.DeleteRelationshipsRequest{
+		RelationshipFilter: documentFilter,
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	// The matching relationships are deleted across several pages.
+	defer func(pageSize int) { filterDeletePageSize = pageSize }(filterDeletePageSize)
+	filterDeletePageSize = 1
+
+	_, err = server.DeleteRelationships(withFilter("document:tenant1-*@user:ali*"), &v1.DeleteRelationshipsRequest{
+		RelationshipFilter: documentFilter,
+	})
+	require.NoError(err)
+	require.Equal(uint64(2), countTypeRelationships(t, ds, "document"))
+
+	_, err = server.DeleteRelationships(withFilter("document:**@user:**"), &v1.DeleteRelationshipsRequest{
+		RelationshipFilter: documentFilter,
+	})
+	require.NoError(err)
+	require.Equal(uint64(0), countTypeRelationships(t, ds, "document"))
+}
@@ -10,6 +10,8 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/internal/decisionlog"
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/middleware"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
@@ -18,6 +20,7 @@ import (
 	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/admission"
 	"github.com/authzed/spicedb/pkg/caveats"
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/middleware/consistency"
@@ -41,6 +44,36 @@ type PermissionsServerConfig struct {
 	// MaximumAPIDepth is the default/starting depth remaining for API calls made
 	// to the permissions server.
 	MaximumAPIDepth uint32
+
+	// MaxLookupMemoryBytes is the approximate number of bytes of intermediate results that
+	// a single LookupResources or LookupSubjects call may hold in memory. Zero places no
+	// limit.
+	MaxLookupMemoryBytes uint64
+
+	// LookupSpillDirectory is the directory in which LookupSubjects writes the intermediate
+	// results exceeding MaxLookupMemoryBytes. If empty, the default directory for
+	// temporary files is used.
+	LookupSpillDirectory string
+
+	// SortLookupResults, if true, makes LookupResources and LookupSubjects send their
+	// results sorted by ID, once the lookup has completed. Otherwise, results are sent
+	// as they are found, unordered. Either way, each result is sent once.
+	SortLookupResults bool
+
+	// AdmissionHook, if set, admits the updates of WriteRelationships calls before
+	// they are written.
+	AdmissionHook admission.Hook
+
+	// CardinalityLimits bound the number of relationships written by WriteRelationships
+	// calls for a single resource on a relation.
+	CardinalityLimits CardinalityLimits
+
+	// CanarySchema, if set, evaluates a sampled fraction of checks against the canary
+	// schema set on the experimental server.
+	CanarySchema *CanarySchema
+
+	// DecisionLog, if set, records a sample of the decisions of checks.
+	DecisionLog *decisionlog.Logger
 }
 
 // NewPermissionsServer creates a PermissionsServiceServer instance.
@@ -53,6 +86,13 @@ func NewPermissionsServer(
 		MaxPreconditionsCount: defaultIfZero(config.MaxPreconditionsCount, 1000),
 		MaxUpdatesPerWrite:    defaultIfZero(config.MaxUpdatesPerWrite, 1000),
 		MaximumAPIDepth:       defaultIfZero(config.MaximumAPIDepth, 50),
+		MaxLookupMemoryBytes:  config.MaxLookupMemoryBytes,
+		LookupSpillDirectory:  config.LookupSpillDirectory,
+		SortLookupResults:     config.SortLookupResults,
+		AdmissionHook:         config.AdmissionHook,
+		CardinalityLimits:     config.CardinalityLimits,
+		CanarySchema:          config.CanarySchema,
+		DecisionLog:           config.DecisionLog,
 	}
 
 	return &permissionServer{
@@ -112,7 +152,17 @@ func (ps *permissionServer) ReadRelationships(req *v1.ReadRelationshipsRequest,
 	atRevision, revisionReadAt := consistency.MustRevisionFromContext(ctx)
 	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
 
-	if err := ps.checkFilterNamespaces(ctx, req.RelationshipFilter, ds); err != nil {
+	filterExpr, err := requestedRelationshipFilter(ctx, req.RelationshipFilter)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	if filterExpr != nil {
+		err = ps.checkFilterExpressionNamespaces(ctx, filterExpr, ds)
+	} else {
+		err = ps.checkFilterNamespaces(ctx, req.RelationshipFilter, ds)
+	}
+	if err != nil {
 		return rewriteError(ctx, err)
 	}
 
@@ -120,18 +170,28 @@ func (ps *permissionServer) ReadRelationships(req *v1.ReadRelationshipsRequest,
 		DispatchCount: 1,
 	})
 
-	tupleIterator, err := ds.QueryRelationships(ctx, datastore.RelationshipsFilterFromPublicFilter(req.RelationshipFilter))
+	// The relationships are read from the datastore as they are sent, rather than loaded
+	// up front, so that each is held in memory only until it is sent.
+	var tupleIterator datastore.RelationshipIterator
+	if filterExpr != nil {
+		tupleIterator, err = queryFilterExpression(ctx, ds, filterExpr, options.WithStreamRows(true))
+	} else {
+		tupleIterator, err = ds.QueryRelationships(
+			ctx,
+			datastore.RelationshipsFilterFromPublicFilter(req.RelationshipFilter),
+			options.WithStreamRows(true),
+		)
+	}
 	if err != nil {
 		return rewriteError(ctx, err)
 	}
 	defer tupleIterator.Close()
 
 	for tpl := tupleIterator.Next(); tpl != nil; tpl = tupleIterator.Next() {
-		err := resp.Send(&v1.ReadRelationshipsResponse{
+		if err := resp.Send(&v1.ReadRelationshipsResponse{
 			ReadAt:       revisionReadAt,
 			Relationship: tuple.ToRelationship(tpl),
-		})
-		if err != nil {
+		}); err != nil {
 			return err
 		}
 	}
@@ -160,9 +220,8 @@ func (ps *permissionServer) WriteRelationships(ctx context.Context, req *v1.Writ
 		)
 	}
 
-	// Check for duplicate updates and create the set of caveat names to load.
+	// Check for duplicate updates.
 	updateRelationshipSet := util.NewSet[string]()
-	referencedCaveatNamesWithContext := util.NewSet[string]()
 	for _, update := range req.Updates {
 		tupleStr := tuple.StringRelationship(update.Relationship)
 		if !updateRelationshipSet.Add(tupleStr) {
@@ -176,17 +235,17 @@ func (ps *permissionServer) WriteRelationships(ctx context.Context, req *v1.Writ
 			)
 		}
 
-		// Only load the caveat if we need its type information for context checking.
-		if hasNonEmptyCaveatContext(update) {
-			if !ps.caveatsEnabled {
-				return nil, fmt.Errorf("caveats are currently not supported")
-			}
-			referencedCaveatNamesWithContext.Add(update.Relationship.OptionalCaveat.CaveatName)
+		if hasNonEmptyCaveatContext(update) && !ps.caveatsEnabled {
+			return nil, fmt.Errorf("caveats are currently not supported")
 		}
 	}
 
+	if err := admitRelationships(ctx, ps.config.AdmissionHook, req); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
 	// Execute the write operation(s).
-	// TODO(jschorr): look into loading the type system once per type, rather than once per relationship
+	var exceededSoftLimits []exceededCardinality
 	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
 		// Validate the preconditions.
 		for _, precond := range req.OptionalPreconditions {
@@ -195,145 +254,186 @@ func (ps *permissionServer) WriteRelationships(ctx context.Context, req *v1.Writ
 			}
 		}
 
-		// Load caveats, if any.
-		var referencedCaveatMap map[string]*core.CaveatDefinition
-		if !referencedCaveatNamesWithContext.IsEmpty() {
-			foundCaveats, err := rwt.ListCaveats(ctx, referencedCaveatNamesWithContext.AsSlice()...)
-			if err != nil {
-				return err
-			}
+		if err := validateRelationshipUpdates(ctx, rwt, req.Updates); err != nil {
+			return err
+		}
 
-			referencedCaveatMap = make(map[string]*core.CaveatDefinition, len(foundCaveats))
-			for _, caveatDef := range foundCaveats {
-				referencedCaveatMap[caveatDef.Name] = caveatDef
-			}
+		usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+			// One request per precondition and one request for the actual writes.
+			DispatchCount: uint32(len(req.OptionalPreconditions)) + 1,
+		})
+
+		if err := checkPreconditions(ctx, rwt, req.OptionalPreconditions); err != nil {
+			return err
 		}
 
-		// Validate the updates.
-		for _, update := range req.Updates {
-			if err := tuple.ValidateResourceID(update.Relationship.Resource.ObjectId); err != nil {
-				return err
-			}
+		updates := tuple.UpdateFromRelationshipUpdates(req.Updates)
+		if err := rwt.WriteRelationships(ctx, updates); err != nil {
+			return err
+		}
 
-			if err := tuple.ValidateSubjectID(update.Relationship.Subject.Object.ObjectId); err != nil {
-				return err
-			}
+		if err := recordRelationshipSources(ctx, rwt, relationshipSource(ctx), updates); err != nil {
+			return err
+		}
 
-			if err := namespace.CheckNamespaceAndRelation(
-				ctx,
-				update.Relationship.Resource.ObjectType,
-				update.Relationship.Relation,
-				false,
-				rwt,
-			); err != nil {
-				return err
-			}
+		// Cardinalities are counted once written, so that TOUCHes of existing relationships
+		// and deletes in the same call are accounted for.
+		var err error
+		exceededSoftLimits, err = ps.config.CardinalityLimits.check(ctx, rwt, req.Updates)
+		return err
+	})
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
 
-			if err := namespace.CheckNamespaceAndRelation(
-				ctx,
-				update.Relationship.Subject.Object.ObjectType,
-				stringz.DefaultEmpty(update.Relationship.Subject.OptionalRelation, datastore.Ellipsis),
-				true,
-				rwt,
-			); err != nil {
-				return err
-			}
+	for _, exceeded := range exceededSoftLimits {
+		exceeded.report(ctx)
+	}
 
-			// Build the type system for the object type.
-			_, ts, err := namespace.ReadNamespaceAndTypes(
-				ctx,
-				update.Relationship.Resource.ObjectType,
-				rwt,
-			)
-			if err != nil {
-				return err
-			}
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
 
-			// Validate that the relationship is not writing to a permission.
-			if ts.IsPermission(update.Relationship.Relation) {
-				return status.Errorf(
-					codes.InvalidArgument,
-					"cannot write a relationship to permission %s",
-					update.Relationship.Relation,
-				)
-			}
+	return &v1.WriteRelationshipsResponse{
+		WrittenAt: zedtoken.NewFromDatastoreRevision(revision, ds, binding),
+	}, nil
+}
 
-			// Validate the subject against the allowed relation(s).
-			var relationToCheck *core.AllowedRelation
-			var caveat *core.AllowedCaveat
+// validateRelationshipUpdates validates the relationships of the updates against the schema
+// read by the reader: their relations must exist and not be permissions, their subjects
+// must be allowed on them, and the contexts of their caveats must match the parameters of
+// the caveats.
+func validateRelationshipUpdates(ctx context.Context, reader datastore.Reader, updates []*v1.RelationshipUpdate) error {
+	// Load the type systems for every namespace referenced by the updates in a single read.
+	referencedNamespaceNames := make([]string, 0, len(updates)*2)
+	for _, update := range updates {
+		referencedNamespaceNames = append(referencedNamespaceNames,
+			update.Relationship.Resource.ObjectType,
+			update.Relationship.Subject.Object.ObjectType,
+		)
+	}
 
-			if update.Relationship.OptionalCaveat != nil {
-				caveat = ns.AllowedCaveat(update.Relationship.OptionalCaveat.CaveatName)
-			}
+	typeSystems, err := namespace.ReadNamespacesAndTypes(ctx, referencedNamespaceNames, reader)
+	if err != nil {
+		return err
+	}
 
-			if update.Relationship.Subject.Object.ObjectId == tuple.PublicWildcard {
-				relationToCheck = ns.AllowedPublicNamespaceWithCaveat(update.Relationship.Subject.Object.ObjectType, caveat)
-			} else {
-				relationToCheck = ns.AllowedRelationWithCaveat(
-					update.Relationship.Subject.Object.ObjectType,
-					stringz.DefaultEmpty(
-						update.Relationship.Subject.OptionalRelation,
-						datastore.Ellipsis),
-					caveat)
-			}
+	// Load the caveats whose type information is needed to check the contexts given, if any.
+	referencedCaveatNamesWithContext := util.NewSet[string]()
+	for _, update := range updates {
+		if hasNonEmptyCaveatContext(update) {
+			referencedCaveatNamesWithContext.Add(update.Relationship.OptionalCaveat.CaveatName)
+		}
+	}
+
+	var referencedCaveatMap map[string]*core.CaveatDefinition
+	if !referencedCaveatNamesWithContext.IsEmpty() {
+		foundCaveats, err := reader.ListCaveats(ctx, referencedCaveatNamesWithContext.AsSlice()...)
+		if err != nil {
+			return err
+		}
+
+		referencedCaveatMap = make(map[string]*core.CaveatDefinition, len(foundCaveats))
+		for _, caveatDef := range foundCaveats {
+			referencedCaveatMap[caveatDef.Name] = caveatDef
+		}
+	}
 
-			isAllowed, err := ts.HasAllowedRelation(
+	// Validate the updates.
+	for _, update := range updates {
+		if err := tuple.ValidateResourceID(update.Relationship.Resource.ObjectId); err != nil {
+			return err
+		}
+
+		if err := tuple.ValidateSubjectID(update.Relationship.Subject.Object.ObjectId); err != nil {
+			return err
+		}
+
+		ts := typeSystems[update.Relationship.Resource.ObjectType]
+		if !ts.HasRelation(update.Relationship.Relation) {
+			return namespace.NewRelationNotFoundErr(
+				update.Relationship.Resource.ObjectType,
 				update.Relationship.Relation,
-				relationToCheck,
 			)
-			if err != nil {
-				return err
-			}
+		}
 
-			if isAllowed != namespace.AllowedRelationValid {
-				return status.Errorf(
-					codes.InvalidArgument,
-					"subjects of type `%s` are not allowed on relation `%v`",
-					namespace.SourceForAllowedRelation(relationToCheck),
-					tuple.StringObjectRef(update.Relationship.Resource),
-				)
-			}
+		subjectRelation := stringz.DefaultEmpty(update.Relationship.Subject.OptionalRelation, datastore.Ellipsis)
+		subjectTS := typeSystems[update.Relationship.Subject.Object.ObjectType]
+		if subjectRelation != datastore.Ellipsis && !subjectTS.HasRelation(subjectRelation) {
+			return namespace.NewRelationNotFoundErr(
+				update.Relationship.Subject.Object.ObjectType,
+				subjectRelation,
+			)
+		}
 
-			// Validate caveat and its context, if applicable.
-			// TODO(jschorr): once caveats are supported on all datastores, we should elide this check if the
-			// provided context is empty, as the allowed relation check above will ensure the caveat exists.
-			if hasNonEmptyCaveatContext(update) {
-				caveat, ok := referencedCaveatMap[update.Relationship.OptionalCaveat.CaveatName]
-				if !ok {
-					// Should ideally never happen since the caveat is type checked above, but just in case.
-					return rewriteError(ctx, NewCaveatNotFoundError(update))
-				}
-
-				// Verify that the provided context information matches the types of the parameters defined.
-				_, err := caveats.ConvertContextToParameters(
-					update.Relationship.OptionalCaveat.Context.AsMap(),
-					caveat.ParameterTypes,
-					caveats.ErrorForUnknownParameters,
-				)
-				if err != nil {
-					return rewriteError(ctx, err)
-				}
-			}
+		// Validate that the relationship is not writing to a permission.
+		if ts.IsPermission(update.Relationship.Relation) {
+			return status.Errorf(
+				codes.InvalidArgument,
+				"cannot write a relationship to permission %s",
+				update.Relationship.Relation,
+			)
 		}
 
-		usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
-			// One request per precondition and one request for the actual writes.
-			DispatchCount: uint32(len(req.OptionalPreconditions)) + 1,
-		})
+		// Validate the subject against the allowed relation(s).
+		var relationToCheck *core.AllowedRelation
+		var caveat *core.AllowedCaveat
 
-		if err := checkPreconditions(ctx, rwt, req.OptionalPreconditions); err != nil {
+		if update.Relationship.OptionalCaveat != nil {
+			caveat = ns.AllowedCaveat(update.Relationship.OptionalCaveat.CaveatName)
+		}
+
+		if update.Relationship.Subject.Object.ObjectId == tuple.PublicWildcard {
+			relationToCheck = ns.AllowedPublicNamespaceWithCaveat(update.Relationship.Subject.Object.ObjectType, caveat)
+		} else {
+			relationToCheck = ns.AllowedRelationWithCaveat(
+				update.Relationship.Subject.Object.ObjectType,
+				stringz.DefaultEmpty(
+					update.Relationship.Subject.OptionalRelation,
+					datastore.Ellipsis),
+				caveat)
+		}
+
+		isAllowed, err := ts.HasAllowedRelation(
+			update.Relationship.Relation,
+			relationToCheck,
+		)
+		if err != nil {
 			return err
 		}
 
-		return rwt.WriteRelationships(ctx, tuple.UpdateFromRelationshipUpdates(req.Updates))
-	})
-	if err != nil {
-		return nil, rewriteError(ctx, err)
-	}
+		if isAllowed != namespace.AllowedRelationValid {
+			return status.Errorf(
+				codes.InvalidArgument,
+				"subjects of type `%s` are not allowed on relation `%v`",
+				namespace.SourceForAllowedRelation(relationToCheck),
+				tuple.StringObjectRef(update.Relationship.Resource),
+			)
+		}
 
-	return &v1.WriteRelationshipsResponse{
-		WrittenAt: zedtoken.NewFromRevision(revision),
-	}, nil
+		// Validate caveat and its context, if applicable.
+		// TODO(jschorr): once caveats are supported on all datastores, we should elide this check if the
+		// provided context is empty, as the allowed relation check above will ensure the caveat exists.
+		if hasNonEmptyCaveatContext(update) {
+			caveat, ok := referencedCaveatMap[update.Relationship.OptionalCaveat.CaveatName]
+			if !ok {
+				// Should ideally never happen since the caveat is type checked above, but just in case.
+				return rewriteError(ctx, NewCaveatNotFoundError(update))
+			}
+
+			// Verify that the provided context information matches the types of the parameters defined.
+			_, err := caveats.ConvertContextToParameters(
+				update.Relationship.OptionalCaveat.Context.AsMap(),
+				caveat.ParameterTypes,
+				caveats.ErrorForUnknownParameters,
+			)
+			if err != nil {
+				return rewriteError(ctx, err)
+			}
+		}
+	}
+	return nil
 }
 
 func hasNonEmptyCaveatContext(update *v1.RelationshipUpdate) bool {
@@ -353,8 +453,17 @@ func (ps *permissionServer) DeleteRelationships(ctx context.Context, req *v1.Del
 
 	ds := datastoremw.MustFromContext(ctx)
 
+	filterExpr, err := requestedRelationshipFilter(ctx, req.RelationshipFilter)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
 	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
-		if err := ps.checkFilterNamespaces(ctx, req.RelationshipFilter, rwt); err != nil {
+		if filterExpr != nil {
+			if err := ps.checkFilterExpressionNamespaces(ctx, filterExpr, rwt); err != nil {
+				return err
+			}
+		} else if err := ps.checkFilterNamespaces(ctx, req.RelationshipFilter, rwt); err != nil {
 			return err
 		}
 
@@ -367,13 +476,24 @@ func (ps *permissionServer) DeleteRelationships(ctx context.Context, req *v1.Del
 			return err
 		}
 
-		return rwt.DeleteRelationships(ctx, req.RelationshipFilter)
+		if filterExpr != nil {
+			return deleteFilterExpression(ctx, rwt, filterExpr)
+		}
+		if err := rwt.DeleteRelationships(ctx, req.RelationshipFilter); err != nil {
+			return err
+		}
+		return clearRelationshipSources(ctx, rwt, req.RelationshipFilter)
 	})
 	if err != nil {
 		return nil, rewriteError(ctx, err)
 	}
 
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
 	return &v1.DeleteRelationshipsResponse{
-		DeletedAt: zedtoken.NewFromRevision(revision),
+		DeletedAt: zedtoken.NewFromDatastoreRevision(revision, ds, binding),
 	}, nil
 }
@@ -1,6 +1,7 @@
 package v1_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/authzed/spicedb/internal/datastore/backup"
 	"github.com/authzed/spicedb/internal/datastore/memdb"
 	tf "github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/internal/testserver"
@@ -1117,3 +1119,50 @@ func standardTuplesWithout(without map[string]struct{}) map[string]struct{} {
 	}
 	return out
 }
+
+func TestZedTokensRejectedAfterRestore(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	conn, cleanup, ds, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := v1.NewPermissionsServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	var snapshot bytes.Buffer
+	_, err := backup.Backup(ctx, ds, "memory", true, &snapshot)
+	require.NoError(err)
+
+	write := func() *v1.ZedToken {
+		resp, err := client.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+			Updates: []*v1.RelationshipUpdate{tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.MustParse("document:totallynew#parent@folder:plans")))},
+		})
+		require.NoError(err)
+		return resp.WrittenAt
+	}
+
+	read := func(token *v1.ZedToken) error {
+		stream, err := client.ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_AtLeastAsFresh{AtLeastAsFresh: token},
+			},
+			RelationshipFilter: &v1.RelationshipFilter{ResourceType: tf.DocumentNS.Name},
+		})
+		require.NoError(err)
+		_, err = stream.Recv()
+		return err
+	}
+
+	writtenAt := write()
+	require.NoError(read(writtenAt))
+
+	// The restore replaces the contents of the datastore at which the token was minted.
+	_, _, err = backup.Restore(ctx, ds, bytes.NewReader(snapshot.Bytes()), backup.DefaultRestoreBatchSize, true)
+	require.NoError(err)
+
+	err = read(writtenAt)
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	spiceerrors.RequireExtendedReason(t, spiceerrors.ReasonMismatchedDatastore, err)
+
+	// Tokens minted after the restore are accepted.
+	require.NoError(read(write()))
+}
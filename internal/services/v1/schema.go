@@ -2,19 +2,25 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/authzed/authzed-go/pkg/requestmeta"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/internal/middleware/consistency"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
 	"github.com/authzed/spicedb/internal/services/shared"
+	"github.com/authzed/spicedb/pkg/admission"
 	"github.com/authzed/spicedb/pkg/datastore"
 	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
@@ -22,15 +28,22 @@ import (
 	"github.com/authzed/spicedb/pkg/schemadsl/input"
 )
 
-// NewSchemaServer creates a SchemaServiceServer instance.
-func NewSchemaServer(additiveOnly, caveatsEnabled bool) v1.SchemaServiceServer {
+// RequestSchemaAuthor, if specified in the request header of a WriteSchema call, is
+// recorded as the author of the version of the schema it writes.
+const RequestSchemaAuthor requestmeta.RequestMetadataHeaderKey = "io.spicedb.schemaauthor"
+
+// NewSchemaServer creates a SchemaServiceServer instance. Definitions removed by
+// WriteSchema are retained for removedRetention, if non-zero.
+func NewSchemaServer(additiveOnly, caveatsEnabled bool, removedRetention time.Duration, admissionHook admission.Hook) v1.SchemaServiceServer {
 	return &schemaServer{
 		WithServiceSpecificInterceptors: shared.WithServiceSpecificInterceptors{
 			Unary:  grpcvalidate.UnaryServerInterceptor(true),
 			Stream: grpcvalidate.StreamServerInterceptor(true),
 		},
-		additiveOnly:   additiveOnly,
-		caveatsEnabled: caveatsEnabled,
+		additiveOnly:     additiveOnly,
+		caveatsEnabled:   caveatsEnabled,
+		removedRetention: removedRetention,
+		admissionHook:    admissionHook,
 	}
 }
 
@@ -38,8 +51,10 @@ type schemaServer struct {
 	v1.UnimplementedSchemaServiceServer
 	shared.WithServiceSpecificInterceptors
 
-	additiveOnly   bool
-	caveatsEnabled bool
+	additiveOnly     bool
+	caveatsEnabled   bool
+	removedRetention time.Duration
+	admissionHook    admission.Hook
 }
 
 func (ss *schemaServer) ReadSchema(ctx context.Context, in *v1.ReadSchemaRequest) (*v1.ReadSchemaResponse, error) {
@@ -73,31 +88,65 @@ func (ss *schemaServer) ReadSchema(ctx context.Context, in *v1.ReadSchemaRequest
 func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaRequest) (*v1.WriteSchemaResponse, error) {
 	log.Ctx(ctx).Trace().Str("schema", in.GetSchema()).Msg("requested Schema to be written")
 
+	if _, err := writeSchema(ctx, in.GetSchema(), ss.additiveOnly, ss.caveatsEnabled, ss.removedRetention, ss.admissionHook); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &v1.WriteSchemaResponse{}, nil
+}
+
+// writeSchema compiles, validates, admits and writes the schema in a single transaction,
+// and records it as the next version of the schema, returning the revision at which it
+// was written. If removedRetention is non-zero, the definitions it removes are retained
+// for that long, and their relationships are deleted along with them.
+func writeSchema(ctx context.Context, schemaText string, additiveOnly, caveatsEnabled bool, removedRetention time.Duration, admissionHook admission.Hook) (datastore.Revision, error) {
 	ds := datastoremw.MustFromContext(ctx)
 
 	// Compile the schema into the namespace definitions.
 	emptyDefaultPrefix := ""
 	compiled, err := compiler.Compile(compiler.InputSchema{
 		Source:       input.Source("schema"),
-		SchemaString: in.GetSchema(),
+		SchemaString: schemaText,
 	}, &emptyDefaultPrefix)
 	if err != nil {
-		return nil, rewriteError(ctx, err)
+		return nil, err
 	}
 	log.Ctx(ctx).Trace().Int("objectDefinitions", len(compiled.ObjectDefinitions)).Int("caveatDefinitions", len(compiled.CaveatDefinitions)).Msg("compiled namespace definitions")
 
-	if !ss.caveatsEnabled && len(compiled.CaveatDefinitions) > 0 {
+	if !caveatsEnabled && len(compiled.CaveatDefinitions) > 0 {
 		return nil, fmt.Errorf("caveats are currently not supported")
 	}
 
 	// Do as much validation as we can before talking to the datastore.
-	validated, err := shared.ValidateSchemaChanges(ctx, compiled, ss.additiveOnly)
+	validated, err := shared.ValidateSchemaChanges(ctx, compiled, additiveOnly)
 	if err != nil {
-		return nil, rewriteError(ctx, err)
+		return nil, err
+	}
+
+	if err := admitSchema(ctx, admissionHook, schemaText); err != nil {
+		return nil, err
+	}
+
+	var retained []string
+	if removedRetention > 0 && !additiveOnly {
+		retained, err = retainRemovedDefinitions(ctx, ds, compiled, removedRetention)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Update the schema.
-	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+	writtenAt, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		for _, name := range retained {
+			filter := &v1.RelationshipFilter{ResourceType: name}
+			if err := rwt.DeleteRelationships(ctx, filter); err != nil {
+				return err
+			}
+			if err := clearRelationshipSources(ctx, rwt, filter); err != nil {
+				return err
+			}
+		}
+
 		applied, err := shared.ApplySchemaChanges(ctx, rwt, validated)
 		if err != nil {
 			return err
@@ -108,8 +157,36 @@ func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaReque
 		return nil
 	})
 	if err != nil {
-		return nil, rewriteError(ctx, err)
+		releaseRemovedDefinitions(ctx, ds, retained)
+		return nil, err
 	}
 
-	return &v1.WriteSchemaResponse{}, nil
+	// The schema is written even if its version cannot be recorded, so failing to record
+	// it does not fail the call.
+	store, err := common.SchemaHistoryStoreOf(ds)
+	if err == nil {
+		_, err = store.AddSchemaVersion(ctx, writtenAt, schemaText, schemaAuthor(ctx))
+	}
+	if err != nil {
+		if errors.As(err, &datastore.ErrSchemaHistoryUnsupported{}) {
+			log.Ctx(ctx).Trace().Err(err).Msg("schema version not recorded")
+		} else {
+			log.Ctx(ctx).Warn().Err(err).Msg("failed to record schema version")
+		}
+	}
+
+	return writtenAt, nil
+}
+
+func schemaAuthor(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(string(RequestSchemaAuthor))
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
 }
@@ -0,0 +1,223 @@
+package v1
+
+import (
+	"context"
+	"sort"
+
+	"golang.org/x/exp/maps"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/authzed/spicedb/internal/caveats"
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func (es *experimentalServer) ListSchemaVersions(ctx context.Context, _ *experimental.ListSchemaVersionsRequest) (*experimental.ListSchemaVersionsResponse, error) {
+	ds := datastoremw.MustFromContext(ctx)
+
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	store, err := common.SchemaHistoryStoreOf(ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	versions, err := store.ListSchemaVersions(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	resp := &experimental.ListSchemaVersionsResponse{
+		Versions: make([]*experimental.SchemaVersion, 0, len(versions)),
+	}
+	for _, version := range versions {
+		resp.Versions = append(resp.Versions, schemaVersionMessage(version, ds, binding))
+	}
+	return resp, nil
+}
+
+func (es *experimentalServer) ReadSchemaVersion(ctx context.Context, req *experimental.ReadSchemaVersionRequest) (*experimental.ReadSchemaVersionResponse, error) {
+	ds := datastoremw.MustFromContext(ctx)
+
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	store, err := common.SchemaHistoryStoreOf(ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	version, err := store.ReadSchemaVersion(ctx, req.Version)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &experimental.ReadSchemaVersionResponse{
+		Version:    schemaVersionMessage(version, ds, binding),
+		SchemaText: version.SchemaText,
+	}, nil
+}
+
+func (es *experimentalServer) DiffSchemaVersions(ctx context.Context, req *experimental.DiffSchemaVersionsRequest) (*experimental.DiffSchemaVersionsResponse, error) {
+	store, err := common.SchemaHistoryStoreOf(datastoremw.MustFromContext(ctx))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	from, err := store.ReadSchemaVersion(ctx, req.FromVersion)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	to, err := store.ReadSchemaVersion(ctx, req.ToVersion)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	changes, err := diffSchemas(from.SchemaText, to.SchemaText)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &experimental.DiffSchemaVersionsResponse{Changes: changes}, nil
+}
+
+func (es *experimentalServer) RestoreSchemaVersion(ctx context.Context, req *experimental.RestoreSchemaVersionRequest) (*experimental.RestoreSchemaVersionResponse, error) {
+	if es.config.SchemaWritesDisabled {
+		return nil, status.Errorf(codes.Unimplemented, "schema writes are disabled")
+	}
+
+	ds := datastoremw.MustFromContext(ctx)
+
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	store, err := common.SchemaHistoryStoreOf(ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	version, err := store.ReadSchemaVersion(ctx, req.Version)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	log.Ctx(ctx).Info().Uint64("version", version.Version).Msg("restoring schema version")
+	writtenAt, err := writeSchema(ctx, version.SchemaText, es.config.SchemaAdditiveOnly, es.config.CaveatsEnabled, es.config.RemovedDefinitionRetention, es.config.AdmissionHook)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &experimental.RestoreSchemaVersionResponse{
+		WrittenAt: zedtoken.NewFromDatastoreRevision(writtenAt, ds, binding),
+	}, nil
+}
+
+func schemaVersionMessage(version datastore.SchemaVersion, ds datastore.Datastore, binding zedtoken.Binding) *experimental.SchemaVersion {
+	return &experimental.SchemaVersion{
+		Version:   version.Version,
+		WrittenAt: zedtoken.NewFromDatastoreRevision(version.Revision, ds, binding),
+		Author:    version.Author,
+		CreatedAt: timestamppb.New(version.CreatedAt),
+	}
+}
+
+// diffSchemas returns the changes to the object definitions of the schema, followed by
+// those to its caveats, each ordered by name.
+func diffSchemas(fromText, toText string) ([]*experimental.SchemaChange, error) {
+	from, err := compileSchemaVersion(fromText)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := compileSchemaVersion(toText)
+	if err != nil {
+		return nil, err
+	}
+
+	fromDefs := make(map[string]*core.NamespaceDefinition, len(from.ObjectDefinitions))
+	for _, def := range from.ObjectDefinitions {
+		fromDefs[def.Name] = def
+	}
+	toDefs := make(map[string]*core.NamespaceDefinition, len(to.ObjectDefinitions))
+	for _, def := range to.ObjectDefinitions {
+		toDefs[def.Name] = def
+	}
+
+	var changes []*experimental.SchemaChange
+	for _, name := range sortedUnion(maps.Keys(fromDefs), maps.Keys(toDefs)) {
+		diff, err := namespace.DiffNamespaces(fromDefs[name], toDefs[name])
+		if err != nil {
+			return nil, err
+		}
+		for _, delta := range diff.Deltas() {
+			changes = append(changes, &experimental.SchemaChange{
+				DefinitionName: name,
+				Kind:           string(delta.Type),
+				RelationName:   delta.RelationName,
+			})
+		}
+	}
+
+	fromCaveats := make(map[string]*core.CaveatDefinition, len(from.CaveatDefinitions))
+	for _, def := range from.CaveatDefinitions {
+		fromCaveats[def.Name] = def
+	}
+	toCaveats := make(map[string]*core.CaveatDefinition, len(to.CaveatDefinitions))
+	for _, def := range to.CaveatDefinitions {
+		toCaveats[def.Name] = def
+	}
+
+	for _, name := range sortedUnion(maps.Keys(fromCaveats), maps.Keys(toCaveats)) {
+		diff, err := caveats.DiffCaveats(fromCaveats[name], toCaveats[name])
+		if err != nil {
+			return nil, err
+		}
+		for _, delta := range diff.Deltas() {
+			changes = append(changes, &experimental.SchemaChange{
+				DefinitionName: name,
+				Kind:           string(delta.Type),
+				ParameterName:  delta.ParameterName,
+			})
+		}
+	}
+	return changes, nil
+}
+
+func compileSchemaVersion(schemaText string) (*compiler.CompiledSchema, error) {
+	emptyDefaultPrefix := ""
+	return compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("schema"),
+		SchemaString: schemaText,
+	}, &emptyDefaultPrefix)
+}
+
+func sortedUnion(left, right []string) []string {
+	names := append(left, right...)
+	sort.Strings(names)
+
+	deduplicated := names[:0]
+	for index, name := range names {
+		if index == 0 || names[index-1] != name {
+			deduplicated = append(deduplicated, name)
+		}
+	}
+	return deduplicated
+}
@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	from := `caveat ipcheck(ip ipaddress) {
+		ip.in_cidr('10.0.0.0/8')
+	}
+
+	caveat expiry(now timestamp) {
+		now < timestamp("2030-01-01T00:00:00Z")
+	}
+
+	definition user {}
+
+	definition document {
+		relation viewer: user
+		relation banned: user
+		permission view = viewer - banned
+	}`
+
+	to := `caveat ipcheck(ip ipaddress, allowed list<ipaddress>) {
+		ip.in_cidr('10.0.0.0/8')
+	}
+
+	definition user {}
+
+	definition document {
+		relation viewer: user | user with ipcheck
+		permission view = viewer
+	}`
+
+	changes, err := diffSchemas(from, to)
+	require.NoError(t, err)
+	require.Equal(t, []*experimental.SchemaChange{
+		{DefinitionName: "document", Kind: "removed-relation", RelationName: "banned"},
+		{DefinitionName: "document", Kind: "changed-permission-implementation", RelationName: "view"},
+		{DefinitionName: "document", Kind: "relation-allowed-type-added", RelationName: "viewer"},
+		{DefinitionName: "expiry", Kind: "caveat-removed"},
+		{DefinitionName: "ipcheck", Kind: "added-parameter", ParameterName: "allowed"},
+	}, changes)
+
+	changes, err = diffSchemas(to, to)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	_, err = diffSchemas(from, "definition {")
+	require.Error(t, err)
+}
@@ -0,0 +1,202 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+// RequestRelationshipSource, if specified in the request header of a WriteRelationships
+// call, is recorded as the source of the relationships it writes, such as the identity
+// provider synchronized by an import, so that they can be deleted together later.
+const RequestRelationshipSource requestmeta.RequestMetadataHeaderKey = "io.spicedb.relationshipsource"
+
+func (es *experimentalServer) DeleteRelationshipsFromSource(ctx context.Context, req *experimental.DeleteRelationshipsFromSourceRequest) (*experimental.DeleteRelationshipsFromSourceResponse, error) {
+	ds := datastoremw.MustFromContext(ctx)
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	var deleted uint64
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		tracker, ok := datastore.UnwrapTransactionAs[common.RelationshipSourceTracker](rwt)
+		if !ok {
+			return common.ErrRelationshipSourcesUnsupported
+		}
+
+		relationships, err := tracker.RelationshipsFromSource(ctx, req.Source)
+		if err != nil {
+			return err
+		}
+
+		// Records can outlive their relationships, such as those deleted by garbage
+		// collection, so only the relationships which still exist are counted.
+		existing, err := existingRelationships(ctx, rwt, relationships)
+		if err != nil {
+			return err
+		}
+
+		updates := make([]*core.RelationTupleUpdate, 0, len(existing))
+		for _, rel := range existing {
+			updates = append(updates, tuple.Delete(rel))
+		}
+		if err := rwt.WriteRelationships(ctx, updates); err != nil {
+			return err
+		}
+
+		deleted = uint64(len(existing))
+		return tracker.SetRelationshipSources(ctx, "", relationships)
+	})
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	log.Ctx(ctx).Info().Str("source", req.Source).Uint64("count", deleted).Msg("deleted relationships from source")
+	return &experimental.DeleteRelationshipsFromSourceResponse{
+		DeletedRelationshipCount: deleted,
+		DeletedAt:                zedtoken.NewFromDatastoreRevision(revision, ds, binding),
+	}, nil
+}
+
+// existingRelationships returns those of the relationships, which are compared without
+// their caveats, which exist.
+func existingRelationships(ctx context.Context, reader datastore.Reader, relationships []*core.RelationTuple) ([]*core.RelationTuple, error) {
+	wanted := util.NewSet[string]()
+	resourceIDsByType := make(map[string]*util.Set[string])
+	for _, rel := range relationships {
+		wanted.Add(tuple.String(rel))
+		resourceIDs, ok := resourceIDsByType[rel.ResourceAndRelation.Namespace]
+		if !ok {
+			resourceIDs = util.NewSet[string]()
+			resourceIDsByType[rel.ResourceAndRelation.Namespace] = resourceIDs
+		}
+		resourceIDs.Add(rel.ResourceAndRelation.ObjectId)
+	}
+
+	var existing []*core.RelationTuple
+	for resourceType, resourceIDs := range resourceIDsByType {
+		remaining := resourceIDs.AsSlice()
+		for len(remaining) > 0 {
+			size := datastore.FilterMaximumIDCount
+			if len(remaining) < size {
+				size = len(remaining)
+			}
+
+			it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+				ResourceType:        resourceType,
+				OptionalResourceIds: remaining[:size],
+			})
+			if err != nil {
+				return nil, err
+			}
+			for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+				if wanted.Has(tuple.String(tpl)) {
+					existing = append(existing, tpl)
+				}
+			}
+			if it.Err() != nil {
+				it.Close()
+				return nil, it.Err()
+			}
+			it.Close()
+
+			remaining = remaining[size:]
+		}
+	}
+	return existing, nil
+}
+
+// recordRelationshipSources records the source of the relationships written by the
+// updates. Records of relationships deleted, or written without a source, are removed, so
+// that deleting the relationships of a source never deletes those since written by others.
+func recordRelationshipSources(ctx context.Context, rwt datastore.ReadWriteTransaction, source string, updates []*core.RelationTupleUpdate) error {
+	tracker, err := trackedRelationshipSources(ctx, rwt, source)
+	if err != nil || tracker == nil {
+		return err
+	}
+
+	var written, cleared []*core.RelationTuple
+	for _, update := range updates {
+		if source != "" && update.Operation != core.RelationTupleUpdate_DELETE {
+			written = append(written, update.Tuple)
+		} else {
+			cleared = append(cleared, update.Tuple)
+		}
+	}
+
+	if len(cleared) > 0 {
+		if err := tracker.SetRelationshipSources(ctx, "", cleared); err != nil {
+			return err
+		}
+	}
+
+	if len(written) == 0 {
+		return nil
+	}
+	return tracker.SetRelationshipSources(ctx, source, written)
+}
+
+// clearRelationshipSources removes the records of the sources of the relationships
+// matching the filter, once they have been deleted.
+func clearRelationshipSources(ctx context.Context, rwt datastore.ReadWriteTransaction, filter *v1.RelationshipFilter) error {
+	tracker, err := trackedRelationshipSources(ctx, rwt, "")
+	if err != nil || tracker == nil {
+		return err
+	}
+	return tracker.ClearRelationshipSources(ctx, filter)
+}
+
+// trackedRelationshipSources returns the tracker of the sources of the relationships of the
+// transaction, or nil if writes without a source need not touch the records, as the
+// datastore records none or is unable to record any.
+func trackedRelationshipSources(ctx context.Context, rwt datastore.ReadWriteTransaction, source string) (common.RelationshipSourceTracker, error) {
+	tracker, ok := datastore.UnwrapTransactionAs[common.RelationshipSourceTracker](rwt)
+	if !ok {
+		if source != "" {
+			return nil, common.ErrRelationshipSourcesUnsupported
+		}
+		return nil, nil
+	}
+	if source != "" {
+		return tracker, nil
+	}
+
+	tracked, err := tracker.HasRelationshipSources(ctx)
+	if err != nil {
+		if errors.Is(err, common.ErrRelationshipSourcesUnsupported) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !tracked {
+		return nil, nil
+	}
+	return tracker, nil
+}
+
+func relationshipSource(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(string(RequestRelationshipSource))
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
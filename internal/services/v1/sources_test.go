@@ -0,0 +1,91 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestDeleteRelationshipsFromSource(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, tombstoneTestSchema, nil, require)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+	idpCtx := metadata.NewIncomingContext(ctx, metadata.Pairs(string(RequestRelationshipSource), "idp"))
+
+	dispatcher := graph.NewLocalOnlyDispatcher(10)
+	permissionServer := NewPermissionsServer(dispatcher, PermissionsServerConfig{}, false)
+	server := NewExperimentalServer(dispatcher, ExperimentalServerConfig{}).(*experimentalServer)
+
+	write := func(ctx context.Context, op v1.RelationshipUpdate_Operation, rels ...string) {
+		updates := make([]*v1.RelationshipUpdate, 0, len(rels))
+		for _, rel := range rels {
+			updates = append(updates, &v1.RelationshipUpdate{
+				Operation:    op,
+				Relationship: tuple.MustToRelationship(tuple.MustParse(rel)),
+			})
+		}
+		_, err := permissionServer.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{Updates: updates})
+		require.NoError(err)
+	}
+
+	write(idpCtx, v1.RelationshipUpdate_OPERATION_TOUCH,
+		"document:first#viewer@user:alice",
+		"document:first#viewer@user:bob",
+		"document:second#viewer@user:alice",
+	)
+	write(ctx, v1.RelationshipUpdate_OPERATION_CREATE, "document:second#viewer@user:carol")
+
+	// Relationships written since by others are no longer from the source.
+	write(ctx, v1.RelationshipUpdate_OPERATION_TOUCH, "document:first#viewer@user:bob")
+	write(ctx, v1.RelationshipUpdate_OPERATION_DELETE, "document:second#viewer@user:alice")
+	require.Equal(uint64(3), countTypeRelationships(t, ds, "document"))
+
+	resp, err := server.DeleteRelationshipsFromSource(ctx, &experimental.DeleteRelationshipsFromSourceRequest{Source: "idp"})
+	require.NoError(err)
+	require.Equal(uint64(1), resp.DeletedRelationshipCount)
+	require.NotNil(resp.DeletedAt)
+	require.Equal(uint64(2), countTypeRelationships(t, ds, "document"))
+
+	resp, err = server.DeleteRelationshipsFromSource(ctx, &experimental.DeleteRelationshipsFromSourceRequest{Source: "idp"})
+	require.NoError(err)
+	require.Zero(resp.DeletedRelationshipCount)
+	require.Equal(uint64(2), countTypeRelationships(t, ds, "document"))
+
+	// Relationships deleted by filter are no longer from the source, even once written
+	// again.
+	write(idpCtx, v1.RelationshipUpdate_OPERATION_TOUCH, "document:third#viewer@user:dave")
+	_, err = permissionServer.DeleteRelationships(ctx, &v1.DeleteRelationshipsRequest{
+		RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document", OptionalResourceId: "third"},
+	})
+	require.NoError(err)
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, tuple.MustParse("document:third#viewer@user:dave"))
+	require.NoError(err)
+
+	// Relationships deleted without the records of their source being removed are not
+	// counted.
+	write(idpCtx, v1.RelationshipUpdate_OPERATION_TOUCH, "document:fourth#viewer@user:erin")
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_DELETE, tuple.MustParse("document:fourth#viewer@user:erin"))
+	require.NoError(err)
+
+	resp, err = server.DeleteRelationshipsFromSource(ctx, &experimental.DeleteRelationshipsFromSourceRequest{Source: "idp"})
+	require.NoError(err)
+	require.Zero(resp.DeletedRelationshipCount)
+	require.Equal(uint64(3), countTypeRelationships(t, ds, "document"))
+}
@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/status"
+
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/middleware/consistency"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+)
+
+func (es *experimentalServer) StreamingCheckPermission(stream experimental.ExperimentalService_StreamingCheckPermissionServer) error {
+	ctx := stream.Context()
+	ds := datastoremw.MustFromContext(ctx)
+
+	g, checkCtx := errgroup.WithContext(ctx)
+	g.SetLimit(es.config.StreamingCheckConcurrency)
+
+	var sendMu sync.Mutex
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// The checks in flight must complete before the stream is closed.
+			_ = g.Wait()
+			return err
+		}
+
+		// The consistency middleware selects the revision of each request as it is
+		// received, so it is read before the next request is.
+		atRevision, checkedAt := consistency.MustRevisionFromContext(ctx)
+		g.Go(func() error {
+			resp := es.streamingCheck(checkCtx, ds.SnapshotReader(atRevision), atRevision, checkedAt, req)
+
+			sendMu.Lock()
+			defer sendMu.Unlock()
+			return stream.Send(resp)
+		})
+	}
+	return g.Wait()
+}
+
+// streamingCheck checks a permission requested on a StreamingCheckPermission call,
+// returning the error of the check as its result if it fails.
+func (es *experimentalServer) streamingCheck(
+	ctx context.Context,
+	ds datastore.Reader,
+	atRevision datastore.Revision,
+	checkedAt *v1.ZedToken,
+	req *experimental.StreamingCheckPermissionRequest,
+) *experimental.StreamingCheckPermissionResponse {
+	resp, err := es.checkPermission(ctx, ds, atRevision, checkedAt, req)
+	if err != nil {
+		return &experimental.StreamingCheckPermissionResponse{
+			CorrelationId: req.CorrelationId,
+			Result: &experimental.StreamingCheckPermissionResponse_Error{
+				Error: status.Convert(rewriteError(ctx, err)).Proto(),
+			},
+		}
+	}
+
+	return &experimental.StreamingCheckPermissionResponse{
+		CorrelationId: req.CorrelationId,
+		Result:        &experimental.StreamingCheckPermissionResponse_Response{Response: resp},
+	}
+}
+
+func (es *experimentalServer) checkPermission(
+	ctx context.Context,
+	ds datastore.Reader,
+	atRevision datastore.Revision,
+	checkedAt *v1.ZedToken,
+	req *experimental.StreamingCheckPermissionRequest,
+) (*v1.CheckPermissionResponse, error) {
+	caveatContext, err := getCaveatContext(ctx, req.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	cr, _, err := computeCheckPermission(ctx, es.dispatch, ds, atRevision, &v1.CheckPermissionRequest{
+		Resource:   req.Resource,
+		Permission: req.Permission,
+		Subject:    req.Subject,
+		Context:    req.Context,
+	}, caveatContext, es.config.MaximumAPIDepth, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkPermissionResponse(cr, checkedAt), nil
+}
@@ -0,0 +1,312 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func (es *experimentalServer) ListNamespaceTombstones(ctx context.Context, _ *experimental.ListNamespaceTombstonesRequest) (*experimental.ListNamespaceTombstonesResponse, error) {
+	ds := datastoremw.MustFromContext(ctx)
+	store, err := tombstoneStore(ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	tombstones, err := store.ListNamespaceTombstones(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	resp := &experimental.ListNamespaceTombstonesResponse{
+		Tombstones: make([]*experimental.NamespaceTombstone, 0, len(tombstones)),
+	}
+	for _, tombstone := range tombstones {
+		resp.Tombstones = append(resp.Tombstones, &experimental.NamespaceTombstone{
+			Namespace:  tombstone.Namespace,
+			RetainedAt: zedtoken.NewFromDatastoreRevision(tombstone.Revision, ds, binding),
+			DeletedAt:  timestamppb.New(tombstone.DeletedAt),
+			ExpiresAt:  timestamppb.New(tombstone.ExpiresAt),
+		})
+	}
+	return resp, nil
+}
+
+func (es *experimentalServer) RestoreNamespace(ctx context.Context, req *experimental.RestoreNamespaceRequest) (*experimental.RestoreNamespaceResponse, error) {
+	if es.config.SchemaWritesDisabled {
+		return nil, status.Errorf(codes.Unimplemented, "schema writes are disabled")
+	}
+
+	ds := datastoremw.MustFromContext(ctx)
+	store, err := tombstoneStore(ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	tombstone, err := store.ReadNamespaceTombstone(ctx, req.Namespace)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	def, _, err := ds.SnapshotReader(tombstone.Revision).ReadNamespace(ctx, req.Namespace)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	log.Ctx(ctx).Info().Str("namespace", req.Namespace).Msg("restoring removed definition")
+	writtenAt, err := es.restoreDefinition(ctx, ds, def)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	restored, relationshipsWrittenAt, err := es.restoreRelationships(ctx, ds, tombstone)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+	if relationshipsWrittenAt != nil {
+		writtenAt = relationshipsWrittenAt
+	}
+
+	releaseRemovedDefinitions(ctx, ds, []string{req.Namespace})
+
+	return &experimental.RestoreNamespaceResponse{
+		RestoredRelationshipCount: restored,
+		WrittenAt:                 zedtoken.NewFromDatastoreRevision(writtenAt, ds, binding),
+	}, nil
+}
+
+// restoreDefinition writes the schema with the definition added back, returning the
+// revision at which it was written. A definition which is already in the schema, as left
+// by an interrupted restore, is not written again.
+func (es *experimentalServer) restoreDefinition(ctx context.Context, ds datastore.Datastore, def *core.NamespaceDefinition) (datastore.Revision, error) {
+	headRevision, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := ds.SnapshotReader(headRevision)
+	existing, err := reader.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	caveats, err := reader.ListCaveats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := make([]compiler.SchemaDefinition, 0, len(caveats)+len(existing)+1)
+	for _, caveat := range caveats {
+		definitions = append(definitions, caveat)
+	}
+	for _, existingDef := range existing {
+		if existingDef.Name != def.Name {
+			definitions = append(definitions, existingDef)
+			continue
+		}
+
+		diff, err := namespace.DiffNamespaces(existingDef, def)
+		if err != nil {
+			return nil, err
+		}
+		if len(diff.Deltas()) > 0 {
+			return nil, spiceerrors.WithCodeAndExtendedReason(
+				fmt.Errorf("object definition `%s` has been redefined since it was removed", def.Name),
+				codes.FailedPrecondition,
+				spiceerrors.ReasonFailedPrecondition,
+				nil,
+			)
+		}
+		return headRevision, nil
+	}
+	definitions = append(definitions, def)
+
+	schemaText, _ := generator.GenerateSchema(definitions)
+	return writeSchema(ctx, schemaText, es.config.SchemaAdditiveOnly, es.config.CaveatsEnabled, es.config.RemovedDefinitionRetention, es.config.AdmissionHook)
+}
+
+// restoreRelationships writes back the relationships of the namespace of the tombstone
+// as of its revision, in batches of separate transactions. It returns the number written
+// and the revision at which the last batch was, or nil if there were none.
+func (es *experimentalServer) restoreRelationships(ctx context.Context, ds datastore.Datastore, tombstone common.NamespaceTombstone) (uint64, datastore.Revision, error) {
+	iter, err := ds.SnapshotReader(tombstone.Revision).QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: tombstone.Namespace})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer iter.Close()
+
+	var restored uint64
+	var writtenAt datastore.Revision
+	batch := make([]*core.RelationTupleUpdate, 0, es.config.JobBatchSize)
+	writeBatch := func() error {
+		revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships(ctx, batch)
+		})
+		if err != nil {
+			return fmt.Errorf("unable to restore relationships: %w", err)
+		}
+
+		restored += uint64(len(batch))
+		writtenAt = revision
+		batch = batch[:0]
+		return nil
+	}
+
+	for rel := iter.Next(); rel != nil; rel = iter.Next() {
+		batch = append(batch, tuple.Touch(rel))
+		if len(batch) == es.config.JobBatchSize {
+			if err := writeBatch(); err != nil {
+				return restored, nil, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return restored, nil, err
+	}
+
+	if len(batch) > 0 {
+		if err := writeBatch(); err != nil {
+			return restored, nil, err
+		}
+	}
+	return restored, writtenAt, nil
+}
+
+// retainRemovedDefinitions retains the definitions which writing the compiled schema
+// would remove, pinning a revision from before the write for each and recording its
+// tombstone, and returns their names. Relationships written after that revision are not
+// retained.
+func retainRemovedDefinitions(ctx context.Context, ds datastore.Datastore, compiled *compiler.CompiledSchema, retention time.Duration) ([]string, error) {
+	headRevision, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := ds.SnapshotReader(headRevision).ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := util.NewSet[string]()
+	for _, def := range compiled.ObjectDefinitions {
+		kept.Add(def.Name)
+	}
+
+	var removed []string
+	for _, def := range existing {
+		if !kept.Has(def.Name) {
+			removed = append(removed, def.Name)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	store, err := tombstoneStore(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	pinner, err := common.RevisionPinnerOf(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	// The retained revision is that of an empty transaction, rather than the head
+	// revision, so that it is the revision of a write, which every datastore can read
+	// back exactly.
+	retainedRevision, err := ds.ReadWriteTx(ctx, func(datastore.ReadWriteTransaction) error { return nil })
+	if err != nil {
+		return nil, err
+	}
+
+	deletedAt := time.Now()
+	expiresAt := deletedAt.Add(retention)
+
+	retained := make([]string, 0, len(removed))
+	for _, name := range removed {
+		if err := pinner.PinRevision(ctx, common.NamespaceTombstonePinName(name), retainedRevision, expiresAt); err != nil {
+			releaseRemovedDefinitions(ctx, ds, retained)
+			return nil, err
+		}
+		retained = append(retained, name)
+
+		if err := store.WriteNamespaceTombstone(ctx, common.NamespaceTombstone{
+			Namespace: name,
+			Revision:  retainedRevision,
+			DeletedAt: deletedAt,
+			ExpiresAt: expiresAt,
+		}); err != nil {
+			releaseRemovedDefinitions(ctx, ds, retained)
+			return nil, err
+		}
+	}
+
+	log.Ctx(ctx).Info().Strs("namespaces", retained).Time("expiresAt", expiresAt).Msg("retaining removed definitions")
+	return retained, nil
+}
+
+// releaseRemovedDefinitions deletes the tombstones of the named definitions and releases
+// their pinned revisions. Failures are logged rather than returned, as the tombstones
+// and pins expire regardless.
+func releaseRemovedDefinitions(ctx context.Context, ds datastore.Datastore, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	store, err := tombstoneStore(ds)
+	if err != nil {
+		return
+	}
+
+	pinner, err := common.RevisionPinnerOf(ds)
+	if err != nil {
+		return
+	}
+
+	for _, name := range names {
+		if err := store.DeleteNamespaceTombstone(ctx, name); err != nil && !errors.Is(err, common.ErrNamespaceTombstoneNotFound) {
+			log.Ctx(ctx).Warn().Err(err).Str("namespace", name).Msg("failed to delete namespace tombstone")
+		}
+		if err := pinner.ReleasePinnedRevision(ctx, common.NamespaceTombstonePinName(name)); err != nil && !errors.As(err, &datastore.ErrPinnedRevisionNotFound{}) {
+			log.Ctx(ctx).Warn().Err(err).Str("namespace", name).Msg("failed to release pinned revision of namespace tombstone")
+		}
+	}
+}
+
+func tombstoneStore(ds datastore.Datastore) (common.NamespaceTombstoneStore, error) {
+	store, ok := datastore.UnwrapAs[common.NamespaceTombstoneStore](ds)
+	if !ok {
+		return nil, common.ErrNamespaceTombstonesUnsupported
+	}
+	return store, nil
+}
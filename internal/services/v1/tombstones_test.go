@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	experimental "github.com/authzed/spicedb/pkg/proto/experimental/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const tombstoneTestSchema = `
+definition user {}
+
+definition document {
+	relation viewer: user
+}`
+
+func TestRemovedDefinitionRetainedAndRestored(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, tombstoneTestSchema, []*core.RelationTuple{
+		tuple.MustParse("document:first#viewer@user:alice"),
+		tuple.MustParse("document:second#viewer@user:bob"),
+		tuple.MustParse("document:third#viewer@user:alice"),
+	}, require)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	schemaServer := NewSchemaServer(false, false, time.Hour, nil)
+	server := NewExperimentalServer(graph.NewLocalOnlyDispatcher(10), ExperimentalServerConfig{
+		RemovedDefinitionRetention: time.Hour,
+		JobBatchSize:               2,
+	}).(*experimentalServer)
+
+	_, err = schemaServer.WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: "definition user {}"})
+	require.NoError(err)
+	require.Zero(countTypeRelationships(t, ds, "document"))
+
+	listed, err := server.ListNamespaceTombstones(ctx, &experimental.ListNamespaceTombstonesRequest{})
+	require.NoError(err)
+	require.Len(listed.Tombstones, 1)
+	require.Equal("document", listed.Tombstones[0].Namespace)
+	require.True(listed.Tombstones[0].ExpiresAt.AsTime().After(time.Now()))
+
+	restored, err := server.RestoreNamespace(ctx, &experimental.RestoreNamespaceRequest{Namespace: "document"})
+	require.NoError(err)
+	require.Equal(uint64(3), restored.RestoredRelationshipCount)
+	require.NotNil(restored.WrittenAt)
+	require.Equal(uint64(3), countTypeRelationships(t, ds, "document"))
+
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	_, _, err = ds.SnapshotReader(headRevision).ReadNamespace(ctx, "document")
+	require.NoError(err)
+
+	listed, err = server.ListNamespaceTombstones(ctx, &experimental.ListNamespaceTombstonesRequest{})
+	require.NoError(err)
+	require.Empty(listed.Tombstones)
+
+	_, err = server.RestoreNamespace(ctx, &experimental.RestoreNamespaceRequest{Namespace: "document"})
+	require.Equal(codes.NotFound, status.Code(err))
+}
+
+func TestRemovedDefinitionNotRetained(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, tombstoneTestSchema, []*core.RelationTuple{
+		tuple.MustParse("document:first#viewer@user:alice"),
+	}, require)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	// Without retention, a definition with relationships cannot be removed.
+	_, err = NewSchemaServer(false, false, 0, nil).WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: "definition user {}"})
+	require.Equal(codes.InvalidArgument, status.Code(err))
+
+	// Nor can one referenced by relationships of another, even with retention.
+	_, err = NewSchemaServer(false, false, time.Hour, nil).WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: "definition document {}"})
+	require.Error(err)
+	require.Equal(uint64(1), countTypeRelationships(t, ds, "document"))
+
+	server := NewExperimentalServer(graph.NewLocalOnlyDispatcher(10), ExperimentalServerConfig{}).(*experimentalServer)
+	listed, err := server.ListNamespaceTombstones(ctx, &experimental.ListNamespaceTombstonesRequest{})
+	require.NoError(err)
+	require.Empty(listed.Tombstones)
+}
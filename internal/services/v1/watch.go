@@ -2,12 +2,16 @@ package v1
 
 import (
 	"errors"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
 	"github.com/authzed/spicedb/internal/services/shared"
@@ -18,6 +22,38 @@ import (
 	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
+var (
+	watchActiveStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "spicedb",
+		Subsystem: "watch",
+		Name:      "active_streams",
+		Help:      "number of Watch API streams currently open",
+	})
+
+	watchEventsEmitted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "watch",
+		Name:      "events_emitted_total",
+		Help:      "total number of relationship updates sent to Watch API streams",
+	})
+
+	watchBufferOccupancy = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "spicedb",
+		Subsystem: "watch",
+		Name:      "buffer_occupancy_ratio",
+		Help:      "fraction of a Watch stream's datastore change buffer which was full when a change was received; values near 1 indicate a slow consumer",
+		Buckets:   []float64{0, .1, .25, .5, .75, .9, 1},
+	})
+
+	watchEmissionLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "spicedb",
+		Subsystem: "watch",
+		Name:      "emission_lag_seconds",
+		Help:      "time between a change being committed to the datastore and it being sent to a Watch stream; only reported by datastores which report commit times",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	})
+)
+
 type watchServer struct {
 	v1.UnimplementedWatchServiceServer
 	shared.WithStreamServiceSpecificInterceptor
@@ -42,16 +78,23 @@ func (ws *watchServer) Watch(req *v1.WatchRequest, stream v1.WatchService_WatchS
 		objectTypesMap[objectType] = struct{}{}
 	}
 
+	binding, err := zedtoken.BindingOf(ctx, ds)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to start watch: %s", err)
+	}
+
 	var afterRevision datastore.Revision
 	if req.OptionalStartCursor != nil && req.OptionalStartCursor.Token != "" {
-		decodedRevision, err := zedtoken.DecodeRevision(req.OptionalStartCursor, ds)
+		decodedRevision, err := zedtoken.DecodeDatastoreRevision(req.OptionalStartCursor, ds, binding)
 		if err != nil {
+			if errors.As(err, &zedtoken.ErrMismatchedDatastore{}) {
+				return rewriteError(ctx, err)
+			}
 			return status.Errorf(codes.InvalidArgument, "failed to decode start revision: %s", err)
 		}
 
 		afterRevision = decodedRevision
 	} else {
-		var err error
 		afterRevision, err = ds.OptimizedRevision(ctx)
 		if err != nil {
 			return status.Errorf(codes.Unavailable, "failed to start watch: %s", err)
@@ -62,19 +105,31 @@ func (ws *watchServer) Watch(req *v1.WatchRequest, stream v1.WatchService_WatchS
 		DispatchCount: 1,
 	})
 
+	watchActiveStreams.Inc()
+	defer watchActiveStreams.Dec()
+
 	updates, errchan := ds.Watch(ctx, afterRevision)
 	for {
 		select {
 		case update, ok := <-updates:
 			if ok {
+				if capacity := cap(updates); capacity > 0 {
+					watchBufferOccupancy.Observe(float64(len(updates)) / float64(capacity))
+				}
+
 				filtered := filterUpdates(objectTypesMap, update.Changes)
 				if len(filtered) > 0 {
 					if err := stream.Send(&v1.WatchResponse{
 						Updates:        filtered,
-						ChangesThrough: zedtoken.NewFromRevision(update.Revision),
+						ChangesThrough: zedtoken.NewFromDatastoreRevision(update.Revision, ds, binding),
 					}); err != nil {
 						return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
 					}
+
+					watchEventsEmitted.Add(float64(len(filtered)))
+					if committedAt, ok := common.RevisionCommitTime(ds, update.Revision); ok {
+						watchEmissionLag.Observe(time.Since(committedAt).Seconds())
+					}
 				}
 			}
 		case err := <-errchan:
@@ -24,6 +24,10 @@ func NewValidatingDatastore(delegate datastore.Datastore) datastore.Datastore {
 	return validatingDatastore{Datastore: delegate}
 }
 
+func (vd validatingDatastore) Unwrap() datastore.Datastore {
+	return vd.Datastore
+}
+
 func (vd validatingDatastore) SnapshotReader(revision datastore.Revision) datastore.Reader {
 	return validatingSnapshotReader{vd.Datastore.SnapshotReader(revision)}
 }
@@ -158,6 +162,10 @@ type validatingReadWriteTransaction struct {
 	delegate datastore.ReadWriteTransaction
 }
 
+func (vrwt validatingReadWriteTransaction) Unwrap() datastore.ReadWriteTransaction {
+	return vrwt.delegate
+}
+
 func (vrwt validatingReadWriteTransaction) WriteNamespaces(ctx context.Context, newConfigs ...*core.NamespaceDefinition) error {
 	for _, newConfig := range newConfigs {
 		if err := newConfig.Validate(); err != nil {
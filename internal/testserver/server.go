@@ -11,7 +11,9 @@ import (
 	"github.com/authzed/spicedb/internal/dispatch/graph"
 	"github.com/authzed/spicedb/internal/middleware/consistency"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/middleware/querystats"
 	"github.com/authzed/spicedb/internal/middleware/servicespecific"
+	"github.com/authzed/spicedb/pkg/admission"
 	"github.com/authzed/spicedb/pkg/cmd/server"
 	"github.com/authzed/spicedb/pkg/cmd/util"
 	"github.com/authzed/spicedb/pkg/datastore"
@@ -22,6 +24,9 @@ import (
 type ServerConfig struct {
 	MaxUpdatesPerWrite    uint16
 	MaxPreconditionsCount uint16
+	AdmissionHooks        []admission.Hook
+	CardinalitySoftLimits []string
+	CardinalityHardLimits []string
 }
 
 // NewTestServer creates a new test server, using defaults for the config.
@@ -50,6 +55,20 @@ func NewTestServerWithConfig(require *require.Assertions,
 	emptyDS, err := memdb.NewMemdbDatastore(0, revisionQuantization, gcWindow)
 	require.NoError(err)
 	ds, revision := dsInitFunc(emptyDS, require)
+	conn, cleanup := runTestServer(require, ds, schemaPrefixRequired, config)
+	return conn, cleanup, ds, revision
+}
+
+// NewTestServerForDatastore creates a new test server over the provided datastore, which
+// may be of any engine, using defaults for the config.
+func NewTestServerForDatastore(require *require.Assertions, ds datastore.Datastore) (*grpc.ClientConn, func()) {
+	return runTestServer(require, ds, false, ServerConfig{
+		MaxUpdatesPerWrite:    1000,
+		MaxPreconditionsCount: 1000,
+	})
+}
+
+func runTestServer(require *require.Assertions, ds datastore.Datastore, schemaPrefixRequired bool, config ServerConfig) (*grpc.ClientConn, func()) {
 	srv, err := server.NewConfigWithOptions(
 		server.WithDatastore(ds),
 		server.WithDispatcher(graph.NewLocalOnlyDispatcher(10)),
@@ -69,15 +88,20 @@ func NewTestServerWithConfig(require *require.Assertions,
 		server.WithMetricsAPI(util.HTTPServerConfig{Enabled: false}),
 		server.WithDispatchServer(util.GRPCServerConfig{Enabled: false}),
 		server.WithExperimentalCaveatsEnabled(true),
+		server.SetAdmissionHooks(config.AdmissionHooks),
+		server.SetCardinalitySoftLimits(config.CardinalitySoftLimits),
+		server.SetCardinalityHardLimits(config.CardinalityHardLimits),
 	).Complete()
 	require.NoError(err)
 	srv.SetMiddleware([]grpc.UnaryServerInterceptor{
 		logging.UnaryServerInterceptor(),
+		querystats.UnaryServerInterceptor(),
 		datastoremw.UnaryServerInterceptor(ds),
 		consistency.UnaryServerInterceptor(),
 		servicespecific.UnaryServerInterceptor,
 	}, []grpc.StreamServerInterceptor{
 		logging.StreamServerInterceptor(),
+		querystats.StreamServerInterceptor(),
 		datastoremw.StreamServerInterceptor(ds),
 		consistency.StreamServerInterceptor(),
 		servicespecific.StreamServerInterceptor,
@@ -96,5 +120,5 @@ func NewTestServerWithConfig(require *require.Assertions,
 			require.NoError(conn.Close())
 		}
 		cancel()
-	}, ds, revision
+	}
 }
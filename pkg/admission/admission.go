@@ -0,0 +1,149 @@
+// Package admission defines the hooks which admit the mutations of SpiceDB before they
+// are committed, enabling invariants specific to an organization to be enforced
+// without changes to the write path. Hooks run in-process, passed to the server as Go
+// values, or in an external service called over gRPC.
+package admission
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	admissionv1 "github.com/authzed/spicedb/pkg/proto/admission/v1"
+)
+
+// Hook admits or rejects mutations before they are committed. A hook may annotate an
+// admitted mutation, and the annotations are returned to the caller.
+type Hook interface {
+	// AdmitRelationships is called with the updates of a WriteRelationships call before
+	// they are written.
+	AdmitRelationships(ctx context.Context, req *admissionv1.AdmitRelationshipsRequest) (*admissionv1.AdmissionResponse, error)
+
+	// AdmitSchema is called with a schema which has been compiled and validated, before
+	// it is written.
+	AdmitSchema(ctx context.Context, req *admissionv1.AdmitSchemaRequest) (*admissionv1.AdmissionResponse, error)
+}
+
+// Hooks admits a mutation if every one of its hooks admits it, calling them in order
+// until one rejects it. Annotations of later hooks replace those of earlier ones.
+type Hooks []Hook
+
+func (hs Hooks) AdmitRelationships(ctx context.Context, req *admissionv1.AdmitRelationshipsRequest) (*admissionv1.AdmissionResponse, error) {
+	return hs.admit(func(hook Hook) (*admissionv1.AdmissionResponse, error) {
+		return hook.AdmitRelationships(ctx, req)
+	})
+}
+
+func (hs Hooks) AdmitSchema(ctx context.Context, req *admissionv1.AdmitSchemaRequest) (*admissionv1.AdmissionResponse, error) {
+	return hs.admit(func(hook Hook) (*admissionv1.AdmissionResponse, error) {
+		return hook.AdmitSchema(ctx, req)
+	})
+}
+
+func (hs Hooks) admit(call func(Hook) (*admissionv1.AdmissionResponse, error)) (*admissionv1.AdmissionResponse, error) {
+	admitted := &admissionv1.AdmissionResponse{Allowed: true}
+	for _, hook := range hs {
+		resp, err := call(hook)
+		if err != nil {
+			return nil, err
+		}
+		if !resp.Allowed {
+			return resp, nil
+		}
+
+		for key, value := range resp.Annotations {
+			if admitted.Annotations == nil {
+				admitted.Annotations = make(map[string]string, len(resp.Annotations))
+			}
+			admitted.Annotations[key] = value
+		}
+	}
+	return admitted, nil
+}
+
+// ErrMutationRejected occurs when an admission hook rejects a mutation.
+type ErrMutationRejected struct {
+	error
+	reason string
+}
+
+// Reason is the reason given by the hook for rejecting the mutation.
+func (err ErrMutationRejected) Reason() string {
+	return err.reason
+}
+
+// DetailsMetadata returns the metadata for details for this error.
+func (err ErrMutationRejected) DetailsMetadata() map[string]string {
+	return map[string]string{
+		"reason": err.reason,
+	}
+}
+
+// NewMutationRejectedErr constructs a new mutation rejected error.
+func NewMutationRejectedErr(reason string) error {
+	return ErrMutationRejected{
+		error:  fmt.Errorf("mutation rejected by admission hook: %s", reason),
+		reason: reason,
+	}
+}
+
+// WebhookConfig configures a hook calling an external AdmissionService.
+type WebhookConfig struct {
+	// Timeout bounds each call to the service. Zero leaves calls bounded only by the
+	// deadline of the mutation.
+	Timeout time.Duration
+
+	// FailOpen admits mutations when the service cannot be reached or fails, rather
+	// than failing them.
+	FailOpen bool
+}
+
+type webhook struct {
+	client admissionv1.AdmissionServiceClient
+	config WebhookConfig
+}
+
+// NewWebhook returns a hook calling the AdmissionService on the connection.
+func NewWebhook(conn grpc.ClientConnInterface, config WebhookConfig) Hook {
+	return &webhook{client: admissionv1.NewAdmissionServiceClient(conn), config: config}
+}
+
+func (w *webhook) AdmitRelationships(ctx context.Context, req *admissionv1.AdmitRelationshipsRequest) (*admissionv1.AdmissionResponse, error) {
+	return w.call(ctx, func(ctx context.Context) (*admissionv1.AdmissionResponse, error) {
+		return w.client.AdmitRelationships(ctx, req)
+	})
+}
+
+func (w *webhook) AdmitSchema(ctx context.Context, req *admissionv1.AdmitSchemaRequest) (*admissionv1.AdmissionResponse, error) {
+	return w.call(ctx, func(ctx context.Context) (*admissionv1.AdmissionResponse, error) {
+		return w.client.AdmitSchema(ctx, req)
+	})
+}
+
+func (w *webhook) call(ctx context.Context, call func(context.Context) (*admissionv1.AdmissionResponse, error)) (*admissionv1.AdmissionResponse, error) {
+	callCtx := ctx
+	if w.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, w.config.Timeout)
+		defer cancel()
+	}
+
+	resp, err := call(callCtx)
+	if err == nil {
+		return resp, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if w.config.FailOpen {
+		log.Ctx(ctx).Warn().Err(err).Msg("admission webhook failed; admitting the mutation")
+		return &admissionv1.AdmissionResponse{Allowed: true}, nil
+	}
+	return nil, status.Errorf(codes.Unavailable, "admission webhook failed: %s", status.Convert(err).Message())
+}
@@ -0,0 +1,142 @@
+package admission
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	admissionv1 "github.com/authzed/spicedb/pkg/proto/admission/v1"
+)
+
+type staticHook struct {
+	admissionv1.UnimplementedAdmissionServiceServer
+
+	resp  *admissionv1.AdmissionResponse
+	err   error
+	delay time.Duration
+	calls int
+}
+
+func (h *staticHook) AdmitRelationships(ctx context.Context, _ *admissionv1.AdmitRelationshipsRequest) (*admissionv1.AdmissionResponse, error) {
+	return h.admit(ctx)
+}
+
+func (h *staticHook) AdmitSchema(ctx context.Context, _ *admissionv1.AdmitSchemaRequest) (*admissionv1.AdmissionResponse, error) {
+	return h.admit(ctx)
+}
+
+func (h *staticHook) admit(ctx context.Context) (*admissionv1.AdmissionResponse, error) {
+	h.calls++
+	if h.delay > 0 {
+		select {
+		case <-time.After(h.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return h.resp, h.err
+}
+
+func allow(annotations map[string]string) *staticHook {
+	return &staticHook{resp: &admissionv1.AdmissionResponse{Allowed: true, Annotations: annotations}}
+}
+
+func TestHooks(t *testing.T) {
+	ctx := context.Background()
+	req := &admissionv1.AdmitSchemaRequest{Schema: "definition user {}"}
+
+	resp, err := Hooks{}.AdmitSchema(ctx, req)
+	require.NoError(t, err)
+	require.True(t, resp.Allowed)
+
+	resp, err = Hooks{allow(map[string]string{"a": "1", "b": "1"}), allow(nil), allow(map[string]string{"b": "2"})}.AdmitSchema(ctx, req)
+	require.NoError(t, err)
+	require.True(t, resp.Allowed)
+	require.Equal(t, map[string]string{"a": "1", "b": "2"}, resp.Annotations)
+
+	rejecting := &staticHook{resp: &admissionv1.AdmissionResponse{Reason: "no"}}
+	last := allow(nil)
+	resp, err = Hooks{allow(nil), rejecting, last}.AdmitRelationships(ctx, &admissionv1.AdmitRelationshipsRequest{})
+	require.NoError(t, err)
+	require.False(t, resp.Allowed)
+	require.Equal(t, "no", resp.Reason)
+	require.Zero(t, last.calls)
+
+	failing := &staticHook{err: context.DeadlineExceeded}
+	_, err = Hooks{failing, last}.AdmitRelationships(ctx, &admissionv1.AdmitRelationshipsRequest{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Zero(t, last.calls)
+}
+
+func TestMutationRejectedErr(t *testing.T) {
+	err := NewMutationRejectedErr("owners are required")
+	require.ErrorAs(t, err, &ErrMutationRejected{})
+	require.Equal(t, "owners are required", err.(ErrMutationRejected).Reason())
+	require.Contains(t, err.Error(), "owners are required")
+}
+
+func dialWebhook(t *testing.T, server *staticHook) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	admissionv1.RegisterAdmissionServiceServer(srv, server)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestWebhook(t *testing.T) {
+	ctx := context.Background()
+	req := &admissionv1.AdmitRelationshipsRequest{}
+
+	conn := dialWebhook(t, allow(map[string]string{"ticket": "SEC-1"}))
+	resp, err := NewWebhook(conn, WebhookConfig{}).AdmitRelationships(ctx, req)
+	require.NoError(t, err)
+	require.True(t, resp.Allowed)
+	require.Equal(t, map[string]string{"ticket": "SEC-1"}, resp.Annotations)
+
+	conn = dialWebhook(t, &staticHook{resp: &admissionv1.AdmissionResponse{Reason: "frozen"}})
+	resp, err = NewWebhook(conn, WebhookConfig{}).AdmitSchema(ctx, &admissionv1.AdmitSchemaRequest{})
+	require.NoError(t, err)
+	require.False(t, resp.Allowed)
+	require.Equal(t, "frozen", resp.Reason)
+
+	for _, server := range []*staticHook{
+		{err: status.Error(codes.Internal, "broken")},
+		{resp: &admissionv1.AdmissionResponse{Allowed: true}, delay: time.Second},
+	} {
+		conn = dialWebhook(t, server)
+		config := WebhookConfig{Timeout: 50 * time.Millisecond}
+
+		_, err = NewWebhook(conn, config).AdmitRelationships(ctx, req)
+		require.Equal(t, codes.Unavailable, status.Code(err))
+
+		config.FailOpen = true
+		resp, err = NewWebhook(conn, config).AdmitRelationships(ctx, req)
+		require.NoError(t, err)
+		require.True(t, resp.Allowed)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = NewWebhook(conn, WebhookConfig{FailOpen: true}).AdmitRelationships(canceled, req)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,234 @@
+package balancer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/pkg/consistent"
+)
+
+// BreakerState is the state of the circuit breaker of a backend.
+type BreakerState string
+
+const (
+	// BreakerClosed is the state of a backend to which requests are routed.
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen is the state of a backend which repeatedly failed, whose keys are
+	// routed to the next backends of the hashring until it is probed again.
+	BreakerOpen BreakerState = "open"
+)
+
+// CircuitBreakerConfig configures the circuit breakers of the backends of the
+// hashring. While the breaker of a backend is open, its keys are routed to the next
+// backends of the hashring, trading the loss of cache hits for availability. If every
+// candidate for a key is open, the key is routed to its usual backends regardless.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed requests after which the
+	// breaker of a backend opens.
+	FailureThreshold uint32
+
+	// OpenDuration is how long a breaker stays open before a single request is sent to
+	// the backend to probe it, closing the breaker if it succeeds.
+	OpenDuration time.Duration
+
+	// OnStateChange, if set, is called whenever the breaker of a backend opens or
+	// closes, with the address of the backend.
+	OnStateChange func(backend string, state BreakerState)
+}
+
+// DefaultCircuitBreakerConfig is the circuit breaker configuration of the dispatch
+// hashring.
+var DefaultCircuitBreakerConfig = CircuitBreakerConfig{
+	FailureThreshold: 5,
+	OpenDuration:     30 * time.Second,
+}
+
+var (
+	breakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "dispatch",
+		Name:      "hashring_breaker_transitions_total",
+		Help:      "total number of times the circuit breaker of a dispatch backend opened or closed",
+	}, []string{"state"})
+
+	openBreakers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "spicedb",
+		Subsystem: "dispatch",
+		Name:      "hashring_open_breakers",
+		Help:      "number of dispatch backends whose circuit breaker is open",
+	})
+)
+
+// isBackendFailure returns whether the error of a request indicates that its backend
+// is unavailable, rather than that the request itself failed.
+func isBackendFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+type backendBreaker struct {
+	state       BreakerState
+	failures    uint32
+	openUntil   time.Time
+	probing     bool
+	lastUpdated time.Time
+}
+
+// circuitBreakers tracks the breakers of the backends of every hashring built by a
+// builder, keyed by the address of the backend, so that they outlive the pickers
+// rebuilt as connections change state.
+type circuitBreakers struct {
+	sync.Mutex
+	config   CircuitBreakerConfig
+	backends map[string]*backendBreaker
+	now      func() time.Time
+}
+
+func newCircuitBreakers(config CircuitBreakerConfig) *circuitBreakers {
+	return &circuitBreakers{
+		config:   config,
+		backends: make(map[string]*backendBreaker),
+		now:      time.Now,
+	}
+}
+
+// available returns whether requests may be routed to the backend: its breaker is
+// closed, or it is due for a probe which no other request has claimed.
+func (cb *circuitBreakers) available(backend string) bool {
+	cb.Lock()
+	defer cb.Unlock()
+
+	breaker, ok := cb.backends[backend]
+	if !ok || breaker.state == BreakerClosed {
+		return true
+	}
+	return !breaker.probing && !cb.now().Before(breaker.openUntil)
+}
+
+// picked records that a request was routed to the backend, claiming the probe of an
+// open breaker.
+func (cb *circuitBreakers) picked(backend string) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	if breaker, ok := cb.backends[backend]; ok && breaker.state == BreakerOpen {
+		breaker.probing = true
+	}
+}
+
+// done records the outcome of a request routed to the backend.
+func (cb *circuitBreakers) done(backend string, err error) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	breaker, ok := cb.backends[backend]
+	if !ok {
+		breaker = &backendBreaker{state: BreakerClosed}
+		cb.backends[backend] = breaker
+	}
+	breaker.lastUpdated = cb.now()
+
+	if !isBackendFailure(err) {
+		breaker.failures = 0
+		if breaker.state == BreakerOpen && breaker.probing {
+			breaker.state, breaker.probing = BreakerClosed, false
+			cb.transitioned(backend, BreakerClosed)
+		}
+		return
+	}
+
+	switch {
+	case breaker.state == BreakerOpen && breaker.probing:
+		// The probe failed, so wait for another one.
+		breaker.probing = false
+		breaker.openUntil = cb.now().Add(cb.config.OpenDuration)
+
+	case breaker.state == BreakerClosed:
+		breaker.failures++
+		if breaker.failures >= cb.config.FailureThreshold {
+			breaker.state = BreakerOpen
+			breaker.openUntil = cb.now().Add(cb.config.OpenDuration)
+			cb.transitioned(backend, BreakerOpen)
+		}
+	}
+}
+
+func (cb *circuitBreakers) transitioned(backend string, state BreakerState) {
+	breakerTransitions.WithLabelValues(string(state)).Inc()
+	if state == BreakerOpen {
+		openBreakers.Inc()
+		logger.Warningf("consistentHashringPicker: opened circuit breaker of backend %s; routing its keys to other backends", backend)
+	} else {
+		openBreakers.Dec()
+		logger.Infof("consistentHashringPicker: closed circuit breaker of backend %s", backend)
+	}
+
+	if cb.config.OnStateChange != nil {
+		cb.config.OnStateChange(backend, state)
+	}
+}
+
+// retain forgets the breakers of backends which are no longer in any hashring and
+// have not been used since before the cutoff.
+func (cb *circuitBreakers) retain(backends map[string]struct{}, cutoff time.Time) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	for backend, breaker := range cb.backends {
+		if _, ok := backends[backend]; ok || breaker.lastUpdated.After(cutoff) {
+			continue
+		}
+		if breaker.state == BreakerOpen {
+			openBreakers.Dec()
+		}
+		delete(cb.backends, backend)
+	}
+}
+
+// route returns the candidates for the key, replacing those whose breaker is open
+// with the next available members of the hashring. If no member is available, the
+// usual candidates are returned.
+func (cb *circuitBreakers) route(hashring *consistent.Hashring, key []byte, candidates []consistent.Member, spread uint8, members int) []consistent.Member {
+	allAvailable := true
+	for _, candidate := range candidates {
+		if !cb.available(candidate.Key()) {
+			allAvailable = false
+			break
+		}
+	}
+	if allAvailable {
+		return candidates
+	}
+
+	if members > 255 {
+		members = 255
+	}
+	all, err := hashring.FindN(key, uint8(members))
+	if err != nil {
+		return candidates
+	}
+
+	routed := make([]consistent.Member, 0, spread)
+	for _, member := range all {
+		if len(routed) == int(spread) {
+			break
+		}
+		if cb.available(member.Key()) {
+			routed = append(routed, member)
+		}
+	}
+	if len(routed) == 0 {
+		return candidates
+	}
+	return routed
+}
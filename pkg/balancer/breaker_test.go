@@ -0,0 +1,106 @@
+package balancer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/pkg/consistent"
+)
+
+type testBackend string
+
+func (tb testBackend) Key() string {
+	return string(tb)
+}
+
+func TestCircuitBreakers(t *testing.T) {
+	require := require.New(t)
+
+	var transitions []string
+	breakers := newCircuitBreakers(CircuitBreakerConfig{
+		FailureThreshold: 3,
+		OpenDuration:     time.Minute,
+		OnStateChange: func(backend string, state BreakerState) {
+			transitions = append(transitions, backend+":"+string(state))
+		},
+	})
+	now := time.Now()
+	breakers.now = func() time.Time { return now }
+
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	invalid := status.Error(codes.InvalidArgument, "invalid request")
+
+	// Failures of the requests themselves do not count, and successes reset the count.
+	breakers.done("a", unavailable)
+	breakers.done("a", unavailable)
+	breakers.done("a", invalid)
+	breakers.done("a", unavailable)
+	breakers.done("a", unavailable)
+	require.True(breakers.available("a"))
+	require.Empty(transitions)
+
+	breakers.done("a", unavailable)
+	require.False(breakers.available("a"))
+	require.True(breakers.available("b"))
+	require.Equal([]string{"a:open"}, transitions)
+
+	// Once open for the duration, a single probe is allowed, which reopens the breaker
+	// if it fails.
+	now = now.Add(time.Minute)
+	require.True(breakers.available("a"))
+	breakers.picked("a")
+	require.False(breakers.available("a"))
+	breakers.done("a", status.Error(codes.DeadlineExceeded, "timed out"))
+	require.False(breakers.available("a"))
+	require.Equal([]string{"a:open"}, transitions)
+
+	// A successful probe closes the breaker.
+	now = now.Add(time.Minute)
+	require.True(breakers.available("a"))
+	breakers.picked("a")
+	breakers.done("a", nil)
+	require.True(breakers.available("a"))
+	require.Equal([]string{"a:open", "a:closed"}, transitions)
+
+	// Breakers of backends which were removed are forgotten once unused.
+	breakers.done("b", nil)
+	breakers.retain(map[string]struct{}{"a": {}}, now.Add(time.Second))
+	require.Len(breakers.backends, 1)
+}
+
+func TestCircuitBreakerRouting(t *testing.T) {
+	require := require.New(t)
+
+	hashring := consistent.NewHashring(xxhash.Sum64, 20)
+	backends := []string{"a", "b", "c", "d"}
+	for _, backend := range backends {
+		require.NoError(hashring.Add(testBackend(backend)))
+	}
+
+	breakers := newCircuitBreakers(CircuitBreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute})
+	key := []byte("document:first#view")
+
+	candidates, err := hashring.FindN(key, 2)
+	require.NoError(err)
+	require.Equal(candidates, breakers.route(hashring, key, candidates, 2, len(backends)))
+
+	order, err := hashring.FindN(key, uint8(len(backends)))
+	require.NoError(err)
+
+	// The keys of an open backend are routed to the next backends of the hashring.
+	breakers.done(order[0].Key(), status.Error(codes.Unavailable, "connection refused"))
+	require.Equal(order[1:3], breakers.route(hashring, key, candidates, 2, len(backends)))
+
+	breakers.done(order[2].Key(), status.Error(codes.Unavailable, "connection refused"))
+	require.Equal([]consistent.Member{order[1], order[3]}, breakers.route(hashring, key, candidates, 2, len(backends)))
+
+	// If every backend is open, the key is routed to its usual backends.
+	breakers.done(order[1].Key(), status.Error(codes.Unavailable, "connection refused"))
+	breakers.done(order[3].Key(), status.Error(codes.Unavailable, "connection refused"))
+	require.Equal(candidates, breakers.route(hashring, key, candidates, 2, len(backends)))
+}
@@ -1,6 +1,7 @@
 package balancer
 
 import (
+	"fmt"
 	"math/rand"
 	"sync"
 	"time"
@@ -9,6 +10,9 @@ import (
 	"google.golang.org/grpc/balancer/base"
 	"google.golang.org/grpc/grpclog"
 
+	// Register client-side health checking, used by HealthCheckedServiceConfig
+	_ "google.golang.org/grpc/health"
+
 	"github.com/authzed/spicedb/pkg/consistent"
 )
 
@@ -29,6 +33,13 @@ const (
 
 var logger = grpclog.Component("consistenthashring")
 
+// HealthCheckedServiceConfig returns a service config that sets the default balancer to
+// the consistent-hashring balancer and checks the health of the named service on each
+// backend, so that backends reporting it as not serving are removed from the hashring.
+func HealthCheckedServiceConfig(serviceName string) string {
+	return fmt.Sprintf(`{"loadBalancingPolicy":%q,"healthCheckConfig":{"serviceName":%q}}`, BalancerName, serviceName)
+}
+
 // NewConsistentHashringBuilder creates a new balancer.Builder that
 // will create a consistent hashring balancer with the given config.
 // Before making a connection, register it with grpc with:
@@ -41,6 +52,22 @@ func NewConsistentHashringBuilder(hasher consistent.HasherFunc, replicationFacto
 	)
 }
 
+// NewConsistentHashringBuilderWithCircuitBreaker is NewConsistentHashringBuilder for a
+// balancer which routes the keys of repeatedly failing backends to other backends
+// while their circuit breaker is open.
+func NewConsistentHashringBuilderWithCircuitBreaker(hasher consistent.HasherFunc, replicationFactor uint16, spread uint8, config CircuitBreakerConfig) balancer.Builder {
+	return base.NewBalancerBuilder(
+		BalancerName,
+		&consistentHashringPickerBuilder{
+			hasher:            hasher,
+			replicationFactor: replicationFactor,
+			spread:            spread,
+			breakers:          newCircuitBreakers(config),
+		},
+		base.Config{HealthCheck: true},
+	)
+}
+
 type subConnMember struct {
 	balancer.SubConn
 	key string
@@ -58,6 +85,7 @@ type consistentHashringPickerBuilder struct {
 	hasher            consistent.HasherFunc
 	replicationFactor uint16
 	spread            uint8
+	breakers          *circuitBreakers
 }
 
 func (b *consistentHashringPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
@@ -66,26 +94,38 @@ func (b *consistentHashringPickerBuilder) Build(info base.PickerBuildInfo) balan
 		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
 	}
 	hashring := consistent.NewHashring(b.hasher, b.replicationFactor)
+	backends := make(map[string]struct{}, len(info.ReadySCs))
 	for sc, scInfo := range info.ReadySCs {
-		if err := hashring.Add(subConnMember{
+		member := subConnMember{
 			SubConn: sc,
 			key:     scInfo.Address.Addr + scInfo.Address.ServerName,
-		}); err != nil {
+		}
+		if err := hashring.Add(member); err != nil {
 			return base.NewErrPicker(err)
 		}
+		backends[member.key] = struct{}{}
+	}
+
+	if b.breakers != nil {
+		b.breakers.retain(backends, time.Now().Add(-b.breakers.config.OpenDuration))
 	}
+
 	return &consistentHashringPicker{
 		hashring: hashring,
+		members:  len(backends),
 		spread:   b.spread,
 		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		breakers: b.breakers,
 	}
 }
 
 type consistentHashringPicker struct {
 	sync.Mutex
 	hashring *consistent.Hashring
+	members  int
 	spread   uint8
 	rand     *rand.Rand
+	breakers *circuitBreakers
 }
 
 func (p *consistentHashringPicker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
@@ -95,13 +135,27 @@ func (p *consistentHashringPicker) Pick(info balancer.PickInfo) (balancer.PickRe
 		return balancer.PickResult{}, err
 	}
 
+	if p.breakers != nil {
+		members = p.breakers.route(p.hashring, key, members, p.spread, p.members)
+	}
+
 	// rand is not safe for concurrent use
 	p.Lock()
-	index := p.rand.Intn(int(p.spread))
+	index := p.rand.Intn(len(members))
 	p.Unlock()
 
 	chosen := members[index].(subConnMember)
+	if p.breakers == nil {
+		return balancer.PickResult{
+			SubConn: chosen.SubConn,
+		}, nil
+	}
+
+	p.breakers.picked(chosen.key)
 	return balancer.PickResult{
 		SubConn: chosen.SubConn,
+		Done: func(info balancer.DoneInfo) {
+			p.breakers.done(chosen.key, info.Err)
+		},
 	}, nil
 }
@@ -59,6 +59,13 @@ type Cache interface {
 	// Close closes the cache's background workers (if any).
 	Close()
 
+	// MaxCost returns the current capacity of the cache.
+	MaxCost() int64
+
+	// UpdateMaxCost changes the capacity of the cache, evicting entries as
+	// necessary.
+	UpdateMaxCost(maxCost int64)
+
 	// GetMetrics returns the metrics block for the cache.
 	GetMetrics() Metrics
 
@@ -93,6 +100,8 @@ func (no *noopCache) Get(key interface{}) (interface{}, bool)                 {
 func (no *noopCache) Set(key interface{}, entry interface{}, cost int64) bool { return false }
 func (no *noopCache) Wait()                                                   {}
 func (no *noopCache) Close()                                                  {}
+func (no *noopCache) MaxCost() int64                                          { return 0 }
+func (no *noopCache) UpdateMaxCost(maxCost int64)                             {}
 func (no *noopCache) GetMetrics() Metrics                                     { return &noopMetrics{} }
 func (no *noopCache) MarshalZerologObject(e *zerolog.Event) {
 	e.Bool("enabled", false)
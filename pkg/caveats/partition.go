@@ -0,0 +1,10 @@
+package caveats
+
+import "github.com/authzed/authzed-go/pkg/requestmeta"
+
+// RequestPartitionedLookup, if specified in the request header of a LookupResources or
+// LookupSubjects call, asks SpiceDB to send every result having the permission before any
+// result which is conditional on caveats. Conditional results list the context they are
+// missing in their PartialCaveatInfo, so callers can tell the two sections apart.
+// Value: `1`
+const RequestPartitionedLookup requestmeta.BoolRequestMetadataHeaderKey = "io.spicedb.requestpartitionedlookup"
@@ -0,0 +1,250 @@
+package caveats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"github.com/authzed/authzed-go/pkg/responsemeta"
+	"golang.org/x/exp/maps"
+
+	"github.com/authzed/spicedb/pkg/caveats/types"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+const (
+	// RequestResidual, if specified in the request header of a CheckPermission call, asks
+	// SpiceDB to return the residual of the caveats of a conditional result in the
+	// ResidualTrailer response trailer.
+	// Value: `1`
+	RequestResidual requestmeta.BoolRequestMetadataHeaderKey = "io.spicedb.requestresidual"
+
+	// ResidualTrailer is the response trailer holding the JSON form of the Residual of a
+	// conditional CheckPermission result.
+	ResidualTrailer responsemeta.ResponseMetadataTrailerKey = "io.spicedb.respmeta.residual"
+)
+
+// ResidualOperation is the operation combining the children of a Residual.
+type ResidualOperation string
+
+const (
+	ResidualAnd ResidualOperation = "and"
+	ResidualOr  ResidualOperation = "or"
+	ResidualNot ResidualOperation = "not"
+)
+
+// Residual is the part of the caveats of a conditional permission which could not be
+// evaluated for lack of context, exported so that it can be evaluated by the caller once
+// the missing context is known, without another CheckPermission.
+//
+// A Residual is either an operation over its children, or a caveat. Every caveat holds a
+// CEL expression over the parameters of the caveat, from which the parts already evaluated
+// have been removed. The expression is evaluated in a CEL environment declaring each of the
+// parameters with its type, over the missing context merged with the context of the
+// caveat, whose values take precedence. Types are written as in schemas, e.g. `int` or
+// `list<string>`; the `ipaddress` type and its `in_cidr` method are SpiceDB extensions,
+// which an evaluator must provide.
+//
+// The result of a check is the result of the residual: an operation is true if all (and),
+// any (or) or none (not) of its children are true, and a caveat is true if its expression is.
+// EvaluateResidual is the reference implementation of this contract.
+//
+// No residual is returned if it cannot be written as CEL, such as when a comprehension
+// depends on missing context; the caller must then check again with the full context.
+type Residual struct {
+	Operation ResidualOperation `json:"operation,omitempty"`
+	Children  []*Residual       `json:"children,omitempty"`
+
+	// Caveat is the name of the caveat the expression was derived from.
+	Caveat     string            `json:"caveat,omitempty"`
+	Expression string            `json:"expression,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Context    map[string]any    `json:"context,omitempty"`
+}
+
+// MissingParameters returns the names of the parameters of the residual's caveats which
+// are not given by their context, in sorted order.
+func (r *Residual) MissingParameters() []string {
+	missing := make(map[string]struct{})
+	r.collectMissingParameters(missing)
+
+	names := maps.Keys(missing)
+	sort.Strings(names)
+	return names
+}
+
+func (r *Residual) collectMissingParameters(missing map[string]struct{}) {
+	for name := range r.Parameters {
+		if _, ok := r.Context[name]; !ok {
+			missing[name] = struct{}{}
+		}
+	}
+	for _, child := range r.Children {
+		child.collectMissingParameters(missing)
+	}
+}
+
+// ResidualResult is the result of evaluating a Residual.
+type ResidualResult struct {
+	// Value is the value of the residual, which is false if any parameters are missing.
+	Value bool
+
+	// MissingParameters are the names of the parameters the value depends on which were
+	// missing from the context, in sorted order.
+	MissingParameters []string
+}
+
+// IsPartial returns true if the value of the residual could not be determined.
+func (rr ResidualResult) IsPartial() bool {
+	return len(rr.MissingParameters) > 0
+}
+
+// EvaluateResidual evaluates the residual over the given context.
+func EvaluateResidual(residual *Residual, context map[string]any) (ResidualResult, error) {
+	if residual.Operation == "" {
+		return evaluateResidualCaveat(residual, context)
+	}
+
+	results := make([]ResidualResult, 0, len(residual.Children))
+	for _, child := range residual.Children {
+		result, err := EvaluateResidual(child, context)
+		if err != nil {
+			return ResidualResult{}, err
+		}
+		results = append(results, result)
+	}
+
+	switch residual.Operation {
+	case ResidualAnd, ResidualOr:
+		// The first known child equal to the short-circuiting value decides the operation,
+		// whatever the value of the others.
+		shortCircuit := residual.Operation == ResidualOr
+		missing := make(map[string]struct{})
+		for _, result := range results {
+			if !result.IsPartial() && result.Value == shortCircuit {
+				return ResidualResult{Value: shortCircuit}, nil
+			}
+			for _, name := range result.MissingParameters {
+				missing[name] = struct{}{}
+			}
+		}
+		if len(missing) > 0 {
+			names := maps.Keys(missing)
+			sort.Strings(names)
+			return ResidualResult{MissingParameters: names}, nil
+		}
+		return ResidualResult{Value: !shortCircuit}, nil
+
+	case ResidualNot:
+		if len(results) != 1 {
+			return ResidualResult{}, fmt.Errorf("`not` residual requires a single child; found %d", len(results))
+		}
+		if results[0].IsPartial() {
+			return results[0], nil
+		}
+		return ResidualResult{Value: !results[0].Value}, nil
+
+	default:
+		return ResidualResult{}, fmt.Errorf("unknown residual operation `%s`", residual.Operation)
+	}
+}
+
+func evaluateResidualCaveat(residual *Residual, context map[string]any) (ResidualResult, error) {
+	env := NewEnvironment()
+	parameterTypes := make(map[string]*core.CaveatTypeReference, len(residual.Parameters))
+	for name, typeString := range residual.Parameters {
+		typeRef, err := ParseParameterType(typeString)
+		if err != nil {
+			return ResidualResult{}, fmt.Errorf("invalid type for parameter `%s` of caveat `%s`: %w", name, residual.Caveat, err)
+		}
+		varType, err := types.DecodeParameterType(typeRef)
+		if err != nil {
+			return ResidualResult{}, err
+		}
+		if err := env.AddVariable(name, *varType); err != nil {
+			return ResidualResult{}, err
+		}
+		parameterTypes[name] = typeRef
+	}
+
+	compiled, err := CompileCaveatWithName(env, residual.Expression, residual.Caveat)
+	if err != nil {
+		return ResidualResult{}, fmt.Errorf("invalid expression for caveat `%s`: %w", residual.Caveat, err)
+	}
+
+	fullContext := maps.Clone(context)
+	if fullContext == nil {
+		fullContext = map[string]any{}
+	}
+	maps.Copy(fullContext, residual.Context)
+
+	typedParameters, err := ConvertContextToParameters(fullContext, parameterTypes, SkipUnknownParameters)
+	if err != nil {
+		return ResidualResult{}, fmt.Errorf("type error for parameters for caveat `%s`: %w", residual.Caveat, err)
+	}
+
+	result, err := EvaluateCaveat(compiled, typedParameters)
+	if err != nil {
+		return ResidualResult{}, err
+	}
+	if result.IsPartial() {
+		missing := make([]string, 0, len(residual.Parameters))
+		for name := range residual.Parameters {
+			if _, ok := fullContext[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		sort.Strings(missing)
+		return ResidualResult{MissingParameters: missing}, nil
+	}
+	return ResidualResult{Value: result.Value()}, nil
+}
+
+// ParseParameterType parses a parameter type written as in schemas, such as `int` or
+// `map<list<string>>`.
+func ParseParameterType(typeString string) (*core.CaveatTypeReference, error) {
+	typeString = strings.TrimSpace(typeString)
+	open := strings.IndexByte(typeString, '<')
+	if open < 0 {
+		if typeString == "" || strings.ContainsAny(typeString, ">,") {
+			return nil, fmt.Errorf("malformed type `%s`", typeString)
+		}
+		return &core.CaveatTypeReference{TypeName: typeString}, nil
+	}
+	if !strings.HasSuffix(typeString, ">") {
+		return nil, fmt.Errorf("malformed type `%s`", typeString)
+	}
+
+	typeRef := &core.CaveatTypeReference{TypeName: strings.TrimSpace(typeString[:open])}
+	children := typeString[open+1 : len(typeString)-1]
+
+	// Split the children at the commas which are not nested within another type.
+	depth, start := 0, 0
+	for i := 0; i <= len(children); i++ {
+		if i < len(children) {
+			switch children[i] {
+			case '<':
+				depth++
+				continue
+			case '>':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+
+		child, err := ParseParameterType(children[start:i])
+		if err != nil {
+			return nil, err
+		}
+		typeRef.ChildTypes = append(typeRef.ChildTypes, child)
+		start = i + 1
+	}
+	return typeRef, nil
+}
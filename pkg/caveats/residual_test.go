@@ -0,0 +1,70 @@
+package caveats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/caveats/types"
+)
+
+func TestParseParameterType(t *testing.T) {
+	for _, varType := range []types.VariableType{
+		types.IntType,
+		types.IPAddressType,
+		types.ListType(types.StringType),
+		types.MapType(types.ListType(types.UIntType)),
+	} {
+		typeRef, err := ParseParameterType(varType.String())
+		require.NoError(t, err)
+
+		decoded, err := types.DecodeParameterType(typeRef)
+		require.NoError(t, err)
+		require.Equal(t, varType.String(), decoded.String())
+	}
+
+	for _, malformed := range []string{"", "list<string", "list<>", "int>"} {
+		_, err := ParseParameterType(malformed)
+		require.Error(t, err, malformed)
+	}
+}
+
+func TestEvaluateResidual(t *testing.T) {
+	residual := &Residual{
+		Operation: ResidualAnd,
+		Children: []*Residual{
+			{Caveat: "first", Expression: "count < limit", Parameters: map[string]string{"count": "int", "limit": "int"}, Context: map[string]any{"limit": 10.0}},
+			{
+				Operation: ResidualNot,
+				Children: []*Residual{
+					{Caveat: "second", Expression: "name in blocked", Parameters: map[string]string{"name": "string", "blocked": "list<string>"}, Context: map[string]any{"blocked": []any{"mallory"}}},
+				},
+			},
+		},
+	}
+	require.Equal(t, []string{"count", "name"}, residual.MissingParameters())
+
+	tcs := []struct {
+		name            string
+		context         map[string]any
+		expectedValue   bool
+		expectedMissing []string
+	}{
+		{"all true", map[string]any{"count": 5.0, "name": "anne"}, true, nil},
+		{"first false", map[string]any{"count": 15.0, "name": "anne"}, false, nil},
+		{"second false", map[string]any{"count": 5.0, "name": "mallory"}, false, nil},
+		{"false decides without missing context", map[string]any{"count": 15.0}, false, nil},
+		{"missing context", map[string]any{"count": 5.0}, false, []string{"name"}},
+		{"context of caveat takes precedence", map[string]any{"count": 5.0, "limit": 1.0, "name": "anne"}, true, nil},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := EvaluateResidual(residual, tc.context)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedValue, result.Value)
+			require.Equal(t, tc.expectedMissing, result.MissingParameters)
+		})
+	}
+}
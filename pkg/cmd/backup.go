@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jzelinskie/cobrautil/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/datastore/backup"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+)
+
+func NewDatastoreCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "datastore",
+		Short: "datastore maintenance operations",
+		Long:  "Operations which act directly upon a datastore, without a running SpiceDB",
+	}
+}
+
+func RegisterBackupFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().Bool("include-revision", false, "record the datastore revision at which the snapshot was taken in the backup metadata")
+}
+
+func NewBackupCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup <file|url|->",
+		Short: "write a consistent snapshot of the datastore to a backup",
+		Long: fmt.Sprintf(`Writes the schema, caveats and relationships found at the head revision of the datastore to a file, stdout ("-"), or an http(s) URL such as a presigned object storage URL.
+
+Backups are in an engine-independent format, and can be restored with "%s datastore restore" into any datastore engine.`, programName),
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ds, err := datastore.NewDatastore(config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to init datastore: %w", err)
+			}
+			defer ds.Close()
+
+			w, err := backup.Create(cmd.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("unable to create backup: %w", err)
+			}
+
+			counts, err := backup.Backup(cmd.Context(), ds, config.Engine, cobrautil.MustGetBool(cmd, "include-revision"), w)
+			if err != nil {
+				w.Close()
+				return fmt.Errorf("unable to back up datastore: %w", err)
+			}
+			if err := w.Close(); err != nil {
+				return fmt.Errorf("unable to write backup: %w", err)
+			}
+
+			log.Info().
+				Uint64("caveats", counts.Caveats).
+				Uint64("namespaces", counts.Namespaces).
+				Uint64("relationships", counts.Relationships).
+				Msg("backup complete")
+			return nil
+		},
+	}
+}
+
+func RegisterBackupChangesFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().Duration("idle-timeout", backup.DefaultChangesIdleTimeout, "time to wait for a further transaction before ending the segment short of the head revision")
+}
+
+func NewBackupChangesCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup-changes <previous file|url> <file|url|->",
+		Short: "write the changes made since a previous backup to a change segment",
+		Long: fmt.Sprintf(`Writes the relationship changes committed to the datastore since a previous backup, as reported by the Watch API, to a change segment in a file, stdout ("-"), or an http(s) URL.
+
+The previous backup is either a snapshot taken by "%[1]s datastore backup --include-revision", or the last change segment taken after it. Segments hold a checkpoint per transaction, so that "%[1]s datastore restore --to-revision" can restore the snapshot and its segments to any revision between backups.
+
+Segments only hold relationship changes: schema changes require a new snapshot.`, programName),
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			previous, err := backup.Open(cmd.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("unable to open previous backup: %w", err)
+			}
+			previousMetadata, after, err := backup.LastRevision(previous)
+			previous.Close()
+			if err != nil {
+				return fmt.Errorf("unable to read previous backup: %w", err)
+			}
+			if previousMetadata.Engine != config.Engine {
+				return fmt.Errorf("previous backup was taken from a %s datastore, whose revisions cannot be used with %s", previousMetadata.Engine, config.Engine)
+			}
+
+			ds, err := datastore.NewDatastore(config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to init datastore: %w", err)
+			}
+			defer ds.Close()
+
+			w, err := backup.Create(cmd.Context(), args[1])
+			if err != nil {
+				return fmt.Errorf("unable to create change segment: %w", err)
+			}
+
+			last, counts, err := backup.BackupChanges(cmd.Context(), ds, config.Engine, after, cobrautil.MustGetDuration(cmd, "idle-timeout"), w)
+			if err != nil {
+				w.Close()
+				return fmt.Errorf("unable to back up changes: %w", err)
+			}
+			if err := w.Close(); err != nil {
+				return fmt.Errorf("unable to write change segment: %w", err)
+			}
+
+			log.Info().
+				Str("afterRevision", after).
+				Str("lastRevision", last).
+				Uint64("transactions", counts.Checkpoints).
+				Uint64("changes", counts.Changes).
+				Msg("change segment complete")
+			return nil
+		},
+	}
+}
+
+func RegisterRestoreFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().Int("batch-size", backup.DefaultRestoreBatchSize, "number of relationships to write per transaction")
+	cmd.Flags().Bool("overwrite", false, "restore into a datastore which already contains a schema, first deleting its relationships and schema so that it holds only the contents of the backup")
+	cmd.Flags().String("to-revision", "", "revision of the source datastore, as found in the backup or a checkpoint of its change segments, at which to stop restoring")
+	cmd.Flags().String("to-time", "", "RFC 3339 time as of which to restore, applying the transactions of change segments committed by then; requires segments of a datastore which reports commit times")
+}
+
+func NewRestoreCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <file|url|-> [change segment file|url...]",
+		Short: "restore a backup into the datastore",
+		Long: fmt.Sprintf(`Writes the contents of a backup created by "%[1]s datastore backup" from a file, stdin ("-"), or an http(s) URL into the datastore, which must have been migrated.
+
+Change segments created by "%[1]s datastore backup-changes" are then applied in the order given, to restore the state of the source datastore as of the checkpoint at --to-revision or as of --to-time, if set, or otherwise as of the last checkpoint. "%[1]s datastore backup-checkpoints" lists the checkpoints of segments.
+
+Restoring into a datastore which already contains a schema requires --overwrite, which deletes its existing relationships and schema first.`, programName),
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchSize := cobrautil.MustGetInt(cmd, "batch-size")
+			if batchSize <= 0 {
+				return fmt.Errorf("batch size must be positive, got %d", batchSize)
+			}
+
+			target, err := restoreTarget(cmd)
+			if err != nil {
+				return err
+			}
+
+			ds, err := datastore.NewDatastore(config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to init datastore: %w", err)
+			}
+			defer ds.Close()
+
+			metadata, counts, checkpoint, err := backup.RestoreTo(cmd.Context(), ds, args, batchSize, cobrautil.MustGetBool(cmd, "overwrite"), target)
+			if err != nil {
+				return fmt.Errorf("unable to restore backup: %w", err)
+			}
+
+			log.Info().
+				Str("sourceEngine", metadata.Engine).
+				Time("createdAt", metadata.CreatedAt).
+				Str("sourceRevision", metadata.Revision).
+				Uint64("caveats", counts.Caveats).
+				Uint64("namespaces", counts.Namespaces).
+				Uint64("relationships", counts.Relationships).
+				Uint64("transactions", counts.Checkpoints).
+				Uint64("changes", counts.Changes).
+				Str("restoredRevision", checkpoint.Revision).
+				Msg("restore complete")
+
+			if !target.Time.IsZero() && len(args) > 1 && checkpoint.CommittedAt.Before(target.Time) {
+				log.Warn().
+					Time("targetTime", target.Time).
+					Time("lastCommittedAt", checkpoint.CommittedAt).
+					Msg("change segments end before the requested time; restored up to their last checkpoint")
+			}
+			return nil
+		},
+	}
+}
+
+func restoreTarget(cmd *cobra.Command) (backup.Target, error) {
+	target := backup.Target{Revision: cobrautil.MustGetString(cmd, "to-revision")}
+	if toTime := cobrautil.MustGetString(cmd, "to-time"); toTime != "" {
+		if target.Revision != "" {
+			return backup.Target{}, fmt.Errorf("only one of --to-revision and --to-time can be set")
+		}
+
+		parsed, err := time.Parse(time.RFC3339Nano, toTime)
+		if err != nil {
+			return backup.Target{}, fmt.Errorf("invalid --to-time: %w", err)
+		}
+		target.Time = parsed
+	}
+	return target, nil
+}
+
+func NewBackupCheckpointsCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup-checkpoints <file|url|->...",
+		Short: "list the transactions found in change segments",
+		Long: fmt.Sprintf(`Lists the checkpoint of each transaction found in change segments created by "%[1]s datastore backup-changes", with the number of relationships it touched and deleted.
+
+The revision or time of a checkpoint can be given to "%[1]s datastore restore" as --to-revision or --to-time, e.g. to restore the state preceding an accidental bulk deletion.`, programName),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w := cmd.OutOrStdout()
+			fmt.Fprintf(w, "%-32s %-36s %10s %10s\n", "revision", "committed at", "touches", "deletes")
+			for _, location := range args {
+				r, err := backup.Open(cmd.Context(), location)
+				if err != nil {
+					return fmt.Errorf("unable to open change segment: %w", err)
+				}
+				_, summaries, err := backup.SummarizeTransactions(r)
+				r.Close()
+				if err != nil {
+					return fmt.Errorf("unable to read change segment %s: %w", location, err)
+				}
+
+				for _, summary := range summaries {
+					committedAt := "-"
+					if !summary.CommittedAt.IsZero() {
+						committedAt = summary.CommittedAt.Format(time.RFC3339Nano)
+					}
+					fmt.Fprintf(w, "%-32s %-36s %10d %10d\n",
+						summary.Revision, committedAt, summary.Touches, summary.Deletes)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func RegisterCopyFlags(cmd *cobra.Command, source, target *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, source)
+	datastore.RegisterDatastoreFlagsWithPrefix(cmd, "target", target)
+	cmd.Flags().Int("batch-size", backup.DefaultRestoreBatchSize, "number of relationships to write per transaction")
+	cmd.Flags().Bool("overwrite", false, "copy into a target datastore which already contains a schema, overwriting definitions and relationships found in the source")
+	cmd.Flags().Bool("skip-verify", false, "skip comparing the counts and checksums of the source snapshot and the target once copied")
+}
+
+func NewCopyCommand(programName string, source, target *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "copy",
+		Short: "copy the contents of one datastore into another",
+		Long: `Copies the schema, caveats and relationships found at the head revision of the source datastore, configured by the --datastore-* flags, into the target datastore, configured by the --target-datastore-* flags, which must have been migrated. The engines may differ, e.g. to move from Postgres to CockroachDB.
+
+Once copied, the number of objects and an order-independent checksum of each namespace are compared between the source snapshot and the target. Copying into a datastore which already contains a schema requires --overwrite.`,
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchSize := cobrautil.MustGetInt(cmd, "batch-size")
+			if batchSize <= 0 {
+				return fmt.Errorf("batch size must be positive, got %d", batchSize)
+			}
+
+			sourceDS, err := datastore.NewDatastore(source.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to init source datastore: %w", err)
+			}
+			defer sourceDS.Close()
+
+			targetDS, err := datastore.NewDatastore(target.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to init target datastore: %w", err)
+			}
+			defer targetDS.Close()
+
+			rev, counts, err := backup.Copy(cmd.Context(), sourceDS, targetDS, source.Engine, batchSize, cobrautil.MustGetBool(cmd, "overwrite"))
+			if err != nil {
+				return fmt.Errorf("unable to copy datastore: %w", err)
+			}
+
+			log.Info().
+				Str("sourceEngine", source.Engine).
+				Str("targetEngine", target.Engine).
+				Uint64("caveats", counts.Caveats).
+				Uint64("namespaces", counts.Namespaces).
+				Uint64("relationships", counts.Relationships).
+				Msg("copy complete")
+
+			if cobrautil.MustGetBool(cmd, "skip-verify") {
+				return nil
+			}
+
+			targetRev, err := targetDS.HeadRevision(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("unable to determine target head revision: %w", err)
+			}
+			if err := backup.Verify(cmd.Context(), sourceDS.SnapshotReader(rev), targetDS.SnapshotReader(targetRev)); err != nil {
+				return err
+			}
+			log.Info().Msg("copy verified")
+			return nil
+		},
+	}
+}
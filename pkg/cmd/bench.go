@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jzelinskie/cobrautil/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/bench"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+)
+
+func RegisterBenchFlags(cmd *cobra.Command) {
+	registerClientFlags(cmd, "address of the server to benchmark")
+
+	cmd.Flags().Int("depth", 3, "number of levels of folders above each document")
+	cmd.Flags().Int("fan-out", 5, "number of root folders, of child folders within each folder, and of documents within each innermost folder")
+	cmd.Flags().Int("users", 1000, "number of users from which viewers are drawn")
+	cmd.Flags().Int("viewers-per-object", 3, "number of users directly granted view on each folder and document")
+	cmd.Flags().Float64("caveat-ratio", 0, "fraction of viewer relationships which are caveated; requires a server with caveats enabled")
+	cmd.Flags().Int64("seed", 1, "seed for generating the graph and choosing requests")
+
+	cmd.Flags().Bool("skip-load", false, "skip writing the schema and relationships, reusing a graph loaded by an earlier run with the same shape and seed")
+	cmd.Flags().Int("load-batch-size", 1000, "number of relationships written per request while loading")
+
+	cmd.Flags().Duration("duration", 30*time.Second, "how long to issue requests for")
+	cmd.Flags().Int("concurrency", 10, "number of requests in flight at once")
+	cmd.Flags().Float64("lookup-ratio", 0.1, "fraction of requests which are LookupResources rather than CheckPermission")
+	cmd.Flags().Bool("fully-consistent", false, "issue every request at the head revision instead of allowing cached results")
+	cmd.Flags().String("output", "text", "output format (text, json)")
+}
+
+func NewBenchCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "bench",
+		Short: "load-test a running server",
+		Long: `Generates a synthetic schema and relationship graph of nested folders and documents, writes it to a running server and then issues CheckPermission and LookupResources requests against it, reporting their latency percentiles.
+
+The graph is generated deterministically from its shape and --seed, so runs are reproducible and a graph can be loaded once and benchmarked repeatedly with --skip-load. The schema of the target server is overwritten, so do not run this against a server holding data you want to keep.`,
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.NoArgs,
+		RunE:    benchRun,
+	}
+}
+
+func benchRun(cmd *cobra.Command, _ []string) error {
+	output := cobrautil.MustGetString(cmd, "output")
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unknown output format `%s`: expected text or json", output)
+	}
+
+	seed := cobrautil.MustGetInt64(cmd, "seed")
+	graph, err := bench.Generate(bench.Shape{
+		Depth:            cobrautil.MustGetInt(cmd, "depth"),
+		FanOut:           cobrautil.MustGetInt(cmd, "fan-out"),
+		Users:            cobrautil.MustGetInt(cmd, "users"),
+		ViewersPerObject: cobrautil.MustGetInt(cmd, "viewers-per-object"),
+		CaveatRatio:      cobrautil.MustGetFloat64(cmd, "caveat-ratio"),
+	}, seed)
+	if err != nil {
+		return fmt.Errorf("invalid graph shape: %w", err)
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	if !cobrautil.MustGetBool(cmd, "skip-load") {
+		log.Ctx(ctx).Info().
+			Int("documents", len(graph.Documents)).
+			Int("relationships", len(graph.Relationships)).
+			Msg("loading benchmark graph")
+
+		start := time.Now()
+		if err := bench.Load(ctx, client, graph, cobrautil.MustGetInt(cmd, "load-batch-size")); err != nil {
+			return err
+		}
+		log.Ctx(ctx).Info().Stringer("duration", time.Since(start)).Msg("loaded benchmark graph")
+	}
+
+	workload := bench.Workload{
+		Duration:        cobrautil.MustGetDuration(cmd, "duration"),
+		Concurrency:     cobrautil.MustGetInt(cmd, "concurrency"),
+		LookupRatio:     cobrautil.MustGetFloat64(cmd, "lookup-ratio"),
+		FullyConsistent: cobrautil.MustGetBool(cmd, "fully-consistent"),
+		Seed:            seed,
+	}
+	log.Ctx(ctx).Info().
+		Stringer("duration", workload.Duration).
+		Int("concurrency", workload.Concurrency).
+		Msg("running benchmark")
+
+	report, err := bench.Run(ctx, client, graph, workload)
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	printBenchReport(cmd.OutOrStdout(), report)
+	return nil
+}
+
+func printBenchReport(w io.Writer, report bench.Report) {
+	fmt.Fprintf(w, "%-8s %10s %8s %10s %10s %10s %10s %10s\n",
+		"op", "requests", "errors", "req/s", "p50", "p90", "p99", "max")
+	for _, stats := range report.Stats {
+		fmt.Fprintf(w, "%-8s %10d %8d %10.1f %10s %10s %10s %10s\n",
+			stats.Operation, stats.Count, stats.Errors, stats.PerSecond,
+			stats.P50.Round(time.Microsecond), stats.P90.Round(time.Microsecond),
+			stats.P99.Round(time.Microsecond), stats.Max.Round(time.Microsecond))
+	}
+	fmt.Fprintf(w, "elapsed: %s\n", report.Elapsed.Round(time.Millisecond))
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jzelinskie/cobrautil/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/datastore/compact"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+)
+
+func RegisterCompactFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().Int("batch-size", compact.DefaultBatchSize, "number of pairs of relationship versions to merge per statement")
+	cmd.Flags().Duration("batch-interval", 0, "minimum time between statements, to limit the write load of compaction")
+}
+
+func NewCompactCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "compact",
+		Short: "merge the identical versions of relationships left by rewrites which changed nothing",
+		Long: `Merges consecutive versions of each relationship which are identical, including their caveat, such as those left by TOUCHing a relationship which was already stored (postgres driver only).
+
+Reads at any revision return the same relationships after compaction, but the merged rewrites are no longer reported as changes by Watch. Newly written no-op TOUCHes can be skipped with --datastore-skip-noop-touches.`,
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchSize := cobrautil.MustGetInt(cmd, "batch-size")
+			if batchSize <= 0 {
+				return fmt.Errorf("batch size must be positive, got %d", batchSize)
+			}
+
+			config.RequestHedgingEnabled = false
+			config.SlowQueryThreshold = 0
+
+			ds, err := datastore.NewDatastore(config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to init datastore: %w", err)
+			}
+			defer ds.Close()
+
+			merged, err := compact.Run(cmd.Context(), ds, compact.Options{
+				BatchSize:     batchSize,
+				BatchInterval: cobrautil.MustGetDuration(cmd, "batch-interval"),
+				OnProgress: func(merged uint64) {
+					log.Info().Uint64("merged", merged).Msg("compaction progress")
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("unable to compact datastore: %w", err)
+			}
+
+			log.Info().Uint64("merged", merged).Msg("compaction complete")
+			return nil
+		},
+	}
+}
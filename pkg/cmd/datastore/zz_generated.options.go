@@ -1,7 +1,11 @@
 // Code generated by github.com/ecordell/optgen. DO NOT EDIT.
 package datastore
 
-import "time"
+import (
+	"time"
+
+	encryption "github.com/authzed/spicedb/pkg/datastore/encryption"
+)
 
 type ConfigOption func(c *Config)
 
@@ -22,14 +26,27 @@ func (c *Config) ToOption() ConfigOption {
 		to.GCWindow = c.GCWindow
 		to.LegacyFuzzing = c.LegacyFuzzing
 		to.RevisionQuantization = c.RevisionQuantization
+		to.MaxRevisionQuantization = c.MaxRevisionQuantization
 		to.MaxIdleTime = c.MaxIdleTime
 		to.MaxLifetime = c.MaxLifetime
 		to.MaxOpenConns = c.MaxOpenConns
 		to.MinOpenConns = c.MinOpenConns
+		to.TargetAcquireLatency = c.TargetAcquireLatency
 		to.SplitQueryCount = c.SplitQueryCount
 		to.ReadOnly = c.ReadOnly
 		to.EnableDatastoreMetrics = c.EnableDatastoreMetrics
 		to.DisableStats = c.DisableStats
+		to.SlowQueryThreshold = c.SlowQueryThreshold
+		to.RequestIDQueryComments = c.RequestIDQueryComments
+		to.QueryTagging = c.QueryTagging
+		to.QueryTagNodeID = c.QueryTagNodeID
+		to.SkipNoopTouches = c.SkipNoopTouches
+		to.CaveatContextEncryptionKeyring = c.CaveatContextEncryptionKeyring
+		to.CaveatContextKeyManager = c.CaveatContextKeyManager
+		to.PseudonymizedObjectTypes = c.PseudonymizedObjectTypes
+		to.PseudonymizationKey = c.PseudonymizationKey
+		to.ReadinessCheckInterval = c.ReadinessCheckInterval
+		to.ReadinessMaxRevisionStall = c.ReadinessMaxRevisionStall
 		to.BootstrapFiles = c.BootstrapFiles
 		to.BootstrapOverwrite = c.BootstrapOverwrite
 		to.RequestHedgingEnabled = c.RequestHedgingEnabled
@@ -43,6 +60,10 @@ func (c *Config) ToOption() ConfigOption {
 		to.HealthCheckPeriod = c.HealthCheckPeriod
 		to.GCInterval = c.GCInterval
 		to.GCMaxOperationTime = c.GCMaxOperationTime
+		to.RevisionScheme = c.RevisionScheme
+		to.GCArchiveURI = c.GCArchiveURI
+		to.GCArchiveEndpoint = c.GCArchiveEndpoint
+		to.GCArchiveRegion = c.GCArchiveRegion
 		to.SpannerCredentialsFile = c.SpannerCredentialsFile
 		to.SpannerEmulatorHost = c.SpannerEmulatorHost
 		to.TablePrefix = c.TablePrefix
@@ -94,6 +115,13 @@ func WithRevisionQuantization(revisionQuantization time.Duration) ConfigOption {
 	}
 }
 
+// WithMaxRevisionQuantization returns an option that can set MaxRevisionQuantization on a Config
+func WithMaxRevisionQuantization(maxRevisionQuantization time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.MaxRevisionQuantization = maxRevisionQuantization
+	}
+}
+
 // WithMaxIdleTime returns an option that can set MaxIdleTime on a Config
 func WithMaxIdleTime(maxIdleTime time.Duration) ConfigOption {
 	return func(c *Config) {
@@ -122,6 +150,13 @@ func WithMinOpenConns(minOpenConns int) ConfigOption {
 	}
 }
 
+// WithTargetAcquireLatency returns an option that can set TargetAcquireLatency on a Config
+func WithTargetAcquireLatency(targetAcquireLatency time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.TargetAcquireLatency = targetAcquireLatency
+	}
+}
+
 // WithSplitQueryCount returns an option that can set SplitQueryCount on a Config
 func WithSplitQueryCount(splitQueryCount uint16) ConfigOption {
 	return func(c *Config) {
@@ -150,6 +185,90 @@ func WithDisableStats(disableStats bool) ConfigOption {
 	}
 }
 
+// WithSlowQueryThreshold returns an option that can set SlowQueryThreshold on a Config
+func WithSlowQueryThreshold(slowQueryThreshold time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.SlowQueryThreshold = slowQueryThreshold
+	}
+}
+
+// WithRequestIDQueryComments returns an option that can set RequestIDQueryComments on a Config
+func WithRequestIDQueryComments(requestIDQueryComments bool) ConfigOption {
+	return func(c *Config) {
+		c.RequestIDQueryComments = requestIDQueryComments
+	}
+}
+
+// WithQueryTagging returns an option that can set QueryTagging on a Config
+func WithQueryTagging(queryTagging bool) ConfigOption {
+	return func(c *Config) {
+		c.QueryTagging = queryTagging
+	}
+}
+
+// WithQueryTagNodeID returns an option that can set QueryTagNodeID on a Config
+func WithQueryTagNodeID(queryTagNodeID string) ConfigOption {
+	return func(c *Config) {
+		c.QueryTagNodeID = queryTagNodeID
+	}
+}
+
+// WithSkipNoopTouches returns an option that can set SkipNoopTouches on a Config
+func WithSkipNoopTouches(skipNoopTouches bool) ConfigOption {
+	return func(c *Config) {
+		c.SkipNoopTouches = skipNoopTouches
+	}
+}
+
+// WithCaveatContextEncryptionKeyring returns an option that can set CaveatContextEncryptionKeyring on a Config
+func WithCaveatContextEncryptionKeyring(caveatContextEncryptionKeyring string) ConfigOption {
+	return func(c *Config) {
+		c.CaveatContextEncryptionKeyring = caveatContextEncryptionKeyring
+	}
+}
+
+// WithCaveatContextKeyManager returns an option that can set CaveatContextKeyManager on a Config
+func WithCaveatContextKeyManager(caveatContextKeyManager encryption.KeyManager) ConfigOption {
+	return func(c *Config) {
+		c.CaveatContextKeyManager = caveatContextKeyManager
+	}
+}
+
+// WithPseudonymizedObjectTypes returns an option that can append PseudonymizedObjectTypess to Config.PseudonymizedObjectTypes
+func WithPseudonymizedObjectTypes(pseudonymizedObjectTypes string) ConfigOption {
+	return func(c *Config) {
+		c.PseudonymizedObjectTypes = append(c.PseudonymizedObjectTypes, pseudonymizedObjectTypes)
+	}
+}
+
+// SetPseudonymizedObjectTypes returns an option that can set PseudonymizedObjectTypes on a Config
+func SetPseudonymizedObjectTypes(pseudonymizedObjectTypes []string) ConfigOption {
+	return func(c *Config) {
+		c.PseudonymizedObjectTypes = pseudonymizedObjectTypes
+	}
+}
+
+// WithPseudonymizationKey returns an option that can set PseudonymizationKey on a Config
+func WithPseudonymizationKey(pseudonymizationKey string) ConfigOption {
+	return func(c *Config) {
+		c.PseudonymizationKey = pseudonymizationKey
+	}
+}
+
+// WithReadinessCheckInterval returns an option that can set ReadinessCheckInterval on a Config
+func WithReadinessCheckInterval(readinessCheckInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.ReadinessCheckInterval = readinessCheckInterval
+	}
+}
+
+// WithReadinessMaxRevisionStall returns an option that can set ReadinessMaxRevisionStall on a Config
+func WithReadinessMaxRevisionStall(readinessMaxRevisionStall time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.ReadinessMaxRevisionStall = readinessMaxRevisionStall
+	}
+}
+
 // WithBootstrapFiles returns an option that can append BootstrapFiless to Config.BootstrapFiles
 func WithBootstrapFiles(bootstrapFiles string) ConfigOption {
 	return func(c *Config) {
@@ -248,6 +367,34 @@ func WithGCMaxOperationTime(gCMaxOperationTime time.Duration) ConfigOption {
 	}
 }
 
+// WithRevisionScheme returns an option that can set RevisionScheme on a Config
+func WithRevisionScheme(revisionScheme string) ConfigOption {
+	return func(c *Config) {
+		c.RevisionScheme = revisionScheme
+	}
+}
+
+// WithGCArchiveURI returns an option that can set GCArchiveURI on a Config
+func WithGCArchiveURI(gCArchiveURI string) ConfigOption {
+	return func(c *Config) {
+		c.GCArchiveURI = gCArchiveURI
+	}
+}
+
+// WithGCArchiveEndpoint returns an option that can set GCArchiveEndpoint on a Config
+func WithGCArchiveEndpoint(gCArchiveEndpoint string) ConfigOption {
+	return func(c *Config) {
+		c.GCArchiveEndpoint = gCArchiveEndpoint
+	}
+}
+
+// WithGCArchiveRegion returns an option that can set GCArchiveRegion on a Config
+func WithGCArchiveRegion(gCArchiveRegion string) ConfigOption {
+	return func(c *Config) {
+		c.GCArchiveRegion = gCArchiveRegion
+	}
+}
+
 // WithSpannerCredentialsFile returns an option that can set SpannerCredentialsFile on a Config
 func WithSpannerCredentialsFile(spannerCredentialsFile string) ConfigOption {
 	return func(c *Config) {
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jzelinskie/cobrautil/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/datastore/generate"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+)
+
+func RegisterGenerateFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().StringToInt("objects", nil, "number of objects of each type, e.g. user=100000,document=50000")
+	cmd.Flags().Int("default-objects", 1000, "number of objects of the types not given by --objects")
+	cmd.Flags().StringToString("density", nil, "mean number of subjects of each relation of each resource, e.g. document#viewer=3.5,folder#parent=1")
+	cmd.Flags().Float64("default-density", 2, "mean number of subjects of the relations not given by --density")
+	cmd.Flags().Int("max-depth", 3, "longest chain of objects nested in objects of their own type, such as folders in folders or groups in groups")
+	cmd.Flags().Float64("skew", 0, "exponent, greater than 1, of the Zipf distribution from which subjects are drawn; 0 draws them uniformly")
+	cmd.Flags().Float64("wildcard-ratio", 0, "fraction of the resources of relations allowing a wildcard subject which are given one")
+	cmd.Flags().Int64("seed", 1, "seed for generating the graph")
+	cmd.Flags().Int("batch-size", generate.DefaultBatchSize, "number of relationships to write per transaction")
+}
+
+func NewGenerateCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "generate <schema file>",
+		Short: "populate a datastore with a synthetic graph of relationships for a schema",
+		Long: `Writes the schema in the file to the datastore, replacing its schema, and then a synthetic graph of relationships for its relations, for load testing and capacity planning.
+
+The graph is shaped by the number of objects of each type, the mean number of subjects of each relation of each resource, drawn from an exponential distribution so that a few resources have many more subjects than most, and the depth to which objects are nested in objects of their own type. Subjects are drawn uniformly, or from a Zipf distribution with --skew, so that a few are much more popular than most.
+
+The graph is generated deterministically from the schema, its parameters and --seed. Relationships are touched rather than created, so an interrupted generation can be resumed by running the same command again.`,
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schemaText, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("unable to read schema: %w", err)
+			}
+
+			objects, err := cmd.Flags().GetStringToInt("objects")
+			if err != nil {
+				return err
+			}
+			rawDensities, err := cmd.Flags().GetStringToString("density")
+			if err != nil {
+				return err
+			}
+			densities := make(map[string]float64, len(rawDensities))
+			for relation, rawDensity := range rawDensities {
+				density, err := strconv.ParseFloat(rawDensity, 64)
+				if err != nil {
+					return fmt.Errorf("invalid density of `%s`: %w", relation, err)
+				}
+				densities[relation] = density
+			}
+
+			ds, err := datastore.NewDatastore(config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to init datastore: %w", err)
+			}
+			defer ds.Close()
+
+			start := time.Now()
+			result, err := generate.Run(cmd.Context(), ds, string(schemaText), generate.Parameters{
+				ObjectsPerType: objects,
+				DefaultObjects: cobrautil.MustGetInt(cmd, "default-objects"),
+				Densities:      densities,
+				DefaultDensity: cobrautil.MustGetFloat64(cmd, "default-density"),
+				MaxDepth:       cobrautil.MustGetInt(cmd, "max-depth"),
+				Skew:           cobrautil.MustGetFloat64(cmd, "skew"),
+				WildcardRatio:  cobrautil.MustGetFloat64(cmd, "wildcard-ratio"),
+				Seed:           cobrautil.MustGetInt64(cmd, "seed"),
+			}, generate.Options{
+				BatchSize: cobrautil.MustGetInt(cmd, "batch-size"),
+				OnProgress: func(written uint64) {
+					log.Info().Uint64("written", written).Msg("generation progress")
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("unable to generate relationships: %w", err)
+			}
+
+			for relation, count := range result.RelationshipsPerRelation {
+				log.Info().Str("relation", relation).Uint64("relationships", count).Msg("generated relationships")
+			}
+			log.Info().
+				Uint64("written", result.Relationships).
+				Stringer("duration", time.Since(start)).
+				Msg("generation complete")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jzelinskie/cobrautil/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/importer"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+)
+
+func RegisterImportFlags(cmd *cobra.Command) {
+	registerClientFlags(cmd, "address of the server to import into")
+
+	cmd.Flags().String("format", "", "format of the input (csv, ndjson, zanzibar); detected from the file extension if empty")
+	cmd.Flags().String("mapping", "", "comma-separated field=column pairs mapping relationship fields to columns or keys; 'quoted' values are literals for every record")
+	cmd.Flags().Int("batch-size", importer.DefaultBatchSize, "number of relationships imported per bulk import")
+	cmd.Flags().Uint64("max-retries", 5, "number of times a batch is retried after a transient error")
+	cmd.Flags().String("checkpoint", "", "file recording progress, used to resume a failed import (default \"<file>.checkpoint\"; none for stdin)")
+	cmd.Flags().Duration("progress-interval", 10*time.Second, "how often progress is logged")
+	cmd.Flags().String("source", "", "source recorded for the imported relationships, so they can be deleted together with the experimental DeleteRelationshipsFromSource API (default none)")
+}
+
+func NewImportCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <file|->",
+		Short: "import relationships from a CSV, NDJSON or Zanzibar tuple file",
+		Long: `Streams relationships from a CSV file with a header row, an NDJSON file with one object per line, or a file of Zanzibar tuples with one tuple per line, into a running server. Use "-" to read from stdin, which requires --format.
+
+Fields are read from the columns or keys resource_type, resource_id, relation, subject_type, subject_id and optionally subject_relation, caveat_name and caveat_context, which can be remapped with --mapping, e.g. --mapping "resource_type='document',resource_id=doc_id".
+
+Zanzibar tuples are written as object#relation@user, e.g. "document:readme#viewer@group:eng#member", as produced by "export". Users given as a bare ID are read as subjects of the type given by --mapping "subject_type='user'".
+
+Relationships are bulk imported in batches, which are retried after transient errors. A batch of which any relationship already exists is instead written with TOUCH operations. Progress is recorded in a checkpoint file after each batch, so a failed import can be resumed by running the same command again.`,
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.ExactArgs(1),
+		RunE:    importRun,
+	}
+}
+
+func importRun(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	format := importer.Format(cobrautil.MustGetString(cmd, "format"))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			format = importer.FormatCSV
+		case ".ndjson", ".jsonl":
+			format = importer.FormatNDJSON
+		case ".zanzibar", ".tuples":
+			format = importer.FormatZanzibar
+		default:
+			return fmt.Errorf("unable to detect the format of `%s`: specify --format", path)
+		}
+	}
+
+	mapping, err := importer.ParseMapping(cobrautil.MustGetString(cmd, "mapping"))
+	if err != nil {
+		return err
+	}
+
+	var input io.Reader = cmd.InOrStdin()
+	checkpointPath := cobrautil.MustGetString(cmd, "checkpoint")
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("unable to open input: %w", err)
+		}
+		defer f.Close()
+		input = f
+
+		if checkpointPath == "" {
+			checkpointPath = path + ".checkpoint"
+		}
+	}
+
+	reader, err := importer.NewReader(format, input, mapping)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialServer(cmd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	result, err := importer.Import(cmd.Context(), importer.NewImportClient(conn), reader, importer.Options{
+		BatchSize:        cobrautil.MustGetInt(cmd, "batch-size"),
+		MaxRetries:       cobrautil.MustGetUint64(cmd, "max-retries"),
+		CheckpointPath:   checkpointPath,
+		ProgressInterval: cobrautil.MustGetDuration(cmd, "progress-interval"),
+		Source:           cobrautil.MustGetString(cmd, "source"),
+	})
+	if err != nil {
+		if checkpointPath != "" {
+			log.Ctx(cmd.Context()).Info().Str("checkpoint", checkpointPath).Uint64("written", result.Written).Msg("import failed; run the same command again to resume")
+		}
+		return fmt.Errorf("unable to import relationships: %w", err)
+	}
+
+	log.Ctx(cmd.Context()).Info().
+		Uint64("skipped", result.Skipped).
+		Uint64("written", result.Written).
+		Stringer("duration", time.Since(start)).
+		Msg("import complete")
+	return nil
+}
+
+func RegisterExportFlags(cmd *cobra.Command) {
+	registerClientFlags(cmd, "address of the server to export from")
+
+	cmd.Flags().String("zookie", "", "zedtoken of the revision at which to export relationships (default fully consistent)")
+}
+
+func NewExportCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "export <file|->",
+		Short: "export relationships as Zanzibar tuples",
+		Long: fmt.Sprintf(`Writes every relationship of a running server to a file, or stdout ("-"), with one Zanzibar tuple per line, e.g. "document:readme#viewer@group:eng#member".
+
+Relationships are exported at a single revision, whose zedtoken is recorded in the file as its zookie. The file can be imported with "%s import --format zanzibar".`, programName),
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.ExactArgs(1),
+		RunE:    exportRun,
+	}
+}
+
+func exportRun(cmd *cobra.Command, args []string) error {
+	var output io.Writer = cmd.OutOrStdout()
+	var file *os.File
+	if path := args[0]; path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("unable to create output: %w", err)
+		}
+		defer f.Close()
+		output, file = f, f
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	result, err := importer.Export(cmd.Context(), client, output, cobrautil.MustGetString(cmd, "zookie"))
+	if err != nil {
+		return fmt.Errorf("unable to export relationships: %w", err)
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("unable to write output: %w", err)
+		}
+	}
+
+	log.Ctx(cmd.Context()).Info().
+		Uint64("exported", result.Exported).
+		Str("zookie", result.Zookie).
+		Stringer("duration", time.Since(start)).
+		Msg("export complete")
+	return nil
+}
@@ -3,6 +3,9 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
@@ -27,6 +30,7 @@ func RegisterMigrateFlags(cmd *cobra.Command) {
 	cmd.Flags().String("datastore-mysql-table-prefix", "", "prefix to add to the name of all mysql database tables")
 	cmd.Flags().Uint64("migration-backfill-batch-size", 1000, "number of items to migrate per iteration of a datastore backfill")
 	cmd.Flags().Duration("migration-timeout", 1*time.Hour, "defines a timeout for the execution of the migration, set to 1 hour by default")
+	cmd.Flags().Bool("dry-run", false, "print the migrations that would run, their statements and estimated lock impact, without applying them")
 }
 
 func NewMigrateCommand(programName string) *cobra.Command {
@@ -45,6 +49,7 @@ func migrateRun(cmd *cobra.Command, args []string) error {
 	dbURL := cobrautil.MustGetStringExpanded(cmd, "datastore-conn-uri")
 	timeout := cobrautil.MustGetDuration(cmd, "migration-timeout")
 	migrationBatachSize := cobrautil.MustGetUint64(cmd, "migration-backfill-batch-size")
+	dryRun := cobrautil.MustGetBool(cmd, "dry-run")
 
 	if datastoreEngine == "cockroachdb" {
 		log.Info().Msg("migrating cockroachdb datastore")
@@ -54,7 +59,7 @@ func migrateRun(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("unable to create migration driver for %s: %w", datastoreEngine, err)
 		}
-		return runMigration(cmd.Context(), migrationDriver, crdbmigrations.CRDBMigrations, args[0], timeout, migrationBatachSize)
+		return runMigration(cmd.Context(), migrationDriver, crdbmigrations.CRDBMigrations, args[0], timeout, migrationBatachSize, dryRun)
 	} else if datastoreEngine == "postgres" {
 		log.Info().Msg("migrating postgres datastore")
 
@@ -63,7 +68,7 @@ func migrateRun(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("unable to create migration driver for %s: %w", datastoreEngine, err)
 		}
-		return runMigration(cmd.Context(), migrationDriver, migrations.DatabaseMigrations, args[0], timeout, migrationBatachSize)
+		return runMigration(cmd.Context(), migrationDriver, migrations.DatabaseMigrations, args[0], timeout, migrationBatachSize, dryRun)
 	} else if datastoreEngine == "spanner" {
 		log.Info().Msg("migrating spanner datastore")
 
@@ -77,7 +82,7 @@ func migrateRun(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("unable to create migration driver for %s: %w", datastoreEngine, err)
 		}
-		return runMigration(cmd.Context(), migrationDriver, spannermigrations.SpannerMigrations, args[0], timeout, migrationBatachSize)
+		return runMigration(cmd.Context(), migrationDriver, spannermigrations.SpannerMigrations, args[0], timeout, migrationBatachSize, dryRun)
 	} else if datastoreEngine == "mysql" {
 		log.Info().Msg("migrating mysql datastore")
 
@@ -91,7 +96,7 @@ func migrateRun(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("unable to create migration driver for %s: %w", datastoreEngine, err)
 		}
-		return runMigration(cmd.Context(), migrationDriver, mysqlmigrations.Manager, args[0], timeout, migrationBatachSize)
+		return runMigration(cmd.Context(), migrationDriver, mysqlmigrations.Manager, args[0], timeout, migrationBatachSize, dryRun)
 	}
 
 	return fmt.Errorf("cannot migrate datastore engine type: %s", datastoreEngine)
@@ -104,8 +109,27 @@ func runMigration[D migrate.Driver[C, T], C any, T any](
 	targetRevision string,
 	timeout time.Duration,
 	backfillBatchSize uint64,
+	dryRun bool,
 ) error {
-	log.Info().Str("targetRevision", targetRevision).Msg("running migrations")
+	plan, err := manager.Plan(ctx, driver, targetRevision)
+	if err != nil {
+		return fmt.Errorf("unable to plan migration to `%s` revision: %w", targetRevision, err)
+	}
+
+	if dryRun {
+		printMigrationPlan(os.Stdout, targetRevision, plan)
+		if err := driver.Close(ctx); err != nil {
+			return fmt.Errorf("unable to close migration driver: %w", err)
+		}
+		return nil
+	}
+
+	versions := make([]string, 0, len(plan))
+	for _, migration := range plan {
+		versions = append(versions, migration.Version)
+	}
+
+	log.Info().Str("targetRevision", targetRevision).Strs("migrations", versions).Msg("running migrations")
 	ctxWithBatch := context.WithValue(ctx, migrate.BackfillBatchSize, backfillBatchSize)
 	ctx, cancel := context.WithTimeout(ctxWithBatch, timeout)
 	defer cancel()
@@ -119,6 +143,29 @@ func runMigration[D migrate.Driver[C, T], C any, T any](
 	return nil
 }
 
+func printMigrationPlan(w io.Writer, targetRevision string, plan []migrate.PlannedMigration) {
+	if len(plan) == 0 {
+		fmt.Fprintf(w, "datastore is already at revision `%s`; no migrations to run\n", targetRevision)
+		return
+	}
+
+	fmt.Fprintf(w, "%d migration(s) to run to reach revision `%s`:\n", len(plan), targetRevision)
+	for i, migration := range plan {
+		fmt.Fprintf(w, "\n%d. %s (replaces %q)\n", i+1, migration.Version, migration.Replaces)
+		if len(migration.Statements) == 0 {
+			fmt.Fprintln(w, "   no statements described")
+			continue
+		}
+
+		for _, stmt := range migration.Statements {
+			fmt.Fprintf(w, "\n   -- lock impact: %s\n", stmt.LockImpact)
+			for _, line := range strings.Split(strings.TrimSpace(stmt.SQL), "\n") {
+				fmt.Fprintf(w, "   %s\n", strings.TrimRight(line, " \t"))
+			}
+		}
+	}
+}
+
 func RegisterHeadFlags(cmd *cobra.Command) {
 	cmd.Flags().String("datastore-engine", "postgres", fmt.Sprintf(`type of datastore to initialize (%s)`, datastore.EngineOptions()))
 }
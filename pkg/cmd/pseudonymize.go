@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jzelinskie/cobrautil/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/datastore/pseudonymize"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/datastore/pseudonym"
+)
+
+func RegisterPseudonymizeFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().Int("batch-size", pseudonymize.DefaultBatchSize, "number of relationships to rewrite per transaction")
+	cmd.Flags().Duration("batch-interval", 0, "minimum time between transactions, to limit the write load of the migration")
+}
+
+func NewPseudonymizeCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pseudonymize",
+		Short: "replace the cleartext IDs of objects of the pseudonymized types in existing relationships",
+		Long: `Rewrites the relationships stored with the cleartext IDs of objects of the types given by --datastore-pseudonymized-object-types, replacing the IDs with their pseudonyms derived from --datastore-pseudonymization-key (memory and postgres drivers only).
+
+Servers configured with the same types and key write new relationships pseudonymized, but do not find those written before with cleartext IDs until they have been migrated, so the migration should be run right after enabling pseudonymization. It can be interrupted and run again, skipping the relationships already migrated.`,
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchSize := cobrautil.MustGetInt(cmd, "batch-size")
+			if batchSize <= 0 {
+				return fmt.Errorf("batch size must be positive, got %d", batchSize)
+			}
+
+			pseudonymizer, err := pseudonym.NewPseudonymizer([]byte(config.PseudonymizationKey), config.PseudonymizedObjectTypes)
+			if err != nil {
+				return fmt.Errorf("unable to configure object ID pseudonymization: %w", err)
+			}
+
+			// The migration reads the cleartext IDs, so it runs beneath the pseudonymizing proxy.
+			config.PseudonymizedObjectTypes = nil
+			config.RequestHedgingEnabled = false
+			config.SlowQueryThreshold = 0
+
+			ds, err := datastore.NewDatastore(config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to init datastore: %w", err)
+			}
+			defer ds.Close()
+
+			migrated, err := pseudonymize.Run(cmd.Context(), ds, pseudonymizer, pseudonymize.Options{
+				BatchSize:     batchSize,
+				BatchInterval: cobrautil.MustGetDuration(cmd, "batch-interval"),
+				OnProgress: func(migrated uint64) {
+					log.Info().Uint64("migrated", migrated).Msg("pseudonymization progress")
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("unable to pseudonymize relationships: %w", err)
+			}
+
+			log.Info().Uint64("migrated", migrated).Msg("pseudonymization complete")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jzelinskie/cobrautil/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/datastore/rename"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+)
+
+func RegisterRenameTypeFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().Int("batch-size", rename.DefaultBatchSize, "number of relationships to move per transaction")
+}
+
+func NewRenameTypeCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rename-type <from> <to>",
+		Short: "rename an object type in the schema and all of its relationships",
+		Long: `Renames an object type, e.g. "document" to "doc", rewriting its definition, the relations of other definitions allowing it as a subject type, and every relationship whose resource or subject is of the type.
+
+The renamed definition is first written alongside the original, after which relationships are moved in batches of separate transactions, and the original definition is finally removed. Writes to the type should be paused while it is renamed. An interrupted rename is resumed by running the same command again.`,
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchSize := cobrautil.MustGetInt(cmd, "batch-size")
+			if batchSize <= 0 {
+				return fmt.Errorf("batch size must be positive, got %d", batchSize)
+			}
+
+			ds, err := datastore.NewDatastore(config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to init datastore: %w", err)
+			}
+			defer ds.Close()
+
+			moved, err := rename.Run(cmd.Context(), ds, args[0], args[1], rename.Options{
+				BatchSize: batchSize,
+				OnProgress: func(progress rename.Progress) {
+					log.Info().Str("phase", string(progress.Phase)).Uint64("moved", progress.Moved).Msg("rename progress")
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("unable to rename type: %w", err)
+			}
+
+			log.Info().Str("from", args[0]).Str("to", args[1]).Uint64("moved", moved).Msg("rename complete")
+			return nil
+		},
+	}
+}
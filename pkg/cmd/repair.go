@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jzelinskie/cobrautil/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/datastore/repair"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func RegisterRepairFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().Bool("fix", false, "delete the relationships of fixable issues, rather than only reporting them")
+	cmd.Flags().Int("batch-size", repair.DefaultBatchSize, "number of relationships to delete per transaction")
+	cmd.Flags().Duration("batch-interval", 0, "minimum time between the transactions deleting relationships, to limit the write load of --fix")
+}
+
+func NewRepairCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "repair",
+		Short: "check the datastore for inconsistent data, optionally fixing it",
+		Long: `Scans the datastore for relationships referencing undefined resource types, relations, subject types or caveats, and for rows with impossible MVCC metadata (postgres driver only), reporting each issue found.
+
+With --fix, the affected relationships are deleted in batches, optionally spaced by --batch-interval. Running servers expose the same checks of relationships through the experimental FindOrphanedRelationships and DeleteOrphanedRelationships APIs. Relations whose schema references an undefined caveat are reported, but must be fixed by writing a corrected schema.`,
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchSize := cobrautil.MustGetInt(cmd, "batch-size")
+			if batchSize <= 0 {
+				return fmt.Errorf("batch size must be positive, got %d", batchSize)
+			}
+
+			// Neither hedging nor slow query logging benefit a one-off scan.
+			config.RequestHedgingEnabled = false
+			config.SlowQueryThreshold = 0
+
+			ds, err := datastore.NewDatastore(config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to init datastore: %w", err)
+			}
+			defer ds.Close()
+
+			summary, err := repair.Run(cmd.Context(), ds, repair.Options{
+				Fix:           cobrautil.MustGetBool(cmd, "fix"),
+				BatchSize:     batchSize,
+				BatchInterval: cobrautil.MustGetDuration(cmd, "batch-interval"),
+				OnIssue: func(issue repair.Issue) {
+					event := log.Warn().Str("kind", string(issue.Kind)).Bool("fixable", issue.Fixable)
+					if issue.Relationship != nil {
+						event = event.Str("relationship", tuple.String(issue.Relationship))
+					}
+					event.Msg(issue.Detail)
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("unable to check datastore: %w", err)
+			}
+
+			event := log.Info().Uint64("fixed", summary.Fixed)
+			for kind, count := range summary.Issues {
+				event = event.Uint64(string(kind), count)
+			}
+			event.Msg("repair complete")
+			return nil
+		},
+	}
+}
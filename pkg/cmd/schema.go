@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/authzed-go/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/jzelinskie/cobrautil/v2"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/authzed/spicedb/internal/schemacheck"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/schemacontroller"
+)
+
+var errSchemaCheckFailed = errors.New("schema check failed")
+
+func NewSchemaCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "schema validation and management commands",
+	}
+}
+
+func RegisterSchemaCheckFlags(cmd *cobra.Command) {
+	cmd.Flags().String("output", "text", "output format (text, json)")
+	cmd.Flags().Bool("fail-on-warnings", false, "exit with an error if any lint warnings are found")
+	cmd.Flags().String("diff-endpoint", "", "address of a running server whose schema is diffed against the file (e.g. localhost:50051)")
+	cmd.Flags().String("diff-token", "", "preshared key used to read the schema from --diff-endpoint")
+	cmd.Flags().Bool("diff-insecure", false, "connect to --diff-endpoint without TLS")
+	cmd.Flags().Bool("diff-skip-verify-ca", false, "connect to --diff-endpoint with TLS, without verifying its certificate")
+}
+
+func NewSchemaCheckCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check <schema file>",
+		Short: "compile, type-check and lint a schema file",
+		Long: `Compiles and type-checks a schema file offline, then lints it for constructs which are valid but likely to be mistaken. Use "-" to read the schema from stdin.
+
+With --diff-endpoint, the schema is also diffed against the schema of a running server. The command exits with an error if the schema is invalid, or with --fail-on-warnings, if any warnings are found.`,
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.ExactArgs(1),
+		RunE:    schemaCheckRun,
+	}
+}
+
+func schemaCheckRun(cmd *cobra.Command, args []string) error {
+	output := cobrautil.MustGetString(cmd, "output")
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unknown output format `%s`: expected text or json", output)
+	}
+
+	var contents []byte
+	var err error
+	if args[0] == "-" {
+		contents, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		contents, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read schema: %w", err)
+	}
+
+	result, err := schemacheck.Check(cmd.Context(), string(contents))
+	if err != nil {
+		return fmt.Errorf("unable to check schema: %w", err)
+	}
+
+	if endpoint := cobrautil.MustGetString(cmd, "diff-endpoint"); endpoint != "" && !result.HasErrors() {
+		existing, err := readServerSchema(cmd, endpoint)
+		if err != nil {
+			return err
+		}
+
+		result.Changes, err = schemacheck.Diff(existing, string(contents))
+		if err != nil {
+			return fmt.Errorf("unable to diff schema: %w", err)
+		}
+	}
+
+	if output == "json" {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		printSchemaCheckResult(cmd.OutOrStdout(), args[0], result)
+	}
+
+	if result.HasErrors() || (result.HasWarnings() && cobrautil.MustGetBool(cmd, "fail-on-warnings")) {
+		return errSchemaCheckFailed
+	}
+	return nil
+}
+
+func readServerSchema(cmd *cobra.Command, endpoint string) (string, error) {
+	client, err := authzed.NewClient(endpoint, clientDialOptions(
+		cobrautil.MustGetString(cmd, "diff-token"),
+		cobrautil.MustGetBool(cmd, "diff-insecure"),
+		cobrautil.MustGetBool(cmd, "diff-skip-verify-ca"),
+	)...)
+	if err != nil {
+		return "", fmt.Errorf("unable to connect to %s: %w", endpoint, err)
+	}
+
+	resp, err := client.ReadSchema(cmd.Context(), &v1.ReadSchemaRequest{})
+	if err != nil {
+		return "", fmt.Errorf("unable to read schema from %s: %w", endpoint, err)
+	}
+	return resp.SchemaText, nil
+}
+
+// registerClientFlags adds the flags for connecting to a running server, read by newClient
+// and dialServer.
+func registerClientFlags(cmd *cobra.Command, endpointUsage string) {
+	cmd.Flags().String("endpoint", "localhost:50051", endpointUsage)
+	cmd.Flags().String("token", "", "preshared key used to authenticate with the server")
+	cmd.Flags().Bool("insecure", false, "connect to the server without TLS")
+	cmd.Flags().Bool("skip-verify-ca", false, "connect to the server with TLS, without verifying its certificate")
+}
+
+// newClient returns a client of the server given by the flags added by registerClientFlags.
+func newClient(cmd *cobra.Command) (*authzed.Client, error) {
+	endpoint := cobrautil.MustGetString(cmd, "endpoint")
+	client, err := authzed.NewClient(endpoint, clientDialOptionsFromFlags(cmd)...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", endpoint, err)
+	}
+	return client, nil
+}
+
+// dialServer returns a connection to the server given by the flags added by
+// registerClientFlags, for the services which newClient does not cover.
+func dialServer(cmd *cobra.Command) (*grpc.ClientConn, error) {
+	endpoint := cobrautil.MustGetString(cmd, "endpoint")
+	conn, err := grpc.Dial(endpoint, clientDialOptionsFromFlags(cmd)...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to %s: %w", endpoint, err)
+	}
+	return conn, nil
+}
+
+func clientDialOptionsFromFlags(cmd *cobra.Command) []grpc.DialOption {
+	return clientDialOptions(
+		cobrautil.MustGetString(cmd, "token"),
+		cobrautil.MustGetBool(cmd, "insecure"),
+		cobrautil.MustGetBool(cmd, "skip-verify-ca"),
+	)
+}
+
+// clientDialOptions returns the options for dialing a server with the given preshared key,
+// which may be empty.
+func clientDialOptions(token string, insecureConn, skipVerifyCA bool) []grpc.DialOption {
+	var opts []grpc.DialOption
+	if insecureConn {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if token != "" {
+			opts = append(opts, grpcutil.WithInsecureBearerToken(token))
+		}
+	} else {
+		opts = append(opts, grpcutil.WithSystemCerts(skipVerifyCA))
+		if token != "" {
+			opts = append(opts, grpcutil.WithBearerToken(token))
+		}
+	}
+	return opts
+}
+
+func printSchemaCheckResult(w io.Writer, filename string, result schemacheck.Result) {
+	for _, diagnostic := range result.Diagnostics {
+		fmt.Fprintf(w, "%s:%d:%d: %s: %s [%s]\n",
+			filename, diagnostic.Line, diagnostic.Column, diagnostic.Severity, diagnostic.Message, diagnostic.Rule)
+	}
+
+	for _, change := range result.Changes {
+		if change.Name != "" {
+			fmt.Fprintf(w, "%s: %s `%s`\n", change.Definition, change.Type, change.Name)
+		} else {
+			fmt.Fprintf(w, "%s: %s\n", change.Definition, change.Type)
+		}
+	}
+
+	if len(result.Diagnostics) == 0 {
+		fmt.Fprintf(w, "%s: schema is valid\n", filename)
+	}
+}
+
+func RegisterSchemaControllerFlags(cmd *cobra.Command) {
+	registerClientFlags(cmd, "address of the server whose schema is managed")
+
+	cmd.Flags().String("kubeconfig", "", "path to a kubeconfig file (default in-cluster configuration)")
+	cmd.Flags().String("namespace", "default", "namespace of the SpiceDBSchema resource")
+	cmd.Flags().String("name", "spicedb", "name of the SpiceDBSchema resource")
+	cmd.Flags().Duration("resync-period", 5*time.Minute, "how often the schema is reconciled when the resource has not changed")
+	cmd.Flags().Bool("print-crd", false, "print the SpiceDBSchema custom resource definition and exit")
+}
+
+func NewSchemaControllerCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "controller",
+		Short: "write the schema of a SpiceDBSchema Kubernetes resource to a running server",
+		Long: fmt.Sprintf(`Watches a SpiceDBSchema custom resource and writes its spec.schema to a running server whenever it changes, reporting the outcome in the status of the resource.
+
+Schemas with destructive changes, which remove definitions, relations, permissions, caveats or allowed subject types, are only written if spec.allowDestructiveChanges is set. The custom resource definition can be installed with "%s schema controller --print-crd | kubectl apply -f -".`, programName),
+		PreRunE: server.DefaultPreRunE(programName),
+		Args:    cobra.NoArgs,
+		RunE:    schemaControllerRun,
+	}
+}
+
+func schemaControllerRun(cmd *cobra.Command, _ []string) error {
+	if cobrautil.MustGetBool(cmd, "print-crd") {
+		_, err := io.WriteString(cmd.OutOrStdout(), schemacontroller.CustomResourceDefinition)
+		return err
+	}
+
+	restConfig, err := clientcmd.BuildConfigFromFlags("", cobrautil.MustGetString(cmd, "kubeconfig"))
+	if err != nil {
+		return fmt.Errorf("unable to load Kubernetes configuration: %w", err)
+	}
+	resources, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("unable to create Kubernetes client: %w", err)
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	controller := schemacontroller.New(resources, client, schemacontroller.Options{
+		Namespace:    cobrautil.MustGetString(cmd, "namespace"),
+		Name:         cobrautil.MustGetString(cmd, "name"),
+		ResyncPeriod: cobrautil.MustGetDuration(cmd, "resync-period"),
+	})
+	return controller.Run(SignalContextWithGracePeriod(cmd.Context(), 0))
+}
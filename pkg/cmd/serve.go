@@ -6,6 +6,10 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/authzed/spicedb/internal/decisionlog"
+	"github.com/authzed/spicedb/internal/jobs"
+	"github.com/authzed/spicedb/internal/profiling"
+	"github.com/authzed/spicedb/internal/runtimeconfig"
 	"github.com/authzed/spicedb/internal/telemetry"
 	"github.com/authzed/spicedb/pkg/cmd/datastore"
 	"github.com/authzed/spicedb/pkg/cmd/server"
@@ -38,13 +42,23 @@ var (
 )
 
 func RegisterServeFlags(cmd *cobra.Command, config *server.Config) {
+	server.RegisterConfigFileFlag(cmd.Flags())
+
 	// Flags for the gRPC API server
 	util.RegisterGRPCServerFlags(cmd.Flags(), &config.GRPCServer, "grpc", "gRPC", ":50051", true)
+	cmd.Flags().IntVar(&config.GRPCGzipLevel, "grpc-gzip-level", 0, "compression level (1-9) of gRPC responses to requests compressed with gzip (0 for the default level)")
+	cmd.Flags().IntVar(&config.GRPCZstdLevel, "grpc-zstd-level", 0, "compression level (1-22) of gRPC responses to requests compressed with zstd (0 for the default level)")
+	cmd.Flags().BoolVar(&config.GRPCServer.WebEnabled, "grpc-web-enabled", false, "serve gRPC-Web for browser clients on the gRPC listener (connections are then not limited by --grpc-max-conn-age, and --grpc-max-workers and the --grpc-keepalive flags cannot be set)")
+	cmd.Flags().StringSliceVar(&config.GRPCServer.WebAllowedOrigins, "grpc-web-allowed-origins", []string{"*"}, "origins from which browsers may call the API with gRPC-Web, defaults to all origins")
 	cmd.Flags().StringSliceVar(&config.PresharedKey, PresharedKeyFlag, []string{}, "preshared key(s) to require for authenticated requests")
-	cmd.Flags().DurationVar(&config.ShutdownGracePeriod, "grpc-shutdown-grace-period", 0*time.Second, "amount of time after receiving sigint to continue serving")
-	if err := cmd.MarkFlagRequired(PresharedKeyFlag); err != nil {
-		panic("failed to mark flag as required: " + err.Error())
+	cmd.Flags().StringVar(&config.PresharedKeyFile, PresharedKeyFlag+"-file", "", "path to a file of additional preshared keys, one per line, which is reloaded when it changes")
+	if err := runtimeconfig.MarkSensitive(cmd.Flags(), PresharedKeyFlag); err != nil {
+		panic("failed to mark flag sensitive: " + err.Error())
 	}
+	cmd.Flags().DurationVar(&config.ShutdownGracePeriod, "grpc-shutdown-grace-period", 0*time.Second, "amount of time after receiving sigint to continue serving, while reporting not serving to health checks and dispatching peers so that they stop sending new requests")
+	cmd.Flags().DurationVar(&config.ShutdownDrainTimeout, "grpc-shutdown-drain-timeout", 0, "amount of time after the grace period to wait for requests in flight to complete before closing connections (0 waits indefinitely)")
+
+	cmd.Flags().BoolVar(&config.ReadOnly, "readonly", false, "serve only reads, rejecting every write with FAILED_PRECONDITION and the reason ERROR_REASON_SERVICE_READ_ONLY; implies --datastore-readonly")
 
 	// Flags for the datastore
 	datastore.RegisterDatastoreFlags(cmd, &config.DatastoreConfig)
@@ -58,6 +72,7 @@ func RegisterServeFlags(cmd *cobra.Command, config *server.Config) {
 
 	// Flags for parsing and validating schemas.
 	cmd.Flags().BoolVar(&config.SchemaPrefixesRequired, "schema-prefixes-required", false, "require prefixes on all object definitions in schemas")
+	cmd.Flags().DurationVar(&config.SchemaRemovedDefinitionRetention, "schema-removed-definition-retention", 0, "period for which object definitions removed by WriteSchema are retained with their relationships, to be restored with the experimental RestoreNamespace API; if zero, definitions can only be removed once they have no relationships")
 
 	// Flags for HTTP gateway
 	util.RegisterHTTPServerFlags(cmd.Flags(), &config.HTTPGateway, "http", "http", ":8443", false)
@@ -77,6 +92,13 @@ func RegisterServeFlags(cmd *cobra.Command, config *server.Config) {
 	if err := cmd.Flags().MarkHidden("http-cors-allowed-origins"); err != nil {
 		panic("failed to mark flag as hidden: " + err.Error())
 	}
+	cmd.Flags().BoolVar(&config.HTTPGatewayOpenFGAEnabled, "http-openfga-enabled", false, "serve the OpenFGA Check, ListObjects and Write HTTP API on the http gateway, for applications migrating from OpenFGA")
+	cmd.Flags().BoolVar(&config.HTTPGatewayGraphQLEnabled, "http-graphql-enabled", false, "serve a GraphQL endpoint for checking permissions, looking up resources and reading relationships on the http gateway at /graphql")
+	cmd.Flags().BoolVar(&config.HTTPGatewaySCIMEnabled, "http-scim-enabled", false, "serve the SCIM v2 provisioning API on the http gateway under /scim/v2, writing group memberships of users as relationships")
+	cmd.Flags().StringVar(&config.HTTPGatewaySCIMConfig.UserType, "http-scim-user-type", "user", "object type of the users provisioned through SCIM")
+	cmd.Flags().StringVar(&config.HTTPGatewaySCIMConfig.GroupType, "http-scim-group-type", "group", "object type of the groups provisioned through SCIM")
+	cmd.Flags().StringVar(&config.HTTPGatewaySCIMConfig.MemberRelation, "http-scim-member-relation", "member", "relation of the groups provisioned through SCIM to their member users")
+	cmd.Flags().StringVar(&config.HTTPGatewaySCIMConfig.Source, "http-scim-relationship-source", "", "source recorded for the memberships provisioned through SCIM, so they can be deleted together with the experimental DeleteRelationshipsFromSource API (default none)")
 
 	// Flags for configuring the dispatch server
 	util.RegisterGRPCServerFlags(cmd.Flags(), &config.DispatchServer, "dispatch-cluster", "dispatch", ":50053", false)
@@ -88,12 +110,45 @@ func RegisterServeFlags(cmd *cobra.Command, config *server.Config) {
 	cmd.Flags().StringVar(&config.DispatchUpstreamAddr, "dispatch-upstream-addr", "", "upstream grpc address to dispatch to")
 	cmd.Flags().StringVar(&config.DispatchUpstreamCAPath, "dispatch-upstream-ca-path", "", "local path to the TLS CA used when connecting to the dispatch cluster")
 	cmd.Flags().Uint16Var(&config.DispatchConcurrencyLimit, "dispatch-concurrency-limit", 50, "maximum number of parallel goroutines to create for each request or subrequest")
+	cmd.Flags().Uint64Var(&config.DispatchSuperNodeThreshold, "dispatch-super-node-threshold", 10_000, "number of relationships of a resource on a relation past which checks of the resource start from the subject (0 to disable)")
 
 	// Flags for configuring API behavior
 	cmd.Flags().BoolVar(&config.DisableV1SchemaAPI, "disable-v1-schema-api", false, "disables the V1 schema API")
 	cmd.Flags().BoolVar(&config.DisableVersionResponse, "disable-version-response", false, "disables version response support in the API")
 	cmd.Flags().Uint16Var(&config.MaximumUpdatesPerWrite, "write-relationships-max-updates-per-call", 1000, "maximum number of updates allowed for WriteRelationships calls")
 	cmd.Flags().Uint16Var(&config.MaximumPreconditionCount, "update-relationships-max-preconditions-per-call", 1000, "maximum number of preconditions allowed for WriteRelationships and DeleteRelationships calls")
+	cmd.Flags().StringSliceVar(&config.CardinalitySoftLimits, "write-relationships-soft-cardinality-limits", []string{}, `limits of the form "resource_type#relation=count" on the relationships of a single resource on a relation, reported in metrics and logs when exceeded by WriteRelationships calls; each write to a limited relation counts up to the greatest limit of the relation`)
+	cmd.Flags().StringSliceVar(&config.CardinalityHardLimits, "write-relationships-hard-cardinality-limits", []string{}, `limits of the form "resource_type#relation=count" on the relationships of a single resource on a relation, failing WriteRelationships calls which would exceed them`)
+	cmd.Flags().StringSliceVar(&config.PeerDatastoreIDs, "peer-datastore-ids", []string{}, "unique IDs of the datastores of other deployments, kept in sync with this one by external replication, whose zedtokens are accepted with at_least_as_fresh consistency and served at a revision at least as recent as their time")
+	cmd.Flags().DurationVar(&config.MaximumPeerClockSkew, "max-peer-clock-skew", 500*time.Millisecond, "maximum time by which the zedtokens of peer datastores may be ahead of the clock of this server; calls wait for the clock to catch up to such zedtokens")
+	cmd.Flags().DurationVar(&config.ReadYourWritesTTL, "read-your-writes-ttl", 0, "if set, the revision of the last write of each caller, identified by its bearer token and io.spicedb.requestcaller header, is tracked for this long, and the caller's calls with minimize_latency consistency are served at least as fresh as it, so that callers read their own writes without passing zedtokens; writes are tracked per server, so callers balanced across servers must still pass zedtokens (0 to disable)")
+	cmd.Flags().DurationVar(&config.MaximumRevisionPinTTL, "max-revision-pin-ttl", 1*time.Hour, "maximum TTL of the revisions pinned with the experimental PinRevision API, which are retained past the garbage collection window until released or expired")
+	cmd.Flags().IntVar(&config.OrphanDeletionBatchSize, "orphan-deletion-batch-size", 1000, "number of relationships deleted per transaction by the experimental DeleteOrphanedRelationships API")
+	cmd.Flags().DurationVar(&config.OrphanDeletionInterval, "orphan-deletion-batch-interval", 1*time.Second, "minimum time between the transactions of the experimental DeleteOrphanedRelationships API, limiting the rate at which it deletes relationships")
+	cmd.Flags().IntVar(&config.StreamingCheckConcurrency, "streaming-check-concurrency", 50, "number of checks of a single call to the experimental StreamingCheckPermission API run concurrently")
+	cmd.Flags().IntVar(&config.BulkCheckConcurrency, "bulk-check-concurrency", 50, "number of dispatches of a single call to the experimental BulkCheckPermission API run concurrently")
+	cmd.Flags().IntVar(&config.AccessReportConcurrency, "access-report-concurrency", 50, "number of resources of a single call to the experimental AccessReport API whose paths are found concurrently")
+	cmd.Flags().IntVar(&config.DeletionImpactConcurrency, "deletion-impact-concurrency", 50, "number of dispatches of each lookup of a single call to the experimental DeleteRelationshipsImpact API without its relationships run concurrently")
+	cmd.Flags().IntVar(&config.PrefetchConcurrency, "prefetch-concurrency", 10, "number of checks prefetched with the experimental PrefetchChecks API run concurrently, across all calls")
+	cmd.Flags().IntVar(&config.CanarySchemaConcurrency, "canary-schema-concurrency", 10, "number of sampled checks evaluated concurrently against the canary schema set with the experimental SetCanarySchema API; further sampled checks are skipped")
+	cmd.Flags().DurationVar(&config.JobsHeartbeatInterval, "jobs-heartbeat-interval", jobs.DefaultHeartbeatInterval, "interval at which the progress of jobs started with the experimental StartJob API is stored and their cancellation is checked; jobs whose progress is not stored for several intervals are reported as interrupted")
+	cmd.Flags().DurationVar(&config.MaximumRequestedStaleness, "max-requested-staleness", 0, "maximum staleness callers can request for the revision of minimize_latency calls with the io.spicedb.requestmaxstaleness header, so they share revisions over wider windows than the quantization interval; must be well under the garbage collection window (0 to ignore the header)")
+	cmd.Flags().BoolVar(&config.SubstituteExpiredRevisions, "substitute-expired-revisions", false, "serve calls at an exact snapshot or in a session whose revision has been garbage collected at the nearest available revision, flagged in the io.spicedb.respmeta.revisionsubstituted response header, rather than failing them; callers may opt in per call with the io.spicedb.requestrevisionsubstitution header")
+	cmd.Flags().Uint64Var(&config.MaximumLookupMemoryBytes, "lookup-max-memory-bytes", 0, "approximate maximum number of bytes of intermediate results held in memory by a single LookupResources or LookupSubjects call (0 for no limit); LookupResources returns the results found so far once reached, and LookupSubjects spills to disk")
+	cmd.Flags().StringVar(&config.LookupSpillDirectory, "lookup-spill-dir", "", "directory in which LookupSubjects spills intermediate results exceeding --lookup-max-memory-bytes (defaults to the system temporary directory)")
+	cmd.Flags().DurationVar(&config.NamespaceExperimentsRefreshInterval, "namespace-experiments-refresh-interval", server.DefaultNamespaceExperimentsRefreshInterval, "interval at which the experimental behaviors enabled per namespace with the experimental SetNamespaceExperiment API are reloaded from the datastore")
+	cmd.Flags().BoolVar(&config.SortLookupResults, "lookup-sort-results", false, "send the results of LookupResources and LookupSubjects sorted by ID once each lookup completes, instead of as they are found")
+	cmd.Flags().StringVar(&config.DecisionLogURI, "decision-log-uri", "", `if set, a sample of the decisions of checks is recorded with their inputs, revision and schema version as files at this location, e.g. "s3://bucket/prefix" or "file:///var/lib/spicedb/decisions"`)
+	cmd.Flags().StringVar(&config.DecisionLogS3Endpoint, "decision-log-s3-endpoint", "", "endpoint of an S3-compatible API to which to write the decision log, if not AWS")
+	cmd.Flags().StringVar(&config.DecisionLogS3Region, "decision-log-s3-region", "", "region of the S3 bucket to which to write the decision log")
+	cmd.Flags().Float64Var(&config.DecisionLogSampleRate, "decision-log-sample-rate", 0.01, "fraction of the decisions of checks recorded in the decision log, in (0, 1]")
+	cmd.Flags().StringVar(&config.DecisionLogFormat, "decision-log-format", string(decisionlog.FormatParquet), `format of the files of the decision log: "parquet" for columnar analysis, or "ndjson" for one JSON decision per line, comparable with diff`)
+	cmd.Flags().DurationVar(&config.DecisionLogFlushInterval, "decision-log-flush-interval", decisionlog.DefaultFlushInterval, "maximum interval between the writes of the files of the decision log")
+	cmd.Flags().IntVar(&config.DecisionLogBatchSize, "decision-log-batch-size", decisionlog.DefaultBatchSize, "maximum number of decisions per file of the decision log; decisions recorded while a full batch is being written are dropped")
+	cmd.Flags().StringVar(&config.AdmissionWebhookAddr, "admission-webhook-addr", "", "address of an AdmissionService admitting or rejecting the writes of relationships and schemas before they are committed")
+	cmd.Flags().StringVar(&config.AdmissionWebhookCAPath, "admission-webhook-tls-ca-path", "", "path to the CA certificate of the admission webhook; if empty, the webhook is called without TLS")
+	cmd.Flags().DurationVar(&config.AdmissionWebhookTimeout, "admission-webhook-timeout", 1*time.Second, "maximum duration of each call to the admission webhook")
+	cmd.Flags().BoolVar(&config.AdmissionWebhookFailOpen, "admission-webhook-fail-open", false, "admit writes when the admission webhook fails or cannot be reached, rather than failing them")
 
 	cmd.Flags().BoolVar(&config.V1SchemaAdditiveOnly, "testing-only-schema-additive-writes", false, "append new definitions to the existing schema, rather than overwriting it")
 	if err := cmd.Flags().MarkHidden("testing-only-schema-additive-writes"); err != nil {
@@ -109,6 +164,11 @@ func RegisterServeFlags(cmd *cobra.Command, config *server.Config) {
 	cmd.Flags().StringVar(&config.TelemetryCAOverridePath, "telemetry-ca-override-path", "", "TODO")
 	cmd.Flags().DurationVar(&config.TelemetryInterval, "telemetry-interval", telemetry.DefaultInterval, "approximate period between telemetry reports, minimum 1 minute")
 
+	// Flags for continuous profiling
+	cmd.Flags().StringVar(&config.ContinuousProfilingEndpoint, "profiling-push-endpoint", "", "Pyroscope-compatible endpoint to which CPU and heap profiles are continuously pushed, empty string to disable")
+	cmd.Flags().StringVar(&config.ContinuousProfilingApplicationName, "profiling-push-application-name", profiling.DefaultApplicationName, "application name under which profiles are pushed")
+	cmd.Flags().DurationVar(&config.ContinuousProfilingInterval, "profiling-push-interval", profiling.DefaultInterval, "length of each pushed profile")
+
 	cmd.Flags().BoolVar(&config.ExperimentalCaveatsEnabled, "experiment-enable-caveats", false, "if true, experimental support for caveats is enabled; note that these are not fully implemented and may break")
 	if err := cmd.Flags().MarkDeprecated("experiment-enable-caveats", "this is an experiment"); err != nil {
 		panic("failed to mark flag deprecated: " + err.Error())
@@ -122,13 +182,15 @@ func NewServeCommand(programName string, config *server.Config) *cobra.Command {
 		Long:    "A database that stores, computes, and validates application permissions",
 		PreRunE: server.DefaultPreRunE(programName),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			config.ResolvedFlags = runtimeconfig.ResolveFlags(cmd.Flags(), programName)
 			server, err := config.Complete()
 			if err != nil {
 				return err
 			}
-			signalctx := SignalContextWithGracePeriod(
+			signalctx := SignalContextWithDrain(
 				context.Background(),
 				config.ShutdownGracePeriod,
+				server.Drain,
 			)
 			return server.Run(signalctx)
 		},
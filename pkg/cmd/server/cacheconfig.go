@@ -11,6 +11,7 @@ import (
 	"github.com/pbnjay/memory"
 	"github.com/spf13/pflag"
 
+	"github.com/authzed/spicedb/internal/datastore/common/revisions"
 	"github.com/authzed/spicedb/pkg/cache"
 )
 
@@ -39,25 +40,35 @@ func (cc *CacheConfig) Complete() (cache.Cache, error) {
 		return cache.NoopCache(), nil
 	}
 
+	maxCost, err := parseMaxCost(cc.MaxCost)
+	if err != nil {
+		return nil, err
+	}
+
+	return cache.NewCache(&cache.Config{
+		MaxCost:     int64(maxCost),
+		NumCounters: cc.NumCounters,
+		Metrics:     cc.Metrics,
+	})
+}
+
+// parseMaxCost parses a cache size given either in bytes or as a percentage of the
+// free memory measured at startup.
+func parseMaxCost(str string) (uint64, error) {
 	var (
 		maxCost uint64
 		err     error
 	)
 
-	if strings.HasSuffix(cc.MaxCost, "%") {
-		maxCost, err = parsePercent(cc.MaxCost, freeMemory)
+	if strings.HasSuffix(str, "%") {
+		maxCost, err = parsePercent(str, freeMemory)
 	} else {
-		maxCost, err = humanize.ParseBytes(cc.MaxCost)
+		maxCost, err = humanize.ParseBytes(str)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("error parsing cache max memory: `%s`: %w", cc.MaxCost, err)
+		return 0, fmt.Errorf("error parsing cache max memory: `%s`: %w", str, err)
 	}
-
-	return cache.NewCache(&cache.Config{
-		MaxCost:     int64(maxCost),
-		NumCounters: cc.NumCounters,
-		Metrics:     cc.Metrics,
-	})
+	return maxCost, nil
 }
 
 func parsePercent(str string, freeMem uint64) (uint64, error) {
@@ -83,3 +94,15 @@ func RegisterCacheFlags(flags *pflag.FlagSet, flagPrefix string, config, default
 	flags.BoolVar(&config.Metrics, flagPrefix+"-metrics", defaults.Metrics, "enable cache metrics")
 	flags.BoolVar(&config.Enabled, flagPrefix+"-enabled", defaults.Enabled, "enable caching")
 }
+
+// dispatchCacheStats returns the combined statistics of the dispatch caches.
+func dispatchCacheStats(caches []cache.Cache) revisions.CacheStatsFunc {
+	return func() (hits, misses uint64) {
+		for _, c := range caches {
+			metrics := c.GetMetrics()
+			hits += metrics.Hits()
+			misses += metrics.Misses()
+		}
+		return hits, misses
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jzelinskie/cobrautil/v2"
+	"github.com/pelletier/go-toml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileFlag is the name of the flag which names a configuration file.
+const ConfigFileFlag = "config"
+
+// RegisterConfigFileFlag registers the flag used to provide a YAML or TOML configuration
+// file for the other flags of a command.
+func RegisterConfigFileFlag(flags *pflag.FlagSet) {
+	flags.String(ConfigFileFlag, "", "path to a YAML or TOML file of flag values; flags and environment variables take precedence over the file")
+}
+
+// ConfigFilePreRunE sets the flags of a command which were not set on the command line or
+// in the environment from the configuration file named by the config flag, if the command
+// has one. It must run after the flags have been synced with the environment.
+func ConfigFilePreRunE() cobrautil.CobraRunFunc {
+	return func(cmd *cobra.Command, args []string) error {
+		flag := cmd.Flags().Lookup(ConfigFileFlag)
+		if flag == nil || flag.Value.String() == "" {
+			return nil
+		}
+		return ApplyConfigFile(cmd.Flags(), flag.Value.String())
+	}
+}
+
+// ApplyConfigFile reads the configuration file at path and sets the value of every flag
+// it contains that has not already been changed. Keys are flag names, and may be nested:
+// `datastore: {engine: postgres}` sets `--datastore-engine`.
+func ApplyConfigFile(flags *pflag.FlagSet, path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	var values map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(contents, &values); err != nil {
+			return fmt.Errorf("unable to parse config file `%s`: %w", path, err)
+		}
+	case ".toml":
+		tree, err := toml.LoadBytes(contents)
+		if err != nil {
+			return fmt.Errorf("unable to parse config file `%s`: %w", path, err)
+		}
+		values = tree.ToMap()
+	default:
+		return fmt.Errorf("unsupported config file extension `%s`: expected .yaml, .yml or .toml", ext)
+	}
+
+	return applyConfigValues(flags, "", "", values)
+}
+
+func applyConfigValues(flags *pflag.FlagSet, keyPrefix, flagPrefix string, values map[string]any) error {
+	// Apply keys in a stable order, so the same file always reports the same error.
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := values[key]
+		configKey, flagName := key, key
+		if keyPrefix != "" {
+			configKey = keyPrefix + "." + key
+			flagName = flagPrefix + "-" + key
+		}
+
+		if flagName == ConfigFileFlag {
+			return fmt.Errorf("config key `%s` cannot be set within a config file", configKey)
+		}
+
+		flag := flags.Lookup(flagName)
+		if flag == nil {
+			nested, ok := value.(map[string]any)
+			if !ok {
+				return fmt.Errorf("unknown config key `%s`: no flag named `--%s`", configKey, flagName)
+			}
+			if err := applyConfigValues(flags, configKey, flagName, nested); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if flag.Changed {
+			continue
+		}
+
+		if err := setFlagFromConfig(flags, flag, value); err != nil {
+			return fmt.Errorf("invalid value for config key `%s`: %w", configKey, err)
+		}
+	}
+	return nil
+}
+
+func setFlagFromConfig(flags *pflag.FlagSet, flag *pflag.Flag, value any) error {
+	switch v := value.(type) {
+	case []any:
+		slice, ok := flag.Value.(pflag.SliceValue)
+		if !ok {
+			return fmt.Errorf("`--%s` does not accept a list", flag.Name)
+		}
+
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		if err := slice.Replace(items); err != nil {
+			return err
+		}
+		flag.Changed = true
+		return nil
+
+	case map[string]any:
+		if flag.Value.Type() != "stringToString" {
+			return fmt.Errorf("`--%s` does not accept a map", flag.Name)
+		}
+
+		pairs := make([]string, 0, len(v))
+		for key, item := range v {
+			pairs = append(pairs, fmt.Sprintf("%s=%v", key, item))
+		}
+		sort.Strings(pairs)
+		return flags.Set(flag.Name, strings.Join(pairs, ","))
+
+	case nil:
+		return fmt.Errorf("`--%s` requires a value", flag.Name)
+
+	default:
+		return flags.Set(flag.Name, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func testFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	RegisterConfigFileFlag(flags)
+	flags.String("datastore-engine", "memory", "")
+	flags.Int("datastore-conn-max-open", 20, "")
+	flags.Duration("datastore-gc-window", time.Hour, "")
+	flags.StringSlice("grpc-preshared-key", nil, "")
+	flags.StringToString("datastore-labels", nil, "")
+	return flags
+}
+
+func writeConfig(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filename string
+		contents string
+	}{
+		{
+			"flat yaml",
+			"config.yaml",
+			`
+datastore-engine: postgres
+datastore-conn-max-open: 50
+datastore-gc-window: 10m
+grpc-preshared-key: [first, second]
+datastore-labels: {region: us-east}
+`,
+		},
+		{
+			"nested yaml",
+			"config.yml",
+			`
+datastore:
+  engine: postgres
+  conn:
+    max-open: 50
+  gc-window: 10m
+  labels:
+    region: us-east
+grpc:
+  preshared-key:
+    - first
+    - second
+`,
+		},
+		{
+			"toml",
+			"config.toml",
+			`
+grpc-preshared-key = ["first", "second"]
+
+[datastore]
+engine = "postgres"
+conn-max-open = 50
+gc-window = "10m"
+labels = { region = "us-east" }
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			flags := testFlags()
+			require.NoError(t, ApplyConfigFile(flags, writeConfig(t, tc.filename, tc.contents)))
+
+			engine, _ := flags.GetString("datastore-engine")
+			require.Equal(t, "postgres", engine)
+			maxOpen, _ := flags.GetInt("datastore-conn-max-open")
+			require.Equal(t, 50, maxOpen)
+			window, _ := flags.GetDuration("datastore-gc-window")
+			require.Equal(t, 10*time.Minute, window)
+			keys, _ := flags.GetStringSlice("grpc-preshared-key")
+			require.Equal(t, []string{"first", "second"}, keys)
+			labels, _ := flags.GetStringToString("datastore-labels")
+			require.Equal(t, map[string]string{"region": "us-east"}, labels)
+			require.True(t, flags.Lookup("grpc-preshared-key").Changed)
+		})
+	}
+}
+
+func TestApplyConfigFileDoesNotOverrideChangedFlags(t *testing.T) {
+	flags := testFlags()
+	require.NoError(t, flags.Parse([]string{"--datastore-engine", "cockroachdb"}))
+
+	path := writeConfig(t, "config.yaml", "datastore-engine: postgres\ndatastore-conn-max-open: 50\n")
+	require.NoError(t, ApplyConfigFile(flags, path))
+
+	engine, _ := flags.GetString("datastore-engine")
+	require.Equal(t, "cockroachdb", engine)
+	maxOpen, _ := flags.GetInt("datastore-conn-max-open")
+	require.Equal(t, 50, maxOpen)
+}
+
+func TestApplyConfigFileErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		filename      string
+		contents      string
+		expectedError string
+	}{
+		{"unknown key", "config.yaml", "datastore-engin: postgres", "unknown config key `datastore-engin`"},
+		{"unknown nested key", "config.yaml", "datastore:\n  conn:\n    max-opne: 5", "unknown config key `datastore.conn.max-opne`"},
+		{"invalid value", "config.yaml", "datastore-conn-max-open: many", "invalid value for config key `datastore-conn-max-open`"},
+		{"list for scalar", "config.toml", `datastore-engine = ["a", "b"]`, "`--datastore-engine` does not accept a list"},
+		{"nested config", "config.yaml", "config: other.yaml", "config key `config` cannot be set"},
+		{"unsupported extension", "config.json", "{}", "unsupported config file extension `.json`"},
+		{"malformed", "config.yaml", "datastore: [", "unable to parse config file"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := ApplyConfigFile(testFlags(), writeConfig(t, tc.filename, tc.contents))
+			require.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/pprof"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/go-logr/zerologr"
@@ -22,10 +23,17 @@ import (
 	"google.golang.org/grpc/codes"
 
 	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/experiments"
 	"github.com/authzed/spicedb/internal/logging"
 	consistencymw "github.com/authzed/spicedb/internal/middleware/consistency"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	dispatchmw "github.com/authzed/spicedb/internal/middleware/dispatcher"
+	"github.com/authzed/spicedb/internal/middleware/errorinfo"
+	experimentsmw "github.com/authzed/spicedb/internal/middleware/experiments"
+	"github.com/authzed/spicedb/internal/middleware/namespacemetrics"
+	"github.com/authzed/spicedb/internal/middleware/profilelabels"
+	"github.com/authzed/spicedb/internal/middleware/querystats"
+	"github.com/authzed/spicedb/internal/middleware/readonly"
 	"github.com/authzed/spicedb/internal/middleware/serverversion"
 	"github.com/authzed/spicedb/internal/middleware/servicespecific"
 	"github.com/authzed/spicedb/pkg/datastore"
@@ -57,6 +65,7 @@ func ServeExample(programName string) string {
 func DefaultPreRunE(programName string) cobrautil.CobraRunFunc {
 	return cobrautil.CommandStack(
 		cobrautil.SyncViperPreRunE(programName),
+		ConfigFilePreRunE(),
 		cobrazerolog.New(
 			cobrazerolog.WithTarget(func(logger zerolog.Logger) {
 				logging.SetGlobalLogger(logger)
@@ -100,35 +109,93 @@ var defaultGRPCLogOptions = []grpclog.Option{
 	}),
 }
 
-func DefaultMiddleware(logger zerolog.Logger, authFunc grpcauth.AuthFunc, enableVersionResponse bool, dispatcher dispatch.Dispatcher, ds datastore.Datastore) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
-	return []grpc.UnaryServerInterceptor{
-			requestid.UnaryServerInterceptor(requestid.GenerateIfMissing(true)),
-			logmw.UnaryServerInterceptor(logmw.ExtractMetadataField("x-request-id", "requestID")),
-			grpclog.UnaryServerInterceptor(grpczerolog.InterceptorLogger(logger), defaultGRPCLogOptions...),
-			otelgrpc.UnaryServerInterceptor(),
-			grpcauth.UnaryServerInterceptor(authFunc),
-			grpcprom.UnaryServerInterceptor,
-			dispatchmw.UnaryServerInterceptor(dispatcher),
-			datastoremw.UnaryServerInterceptor(ds),
-			consistencymw.UnaryServerInterceptor(),
-			servicespecific.UnaryServerInterceptor,
-			serverversion.UnaryServerInterceptor(enableVersionResponse),
-		}, []grpc.StreamServerInterceptor{
-			requestid.StreamServerInterceptor(requestid.GenerateIfMissing(true)),
-			logmw.StreamServerInterceptor(logmw.ExtractMetadataField("x-request-id", "requestID")),
-			grpclog.StreamServerInterceptor(grpczerolog.InterceptorLogger(logger), defaultGRPCLogOptions...),
-			otelgrpc.StreamServerInterceptor(),
-			grpcauth.StreamServerInterceptor(authFunc),
-			grpcprom.StreamServerInterceptor,
-			dispatchmw.StreamServerInterceptor(dispatcher),
-			datastoremw.StreamServerInterceptor(ds),
-			consistencymw.StreamServerInterceptor(),
-			servicespecific.StreamServerInterceptor,
-			serverversion.StreamServerInterceptor(enableVersionResponse),
-		}
+// MiddlewareOption is the configuration of the default middleware of the API server.
+type MiddlewareOption struct {
+	Logger                zerolog.Logger
+	AuthFunc              grpcauth.AuthFunc
+	EnableVersionResponse bool
+	Dispatcher            dispatch.Dispatcher
+	Datastore             datastore.Datastore
+
+	// ReadOnly rejects mutating methods once the request has been authenticated.
+	ReadOnly bool
+
+	MaximumRequestedStaleness  time.Duration
+	SubstituteExpiredRevisions bool
+
+	// PeerDatastoreIDs are the peer datastores whose zedtokens are accepted with
+	// at_least_as_fresh consistency.
+	PeerDatastoreIDs     []string
+	MaximumPeerClockSkew time.Duration
+	ReadYourWritesTTL    time.Duration
+
+	// NamespaceExperiments are the experiments enabled per namespace.
+	NamespaceExperiments *experiments.Registry
+
+	// ObjectTypes are the object types with which the request metrics are labeled.
+	ObjectTypes *namespacemetrics.ObjectTypes
+}
+
+// DefaultMiddleware returns the default middleware for the API server.
+func DefaultMiddleware(opts MiddlewareOption) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
+	unary := []grpc.UnaryServerInterceptor{
+		requestid.UnaryServerInterceptor(requestid.GenerateIfMissing(true)),
+		errorinfo.UnaryServerInterceptor(),
+		logmw.UnaryServerInterceptor(logmw.ExtractMetadataField("x-request-id", "requestID")),
+		grpclog.UnaryServerInterceptor(grpczerolog.InterceptorLogger(opts.Logger), defaultGRPCLogOptions...),
+		otelgrpc.UnaryServerInterceptor(),
+		grpcauth.UnaryServerInterceptor(opts.AuthFunc),
+		grpcprom.UnaryServerInterceptor,
+		namespacemetrics.UnaryServerInterceptor(opts.ObjectTypes),
+		profilelabels.UnaryServerInterceptor(),
+		querystats.UnaryServerInterceptor(),
+		dispatchmw.UnaryServerInterceptor(opts.Dispatcher),
+		datastoremw.UnaryServerInterceptor(opts.Datastore),
+		experimentsmw.UnaryServerInterceptor(opts.NamespaceExperiments),
+	}
+	streaming := []grpc.StreamServerInterceptor{
+		requestid.StreamServerInterceptor(requestid.GenerateIfMissing(true)),
+		errorinfo.StreamServerInterceptor(),
+		logmw.StreamServerInterceptor(logmw.ExtractMetadataField("x-request-id", "requestID")),
+		grpclog.StreamServerInterceptor(grpczerolog.InterceptorLogger(opts.Logger), defaultGRPCLogOptions...),
+		otelgrpc.StreamServerInterceptor(),
+		grpcauth.StreamServerInterceptor(opts.AuthFunc),
+		grpcprom.StreamServerInterceptor,
+		namespacemetrics.StreamServerInterceptor(opts.ObjectTypes),
+		profilelabels.StreamServerInterceptor(),
+		querystats.StreamServerInterceptor(),
+		dispatchmw.StreamServerInterceptor(opts.Dispatcher),
+		datastoremw.StreamServerInterceptor(opts.Datastore),
+		experimentsmw.StreamServerInterceptor(opts.NamespaceExperiments),
+	}
+
+	if opts.ReadOnly {
+		unary = append(unary, readonly.UnaryServerInterceptor())
+		streaming = append(streaming, readonly.StreamServerInterceptor())
+	}
+
+	consistencyOpts := []consistencymw.Option{
+		consistencymw.WithMaximumRequestedStaleness(opts.MaximumRequestedStaleness),
+		consistencymw.WithExpiredRevisionSubstitution(opts.SubstituteExpiredRevisions),
+		consistencymw.WithPeerDatastores(opts.PeerDatastoreIDs...),
+		consistencymw.WithMaximumPeerClockSkew(opts.MaximumPeerClockSkew),
+		consistencymw.WithReadYourWrites(opts.ReadYourWritesTTL),
+	}
+
+	unary = append(unary,
+		consistencymw.UnaryServerInterceptor(consistencyOpts...),
+		servicespecific.UnaryServerInterceptor,
+		serverversion.UnaryServerInterceptor(opts.EnableVersionResponse),
+	)
+	streaming = append(streaming,
+		consistencymw.StreamServerInterceptor(consistencyOpts...),
+		servicespecific.StreamServerInterceptor,
+		serverversion.StreamServerInterceptor(opts.EnableVersionResponse),
+	)
+	return unary, streaming
 }
 
-func DefaultDispatchMiddleware(logger zerolog.Logger, authFunc grpcauth.AuthFunc, ds datastore.Datastore) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
+func DefaultDispatchMiddleware(logger zerolog.Logger, authFunc grpcauth.AuthFunc, ds datastore.Datastore, namespaceExperiments *experiments.Registry) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
 	return []grpc.UnaryServerInterceptor{
 			requestid.UnaryServerInterceptor(requestid.GenerateIfMissing(true)),
 			logmw.UnaryServerInterceptor(logmw.ExtractMetadataField("x-request-id", "requestID")),
@@ -137,6 +204,7 @@ func DefaultDispatchMiddleware(logger zerolog.Logger, authFunc grpcauth.AuthFunc
 			grpcauth.UnaryServerInterceptor(authFunc),
 			grpcprom.UnaryServerInterceptor,
 			datastoremw.UnaryServerInterceptor(ds),
+			experimentsmw.UnaryServerInterceptor(namespaceExperiments),
 			servicespecific.UnaryServerInterceptor,
 		}, []grpc.StreamServerInterceptor{
 			requestid.StreamServerInterceptor(requestid.GenerateIfMissing(true)),
@@ -146,6 +214,7 @@ func DefaultDispatchMiddleware(logger zerolog.Logger, authFunc grpcauth.AuthFunc
 			grpcauth.StreamServerInterceptor(authFunc),
 			grpcprom.StreamServerInterceptor,
 			datastoremw.StreamServerInterceptor(ds),
+			experimentsmw.StreamServerInterceptor(namespaceExperiments),
 			servicespecific.StreamServerInterceptor,
 		}
 }
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rs/zerolog"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/graph"
+	"github.com/authzed/spicedb/internal/runtimeconfig"
+	"github.com/authzed/spicedb/pkg/cache"
+)
+
+// RuntimeConfigPath is the path on the metrics server at which the runtime
+// configuration is reported and updated.
+const RuntimeConfigPath = "/debug/config"
+
+func registerLogLevelSetting(settings *runtimeconfig.Registry) {
+	settings.Register(runtimeconfig.Setting{
+		Name:        "log-level",
+		Description: "verbosity of logging (trace, debug, info, warn, error)",
+		Get:         func() string { return zerolog.GlobalLevel().String() },
+		Set: func(value string) error {
+			level, err := zerolog.ParseLevel(value)
+			if err != nil {
+				return err
+			}
+			zerolog.SetGlobalLevel(level)
+			return nil
+		},
+	})
+}
+
+func registerGCIntervalSetting(settings *runtimeconfig.Registry, configured time.Duration) {
+	settings.Register(runtimeconfig.Setting{
+		Name:        "datastore-gc-interval",
+		Description: "amount of time between passes of garbage collection, applied after the pending pass",
+		Get:         func() string { return common.EffectiveGarbageCollectionInterval(configured).String() },
+		Set: func(value string) error {
+			interval, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			if interval <= 0 {
+				return fmt.Errorf("interval must be positive")
+			}
+			common.SetGarbageCollectionIntervalOverride(interval)
+			return nil
+		},
+	})
+}
+
+func registerConcurrencyLimitSetting(settings *runtimeconfig.Registry, configured uint16) {
+	settings.Register(runtimeconfig.Setting{
+		Name:        "dispatch-concurrency-limit",
+		Description: "maximum number of parallel goroutines to create for each request or subrequest",
+		Get:         func() string { return strconv.Itoa(int(graph.EffectiveConcurrencyLimit(configured))) },
+		Set: func(value string) error {
+			limit, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				return err
+			}
+			if limit == 0 {
+				return fmt.Errorf("limit must be positive")
+			}
+			graph.SetConcurrencyLimitOverride(uint16(limit))
+			return nil
+		},
+	})
+}
+
+func registerCacheSizeSetting(settings *runtimeconfig.Registry, flagPrefix string, c cache.Cache) {
+	settings.Register(runtimeconfig.Setting{
+		Name:        flagPrefix + "-max-cost",
+		Description: "upper bound cache size in bytes or percent of available memory",
+		Get:         func() string { return humanize.IBytes(uint64(c.MaxCost())) },
+		Set: func(value string) error {
+			maxCost, err := parseMaxCost(value)
+			if err != nil {
+				return err
+			}
+			if maxCost == 0 {
+				return fmt.Errorf("caches cannot be disabled at runtime")
+			}
+			c.UpdateMaxCost(int64(maxCost))
+			return nil
+		},
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
+	"os"
 	"sync"
 	"time"
 
@@ -13,36 +15,56 @@ import (
 	"github.com/rs/cors"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"golang.org/x/exp/slices"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/authzed/spicedb/internal/auth"
 	"github.com/authzed/spicedb/internal/dashboard"
+	"github.com/authzed/spicedb/internal/datastore/archive"
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/common/revisions"
 	"github.com/authzed/spicedb/internal/datastore/proxy"
+	"github.com/authzed/spicedb/internal/decisionlog"
 	"github.com/authzed/spicedb/internal/dispatch"
 	clusterdispatch "github.com/authzed/spicedb/internal/dispatch/cluster"
 	combineddispatch "github.com/authzed/spicedb/internal/dispatch/combined"
+	"github.com/authzed/spicedb/internal/experiments"
 	"github.com/authzed/spicedb/internal/gateway"
+	"github.com/authzed/spicedb/internal/gateway/scim"
+	"github.com/authzed/spicedb/internal/jobs"
 	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/middleware/namespacemetrics"
+	"github.com/authzed/spicedb/internal/profiling"
+	"github.com/authzed/spicedb/internal/runtimeconfig"
 	"github.com/authzed/spicedb/internal/services"
 	dispatchSvc "github.com/authzed/spicedb/internal/services/dispatch"
 	"github.com/authzed/spicedb/internal/services/health"
 	v1svc "github.com/authzed/spicedb/internal/services/v1"
 	"github.com/authzed/spicedb/internal/telemetry"
+	"github.com/authzed/spicedb/pkg/admission"
 	"github.com/authzed/spicedb/pkg/balancer"
+	"github.com/authzed/spicedb/pkg/cache"
 	datastorecfg "github.com/authzed/spicedb/pkg/cmd/datastore"
 	"github.com/authzed/spicedb/pkg/cmd/util"
 	"github.com/authzed/spicedb/pkg/datastore"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
 //go:generate go run github.com/ecordell/optgen -output zz_generated.options.go . Config
 type Config struct {
 	// API config
 	GRPCServer             util.GRPCServerConfig
+	GRPCGzipLevel          int
+	GRPCZstdLevel          int
 	GRPCAuthFunc           grpc_auth.AuthFunc
 	PresharedKey           []string
+	PresharedKeyFile       string
 	ShutdownGracePeriod    time.Duration
+	ShutdownDrainTimeout   time.Duration
 	DisableVersionResponse bool
+	ReadOnly               bool
 
 	// GRPC Gateway config
 	HTTPGateway                    util.HTTPServerConfig
@@ -50,6 +72,10 @@ type Config struct {
 	HTTPGatewayUpstreamTLSCertPath string
 	HTTPGatewayCorsEnabled         bool
 	HTTPGatewayCorsAllowedOrigins  []string
+	HTTPGatewayOpenFGAEnabled      bool
+	HTTPGatewayGraphQLEnabled      bool
+	HTTPGatewaySCIMEnabled         bool
+	HTTPGatewaySCIMConfig          scim.Config
 
 	// Datastore
 	DatastoreConfig datastorecfg.Config
@@ -59,12 +85,14 @@ type Config struct {
 	NamespaceCacheConfig CacheConfig
 
 	// Schema options
-	SchemaPrefixesRequired bool
+	SchemaPrefixesRequired           bool
+	SchemaRemovedDefinitionRetention time.Duration
 
 	// Dispatch options
 	DispatchServer               util.GRPCServerConfig
 	DispatchMaxDepth             uint32
 	DispatchConcurrencyLimit     uint16
+	DispatchSuperNodeThreshold   uint64
 	DispatchUpstreamAddr         string
 	DispatchUpstreamCAPath       string
 	DispatchClientMetricsPrefix  string
@@ -79,8 +107,51 @@ type Config struct {
 	V1SchemaAdditiveOnly       bool
 	MaximumUpdatesPerWrite     uint16
 	MaximumPreconditionCount   uint16
+	CardinalitySoftLimits      []string
+	CardinalityHardLimits      []string
+	MaximumRequestedStaleness  time.Duration
+	SubstituteExpiredRevisions bool
+	MaximumRevisionPinTTL      time.Duration
+	OrphanDeletionBatchSize    int
+	OrphanDeletionInterval     time.Duration
+	StreamingCheckConcurrency  int
+	BulkCheckConcurrency       int
+	AccessReportConcurrency    int
+	DeletionImpactConcurrency  int
+	PrefetchConcurrency        int
+	CanarySchemaConcurrency    int
+	JobsHeartbeatInterval      time.Duration
+	PeerDatastoreIDs           []string
+	MaximumPeerClockSkew       time.Duration
+	ReadYourWritesTTL          time.Duration
+	MaximumLookupMemoryBytes   uint64
+	LookupSpillDirectory       string
+	SortLookupResults          bool
 	ExperimentalCaveatsEnabled bool
 
+	// NamespaceExperimentsRefreshInterval is the interval at which the experiments
+	// enabled per namespace are reloaded from the datastore, defaulting to
+	// DefaultNamespaceExperimentsRefreshInterval.
+	NamespaceExperimentsRefreshInterval time.Duration
+
+	// Admission hooks, which admit or reject the writes of relationships and schemas
+	// before they are committed. In-process hooks run before the webhook, if any.
+	AdmissionHooks           []admission.Hook
+	AdmissionWebhookAddr     string
+	AdmissionWebhookCAPath   string
+	AdmissionWebhookTimeout  time.Duration
+	AdmissionWebhookFailOpen bool
+
+	// Decision log, which records a sample of the decisions of checks as files in an
+	// object store, if the URI is set.
+	DecisionLogURI           string
+	DecisionLogS3Endpoint    string
+	DecisionLogS3Region      string
+	DecisionLogSampleRate    float64
+	DecisionLogFormat        string
+	DecisionLogFlushInterval time.Duration
+	DecisionLogBatchSize     int
+
 	// Additional Services
 	DashboardAPI util.HTTPServerConfig
 	MetricsAPI   util.HTTPServerConfig
@@ -98,18 +169,64 @@ type Config struct {
 	TelemetryCAOverridePath  string
 	TelemetryEndpoint        string
 	TelemetryInterval        time.Duration
+
+	// ResolvedFlags are the command-line flags the server was started with, as
+	// reported by the runtime configuration endpoint.
+	ResolvedFlags []runtimeconfig.Flag
+
+	// Continuous profiling
+	ContinuousProfilingEndpoint        string
+	ContinuousProfilingApplicationName string
+	ContinuousProfilingInterval        time.Duration
 }
 
+// DefaultNamespaceExperimentsRefreshInterval is the default interval at which the
+// experiments enabled per namespace are reloaded from the datastore.
+const DefaultNamespaceExperimentsRefreshInterval = 10 * time.Second
+
+// revisionQuantizationAdjustInterval is the interval at which an adaptive revision
+// quantization window of the datastore is adjusted.
+const revisionQuantizationAdjustInterval = 30 * time.Second
+
+// objectTypesRefreshInterval is the interval at which the object types with which the
+// request metrics are labeled are reloaded from the schema.
+const objectTypesRefreshInterval = 30 * time.Second
+
 // Complete validates the config and fills out defaults.
 // if there is no error, a completedServerConfig (with limited options for
 // mutation) is returned.
 func (c *Config) Complete() (RunnableServer, error) {
-	if len(c.PresharedKey) < 1 && c.GRPCAuthFunc == nil {
+	if err := util.ConfigureCompression(c.GRPCGzipLevel, c.GRPCZstdLevel); err != nil {
+		return nil, err
+	}
+
+	if c.MaximumRequestedStaleness > 0 && c.DatastoreConfig.GCWindow > 0 && c.MaximumRequestedStaleness >= c.DatastoreConfig.GCWindow {
+		return nil, fmt.Errorf("maximum requested staleness %s must be under the datastore GC window %s", c.MaximumRequestedStaleness, c.DatastoreConfig.GCWindow)
+	}
+
+	// Keys read from the preshared key file follow those provided directly, and are
+	// reloaded when the file changes.
+	var presharedKeyFile *auth.PresharedKeyFile
+	if c.PresharedKeyFile != "" {
+		var err error
+		presharedKeyFile, err = auth.NewPresharedKeyFile(c.PresharedKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	presharedKeys := func() []string {
+		if presharedKeyFile == nil {
+			return c.PresharedKey
+		}
+		return append(slices.Clone(c.PresharedKey), presharedKeyFile.Keys()...)
+	}
+
+	if len(presharedKeys()) < 1 && c.GRPCAuthFunc == nil {
 		return nil, fmt.Errorf("a preshared key must be provided to authenticate API requests")
 	}
 
 	if c.GRPCAuthFunc == nil {
-		log.Trace().Int("preshared-keys-count", len(c.PresharedKey)).Msg("using gRPC auth with preshared key(s)")
+		log.Trace().Int("preshared-keys-count", len(presharedKeys())).Msg("using gRPC auth with preshared key(s)")
 		for index, presharedKey := range c.PresharedKey {
 			if len(presharedKey) == 0 {
 				return nil, fmt.Errorf("preshared key #%d is empty", index+1)
@@ -118,11 +235,16 @@ func (c *Config) Complete() (RunnableServer, error) {
 			log.Trace().Int(fmt.Sprintf("preshared-key-%d-length", index+1), len(presharedKey)).Msg("preshared key configured")
 		}
 
-		c.GRPCAuthFunc = auth.RequirePresharedKey(c.PresharedKey)
+		c.GRPCAuthFunc = auth.RequireDynamicPresharedKey(presharedKeys)
 	} else {
 		log.Trace().Msg("using preconfigured auth function")
 	}
 
+	// Read-only mode also disables garbage collection, which writes to the datastore.
+	if c.ReadOnly {
+		c.DatastoreConfig.ReadOnly = true
+	}
+
 	ds := c.Datastore
 	if ds == nil {
 		var err error
@@ -130,6 +252,8 @@ func (c *Config) Complete() (RunnableServer, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create datastore: %w", err)
 		}
+	} else if c.ReadOnly {
+		ds = proxy.NewReadonlyDatastore(ds)
 	}
 
 	nscc, err := c.NamespaceCacheConfig.Complete()
@@ -138,9 +262,31 @@ func (c *Config) Complete() (RunnableServer, error) {
 	}
 	log.Info().EmbedObject(nscc).Msg("configured namespace cache")
 
+	runtimeSettings := runtimeconfig.NewRegistry(c.ResolvedFlags)
+	registerLogLevelSetting(runtimeSettings)
+	registerGCIntervalSetting(runtimeSettings, c.DatastoreConfig.GCInterval)
+	registerCacheSizeSetting(runtimeSettings, "ns-cache", nscc)
+
 	ds = proxy.NewCachingDatastoreProxy(ds, nscc)
 	ds = proxy.NewObservableDatastoreProxy(ds)
 
+	// An adaptive revision quantization window adapts to the hit ratio of the dispatch
+	// caches, whose statistics are therefore kept.
+	var quantization *revisions.Quantization
+	if quantized, ok := datastore.UnwrapAs[common.QuantizedDatastore](ds); ok && quantized.RevisionQuantization().IsAdaptive() {
+		quantization = quantized.RevisionQuantization()
+		c.DispatchCacheConfig.Metrics = true
+		c.ClusterDispatchCacheConfig.Metrics = true
+	}
+	var dispatchCaches []cache.Cache
+
+	namespaceExperiments := experiments.NewRegistry(ds)
+	if c.NamespaceExperimentsRefreshInterval <= 0 {
+		c.NamespaceExperimentsRefreshInterval = DefaultNamespaceExperimentsRefreshInterval
+	}
+
+	objectTypes := namespacemetrics.NewObjectTypes(ds)
+
 	enableGRPCHistogram()
 
 	dispatcher := c.Dispatcher
@@ -151,23 +297,24 @@ func (c *Config) Complete() (RunnableServer, error) {
 			return nil, fmt.Errorf("failed to create dispatcher: %w", cerr)
 		}
 		log.Info().EmbedObject(cc).Msg("configured dispatch cache")
+		dispatchCaches = append(dispatchCaches, cc)
+		registerCacheSizeSetting(runtimeSettings, "dispatch-cache", cc)
+		registerConcurrencyLimitSetting(runtimeSettings, c.DispatchConcurrencyLimit)
 
-		dispatchPresharedKey := ""
-		if len(c.PresharedKey) > 0 {
-			dispatchPresharedKey = c.PresharedKey[0]
-		}
-
+		// Dispatch requests to other nodes are authenticated with the first key at the
+		// time of each request, so that they follow the keys as they are rotated.
 		dispatcher, err = combineddispatch.NewDispatcher(
 			combineddispatch.UpstreamAddr(c.DispatchUpstreamAddr),
 			combineddispatch.UpstreamCAPath(c.DispatchUpstreamCAPath),
-			combineddispatch.GrpcPresharedKey(dispatchPresharedKey),
+			combineddispatch.GrpcPresharedKeys(presharedKeys),
 			combineddispatch.GrpcDialOpts(
 				grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
-				grpc.WithDefaultServiceConfig(balancer.BalancerServiceConfig),
+				grpc.WithDefaultServiceConfig(balancer.HealthCheckedServiceConfig(dispatchv1.DispatchService_ServiceDesc.ServiceName)),
 			),
 			combineddispatch.PrometheusSubsystem(c.DispatchClientMetricsPrefix),
 			combineddispatch.Cache(cc),
 			combineddispatch.ConcurrencyLimit(c.DispatchConcurrencyLimit),
+			combineddispatch.SuperNodeThreshold(c.DispatchSuperNodeThreshold),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create dispatcher: %w", err)
@@ -176,9 +323,9 @@ func (c *Config) Complete() (RunnableServer, error) {
 
 	if len(c.DispatchUnaryMiddleware) == 0 && len(c.DispatchStreamingMiddleware) == 0 {
 		if c.GRPCAuthFunc == nil {
-			c.DispatchUnaryMiddleware, c.DispatchStreamingMiddleware = DefaultDispatchMiddleware(log.Logger, auth.RequirePresharedKey(c.PresharedKey), ds)
+			c.DispatchUnaryMiddleware, c.DispatchStreamingMiddleware = DefaultDispatchMiddleware(log.Logger, auth.RequireDynamicPresharedKey(presharedKeys), ds, namespaceExperiments)
 		} else {
-			c.DispatchUnaryMiddleware, c.DispatchStreamingMiddleware = DefaultDispatchMiddleware(log.Logger, c.GRPCAuthFunc, ds)
+			c.DispatchUnaryMiddleware, c.DispatchStreamingMiddleware = DefaultDispatchMiddleware(log.Logger, c.GRPCAuthFunc, ds, namespaceExperiments)
 		}
 	}
 
@@ -189,21 +336,29 @@ func (c *Config) Complete() (RunnableServer, error) {
 			return nil, fmt.Errorf("failed to configure cluster dispatch: %w", cerr)
 		}
 		log.Info().EmbedObject(cdcc).Msg("configured cluster dispatch cache")
+		dispatchCaches = append(dispatchCaches, cdcc)
+		registerCacheSizeSetting(runtimeSettings, "dispatch-cluster-cache", cdcc)
 
 		var err error
 		cachingClusterDispatch, err = clusterdispatch.NewClusterDispatcher(
 			dispatcher,
 			clusterdispatch.PrometheusSubsystem(c.DispatchClusterMetricsPrefix),
 			clusterdispatch.Cache(cdcc),
+			clusterdispatch.SuperNodeThreshold(c.DispatchSuperNodeThreshold),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to configure cluster dispatch: %w", err)
 		}
 	}
 
+	if quantization != nil {
+		quantization.SetCacheStats(dispatchCacheStats(dispatchCaches))
+	}
+
+	dispatchHealthSrv := grpcutil.NewAuthlessHealthServer()
 	dispatchGrpcServer, err := c.DispatchServer.Complete(zerolog.InfoLevel,
 		func(server *grpc.Server) {
-			dispatchSvc.RegisterGrpcServices(server, cachingClusterDispatch)
+			dispatchSvc.RegisterGrpcServices(server, cachingClusterDispatch, dispatchHealthSrv)
 		},
 		grpc.ChainUnaryInterceptor(c.DispatchUnaryMiddleware...),
 		grpc.ChainStreamInterceptor(c.DispatchStreamingMiddleware...),
@@ -220,6 +375,11 @@ func (c *Config) Complete() (RunnableServer, error) {
 		return nil, fmt.Errorf("error determining datastore features: %w", err)
 	}
 
+	admissionHook, admissionWebhookConn, err := c.admissionHook()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admission webhook: %w", err)
+	}
+
 	v1SchemaServiceOption := services.V1SchemaServiceEnabled
 	if c.DisableV1SchemaAPI {
 		v1SchemaServiceOption = services.V1SchemaServiceDisabled
@@ -234,13 +394,63 @@ func (c *Config) Complete() (RunnableServer, error) {
 	}
 
 	if len(c.UnaryMiddleware) == 0 && len(c.StreamingMiddleware) == 0 {
-		c.UnaryMiddleware, c.StreamingMiddleware = DefaultMiddleware(log.Logger, c.GRPCAuthFunc, !c.DisableVersionResponse, dispatcher, ds)
+		c.UnaryMiddleware, c.StreamingMiddleware = DefaultMiddleware(MiddlewareOption{
+			Logger:                     log.Logger,
+			AuthFunc:                   c.GRPCAuthFunc,
+			EnableVersionResponse:      !c.DisableVersionResponse,
+			Dispatcher:                 dispatcher,
+			Datastore:                  ds,
+			ReadOnly:                   c.ReadOnly,
+			MaximumRequestedStaleness:  c.MaximumRequestedStaleness,
+			SubstituteExpiredRevisions: c.SubstituteExpiredRevisions,
+			PeerDatastoreIDs:           c.PeerDatastoreIDs,
+			MaximumPeerClockSkew:       c.MaximumPeerClockSkew,
+			ReadYourWritesTTL:          c.ReadYourWritesTTL,
+			NamespaceExperiments:       namespaceExperiments,
+			ObjectTypes:                objectTypes,
+		})
+	}
+
+	softCardinalityLimits, err := v1svc.ParseCardinalityLimits(c.CardinalitySoftLimits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid soft cardinality limits: %w", err)
+	}
+	hardCardinalityLimits, err := v1svc.ParseCardinalityLimits(c.CardinalityHardLimits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hard cardinality limits: %w", err)
+	}
+
+	var decisionLog *decisionlog.Logger
+	if c.DecisionLogURI != "" {
+		store, err := archive.NewStoreForURI(c.DecisionLogURI, c.DecisionLogS3Endpoint, c.DecisionLogS3Region)
+		if err != nil {
+			return nil, fmt.Errorf("invalid decision log: %w", err)
+		}
+		decisionLog, err = decisionlog.NewLogger(decisionlog.Config{
+			Store:         store,
+			Datastore:     ds,
+			SampleRate:    c.DecisionLogSampleRate,
+			Format:        decisionlog.Format(c.DecisionLogFormat),
+			FlushInterval: c.DecisionLogFlushInterval,
+			BatchSize:     c.DecisionLogBatchSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("invalid decision log: %w", err)
+		}
 	}
 
 	permSysConfig := v1svc.PermissionsServerConfig{
 		MaxPreconditionsCount: c.MaximumPreconditionCount,
 		MaxUpdatesPerWrite:    c.MaximumUpdatesPerWrite,
 		MaximumAPIDepth:       c.DispatchMaxDepth,
+		MaxLookupMemoryBytes:  c.MaximumLookupMemoryBytes,
+		LookupSpillDirectory:  c.LookupSpillDirectory,
+		SortLookupResults:     c.SortLookupResults,
+		DecisionLog:           decisionLog,
+		CardinalityLimits: v1svc.CardinalityLimits{
+			Soft: softCardinalityLimits,
+			Hard: hardCardinalityLimits,
+		},
 	}
 
 	caveatsOption := services.CaveatsDisabled
@@ -249,7 +459,12 @@ func (c *Config) Complete() (RunnableServer, error) {
 		caveatsOption = services.CaveatsEnabled
 	}
 
-	healthManager := health.NewHealthManager(dispatcher, ds)
+	jobRunner := jobs.NewRunner(c.JobsHeartbeatInterval)
+
+	healthManager := health.NewHealthManager(dispatcher, ds, health.WithFreshnessChecks(
+		c.DatastoreConfig.ReadinessCheckInterval,
+		c.DatastoreConfig.ReadinessMaxRevisionStall,
+	))
 	grpcServer, err := c.GRPCServer.Complete(zerolog.InfoLevel,
 		func(server *grpc.Server) {
 			services.RegisterGrpcServices(
@@ -260,6 +475,23 @@ func (c *Config) Complete() (RunnableServer, error) {
 				watchServiceOption,
 				caveatsOption,
 				permSysConfig,
+				v1svc.ExperimentalServerConfig{
+					MaximumPinTTL:               c.MaximumRevisionPinTTL,
+					OrphanDeletionBatchSize:     c.OrphanDeletionBatchSize,
+					OrphanDeletionBatchInterval: c.OrphanDeletionInterval,
+					MaximumAPIDepth:             c.DispatchMaxDepth,
+					StreamingCheckConcurrency:   c.StreamingCheckConcurrency,
+					BulkCheckConcurrency:        c.BulkCheckConcurrency,
+					AccessReportConcurrency:     c.AccessReportConcurrency,
+					DeletionImpactConcurrency:   c.DeletionImpactConcurrency,
+					PrefetchConcurrency:         c.PrefetchConcurrency,
+					CanarySchema:                v1svc.NewCanarySchema(c.CanarySchemaConcurrency, c.DispatchMaxDepth),
+					JobRunner:                   jobRunner,
+					GCWindow:                    c.DatastoreConfig.GCWindow,
+					DatastoreEngine:             c.DatastoreConfig.Engine,
+					RemovedDefinitionRetention:  c.SchemaRemovedDefinitionRetention,
+				},
+				admissionHook,
 			)
 		},
 	)
@@ -280,7 +512,16 @@ func (c *Config) Complete() (RunnableServer, error) {
 		log.Info().Str("cert-path", c.HTTPGatewayUpstreamTLSCertPath).Msg("Overriding REST gateway upstream TLS")
 	}
 
-	gatewayHandler, err := gateway.NewHandler(context.TODO(), c.HTTPGatewayUpstreamAddr, c.HTTPGatewayUpstreamTLSCertPath)
+	var scimConfig *scim.Config
+	if c.HTTPGatewaySCIMEnabled {
+		scimConfig = &c.HTTPGatewaySCIMConfig
+	}
+
+	gatewayHandler, err := gateway.NewHandler(context.TODO(), c.HTTPGatewayUpstreamAddr, c.HTTPGatewayUpstreamTLSCertPath, gateway.Options{
+		OpenFGAEnabled: c.HTTPGatewayOpenFGAEnabled,
+		GraphQLEnabled: c.HTTPGatewayGraphQLEnabled,
+		SCIM:           scimConfig,
+	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize rest gateway")
 	}
@@ -334,29 +575,57 @@ func (c *Config) Complete() (RunnableServer, error) {
 		}
 	}
 
-	metricsServer, err := c.MetricsAPI.Complete(zerolog.InfoLevel, MetricsHandler(registry))
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle(RuntimeConfigPath, runtimeSettings.DynamicHandler(presharedKeys))
+	metricsMux.Handle("/", MetricsHandler(registry))
+
+	metricsServer, err := c.MetricsAPI.Complete(zerolog.InfoLevel, metricsMux)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize metrics server: %w", err)
 	}
 
+	profilingPusher := profiling.DisabledPusher
+	if c.ContinuousProfilingEndpoint != "" {
+		profilingPusher, err = profiling.NewPusher(c.ContinuousProfilingEndpoint, c.ContinuousProfilingApplicationName, c.ContinuousProfilingInterval)
+		if err != nil {
+			return nil, fmt.Errorf("unable to initialize continuous profiling: %w", err)
+		}
+	}
+
 	return &completedServerConfig{
-		gRPCServer:          grpcServer,
-		dispatchGRPCServer:  dispatchGrpcServer,
-		gatewayServer:       gatewayServer,
-		metricsServer:       metricsServer,
-		dashboardServer:     dashboardServer,
-		unaryMiddleware:     c.UnaryMiddleware,
-		streamingMiddleware: c.StreamingMiddleware,
-		presharedKeys:       c.PresharedKey,
-		telemetryReporter:   reporter,
-		healthManager:       healthManager,
+		gRPCServer:                 grpcServer,
+		dispatchGRPCServer:         dispatchGrpcServer,
+		dispatchHealthSrv:          dispatchHealthSrv,
+		drainTimeout:               c.ShutdownDrainTimeout,
+		gatewayServer:              gatewayServer,
+		metricsServer:              metricsServer,
+		dashboardServer:            dashboardServer,
+		unaryMiddleware:            c.UnaryMiddleware,
+		streamingMiddleware:        c.StreamingMiddleware,
+		presharedKeys:              presharedKeys,
+		presharedKeyFile:           presharedKeyFile,
+		telemetryReporter:          reporter,
+		profilingPusher:            profilingPusher,
+		healthManager:              healthManager,
+		experiments:                namespaceExperiments,
+		experimentsRefreshInterval: c.NamespaceExperimentsRefreshInterval,
+		objectTypes:                objectTypes,
+		decisionLog:                decisionLog,
+		quantization:               quantization,
 		closeFunc: func() {
+			// Jobs are interrupted before the datastore in which their state is stored is closed.
+			jobRunner.Close()
 			if err := ds.Close(); err != nil {
 				log.Warn().Err(err).Msg("couldn't close datastore")
 			}
 			if err := dispatcher.Close(); err != nil {
 				log.Warn().Err(err).Msg("couldn't close dispatcher")
 			}
+			if admissionWebhookConn != nil {
+				if err := admissionWebhookConn.Close(); err != nil {
+					log.Warn().Err(err).Msg("couldn't close admission webhook connection")
+				}
+			}
 			if cachingClusterDispatch == nil {
 				return
 			}
@@ -367,6 +636,40 @@ func (c *Config) Complete() (RunnableServer, error) {
 	}, nil
 }
 
+// admissionHook returns the hook admitting writes, if any, with the connection to the
+// admission webhook, if one is configured.
+func (c *Config) admissionHook() (admission.Hook, *grpc.ClientConn, error) {
+	hooks := append(admission.Hooks{}, c.AdmissionHooks...)
+
+	var conn *grpc.ClientConn
+	if c.AdmissionWebhookAddr != "" {
+		var dialOpts []grpc.DialOption
+		if c.AdmissionWebhookCAPath != "" {
+			if _, err := os.Stat(c.AdmissionWebhookCAPath); err != nil {
+				return nil, nil, err
+			}
+			dialOpts = append(dialOpts, grpcutil.WithCustomCerts(c.AdmissionWebhookCAPath, grpcutil.VerifyCA))
+		} else {
+			dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		}
+
+		var err error
+		conn, err = grpc.Dial(c.AdmissionWebhookAddr, dialOpts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		hooks = append(hooks, admission.NewWebhook(conn, admission.WebhookConfig{
+			Timeout:  c.AdmissionWebhookTimeout,
+			FailOpen: c.AdmissionWebhookFailOpen,
+		}))
+	}
+
+	if len(hooks) == 0 {
+		return nil, nil, nil
+	}
+	return hooks, conn, nil
+}
+
 // RunnableServer is a spicedb service set ready to run
 type RunnableServer interface {
 	Run(ctx context.Context) error
@@ -374,6 +677,11 @@ type RunnableServer interface {
 	SetMiddleware(unaryInterceptors []grpc.UnaryServerInterceptor, streamingInterceptors []grpc.StreamServerInterceptor) RunnableServer
 	GRPCDialContext(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error)
 	DispatchNetDialContext(ctx context.Context, s string) (net.Conn, error)
+
+	// Drain reports the server as not serving ahead of its shutdown, which removes it
+	// from load balancers watching its health and from the dispatch hashrings of its
+	// peers, while it continues to serve the requests it still receives.
+	Drain()
 }
 
 // completedServerConfig holds the full configuration to run a spicedb server,
@@ -382,16 +690,25 @@ type RunnableServer interface {
 type completedServerConfig struct {
 	gRPCServer         util.RunnableGRPCServer
 	dispatchGRPCServer util.RunnableGRPCServer
+	dispatchHealthSrv  *grpcutil.AuthlessHealthServer
 	gatewayServer      util.RunnableHTTPServer
 	metricsServer      util.RunnableHTTPServer
 	dashboardServer    util.RunnableHTTPServer
 	telemetryReporter  telemetry.Reporter
+	profilingPusher    profiling.Pusher
 	healthManager      health.Manager
-
-	unaryMiddleware     []grpc.UnaryServerInterceptor
-	streamingMiddleware []grpc.StreamServerInterceptor
-	presharedKeys       []string
-	closeFunc           func()
+	experiments        *experiments.Registry
+	decisionLog        *decisionlog.Logger
+	quantization       *revisions.Quantization
+	objectTypes        *namespacemetrics.ObjectTypes
+
+	experimentsRefreshInterval time.Duration
+	unaryMiddleware            []grpc.UnaryServerInterceptor
+	streamingMiddleware        []grpc.StreamServerInterceptor
+	presharedKeys              func() []string
+	presharedKeyFile           *auth.PresharedKeyFile
+	drainTimeout               time.Duration
+	closeFunc                  func()
 }
 
 func (c *completedServerConfig) Middleware() ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
@@ -405,14 +722,10 @@ func (c *completedServerConfig) SetMiddleware(unaryInterceptors []grpc.UnaryServ
 }
 
 func (c *completedServerConfig) GRPCDialContext(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
-	if len(c.presharedKeys) == 0 {
+	if len(c.presharedKeys()) == 0 {
 		return c.gRPCServer.DialContext(ctx, opts...)
 	}
-	if c.gRPCServer.Insecure() {
-		opts = append(opts, grpcutil.WithInsecureBearerToken(c.presharedKeys[0]))
-	} else {
-		opts = append(opts, grpcutil.WithBearerToken(c.presharedKeys[0]))
-	}
+	opts = append(opts, grpc.WithPerRPCCredentials(auth.PresharedKeyCredentials(c.presharedKeys, !c.gRPCServer.Insecure())))
 	return c.gRPCServer.DialContext(ctx, opts...)
 }
 
@@ -420,6 +733,39 @@ func (c *completedServerConfig) DispatchNetDialContext(ctx context.Context, s st
 	return c.dispatchGRPCServer.NetDialContext(ctx, s)
 }
 
+func (c *completedServerConfig) Drain() {
+	log.Info().Msg("draining: reporting not serving to health checks and dispatching peers")
+	c.healthManager.Drain()
+	c.dispatchHealthSrv.Shutdown()
+}
+
+// stopWithDrainTimeout gracefully stops the server, forcibly stopping it if requests in
+// flight have not completed within the drain timeout.
+func (c *completedServerConfig) stopWithDrainTimeout(srv util.RunnableGRPCServer) func() {
+	return func() {
+		if c.drainTimeout <= 0 {
+			srv.GracefulStop()
+			return
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+
+		timer := time.NewTimer(c.drainTimeout)
+		defer timer.Stop()
+		select {
+		case <-stopped:
+		case <-timer.C:
+			log.Warn().Stringer("timeout", c.drainTimeout).Msg("requests still in flight after drain timeout; closing connections")
+			srv.Stop()
+			<-stopped
+		}
+	}
+}
+
 func (c *completedServerConfig) Run(ctx context.Context) error {
 	g, ctx := errgroup.WithContext(ctx)
 
@@ -434,10 +780,10 @@ func (c *completedServerConfig) Run(ctx context.Context) error {
 	grpcServer := c.gRPCServer.WithOpts(grpc.ChainUnaryInterceptor(c.unaryMiddleware...), grpc.ChainStreamInterceptor(c.streamingMiddleware...))
 	g.Go(c.healthManager.Checker(ctx))
 	g.Go(grpcServer.Listen(ctx))
-	g.Go(stopOnCancel(grpcServer.GracefulStop))
+	g.Go(stopOnCancel(c.stopWithDrainTimeout(grpcServer)))
 
 	g.Go(c.dispatchGRPCServer.Listen(ctx))
-	g.Go(stopOnCancel(c.dispatchGRPCServer.GracefulStop))
+	g.Go(stopOnCancel(c.stopWithDrainTimeout(c.dispatchGRPCServer)))
 
 	g.Go(c.gatewayServer.ListenAndServe)
 	g.Go(stopOnCancel(c.gatewayServer.Close))
@@ -449,6 +795,18 @@ func (c *completedServerConfig) Run(ctx context.Context) error {
 	g.Go(stopOnCancel(c.dashboardServer.Close))
 
 	g.Go(func() error { return c.telemetryReporter(ctx) })
+	g.Go(func() error { return c.profilingPusher(ctx) })
+	g.Go(func() error { return c.experiments.Start(ctx, c.experimentsRefreshInterval) })
+	g.Go(func() error { return c.objectTypes.Start(ctx, objectTypesRefreshInterval) })
+	g.Go(func() error { return c.decisionLog.Start(ctx) })
+
+	if c.quantization != nil {
+		g.Go(func() error { return c.quantization.Start(ctx, revisionQuantizationAdjustInterval) })
+	}
+
+	if c.presharedKeyFile != nil {
+		g.Go(func() error { return c.presharedKeyFile.Start(ctx) })
+	}
 
 	g.Go(stopOnCancel(c.closeFunc))
 
@@ -462,7 +820,8 @@ func (c *completedServerConfig) Run(ctx context.Context) error {
 var promOnce sync.Once
 
 // enableGRPCHistogram enables the standard time history for gRPC requests,
-// ensuring that it is only enabled once
+// ensuring that it is only enabled once, and registers the metrics of API requests by
+// object type alongside it.
 func enableGRPCHistogram() {
 	// EnableHandlingTimeHistogram is not thread safe and only needs to happen
 	// once
@@ -470,5 +829,8 @@ func enableGRPCHistogram() {
 		grpcprom.EnableHandlingTimeHistogram(grpcprom.WithHistogramBuckets(
 			[]float64{.006, .010, .018, .024, .032, .042, .056, .075, .100, .178, .316, .562, 1.000},
 		))
+		if err := namespacemetrics.RegisterMetrics(); err != nil {
+			log.Warn().Err(err).Msg("unable to register the metrics of API requests by object type")
+		}
 	})
 }
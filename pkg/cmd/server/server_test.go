@@ -0,0 +1,37 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/cmd/util"
+)
+
+// inflightServer is a server with a request in flight which never completes by itself,
+// so that GracefulStop only returns once Stop is called.
+type inflightServer struct {
+	util.RunnableGRPCServer
+	stopped chan struct{}
+	forced  bool
+}
+
+func (s *inflightServer) GracefulStop() {
+	<-s.stopped
+}
+
+func (s *inflightServer) Stop() {
+	s.forced = true
+	close(s.stopped)
+}
+
+func TestStopWithDrainTimeout(t *testing.T) {
+	srv := &inflightServer{stopped: make(chan struct{})}
+	c := &completedServerConfig{drainTimeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	c.stopWithDrainTimeout(srv)()
+	require.True(t, srv.forced)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
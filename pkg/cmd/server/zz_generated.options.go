@@ -2,13 +2,17 @@
 package server
 
 import (
+	"time"
+
 	dispatch "github.com/authzed/spicedb/internal/dispatch"
+	scim "github.com/authzed/spicedb/internal/gateway/scim"
+	runtimeconfig "github.com/authzed/spicedb/internal/runtimeconfig"
+	admission "github.com/authzed/spicedb/pkg/admission"
 	datastore "github.com/authzed/spicedb/pkg/cmd/datastore"
 	util "github.com/authzed/spicedb/pkg/cmd/util"
 	datastore1 "github.com/authzed/spicedb/pkg/datastore"
 	auth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
 	grpc "google.golang.org/grpc"
-	"time"
 )
 
 type ConfigOption func(c *Config)
@@ -26,22 +30,33 @@ func NewConfigWithOptions(opts ...ConfigOption) *Config {
 func (c *Config) ToOption() ConfigOption {
 	return func(to *Config) {
 		to.GRPCServer = c.GRPCServer
+		to.GRPCGzipLevel = c.GRPCGzipLevel
+		to.GRPCZstdLevel = c.GRPCZstdLevel
 		to.GRPCAuthFunc = c.GRPCAuthFunc
 		to.PresharedKey = c.PresharedKey
+		to.PresharedKeyFile = c.PresharedKeyFile
 		to.ShutdownGracePeriod = c.ShutdownGracePeriod
+		to.ShutdownDrainTimeout = c.ShutdownDrainTimeout
 		to.DisableVersionResponse = c.DisableVersionResponse
+		to.ReadOnly = c.ReadOnly
 		to.HTTPGateway = c.HTTPGateway
 		to.HTTPGatewayUpstreamAddr = c.HTTPGatewayUpstreamAddr
 		to.HTTPGatewayUpstreamTLSCertPath = c.HTTPGatewayUpstreamTLSCertPath
 		to.HTTPGatewayCorsEnabled = c.HTTPGatewayCorsEnabled
 		to.HTTPGatewayCorsAllowedOrigins = c.HTTPGatewayCorsAllowedOrigins
+		to.HTTPGatewayOpenFGAEnabled = c.HTTPGatewayOpenFGAEnabled
+		to.HTTPGatewayGraphQLEnabled = c.HTTPGatewayGraphQLEnabled
+		to.HTTPGatewaySCIMEnabled = c.HTTPGatewaySCIMEnabled
+		to.HTTPGatewaySCIMConfig = c.HTTPGatewaySCIMConfig
 		to.DatastoreConfig = c.DatastoreConfig
 		to.Datastore = c.Datastore
 		to.NamespaceCacheConfig = c.NamespaceCacheConfig
 		to.SchemaPrefixesRequired = c.SchemaPrefixesRequired
+		to.SchemaRemovedDefinitionRetention = c.SchemaRemovedDefinitionRetention
 		to.DispatchServer = c.DispatchServer
 		to.DispatchMaxDepth = c.DispatchMaxDepth
 		to.DispatchConcurrencyLimit = c.DispatchConcurrencyLimit
+		to.DispatchSuperNodeThreshold = c.DispatchSuperNodeThreshold
 		to.DispatchUpstreamAddr = c.DispatchUpstreamAddr
 		to.DispatchUpstreamCAPath = c.DispatchUpstreamCAPath
 		to.DispatchClientMetricsPrefix = c.DispatchClientMetricsPrefix
@@ -53,7 +68,40 @@ func (c *Config) ToOption() ConfigOption {
 		to.V1SchemaAdditiveOnly = c.V1SchemaAdditiveOnly
 		to.MaximumUpdatesPerWrite = c.MaximumUpdatesPerWrite
 		to.MaximumPreconditionCount = c.MaximumPreconditionCount
+		to.CardinalitySoftLimits = c.CardinalitySoftLimits
+		to.CardinalityHardLimits = c.CardinalityHardLimits
+		to.MaximumRequestedStaleness = c.MaximumRequestedStaleness
+		to.SubstituteExpiredRevisions = c.SubstituteExpiredRevisions
+		to.MaximumRevisionPinTTL = c.MaximumRevisionPinTTL
+		to.OrphanDeletionBatchSize = c.OrphanDeletionBatchSize
+		to.OrphanDeletionInterval = c.OrphanDeletionInterval
+		to.StreamingCheckConcurrency = c.StreamingCheckConcurrency
+		to.BulkCheckConcurrency = c.BulkCheckConcurrency
+		to.AccessReportConcurrency = c.AccessReportConcurrency
+		to.DeletionImpactConcurrency = c.DeletionImpactConcurrency
+		to.PrefetchConcurrency = c.PrefetchConcurrency
+		to.CanarySchemaConcurrency = c.CanarySchemaConcurrency
+		to.JobsHeartbeatInterval = c.JobsHeartbeatInterval
+		to.PeerDatastoreIDs = c.PeerDatastoreIDs
+		to.MaximumPeerClockSkew = c.MaximumPeerClockSkew
+		to.ReadYourWritesTTL = c.ReadYourWritesTTL
+		to.MaximumLookupMemoryBytes = c.MaximumLookupMemoryBytes
+		to.LookupSpillDirectory = c.LookupSpillDirectory
+		to.SortLookupResults = c.SortLookupResults
 		to.ExperimentalCaveatsEnabled = c.ExperimentalCaveatsEnabled
+		to.NamespaceExperimentsRefreshInterval = c.NamespaceExperimentsRefreshInterval
+		to.AdmissionHooks = c.AdmissionHooks
+		to.AdmissionWebhookAddr = c.AdmissionWebhookAddr
+		to.AdmissionWebhookCAPath = c.AdmissionWebhookCAPath
+		to.AdmissionWebhookTimeout = c.AdmissionWebhookTimeout
+		to.AdmissionWebhookFailOpen = c.AdmissionWebhookFailOpen
+		to.DecisionLogURI = c.DecisionLogURI
+		to.DecisionLogS3Endpoint = c.DecisionLogS3Endpoint
+		to.DecisionLogS3Region = c.DecisionLogS3Region
+		to.DecisionLogSampleRate = c.DecisionLogSampleRate
+		to.DecisionLogFormat = c.DecisionLogFormat
+		to.DecisionLogFlushInterval = c.DecisionLogFlushInterval
+		to.DecisionLogBatchSize = c.DecisionLogBatchSize
 		to.DashboardAPI = c.DashboardAPI
 		to.MetricsAPI = c.MetricsAPI
 		to.UnaryMiddleware = c.UnaryMiddleware
@@ -64,6 +112,10 @@ func (c *Config) ToOption() ConfigOption {
 		to.TelemetryCAOverridePath = c.TelemetryCAOverridePath
 		to.TelemetryEndpoint = c.TelemetryEndpoint
 		to.TelemetryInterval = c.TelemetryInterval
+		to.ResolvedFlags = c.ResolvedFlags
+		to.ContinuousProfilingEndpoint = c.ContinuousProfilingEndpoint
+		to.ContinuousProfilingApplicationName = c.ContinuousProfilingApplicationName
+		to.ContinuousProfilingInterval = c.ContinuousProfilingInterval
 	}
 }
 
@@ -82,6 +134,20 @@ func WithGRPCServer(gRPCServer util.GRPCServerConfig) ConfigOption {
 	}
 }
 
+// WithGRPCGzipLevel returns an option that can set GRPCGzipLevel on a Config
+func WithGRPCGzipLevel(gRPCGzipLevel int) ConfigOption {
+	return func(c *Config) {
+		c.GRPCGzipLevel = gRPCGzipLevel
+	}
+}
+
+// WithGRPCZstdLevel returns an option that can set GRPCZstdLevel on a Config
+func WithGRPCZstdLevel(gRPCZstdLevel int) ConfigOption {
+	return func(c *Config) {
+		c.GRPCZstdLevel = gRPCZstdLevel
+	}
+}
+
 // WithGRPCAuthFunc returns an option that can set GRPCAuthFunc on a Config
 func WithGRPCAuthFunc(gRPCAuthFunc auth.AuthFunc) ConfigOption {
 	return func(c *Config) {
@@ -103,6 +169,13 @@ func SetPresharedKey(presharedKey []string) ConfigOption {
 	}
 }
 
+// WithPresharedKeyFile returns an option that can set PresharedKeyFile on a Config
+func WithPresharedKeyFile(presharedKeyFile string) ConfigOption {
+	return func(c *Config) {
+		c.PresharedKeyFile = presharedKeyFile
+	}
+}
+
 // WithShutdownGracePeriod returns an option that can set ShutdownGracePeriod on a Config
 func WithShutdownGracePeriod(shutdownGracePeriod time.Duration) ConfigOption {
 	return func(c *Config) {
@@ -110,6 +183,13 @@ func WithShutdownGracePeriod(shutdownGracePeriod time.Duration) ConfigOption {
 	}
 }
 
+// WithShutdownDrainTimeout returns an option that can set ShutdownDrainTimeout on a Config
+func WithShutdownDrainTimeout(shutdownDrainTimeout time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.ShutdownDrainTimeout = shutdownDrainTimeout
+	}
+}
+
 // WithDisableVersionResponse returns an option that can set DisableVersionResponse on a Config
 func WithDisableVersionResponse(disableVersionResponse bool) ConfigOption {
 	return func(c *Config) {
@@ -117,6 +197,13 @@ func WithDisableVersionResponse(disableVersionResponse bool) ConfigOption {
 	}
 }
 
+// WithReadOnly returns an option that can set ReadOnly on a Config
+func WithReadOnly(readOnly bool) ConfigOption {
+	return func(c *Config) {
+		c.ReadOnly = readOnly
+	}
+}
+
 // WithHTTPGateway returns an option that can set HTTPGateway on a Config
 func WithHTTPGateway(hTTPGateway util.HTTPServerConfig) ConfigOption {
 	return func(c *Config) {
@@ -159,6 +246,34 @@ func SetHTTPGatewayCorsAllowedOrigins(hTTPGatewayCorsAllowedOrigins []string) Co
 	}
 }
 
+// WithHTTPGatewayOpenFGAEnabled returns an option that can set HTTPGatewayOpenFGAEnabled on a Config
+func WithHTTPGatewayOpenFGAEnabled(hTTPGatewayOpenFGAEnabled bool) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewayOpenFGAEnabled = hTTPGatewayOpenFGAEnabled
+	}
+}
+
+// WithHTTPGatewayGraphQLEnabled returns an option that can set HTTPGatewayGraphQLEnabled on a Config
+func WithHTTPGatewayGraphQLEnabled(hTTPGatewayGraphQLEnabled bool) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewayGraphQLEnabled = hTTPGatewayGraphQLEnabled
+	}
+}
+
+// WithHTTPGatewaySCIMEnabled returns an option that can set HTTPGatewaySCIMEnabled on a Config
+func WithHTTPGatewaySCIMEnabled(hTTPGatewaySCIMEnabled bool) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewaySCIMEnabled = hTTPGatewaySCIMEnabled
+	}
+}
+
+// WithHTTPGatewaySCIMConfig returns an option that can set HTTPGatewaySCIMConfig on a Config
+func WithHTTPGatewaySCIMConfig(hTTPGatewaySCIMConfig scim.Config) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewaySCIMConfig = hTTPGatewaySCIMConfig
+	}
+}
+
 // WithDatastoreConfig returns an option that can set DatastoreConfig on a Config
 func WithDatastoreConfig(datastoreConfig datastore.Config) ConfigOption {
 	return func(c *Config) {
@@ -187,6 +302,13 @@ func WithSchemaPrefixesRequired(schemaPrefixesRequired bool) ConfigOption {
 	}
 }
 
+// WithSchemaRemovedDefinitionRetention returns an option that can set SchemaRemovedDefinitionRetention on a Config
+func WithSchemaRemovedDefinitionRetention(schemaRemovedDefinitionRetention time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.SchemaRemovedDefinitionRetention = schemaRemovedDefinitionRetention
+	}
+}
+
 // WithDispatchServer returns an option that can set DispatchServer on a Config
 func WithDispatchServer(dispatchServer util.GRPCServerConfig) ConfigOption {
 	return func(c *Config) {
@@ -208,6 +330,13 @@ func WithDispatchConcurrencyLimit(dispatchConcurrencyLimit uint16) ConfigOption
 	}
 }
 
+// WithDispatchSuperNodeThreshold returns an option that can set DispatchSuperNodeThreshold on a Config
+func WithDispatchSuperNodeThreshold(dispatchSuperNodeThreshold uint64) ConfigOption {
+	return func(c *Config) {
+		c.DispatchSuperNodeThreshold = dispatchSuperNodeThreshold
+	}
+}
+
 // WithDispatchUpstreamAddr returns an option that can set DispatchUpstreamAddr on a Config
 func WithDispatchUpstreamAddr(dispatchUpstreamAddr string) ConfigOption {
 	return func(c *Config) {
@@ -285,6 +414,167 @@ func WithMaximumPreconditionCount(maximumPreconditionCount uint16) ConfigOption
 	}
 }
 
+// WithCardinalitySoftLimits returns an option that can append CardinalitySoftLimitss to Config.CardinalitySoftLimits
+func WithCardinalitySoftLimits(cardinalitySoftLimits string) ConfigOption {
+	return func(c *Config) {
+		c.CardinalitySoftLimits = append(c.CardinalitySoftLimits, cardinalitySoftLimits)
+	}
+}
+
+// SetCardinalitySoftLimits returns an option that can set CardinalitySoftLimits on a Config
+func SetCardinalitySoftLimits(cardinalitySoftLimits []string) ConfigOption {
+	return func(c *Config) {
+		c.CardinalitySoftLimits = cardinalitySoftLimits
+	}
+}
+
+// WithCardinalityHardLimits returns an option that can append CardinalityHardLimitss to Config.CardinalityHardLimits
+func WithCardinalityHardLimits(cardinalityHardLimits string) ConfigOption {
+	return func(c *Config) {
+		c.CardinalityHardLimits = append(c.CardinalityHardLimits, cardinalityHardLimits)
+	}
+}
+
+// SetCardinalityHardLimits returns an option that can set CardinalityHardLimits on a Config
+func SetCardinalityHardLimits(cardinalityHardLimits []string) ConfigOption {
+	return func(c *Config) {
+		c.CardinalityHardLimits = cardinalityHardLimits
+	}
+}
+
+// WithMaximumRequestedStaleness returns an option that can set MaximumRequestedStaleness on a Config
+func WithMaximumRequestedStaleness(maximumRequestedStaleness time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.MaximumRequestedStaleness = maximumRequestedStaleness
+	}
+}
+
+// WithSubstituteExpiredRevisions returns an option that can set SubstituteExpiredRevisions on a Config
+func WithSubstituteExpiredRevisions(substituteExpiredRevisions bool) ConfigOption {
+	return func(c *Config) {
+		c.SubstituteExpiredRevisions = substituteExpiredRevisions
+	}
+}
+
+// WithMaximumRevisionPinTTL returns an option that can set MaximumRevisionPinTTL on a Config
+func WithMaximumRevisionPinTTL(maximumRevisionPinTTL time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.MaximumRevisionPinTTL = maximumRevisionPinTTL
+	}
+}
+
+// WithOrphanDeletionBatchSize returns an option that can set OrphanDeletionBatchSize on a Config
+func WithOrphanDeletionBatchSize(orphanDeletionBatchSize int) ConfigOption {
+	return func(c *Config) {
+		c.OrphanDeletionBatchSize = orphanDeletionBatchSize
+	}
+}
+
+// WithOrphanDeletionInterval returns an option that can set OrphanDeletionInterval on a Config
+func WithOrphanDeletionInterval(orphanDeletionInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.OrphanDeletionInterval = orphanDeletionInterval
+	}
+}
+
+// WithStreamingCheckConcurrency returns an option that can set StreamingCheckConcurrency on a Config
+func WithStreamingCheckConcurrency(streamingCheckConcurrency int) ConfigOption {
+	return func(c *Config) {
+		c.StreamingCheckConcurrency = streamingCheckConcurrency
+	}
+}
+
+// WithBulkCheckConcurrency returns an option that can set BulkCheckConcurrency on a Config
+func WithBulkCheckConcurrency(bulkCheckConcurrency int) ConfigOption {
+	return func(c *Config) {
+		c.BulkCheckConcurrency = bulkCheckConcurrency
+	}
+}
+
+// WithAccessReportConcurrency returns an option that can set AccessReportConcurrency on a Config
+func WithAccessReportConcurrency(accessReportConcurrency int) ConfigOption {
+	return func(c *Config) {
+		c.AccessReportConcurrency = accessReportConcurrency
+	}
+}
+
+// WithDeletionImpactConcurrency returns an option that can set DeletionImpactConcurrency on a Config
+func WithDeletionImpactConcurrency(deletionImpactConcurrency int) ConfigOption {
+	return func(c *Config) {
+		c.DeletionImpactConcurrency = deletionImpactConcurrency
+	}
+}
+
+// WithPrefetchConcurrency returns an option that can set PrefetchConcurrency on a Config
+func WithPrefetchConcurrency(prefetchConcurrency int) ConfigOption {
+	return func(c *Config) {
+		c.PrefetchConcurrency = prefetchConcurrency
+	}
+}
+
+// WithCanarySchemaConcurrency returns an option that can set CanarySchemaConcurrency on a Config
+func WithCanarySchemaConcurrency(canarySchemaConcurrency int) ConfigOption {
+	return func(c *Config) {
+		c.CanarySchemaConcurrency = canarySchemaConcurrency
+	}
+}
+
+// WithJobsHeartbeatInterval returns an option that can set JobsHeartbeatInterval on a Config
+func WithJobsHeartbeatInterval(jobsHeartbeatInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.JobsHeartbeatInterval = jobsHeartbeatInterval
+	}
+}
+
+// WithPeerDatastoreIDs returns an option that can append PeerDatastoreIDss to Config.PeerDatastoreIDs
+func WithPeerDatastoreIDs(peerDatastoreIDs string) ConfigOption {
+	return func(c *Config) {
+		c.PeerDatastoreIDs = append(c.PeerDatastoreIDs, peerDatastoreIDs)
+	}
+}
+
+// SetPeerDatastoreIDs returns an option that can set PeerDatastoreIDs on a Config
+func SetPeerDatastoreIDs(peerDatastoreIDs []string) ConfigOption {
+	return func(c *Config) {
+		c.PeerDatastoreIDs = peerDatastoreIDs
+	}
+}
+
+// WithMaximumPeerClockSkew returns an option that can set MaximumPeerClockSkew on a Config
+func WithMaximumPeerClockSkew(maximumPeerClockSkew time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.MaximumPeerClockSkew = maximumPeerClockSkew
+	}
+}
+
+// WithReadYourWritesTTL returns an option that can set ReadYourWritesTTL on a Config
+func WithReadYourWritesTTL(readYourWritesTTL time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.ReadYourWritesTTL = readYourWritesTTL
+	}
+}
+
+// WithMaximumLookupMemoryBytes returns an option that can set MaximumLookupMemoryBytes on a Config
+func WithMaximumLookupMemoryBytes(maximumLookupMemoryBytes uint64) ConfigOption {
+	return func(c *Config) {
+		c.MaximumLookupMemoryBytes = maximumLookupMemoryBytes
+	}
+}
+
+// WithLookupSpillDirectory returns an option that can set LookupSpillDirectory on a Config
+func WithLookupSpillDirectory(lookupSpillDirectory string) ConfigOption {
+	return func(c *Config) {
+		c.LookupSpillDirectory = lookupSpillDirectory
+	}
+}
+
+// WithSortLookupResults returns an option that can set SortLookupResults on a Config
+func WithSortLookupResults(sortLookupResults bool) ConfigOption {
+	return func(c *Config) {
+		c.SortLookupResults = sortLookupResults
+	}
+}
+
 // WithExperimentalCaveatsEnabled returns an option that can set ExperimentalCaveatsEnabled on a Config
 func WithExperimentalCaveatsEnabled(experimentalCaveatsEnabled bool) ConfigOption {
 	return func(c *Config) {
@@ -292,6 +582,104 @@ func WithExperimentalCaveatsEnabled(experimentalCaveatsEnabled bool) ConfigOptio
 	}
 }
 
+// WithNamespaceExperimentsRefreshInterval returns an option that can set NamespaceExperimentsRefreshInterval on a Config
+func WithNamespaceExperimentsRefreshInterval(namespaceExperimentsRefreshInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.NamespaceExperimentsRefreshInterval = namespaceExperimentsRefreshInterval
+	}
+}
+
+// WithAdmissionHooks returns an option that can append AdmissionHookss to Config.AdmissionHooks
+func WithAdmissionHooks(admissionHooks admission.Hook) ConfigOption {
+	return func(c *Config) {
+		c.AdmissionHooks = append(c.AdmissionHooks, admissionHooks)
+	}
+}
+
+// SetAdmissionHooks returns an option that can set AdmissionHooks on a Config
+func SetAdmissionHooks(admissionHooks []admission.Hook) ConfigOption {
+	return func(c *Config) {
+		c.AdmissionHooks = admissionHooks
+	}
+}
+
+// WithAdmissionWebhookAddr returns an option that can set AdmissionWebhookAddr on a Config
+func WithAdmissionWebhookAddr(admissionWebhookAddr string) ConfigOption {
+	return func(c *Config) {
+		c.AdmissionWebhookAddr = admissionWebhookAddr
+	}
+}
+
+// WithAdmissionWebhookCAPath returns an option that can set AdmissionWebhookCAPath on a Config
+func WithAdmissionWebhookCAPath(admissionWebhookCAPath string) ConfigOption {
+	return func(c *Config) {
+		c.AdmissionWebhookCAPath = admissionWebhookCAPath
+	}
+}
+
+// WithAdmissionWebhookTimeout returns an option that can set AdmissionWebhookTimeout on a Config
+func WithAdmissionWebhookTimeout(admissionWebhookTimeout time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.AdmissionWebhookTimeout = admissionWebhookTimeout
+	}
+}
+
+// WithAdmissionWebhookFailOpen returns an option that can set AdmissionWebhookFailOpen on a Config
+func WithAdmissionWebhookFailOpen(admissionWebhookFailOpen bool) ConfigOption {
+	return func(c *Config) {
+		c.AdmissionWebhookFailOpen = admissionWebhookFailOpen
+	}
+}
+
+// WithDecisionLogURI returns an option that can set DecisionLogURI on a Config
+func WithDecisionLogURI(decisionLogURI string) ConfigOption {
+	return func(c *Config) {
+		c.DecisionLogURI = decisionLogURI
+	}
+}
+
+// WithDecisionLogS3Endpoint returns an option that can set DecisionLogS3Endpoint on a Config
+func WithDecisionLogS3Endpoint(decisionLogS3Endpoint string) ConfigOption {
+	return func(c *Config) {
+		c.DecisionLogS3Endpoint = decisionLogS3Endpoint
+	}
+}
+
+// WithDecisionLogS3Region returns an option that can set DecisionLogS3Region on a Config
+func WithDecisionLogS3Region(decisionLogS3Region string) ConfigOption {
+	return func(c *Config) {
+		c.DecisionLogS3Region = decisionLogS3Region
+	}
+}
+
+// WithDecisionLogSampleRate returns an option that can set DecisionLogSampleRate on a Config
+func WithDecisionLogSampleRate(decisionLogSampleRate float64) ConfigOption {
+	return func(c *Config) {
+		c.DecisionLogSampleRate = decisionLogSampleRate
+	}
+}
+
+// WithDecisionLogFormat returns an option that can set DecisionLogFormat on a Config
+func WithDecisionLogFormat(decisionLogFormat string) ConfigOption {
+	return func(c *Config) {
+		c.DecisionLogFormat = decisionLogFormat
+	}
+}
+
+// WithDecisionLogFlushInterval returns an option that can set DecisionLogFlushInterval on a Config
+func WithDecisionLogFlushInterval(decisionLogFlushInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.DecisionLogFlushInterval = decisionLogFlushInterval
+	}
+}
+
+// WithDecisionLogBatchSize returns an option that can set DecisionLogBatchSize on a Config
+func WithDecisionLogBatchSize(decisionLogBatchSize int) ConfigOption {
+	return func(c *Config) {
+		c.DecisionLogBatchSize = decisionLogBatchSize
+	}
+}
+
 // WithDashboardAPI returns an option that can set DashboardAPI on a Config
 func WithDashboardAPI(dashboardAPI util.HTTPServerConfig) ConfigOption {
 	return func(c *Config) {
@@ -389,3 +777,38 @@ func WithTelemetryInterval(telemetryInterval time.Duration) ConfigOption {
 		c.TelemetryInterval = telemetryInterval
 	}
 }
+
+// WithResolvedFlags returns an option that can append ResolvedFlagss to Config.ResolvedFlags
+func WithResolvedFlags(resolvedFlags runtimeconfig.Flag) ConfigOption {
+	return func(c *Config) {
+		c.ResolvedFlags = append(c.ResolvedFlags, resolvedFlags)
+	}
+}
+
+// SetResolvedFlags returns an option that can set ResolvedFlags on a Config
+func SetResolvedFlags(resolvedFlags []runtimeconfig.Flag) ConfigOption {
+	return func(c *Config) {
+		c.ResolvedFlags = resolvedFlags
+	}
+}
+
+// WithContinuousProfilingEndpoint returns an option that can set ContinuousProfilingEndpoint on a Config
+func WithContinuousProfilingEndpoint(continuousProfilingEndpoint string) ConfigOption {
+	return func(c *Config) {
+		c.ContinuousProfilingEndpoint = continuousProfilingEndpoint
+	}
+}
+
+// WithContinuousProfilingApplicationName returns an option that can set ContinuousProfilingApplicationName on a Config
+func WithContinuousProfilingApplicationName(continuousProfilingApplicationName string) ConfigOption {
+	return func(c *Config) {
+		c.ContinuousProfilingApplicationName = continuousProfilingApplicationName
+	}
+}
+
+// WithContinuousProfilingInterval returns an option that can set ContinuousProfilingInterval on a Config
+func WithContinuousProfilingInterval(continuousProfilingInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.ContinuousProfilingInterval = continuousProfilingInterval
+	}
+}
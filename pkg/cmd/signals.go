@@ -14,12 +14,22 @@ import (
 // when an interrupt/SIGTERM signal is received and the provided grace period
 // subsequently finishes.
 func SignalContextWithGracePeriod(ctx context.Context, gracePeriod time.Duration) context.Context {
+	return SignalContextWithDrain(ctx, gracePeriod, nil)
+}
+
+// SignalContextWithDrain is SignalContextWithGracePeriod, additionally calling drainFn,
+// if non-nil, as soon as the signal is received, before the grace period starts.
+func SignalContextWithDrain(ctx context.Context, gracePeriod time.Duration, drainFn func()) context.Context {
 	newCtx, cancelfn := context.WithCancel(ctx)
 	go func() {
 		signalctx, _ := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		<-signalctx.Done()
 		log.Info().Msg("received interrupt")
 
+		if drainFn != nil {
+			drainFn()
+		}
+
 		if gracePeriod > 0 {
 			interruptGrace, _ := signal.NotifyContext(context.Background(), os.Interrupt)
 			graceTimer := time.NewTimer(gracePeriod)
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -18,6 +19,8 @@ func RegisterTestingFlags(cmd *cobra.Command, config *testserver.Config) {
 	util.RegisterHTTPServerFlags(cmd.Flags(), &config.ReadOnlyHTTPGateway, "readonly-http", "read-only HTTP", ":8082", false)
 
 	cmd.Flags().StringSliceVar(&config.LoadConfigs, "load-configs", []string{}, "configuration yaml files to load")
+	cmd.Flags().StringVar(&config.PersistDir, "persist-dir", "", "directory to which each token's datastore is persisted, and from which it is restored instead of being loaded from --load-configs (empty to keep datastores in memory only)")
+	cmd.Flags().DurationVar(&config.PersistInterval, "persist-interval", 30*time.Second, "how often changed datastores are persisted to --persist-dir, in addition to at shutdown (0 to only persist at shutdown)")
 
 	// Flags for API behavior
 	cmd.Flags().Uint16Var(&config.MaximumUpdatesPerWrite, "write-relationships-max-updates-per-call", 1000, "maximum number of updates allowed for WriteRelationships calls")
@@ -28,7 +31,7 @@ func NewTestingCommand(programName string, config *testserver.Config) *cobra.Com
 	return &cobra.Command{
 		Use:     "serve-testing",
 		Short:   "test server with an in-memory datastore",
-		Long:    "An in-memory spicedb server which serves completely isolated datastores per client-supplied auth token used.",
+		Long:    "An in-memory spicedb server which serves completely isolated datastores per client-supplied auth token used. With --persist-dir, each datastore is kept across restarts, for use as a long-lived shared environment.",
 		PreRunE: server.DefaultPreRunE(programName),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			signalctx := SignalContextWithGracePeriod(
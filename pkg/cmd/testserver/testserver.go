@@ -3,6 +3,8 @@ package testserver
 import (
 	"context"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/rs/zerolog"
 	"golang.org/x/sync/errgroup"
@@ -31,6 +33,8 @@ type Config struct {
 	HTTPGateway              util.HTTPServerConfig
 	ReadOnlyHTTPGateway      util.HTTPServerConfig
 	LoadConfigs              []string
+	PersistDir               string
+	PersistInterval          time.Duration
 	MaximumUpdatesPerWrite   uint16
 	MaximumPreconditionCount uint16
 }
@@ -50,7 +54,12 @@ func (dr datastoreReady) IsReady(ctx context.Context) (bool, error) {
 func (c *Config) Complete() (RunnableTestServer, error) {
 	dispatcher := graph.NewLocalOnlyDispatcher(10)
 
-	datastoreMiddleware := pertoken.NewMiddleware(c.LoadConfigs)
+	if c.PersistDir != "" {
+		if err := os.MkdirAll(c.PersistDir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create persistence directory: %w", err)
+		}
+	}
+	datastoreMiddleware := pertoken.NewPersistentMiddleware(c.LoadConfigs, c.PersistDir)
 
 	healthManager := health.NewHealthManager(dispatcher, &datastoreReady{})
 
@@ -67,6 +76,10 @@ func (c *Config) Complete() (RunnableTestServer, error) {
 				MaxUpdatesPerWrite:    c.MaximumUpdatesPerWrite,
 				MaximumAPIDepth:       maxDepth,
 			},
+			v1svc.ExperimentalServerConfig{
+				MaximumAPIDepth: maxDepth,
+			},
+			nil,
 		)
 	}
 	gRPCSrv, err := c.GRPCServer.Complete(zerolog.InfoLevel, registerServices,
@@ -107,7 +120,7 @@ func (c *Config) Complete() (RunnableTestServer, error) {
 		return nil, err
 	}
 
-	gatewayHandler, err := gateway.NewHandler(context.TODO(), c.GRPCServer.Address, c.GRPCServer.TLSCertPath)
+	gatewayHandler, err := gateway.NewHandler(context.TODO(), c.GRPCServer.Address, c.GRPCServer.TLSCertPath, gateway.Options{})
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize rest gateway")
 	}
@@ -121,7 +134,7 @@ func (c *Config) Complete() (RunnableTestServer, error) {
 		return nil, fmt.Errorf("failed to initialize rest gateway: %w", err)
 	}
 
-	readOnlyGatewayHandler, err := gateway.NewHandler(context.TODO(), c.ReadOnlyGRPCServer.Address, c.ReadOnlyGRPCServer.TLSCertPath)
+	readOnlyGatewayHandler, err := gateway.NewHandler(context.TODO(), c.ReadOnlyGRPCServer.Address, c.ReadOnlyGRPCServer.TLSCertPath, gateway.Options{})
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize rest gateway")
 	}
@@ -141,6 +154,8 @@ func (c *Config) Complete() (RunnableTestServer, error) {
 		gatewayServer:         gatewayServer,
 		readOnlyGatewayServer: readOnlyGatewayServer,
 		healthManager:         healthManager,
+		datastoreMiddleware:   datastoreMiddleware,
+		persistInterval:       c.PersistInterval,
 	}, nil
 }
 
@@ -152,6 +167,9 @@ type completedTestServer struct {
 	readOnlyGatewayServer util.RunnableHTTPServer
 
 	healthManager health.Manager
+
+	datastoreMiddleware *pertoken.MiddlewareForTesting
+	persistInterval     time.Duration
 }
 
 func (c *completedTestServer) Run(ctx context.Context) error {
@@ -179,10 +197,32 @@ func (c *completedTestServer) Run(ctx context.Context) error {
 	g.Go(c.readOnlyGatewayServer.ListenAndServe)
 	g.Go(stopOnCancel(c.readOnlyGatewayServer.Close))
 
+	if c.persistInterval > 0 {
+		g.Go(func() error {
+			ticker := time.NewTicker(c.persistInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-ticker.C:
+					if err := c.datastoreMiddleware.Persist(ctx); err != nil {
+						log.Warn().Err(err).Msg("error persisting datastores")
+					}
+				}
+			}
+		})
+	}
+
 	if err := g.Wait(); err != nil {
 		log.Warn().Err(err).Msg("error shutting down servers")
 	}
 
+	// Persist any changes made since the last interval, now that no more requests are being served.
+	if err := c.datastoreMiddleware.Persist(context.Background()); err != nil {
+		log.Warn().Err(err).Msg("error persisting datastores")
+	}
+
 	return nil
 }
 
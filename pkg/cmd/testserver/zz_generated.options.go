@@ -1,7 +1,10 @@
 // Code generated by github.com/ecordell/optgen. DO NOT EDIT.
 package testserver
 
-import util "github.com/authzed/spicedb/pkg/cmd/util"
+import (
+	util "github.com/authzed/spicedb/pkg/cmd/util"
+	"time"
+)
 
 type ConfigOption func(c *Config)
 
@@ -22,6 +25,8 @@ func (c *Config) ToOption() ConfigOption {
 		to.HTTPGateway = c.HTTPGateway
 		to.ReadOnlyHTTPGateway = c.ReadOnlyHTTPGateway
 		to.LoadConfigs = c.LoadConfigs
+		to.PersistDir = c.PersistDir
+		to.PersistInterval = c.PersistInterval
 		to.MaximumUpdatesPerWrite = c.MaximumUpdatesPerWrite
 		to.MaximumPreconditionCount = c.MaximumPreconditionCount
 	}
@@ -77,6 +82,20 @@ func SetLoadConfigs(loadConfigs []string) ConfigOption {
 	}
 }
 
+// WithPersistDir returns an option that can set PersistDir on a Config
+func WithPersistDir(persistDir string) ConfigOption {
+	return func(c *Config) {
+		c.PersistDir = persistDir
+	}
+}
+
+// WithPersistInterval returns an option that can set PersistInterval on a Config
+func WithPersistInterval(persistInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.PersistInterval = persistInterval
+	}
+}
+
 // WithMaximumUpdatesPerWrite returns an option that can set MaximumUpdatesPerWrite on a Config
 func WithMaximumUpdatesPerWrite(maximumUpdatesPerWrite uint16) ConfigOption {
 	return func(c *Config) {
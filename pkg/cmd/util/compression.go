@@ -0,0 +1,36 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+	grpczstd "github.com/mostynb/go-grpc-compression/zstd"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// ConfigureCompression sets the levels used by the gzip and zstd gRPC compressors. gRPC
+// compresses each response with the compressor its request was sent with, so these apply
+// to any client that compresses its requests; large streamed responses, such as those of
+// ReadRelationships, LookupResources and Watch, benefit the most. A level of zero keeps
+// the default of the compressor.
+//
+// The compressors are registered globally, so this must be called before any server or
+// client using them is started.
+func ConfigureCompression(gzipLevel, zstdLevel int) error {
+	if gzipLevel != 0 {
+		if err := gzip.SetLevel(gzipLevel); err != nil {
+			return fmt.Errorf("invalid gzip compression level %d: %w", gzipLevel, err)
+		}
+	}
+
+	if zstdLevel != 0 {
+		if zstdLevel < 1 || zstdLevel > 22 {
+			return fmt.Errorf("invalid zstd compression level %d: must be between 1 and 22", zstdLevel)
+		}
+		if err := grpczstd.SetLevel(zstd.EncoderLevelFromZstd(zstdLevel)); err != nil {
+			return fmt.Errorf("invalid zstd compression level %d: %w", zstdLevel, err)
+		}
+	}
+
+	return nil
+}
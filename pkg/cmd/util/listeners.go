@@ -0,0 +1,248 @@
+package util
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jzelinskie/stringz"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+// ListenerConfig configures an address on which a server listens besides its own, such
+// as an IPv6 address next to an IPv4 one, with its own TLS configuration.
+type ListenerConfig struct {
+	Address     string
+	Network     string
+	TLSCertPath string
+	TLSKeyPath  string
+
+	// GRPCOpts are added to the options of a gRPC server on the listener. Interceptors
+	// chained by them run before the middleware the server is run with.
+	GRPCOpts []grpc.ServerOption
+
+	// HTTPMiddleware wraps the handler of an HTTP server on the listener.
+	HTTPMiddleware func(http.Handler) http.Handler
+}
+
+// ParseListenerConfig parses a listener from `[network://]address[?tls-cert-path=path&tls-key-path=path]`,
+// where the network defaults to the given one.
+func ParseListenerConfig(spec, defaultNetwork string) (ListenerConfig, error) {
+	raw := spec
+	if !strings.Contains(raw, "://") {
+		raw = defaultNetwork + "://" + raw
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ListenerConfig{}, fmt.Errorf("invalid listener `%s`: %w", spec, err)
+	}
+
+	config := ListenerConfig{
+		Address: parsed.Host,
+		Network: parsed.Scheme,
+	}
+	switch config.Network {
+	case "tcp", "tcp4", "tcp6":
+	case "unix", "unixpacket":
+		config.Address += parsed.Path
+	default:
+		return ListenerConfig{}, fmt.Errorf("invalid listener `%s`: unsupported network `%s`", spec, config.Network)
+	}
+	if config.Address == "" {
+		return ListenerConfig{}, fmt.Errorf("invalid listener `%s`: missing address", spec)
+	}
+
+	for key, values := range parsed.Query() {
+		switch key {
+		case "tls-cert-path":
+			config.TLSCertPath = values[0]
+		case "tls-key-path":
+			config.TLSKeyPath = values[0]
+		default:
+			return ListenerConfig{}, fmt.Errorf("invalid listener `%s`: unknown option `%s`", spec, key)
+		}
+	}
+	if (config.TLSCertPath == "") != (config.TLSKeyPath == "") {
+		return ListenerConfig{}, fmt.Errorf("invalid listener `%s`: must provide both tls-cert-path and tls-key-path", spec)
+	}
+	return config, nil
+}
+
+func parseListenerSpecs(specs []string, defaultNetwork string) ([]ListenerConfig, error) {
+	listeners := make([]ListenerConfig, 0, len(specs))
+	for _, spec := range specs {
+		listener, err := ParseListenerConfig(spec, defaultNetwork)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// listen listens on the address of the listener, returning the watcher of its
+// certificate when it is configured with TLS.
+func (lc ListenerConfig) listen(defaultNetwork string) (net.Listener, certificateWatchers, error) {
+	network := stringz.DefaultEmpty(lc.Network, stringz.DefaultEmpty(defaultNetwork, "tcp"))
+
+	l, err := net.Listen(network, lc.Address)
+	if err != nil {
+		return nil, nil, err
+	}
+	if lc.TLSCertPath == "" && lc.TLSKeyPath == "" {
+		return l, nil, nil
+	}
+
+	watcher, err := newCertificateWatchers(lc.TLSCertPath, lc.TLSKeyPath, nil, nil)
+	if err != nil {
+		_ = l.Close()
+		return nil, nil, err
+	}
+	return l, watcher, nil
+}
+
+// grpcListenerServer is a gRPC server serving on one of the additional listeners of a
+// GRPCServerConfig.
+type grpcListenerServer struct {
+	config      ListenerConfig
+	opts        []grpc.ServerOption
+	serve       servingFunc
+	certWatcher certificateWatchers
+	listenFunc  func() error
+	stopFunc    func()
+	forceStop   func()
+}
+
+func (c *GRPCServerConfig) completeListeners(level zerolog.Level, svcRegistrationFn func(server *grpc.Server), opts []grpc.ServerOption) ([]*grpcListenerServer, error) {
+	configs, err := parseListenerSpecs(c.listenerSpecs, c.Network)
+	if err != nil {
+		return nil, err
+	}
+	configs = append(append([]ListenerConfig{}, c.AdditionalListeners...), configs...)
+	if len(configs) > 0 && c.Network == BufferedNetwork {
+		return nil, fmt.Errorf("additional listeners are not supported on the %s network", BufferedNetwork)
+	}
+
+	servers := make([]*grpcListenerServer, 0, len(configs))
+	for _, config := range configs {
+		l, watcher, err := config.listen(c.Network)
+		if err != nil {
+			for _, server := range servers {
+				server.forceStop()
+			}
+			return nil, fmt.Errorf("failed to listen on addr %s for gRPC server: %w", config.Address, err)
+		}
+		config.Address = l.Addr().String()
+
+		serverOpts := append([]grpc.ServerOption{}, opts...)
+		if watcher != nil {
+			serverOpts = append(serverOpts, tlsServerOpts(watcher)...)
+		}
+		serverOpts = append(serverOpts, config.GRPCOpts...)
+
+		log.WithLevel(level).
+			Str("addr", config.Address).
+			Str("network", l.Addr().Network()).
+			Str("service", c.flagPrefix).
+			Bool("insecure", watcher == nil).
+			Msg("grpc server started serving on additional listener")
+
+		server := &grpcListenerServer{
+			config:      config,
+			opts:        serverOpts,
+			serve:       c.serving(l, watcher),
+			certWatcher: watcher,
+		}
+		server.register(svcRegistrationFn)
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
+
+func (ls *grpcListenerServer) register(svcRegistrationFn func(server *grpc.Server)) {
+	srv := grpc.NewServer(ls.opts...)
+	svcRegistrationFn(srv)
+	ls.listenFunc, ls.stopFunc, ls.forceStop = ls.serve(srv)
+}
+
+// httpListenerServer is an HTTP server serving on one of the additional listeners of an
+// HTTPServerConfig.
+type httpListenerServer struct {
+	config      ListenerConfig
+	srv         *http.Server
+	listener    net.Listener
+	certWatcher certificateWatchers
+}
+
+func (c *HTTPServerConfig) completeListeners(handler http.Handler) ([]*httpListenerServer, error) {
+	configs, err := parseListenerSpecs(c.listenerSpecs, "tcp")
+	if err != nil {
+		return nil, err
+	}
+	configs = append(append([]ListenerConfig{}, c.AdditionalListeners...), configs...)
+
+	servers := make([]*httpListenerServer, 0, len(configs))
+	for _, config := range configs {
+		l, watcher, err := config.listen("tcp")
+		if err != nil {
+			for _, server := range servers {
+				_ = server.listener.Close()
+			}
+			return nil, fmt.Errorf("failed to listen on addr %s for http server: %w", config.Address, err)
+		}
+		config.Address = l.Addr().String()
+		if watcher != nil {
+			l = tls.NewListener(l, &tls.Config{
+				GetCertificate: watcher.GetCertificate,
+				MinVersion:     tls.VersionTLS12,
+			})
+		}
+
+		listenerHandler := handler
+		if config.HTTPMiddleware != nil {
+			listenerHandler = config.HTTPMiddleware(handler)
+		}
+		servers = append(servers, &httpListenerServer{
+			config: config,
+			srv: &http.Server{
+				Handler:           listenerHandler,
+				ReadHeaderTimeout: 5 * time.Second,
+			},
+			listener:    l,
+			certWatcher: watcher,
+		})
+	}
+	return servers, nil
+}
+
+func (ls *httpListenerServer) serve(level zerolog.Level, service string) error {
+	watchCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if ls.certWatcher != nil {
+		go func() {
+			if err := ls.certWatcher.Start(watchCtx); err != nil {
+				log.Error().Err(err).Str("service", service).Msg("error watching tls certs")
+			}
+		}()
+	}
+
+	log.WithLevel(level).
+		Str("addr", ls.listener.Addr().String()).
+		Str("service", service).
+		Bool("insecure", ls.certWatcher == nil).
+		Msg("http server started serving on additional listener")
+	if err := ls.srv.Serve(ls.listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
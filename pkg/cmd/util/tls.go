@@ -0,0 +1,83 @@
+package util
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+)
+
+// TLSCertificateConfig is a certificate served besides the one of a server, to clients
+// requesting a server name it is valid for.
+type TLSCertificateConfig struct {
+	CertPath string
+	KeyPath  string
+}
+
+// ParseTLSCertificateConfig parses a certificate from `cert-path,key-path`.
+func ParseTLSCertificateConfig(spec string) (TLSCertificateConfig, error) {
+	certPath, keyPath, ok := strings.Cut(spec, ",")
+	if !ok || certPath == "" || keyPath == "" {
+		return TLSCertificateConfig{}, fmt.Errorf("invalid TLS certificate `%s`: must be `cert-path,key-path`", spec)
+	}
+	return TLSCertificateConfig{CertPath: certPath, KeyPath: keyPath}, nil
+}
+
+// certificateWatchers watch the certificates served on a listener, which are reloaded
+// when they change on disk.
+type certificateWatchers []*certwatcher.CertWatcher
+
+// newCertificateWatchers watches the certificate of a server followed by its additional
+// certificates.
+func newCertificateWatchers(certPath, keyPath string, additional []TLSCertificateConfig, additionalSpecs []string) (certificateWatchers, error) {
+	for _, spec := range additionalSpecs {
+		config, err := ParseTLSCertificateConfig(spec)
+		if err != nil {
+			return nil, err
+		}
+		additional = append(additional, config)
+	}
+
+	watchers := make(certificateWatchers, 0, 1+len(additional))
+	for _, config := range append([]TLSCertificateConfig{{CertPath: certPath, KeyPath: keyPath}}, additional...) {
+		watcher, err := certwatcher.New(config.CertPath, config.KeyPath)
+		if err != nil {
+			return nil, err
+		}
+		watchers = append(watchers, watcher)
+	}
+	return watchers, nil
+}
+
+// GetCertificate returns the first certificate supported by the client, such as by being
+// valid for the server name it indicated with SNI, or the first certificate if none is.
+func (cw certificateWatchers) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if len(cw) == 1 {
+		return cw[0].GetCertificate(hello)
+	}
+
+	for _, watcher := range cw {
+		cert, err := watcher.GetCertificate(hello)
+		if err != nil {
+			return nil, err
+		}
+		if hello.SupportsCertificate(cert) == nil {
+			return cert, nil
+		}
+	}
+	return cw[0].GetCertificate(hello)
+}
+
+// Start watches the certificates until the context is canceled.
+func (cw certificateWatchers) Start(ctx context.Context) error {
+	g, ctx := errgroup.WithContext(ctx)
+	for _, watcher := range cw {
+		g.Go(func(watcher *certwatcher.CertWatcher) func() error {
+			return func() error { return watcher.Start(ctx) }
+		}(watcher))
+	}
+	return g.Wait()
+}
@@ -0,0 +1,105 @@
+package util
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// writeSelfSignedCert writes a self-signed certificate valid for the DNS name, returning
+// the paths of the certificate and its key.
+func writeSelfSignedCert(t *testing.T, dnsName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0o600))
+	return certPath, keyPath
+}
+
+func TestParseTLSCertificateConfig(t *testing.T) {
+	config, err := ParseTLSCertificateConfig("/certs/tls.crt,/certs/tls.key")
+	require.NoError(t, err)
+	require.Equal(t, TLSCertificateConfig{CertPath: "/certs/tls.crt", KeyPath: "/certs/tls.key"}, config)
+
+	for _, spec := range []string{"/certs/tls.crt", ",/certs/tls.key", "/certs/tls.crt,"} {
+		_, err := ParseTLSCertificateConfig(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestGRPCServerSNI(t *testing.T) {
+	defaultCert, defaultKey := writeSelfSignedCert(t, "api.example.com")
+	otherCert, otherKey := writeSelfSignedCert(t, "authz.example.org")
+
+	config := &GRPCServerConfig{
+		Enabled:     true,
+		Network:     BufferedNetwork,
+		TLSCertPath: defaultCert,
+		TLSKeyPath:  defaultKey,
+	}
+	config.tlsCertificateSpecs = []string{otherCert + "," + otherKey}
+
+	s, err := config.Complete(zerolog.InfoLevel, func(server *grpc.Server) {
+		healthpb.RegisterHealthServer(server, health.NewServer())
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = s.Listen(ctx)()
+	}()
+	defer s.GracefulStop()
+
+	for _, tc := range []struct {
+		serverName string
+		expected   string
+	}{
+		{"api.example.com", "api.example.com"},
+		{"authz.example.org", "authz.example.org"},
+		{"unknown.example.net", "api.example.com"},
+		{"", "api.example.com"},
+	} {
+		conn, err := s.NetDialContext(ctx, "")
+		require.NoError(t, err)
+
+		// The certificates are self-signed, and only the one served is checked.
+		client := tls.Client(conn, &tls.Config{ServerName: tc.serverName, InsecureSkipVerify: true, NextProtos: []string{"h2"}}) //nolint:gosec
+		require.NoError(t, client.HandshakeContext(ctx), tc.serverName)
+		require.Equal(t, []string{tc.expected}, client.ConnectionState().PeerCertificates[0].DNSNames, tc.serverName)
+		require.NoError(t, client.Close())
+	}
+}
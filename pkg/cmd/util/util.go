@@ -8,11 +8,17 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
 	"github.com/jzelinskie/stringz"
 	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
@@ -21,8 +27,10 @@ import (
 
 	// Register Snappy S2 compression
 	_ "github.com/mostynb/go-grpc-compression/experimental/s2"
+	// Register zstd and gzip compression
+	_ "github.com/mostynb/go-grpc-compression/zstd"
+	_ "google.golang.org/grpc/encoding/gzip"
 
-	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
 	// Register cert watcher metrics
 	_ "sigs.k8s.io/controller-runtime/pkg/certwatcher/metrics"
 
@@ -33,17 +41,57 @@ import (
 const BufferedNetwork string = "buffnet"
 
 type GRPCServerConfig struct {
-	Address      string
-	Network      string
-	TLSCertPath  string
-	TLSKeyPath   string
-	MaxConnAge   time.Duration
-	Enabled      bool
-	BufferSize   int
-	ClientCAPath string
-	MaxWorkers   uint32
-
-	flagPrefix string
+	Address         string
+	Network         string
+	TLSCertPath     string
+	TLSKeyPath      string
+	MaxConnAge      time.Duration
+	MaxConnAgeGrace time.Duration
+	Enabled         bool
+	BufferSize      int
+	ClientCAPath    string
+	MaxWorkers      uint32
+	MaxRecvMsgSize  int
+	MaxSendMsgSize  int
+
+	// MaxConnIdle, KeepaliveTime and KeepaliveTimeout configure when the server closes
+	// idle connections and pings clients to keep connections alive, such as through load
+	// balancers dropping connections idle for too long. Zero values keep the gRPC defaults.
+	MaxConnIdle      time.Duration
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+
+	// KeepaliveMinTime and KeepalivePermitWithoutStream configure how often clients may
+	// ping the server before it closes their connections.
+	KeepaliveMinTime             time.Duration
+	KeepalivePermitWithoutStream bool
+
+	// InitialWindowSize and InitialConnWindowSize are the HTTP/2 flow control windows of
+	// streams and connections; values below 64KiB keep the gRPC defaults.
+	InitialWindowSize     int32
+	InitialConnWindowSize int32
+
+	// WebEnabled serves gRPC-Web on the listener of the server, besides gRPC, so that
+	// browsers can call the server without a proxy. Both are then served through net/http,
+	// which enforces MaxConnIdle and the window sizes but not MaxConnAge, and cannot be
+	// configured with MaxWorkers or the keepalive settings, so those are rejected.
+	WebEnabled bool
+
+	// WebAllowedOrigins are the origins from which browsers may call the server with
+	// gRPC-Web; "*" allows every origin.
+	WebAllowedOrigins []string
+
+	// AdditionalTLSCertificates are served besides the TLS certificate of the server, to
+	// clients indicating a server name they are valid for with SNI.
+	AdditionalTLSCertificates []TLSCertificateConfig
+
+	// AdditionalListeners are served besides the address of the server, with the same
+	// services and options other than TLS.
+	AdditionalListeners []ListenerConfig
+
+	flagPrefix          string
+	listenerSpecs       []string
+	tlsCertificateSpecs []string
 }
 
 // RegisterGRPCServerFlags adds the following flags for use with
@@ -51,7 +99,19 @@ type GRPCServerConfig struct {
 // - "$PREFIX-addr"
 // - "$PREFIX-tls-cert-path"
 // - "$PREFIX-tls-key-path"
+// - "$PREFIX-tls-additional-cert"
 // - "$PREFIX-max-conn-age"
+// - "$PREFIX-max-conn-age-grace"
+// - "$PREFIX-max-recv-msg-size"
+// - "$PREFIX-max-send-msg-size"
+// - "$PREFIX-max-conn-idle"
+// - "$PREFIX-keepalive-time"
+// - "$PREFIX-keepalive-timeout"
+// - "$PREFIX-keepalive-min-time"
+// - "$PREFIX-keepalive-permit-without-stream"
+// - "$PREFIX-initial-window-size"
+// - "$PREFIX-initial-conn-window-size"
+// - "$PREFIX-additional-addr"
 func RegisterGRPCServerFlags(flags *pflag.FlagSet, config *GRPCServerConfig, flagPrefix, serviceName, defaultAddr string, defaultEnabled bool) {
 	flagPrefix = stringz.DefaultEmpty(flagPrefix, "grpc")
 	serviceName = stringz.DefaultEmpty(serviceName, "grpc")
@@ -62,9 +122,21 @@ func RegisterGRPCServerFlags(flags *pflag.FlagSet, config *GRPCServerConfig, fla
 	flags.StringVar(&config.Network, flagPrefix+"-network", "tcp", "network type to serve "+serviceName+` ("tcp", "tcp4", "tcp6", "unix", "unixpacket")`)
 	flags.StringVar(&config.TLSCertPath, flagPrefix+"-tls-cert-path", "", "local path to the TLS certificate used to serve "+serviceName)
 	flags.StringVar(&config.TLSKeyPath, flagPrefix+"-tls-key-path", "", "local path to the TLS key used to serve "+serviceName)
+	flags.StringArrayVar(&config.tlsCertificateSpecs, flagPrefix+"-tls-additional-cert", nil, "additional TLS certificate served to clients of "+serviceName+" requesting a server name it is valid for, as `cert-path,key-path` (may be repeated)")
 	flags.DurationVar(&config.MaxConnAge, flagPrefix+"-max-conn-age", 30*time.Second, "how long a connection serving "+serviceName+" should be able to live")
+	flags.DurationVar(&config.MaxConnAgeGrace, flagPrefix+"-max-conn-age-grace", 0, "how long requests in flight on a connection serving "+serviceName+" may run once it reaches its max age, before the connection is forcibly closed (0 waits indefinitely)")
 	flags.BoolVar(&config.Enabled, flagPrefix+"-enabled", defaultEnabled, "enable "+serviceName+" gRPC server")
 	flags.Uint32Var(&config.MaxWorkers, flagPrefix+"-max-workers", 0, "set the number of workers for this server (0 value means 1 worker per request)")
+	flags.IntVar(&config.MaxRecvMsgSize, flagPrefix+"-max-recv-msg-size", 0, "maximum size in bytes of a message received by "+serviceName+" (0 keeps the gRPC default of 4MiB)")
+	flags.IntVar(&config.MaxSendMsgSize, flagPrefix+"-max-send-msg-size", 0, "maximum size in bytes of a message sent by "+serviceName+" (0 places no limit)")
+	flags.DurationVar(&config.MaxConnIdle, flagPrefix+"-max-conn-idle", 0, "how long a connection serving "+serviceName+" may be idle before it is closed (0 never closes idle connections)")
+	flags.DurationVar(&config.KeepaliveTime, flagPrefix+"-keepalive-time", 0, "how long a connection serving "+serviceName+" may be inactive before the server pings the client (0 keeps the gRPC default of 2h)")
+	flags.DurationVar(&config.KeepaliveTimeout, flagPrefix+"-keepalive-timeout", 0, "how long the server waits for a keepalive ping serving "+serviceName+" to be acknowledged before closing the connection (0 keeps the gRPC default of 20s)")
+	flags.DurationVar(&config.KeepaliveMinTime, flagPrefix+"-keepalive-min-time", 0, "minimum interval at which clients of "+serviceName+" may send keepalive pings before their connection is closed (0 keeps the gRPC default of 5m)")
+	flags.BoolVar(&config.KeepalivePermitWithoutStream, flagPrefix+"-keepalive-permit-without-stream", false, "allow clients of "+serviceName+" to send keepalive pings on connections without active streams")
+	flags.Int32Var(&config.InitialWindowSize, flagPrefix+"-initial-window-size", 0, "initial HTTP/2 flow control window size in bytes of streams serving "+serviceName+" (values below 64KiB keep the gRPC default)")
+	flags.Int32Var(&config.InitialConnWindowSize, flagPrefix+"-initial-conn-window-size", 0, "initial HTTP/2 flow control window size in bytes of connections serving "+serviceName+" (values below 64KiB keep the gRPC default)")
+	flags.StringArrayVar(&config.listenerSpecs, flagPrefix+"-additional-addr", nil, "additional address to listen on to serve "+serviceName+", as `[network://]address[?tls-cert-path=path&tls-key-path=path]` (may be repeated)")
 }
 
 type (
@@ -80,10 +152,42 @@ func (c *GRPCServerConfig) Complete(level zerolog.Level, svcRegistrationFn func(
 	if c.BufferSize == 0 {
 		c.BufferSize = 1024 * 1024
 	}
+	if err := c.validateWeb(); err != nil {
+		return nil, err
+	}
 	opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
-		MaxConnectionAge: c.MaxConnAge,
+		MaxConnectionIdle:     c.MaxConnIdle,
+		MaxConnectionAge:      c.MaxConnAge,
+		MaxConnectionAgeGrace: c.MaxConnAgeGrace,
+		Time:                  c.KeepaliveTime,
+		Timeout:               c.KeepaliveTimeout,
 	}), grpc.NumStreamWorkers(c.MaxWorkers))
 
+	if c.KeepaliveMinTime > 0 || c.KeepalivePermitWithoutStream {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             c.KeepaliveMinTime,
+			PermitWithoutStream: c.KeepalivePermitWithoutStream,
+		}))
+	}
+	if c.InitialWindowSize > 0 {
+		opts = append(opts, grpc.InitialWindowSize(c.InitialWindowSize))
+	}
+	if c.InitialConnWindowSize > 0 {
+		opts = append(opts, grpc.InitialConnWindowSize(c.InitialConnWindowSize))
+	}
+
+	if c.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(c.MaxRecvMsgSize))
+	}
+	if c.MaxSendMsgSize > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(c.MaxSendMsgSize))
+	}
+
+	listeners, err := c.completeListeners(level, svcRegistrationFn, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	tlsOpts, certWatcher, err := c.tlsOpts()
 	if err != nil {
 		return nil, err
@@ -107,17 +211,17 @@ func (c *GRPCServerConfig) Complete(level zerolog.Level, svcRegistrationFn func(
 		Bool("insecure", c.TLSCertPath == "" && c.TLSKeyPath == "").
 		Msg("grpc server started serving")
 
+	serve := c.serving(l, certWatcher)
 	srv := grpc.NewServer(opts...)
 	svcRegistrationFn(srv)
+	listenFunc, stopFunc, forceStop := serve(srv)
 	return &completedGRPCServer{
 		opts:              opts,
-		listener:          l,
 		svcRegistrationFn: svcRegistrationFn,
-		listenFunc: func() error {
-			return srv.Serve(l)
-		},
-		dial:    dial,
-		netDial: netDial,
+		serve:             serve,
+		listenFunc:        listenFunc,
+		dial:              dial,
+		netDial:           netDial,
 		prestopFunc: func() {
 			log.WithLevel(level).
 				Str("addr", c.Address).
@@ -125,12 +229,106 @@ func (c *GRPCServerConfig) Complete(level zerolog.Level, svcRegistrationFn func(
 				Str("service", c.flagPrefix).
 				Msg("grpc server stopped serving")
 		},
-		stopFunc:    srv.GracefulStop,
+		stopFunc:    stopFunc,
+		forceStop:   forceStop,
 		creds:       clientCreds,
 		certWatcher: certWatcher,
+		listeners:   listeners,
 	}, nil
 }
 
+// servingFunc returns the functions that serve a gRPC server on the listener, and stop
+// serving it.
+type servingFunc func(srv *grpc.Server) (listenFunc func() error, stopFunc, forceStop func())
+
+func (c *GRPCServerConfig) serving(l net.Listener, certWatcher certificateWatchers) servingFunc {
+	if !c.WebEnabled {
+		return func(srv *grpc.Server) (func() error, func(), func()) {
+			return func() error { return srv.Serve(l) }, srv.GracefulStop, srv.Stop
+		}
+	}
+
+	// The gRPC server does not terminate TLS when serving through net/http.
+	if certWatcher != nil {
+		l = tls.NewListener(l, &tls.Config{
+			GetCertificate: certWatcher.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+			NextProtos:     []string{"h2", "http/1.1"},
+		})
+	}
+
+	log.Info().Str("service", c.flagPrefix).Strs("origins", c.WebAllowedOrigins).Msg("serving gRPC-Web")
+	return func(srv *grpc.Server) (func() error, func(), func()) {
+		h2s := &http2.Server{
+			IdleTimeout:                  c.MaxConnIdle,
+			MaxUploadBufferPerStream:     c.InitialWindowSize,
+			MaxUploadBufferPerConnection: c.InitialConnWindowSize,
+		}
+		httpSrv := &http.Server{
+			Handler:           h2c.NewHandler(grpcweb.WrapServer(srv, grpcweb.WithOriginFunc(c.allowedWebOrigin)), h2s),
+			ReadHeaderTimeout: 5 * time.Second,
+			IdleTimeout:       c.MaxConnIdle,
+		}
+
+		// Configuring HTTP/2 only fails for TLS configurations it does not support, and
+		// the TLS configuration is that of the listener.
+		_ = http2.ConfigureServer(httpSrv, h2s)
+
+		listenFunc := func() error {
+			if err := httpSrv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		}
+		stopFunc := func() {
+			_ = httpSrv.Shutdown(context.Background())
+			srv.GracefulStop()
+		}
+		forceStop := func() {
+			_ = httpSrv.Close()
+			srv.Stop()
+		}
+		return listenFunc, stopFunc, forceStop
+	}
+}
+
+// validateWeb rejects the settings which net/http does not support, when serving gRPC-Web.
+func (c *GRPCServerConfig) validateWeb() error {
+	if !c.WebEnabled {
+		return nil
+	}
+
+	var unsupported []string
+	if c.MaxWorkers > 0 {
+		unsupported = append(unsupported, "max-workers")
+	}
+	if c.KeepaliveTime > 0 {
+		unsupported = append(unsupported, "keepalive-time")
+	}
+	if c.KeepaliveTimeout > 0 {
+		unsupported = append(unsupported, "keepalive-timeout")
+	}
+	if c.KeepaliveMinTime > 0 {
+		unsupported = append(unsupported, "keepalive-min-time")
+	}
+	if c.KeepalivePermitWithoutStream {
+		unsupported = append(unsupported, "keepalive-permit-without-stream")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s cannot be configured for %s while serving gRPC-Web, as gRPC is then served through net/http", strings.Join(unsupported, ", "), stringz.DefaultEmpty(c.flagPrefix, "grpc"))
+	}
+	return nil
+}
+
+func (c *GRPCServerConfig) allowedWebOrigin(origin string) bool {
+	for _, allowed := range c.WebAllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *GRPCServerConfig) listenerAndDialer() (net.Listener, DialFunc, NetDialFunc, error) {
 	if c.Network == BufferedNetwork {
 		bl := bufconn.Listen(c.BufferSize)
@@ -153,25 +351,29 @@ func (c *GRPCServerConfig) listenerAndDialer() (net.Listener, DialFunc, NetDialF
 	}, nil, nil
 }
 
-func (c *GRPCServerConfig) tlsOpts() ([]grpc.ServerOption, *certwatcher.CertWatcher, error) {
+func (c *GRPCServerConfig) tlsOpts() ([]grpc.ServerOption, certificateWatchers, error) {
 	switch {
 	case c.TLSCertPath == "" && c.TLSKeyPath == "":
 		return nil, nil, nil
 	case c.TLSCertPath != "" && c.TLSKeyPath != "":
-		watcher, err := certwatcher.New(c.TLSCertPath, c.TLSKeyPath)
+		watchers, err := newCertificateWatchers(c.TLSCertPath, c.TLSKeyPath, c.AdditionalTLSCertificates, c.tlsCertificateSpecs)
 		if err != nil {
 			return nil, nil, err
 		}
-		creds := credentials.NewTLS(&tls.Config{
-			GetCertificate: watcher.GetCertificate,
-			MinVersion:     tls.VersionTLS12,
-		})
-		return []grpc.ServerOption{grpc.Creds(creds)}, watcher, nil
+		return tlsServerOpts(watchers), watchers, nil
 	default:
 		return nil, nil, nil
 	}
 }
 
+func tlsServerOpts(watcher certificateWatchers) []grpc.ServerOption {
+	creds := credentials.NewTLS(&tls.Config{
+		GetCertificate: watcher.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	})
+	return []grpc.ServerOption{grpc.Creds(creds)}
+}
+
 func (c *GRPCServerConfig) clientCreds() (credentials.TransportCredentials, error) {
 	switch {
 	case c.TLSCertPath == "" && c.TLSKeyPath == "":
@@ -201,19 +403,25 @@ type RunnableGRPCServer interface {
 	NetDialContext(ctx context.Context, s string) (net.Conn, error)
 	Insecure() bool
 	GracefulStop()
+
+	// Stop closes every connection and cancels requests in flight, for use when
+	// GracefulStop does not complete in time.
+	Stop()
 }
 
 type completedGRPCServer struct {
 	opts              []grpc.ServerOption
-	listener          net.Listener
 	svcRegistrationFn func(*grpc.Server)
+	serve             servingFunc
 	listenFunc        func() error
 	prestopFunc       func()
 	stopFunc          func()
+	forceStop         func()
 	dial              func(context.Context, ...grpc.DialOption) (*grpc.ClientConn, error)
 	netDial           func(ctx context.Context, s string) (net.Conn, error)
 	creds             credentials.TransportCredentials
-	certWatcher       *certwatcher.CertWatcher
+	certWatcher       certificateWatchers
+	listeners         []*grpcListenerServer
 }
 
 // WithOpts adds to the options for running the server
@@ -221,10 +429,11 @@ func (c *completedGRPCServer) WithOpts(opts ...grpc.ServerOption) RunnableGRPCSe
 	c.opts = append(c.opts, opts...)
 	srv := grpc.NewServer(c.opts...)
 	c.svcRegistrationFn(srv)
-	c.listenFunc = func() error {
-		return srv.Serve(c.listener)
+	c.listenFunc, c.stopFunc, c.forceStop = c.serve(srv)
+	for _, listener := range c.listeners {
+		listener.opts = append(listener.opts, opts...)
+		listener.register(c.svcRegistrationFn)
 	}
-	c.stopFunc = srv.GracefulStop
 	return c
 }
 
@@ -237,7 +446,47 @@ func (c *completedGRPCServer) Listen(ctx context.Context) func() error {
 			}
 		}()
 	}
-	return c.listenFunc
+	if len(c.listeners) == 0 {
+		return c.listenFunc
+	}
+
+	for _, listener := range c.listeners {
+		if listener.certWatcher == nil {
+			continue
+		}
+		watcher, addr := listener.certWatcher, listener.config.Address
+		go func() {
+			if err := watcher.Start(ctx); err != nil {
+				log.Error().Err(err).Str("addr", addr).Msg("error watching tls certs")
+			}
+		}()
+	}
+
+	return func() error {
+		// A listener failing to serve stops the others, so that the server stops as a
+		// whole as it does with a single listener.
+		var g errgroup.Group
+		serveAll := append([]func() error{c.listenFunc}, c.listenerFuncs()...)
+		for _, serve := range serveAll {
+			serve := serve
+			g.Go(func() error {
+				err := serve()
+				if err != nil {
+					c.Stop()
+				}
+				return err
+			})
+		}
+		return g.Wait()
+	}
+}
+
+func (c *completedGRPCServer) listenerFuncs() []func() error {
+	funcs := make([]func() error, 0, len(c.listeners))
+	for _, listener := range c.listeners {
+		funcs = append(funcs, listener.listenFunc)
+	}
+	return funcs
 }
 
 // DialContext starts a connection to grpc server
@@ -259,7 +508,25 @@ func (c *completedGRPCServer) Insecure() bool {
 // GracefulStop stops a running server
 func (c *completedGRPCServer) GracefulStop() {
 	c.prestopFunc()
+
+	var wg sync.WaitGroup
+	for _, listener := range c.listeners {
+		wg.Add(1)
+		go func(stop func()) {
+			defer wg.Done()
+			stop()
+		}(listener.stopFunc)
+	}
 	c.stopFunc()
+	wg.Wait()
+}
+
+// Stop forcibly stops a running server
+func (c *completedGRPCServer) Stop() {
+	c.forceStop()
+	for _, listener := range c.listeners {
+		listener.forceStop()
+	}
 }
 
 type disabledGrpcServer struct{}
@@ -294,13 +561,26 @@ func (d *disabledGrpcServer) NetDialContext(ctx context.Context, s string) (net.
 // GracefulStop stops a running server
 func (d *disabledGrpcServer) GracefulStop() {}
 
+// Stop forcibly stops a running server
+func (d *disabledGrpcServer) Stop() {}
+
 type HTTPServerConfig struct {
 	Address     string
 	TLSCertPath string
 	TLSKeyPath  string
 	Enabled     bool
 
-	flagPrefix string
+	// AdditionalTLSCertificates are served besides the TLS certificate of the server, to
+	// clients indicating a server name they are valid for with SNI.
+	AdditionalTLSCertificates []TLSCertificateConfig
+
+	// AdditionalListeners are served besides the address of the server, with the same
+	// handler.
+	AdditionalListeners []ListenerConfig
+
+	flagPrefix          string
+	listenerSpecs       []string
+	tlsCertificateSpecs []string
 }
 
 func (c *HTTPServerConfig) Complete(level zerolog.Level, handler http.Handler) (RunnableHTTPServer, error) {
@@ -325,7 +605,7 @@ func (c *HTTPServerConfig) Complete(level zerolog.Level, handler http.Handler) (
 		}
 
 	case c.TLSCertPath != "" && c.TLSKeyPath != "":
-		watcher, err := certwatcher.New(c.TLSCertPath, c.TLSKeyPath)
+		watcher, err := newCertificateWatchers(c.TLSCertPath, c.TLSKeyPath, c.AdditionalTLSCertificates, c.tlsCertificateSpecs)
 		if err != nil {
 			return nil, err
 		}
@@ -338,6 +618,16 @@ func (c *HTTPServerConfig) Complete(level zerolog.Level, handler http.Handler) (
 			return nil, err
 		}
 		serveFunc = func() error {
+			// The watcher runs until the server stops serving, so that renewed
+			// certificates are served to new connections without a restart.
+			watchCtx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				if err := watcher.Start(watchCtx); err != nil {
+					log.Error().Err(err).Str("service", c.flagPrefix).Msg("error watching tls certs")
+				}
+			}()
+
 			log.WithLevel(level).
 				Str("addr", srv.Addr).
 				Str("prefix", c.flagPrefix).
@@ -352,20 +642,51 @@ func (c *HTTPServerConfig) Complete(level zerolog.Level, handler http.Handler) (
 		)
 	}
 
+	listeners, err := c.completeListeners(handler)
+	if err != nil {
+		return nil, err
+	}
+
+	closeListeners := func() {
+		for _, listener := range listeners {
+			if err := listener.srv.Close(); err != nil {
+				log.Error().Str("addr", listener.config.Address).Str("service", c.flagPrefix).Err(err).Msg("error stopping http server")
+			}
+		}
+	}
+
 	return &completedHTTPServer{
 		srvFunc: func() error {
-			if err := serveFunc(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-				return fmt.Errorf("failed while serving http: %w", err)
+			var g errgroup.Group
+			g.Go(func() error {
+				if err := serveFunc(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					closeListeners()
+					return fmt.Errorf("failed while serving http: %w", err)
+				}
+				return nil
+			})
+			for _, listener := range listeners {
+				listener := listener
+				g.Go(func() error {
+					if err := listener.serve(level, c.flagPrefix); err != nil {
+						_ = srv.Close()
+						closeListeners()
+						return fmt.Errorf("failed while serving http on %s: %w", listener.config.Address, err)
+					}
+					return nil
+				})
 			}
-			return nil
+			return g.Wait()
 		},
 		closeFunc: func() {
 			if err := srv.Close(); err != nil {
 				log.Error().Str("addr", srv.Addr).Str("service", c.flagPrefix).Err(err).Msg("error stopping http server")
 			}
+			closeListeners()
 			log.WithLevel(level).Str("addr", srv.Addr).Str("service", c.flagPrefix).Msg("http server stopped serving")
 		},
-		enabled: c.Enabled,
+		enabled:   c.Enabled,
+		listeners: listeners,
 	}, nil
 }
 
@@ -378,6 +699,7 @@ type completedHTTPServer struct {
 	srvFunc   func() error
 	closeFunc func()
 	enabled   bool
+	listeners []*httpListenerServer
 }
 
 func (c *completedHTTPServer) ListenAndServe() error {
@@ -396,7 +718,9 @@ func (c *completedHTTPServer) Close() {
 // - "$PREFIX-addr"
 // - "$PREFIX-tls-cert-path"
 // - "$PREFIX-tls-key-path"
+// - "$PREFIX-tls-additional-cert"
 // - "$PREFIX-enabled"
+// - "$PREFIX-additional-addr"
 func RegisterHTTPServerFlags(flags *pflag.FlagSet, config *HTTPServerConfig, flagPrefix, serviceName, defaultAddr string, defaultEnabled bool) {
 	flagPrefix = stringz.DefaultEmpty(flagPrefix, "http")
 	serviceName = stringz.DefaultEmpty(serviceName, "http")
@@ -405,7 +729,9 @@ func RegisterHTTPServerFlags(flags *pflag.FlagSet, config *HTTPServerConfig, fla
 	flags.StringVar(&config.Address, flagPrefix+"-addr", defaultAddr, "address to listen on to serve "+serviceName)
 	flags.StringVar(&config.TLSCertPath, flagPrefix+"-tls-cert-path", "", "local path to the TLS certificate used to serve "+serviceName)
 	flags.StringVar(&config.TLSKeyPath, flagPrefix+"-tls-key-path", "", "local path to the TLS key used to serve "+serviceName)
+	flags.StringArrayVar(&config.tlsCertificateSpecs, flagPrefix+"-tls-additional-cert", nil, "additional TLS certificate served to clients of "+serviceName+" requesting a server name it is valid for, as `cert-path,key-path` (may be repeated)")
 	flags.BoolVar(&config.Enabled, flagPrefix+"-enabled", defaultEnabled, "enable "+serviceName+" http server")
+	flags.StringArrayVar(&config.listenerSpecs, flagPrefix+"-additional-addr", nil, "additional address to listen on to serve "+serviceName+", as `[network://]address[?tls-cert-path=path&tls-key-path=path]` (may be repeated)")
 }
 
 type disabledHTTPServer struct{}
@@ -1,11 +1,28 @@
 package util
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestDisabledGRPC(t *testing.T) {
@@ -22,3 +39,292 @@ func TestDisabledHTTP(t *testing.T) {
 	require.NoError(t, s.ListenAndServe())
 	s.Close()
 }
+
+func TestGRPCMessageSizeAndCompression(t *testing.T) {
+	s, err := (&GRPCServerConfig{
+		Enabled:        true,
+		Network:        BufferedNetwork,
+		MaxRecvMsgSize: 1024,
+	}).Complete(zerolog.InfoLevel, func(server *grpc.Server) {
+		healthpb.RegisterHealthServer(server, health.NewServer())
+	})
+	require.NoError(t, err)
+
+	go func() {
+		_ = s.Listen(context.Background())()
+	}()
+	defer s.GracefulStop()
+
+	conn, err := s.DialContext(context.Background(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	for _, compressor := range []string{"gzip", "zstd"} {
+		_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.UseCompressor(compressor))
+		require.NoError(t, err, compressor)
+	}
+
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: strings.Repeat("a", 2048)})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestGRPCKeepaliveAndFlowControl(t *testing.T) {
+	for _, webEnabled := range []bool{false, true} {
+		webEnabled := webEnabled
+		t.Run(fmt.Sprintf("web=%t", webEnabled), func(t *testing.T) {
+			config := &GRPCServerConfig{
+				Enabled:               true,
+				Network:               BufferedNetwork,
+				MaxConnIdle:           200 * time.Millisecond,
+				InitialWindowSize:     1 << 20,
+				InitialConnWindowSize: 1 << 21,
+				WebEnabled:            webEnabled,
+			}
+			if !webEnabled {
+				config.KeepaliveTime = 30 * time.Second
+				config.KeepaliveTimeout = 5 * time.Second
+				config.KeepaliveMinTime = 10 * time.Second
+				config.KeepalivePermitWithoutStream = true
+			}
+
+			s, err := config.Complete(zerolog.InfoLevel, func(server *grpc.Server) {
+				healthpb.RegisterHealthServer(server, health.NewServer())
+			})
+			require.NoError(t, err)
+
+			go func() {
+				_ = s.Listen(context.Background())()
+			}()
+			defer s.GracefulStop()
+
+			conn, err := s.DialContext(context.Background(),
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+				grpc.WithKeepaliveParams(keepalive.ClientParameters{Time: 10 * time.Second, PermitWithoutStream: true}),
+			)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+			require.NoError(t, err)
+			require.Equal(t, connectivity.Ready, conn.GetState())
+
+			// The connection is closed by the server once idle for MaxConnIdle.
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			require.True(t, conn.WaitForStateChange(ctx, connectivity.Ready), "idle connection was not closed")
+		})
+	}
+}
+
+func TestGRPCWebRejectsUnsupportedSettings(t *testing.T) {
+	for _, config := range []*GRPCServerConfig{
+		{MaxWorkers: 4},
+		{KeepaliveTime: time.Minute},
+		{KeepaliveTimeout: time.Minute},
+		{KeepaliveMinTime: time.Minute},
+		{KeepalivePermitWithoutStream: true},
+	} {
+		config.Enabled = true
+		config.Network = BufferedNetwork
+		config.WebEnabled = true
+		_, err := config.Complete(zerolog.InfoLevel, func(*grpc.Server) {})
+		require.ErrorContains(t, err, "while serving gRPC-Web")
+	}
+}
+
+func TestConfigureCompression(t *testing.T) {
+	require.NoError(t, ConfigureCompression(0, 0))
+	require.NoError(t, ConfigureCompression(6, 3))
+	require.Error(t, ConfigureCompression(10, 0))
+	require.Error(t, ConfigureCompression(0, 23))
+	require.NoError(t, ConfigureCompression(-1, 3))
+}
+
+func TestGRPCWeb(t *testing.T) {
+	s, err := (&GRPCServerConfig{
+		Enabled:           true,
+		Network:           BufferedNetwork,
+		WebEnabled:        true,
+		WebAllowedOrigins: []string{"https://admin.example.com"},
+	}).Complete(zerolog.InfoLevel, func(server *grpc.Server) {
+		healthpb.RegisterHealthServer(server, health.NewServer())
+	})
+	require.NoError(t, err)
+
+	go func() {
+		_ = s.Listen(context.Background())()
+	}()
+	defer s.GracefulStop()
+
+	// gRPC is still served on the same listener.
+	conn, err := s.DialContext(context.Background(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return s.NetDialContext(ctx, addr)
+		},
+	}}
+
+	for _, tc := range []struct {
+		origin        string
+		expectAllowed bool
+	}{
+		{"https://admin.example.com", true},
+		{"https://evil.example.com", false},
+	} {
+		req, err := http.NewRequest(http.MethodOptions, "http://buffnet/grpc.health.v1.Health/Check", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", tc.origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, tc.expectAllowed, resp.Header.Get("Access-Control-Allow-Origin") == tc.origin, tc.origin)
+	}
+
+	// A gRPC-Web request is a length-prefixed message, answered by the response message
+	// followed by a trailers frame.
+	message, err := proto.Marshal(&healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	body := append([]byte{0, 0, 0, 0, byte(len(message))}, message...)
+
+	req, err := http.NewRequest(http.MethodPost, "http://buffnet/grpc.health.v1.Health/Check", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("X-Grpc-Web", "1")
+	req.Header.Set("Origin", "https://admin.example.com")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/grpc-web+proto", resp.Header.Get("Content-Type"))
+
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(respBody), 5)
+	length := binary.BigEndian.Uint32(respBody[1:5])
+	var checkResp healthpb.HealthCheckResponse
+	require.NoError(t, proto.Unmarshal(respBody[5:5+length], &checkResp))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, checkResp.Status)
+	require.Contains(t, string(respBody[5+length:]), "grpc-status: 0")
+}
+
+func TestParseListenerConfig(t *testing.T) {
+	for _, tc := range []struct {
+		spec     string
+		expected ListenerConfig
+	}{
+		{":50051", ListenerConfig{Address: ":50051", Network: "tcp"}},
+		{"tcp6://[::1]:50051", ListenerConfig{Address: "[::1]:50051", Network: "tcp6"}},
+		{"unix:///tmp/spicedb.sock", ListenerConfig{Address: "/tmp/spicedb.sock", Network: "unix"}},
+		{
+			"127.0.0.1:50051?tls-cert-path=/certs/tls.crt&tls-key-path=/certs/tls.key",
+			ListenerConfig{Address: "127.0.0.1:50051", Network: "tcp", TLSCertPath: "/certs/tls.crt", TLSKeyPath: "/certs/tls.key"},
+		},
+	} {
+		config, err := ParseListenerConfig(tc.spec, "tcp")
+		require.NoError(t, err, tc.spec)
+		require.Equal(t, tc.expected, config, tc.spec)
+	}
+
+	for _, spec := range []string{
+		"udp://:50051",
+		"tcp://",
+		":50051?tls-cert-path=/certs/tls.crt",
+		":50051?insecure=true",
+	} {
+		_, err := ParseListenerConfig(spec, "tcp")
+		require.Error(t, err, spec)
+	}
+}
+
+func TestGRPCAdditionalListeners(t *testing.T) {
+	config := &GRPCServerConfig{
+		Enabled: true,
+		Network: "tcp",
+		Address: "127.0.0.1:0",
+		AdditionalListeners: []ListenerConfig{{
+			Address: "127.0.0.1:0",
+			GRPCOpts: []grpc.ServerOption{grpc.ChainUnaryInterceptor(
+				func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+					return nil, status.Error(codes.PermissionDenied, "not on this listener")
+				},
+			)},
+		}},
+	}
+	config.listenerSpecs = []string{"127.0.0.1:0"}
+
+	s, err := config.Complete(zerolog.InfoLevel, func(server *grpc.Server) {
+		healthpb.RegisterHealthServer(server, health.NewServer())
+	})
+	require.NoError(t, err)
+	s = s.WithOpts(grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(ctx, req)
+	}))
+
+	listeners := s.(*completedGRPCServer).listeners
+	require.Len(t, listeners, 2)
+
+	go func() {
+		_ = s.Listen(context.Background())()
+	}()
+	defer s.GracefulStop()
+
+	check := func(addr string) error {
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+		return err
+	}
+
+	require.Equal(t, codes.PermissionDenied, status.Code(check(listeners[0].config.Address)))
+	require.NoError(t, check(listeners[1].config.Address))
+}
+
+func TestHTTPAdditionalListeners(t *testing.T) {
+	config := &HTTPServerConfig{
+		Enabled: true,
+		Address: "127.0.0.1:0",
+		AdditionalListeners: []ListenerConfig{{
+			Address: "127.0.0.1:0",
+			HTTPMiddleware: func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("X-Listener", "additional")
+					next.ServeHTTP(w, r)
+				})
+			},
+		}},
+	}
+
+	s, err := config.Complete(zerolog.InfoLevel, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.ListenAndServe()
+	}()
+
+	addr := "http://" + s.(*completedHTTPServer).listeners[0].config.Address
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(addr)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusNoContent && resp.Header.Get("X-Listener") == "additional"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	s.Close()
+	require.NoError(t, <-served)
+}
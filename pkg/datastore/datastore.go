@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"golang.org/x/exp/slices"
 
 	"github.com/authzed/spicedb/internal/datastore/options"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -54,6 +56,9 @@ type RelationshipsFilter struct {
 	// OptionalResourceIds are the IDs of the resources to find. If nil empty, any resource ID will be allowed.
 	OptionalResourceIds []string
 
+	// OptionalResourceIDPrefix, if not empty, is a prefix of the IDs of the resources to find.
+	OptionalResourceIDPrefix string
+
 	// OptionalResourceRelation is the relation of the resource to find. If empty, any relation is allowed.
 	OptionalResourceRelation string
 
@@ -65,6 +70,31 @@ type RelationshipsFilter struct {
 	OptionalCaveatName string
 }
 
+// Test returns whether the relationship matches the filter.
+func (rf RelationshipsFilter) Test(tpl *core.RelationTuple) bool {
+	if tpl.ResourceAndRelation.Namespace != rf.ResourceType {
+		return false
+	}
+
+	if len(rf.OptionalResourceIds) > 0 && !slices.Contains(rf.OptionalResourceIds, tpl.ResourceAndRelation.ObjectId) {
+		return false
+	}
+
+	if !strings.HasPrefix(tpl.ResourceAndRelation.ObjectId, rf.OptionalResourceIDPrefix) {
+		return false
+	}
+
+	if rf.OptionalResourceRelation != "" && tpl.ResourceAndRelation.Relation != rf.OptionalResourceRelation {
+		return false
+	}
+
+	if rf.OptionalCaveatName != "" && (tpl.Caveat == nil || tpl.Caveat.CaveatName != rf.OptionalCaveatName) {
+		return false
+	}
+
+	return rf.OptionalSubjectsFilter == nil || rf.OptionalSubjectsFilter.Test(tpl)
+}
+
 // RelationshipsFilterFromPublicFilter constructs a datastore RelationshipsFilter from an API-defined RelationshipFilter.
 func RelationshipsFilterFromPublicFilter(filter *v1.RelationshipFilter) RelationshipsFilter {
 	var resourceIds []string
@@ -113,11 +143,38 @@ type SubjectsFilter struct {
 	// OptionalSubjectIds are the IDs of the subjects to find. If nil or empty, any subject ID will be allowed.
 	OptionalSubjectIds []string
 
+	// OptionalSubjectIDPrefix, if not empty, is a prefix of the IDs of the subjects to find.
+	OptionalSubjectIDPrefix string
+
 	// RelationFilter is the filter to use for the relation(s) of the subjects. If neither field
 	// is set, any relation is allowed.
 	RelationFilter SubjectRelationFilter
 }
 
+// Test returns whether the subject of the relationship matches the filter.
+func (sf SubjectsFilter) Test(tpl *core.RelationTuple) bool {
+	if tpl.Subject.Namespace != sf.SubjectType {
+		return false
+	}
+
+	if len(sf.OptionalSubjectIds) > 0 && !slices.Contains(sf.OptionalSubjectIds, tpl.Subject.ObjectId) {
+		return false
+	}
+
+	if !strings.HasPrefix(tpl.Subject.ObjectId, sf.OptionalSubjectIDPrefix) {
+		return false
+	}
+
+	if sf.RelationFilter.IsEmpty() {
+		return true
+	}
+
+	if tpl.Subject.Relation == Ellipsis {
+		return sf.RelationFilter.IncludeEllipsisRelation
+	}
+	return tpl.Subject.Relation == sf.RelationFilter.NonEllipsisRelation
+}
+
 // SubjectRelationFilter is the filter to use for relation(s) of subjects being queried.
 type SubjectRelationFilter struct {
 	// NonEllipsisRelation is the relation of the subject type to find. If empty,
@@ -175,6 +232,11 @@ type Reader interface {
 	LookupNamespaces(ctx context.Context, nsNames []string) ([]*core.NamespaceDefinition, error)
 }
 
+// ReadWriteTransaction reads and writes the datastore in a transaction. The reads of the
+// transaction observe the writes made earlier in it, such as relationships just written
+// or deleted, along with the data committed before the transaction started. Code which
+// must inspect the data it has written before committing, such as write hooks or
+// cardinality checks, can therefore read it back through the transaction itself.
 type ReadWriteTransaction interface {
 	Reader
 	CaveatStorer
@@ -238,10 +300,39 @@ type Datastore interface {
 	// Statistics returns relevant values about the data contained in this cluster.
 	Statistics(ctx context.Context) (Stats, error)
 
+	// UniqueID returns the unique ID of the datastore, which is the same as
+	// the UniqueID of its Stats but is expected to be cheap to call.
+	UniqueID(ctx context.Context) (string, error)
+
 	// Close closes the data store.
 	Close() error
 }
 
+// SchemaVersion is a version of the schema, recorded when it was written.
+type SchemaVersion struct {
+	// Version identifies the version, and increases in the order versions are written.
+	Version uint64
+
+	// Revision is the revision at which the schema was written.
+	Revision Revision
+
+	// SchemaText is the schema as it was written.
+	SchemaText string
+
+	// Author is the author of the schema as given by the writer, if any.
+	Author string
+
+	// CreatedAt is when the version was recorded.
+	CreatedAt time.Time
+}
+
+// NamespaceExperiment is an experimental behavior enabled for the resources of a
+// namespace.
+type NamespaceExperiment struct {
+	Namespace  string
+	Experiment string
+}
+
 // Feature represents a capability that a datastore can support, plus an
 // optional message explaining the feature is available (or not).
 type Feature struct {
@@ -329,6 +420,59 @@ func (nilRevision) MarshalBinary() ([]byte, error) {
 	panic("the nil revision should never be serialized")
 }
 
+// UnwrappableDatastore is implemented by proxies which wrap another datastore, so that
+// optional interfaces implemented by the underlying datastore can be found.
+type UnwrappableDatastore interface {
+	// Unwrap returns the wrapped datastore.
+	Unwrap() Datastore
+}
+
+// UnwrapAs returns the first datastore in the chain of proxies starting with ds, ds
+// included, which implements T, and whether one was found.
+func UnwrapAs[T any](ds Datastore) (T, bool) {
+	for ds != nil {
+		if found, ok := ds.(T); ok {
+			return found, true
+		}
+
+		unwrappable, ok := ds.(UnwrappableDatastore)
+		if !ok {
+			break
+		}
+		ds = unwrappable.Unwrap()
+	}
+
+	var none T
+	return none, false
+}
+
+// UnwrappableReadWriteTransaction is implemented by the transactions of proxies which wrap
+// the transaction of another datastore, so that optional interfaces implemented by the
+// underlying transaction can be found.
+type UnwrappableReadWriteTransaction interface {
+	// Unwrap returns the wrapped transaction.
+	Unwrap() ReadWriteTransaction
+}
+
+// UnwrapTransactionAs returns the first transaction in the chain of proxies starting with
+// rwt, rwt included, which implements T, and whether one was found.
+func UnwrapTransactionAs[T any](rwt ReadWriteTransaction) (T, bool) {
+	for rwt != nil {
+		if found, ok := rwt.(T); ok {
+			return found, true
+		}
+
+		unwrappable, ok := rwt.(UnwrappableReadWriteTransaction)
+		if !ok {
+			break
+		}
+		rwt = unwrappable.Unwrap()
+	}
+
+	var none T
+	return none, false
+}
+
 // NoRevision is a zero type for the revision that will make changing the
 // revision type in the future a bit easier if necessary. Implementations
 // should use any time they want to signal an empty/error revision.
@@ -5,6 +5,8 @@ import (
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 func TestRelationshipsFilterFromPublicFilter(t *testing.T) {
@@ -95,3 +97,70 @@ func TestRelationshipsFilterFromPublicFilter(t *testing.T) {
 		})
 	}
 }
+
+type fakeDatastore struct{ Datastore }
+
+type fakeProxy struct{ Datastore }
+
+func (p fakeProxy) Unwrap() Datastore { return p.Datastore }
+
+type marker interface{ isMarked() }
+
+func (fakeDatastore) isMarked() {}
+
+func TestUnwrapAs(t *testing.T) {
+	underlying := fakeDatastore{}
+
+	found, ok := UnwrapAs[marker](fakeProxy{fakeProxy{underlying}})
+	require.True(t, ok)
+	require.Equal(t, underlying, found)
+
+	_, ok = UnwrapAs[marker](fakeProxy{fakeProxy{}})
+	require.False(t, ok)
+}
+
+func TestRelationshipsFilterTest(t *testing.T) {
+	filter := RelationshipsFilter{
+		ResourceType:             "document",
+		OptionalResourceIDPrefix: "tenant1-",
+		OptionalResourceRelation: "viewer",
+		OptionalSubjectsFilter: &SubjectsFilter{
+			SubjectType:    "user",
+			RelationFilter: SubjectRelationFilter{}.WithEllipsisRelation(),
+		},
+	}
+
+	require.True(t, filter.Test(tuple.MustParse("document:tenant1-first#viewer@user:tom")))
+	require.False(t, filter.Test(tuple.MustParse("document:tenant2-first#viewer@user:tom")))
+	require.False(t, filter.Test(tuple.MustParse("document:tenant1-first#editor@user:tom")))
+	require.False(t, filter.Test(tuple.MustParse("document:tenant1-first#viewer@group:admins")))
+	require.False(t, filter.Test(tuple.MustParse("document:tenant1-first#viewer@user:tom#friend")))
+	require.False(t, filter.Test(tuple.MustParse("folder:tenant1-first#viewer@user:tom")))
+
+	filter = RelationshipsFilter{
+		ResourceType:        "document",
+		OptionalResourceIds: []string{"first", "second"},
+		OptionalCaveatName:  "only_weekdays",
+	}
+	require.True(t, filter.Test(tuple.WithCaveat(tuple.MustParse("document:second#viewer@user:tom"), "only_weekdays")))
+	require.False(t, filter.Test(tuple.MustParse("document:second#viewer@user:tom")))
+	require.False(t, filter.Test(tuple.WithCaveat(tuple.MustParse("document:third#viewer@user:tom"), "only_weekdays")))
+}
+
+func TestSubjectsFilterTest(t *testing.T) {
+	filter := SubjectsFilter{
+		SubjectType:        "group",
+		OptionalSubjectIds: []string{"admins"},
+		RelationFilter:     SubjectRelationFilter{}.WithEllipsisRelation().WithNonEllipsisRelation("member"),
+	}
+
+	require.True(t, filter.Test(tuple.MustParse("document:first#viewer@group:admins")))
+	require.True(t, filter.Test(tuple.MustParse("document:first#viewer@group:admins#member")))
+	require.False(t, filter.Test(tuple.MustParse("document:first#viewer@group:admins#manager")))
+	require.False(t, filter.Test(tuple.MustParse("document:first#viewer@group:editors#member")))
+	require.False(t, filter.Test(tuple.MustParse("document:first#viewer@user:admins")))
+
+	filter = SubjectsFilter{SubjectType: "user", OptionalSubjectIDPrefix: "tenant1-"}
+	require.True(t, filter.Test(tuple.MustParse("document:first#viewer@user:tenant1-tom#friend")))
+	require.False(t, filter.Test(tuple.MustParse("document:first#viewer@user:tenant2-tom")))
+}
@@ -0,0 +1,225 @@
+// Package encryption implements the envelope encryption of the caveat contexts of
+// relationships at rest.
+//
+// Each batch of written contexts is encrypted with a fresh data key, which is stored
+// alongside the contexts after being wrapped by a KeyManager, such as a KMS holding the
+// key encryption keys. Keys are rotated by having the KeyManager wrap new data keys with
+// a new key encryption key, while still unwrapping those of relationships written before.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/secrets"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// KeyManager wraps and unwraps data keys with key encryption keys it holds, such as
+// those of a KMS.
+type KeyManager interface {
+	// WrapKey wraps a data key with the current key encryption key, returning the ID of
+	// that key along with the wrapped data key.
+	WrapKey(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+
+	// UnwrapKey unwraps a data key wrapped with the key encryption key of the given ID,
+	// which need not be the current one.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+const (
+	// envelopeField is the only field of an encrypted caveat context, holding the
+	// envelope of the encrypted context.
+	envelopeField = "__spicedb_encrypted_context"
+
+	envelopeVersion = "1"
+	dataKeyLength   = 32
+
+	// maxCachedDataKeys bounds the number of unwrapped data keys held in memory, to avoid
+	// unwrapping the data key of every context read with the KeyManager.
+	maxCachedDataKeys = 1024
+)
+
+// ErrDecryptionFailed is returned when an encrypted caveat context cannot be decrypted,
+// as its data key cannot be unwrapped or it has been tampered with.
+var ErrDecryptionFailed = errors.New("unable to decrypt caveat context")
+
+// Encryptor encrypts and decrypts the caveat contexts of relationships.
+type Encryptor struct {
+	keys KeyManager
+
+	lock     sync.Mutex
+	dataKeys map[string]cipher.AEAD
+}
+
+// NewEncryptor returns an Encryptor whose data keys are wrapped by the KeyManager.
+func NewEncryptor(keys KeyManager) *Encryptor {
+	return &Encryptor{keys: keys, dataKeys: make(map[string]cipher.AEAD)}
+}
+
+// EncryptRelationships returns the relationships with their caveat contexts encrypted
+// with a single new data key. Relationships without a caveat context are returned as-is,
+// and the relationships given are left unmodified.
+func (e *Encryptor) EncryptRelationships(ctx context.Context, tuples []*core.RelationTuple) ([]*core.RelationTuple, error) {
+	encrypted := make([]*core.RelationTuple, 0, len(tuples))
+	var aead cipher.AEAD
+	var keyID, wrapped string
+	for _, tpl := range tuples {
+		if !hasContext(tpl) {
+			encrypted = append(encrypted, tpl)
+			continue
+		}
+
+		if aead == nil {
+			dataKey, err := secrets.TokenBytes(dataKeyLength)
+			if err != nil {
+				return nil, fmt.Errorf("unable to generate data key: %w", err)
+			}
+			id, wrappedKey, err := e.keys.WrapKey(ctx, dataKey)
+			if err != nil {
+				return nil, fmt.Errorf("unable to wrap data key: %w", err)
+			}
+			aead, err = newAEAD(dataKey)
+			if err != nil {
+				return nil, err
+			}
+			keyID, wrapped = id, base64.StdEncoding.EncodeToString(wrappedKey)
+		}
+
+		plaintext, err := protojson.Marshal(tpl.Caveat.Context)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal caveat context: %w", err)
+		}
+		nonce, err := secrets.TokenBytes(uint8(aead.NonceSize()))
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate nonce: %w", err)
+		}
+		ciphertext := aead.Seal(nil, nonce, plaintext, associatedData(tpl))
+
+		envelope, err := structpb.NewStruct(map[string]any{
+			envelopeField: map[string]any{
+				"version":    envelopeVersion,
+				"key_id":     keyID,
+				"data_key":   wrapped,
+				"nonce":      base64.StdEncoding.EncodeToString(nonce),
+				"ciphertext": base64.StdEncoding.EncodeToString(ciphertext),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		cloned := tpl.CloneVT()
+		cloned.Caveat.Context = envelope
+		encrypted = append(encrypted, cloned)
+	}
+	return encrypted, nil
+}
+
+// DecryptRelationship returns the relationship with its caveat context decrypted, if it
+// is encrypted, leaving the relationship given unmodified. Relationships whose contexts
+// were written before encryption was enabled are returned as-is.
+func (e *Encryptor) DecryptRelationship(ctx context.Context, tpl *core.RelationTuple) (*core.RelationTuple, error) {
+	if !hasContext(tpl) {
+		return tpl, nil
+	}
+	envelopeValue, ok := tpl.Caveat.Context.Fields[envelopeField]
+	if !ok || len(tpl.Caveat.Context.Fields) != 1 {
+		return tpl, nil
+	}
+
+	envelope := envelopeValue.GetStructValue().GetFields()
+	field := func(name string) string {
+		return envelope[name].GetStringValue()
+	}
+	if field("version") != envelopeVersion {
+		return nil, fmt.Errorf("%w: unsupported envelope version `%s`", ErrDecryptionFailed, field("version"))
+	}
+
+	aead, err := e.dataKey(ctx, field("key_id"), field("data_key"))
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(field("nonce"))
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce", ErrDecryptionFailed)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(field("ciphertext"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid ciphertext", ErrDecryptionFailed)
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, associatedData(tpl))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDecryptionFailed, err)
+	}
+
+	decrypted := &structpb.Struct{}
+	if err := protojson.Unmarshal(plaintext, decrypted); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDecryptionFailed, err)
+	}
+
+	cloned := tpl.CloneVT()
+	cloned.Caveat.Context = decrypted
+	return cloned, nil
+}
+
+// dataKey returns the cipher of a wrapped data key, unwrapping it if it is not cached.
+func (e *Encryptor) dataKey(ctx context.Context, keyID, wrapped string) (cipher.AEAD, error) {
+	cacheKey := keyID + "/" + wrapped
+
+	e.lock.Lock()
+	aead, ok := e.dataKeys[cacheKey]
+	e.lock.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid data key", ErrDecryptionFailed)
+	}
+	dataKey, err := e.keys.UnwrapKey(ctx, keyID, wrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to unwrap data key with key `%s`: %s", ErrDecryptionFailed, keyID, err)
+	}
+	aead, err = newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if len(e.dataKeys) >= maxCachedDataKeys {
+		e.dataKeys = make(map[string]cipher.AEAD)
+	}
+	e.dataKeys[cacheKey] = aead
+	return aead, nil
+}
+
+func hasContext(tpl *core.RelationTuple) bool {
+	return tpl.Caveat != nil && tpl.Caveat.Context != nil && len(tpl.Caveat.Context.Fields) > 0
+}
+
+// associatedData binds an encrypted context to its relationship and caveat, so that it
+// cannot be swapped with that of another relationship.
+func associatedData(tpl *core.RelationTuple) []byte {
+	return []byte(tuple.String(tpl) + "[" + tpl.Caveat.CaveatName + "]")
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,131 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func caveated(t *testing.T, tpl string, context map[string]any) *core.RelationTuple {
+	caveatContext, err := structpb.NewStruct(context)
+	require.NoError(t, err)
+
+	parsed := tuple.MustParse(tpl)
+	parsed.Caveat = &core.ContextualizedCaveat{CaveatName: "somecaveat", Context: caveatContext}
+	return parsed
+}
+
+func newTestKeyring(t *testing.T, primary string, ids ...string) *Keyring {
+	keys := make(map[string][]byte, len(ids))
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, 32)
+	}
+	keyring, err := NewKeyring(primary, keys)
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestEncryptRelationships(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	encryptor := NewEncryptor(newTestKeyring(t, "k1", "k1"))
+
+	plain := []*core.RelationTuple{
+		caveated(t, "document:first#viewer@user:tom", map[string]any{"ip": "10.0.0.1"}),
+		tuple.MustParse("document:second#viewer@user:sarah"),
+		caveated(t, "document:third#viewer@user:fred", map[string]any{"allowed": []any{"a", "b"}}),
+	}
+	original := make([]*core.RelationTuple, 0, len(plain))
+	for _, tpl := range plain {
+		original = append(original, tpl.CloneVT())
+	}
+
+	encrypted, err := encryptor.EncryptRelationships(ctx, plain)
+	require.NoError(err)
+	require.Len(encrypted, len(plain))
+	for i, tpl := range plain {
+		require.True(original[i].EqualVT(tpl), "relationships given must be left unmodified")
+	}
+
+	require.Same(plain[1], encrypted[1])
+	for _, i := range []int{0, 2} {
+		require.Equal(tuple.String(plain[i]), tuple.String(encrypted[i]))
+		require.Contains(encrypted[i].Caveat.Context.Fields, envelopeField)
+		require.NotContains(encrypted[i].Caveat.Context.String(), "10.0.0.1")
+
+		decrypted, err := encryptor.DecryptRelationship(ctx, encrypted[i])
+		require.NoError(err)
+		require.True(plain[i].EqualVT(decrypted))
+	}
+
+	// Contexts written before encryption was enabled are read as-is.
+	decrypted, err := encryptor.DecryptRelationship(ctx, plain[0])
+	require.NoError(err)
+	require.Same(plain[0], decrypted)
+
+	// An encrypted context cannot be moved to another relationship.
+	moved := encrypted[0].CloneVT()
+	moved.Subject.ObjectId = "sarah"
+	_, err = encryptor.DecryptRelationship(ctx, moved)
+	require.ErrorIs(err, ErrDecryptionFailed)
+}
+
+func TestKeyRotation(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	tpl := caveated(t, "document:first#viewer@user:tom", map[string]any{"ip": "10.0.0.1"})
+
+	before, err := NewEncryptor(newTestKeyring(t, "k1", "k1")).EncryptRelationships(ctx, []*core.RelationTuple{tpl})
+	require.NoError(err)
+
+	rotated := NewEncryptor(newTestKeyring(t, "k2", "k1", "k2"))
+	after, err := rotated.EncryptRelationships(ctx, []*core.RelationTuple{tpl})
+	require.NoError(err)
+	require.Equal("k2", after[0].Caveat.Context.Fields[envelopeField].GetStructValue().Fields["key_id"].GetStringValue())
+
+	for _, encrypted := range []*core.RelationTuple{before[0], after[0]} {
+		decrypted, err := rotated.DecryptRelationship(ctx, encrypted)
+		require.NoError(err)
+		require.True(tpl.EqualVT(decrypted))
+	}
+
+	// Once the previous key is removed, the contexts written with it cannot be read.
+	_, err = NewEncryptor(newTestKeyring(t, "k2", "k2")).DecryptRelationship(ctx, before[0])
+	require.ErrorIs(err, ErrDecryptionFailed)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+
+	require.NoError(os.WriteFile(path, []byte(`{"primary": "k1", "keys": {"k1": "`+key+`"}}`), 0o600))
+	keyring, err := LoadKeyring(path)
+	require.NoError(err)
+
+	keyID, wrapped, err := keyring.WrapKey(context.Background(), []byte("datakey"))
+	require.NoError(err)
+	require.Equal("k1", keyID)
+	unwrapped, err := keyring.UnwrapKey(context.Background(), keyID, wrapped)
+	require.NoError(err)
+	require.Equal([]byte("datakey"), unwrapped)
+
+	for _, contents := range []string{
+		`{"primary": "k2", "keys": {"k1": "` + key + `"}}`,
+		`{"primary": "k1", "keys": {"k1": "c2hvcnQ="}}`,
+		`{"primary": "k1", "keys": {"k1": "not base64"}}`,
+	} {
+		require.NoError(os.WriteFile(path, []byte(contents), 0o600))
+		_, err := LoadKeyring(path)
+		require.Error(err, contents)
+	}
+}
@@ -0,0 +1,93 @@
+package encryption
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/authzed/spicedb/pkg/secrets"
+)
+
+// Keyring is a KeyManager holding AES-256 key encryption keys locally, for deployments
+// without a KMS. Keys are rotated by adding a new key and making it the primary one,
+// keeping the previous keys to unwrap the data keys of relationships written before.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring returns a Keyring of 32 byte keys by ID, wrapping data keys with the primary
+// one.
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key `%s` not found in keyring", primary)
+	}
+
+	keyring := &Keyring{primary: primary, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if len(key) != dataKeyLength {
+			return nil, fmt.Errorf("key `%s` must be %d bytes, got %d", id, dataKeyLength, len(key))
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("key `%s`: %w", id, err)
+		}
+		keyring.keys[id] = aead
+	}
+	return keyring, nil
+}
+
+type keyringFile struct {
+	Primary string            `json:"primary"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// LoadKeyring loads a Keyring from a JSON file of the form
+// `{"primary": "id", "keys": {"id": "base64-encoded key"}}`.
+func LoadKeyring(path string) (*Keyring, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read keyring: %w", err)
+	}
+
+	var file keyringFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, fmt.Errorf("unable to parse keyring: %w", err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key `%s` is not valid base64: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return NewKeyring(file.Primary, keys)
+}
+
+func (k *Keyring) WrapKey(_ context.Context, dataKey []byte) (string, []byte, error) {
+	aead := k.keys[k.primary]
+	nonce, err := secrets.TokenBytes(uint8(aead.NonceSize()))
+	if err != nil {
+		return "", nil, err
+	}
+	return k.primary, aead.Seal(nonce, nonce, dataKey, []byte(k.primary)), nil
+}
+
+func (k *Keyring) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key `%s` not found in keyring", keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	nonce, ciphertext := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(keyID))
+}
+
+var _ KeyManager = &Keyring{}
@@ -2,6 +2,7 @@ package datastore
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/rs/zerolog"
 )
@@ -131,6 +132,92 @@ func NewInvalidRevisionErr(revision Revision, reason InvalidRevisionReason) erro
 	}
 }
 
+// ErrPinnedRevisionNotFound occurs when a pinned revision was not found by its name.
+type ErrPinnedRevisionNotFound struct {
+	error
+	name string
+}
+
+// PinName returns the name of the pin that couldn't be found.
+func (err ErrPinnedRevisionNotFound) PinName() string {
+	return err.name
+}
+
+// DetailsMetadata returns the metadata for details for this error.
+func (err ErrPinnedRevisionNotFound) DetailsMetadata() map[string]string {
+	return map[string]string{
+		"pin_name": err.name,
+	}
+}
+
+// NewPinnedRevisionNotFoundErr constructs a new pinned revision not found error.
+func NewPinnedRevisionNotFoundErr(name string) error {
+	return ErrPinnedRevisionNotFound{
+		error: fmt.Errorf("pinned revision `%s` not found", name),
+		name:  name,
+	}
+}
+
+// ErrRevisionPinningUnsupported is returned when pinning revisions in a datastore which
+// cannot retain them past its garbage collection window.
+type ErrRevisionPinningUnsupported struct{ error }
+
+// NewRevisionPinningUnsupportedErr constructs a new revision pinning unsupported error.
+func NewRevisionPinningUnsupportedErr(engine string) error {
+	return ErrRevisionPinningUnsupported{
+		error: fmt.Errorf("the %s datastore does not support pinning revisions", engine),
+	}
+}
+
+// ErrSchemaVersionNotFound occurs when a version of the schema was not found.
+type ErrSchemaVersionNotFound struct {
+	error
+	version uint64
+}
+
+// Version returns the version of the schema that couldn't be found.
+func (err ErrSchemaVersionNotFound) Version() uint64 {
+	return err.version
+}
+
+// DetailsMetadata returns the metadata for details for this error.
+func (err ErrSchemaVersionNotFound) DetailsMetadata() map[string]string {
+	return map[string]string{
+		"schema_version": strconv.FormatUint(err.version, 10),
+	}
+}
+
+// NewSchemaVersionNotFoundErr constructs a new schema version not found error.
+func NewSchemaVersionNotFoundErr(version uint64) error {
+	return ErrSchemaVersionNotFound{
+		error:   fmt.Errorf("schema version `%d` not found", version),
+		version: version,
+	}
+}
+
+// ErrSchemaHistoryUnsupported is returned when recording or reading the versions of the
+// schema in a datastore which does not keep its history.
+type ErrSchemaHistoryUnsupported struct{ error }
+
+// NewSchemaHistoryUnsupportedErr constructs a new schema history unsupported error.
+func NewSchemaHistoryUnsupportedErr(engine string) error {
+	return ErrSchemaHistoryUnsupported{
+		error: fmt.Errorf("the %s datastore does not support schema history", engine),
+	}
+}
+
+// ErrNamespaceExperimentsUnsupported is returned when storing or listing the experiments
+// of namespaces in a datastore which cannot store them.
+type ErrNamespaceExperimentsUnsupported struct{ error }
+
+// NewNamespaceExperimentsUnsupportedErr constructs a new namespace experiments
+// unsupported error.
+func NewNamespaceExperimentsUnsupportedErr(engine string) error {
+	return ErrNamespaceExperimentsUnsupported{
+		error: fmt.Errorf("the %s datastore does not support namespace experiments", engine),
+	}
+}
+
 // ErrCaveatNameNotFound is the error returned when a caveat is not found by its name
 type ErrCaveatNameNotFound struct {
 	error
@@ -0,0 +1,174 @@
+// Package pseudonym implements the pseudonymization of object IDs which must not be
+// stored in cleartext, such as email addresses used as the IDs of users.
+//
+// The IDs of objects of the configured types are replaced by the HMAC of their type and
+// ID, so that relationships can still be found by the IDs requested. As the HMAC cannot
+// be reversed, the original IDs are also stored sealed by their pseudonym, and only
+// unsealed when relationships are read back.
+package pseudonym
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/secrets"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const (
+	// Prefix starts every pseudonym, distinguishing pseudonyms from the IDs of objects
+	// written before pseudonymization was enabled.
+	Prefix = "_ps_"
+
+	// MinKeyLength is the minimum length in bytes of the key pseudonyms are derived from.
+	MinKeyLength = 32
+)
+
+// ErrUnsealFailed is returned when a sealed object ID cannot be unsealed, as it was sealed
+// with another key or has been tampered with.
+var ErrUnsealFailed = errors.New("unable to unseal pseudonymized object ID")
+
+// Pseudonymizer replaces the IDs of objects of the configured types with pseudonyms.
+type Pseudonymizer struct {
+	objectTypes map[string]struct{}
+	macKey      []byte
+	aead        cipher.AEAD
+}
+
+// NewPseudonymizer returns a Pseudonymizer of the IDs of objects of the given types. The
+// key must remain the same for as long as the relationships pseudonymized with it are
+// stored.
+func NewPseudonymizer(key []byte, objectTypes []string) (*Pseudonymizer, error) {
+	if len(key) < MinKeyLength {
+		return nil, fmt.Errorf("pseudonymization key must be at least %d bytes, got %d", MinKeyLength, len(key))
+	}
+	if len(objectTypes) == 0 {
+		return nil, errors.New("at least one object type to pseudonymize is required")
+	}
+
+	// Pseudonyms and seals use separate keys, so that neither reveals the other.
+	block, err := aes.NewCipher(deriveKey(key, "seal"))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]struct{}, len(objectTypes))
+	for _, objectType := range objectTypes {
+		types[objectType] = struct{}{}
+	}
+	return &Pseudonymizer{objectTypes: types, macKey: deriveKey(key, "pseudonym"), aead: aead}, nil
+}
+
+// ObjectTypes returns the sorted types of the objects whose IDs are pseudonymized.
+func (p *Pseudonymizer) ObjectTypes() []string {
+	objectTypes := make([]string, 0, len(p.objectTypes))
+	for objectType := range p.objectTypes {
+		objectTypes = append(objectTypes, objectType)
+	}
+	sort.Strings(objectTypes)
+	return objectTypes
+}
+
+// Applies returns whether the IDs of objects of the type are pseudonymized.
+func (p *Pseudonymizer) Applies(objectType string) bool {
+	_, ok := p.objectTypes[objectType]
+	return ok
+}
+
+// IsPseudonym returns whether the object ID is a pseudonym.
+func IsPseudonym(objectID string) bool {
+	return strings.HasPrefix(objectID, Prefix)
+}
+
+// Pseudonym returns the pseudonym of an object ID. IDs of objects of other types,
+// wildcards and pseudonyms are returned as-is.
+func (p *Pseudonymizer) Pseudonym(objectType, objectID string) string {
+	if !p.Applies(objectType) || objectID == tuple.PublicWildcard || IsPseudonym(objectID) {
+		return objectID
+	}
+
+	mac := hmac.New(sha256.New, p.macKey)
+	mac.Write([]byte(objectType))
+	mac.Write([]byte{0})
+	mac.Write([]byte(objectID))
+	return Prefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Seal returns the pseudonym of an object ID along with the ID sealed, such that it can
+// only be unsealed for that pseudonym.
+func (p *Pseudonymizer) Seal(objectType, objectID string) (string, []byte, error) {
+	pseudonym := p.Pseudonym(objectType, objectID)
+	nonce, err := secrets.TokenBytes(uint8(p.aead.NonceSize()))
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to generate nonce: %w", err)
+	}
+	return pseudonym, p.aead.Seal(nonce, nonce, []byte(objectID), associatedData(objectType, pseudonym)), nil
+}
+
+// Unseal returns the object ID sealed for a pseudonym.
+func (p *Pseudonymizer) Unseal(objectType, pseudonym string, sealed []byte) (string, error) {
+	if len(sealed) < p.aead.NonceSize() {
+		return "", fmt.Errorf("%w: sealed ID is too short", ErrUnsealFailed)
+	}
+	nonce, ciphertext := sealed[:p.aead.NonceSize()], sealed[p.aead.NonceSize():]
+	objectID, err := p.aead.Open(nil, nonce, ciphertext, associatedData(objectType, pseudonym))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrUnsealFailed, err)
+	}
+	return string(objectID), nil
+}
+
+// PseudonymizeRelationship returns the relationship with the IDs of its resource and
+// subject pseudonymized where they apply, along with their sealed IDs by pseudonym. The
+// relationship given is left unmodified, and returned as-is if nothing applies.
+func (p *Pseudonymizer) PseudonymizeRelationship(tpl *core.RelationTuple) (*core.RelationTuple, map[string][]byte, error) {
+	var pseudonymized *core.RelationTuple
+	var sealed map[string][]byte
+	for _, onr := range []func(*core.RelationTuple) *core.ObjectAndRelation{
+		func(tpl *core.RelationTuple) *core.ObjectAndRelation { return tpl.ResourceAndRelation },
+		func(tpl *core.RelationTuple) *core.ObjectAndRelation { return tpl.Subject },
+	} {
+		original := onr(tpl)
+		if p.Pseudonym(original.Namespace, original.ObjectId) == original.ObjectId {
+			continue
+		}
+
+		pseudonym, sealedID, err := p.Seal(original.Namespace, original.ObjectId)
+		if err != nil {
+			return nil, nil, err
+		}
+		if pseudonymized == nil {
+			pseudonymized = tpl.CloneVT()
+			sealed = make(map[string][]byte, 2)
+		}
+		onr(pseudonymized).ObjectId = pseudonym
+		sealed[pseudonym] = sealedID
+	}
+
+	if pseudonymized == nil {
+		return tpl, nil, nil
+	}
+	return pseudonymized, sealed, nil
+}
+
+func deriveKey(key []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("spicedb " + purpose))
+	return mac.Sum(nil)
+}
+
+func associatedData(objectType, pseudonym string) []byte {
+	return []byte(objectType + ":" + pseudonym)
+}
@@ -0,0 +1,66 @@
+package pseudonym
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestPseudonymizeRelationship(t *testing.T) {
+	require := require.New(t)
+	pseudonymizer, err := NewPseudonymizer(bytes.Repeat([]byte{1}, 32), []string{"user"})
+	require.NoError(err)
+
+	tpl := tuple.MustParse("document:first#viewer@user:tom_at_example_com")
+	original := tpl.CloneVT()
+
+	pseudonymized, sealed, err := pseudonymizer.PseudonymizeRelationship(tpl)
+	require.NoError(err)
+	require.True(original.EqualVT(tpl), "relationship given must be left unmodified")
+	require.Equal("first", pseudonymized.ResourceAndRelation.ObjectId)
+
+	pseudonym := pseudonymized.Subject.ObjectId
+	require.True(IsPseudonym(pseudonym))
+	require.Equal(pseudonym, pseudonymizer.Pseudonym("user", "tom_at_example_com"), "pseudonyms must be deterministic")
+	require.NotEqual(pseudonym, pseudonymizer.Pseudonym("user", "sarah_at_example_com"))
+	require.NotContains(pseudonym, "tom")
+	require.NoError(pseudonymized.Validate())
+
+	objectID, err := pseudonymizer.Unseal("user", pseudonym, sealed[pseudonym])
+	require.NoError(err)
+	require.Equal("tom_at_example_com", objectID)
+
+	// Sealed IDs cannot be moved to another pseudonym, nor unsealed with another key.
+	_, err = pseudonymizer.Unseal("user", pseudonymizer.Pseudonym("user", "sarah_at_example_com"), sealed[pseudonym])
+	require.ErrorIs(err, ErrUnsealFailed)
+
+	otherKey, err := NewPseudonymizer(bytes.Repeat([]byte{2}, 32), []string{"user"})
+	require.NoError(err)
+	require.NotEqual(pseudonym, otherKey.Pseudonym("user", "tom_at_example_com"))
+	_, err = otherKey.Unseal("user", pseudonym, sealed[pseudonym])
+	require.ErrorIs(err, ErrUnsealFailed)
+
+	// Relationships without IDs to pseudonymize are returned as-is.
+	for _, unchanged := range []*core.RelationTuple{
+		tuple.MustParse("document:first#viewer@user:*"),
+		tuple.MustParse("document:first#parent@folder:root"),
+		pseudonymized,
+	} {
+		same, sealed, err := pseudonymizer.PseudonymizeRelationship(unchanged)
+		require.NoError(err)
+		require.Same(unchanged, same)
+		require.Empty(sealed)
+	}
+}
+
+func TestNewPseudonymizer(t *testing.T) {
+	_, err := NewPseudonymizer([]byte("short"), []string{"user"})
+	require.Error(t, err)
+
+	_, err = NewPseudonymizer(bytes.Repeat([]byte{1}, 32), nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package sdk
+
+import (
+	"context"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+)
+
+// GarbageCollector is implemented by datastores which delete the data of revisions
+// older than their garbage collection window themselves, rather than leaving it to the
+// database.
+type GarbageCollector = common.GarbageCollector
+
+// DeletionCounts are the counts of what a pass of garbage collection deleted.
+type DeletionCounts = common.DeletionCounts
+
+// ErrNotReadyForGarbageCollection is returned by CollectGarbage for a datastore which is
+// not ready.
+var ErrNotReadyForGarbageCollection = common.ErrNotReadyForGarbageCollection
+
+// RegisterGCMetrics registers the metrics of garbage collection with the default
+// Prometheus registry. It must be called at most once per process.
+func RegisterGCMetrics() error {
+	return common.RegisterGCMetrics()
+}
+
+// StartGarbageCollector collects the garbage of a datastore every interval, deleting the
+// data of the revisions older than window, each pass bounded by timeout, until the
+// context is canceled.
+func StartGarbageCollector(ctx context.Context, gc GarbageCollector, interval, window, timeout time.Duration) error {
+	return common.StartGarbageCollector(ctx, gc, interval, window, timeout)
+}
+
+// CollectGarbage runs a single pass of garbage collection of a datastore, deleting the
+// data of the revisions older than window.
+func CollectGarbage(ctx context.Context, gc GarbageCollector, window time.Duration) (DeletionCounts, error) {
+	return common.CollectGarbage(ctx, gc, window)
+}
@@ -0,0 +1,57 @@
+package sdk
+
+import (
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/common/revisions"
+)
+
+// OptimizedRevisionFunction computes the current optimized revision of a datastore, and
+// for how long it remains valid.
+type OptimizedRevisionFunction = revisions.OptimizedRevisionFunction
+
+// CachedOptimizedRevisions implements datastore.Datastore.OptimizedRevision by caching
+// the revisions of an OptimizedRevisionFunction, set with SetOptimizedRevisionFunc, for
+// as long as they are valid.
+type CachedOptimizedRevisions = revisions.CachedOptimizedRevisions
+
+// NewCachedOptimizedRevisions returns a CachedOptimizedRevisions which also returns
+// revisions for up to maxRevisionStaleness after they are no longer valid.
+func NewCachedOptimizedRevisions(maxRevisionStaleness time.Duration) *CachedOptimizedRevisions {
+	return revisions.NewCachedOptimizedRevisions(maxRevisionStaleness)
+}
+
+// RemoteNowFunction returns the current revision of a datastore whose revisions are the
+// times of its own clock, in nanoseconds.
+type RemoteNowFunction = revisions.RemoteNowFunction
+
+// RemoteClockRevisions implements the revision methods of datastore.Datastore for
+// datastores whose revisions are the times of their own clock, set with SetNowFunc.
+type RemoteClockRevisions = revisions.RemoteClockRevisions
+
+// NewRemoteClockRevisions returns a RemoteClockRevisions for the configuration of a
+// datastore.
+func NewRemoteClockRevisions(gcWindow, maxRevisionStaleness, followerReadDelay time.Duration, quantization *Quantization) *RemoteClockRevisions {
+	return revisions.NewRemoteClockRevisions(gcWindow, maxRevisionStaleness, followerReadDelay, quantization)
+}
+
+// Quantization is the window to which the optimized revisions of a datastore are
+// quantized. A datastore records its writes with RecordWrite for an adaptive window to
+// adapt to them.
+type Quantization = revisions.Quantization
+
+// NewStaticQuantization returns a Quantization whose window never changes.
+func NewStaticQuantization(window time.Duration) *Quantization {
+	return revisions.NewStaticQuantization(window)
+}
+
+// NewAdaptiveQuantization returns a Quantization whose window adapts between minimum and
+// maximum.
+func NewAdaptiveQuantization(minimum, maximum time.Duration) *Quantization {
+	return revisions.NewAdaptiveQuantization(minimum, maximum)
+}
+
+// QuantizedDatastore is implemented by datastores whose quantization window the server
+// adapts to the hit ratio of its dispatch caches.
+type QuantizedDatastore = common.QuantizedDatastore
@@ -0,0 +1,65 @@
+// Package sdk is the toolkit for implementing SpiceDB datastore drivers outside of the
+// SpiceDB repository: the options of the relationship queries of datastore.Reader, the
+// SQL query builders, revision and garbage collection helpers shared by the datastores
+// of SpiceDB, and, in package sdktest, the conformance test suite they are held to.
+//
+// A driver implements datastore.Datastore, and is served by passing an instance of it
+// as the Datastore of the server.Config of package pkg/cmd/server.
+//
+// The types of this package are aliases of those of the internal packages of SpiceDB,
+// and so change with them: like datastore.Datastore itself, they carry no compatibility
+// promise beyond that of the release of SpiceDB a driver is built against.
+package sdk
+
+import (
+	"github.com/authzed/spicedb/internal/datastore/options"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// QueryOptions are the options of a query of the relationships of resources, passed to
+// datastore.Reader.QueryRelationships.
+type QueryOptions = options.QueryOptions
+
+// QueryOptionsOption sets an option of a query of the relationships of resources.
+type QueryOptionsOption = options.QueryOptionsOption
+
+// ReverseQueryOptions are the options of a query of the relationships of subjects,
+// passed to datastore.Reader.ReverseQueryRelationships.
+type ReverseQueryOptions = options.ReverseQueryOptions
+
+// ReverseQueryOptionsOption sets an option of a query of the relationships of subjects.
+type ReverseQueryOptionsOption = options.ReverseQueryOptionsOption
+
+// ResourceRelation is a resource type and relation to which a reverse query is limited.
+type ResourceRelation = options.ResourceRelation
+
+// NewQueryOptions returns the options of a query set by opts.
+func NewQueryOptions(opts ...QueryOptionsOption) *QueryOptions {
+	return options.NewQueryOptionsWithOptions(opts...)
+}
+
+// NewReverseQueryOptions returns the options of a reverse query set by opts.
+func NewReverseQueryOptions(opts ...ReverseQueryOptionsOption) *ReverseQueryOptions {
+	return options.NewReverseQueryOptionsWithOptions(opts...)
+}
+
+// WithLimit limits a query to a number of relationships.
+func WithLimit(limit *uint64) QueryOptionsOption {
+	return options.WithLimit(limit)
+}
+
+// WithUsersets limits a query to the relationships of the subjects.
+func WithUsersets(usersets []*core.ObjectAndRelation) QueryOptionsOption {
+	return options.SetUsersets(usersets)
+}
+
+// WithReverseLimit limits a reverse query to a number of relationships.
+func WithReverseLimit(limit *uint64) ReverseQueryOptionsOption {
+	return options.WithReverseLimit(limit)
+}
+
+// WithResourceRelation limits a reverse query to the relationships of a resource type
+// and relation.
+func WithResourceRelation(resRelation *ResourceRelation) ReverseQueryOptionsOption {
+	return options.WithResRelation(resRelation)
+}
@@ -0,0 +1,103 @@
+package sdk_test
+
+import (
+	"context"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/sdk"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+var testSchema = sdk.SchemaInformation{
+	TableTuple:          "relationships",
+	ColNamespace:        "resource_type",
+	ColObjectID:         "resource_id",
+	ColRelation:         "relation",
+	ColUsersetNamespace: "subject_type",
+	ColUsersetObjectID:  "subject_id",
+	ColUsersetRelation:  "subject_relation",
+	ColCaveatName:       "caveat_name",
+}
+
+type sliceRows struct {
+	tuples []*core.RelationTuple
+}
+
+func (r *sliceRows) Next() (*core.RelationTuple, error) {
+	if len(r.tuples) == 0 {
+		return nil, nil
+	}
+	tpl := r.tuples[0]
+	r.tuples = r.tuples[1:]
+	return tpl, nil
+}
+
+func (r *sliceRows) Close() {}
+
+func TestQueryRelationships(t *testing.T) {
+	require := require.New(t)
+
+	var queries []string
+	splitter := sdk.TupleQuerySplitter{
+		UsersetBatchSize: 2,
+		Executor: func(ctx context.Context, sql string, args []any) (sdk.TupleRows, error) {
+			queries = append(queries, sql)
+			return &sliceRows{tuples: []*core.RelationTuple{
+				tuple.MustParse("document:first#viewer@user:tom"),
+				tuple.MustParse("document:second#viewer@user:fred"),
+			}}, nil
+		},
+	}
+
+	query := sdk.NewSchemaQueryFilterer(testSchema, sq.Select("*").From(testSchema.TableTuple)).
+		FilterWithRelationshipsFilter(datastore.RelationshipsFilter{ResourceType: "document"})
+
+	limit := uint64(3)
+	iter, err := splitter.SplitAndExecuteQuery(context.Background(), query,
+		sdk.WithLimit(&limit),
+		sdk.WithUsersets([]*core.ObjectAndRelation{
+			tuple.ParseSubjectONR("user:tom"),
+			tuple.ParseSubjectONR("user:fred"),
+			tuple.ParseSubjectONR("user:sarah"),
+		}),
+	)
+	require.NoError(err)
+	defer iter.Close()
+
+	var found []string
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		found = append(found, tuple.String(tpl))
+	}
+	require.NoError(iter.Err())
+
+	// The three subjects are queried in batches of two, and the limit stops reading the
+	// second batch after its first relationship.
+	require.Len(queries, 2)
+	for _, query := range queries {
+		require.Contains(query, "FROM relationships WHERE resource_type = ?")
+	}
+	require.Equal([]string{
+		"document:first#viewer@user:tom",
+		"document:second#viewer@user:fred",
+		"document:first#viewer@user:tom",
+	}, found)
+}
+
+func TestQueryOptions(t *testing.T) {
+	require := require.New(t)
+
+	limit := uint64(10)
+	queryOpts := sdk.NewQueryOptions(sdk.WithLimit(&limit))
+	require.Equal(&limit, queryOpts.Limit)
+	require.Empty(queryOpts.Usersets)
+
+	resRelation := &sdk.ResourceRelation{Namespace: "document", Relation: "viewer"}
+	reverseOpts := sdk.NewReverseQueryOptions(sdk.WithReverseLimit(&limit), sdk.WithResourceRelation(resRelation))
+	require.Equal(&limit, reverseOpts.ReverseLimit)
+	require.Equal(resRelation, reverseOpts.ResRelation)
+}
@@ -0,0 +1,23 @@
+// Package sdktest holds the conformance test suite of SpiceDB datastore drivers, which
+// drivers implemented with package sdk run from their own tests.
+package sdktest
+
+import (
+	"testing"
+
+	"github.com/authzed/spicedb/pkg/datastore/test"
+)
+
+// DatastoreTester creates a new, empty, instance of the datastore under test for each
+// test of the suite.
+type DatastoreTester = test.DatastoreTester
+
+// DatastoreTesterFunc is a function implementing DatastoreTester.
+type DatastoreTesterFunc = test.DatastoreTesterFunc
+
+// RunConformanceTests runs the conformance test suite against the datastores created by
+// tester. Tests of optional capabilities, such as garbage collection, are skipped for
+// datastores without them.
+func RunConformanceTests(t *testing.T, tester DatastoreTester) {
+	test.All(t, tester)
+}
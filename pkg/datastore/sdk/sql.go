@@ -0,0 +1,50 @@
+package sdk
+
+import (
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// SchemaInformation names the table of relationships of a SQL datastore and its columns.
+type SchemaInformation = common.SchemaInformation
+
+// SchemaQueryFilterer builds the query selecting the relationships matching filters,
+// such as those of datastore.RelationshipsFilter and datastore.SubjectsFilter.
+type SchemaQueryFilterer = common.SchemaQueryFilterer
+
+// NewSchemaQueryFilterer returns a SchemaQueryFilterer adding filters to initialQuery,
+// which selects from the table of relationships described by schema.
+func NewSchemaQueryFilterer(schema SchemaInformation, initialQuery sq.SelectBuilder) SchemaQueryFilterer {
+	return common.NewSchemaQueryFilterer(schema, initialQuery)
+}
+
+// TupleQuerySplitter executes the queries built by a SchemaQueryFilterer, splitting
+// those for many subjects into batches, and applying the QueryOptions of the query.
+type TupleQuerySplitter = common.TupleQuerySplitter
+
+// ExecuteQueryFunc executes a rendered SQL query for a TupleQuerySplitter.
+type ExecuteQueryFunc = common.ExecuteQueryFunc
+
+// TupleRows is a cursor over the relationships read from the rows of an executed query.
+type TupleRows = common.TupleRows
+
+// CreateRelationshipExistsError is the error returned by datastore.ReadWriteTransaction
+// when creating a relationship which already exists.
+type CreateRelationshipExistsError = common.CreateRelationshipExistsError
+
+// NewCreateRelationshipExistsError returns a CreateRelationshipExistsError for the
+// relationship, which may be nil if the datastore cannot tell which already existed.
+func NewCreateRelationshipExistsError(relationship *core.RelationTuple) error {
+	return common.NewCreateRelationshipExistsError(relationship)
+}
+
+// Changes accumulates the changes of relationships read for datastore.Datastore.Watch,
+// keeping the changes of each revision self-consistent.
+type Changes = common.Changes
+
+// NewChanges returns an empty Changes.
+func NewChanges() Changes {
+	return common.NewChanges()
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/pkg/caveats"
 	caveattypes "github.com/authzed/spicedb/pkg/caveats/types"
@@ -292,6 +293,72 @@ func CaveatedRelationshipWatchTest(t *testing.T, tester DatastoreTester) {
 	expectTupleChange(t, ds, revBeforeWrite, tupleWithNilContext)
 }
 
+// CaveatContextRoundTripTest tests that caveat contexts of every JSON type are stored
+// and returned unchanged by reads in both directions and by Watch.
+func CaveatContextRoundTripTest(t *testing.T, tester DatastoreTester) {
+	req := require.New(t)
+	ds, err := tester.New(0*time.Second, veryLargeGCWindow, 16)
+	req.NoError(err)
+
+	skipIfNotCaveatStorer(t, ds)
+	sds, _ := testfixtures.StandardDatastoreWithSchema(ds, req)
+
+	ctx := context.Background()
+	coreCaveat := createCoreCaveat(t)
+	_, err = writeCaveat(ctx, ds, coreCaveat)
+	req.NoError(err)
+
+	contexts := map[string]map[string]any{
+		"scalars": {"int": 42, "negative": -7.5, "bool": true, "null": nil, "string": "héllo, 世界"},
+		"nested":  {"map": map[string]any{"inner": map[string]any{"list": []any{1, "two", false, nil}}}},
+		"large":   {"big": 9007199254740991, "empty_list": []any{}, "empty_map": map[string]any{}},
+	}
+
+	for name, caveatContext := range contexts {
+		name, caveatContext := name, caveatContext
+		t.Run(name, func(t *testing.T) {
+			req := require.New(t)
+
+			tpl := createTestCaveatedTuple(t, fmt.Sprintf("document:%s#parent@folder:company#...", name), coreCaveat.Name)
+			st, err := structpb.NewStruct(caveatContext)
+			req.NoError(err)
+			tpl.Caveat.Context = st
+
+			revBeforeWrite, err := ds.HeadRevision(ctx)
+			req.NoError(err)
+			rev, err := common.WriteTuples(ctx, sds, core.RelationTupleUpdate_CREATE, tpl)
+			req.NoError(err)
+
+			reader := ds.SnapshotReader(rev)
+			iter, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+				ResourceType:        "document",
+				OptionalResourceIds: []string{name},
+			})
+			req.NoError(err)
+			expectTuple(req, iter, tpl)
+
+			iter, err = reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+				SubjectType:        "folder",
+				OptionalSubjectIds: []string{"company"},
+			}, options.WithResRelation(&options.ResourceRelation{Namespace: "document", Relation: "parent"}))
+			req.NoError(err)
+
+			found := false
+			for rel := iter.Next(); rel != nil; rel = iter.Next() {
+				if rel.ResourceAndRelation.ObjectId == name {
+					req.Empty(cmp.Diff(tpl, rel, protocmp.Transform()))
+					found = true
+				}
+			}
+			req.NoError(iter.Err())
+			iter.Close()
+			req.True(found, "relationship not found by reverse query")
+
+			expectTupleChange(t, ds, revBeforeWrite, tpl)
+		})
+	}
+}
+
 func expectTupleChange(t *testing.T, ds datastore.Datastore, revBeforeWrite datastore.Revision, expectedTuple *core.RelationTuple) {
 	t.Helper()
 
@@ -47,14 +47,21 @@ func All(t *testing.T, tester DatastoreTester) {
 	t.Run("TestCreateAlreadyExisting", func(t *testing.T) { CreateAlreadyExistingTest(t, tester) })
 	t.Run("TestTouchAlreadyExisting", func(t *testing.T) { TouchAlreadyExistingTest(t, tester) })
 	t.Run("TestUsersets", func(t *testing.T) { UsersetsTest(t, tester) })
+	t.Run("TestObjectIDPrefix", func(t *testing.T) { ObjectIDPrefixTest(t, tester) })
+	t.Run("TestSortedQuery", func(t *testing.T) { SortedQueryTest(t, tester) })
 	t.Run("TestMultipleReadsInRWT", func(t *testing.T) { MultipleReadsInRWTTest(t, tester) })
+	t.Run("TestReadYourWritesInRWT", func(t *testing.T) { ReadYourWritesInRWTTest(t, tester) })
 	t.Run("TestConcurrentWriteSerialization", func(t *testing.T) { ConcurrentWriteSerializationTest(t, tester) })
+	t.Run("TestQueryStability", func(t *testing.T) { QueryStabilityTest(t, tester) })
 
 	t.Run("TestRevisionQuantization", func(t *testing.T) { RevisionQuantizationTest(t, tester) })
 	t.Run("TestRevisionSerialization", func(t *testing.T) { RevisionSerializationTest(t, tester) })
 
 	t.Run("TestWatch", func(t *testing.T) { WatchTest(t, tester) })
 	t.Run("TestWatchCancel", func(t *testing.T) { WatchCancelTest(t, tester) })
+	t.Run("TestWatchResume", func(t *testing.T) { WatchResumeTest(t, tester) })
+
+	t.Run("TestGC", func(t *testing.T) { GCTest(t, tester) })
 
 	t.Run("TestStats", func(t *testing.T) { StatsTest(t, tester) })
 
@@ -63,6 +70,7 @@ func All(t *testing.T, tester DatastoreTester) {
 	t.Run("TestCaveatedRelationshipFilter", func(t *testing.T) { CaveatedRelationshipFilterTest(t, tester) })
 	t.Run("TestCaveatSnapshotReads", func(t *testing.T) { CaveatSnapshotReadsTest(t, tester) })
 	t.Run("TestCaveatedRelationshipWatch", func(t *testing.T) { CaveatedRelationshipWatchTest(t, tester) })
+	t.Run("TestCaveatContextRoundTrip", func(t *testing.T) { CaveatContextRoundTripTest(t, tester) })
 }
 
 var testResourceNS = namespace.Namespace(
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// GCTest tests that garbage collection only removes data which is no longer visible at or
+// after the revision it is run for, for datastores which support external garbage
+// collection.
+func GCTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+
+	gc, ok := ds.(common.GarbageCollector)
+	if !ok {
+		t.Skip("datastore does not support external garbage collection")
+	}
+
+	setupDatastore(ds, require)
+	ctx := context.Background()
+
+	deleted := makeTestTuple("deleted", "test_user")
+	kept := makeTestTuple("kept", "test_user")
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, deleted, kept)
+	require.NoError(err)
+
+	deletedAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_DELETE, deleted)
+	require.NoError(err)
+
+	later := makeTestTuple("later", "test_user")
+	writtenAt, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, later)
+	require.NoError(err)
+
+	// Collecting at the last write removes the deleted relationship, and nothing else.
+	removed, err := gc.DeleteBeforeTx(ctx, writtenAt)
+	require.NoError(err)
+	require.GreaterOrEqual(removed.Relationships, int64(1))
+	require.Zero(removed.Namespaces)
+
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+	tRequire.NoTupleExists(ctx, deleted, writtenAt)
+	tRequire.TupleExists(ctx, kept, writtenAt)
+	tRequire.TupleExists(ctx, later, writtenAt)
+	tRequire.NoTupleExists(ctx, deleted, deletedAt)
+	tRequire.TupleExists(ctx, kept, deletedAt)
+
+	head, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	nsDefs, err := ds.SnapshotReader(head).ListNamespaces(ctx)
+	require.NoError(err)
+	require.Len(nsDefs, 2)
+
+	// Collecting again removes no more relationships.
+	removed, err = gc.DeleteBeforeTx(ctx, writtenAt)
+	require.NoError(err)
+	require.Zero(removed.Relationships)
+	require.Zero(removed.Namespaces)
+
+	// Collecting relative to the current time finds a revision which is not in the future.
+	now, err := gc.Now(ctx)
+	require.NoError(err)
+	before, err := gc.TxIDBefore(ctx, now.Add(time.Second))
+	require.NoError(err)
+	require.False(before.GreaterThan(head))
+}
@@ -645,6 +645,123 @@ func UsersetsTest(t *testing.T, tester DatastoreTester) {
 	})
 }
 
+// ObjectIDPrefixTest tests that relationships can be queried by prefixes of their
+// resource and subject object IDs, with the LIKE wildcards in prefixes matched literally.
+func ObjectIDPrefixTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+	ctx := context.Background()
+
+	tenant1First := makeTestTuple("tenant1-first", "tenant1-alice")
+	tenant1Second := makeTestTuple("tenant1-second", "tenant2-bob")
+	tenant1Underscore := makeTestTuple("tenant1_third", "tenant1_carol")
+	tenant2First := makeTestTuple("tenant2-first", "tenant1-alice")
+	revision, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, tenant1First, tenant1Second, tenant1Underscore, tenant2First)
+	require.NoError(err)
+	reader := ds.SnapshotReader(revision)
+
+	iter, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             testResourceNamespace,
+		OptionalResourceIDPrefix: "tenant1-",
+	})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, tenant1First, tenant1Second)
+
+	iter, err = reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             testResourceNamespace,
+		OptionalResourceIDPrefix: "tenant1_",
+	})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, tenant1Underscore)
+
+	iter, err = reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             testResourceNamespace,
+		OptionalResourceIDPrefix: "tenant1",
+		OptionalResourceRelation: testReaderRelation,
+		OptionalSubjectsFilter: &datastore.SubjectsFilter{
+			SubjectType:             testUserNamespace,
+			OptionalSubjectIDPrefix: "tenant1-",
+		},
+	})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, tenant1First)
+
+	iter, err = reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+		SubjectType:             testUserNamespace,
+		OptionalSubjectIDPrefix: "tenant1-",
+	})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, tenant1First, tenant2First)
+
+	iter, err = reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+		SubjectType:             testUserNamespace,
+		OptionalSubjectIDPrefix: "tenant1%",
+	})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter)
+}
+
+// SortedQueryTest tests that relationships queried by resource are returned ordered by
+// the bytes of their fields, whatever the collation of the datastore.
+func SortedQueryTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+	ctx := context.Background()
+
+	// Ordered by their bytes, unlike by a case-insensitive or linguistic collation.
+	sorted := []*core.RelationTuple{
+		makeTestTuple("A", "bob"),
+		makeTestTuple("a", "Bob"),
+		makeTestTuple("a", "alice"),
+		makeTestTuple("a-b", "alice"),
+		makeTestTuple("aB", "alice"),
+		makeTestTuple("a_b", "alice"),
+		makeTestTuple("b", "alice"),
+	}
+	revision, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, sorted[3], sorted[6], sorted[0], sorted[5], sorted[2], sorted[4], sorted[1])
+	require.NoError(err)
+	reader := ds.SnapshotReader(revision)
+
+	read := func(opts ...options.QueryOptionsOption) []string {
+		iter, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: testResourceNamespace}, opts...)
+		require.NoError(err)
+		defer iter.Close()
+
+		var found []string
+		for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+			found = append(found, tuple.String(tpl))
+		}
+		require.NoError(iter.Err())
+		return found
+	}
+
+	expected := make([]string, 0, len(sorted))
+	for _, tpl := range sorted {
+		expected = append(expected, tuple.String(tpl))
+	}
+	require.Equal(expected, read(options.WithSort(options.ByResource)))
+	require.Equal(expected, read(options.WithSort(options.ByResource), options.WithStreamRows(true)))
+
+	// The limit is applied to the sorted relationships.
+	limit := uint64(3)
+	require.Equal(expected[:3], read(options.WithSort(options.ByResource), options.WithLimit(&limit)))
+
+	for i := 1; i < len(sorted); i++ {
+		require.Negative(options.CompareByResource(sorted[i-1], sorted[i]))
+	}
+}
+
 func MultipleReadsInRWTTest(t *testing.T, tester DatastoreTester) {
 	require := require.New(t)
 
@@ -672,6 +789,102 @@ func MultipleReadsInRWTTest(t *testing.T, tester DatastoreTester) {
 	require.NoError(err)
 }
 
+// ReadYourWritesInRWTTest tests that the reads made in a read-write transaction observe
+// the writes made earlier in it.
+func ReadYourWritesInRWTTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+	skipIfNotCaveatStorer(t, ds)
+
+	setupDatastore(ds, require)
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+	ctx := context.Background()
+
+	existing := makeTestTuple("existing", "alice")
+	doomed := makeTestTuple("doomed", "bob")
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, existing, doomed)
+	require.NoError(err)
+
+	touched := tuple.WithCaveat(makeTestTuple("existing", "alice"), "only_weekdays")
+	created := makeTestTuple("created", "alice")
+	coreCaveat := createCoreCaveat(t)
+
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		require.NoError(rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{
+			tuple.Touch(touched),
+			tuple.Create(created),
+			tuple.Delete(doomed),
+		}))
+
+		iter, err := rwt.QueryRelationships(ctx, datastore.RelationshipsFilter{
+			ResourceType: testResourceNamespace,
+		})
+		require.NoError(err)
+		tRequire.VerifyIteratorResults(iter, touched, created)
+
+		iter, err = rwt.QueryRelationships(ctx, datastore.RelationshipsFilter{
+			ResourceType: testResourceNamespace,
+		}, options.WithLimit(options.LimitOne))
+		require.NoError(err)
+		tRequire.VerifyIteratorCount(iter, 1)
+
+		iter, err = rwt.QueryRelationships(ctx, datastore.RelationshipsFilter{
+			ResourceType:             testResourceNamespace,
+			OptionalResourceIDPrefix: "cr",
+		})
+		require.NoError(err)
+		tRequire.VerifyIteratorResults(iter, created)
+
+		iter, err = rwt.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+			SubjectType:        testUserNamespace,
+			OptionalSubjectIds: []string{"alice", "bob"},
+		})
+		require.NoError(err)
+		tRequire.VerifyIteratorResults(iter, touched, created)
+
+		require.NoError(rwt.DeleteRelationships(ctx, &v1.RelationshipFilter{
+			ResourceType:       testResourceNamespace,
+			OptionalResourceId: created.ResourceAndRelation.ObjectId,
+		}))
+
+		iter, err = rwt.QueryRelationships(ctx, datastore.RelationshipsFilter{
+			ResourceType: testResourceNamespace,
+		})
+		require.NoError(err)
+		tRequire.VerifyIteratorResults(iter, touched)
+
+		require.NoError(rwt.WriteNamespaces(ctx, testNamespace))
+		found, _, err := rwt.ReadNamespace(ctx, testNamespace.Name)
+		require.NoError(err)
+		require.Equal(testNamespace.Name, found.Name)
+
+		foundNamespaces, err := rwt.LookupNamespaces(ctx, []string{testNamespace.Name})
+		require.NoError(err)
+		require.Len(foundNamespaces, 1)
+
+		require.NoError(rwt.WriteCaveats(ctx, []*core.CaveatDefinition{coreCaveat}))
+		foundCaveat, _, err := rwt.ReadCaveatByName(ctx, coreCaveat.Name)
+		require.NoError(err)
+		require.Equal(coreCaveat.Name, foundCaveat.Name)
+
+		require.NoError(rwt.DeleteCaveats(ctx, []string{coreCaveat.Name}))
+		_, _, err = rwt.ReadCaveatByName(ctx, coreCaveat.Name)
+		require.ErrorAs(err, &datastore.ErrCaveatNameNotFound{})
+
+		return nil
+	})
+	require.NoError(err)
+
+	iter, err := ds.SnapshotReader(revision).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType: testResourceNamespace,
+	})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, touched)
+}
+
 // ConcurrentWriteSerializationTest uses goroutines and channels to intentionally set up a
 // deadlocking dependency between transactions.
 func ConcurrentWriteSerializationTest(t *testing.T, tester DatastoreTester) {
@@ -731,6 +944,90 @@ func ConcurrentWriteSerializationTest(t *testing.T, tester DatastoreTester) {
 	require.Less(time.Since(startTime), 10*time.Second)
 }
 
+// QueryStabilityTest tests that reads at a revision return the same relationships, each
+// exactly once, however they are paged with limits and whatever is written afterwards.
+func QueryStabilityTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	setupDatastore(ds, require)
+	ctx := context.Background()
+
+	var expected []*core.RelationTuple
+	for i := 0; i < 50; i++ {
+		expected = append(expected, makeTestTuple(fmt.Sprintf("resource%d", i), fmt.Sprintf("user%d", i%7)))
+	}
+	rev, err := common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, expected...)
+	require.NoError(err)
+
+	readAll := func(opts ...options.QueryOptionsOption) []string {
+		iter, err := ds.SnapshotReader(rev).QueryRelationships(ctx, datastore.RelationshipsFilter{
+			ResourceType: testResourceNamespace,
+		}, opts...)
+		require.NoError(err)
+		defer iter.Close()
+
+		var found []string
+		for rel := iter.Next(); rel != nil; rel = iter.Next() {
+			found = append(found, tuple.String(rel))
+		}
+		require.NoError(iter.Err())
+		return found
+	}
+
+	expectedStrings := make([]string, 0, len(expected))
+	for _, tpl := range expected {
+		expectedStrings = append(expectedStrings, tuple.String(tpl))
+	}
+	require.ElementsMatch(expectedStrings, readAll())
+
+	// Start iterating, then change the relationships before the iterator is drained.
+	iter, err := ds.SnapshotReader(rev).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType: testResourceNamespace,
+	})
+	require.NoError(err)
+	var interleaved []string
+	for i := 0; i < 10; i++ {
+		rel := iter.Next()
+		require.NotNil(rel)
+		interleaved = append(interleaved, tuple.String(rel))
+	}
+
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_DELETE, expected[:25]...)
+	require.NoError(err)
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, makeTestTuple("added", "user0"))
+	require.NoError(err)
+
+	for rel := iter.Next(); rel != nil; rel = iter.Next() {
+		interleaved = append(interleaved, tuple.String(rel))
+	}
+	require.NoError(iter.Err())
+	iter.Close()
+	require.ElementsMatch(expectedStrings, interleaved)
+
+	// Reads of the revision are unchanged by the later writes.
+	require.ElementsMatch(expectedStrings, readAll())
+
+	// Limited reads return distinct relationships from the revision, up to the limit.
+	for _, limit := range []uint64{1, 10, 50, 100} {
+		limit := limit
+		limited := readAll(options.WithLimit(&limit))
+		expectedCount := len(expected)
+		if int(limit) < expectedCount {
+			expectedCount = int(limit)
+		}
+		require.Len(limited, expectedCount)
+		require.Subset(expectedStrings, limited)
+
+		seen := make(map[string]struct{}, len(limited))
+		for _, rel := range limited {
+			require.NotContains(seen, rel, "relationship returned more than once")
+			seen[rel] = struct{}{}
+		}
+	}
+}
+
 func onrToSubjectsFilter(onr *core.ObjectAndRelation) datastore.SubjectsFilter {
 	return datastore.SubjectsFilter{
 		SubjectType:        onr.Namespace,
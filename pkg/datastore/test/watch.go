@@ -166,6 +166,67 @@ func setOfChanges(changes []*core.RelationTupleUpdate) *strset.Set {
 	return changeSet
 }
 
+// WatchResumeTest tests that the revision of each change can be used to resume watching,
+// receiving only the changes which followed it, in the order they were committed.
+func WatchResumeTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const numWrites = 5
+	var expected [][]*core.RelationTupleUpdate
+	for i := 0; i < numWrites; i++ {
+		update := tuple.Touch(makeTestTuple(fmt.Sprintf("resume%d", i), "test_user"))
+		_, err := common.UpdateTuplesInDatastore(ctx, ds, update)
+		require.NoError(err)
+		expected = append(expected, []*core.RelationTupleUpdate{update})
+	}
+
+	received := receiveChanges(ctx, require, ds, startRevision, numWrites)
+	for i, change := range received {
+		require.True(setOfChanges(expected[i]).IsEqual(setOfChanges(change.Changes)), "unexpected changes at %d", i)
+		if i > 0 {
+			require.True(change.Revision.GreaterThan(received[i-1].Revision), "revisions must be strictly increasing")
+		}
+	}
+
+	for resumeFrom := 0; resumeFrom < numWrites-1; resumeFrom++ {
+		resumed := receiveChanges(ctx, require, ds, received[resumeFrom].Revision, numWrites-resumeFrom-1)
+		for i, change := range resumed {
+			require.True(change.Revision.Equal(received[resumeFrom+i+1].Revision), "resuming from %d", resumeFrom)
+			require.True(setOfChanges(expected[resumeFrom+i+1]).IsEqual(setOfChanges(change.Changes)), "resuming from %d", resumeFrom)
+		}
+	}
+}
+
+// receiveChanges watches the datastore from the revision, returning the first count changes.
+func receiveChanges(ctx context.Context, require *require.Assertions, ds datastore.Datastore, afterRevision datastore.Revision, count int) []*datastore.RevisionChanges {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, afterRevision)
+	received := make([]*datastore.RevisionChanges, 0, count)
+	for len(received) < count {
+		changeWait := time.NewTimer(waitForChangesTimeout)
+		select {
+		case change, ok := <-changes:
+			require.True(ok, "unexpected disconnect")
+			received = append(received, change)
+		case err := <-errchan:
+			require.NoError(err)
+		case <-changeWait.C:
+			require.Fail("Timed out", "waiting for change %d of %d", len(received)+1, count)
+		}
+	}
+	return received
+}
+
 // WatchCancelTest tests whether or not the requirements for cancelling watches
 // hold for a particular datastore.
 func WatchCancelTest(t *testing.T, tester DatastoreTester) {
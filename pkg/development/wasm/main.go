@@ -11,6 +11,8 @@ import (
 func main() {
 	c := make(chan struct{}, 0)
 	js.Global().Set("runSpiceDBDeveloperRequest", js.FuncOf(runDeveloperRequest))
+	js.Global().Set("validateSpiceDBSchema", js.FuncOf(validateSchema))
+	js.Global().Set("checkSpiceDBPermission", js.FuncOf(checkPermission))
 	fmt.Println("Developer system initialized")
 	<-c
 }
@@ -41,36 +41,42 @@ func runDeveloperRequest(this js.Value, args []js.Value) any {
 		return respErr(fmt.Errorf("could not decode developer request: %w", err))
 	}
 
+	return encode(runRequest(devRequest))
+}
+
+// runRequest runs the operation(s) of a developer request over a new developer context.
+func runRequest(devRequest *devinterface.DeveloperRequest) *devinterface.DeveloperResponse {
 	if devRequest.Context == nil {
-		return respErr(fmt.Errorf("missing required context"))
+		return errResponse(fmt.Errorf("missing required context"))
 	}
 
 	// Construct the developer context.
 	devContext, devErrors, err := development.NewDevContext(context.Background(), devRequest.Context)
 	if err != nil {
-		return respErr(err)
+		return errResponse(err)
 	}
 
 	if devErrors != nil && len(devErrors.InputErrors) > 0 {
-		return respInputErr(devErrors.InputErrors)
+		return inputErrResponse(devErrors.InputErrors)
 	}
+	defer devContext.Dispose()
 
 	// Run operations.
 	results := make(map[uint64]*devinterface.OperationResult, len(devRequest.Operations))
 	for index, op := range devRequest.Operations {
 		result, err := runOperation(devContext, op)
 		if err != nil {
-			return respErr(err)
+			return errResponse(err)
 		}
 
 		results[uint64(index)] = result
 	}
 
-	return encode(&devinterface.DeveloperResponse{
+	return &devinterface.DeveloperResponse{
 		OperationsResults: &devinterface.OperationsResults{
 			Results: results,
 		},
-	})
+	}
 }
 
 func encode(response *devinterface.DeveloperResponse) js.Value {
@@ -83,15 +89,19 @@ func encode(response *devinterface.DeveloperResponse) js.Value {
 }
 
 func respErr(err error) js.Value {
-	return encode(&devinterface.DeveloperResponse{
+	return encode(errResponse(err))
+}
+
+func errResponse(err error) *devinterface.DeveloperResponse {
+	return &devinterface.DeveloperResponse{
 		InternalError: err.Error(),
-	})
+	}
 }
 
-func respInputErr(inputErrors []*devinterface.DeveloperError) js.Value {
-	return encode(&devinterface.DeveloperResponse{
+func inputErrResponse(inputErrors []*devinterface.DeveloperError) *devinterface.DeveloperResponse {
+	return &devinterface.DeveloperResponse{
 		DeveloperErrors: &devinterface.DeveloperErrors{
 			InputErrors: inputErrors,
 		},
-	})
+	}
 }
@@ -0,0 +1,110 @@
+//go:build wasm
+// +build wasm
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall/js"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	devinterface "github.com/authzed/spicedb/pkg/proto/developer/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// validateSchema is the function exported into the WASM environment for validating a
+// schema, without building a full DeveloperRequest.
+//
+// The arguments are:
+//
+//  1. The schema, as a string.
+//
+// The function returns a JSON-encoded DeveloperResponse, holding the errors found in the
+// schema, if any, or the formatted schema as the result of its single operation.
+func validateSchema(this js.Value, args []js.Value) any {
+	if len(args) != 1 {
+		return respErr(fmt.Errorf("invalid number of arguments specified"))
+	}
+
+	return encode(runRequest(&devinterface.DeveloperRequest{
+		Context: &devinterface.RequestContext{
+			Schema: args[0].String(),
+		},
+		Operations: []*devinterface.Operation{
+			{FormatSchemaParameters: &devinterface.FormatSchemaParameters{}},
+		},
+	}))
+}
+
+// checkPermission is the function exported into the WASM environment for simulating a
+// check over a schema and a set of relationships, in their string forms.
+//
+// The arguments are:
+//
+//  1. The schema, as a string.
+//  2. The relationships, one per line, such as `document:firstdoc#viewer@user:tom`. Empty
+//     lines and lines starting with `//` are ignored.
+//  3. The check, in the same form as a relationship, such as `document:firstdoc#view@user:tom`.
+//
+// The function returns a JSON-encoded DeveloperResponse, holding the errors found in the
+// input, if any, or the result of the check as the result of its single operation.
+func checkPermission(this js.Value, args []js.Value) any {
+	if len(args) != 3 {
+		return respErr(fmt.Errorf("invalid number of arguments specified"))
+	}
+
+	relationships, inputErrors := parseRelationships(args[1].String())
+	check := tuple.Parse(strings.TrimSpace(args[2].String()))
+	if check == nil {
+		inputErrors = append(inputErrors, &devinterface.DeveloperError{
+			Message: fmt.Sprintf("invalid check `%s`", args[2].String()),
+			Source:  devinterface.DeveloperError_CHECK_WATCH,
+			Kind:    devinterface.DeveloperError_PARSE_ERROR,
+			Context: args[2].String(),
+		})
+	}
+	if len(inputErrors) > 0 {
+		return encode(inputErrResponse(inputErrors))
+	}
+
+	return encode(runRequest(&devinterface.DeveloperRequest{
+		Context: &devinterface.RequestContext{
+			Schema:        args[0].String(),
+			Relationships: relationships,
+		},
+		Operations: []*devinterface.Operation{
+			{CheckParameters: &devinterface.CheckOperationParameters{
+				Resource: check.ResourceAndRelation,
+				Subject:  check.Subject,
+			}},
+		},
+	}))
+}
+
+func parseRelationships(relationships string) ([]*core.RelationTuple, []*devinterface.DeveloperError) {
+	var (
+		parsed      []*core.RelationTuple
+		inputErrors []*devinterface.DeveloperError
+	)
+	for index, line := range strings.Split(relationships, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+
+		tpl := tuple.Parse(trimmed)
+		if tpl == nil {
+			inputErrors = append(inputErrors, &devinterface.DeveloperError{
+				Message: fmt.Sprintf("invalid relationship `%s`", trimmed),
+				Line:    uint32(index + 1),
+				Source:  devinterface.DeveloperError_RELATIONSHIP,
+				Kind:    devinterface.DeveloperError_PARSE_ERROR,
+				Context: trimmed,
+			})
+			continue
+		}
+		parsed = append(parsed, tpl)
+	}
+	return parsed, inputErrors
+}
@@ -0,0 +1,118 @@
+//go:build wasm
+// +build wasm
+
+package main
+
+import (
+	"syscall/js"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	devinterface "github.com/authzed/spicedb/pkg/proto/developer/v1"
+)
+
+const simpleSchema = `definition user {}
+
+definition document {
+	relation viewer: user
+	permission view = viewer
+}`
+
+func decodeResponse(t *testing.T, encoded any) *devinterface.DeveloperResponse {
+	response := &devinterface.DeveloperResponse{}
+	require.NoError(t, protojson.Unmarshal([]byte(encoded.(js.Value).String()), response))
+	return response
+}
+
+func TestValidateSchema(t *testing.T) {
+	response := decodeResponse(t, validateSchema(js.Null(), []js.Value{js.ValueOf("definition user {}\n\n\n")}))
+	require.Equal(t, "", response.GetInternalError())
+	require.Equal(t, "definition user {}", response.GetOperationsResults().Results[0].GetFormatSchemaResult().FormattedSchema)
+
+	response = decodeResponse(t, validateSchema(js.Null(), []js.Value{js.ValueOf("definitio user {")}))
+	require.Equal(t, 1, len(response.GetDeveloperErrors().InputErrors))
+	require.Equal(t, devinterface.DeveloperError_SCHEMA, response.GetDeveloperErrors().InputErrors[0].Source)
+
+	response = decodeResponse(t, validateSchema(js.Null(), []js.Value{}))
+	require.Equal(t, "invalid number of arguments specified", response.GetInternalError())
+}
+
+func TestCheckPermission(t *testing.T) {
+	relationships := `
+		// the viewers of the first document
+		document:firstdoc#viewer@user:tom
+	`
+
+	tcs := []struct {
+		name               string
+		relationships      string
+		check              string
+		expectedMembership devinterface.CheckOperationsResult_Membership
+		expectedErrors     []*devinterface.DeveloperError
+	}{
+		{
+			"member",
+			relationships,
+			"document:firstdoc#view@user:tom",
+			devinterface.CheckOperationsResult_MEMBER,
+			nil,
+		},
+		{
+			"not member",
+			relationships,
+			"document:firstdoc#view@user:sarah",
+			devinterface.CheckOperationsResult_NOT_MEMBER,
+			nil,
+		},
+		{
+			"invalid relationship",
+			"document:firstdoc#viewer@user:tom\ndocument:firstdoc#viewer",
+			"document:firstdoc#view@user:tom",
+			devinterface.CheckOperationsResult_UNKNOWN,
+			[]*devinterface.DeveloperError{{
+				Message: "invalid relationship `document:firstdoc#viewer`",
+				Line:    2,
+				Source:  devinterface.DeveloperError_RELATIONSHIP,
+				Kind:    devinterface.DeveloperError_PARSE_ERROR,
+				Context: "document:firstdoc#viewer",
+			}},
+		},
+		{
+			"invalid check",
+			relationships,
+			"document:firstdoc",
+			devinterface.CheckOperationsResult_UNKNOWN,
+			[]*devinterface.DeveloperError{{
+				Message: "invalid check `document:firstdoc`",
+				Source:  devinterface.DeveloperError_CHECK_WATCH,
+				Kind:    devinterface.DeveloperError_PARSE_ERROR,
+				Context: "document:firstdoc",
+			}},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			response := decodeResponse(t, checkPermission(js.Null(), []js.Value{
+				js.ValueOf(simpleSchema),
+				js.ValueOf(tc.relationships),
+				js.ValueOf(tc.check),
+			}))
+			require.Equal(t, "", response.GetInternalError())
+
+			if tc.expectedErrors != nil {
+				require.Equal(t, len(tc.expectedErrors), len(response.GetDeveloperErrors().InputErrors))
+				for index, expected := range tc.expectedErrors {
+					require.Equal(t, expected.String(), response.GetDeveloperErrors().InputErrors[index].String())
+				}
+				return
+			}
+
+			require.Nil(t, response.GetDeveloperErrors())
+			require.Equal(t, tc.expectedMembership, response.GetOperationsResults().Results[0].GetCheckResult().Membership)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Package embedded runs SpiceDB within a Go process, serving the v1 API over an
+// in-memory connection rather than network listeners.
+package embedded
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/authzed-go/v1"
+	"google.golang.org/grpc"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/cmd/util"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+const (
+	defaultMaxDepth                 = 50
+	defaultConcurrencyLimit         = 50
+	defaultMaximumUpdatesPerWrite   = 1000
+	defaultMaximumPreconditionCount = 1000
+	defaultGCWindow                 = 24 * time.Hour
+)
+
+//go:generate go run github.com/ecordell/optgen -output zz_generated.options.go . Config
+type Config struct {
+	// Datastore is the datastore backing the API. When unset, an in-memory datastore is
+	// created, and its contents are lost when the instance is closed.
+	Datastore datastore.Datastore
+
+	// RevisionQuantization and GCWindow configure the in-memory datastore created when
+	// no Datastore is given.
+	RevisionQuantization time.Duration
+	GCWindow             time.Duration
+
+	// Schema, if set, is written before the instance is returned.
+	Schema string
+
+	SchemaPrefixesRequired   bool
+	MaximumUpdatesPerWrite   uint16
+	MaximumPreconditionCount uint16
+	DispatchMaxDepth         uint32
+	DispatchConcurrencyLimit uint16
+
+	// ServerOptions are applied to the configuration of the server after the options
+	// above, for the settings this package does not expose. Options enabling network
+	// listeners defeat the purpose of this package.
+	ServerOptions []server.ConfigOption
+}
+
+// SpiceDB is a running embedded instance of SpiceDB.
+type SpiceDB struct {
+	conn   *grpc.ClientConn
+	client *authzed.Client
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New starts an embedded instance of SpiceDB, which serves requests until it is closed.
+//
+// The context bounds the startup of the instance only.
+func New(ctx context.Context, opts ...ConfigOption) (*SpiceDB, error) {
+	c := NewConfigWithOptions(
+		WithGCWindow(defaultGCWindow),
+		WithMaximumUpdatesPerWrite(defaultMaximumUpdatesPerWrite),
+		WithMaximumPreconditionCount(defaultMaximumPreconditionCount),
+		WithDispatchMaxDepth(defaultMaxDepth),
+		WithDispatchConcurrencyLimit(defaultConcurrencyLimit),
+	)
+	c = ConfigWithOptions(c, opts...)
+
+	ds := c.Datastore
+	if ds == nil {
+		var err error
+		ds, err = memdb.NewMemdbDatastore(0, c.RevisionQuantization, c.GCWindow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create datastore: %w", err)
+		}
+	}
+
+	serverConfig := server.NewConfigWithOptions(
+		server.WithDatastore(ds),
+		server.WithDispatcher(graph.NewLocalOnlyDispatcher(c.DispatchConcurrencyLimit)),
+		server.WithDispatchMaxDepth(c.DispatchMaxDepth),
+		server.WithDispatchConcurrencyLimit(c.DispatchConcurrencyLimit),
+		server.WithMaximumUpdatesPerWrite(c.MaximumUpdatesPerWrite),
+		server.WithMaximumPreconditionCount(c.MaximumPreconditionCount),
+		server.WithSchemaPrefixesRequired(c.SchemaPrefixesRequired),
+		server.WithExperimentalCaveatsEnabled(true),
+		server.WithGRPCServer(util.GRPCServerConfig{
+			Network: util.BufferedNetwork,
+			Enabled: true,
+		}),
+		// Requests never leave the process, so there is nothing to authenticate.
+		server.WithGRPCAuthFunc(func(ctx context.Context) (context.Context, error) {
+			return ctx, nil
+		}),
+		server.WithHTTPGateway(util.HTTPServerConfig{Enabled: false}),
+		server.WithDashboardAPI(util.HTTPServerConfig{Enabled: false}),
+		server.WithMetricsAPI(util.HTTPServerConfig{Enabled: false}),
+		server.WithDispatchServer(util.GRPCServerConfig{Enabled: false}),
+		server.WithSilentlyDisableTelemetry(true),
+	)
+	serverConfig = server.ConfigWithOptions(serverConfig, c.ServerOptions...)
+
+	srv, err := serverConfig.Complete()
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = srv.Run(runCtx)
+	}()
+
+	embedded := &SpiceDB{cancel: cancel, done: done}
+	embedded.conn, err = srv.GRPCDialContext(ctx, grpc.WithBlock())
+	if err != nil {
+		_ = embedded.Close()
+		return nil, fmt.Errorf("failed to connect to embedded server: %w", err)
+	}
+	embedded.client = &authzed.Client{
+		SchemaServiceClient:      v1.NewSchemaServiceClient(embedded.conn),
+		PermissionsServiceClient: v1.NewPermissionsServiceClient(embedded.conn),
+		WatchServiceClient:       v1.NewWatchServiceClient(embedded.conn),
+	}
+
+	if c.Schema != "" {
+		if _, err := embedded.client.WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: c.Schema}); err != nil {
+			_ = embedded.Close()
+			return nil, fmt.Errorf("failed to write schema: %w", err)
+		}
+	}
+
+	return embedded, nil
+}
+
+// Client returns a client of the v1 API of the instance.
+func (s *SpiceDB) Client() *authzed.Client {
+	return s.client
+}
+
+// Conn returns the in-memory connection to the instance, from which clients of the
+// other services it serves, such as the experimental or health services, can be built.
+func (s *SpiceDB) Conn() *grpc.ClientConn {
+	return s.conn
+}
+
+// Close stops the instance and closes its datastore, including a datastore given with
+// WithDatastore, once the requests in flight have completed.
+func (s *SpiceDB) Close() error {
+	var err error
+	if s.conn != nil {
+		err = s.conn.Close()
+	}
+	s.cancel()
+	<-s.done
+	return err
+}
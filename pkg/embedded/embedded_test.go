@@ -0,0 +1,78 @@
+package embedded_test
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/embedded"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const testSchema = `
+definition user {}
+
+definition document {
+	relation viewer: user
+	permission view = viewer
+}`
+
+func TestEmbedded(t *testing.T) {
+	ctx := context.Background()
+	spicedb, err := embedded.New(ctx, embedded.WithSchema(testSchema))
+	require.NoError(t, err)
+	client := spicedb.Client()
+
+	resp, err := client.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.MustParse("document:firstdoc#viewer@user:tom"))),
+		},
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		user     string
+		expected v1.CheckPermissionResponse_Permissionship
+	}{
+		{"tom", v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION},
+		{"sarah", v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION},
+	} {
+		checkResp, err := client.CheckPermission(ctx, &v1.CheckPermissionRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_AtLeastAsFresh{AtLeastAsFresh: resp.WrittenAt},
+			},
+			Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: "firstdoc"},
+			Permission: "view",
+			Subject:    &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: tc.user}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, checkResp.Permissionship, tc.user)
+	}
+
+	require.NoError(t, spicedb.Close())
+}
+
+func TestEmbeddedWithDatastore(t *testing.T) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	spicedb, err := embedded.New(ctx, embedded.WithDatastore(ds), embedded.WithSchema(testSchema))
+	require.NoError(t, err)
+
+	// The schema was written into the given datastore.
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(t, err)
+	_, _, err = ds.SnapshotReader(headRevision).ReadNamespace(ctx, "document")
+	require.NoError(t, err)
+
+	require.NoError(t, spicedb.Close())
+}
+
+func TestEmbeddedInvalidSchema(t *testing.T) {
+	_, err := embedded.New(context.Background(), embedded.WithSchema("definition user {"))
+	require.ErrorContains(t, err, "failed to write schema")
+}
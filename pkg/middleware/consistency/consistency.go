@@ -7,7 +7,36 @@ import (
 
 	"github.com/authzed/spicedb/internal/middleware/consistency"
 	"github.com/authzed/spicedb/pkg/datastore"
-	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+// RequestMaximumStaleness, if specified in the request header of a call with
+// minimize_latency consistency, is the maximum staleness the caller tolerates for the
+// revision of the call, as a duration such as `30s`.
+const RequestMaximumStaleness = consistency.RequestMaximumStaleness
+
+const (
+	// RequestSession, if specified in the request header of a call, places the call in a
+	// snapshot-consistent session, whose calls with no consistency or with minimize_latency
+	// consistency are all served at the same revision.
+	RequestSession = consistency.RequestSession
+
+	// NewSession is the value of the RequestSession request header starting a session.
+	NewSession = consistency.NewSession
+
+	// SessionHeader is the response header holding the session started by a call, to be
+	// specified in the RequestSession request header of the later calls of the session.
+	SessionHeader = consistency.SessionHeader
+)
+
+const (
+	// RequestRevisionSubstitution, if specified in the request header of a call at an
+	// exact snapshot or in a session whose revision has been garbage collected, serves the
+	// call at the nearest revision still available rather than failing it.
+	RequestRevisionSubstitution = consistency.RequestRevisionSubstitution
+
+	// RevisionSubstitutedHeader is the response header set on calls served at a revision
+	// substituted for the garbage collected one requested.
+	RevisionSubstitutedHeader = consistency.RevisionSubstitutedHeader
 )
 
 // RevisionFromContext reads the selected revision out of a context.Context and returns nil if it
@@ -19,10 +48,5 @@ func RevisionFromContext(ctx context.Context) datastore.Revision {
 // MustRevisionFromContext reads the selected revision out of a context.Context, computes a zedtoken
 // from it, and panics if it has not been set on the context.
 func MustRevisionFromContext(ctx context.Context) (datastore.Revision, *v1.ZedToken) {
-	rev := consistency.RevisionFromContext(ctx)
-	if rev == nil {
-		panic("consistency middleware did not inject revision")
-	}
-
-	return rev, zedtoken.NewFromRevision(rev)
+	return consistency.MustRevisionFromContext(ctx)
 }
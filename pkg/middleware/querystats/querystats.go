@@ -0,0 +1,19 @@
+package querystats
+
+import (
+	"github.com/authzed/spicedb/internal/middleware/querystats"
+)
+
+const (
+	// RequestQueryStatistics, if specified in the request header of a call, returns the
+	// statistics of its execution in the QueryStatisticsTrailer response trailer.
+	RequestQueryStatistics = querystats.RequestQueryStatistics
+
+	// QueryStatisticsTrailer is the response trailer holding the statistics of the
+	// execution of a call, as a JSON Summary.
+	QueryStatisticsTrailer = querystats.QueryStatisticsTrailer
+)
+
+// Summary summarizes the execution of a call, as returned in the QueryStatisticsTrailer
+// response trailer.
+type Summary = querystats.Summary
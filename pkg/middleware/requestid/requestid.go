@@ -55,6 +55,8 @@ func (r *handleRequestID) ServerReporter(ctx context.Context, _ interceptors.Cal
 		if haveRequestID {
 			requestID = requestIDs[0]
 		}
+	} else {
+		md = metadata.MD{}
 	}
 
 	if !haveRequestID && r.generateIfMissing {
@@ -83,6 +85,18 @@ func (r *handleRequestID) ServerReporter(ctx context.Context, _ interceptors.Cal
 	return interceptors.NoopReporter{}, ctx
 }
 
+// FromContext returns the request ID of the call being served by the context, if any.
+//
+// The request ID is read from the incoming metadata, into which the middleware injects
+// it when generating one, so it is also found on contexts derived from a dispatched call.
+func FromContext(ctx context.Context) (string, bool) {
+	requestIDs := metadata.ValueFromIncomingContext(ctx, RequestIDMetadataKey)
+	if len(requestIDs) == 0 || requestIDs[0] == "" {
+		return "", false
+	}
+	return requestIDs[0], true
+}
+
 // UnaryServerInterceptor returns a new interceptor which handles request IDs according
 // to the provided options.
 func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
@@ -55,6 +55,26 @@ type migration[C any, T any] struct {
 	upTx     TxMigrationFunc[T]
 }
 
+// LockEstimator returns a human readable estimate of the locks taken by a statement
+// executed by a migration, and their impact on concurrent reads and writes.
+type LockEstimator func(statement string) string
+
+// PlannedStatement is a statement which will be executed by a migration.
+type PlannedStatement struct {
+	SQL        string
+	LockImpact string
+}
+
+// PlannedMigration is a migration which will be run to reach a target revision.
+type PlannedMigration struct {
+	Version  string
+	Replaces string
+
+	// Statements are the statements executed by the migration, in order, or empty if
+	// the migration has not been described.
+	Statements []PlannedStatement
+}
+
 // Manager is used to manage a self-contained set of migrations. Standard usage
 // would be to instantiate one at the package level for a particular application
 // and then statically register migrations to the single instantiation in init
@@ -63,12 +83,17 @@ type migration[C any, T any] struct {
 // a database connection handler. This makes it possible for MigrationFunc to run without
 // having to abstract each connection handler behind a common interface.
 type Manager[D Driver[C, T], C any, T any] struct {
-	migrations map[string]migration[C, T]
+	migrations    map[string]migration[C, T]
+	statements    map[string][]string
+	lockEstimator LockEstimator
 }
 
 // NewManager creates a new empty instance of a migration manager.
 func NewManager[D Driver[C, T], C any, T any]() *Manager[D, C, T] {
-	return &Manager[D, C, T]{migrations: make(map[string]migration[C, T])}
+	return &Manager[D, C, T]{
+		migrations: make(map[string]migration[C, T]),
+		statements: make(map[string][]string),
+	}
 }
 
 // Register is used to associate a single migration with the migration engine.
@@ -96,25 +121,78 @@ func (m *Manager[D, C, T]) Register(version, replaces string, up MigrationFunc[C
 	return nil
 }
 
-// Run will actually perform the necessary migrations to bring the backing datastore
-// from its current revision to the specified revision.
-func (m *Manager[D, C, T]) Run(ctx context.Context, driver D, throughRevision string, dryRun RunType) error {
-	requestedRevision := throughRevision
+// Describe records the statements executed by a registered migration, for inclusion in
+// migration plans. Statements built at runtime should be described by their template.
+func (m *Manager[D, C, T]) Describe(version string, statements ...string) error {
+	if _, ok := m.migrations[version]; !ok {
+		return fmt.Errorf("unable to describe unknown revision: %s", version)
+	}
+
+	m.statements[version] = statements
+	return nil
+}
+
+// SetLockEstimator sets the function used to estimate the lock impact of the statements
+// in migration plans.
+func (m *Manager[D, C, T]) SetLockEstimator(estimator LockEstimator) {
+	m.lockEstimator = estimator
+}
+
+// Plan returns the ordered list of migrations which Run would execute to bring the
+// backing datastore from its current revision to the specified revision.
+func (m *Manager[D, C, T]) Plan(ctx context.Context, driver D, throughRevision string) ([]PlannedMigration, error) {
+	toRun, err := m.migrationsToRun(ctx, driver, throughRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	planned := make([]PlannedMigration, 0, len(toRun))
+	for _, migration := range toRun {
+		statements := make([]PlannedStatement, 0, len(m.statements[migration.version]))
+		for _, stmt := range m.statements[migration.version] {
+			statement := PlannedStatement{SQL: stmt}
+			if m.lockEstimator != nil {
+				statement.LockImpact = m.lockEstimator(stmt)
+			}
+			statements = append(statements, statement)
+		}
+
+		planned = append(planned, PlannedMigration{
+			Version:    migration.version,
+			Replaces:   migration.replaces,
+			Statements: statements,
+		})
+	}
+	return planned, nil
+}
+
+func (m *Manager[D, C, T]) migrationsToRun(ctx context.Context, driver D, throughRevision string) ([]migration[C, T], error) {
 	starting, err := driver.Version(ctx)
 	if err != nil {
-		return fmt.Errorf("unable to compute target revision: %w", err)
+		return nil, fmt.Errorf("unable to compute target revision: %w", err)
 	}
 
 	if strings.ToLower(throughRevision) == Head {
 		throughRevision, err = m.HeadRevision()
 		if err != nil {
-			return fmt.Errorf("unable to compute head revision: %w", err)
+			return nil, fmt.Errorf("unable to compute head revision: %w", err)
 		}
 	}
 
 	toRun, err := collectMigrationsInRange(starting, throughRevision, m.migrations)
 	if err != nil {
-		return fmt.Errorf("unable to compute migration list: %w", err)
+		return nil, fmt.Errorf("unable to compute migration list: %w", err)
+	}
+	return toRun, nil
+}
+
+// Run will actually perform the necessary migrations to bring the backing datastore
+// from its current revision to the specified revision.
+func (m *Manager[D, C, T]) Run(ctx context.Context, driver D, throughRevision string, dryRun RunType) error {
+	requestedRevision := throughRevision
+	toRun, err := m.migrationsToRun(ctx, driver, throughRevision)
+	if err != nil {
+		return err
 	}
 	if len(toRun) == 0 {
 		log.Info().Str("targetRevision", requestedRevision).Msg("server already at requested revision")
@@ -217,3 +217,35 @@ var missingEarlyMigrations = map[string]migration[fakeConnPool, fakeTx]{
 	"789": {"789", "456", noNonatomicMigration, noTxMigration},
 	"10":  {"10", "789", noNonatomicMigration, noTxMigration},
 }
+
+func TestPlan(t *testing.T) {
+	req := require.New(t)
+	m := NewManager[Driver[fakeConnPool, fakeTx], fakeConnPool, fakeTx]()
+	m.SetLockEstimator(func(statement string) string {
+		return "locks for " + statement
+	})
+
+	req.NoError(m.Register("1", "", noNonatomicMigration, noTxMigration))
+	req.NoError(m.Register("2", "1", noNonatomicMigration, noTxMigration))
+	req.NoError(m.Register("3", "2", noNonatomicMigration, noTxMigration))
+	req.NoError(m.Describe("2", "CREATE TABLE two", "CREATE INDEX two_idx"))
+	req.Error(m.Describe("4", "CREATE TABLE four"))
+
+	plan, err := m.Plan(context.Background(), &fakeDriver{currentVersion: "1"}, Head)
+	req.NoError(err)
+	req.Equal([]PlannedMigration{
+		{
+			Version:  "2",
+			Replaces: "1",
+			Statements: []PlannedStatement{
+				{"CREATE TABLE two", "locks for CREATE TABLE two"},
+				{"CREATE INDEX two_idx", "locks for CREATE INDEX two_idx"},
+			},
+		},
+		{Version: "3", Replaces: "2", Statements: []PlannedStatement{}},
+	}, plan)
+
+	plan, err = m.Plan(context.Background(), &fakeDriver{currentVersion: "3"}, Head)
+	req.NoError(err)
+	req.Empty(plan)
+}
@@ -0,0 +1,214 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/authzed/spicedb/internal/caveats"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/caveats/types"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// ChangeKind is the kind of a change between two schemas.
+type ChangeKind string
+
+const (
+	// DefinitionAdded is an object definition added.
+	DefinitionAdded ChangeKind = "definition-added"
+
+	// DefinitionRemoved is an object definition removed.
+	DefinitionRemoved ChangeKind = "definition-removed"
+
+	// RelationAdded is a relation added to an object definition.
+	RelationAdded ChangeKind = "relation-added"
+
+	// RelationRemoved is a relation removed from an object definition.
+	RelationRemoved ChangeKind = "relation-removed"
+
+	// RelationAllowedTypeAdded is a subject type allowed on a relation.
+	RelationAllowedTypeAdded ChangeKind = "relation-allowed-type-added"
+
+	// RelationAllowedTypeRemoved is a subject type no longer allowed on a relation.
+	RelationAllowedTypeRemoved ChangeKind = "relation-allowed-type-removed"
+
+	// PermissionAdded is a permission added to an object definition.
+	PermissionAdded ChangeKind = "permission-added"
+
+	// PermissionRemoved is a permission removed from an object definition.
+	PermissionRemoved ChangeKind = "permission-removed"
+
+	// PermissionChanged is a permission whose expression changed.
+	PermissionChanged ChangeKind = "permission-changed"
+
+	// CaveatAdded is a caveat definition added.
+	CaveatAdded ChangeKind = "caveat-added"
+
+	// CaveatRemoved is a caveat definition removed.
+	CaveatRemoved ChangeKind = "caveat-removed"
+
+	// CaveatParameterAdded is a parameter added to a caveat.
+	CaveatParameterAdded ChangeKind = "caveat-parameter-added"
+
+	// CaveatParameterRemoved is a parameter removed from a caveat.
+	CaveatParameterRemoved ChangeKind = "caveat-parameter-removed"
+
+	// CaveatParameterTypeChanged is a parameter of a caveat whose type changed.
+	CaveatParameterTypeChanged ChangeKind = "caveat-parameter-type-changed"
+)
+
+// Change is a change between two schemas.
+type Change struct {
+	// Kind is the kind of the change.
+	Kind ChangeKind
+
+	// Definition is the name of the object definition changed, for changes of object
+	// definitions.
+	Definition string
+
+	// Relation is the name of the relation or permission changed, if any.
+	Relation string
+
+	// AllowedType is the subject type allowed or no longer allowed on the relation, such
+	// as `user`, `user:*` or `group#member`, if any.
+	AllowedType string
+
+	// Caveat is the name of the caveat definition changed, for changes of caveats.
+	Caveat string
+
+	// Parameter is the name of the parameter of the caveat changed, if any.
+	Parameter string
+
+	// PreviousType and CurrentType are the types of the parameter of the caveat before
+	// and after the change, if any.
+	PreviousType string
+	CurrentType  string
+}
+
+// Diff returns the changes from the existing schema to the updated one, ordered by the
+// name of the caveat or object definition changed, caveats first. Changes to the
+// comments of definitions are not reported.
+func Diff(existing, updated *Schema) ([]Change, error) {
+	var changes []Change
+
+	existingCaveats, updatedCaveats := caveatsByName(existing), caveatsByName(updated)
+	for _, name := range unionOfNames(existingCaveats, updatedCaveats) {
+		diff, err := caveats.DiffCaveats(existingCaveats[name], updatedCaveats[name])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, delta := range diff.Deltas() {
+			change := Change{Caveat: name, Parameter: delta.ParameterName}
+			switch delta.Type {
+			case caveats.CaveatAdded:
+				change.Kind = CaveatAdded
+			case caveats.CaveatRemoved:
+				change.Kind = CaveatRemoved
+			case caveats.AddedParameter:
+				change.Kind = CaveatParameterAdded
+			case caveats.RemovedParameter:
+				change.Kind = CaveatParameterRemoved
+			case caveats.ParameterTypeChanged:
+				change.Kind = CaveatParameterTypeChanged
+			default:
+				return nil, fmt.Errorf("unknown change of caveat `%s`: %s", name, delta.Type)
+			}
+
+			if change.PreviousType, err = parameterType(delta.PreviousType); err != nil {
+				return nil, err
+			}
+			if change.CurrentType, err = parameterType(delta.CurrentType); err != nil {
+				return nil, err
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	existingDefs, updatedDefs := definitionsByName(existing), definitionsByName(updated)
+	for _, name := range unionOfNames(existingDefs, updatedDefs) {
+		diff, err := namespace.DiffNamespaces(existingDefs[name], updatedDefs[name])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, delta := range diff.Deltas() {
+			change := Change{Definition: name, Relation: delta.RelationName}
+			switch delta.Type {
+			case namespace.NamespaceAdded:
+				change.Kind = DefinitionAdded
+			case namespace.NamespaceRemoved:
+				change.Kind = DefinitionRemoved
+			case namespace.AddedRelation:
+				change.Kind = RelationAdded
+			case namespace.RemovedRelation:
+				change.Kind = RelationRemoved
+			case namespace.AddedPermission:
+				change.Kind = PermissionAdded
+			case namespace.RemovedPermission:
+				change.Kind = PermissionRemoved
+			case namespace.ChangedPermissionImpl, namespace.LegacyChangedRelationImpl:
+				change.Kind = PermissionChanged
+			case namespace.RelationAllowedTypeAdded:
+				change.Kind = RelationAllowedTypeAdded
+			case namespace.RelationAllowedTypeRemoved:
+				change.Kind = RelationAllowedTypeRemoved
+			default:
+				return nil, fmt.Errorf("unknown change of definition `%s`: %s", name, delta.Type)
+			}
+
+			if delta.AllowedType != nil {
+				change.AllowedType = namespace.SourceForAllowedRelation(delta.AllowedType)
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+func caveatsByName(s *Schema) map[string]*core.CaveatDefinition {
+	byName := make(map[string]*core.CaveatDefinition)
+	if s != nil {
+		for _, caveat := range s.CaveatDefinitions {
+			byName[caveat.Name] = caveat
+		}
+	}
+	return byName
+}
+
+func definitionsByName(s *Schema) map[string]*core.NamespaceDefinition {
+	byName := make(map[string]*core.NamespaceDefinition)
+	if s != nil {
+		for _, def := range s.ObjectDefinitions {
+			byName[def.Name] = def
+		}
+	}
+	return byName
+}
+
+func unionOfNames[T any](existing, updated map[string]T) []string {
+	names := make([]string, 0, len(existing)+len(updated))
+	for name := range existing {
+		names = append(names, name)
+	}
+	for name := range updated {
+		if _, ok := existing[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parameterType(ref *core.CaveatTypeReference) (string, error) {
+	if ref == nil {
+		return "", nil
+	}
+
+	decoded, err := types.DecodeParameterType(ref)
+	if err != nil {
+		return "", err
+	}
+	return decoded.String(), nil
+}
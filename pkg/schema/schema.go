@@ -0,0 +1,201 @@
+// Package schema compiles, validates and compares SpiceDB schemas, for tools which
+// manage schemas outside of SpiceDB, such as infrastructure providers and linters run
+// in CI.
+//
+// Unlike the packages it is built on, this package is a stable API: its exported
+// identifiers are only removed or changed incompatibly in a new major version of
+// SpiceDB, and schemas which it accepts are accepted by WriteSchema of the same
+// version.
+package schema
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/internal/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+// CompilationError is the error returned by Compile for a schema which does not parse or
+// compile, holding the position of the error in the schema text.
+type CompilationError = compiler.ErrorWithContext
+
+// Schema is a compiled schema.
+type Schema struct {
+	// ObjectDefinitions are the object definitions of the schema, in the order defined.
+	ObjectDefinitions []*core.NamespaceDefinition
+
+	// CaveatDefinitions are the caveat definitions of the schema, in the order defined.
+	CaveatDefinitions []*core.CaveatDefinition
+}
+
+type compileOptions struct {
+	source           string
+	objectTypePrefix string
+}
+
+// CompileOption configures the compilation of a schema.
+type CompileOption func(*compileOptions)
+
+// WithSourceName sets the name of the source of the schema, such as the path of the
+// file from which it was read, as reported in compilation errors.
+//
+// default: "schema"
+func WithSourceName(name string) CompileOption {
+	return func(o *compileOptions) {
+		o.source = name
+	}
+}
+
+// WithObjectTypePrefix sets the prefix of the object types and caveats of the schema
+// whose names have none, as with `prefix/document`.
+//
+// default: none
+func WithObjectTypePrefix(prefix string) CompileOption {
+	return func(o *compileOptions) {
+		o.objectTypePrefix = prefix
+	}
+}
+
+// Compile compiles the text of a schema. Errors in the schema are returned as a
+// *CompilationError. A compiled schema has not yet been validated.
+func Compile(schemaText string, opts ...CompileOption) (*Schema, error) {
+	o := &compileOptions{source: "schema"}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source:       input.Source(o.source),
+		SchemaString: schemaText,
+	}, &o.objectTypePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schema{
+		ObjectDefinitions: compiled.ObjectDefinitions,
+		CaveatDefinitions: compiled.CaveatDefinitions,
+	}, nil
+}
+
+// String returns the schema as canonically formatted schema text.
+func (s *Schema) String() string {
+	definitions := make([]compiler.SchemaDefinition, 0, len(s.CaveatDefinitions)+len(s.ObjectDefinitions))
+	for _, caveat := range s.CaveatDefinitions {
+		definitions = append(definitions, caveat)
+	}
+	for _, def := range s.ObjectDefinitions {
+		definitions = append(definitions, def)
+	}
+
+	text, _ := generator.GenerateSchema(definitions)
+	return text
+}
+
+// Resolver resolves the definitions referenced by a schema but defined outside of it,
+// such as those already written to a SpiceDB when validating a schema which extends
+// its schema.
+type Resolver interface {
+	// LookupDefinition returns the object definition with the name, or nil if there is
+	// none.
+	LookupDefinition(ctx context.Context, name string) (*core.NamespaceDefinition, error)
+
+	// LookupCaveat returns the caveat definition with the name, or nil if there is none.
+	LookupCaveat(ctx context.Context, name string) (*core.CaveatDefinition, error)
+}
+
+// Validate validates the definitions of a compiled schema as WriteSchema does, checking
+// that the relations, permissions and caveats they reference exist and that their
+// expressions are well typed. Definitions referenced but not defined in the schema are
+// looked up with the resolver, which may be nil for a schema defining all of them.
+//
+// Validation annotates the definitions of the schema with the type information stored
+// by SpiceDB along with them.
+func Validate(ctx context.Context, s *Schema, resolver Resolver) error {
+	for _, caveat := range s.CaveatDefinitions {
+		if err := namespace.ValidateCaveatDefinition(caveat); err != nil {
+			return err
+		}
+	}
+
+	predefined := namespace.PredefinedElements{
+		Namespaces: s.ObjectDefinitions,
+		Caveats:    s.CaveatDefinitions,
+	}
+
+	var nsResolver namespace.Resolver = namespace.ResolverForPredefinedDefinitions(predefined)
+	if resolver != nil {
+		nsResolver = resolverAdapter{resolver, predefined}
+	}
+
+	for _, def := range s.ObjectDefinitions {
+		ts, err := namespace.NewNamespaceTypeSystem(def, nsResolver)
+		if err != nil {
+			return err
+		}
+
+		vts, err := ts.Validate(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := namespace.AnnotateNamespace(vts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolverAdapter resolves the definitions of a schema, and those outside of it with a
+// Resolver.
+type resolverAdapter struct {
+	resolver   Resolver
+	predefined namespace.PredefinedElements
+}
+
+func (ra resolverAdapter) LookupNamespace(ctx context.Context, name string) (*core.NamespaceDefinition, error) {
+	for _, def := range ra.predefined.Namespaces {
+		if def.Name == name {
+			return def, nil
+		}
+	}
+
+	def, err := ra.resolver.LookupDefinition(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if def == nil {
+		return nil, namespace.NewNamespaceNotFoundErr(name)
+	}
+	return def, nil
+}
+
+func (ra resolverAdapter) LookupCaveat(ctx context.Context, name string) (*core.CaveatDefinition, error) {
+	for _, caveat := range ra.predefined.Caveats {
+		if caveat.Name == name {
+			return caveat, nil
+		}
+	}
+
+	caveat, err := ra.resolver.LookupCaveat(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if caveat == nil {
+		return nil, namespace.NewCaveatNotFoundErr(name)
+	}
+	return caveat, nil
+}
+
+func (ra resolverAdapter) WithPredefinedElements(predefined namespace.PredefinedElements) namespace.Resolver {
+	return resolverAdapter{
+		resolver: ra.resolver,
+		predefined: namespace.PredefinedElements{
+			Namespaces: append(append([]*core.NamespaceDefinition{}, predefined.Namespaces...), ra.predefined.Namespaces...),
+			Caveats:    append(append([]*core.CaveatDefinition{}, predefined.Caveats...), ra.predefined.Caveats...),
+		},
+	}
+}
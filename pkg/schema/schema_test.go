@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+type mapResolver map[string]*core.NamespaceDefinition
+
+func (mr mapResolver) LookupDefinition(_ context.Context, name string) (*core.NamespaceDefinition, error) {
+	return mr[name], nil
+}
+
+func (mr mapResolver) LookupCaveat(context.Context, string) (*core.CaveatDefinition, error) {
+	return nil, nil
+}
+
+func TestCompile(t *testing.T) {
+	_, err := Compile("definition document {", WithSourceName("schema.zed"))
+	var compilationErr CompilationError
+	require.ErrorAs(t, err, &compilationErr)
+	require.Equal(t, "schema.zed", string(compilationErr.Source))
+
+	compiled, err := Compile("definition user {}\n\ndefinition document {\n\trelation viewer: user\n}", WithObjectTypePrefix("acme"))
+	require.NoError(t, err)
+	require.Len(t, compiled.ObjectDefinitions, 2)
+	require.Equal(t, "acme/document", compiled.ObjectDefinitions[1].Name)
+	require.Equal(t, "definition acme/user {}\n\ndefinition acme/document {\n\trelation viewer: acme/user\n}", compiled.String())
+}
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+
+	compiled, err := Compile(`definition user {}
+
+caveat is_weekday(day string) {
+	day != "saturday" && day != "sunday"
+}
+
+definition document {
+	relation viewer: user with is_weekday
+	permission view = viewer
+}`)
+	require.NoError(t, err)
+	require.NoError(t, Validate(ctx, compiled, nil))
+
+	compiled, err = Compile("definition document {\n\tpermission view = viewer\n}")
+	require.NoError(t, err)
+	require.ErrorContains(t, Validate(ctx, compiled, nil), "viewer")
+
+	// Definitions outside of the schema are looked up with the resolver.
+	compiled, err = Compile("definition document {\n\trelation viewer: user\n}")
+	require.NoError(t, err)
+	require.Error(t, Validate(ctx, compiled, nil))
+	require.Error(t, Validate(ctx, compiled, mapResolver{}))
+	require.NoError(t, Validate(ctx, compiled, mapResolver{"user": ns.Namespace("user")}))
+}
+
+func TestDiff(t *testing.T) {
+	existing, err := Compile(`definition user {}
+
+caveat only_on(day string) {
+	day == "monday"
+}
+
+definition document {
+	relation viewer: user
+	relation editor: user
+	permission view = viewer + editor
+}`)
+	require.NoError(t, err)
+
+	updated, err := Compile(`definition user {}
+
+caveat only_on(day int) {
+	day == 1
+}
+
+definition group {
+	relation member: user
+}
+
+definition document {
+	relation viewer: user | group#member
+	permission view = viewer
+}`)
+	require.NoError(t, err)
+
+	changes, err := Diff(existing, updated)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []Change{
+		{Kind: CaveatParameterTypeChanged, Caveat: "only_on", Parameter: "day", PreviousType: "string", CurrentType: "int"},
+		{Kind: RelationRemoved, Definition: "document", Relation: "editor"},
+		{Kind: PermissionChanged, Definition: "document", Relation: "view"},
+		{Kind: RelationAllowedTypeAdded, Definition: "document", Relation: "viewer", AllowedType: "group#member"},
+		{Kind: DefinitionAdded, Definition: "group"},
+	}, changes)
+
+	changes, err = Diff(nil, existing)
+	require.NoError(t, err)
+	require.Equal(t, CaveatAdded, changes[0].Kind)
+}
@@ -0,0 +1,294 @@
+// Package schemacontroller implements a Kubernetes controller which writes the schema held
+// in a SpiceDBSchema custom resource to SpiceDB, so that schemas can be managed with GitOps.
+package schemacontroller
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/authzed/spicedb/internal/caveats"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/schemacheck"
+)
+
+// CustomResourceDefinition is the manifest of the SpiceDBSchema custom resource definition,
+// which must be applied to the cluster before the controller is run.
+//
+//go:embed crd.yaml
+var CustomResourceDefinition string
+
+// GroupVersionResource identifies SpiceDBSchema resources.
+var GroupVersionResource = schema.GroupVersionResource{
+	Group:    "authzed.com",
+	Version:  "v1alpha1",
+	Resource: "spicedbschemas",
+}
+
+// Phase is the outcome of the last reconciliation of a SpiceDBSchema.
+type Phase string
+
+const (
+	// PhaseApplied means the schema in SpiceDB matches the resource.
+	PhaseApplied Phase = "Applied"
+
+	// PhaseBlocked means the schema was not written because it contains destructive
+	// changes and the resource does not allow them.
+	PhaseBlocked Phase = "Blocked"
+
+	// PhaseInvalid means the schema of the resource does not compile.
+	PhaseInvalid Phase = "Invalid"
+
+	// PhaseFailed means SpiceDB could not be read or refused to write the schema, such as
+	// when a removed relation still has relationships. Failed writes are retried.
+	PhaseFailed Phase = "Failed"
+)
+
+// Spec is the desired state of a SpiceDBSchema.
+type Spec struct {
+	Schema                  string `json:"schema"`
+	AllowDestructiveChanges bool   `json:"allowDestructiveChanges,omitempty"`
+}
+
+// Status is the observed state of a SpiceDBSchema.
+type Status struct {
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"`
+	Phase              Phase  `json:"phase,omitempty"`
+	Message            string `json:"message,omitempty"`
+
+	// Changes lists the changes from the schema in SpiceDB, such as
+	// `removed-relation document#reader`, which were written, or which blocked the write.
+	Changes []string `json:"changes,omitempty"`
+
+	// LastAppliedTime is the time the schema was last written.
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+}
+
+// destructiveChanges are the types of changes which can make existing relationships or
+// caveat contexts invalid.
+var destructiveChanges = map[string]struct{}{
+	string(namespace.NamespaceRemoved):           {},
+	string(namespace.RemovedRelation):            {},
+	string(namespace.RemovedPermission):          {},
+	string(namespace.RelationAllowedTypeRemoved): {},
+	string(caveats.CaveatRemoved):                {},
+	string(caveats.RemovedParameter):             {},
+	string(caveats.ParameterTypeChanged):         {},
+}
+
+// Options configures a Controller.
+type Options struct {
+	// Namespace and Name identify the SpiceDBSchema resource which is reconciled. SpiceDB
+	// has a single schema, so a controller only manages a single resource.
+	Namespace string
+	Name      string
+
+	// ResyncPeriod is how often the resource is reconciled when it has not changed, which
+	// reverts changes made to the schema outside of the resource.
+	ResyncPeriod time.Duration
+}
+
+// Controller writes the schema of a SpiceDBSchema resource to SpiceDB whenever the
+// resource changes, and reports the outcome in the status of the resource.
+type Controller struct {
+	resources dynamic.Interface
+	schemas   v1.SchemaServiceClient
+	options   Options
+	now       func() time.Time
+}
+
+// New returns a controller which reads resources with the given client and writes schemas
+// with the given schema service client.
+func New(resources dynamic.Interface, schemas v1.SchemaServiceClient, options Options) *Controller {
+	return &Controller{resources: resources, schemas: schemas, options: options, now: time.Now}
+}
+
+// Run reconciles the resource until the context is canceled. Failed reconciliations are
+// retried with an exponential backoff.
+func (c *Controller) Run(ctx context.Context) error {
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(c.resources, c.options.ResyncPeriod, c.options.Namespace, func(opts *metav1.ListOptions) {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", c.options.Name).String()
+	})
+	informer := factory.ForResource(GroupVersionResource)
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	enqueue := func(obj any) {
+		if key, err := cache.MetaNamespaceKeyFunc(obj); err == nil {
+			queue.Add(key)
+		}
+	}
+	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, obj any) { enqueue(obj) },
+	})
+
+	factory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.Informer().HasSynced) {
+		return fmt.Errorf("unable to sync the cache of %s: %w", GroupVersionResource.Resource, ctx.Err())
+	}
+	log.Ctx(ctx).Info().Str("namespace", c.options.Namespace).Str("name", c.options.Name).Msg("schema controller started")
+
+	go func() {
+		<-ctx.Done()
+		queue.ShutDown()
+	}()
+
+	for {
+		key, shutdown := queue.Get()
+		if shutdown {
+			return nil
+		}
+
+		obj, exists, err := informer.Informer().GetStore().GetByKey(key.(string))
+		if err == nil && exists {
+			err = c.Reconcile(ctx, obj.(*unstructured.Unstructured))
+		}
+		if err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("key", key.(string)).Msg("failed to reconcile schema; retrying")
+			queue.AddRateLimited(key)
+		} else {
+			queue.Forget(key)
+		}
+		queue.Done(key)
+	}
+}
+
+// Reconcile writes the schema of the resource to SpiceDB, unless it matches the schema in
+// SpiceDB or contains destructive changes the resource does not allow, and updates the
+// status of the resource. An error is returned if the reconciliation should be retried.
+func (c *Controller) Reconcile(ctx context.Context, obj *unstructured.Unstructured) error {
+	var spec Spec
+	if specObj, ok := obj.Object["spec"].(map[string]any); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specObj, &spec); err != nil {
+			return fmt.Errorf("unable to read spec: %w", err)
+		}
+	}
+
+	var existingStatus Status
+	if statusObj, ok := obj.Object["status"].(map[string]any); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(statusObj, &existingStatus); err != nil {
+			return fmt.Errorf("unable to read status: %w", err)
+		}
+	}
+
+	updated, reconcileErr := c.reconcile(ctx, spec, existingStatus)
+	updated.ObservedGeneration = obj.GetGeneration()
+	if reflect.DeepEqual(updated, existingStatus) {
+		return reconcileErr
+	}
+
+	statusObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&updated)
+	if err != nil {
+		return err
+	}
+	obj = obj.DeepCopy()
+	obj.Object["status"] = statusObj
+	if _, err := c.resources.Resource(GroupVersionResource).Namespace(obj.GetNamespace()).UpdateStatus(ctx, obj, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to update status: %w", err)
+	}
+
+	log.Ctx(ctx).Info().
+		Str("phase", string(updated.Phase)).
+		Str("message", updated.Message).
+		Strs("changes", updated.Changes).
+		Msg("reconciled schema")
+	return reconcileErr
+}
+
+func (c *Controller) reconcile(ctx context.Context, spec Spec, existingStatus Status) (Status, error) {
+	// Keep the record of the last write, which is still accurate if nothing is written.
+	updated := Status{LastAppliedTime: existingStatus.LastAppliedTime}
+
+	result, err := schemacheck.Check(ctx, spec.Schema)
+	if err != nil {
+		return failed(updated, err), err
+	}
+	if result.HasErrors() {
+		for _, diagnostic := range result.Diagnostics {
+			if diagnostic.Severity == schemacheck.SeverityError {
+				updated.Phase = PhaseInvalid
+				updated.Message = diagnostic.Message
+				return updated, nil
+			}
+		}
+	}
+
+	var existing string
+	resp, err := c.schemas.ReadSchema(ctx, &v1.ReadSchemaRequest{})
+	switch {
+	case status.Code(err) == codes.NotFound:
+	case err != nil:
+		return failed(updated, err), err
+	default:
+		existing = resp.SchemaText
+	}
+
+	changes, err := schemacheck.Diff(existing, spec.Schema)
+	if err != nil {
+		return failed(updated, err), err
+	}
+
+	var destructive []string
+	for _, change := range changes {
+		formatted := formatChange(change)
+		updated.Changes = append(updated.Changes, formatted)
+		if _, ok := destructiveChanges[change.Type]; ok {
+			destructive = append(destructive, formatted)
+		}
+	}
+
+	if len(changes) == 0 {
+		updated.Phase = PhaseApplied
+		return updated, nil
+	}
+	if len(destructive) > 0 && !spec.AllowDestructiveChanges {
+		updated.Phase = PhaseBlocked
+		updated.Message = fmt.Sprintf("the schema contains destructive changes (%s); set spec.allowDestructiveChanges to write it", strings.Join(destructive, ", "))
+		return updated, nil
+	}
+
+	_, err = c.schemas.WriteSchema(ctx, &v1.WriteSchemaRequest{Schema: spec.Schema})
+	if err != nil {
+		return failed(updated, err), err
+	}
+
+	now := metav1.NewTime(c.now())
+	updated.Phase = PhaseApplied
+	updated.LastAppliedTime = &now
+	return updated, nil
+}
+
+func failed(updated Status, err error) Status {
+	updated.Phase = PhaseFailed
+	updated.Message = err.Error()
+	if s, ok := status.FromError(err); ok {
+		updated.Message = s.Message()
+	}
+	return updated
+}
+
+func formatChange(change schemacheck.Change) string {
+	if change.Name == "" {
+		return change.Type + " " + change.Definition
+	}
+	return change.Type + " " + change.Definition + "#" + change.Name
+}
@@ -0,0 +1,170 @@
+package schemacontroller_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/schemacheck"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/schemacontroller"
+)
+
+const (
+	initialSchema = `definition user {}
+
+definition document {
+	relation reader: user
+	relation writer: user
+	permission view = reader + writer
+}`
+
+	withoutWriterSchema = `definition user {}
+
+definition document {
+	relation reader: user
+	permission view = reader
+}`
+)
+
+func newResource(generation int64, spec map[string]any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "authzed.com/v1alpha1",
+		"kind":       "SpiceDBSchema",
+		"metadata": map[string]any{
+			"namespace": "default",
+			"name":      "spicedb",
+		},
+		"spec": spec,
+	}}
+	obj.SetGeneration(generation)
+	return obj
+}
+
+func newFakeClient(objects ...runtime.Object) *fake.FakeDynamicClient {
+	return fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		schemacontroller.GroupVersionResource: "SpiceDBSchemaList",
+	}, objects...)
+}
+
+func readStatus(t *testing.T, client *fake.FakeDynamicClient) schemacontroller.Status {
+	obj, err := client.Resource(schemacontroller.GroupVersionResource).Namespace("default").Get(context.Background(), "spicedb", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	var status schemacontroller.Status
+	statusObj, _, err := unstructured.NestedMap(obj.Object, "status")
+	require.NoError(t, err)
+	require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(statusObj, &status))
+	return status
+}
+
+func TestReconcile(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+	schemaClient := v1.NewSchemaServiceClient(conn)
+
+	// The steps share the server, so each starts from the schema written by the last.
+	steps := []struct {
+		name            string
+		spec            map[string]any
+		expectedPhase   schemacontroller.Phase
+		expectedChanges []string
+		expectedSchema  string
+	}{
+		{
+			"initial schema is written",
+			map[string]any{"schema": initialSchema},
+			schemacontroller.PhaseApplied,
+			[]string{"namespace-added document", "namespace-added user"},
+			initialSchema,
+		},
+		{
+			"unchanged schema is not written",
+			map[string]any{"schema": initialSchema},
+			schemacontroller.PhaseApplied,
+			nil,
+			initialSchema,
+		},
+		{
+			"destructive change is blocked",
+			map[string]any{"schema": withoutWriterSchema},
+			schemacontroller.PhaseBlocked,
+			[]string{"removed-relation document#writer", "changed-permission-implementation document#view"},
+			initialSchema,
+		},
+		{
+			"invalid schema is not written",
+			map[string]any{"schema": "definition document { relation reader: unknown }"},
+			schemacontroller.PhaseInvalid,
+			nil,
+			initialSchema,
+		},
+		{
+			"allowed destructive change is written",
+			map[string]any{"schema": withoutWriterSchema, "allowDestructiveChanges": true},
+			schemacontroller.PhaseApplied,
+			[]string{"removed-relation document#writer", "changed-permission-implementation document#view"},
+			withoutWriterSchema,
+		},
+	}
+
+	for i, step := range steps {
+		step := step
+		generation := int64(i + 1)
+		t.Run(step.name, func(t *testing.T) {
+			resource := newResource(generation, step.spec)
+			client := newFakeClient(resource)
+			controller := schemacontroller.New(client, schemaClient, schemacontroller.Options{Namespace: "default", Name: "spicedb"})
+			require.NoError(t, controller.Reconcile(context.Background(), resource))
+
+			status := readStatus(t, client)
+			require.Equal(t, step.expectedPhase, status.Phase, status.Message)
+			require.Equal(t, generation, status.ObservedGeneration)
+			require.ElementsMatch(t, step.expectedChanges, status.Changes)
+			if step.expectedPhase != schemacontroller.PhaseApplied {
+				require.NotEmpty(t, status.Message)
+			}
+
+			resp, err := schemaClient.ReadSchema(context.Background(), &v1.ReadSchemaRequest{})
+			require.NoError(t, err)
+			changes, err := schemacheck.Diff(resp.SchemaText, step.expectedSchema)
+			require.NoError(t, err)
+			require.Empty(t, changes)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	client := newFakeClient(newResource(1, map[string]any{"schema": initialSchema}))
+	controller := schemacontroller.New(client, v1.NewSchemaServiceClient(conn), schemacontroller.Options{
+		Namespace:    "default",
+		Name:         "spicedb",
+		ResyncPeriod: time.Minute,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- controller.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return readStatus(t, client).Phase == schemacontroller.PhaseApplied
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
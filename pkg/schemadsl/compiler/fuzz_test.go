@@ -0,0 +1,72 @@
+package compiler_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+var fuzzSeeds = []string{
+	``,
+	`definition user {}`,
+	`/** a user */
+definition user {}
+
+definition group {
+	relation member: user | group#member | user:*
+}
+
+definition document {
+	relation parent: document
+	relation owner: user
+	relation viewer: user | group#member
+	relation banned: user
+
+	// Editors and viewers, less anyone banned.
+	permission edit = owner
+	permission view = (viewer + edit + parent->view) - banned
+	permission both = viewer & owner
+	permission nothing = nil
+}`,
+	`caveat only_on(day int, allowed list<int>) {
+	day in allowed && day != 0
+}
+
+definition user {}
+
+definition document {
+	relation viewer: user with only_on
+}`,
+	`definition tenant/user {}`,
+	`definition document { relation viewer: user permission view = viewer->`,
+}
+
+func FuzzCompile(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	emptyPrefix := ""
+	f.Fuzz(func(t *testing.T, schema string) {
+		compiled, err := compiler.Compile(compiler.InputSchema{
+			Source:       input.Source("schema"),
+			SchemaString: schema,
+		}, &emptyPrefix)
+		if err != nil {
+			return
+		}
+
+		// Anything which compiles must generate a schema which compiles to the same definitions.
+		generated, _ := generator.GenerateSchema(compiled.OrderedDefinitions)
+		recompiled, err := compiler.Compile(compiler.InputSchema{
+			Source:       input.Source("generated"),
+			SchemaString: generated,
+		}, &emptyPrefix)
+		require.NoError(t, err, "unable to recompile generated schema:\n%s", generated)
+		require.Len(t, recompiled.OrderedDefinitions, len(compiled.OrderedDefinitions))
+	})
+}
@@ -222,6 +222,7 @@ func translateObjectDefinition(tctx translationContext, defNode *dslNode) (*core
 	if len(relationsAndPermissions) == 0 {
 		ns := namespace.Namespace(nspath)
 		ns.Metadata = addComments(ns.Metadata, defNode)
+		ns.SourcePosition = getSourcePosition(defNode, tctx.mapper)
 
 		err = ns.Validate()
 		if err != nil {
@@ -161,20 +161,16 @@ func (l *Lexer) errorf(currentRune rune, format string, args ...interface{}) sta
 
 // peekValue looks forward for the given value string. If found, returns true.
 func (l *Lexer) peekValue(value string) bool {
-	for index, runeValue := range value {
-		r := l.next()
-		if r != runeValue {
-			for j := 0; j <= index; j++ {
-				l.backup()
-			}
+	pos, width := l.pos, l.width
+	defer func() {
+		l.pos, l.width = pos, width
+	}()
+
+	for _, runeValue := range value {
+		if l.next() != runeValue {
 			return false
 		}
 	}
-
-	for i := 0; i < len(value); i++ {
-		l.backup()
-	}
-
 	return true
 }
 
@@ -189,12 +185,12 @@ func (l *Lexer) accept(valid string) bool {
 
 // acceptString consumes the full given string, if the next tokens in the stream.
 func (l *Lexer) acceptString(value string) bool {
-	for index, runeValue := range value {
+	// Runes may be of any width, so the position is restored directly rather than by
+	// backing up, which can only step back over the last rune.
+	pos, width := l.pos, l.width
+	for _, runeValue := range value {
 		if l.next() != runeValue {
-			for i := 0; i <= index; i++ {
-				l.backup()
-			}
-
+			l.pos, l.width = pos, width
 			return false
 		}
 	}
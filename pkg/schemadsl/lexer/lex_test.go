@@ -223,6 +223,13 @@ var lexerTests = []lexerTest{
 			tEOF,
 		},
 	},
+	{
+		"cel string literal with multibyte runes", `'͔€'`,
+		[]Lexeme{
+			{TokenTypeString, 0, `'͔€'`, ""},
+			tEOF,
+		},
+	},
 	{
 		"unterminated cel string literal", "\"hi\nthere\"",
 		[]Lexeme{
@@ -51,6 +51,12 @@ Loop:
 			break Loop
 		}
 
+		// Report the error which stopped the lexer, if it was not already consumed.
+		if p.isToken(lexer.TokenTypeError) && p.currentToken.Error != "" {
+			p.emitErrorf("%s", p.currentToken.Error)
+			break Loop
+		}
+
 		// The top level of the DSL is a set of definitions and caveats:
 		// definition foobar { ... }
 		// caveat somecaveat (...) { ... }
@@ -159,6 +165,11 @@ consumer:
 
 		case lexer.TokenTypeEOF:
 			break consumer
+
+		case lexer.TokenTypeError:
+			// The lexer stops at its first error, so no closing brace will ever be found. The
+			// error token is left in place to be reported at the root level.
+			return exprNode, false
 		}
 
 		if startToken == nil {
@@ -113,6 +113,7 @@ func TestParser(t *testing.T) {
 		{"complex caveat test", "complexcaveat"},
 		{"empty caveat test", "emptycaveat"},
 		{"unclosed caveat test", "unclosedcaveat"},
+		{"caveat invalid character test", "caveatinvalidchar"},
 	}
 
 	for _, test := range parserTests {
@@ -0,0 +1,159 @@
+package spiceerrors
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExtendedReason is an error reason for a condition which is not covered by the
+// ErrorReason enum of the V1 API. Extended reasons are returned in ErrorInfo details
+// under the same Domain, and their names are stable.
+type ExtendedReason string
+
+const (
+	// ReasonRevisionExpired indicates the requested revision has been garbage collected.
+	ReasonRevisionExpired ExtendedReason = "ERROR_REASON_REVISION_EXPIRED"
+
+	// ReasonInvalidRevision indicates the requested revision is invalid or could not be
+	// determined.
+	ReasonInvalidRevision ExtendedReason = "ERROR_REASON_INVALID_REVISION"
+
+	// ReasonMismatchedDatastore indicates the requested revision was issued by a
+	// different datastore, such as one of another environment, or by the same
+	// datastore before a restore into it.
+	ReasonMismatchedDatastore ExtendedReason = "ERROR_REASON_MISMATCHED_DATASTORE"
+
+	// ReasonPinnedRevisionNotFound indicates the named pinned revision does not exist,
+	// either because it was never pinned, or because it was released or has expired.
+	ReasonPinnedRevisionNotFound ExtendedReason = "ERROR_REASON_PINNED_REVISION_NOT_FOUND"
+
+	// ReasonSchemaVersionNotFound indicates the requested version of the schema was not
+	// recorded.
+	ReasonSchemaVersionNotFound ExtendedReason = "ERROR_REASON_SCHEMA_VERSION_NOT_FOUND"
+
+	// ReasonAdmissionRejected indicates an admission hook rejected the mutation.
+	ReasonAdmissionRejected ExtendedReason = "ERROR_REASON_ADMISSION_REJECTED"
+
+	// ReasonJobNotFound indicates the requested job was never started.
+	ReasonJobNotFound ExtendedReason = "ERROR_REASON_JOB_NOT_FOUND"
+
+	// ReasonNamespaceTombstoneNotFound indicates the requested removed definition is not
+	// retained, or no longer is.
+	ReasonNamespaceTombstoneNotFound ExtendedReason = "ERROR_REASON_NAMESPACE_TOMBSTONE_NOT_FOUND"
+
+	// ReasonRelationshipExists indicates a relationship to be created already exists.
+	ReasonRelationshipExists ExtendedReason = "ERROR_REASON_RELATIONSHIP_EXISTS"
+
+	// ReasonCardinalityLimitExceeded indicates the mutation would leave a resource with
+	// more relationships on a relation than its configured hard limit.
+	ReasonCardinalityLimitExceeded ExtendedReason = "ERROR_REASON_CARDINALITY_LIMIT_EXCEEDED"
+
+	// ReasonMaximumDepthExceeded indicates the request exceeded the maximum dispatch
+	// depth, usually due to a recursive or overly deep data dependency.
+	ReasonMaximumDepthExceeded ExtendedReason = "ERROR_REASON_MAXIMUM_DEPTH_EXCEEDED"
+
+	// ReasonRelationMissingTypeInfo indicates a relation in the schema is missing the
+	// type information required to process the request.
+	ReasonRelationMissingTypeInfo ExtendedReason = "ERROR_REASON_RELATION_MISSING_TYPE_INFO"
+
+	// ReasonInvalidArgument indicates the request was malformed.
+	ReasonInvalidArgument ExtendedReason = "ERROR_REASON_INVALID_ARGUMENT"
+
+	// ReasonFailedPrecondition indicates the request could not be processed in the
+	// current state of the system.
+	ReasonFailedPrecondition ExtendedReason = "ERROR_REASON_FAILED_PRECONDITION"
+
+	// ReasonUnauthenticated indicates the request carried missing or invalid credentials.
+	ReasonUnauthenticated ExtendedReason = "ERROR_REASON_UNAUTHENTICATED"
+
+	// ReasonPermissionDenied indicates the credentials of the request were rejected.
+	ReasonPermissionDenied ExtendedReason = "ERROR_REASON_PERMISSION_DENIED"
+
+	// ReasonRequestCanceled indicates the request was canceled.
+	ReasonRequestCanceled ExtendedReason = "ERROR_REASON_REQUEST_CANCELED"
+
+	// ReasonDeadlineExceeded indicates the request did not complete before its deadline.
+	ReasonDeadlineExceeded ExtendedReason = "ERROR_REASON_DEADLINE_EXCEEDED"
+
+	// ReasonResourceExhausted indicates a limit was reached while serving the request.
+	ReasonResourceExhausted ExtendedReason = "ERROR_REASON_RESOURCE_EXHAUSTED"
+
+	// ReasonUnavailable indicates the service is temporarily unable to serve the request.
+	ReasonUnavailable ExtendedReason = "ERROR_REASON_UNAVAILABLE"
+
+	// ReasonUnimplemented indicates the request is not supported.
+	ReasonUnimplemented ExtendedReason = "ERROR_REASON_UNIMPLEMENTED"
+
+	// ReasonInternal indicates an internal error occurred.
+	ReasonInternal ExtendedReason = "ERROR_REASON_INTERNAL"
+
+	// ReasonUnknown indicates an error for which no more specific reason is known.
+	ReasonUnknown ExtendedReason = "ERROR_REASON_UNKNOWN"
+)
+
+var reasonForCode = map[codes.Code]ExtendedReason{
+	codes.Canceled:           ReasonRequestCanceled,
+	codes.InvalidArgument:    ReasonInvalidArgument,
+	codes.DeadlineExceeded:   ReasonDeadlineExceeded,
+	codes.PermissionDenied:   ReasonPermissionDenied,
+	codes.ResourceExhausted:  ReasonResourceExhausted,
+	codes.FailedPrecondition: ReasonFailedPrecondition,
+	codes.OutOfRange:         ReasonInvalidRevision,
+	codes.Unimplemented:      ReasonUnimplemented,
+	codes.Internal:           ReasonInternal,
+	codes.Unavailable:        ReasonUnavailable,
+	codes.Unauthenticated:    ReasonUnauthenticated,
+}
+
+// ReasonForCode returns the generic reason used for errors with the given code which
+// were not given a more specific reason.
+func ReasonForCode(code codes.Code) ExtendedReason {
+	if reason, ok := reasonForCode[code]; ok {
+		return reason
+	}
+	return ReasonUnknown
+}
+
+// ForExtendedReason returns an ErrorInfo block for an extended error reason.
+func ForExtendedReason(reason ExtendedReason, metadata map[string]string) *errdetails.ErrorInfo {
+	return &errdetails.ErrorInfo{
+		Reason:   string(reason),
+		Domain:   Domain,
+		Metadata: metadata,
+	}
+}
+
+// WithCodeAndExtendedReason returns a new error which wraps the existing error with a
+// gRPC code and an extended reason block.
+func WithCodeAndExtendedReason(err error, code codes.Code, reason ExtendedReason, metadata map[string]string) error {
+	return errWithStatus{err, WithCodeAndDetails(err, code, ForExtendedReason(reason, metadata))}
+}
+
+// ErrorInfoFromStatus returns the ErrorInfo attached to the status, if any.
+func ErrorInfoFromStatus(st *status.Status) (*errdetails.ErrorInfo, bool) {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info, true
+		}
+	}
+	return nil, false
+}
+
+// EnsureErrorInfo returns the error as a gRPC status error which carries an ErrorInfo
+// detail, adding one with the generic reason for its code if it has none.
+func EnsureErrorInfo(err error) error {
+	st := status.Convert(err)
+	if st.Code() == codes.OK {
+		return err
+	}
+	if _, ok := ErrorInfoFromStatus(st); ok {
+		return err
+	}
+
+	withInfo, derr := st.WithDetails(ForExtendedReason(ReasonForCode(st.Code()), nil))
+	if derr != nil {
+		return err
+	}
+	return withInfo.Err()
+}
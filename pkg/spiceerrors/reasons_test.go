@@ -0,0 +1,67 @@
+package spiceerrors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+)
+
+func TestEnsureErrorInfo(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedCode   codes.Code
+		expectedReason string
+	}{
+		{
+			"plain error",
+			errors.New("something went wrong"),
+			codes.Unknown,
+			string(ReasonUnknown),
+		},
+		{
+			"status without details",
+			status.Error(codes.Unauthenticated, "missing preshared key"),
+			codes.Unauthenticated,
+			string(ReasonUnauthenticated),
+		},
+		{
+			"status with existing reason",
+			WithCodeAndReason(errors.New("unknown definition"), codes.FailedPrecondition, v1.ErrorReason_ERROR_REASON_UNKNOWN_DEFINITION),
+			codes.FailedPrecondition,
+			"ERROR_REASON_UNKNOWN_DEFINITION",
+		},
+		{
+			"status with existing extended reason",
+			WithCodeAndExtendedReason(errors.New("too deep"), codes.ResourceExhausted, ReasonMaximumDepthExceeded, nil),
+			codes.ResourceExhausted,
+			string(ReasonMaximumDepthExceeded),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ensured := EnsureErrorInfo(tc.err)
+
+			st, ok := status.FromError(ensured)
+			require.True(t, ok)
+			require.Equal(t, tc.expectedCode, st.Code())
+			require.Len(t, st.Details(), 1)
+
+			info, ok := ErrorInfoFromStatus(st)
+			require.True(t, ok)
+			require.Equal(t, tc.expectedReason, info.GetReason())
+			require.Equal(t, Domain, info.GetDomain())
+		})
+	}
+}
+
+func TestEnsureErrorInfoNil(t *testing.T) {
+	require.NoError(t, EnsureErrorInfo(nil))
+}
@@ -26,3 +26,20 @@ func RequireReason(t testing.TB, reason v1.ErrorReason, err error, expectedMetad
 		require.Contains(t, info.Metadata, expectedKey)
 	}
 }
+
+// RequireExtendedReason asserts that an error is a gRPC error and returns the expected
+// extended reason in the ErrorInfo.
+func RequireExtendedReason(t testing.TB, reason ExtendedReason, err error, expectedMetadataKeys ...string) {
+	require.Error(t, err)
+	withStatus, ok := status.FromError(err)
+	require.True(t, ok)
+
+	info, ok := ErrorInfoFromStatus(withStatus)
+	require.True(t, ok, "missing ErrorInfo detail")
+	require.Equal(t, string(reason), info.GetReason())
+	require.Equal(t, Domain, info.GetDomain())
+
+	for _, expectedKey := range expectedMetadataKeys {
+		require.Contains(t, info.Metadata, expectedKey)
+	}
+}
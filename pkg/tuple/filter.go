@@ -0,0 +1,200 @@
+package tuple
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+const filterIDExpr = `[a-zA-Z0-9_][a-zA-Z0-9/_|-]{0,127}\*?|\*\*|\*`
+
+var filterRegex = regexp.MustCompile(fmt.Sprintf(
+	`^(?P<resourceType>%s)(:(?P<resourceID>%s))?(#(?P<resourceRel>%s|\*))?(@(?P<subjectType>%s)(:(?P<subjectID>%s))?(#(?P<subjectRel>%s|\.\.\.|\*))?)?(\[(?P<caveat>%s)\])?$`,
+	namespaceNameExpr,
+	filterIDExpr,
+	relationExpr,
+	namespaceNameExpr,
+	filterIDExpr,
+	relationExpr,
+	namespaceNameExpr,
+))
+
+var (
+	filterResourceTypeIndex = filterRegex.SubexpIndex("resourceType")
+	filterResourceIDIndex   = filterRegex.SubexpIndex("resourceID")
+	filterResourceRelIndex  = filterRegex.SubexpIndex("resourceRel")
+	filterSubjectTypeIndex  = filterRegex.SubexpIndex("subjectType")
+	filterSubjectIDIndex    = filterRegex.SubexpIndex("subjectID")
+	filterSubjectRelIndex   = filterRegex.SubexpIndex("subjectRel")
+	filterCaveatIndex       = filterRegex.SubexpIndex("caveat")
+)
+
+// ObjectIDPattern matches object IDs: exactly ID, or those starting with ID if IsPrefix.
+// A pattern with an empty ID matches any object ID, while one with ID PublicWildcard
+// matches only the public wildcard.
+type ObjectIDPattern struct {
+	ID       string
+	IsPrefix bool
+}
+
+// IsAny returns whether the pattern matches any object ID.
+func (p ObjectIDPattern) IsAny() bool {
+	return p.ID == ""
+}
+
+// Matches returns whether the object ID matches the pattern.
+func (p ObjectIDPattern) Matches(objectID string) bool {
+	if p.IsPrefix || p.IsAny() {
+		return strings.HasPrefix(objectID, p.ID)
+	}
+	return objectID == p.ID
+}
+
+func (p ObjectIDPattern) String() string {
+	if p.IsAny() {
+		return anyIDToken
+	}
+	if p.IsPrefix {
+		return p.ID + "*"
+	}
+	return p.ID
+}
+
+// anyIDToken is the ID of filter expressions matching any object ID. It differs from the
+// public wildcard `*`, which as a subject ID matches only wildcard subjects.
+const anyIDToken = "**"
+
+func parseObjectIDPattern(pattern string) ObjectIDPattern {
+	if pattern == anyIDToken {
+		return ObjectIDPattern{}
+	}
+	if pattern == PublicWildcard {
+		return ObjectIDPattern{ID: PublicWildcard}
+	}
+	if strings.HasSuffix(pattern, "*") {
+		return ObjectIDPattern{ID: strings.TrimSuffix(pattern, "*"), IsPrefix: true}
+	}
+	return ObjectIDPattern{ID: pattern}
+}
+
+// RelationshipFilter filters relationships, as parsed from a filter expression by
+// ParseRelationshipFilter.
+type RelationshipFilter struct {
+	// ResourceType is the type of the resources of the relationships.
+	ResourceType string
+
+	// ResourceID matches the IDs of the resources of the relationships.
+	ResourceID ObjectIDPattern
+
+	// Relation is the relation of the relationships, or empty for any.
+	Relation string
+
+	// Subject filters the subjects of the relationships, or is nil for any.
+	Subject *SubjectFilter
+
+	// Caveat is the name of the caveat of the relationships, or empty for any, caveated
+	// or not.
+	Caveat string
+}
+
+// SubjectFilter filters the subjects of relationships.
+type SubjectFilter struct {
+	// SubjectType is the type of the subjects.
+	SubjectType string
+
+	// SubjectID matches the IDs of the subjects, including the public wildcard.
+	SubjectID ObjectIDPattern
+
+	// Relation is the relation of the subjects: empty for any, or Ellipsis for subjects
+	// without one.
+	Relation string
+}
+
+// ParseRelationshipFilter parses a relationship filter expression, of the form
+//
+//	resource_type[:id][#relation][@subject_type[:id][#relation]][[caveat]]
+//
+// in which IDs ending with `*` match by prefix, `**` matches any ID, and omitted parts
+// match anything. A subject ID of `*` matches only the public wildcard, as in
+// `document@user:*`. `document:tenant1-*#viewer@user:alice*`, for example, matches the
+// viewers of the documents of tenant1 whose user IDs start with alice, and `#...` matches
+// subjects without a relation.
+func ParseRelationshipFilter(expr string) (*RelationshipFilter, error) {
+	groups := filterRegex.FindStringSubmatch(strings.TrimSpace(expr))
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("invalid relationship filter `%s`: must be of the form resource_type[:id][#relation][@subject_type[:id][#relation]][[caveat]]", expr)
+	}
+
+	filter := &RelationshipFilter{
+		ResourceType: groups[filterResourceTypeIndex],
+		ResourceID:   parseObjectIDPattern(groups[filterResourceIDIndex]),
+		Relation:     groups[filterResourceRelIndex],
+		Caveat:       groups[filterCaveatIndex],
+	}
+	if filter.Relation == "*" {
+		filter.Relation = ""
+	}
+	if filter.ResourceID.ID == PublicWildcard && !filter.ResourceID.IsPrefix {
+		return nil, fmt.Errorf("invalid relationship filter `%s`: resource IDs cannot be the public wildcard; use `%s` to match any ID", expr, anyIDToken)
+	}
+
+	if subjectType := groups[filterSubjectTypeIndex]; subjectType != "" {
+		filter.Subject = &SubjectFilter{
+			SubjectType: subjectType,
+			SubjectID:   parseObjectIDPattern(groups[filterSubjectIDIndex]),
+			Relation:    groups[filterSubjectRelIndex],
+		}
+		if filter.Subject.Relation == "*" {
+			filter.Subject.Relation = ""
+		}
+	}
+
+	return filter, nil
+}
+
+// Matches returns whether the relationship matches the filter.
+func (rf *RelationshipFilter) Matches(tpl *core.RelationTuple) bool {
+	if tpl.ResourceAndRelation.Namespace != rf.ResourceType ||
+		!rf.ResourceID.Matches(tpl.ResourceAndRelation.ObjectId) ||
+		(rf.Relation != "" && tpl.ResourceAndRelation.Relation != rf.Relation) {
+		return false
+	}
+
+	if rf.Caveat != "" && (tpl.Caveat == nil || tpl.Caveat.CaveatName != rf.Caveat) {
+		return false
+	}
+
+	if rf.Subject == nil {
+		return true
+	}
+	return tpl.Subject.Namespace == rf.Subject.SubjectType &&
+		rf.Subject.SubjectID.Matches(tpl.Subject.ObjectId) &&
+		(rf.Subject.Relation == "" || tpl.Subject.Relation == rf.Subject.Relation)
+}
+
+// String returns the filter as an expression.
+func (rf *RelationshipFilter) String() string {
+	var sb strings.Builder
+	sb.WriteString(rf.ResourceType)
+	if !rf.ResourceID.IsAny() {
+		sb.WriteString(":" + rf.ResourceID.String())
+	}
+	if rf.Relation != "" {
+		sb.WriteString("#" + rf.Relation)
+	}
+	if rf.Subject != nil {
+		sb.WriteString("@" + rf.Subject.SubjectType)
+		if !rf.Subject.SubjectID.IsAny() {
+			sb.WriteString(":" + rf.Subject.SubjectID.String())
+		}
+		if rf.Subject.Relation != "" {
+			sb.WriteString("#" + rf.Subject.Relation)
+		}
+	}
+	if rf.Caveat != "" {
+		sb.WriteString("[" + rf.Caveat + "]")
+	}
+	return sb.String()
+}
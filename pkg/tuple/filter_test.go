@@ -0,0 +1,109 @@
+package tuple
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRelationshipFilter(t *testing.T) {
+	testCases := []struct {
+		expr      string
+		expected  *RelationshipFilter
+		canonical string
+	}{
+		{
+			"document",
+			&RelationshipFilter{ResourceType: "document", ResourceID: ObjectIDPattern{}},
+			"document",
+		},
+		{
+			"document:**#viewer@user:alice*",
+			&RelationshipFilter{
+				ResourceType: "document",
+				ResourceID:   ObjectIDPattern{},
+				Relation:     "viewer",
+				Subject:      &SubjectFilter{SubjectType: "user", SubjectID: ObjectIDPattern{ID: "alice", IsPrefix: true}},
+			},
+			"document#viewer@user:alice*",
+		},
+		{
+			"tenant/document:first#*@group:admins#member[only_weekdays]",
+			&RelationshipFilter{
+				ResourceType: "tenant/document",
+				ResourceID:   ObjectIDPattern{ID: "first"},
+				Subject:      &SubjectFilter{SubjectType: "group", SubjectID: ObjectIDPattern{ID: "admins"}, Relation: "member"},
+				Caveat:       "only_weekdays",
+			},
+			"tenant/document:first@group:admins#member[only_weekdays]",
+		},
+		{
+			"document:tenant1-*@user#...",
+			&RelationshipFilter{
+				ResourceType: "document",
+				ResourceID:   ObjectIDPattern{ID: "tenant1-", IsPrefix: true},
+				Subject:      &SubjectFilter{SubjectType: "user", SubjectID: ObjectIDPattern{}, Relation: Ellipsis},
+			},
+			"document:tenant1-*@user#...",
+		},
+		{
+			"document@user:*",
+			&RelationshipFilter{
+				ResourceType: "document",
+				Subject:      &SubjectFilter{SubjectType: "user", SubjectID: ObjectIDPattern{ID: PublicWildcard}},
+			},
+			"document@user:*",
+		},
+		{
+			"document@user:**",
+			&RelationshipFilter{
+				ResourceType: "document",
+				Subject:      &SubjectFilter{SubjectType: "user", SubjectID: ObjectIDPattern{}},
+			},
+			"document@user",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.expr, func(t *testing.T) {
+			parsed, err := ParseRelationshipFilter(tc.expr)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, parsed)
+			require.Equal(t, tc.canonical, parsed.String())
+		})
+	}
+
+	for _, invalid := range []string{"", "document:", "document:a*b", "document#viewer@", "Document", "document[]", "document@user:tom#...x", "document:*", "document:***"} {
+		_, err := ParseRelationshipFilter(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestRelationshipFilterMatches(t *testing.T) {
+	filter, err := ParseRelationshipFilter("document:tenant1-*#viewer@user:a*")
+	require.NoError(t, err)
+
+	require.True(t, filter.Matches(MustParse("document:tenant1-first#viewer@user:alice")))
+	require.True(t, filter.Matches(MustParse("document:tenant1-#viewer@user:a")))
+	require.False(t, filter.Matches(MustParse("document:tenant2-first#viewer@user:alice")))
+	require.False(t, filter.Matches(MustParse("document:tenant1-first#editor@user:alice")))
+	require.False(t, filter.Matches(MustParse("document:tenant1-first#viewer@user:bob")))
+	require.False(t, filter.Matches(MustParse("folder:tenant1-first#viewer@user:alice")))
+
+	filter, err = ParseRelationshipFilter("document@user:*")
+	require.NoError(t, err)
+	require.True(t, filter.Matches(MustParse("document:first#viewer@user:*")))
+	require.False(t, filter.Matches(MustParse("document:first#viewer@user:tom")))
+	require.False(t, filter.Matches(MustParse("document:first#viewer@group:admins#member")))
+
+	filter, err = ParseRelationshipFilter("document@group#...")
+	require.NoError(t, err)
+	require.False(t, filter.Matches(MustParse("document:first#viewer@group:admins#member")))
+	require.True(t, filter.Matches(MustParse("document:first#parent@group:admins")))
+
+	filter, err = ParseRelationshipFilter("document[only_weekdays]")
+	require.NoError(t, err)
+	require.False(t, filter.Matches(MustParse("document:first#viewer@user:tom")))
+	require.True(t, filter.Matches(WithCaveat(MustParse("document:first#viewer@user:tom"), "only_weekdays")))
+}
@@ -0,0 +1,54 @@
+package tuple
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func FuzzParse(f *testing.F) {
+	for _, tc := range testCases {
+		f.Add(tc.input)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		tpl := Parse(input)
+		rel := ParseRel(input)
+		if tpl == nil {
+			require.Nil(t, rel)
+			return
+		}
+
+		// Anything which parses must survive serializing and parsing again.
+		reparsed := Parse(String(tpl))
+		require.NotNil(t, reparsed, "unable to reparse %q serialized from %q", String(tpl), input)
+		require.True(t, proto.Equal(tpl, reparsed), "%q reparsed as %q", String(tpl), String(reparsed))
+
+		require.NotNil(t, rel)
+		require.True(t, proto.Equal(tpl, FromRelationship(rel)))
+	})
+}
+
+func FuzzParseONR(f *testing.F) {
+	for _, tc := range onrTestCases {
+		f.Add(tc.serialized)
+	}
+	for _, tc := range subjectOnrTestCases {
+		f.Add(tc.serialized)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		if onr := ParseONR(input); onr != nil {
+			reparsed := ParseONR(StringONR(onr))
+			require.NotNil(t, reparsed, "unable to reparse %q serialized from %q", StringONR(onr), input)
+			require.True(t, proto.Equal(onr, reparsed))
+		}
+
+		if onr := ParseSubjectONR(input); onr != nil {
+			reparsed := ParseSubjectONR(StringONR(onr))
+			require.NotNil(t, reparsed, "unable to reparse %q serialized from %q", StringONR(onr), input)
+			require.True(t, proto.Equal(onr, reparsed))
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package tuple
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// maxInterned bounds the number of distinct strings interned. Namespace and relation
+// names are bounded by the schemas in use, but the names found in requests are not, so
+// once the bound is reached further names are returned as given.
+const maxInterned = 1 << 14
+
+// interner deduplicates namespace and relation names, so that the many tuples loaded for
+// a high volume of checks share a single copy of each name, rather than each holding on
+// to its own copy or to the larger buffer from which it was sliced.
+type interner struct {
+	values sync.Map
+	count  atomic.Int64
+}
+
+var names = &interner{}
+
+func (i *interner) intern(s string) string {
+	if s == "" {
+		return s
+	}
+
+	if existing, ok := i.values.Load(s); ok {
+		return existing.(string)
+	}
+
+	if i.count.Load() >= maxInterned {
+		return s
+	}
+
+	// The interned copy is cloned so that it does not reference the buffer s came from.
+	cloned := strings.Clone(s)
+	existing, loaded := i.values.LoadOrStore(cloned, cloned)
+	if !loaded {
+		i.count.Add(1)
+	}
+	return existing.(string)
+}
@@ -0,0 +1,49 @@
+package tuple
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntern(t *testing.T) {
+	require := require.New(t)
+	i := &interner{}
+
+	first := i.intern(string([]byte("document")))
+	second := i.intern(string([]byte("document")))
+	require.Equal("document", second)
+	require.Equal(stringData(first), stringData(second))
+
+	// An interned string must not reference the buffer it was sliced from.
+	source := "folder:root"
+	sliced := i.intern(source[:6])
+	require.Equal("folder", sliced)
+	require.NotEqual(stringData(source), stringData(sliced))
+
+	require.Equal("", i.intern(""))
+}
+
+func TestInternBounded(t *testing.T) {
+	require := require.New(t)
+	i := &interner{}
+
+	for n := 0; n < maxInterned; n++ {
+		i.intern(fmt.Sprintf("name%d", n))
+	}
+	require.Equal(int64(maxInterned), i.count.Load())
+
+	overflow := string([]byte("overflow"))
+	require.Equal(stringData(overflow), stringData(i.intern(overflow)))
+	require.Equal(int64(maxInterned), i.count.Load())
+
+	// Names interned before the bound was reached are still shared.
+	require.Equal("name1", i.intern(string([]byte("name1"))))
+}
+
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
@@ -1,18 +1,19 @@
 package tuple
 
 import (
-	"fmt"
 	"sort"
-
-	"github.com/jzelinskie/stringz"
+	"strings"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
-const (
-	// Format is the serialized form of the tuple
-	formatWithRel            = "%s:%s#%s"
-	formatImplicitSubjectRel = "%s:%s"
+var (
+	onrResourceTypeIndex = onrRegex.SubexpIndex("resourceType")
+	onrResourceIDIndex   = onrRegex.SubexpIndex("resourceID")
+	onrResourceRelIndex  = onrRegex.SubexpIndex("resourceRel")
+	subjectTypeIndex     = subjectRegex.SubexpIndex("subjectType")
+	subjectIDIndex       = subjectRegex.SubexpIndex("subjectID")
+	subjectRelIndex      = subjectRegex.SubexpIndex("subjectRel")
 )
 
 // ObjectAndRelation creates an ONR from string pieces.
@@ -43,14 +44,13 @@ func ParseSubjectONR(subjectOnr string) *core.ObjectAndRelation {
 	}
 
 	relation := Ellipsis
-	subjectRelIndex := stringz.SliceIndex(subjectRegex.SubexpNames(), "subjectRel")
 	if len(groups[subjectRelIndex]) > 0 {
-		relation = groups[subjectRelIndex]
+		relation = names.intern(groups[subjectRelIndex])
 	}
 
 	return &core.ObjectAndRelation{
-		Namespace: groups[stringz.SliceIndex(subjectRegex.SubexpNames(), "subjectType")],
-		ObjectId:  groups[stringz.SliceIndex(subjectRegex.SubexpNames(), "subjectID")],
+		Namespace: names.intern(groups[subjectTypeIndex]),
+		ObjectId:  groups[subjectIDIndex],
 		Relation:  relation,
 	}
 }
@@ -64,9 +64,9 @@ func ParseONR(onr string) *core.ObjectAndRelation {
 	}
 
 	return &core.ObjectAndRelation{
-		Namespace: groups[stringz.SliceIndex(onrRegex.SubexpNames(), "resourceType")],
-		ObjectId:  groups[stringz.SliceIndex(onrRegex.SubexpNames(), "resourceID")],
-		Relation:  groups[stringz.SliceIndex(onrRegex.SubexpNames(), "resourceRel")],
+		Namespace: names.intern(groups[onrResourceTypeIndex]),
+		ObjectId:  groups[onrResourceIDIndex],
+		Relation:  names.intern(groups[onrResourceRelIndex]),
 	}
 }
 
@@ -76,7 +76,7 @@ func StringRR(rr *core.RelationReference) string {
 		return ""
 	}
 
-	return fmt.Sprintf("%s#%s", rr.Namespace, rr.Relation)
+	return rr.Namespace + "#" + rr.Relation
 }
 
 // StringONR converts an ONR object to a string.
@@ -86,10 +86,29 @@ func StringONR(onr *core.ObjectAndRelation) string {
 	}
 
 	if onr.Relation == Ellipsis {
-		return fmt.Sprintf(formatImplicitSubjectRel, onr.Namespace, onr.ObjectId)
+		return onr.Namespace + ":" + onr.ObjectId
+	}
+
+	return onr.Namespace + ":" + onr.ObjectId + "#" + onr.Relation
+}
+
+// onrLen returns the length of the string form of an ONR.
+func onrLen(onr *core.ObjectAndRelation) int {
+	if onr.Relation == Ellipsis {
+		return len(onr.Namespace) + 1 + len(onr.ObjectId)
 	}
+	return len(onr.Namespace) + 1 + len(onr.ObjectId) + 1 + len(onr.Relation)
+}
 
-	return fmt.Sprintf(formatWithRel, onr.Namespace, onr.ObjectId, onr.Relation)
+// writeONR writes the string form of an ONR to the builder.
+func writeONR(sb *strings.Builder, onr *core.ObjectAndRelation) {
+	sb.WriteString(onr.Namespace)
+	sb.WriteByte(':')
+	sb.WriteString(onr.ObjectId)
+	if onr.Relation != Ellipsis {
+		sb.WriteByte('#')
+		sb.WriteString(onr.Relation)
+	}
 }
 
 // StringsONRs converts ONR objects to a string slice, sorted.
@@ -3,6 +3,7 @@ package tuple
 import (
 	"fmt"
 	"regexp"
+	"strings"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
@@ -55,6 +56,15 @@ var parserRegex = regexp.MustCompile(
 	),
 )
 
+var (
+	parserResourceTypeIndex = parserRegex.SubexpIndex("resourceType")
+	parserResourceIDIndex   = parserRegex.SubexpIndex("resourceID")
+	parserResourceRelIndex  = parserRegex.SubexpIndex("resourceRel")
+	parserSubjectTypeIndex  = parserRegex.SubexpIndex("subjectType")
+	parserSubjectIDIndex    = parserRegex.SubexpIndex("subjectID")
+	parserSubjectRelIndex   = parserRegex.SubexpIndex("subjectRel")
+)
+
 // ValidateResourceID ensures that the given resource ID is valid. Returns an error if not.
 func ValidateResourceID(objectID string) error {
 	if !resourceIDRegex.MatchString(objectID) {
@@ -79,7 +89,12 @@ func String(tpl *core.RelationTuple) string {
 		return ""
 	}
 
-	return fmt.Sprintf("%s@%s", StringONR(tpl.ResourceAndRelation), StringONR(tpl.Subject))
+	var sb strings.Builder
+	sb.Grow(onrLen(tpl.ResourceAndRelation) + 1 + onrLen(tpl.Subject))
+	writeONR(&sb, tpl.ResourceAndRelation)
+	sb.WriteByte('@')
+	writeONR(&sb, tpl.Subject)
+	return sb.String()
 }
 
 // MustRelString converts a relationship into a string.  Will panic if
@@ -111,23 +126,18 @@ func Parse(tpl string) *core.RelationTuple {
 	}
 
 	subjectRelation := Ellipsis
-	subjectRelIndex := stringz.SliceIndex(parserRegex.SubexpNames(), "subjectRel")
-	if len(groups[subjectRelIndex]) > 0 {
-		subjectRelation = groups[subjectRelIndex]
+	if len(groups[parserSubjectRelIndex]) > 0 {
+		subjectRelation = names.intern(groups[parserSubjectRelIndex])
 	}
 
-	return &core.RelationTuple{
-		ResourceAndRelation: &core.ObjectAndRelation{
-			Namespace: groups[stringz.SliceIndex(parserRegex.SubexpNames(), "resourceType")],
-			ObjectId:  groups[stringz.SliceIndex(parserRegex.SubexpNames(), "resourceID")],
-			Relation:  groups[stringz.SliceIndex(parserRegex.SubexpNames(), "resourceRel")],
-		},
-		Subject: &core.ObjectAndRelation{
-			Namespace: groups[stringz.SliceIndex(parserRegex.SubexpNames(), "subjectType")],
-			ObjectId:  groups[stringz.SliceIndex(parserRegex.SubexpNames(), "subjectID")],
-			Relation:  subjectRelation,
-		},
-	}
+	return newTuple(
+		names.intern(groups[parserResourceTypeIndex]),
+		groups[parserResourceIDIndex],
+		names.intern(groups[parserResourceRelIndex]),
+		names.intern(groups[parserSubjectTypeIndex]),
+		groups[parserSubjectIDIndex],
+		subjectRelation,
+	)
 }
 
 func ParseRel(rel string) *v1.Relationship {
@@ -169,30 +179,35 @@ func MustToRelationship(tpl *core.RelationTuple) *v1.Relationship {
 	return ToRelationship(tpl)
 }
 
+// relationshipAlloc holds a Relationship along with the messages it references, so that
+// a conversion makes a single allocation rather than one per message.
+type relationshipAlloc struct {
+	rel      v1.Relationship
+	resource v1.ObjectReference
+	subject  v1.SubjectReference
+	object   v1.ObjectReference
+}
+
 // ToRelationship converts a RelationTuple into a Relationship.
 func ToRelationship(tpl *core.RelationTuple) *v1.Relationship {
-	var caveat *v1.ContextualizedCaveat
+	alloc := &relationshipAlloc{}
+	alloc.resource.ObjectType = tpl.ResourceAndRelation.Namespace
+	alloc.resource.ObjectId = tpl.ResourceAndRelation.ObjectId
+	alloc.object.ObjectType = tpl.Subject.Namespace
+	alloc.object.ObjectId = tpl.Subject.ObjectId
+	alloc.subject.Object = &alloc.object
+	alloc.subject.OptionalRelation = stringz.Default(tpl.Subject.Relation, "", Ellipsis)
+	alloc.rel.Resource = &alloc.resource
+	alloc.rel.Relation = tpl.ResourceAndRelation.Relation
+	alloc.rel.Subject = &alloc.subject
+
 	if tpl.Caveat != nil {
-		caveat = &v1.ContextualizedCaveat{
+		alloc.rel.OptionalCaveat = &v1.ContextualizedCaveat{
 			CaveatName: tpl.Caveat.CaveatName,
 			Context:    tpl.Caveat.Context,
 		}
 	}
-	return &v1.Relationship{
-		Resource: &v1.ObjectReference{
-			ObjectType: tpl.ResourceAndRelation.Namespace,
-			ObjectId:   tpl.ResourceAndRelation.ObjectId,
-		},
-		Relation: tpl.ResourceAndRelation.Relation,
-		Subject: &v1.SubjectReference{
-			Object: &v1.ObjectReference{
-				ObjectType: tpl.Subject.Namespace,
-				ObjectId:   tpl.Subject.ObjectId,
-			},
-			OptionalRelation: stringz.Default(tpl.Subject.Relation, "", Ellipsis),
-		},
-		OptionalCaveat: caveat,
-	}
+	return &alloc.rel
 }
 
 // MustToFilter converts a RelationTuple into a RelationshipFilter. Will panic if
@@ -246,9 +261,12 @@ func RelToFilter(rel *v1.Relationship) *v1.RelationshipFilter {
 // slice of RelationshipUpdate.
 func UpdatesToRelationshipUpdates(updates []*core.RelationTupleUpdate) []*v1.RelationshipUpdate {
 	relationshipUpdates := make([]*v1.RelationshipUpdate, 0, len(updates))
+	backing := make([]v1.RelationshipUpdate, len(updates))
 
-	for _, update := range updates {
-		relationshipUpdates = append(relationshipUpdates, UpdateToRelationshipUpdate(update))
+	for i, update := range updates {
+		backing[i].Operation = relationshipUpdateOperation(update.Operation)
+		backing[i].Relationship = ToRelationship(update.Tuple)
+		relationshipUpdates = append(relationshipUpdates, &backing[i])
 	}
 
 	return relationshipUpdates
@@ -256,9 +274,12 @@ func UpdatesToRelationshipUpdates(updates []*core.RelationTupleUpdate) []*v1.Rel
 
 func UpdateFromRelationshipUpdates(updates []*v1.RelationshipUpdate) []*core.RelationTupleUpdate {
 	relationshipUpdates := make([]*core.RelationTupleUpdate, 0, len(updates))
+	backing := make([]core.RelationTupleUpdate, len(updates))
 
-	for _, update := range updates {
-		relationshipUpdates = append(relationshipUpdates, UpdateFromRelationshipUpdate(update))
+	for i, update := range updates {
+		backing[i].Operation = tupleUpdateOperation(update.Operation)
+		backing[i].Tuple = FromRelationship(update.Relationship)
+		relationshipUpdates = append(relationshipUpdates, &backing[i])
 	}
 
 	return relationshipUpdates
@@ -267,22 +288,23 @@ func UpdateFromRelationshipUpdates(updates []*v1.RelationshipUpdate) []*core.Rel
 // UpdateToRelationshipUpdate converts a RelationTupleUpdate into a
 // RelationshipUpdate.
 func UpdateToRelationshipUpdate(update *core.RelationTupleUpdate) *v1.RelationshipUpdate {
-	var op v1.RelationshipUpdate_Operation
-	switch update.Operation {
+	return &v1.RelationshipUpdate{
+		Operation:    relationshipUpdateOperation(update.Operation),
+		Relationship: ToRelationship(update.Tuple),
+	}
+}
+
+func relationshipUpdateOperation(op core.RelationTupleUpdate_Operation) v1.RelationshipUpdate_Operation {
+	switch op {
 	case core.RelationTupleUpdate_CREATE:
-		op = v1.RelationshipUpdate_OPERATION_CREATE
+		return v1.RelationshipUpdate_OPERATION_CREATE
 	case core.RelationTupleUpdate_DELETE:
-		op = v1.RelationshipUpdate_OPERATION_DELETE
+		return v1.RelationshipUpdate_OPERATION_DELETE
 	case core.RelationTupleUpdate_TOUCH:
-		op = v1.RelationshipUpdate_OPERATION_TOUCH
+		return v1.RelationshipUpdate_OPERATION_TOUCH
 	default:
 		panic("unknown tuple mutation")
 	}
-
-	return &v1.RelationshipUpdate{
-		Operation:    op,
-		Relationship: ToRelationship(update.Tuple),
-	}
 }
 
 // MustFromRelationship converts a Relationship into a RelationTuple.
@@ -295,47 +317,69 @@ func MustFromRelationship(r *v1.Relationship) *core.RelationTuple {
 
 // FromRelationship converts a Relationship into a RelationTuple.
 func FromRelationship(r *v1.Relationship) *core.RelationTuple {
-	var caveat *core.ContextualizedCaveat
+	subjectRelation := Ellipsis
+	if r.Subject.OptionalRelation != "" {
+		subjectRelation = names.intern(r.Subject.OptionalRelation)
+	}
+
+	tpl := newTuple(
+		names.intern(r.Resource.ObjectType),
+		r.Resource.ObjectId,
+		names.intern(r.Relation),
+		names.intern(r.Subject.Object.ObjectType),
+		r.Subject.Object.ObjectId,
+		subjectRelation,
+	)
 	if r.OptionalCaveat != nil {
-		caveat = &core.ContextualizedCaveat{
+		tpl.Caveat = &core.ContextualizedCaveat{
 			CaveatName: r.OptionalCaveat.CaveatName,
 			Context:    r.OptionalCaveat.Context,
 		}
 	}
-	return &core.RelationTuple{
-		ResourceAndRelation: &core.ObjectAndRelation{
-			Namespace: r.Resource.ObjectType,
-			ObjectId:  r.Resource.ObjectId,
-			Relation:  r.Relation,
-		},
-		Subject: &core.ObjectAndRelation{
-			Namespace: r.Subject.Object.ObjectType,
-			ObjectId:  r.Subject.Object.ObjectId,
-			Relation:  stringz.DefaultEmpty(r.Subject.OptionalRelation, Ellipsis),
-		},
-		Caveat: caveat,
-	}
+	return tpl
+}
+
+// tupleAlloc holds a RelationTuple along with its resource and subject, so that they are
+// made in a single allocation.
+type tupleAlloc struct {
+	tpl      core.RelationTuple
+	resource core.ObjectAndRelation
+	subject  core.ObjectAndRelation
+}
+
+func newTuple(resourceType, resourceID, relation, subjectType, subjectID, subjectRelation string) *core.RelationTuple {
+	alloc := &tupleAlloc{}
+	alloc.resource.Namespace = resourceType
+	alloc.resource.ObjectId = resourceID
+	alloc.resource.Relation = relation
+	alloc.subject.Namespace = subjectType
+	alloc.subject.ObjectId = subjectID
+	alloc.subject.Relation = subjectRelation
+	alloc.tpl.ResourceAndRelation = &alloc.resource
+	alloc.tpl.Subject = &alloc.subject
+	return &alloc.tpl
 }
 
 // UpdateFromRelationshipUpdate converts a RelationshipUpdate into a
 // RelationTupleUpdate.
 func UpdateFromRelationshipUpdate(update *v1.RelationshipUpdate) *core.RelationTupleUpdate {
-	var op core.RelationTupleUpdate_Operation
-	switch update.Operation {
+	return &core.RelationTupleUpdate{
+		Operation: tupleUpdateOperation(update.Operation),
+		Tuple:     FromRelationship(update.Relationship),
+	}
+}
+
+func tupleUpdateOperation(op v1.RelationshipUpdate_Operation) core.RelationTupleUpdate_Operation {
+	switch op {
 	case v1.RelationshipUpdate_OPERATION_CREATE:
-		op = core.RelationTupleUpdate_CREATE
+		return core.RelationTupleUpdate_CREATE
 	case v1.RelationshipUpdate_OPERATION_DELETE:
-		op = core.RelationTupleUpdate_DELETE
+		return core.RelationTupleUpdate_DELETE
 	case v1.RelationshipUpdate_OPERATION_TOUCH:
-		op = core.RelationTupleUpdate_TOUCH
+		return core.RelationTupleUpdate_TOUCH
 	default:
 		panic("unknown tuple mutation")
 	}
-
-	return &core.RelationTupleUpdate{
-		Operation: op,
-		Tuple:     FromRelationship(update.Relationship),
-	}
 }
 
 // WithCaveat adds the given caveat name to the tuple. This is for testing only and may be
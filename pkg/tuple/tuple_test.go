@@ -210,3 +210,59 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertUpdates(t *testing.T) {
+	require := require.New(t)
+
+	updates := []*core.RelationTupleUpdate{
+		Create(MustParse("document:first#viewer@user:tom")),
+		Touch(WithCaveat(MustParse("document:first#viewer@group:eng#member"), "somecaveat")),
+		Delete(MustParse("document:second#parent@folder:root")),
+	}
+
+	relUpdates := UpdatesToRelationshipUpdates(updates)
+	require.Len(relUpdates, len(updates))
+	for i, update := range updates {
+		require.Equal(UpdateToRelationshipUpdate(update), relUpdates[i])
+	}
+
+	roundTripped := UpdateFromRelationshipUpdates(relUpdates)
+	require.Equal(updates, roundTripped)
+	for i, relUpdate := range relUpdates {
+		require.Equal(UpdateFromRelationshipUpdate(relUpdate), roundTripped[i])
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = Parse("document:first#viewer@group:eng#member")
+	}
+}
+
+func BenchmarkString(b *testing.B) {
+	tpl := MustParse("document:first#viewer@group:eng#member")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = String(tpl)
+	}
+}
+
+func BenchmarkToRelationship(b *testing.B) {
+	tpl := MustParse("document:first#viewer@group:eng#member")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ToRelationship(tpl)
+	}
+}
+
+func BenchmarkFromRelationship(b *testing.B) {
+	relationship := ParseRel("document:first#viewer@group:eng#member")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = FromRelationship(relationship)
+	}
+}
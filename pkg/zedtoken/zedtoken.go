@@ -2,13 +2,17 @@
 package zedtoken
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/rs/zerolog"
 	"github.com/shopspring/decimal"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 	zedtoken "github.com/authzed/spicedb/pkg/proto/impl/v1"
@@ -24,12 +28,92 @@ const (
 // zedtoken argument to Decode
 var ErrNilZedToken = errors.New("zedtoken pointer was nil")
 
-// NewFromRevision generates an encoded zedtoken from an integral revision.
+// ErrMismatchedDatastore is returned when decoding a zedtoken which was minted
+// by a datastore other than the one it is decoded against, such as a datastore
+// of another environment, or by the same datastore before it was restored.
+type ErrMismatchedDatastore struct {
+	error
+	tokenDatastoreID string
+	datastoreID      string
+	restored         bool
+	minimumTimestamp time.Time
+}
+
+// TokenDatastoreID is the unique ID of the datastore which minted the zedtoken.
+func (err ErrMismatchedDatastore) TokenDatastoreID() string {
+	return err.tokenDatastoreID
+}
+
+// DatastoreID is the unique ID of the datastore the zedtoken was decoded against.
+func (err ErrMismatchedDatastore) DatastoreID() string {
+	return err.datastoreID
+}
+
+// Restored returns whether the zedtoken was minted by the same datastore at another
+// epoch, before a restore into it.
+func (err ErrMismatchedDatastore) Restored() bool {
+	return err.restored
+}
+
+// MinimumTimestamp is the time by which the revision of the zedtoken was committed in
+// the datastore which minted it, if the zedtoken carries one.
+func (err ErrMismatchedDatastore) MinimumTimestamp() (time.Time, bool) {
+	return err.minimumTimestamp, !err.minimumTimestamp.IsZero()
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrMismatchedDatastore) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Str("token_datastore_id", err.tokenDatastoreID).Str("datastore_id", err.datastoreID).Bool("restored", err.restored)
+}
+
+// Binding identifies the contents of a datastore to which zedtokens are bound: the unique
+// ID of the datastore, and its epoch, which is bumped by every restore into it. The zero
+// Binding binds nothing.
+type Binding struct {
+	DatastoreID string
+	Epoch       uint64
+}
+
+// BindingOf returns the binding of the current contents of the datastore. Its epoch is
+// read from the datastore on every call, as the datastore may have been restored by
+// another process.
+func BindingOf(ctx context.Context, ds datastore.Datastore) (Binding, error) {
+	datastoreID, err := ds.UniqueID(ctx)
+	if err != nil {
+		return Binding{}, err
+	}
+
+	epoch, err := common.DatastoreEpoch(ctx, ds)
+	if err != nil {
+		return Binding{}, err
+	}
+	return Binding{DatastoreID: datastoreID, Epoch: epoch}, nil
+}
+
+// NewFromRevision generates an encoded zedtoken from a revision, which is not
+// bound to any datastore.
 func NewFromRevision(revision datastore.Revision) *v1.ZedToken {
+	return NewFromDatastoreRevision(revision, nil, Binding{})
+}
+
+// NewFromDatastoreRevision generates an encoded zedtoken from a revision of the
+// datastore with the given binding, which is then rejected by DecodeDatastoreRevision
+// for any other datastore, or for the same datastore once it has been restored. The
+// zedtoken also carries the time by which the revision was committed, for datastores
+// peered with it.
+func NewFromDatastoreRevision(revision datastore.Revision, ds datastore.Datastore, binding Binding) *v1.ZedToken {
+	var minimumTimestampNanos int64
+	if binding.DatastoreID != "" {
+		minimumTimestampNanos = committedBy(ds, revision).UnixNano()
+	}
+
 	toEncode := &zedtoken.DecodedZedToken{
 		VersionOneof: &zedtoken.DecodedZedToken_V1{
 			V1: &zedtoken.DecodedZedToken_V1ZedToken{
-				Revision: revision.String(),
+				Revision:              revision.String(),
+				DatastoreUniqueId:     binding.DatastoreID,
+				MinimumTimestampNanos: minimumTimestampNanos,
+				DatastoreEpoch:        binding.Epoch,
 			},
 		},
 	}
@@ -69,8 +153,17 @@ func Decode(encoded *v1.ZedToken) (*zedtoken.DecodedZedToken, error) {
 	return decoded, nil
 }
 
-// DecodeRevision converts and extracts the revision from a zedtoken or legacy zookie.
+// DecodeRevision converts and extracts the revision from a zedtoken or legacy zookie,
+// regardless of the datastore which minted it.
 func DecodeRevision(encoded *v1.ZedToken, ds revisionDecoder) (datastore.Revision, error) {
+	return DecodeDatastoreRevision(encoded, ds, Binding{})
+}
+
+// DecodeDatastoreRevision converts and extracts the revision from a zedtoken or legacy
+// zookie, returning ErrMismatchedDatastore if the zedtoken was minted by a datastore
+// other than the one with the given binding, or by the same datastore at another epoch.
+// Zedtokens minted without a datastore unique ID are accepted.
+func DecodeDatastoreRevision(encoded *v1.ZedToken, ds revisionDecoder, binding Binding) (datastore.Revision, error) {
 	decoded, err := Decode(encoded)
 	if err != nil {
 		return datastore.NoRevision, err
@@ -80,6 +173,30 @@ func DecodeRevision(encoded *v1.ZedToken, ds revisionDecoder) (datastore.Revisio
 	case *zedtoken.DecodedZedToken_DeprecatedV1Zookie:
 		return revision.NewFromDecimal(decimal.NewFromInt(int64(ver.DeprecatedV1Zookie.Revision))), nil
 	case *zedtoken.DecodedZedToken_V1:
+		tokenDatastoreID := ver.V1.DatastoreUniqueId
+		if binding.DatastoreID != "" && tokenDatastoreID != "" && tokenDatastoreID != binding.DatastoreID {
+			var minimumTimestamp time.Time
+			if ver.V1.MinimumTimestampNanos > 0 {
+				minimumTimestamp = time.Unix(0, ver.V1.MinimumTimestampNanos)
+			}
+
+			return datastore.NoRevision, ErrMismatchedDatastore{
+				error:            fmt.Errorf("zedtoken was minted by datastore `%s`, not by this datastore `%s`", tokenDatastoreID, binding.DatastoreID),
+				tokenDatastoreID: tokenDatastoreID,
+				datastoreID:      binding.DatastoreID,
+				minimumTimestamp: minimumTimestamp,
+			}
+		}
+
+		if tokenDatastoreID != "" && tokenDatastoreID == binding.DatastoreID && ver.V1.DatastoreEpoch != binding.Epoch {
+			return datastore.NoRevision, ErrMismatchedDatastore{
+				error:            fmt.Errorf("zedtoken was minted at epoch %d of datastore `%s`, which has since been restored to epoch %d", ver.V1.DatastoreEpoch, tokenDatastoreID, binding.Epoch),
+				tokenDatastoreID: tokenDatastoreID,
+				datastoreID:      binding.DatastoreID,
+				restored:         true,
+			}
+		}
+
 		parsed, err := ds.RevisionFromString(ver.V1.Revision)
 		if err != nil {
 			return datastore.NoRevision, fmt.Errorf(errDecodeError, err)
@@ -90,6 +207,16 @@ func DecodeRevision(encoded *v1.ZedToken, ds revisionDecoder) (datastore.Revisio
 	}
 }
 
+// committedBy returns a time by which the revision was committed: its commit time for
+// datastores which report one, or the current time, which is necessarily later, for the
+// others.
+func committedBy(ds datastore.Datastore, rev datastore.Revision) time.Time {
+	if committedAt, ok := common.RevisionCommitTime(ds, rev); ok {
+		return committedAt
+	}
+	return time.Now()
+}
+
 type revisionDecoder interface {
 	RevisionFromString(string) (datastore.Revision, error)
 }
@@ -3,6 +3,7 @@ package zedtoken
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/shopspring/decimal"
@@ -135,3 +136,78 @@ func TestDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeDatastoreRevision(t *testing.T) {
+	rev := revision.NewFromDecimal(decimal.NewFromInt(1621538189028928000))
+
+	testCases := []struct {
+		name               string
+		tokenDatastoreID   string
+		datastoreID        string
+		expectedMismatched bool
+	}{
+		{"same datastore", "first", "first", false},
+		{"different datastore", "first", "second", true},
+		{"unbound token", "", "first", false},
+		{"unchecked datastore", "first", "", false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			encoded := NewFromDatastoreRevision(rev, nil, Binding{DatastoreID: tc.tokenDatastoreID})
+			decoded, err := DecodeDatastoreRevision(encoded, revision.DecimalDecoder{}, Binding{DatastoreID: tc.datastoreID})
+			if !tc.expectedMismatched {
+				require.NoError(err)
+				require.True(rev.Equal(decoded))
+				return
+			}
+
+			var mismatched ErrMismatchedDatastore
+			require.ErrorAs(err, &mismatched)
+			require.Equal(tc.tokenDatastoreID, mismatched.TokenDatastoreID())
+			require.Equal(tc.datastoreID, mismatched.DatastoreID())
+		})
+	}
+}
+
+func TestMinimumTimestampWithoutCommitTimes(t *testing.T) {
+	require := require.New(t)
+
+	// The revisions of datastores which do not report commit times, such as the
+	// transaction IDs of MySQL, carry the time at which their zedtokens were minted.
+	before := time.Now()
+	encoded := NewFromDatastoreRevision(revision.NewFromDecimal(decimal.NewFromInt(5)), nil, Binding{DatastoreID: "first"})
+	_, err := DecodeDatastoreRevision(encoded, revision.DecimalDecoder{}, Binding{DatastoreID: "second"})
+
+	var mismatched ErrMismatchedDatastore
+	require.ErrorAs(err, &mismatched)
+	minimum, ok := mismatched.MinimumTimestamp()
+	require.True(ok)
+	require.False(minimum.Before(before))
+}
+
+func TestDecodeRestoredDatastore(t *testing.T) {
+	require := require.New(t)
+
+	rev := revision.NewFromDecimal(decimal.NewFromInt(1621538189028928000))
+	encoded := NewFromDatastoreRevision(rev, nil, Binding{DatastoreID: "first", Epoch: 1})
+
+	decoded, err := DecodeDatastoreRevision(encoded, revision.DecimalDecoder{}, Binding{DatastoreID: "first", Epoch: 1})
+	require.NoError(err)
+	require.True(rev.Equal(decoded))
+
+	// Zedtokens minted before a restore are rejected after it.
+	_, err = DecodeDatastoreRevision(encoded, revision.DecimalDecoder{}, Binding{DatastoreID: "first", Epoch: 2})
+	var mismatched ErrMismatchedDatastore
+	require.ErrorAs(err, &mismatched)
+	require.True(mismatched.Restored())
+	_, ok := mismatched.MinimumTimestamp()
+	require.False(ok)
+
+	// Zedtokens not bound to a datastore are accepted at any epoch.
+	_, err = DecodeDatastoreRevision(NewFromRevision(rev), revision.DecimalDecoder{}, Binding{DatastoreID: "first", Epoch: 2})
+	require.NoError(err)
+}